@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/components/browse"
+	"github.com/ztaylor/claude-mon/internal/config"
+	"github.com/ztaylor/claude-mon/internal/daemon"
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/diff"
+	"github.com/ztaylor/claude-mon/internal/theme"
+)
+
+// queryBrowse opens a list of query results, recent edits by default, one
+// file's edits or sessions, with fuzzy filtering and a preview pane. It
+// draws on stderr, so choosing a result with Enter prints its file or
+// workspace path on stdout for the shell, e.g. vim $(claude-mon query browse).
+func queryBrowse(args []string, group, user string) error {
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stderr) {
+		return fmt.Errorf("query browse needs a terminal")
+	}
+
+	source := "recent"
+	if len(args) > 0 && (args[0] == "recent" || args[0] == "file" || args[0] == "sessions") {
+		source, args = args[0], args[1:]
+	}
+	query := &daemon.Query{Type: source, Group: group, User: user, Limit: 200}
+	switch source {
+	case "recent":
+		expr, rest, err := parseFilterFlags(args)
+		if err != nil {
+			return err
+		}
+		query.Filter = expr
+		if err := limitArg(rest, 0, &query.Limit); err != nil {
+			return err
+		}
+	case "file":
+		if len(args) < 1 {
+			return usagef("missing <path>")
+		}
+		query.FilePath = args[0]
+		if err := limitArg(args, 1, &query.Limit); err != nil {
+			return err
+		}
+	case "sessions":
+		if err := limitArg(args, 0, &query.Limit); err != nil {
+			return err
+		}
+	}
+
+	result, err := sendQuery(query)
+	if err != nil {
+		return err
+	}
+	t := browseTheme()
+	var items []browse.Item
+	var title string
+	switch source {
+	case "sessions":
+		title = "Sessions"
+		for _, s := range result.Sessions {
+			items = append(items, sessionItem(s))
+		}
+	default:
+		title = "Recent edits"
+		if source == "file" {
+			title = "Edits to " + diff.RelativePath(query.FilePath)
+		}
+		for _, e := range result.Edits {
+			items = append(items, editItem(e, t))
+		}
+	}
+	if len(items) == 0 {
+		fmt.Fprintln(os.Stderr, "Nothing found")
+		return nil
+	}
+
+	p := tea.NewProgram(browse.New(title, items, t), tea.WithAltScreen(), tea.WithOutput(os.Stderr))
+	final, err := p.Run()
+	if err != nil {
+		return err
+	}
+	if item, ok := final.(browse.Model).Chosen(); ok {
+		fmt.Println(item.Value)
+	}
+	return nil
+}
+
+// browseTheme returns the theme --theme or the TUI config names
+func browseTheme() *theme.Theme {
+	name := selectedTheme
+	if name == "" {
+		if cfg, err := config.Load(); err == nil {
+			name = cfg.Theme
+		}
+	}
+	return theme.Get(theme.Resolve(name))
+}
+
+// editItem is an edit in the browser, previewed as its diff
+func editItem(e *database.Edit, t *theme.Theme) browse.Item {
+	heading := diff.RelativePath(e.FilePath)
+	if e.LineNum > 0 {
+		heading += fmt.Sprintf(":%d", e.LineNum)
+	}
+	detail := e.Timestamp.Local().Format("2006-01-02 15:04") + "  " + e.ToolName
+	if e.Summary != "" {
+		detail += "  " + e.Summary
+	}
+
+	header := []string{t.Title.Render(heading), t.Dim.Render(detail)}
+	if e.User != "" {
+		header = append(header, t.Dim.Render("User: "+e.User))
+	}
+	if e.Note != "" {
+		header = append(header, t.Normal.Render("Note: "+e.Note))
+	}
+	body := diff.FormatDiff(e.OldString, e.NewString, t, diff.DefaultOptions())
+	if diff.IsBinary(e.OldString) || diff.IsBinary(e.NewString) {
+		body = diff.FormatBinary(e.OldString, e.NewString, t)
+	}
+	return browse.Item{
+		Heading: heading,
+		Detail:  detail,
+		Preview: strings.Join(header, "\n") + "\n\n" + body,
+		Value:   e.FilePath,
+	}
+}
+
+// sessionItem is a session in the browser, previewed as its details
+func sessionItem(s *database.Session) browse.Item {
+	heading := s.WorkspaceName
+	if s.Branch != "" {
+		heading += " (" + s.Branch + ")"
+	}
+	detail := "last active " + s.LastActivity.Local().Format("2006-01-02 15:04")
+	preview := []string{
+		"Workspace: " + s.WorkspacePath,
+		"Branch:    " + s.Branch,
+		"Commit:    " + s.CommitSHA,
+		"Started:   " + s.StartedAt.Local().Format("2006-01-02 15:04:05"),
+		"Last:      " + s.LastActivity.Local().Format("2006-01-02 15:04:05"),
+	}
+	return browse.Item{
+		Heading: heading,
+		Detail:  detail,
+		Preview: strings.Join(preview, "\n"),
+		Value:   s.WorkspacePath,
+	}
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ztaylor/claude-mon/internal/theme"
+)
+
+// flagSpec is a flag a command accepts
+type flagSpec struct {
+	name    string // Long form, e.g. "--group"
+	short   string // One-letter form, e.g. "-f", if any
+	value   string // Placeholder for the flag's value, empty for a switch
+	usage   string
+	choices []string // Values shell completion offers for it
+}
+
+// command is a claude-mon command or subcommand. Flags are declared up
+// front, so unknown ones are rejected before the command runs, and help and
+// shell completion are generated from the same tree.
+type command struct {
+	name     string
+	aliases  []string
+	args     string // Positional arguments, e.g. "<path> [limit]"; empty if it takes none (see argSpecs)
+	summary  string
+	long     string // More detail, shown in the command's own help
+	flags    []flagSpec
+	commands []*command
+	choices  []string // Values shell completion offers for the first argument
+	hidden   bool
+	rawArgs  bool // Arguments are passed on as they are, flags and all
+
+	// run is called with the arguments after the command's name, flags in
+	// their long form. Only the flags declared for the command reach it, and
+	// its positional arguments have been checked against args. A subcommand
+	// without its own run is passed to its parent's, its name first.
+	run func(args []string) error
+}
+
+// usageError is a command line that doesn't match the command tree
+type usageError struct {
+	err error
+}
+
+func (e *usageError) Error() string { return e.err.Error() }
+
+// usagef returns a usageError
+func usagef(format string, a ...any) error {
+	return &usageError{fmt.Errorf(format, a...)}
+}
+
+// globalFlags are accepted anywhere on the command line and set the
+// package's settings before a command runs
+var globalFlags = []flagSpec{
+	{name: "--config", value: "path", usage: "Path to daemon config file (default: ~/.config/claude-mon/daemon.toml)"},
+	{name: "--theme", short: "-t", value: "name", usage: `Set color theme (default: config theme, or dark); "auto" follows the terminal`,
+		choices: append(append([]string{}, theme.Available()...), theme.Auto)},
+	{name: "--persist", short: "-p", usage: "Persist history to file (.claude-mon-history.json)"},
+	{name: "--debug", short: "-d", usage: "Enable debug logging"},
+}
+
+// setGlobalFlag applies a global flag's value
+func setGlobalFlag(name, value string) {
+	switch name {
+	case "--config":
+		configPath = value
+	case "--theme":
+		selectedTheme = value
+	case "--persist":
+		persistMode = true
+	case "--debug":
+		debugMode = true
+	}
+}
+
+// execute runs the command argv names and returns the exit status: 2 for
+// a command line that doesn't parse, 1 if the command failed
+func execute(root *command, argv []string) int {
+	path, args, err := root.parse(argv)
+	name := commandName(path)
+	if err == nil {
+		err = runCommand(path, args)
+	}
+
+	var usage *usageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.As(err, &usage):
+		fmt.Fprintf(os.Stderr, "%s: %v\nRun '%s --help' for usage.\n", name, err, name)
+		return 2
+	default:
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return 1
+	}
+}
+
+// parse finds the command argv names, applies its global flags and returns
+// the command's path from the root with its remaining arguments. --help
+// yields a nil argument list.
+func (root *command) parse(argv []string) ([]*command, []string, error) {
+	path := []*command{root}
+	var args, positional []string
+	literal := false
+	for i := 0; i < len(argv); i++ {
+		arg := argv[i]
+		cmd := path[len(path)-1]
+		if literal || arg == "-" || !strings.HasPrefix(arg, "-") {
+			if len(positional) == 0 {
+				if sub := cmd.subcommand(arg); sub != nil {
+					path = append(path, sub)
+					literal = literal || sub.rawArgs
+					continue
+				}
+			}
+			positional = append(positional, arg)
+			args = append(args, arg)
+			continue
+		}
+		if arg == "--" {
+			literal = true
+			continue
+		}
+		if arg == "--help" || arg == "-h" {
+			return path, nil, nil
+		}
+
+		name, value, hasValue := strings.Cut(arg, "=")
+		spec, global := lookupFlag(globalFlags, name), true
+		if spec == nil {
+			spec, global = lookupFlag(cmd.flags, name), false
+		}
+		switch {
+		case spec == nil:
+			return path, nil, usagef("unknown flag: %s", name)
+		case spec.value == "" && hasValue:
+			return path, nil, usagef("flag %s doesn't take a value", spec.name)
+		case spec.value != "" && !hasValue:
+			if i+1 >= len(argv) {
+				return path, nil, usagef("flag needs an argument: %s <%s>", spec.name, spec.value)
+			}
+			i++
+			value = argv[i]
+		}
+		if global {
+			setGlobalFlag(spec.name, value)
+			continue
+		}
+		args = append(args, spec.name)
+		if spec.value != "" {
+			args = append(args, value)
+		}
+	}
+
+	cmd := path[len(path)-1]
+	if len(positional) > 0 && cmd.args == "" && len(cmd.commands) > 0 {
+		return path, nil, usagef("unknown command %q", positional[0])
+	}
+	if !cmd.rawArgs {
+		if err := checkArgs(argSpecs(cmd.args), positional); err != nil {
+			return path, nil, err
+		}
+	}
+	if args == nil {
+		args = []string{}
+	}
+	return path, args, nil
+}
+
+// argSpec is a positional argument a command takes
+type argSpec struct {
+	name     string
+	required bool
+	variadic bool // Takes any number of words
+}
+
+// countArgs are the positional arguments that must be whole numbers
+var countArgs = map[string]bool{"limit": true, "version": true}
+
+// argSpecs reads a command's positional arguments from its args: <name> is
+// required, [name] optional, and a name ending in ... takes any number of
+// words. Alternatives such as <bash|zsh|fish> are one argument.
+func argSpecs(args string) []argSpec {
+	var specs []argSpec
+	depth, start := 0, 0
+	for i := 0; i <= len(args); i++ {
+		if i < len(args) {
+			switch args[i] {
+			case '<', '[':
+				depth++
+				continue
+			case '>', ']':
+				depth--
+				continue
+			case ' ':
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+		if token := args[start:i]; len(token) > 2 {
+			name := token[1 : len(token)-1]
+			specs = append(specs, argSpec{
+				name:     strings.TrimSuffix(name, "..."),
+				required: token[0] == '<',
+				variadic: strings.HasSuffix(name, "..."),
+			})
+		}
+		start = i + 1
+	}
+	return specs
+}
+
+// checkArgs checks a command's positional arguments against its specs
+func checkArgs(specs []argSpec, positional []string) error {
+	variadic := false
+	for i, spec := range specs {
+		variadic = variadic || spec.variadic
+		if spec.required && i >= len(positional) {
+			return usagef("missing <%s>", spec.name)
+		}
+	}
+	if !variadic && len(positional) > len(specs) {
+		return usagef("unexpected argument %q", positional[len(specs)])
+	}
+	for i, arg := range positional {
+		if i < len(specs) && countArgs[specs[i].name] {
+			if _, err := parseCount(specs[i].name, arg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseCount parses a whole-number positional argument, such as a limit
+func parseCount(name, arg string) (int, error) {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 0 {
+		return 0, usagef("%s must be a whole number, got %q", name, arg)
+	}
+	return n, nil
+}
+
+// runCommand runs the command at the end of path with args, or prints its
+// help if args is nil
+func runCommand(path []*command, args []string) error {
+	if args == nil {
+		printCommandHelp(os.Stdout, path)
+		return nil
+	}
+	// Subcommands without their own run are passed to the nearest parent's
+	for i := len(path) - 1; i >= 0; i-- {
+		if path[i].run == nil {
+			continue
+		}
+		var names []string
+		for _, sub := range path[i+1:] {
+			names = append(names, sub.name)
+		}
+		return path[i].run(append(names, args...))
+	}
+	return usagef("missing command")
+}
+
+// subcommand returns the subcommand called name, or nil
+func (c *command) subcommand(name string) *command {
+	for _, sub := range c.commands {
+		if sub.name == name {
+			return sub
+		}
+		for _, alias := range sub.aliases {
+			if alias == name {
+				return sub
+			}
+		}
+	}
+	return nil
+}
+
+// lookupFlag returns the flag in specs called name, long or short, or nil
+func lookupFlag(specs []flagSpec, name string) *flagSpec {
+	for i := range specs {
+		if specs[i].name == name || specs[i].short != "" && specs[i].short == name {
+			return &specs[i]
+		}
+	}
+	return nil
+}
+
+// commandName returns the command line path names, e.g. "claude-mon query recent"
+func commandName(path []*command) string {
+	names := make([]string, len(path))
+	for i, cmd := range path {
+		names[i] = cmd.name
+	}
+	return strings.Join(names, " ")
+}
+
+// helpColumn is where help text starts after a command or flag
+const helpColumn = 32
+
+// writeHelpEntry writes one aligned line of help, moving text to its own
+// line when the entry is too long to fit before it
+func writeHelpEntry(w io.Writer, entry, text string) {
+	entry = "  " + entry
+	if len(entry) > helpColumn-2 {
+		fmt.Fprintf(w, "%s\n%s%s\n", entry, strings.Repeat(" ", helpColumn), text)
+		return
+	}
+	fmt.Fprintf(w, "%-*s%s\n", helpColumn, entry, text)
+}
+
+// flagEntry is how help shows a flag, e.g. "--theme, -t <name>"
+func flagEntry(spec flagSpec) string {
+	entry := spec.name
+	if spec.short != "" {
+		entry += ", " + spec.short
+	}
+	if spec.value != "" {
+		entry += " <" + spec.value + ">"
+	}
+	return entry
+}
+
+// visibleCommands returns a command's subcommands that help and completion
+// show
+func (c *command) visibleCommands() []*command {
+	var visible []*command
+	for _, sub := range c.commands {
+		if !sub.hidden {
+			visible = append(visible, sub)
+		}
+	}
+	return visible
+}
+
+// printCommandHelp prints the usage of the command at the end of path: its
+// arguments, subcommands and flags
+func printCommandHelp(w io.Writer, path []*command) {
+	cmd := path[len(path)-1]
+	if len(path) == 1 {
+		printHelp(w, cmd)
+		return
+	}
+
+	name := commandName(path)
+	usage := name
+	if len(cmd.visibleCommands()) > 0 {
+		usage += " <command>"
+	}
+	if cmd.args != "" {
+		usage += " " + cmd.args
+	}
+	if len(cmd.flags) > 0 {
+		usage += " [flags]"
+	}
+	fmt.Fprintf(w, "Usage: %s\n\n%s\n", usage, cmd.summary)
+	if cmd.long != "" {
+		fmt.Fprintf(w, "\n%s\n", cmd.long)
+	}
+	if len(cmd.aliases) > 0 {
+		fmt.Fprintf(w, "\nAliases: %s\n", strings.Join(cmd.aliases, ", "))
+	}
+	if subs := cmd.visibleCommands(); len(subs) > 0 {
+		fmt.Fprintln(w, "\nCommands:")
+		for _, sub := range subs {
+			writeHelpEntry(w, strings.TrimSpace(sub.name+" "+sub.args), sub.summary)
+		}
+	}
+	if len(cmd.flags) > 0 {
+		fmt.Fprintln(w, "\nFlags:")
+		for _, spec := range cmd.flags {
+			writeHelpEntry(w, flagEntry(spec), spec.usage)
+		}
+	}
+	fmt.Fprintln(w, "\nGlobal Flags:")
+	for _, spec := range globalFlags {
+		writeHelpEntry(w, flagEntry(spec), spec.usage)
+	}
+}
+
+// writeCommandList writes every visible command under c that runs on its
+// own, e.g. "daemon start" but not "daemon", for the top-level help
+func writeCommandList(w io.Writer, prefix string, c *command) {
+	for _, sub := range c.visibleCommands() {
+		name := prefix + sub.name
+		if len(sub.visibleCommands()) == 0 {
+			writeHelpEntry(w, strings.TrimSpace(name+" "+sub.args), sub.summary)
+		}
+		writeCommandList(w, name+" ", sub)
+	}
+}
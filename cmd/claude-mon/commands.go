@@ -0,0 +1,365 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ztaylor/claude-mon/internal/annotate"
+	"github.com/ztaylor/claude-mon/internal/theme"
+)
+
+// Flags several query commands share
+var (
+	outputFlags = []flagSpec{
+		{name: "--json", usage: "Print one JSON document: type, count and results"},
+		{name: "--table", usage: "Print aligned columns, cutting long cells short"},
+		{name: "--no-color", usage: "Don't color the output (also $NO_COLOR)"},
+	}
+	scopeFlags = []flagSpec{
+		{name: "--group", value: "name", usage: "Only workspaces in a group from the daemon config"},
+		{name: "--user", value: "name", usage: "Only one user's edits (on a shared daemon, only admins see other users')"},
+	}
+	filterFlags = []flagSpec{
+		{name: "--path", value: "glob", usage: "Only files matching a glob, e.g. 'internal/**'"},
+		{name: "--lang", value: "names", usage: "Only files in these languages, comma separated"},
+		{name: "--tool", value: "name", usage: "Only edits made with a tool, e.g. Write"},
+		{name: "--since", value: "when", usage: "Only edits since a duration ago (30m, 2h, 7d) or a date"},
+		{name: "--until", value: "when", usage: "Only edits until a duration ago or a date"},
+	}
+)
+
+// withFlags joins flag lists
+func withFlags(lists ...[]flagSpec) []flagSpec {
+	var flags []flagSpec
+	for _, list := range lists {
+		flags = append(flags, list...)
+	}
+	return flags
+}
+
+// rootCommand returns the claude-mon command tree
+func rootCommand() *command {
+	root := &command{
+		name:    "claude-mon",
+		summary: "Run the TUI",
+		flags: []flagSpec{
+			{name: "--list-themes", usage: "List available themes"},
+			{name: "--version", short: "-v", usage: "Print the version"},
+		},
+		run: runRoot,
+	}
+	root.commands = []*command{
+		{
+			name:    "send",
+			summary: "Send JSON to running TUI (for hooks)",
+			run: func([]string) error {
+				// Fail silently - TUI might not be running
+				sendToSocket()
+				return nil
+			},
+		},
+		{
+			name:    "gate",
+			summary: "Hold an edit until it's allowed in the TUI (PreToolUse hook, with [gate] enabled)",
+			run: func([]string) error {
+				os.Exit(gateToolCall())
+				return nil
+			},
+		},
+		{
+			name:    "help",
+			args:    "[command...]",
+			summary: "Show help for claude-mon or a command",
+			run: func(args []string) error {
+				path := []*command{root}
+				for _, name := range args {
+					sub := path[len(path)-1].subcommand(name)
+					if sub == nil {
+						return usagef("unknown command %q", strings.Join(args, " "))
+					}
+					path = append(path, sub)
+				}
+				printCommandHelp(os.Stdout, path)
+				return nil
+			},
+		},
+		{
+			name:    "version",
+			summary: "Print the version",
+			run: func([]string) error {
+				fmt.Println("claude-mon " + version)
+				return nil
+			},
+		},
+		{
+			name:    "write-config",
+			args:    "[path]",
+			summary: "Write the default daemon configuration (default: ~/.config/claude-mon/daemon.toml)",
+			run: func(args []string) error {
+				path := ""
+				if len(args) > 0 {
+					path = args[0]
+				}
+				return writeDefaultConfig(path)
+			},
+		},
+		{
+			name:    "completion",
+			args:    "<bash|zsh|fish>",
+			summary: "Print a shell completion script",
+			long: `Load it in the current shell, or add the line to your shell's startup file:
+  source <(claude-mon completion bash)
+  source <(claude-mon completion zsh)
+  claude-mon completion fish | source`,
+			choices: []string{"bash", "zsh", "fish"},
+			run:     printCompletion,
+		},
+		{
+			name:    "__complete",
+			args:    "[words...] <current>",
+			summary: "Print completions for a command line (used by the completion scripts)",
+			hidden:  true,
+			rawArgs: true,
+			run: func(args []string) error {
+				if len(args) == 0 {
+					return nil
+				}
+				for _, c := range root.complete(args[:len(args)-1], args[len(args)-1]) {
+					fmt.Println(c)
+				}
+				return nil
+			},
+		},
+		daemonCommand(),
+		queryCommand(),
+		{
+			name:    "timeline",
+			args:    "[limit]",
+			summary: "Edits of every tracked workspace, newest first with a badge per workspace",
+			long:    "Enter pages back through older edits; --follow prints the latest edits oldest first, then new ones as they are made.",
+			flags: withFlags([]flagSpec{
+				{name: "--since", value: "when", usage: "Only edits since a duration ago (30m, 2h, 7d) or a date"},
+				{name: "--cursor", value: "cursor", usage: "Start from a page cursor printed by an earlier page"},
+				{name: "--follow", short: "-f", usage: "Print new edits as they are made"},
+			}, scopeFlags),
+			run: handleTimelineCommand,
+		},
+		{
+			name:    "digest",
+			summary: "Activity summary for a team channel: files touched, lines added/removed, sessions and prompts used",
+			long:    "The daemon can post it daily, see [digest] in daemon.toml.",
+			flags: withFlags([]flagSpec{
+				{name: "--since", value: "when", usage: "Summarize activity since a duration ago or a date (default: 24h)"},
+				{name: "--format", value: "format", usage: "slack, discord or markdown (default)", choices: []string{"slack", "discord", "markdown"}},
+				{name: "--workspace", value: "path", usage: "Only one workspace"},
+			}, scopeFlags),
+			run: handleDigestCommand,
+		},
+		{
+			name:    "token",
+			summary: "Manage scoped API tokens; clients send $CLAUDE_MON_TOKEN",
+			run:     handleTokenCommand,
+			commands: []*command{
+				{name: "create", args: "<name>", summary: "Create a token, shown once", flags: []flagSpec{
+					{name: "--scope", value: "scope", usage: "read (default), ingest or admin", choices: []string{"read", "ingest", "admin"}},
+				}},
+				{name: "revoke", args: "<name|id>", summary: "Revoke a token"},
+				{name: "list", summary: "List tokens"},
+			},
+		},
+		{
+			name:    "prompts",
+			summary: "Manage the prompt library",
+			run:     handlePromptsCommand,
+			commands: []*command{
+				{name: "sync", summary: "Pull and push the global prompt library (needs prompts_remote in config.toml)"},
+				{name: "import", summary: "Copy the global and project prompt files into the daemon's library"},
+				{name: "export", args: "[dir]", summary: "Write the daemon's prompts out as files (default ~/.claude/prompts)"},
+			},
+		},
+		{
+			name:    "workspace",
+			aliases: []string{"workspaces"},
+			summary: "The current workspace's daemon rows, history, prompts, context and plans",
+			run:     handleWorkspaceCommand,
+			commands: []*command{
+				{name: "archive", args: "[file]", summary: "Bundle the workspace's state into one file", flags: []flagSpec{
+					{name: "--purge", usage: "Remove the workspace's state once archived"},
+				}},
+				{name: "restore", args: "<file>", summary: "Revive an archived workspace here", flags: []flagSpec{
+					{name: "--force", usage: "Overwrite existing files"},
+				}},
+				{name: "list", summary: "Show the tracked/ignored rules in effect here"},
+			},
+		},
+		{
+			name:    "snapshot",
+			aliases: []string{"snapshots"},
+			summary: "File contents before each Claude session's first edit",
+			run:     handleSnapshotCommand,
+			commands: []*command{
+				{name: "list", args: "[file]", summary: "List the current workspace's snapshots, newest first"},
+				{name: "restore", args: "<file>", summary: "Restore a file to the latest snapshot, or snapshot <id>", flags: []flagSpec{
+					{name: "--id", value: "id", usage: "Restore this snapshot instead of the latest"},
+				}},
+			},
+		},
+		{
+			name:    "logs",
+			summary: "Print the daemon's log, or the TUI's (written with --debug)",
+			flags: []flagSpec{
+				{name: "--follow", short: "-f", usage: "Keep printing new entries"},
+				{name: "--level", value: "level", usage: "Only entries at this level or above", choices: []string{"debug", "info", "warn", "error"}},
+				{name: "--component", value: "name", usage: "Only entries from one component"},
+				{name: "--tui", usage: "Print the TUI's log instead of the daemon's"},
+			},
+			run: handleLogsCommand,
+		},
+		{
+			name:    "mcp",
+			summary: "Serve the workspace's edit history over MCP on stdio",
+			long: `Tools: get_recent_edits, get_file_history, get_working_context and search_history.
+Register it with Claude Code: claude mcp add claude-mon -- claude-mon mcp`,
+			run: func([]string) error { return runMCPServer() },
+		},
+		{
+			name:    "ctl",
+			summary: "Control the running TUI in the current workspace",
+			run:     sendControlCommand,
+			commands: []*command{
+				{name: "switch-tab", args: "<tab>", summary: "Switch to a tab", choices: []string{"history", "prompts", "ralph", "plan", "context", "chat"}},
+				{name: "select-file", args: "<path>", summary: "Select the newest change to a file"},
+				{name: "set-filter", args: "[expr]", summary: "Set the history filter (empty clears)"},
+				{name: "jump-to-edit", args: "<n|newest|oldest|next|prev>", summary: "Select an edit", choices: []string{"newest", "oldest", "next", "prev"}},
+				{name: "quit", summary: "Quit the TUI"},
+			},
+		},
+	}
+	return root
+}
+
+// daemonCommand returns the daemon commands
+func daemonCommand() *command {
+	return &command{
+		name:    "daemon",
+		summary: "Run and maintain the background daemon",
+		run:     handleDaemonCommand,
+		commands: []*command{
+			{name: "start", summary: "Start the background daemon"},
+			{name: "stop", summary: "Stop the background daemon"},
+			{name: "status", summary: "Check daemon status"},
+			{name: "dedupe", summary: "Store file snapshots once per distinct content, reporting the space saved"},
+			{
+				name:    "migrate",
+				summary: "Show or change the database's schema version",
+				commands: []*command{
+					{name: "status", summary: "Show the database's schema version and each migration"},
+					{name: "up", args: "[version]", summary: "Apply pending migrations (the daemon does on start)", flags: []flagSpec{
+						{name: "--dry-run", usage: "Show what would be applied"},
+					}},
+					{name: "down", args: "<version>", summary: "Revert migrations after <version>, with the daemon stopped", flags: []flagSpec{
+						{name: "--dry-run", usage: "Show what would be reverted"},
+					}},
+				},
+			},
+			{
+				name:    "sync",
+				args:    "<database | [user@]host[:database]>",
+				summary: "Merge edits, sessions and prompts with another machine's history, both ways",
+				flags: []flagSpec{
+					{name: "--serve", usage: "Answer a sync on stdin and stdout (what sync runs over ssh)"},
+				},
+			},
+		},
+	}
+}
+
+// queryCommand returns the query commands
+func queryCommand() *command {
+	return &command{
+		name:    "query",
+		summary: "Query the daemon's history",
+		run:     handleQueryCommand,
+		commands: []*command{
+			{name: "recent", args: "[limit]", summary: "Show recent activity (all sessions)",
+				flags: withFlags(filterFlags, scopeFlags, outputFlags)},
+			{name: "file", args: "<path> [limit]", summary: "Show edits for specific file",
+				flags: withFlags([]flagSpec{{name: "--show-content", usage: "Print the file's content after each edit"}}, outputFlags)},
+			{name: "search", args: "<text> [limit]", summary: "Find edits whose old or new text, summary or note contains text",
+				flags: withFlags(filterFlags, scopeFlags, outputFlags)},
+			{name: "bookmarks", args: "[limit]", summary: "Show bookmarked edits (all sessions)",
+				flags: withFlags(scopeFlags, outputFlags)},
+			{name: "prompts", args: "[name] [limit]", summary: "List prompts, optionally only those with a tag",
+				flags: withFlags([]flagSpec{{name: "--tag", value: "tag", usage: "Only prompts with a tag"}}, outputFlags)},
+			{name: "injections", args: "[name] [limit]", summary: "Show when prompts were sent, and where",
+				flags: outputFlags},
+			{name: "prompt-stats", args: "[limit]", summary: "Count sends per prompt and list prompts never sent",
+				flags: outputFlags},
+			{name: "sessions", args: "[limit]", summary: "List all sessions",
+				flags: withFlags(scopeFlags, outputFlags)},
+			{name: "events", args: "[limit]", summary: "Show the incident timeline (Ralph, huge edits, failures, ...)",
+				flags: withFlags(scopeFlags, outputFlags)},
+			{name: "ralph", args: "[limit]", summary: "Show ended Ralph loops: outcome, iterations used, duration and edits",
+				flags: outputFlags},
+			{name: "checks", args: "[file] [limit]", summary: "Show post-edit check results in this workspace, with failing output",
+				flags: outputFlags},
+			{name: "review-report", summary: "Markdown summary of the review: progress and each rejected change with its note and diff",
+				flags: withFlags([]flagSpec{{name: "--workspace", value: "path", usage: "Report on another workspace"}}, outputFlags)},
+			{name: "groups", summary: "List workspace groups from the daemon config",
+				flags: outputFlags},
+			{name: "export", args: "[limit]", summary: "Write every edit with its old and new strings as JSON lines",
+				flags: withFlags([]flagSpec{
+					{name: "--since", value: "when", usage: "Only edits since a duration ago or a date"},
+					{name: "--workspace", value: "path", usage: "Only one workspace"},
+					{name: "--format", value: "format", usage: "Write editor annotations instead: a SARIF log, or JSON problems for a VS Code problem matcher",
+						choices: annotate.Formats},
+				}, scopeFlags, outputFlags)},
+			{name: "browse", args: "[limit]",
+				summary: "Browse results with fuzzy filtering and a preview; Enter prints the chosen path",
+				flags:   withFlags(filterFlags, scopeFlags),
+				commands: []*command{
+					{name: "recent", args: "[limit]", summary: "Browse recent edits (the default)",
+						flags: withFlags(filterFlags, scopeFlags)},
+					{name: "file", args: "<path> [limit]", summary: "Browse a file's edits",
+						flags: scopeFlags},
+					{name: "sessions", args: "[limit]", summary: "Browse sessions",
+						flags: scopeFlags},
+				}},
+		},
+	}
+}
+
+// runRoot runs the TUI, or lists themes or prints the version
+func runRoot(args []string) error {
+	for _, arg := range args {
+		switch arg {
+		case "--version":
+			fmt.Println("claude-mon " + version)
+			return nil
+		case "--list-themes":
+			fmt.Println("Available themes:")
+			for _, name := range theme.Available() {
+				if name == "dark" {
+					fmt.Printf("  %s (default)\n", name)
+				} else {
+					fmt.Printf("  %s\n", name)
+				}
+			}
+			fmt.Printf("  %s (light or dark, from the terminal background)\n", theme.Auto)
+			return nil
+		}
+	}
+
+	validTheme := selectedTheme == "" || selectedTheme == theme.Auto
+	for _, name := range theme.Available() {
+		if name == selectedTheme {
+			validTheme = true
+			break
+		}
+	}
+	if !validTheme {
+		return usagef("unknown theme %q (available: %s)", selectedTheme, strings.Join(theme.Available(), ", "))
+	}
+	return runTUI()
+}
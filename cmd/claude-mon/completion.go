@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// completionScripts are what `claude-mon completion <shell>` prints. Each
+// asks `claude-mon __complete` what can follow the words typed so far, so
+// completion follows the command tree without being regenerated.
+var completionScripts = map[string]string{
+	"bash": `# bash completion for claude-mon; load with: source <(claude-mon completion bash)
+_claude_mon() {
+    local cur=${COMP_WORDS[COMP_CWORD]}
+    local IFS=$'\n'
+    COMPREPLY=($(compgen -W "$(claude-mon __complete "${COMP_WORDS[@]:1:COMP_CWORD-1}" "$cur" 2>/dev/null)" -- "$cur"))
+}
+complete -o default -F _claude_mon claude-mon clmon
+`,
+	"zsh": `#compdef claude-mon clmon
+# zsh completion for claude-mon; load with: source <(claude-mon completion zsh)
+_claude_mon() {
+    local -a candidates
+    candidates=("${(@f)$(claude-mon __complete "${(@)words[2,CURRENT-1]}" "${words[CURRENT]}" 2>/dev/null)}")
+    candidates=(${candidates:#})
+    if (( ${#candidates} )); then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+if [[ "${funcstack[1]}" == _claude_mon ]]; then
+    _claude_mon "$@"
+else
+    compdef _claude_mon claude-mon clmon
+fi
+`,
+	"fish": `# fish completion for claude-mon; load with: claude-mon completion fish | source
+function __claude_mon_complete
+    set -l words (commandline -opc)
+    claude-mon __complete $words[2..-1] (commandline -ct) 2>/dev/null
+end
+complete -c claude-mon -a '(__claude_mon_complete)'
+complete -c clmon -a '(__claude_mon_complete)'
+`,
+}
+
+// printCompletion prints the completion script for a shell
+func printCompletion(args []string) error {
+	shells := make([]string, 0, len(completionScripts))
+	for shell := range completionScripts {
+		shells = append(shells, shell)
+	}
+	sort.Strings(shells)
+	if len(args) != 1 {
+		return usagef("expected a shell: %s", strings.Join(shells, ", "))
+	}
+	script, ok := completionScripts[args[0]]
+	if !ok {
+		return usagef("unsupported shell %q (%s)", args[0], strings.Join(shells, ", "))
+	}
+	fmt.Print(script)
+	return nil
+}
+
+// complete returns what can replace current after words on the command
+// line: a flag's choices if it's waiting for a value, flags if current
+// starts with "-", otherwise subcommands and choices for the first
+// argument. Nothing leaves the shell to complete file names.
+func (root *command) complete(words []string, current string) []string {
+	cmd := root
+	positional := 0
+	var pending *flagSpec
+	for _, word := range words {
+		if pending != nil {
+			pending = nil
+			continue
+		}
+		if strings.HasPrefix(word, "-") && word != "-" {
+			name, _, hasValue := strings.Cut(word, "=")
+			spec := lookupFlag(globalFlags, name)
+			if spec == nil {
+				spec = lookupFlag(cmd.flags, name)
+			}
+			if spec != nil && spec.value != "" && !hasValue {
+				pending = spec
+			}
+			continue
+		}
+		if positional == 0 {
+			if sub := cmd.subcommand(word); sub != nil {
+				cmd = sub
+				continue
+			}
+		}
+		positional++
+	}
+
+	var candidates []string
+	switch {
+	case pending != nil:
+		candidates = pending.choices
+	case strings.HasPrefix(current, "-"):
+		for _, spec := range append(append([]flagSpec{}, cmd.flags...), globalFlags...) {
+			candidates = append(candidates, spec.name)
+		}
+		candidates = append(candidates, "--help")
+	case positional == 0:
+		for _, sub := range cmd.visibleCommands() {
+			candidates = append(candidates, sub.name)
+		}
+		candidates = append(candidates, cmd.choices...)
+	}
+
+	var matches []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, current) {
+			matches = append(matches, c)
+		}
+	}
+	return matches
+}
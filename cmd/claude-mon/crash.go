@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/crash"
+)
+
+// lastCrashReport is the report written when the TUI panicked, for
+// pointing at once the terminal's restored
+var lastCrashReport atomic.Value // string
+
+// crashGuard wraps the TUI's model so a panic in Update, View or a command
+// writes a crash report. The panic carries on to Bubble Tea, which restores
+// the terminal and ends the program.
+type crashGuard struct {
+	tea.Model
+}
+
+func (g crashGuard) Init() tea.Cmd {
+	defer reportPanic()
+	return guardCmd(g.Model.Init())
+}
+
+func (g crashGuard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	defer reportPanic()
+	m, cmd := g.Model.Update(msg)
+	return crashGuard{m}, guardCmd(cmd)
+}
+
+func (g crashGuard) View() string {
+	defer reportPanic()
+	return g.Model.View()
+}
+
+// reportPanic writes a report of a panic and panics again
+func reportPanic() {
+	r := recover()
+	if r == nil {
+		return
+	}
+	if path, err := crash.Write("tui", r, debug.Stack()); err == nil {
+		lastCrashReport.Store(path)
+	}
+	panic(r)
+}
+
+// guardCmd reports panics in a command and in the commands it batches
+func guardCmd(cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		defer reportPanic()
+		msg := cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			for i := range batch {
+				batch[i] = guardCmd(batch[i])
+			}
+		}
+		return msg
+	}
+}
+
+// offerCrashReports tells the user about crash reports written since the
+// last start and, on a terminal, offers to show the latest
+func offerCrashReports() {
+	pending := crash.Pending()
+	if len(pending) == 0 {
+		return
+	}
+	defer crash.MarkSeen()
+
+	latest := pending[len(pending)-1]
+	fmt.Fprintf(os.Stderr, "claude-mon crashed %d time(s) since it last started; latest report: %s\n", len(pending), latest)
+	if !isTerminal(os.Stdin) || !isTerminal(os.Stdout) {
+		return
+	}
+
+	input := bufio.NewReader(os.Stdin)
+	fmt.Fprint(os.Stderr, "Show it? [y/N] ")
+	answer, _ := input.ReadString('\n')
+	if !strings.EqualFold(strings.TrimSpace(answer), "y") {
+		return
+	}
+	data, err := os.ReadFile(latest)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not read %s: %v\n", filepath.Base(latest), err)
+		return
+	}
+	fmt.Println(string(data))
+	fmt.Fprint(os.Stderr, "Press enter to start claude-mon... ")
+	input.ReadString('\n')
+}
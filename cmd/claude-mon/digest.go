@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/ztaylor/claude-mon/internal/daemon"
+	"github.com/ztaylor/claude-mon/internal/digest"
+	"github.com/ztaylor/claude-mon/internal/filter"
+)
+
+// handleDigestCommand prints a summary of recent activity across every
+// tracked workspace, for posting to a team channel
+func handleDigestCommand(args []string) error {
+	args, sinceFlag, err := extractFlag(args, "--since")
+	if err != nil {
+		return err
+	}
+	args, format, err := extractFlag(args, "--format")
+	if err != nil {
+		return err
+	}
+	args, group, err := extractFlag(args, "--group")
+	if err != nil {
+		return err
+	}
+	args, user, err := extractFlag(args, "--user")
+	if err != nil {
+		return err
+	}
+	_, workspace, err := extractFlag(args, "--workspace")
+	if err != nil {
+		return err
+	}
+	if sinceFlag == "" {
+		sinceFlag = "24h"
+	}
+	if format == "" {
+		format = digest.FormatMarkdown
+	}
+
+	f, err := filter.Parse("since:" + sinceFlag)
+	if err != nil {
+		return err
+	}
+	query := &daemon.Query{Type: "digest", Since: f.Since, Group: group, User: user}
+	if workspace != "" {
+		if query.WorkspacePath, err = filepath.Abs(workspace); err != nil {
+			return err
+		}
+	}
+	result, err := sendQuery(query)
+	if err != nil {
+		return err
+	}
+
+	text, err := digest.Format(result.Digest, format)
+	if err != nil {
+		return err
+	}
+	fmt.Print(text)
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/config"
+	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/internal/socket"
+)
+
+// gateToolCall holds an edit, as a PreToolUse hook, until the TUI running
+// in this workspace allows or denies it: claude-mon gate < hook input. It
+// returns the hook's exit status; 2 blocks the edit and tells Claude why on
+// stderr. Without a TUI gating edits here, or if asking it fails, the edit
+// goes ahead.
+func gateToolCall() int {
+	payload, err := io.ReadAll(os.Stdin)
+	if err != nil || len(payload) == 0 {
+		return 0
+	}
+
+	// The TUI answers by its own timeout; wait a little past it
+	cfg, err := config.Load()
+	if err != nil {
+		cfg = config.DefaultConfig()
+	}
+	decision, err := socket.AskGate(socket.GetGateSocketPath(), payload, cfg.Gate.TimeoutDuration()+5*time.Second)
+	if err != nil {
+		logger.Log("Gate: %v", err)
+		return 0
+	}
+	if !decision.Allow {
+		fmt.Fprintln(os.Stderr, decision.Reason)
+		return 2
+	}
+	return 0
+}
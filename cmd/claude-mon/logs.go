@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/ztaylor/claude-mon/internal/daemon"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// handleLogsCommand prints the daemon's log, or the TUI's with --tui,
+// filtered by level and component
+func handleLogsCommand(args []string) error {
+	args, levelFlag, err := extractFlag(args, "--level")
+	if err != nil {
+		return err
+	}
+	args, component, err := extractFlag(args, "--component")
+	if err != nil {
+		return err
+	}
+
+	if levelFlag == "" {
+		levelFlag = "debug"
+	}
+	level, err := logger.ParseLevel(levelFlag)
+	if err != nil {
+		return err
+	}
+	opts := logger.ViewOptions{Level: level, Component: component}
+	tui := false
+	for _, arg := range args {
+		switch arg {
+		case "--follow":
+			opts.Follow = true
+		case "--tui":
+			tui = true
+		}
+	}
+
+	cfg, err := daemon.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	path := cfg.GetLogPath()
+	if tui {
+		path = cfg.GetTUILogPath()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := logger.View(ctx, path, opts, os.Stdout); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no log at %s yet", path)
+		}
+		return err
+	}
+	return nil
+}
@@ -1,156 +1,70 @@
 package main
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/ztaylor/claude-mon/internal/annotate"
+	"github.com/ztaylor/claude-mon/internal/auth"
+	"github.com/ztaylor/claude-mon/internal/config"
+	"github.com/ztaylor/claude-mon/internal/crash"
 	"github.com/ztaylor/claude-mon/internal/daemon"
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/filter"
 	"github.com/ztaylor/claude-mon/internal/logger"
 	"github.com/ztaylor/claude-mon/internal/model"
+	"github.com/ztaylor/claude-mon/internal/prompt"
 	"github.com/ztaylor/claude-mon/internal/socket"
 	"github.com/ztaylor/claude-mon/internal/theme"
+	"github.com/ztaylor/claude-mon/pkg/hookclient"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
 var (
-	selectedTheme = "dark"
+	version       = "v0.1.0" // Set at build time with -X main.version
+	selectedTheme = ""       // Empty uses the config file's theme
 	debugMode     = false
 	persistMode   = false
 	configPath    = ""
 )
 
 func main() {
-	// Parse global flags first (--config, --theme, etc.)
-	args := os.Args[1:]
-	for i := 0; i < len(args); i++ {
-		switch args[i] {
-		case "--config":
-			if i+1 < len(args) {
-				configPath = args[i+1]
-				i++ // skip next arg
-			}
-		case "--theme", "-t":
-			if i+1 < len(args) {
-				selectedTheme = args[i+1]
-				i++ // skip next arg
-			}
-		case "--debug", "-d":
-			debugMode = true
-		case "--persist", "-p":
-			persistMode = true
-		case "--version", "-v", "version":
-			fmt.Println("claude-mon v0.1.0")
-			return
-		}
-	}
-
-	// Handle daemon and query commands
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
-		case "daemon":
-			if err := handleDaemonCommand(); err != nil {
-				fmt.Fprintf(os.Stderr, "Daemon error: %v\n", err)
-				os.Exit(1)
-			}
-			return
-		case "query":
-			if err := handleQueryCommand(); err != nil {
-				fmt.Fprintf(os.Stderr, "Query error: %v\n", err)
-				os.Exit(1)
-			}
-			return
-		}
-	}
+	os.Exit(execute(rootCommand(), os.Args[1:]))
+}
 
-	// Continue parsing other flags for TUI
-	args = os.Args[1:]
-	for i := 0; i < len(args); i++ {
-		switch args[i] {
-		case "--theme", "-t":
-			if i+1 < len(args) {
-				selectedTheme = args[i+1]
-				i++ // skip next arg
-			}
-		case "--debug", "-d":
-			debugMode = true
-		case "--persist", "-p":
-			persistMode = true
-		case "--config":
-			if i+1 < len(args) {
-				configPath = args[i+1]
-				i++ // skip next arg
-			}
-		case "--list-themes":
-			fmt.Println("Available themes:")
-			for _, name := range theme.Available() {
-				if name == "dark" {
-					fmt.Printf("  %s (default)\n", name)
-				} else {
-					fmt.Printf("  %s\n", name)
-				}
-			}
-			return
-		case "send":
-			if err := sendToSocket(); err != nil {
-				// Fail silently - TUI might not be running
-				os.Exit(0)
-			}
-			return
-		case "--help", "-h", "help":
-			printHelp()
-			return
-		case "--version", "-v", "version":
-			fmt.Println("claude-mon v0.1.0")
-			return
-		case "write-config":
-			// Get path from next argument if available
-			writePath := ""
-			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
-				writePath = args[i+1]
-			}
-			if err := writeDefaultConfig(writePath); err != nil {
-				fmt.Fprintf(os.Stderr, "Error writing config: %v\n", err)
-				os.Exit(1)
-			}
-			return
-		}
-	}
+// logBuffer is how many log entries are kept in memory, for the TUI's debug
+// console and crash reports
+const logBuffer = 2000
 
-	// Validate theme
-	validTheme := false
-	for _, name := range theme.Available() {
-		if name == selectedTheme {
-			validTheme = true
-			break
-		}
-	}
-	if !validTheme {
-		fmt.Fprintf(os.Stderr, "Unknown theme: %s\nAvailable: %s\n",
-			selectedTheme, strings.Join(theme.Available(), ", "))
-		os.Exit(1)
-	}
+func runTUI() error {
+	daemonCfg, cfgErr := daemon.LoadConfig(configPath)
 
-	// Run TUI
-	if err := runTUI(); err != nil {
-		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+	// Initialize logger: recent entries are kept for the debug console, and
+	// written to a file only when debug mode is enabled
+	logOpts := logger.Options{Level: "debug", Component: "tui", Buffer: logBuffer}
+	if debugMode && cfgErr == nil {
+		logOpts = daemonCfg.LoggerOptions(daemonCfg.GetTUILogPath(), "tui")
+		logOpts.Level, logOpts.Buffer = "debug", logBuffer
 	}
-}
-
-func runTUI() error {
-	// Initialize logger (only logs to file when debug mode enabled)
-	if err := logger.Init("/tmp/claude-mon.log", debugMode); err != nil {
+	if err := logger.Setup(logOpts); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: could not init logger: %v\n", err)
 	}
 	defer logger.Close()
 	logger.Log("Starting TUI, debug=%v, persist=%v", debugMode, persistMode)
 
+	crash.Version = version
+	offerCrashReports()
+
 	// Create socket listener
 	socketPath := socket.GetSocketPath()
 	listener, err := socket.NewListener(socketPath)
@@ -160,17 +74,95 @@ func runTUI() error {
 	defer listener.Close()
 
 	// Create the Bubbletea program with theme and options
-	t := theme.Get(selectedTheme)
-	m := model.New(socketPath, model.WithTheme(t), model.WithPersistence(persistMode))
-	p := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion())
+	opts := []model.Option{
+		model.WithPersistence(persistMode),
+		model.WithVersion(version),
+		model.WithRestoredState(config.UIStatePath()),
+	}
+	if selectedTheme != "" {
+		opts = append(opts, model.WithTheme(theme.Get(theme.Resolve(selectedTheme))))
+	}
+	if cfgErr == nil {
+		opts = append(opts, model.WithWorkspaceRules(daemonCfg.WorkspaceRules()))
+	} else {
+		logger.Log("Failed to load daemon config, recording all workspaces: %v", cfgErr)
+	}
+	m := model.New(socketPath, opts...)
+	p := tea.NewProgram(crashGuard{m}, tea.WithAltScreen(), tea.WithMouseCellMotion())
 
-	// Start socket listener in goroutine, sending messages to program
+	// A panic handling a payload or command outside the program ends it,
+	// restoring the terminal, once it's been reported
+	killTUI := func(path string) {
+		lastCrashReport.Store(path)
+		p.Kill()
+	}
+
+	// Start socket listener in goroutine, sending messages to program.
+	// Enveloped payloads from `claude-mon send` are unwrapped; raw JSON from
+	// shell hooks passes through unchanged.
 	go listener.Listen(func(payload []byte) {
-		p.Send(model.SocketMsg{Payload: payload})
+		defer crash.Recover("tui socket", killTUI)
+		crash.SetLastPayload(payload)
+		p.Send(model.SocketMsg{Payload: hookclient.Unwrap(payload)})
 	})
 
+	// Control socket for scripting the TUI (optional - the TUI works without it)
+	control, err := socket.NewControlListener(socket.GetControlSocketPath())
+	if err != nil {
+		logger.Log("Control socket unavailable: %v", err)
+	} else {
+		defer control.Close()
+		go control.Serve(func(cmd socket.Command) error {
+			defer crash.Recover("tui control", killTUI)
+			if err := model.ValidateControlCommand(cmd.Name, cmd.Args); err != nil {
+				return err
+			}
+			p.Send(model.ControlMsg{Command: cmd.Name, Args: cmd.Args})
+			return nil
+		})
+	}
+
+	// Gate socket the PreToolUse hook holds edits on, when [gate] is enabled
+	if m.GateEnabled() {
+		gate, err := socket.NewGateListener(socket.GetGateSocketPath())
+		if err != nil {
+			logger.Log("Gate socket unavailable, edits go ahead unapproved: %v", err)
+		} else {
+			defer gate.Close()
+			go gate.Serve(func(payload []byte, answer func(socket.GateDecision)) {
+				defer crash.Recover("tui gate", killTUI)
+				p.Send(model.GateMsg{Payload: payload, Answer: answer})
+			})
+		}
+	}
+
+	// Reload the prompt list when prompt files change outside the TUI
+	// (optional - the list still refreshes on tab switches without it)
+	if store := m.PromptStore(); store != nil {
+		if watcher, err := prompt.NewWatcher(store); err != nil {
+			logger.Log("Prompt watcher unavailable: %v", err)
+		} else {
+			defer watcher.Close()
+			go watcher.Watch(func() {
+				defer crash.Recover("tui prompt watcher", killTUI)
+				p.Send(model.PromptsChangedMsg{})
+			})
+		}
+	}
+
 	// Run the program
-	if _, err := p.Run(); err != nil {
+	final, err := p.Run()
+	if g, ok := final.(crashGuard); ok {
+		final = g.Model
+	}
+	if path, ok := lastCrashReport.Load().(string); ok {
+		fmt.Fprintf(os.Stderr, "claude-mon crashed; report written to %s\n", path)
+	}
+	if fm, ok := final.(model.Model); ok {
+		// Stop a running chat session and save its transcript
+		fm.Close()
+	}
+	if err != nil {
 		return fmt.Errorf("error running program: %w", err)
 	}
 
@@ -178,41 +170,60 @@ func runTUI() error {
 }
 
 func sendToSocket() error {
-	socketPath := socket.GetSocketPath()
-
-	conn, err := net.Dial("unix", socketPath)
+	payload, err := io.ReadAll(os.Stdin)
 	if err != nil {
-		// Socket doesn't exist or TUI not running
 		return err
 	}
-	defer conn.Close()
 
-	// Copy stdin to socket
-	_, err = io.Copy(conn, os.Stdin)
-	return err
+	// Validate, wrap in the hook envelope and deliver with retries
+	return hookclient.New(socket.GetSocketPath()).Send(payload)
 }
 
-func printHelp() {
-	fmt.Print(`claude-mon (clmon) - Watch Claude Code edits in real-time
+// sendControlCommand sends one command to the running TUI's control socket
+func sendControlCommand(args []string) error {
+	if len(args) == 0 {
+		return usagef("usage: claude-mon ctl <command> [args...]")
+	}
 
-Usage:
-  claude-mon, clmon              Run the TUI
-  claude-mon send, clmon send    Send JSON to running TUI (for hooks)
-  claude-mon help, clmon help    Show this help
+	conn, err := net.Dial("unix", socket.GetControlSocketPath())
+	if err != nil {
+		return fmt.Errorf("TUI not running: %w", err)
+	}
+	defer conn.Close()
 
-Flags:
-  --theme, -t <name>   Set color theme (default: dark)
-  --list-themes        List available themes
-  --persist, -p        Persist history to file (.claude-mon-history.json)
-  --debug, -d          Enable debug logging
-  --config <path>      Path to daemon config file (default: ~/.config/claude-mon/daemon.toml)
+	cmd := socket.Command{Name: args[0], Args: args[1:]}
+	if err := json.NewEncoder(conn).Encode(cmd); err != nil {
+		return fmt.Errorf("failed to send command: %w", err)
+	}
 
-Config Commands:
-  write-config                 Write default configuration to file
-  write-config <path>          Write configuration to custom path
+	var reply map[string]string
+	if err := json.NewDecoder(conn).Decode(&reply); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	if reply["error"] != "" {
+		return fmt.Errorf("%s", reply["error"])
+	}
+	return nil
+}
 
-Available themes: dark, light, dracula, monokai, gruvbox, nord, catppuccin
+// printHelp prints the top-level help: every command and flag in the tree,
+// then the TUI's keys
+func printHelp(w io.Writer, root *command) {
+	fmt.Fprint(w, `claude-mon (clmon) - Watch Claude Code edits in real-time
 
+Usage:
+  claude-mon [flags]             Run the TUI
+  claude-mon <command> [args]    Run a command; claude-mon <command> --help shows its flags
+
+Commands:
+`)
+	writeCommandList(w, "", root)
+	fmt.Fprintln(w, "\nFlags:")
+	for _, spec := range withFlags(root.flags, globalFlags) {
+		writeHelpEntry(w, flagEntry(spec), spec.usage)
+	}
+	fmt.Fprintf(w, "\nAvailable themes: %s, %s\n", strings.Join(theme.Available(), ", "), theme.Auto)
+	fmt.Fprint(w, `
 Keybindings:
   n/p          Navigate changes in queue
   j/k          Scroll diff up/down
@@ -232,27 +243,94 @@ History:
 
 Mouse:
   Scroll       Scroll diff viewport
+`)
+}
+
+// handlePromptsCommand handles prompt library subcommands
+func handlePromptsCommand(args []string) error {
+	if len(args) == 0 {
+		return usagef("usage: claude-mon prompts {sync|import|export [dir]}")
+	}
+	switch args[0] {
+	case "sync":
+		return syncPrompts()
+	case "import":
+		return importPrompts()
+	case "export":
+		return exportPrompts(args[1:])
+	default:
+		return fmt.Errorf("unknown prompts command: %s", args[0])
+	}
+}
 
-Daemon Commands:
-  claude-mon daemon start       Start the background daemon
-  claude-mon daemon stop        Stop the background daemon
-  claude-mon daemon status      Check daemon status
+// syncPrompts pulls and pushes the git-backed global prompt library, or
+// rewrites the daemon's prompts to disk when prompt_store is "daemon"
+func syncPrompts() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	store, err := daemon.NewPromptStore(cfg.PromptStore)
+	if err != nil {
+		return err
+	}
+	if cfg.PromptsRemote != "" {
+		if err := store.EnableGit(cfg.PromptsRemote); err != nil {
+			return err
+		}
+	}
+	if err := store.Sync(); err != nil {
+		return err
+	}
+	fmt.Printf("Synced %s\n", store.GlobalDir())
+	return nil
+}
 
-Query Commands:
-  claude-mon query recent       Show recent activity (all sessions)
-  claude-mon query file <path>  Show edits for specific file
-  claude-mon query prompts      List all prompts
-  claude-mon query sessions     List all sessions
-`)
+// importPrompts copies the global and project prompt files into the
+// daemon's prompt library
+func importPrompts() error {
+	store, err := prompt.NewStore()
+	if err != nil {
+		return err
+	}
+	lib := &daemon.PromptLibrary{SocketPath: daemon.DefaultQuerySocketPath}
+	n, err := prompt.Import(lib, store)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d prompts into the daemon\n", n)
+	return nil
+}
+
+// exportPrompts writes the daemon's prompt library out as prompt files, to
+// the global prompts directory unless another is given
+func exportPrompts(args []string) error {
+	dir := ""
+	if len(args) > 0 {
+		dir = args[0]
+	} else {
+		store, err := prompt.NewStore()
+		if err != nil {
+			return err
+		}
+		dir = store.GlobalDir()
+	}
+	lib := &daemon.PromptLibrary{SocketPath: daemon.DefaultQuerySocketPath}
+	n, err := prompt.Export(lib, dir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d prompts to %s\n", n, dir)
+	return nil
 }
 
 // handleDaemonCommand handles daemon subcommands
-func handleDaemonCommand() error {
-	if len(os.Args) < 3 {
-		return fmt.Errorf("usage: claude-mon daemon {start|stop|status}")
+func handleDaemonCommand(args []string) error {
+	if len(args) == 0 {
+		return usagef("usage: claude-mon daemon {start|stop|status|dedupe|migrate|sync}")
 	}
 
-	cmd := os.Args[2]
+	cmd := args[0]
 	switch cmd {
 	case "start":
 		return startDaemon()
@@ -260,6 +338,12 @@ func handleDaemonCommand() error {
 		return stopDaemon()
 	case "status":
 		return daemonStatus()
+	case "dedupe":
+		return dedupeSnapshots()
+	case "migrate":
+		return migrateDatabase(args[1:])
+	case "sync":
+		return syncDatabases(args[1:])
 	default:
 		return fmt.Errorf("unknown daemon command: %s", cmd)
 	}
@@ -272,6 +356,20 @@ func startDaemon() error {
 		return fmt.Errorf("failed to load config: %w", err)
 	}
 
+	logOpts := cfg.LoggerOptions(cfg.GetLogPath(), "daemon")
+	logOpts.Buffer = logBuffer // For crash reports
+	if err := logger.Setup(logOpts); err != nil {
+		return fmt.Errorf("failed to open log: %w", err)
+	}
+	defer logger.Close()
+
+	crash.Version = version
+	if pending := crash.Pending(); len(pending) > 0 {
+		fmt.Printf("claude-mon crashed %d time(s) since it last started; latest report: %s\n", len(pending), pending[len(pending)-1])
+		crash.MarkSeen()
+	}
+
+	daemon.Version = version
 	d, err := daemon.New(cfg)
 	if err != nil {
 		return fmt.Errorf("failed to create daemon: %w", err)
@@ -281,6 +379,7 @@ func startDaemon() error {
 	fmt.Printf("Data socket: %s\n", cfg.Sockets.DaemonSocket)
 	fmt.Printf("Query socket: %s\n", cfg.Sockets.QuerySocket)
 	fmt.Printf("Database: %s\n", cfg.GetDBPath())
+	fmt.Printf("Log: %s\n", cfg.GetLogPath())
 	fmt.Println("Press Ctrl+C to stop")
 
 	return d.Run()
@@ -314,78 +413,639 @@ func daemonStatus() error {
 	defer conn.Close()
 
 	fmt.Println("Daemon: running")
+	result, err := sendQuery(&daemon.Query{Type: "status"})
+	if err != nil || result.Status == nil {
+		return nil
+	}
+	status := result.Status
+	fmt.Printf("Version: %s, up %s\n", status.Version, status.UptimeStr)
+	ingest := status.Ingest
+	fmt.Printf("Ingest: %d written in %d batches (largest %d, last %.1fms), %d queued, %d failed, %d turned away busy\n",
+		ingest.Written, ingest.Batches, ingest.LargestBatch, ingest.LastFlushMS, ingest.Queued, ingest.Failed, ingest.Rejected)
+	pool := status.Database
+	fmt.Printf("Database: %d/%d connections open (%d in use), %d waits for a connection (%.1fms)\n",
+		pool.Open, pool.MaxOpen, pool.InUse, pool.WaitCount, pool.WaitMS)
+	return nil
+}
+
+// dedupeSnapshots has the daemon store edit snapshots once per distinct
+// content and reports the space saved
+func dedupeSnapshots() error {
+	fmt.Println("Deduplicating file snapshots...")
+	result, err := sendQuery(&daemon.Query{Type: "dedupe"})
+	if err != nil {
+		return err
+	}
+	stats := result.Dedupe
+	fmt.Printf("Moved %d snapshots into shared storage, recompressed %d\n", stats.Moved, stats.Recompressed)
+	if stats.Skipped > 0 {
+		fmt.Printf("Skipped %d snapshots that couldn't be read\n", stats.Skipped)
+	}
+	fmt.Printf("%d distinct contents referenced by %d edits\n", stats.Blobs, stats.Refs)
+	saved := stats.BytesBefore - stats.BytesAfter
+	var percent float64
+	if stats.BytesBefore > 0 {
+		percent = float64(saved) / float64(stats.BytesBefore) * 100
+	}
+	fmt.Printf("Snapshots: %.1f MB -> %.1f MB (%.0f%% saved); the database file shrinks after the next vacuum\n",
+		float64(stats.BytesBefore)/(1<<20), float64(stats.BytesAfter)/(1<<20), percent)
+	return nil
+}
+
+// migrateDatabase shows or changes the database's schema version. The
+// daemon migrates up on start, so this is for checking where a database is
+// and stepping back before a downgrade.
+func migrateDatabase(args []string) error {
+	usage := usagef("usage: claude-mon daemon migrate {status|up [version]|down <version>} [--dry-run]")
+	dryRun := false
+	var rest []string
+	for _, arg := range args {
+		if arg == "--dry-run" {
+			dryRun = true
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	if len(rest) == 0 {
+		rest = []string{"status"}
+	}
+	cmd := rest[0]
+	target := 0
+	switch {
+	case cmd == "status" && len(rest) == 1:
+	case cmd == "up" && len(rest) <= 2, cmd == "down" && len(rest) == 2:
+		if len(rest) == 2 {
+			var err error
+			if target, err = parseCount("version", rest[1]); err != nil {
+				return err
+			}
+		}
+	default:
+		return usage
+	}
+
+	cfg, err := daemon.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if cmd != "status" && !dryRun {
+		if conn, err := net.Dial("unix", cfg.Sockets.QuerySocket); err == nil {
+			conn.Close()
+			return fmt.Errorf("daemon is running; stop it before migrating")
+		}
+	}
+	dbCfg, err := cfg.ToDBConfig()
+	if err != nil {
+		return err
+	}
+	m, err := database.NewMigrator(dbCfg)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if cmd == "status" {
+		statuses, err := m.Status()
+		if err != nil {
+			return err
+		}
+		current, err := m.Current()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Database: %s\n", dbCfg.Path)
+		fmt.Printf("Schema version: %d (latest %d)\n", current, m.Latest())
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Local().Format("2006-01-02 15:04")
+			}
+			reversible := ""
+			if !s.Reversible {
+				reversible = ", irreversible"
+			}
+			fmt.Printf("  %04d %-24s %s%s\n", s.Version, s.Name, applied, reversible)
+		}
+		return nil
+	}
+
+	var migrations []database.Migration
+	if cmd == "up" {
+		migrations, err = m.Up(target, dryRun)
+	} else {
+		migrations, err = m.Down(target, dryRun)
+	}
+	if err != nil {
+		return err
+	}
+	if len(migrations) == 0 {
+		fmt.Println("Nothing to migrate")
+		return nil
+	}
+	verb := map[string]string{"up": "Applied", "down": "Reverted"}[cmd]
+	if dryRun {
+		verb = map[string]string{"up": "Would apply", "down": "Would revert"}[cmd]
+	}
+	for _, mig := range migrations {
+		fmt.Printf("%s %04d %s\n", verb, mig.Version, mig.Name)
+	}
 	return nil
 }
 
 // handleQueryCommand handles query commands
-func handleQueryCommand() error {
-	if len(os.Args) < 3 {
-		return fmt.Errorf("usage: claude-mon query {recent|file|prompts|sessions} [args]")
+func handleQueryCommand(args []string) error {
+	if len(args) == 0 {
+		return usagef("usage: claude-mon query {recent|file|bookmarks|prompts|injections|prompt-stats|sessions|events|ralph|checks|review-report|groups|export|browse} [args] [--group <name>] [--user <name>] [--json|--table] [--no-color]")
 	}
 
-	queryType := os.Args[2]
+	queryType := args[0]
 	query := &daemon.Query{Type: queryType}
 
+	args, out, err := extractOutputFlags(args[1:])
+	if err != nil {
+		return err
+	}
+	args, group, err := extractFlag(args, "--group")
+	if err != nil {
+		return err
+	}
+	query.Group = group
+	args, query.User, err = extractFlag(args, "--user")
+	if err != nil {
+		return err
+	}
+
 	switch queryType {
 	case "recent":
-		// Optional limit
-		if len(os.Args) > 3 {
-			fmt.Sscanf(os.Args[3], "%d", &query.Limit)
+		expr, rest, err := parseFilterFlags(args)
+		if err != nil {
+			return err
+		}
+		query.Filter = expr
+		if err := limitArg(rest, 0, &query.Limit); err != nil {
+			return err
+		}
+	case "search":
+		expr, rest, err := parseFilterFlags(args)
+		if err != nil {
+			return err
+		}
+		if len(rest) < 1 {
+			return usagef("missing <text>")
+		}
+		query.Text, query.Filter = rest[0], expr
+		if err := limitArg(rest, 1, &query.Limit); err != nil {
+			return err
 		}
 	case "file":
-		if len(os.Args) < 4 {
-			return fmt.Errorf("usage: claude-mon query file <path> [limit]")
+		showContent := false
+		var rest []string
+		for _, arg := range args {
+			if arg == "--show-content" {
+				showContent = true
+			} else {
+				rest = append(rest, arg)
+			}
+		}
+		if len(rest) < 1 {
+			return usagef("missing <path>")
+		}
+		query.FilePath = rest[0]
+		if err := limitArg(rest, 1, &query.Limit); err != nil {
+			return err
+		}
+		if showContent {
+			return queryFileContent(query, out)
 		}
-		query.FilePath = os.Args[3]
-		if len(os.Args) > 4 {
-			fmt.Sscanf(os.Args[4], "%d", &query.Limit)
+	case "bookmarks", "sessions", "events":
+		if err := limitArg(args, 0, &query.Limit); err != nil {
+			return err
 		}
 	case "prompts":
-		if len(os.Args) > 3 {
-			query.Name = os.Args[3]
+		args, query.Tag, err = extractFlag(args, "--tag")
+		if err != nil {
+			return err
 		}
-		if len(os.Args) > 4 {
-			fmt.Sscanf(os.Args[4], "%d", &query.Limit)
+		if len(args) > 0 {
+			query.Name = args[0]
 		}
-	case "sessions":
-		if len(os.Args) > 3 {
-			fmt.Sscanf(os.Args[3], "%d", &query.Limit)
+		if err := limitArg(args, 1, &query.Limit); err != nil {
+			return err
+		}
+	case "injections":
+		if len(args) > 0 {
+			query.Name = args[0]
+		}
+		if err := limitArg(args, 1, &query.Limit); err != nil {
+			return err
+		}
+	case "ralph":
+		query.Type = "ralph_loops"
+		if err := limitArg(args, 0, &query.Limit); err != nil {
+			return err
 		}
+	case "prompt-stats":
+		return queryPromptStats(args, out)
+	case "checks":
+		return queryChecks(args, out)
+	case "review-report":
+		return queryReviewReport(args, out)
+	case "export":
+		return queryExport(args, group, query.User, out)
+	case "browse":
+		return queryBrowse(args, group, query.User)
+	case "groups":
 	default:
 		return fmt.Errorf("unknown query type: %s", queryType)
 	}
 
-	return executeQuery(query)
+	return executeQuery(query, out)
 }
 
-// executeQuery sends query to daemon and prints results
-func executeQuery(query *daemon.Query) error {
-	conn, err := net.Dial("unix", daemon.DefaultQuerySocketPath)
+// queryFileContent prints a file's edits with the file's content after
+// each, filled in from the neighbouring edits where it wasn't stored.
+// --json includes the same content as each edit's file_content.
+func queryFileContent(query *daemon.Query, out queryOutput) error {
+	if out.format == formatTable {
+		return usagef("--show-content can't be used with --table")
+	}
+	result, err := sendQuery(query)
 	if err != nil {
-		return fmt.Errorf("daemon not running: %w", err)
+		return err
+	}
+	oldest := slices.Clone(result.Edits)
+	slices.Reverse(oldest)
+	database.ComposeFileVersions(oldest)
+	if out.format == formatJSON {
+		return writeJSON(os.Stdout, queryResultJSON(result))
 	}
-	defer conn.Close()
 
-	// Send query
-	if err := json.NewEncoder(conn).Encode(query); err != nil {
-		return fmt.Errorf("failed to send query: %w", err)
+	if len(result.Edits) == 0 {
+		fmt.Println("No edits found")
+		return nil
+	}
+	for _, edit := range result.Edits {
+		fmt.Printf("[%s] %s:%d  %s\n", out.paint("36", edit.ToolName), edit.FilePath, edit.LineNum,
+			edit.Timestamp.Format("2006-01-02 15:04:05"))
+		if edit.FileContent == "" {
+			fmt.Printf("  %s\n\n", out.paint("2", "(content not recorded)"))
+			continue
+		}
+		fmt.Println(out.paint("2", "──── after this edit ────"))
+		fmt.Print(edit.FileContent)
+		if !strings.HasSuffix(edit.FileContent, "\n") {
+			fmt.Println()
+		}
+		fmt.Println()
 	}
+	return nil
+}
 
-	// Read response
-	var result daemon.QueryResult
-	if err := json.NewDecoder(conn).Decode(&result); err != nil {
-		return fmt.Errorf("failed to decode response: %w", err)
+// queryExport writes edits across workspaces, newest first with their old
+// and new strings, as one JSON object per line. They're streamed from the
+// daemon, so exports of any size print as they're read. --table prints a
+// row per edit instead, once all have arrived, and --format annotations
+// for editors.
+func queryExport(args []string, group, user string, out queryOutput) error {
+	args, workspace, err := extractFlag(args, "--workspace")
+	if err != nil {
+		return err
+	}
+	args, sinceFlag, err := extractFlag(args, "--since")
+	if err != nil {
+		return err
+	}
+	args, format, err := extractFlag(args, "--format")
+	if err != nil {
+		return err
+	}
+	if format != "" && !slices.Contains(annotate.Formats, format) {
+		return usagef("unknown --format %q (want %s)", format, strings.Join(annotate.Formats, " or "))
+	}
+	if format != "" && out.format == formatTable {
+		return usagef("--format can't be combined with --table")
+	}
+	query := &daemon.Query{Type: "export", Group: group, User: user, WorkspacePath: workspace}
+	if err := limitArg(args, 0, &query.Limit); err != nil {
+		return err
+	}
+	if sinceFlag != "" {
+		f, err := filter.Parse("since:" + sinceFlag)
+		if err != nil {
+			return err
+		}
+		query.Since = f.Since
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	enc := json.NewEncoder(w)
+	t := newTable("TIME", "WORKSPACE:24", "TOOL", "FILE:60", "LINE", "USER:16", "SUMMARY:50")
+	problems := annotate.NewProblemWriter(w)
+	var annotations []annotate.Annotation
+	end, err := streamQuery(query, func(e *database.TimelineEdit) error {
+		switch format {
+		case annotate.FormatProblems:
+			return problems.Write(annotate.FromEdit(e))
+		case annotate.FormatSARIF:
+			// A SARIF log is one document, written once all have arrived
+			annotations = append(annotations, annotate.FromEdit(e))
+			return nil
+		}
+		if out.format == formatTable {
+			t.add(e.Timestamp.Format(tableTimeFormat), e.WorkspaceName, e.ToolName, e.FilePath,
+				fmt.Sprint(e.LineNum), e.User, e.Summary)
+			return nil
+		}
+		return enc.Encode(e)
+	})
+	if err == nil && out.format == formatTable {
+		err = out.writeTable(w, t)
+	}
+	if err == nil && format == annotate.FormatSARIF {
+		err = annotate.WriteSARIF(w, annotations, version)
+	}
+	if flushErr := w.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d edits\n", end.Count)
+	return nil
+}
+
+// queryChecks prints the latest post-edit check results in the current
+// workspace, optionally for one file, with the output of failures
+func queryChecks(args []string, out queryOutput) error {
+	limit := 100
+	var file string
+	if len(args) > 0 {
+		file = args[0]
+	}
+	if err := limitArg(args, 1, &limit); err != nil {
+		return err
+	}
+
+	workspacePath, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	result, err := sendQuery(&daemon.Query{Type: "checks", WorkspacePath: workspacePath, Limit: limit})
+	if err != nil {
+		return err
+	}
+
+	checks := []*database.CheckResult{}
+	for _, r := range result.Checks {
+		if file == "" || snapshotPath(workspacePath, r.FilePath) == snapshotPath(workspacePath, file) {
+			checks = append(checks, r)
+		}
+	}
+	switch out.format {
+	case formatJSON:
+		return writeJSON(os.Stdout, queryJSON{Type: "checks", Count: len(checks), Results: checks})
+	case formatTable:
+		t := newTable("TIME", "CHECK:24", "STATUS", "DURATION", "FILE:60", "COMMAND:50")
+		for _, r := range checks {
+			status := "pass"
+			if !r.Passed {
+				status = fmt.Sprintf("fail (exit %d)", r.ExitCode)
+			}
+			t.add(r.StartedAt.Local().Format(tableTimeFormat), r.Name, status,
+				(time.Duration(r.DurationMS) * time.Millisecond).String(), r.FilePath, r.Command)
+		}
+		return out.writeTable(os.Stdout, t)
+	}
+
+	for _, r := range checks {
+		status := out.paint("32", "PASS")
+		if !r.Passed {
+			status = out.paint("31", fmt.Sprintf("FAIL (exit %d)", r.ExitCode))
+		}
+		fmt.Printf("%s  %s %s  %s\n", r.StartedAt.Local().Format("2006-01-02 15:04:05"), r.Name, status, r.FilePath)
+		fmt.Printf("  $ %s  (%s)\n", r.Command, time.Duration(r.DurationMS)*time.Millisecond)
+		if !r.Passed {
+			for _, line := range strings.Split(strings.TrimRight(r.Output, "\n"), "\n") {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+	}
+	if len(checks) == 0 {
+		fmt.Println("No check results found")
+	}
+	return nil
+}
+
+// queryPromptStats prints how often each prompt was sent, then the prompts
+// in the library that never were. --json and --table list those as prompts
+// with no uses.
+func queryPromptStats(args []string, out queryOutput) error {
+	limit := 1000
+	if err := limitArg(args, 0, &limit); err != nil {
+		return err
+	}
+	result, err := sendQuery(&daemon.Query{Type: "prompt_stats", Limit: limit})
+	if err != nil {
+		return err
+	}
+	unused := unusedPrompts(result.PromptStats, limit)
+
+	switch out.format {
+	case formatJSON, formatTable:
+		stats := jsonSlice(result.PromptStats)
+		for _, name := range unused {
+			stats = append(stats, &database.PromptStat{PromptName: name})
+		}
+		if out.format == formatJSON {
+			return writeJSON(os.Stdout, queryJSON{Type: "prompt_stats", Count: len(stats), Results: stats})
+		}
+		t := newTable("USES", "WORKSPACES", "LAST USED", "PROMPT:50")
+		for _, stat := range stats {
+			lastUsed, name := "never", stat.PromptName
+			if stat.Uses > 0 {
+				lastUsed = stat.LastUsed.Local().Format(tableTimeFormat)
+				name = fmt.Sprintf("%s (v%d)", stat.PromptName, stat.LastVersion)
+			}
+			t.add(fmt.Sprint(stat.Uses), fmt.Sprint(stat.Workspaces), lastUsed, name)
+		}
+		return out.writeTable(os.Stdout, t)
+	}
+
+	if len(result.PromptStats) == 0 {
+		fmt.Println("No prompt injections found")
+	} else {
+		fmt.Printf("%5s  %10s  %-16s  %s\n", "USES", "WORKSPACES", "LAST USED", "PROMPT")
+		for _, stat := range result.PromptStats {
+			fmt.Printf("%5d  %10d  %-16s  %s (v%d)\n", stat.Uses, stat.Workspaces,
+				stat.LastUsed.Local().Format("2006-01-02 15:04"), stat.PromptName, stat.LastVersion)
+		}
+	}
+	if len(unused) > 0 {
+		fmt.Printf("\nNever sent (%d):\n", len(unused))
+		for _, name := range unused {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+	return nil
+}
+
+// unusedPrompts returns the names of prompts in the library that stats
+// don't include, in order. Only a complete list of used prompts, shorter
+// than limit, shows which ones were never sent.
+func unusedPrompts(stats []*database.PromptStat, limit int) []string {
+	if len(stats) >= limit {
+		return nil
+	}
+	store, err := prompt.NewStore()
+	if err != nil {
+		return nil
+	}
+	prompts, err := store.List()
+	if err != nil {
+		return nil
+	}
+	used := make(map[string]bool)
+	for _, stat := range stats {
+		used[stat.PromptName] = true
+	}
+	var unused []string
+	for _, p := range prompts {
+		if !used[p.Name] {
+			unused = append(unused, p.Name)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+// extractFlag removes "<flag> <value>" from query arguments, returning the
+// remaining arguments and the value
+func extractFlag(args []string, flag string) ([]string, string, error) {
+	var rest []string
+	var value string
+	for i := 0; i < len(args); i++ {
+		if args[i] != flag {
+			rest = append(rest, args[i])
+			continue
+		}
+		if i+1 >= len(args) {
+			return nil, "", fmt.Errorf("%s requires a value", flag)
+		}
+		i++
+		value = args[i]
+	}
+	return rest, value, nil
+}
+
+// handleTokenCommand handles token management subcommands
+func handleTokenCommand(args []string) error {
+	usage := usagef("usage: claude-mon token {create <name> [--scope read|ingest|admin]|revoke <name|id>|list}")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "create":
+		if len(args) < 2 {
+			return usage
+		}
+		query := &daemon.Query{Type: "token_create", Name: args[1], Scope: string(auth.ScopeRead)}
+		for i := 2; i < len(args); i++ {
+			if args[i] == "--scope" && i+1 < len(args) {
+				scope, err := auth.ParseScope(args[i+1])
+				if err != nil {
+					return err
+				}
+				query.Scope = string(scope)
+				i++
+			}
+		}
+		return executeQuery(query, queryOutput{})
+	case "revoke":
+		if len(args) < 2 {
+			return usage
+		}
+		return executeQuery(&daemon.Query{Type: "token_revoke", Name: args[1]}, queryOutput{})
+	case "list":
+		return executeQuery(&daemon.Query{Type: "tokens"}, queryOutput{})
+	default:
+		return usage
+	}
+}
+
+// parseFilterFlags parses "[--path glob] [--lang names] [--tool name] [--since when] [--until when]"
+// into a filter expression, returning the other arguments
+func parseFilterFlags(args []string) (string, []string, error) {
+	var terms, rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch arg {
+		case "--path", "--lang", "--tool", "--since", "--until":
+			if i+1 >= len(args) {
+				return "", nil, usagef("flag needs an argument: %s", arg)
+			}
+			i++
+			terms = append(terms, strings.TrimPrefix(arg, "--")+":"+args[i])
+		default:
+			rest = append(rest, arg)
+		}
+	}
+
+	expr := strings.Join(terms, " ")
+	if _, err := filter.Parse(expr); err != nil {
+		return "", nil, err
+	}
+	return expr, rest, nil
+}
+
+// limitArg sets limit from args[i], a [limit] argument, if it was given
+func limitArg(args []string, i int, limit *int) error {
+	if i >= len(args) {
+		return nil
+	}
+	n, err := parseCount("limit", args[i])
+	if err != nil {
+		return err
+	}
+	*limit = n
+	return nil
+}
+
+// executeQuery sends query to daemon and prints results in the format out
+// asks for
+func executeQuery(query *daemon.Query, out queryOutput) error {
+	result, err := sendQuery(query)
+	if err != nil {
+		return err
+	}
+	switch out.format {
+	case formatJSON:
+		return writeJSON(os.Stdout, queryResultJSON(result))
+	case formatTable:
+		return out.writeTable(os.Stdout, queryResultTable(result))
 	}
 
 	// Print results
 	switch result.Type {
-	case "recent", "file":
+	case "recent", "file", "search", "bookmarks":
 		if len(result.Edits) == 0 {
 			fmt.Println("No edits found")
 			return nil
 		}
 		for _, edit := range result.Edits {
-			fmt.Printf("[%s] %s:%d\n", edit.ToolName, edit.FilePath, edit.LineNum)
+			fmt.Printf("[%s] %s:%d\n", out.paint("36", edit.ToolName), edit.FilePath, edit.LineNum)
+			if edit.User != "" {
+				fmt.Printf("  User: %s\n", edit.User)
+			}
+			if edit.Summary != "" {
+				fmt.Printf("  %s\n", edit.Summary)
+			}
+			if edit.Review != "" {
+				fmt.Printf("  Review: %s\n", edit.Review)
+			}
+			if edit.Note != "" {
+				fmt.Printf("  Note: %s\n", edit.Note)
+			}
 			fmt.Printf("  Timestamp: %s\n", edit.Timestamp.Format("2006-01-02 15:04:05"))
 		}
 	case "prompts":
@@ -399,8 +1059,21 @@ func executeQuery(query *daemon.Query) error {
 				fmt.Printf("  Description: %s\n", prompt.Description)
 			}
 			fmt.Printf("  Tags: %v\n", prompt.Tags)
+			fmt.Printf("  Uses: %d\n", prompt.Uses)
 			fmt.Printf("  Updated: %s\n\n", prompt.UpdatedAt.Format("2006-01-02 15:04:05"))
 		}
+	case "injections":
+		if len(result.Injections) == 0 {
+			fmt.Println("No prompt injections found")
+			return nil
+		}
+		for _, inj := range result.Injections {
+			fmt.Printf("%s  %s (v%d) via %s", inj.Timestamp.Format("2006-01-02 15:04:05"), inj.PromptName, inj.PromptVersion, inj.Method)
+			if inj.Target != "" {
+				fmt.Printf(" %s", inj.Target)
+			}
+			fmt.Printf("\n  Workspace: %s\n", inj.WorkspacePath)
+		}
 	case "sessions":
 		if len(result.Sessions) == 0 {
 			fmt.Println("No sessions found")
@@ -412,11 +1085,120 @@ func executeQuery(query *daemon.Query) error {
 			fmt.Printf("  Branch: %s\n", session.Branch)
 			fmt.Printf("  Last Activity: %s\n\n", session.LastActivity.Format("2006-01-02 15:04:05"))
 		}
+	case "events":
+		if len(result.Events) == 0 {
+			fmt.Println("No events found")
+			return nil
+		}
+		for _, event := range result.Events {
+			fmt.Printf("[%s] %s %s: %s\n", out.paint(severityColor(event.Severity), event.Severity), event.Timestamp.Format("2006-01-02 15:04:05"), event.Kind, event.Message)
+			if event.WorkspacePath != "" {
+				fmt.Printf("  Workspace: %s\n", event.WorkspacePath)
+			}
+		}
+	case "ralph_loops":
+		if len(result.RalphLoops) == 0 {
+			fmt.Println("No Ralph loops found")
+			return nil
+		}
+		for _, loop := range result.RalphLoops {
+			limit := "∞"
+			if loop.MaxIterations > 0 {
+				limit = fmt.Sprint(loop.MaxIterations)
+			}
+			fmt.Printf("%s  %s after %d/%s iterations (%s, %d edits)\n", loop.EndedAt.Format("2006-01-02 15:04:05"),
+				loop.Outcome, loop.Iterations, limit, loop.EndedAt.Sub(loop.StartedAt).Round(time.Second), loop.Edits)
+			fmt.Printf("  Workspace: %s\n", loop.WorkspacePath)
+			if loop.Promise != "" {
+				fmt.Printf("  Promise: %q\n", loop.Promise)
+			}
+			prompt, _, _ := strings.Cut(strings.TrimSpace(loop.Prompt), "\n")
+			fmt.Printf("  Prompt: %s\n", prompt)
+		}
+	case "groups":
+		if len(result.Groups) == 0 {
+			fmt.Println("No workspace groups configured")
+			return nil
+		}
+		for _, name := range sortedGroupNames(result.Groups) {
+			fmt.Printf("%s:\n", name)
+			for _, path := range result.Groups[name] {
+				fmt.Printf("  %s\n", path)
+			}
+		}
+	case "tokens":
+		if len(result.Tokens) == 0 {
+			fmt.Println("No tokens found")
+			return nil
+		}
+		for _, token := range result.Tokens {
+			status := "active"
+			if token.RevokedAt != nil {
+				status = "revoked " + token.RevokedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%d  %s [%s] %s\n", token.ID, token.Name, token.Scope, status)
+			fmt.Printf("  Created: %s\n", token.CreatedAt.Format("2006-01-02 15:04:05"))
+			if token.LastUsedAt != nil {
+				fmt.Printf("  Last used: %s\n", token.LastUsedAt.Format("2006-01-02 15:04:05"))
+			}
+		}
+	case "token_create":
+		fmt.Printf("Created %s token %q. Store it now - it will not be shown again:\n\n", result.Tokens[0].Scope, result.Tokens[0].Name)
+		fmt.Printf("  %s\n\n", result.Token)
+		fmt.Printf("Clients read it from $%s.\n", auth.TokenEnvVar)
+	case "token_revoke":
+		fmt.Println("Token revoked")
 	}
 
 	return nil
 }
 
+// sendQuery sends a query to the daemon and returns its result
+func sendQuery(query *daemon.Query) (*daemon.QueryResult, error) {
+	return daemon.SendQuery(daemon.DefaultQuerySocketPath, query)
+}
+
+// streamQuery sends a query to the daemon in stream mode, calling fn with
+// each edit as it arrives, and returns the line that ended the stream
+func streamQuery(query *daemon.Query, fn func(*database.TimelineEdit) error) (*daemon.StreamLine, error) {
+	conn, err := net.Dial("unix", daemon.DefaultQuerySocketPath)
+	if err != nil {
+		return nil, fmt.Errorf("daemon not running: %w", err)
+	}
+	defer conn.Close()
+
+	if query.Token == "" {
+		query.Token = auth.FromEnv()
+	}
+	query.Stream = true
+	if err := json.NewEncoder(conn).Encode(query); err != nil {
+		return nil, fmt.Errorf("failed to send query: %w", err)
+	}
+
+	// A query rejected before streaming answers with just an error, which
+	// reads as an end line
+	decoder := json.NewDecoder(conn)
+	for {
+		var line daemon.StreamLine
+		if err := decoder.Decode(&line); err == io.EOF {
+			return nil, fmt.Errorf("stream ended early")
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		if line.Error != "" {
+			return nil, fmt.Errorf("%s", line.Error)
+		}
+		if line.Done {
+			return &line, nil
+		}
+		if line.Edit != nil {
+			if err := fn(line.Edit); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
 // writeDefaultConfig writes the default configuration to a file
 func writeDefaultConfig(path string) error {
 	// Use default path if not provided
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+
+	workingctx "github.com/ztaylor/claude-mon/internal/context"
+	"github.com/ztaylor/claude-mon/internal/mcp"
+)
+
+// runMCPServer serves the current workspace's history to Claude Code over
+// MCP on stdin and stdout, until Claude Code closes stdin
+func runMCPServer() error {
+	workspace, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	server := &mcp.Server{
+		Workspace: workspace,
+		Version:   version,
+		Query:     sendQuery,
+		Context: func() (string, error) {
+			ctx, err := workingctx.Load()
+			if err != nil {
+				return "", err
+			}
+			return ctx.FormatForInjection(), nil
+		},
+	}
+	return server.Serve(os.Stdin, os.Stdout)
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ztaylor/claude-mon/internal/daemon"
+	"github.com/ztaylor/claude-mon/internal/database"
+)
+
+// outputFormat is how query commands print their results
+type outputFormat int
+
+const (
+	formatText  outputFormat = iota // Readable records, the default
+	formatJSON                      // One queryJSON document
+	formatTable                     // Aligned columns, one row per result
+)
+
+// tableTimeFormat is how table cells show times
+const tableTimeFormat = "2006-01-02 15:04:05"
+
+// queryOutput is how a query command prints its results
+type queryOutput struct {
+	format outputFormat
+	color  bool // Text and tables use ANSI colors
+}
+
+// extractOutputFlags removes --json, --table and --no-color from query
+// arguments. Color is on for terminals unless --no-color or $NO_COLOR says
+// otherwise.
+func extractOutputFlags(args []string) ([]string, queryOutput, error) {
+	out := queryOutput{format: formatText, color: isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""}
+	var rest []string
+	var asJSON, asTable bool
+	for _, arg := range args {
+		switch arg {
+		case "--json":
+			asJSON = true
+		case "--table":
+			asTable = true
+		case "--no-color":
+			out.color = false
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	switch {
+	case asJSON && asTable:
+		return nil, out, fmt.Errorf("--json and --table can't be combined")
+	case asJSON:
+		out.format = formatJSON
+	case asTable:
+		out.format = formatTable
+	}
+	return rest, out, nil
+}
+
+// paint wraps s in an ANSI SGR code when color is on
+func (o queryOutput) paint(code, s string) string {
+	if !o.color || s == "" {
+		return s
+	}
+	return "\x1b[" + code + "m" + s + "\x1b[0m"
+}
+
+// severityColor is the ANSI color of an event severity
+func severityColor(severity string) string {
+	switch severity {
+	case "error":
+		return "31"
+	case "warning":
+		return "33"
+	}
+	return "2"
+}
+
+// queryJSON is what --json prints for every query: the query type, the
+// number of results and the results themselves, never null
+type queryJSON struct {
+	Type    string                `json:"type"`
+	Count   int                   `json:"count"`
+	Results any                   `json:"results"`
+	Review  *database.ReviewStats `json:"review,omitempty"` // Progress, from "review-report"
+}
+
+// writeJSON prints doc indented
+func writeJSON(w io.Writer, doc queryJSON) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(doc)
+}
+
+// jsonSlice returns items, or an empty slice in place of nil so results
+// always encode as an array
+func jsonSlice[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
+
+// promptRecord is a prompt as --json prints it
+type promptRecord struct {
+	Name        string    `json:"name"`
+	Version     int       `json:"version"`
+	Description string    `json:"description"`
+	Tags        []string  `json:"tags"`
+	Uses        int       `json:"uses"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// sessionRecord is a session as --json prints it
+type sessionRecord struct {
+	WorkspaceName string    `json:"workspace_name"`
+	WorkspacePath string    `json:"workspace_path"`
+	Branch        string    `json:"branch"`
+	LastActivity  time.Time `json:"last_activity"`
+}
+
+// groupRecord is a workspace group as --json prints it
+type groupRecord struct {
+	Name       string   `json:"name"`
+	Workspaces []string `json:"workspaces"`
+}
+
+// queryResultJSON returns the --json document for a daemon query result
+func queryResultJSON(result *daemon.QueryResult) queryJSON {
+	doc := queryJSON{Type: result.Type}
+	switch result.Type {
+	case "recent", "file", "search", "bookmarks":
+		doc.Count, doc.Results = len(result.Edits), jsonSlice(result.Edits)
+	case "prompts":
+		records := make([]promptRecord, 0, len(result.Prompts))
+		for _, p := range result.Prompts {
+			records = append(records, promptRecord{p.Name, p.Version, p.Description, jsonSlice(p.Tags), p.Uses, p.UpdatedAt})
+		}
+		doc.Count, doc.Results = len(records), records
+	case "injections":
+		doc.Count, doc.Results = len(result.Injections), jsonSlice(result.Injections)
+	case "sessions":
+		records := make([]sessionRecord, 0, len(result.Sessions))
+		for _, s := range result.Sessions {
+			records = append(records, sessionRecord{s.WorkspaceName, s.WorkspacePath, s.Branch, s.LastActivity})
+		}
+		doc.Count, doc.Results = len(records), records
+	case "events":
+		doc.Count, doc.Results = len(result.Events), jsonSlice(result.Events)
+	case "ralph_loops":
+		doc.Count, doc.Results = len(result.RalphLoops), jsonSlice(result.RalphLoops)
+	case "groups":
+		records := make([]groupRecord, 0, len(result.Groups))
+		for _, name := range sortedGroupNames(result.Groups) {
+			records = append(records, groupRecord{name, jsonSlice(result.Groups[name])})
+		}
+		doc.Count, doc.Results = len(records), records
+	default:
+		doc.Results = []any{}
+	}
+	return doc
+}
+
+// sortedGroupNames returns the names of workspace groups in order
+func sortedGroupNames(groups map[string][]string) []string {
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// table is rows of cells printed in aligned columns. Cells longer than
+// their column's limit are cut short with "…".
+type table struct {
+	headers []string
+	limits  []int // Widest each column may be, 0 for no limit
+	rows    [][]string
+}
+
+// newTable creates a table from "HEADER" or "HEADER:limit" column specs
+func newTable(columns ...string) *table {
+	t := &table{}
+	for _, col := range columns {
+		header, limit, _ := strings.Cut(col, ":")
+		n := 0
+		fmt.Sscanf(limit, "%d", &n)
+		t.headers = append(t.headers, header)
+		t.limits = append(t.limits, n)
+	}
+	return t
+}
+
+// add appends a row. Only the first line of a cell is shown.
+func (t *table) add(cells ...string) {
+	row := make([]string, len(t.headers))
+	for i := range row {
+		if i >= len(cells) {
+			break
+		}
+		cell, _, _ := strings.Cut(strings.TrimSpace(cells[i]), "\n")
+		row[i] = truncateCell(strings.ReplaceAll(cell, "\t", " "), t.limits[i])
+	}
+	t.rows = append(t.rows, row)
+}
+
+// truncateCell cuts s to at most limit columns, ending it with "…"
+func truncateCell(s string, limit int) string {
+	if limit <= 0 || lipgloss.Width(s) <= limit {
+		return s
+	}
+	var sb strings.Builder
+	width := 0
+	for _, r := range s {
+		w := lipgloss.Width(string(r))
+		if width+w > limit-1 {
+			break
+		}
+		sb.WriteRune(r)
+		width += w
+	}
+	return sb.String() + "…"
+}
+
+// writeTable prints t with two spaces between columns and the header in
+// bold, without padding the last column
+func (o queryOutput) writeTable(w io.Writer, t *table) error {
+	widths := make([]int, len(t.headers))
+	for _, row := range append([][]string{t.headers}, t.rows...) {
+		for i, cell := range row {
+			widths[i] = max(widths[i], lipgloss.Width(cell))
+		}
+	}
+
+	line := func(row []string, paint func(string) string) string {
+		var sb strings.Builder
+		for i, cell := range row {
+			if i == len(row)-1 {
+				sb.WriteString(paint(cell))
+				break
+			}
+			sb.WriteString(paint(cell) + strings.Repeat(" ", widths[i]-lipgloss.Width(cell)+2))
+		}
+		return strings.TrimRight(sb.String(), " ") + "\n"
+	}
+
+	header := line(t.headers, func(s string) string { return o.paint("1", s) })
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+	for _, row := range t.rows {
+		if _, err := io.WriteString(w, line(row, func(s string) string { return s })); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// editsTable returns a table of edits
+func editsTable(edits []*database.Edit) *table {
+	t := newTable("TIME", "TOOL", "FILE:60", "LINE", "USER:16", "SUMMARY:50")
+	for _, e := range edits {
+		t.add(e.Timestamp.Format(tableTimeFormat), e.ToolName, e.FilePath, fmt.Sprint(e.LineNum), e.User, e.Summary)
+	}
+	return t
+}
+
+// queryResultTable returns the --table rendering of a daemon query result
+func queryResultTable(result *daemon.QueryResult) *table {
+	switch result.Type {
+	case "recent", "file", "search", "bookmarks":
+		return editsTable(result.Edits)
+	case "prompts":
+		t := newTable("NAME:40", "VERSION", "USES", "UPDATED", "TAGS:30", "DESCRIPTION:60")
+		for _, p := range result.Prompts {
+			t.add(p.Name, fmt.Sprint(p.Version), fmt.Sprint(p.Uses), p.UpdatedAt.Format(tableTimeFormat),
+				strings.Join(p.Tags, ","), p.Description)
+		}
+		return t
+	case "injections":
+		t := newTable("TIME", "PROMPT:40", "VERSION", "METHOD", "TARGET:20", "WORKSPACE:60")
+		for _, inj := range result.Injections {
+			t.add(inj.Timestamp.Format(tableTimeFormat), inj.PromptName, fmt.Sprint(inj.PromptVersion),
+				inj.Method, inj.Target, inj.WorkspacePath)
+		}
+		return t
+	case "sessions":
+		t := newTable("WORKSPACE:30", "BRANCH:30", "LAST ACTIVITY", "PATH:60")
+		for _, s := range result.Sessions {
+			t.add(s.WorkspaceName, s.Branch, s.LastActivity.Format(tableTimeFormat), s.WorkspacePath)
+		}
+		return t
+	case "events":
+		t := newTable("TIME", "SEVERITY", "KIND:24", "WORKSPACE:40", "MESSAGE:80")
+		for _, e := range result.Events {
+			t.add(e.Timestamp.Format(tableTimeFormat), e.Severity, e.Kind, e.WorkspacePath, e.Message)
+		}
+		return t
+	case "ralph_loops":
+		t := newTable("ENDED", "OUTCOME", "ITERATIONS", "DURATION", "EDITS", "WORKSPACE:40", "PROMPT:50")
+		for _, loop := range result.RalphLoops {
+			limit := "∞"
+			if loop.MaxIterations > 0 {
+				limit = fmt.Sprint(loop.MaxIterations)
+			}
+			t.add(loop.EndedAt.Format(tableTimeFormat), loop.Outcome, fmt.Sprintf("%d/%s", loop.Iterations, limit),
+				loop.EndedAt.Sub(loop.StartedAt).Round(time.Second).String(), fmt.Sprint(loop.Edits),
+				loop.WorkspacePath, loop.Prompt)
+		}
+		return t
+	case "groups":
+		t := newTable("GROUP:30", "WORKSPACE")
+		for _, name := range sortedGroupNames(result.Groups) {
+			for _, path := range result.Groups[name] {
+				t.add(name, path)
+			}
+		}
+		return t
+	}
+	return newTable("RESULT")
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ztaylor/claude-mon/internal/daemon"
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/diff"
+)
+
+// reviewReportMaxLines caps each side of a rejected change's diff in the
+// report, so a rejected Write doesn't bury the others
+const reviewReportMaxLines = 60
+
+// queryReviewReport prints a markdown summary of the workspace's review:
+// progress, then each rejected change with its note and diff, ready to
+// paste back to Claude. --json and --table list the rejected changes.
+func queryReviewReport(args []string, out queryOutput) error {
+	_, workspacePath, err := extractFlag(args, "--workspace")
+	if err != nil {
+		return err
+	}
+	if workspacePath == "" {
+		if workspacePath, err = os.Getwd(); err != nil {
+			return err
+		}
+	}
+	result, err := sendQuery(&daemon.Query{Type: "review", WorkspacePath: workspacePath})
+	if err != nil {
+		return err
+	}
+	stats := result.Review
+	if stats == nil {
+		stats = &database.ReviewStats{}
+	}
+	switch out.format {
+	case formatJSON:
+		return writeJSON(os.Stdout, queryJSON{Type: "review", Count: len(result.Edits), Results: jsonSlice(result.Edits), Review: stats})
+	case formatTable:
+		return out.writeTable(os.Stdout, editsTable(result.Edits))
+	}
+	fmt.Print(formatReviewReport(workspacePath, *stats, result.Edits))
+	return nil
+}
+
+// formatReviewReport renders the review report as markdown
+func formatReviewReport(workspacePath string, stats database.ReviewStats, rejected []*database.Edit) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Review of %s\n\n", filepath.Base(workspacePath))
+	fmt.Fprintf(&sb, "%d/%d changes reviewed: %d approved, %d rejected.\n",
+		stats.Reviewed(), stats.Total, stats.Approved, stats.Rejected)
+	if len(rejected) == 0 {
+		sb.WriteString("\nNo changes were rejected.\n")
+		return sb.String()
+	}
+
+	sb.WriteString("\n## Rejected changes\n")
+	for i, e := range rejected {
+		path := e.FilePath
+		if rel, err := filepath.Rel(workspacePath, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+		if e.LineNum > 0 {
+			path += fmt.Sprintf(":%d", e.LineNum)
+		}
+		fmt.Fprintf(&sb, "\n### %d. %s\n\n", i+1, path)
+		what := fmt.Sprintf("%s at %s", e.ToolName, e.Timestamp.Local().Format("2006-01-02 15:04"))
+		if e.Summary != "" {
+			what += ": " + e.Summary
+		}
+		sb.WriteString(what + "\n")
+		if e.Note != "" {
+			fmt.Fprintf(&sb, "\n> %s\n", strings.ReplaceAll(e.Note, "\n", "\n> "))
+		}
+		sb.WriteString("\n" + diff.Markdown(e.OldString, e.NewString, reviewReportMaxLines))
+	}
+	return sb.String()
+}
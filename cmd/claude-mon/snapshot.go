@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/ztaylor/claude-mon/internal/daemon"
+	"github.com/ztaylor/claude-mon/internal/database"
+)
+
+// handleSnapshotCommand handles snapshot subcommands
+func handleSnapshotCommand(args []string) error {
+	usage := usagef("usage: claude-mon snapshot {list [file]|restore <file> [--id <id>]}")
+	if len(args) == 0 {
+		return usage
+	}
+
+	rest, idFlag, err := extractFlag(args[1:], "--id")
+	if err != nil {
+		return err
+	}
+	var file string
+	if len(rest) > 0 {
+		file = rest[0]
+	}
+
+	switch args[0] {
+	case "list":
+		return listSnapshots(file)
+	case "restore":
+		if file == "" {
+			return usage
+		}
+		var id int64
+		if idFlag != "" {
+			if id, err = strconv.ParseInt(idFlag, 10, 64); err != nil {
+				return fmt.Errorf("invalid snapshot id %q", idFlag)
+			}
+		}
+		return restoreSnapshot(file, id)
+	default:
+		return usage
+	}
+}
+
+// workspaceSnapshots returns the current workspace's snapshots, newest
+// first, of file or of every file if file is empty
+func workspaceSnapshots(file string) (string, []*database.FileSnapshot, error) {
+	workspacePath, err := os.Getwd()
+	if err != nil {
+		return "", nil, err
+	}
+	result, err := sendQuery(&daemon.Query{Type: "snapshots", WorkspacePath: workspacePath, Limit: 1000})
+	if err != nil {
+		return "", nil, err
+	}
+	if file == "" {
+		return workspacePath, result.Snapshots, nil
+	}
+
+	// The hook records paths as Claude passed them, absolute or relative
+	target := snapshotPath(workspacePath, file)
+	var snapshots []*database.FileSnapshot
+	for _, s := range result.Snapshots {
+		if snapshotPath(workspacePath, s.FilePath) == target {
+			snapshots = append(snapshots, s)
+		}
+	}
+	return workspacePath, snapshots, nil
+}
+
+func snapshotPath(workspacePath, path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workspacePath, path)
+	}
+	return filepath.Clean(path)
+}
+
+// listSnapshots prints the snapshots taken before each session's first
+// edit to a file
+func listSnapshots(file string) error {
+	workspacePath, snapshots, err := workspaceSnapshots(file)
+	if err != nil {
+		return err
+	}
+	if len(snapshots) == 0 {
+		fmt.Println("No snapshots found")
+		return nil
+	}
+	for _, s := range snapshots {
+		path, _ := filepath.Rel(workspacePath, snapshotPath(workspacePath, s.FilePath))
+		size := fmt.Sprintf("%d bytes", s.Size)
+		if !s.Existed {
+			size = "did not exist"
+		}
+		session := s.ChatSessionID
+		if len(session) > 8 {
+			session = session[:8]
+		}
+		if session == "" {
+			session = "-"
+		}
+		fmt.Printf("%5d  %s  %-8s  %s (%s)\n", s.ID, s.Timestamp.Local().Format("2006-01-02 15:04:05"), session, path, size)
+	}
+	return nil
+}
+
+// restoreSnapshot restores a file to its content before the first edit of
+// the latest session to edit it, or of snapshot id
+func restoreSnapshot(file string, id int64) error {
+	workspacePath, snapshots, err := workspaceSnapshots(file)
+	if err != nil {
+		return err
+	}
+	if id == 0 {
+		if len(snapshots) == 0 {
+			return fmt.Errorf("no snapshot of %s", file)
+		}
+		id = snapshots[0].ID
+	}
+
+	result, err := sendQuery(&daemon.Query{Type: "snapshot", SnapshotID: id})
+	if err != nil {
+		return err
+	}
+	if len(result.Snapshots) == 0 {
+		return fmt.Errorf("no snapshot %d", id)
+	}
+	s := result.Snapshots[0]
+	path := snapshotPath(workspacePath, s.FilePath)
+
+	if !s.Existed {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", file, err)
+		}
+		fmt.Printf("Removed %s, which the session created\n", file)
+		return nil
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(path, s.Content, mode); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", file, err)
+	}
+	fmt.Printf("Restored %s to its content before %s\n", file, s.Timestamp.Local().Format("2006-01-02 15:04:05"))
+	return nil
+}
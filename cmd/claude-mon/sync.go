@@ -0,0 +1,207 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/ztaylor/claude-mon/internal/daemon"
+	"github.com/ztaylor/claude-mon/internal/database"
+)
+
+// syncMessage is one message of the sync exchange over ssh. The local side
+// sends its manifest; the remote answers with its own and the rows the
+// local side lacks; the local side sends the rows the remote lacks, and
+// the remote answers with what it added.
+type syncMessage struct {
+	Manifest *database.SyncManifest `json:"manifest,omitempty"`
+	Batch    *database.SyncBatch    `json:"batch,omitempty"`
+	Stats    *database.SyncStats    `json:"stats,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+}
+
+// syncDatabases merges this machine's history with another's, both ways.
+// The target is another database file, or an ssh host running claude-mon,
+// optionally with the path of its database: "desktop" or
+// "me@desktop:~/.claude-mon/claude-mon.db".
+func syncDatabases(args []string) error {
+	usage := usagef("usage: claude-mon daemon sync <database | [user@]host[:database]>")
+	var target string
+	serve := false
+	for _, arg := range args {
+		switch {
+		case arg == "--serve":
+			serve = true
+		case !strings.HasPrefix(arg, "-") && target == "":
+			target = arg
+		}
+	}
+
+	local, err := openLocalDB(target, serve)
+	if err != nil {
+		return err
+	}
+	defer local.Close()
+	if serve {
+		return serveSync(local, os.Stdin, os.Stdout)
+	}
+	if target == "" {
+		return usage
+	}
+
+	// A file here is another database; anything else is reached over ssh
+	var toLocal, toRemote *database.SyncStats
+	if _, err := os.Stat(target); err == nil {
+		remote, err := database.Open(&database.Config{Path: target})
+		if err != nil {
+			return err
+		}
+		defer remote.Close()
+		if toLocal, toRemote, err = database.Sync(local, remote); err != nil {
+			return err
+		}
+	} else if toLocal, toRemote, err = syncOverSSH(local, target); err != nil {
+		return err
+	}
+
+	fmt.Printf("Pulled from %s: %s\n", target, describeSync(toLocal))
+	fmt.Printf("Pushed to %s: %s\n", target, describeSync(toRemote))
+	return nil
+}
+
+// openLocalDB opens this machine's database: the daemon's, or the one
+// named when serving a sync
+func openLocalDB(path string, serve bool) (*database.DB, error) {
+	if serve && path != "" {
+		return database.Open(&database.Config{Path: path})
+	}
+	cfg, err := daemon.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	dbCfg, err := cfg.ToDBConfig()
+	if err != nil {
+		return nil, err
+	}
+	return database.Open(dbCfg)
+}
+
+// syncOverSSH runs the sync exchange with claude-mon on a remote host
+func syncOverSSH(local *database.DB, target string) (toLocal, toRemote *database.SyncStats, err error) {
+	host, path, _ := strings.Cut(target, ":")
+	remoteArgs := []string{host, "claude-mon", "daemon", "sync", "--serve"}
+	if path != "" {
+		remoteArgs = append(remoteArgs, path)
+	}
+	cmd := exec.Command("ssh", remoteArgs...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failed to run ssh: %w", err)
+	}
+	defer cmd.Wait()
+	defer stdin.Close()
+
+	enc, dec := json.NewEncoder(stdin), json.NewDecoder(stdout)
+	receive := func() (*syncMessage, error) {
+		var msg syncMessage
+		if err := dec.Decode(&msg); err != nil {
+			return nil, fmt.Errorf("sync with %s failed: %w", host, err)
+		}
+		if msg.Error != "" {
+			return nil, fmt.Errorf("sync with %s failed: %s", host, msg.Error)
+		}
+		return &msg, nil
+	}
+
+	have, err := local.SyncManifest()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := enc.Encode(syncMessage{Manifest: have}); err != nil {
+		return nil, nil, err
+	}
+	reply, err := receive()
+	if err != nil {
+		return nil, nil, err
+	}
+	if reply.Manifest == nil || reply.Batch == nil {
+		return nil, nil, fmt.Errorf("sync with %s failed: unexpected reply", host)
+	}
+	if toLocal, err = local.ImportSync(reply.Batch); err != nil {
+		return nil, nil, err
+	}
+
+	batch, err := local.ExportSync(reply.Manifest)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := enc.Encode(syncMessage{Batch: batch}); err != nil {
+		return nil, nil, err
+	}
+	if reply, err = receive(); err != nil {
+		return nil, nil, err
+	}
+	return toLocal, reply.Stats, nil
+}
+
+// serveSync answers the sync exchange on stdin and stdout, for a
+// claude-mon syncing with this machine over ssh
+func serveSync(db *database.DB, r io.Reader, w io.Writer) error {
+	enc, dec := json.NewEncoder(w), json.NewDecoder(r)
+	fail := func(err error) error {
+		enc.Encode(syncMessage{Error: err.Error()})
+		return err
+	}
+
+	var msg syncMessage
+	if err := dec.Decode(&msg); err != nil {
+		return err
+	}
+	if msg.Manifest == nil {
+		return fail(fmt.Errorf("expected a manifest"))
+	}
+	have, err := db.SyncManifest()
+	if err != nil {
+		return fail(err)
+	}
+	batch, err := db.ExportSync(msg.Manifest)
+	if err != nil {
+		return fail(err)
+	}
+	if err := enc.Encode(syncMessage{Manifest: have, Batch: batch}); err != nil {
+		return err
+	}
+
+	msg = syncMessage{}
+	if err := dec.Decode(&msg); err != nil {
+		return err
+	}
+	if msg.Batch == nil {
+		return fail(fmt.Errorf("expected a batch"))
+	}
+	stats, err := db.ImportSync(msg.Batch)
+	if err != nil {
+		return fail(err)
+	}
+	return enc.Encode(syncMessage{Stats: stats})
+}
+
+// describeSync summarizes what a sync added, e.g. "12 edits (2 new
+// sessions), 1 prompts"
+func describeSync(s *database.SyncStats) string {
+	if s == nil || s.Edits == 0 && s.Prompts == 0 && s.Sessions == 0 {
+		return "nothing new"
+	}
+	return fmt.Sprintf("%d edits (%d new sessions), %d prompts", s.Edits, s.Sessions, s.Prompts)
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ztaylor/claude-mon/internal/daemon"
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/filter"
+)
+
+// timelineFollowInterval is how often --follow asks the daemon for new edits
+const timelineFollowInterval = 2 * time.Second
+
+// timelineBadgeWidth is the width workspace badges are padded or cut to
+const timelineBadgeWidth = 14
+
+// badgeColors are the ANSI colors workspace badges take in turn
+var badgeColors = []string{"39", "208", "170", "76", "220", "203", "45", "141"}
+
+// handleTimelineCommand prints the edits of every tracked workspace
+// interleaved, newest first, paging back through older edits on request
+func handleTimelineCommand(args []string) error {
+	args, group, err := extractFlag(args, "--group")
+	if err != nil {
+		return err
+	}
+	args, sinceFlag, err := extractFlag(args, "--since")
+	if err != nil {
+		return err
+	}
+	args, cursor, err := extractFlag(args, "--cursor")
+	if err != nil {
+		return err
+	}
+	args, user, err := extractFlag(args, "--user")
+	if err != nil {
+		return err
+	}
+
+	query := &daemon.Query{Type: "timeline", Group: group, User: user, Cursor: cursor, Limit: 50}
+	follow := false
+	var rest []string
+	for _, arg := range args {
+		if arg == "--follow" {
+			follow = true
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	if err := limitArg(rest, 0, &query.Limit); err != nil {
+		return err
+	}
+	if sinceFlag != "" {
+		f, err := filter.Parse("since:" + sinceFlag)
+		if err != nil {
+			return err
+		}
+		query.Since = f.Since
+	}
+
+	tl := &timelinePrinter{badges: make(map[string]lipgloss.Style)}
+	if follow {
+		return tl.follow(query)
+	}
+
+	interactive := isTerminal(os.Stdin) && isTerminal(os.Stdout)
+	input := bufio.NewReader(os.Stdin)
+	for {
+		// Pages are streamed, so large ones print as they're read
+		result, err := streamQuery(query, func(e *database.TimelineEdit) error {
+			tl.print(e)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if result.Count == 0 && query.Cursor == "" {
+			fmt.Println("No edits found")
+			return nil
+		}
+		if result.NextCursor == "" {
+			return nil
+		}
+
+		if !interactive {
+			fmt.Fprintf(os.Stderr, "More edits: add --cursor %s\n", result.NextCursor)
+			return nil
+		}
+		fmt.Fprint(os.Stderr, "-- Enter for older edits, q to quit -- ")
+		line, err := input.ReadString('\n')
+		if err != nil || strings.TrimSpace(line) == "q" {
+			return nil
+		}
+		query.Cursor = result.NextCursor
+	}
+}
+
+// timelinePrinter prints timeline edits with a badge per workspace and a
+// line where the day changes
+type timelinePrinter struct {
+	badges  map[string]lipgloss.Style // Workspace path -> badge style
+	lastDay string
+}
+
+// follow prints the latest page oldest first, then new edits as the daemon
+// records them, until interrupted
+func (tl *timelinePrinter) follow(query *daemon.Query) error {
+	result, err := sendQuery(query)
+	if err != nil {
+		return err
+	}
+
+	// Edits made in the second of the newest one may arrive after it, so
+	// that second is asked for again and the edits already printed skipped
+	var newest time.Time
+	seen := make(map[int64]bool)
+	show := func(edits []*database.TimelineEdit) {
+		for i := len(edits) - 1; i >= 0; i-- {
+			e := edits[i]
+			if seen[e.ID] {
+				continue
+			}
+			if e.Timestamp.After(newest) {
+				newest = e.Timestamp
+				seen = make(map[int64]bool)
+			}
+			seen[e.ID] = true
+			tl.print(e)
+		}
+	}
+	show(result.Timeline)
+
+	for {
+		time.Sleep(timelineFollowInterval)
+		next := *query
+		next.Cursor = ""
+		if !newest.IsZero() {
+			next.Since = newest
+		}
+		result, err := sendQuery(&next)
+		if err != nil {
+			return err
+		}
+		show(result.Timeline)
+	}
+}
+
+// print prints one edit, e.g.
+// "14:03:21  api            Edit   internal/server.go:42  modified func Serve"
+func (tl *timelinePrinter) print(e *database.TimelineEdit) {
+	ts := e.Timestamp.Local()
+	if day := ts.Format("Mon Jan 2 2006"); day != tl.lastDay {
+		fmt.Printf("── %s ──\n", day)
+		tl.lastDay = day
+	}
+
+	path := e.FilePath
+	if rel, err := filepath.Rel(e.WorkspacePath, path); err == nil && filepath.IsAbs(path) && !strings.HasPrefix(rel, "..") {
+		path = rel
+	}
+	if e.LineNum > 0 {
+		path = fmt.Sprintf("%s:%d", path, e.LineNum)
+	}
+
+	line := fmt.Sprintf("%s  %s  %-6s %s", ts.Format("15:04:05"), tl.badge(e), e.ToolName, path)
+	if e.Summary != "" {
+		line += "  " + e.Summary
+	}
+	fmt.Println(line)
+}
+
+// badge renders a workspace's name in the color it was given when first seen
+func (tl *timelinePrinter) badge(e *database.TimelineEdit) string {
+	style, ok := tl.badges[e.WorkspacePath]
+	if !ok {
+		color := badgeColors[len(tl.badges)%len(badgeColors)]
+		style = lipgloss.NewStyle().Foreground(lipgloss.Color(color)).Bold(true)
+		tl.badges[e.WorkspacePath] = style
+	}
+
+	name := e.WorkspaceName
+	if name == "" {
+		name = filepath.Base(e.WorkspacePath)
+	}
+	if runes := []rune(name); len(runes) > timelineBadgeWidth {
+		name = string(runes[:timelineBadgeWidth-1]) + "…"
+	}
+	return style.Render(name) + strings.Repeat(" ", timelineBadgeWidth-len([]rune(name)))
+}
+
+// isTerminal reports whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/archive"
+	"github.com/ztaylor/claude-mon/internal/daemon"
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/workspace"
+)
+
+// handleWorkspaceCommand handles workspace subcommands
+func handleWorkspaceCommand(args []string) error {
+	usage := usagef("usage: claude-mon workspace {archive [file] [--purge]|restore <file> [--force]|list}")
+	if len(args) == 0 {
+		return usage
+	}
+
+	var path string
+	var purge, force bool
+	for _, arg := range args[1:] {
+		switch arg {
+		case "--purge":
+			purge = true
+		case "--force":
+			force = true
+		default:
+			path = arg
+		}
+	}
+
+	switch args[0] {
+	case "list":
+		return listWorkspaceRules()
+	case "archive":
+		return archiveWorkspace(path, purge)
+	case "restore":
+		if path == "" {
+			return usage
+		}
+		return restoreWorkspace(path, force)
+	default:
+		return usage
+	}
+}
+
+// listWorkspaceRules prints the tracked and ignored workspace patterns from
+// the daemon config and the current workspace's ignore file, and whether the
+// current workspace is tracked
+func listWorkspaceRules() error {
+	cfg, err := daemon.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	workspacePath, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	rules := cfg.WorkspaceRules()
+
+	path := configPath
+	if path == "" {
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, ".config", "claude-mon", "daemon.toml")
+	}
+	fmt.Printf("User rules (%s):\n", path)
+	if len(rules.Tracked) == 0 {
+		fmt.Println("  tracked: everything")
+	}
+	for _, pattern := range rules.Tracked {
+		fmt.Printf("  tracked: %s\n", pattern)
+	}
+	for _, pattern := range rules.Ignored {
+		fmt.Printf("  ignored: %s\n", pattern)
+	}
+
+	fmt.Printf("\nProject rules (%s):\n", filepath.Join(workspacePath, workspace.ProjectFile))
+	patterns, err := workspace.LoadProject(workspacePath)
+	if err != nil {
+		return fmt.Errorf("failed to read project rules: %w", err)
+	}
+	if len(patterns) == 0 {
+		fmt.Println("  none")
+	}
+	for _, p := range patterns {
+		if p.Include {
+			fmt.Printf("  included: %s\n", p.Glob)
+		} else {
+			fmt.Printf("  ignored: %s\n", p.Glob)
+		}
+	}
+
+	if rules.TrackWorkspace(workspacePath) {
+		fmt.Printf("\n%s is tracked\n", workspacePath)
+	} else {
+		fmt.Printf("\n%s is ignored\n", workspacePath)
+	}
+	return nil
+}
+
+// archiveWorkspace bundles the current workspace's state into path, by
+// default under ~/.claude-mon/archives
+func archiveWorkspace(path string, purge bool) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	workspacePath, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	if path == "" {
+		name := fmt.Sprintf("%s-%s.claude-mon.tar.gz", filepath.Base(workspacePath), time.Now().Format("20060102-150405"))
+		path = filepath.Join(home, ".claude-mon", "archives", name)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create archive directory: %w", err)
+	}
+
+	var dump *database.WorkspaceDump
+	result, err := sendQuery(&daemon.Query{Type: "workspace_export", WorkspacePath: workspacePath})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: daemon rows not included: %v\n", err)
+	} else {
+		dump = result.Dump
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create archive: %w", err)
+	}
+	src := archive.SourcesFor(home, workspacePath)
+	manifest, err := archive.Write(f, workspacePath, src, dump)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	fmt.Printf("Archived %s to %s\n", workspacePath, path)
+	fmt.Printf("  %d session(s), %d edit(s), %d file(s)\n", manifest.Sessions, manifest.Edits, len(manifest.Files))
+
+	if !purge {
+		return nil
+	}
+	if dump != nil {
+		result, err := sendQuery(&daemon.Query{Type: "workspace_purge", WorkspacePath: workspacePath})
+		if err != nil {
+			return fmt.Errorf("archive written, but purging daemon rows failed: %w", err)
+		}
+		fmt.Printf("Removed %d session(s) from the daemon\n", result.Removed)
+	}
+	if err := archive.Purge(src); err != nil {
+		return fmt.Errorf("archive written, but purging files failed: %w", err)
+	}
+	fmt.Println("Removed the workspace's history, prompts, context and plans")
+	return nil
+}
+
+// restoreWorkspace revives an archive into the current workspace, which
+// may be a different path than the one archived
+func restoreWorkspace(path string, force bool) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return fmt.Errorf("failed to get home directory: %w", err)
+	}
+	workspacePath, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	a, err := archive.Read(f)
+	if err != nil {
+		return err
+	}
+
+	if a.Manifest.WorkspacePath != workspacePath {
+		fmt.Printf("Archived from %s, restoring into %s\n", a.Manifest.WorkspacePath, workspacePath)
+	}
+
+	// Restore daemon rows first; the daemon refuses if this workspace already has history
+	if a.Dump != nil {
+		if _, err := sendQuery(&daemon.Query{Type: "workspace_import", WorkspacePath: workspacePath, Dump: a.Dump}); err != nil {
+			return fmt.Errorf("failed to restore daemon rows: %w", err)
+		}
+		fmt.Printf("Restored %d session(s), %d edit(s) into the daemon\n", a.Manifest.Sessions, a.Manifest.Edits)
+	}
+
+	restored, skipped, err := a.Restore(archive.SourcesFor(home, workspacePath), force)
+	for _, p := range restored {
+		fmt.Printf("  restored %s\n", p)
+	}
+	for _, p := range skipped {
+		fmt.Printf("  kept existing %s (use --force to overwrite)\n", p)
+	}
+	return err
+}
@@ -25,7 +25,7 @@
             inherit version;
             src = ./.;
 
-            vendorHash = "sha256-GMsaY7QvFkl0Zgz/3jUyflOH8ZtltgUgnvUP1NO2Tms=";
+            vendorHash = "sha256-pSynRy/81VgOXFlq7V0/NFmTfeKjD+nv1gW29iLFbZQ=";
 
             # Exclude e2e tests that require the binary to be built first
             excludedPackages = [ "internal/e2e" ];
@@ -1,6 +1,9 @@
 #!/bin/bash
 # claude-mon PostToolUse hook
-# Sends tool edits to both the TUI and daemon for real-time display and persistence
+# Sends tool edits to both the TUI and daemon for real-time display and persistence.
+# Also registered as a PreToolUse hook, it snapshots files before Claude edits them,
+# and as a SessionStart, Stop and SubagentStop hook, it marks where sessions and
+# their turns begin and end.
 
 # Get the current directory and resolve to absolute path
 CWD="$(cd "$(pwd)" && pwd)"
@@ -23,11 +26,167 @@ USER="${USER:-unknown}"
 
 # Socket paths
 TUI_SOCKET="/tmp/claude-mon-${USER}-${HASH}.sock"
+TUI_GATE_SOCKET="/tmp/claude-mon-${USER}-${HASH}-gate.sock"
 DAEMON_SOCKET="/tmp/claude-mon-daemon.sock"
 
-# Send to TUI if socket exists (raw TOOL_INPUT)
+# Claude Code passes the full hook event (session_id, tool_name, tool_input)
+# on stdin; older versions only set TOOL_NAME and TOOL_INPUT
+HOOK_INPUT=""
+if [[ ! -t 0 ]]; then
+    HOOK_INPUT="$(cat)"
+fi
+SESSION_ID=""
+if [[ -n "$HOOK_INPUT" ]] && command -v jq &>/dev/null; then
+    SESSION_ID=$(echo "$HOOK_INPUT" | jq -r '.session_id // empty' 2>/dev/null)
+    TOOL_NAME="${TOOL_NAME:-$(echo "$HOOK_INPUT" | jq -r '.tool_name // empty' 2>/dev/null)}"
+    TOOL_INPUT="${TOOL_INPUT:-$(echo "$HOOK_INPUT" | jq -c '.tool_input // empty' 2>/dev/null)}"
+fi
+
+HOOK_EVENT=""
+if [[ -n "$HOOK_INPUT" ]] && command -v jq &>/dev/null; then
+    HOOK_EVENT=$(echo "$HOOK_INPUT" | jq -r '.hook_event_name // empty' 2>/dev/null)
+fi
+
+# Sets BRANCH, COMMIT_SHA and VCS_TYPE for the current directory (jj or git)
+detect_vcs() {
+    BRANCH=""
+    COMMIT_SHA=""
+    VCS_TYPE=""
+
+    # Check for jj first (it auto-commits every change)
+    if jj root &>/dev/null 2>&1; then
+        VCS_TYPE="jj"
+        # Get current change ID (short form)
+        COMMIT_SHA=$(jj log -r @ --no-graph -T 'change_id.short()' 2>/dev/null || echo "")
+        # jj doesn't have branches in the same way, use bookmark or description
+        BRANCH=$(jj log -r @ --no-graph -T 'bookmarks' 2>/dev/null | head -1 || echo "")
+    elif git rev-parse --git-dir &>/dev/null; then
+        VCS_TYPE="git"
+        BRANCH=$(git branch --show-current 2>/dev/null || echo "")
+        COMMIT_SHA=$(git rev-parse HEAD 2>/dev/null || echo "")
+    fi
+}
+
+# Prints a file's base64-encoded content (max 500KB to avoid huge payloads)
+file_content_b64() {
+    local path="$1"
+    if [[ ! "$path" = /* ]]; then
+        path="$CWD/$path"
+    fi
+    if [[ -f "$path" ]] && [[ $(stat -f%z "$path" 2>/dev/null || stat -c%s "$path" 2>/dev/null) -lt 512000 ]]; then
+        base64 < "$path" 2>/dev/null | tr -d '\n' || echo ""
+    fi
+}
+
+# Sends the daemon a hook event for the session: its start, the end of a
+# turn or subagent, or a tool about to change the file given as $1. Needs
+# detect_vcs to have run.
+send_hook_event() {
+    jq -n \
+        --arg workspace "$CWD" \
+        --arg workspace_name "$(basename "$CWD")" \
+        --arg branch "$BRANCH" \
+        --arg commit_sha "$COMMIT_SHA" \
+        --arg hook_event "$HOOK_EVENT" \
+        --arg source "$(echo "$HOOK_INPUT" | jq -r '.source // empty' 2>/dev/null)" \
+        --arg tool_name "$TOOL_NAME" \
+        --arg file_path "$1" \
+        --arg chat_session_id "$SESSION_ID" \
+        '{
+            type: "hook",
+            workspace: $workspace,
+            workspace_name: $workspace_name,
+            branch: $branch,
+            commit_sha: $commit_sha,
+            hook_event: $hook_event,
+            source: $source,
+            tool_name: $tool_name,
+            file_path: $file_path,
+            chat_session_id: $chat_session_id
+        }' | nc -U "$DAEMON_SOCKET"
+}
+
+# Session starts and the ends of turns and subagents go to the TUI as they
+# are, and to the daemon for the session's history
+if [[ "$HOOK_EVENT" == "SessionStart" || "$HOOK_EVENT" == "Stop" || "$HOOK_EVENT" == "SubagentStop" ]]; then
+    if [[ -S "$TUI_SOCKET" ]]; then
+        echo "$HOOK_INPUT" | nc -U "$TUI_SOCKET" &
+    fi
+    if [[ -S "$DAEMON_SOCKET" ]] && [[ -n "$SESSION_ID" ]]; then
+        detect_vcs
+        TOOL_NAME=""
+        send_hook_event "" &
+    fi
+    wait
+    exit 0
+fi
+
+# As a PreToolUse hook: tell the TUI an edit is coming, and send the daemon
+# the file as it is before the edit, kept as a snapshot on the session's
+# first edit to it (claude-mon snapshot). With [gate] enabled, the TUI holds
+# the edit until it's allowed; a denial blocks it, telling Claude why.
+if [[ "$HOOK_EVENT" == "PreToolUse" ]]; then
+    FILE_PATH=$(echo "$TOOL_INPUT" | jq -r '.file_path // .path // empty' 2>/dev/null)
+    if [[ -S "$TUI_SOCKET" ]]; then
+        echo "$HOOK_INPUT" | nc -U "$TUI_SOCKET" &
+    fi
+    if [[ -S "$TUI_GATE_SOCKET" ]] && command -v claude-mon &>/dev/null; then
+        echo "$HOOK_INPUT" | claude-mon gate
+        if [[ $? -eq 2 ]]; then
+            wait
+            exit 2
+        fi
+    fi
+    if [[ -S "$DAEMON_SOCKET" ]] && [[ -n "$FILE_PATH" ]]; then
+        detect_vcs
+        if [[ -n "$SESSION_ID" ]]; then
+            send_hook_event "$FILE_PATH" &
+        fi
+        ABSOLUTE_PATH="$FILE_PATH"
+        if [[ ! "$FILE_PATH" = /* ]]; then
+            ABSOLUTE_PATH="$CWD/$FILE_PATH"
+        fi
+        FILE_MISSING=false
+        if [[ ! -e "$ABSOLUTE_PATH" ]]; then
+            FILE_MISSING=true
+        fi
+        FILE_CONTENT_B64="$(file_content_b64 "$FILE_PATH")"
+        # Files too large to send are left to the VCS
+        if [[ "$FILE_MISSING" == true ]] || [[ -n "$FILE_CONTENT_B64" ]] || [[ ! -s "$ABSOLUTE_PATH" ]]; then
+            jq -n \
+                --arg workspace "$CWD" \
+                --arg workspace_name "$(basename "$CWD")" \
+                --arg branch "$BRANCH" \
+                --arg commit_sha "$COMMIT_SHA" \
+                --arg file_path "$FILE_PATH" \
+                --arg file_content_b64 "$FILE_CONTENT_B64" \
+                --arg chat_session_id "$SESSION_ID" \
+                --argjson file_missing "$FILE_MISSING" \
+                '{
+                    type: "snapshot",
+                    workspace: $workspace,
+                    workspace_name: $workspace_name,
+                    branch: $branch,
+                    commit_sha: $commit_sha,
+                    file_path: $file_path,
+                    file_content_b64: $file_content_b64,
+                    file_missing: $file_missing,
+                    chat_session_id: $chat_session_id
+                }' | nc -U "$DAEMON_SOCKET"
+        fi
+    fi
+    wait
+    exit 0
+fi
+
+# Send to TUI if socket exists. The full event lets the Chat tab match tool
+# calls to the session it runs; otherwise send the raw TOOL_INPUT.
 if [[ -S "$TUI_SOCKET" ]]; then
-    echo "$TOOL_INPUT" | nc -U "$TUI_SOCKET" &
+    if [[ -n "$HOOK_INPUT" ]]; then
+        echo "$HOOK_INPUT" | nc -U "$TUI_SOCKET" &
+    else
+        echo "$TOOL_INPUT" | nc -U "$TUI_SOCKET" &
+    fi
 fi
 
 # Send to daemon if socket exists (formatted payload)
@@ -39,23 +198,7 @@ if [[ -S "$DAEMON_SOCKET" ]] && command -v jq &>/dev/null; then
     NEW_STRING=$(echo "$TOOL_INPUT" | jq -r '.new_string // .content // empty' 2>/dev/null | head -c 10000)
 
     if [[ -n "$FILE_PATH" ]]; then
-        # Get VCS info (jj or git)
-        BRANCH=""
-        COMMIT_SHA=""
-        VCS_TYPE=""
-
-        # Check for jj first (it auto-commits every change)
-        if jj root &>/dev/null 2>&1; then
-            VCS_TYPE="jj"
-            # Get current change ID (short form)
-            COMMIT_SHA=$(jj log -r @ --no-graph -T 'change_id.short()' 2>/dev/null || echo "")
-            # jj doesn't have branches in the same way, use bookmark or description
-            BRANCH=$(jj log -r @ --no-graph -T 'bookmarks' 2>/dev/null | head -1 || echo "")
-        elif git rev-parse --git-dir &>/dev/null; then
-            VCS_TYPE="git"
-            BRANCH=$(git branch --show-current 2>/dev/null || echo "")
-            COMMIT_SHA=$(git rev-parse HEAD 2>/dev/null || echo "")
-        fi
+        detect_vcs
 
         # Calculate line count
         LINE_COUNT=0
@@ -64,14 +207,16 @@ if [[ -S "$DAEMON_SOCKET" ]] && command -v jq &>/dev/null; then
         fi
 
         # Read and base64-encode file content (max 500KB to avoid huge payloads)
-        FILE_CONTENT_B64=""
-        ABSOLUTE_PATH="$FILE_PATH"
-        if [[ ! "$FILE_PATH" = /* ]]; then
-            ABSOLUTE_PATH="$CWD/$FILE_PATH"
-        fi
-        if [[ -f "$ABSOLUTE_PATH" ]] && [[ $(stat -f%z "$ABSOLUTE_PATH" 2>/dev/null || stat -c%s "$ABSOLUTE_PATH" 2>/dev/null) -lt 512000 ]]; then
-            FILE_CONTENT_B64=$(base64 < "$ABSOLUTE_PATH" 2>/dev/null | tr -d '\n' || echo "")
-        fi
+        FILE_CONTENT_B64="$(file_content_b64 "$FILE_PATH")"
+
+        # Ralph loop iteration, if a loop is running (project-local state first)
+        RALPH_ITERATION=""
+        for RALPH_STATE in "$CWD/.claude/ralph-loop.local.md" "$HOME/.claude/ralph-loop.local.md"; do
+            if [[ -f "$RALPH_STATE" ]]; then
+                RALPH_ITERATION=$(sed -n '/^---$/,/^---$/s/^iteration: *\([0-9][0-9]*\).*/\1/p' "$RALPH_STATE" | head -1)
+                break
+            fi
+        done
 
         # Create daemon payload
         PAYLOAD=$(jq -n \
@@ -86,8 +231,10 @@ if [[ -S "$DAEMON_SOCKET" ]] && command -v jq &>/dev/null; then
             --arg old_string "$OLD_STRING" \
             --arg new_string "$NEW_STRING" \
             --arg file_content_b64 "$FILE_CONTENT_B64" \
+            --arg chat_session_id "$SESSION_ID" \
             --argjson line_num 0 \
             --argjson line_count "$LINE_COUNT" \
+            --argjson ralph_iteration "${RALPH_ITERATION:-0}" \
             '{
                 type: $type,
                 workspace: $workspace,
@@ -100,8 +247,10 @@ if [[ -S "$DAEMON_SOCKET" ]] && command -v jq &>/dev/null; then
                 old_string: $old_string,
                 new_string: $new_string,
                 file_content_b64: $file_content_b64,
+                chat_session_id: $chat_session_id,
                 line_num: $line_num,
-                line_count: $line_count
+                line_count: $line_count,
+                ralph_iteration: $ralph_iteration
             }')
 
         echo "$PAYLOAD" | nc -U "$DAEMON_SOCKET" &
@@ -0,0 +1,314 @@
+// Package annotate writes Claude's edits as editor annotations: a SARIF log,
+// which code scanning tools and the SARIF Viewer extension show in the
+// gutter, or one JSON diagnostic per line for a VS Code problem matcher.
+package annotate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/database"
+)
+
+// Formats edits can be written in
+const (
+	FormatSARIF    = "sarif"
+	FormatProblems = "problems"
+)
+
+// Formats lists the formats, for help and errors
+var Formats = []string{FormatSARIF, FormatProblems}
+
+// Rule IDs, one for Claude's edits and one for changes made outside it
+const (
+	RuleEdit     = "claude-edit"
+	RuleExternal = "external-edit"
+)
+
+// Annotation is where an edit left a file and what it did there
+type Annotation struct {
+	Workspace string // Workspace root; empty if unknown
+	File      string // As the hook reported it, usually absolute
+	StartLine int    // 1-based
+	EndLine   int    // Inclusive
+	Message   string
+	Rule      string
+	Warning   bool // A reviewer rejected the edit
+	Edit      *database.TimelineEdit
+}
+
+// FromEdit annotates the lines an edit left behind. They're numbered as
+// the file was right after the edit, so later edits above them shift them.
+func FromEdit(e *database.TimelineEdit) Annotation {
+	a := Annotation{
+		Workspace: e.WorkspacePath,
+		File:      e.FilePath,
+		StartLine: max(e.LineNum, 1),
+		Message:   Message(&e.Edit),
+		Rule:      RuleEdit,
+		Warning:   e.Review == database.ReviewRejected,
+		Edit:      e,
+	}
+	if e.ToolName == database.ToolExternal {
+		a.Rule = RuleExternal
+	}
+	// LineCount is the old string's length; what's left is the new one.
+	a.EndLine = a.StartLine + max(lines(e.NewString), 1) - 1
+	return a
+}
+
+// Message summarizes what an edit changed, e.g. "Claude edited 3 lines
+// (+3/-1): modified func Load"
+func Message(e *database.Edit) string {
+	added, removed := lines(e.NewString), lines(e.OldString)
+	var msg string
+	switch e.ToolName {
+	case database.ToolExternal:
+		msg = fmt.Sprintf("Changed outside Claude (+%d/-%d)", added, removed)
+	case "Write":
+		msg = fmt.Sprintf("Claude wrote this file (%d lines)", added)
+	default:
+		if added == 0 {
+			msg = fmt.Sprintf("Claude removed %s", plural(removed, "line"))
+			break
+		}
+		msg = fmt.Sprintf("Claude edited %s (+%d/-%d)", plural(added, "line"), added, removed)
+	}
+	if e.Summary != "" {
+		msg += ": " + e.Summary
+	}
+	switch e.Review {
+	case database.ReviewApproved:
+		msg += " [approved]"
+	case database.ReviewRejected:
+		msg += " [rejected]"
+	}
+	if e.Note != "" {
+		msg += " — " + e.Note
+	}
+	return msg
+}
+
+// lines counts a string's lines the way the history's +N/-M does: a
+// trailing newline doesn't start another line
+func lines(s string) int {
+	if s == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(s, "\n"), "\n") + 1
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// path returns an annotation's file as an absolute path
+func (a Annotation) path() string {
+	if filepath.IsAbs(a.File) || a.Workspace == "" {
+		return a.File
+	}
+	return filepath.Join(a.Workspace, a.File)
+}
+
+// Problem is an annotation as a problem matcher reads it. Its fields are
+// written in this order, so a matcher's regexp can pick them out.
+type Problem struct {
+	File      string    `json:"file"` // Absolute
+	Line      int       `json:"line"`
+	EndLine   int       `json:"endLine"`
+	Severity  string    `json:"severity"` // "info", or "warning" for rejected edits
+	Message   string    `json:"message"`
+	Code      string    `json:"code"` // The tool that made the edit
+	Source    string    `json:"source"`
+	EditID    int64     `json:"editId"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ProblemWriter writes annotations one JSON problem per line, as they
+// come, so an export of any size can be piped to an editor
+type ProblemWriter struct {
+	enc *json.Encoder
+}
+
+// NewProblemWriter returns a ProblemWriter writing to w
+func NewProblemWriter(w io.Writer) *ProblemWriter {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return &ProblemWriter{enc: enc}
+}
+
+// Write writes one annotation
+func (p *ProblemWriter) Write(a Annotation) error {
+	severity := "info"
+	if a.Warning {
+		severity = "warning"
+	}
+	return p.enc.Encode(Problem{
+		File:      a.path(),
+		Line:      a.StartLine,
+		EndLine:   a.EndLine,
+		Severity:  severity,
+		Message:   a.Message,
+		Code:      a.Edit.ToolName,
+		Source:    "claude-mon",
+		EditID:    a.Edit.ID,
+		Timestamp: a.Edit.Timestamp,
+	})
+}
+
+// SARIF 2.1.0, as much of it as annotations need
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                        `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLocation `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult                    `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Properties map[string]any  `json:"properties"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine"`
+}
+
+// workspaceBase is the uriBaseId results' paths are relative to
+const workspaceBase = "WORKSPACE"
+
+// WriteSARIF writes annotations as a SARIF log, with a run per workspace
+// so each resolves its paths against its own root. version is
+// claude-mon's.
+func WriteSARIF(w io.Writer, annotations []Annotation, version string) error {
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{},
+	}
+	runs := make(map[string]int)
+	for _, a := range annotations {
+		i, ok := runs[a.Workspace]
+		if !ok {
+			i = len(log.Runs)
+			runs[a.Workspace] = i
+			log.Runs = append(log.Runs, newRun(a.Workspace, version))
+		}
+		log.Runs[i].Results = append(log.Runs[i].Results, sarifResultOf(a))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(log)
+}
+
+func newRun(workspace, version string) sarifRun {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "claude-mon",
+			Version:        version,
+			InformationURI: "https://github.com/ztaylor/claude-mon",
+			Rules: []sarifRule{
+				{ID: RuleEdit, ShortDescription: sarifMessage{Text: "Change made by Claude"}},
+				{ID: RuleExternal, ShortDescription: sarifMessage{Text: "Change made outside Claude"}},
+			},
+		}},
+		Results: []sarifResult{},
+	}
+	if workspace != "" {
+		run.OriginalURIBaseIDs = map[string]sarifArtifactLocation{
+			workspaceBase: {URI: fileURI(workspace) + "/"},
+		}
+	}
+	return run
+}
+
+func sarifResultOf(a Annotation) sarifResult {
+	level := "note"
+	if a.Warning {
+		level = "warning"
+	}
+	loc := sarifArtifactLocation{URI: fileURI(a.path())}
+	if rel, err := filepath.Rel(a.Workspace, a.path()); a.Workspace != "" && err == nil && !strings.HasPrefix(rel, "..") {
+		loc = sarifArtifactLocation{URI: (&url.URL{Path: filepath.ToSlash(rel)}).EscapedPath(), URIBaseID: workspaceBase}
+	}
+
+	e := a.Edit
+	props := map[string]any{"editId": e.ID, "tool": e.ToolName, "timestamp": e.Timestamp}
+	for key, value := range map[string]string{"user": e.User, "chatSessionId": e.ChatSessionID, "commit": e.CommitSHA, "branch": e.Branch} {
+		if value != "" {
+			props[key] = value
+		}
+	}
+	return sarifResult{
+		RuleID:  a.Rule,
+		Level:   level,
+		Message: sarifMessage{Text: a.Message},
+		Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+			ArtifactLocation: loc,
+			Region:           sarifRegion{StartLine: a.StartLine, EndLine: a.EndLine},
+		}}},
+		Properties: props,
+	}
+}
+
+// fileURI returns an absolute path as a file: URI, and a relative one as
+// a relative URI
+func fileURI(path string) string {
+	if !filepath.IsAbs(path) {
+		return (&url.URL{Path: filepath.ToSlash(path)}).EscapedPath()
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
@@ -0,0 +1,114 @@
+package annotate
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/database"
+)
+
+func testEdits() []*database.TimelineEdit {
+	at := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	return []*database.TimelineEdit{
+		{WorkspacePath: "/src/api", Branch: "main", Edit: database.Edit{ID: 7, ToolName: "Edit", FilePath: "/src/api/internal/my server.go",
+			OldString: "a\n", NewString: "b\nc\nd\n", LineNum: 12, LineCount: 1, Summary: "modified func Serve", User: "zach", Timestamp: at}},
+		{WorkspacePath: "/src/api", Edit: database.Edit{ID: 8, ToolName: "Edit", FilePath: "main.go",
+			OldString: "x\ny", LineNum: 3, LineCount: 2, Review: database.ReviewRejected, Note: "keep this", Timestamp: at}},
+		{WorkspacePath: "/src/web", Edit: database.Edit{ID: 9, ToolName: database.ToolExternal, FilePath: "/tmp/gen.go",
+			NewString: "z", Timestamp: at}},
+	}
+}
+
+func TestFromEdit(t *testing.T) {
+	tests := []struct {
+		start, end int
+		message    string
+		rule       string
+		warning    bool
+	}{
+		{12, 14, "Claude edited 3 lines (+3/-1): modified func Serve", RuleEdit, false},
+		{3, 3, "Claude removed 2 lines [rejected] — keep this", RuleEdit, true},
+		{1, 1, "Changed outside Claude (+1/-0)", RuleExternal, false},
+	}
+	for i, e := range testEdits() {
+		a := FromEdit(e)
+		want := tests[i]
+		if a.StartLine != want.start || a.EndLine != want.end || a.Message != want.message || a.Rule != want.rule || a.Warning != want.warning {
+			t.Errorf("edit %d: got lines %d-%d %q %s warning=%v, want %d-%d %q %s warning=%v", e.ID,
+				a.StartLine, a.EndLine, a.Message, a.Rule, a.Warning, want.start, want.end, want.message, want.rule, want.warning)
+		}
+	}
+}
+
+func TestProblemWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewProblemWriter(&buf)
+	for _, e := range testEdits() {
+		if err := w.Write(FromEdit(e)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3:\n%s", len(lines), buf.String())
+	}
+	// Fields come in a fixed order for problem matchers' regexps
+	if !strings.HasPrefix(lines[1], `{"file":"/src/api/main.go","line":3,"endLine":3,"severity":"warning","message":"Claude removed 2 lines`) {
+		t.Errorf("unexpected problem line: %s", lines[1])
+	}
+	var p Problem
+	if err := json.Unmarshal([]byte(lines[0]), &p); err != nil {
+		t.Fatal(err)
+	}
+	if p.File != "/src/api/internal/my server.go" || p.Severity != "info" || p.Code != "Edit" || p.EditID != 7 {
+		t.Errorf("unexpected problem: %+v", p)
+	}
+}
+
+func TestWriteSARIF(t *testing.T) {
+	var annotations []Annotation
+	for _, e := range testEdits() {
+		annotations = append(annotations, FromEdit(e))
+	}
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, annotations, "1.2.3"); err != nil {
+		t.Fatal(err)
+	}
+
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatalf("invalid SARIF: %v\n%s", err, buf.String())
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 2 {
+		t.Fatalf("got version %s with %d runs, want 2.1.0 with one per workspace", log.Version, len(log.Runs))
+	}
+	api, web := log.Runs[0], log.Runs[1]
+	if api.OriginalURIBaseIDs[workspaceBase].URI != "file:///src/api/" || api.Tool.Driver.Version != "1.2.3" {
+		t.Errorf("unexpected run: %+v", api)
+	}
+	if len(api.Results) != 2 {
+		t.Fatalf("got %d results for api, want 2", len(api.Results))
+	}
+
+	first := api.Results[0]
+	loc := first.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "internal/my%20server.go" || loc.ArtifactLocation.URIBaseID != workspaceBase {
+		t.Errorf("artifact = %+v, want a path relative to the workspace", loc.ArtifactLocation)
+	}
+	if loc.Region != (sarifRegion{StartLine: 12, EndLine: 14}) || first.Level != "note" || first.Properties["user"] != "zach" {
+		t.Errorf("unexpected result: %+v", first)
+	}
+	if api.Results[1].Level != "warning" {
+		t.Errorf("rejected edit level = %s, want warning", api.Results[1].Level)
+	}
+
+	// Files outside their workspace keep their absolute path
+	ext := web.Results[0]
+	if ext.RuleID != RuleExternal || ext.Locations[0].PhysicalLocation.ArtifactLocation.URI != "file:///tmp/gen.go" {
+		t.Errorf("unexpected external result: %+v", ext)
+	}
+}
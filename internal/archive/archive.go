@@ -0,0 +1,284 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	workingctx "github.com/ztaylor/claude-mon/internal/context"
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/history"
+	"github.com/ztaylor/claude-mon/internal/plan"
+)
+
+// formatVersion is bumped when the archive layout changes
+const formatVersion = 1
+
+// Entry names inside an archive
+const (
+	manifestEntry = "manifest.json"
+	daemonEntry   = "daemon.json"
+	historyEntry  = "history.json"
+	contextEntry  = "context.json"
+	promptsPrefix = "prompts/"
+	plansPrefix   = "plans/"
+)
+
+// Sources are the places a workspace's claude-mon state lives on disk
+type Sources struct {
+	HistoryPath string   // Local history file (--persist)
+	PromptsDir  string   // Project-scope prompts
+	ContextPath string   // Working context
+	PlansDir    string   // Where plans are restored to
+	Plans       []string // Plans referenced by the workspace's sessions
+}
+
+// SourcesFor returns the state locations for a workspace
+func SourcesFor(home, workspacePath string) Sources {
+	return Sources{
+		HistoryPath: history.PathIn(workspacePath),
+		PromptsDir:  filepath.Join(workspacePath, ".claude", "prompts"),
+		ContextPath: workingctx.PathFor(workspacePath),
+		PlansDir:    filepath.Join(home, ".claude", "plans"),
+		Plans:       plan.WorkspacePlans(home, workspacePath),
+	}
+}
+
+// Manifest describes an archive
+type Manifest struct {
+	Version       int       `json:"version"`
+	WorkspacePath string    `json:"workspace_path"`
+	CreatedAt     time.Time `json:"created_at"`
+	Files         []string  `json:"files"` // Archive entries besides the manifest
+	Sessions      int       `json:"sessions"`
+	Edits         int       `json:"edits"`
+}
+
+// Archive is a read archive, held in memory until restored
+type Archive struct {
+	Manifest Manifest
+	Dump     *database.WorkspaceDump // nil when the daemon wasn't running at archive time
+	files    map[string][]byte
+}
+
+// Write bundles a workspace's state into a gzipped tar. A nil dump archives
+// only the files on disk.
+func Write(w io.Writer, workspacePath string, src Sources, dump *database.WorkspaceDump) (*Manifest, error) {
+	files := make(map[string][]byte)
+
+	if dump != nil {
+		data, err := json.Marshal(dump)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode daemon rows: %w", err)
+		}
+		files[daemonEntry] = data
+	}
+	if err := readOptional(files, historyEntry, src.HistoryPath); err != nil {
+		return nil, err
+	}
+	if err := readOptional(files, contextEntry, src.ContextPath); err != nil {
+		return nil, err
+	}
+	for _, p := range src.Plans {
+		if err := readOptional(files, plansPrefix+filepath.Base(p), p); err != nil {
+			return nil, err
+		}
+	}
+	err := filepath.WalkDir(src.PromptsDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == src.PromptsDir {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(src.PromptsDir, p)
+		if err != nil {
+			return err
+		}
+		return readOptional(files, promptsPrefix+filepath.ToSlash(rel), p)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompts: %w", err)
+	}
+
+	manifest := &Manifest{
+		Version:       formatVersion,
+		WorkspacePath: workspacePath,
+		CreatedAt:     time.Now().UTC(),
+	}
+	for name := range files {
+		manifest.Files = append(manifest.Files, name)
+	}
+	sort.Strings(manifest.Files)
+	if dump != nil {
+		manifest.Sessions = len(dump.Sessions)
+		for _, s := range dump.Sessions {
+			manifest.Edits += len(s.Edits)
+		}
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	entries := append([]string{manifestEntry}, manifest.Files...)
+	files[manifestEntry] = manifestData
+	for _, name := range entries {
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(files[name])),
+			ModTime: manifest.CreatedAt,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, fmt.Errorf("failed to write archive: %w", err)
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			return nil, fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write archive: %w", err)
+	}
+	return manifest, nil
+}
+
+// readOptional adds a file to the archive contents if it exists
+func readOptional(files map[string][]byte, name, path string) error {
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	files[name] = data
+	return nil
+}
+
+// Read loads an archive written by Write
+func Read(r io.Reader) (*Archive, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a claude-mon archive: %w", err)
+	}
+	defer gz.Close()
+
+	a := &Archive{files: make(map[string][]byte)}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		name := path.Clean(hdr.Name)
+		if hdr.Typeflag != tar.TypeReg || path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("unexpected archive entry %q", hdr.Name)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		a.files[name] = data
+	}
+
+	manifestData, ok := a.files[manifestEntry]
+	if !ok {
+		return nil, fmt.Errorf("not a claude-mon archive: no manifest")
+	}
+	if err := json.Unmarshal(manifestData, &a.Manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if a.Manifest.Version > formatVersion {
+		return nil, fmt.Errorf("archive format v%d is newer than this claude-mon supports (v%d)", a.Manifest.Version, formatVersion)
+	}
+	delete(a.files, manifestEntry)
+
+	if data, ok := a.files[daemonEntry]; ok {
+		a.Dump = &database.WorkspaceDump{}
+		if err := json.Unmarshal(data, a.Dump); err != nil {
+			return nil, fmt.Errorf("invalid daemon rows: %w", err)
+		}
+		delete(a.files, daemonEntry)
+	}
+	return a, nil
+}
+
+// Restore writes the archived files back to their places in src. Existing
+// files are left alone and reported as skipped unless overwrite is set.
+func (a *Archive) Restore(src Sources, overwrite bool) (restored, skipped []string, err error) {
+	names := make([]string, 0, len(a.files))
+	for name := range a.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		dest := a.destination(name, src)
+		if dest == "" {
+			continue
+		}
+		if _, err := os.Stat(dest); err == nil && !overwrite {
+			skipped = append(skipped, dest)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return restored, skipped, err
+		}
+		if err := os.WriteFile(dest, a.files[name], 0644); err != nil {
+			return restored, skipped, fmt.Errorf("failed to restore %s: %w", dest, err)
+		}
+		restored = append(restored, dest)
+	}
+	return restored, skipped, nil
+}
+
+// destination maps an archive entry to where it's restored, or "" for
+// entries this version doesn't know
+func (a *Archive) destination(name string, src Sources) string {
+	switch {
+	case name == historyEntry:
+		return src.HistoryPath
+	case name == contextEntry:
+		return src.ContextPath
+	case strings.HasPrefix(name, promptsPrefix):
+		return filepath.Join(src.PromptsDir, filepath.FromSlash(strings.TrimPrefix(name, promptsPrefix)))
+	case strings.HasPrefix(name, plansPrefix):
+		return filepath.Join(src.PlansDir, path.Base(name))
+	}
+	return ""
+}
+
+// Purge removes a workspace's files after they've been archived
+func Purge(src Sources) error {
+	for _, p := range append([]string{src.HistoryPath, src.ContextPath}, src.Plans...) {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.RemoveAll(src.PromptsDir)
+}
@@ -0,0 +1,103 @@
+package archive
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ztaylor/claude-mon/internal/database"
+)
+
+// testSources lays out a workspace's state under a temp dir
+func testSources(t *testing.T) Sources {
+	dir := t.TempDir()
+	return Sources{
+		HistoryPath: filepath.Join(dir, "ws", ".claude-mon-history.json"),
+		PromptsDir:  filepath.Join(dir, "ws", ".claude", "prompts"),
+		ContextPath: filepath.Join(dir, "contexts", "ws-123.json"),
+		PlansDir:    filepath.Join(dir, "plans"),
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	src := testSources(t)
+	writeFile(t, src.HistoryPath, `[{"file_path": "main.go"}]`)
+	writeFile(t, filepath.Join(src.PromptsDir, "review.prompt.md"), "review")
+	writeFile(t, filepath.Join(src.PromptsDir, "review.v1.prompt.md"), "review v1")
+	writeFile(t, src.ContextPath, `{"version": 2}`)
+	planPath := filepath.Join(src.PlansDir, "golden-falcon.md")
+	writeFile(t, planPath, "# Plan")
+	src.Plans = []string{planPath}
+
+	dump := &database.WorkspaceDump{
+		WorkspacePath: "/ws",
+		Sessions: []*database.SessionDump{{
+			Session: database.Session{WorkspacePath: "/ws", Branch: "main"},
+			Edits:   []*database.EditDump{{Edit: &database.Edit{FilePath: "/ws/main.go"}, Snapshot: []byte{1, 2, 3}}},
+		}},
+	}
+
+	var buf bytes.Buffer
+	manifest, err := Write(&buf, "/ws", src, dump)
+	if err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if manifest.Sessions != 1 || manifest.Edits != 1 || len(manifest.Files) != 6 {
+		t.Errorf("unexpected manifest: %+v", manifest)
+	}
+
+	if err := Purge(src); err != nil {
+		t.Fatalf("purge failed: %v", err)
+	}
+	if _, err := os.Stat(src.PromptsDir); !os.IsNotExist(err) {
+		t.Error("expected purge to remove the prompts")
+	}
+
+	a, err := Read(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if a.Manifest.WorkspacePath != "/ws" || a.Dump == nil || string(a.Dump.Sessions[0].Edits[0].Snapshot) != "\x01\x02\x03" {
+		t.Errorf("daemon rows didn't survive the round trip: %+v", a.Dump)
+	}
+
+	// A file recreated since archiving is kept
+	writeFile(t, src.HistoryPath, "[]")
+	restored, skipped, err := a.Restore(src, false)
+	if err != nil {
+		t.Fatalf("restore failed: %v", err)
+	}
+	if len(restored) != 4 || len(skipped) != 1 || skipped[0] != src.HistoryPath {
+		t.Errorf("expected 4 restored and the history skipped, got %v / %v", restored, skipped)
+	}
+	if data, _ := os.ReadFile(filepath.Join(src.PromptsDir, "review.v1.prompt.md")); string(data) != "review v1" {
+		t.Errorf("expected prompt versions restored, got %q", data)
+	}
+	if data, _ := os.ReadFile(planPath); string(data) != "# Plan" {
+		t.Errorf("expected the plan restored, got %q", data)
+	}
+
+	if _, _, err := a.Restore(src, true); err != nil {
+		t.Fatalf("overwrite failed: %v", err)
+	}
+	if data, _ := os.ReadFile(src.HistoryPath); string(data) != `[{"file_path": "main.go"}]` {
+		t.Errorf("expected overwrite to restore the history, got %q", data)
+	}
+}
+
+func TestReadRejectsOtherFiles(t *testing.T) {
+	if _, err := Read(bytes.NewReader([]byte("not an archive"))); err == nil {
+		t.Error("expected an error for a non-archive")
+	}
+}
@@ -0,0 +1,68 @@
+// Package auth provides scoped API tokens for the daemon
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Scope is what a token is allowed to do
+type Scope string
+
+const (
+	ScopeRead   Scope = "read"   // Run queries
+	ScopeIngest Scope = "ingest" // Send hook payloads
+	ScopeAdmin  Scope = "admin"  // Everything, including token management
+)
+
+// tokenPrefix marks claude-mon tokens so they are recognizable in configs and logs
+const tokenPrefix = "cmon_"
+
+// ParseScope validates a scope name
+func ParseScope(s string) (Scope, error) {
+	switch Scope(strings.ToLower(s)) {
+	case ScopeRead:
+		return ScopeRead, nil
+	case ScopeIngest:
+		return ScopeIngest, nil
+	case ScopeAdmin:
+		return ScopeAdmin, nil
+	default:
+		return "", fmt.Errorf("unknown scope %q (want read, ingest or admin)", s)
+	}
+}
+
+// Allows reports whether a token with this scope may perform an operation
+// requiring the given scope. Admin tokens may do anything.
+func (s Scope) Allows(required Scope) bool {
+	return s == ScopeAdmin || s == required
+}
+
+// Generate creates a new random token and returns it with its hash. Only the
+// hash is stored; the token itself is shown once at creation.
+func Generate() (token, hash string, err error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token = tokenPrefix + hex.EncodeToString(buf)
+	return token, Hash(token), nil
+}
+
+// Hash returns the stored form of a token
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// TokenEnvVar names the environment variable clients read their token from
+const TokenEnvVar = "CLAUDE_MON_TOKEN"
+
+// FromEnv returns the client token from the environment, if any
+func FromEnv() string {
+	return os.Getenv(TokenEnvVar)
+}
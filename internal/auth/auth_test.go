@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseScope(t *testing.T) {
+	for _, s := range []string{"read", "ingest", "ADMIN"} {
+		if _, err := ParseScope(s); err != nil {
+			t.Errorf("ParseScope(%q) failed: %v", s, err)
+		}
+	}
+	if _, err := ParseScope("write"); err == nil {
+		t.Error("expected error for unknown scope")
+	}
+}
+
+func TestScopeAllows(t *testing.T) {
+	tests := []struct {
+		scope    Scope
+		required Scope
+		want     bool
+	}{
+		{ScopeRead, ScopeRead, true},
+		{ScopeRead, ScopeIngest, false},
+		{ScopeIngest, ScopeIngest, true},
+		{ScopeIngest, ScopeAdmin, false},
+		{ScopeAdmin, ScopeRead, true},
+		{ScopeAdmin, ScopeIngest, true},
+	}
+	for _, tt := range tests {
+		if got := tt.scope.Allows(tt.required); got != tt.want {
+			t.Errorf("%s.Allows(%s) = %v, want %v", tt.scope, tt.required, got, tt.want)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	token, hash, err := Generate()
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+	if !strings.HasPrefix(token, tokenPrefix) {
+		t.Errorf("expected %s prefix, got %s", tokenPrefix, token)
+	}
+	if hash != Hash(token) || hash == token {
+		t.Error("expected hash to match Hash(token) and differ from the token")
+	}
+
+	other, _, _ := Generate()
+	if other == token {
+		t.Error("expected unique tokens")
+	}
+}
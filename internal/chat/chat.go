@@ -10,11 +10,14 @@ import (
 	"sync"
 	"time"
 
+	"github.com/charmbracelet/x/ansi"
 	"github.com/creack/pty"
 	"github.com/google/uuid"
 	"github.com/ztaylor/claude-mon/internal/logger"
 )
 
+var log = logger.For("chat")
+
 // Mode represents the chat operation mode
 type Mode int
 
@@ -113,11 +116,13 @@ type ClaudeChat struct {
 	// stdin    io.WriteCloser  // stdin for JSON mode (DISABLED)
 	// stdout   io.ReadCloser   // stdout for JSON mode (DISABLED)
 	// stderr   io.ReadCloser   // stderr for JSON mode (DISABLED)
-	cmd      *exec.Cmd       // Claude CLI process
-	output   strings.Builder // Accumulated output
-	messages []Message       // Chat history
-	active   bool            // Whether chat is active
-	mu       sync.Mutex      // Protects shared state
+	cmd        *exec.Cmd       // Claude CLI process
+	output     strings.Builder // Accumulated output
+	replyStart int             // Offset in output where Claude's current reply begins
+	replaying  bool            // Output so far is a resumed session redrawing its history
+	messages   []Message       // Chat history
+	active     bool            // Whether chat is active
+	mu         sync.Mutex      // Protects shared state
 
 	// Session identification
 	sessionID string         // Unique session ID for isolation
@@ -127,6 +132,11 @@ type ClaudeChat struct {
 	mode      Mode   // Current operation mode
 	objective string // The objective/prompt for objective mode
 
+	// Process exit, reaped once by whichever of Stop and the objective
+	// reader gets there first
+	waitOnce sync.Once
+	exitCode int
+
 	// JSON streaming state (DISABLED)
 	// currentMessage  *strings.Builder // Current message being built
 	// currentThinking *strings.Builder // Current thinking content
@@ -167,13 +177,34 @@ func (c *ClaudeChat) Start(mcpConfigPath string) error {
 		c.sessionID = uuid.New().String()
 	}
 
-	// Build command with session ID for isolation
-	args := []string{"--session-id", c.sessionID}
+	return c.startInteractive([]string{"--session-id", c.sessionID}, mcpConfigPath, nil)
+}
+
+// Resume continues an earlier session with claude --resume. history is the
+// session's recorded transcript, which starts the message history.
+func (c *ClaudeChat) Resume(sessionID string, history []Message, mcpConfigPath string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.active {
+		return fmt.Errorf("chat already active")
+	}
+
+	c.sessionID = sessionID
+	if err := c.startInteractive([]string{"--resume", sessionID}, mcpConfigPath, history); err != nil {
+		return err
+	}
+	c.replaying = true
+	return nil
+}
+
+// startInteractive runs the Claude CLI with args in a PTY. Callers hold c.mu.
+func (c *ClaudeChat) startInteractive(args []string, mcpConfigPath string, history []Message) error {
 	if mcpConfigPath != "" {
 		args = append(args, "--mcp-config", mcpConfigPath)
 	}
 
-	logger.Log("Starting claude CLI with session ID %s, args: %v", c.sessionID, args)
+	log.Log("Starting claude CLI with session ID %s, args: %v", c.sessionID, args)
 
 	c.cmd = exec.Command("claude", args...)
 	c.cmd.Env = append(os.Environ(), "TERM=xterm-256color")
@@ -182,16 +213,18 @@ func (c *ClaudeChat) Start(mcpConfigPath string) error {
 	var err error
 	c.ptmx, err = pty.Start(c.cmd)
 	if err != nil {
-		logger.Log("Failed to start PTY: %v", err)
+		log.Log("Failed to start PTY: %v", err)
 		return fmt.Errorf("failed to start PTY: %w", err)
 	}
 
-	logger.Log("PTY started successfully, PID: %d, session: %s", c.cmd.Process.Pid, c.sessionID)
+	log.Log("PTY started successfully, PID: %d, session: %s", c.cmd.Process.Pid, c.sessionID)
 
 	c.active = true
 	c.mode = ModeInteractive
 	c.output.Reset()
-	c.messages = make([]Message, 0)
+	c.replyStart = 0
+	c.replaying = false
+	c.messages = append(make([]Message, 0, len(history)), history...)
 
 	// Start goroutine to read output (also handles auto-confirmation of prompts)
 	go c.readOutput()
@@ -215,7 +248,7 @@ func (c *ClaudeChat) StartWithObjective(objective string, mcpConfigPath string)
 		args = append(args, "--mcp-config", mcpConfigPath)
 	}
 
-	logger.Log("Starting claude CLI with objective: %s, args: %v", objective[:min(50, len(objective))], args)
+	log.Log("Starting claude CLI with objective: %s, args: %v", objective[:min(50, len(objective))], args)
 
 	c.cmd = exec.Command("claude", args...)
 	c.cmd.Env = append(os.Environ(), "TERM=xterm-256color")
@@ -224,16 +257,17 @@ func (c *ClaudeChat) StartWithObjective(objective string, mcpConfigPath string)
 	var err error
 	c.ptmx, err = pty.Start(c.cmd)
 	if err != nil {
-		logger.Log("Failed to start PTY for objective: %v", err)
+		log.Log("Failed to start PTY for objective: %v", err)
 		return fmt.Errorf("failed to start PTY: %w", err)
 	}
 
-	logger.Log("PTY started for objective, PID: %d", c.cmd.Process.Pid)
+	log.Log("PTY started for objective, PID: %d", c.cmd.Process.Pid)
 
 	c.active = true
 	c.mode = ModeObjective
 	c.objective = objective
 	c.output.Reset()
+	c.replyStart = 0
 	c.messages = make([]Message, 0)
 
 	// Record the objective as first message
@@ -259,7 +293,7 @@ func (c *ClaudeChat) StartJSON(initialPrompt string, mcpConfigPath string) error
 // DISABLED: JSON streaming mode is disabled, use PTY mode instead
 /*
 func (c *ClaudeChat) readJSONOutput() {
-	logger.Log("JSON stream: starting output reader")
+	log.Log("JSON stream: starting output reader")
 	scanner := bufio.NewScanner(c.stdout)
 	// Increase buffer size for long JSON lines (up to 1MB)
 	buf := make([]byte, 0, 1024*1024)
@@ -267,11 +301,11 @@ func (c *ClaudeChat) readJSONOutput() {
 
 	for scanner.Scan() {
 		line := scanner.Bytes()
-		logger.Log("JSON stream: received line: %s", string(line))
+		log.Log("JSON stream: received line: %s", string(line))
 
 		var event JSONEvent
 		if err := json.Unmarshal(line, &event); err != nil {
-			logger.Log("JSON stream: failed to parse JSON: %v, line: %s", err, string(line))
+			log.Log("JSON stream: failed to parse JSON: %v, line: %s", err, string(line))
 			// Send as raw text if JSON parsing fails
 			select {
 			case c.outputCh <- string(line):
@@ -313,7 +347,7 @@ func (c *ClaudeChat) readJSONOutput() {
 			// Final result event - contains the complete response text
 			if event.Result != "" {
 				// Result is already captured from assistant events, but log it
-				logger.Log("JSON stream: received result, subtype=%s", event.Subtype)
+				log.Log("JSON stream: received result, subtype=%s", event.Subtype)
 
 				// Signal completion
 				c.mu.Lock()
@@ -347,16 +381,16 @@ func (c *ClaudeChat) readJSONOutput() {
 				if wasActive && (event.Subtype == "success" || event.Subtype == "error") {
 					select {
 					case c.completedCh <- struct{}{}:
-						logger.Log("JSON stream: sent completion signal from result")
+						log.Log("JSON stream: sent completion signal from result")
 					default:
-						logger.Log("JSON stream: completion channel full")
+						log.Log("JSON stream: completion channel full")
 					}
 				}
 			}
 
 		case "system":
 			// System initialization event - log but don't display
-			logger.Log("JSON stream: system event, subtype=%s", event.Subtype)
+			log.Log("JSON stream: system event, subtype=%s", event.Subtype)
 
 		case "error":
 			if event.Error != "" {
@@ -371,7 +405,7 @@ func (c *ClaudeChat) readJSONOutput() {
 
 		default:
 			// Unknown event type, log and pass through for debugging
-			logger.Log("JSON stream: unknown event type: %s", event.Type)
+			log.Log("JSON stream: unknown event type: %s", event.Type)
 			select {
 			case c.outputCh <- event:
 			default:
@@ -380,7 +414,7 @@ func (c *ClaudeChat) readJSONOutput() {
 	}
 
 	if err := scanner.Err(); err != nil {
-		logger.Log("JSON stream: scanner error: %v", err)
+		log.Log("JSON stream: scanner error: %v", err)
 		select {
 		case c.errCh <- err:
 		default:
@@ -394,7 +428,7 @@ func (c *ClaudeChat) readJSONOutput() {
 	c.awaitingInput = false
 	c.mu.Unlock()
 
-	logger.Log("JSON stream: process ended, wasActive=%v", wasActive)
+	log.Log("JSON stream: process ended, wasActive=%v", wasActive)
 
 	// Finalize any pending message
 	c.mu.Lock()
@@ -413,9 +447,9 @@ func (c *ClaudeChat) readJSONOutput() {
 	if wasActive {
 		select {
 		case c.completedCh <- struct{}{}:
-			logger.Log("JSON stream: sent completion signal")
+			log.Log("JSON stream: sent completion signal")
 		default:
-			logger.Log("JSON stream: completion channel full")
+			log.Log("JSON stream: completion channel full")
 		}
 	}
 
@@ -472,7 +506,7 @@ func (c *ClaudeChat) readOutput() {
 
 		if n > 0 {
 			chunk := string(buf[:n])
-			logger.Log("Chat PTY read: %d bytes", n)
+			log.Log("Chat PTY read: %d bytes", n)
 			c.mu.Lock()
 			c.output.WriteString(chunk)
 			c.mu.Unlock()
@@ -481,11 +515,11 @@ func (c *ClaudeChat) readOutput() {
 			// Only respond once per session
 			if !sentConfirm && len(chunk) > 0 {
 				if containsIgnoreCase(chunk, "trust") || containsIgnoreCase(chunk, "confirm") {
-					logger.Log("Detected trust/confirm prompt, sending 'y'")
+					log.Log("Detected trust/confirm prompt, sending 'y'")
 					c.ptmx.Write([]byte("y\n"))
 					sentConfirm = true
 				} else if containsIgnoreCase(chunk, "continue") && (containsIgnoreCase(chunk, "press") || containsIgnoreCase(chunk, "enter")) {
-					logger.Log("Detected continue prompt, sending enter")
+					log.Log("Detected continue prompt, sending enter")
 					c.ptmx.Write([]byte("\n"))
 					sentConfirm = true
 				}
@@ -493,14 +527,14 @@ func (c *ClaudeChat) readOutput() {
 
 			select {
 			case c.outputCh <- chunk:
-				logger.Log("Chat output sent to channel")
+				log.Log("Chat output sent to channel")
 			default:
-				logger.Log("Chat output channel full, skipped")
+				log.Log("Chat output channel full, skipped")
 			}
 		}
 	}
 
-	logger.Log("Chat readOutput loop ended")
+	log.Log("Chat readOutput loop ended")
 	close(c.doneCh)
 }
 
@@ -511,7 +545,7 @@ func containsIgnoreCase(s, substr string) bool {
 
 // readOutputObjective reads output in objective mode and signals completion when process exits
 func (c *ClaudeChat) readOutputObjective() {
-	logger.Log("Objective mode: starting output reader")
+	log.Log("Objective mode: starting output reader")
 	reader := bufio.NewReader(c.ptmx)
 	buf := make([]byte, 4096)
 	sentConfirm := false
@@ -519,7 +553,7 @@ func (c *ClaudeChat) readOutputObjective() {
 	for {
 		n, err := reader.Read(buf)
 		if err != nil {
-			logger.Log("Objective mode: read error: %v", err)
+			log.Log("Objective mode: read error: %v", err)
 			if err != io.EOF {
 				select {
 				case c.errCh <- err:
@@ -531,7 +565,7 @@ func (c *ClaudeChat) readOutputObjective() {
 
 		if n > 0 {
 			chunk := string(buf[:n])
-			logger.Log("Objective mode PTY read: %d bytes", n)
+			log.Log("Objective mode PTY read: %d bytes", n)
 			c.mu.Lock()
 			c.output.WriteString(chunk)
 			c.mu.Unlock()
@@ -539,11 +573,11 @@ func (c *ClaudeChat) readOutputObjective() {
 			// Check for trust prompts in output (check continuously, not just first chunk)
 			if !sentConfirm && len(chunk) > 0 {
 				if containsIgnoreCase(chunk, "trust") || containsIgnoreCase(chunk, "confirm") {
-					logger.Log("Objective mode: detected trust/confirm prompt, sending 'y'")
+					log.Log("Objective mode: detected trust/confirm prompt, sending 'y'")
 					c.ptmx.Write([]byte("y\n"))
 					sentConfirm = true
 				} else if containsIgnoreCase(chunk, "continue") && (containsIgnoreCase(chunk, "press") || containsIgnoreCase(chunk, "enter")) {
-					logger.Log("Objective mode: detected continue prompt, sending enter")
+					log.Log("Objective mode: detected continue prompt, sending enter")
 					c.ptmx.Write([]byte("\n"))
 					sentConfirm = true
 				}
@@ -551,28 +585,29 @@ func (c *ClaudeChat) readOutputObjective() {
 
 			select {
 			case c.outputCh <- chunk:
-				logger.Log("Objective mode output sent to channel")
+				log.Log("Objective mode output sent to channel")
 			default:
-				logger.Log("Objective mode output channel full, skipped")
+				log.Log("Objective mode output channel full, skipped")
 			}
 		}
 	}
 
 	// In objective mode, process exit means objective complete
+	c.wait()
 	c.mu.Lock()
 	wasActive := c.active
 	c.active = false
 	c.mu.Unlock()
 
-	logger.Log("Objective mode: process ended, wasActive=%v", wasActive)
+	log.Log("Objective mode: process ended, wasActive=%v", wasActive)
 
 	if wasActive {
 		// Signal completion
 		select {
 		case c.completedCh <- struct{}{}:
-			logger.Log("Objective mode: sent completion signal")
+			log.Log("Objective mode: sent completion signal")
 		default:
-			logger.Log("Objective mode: completion channel full")
+			log.Log("Objective mode: completion channel full")
 		}
 	}
 
@@ -588,6 +623,8 @@ func (c *ClaudeChat) Send(input string) error {
 		return fmt.Errorf("chat not active")
 	}
 
+	c.recordReply()
+
 	// Record user message
 	c.messages = append(c.messages, Message{
 		Role:      "user",
@@ -600,6 +637,29 @@ func (c *ClaudeChat) Send(input string) error {
 	return err
 }
 
+// Inject writes a prompt into the session as if it were pasted and submits
+// it. Unlike Send, newlines in the prompt don't submit each line separately:
+// the text is wrapped in bracketed paste sequences, which Claude Code reads
+// as a single multi-line input.
+func (c *ClaudeChat) Inject(prompt string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.active || c.ptmx == nil {
+		return fmt.Errorf("chat not active")
+	}
+
+	c.recordReply()
+	c.messages = append(c.messages, Message{
+		Role:      "user",
+		Content:   prompt,
+		Timestamp: time.Now(),
+	})
+
+	_, err := c.ptmx.Write([]byte("\x1b[200~" + strings.TrimRight(prompt, "\n") + "\x1b[201~\r"))
+	return err
+}
+
 // Stop terminates the Claude CLI process
 func (c *ClaudeChat) Stop() error {
 	c.mu.Lock()
@@ -610,6 +670,7 @@ func (c *ClaudeChat) Stop() error {
 	}
 
 	c.active = false
+	c.recordReply()
 
 	// Close PTY
 	if c.ptmx != nil {
@@ -619,12 +680,29 @@ func (c *ClaudeChat) Stop() error {
 	// Kill process
 	if c.cmd != nil && c.cmd.Process != nil {
 		c.cmd.Process.Kill()
-		c.cmd.Wait()
+		c.wait()
 	}
 
 	return nil
 }
 
+// wait reaps the Claude process and records its exit code
+func (c *ClaudeChat) wait() {
+	c.waitOnce.Do(func() {
+		c.cmd.Wait()
+		c.exitCode = -1
+		if c.cmd.ProcessState != nil {
+			c.exitCode = c.cmd.ProcessState.ExitCode()
+		}
+	})
+}
+
+// ExitCode returns the Claude process's exit code once DoneChan is closed:
+// -1 if it was killed
+func (c *ClaudeChat) ExitCode() int {
+	return c.exitCode
+}
+
 // IsActive returns whether the chat is currently active
 func (c *ClaudeChat) IsActive() bool {
 	c.mu.Lock()
@@ -648,6 +726,55 @@ func (c *ClaudeChat) Messages() []Message {
 	return result
 }
 
+// Pending returns Claude's output since the last message, as plain text
+func (c *ClaudeChat) Pending() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return cleanOutput(c.output.String()[c.replyStart:])
+}
+
+// CleanOutput returns everything the session printed, without terminal
+// control sequences
+func (c *ClaudeChat) CleanOutput() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return cleanOutput(c.output.String())
+}
+
+// recordReply adds the output since the last message to the history as
+// Claude's reply. Callers hold c.mu.
+func (c *ClaudeChat) recordReply() {
+	reply := cleanOutput(c.output.String()[c.replyStart:])
+	c.replyStart = c.output.Len()
+	if c.replaying {
+		// The history is already in messages
+		c.replaying = false
+		return
+	}
+	if reply == "" {
+		return
+	}
+	c.messages = append(c.messages, Message{
+		Role:      "assistant",
+		Content:   reply,
+		Timestamp: time.Now(),
+	})
+}
+
+// cleanOutput turns raw PTY output into plain text: escape sequences are
+// dropped and a line redrawn after a carriage return keeps its last version
+func cleanOutput(raw string) string {
+	lines := strings.Split(ansi.Strip(raw), "\n")
+	for i, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if j := strings.LastIndex(line, "\r"); j >= 0 {
+			line = line[j+1:]
+		}
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
 // OutputChan returns the channel for receiving output (may contain string or JSONEvent)
 func (c *ClaudeChat) OutputChan() <-chan interface{} {
 	return c.outputCh
@@ -668,6 +795,7 @@ func (c *ClaudeChat) ClearOutput() {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.output.Reset()
+	c.replyStart = 0
 }
 
 // SetSize sets the PTY window size
@@ -676,11 +804,11 @@ func (c *ClaudeChat) SetSize(rows, cols int) error {
 	defer c.mu.Unlock()
 
 	if c.ptmx == nil {
-		logger.Log("SetSize called but ptmx is nil")
+		log.Log("SetSize called but ptmx is nil")
 		return nil
 	}
 
-	logger.Log("Setting PTY size: %dx%d", cols, rows)
+	log.Log("Setting PTY size: %dx%d", cols, rows)
 	return pty.Setsize(c.ptmx, &pty.Winsize{
 		Rows: uint16(rows),
 		Cols: uint16(cols),
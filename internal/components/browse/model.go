@@ -0,0 +1,158 @@
+// Package browse is a list of query results with fuzzy filtering and a
+// preview of the selected one, for `claude-mon query browse`
+package browse
+
+import (
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/ztaylor/claude-mon/internal/theme"
+)
+
+// Item is one query result
+type Item struct {
+	Heading string // First line in the list
+	Detail  string // Second line in the list, dimmed
+	Preview string // Shown in the preview pane while the item is selected
+	Value   string // What choosing the item with Enter returns, e.g. its path
+}
+
+// FilterValue implements list.Item - the filter matches heading and detail
+func (i Item) FilterValue() string {
+	return i.Heading + " " + i.Detail
+}
+
+// Title implements list.DefaultItem
+func (i Item) Title() string {
+	return i.Heading
+}
+
+// Description implements list.DefaultItem
+func (i Item) Description() string {
+	return i.Detail
+}
+
+// Model is the browser: the results on the left, the selected one's
+// preview on the right
+type Model struct {
+	list    list.Model
+	preview viewport.Model
+	theme   *theme.Theme
+	width   int
+	height  int
+
+	previewFocus bool // j/k scroll the preview rather than move through the list
+	previewIndex int  // Item the preview shows, -1 before the first
+	chosen       *Item
+}
+
+// New creates a browser of items under a title
+func New(title string, items []Item, t *theme.Theme) Model {
+	listItems := make([]list.Item, len(items))
+	for i, item := range items {
+		listItems[i] = item
+	}
+
+	delegate := list.NewDefaultDelegate()
+	l := list.New(listItems, delegate, 0, 0)
+	l.Title = title
+	l.Styles.Title = t.Title
+	l.SetShowHelp(false) // One line of our own help instead
+	l.SetFilteringEnabled(true)
+
+	m := Model{
+		list:         l,
+		preview:      viewport.New(0, 0),
+		theme:        t,
+		previewIndex: -1,
+	}
+	m.syncPreview()
+	return m
+}
+
+// Chosen returns the item chosen with Enter, if the browser was left that
+// way
+func (m Model) Chosen() (Item, bool) {
+	if m.chosen == nil {
+		return Item{}, false
+	}
+	return *m.chosen, true
+}
+
+// Init implements tea.Model
+func (m Model) Init() tea.Cmd {
+	return nil
+}
+
+// Update implements tea.Model
+func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.setSize(msg.Width, msg.Height)
+		return m, nil
+
+	case tea.KeyMsg:
+		// Typing a filter goes to the list, Enter included
+		if m.list.FilterState() == list.Filtering {
+			m.list, cmd = m.list.Update(msg)
+			m.syncPreview()
+			return m, cmd
+		}
+		switch msg.String() {
+		case "ctrl+c":
+			return m, tea.Quit
+		case "tab":
+			m.previewFocus = !m.previewFocus
+			return m, nil
+		case "enter":
+			if item, ok := m.list.SelectedItem().(Item); ok {
+				m.chosen = &item
+				return m, tea.Quit
+			}
+			return m, nil
+		}
+		if m.previewFocus {
+			switch msg.String() {
+			case "q", "esc":
+				m.previewFocus = false
+			default:
+				m.preview, cmd = m.preview.Update(msg)
+			}
+			return m, cmd
+		}
+	}
+
+	m.list, cmd = m.list.Update(msg)
+	m.syncPreview()
+	return m, cmd
+}
+
+// setSize lays out the list and preview side by side, above a help line
+func (m *Model) setSize(width, height int) {
+	m.width, m.height = width, height
+	listWidth := width * 2 / 5
+	m.list.SetSize(listWidth, height-1)
+	// The preview has a border on every side
+	m.preview.Width = max(width-listWidth-2, 0)
+	m.preview.Height = max(height-3, 0)
+	m.previewIndex = -1
+	m.syncPreview()
+}
+
+// syncPreview shows the selected item in the preview, from the top when
+// the selection changed
+func (m *Model) syncPreview() {
+	item, ok := m.list.SelectedItem().(Item)
+	index := m.list.GlobalIndex()
+	if !ok {
+		index = -1
+	}
+	if index == m.previewIndex && index != -1 {
+		return
+	}
+	m.previewIndex = index
+	m.preview.SetContent(item.Preview)
+	m.preview.GotoTop()
+}
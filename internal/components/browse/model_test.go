@@ -0,0 +1,54 @@
+package browse
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/theme"
+)
+
+func update(m Model, msgs ...tea.Msg) Model {
+	for _, msg := range msgs {
+		next, _ := m.Update(msg)
+		m = next.(Model)
+	}
+	return m
+}
+
+func TestBrowse(t *testing.T) {
+	items := []Item{
+		{Heading: "internal/model/model.go:12", Detail: "Edit", Preview: "model diff", Value: "/w/internal/model/model.go"},
+		{Heading: "cmd/main.go:3", Detail: "Write", Preview: "main diff", Value: "/w/cmd/main.go"},
+	}
+	m := update(New("Recent edits", items, theme.Default()), tea.WindowSizeMsg{Width: 120, Height: 30})
+
+	if !strings.Contains(m.View(), "model diff") {
+		t.Errorf("preview should show the first item:\n%s", m.View())
+	}
+
+	m = update(m, tea.KeyMsg{Type: tea.KeyDown})
+	if !strings.Contains(m.View(), "main diff") {
+		t.Errorf("preview should follow the selection:\n%s", m.View())
+	}
+
+	// Tab moves keys to the preview, so k scrolls rather than selecting
+	m = update(m, tea.KeyMsg{Type: tea.KeyTab}, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("k")})
+	if !m.previewFocus || !strings.Contains(m.View(), "main diff") {
+		t.Errorf("k in the preview should scroll it, not move the selection")
+	}
+
+	_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if cmd == nil {
+		t.Fatal("Enter should quit")
+	}
+	next, _ := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	chosen, ok := next.(Model).Chosen()
+	if !ok || chosen.Value != "/w/cmd/main.go" {
+		t.Errorf("chosen = %+v, %v; want the selected item", chosen, ok)
+	}
+
+	if _, ok := m.Chosen(); ok {
+		t.Error("nothing should be chosen before Enter")
+	}
+}
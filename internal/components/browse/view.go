@@ -0,0 +1,28 @@
+package browse
+
+import (
+	"github.com/charmbracelet/lipgloss"
+)
+
+// View implements tea.Model
+func (m Model) View() string {
+	if m.width == 0 {
+		return ""
+	}
+
+	border := m.theme.Border
+	if m.previewFocus {
+		border = m.theme.ActiveBorder
+	}
+	preview := border.
+		Width(m.preview.Width).
+		Height(m.preview.Height).
+		Render(m.preview.View())
+
+	help := "/:filter  tab:preview  enter:choose  q:quit"
+	if m.previewFocus {
+		help = "j/k:scroll  tab/esc:list  enter:choose"
+	}
+	body := lipgloss.JoinHorizontal(lipgloss.Top, m.list.View(), preview)
+	return lipgloss.JoinVertical(lipgloss.Left, body, m.theme.Help.Render(help))
+}
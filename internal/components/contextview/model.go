@@ -287,24 +287,15 @@ func (m *Model) saveEdit() {
 			git.Branch = value
 		}
 	case EditEnvVar:
-		// Parse KEY=VALUE with quote support and merge into existing env
+		// Parse KEY=VALUE with quote support and merge into existing env,
+		// keeping a secret key secret
 		if k, v, ok := parseKeyValue(value); ok {
-			env := m.current.GetEnv()
-			if env == nil {
-				env = make(map[string]string)
-			}
-			env[k] = v
-			m.current.SetEnv(env)
+			_ = m.current.SetValue("env", k, v, m.current.SecretFor("env", k))
 		}
 	case EditCustom:
 		// Parse KEY=VALUE with quote support and merge into existing custom
 		if k, v, ok := parseKeyValue(value); ok {
-			custom := m.current.GetCustom()
-			if custom == nil {
-				custom = make(map[string]string)
-			}
-			custom[k] = v
-			m.current.SetCustom(custom)
+			_ = m.current.SetValue("custom", k, v, m.current.SecretFor("custom", k))
 		}
 	}
 
@@ -109,11 +109,16 @@ func (m Model) renderCurrentContext() string {
 		}
 	}
 
+	// Terraform workspace / Pulumi stack
+	if iac := m.current.GetIaC(); iac != nil && iac.Workspace != "" {
+		rows = append(rows, []string{"🏗️ " + iac.Label(), iac.String()})
+	}
+
 	// Environment variables
 	if env := m.current.GetEnv(); env != nil && len(env) > 0 {
 		var envPairs []string
 		for k, v := range env {
-			envPairs = append(envPairs, k+"="+v)
+			envPairs = append(envPairs, k+"="+m.current.DisplayValue("env", k, v))
 		}
 		if len(envPairs) > 3 {
 			envPairs = append(envPairs[:3], fmt.Sprintf("(+%d more)", len(envPairs)-3))
@@ -124,7 +129,7 @@ func (m Model) renderCurrentContext() string {
 	// Custom values
 	if custom := m.current.GetCustom(); custom != nil && len(custom) > 0 {
 		for k, v := range custom {
-			rows = append(rows, []string{"🔧 " + k, v})
+			rows = append(rows, []string{"🔧 " + k, m.current.DisplayValue("custom", k, v)})
 		}
 	}
 
@@ -225,6 +230,12 @@ func (m Model) renderAllContexts() string {
 			}
 		}
 
+		// Terraform workspace / Pulumi stack
+		if iac := ctx.GetIaC(); iac != nil && iac.Workspace != "" {
+			sb.WriteString(m.theme.Dim.Render("    🏗️ "+iac.Label()+": ") + m.theme.Normal.Render(iac.Workspace))
+			sb.WriteString("\n")
+		}
+
 		sb.WriteString("\n")
 	}
 
@@ -46,6 +46,9 @@ func FromConfig(cfg *config.Config) KeyMap {
 	if cfg.Keys.OpenNvimCwd != "" {
 		km.OpenNvimCwd = key.NewBinding(key.WithKeys(cfg.Keys.OpenNvimCwd), key.WithHelp(cfg.Keys.OpenNvimCwd, "nvim cwd"))
 	}
+	if cfg.Keys.ToggleContext != "" {
+		km.WholeFile = key.NewBinding(key.WithKeys(cfg.Keys.ToggleContext), key.WithHelp(cfg.Keys.ToggleContext, "whole file"))
+	}
 
 	return km
 }
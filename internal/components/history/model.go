@@ -9,6 +9,7 @@ import (
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/ztaylor/claude-mon/internal/config"
 	"github.com/ztaylor/claude-mon/internal/diff"
 	"github.com/ztaylor/claude-mon/internal/highlight"
 	"github.com/ztaylor/claude-mon/internal/history"
@@ -45,6 +46,7 @@ type KeyMap struct {
 	ClearHistory key.Binding
 	OpenInNvim   key.Binding
 	OpenNvimCwd  key.Binding
+	WholeFile    key.Binding
 }
 
 // DefaultKeyMap returns the default key bindings for history
@@ -61,6 +63,7 @@ func DefaultKeyMap() KeyMap {
 		ClearHistory: key.NewBinding(key.WithKeys("C"), key.WithHelp("C", "clear history")),
 		OpenInNvim:   key.NewBinding(key.WithKeys("ctrl+n"), key.WithHelp("C-n", "open in nvim")),
 		OpenNvimCwd:  key.NewBinding(key.WithKeys("ctrl+o"), key.WithHelp("C-o", "nvim cwd")),
+		WholeFile:    key.NewBinding(key.WithKeys("z"), key.WithHelp("z", "whole file")),
 	}
 }
 
@@ -73,7 +76,7 @@ func (k KeyMap) ShortHelp() []key.Binding {
 func (k KeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Up, k.Down, k.PageUp, k.PageDown},
-		{k.ScrollLeft, k.ScrollRight, k.OpenInNvim, k.OpenNvimCwd},
+		{k.ScrollLeft, k.ScrollRight, k.OpenInNvim, k.OpenNvimCwd, k.WholeFile},
 		{k.ClearHistory, k.Next, k.Prev},
 	}
 }
@@ -91,6 +94,8 @@ type Model struct {
 	totalLines       int // Total lines in current file (for minimap)
 	minimapData      *minimap.Minimap
 	diffCache        map[int]string // Cached rendered diffs by index
+	diffConfig       config.DiffConfig
+	wholeFile        bool // Show whole files whatever diffConfig's context lines
 
 	// Storage
 	store      *history.Store
@@ -124,6 +129,13 @@ func WithKeyMap(km KeyMap) Option {
 	}
 }
 
+// WithDiffConfig sets how many lines are shown around changes
+func WithDiffConfig(cfg config.DiffConfig) Option {
+	return func(m *Model) {
+		m.diffConfig = cfg
+	}
+}
+
 // WithPersistence enables file-based history persistence
 func WithPersistence(enabled bool) Option {
 	return func(m *Model) {
@@ -134,11 +146,12 @@ func WithPersistence(enabled bool) Option {
 // New creates a new history component
 func New(opts ...Option) Model {
 	m := Model{
-		changes:   []Change{},
-		diffCache: make(map[int]string),
-		keyMap:    DefaultKeyMap(),
-		theme:     theme.Default(),
-		focusLeft: true,
+		changes:    []Change{},
+		diffCache:  make(map[int]string),
+		diffConfig: config.DiffConfig{ContextLines: -1},
+		keyMap:     DefaultKeyMap(),
+		theme:      theme.Default(),
+		focusLeft:  true,
 	}
 
 	// Apply options
@@ -304,6 +317,12 @@ func (m Model) handleKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
 		m.scrollX += 4
 		m.viewport.SetContent(m.RenderDiff())
 
+	case key.Matches(msg, m.keyMap.WholeFile):
+		m.wholeFile = !m.wholeFile
+		m.diffCache = make(map[int]string)
+		m.viewport.SetContent(m.RenderDiff())
+		m.scrollToChange()
+
 	case key.Matches(msg, m.keyMap.ClearHistory):
 		m.changes = []Change{}
 		m.selectedIndex = 0
@@ -431,12 +450,25 @@ func (m *Model) scrollToChange() {
 	// Scroll to a few lines before the change so it's visible in context
 	// Add 2 for the header lines in the diff view
 	targetLine := change.LineNum - 3
+	if n := m.contextLines(change.FilePath); n >= 0 {
+		// The file starts n lines above the change
+		targetLine -= max(change.LineNum-1-n, 0)
+	}
 	if targetLine < 0 {
 		targetLine = 0
 	}
 	m.viewport.SetYOffset(targetLine)
 }
 
+// contextLines returns how many lines of a file to show around a change,
+// or a negative number for the whole file
+func (m *Model) contextLines(path string) int {
+	if m.wholeFile {
+		return -1
+	}
+	return m.diffConfig.ContextLinesFor(path)
+}
+
 // preloadAdjacent pre-caches rendered diffs for adjacent changes
 func (m *Model) preloadAdjacent() {
 	// Preload next
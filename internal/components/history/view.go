@@ -193,7 +193,8 @@ func (m *Model) RenderDiff() string {
 	return sb.String()
 }
 
-// renderFileWithChange shows the full file with the changed section highlighted
+// renderFileWithChange shows the file with the changed section highlighted,
+// whole or cut to the configured context lines around the change
 func (m *Model) renderFileWithChange(change Change) string {
 	var sb strings.Builder
 
@@ -205,6 +206,15 @@ func (m *Model) renderFileWithChange(change Change) string {
 	changeStart := change.LineNum - 1 // 0-indexed
 	changeEnd := changeStart + len(oldLines)
 
+	// Cut the file to the context around the change, keeping line numbers
+	first, total := 0, len(fileLines)
+	if n := m.contextLines(change.FilePath); n >= 0 {
+		first = min(max(changeStart-n, 0), total)
+		fileLines = fileLines[first:min(max(changeEnd+n, first), total)]
+		changeStart -= first
+		changeEnd -= first
+	}
+
 	// Track total lines for minimap
 	m.totalLines = len(fileLines) + len(newLines)
 
@@ -224,14 +234,18 @@ func (m *Model) renderFileWithChange(change Change) string {
 	sb.WriteString(m.theme.Added.Render(fmt.Sprintf("+%d", len(newLines))))
 	sb.WriteString(" ")
 	sb.WriteString(m.theme.Removed.Render(fmt.Sprintf("-%d", len(oldLines))))
+	if len(fileLines) < total {
+		sb.WriteString("  ")
+		sb.WriteString(m.theme.Dim.Render(fmt.Sprintf("lines %d-%d of %d", first+1, first+len(fileLines), total)))
+	}
 	sb.WriteString("\n\n")
 
 	// Soft highlight style for changed lines
 	changedBg := lipgloss.NewStyle().Background(m.theme.ChangedLineBg)
 
-	// Render the entire file
+	// Render the file
 	for i := 0; i < len(fileLines); i++ {
-		lineNum := fmt.Sprintf("%4d", i+1)
+		lineNum := fmt.Sprintf("%4d", first+i+1)
 		line := fileLines[i]
 
 		// Apply horizontal scroll
@@ -261,7 +275,7 @@ func (m *Model) renderFileWithChange(change Change) string {
 						scrolledNew = ""
 					}
 
-					newLineNum := fmt.Sprintf("%4d", changeStart+j+1)
+					newLineNum := fmt.Sprintf("%4d", first+changeStart+j+1)
 					lineContent := m.theme.LineNumberActive.Render(newLineNum) + " " +
 						m.theme.Added.Render("+ "+scrolledNew)
 					sb.WriteString(changedBg.Render(lineContent))
@@ -3,17 +3,208 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
+
+	"github.com/ztaylor/claude-mon/internal/lang"
 )
 
 // Config holds all configuration options
 type Config struct {
-	Theme     string      `toml:"theme"`
-	LeaderKey string      `toml:"leader_key"`
-	Keys      KeyBindings `toml:"keys"`
+	Theme         string      `toml:"theme"`
+	LeaderKey     string      `toml:"leader_key"`
+	ListDensity   string      `toml:"list_density"`   // "auto", "compact" or "comfortable"
+	TimeGap       string      `toml:"time_gap"`       // Idle time marked in the history list, e.g. "15m"; "0" disables
+	FileIcons     string      `toml:"file_icons"`     // "nerd", "ascii" or "none"
+	NvimRemote    bool        `toml:"nvim_remote"`    // Open files in a running nvim when one is listening
+	NvimServer    string      `toml:"nvim_server"`    // nvim --server address; empty uses $NVIM_LISTEN_ADDRESS or $NVIM
+	PromptsRemote string      `toml:"prompts_remote"` // Git remote the global prompt library syncs with; empty keeps it local
+	PromptStore   string      `toml:"prompt_store"`   // "files" (the default) or "daemon" to keep prompts in the daemon's database
+	TmuxTarget    string      `toml:"tmux_target"`    // tmux pane prompts are sent to; empty finds the pane running Claude Code
+	StatusBar     string      `toml:"status_bar"`     // Status bar template; see DefaultStatusBar
+	Keys          KeyBindings `toml:"keys"`
+
+	FeedbackTemplate string `toml:"feedback_template"` // Feedback sent to Claude about changes; see DefaultFeedbackTemplate
+
+	Inject InjectConfig `toml:"inject"` // What the inject-context hook adds to prompts
+	Diff   DiffConfig   `toml:"diff"`   // Normalization and context lines of history diffs
+
+	Notifications NotificationsConfig `toml:"notifications"` // How long toasts stay up and how many are kept
+	Follow        FollowConfig        `toml:"follow"`        // Which edits follow mode opens in nvim
+	Gate          GateConfig          `toml:"gate"`          // Holding Claude's edits until they're allowed in the TUI
+}
+
+// GateConfig sets up the edit gate: the PreToolUse hook (claude-mon gate)
+// holds each edit until it's allowed or denied in the TUI
+type GateConfig struct {
+	Enabled   bool   `toml:"enabled"`    // Hold edits for approval
+	Timeout   string `toml:"timeout"`    // How long an edit waits for an answer, e.g. "45s"; keep it under the hook's timeout
+	OnTimeout string `toml:"on_timeout"` // "allow" or "deny" an edit nobody answered
+}
+
+// TimeoutDuration returns how long an edit waits for an answer. An invalid
+// value waits 45s.
+func (g GateConfig) TimeoutDuration() time.Duration {
+	d, err := time.ParseDuration(g.Timeout)
+	if err != nil || d <= 0 {
+		return 45 * time.Second
+	}
+	return d
+}
+
+// AllowsOnTimeout reports whether an edit nobody answered goes ahead
+func (g GateConfig) AllowsOnTimeout() bool {
+	return g.OnTimeout != "deny"
+}
+
+// FollowConfig sets up follow mode, which opens each new change in the
+// running nvim so the editor tracks Claude's edits
+type FollowConfig struct {
+	Enabled  bool     `toml:"enabled"`  // Start with follow mode on
+	Debounce string   `toml:"debounce"` // Quiet time after an edit before nvim jumps to it, e.g. "500ms"
+	Files    []string `toml:"files"`    // Glob patterns of files to follow; empty follows every file
+}
+
+// DebounceDuration returns how long follow mode waits for edits to settle
+// before opening the latest one. An invalid value waits 500ms.
+func (f FollowConfig) DebounceDuration() time.Duration {
+	d, err := time.ParseDuration(f.Debounce)
+	if err != nil || d < 0 {
+		return 500 * time.Millisecond
+	}
+	return d
+}
+
+// Follows reports whether follow mode opens an edit to path. Patterns match
+// the path relative to the workspace or the file's base name; an invalid
+// pattern never matches.
+func (f FollowConfig) Follows(path, workspace string) bool {
+	if len(f.Files) == 0 {
+		return true
+	}
+	rel := path
+	if r, err := filepath.Rel(workspace, path); err == nil && !strings.HasPrefix(r, "..") {
+		rel = r
+	}
+	for _, pattern := range f.Files {
+		full, _ := filepath.Match(pattern, rel)
+		base, _ := filepath.Match(pattern, filepath.Base(path))
+		if full || base {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationsConfig sets how long each kind of toast stays up, in Go
+// duration syntax, and how many the notification center keeps
+type NotificationsConfig struct {
+	Info         string `toml:"info"`
+	Success      string `toml:"success"`
+	Warning      string `toml:"warning"`
+	Error        string `toml:"error"`
+	History      int    `toml:"history"`        // Toasts kept for the notification center
+	DoNotDisturb bool   `toml:"do_not_disturb"` // Start with toasts held in the notification center
+}
+
+// DiffConfig picks the changes diff normalization hides, so a rewrite that
+// only switches line endings or adds a byte order mark doesn't show as the
+// whole file changing, and how much of the file is shown around a change.
+// Normalization and the whole file are toggled in the diff pane.
+type DiffConfig struct {
+	IgnoreWhitespace bool `toml:"ignore_whitespace"` // Lines differing only in whitespace compare equal
+	IgnoreEOL        bool `toml:"ignore_eol"`        // CRLF, CR and LF line endings compare equal
+	DetectEncoding   bool `toml:"detect_encoding"`   // Byte order marks and UTF-16 are decoded before comparing
+
+	ContextLines int            `toml:"context_lines"` // Lines shown on each side of a change; negative shows the whole file
+	Context      map[string]int `toml:"context"`       // context_lines by file name pattern, extension or language
+}
+
+// ContextLinesFor returns how many lines to show on each side of a change
+// to path, or a negative number for the whole file. Context keys are tried
+// as file name patterns ("*.pb.go", "go.sum"), the longest matching first,
+// then as extensions (".json"), then as languages ("yaml", "Python").
+func (d DiffConfig) ContextLinesFor(path string) int {
+	base := filepath.Base(path)
+	pattern, found := "", false
+	for key := range d.Context {
+		if ok, _ := filepath.Match(key, base); ok && (!found || len(key) > len(pattern) || len(key) == len(pattern) && key < pattern) {
+			pattern, found = key, true
+		}
+	}
+	if found {
+		return d.Context[pattern]
+	}
+
+	for key, n := range d.Context {
+		if strings.HasPrefix(key, ".") && strings.EqualFold(filepath.Ext(base), key) {
+			return n
+		}
+	}
+	if id := lang.Detect(path).ID; id != "" {
+		for key, n := range d.Context {
+			if l, ok := lang.Lookup(key); ok && !strings.HasPrefix(key, ".") && l.ID == id {
+				return n
+			}
+		}
+	}
+	return d.ContextLines
+}
+
+// NvimServerAddress returns the address of the running nvim that files
+// should be opened in, or "" if remote opening is off or no server is known
+func (c *Config) NvimServerAddress() string {
+	if !c.NvimRemote {
+		return ""
+	}
+	if c.NvimServer != "" {
+		return c.NvimServer
+	}
+	if addr := os.Getenv("NVIM_LISTEN_ADDRESS"); addr != "" {
+		return addr
+	}
+	return os.Getenv("NVIM")
 }
 
+// TimeGapDuration returns the idle interval that gets a separator in the
+// history list, or 0 if separators are disabled or the value is invalid
+func (c *Config) TimeGapDuration() time.Duration {
+	d, err := time.ParseDuration(c.TimeGap)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// DefaultStatusBar is the status bar template when none is configured.
+// Segments are {mode} {pane} {hints} {daemon} {socket} {dnd} {follow}
+// {pending} {review} {workspace} {branch} {edits_today} and {time}; those
+// after {right} are right-aligned.
+const DefaultStatusBar = "{mode} [{pane}]  {hints}{right}{pending} {review} {follow} {dnd} {daemon} {socket}"
+
+// DefaultFeedbackTemplate wraps changes sent back to Claude as feedback.
+// {{changes}} is each change's path, note and diff; {{count}} how many
+// there are.
+const DefaultFeedbackTemplate = `Please revisit the following changes you made. Each is listed with its diff and, where given, the reason it needs another look.
+
+{{changes}}`
+
+// History list densities
+const (
+	DensityAuto        = "auto"        // compact, switching to comfortable on tall terminals
+	DensityCompact     = "compact"     // one line per change
+	DensityComfortable = "comfortable" // two lines per change with stats, commit and age
+)
+
+// File type icon styles for the history list
+const (
+	IconsNerd  = "nerd"  // Nerd Font glyphs
+	IconsASCII = "ascii" // Two-letter tags such as "go" or "ts"
+	IconsNone  = "none"  // No icons
+)
+
 // KeyBindings holds all configurable key bindings
 type KeyBindings struct {
 	// Global
@@ -25,6 +216,8 @@ type KeyBindings struct {
 	RightPane      string `toml:"right_pane"`
 	ToggleMinimap  string `toml:"toggle_minimap"`
 	ToggleLeftPane string `toml:"toggle_left_pane"`
+	ShrinkLeftPane string `toml:"shrink_left_pane"`
+	GrowLeftPane   string `toml:"grow_left_pane"`
 
 	// Navigation
 	Up       string `toml:"up"`
@@ -35,11 +228,29 @@ type KeyBindings struct {
 	Prev     string `toml:"prev"`
 
 	// History mode
-	ClearHistory string `toml:"clear_history"`
-	OpenInNvim   string `toml:"open_in_nvim"`
-	OpenNvimCwd  string `toml:"open_nvim_cwd"`
-	ScrollLeft   string `toml:"scroll_left"`
-	ScrollRight  string `toml:"scroll_right"`
+	ClearHistory      string `toml:"clear_history"`
+	OpenInNvim        string `toml:"open_in_nvim"`
+	OpenNvimCwd       string `toml:"open_nvim_cwd"`
+	ScrollLeft        string `toml:"scroll_left"`
+	ScrollRight       string `toml:"scroll_right"`
+	Bookmark          string `toml:"bookmark"`
+	BookmarksOnly     string `toml:"bookmarks_only"`
+	ToggleDensity     string `toml:"toggle_density"`
+	FilterHistory     string `toml:"filter_history"`
+	ToggleWorkingTree string `toml:"toggle_working_tree"`
+	ToggleBlame       string `toml:"toggle_blame"`
+	ToggleSquash      string `toml:"toggle_squash"`
+	ExpandSquash      string `toml:"expand_squash"`
+	ToggleNormalize   string `toml:"toggle_normalize"`
+	ToggleContext     string `toml:"toggle_context"`
+	ToggleStructural  string `toml:"toggle_structural"`
+	ToggleSemantic    string `toml:"toggle_semantic"`
+	ToggleChecks      string `toml:"toggle_checks"`
+	FileHistory       string `toml:"file_history"`
+	ToggleSession     string `toml:"toggle_session"`
+	NextSession       string `toml:"next_session"`
+	PrevSession       string `toml:"prev_session"`
+	JumpRegion        string `toml:"jump_region"`
 
 	// Prompts mode
 	NewPrompt       string `toml:"new_prompt"`
@@ -65,8 +276,36 @@ type KeyBindings struct {
 // DefaultConfig returns a config with default values
 func DefaultConfig() *Config {
 	return &Config{
-		Theme:     "dark",
-		LeaderKey: "ctrl+g",
+		Theme:            "dark",
+		LeaderKey:        "ctrl+g",
+		ListDensity:      DensityAuto,
+		TimeGap:          "15m",
+		FileIcons:        IconsASCII,
+		NvimRemote:       true,
+		StatusBar:        DefaultStatusBar,
+		FeedbackTemplate: DefaultFeedbackTemplate,
+		Inject: InjectConfig{
+			MaxChars: DefaultInjectMaxChars,
+		},
+		Diff: DiffConfig{
+			IgnoreEOL:      true,
+			DetectEncoding: true,
+			ContextLines:   -1,
+		},
+		Notifications: NotificationsConfig{
+			Info:    "3s",
+			Success: "3s",
+			Warning: "5s",
+			Error:   "8s",
+			History: 100,
+		},
+		Follow: FollowConfig{
+			Debounce: "500ms",
+		},
+		Gate: GateConfig{
+			Timeout:   "45s",
+			OnTimeout: "allow",
+		},
 		Keys: KeyBindings{
 			// Global
 			Quit:           "q",
@@ -77,6 +316,8 @@ func DefaultConfig() *Config {
 			RightPane:      "]",
 			ToggleMinimap:  "m",
 			ToggleLeftPane: "h",
+			ShrinkLeftPane: "<",
+			GrowLeftPane:   ">",
 
 			// Navigation
 			Up:       "k",
@@ -87,11 +328,29 @@ func DefaultConfig() *Config {
 			Prev:     "p",
 
 			// History mode
-			ClearHistory: "C",
-			OpenInNvim:   "ctrl+n",
-			OpenNvimCwd:  "ctrl+o",
-			ScrollLeft:   "left",
-			ScrollRight:  "right",
+			ClearHistory:      "C",
+			OpenInNvim:        "ctrl+n",
+			OpenNvimCwd:       "ctrl+o",
+			ScrollLeft:        "left",
+			ScrollRight:       "right",
+			Bookmark:          "b",
+			BookmarksOnly:     "B",
+			ToggleDensity:     "D",
+			FilterHistory:     "f",
+			ToggleWorkingTree: "w",
+			ToggleBlame:       "a",
+			ToggleSquash:      "s",
+			ExpandSquash:      "e",
+			ToggleNormalize:   "W",
+			ToggleContext:     "z",
+			ToggleStructural:  "P",
+			ToggleSemantic:    "S",
+			ToggleChecks:      "c",
+			FileHistory:       "H",
+			ToggleSession:     "o",
+			NextSession:       "}",
+			PrevSession:       "{",
+			JumpRegion:        "g",
 
 			// Prompts mode
 			NewPrompt:       "n",
@@ -140,6 +399,40 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// SaveListDensity persists the history list density to the config file,
+// creating the file with defaults if needed and preserving other settings
+func SaveListDensity(density string) error {
+	if _, err := os.Stat(Path()); os.IsNotExist(err) {
+		if err := WriteDefault(); err != nil {
+			return err
+		}
+	}
+
+	data, err := os.ReadFile(Path())
+	if err != nil {
+		return err
+	}
+
+	setting := `list_density = "` + density + `"`
+	lines := strings.Split(string(data), "\n")
+	insertAt := len(lines)
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			// Top-level keys must come before the first table
+			insertAt = i
+			break
+		}
+		if strings.HasPrefix(trimmed, "list_density") {
+			lines[i] = setting
+			return os.WriteFile(Path(), []byte(strings.Join(lines, "\n")), 0644)
+		}
+	}
+
+	lines = append(lines[:insertAt], append([]string{setting, ""}, lines[insertAt:]...)...)
+	return os.WriteFile(Path(), []byte(strings.Join(lines, "\n")), 0644)
+}
+
 // Path returns the path to the config file
 func Path() string {
 	home, _ := os.UserHomeDir()
@@ -167,12 +460,129 @@ func WriteDefault() error {
 # Location: ~/.config/claude-mon/config.toml
 
 # Theme: dark, light, dracula, monokai, gruvbox, nord, catppuccin
+# "auto" picks light or dark from the terminal background at startup
+# Switch at runtime with <leader> T
 theme = "dark"
 
 # Leader key for which-key popup (like tmux/vim)
 # Press this key to see available commands
 leader_key = "ctrl+g"
 
+# History list density: compact (one line per change), comfortable (two lines
+# with +N/-M stats, commit and relative time), or auto (comfortable on very
+# tall terminals). Toggling density in the TUI saves it here.
+list_density = "auto"
+
+# Mark idle stretches longer than this between history entries
+# ("── 42 min idle ──"). Go duration syntax; "0" disables.
+time_gap = "15m"
+
+# File type icons in the history list: nerd (requires a Nerd Font), ascii
+# (two-letter tags like "go" or "ts") or none
+file_icons = "ascii"
+
+# Open files in an already running nvim (via --server) instead of starting
+# one inside the TUI. The server address defaults to $NVIM_LISTEN_ADDRESS,
+# or $NVIM when claude-mon runs in an nvim terminal. Falls back to starting
+# nvim when the server can't be reached.
+nvim_remote = true
+# nvim_server = "/tmp/nvim.sock"
+
+# Keep the global prompt library (~/.claude/prompts) in git: every save and
+# version is committed, and "claude-mon prompts sync" or <leader> S in prompts
+# mode pulls and pushes it. Share one remote across machines or a team.
+# prompts_remote = "git@github.com:me/prompts.git"
+
+# Where prompts are kept: "files" (.prompt.md files in ~/.claude/prompts and
+# the project's .claude/prompts) or "daemon", the daemon's database, shared
+# by every workspace with versions, tags and usage counts. Daemon prompts are
+# written out to ~/.cache/claude-mon/prompts for editing. Move prompts over
+# with "claude-mon prompts import" and back with "claude-mon prompts export".
+# prompt_store = "files"
+
+# tmux pane that prompts are typed into with the tmux inject method, as
+# session:window.pane or a pane ID. By default the pane running Claude Code
+# is found automatically; press P in prompts mode to pick one instead.
+# tmux_target = "main:1.0"
+
+# Status bar contents. Segments: {mode} (open tab), {pane} (L or R),
+# {hints} (navigation keys), {daemon} and {socket} (connection indicators),
+# {dnd} (toasts held by do not disturb), {follow} (follow mode on), {review}
+# (progress while reviewing), {workspace}, {branch} (git or jj), {edits_today}
+# and {time}. Segments after {right} are right-aligned; empty ones are left
+# out, and the left side is cut short first when space runs out.
+status_bar = "{mode} [{pane}]  {hints}{right}{review} {follow} {dnd} {daemon} {socket}"
+
+# Feedback sent to Claude about changes (<leader> F for the selected change,
+# <leader> R for every rejected one). {{changes}} is each change's path, note
+# and diff; {{count}} how many there are. Sent like prompts, via tmux, the
+# clipboard or the chat session.
+feedback_template = """
+Please revisit the following changes you made. Each is listed with its diff and, where given, the reason it needs another look.
+
+{{changes}}"""
+
+[inject]
+# Size budget for the working context the inject-context hook adds to each
+# prompt, in characters; 0 is unlimited. Over budget, sections are kept in
+# priority order and long env/custom lists are cut short.
+max_chars = 2000
+# priority = ["profile", "git", "kubernetes", "aws", "iac", "custom", "env"]
+
+# Inject sections only when a rule matches. prompt is a case-insensitive
+# regexp, workspace a glob matched against the project root or its name.
+# Sections without rules are always injected.
+# [[inject.rules]]
+# sections = ["kubernetes"]
+# prompt = "deploy|kubectl|k8s|pod|helm"
+#
+# [[inject.rules]]
+# sections = ["aws"]
+# workspace = "~/src/infra*"
+
+[diff]
+# Changes hidden from diffs while normalization is on (W in history mode
+# toggles it); the diff header notes what was hidden
+ignore_whitespace = false
+ignore_eol = true
+detect_encoding = true
+# Lines shown on each side of a change in the file; -1 shows the whole file
+# (z in history mode shows the whole file either way)
+context_lines = -1
+
+# context_lines for some files, by file name pattern, extension or language.
+# The longest matching pattern wins, then extensions, then languages.
+# [diff.context]
+# "*.pb.go" = 30
+# "package-lock.json" = 30
+# toml = -1
+# yaml = -1
+
+[notifications]
+# How long each kind of toast stays up (Go duration syntax). Every toast is
+# also kept in the notification center (<leader> !)
+info = "3s"
+success = "3s"
+warning = "5s"
+error = "8s"
+# Toasts the notification center keeps
+history = 100
+# Start with toasts held in the notification center instead of shown
+# (d in the notification center toggles it)
+do_not_disturb = false
+
+[follow]
+# Follow mode (<leader> f in history) opens each new change in the running
+# nvim at its line, so the editor tracks Claude's edits. Needs nvim_remote
+# and a server address.
+# Start with follow mode on
+enabled = false
+# Wait for edits to settle this long, then open the latest one
+debounce = "500ms"
+# Only follow files matching these globs (relative path or base name);
+# empty follows every file
+# files = ["*.go", "internal/*"]
+
 [keys]
 # Global shortcuts
 quit = "q"
@@ -183,6 +593,8 @@ left_pane = "["
 right_pane = "]"
 toggle_minimap = "m"
 toggle_left_pane = "h"
+shrink_left_pane = "<"
+grow_left_pane = ">"
 
 # Navigation (used in multiple modes)
 up = "k"
@@ -198,6 +610,23 @@ open_in_nvim = "ctrl+n"
 open_nvim_cwd = "ctrl+o"
 scroll_left = "left"
 scroll_right = "right"
+bookmark = "b"
+bookmarks_only = "B"
+toggle_density = "D"
+filter_history = "f"
+toggle_working_tree = "w"
+toggle_blame = "a"
+toggle_squash = "s"
+expand_squash = "e"
+toggle_normalize = "W"
+toggle_context = "z"
+toggle_structural = "P"
+toggle_semantic = "S"
+toggle_checks = "c"
+toggle_session = "o"
+next_session = "}"
+prev_session = "{"
+jump_region = "g"
 
 # Prompts mode
 new_prompt = "n"
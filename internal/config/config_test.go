@@ -0,0 +1,49 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestDiffContextLinesFor(t *testing.T) {
+	cfg := DefaultConfig()
+	_, err := toml.Decode(`
+[diff]
+context_lines = 5
+
+[diff.context]
+"*.go" = 20
+"*.pb.go" = 30
+"go.sum" = 0
+".json" = -1
+yaml = -1
+Python = 8
+`, cfg)
+	if err != nil {
+		t.Fatalf("decode failed: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/proj/main.go", 20},
+		{"/proj/api/v1/api.pb.go", 30}, // The longest pattern wins
+		{"/proj/go.sum", 0},
+		{"/proj/tsconfig.JSON", -1},
+		{"/proj/deploy.yml", -1}, // Languages cover their extensions
+		{"/proj/tool.py", 8},
+		{"/proj/app.ts", 5},
+		{"/proj/events.jsonl", 5}, // Extensions match exactly
+	}
+	for _, tt := range tests {
+		if got := cfg.Diff.ContextLinesFor(tt.path); got != tt.want {
+			t.Errorf("ContextLinesFor(%q) = %d, want %d", tt.path, got, tt.want)
+		}
+	}
+
+	if got := DefaultConfig().Diff.ContextLinesFor("/proj/main.go"); got >= 0 {
+		t.Errorf("expected the default to show whole files, got %d lines", got)
+	}
+}
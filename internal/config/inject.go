@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// InjectConfig controls what the inject-context hook adds to prompts
+type InjectConfig struct {
+	MaxChars int          `toml:"max_chars"` // Size budget for the injected block; 0 is unlimited
+	Priority []string     `toml:"priority"`  // Sections kept first when over budget; empty uses the default order
+	Rules    []InjectRule `toml:"rules"`     // When sections are injected; sections without rules always are
+}
+
+// InjectRule injects context sections only for matching prompts or
+// workspaces. Empty fields match anything.
+type InjectRule struct {
+	Sections  []string `toml:"sections"`  // kubernetes, aws, git, iac, env, custom or profile
+	Prompt    string   `toml:"prompt"`    // Regexp matched against the prompt, case-insensitively
+	Workspace string   `toml:"workspace"` // Glob matched against the project root or its name
+}
+
+// DefaultInjectMaxChars keeps the injected context to a few hundred tokens
+const DefaultInjectMaxChars = 2000
+
+// Allows reports whether a section should be injected for a prompt in a
+// workspace: it has no rules, or one of its rules matches
+func (c InjectConfig) Allows(section, prompt, workspace string) bool {
+	ruled := false
+	for _, rule := range c.Rules {
+		if !rule.covers(section) {
+			continue
+		}
+		if rule.Matches(prompt, workspace) {
+			return true
+		}
+		ruled = true
+	}
+	return !ruled
+}
+
+// Matches reports whether a rule applies to a prompt in a workspace. An
+// invalid pattern never matches.
+func (r InjectRule) Matches(prompt, workspace string) bool {
+	if r.Prompt != "" {
+		re, err := regexp.Compile("(?i)" + r.Prompt)
+		if err != nil || !re.MatchString(prompt) {
+			return false
+		}
+	}
+	if r.Workspace != "" {
+		pattern := r.Workspace
+		if strings.HasPrefix(pattern, "~/") {
+			home, _ := os.UserHomeDir()
+			pattern = filepath.Join(home, pattern[2:])
+		}
+		full, _ := filepath.Match(pattern, workspace)
+		base, _ := filepath.Match(pattern, filepath.Base(workspace))
+		if !full && !base {
+			return false
+		}
+	}
+	return true
+}
+
+func (r InjectRule) covers(section string) bool {
+	for _, s := range r.Sections {
+		if s == section {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// UIState remembers where the TUI was left in each workspace, so restarting
+// it puts the user back where they were
+type UIState struct {
+	Workspaces map[string]WorkspaceState `json:"workspaces,omitempty"` // By workspace directory
+
+	path string
+}
+
+// WorkspaceState is the TUI's tab, layout and selection in one workspace
+type WorkspaceState struct {
+	Tab             string `json:"tab,omitempty"`               // e.g. "history", "prompts"
+	LeftPanePercent int    `json:"left_pane_percent,omitempty"` // 0 for the default third
+	HideMinimap     bool   `json:"hide_minimap,omitempty"`
+	HideLeftPane    bool   `json:"hide_left_pane,omitempty"`
+	PromptFilter    string `json:"prompt_filter,omitempty"`   // "project" or "global"; empty for all
+	SelectedChange  string `json:"selected_change,omitempty"` // History key of the selected change
+}
+
+// UIStatePath returns the path of the UI state file
+func UIStatePath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "claude-follow", "state.json")
+}
+
+// LoadUIState reads the UI state, returning empty state if the file doesn't
+// exist yet. A corrupt file also gives empty state, along with the error, and
+// is replaced on the next save.
+func LoadUIState(path string) (*UIState, error) {
+	s := &UIState{
+		Workspaces: make(map[string]WorkspaceState),
+		path:       path,
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		s.Workspaces = make(map[string]WorkspaceState)
+		return s, err
+	}
+	if s.Workspaces == nil {
+		s.Workspaces = make(map[string]WorkspaceState)
+	}
+	return s, nil
+}
+
+// Workspace returns the state saved for a workspace, zero if there's none
+func (s *UIState) Workspace(dir string) WorkspaceState {
+	return s.Workspaces[dir]
+}
+
+// SetWorkspace saves a workspace's state. The file is replaced atomically so
+// a crash mid-write can't leave it corrupt.
+func (s *UIState) SetWorkspace(dir string, ws WorkspaceState) error {
+	s.Workspaces[dir] = ws
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
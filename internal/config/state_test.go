@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUIState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "claude-follow", "state.json")
+	s, err := LoadUIState(path)
+	if err != nil {
+		t.Fatalf("expected a missing file to load as empty state: %v", err)
+	}
+	if ws := s.Workspace("/proj"); ws != (WorkspaceState{}) {
+		t.Errorf("expected no state for an unknown workspace, got %+v", ws)
+	}
+
+	want := WorkspaceState{Tab: "prompts", LeftPanePercent: 45, HideMinimap: true, PromptFilter: "global",
+		SelectedChange: "/proj/main.go:2025-06-01T12:00:00Z:4"}
+	if err := s.SetWorkspace("/proj", want); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	if err := s.SetWorkspace("/other", WorkspaceState{HideLeftPane: true}); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	reloaded, err := LoadUIState(path)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if got := reloaded.Workspace("/proj"); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if !reloaded.Workspace("/other").HideLeftPane {
+		t.Error("expected each workspace kept separately")
+	}
+}
+
+func TestUIStateCorrupt(t *testing.T) {
+	for name, data := range map[string]string{
+		"truncated":  `{"workspaces": {"/proj": {"tab": "pro`,
+		"wrong type": `{"workspaces": ["/proj"]}`,
+		"binary":     "\x00\x01\x02",
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "state.json")
+			if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			s, err := LoadUIState(path)
+			if err == nil {
+				t.Fatal("expected an error for a corrupt file")
+			}
+			if s == nil || len(s.Workspaces) != 0 {
+				t.Fatalf("expected empty state, got %+v", s)
+			}
+
+			// Saving replaces the corrupt file
+			if err := s.SetWorkspace("/proj", WorkspaceState{Tab: "plan"}); err != nil {
+				t.Fatalf("save failed: %v", err)
+			}
+			reloaded, err := LoadUIState(path)
+			if err != nil || reloaded.Workspace("/proj").Tab != "plan" {
+				t.Errorf("expected the saved state after a corrupt file, got %+v, %v", reloaded, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Warnings remembers which TUI warnings the user has snoozed or turned off,
+// by warning type, so acknowledged warnings don't come back every startup
+type Warnings struct {
+	Dismissed map[string]bool      `json:"dismissed,omitempty"`     // Never shown again
+	Snoozed   map[string]time.Time `json:"snoozed_until,omitempty"` // Hidden until the given time
+
+	path string
+}
+
+// WarningsPath returns the path of the warning state file
+func WarningsPath() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "claude-follow", "warnings.json")
+}
+
+// LoadWarnings reads the warning state, returning empty state if the file
+// doesn't exist yet
+func LoadWarnings(path string) (*Warnings, error) {
+	w := &Warnings{
+		Dismissed: make(map[string]bool),
+		Snoozed:   make(map[string]time.Time),
+		path:      path,
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return w, nil
+	}
+	if err != nil {
+		return w, err
+	}
+	if err := json.Unmarshal(data, w); err != nil {
+		return w, err
+	}
+	if w.Dismissed == nil {
+		w.Dismissed = make(map[string]bool)
+	}
+	if w.Snoozed == nil {
+		w.Snoozed = make(map[string]time.Time)
+	}
+	return w, nil
+}
+
+// Suppressed reports whether a warning type is dismissed or snoozed at now
+func (w *Warnings) Suppressed(kind string, now time.Time) bool {
+	return w.Dismissed[kind] || now.Before(w.Snoozed[kind])
+}
+
+// SnoozeToday hides a warning type until the next local midnight
+func (w *Warnings) SnoozeToday(kind string, now time.Time) error {
+	y, m, d := now.Date()
+	w.Snoozed[kind] = time.Date(y, m, d+1, 0, 0, 0, 0, now.Location())
+	return w.save()
+}
+
+// Dismiss stops showing a warning type
+func (w *Warnings) Dismiss(kind string) error {
+	w.Dismissed[kind] = true
+	delete(w.Snoozed, kind)
+	return w.save()
+}
+
+// save writes the state, dropping snoozes that have expired
+func (w *Warnings) save() error {
+	now := time.Now()
+	for kind, until := range w.Snoozed {
+		if !now.Before(until) {
+			delete(w.Snoozed, kind)
+		}
+	}
+
+	data, err := json.MarshalIndent(w, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(w.path, data, 0644)
+}
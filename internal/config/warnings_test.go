@@ -0,0 +1,47 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWarnings(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "warnings.json")
+	w, err := LoadWarnings(path)
+	if err != nil {
+		t.Fatalf("expected a missing file to load as empty state: %v", err)
+	}
+
+	y, m, d := time.Now().Date()
+	now := time.Date(y, m, d, 15, 9, 0, 0, time.Local)
+	if w.Suppressed("stale_context", now) {
+		t.Error("expected nothing suppressed initially")
+	}
+
+	// Snoozes last until midnight
+	if err := w.SnoozeToday("stale_context", now); err != nil {
+		t.Fatalf("snooze failed: %v", err)
+	}
+	if !w.Suppressed("stale_context", now.Add(8*time.Hour)) {
+		t.Error("expected the warning snoozed for the rest of the day")
+	}
+	if w.Suppressed("stale_context", now.Add(9*time.Hour)) {
+		t.Error("expected the snooze to end at midnight")
+	}
+
+	// Dismissals persist across loads
+	if err := w.Dismiss("daemon_disconnected"); err != nil {
+		t.Fatalf("dismiss failed: %v", err)
+	}
+	reloaded, err := LoadWarnings(path)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if !reloaded.Suppressed("daemon_disconnected", now.AddDate(1, 0, 0)) {
+		t.Error("expected the dismissal to persist")
+	}
+	if reloaded.Suppressed("daemon_outdated", now) {
+		t.Error("expected other warnings to stay visible")
+	}
+}
@@ -22,6 +22,21 @@ type Context struct {
 	ProjectRoot string                 `json:"project_root"`
 	Updated     string                 `json:"updated"`
 	Context     map[string]interface{} `json:"context"`
+
+	// Profile is the name of the profile last applied, if any
+	Profile string `json:"profile,omitempty"`
+
+	// Checked records when each section was last set or confirmed against
+	// the environment, and Stale why sections are no longer trusted
+	Checked map[string]string `json:"checked,omitempty"`
+	Stale   map[string]string `json:"stale,omitempty"`
+
+	// Revalidated is when the context was last checked against the environment
+	Revalidated string `json:"revalidated,omitempty"`
+
+	// Secrets flags env and custom keys holding secrets, as "env.KEY" or
+	// "custom.KEY"
+	Secrets map[string]Secret `json:"secrets,omitempty"`
 }
 
 // KubernetesContext represents Kubernetes-specific context
@@ -92,10 +107,19 @@ func Load() (*Context, error) {
 	return &ctx, nil
 }
 
+// PathFor returns the context file for a project root
+func PathFor(projectRoot string) string {
+	return filepath.Join(ContextsDir, getProjectID(projectRoot)+".json")
+}
+
 // Save saves the context with an updated timestamp
 func (c *Context) Save() error {
 	c.Updated = time.Now().UTC().Format(time.RFC3339)
+	return c.write()
+}
 
+// write writes the context file as it is
+func (c *Context) write() error {
 	// Ensure directory exists
 	if err := os.MkdirAll(ContextsDir, 0755); err != nil {
 		return fmt.Errorf("failed to create contexts directory: %w", err)
@@ -124,6 +148,7 @@ func (c *Context) SetKubernetes(context, namespace, kubeconfig string) {
 		Kubeconfig: kubeconfig,
 	}
 	c.Context["kubernetes"] = k8s
+	c.touch("kubernetes")
 }
 
 // GetKubernetes gets Kubernetes context
@@ -151,6 +176,7 @@ func (c *Context) SetAWS(profile, region string) {
 		Region:  region,
 	}
 	c.Context["aws"] = aws
+	c.touch("aws")
 }
 
 // GetAWS gets AWS context
@@ -179,13 +205,7 @@ func (c *Context) SetGit(branch, repo string) {
 	// Auto-detect repo if not provided
 	if repo == "" {
 		// Try to get repo name from remote URL
-		if url := runGitCommand("remote", "get-url", "origin"); url != "" {
-			// Extract repo name from URL
-			parts := strings.Split(url, "/")
-			if len(parts) > 0 {
-				repo = strings.TrimSuffix(parts[len(parts)-1], ".git")
-			}
-		}
+		repo = repoName(runGitCommand("remote", "get-url", "origin"))
 	}
 
 	if branch != "" || repo != "" {
@@ -194,6 +214,7 @@ func (c *Context) SetGit(branch, repo string) {
 			Repo:   repo,
 		}
 		c.Context["git"] = git
+		c.touch("git")
 	}
 }
 
@@ -220,6 +241,7 @@ func (c *Context) SetEnv(vars map[string]string) {
 		env[k] = v
 	}
 	c.Context["env"] = env
+	c.touch("env")
 }
 
 // GetEnv gets environment variables
@@ -248,6 +270,7 @@ func (c *Context) SetCustom(custom map[string]string) {
 		customMap[k] = v
 	}
 	c.Context["custom"] = customMap
+	c.touch("custom")
 }
 
 // GetCustom gets custom key-value pairs
@@ -277,16 +300,26 @@ func (c *Context) Clear(section string) {
 		"aws":        "aws",
 		"env":        "env",
 		"git":        "git",
+		"iac":        "iac",
+		"terraform":  "iac",
+		"pulumi":     "iac",
 		"custom":     "custom",
 	}
 
 	if section == "all" {
 		c.Context = make(map[string]interface{})
+		c.Profile = ""
+		c.Checked = nil
+		c.Stale = nil
+		c.Secrets = nil
 		return
 	}
 
 	if key, ok := sectionMap[section]; ok {
 		delete(c.Context, key)
+		delete(c.Checked, key)
+		delete(c.Stale, key)
+		c.clearSecrets(key)
 	}
 }
 
@@ -295,6 +328,9 @@ func (c *Context) Format() string {
 	var lines []string
 
 	lines = append(lines, fmt.Sprintf("  Project: %s", c.ProjectRoot))
+	if c.Profile != "" {
+		lines = append(lines, fmt.Sprintf("  Profile: %s", c.Profile))
+	}
 	lines = append(lines, "")
 
 	if len(c.Context) == 0 {
@@ -323,6 +359,11 @@ func (c *Context) Format() string {
 		lines = append(lines, fmt.Sprintf("  AWS: %s", awsStr))
 	}
 
+	// Terraform workspace / Pulumi stack
+	if iac := c.GetIaC(); iac != nil && iac.Workspace != "" {
+		lines = append(lines, fmt.Sprintf("  %s: %s", iac.Label(), iac.String()))
+	}
+
 	// Git
 	if git := c.GetGit(); git != nil {
 		gitStr := git.Branch
@@ -342,7 +383,7 @@ func (c *Context) Format() string {
 	if env := c.GetEnv(); env != nil && len(env) > 0 {
 		var envParts []string
 		for k, v := range env {
-			envParts = append(envParts, fmt.Sprintf("%s=%s", k, v))
+			envParts = append(envParts, fmt.Sprintf("%s=%s", k, c.DisplayValue("env", k, v)))
 		}
 		lines = append(lines, fmt.Sprintf("  Env: %s", strings.Join(envParts, ", ")))
 	}
@@ -350,7 +391,7 @@ func (c *Context) Format() string {
 	// Custom
 	if custom := c.GetCustom(); custom != nil && len(custom) > 0 {
 		for k, v := range custom {
-			lines = append(lines, fmt.Sprintf("  %s: %s", k, v))
+			lines = append(lines, fmt.Sprintf("  %s: %s", k, c.DisplayValue("custom", k, v)))
 		}
 	}
 
@@ -389,8 +430,13 @@ func (c *Context) GetAge() string {
 	}
 }
 
-// IsStale returns true if context is older than 24 hours
+// IsStale returns true if any section is flagged stale or, when the daemon
+// hasn't revalidated the context in the last day, if it's older than 24 hours
 func (c *Context) IsStale() bool {
+	if revalidated, err := time.Parse(time.RFC3339, c.Revalidated); err == nil && time.Since(revalidated) < DefaultTTL {
+		return len(c.Stale) > 0
+	}
+
 	if c.Updated == "" {
 		return true
 	}
@@ -473,6 +519,15 @@ func runGitCommand(args ...string) string {
 	return strings.TrimSpace(string(output))
 }
 
+// repoName extracts the repository name from a remote URL
+func repoName(url string) string {
+	if url == "" {
+		return ""
+	}
+	parts := strings.Split(url, "/")
+	return strings.TrimSuffix(parts[len(parts)-1], ".git")
+}
+
 func getString(m map[string]interface{}, key string) string {
 	if val, ok := m[key]; ok {
 		if str, ok := val.(string); ok {
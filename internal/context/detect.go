@@ -0,0 +1,160 @@
+package context
+
+import (
+	gocontext "context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// detectTimeout bounds each kubectl call, which may reach for a cluster
+const detectTimeout = 3 * time.Second
+
+// Detected is context read from the environment: kubectl's current context,
+// the AWS_* variables, the git checkout and the Terraform workspace or
+// Pulumi stack. Sections not found are nil.
+type Detected struct {
+	Kubernetes *KubernetesContext
+	AWS        *AWSContext
+	Git        *GitContext
+	IaC        *IaCContext
+}
+
+// Detect reads the current kubectl context and namespace, AWS_PROFILE and
+// AWS_REGION (or their AWS_DEFAULT_ forms), the git branch and remote of
+// the working directory and its Terraform workspace or Pulumi stack
+func Detect() *Detected {
+	d := &Detected{}
+
+	if kubeContext := runKubectl("config", "current-context"); kubeContext != "" {
+		d.Kubernetes = &KubernetesContext{
+			Context:   kubeContext,
+			Namespace: runKubectl("config", "view", "--minify", "-o", "jsonpath={..namespace}"),
+		}
+		// Only a single kubeconfig file can be stored; a KUBECONFIG list is
+		// left for kubectl to merge
+		if kubeconfig := os.Getenv("KUBECONFIG"); kubeconfig != "" && !strings.Contains(kubeconfig, string(filepath.ListSeparator)) {
+			d.Kubernetes.Kubeconfig = kubeconfig
+		}
+	}
+
+	profile := firstEnv("AWS_PROFILE", "AWS_DEFAULT_PROFILE")
+	region := firstEnv("AWS_REGION", "AWS_DEFAULT_REGION")
+	if profile != "" || region != "" {
+		d.AWS = &AWSContext{Profile: profile, Region: region}
+	}
+
+	branch := runGitCommand("rev-parse", "--abbrev-ref", "HEAD")
+	repo := repoName(runGitCommand("remote", "get-url", "origin"))
+	if branch != "" || repo != "" {
+		d.Git = &GitContext{Branch: branch, Repo: repo}
+	}
+
+	if cwd, err := os.Getwd(); err == nil {
+		if iac := DetectIaC(cwd); iac != nil {
+			// Remember where the project is when it's below the root
+			if rel, err := filepath.Rel(getProjectRoot(), cwd); err == nil && rel != "." && !strings.HasPrefix(rel, "..") {
+				iac.Dir = rel
+			}
+			d.IaC = iac
+		}
+	}
+
+	return d
+}
+
+// Empty reports whether nothing was detected
+func (d *Detected) Empty() bool {
+	return d.Kubernetes == nil && d.AWS == nil && d.Git == nil && d.IaC == nil
+}
+
+// Lines describes what was detected, one section per line
+func (d *Detected) Lines() []string {
+	var lines []string
+	if k8s := d.Kubernetes; k8s != nil {
+		k8sStr := k8s.Context
+		if k8s.Namespace != "" {
+			k8sStr += fmt.Sprintf(" / %s", k8s.Namespace)
+		}
+		if k8s.Kubeconfig != "" {
+			k8sStr += fmt.Sprintf(" (kubeconfig: %s)", k8s.Kubeconfig)
+		}
+		lines = append(lines, fmt.Sprintf("Kubernetes: %s", k8sStr))
+	}
+	if aws := d.AWS; aws != nil {
+		awsStr := aws.Profile
+		if awsStr == "" {
+			awsStr = "default"
+		}
+		if aws.Region != "" {
+			awsStr += fmt.Sprintf(" (%s)", aws.Region)
+		}
+		lines = append(lines, fmt.Sprintf("AWS: %s", awsStr))
+	}
+	if git := d.Git; git != nil {
+		gitStr := git.Branch
+		if git.Repo != "" {
+			if gitStr != "" {
+				gitStr = fmt.Sprintf("%s @ %s", gitStr, git.Repo)
+			} else {
+				gitStr = git.Repo
+			}
+		}
+		lines = append(lines, fmt.Sprintf("Git: %s", gitStr))
+	}
+	if iac := d.IaC; iac != nil {
+		lines = append(lines, fmt.Sprintf("%s: %s", iac.Label(), iac.String()))
+	}
+	return lines
+}
+
+// Apply sets the detected sections on the context, leaving the others as
+// they are. The context no longer matches a profile once anything is set.
+func (d *Detected) Apply(c *Context) {
+	if c.Context == nil {
+		c.Context = make(map[string]interface{})
+	}
+	if d.Kubernetes != nil {
+		c.Context["kubernetes"] = *d.Kubernetes
+		c.touch("kubernetes")
+	}
+	if d.AWS != nil {
+		c.Context["aws"] = *d.AWS
+		c.touch("aws")
+	}
+	if d.Git != nil {
+		c.Context["git"] = *d.Git
+		c.touch("git")
+	}
+	if d.IaC != nil {
+		c.Context["iac"] = *d.IaC
+		c.touch("iac")
+	}
+	if !d.Empty() {
+		c.Profile = ""
+	}
+}
+
+// runKubectl runs kubectl, returning its trimmed output or "" on failure
+func runKubectl(args ...string) string {
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), detectTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, "kubectl", args...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// firstEnv returns the first of the environment variables that is set
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if val := os.Getenv(name); val != "" {
+			return val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,209 @@
+package context
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Infrastructure-as-code tools
+const (
+	IaCTerraform = "terraform"
+	IaCPulumi    = "pulumi"
+)
+
+// IaCContext records the active Terraform workspace or Pulumi stack
+type IaCContext struct {
+	Tool      string `json:"tool,omitempty"`      // "terraform" or "pulumi"
+	Workspace string `json:"workspace,omitempty"` // Terraform workspace or Pulumi stack
+	Dir       string `json:"dir,omitempty"`       // Directory of the Terraform/Pulumi project, relative to the project root
+}
+
+// Label names the tool, e.g. "Terraform"
+func (i *IaCContext) Label() string {
+	if i.Tool == IaCPulumi {
+		return "Pulumi"
+	}
+	return "Terraform"
+}
+
+// Kind names what the tool selects: "workspace" or "stack"
+func (i *IaCContext) Kind() string {
+	if i.Tool == IaCPulumi {
+		return "stack"
+	}
+	return "workspace"
+}
+
+// String describes the workspace, e.g. "prod (infra/aws)"
+func (i *IaCContext) String() string {
+	if i.Dir != "" && i.Dir != "." {
+		return fmt.Sprintf("%s (%s)", i.Workspace, i.Dir)
+	}
+	return i.Workspace
+}
+
+// SetIaC sets the Terraform workspace or Pulumi stack. An empty tool is
+// detected from the files in the project root.
+func (c *Context) SetIaC(tool, workspace, dir string) {
+	if tool == "" {
+		tool = IaCTool(filepath.Join(c.ProjectRoot, dir))
+	}
+	c.Context["iac"] = IaCContext{
+		Tool:      tool,
+		Workspace: workspace,
+		Dir:       dir,
+	}
+	c.touch("iac")
+}
+
+// GetIaC gets the Terraform workspace or Pulumi stack
+func (c *Context) GetIaC() *IaCContext {
+	if val, ok := c.Context["iac"]; ok {
+		switch v := val.(type) {
+		case map[string]interface{}:
+			return &IaCContext{
+				Tool:      getString(v, "tool"),
+				Workspace: getString(v, "workspace"),
+				Dir:       getString(v, "dir"),
+			}
+		case IaCContext:
+			return &v
+		}
+	}
+	return nil
+}
+
+// DetectIaC reads the selected Terraform workspace (TF_WORKSPACE, else
+// .terraform/environment) or Pulumi stack (pulumi stack --show-name) of the
+// project in dir. Returns nil if dir isn't a Terraform or Pulumi project.
+func DetectIaC(dir string) *IaCContext {
+	switch IaCTool(dir) {
+	case IaCPulumi:
+		if stack := runPulumi(dir, "stack", "--show-name"); stack != "" {
+			return &IaCContext{Tool: IaCPulumi, Workspace: stack}
+		}
+	case IaCTerraform:
+		workspace := os.Getenv("TF_WORKSPACE")
+		if workspace == "" {
+			data, err := os.ReadFile(filepath.Join(dir, ".terraform", "environment"))
+			workspace = strings.TrimSpace(string(data))
+			if err != nil {
+				// Initialized but never switched
+				workspace = "default"
+			}
+		}
+		return &IaCContext{Tool: IaCTerraform, Workspace: workspace}
+	}
+	return nil
+}
+
+// IaCWorkspaces lists the Terraform workspaces or Pulumi stacks of the
+// project in dir
+func IaCWorkspaces(tool, dir string) ([]string, error) {
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), detectTimeout)
+	defer cancel()
+	return ListIaCWorkspaces(ctx, execRunner{}, tool, dir)
+}
+
+// Runner runs a command in dir and returns its standard output. The TUI's
+// command runner satisfies it.
+type Runner interface {
+	Output(ctx gocontext.Context, dir, name string, args ...string) ([]byte, error)
+}
+
+// ListIaCWorkspaces is IaCWorkspaces running terraform or pulumi through r
+func ListIaCWorkspaces(ctx gocontext.Context, r Runner, tool, dir string) ([]string, error) {
+	if tool == IaCPulumi {
+		output, err := r.Output(ctx, dir, "pulumi", "stack", "ls", "--json")
+		var stacks []struct {
+			Name string `json:"name"`
+		}
+		if err != nil || json.Unmarshal(output, &stacks) != nil {
+			return nil, fmt.Errorf("failed to list pulumi stacks")
+		}
+		var names []string
+		for _, s := range stacks {
+			names = append(names, s.Name)
+		}
+		return names, nil
+	}
+
+	output, err := r.Output(ctx, dir, "terraform", "workspace", "list")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list terraform workspaces: %w", err)
+	}
+	var names []string
+	for _, line := range strings.Split(string(output), "\n") {
+		// The current workspace is marked "* name"
+		if name := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "*")); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// IaCTool returns the tool the project in dir uses: Pulumi if it has a
+// Pulumi.yaml, Terraform if it has been initialized or has .tf files
+func IaCTool(dir string) string {
+	return IaCToolFS(osFS{}, dir)
+}
+
+// FS is the filesystem IaCToolFS looks in. The TUI's filesystem satisfies
+// it.
+type FS interface {
+	Stat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+}
+
+// IaCToolFS is IaCTool looking through fsys
+func IaCToolFS(fsys FS, dir string) string {
+	for _, name := range []string{"Pulumi.yaml", "Pulumi.yml"} {
+		if _, err := fsys.Stat(filepath.Join(dir, name)); err == nil {
+			return IaCPulumi
+		}
+	}
+	if _, err := fsys.Stat(filepath.Join(dir, ".terraform")); err == nil {
+		return IaCTerraform
+	}
+	entries, _ := fsys.ReadDir(dir)
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".tf") {
+			return IaCTerraform
+		}
+	}
+	return ""
+}
+
+// runPulumi runs pulumi in dir, returning its trimmed output or "" on failure
+func runPulumi(dir string, args ...string) string {
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), detectTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "pulumi", args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// osFS is the real filesystem
+type osFS struct{}
+
+func (osFS) Stat(name string) (fs.FileInfo, error)      { return os.Stat(name) }
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) { return os.ReadDir(name) }
+
+// execRunner runs commands with os/exec
+type execRunner struct{}
+
+func (execRunner) Output(ctx gocontext.Context, dir, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	return cmd.Output()
+}
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strings"
+
+	"github.com/ztaylor/claude-mon/internal/config"
 )
 
 // HookPayload represents the UserPromptSubmit hook payload
@@ -52,8 +55,14 @@ func InjectForHook() error {
 		return json.NewEncoder(os.Stdout).Encode(result)
 	}
 
-	// Format the context block
-	contextBlock := ctx.FormatForInjection()
+	// Format the context block, keeping the sections the config's rules
+	// allow for this prompt within its size budget
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "inject-context: invalid config, using defaults: %v\n", err)
+		cfg = config.DefaultConfig()
+	}
+	contextBlock := ctx.FormatForPrompt(payload.Prompt, cfg.Inject)
 	if contextBlock == "" {
 		// Empty context, pass through
 		result := HookResult{Continue: true}
@@ -69,14 +78,55 @@ func InjectForHook() error {
 	return json.NewEncoder(os.Stdout).Encode(result)
 }
 
-// FormatForInjection formats the context as a <working-context> block for prompt injection.
+// DefaultInjectPriority is the order sections are kept in when the injected
+// context is over budget: what identifies the environment first, long
+// key/value lists last
+var DefaultInjectPriority = []string{"profile", "git", "kubernetes", "aws", "iac", "custom", "env"}
+
+// injectLine is one section's line of the injected context. Env and custom
+// lines are built from parts so they can be cut down to fit a budget.
+type injectLine struct {
+	section string
+	label   string
+	parts   []string
+	note    string
+}
+
+// render renders the line with its first n parts
+func (l injectLine) render(n int) string {
+	parts := l.parts[:n]
+	if n < len(l.parts) {
+		parts = append(parts[:n:n], fmt.Sprintf("(+%d more)", len(l.parts)-n))
+	}
+	return fmt.Sprintf("  %s: %s%s\n", l.label, strings.Join(parts, ", "), l.note)
+}
+
+// FormatForInjection formats the whole context as a <working-context> block for prompt injection.
 // This is similar to Format() but uses the specific XML-like format expected by Claude.
 func (c *Context) FormatForInjection() string {
+	return c.FormatForPrompt("", config.InjectConfig{})
+}
+
+// FormatForPrompt formats the context as a <working-context> block for a
+// prompt, keeping only the sections the config's rules allow for it. Over the
+// config's size budget, sections are kept in priority order and env/custom
+// values are cut short.
+func (c *Context) FormatForPrompt(prompt string, cfg config.InjectConfig) string {
 	if len(c.Context) == 0 {
 		return ""
 	}
 
-	var lines []string
+	var lines []injectLine
+	add := func(section, label string, parts ...string) {
+		if len(parts) > 0 && cfg.Allows(section, prompt, c.ProjectRoot) {
+			lines = append(lines, injectLine{section: section, label: label, parts: parts, note: c.staleNote(section)})
+		}
+	}
+
+	// Active profile, so Claude knows which environment it's working in
+	if c.Profile != "" {
+		add("profile", "Profile", c.Profile)
+	}
 
 	// Kubernetes
 	if k8s := c.GetKubernetes(); k8s != nil {
@@ -90,7 +140,7 @@ func (c *Context) FormatForInjection() string {
 		if k8s.Kubeconfig != "" {
 			k8sStr += fmt.Sprintf(" (kubeconfig: %s)", k8s.Kubeconfig)
 		}
-		lines = append(lines, fmt.Sprintf("Kubernetes: %s", k8sStr))
+		add("kubernetes", "Kubernetes", k8sStr)
 	}
 
 	// AWS
@@ -102,7 +152,12 @@ func (c *Context) FormatForInjection() string {
 		if aws.Region != "" {
 			awsStr += fmt.Sprintf(" (%s)", aws.Region)
 		}
-		lines = append(lines, fmt.Sprintf("AWS Profile: %s", awsStr))
+		add("aws", "AWS Profile", awsStr)
+	}
+
+	// Terraform workspace / Pulumi stack, so commands target the right one
+	if iac := c.GetIaC(); iac != nil && iac.Workspace != "" {
+		add("iac", iac.Label()+" "+iac.Kind(), iac.String())
 	}
 
 	// Git
@@ -116,49 +171,112 @@ func (c *Context) FormatForInjection() string {
 			}
 		}
 		if gitStr != "" {
-			lines = append(lines, fmt.Sprintf("Git: %s", gitStr))
+			add("git", "Git", gitStr)
 		}
 	}
 
-	// Environment variables
-	if env := c.GetEnv(); env != nil && len(env) > 0 {
-		var envParts []string
-		for k, v := range env {
-			envParts = append(envParts, fmt.Sprintf("%s=%s", k, v))
-		}
-		lines = append(lines, fmt.Sprintf("Env: %s", strings.Join(envParts, ", ")))
+	// Environment variables and custom values (secrets only when opted in)
+	add("env", "Env", c.injectParts("env", c.GetEnv())...)
+	add("custom", "Custom", c.injectParts("custom", c.GetCustom())...)
+
+	if len(lines) == 0 {
+		return ""
 	}
 
-	// Custom values
-	if custom := c.GetCustom(); custom != nil && len(custom) > 0 {
-		var customParts []string
-		for k, v := range custom {
-			customParts = append(customParts, fmt.Sprintf("%s=%s", k, v))
+	// Add age with stale warning
+	updated := ""
+	if c.Updated != "" {
+		staleWarning := ""
+		if c.IsStale() {
+			staleWarning = " (STALE - consider updating)"
 		}
-		lines = append(lines, fmt.Sprintf("Custom: %s", strings.Join(customParts, ", ")))
+		updated = fmt.Sprintf("  Updated: %s%s\n", c.GetAge(), staleWarning)
 	}
 
-	if len(lines) == 0 {
+	const openTag, closeTag = "<working-context>\n", "</working-context>"
+	rendered := fitInjectLines(lines, cfg, cfg.MaxChars-len(openTag)-len(closeTag)-len(updated))
+	if rendered == nil {
 		return ""
 	}
 
 	// Build the context block
 	var block strings.Builder
-	block.WriteString("<working-context>\n")
-	for _, line := range lines {
-		block.WriteString(fmt.Sprintf("  %s\n", line))
+	block.WriteString(openTag)
+	for _, line := range rendered {
+		block.WriteString(line)
 	}
+	block.WriteString(updated)
+	block.WriteString(closeTag)
+	return block.String()
+}
 
-	// Add age with stale warning
-	if c.Updated != "" {
-		age := c.GetAge()
-		staleWarning := ""
-		if c.IsStale() {
-			staleWarning = " (STALE - consider updating)"
+// injectParts returns a section's values as sorted KEY=value parts, leaving
+// out secrets that aren't opted in
+func (c *Context) injectParts(section string, values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		if v, ok := c.injectValue(section, k, values[k]); ok {
+			parts = append(parts, fmt.Sprintf("%s=%s", k, v))
 		}
-		block.WriteString(fmt.Sprintf("  Updated: %s%s\n", age, staleWarning))
 	}
+	return parts
+}
 
-	block.WriteString("</working-context>")
-	return block.String()
+// fitInjectLines renders the lines within budget characters (unlimited when
+// the config has no budget), keeping sections in priority order and cutting
+// long value lists short. Lines keep their original order; those that don't
+// fit at all are left out.
+func fitInjectLines(lines []injectLine, cfg config.InjectConfig, budget int) []string {
+	rendered := make([]string, len(lines))
+	if cfg.MaxChars <= 0 {
+		for i, line := range lines {
+			rendered[i] = line.render(len(line.parts))
+		}
+		return rendered
+	}
+
+	priority := cfg.Priority
+	if len(priority) == 0 {
+		priority = DefaultInjectPriority
+	}
+	rank := func(section string) int {
+		for i, s := range priority {
+			if s == section {
+				return i
+			}
+		}
+		return len(priority)
+	}
+	order := make([]int, len(lines))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return rank(lines[order[a]].section) < rank(lines[order[b]].section)
+	})
+
+	for _, i := range order {
+		line := lines[i]
+		for n := len(line.parts); n > 0; n-- {
+			if text := line.render(n); len(text) <= budget {
+				rendered[i] = text
+				budget -= len(text)
+				break
+			}
+		}
+	}
+
+	var kept []string
+	for _, text := range rendered {
+		if text != "" {
+			kept = append(kept, text)
+		}
+	}
+	return kept
 }
@@ -0,0 +1,201 @@
+package context
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// profileSections are the context sections a profile holds; custom values
+// stay with the project
+var profileSections = []string{"kubernetes", "aws", "git", "iac", "env"}
+
+// profileNamePattern restricts profile names to ones that are safe file names
+var profileNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Profile is a named set of Kubernetes, AWS, git, Terraform/Pulumi and env
+// context (e.g. "staging" or "prod-readonly") that can be applied to any
+// project
+type Profile struct {
+	Name    string                 `json:"name"`
+	Updated string                 `json:"updated"`
+	Context map[string]interface{} `json:"context"`
+
+	// Secrets flags the env keys holding secrets, as in Context
+	Secrets map[string]Secret `json:"secrets,omitempty"`
+}
+
+// ProfilesDir returns where profiles are stored
+func ProfilesDir() string {
+	return filepath.Join(ContextsDir, "profiles")
+}
+
+// ListProfiles returns all profiles, sorted by name
+func ListProfiles() ([]*Profile, error) {
+	files, err := filepath.Glob(filepath.Join(ProfilesDir(), "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []*Profile
+	for _, file := range files {
+		p, err := readProfile(file)
+		if err != nil {
+			continue
+		}
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles, nil
+}
+
+// LoadProfile loads a profile by name
+func LoadProfile(name string) (*Profile, error) {
+	if !profileNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid profile name %q", name)
+	}
+	p, err := readProfile(filepath.Join(ProfilesDir(), name+".json"))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no profile named %q", name)
+	}
+	return p, err
+}
+
+// SaveProfile saves the context's Kubernetes, AWS, git, Terraform/Pulumi and
+// env sections as a profile, replacing any profile of the same name
+func SaveProfile(name string, c *Context) (*Profile, error) {
+	if !profileNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid profile name %q: use letters, digits, '.', '_' and '-'", name)
+	}
+
+	p := &Profile{
+		Name:    name,
+		Updated: time.Now().UTC().Format(time.RFC3339),
+		Context: make(map[string]interface{}),
+	}
+	for _, section := range profileSections {
+		if val, ok := c.Context[section]; ok {
+			p.Context[section] = val
+		}
+	}
+	for name, secret := range c.Secrets {
+		if strings.HasPrefix(name, "env.") {
+			if p.Secrets == nil {
+				p.Secrets = make(map[string]Secret)
+			}
+			p.Secrets[name] = secret
+		}
+	}
+	if len(p.Context) == 0 {
+		return nil, fmt.Errorf("no Kubernetes, AWS, git, Terraform/Pulumi or env context to save")
+	}
+
+	if err := os.MkdirAll(ProfilesDir(), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create profiles directory: %w", err)
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal profile: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(ProfilesDir(), name+".json"), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write profile: %w", err)
+	}
+	return p, nil
+}
+
+// DeleteProfile removes a profile. Projects using it keep their context.
+func DeleteProfile(name string) error {
+	if !profileNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+	if err := os.Remove(filepath.Join(ProfilesDir(), name+".json")); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no profile named %q", name)
+		}
+		return fmt.Errorf("failed to delete profile: %w", err)
+	}
+	return nil
+}
+
+// ApplyProfile replaces the context's Kubernetes, AWS, git, Terraform/Pulumi
+// and env sections with the profile's and marks the profile active. Sections
+// the profile doesn't set are cleared, so nothing from the previous profile
+// lingers.
+func (c *Context) ApplyProfile(p *Profile) {
+	if c.Context == nil {
+		c.Context = make(map[string]interface{})
+	}
+	for _, section := range profileSections {
+		if val, ok := p.Context[section]; ok {
+			c.Context[section] = val
+			c.touch(section)
+		} else {
+			delete(c.Context, section)
+			delete(c.Checked, section)
+			delete(c.Stale, section)
+		}
+	}
+	c.clearSecrets("env")
+	for name, secret := range p.Secrets {
+		if c.Secrets == nil {
+			c.Secrets = make(map[string]Secret)
+		}
+		c.Secrets[name] = secret
+	}
+	c.Profile = p.Name
+}
+
+// Summary describes the profile on one line, e.g. "k8s prod / api, aws prod-ro"
+func (p *Profile) Summary() string {
+	ctx := &Context{Context: p.Context}
+	var parts []string
+	if k8s := ctx.GetKubernetes(); k8s != nil {
+		k8sStr := "k8s " + k8s.Context
+		if k8s.Namespace != "" {
+			k8sStr += " / " + k8s.Namespace
+		}
+		parts = append(parts, k8sStr)
+	}
+	if aws := ctx.GetAWS(); aws != nil {
+		awsStr := "aws " + aws.Profile
+		if aws.Region != "" {
+			awsStr += " (" + aws.Region + ")"
+		}
+		parts = append(parts, awsStr)
+	}
+	if git := ctx.GetGit(); git != nil && git.Branch != "" {
+		parts = append(parts, "git "+git.Branch)
+	}
+	if iac := ctx.GetIaC(); iac != nil && iac.Workspace != "" {
+		parts = append(parts, iac.Tool+" "+iac.Workspace)
+	}
+	if env := ctx.GetEnv(); len(env) > 0 {
+		parts = append(parts, fmt.Sprintf("%d env", len(env)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func readProfile(file string) (*Profile, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var p Profile
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("invalid profile %s: %w", filepath.Base(file), err)
+	}
+	if p.Name == "" {
+		p.Name = strings.TrimSuffix(filepath.Base(file), ".json")
+	}
+	if p.Context == nil {
+		p.Context = make(map[string]interface{})
+	}
+	return &p, nil
+}
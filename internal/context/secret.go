@@ -0,0 +1,215 @@
+package context
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// secretPrefix marks a value stored encrypted
+const secretPrefix = "enc:v1:"
+
+// SecretMask is shown in place of a secret value
+const SecretMask = "••••••"
+
+// Secret flags an env or custom key whose value is secret. Secret values are
+// stored encrypted, shown masked and left out of the injected context unless
+// Inject opts them in.
+type Secret struct {
+	Inject bool `json:"inject,omitempty"`
+}
+
+// SecretKeyPath returns the file holding the key secret values are encrypted
+// with. It's created on first use, readable only by the user.
+func SecretKeyPath() string {
+	return filepath.Join(ContextsDir, ".secret.key")
+}
+
+// secretName is the key a section's value is flagged under in Secrets
+func secretName(section, key string) string {
+	return section + "." + key
+}
+
+// IsSecret reports whether a section's key holds a secret
+func (c *Context) IsSecret(section, key string) bool {
+	_, ok := c.Secrets[secretName(section, key)]
+	return ok
+}
+
+// SecretFor returns a secret key's flags, or nil if the key isn't secret
+func (c *Context) SecretFor(section, key string) *Secret {
+	if s, ok := c.Secrets[secretName(section, key)]; ok {
+		return &s
+	}
+	return nil
+}
+
+// SetValue sets an env or custom value. A non-nil secret stores the value
+// encrypted and flags it; nil stores it in plain text.
+func (c *Context) SetValue(section, key, value string, secret *Secret) error {
+	var values map[string]string
+	switch section {
+	case "env":
+		values = c.GetEnv()
+	case "custom":
+		values = c.GetCustom()
+	default:
+		return fmt.Errorf("%s values can't be set by key", section)
+	}
+	if values == nil {
+		values = make(map[string]string)
+	}
+
+	name := secretName(section, key)
+	if secret != nil {
+		encrypted, err := encryptSecret(value)
+		if err != nil {
+			return err
+		}
+		value = encrypted
+		if c.Secrets == nil {
+			c.Secrets = make(map[string]Secret)
+		}
+		c.Secrets[name] = *secret
+	} else {
+		delete(c.Secrets, name)
+	}
+	values[key] = value
+
+	if section == "env" {
+		c.SetEnv(values)
+	} else {
+		c.SetCustom(values)
+	}
+	return nil
+}
+
+// SecretValue returns a secret key's decrypted value
+func (c *Context) SecretValue(section, key string) (string, error) {
+	var value string
+	switch section {
+	case "env":
+		value = c.GetEnv()[key]
+	case "custom":
+		value = c.GetCustom()[key]
+	}
+	return decryptSecret(value)
+}
+
+// DisplayValue returns a value as it should be shown: masked if it's secret
+func (c *Context) DisplayValue(section, key, value string) string {
+	if c.IsSecret(section, key) {
+		return SecretMask
+	}
+	return value
+}
+
+// injectValue returns a value for the injected context. Secrets are left out
+// unless opted in, and then decrypted.
+func (c *Context) injectValue(section, key, value string) (string, bool) {
+	secret := c.SecretFor(section, key)
+	if secret == nil {
+		return value, true
+	}
+	if !secret.Inject {
+		return "", false
+	}
+	plain, err := decryptSecret(value)
+	if err != nil {
+		return "", false
+	}
+	return plain, true
+}
+
+// clearSecrets drops the secret flags of a section's keys
+func (c *Context) clearSecrets(section string) {
+	for name := range c.Secrets {
+		if strings.HasPrefix(name, section+".") {
+			delete(c.Secrets, name)
+		}
+	}
+}
+
+// secretKey loads the encryption key, creating it when asked to
+func secretKey(create bool) ([]byte, error) {
+	data, err := os.ReadFile(SecretKeyPath())
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("invalid secret key in %s", SecretKeyPath())
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) || !create {
+		return nil, fmt.Errorf("failed to read secret key: %w", err)
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate secret key: %w", err)
+	}
+	if err := os.MkdirAll(ContextsDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create contexts directory: %w", err)
+	}
+	if err := os.WriteFile(SecretKeyPath(), []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write secret key: %w", err)
+	}
+	return key, nil
+}
+
+// secretCipher returns an AES-GCM cipher for the secret key
+func secretCipher(create bool) (cipher.AEAD, error) {
+	key, err := secretKey(create)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encryptSecret encrypts a value for storage
+func encryptSecret(plain string) (string, error) {
+	gcm, err := secretCipher(true)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to encrypt secret: %w", err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plain), nil)
+	return secretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptSecret decrypts a stored value. Values stored before the key was
+// marked secret are returned as they are.
+func decryptSecret(stored string) (string, error) {
+	if !strings.HasPrefix(stored, secretPrefix) {
+		return stored, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(stored, secretPrefix))
+	if err != nil {
+		return "", fmt.Errorf("invalid secret value: %w", err)
+	}
+	gcm, err := secretCipher(false)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("invalid secret value")
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret (was it encrypted with another key?)")
+	}
+	return string(plain), nil
+}
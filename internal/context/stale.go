@@ -0,0 +1,198 @@
+package context
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultTTL is how long a context value is trusted without being confirmed
+const DefaultTTL = 24 * time.Hour
+
+// touch records that a section was just set, clearing any stale flag
+func (c *Context) touch(section string) {
+	if c.Checked == nil {
+		c.Checked = make(map[string]string)
+	}
+	c.Checked[section] = time.Now().UTC().Format(time.RFC3339)
+	delete(c.Stale, section)
+}
+
+// checkedAt returns when a section was last set or confirmed, falling back
+// to when the context was last saved
+func (c *Context) checkedAt(section string) time.Time {
+	if t, err := time.Parse(time.RFC3339, c.Checked[section]); err == nil {
+		return t
+	}
+	t, _ := time.Parse(time.RFC3339, c.Updated)
+	return t
+}
+
+// StaleReason returns why a section is flagged stale, or "" if it isn't
+func (c *Context) StaleReason(section string) string {
+	return c.Stale[section]
+}
+
+// staleNote returns a note for the injected context when a section is
+// flagged stale
+func (c *Context) staleNote(section string) string {
+	if reason := c.Stale[section]; reason != "" {
+		return fmt.Sprintf(" (STALE: %s)", reason)
+	}
+	return ""
+}
+
+// Revalidate checks the context against the environment. Git branches follow
+// the checkout and Terraform workspaces and Pulumi stacks the project's
+// selection, so changed ones are refreshed. A kube context that differs
+// from kubectl's may be deliberate (a profile, say), so it's flagged stale
+// rather than replaced. Sections that can't be checked are flagged once they
+// go unconfirmed for longer than ttl. Returns the sections refreshed.
+func (c *Context) Revalidate(ttl time.Duration) []string {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	var refreshed []string
+	stale := make(map[string]string)
+
+	for section := range c.Context {
+		switch section {
+		case "git":
+			git := c.GetGit()
+			branch := runGitIn(c.ProjectRoot, "rev-parse", "--abbrev-ref", "HEAD")
+			if git == nil || branch == "" || branch == "HEAD" {
+				break
+			}
+			if git.Branch != branch {
+				c.Context["git"] = GitContext{Branch: branch, Repo: git.Repo}
+				refreshed = append(refreshed, "git")
+			}
+			c.touch("git")
+			continue
+		case "iac":
+			iac := c.GetIaC()
+			if iac == nil || c.ProjectRoot == "" {
+				break
+			}
+			current := DetectIaC(filepath.Join(c.ProjectRoot, iac.Dir))
+			if current == nil || current.Tool != iac.Tool {
+				break
+			}
+			if current.Workspace != iac.Workspace {
+				c.Context["iac"] = IaCContext{Tool: iac.Tool, Workspace: current.Workspace, Dir: iac.Dir}
+				refreshed = append(refreshed, "iac")
+			}
+			c.touch("iac")
+			continue
+		case "kubernetes":
+			k8s := c.GetKubernetes()
+			if k8s == nil || k8s.Context == "" {
+				break
+			}
+			args := []string{"config", "current-context"}
+			if k8s.Kubeconfig != "" {
+				args = append([]string{"--kubeconfig", expandHome(k8s.Kubeconfig)}, args...)
+			}
+			current := runKubectl(args...)
+			if current == "" {
+				break
+			}
+			if current != k8s.Context {
+				stale["kubernetes"] = fmt.Sprintf("kubectl is on %s", current)
+				continue
+			}
+			c.touch("kubernetes")
+			continue
+		}
+
+		// Not checkable (or the check failed): trust it until the TTL runs out
+		if age := time.Since(c.checkedAt(section)); age > ttl {
+			stale[section] = fmt.Sprintf("unconfirmed for %s", formatAge(age))
+		}
+	}
+
+	c.Stale = stale
+	c.Revalidated = time.Now().UTC().Format(time.RFC3339)
+	return refreshed
+}
+
+// Refresh revalidates the context and writes it back. The updated time only
+// moves when a value was refreshed.
+func (c *Context) Refresh(ttl time.Duration) ([]string, error) {
+	refreshed := c.Revalidate(ttl)
+	if len(refreshed) > 0 {
+		return refreshed, c.Save()
+	}
+	return nil, c.write()
+}
+
+// RefreshAll revalidates every project context, returning the project roots
+// with refreshed values
+func RefreshAll(ttl time.Duration) (map[string][]string, error) {
+	files, err := filepath.Glob(filepath.Join(ContextsDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string][]string)
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var ctx Context
+		if err := json.Unmarshal(data, &ctx); err != nil || ctx.ProjectID == "" {
+			continue
+		}
+		if _, err := os.Stat(ctx.ProjectRoot); err != nil {
+			// The project is gone; nothing to check against
+			continue
+		}
+		refreshed, err := ctx.Refresh(ttl)
+		if err != nil {
+			return results, err
+		}
+		if len(refreshed) > 0 {
+			results[ctx.ProjectRoot] = refreshed
+		}
+	}
+	return results, nil
+}
+
+// runGitIn runs git in a directory, returning its trimmed output or "" on
+// failure
+func runGitIn(dir string, args ...string) string {
+	if dir == "" {
+		return ""
+	}
+	output, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// expandHome expands a leading ~ in a path
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[2:])
+		}
+	}
+	return path
+}
+
+// formatAge formats a duration like GetAge, e.g. "3h" or "2d"
+func formatAge(d time.Duration) string {
+	if d < time.Hour {
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	if d < 24*time.Hour {
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
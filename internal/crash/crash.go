@@ -0,0 +1,159 @@
+// Package crash writes a report when claude-mon panics: the panic and its
+// stack, the latest log entries and the last hook payload received, kept in
+// ~/.config/claude-mon/crash until the next start has offered to show them.
+package crash
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+const (
+	logLines       = 50       // Latest log entries included in a report
+	maxPayload     = 64 << 10 // Longest payload included, in bytes
+	keepReports    = 20       // Reports kept; older ones are removed
+	seenMarker     = ".seen"  // Touched when reports are shown; older ones aren't offered again
+	reportTimeForm = "20060102-150405"
+)
+
+// Version is included in reports; set by main
+var Version = "dev"
+
+var (
+	mu          sync.Mutex
+	lastPayload []byte
+)
+
+// Dir returns the directory crash reports are written to
+func Dir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "claude-mon", "crash")
+}
+
+// SetLastPayload remembers the latest hook payload for the next report
+func SetLastPayload(payload []byte) {
+	if len(payload) > maxPayload {
+		payload = payload[:maxPayload]
+	}
+	mu.Lock()
+	lastPayload = append(lastPayload[:0], payload...)
+	mu.Unlock()
+}
+
+// Recover recovers from a panic, writes a report and calls then, if set.
+// Defer it directly: defer crash.Recover("daemon", nil).
+func Recover(component string, then func(path string)) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	path, err := Write(component, r, debug.Stack())
+	if err != nil {
+		logger.Error("Failed to write crash report", "component", component, "error", err)
+	}
+	logger.Error("Recovered from panic", "component", component, "panic", fmt.Sprint(r), "report", path)
+	if then != nil {
+		then(path)
+	}
+}
+
+// Exit is a Recover callback for panics the process can't carry on after
+func Exit(path string) {
+	fmt.Fprintf(os.Stderr, "claude-mon crashed; report written to %s\n", path)
+	logger.Close()
+	os.Exit(2)
+}
+
+// Write writes a report of a panic in component and returns its path
+func Write(component string, r interface{}, stack []byte) (string, error) {
+	dir := Dir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "claude-mon %s crashed in %s at %s\n\n", Version, component, now.Format(time.RFC3339))
+	fmt.Fprintf(&sb, "panic: %v\n\n%s\n", r, stack)
+
+	entries := logger.Recent()
+	if len(entries) > logLines {
+		entries = entries[len(entries)-logLines:]
+	}
+	fmt.Fprintf(&sb, "--- last %d log entries ---\n", len(entries))
+	for _, e := range entries {
+		sb.WriteString(e.String() + "\n")
+	}
+
+	mu.Lock()
+	payload := string(lastPayload)
+	mu.Unlock()
+	sb.WriteString("\n--- last payload ---\n")
+	if payload == "" {
+		payload = "(none)"
+	}
+	sb.WriteString(payload + "\n")
+
+	name := fmt.Sprintf("%s-%s.txt", now.Format(reportTimeForm), strings.ReplaceAll(component, " ", "-"))
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(sb.String()), 0600); err != nil {
+		return "", err
+	}
+	prune(dir)
+	return path, nil
+}
+
+// reports lists the reports in dir, oldest first
+func reports(dir string) []string {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.txt"))
+	sort.Strings(paths)
+	return paths
+}
+
+func prune(dir string) {
+	paths := reports(dir)
+	for len(paths) > keepReports {
+		os.Remove(paths[0])
+		paths = paths[1:]
+	}
+}
+
+// Pending returns the reports written since they were last shown, oldest
+// first
+func Pending() []string {
+	dir := Dir()
+	var seen time.Time
+	if info, err := os.Stat(filepath.Join(dir, seenMarker)); err == nil {
+		seen = info.ModTime()
+	}
+
+	var pending []string
+	for _, path := range reports(dir) {
+		if info, err := os.Stat(path); err == nil && info.ModTime().After(seen) {
+			pending = append(pending, path)
+		}
+	}
+	return pending
+}
+
+// MarkSeen stops the current reports being offered again
+func MarkSeen() error {
+	dir := Dir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	marker := filepath.Join(dir, seenMarker)
+	now := time.Now()
+	if err := os.Chtimes(marker, now, now); err == nil {
+		return nil
+	}
+	return os.WriteFile(marker, nil, 0600)
+}
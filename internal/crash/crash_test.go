@@ -0,0 +1,60 @@
+package crash
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+func TestRecoverWritesReport(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := logger.Setup(logger.Options{Level: "debug", Component: "daemon", Buffer: 10}); err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+	logger.Log("Processing edit for %s", "/src/api/main.go")
+	SetLastPayload([]byte(`{"type":"edit","tool_name":"Edit"}`))
+
+	if len(Pending()) != 0 {
+		t.Fatal("expected no reports yet")
+	}
+
+	var reported string
+	func() {
+		defer Recover("daemon payload", func(path string) { reported = path })
+		var m map[string]int
+		m["boom"]++
+	}()
+	if reported == "" {
+		t.Fatal("expected the panic to be reported")
+	}
+
+	data, err := os.ReadFile(reported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+	for _, want := range []string{
+		"crashed in daemon payload",
+		"panic: assignment to entry in nil map",
+		"crash_test.go",
+		"daemon: Processing edit for /src/api/main.go",
+		`{"type":"edit","tool_name":"Edit"}`,
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("expected report to contain %q, got:\n%s", want, report)
+		}
+	}
+
+	if pending := Pending(); len(pending) != 1 || pending[0] != reported {
+		t.Errorf("expected the report to be pending, got %v", pending)
+	}
+	if err := MarkSeen(); err != nil {
+		t.Fatal(err)
+	}
+	if pending := Pending(); len(pending) != 0 {
+		t.Errorf("expected no reports pending once seen, got %v", pending)
+	}
+}
@@ -0,0 +1,129 @@
+package daemon
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/internal/workspace"
+)
+
+// Timeline event kinds of alerts raised by the [alerts] rules
+const (
+	EventAlertBurst     = "alert_burst"     // More files changed in the burst window than allowed
+	EventAlertProtected = "alert_protected" // An edit touched a protected file
+)
+
+// AlertMonitor applies the [alerts] rules to recorded edits
+type AlertMonitor struct {
+	config func() AlertsConfig // Current settings, which may change on reload
+	raise  func(workspace, kind, message string)
+
+	mu      sync.Mutex
+	touches map[string][]fileTouch // Per workspace, edits within the burst window, oldest first
+	quiet   map[string]time.Time   // Per workspace, when a burst alert may be raised again
+}
+
+// fileTouch is an edit to a file, for counting bursts
+type fileTouch struct {
+	file string
+	at   time.Time
+}
+
+// NewAlertMonitor creates an alert monitor reading its rules from the
+// daemon config. Alerts are recorded as timeline events, sent to webhooks
+// and, if configured, shown as desktop notifications.
+func NewAlertMonitor(d *Daemon) *AlertMonitor {
+	config := func() AlertsConfig {
+		d.cfgMu.RLock()
+		defer d.cfgMu.RUnlock()
+		return d.cfg.Alerts
+	}
+	return newAlertMonitor(config, func(ws, kind, message string) {
+		logger.Log("Alert in %s: %s", ws, message)
+		d.recordEvent(ws, kind, SeverityWarning, message)
+		d.webhooks.Send(alertWebhookEvent(ws, kind, message))
+		if config().Desktop {
+			notifyDesktop("claude-mon: "+filepath.Base(ws), message)
+		}
+	})
+}
+
+func newAlertMonitor(config func() AlertsConfig, raise func(workspace, kind, message string)) *AlertMonitor {
+	return &AlertMonitor{
+		config:  config,
+		raise:   raise,
+		touches: make(map[string][]fileTouch),
+		quiet:   make(map[string]time.Time),
+	}
+}
+
+// Check applies the rules to an edit of file in workspace ws made at a time
+func (a *AlertMonitor) Check(ws, file string, at time.Time) {
+	cfg := a.config()
+	if !cfg.Enabled || file == "" {
+		return
+	}
+
+	rel := file
+	if r, err := filepath.Rel(ws, file); err == nil && !strings.HasPrefix(r, "..") {
+		rel = r
+	}
+	for _, pattern := range cfg.Protected {
+		if workspace.Match(pattern, rel) {
+			a.raise(ws, EventAlertProtected, fmt.Sprintf("edit to protected file %s (%s)", rel, pattern))
+			break
+		}
+	}
+
+	if cfg.BurstFiles <= 0 {
+		return
+	}
+	window := time.Duration(cfg.BurstSecs) * time.Second
+	a.mu.Lock()
+	var kept []fileTouch
+	files := map[string]bool{file: true}
+	for _, t := range a.touches[ws] {
+		if at.Sub(t.at) < window {
+			kept = append(kept, t)
+			files[t.file] = true
+		}
+	}
+	a.touches[ws] = append(kept, fileTouch{file: file, at: at})
+	burst := len(files) > cfg.BurstFiles && !at.Before(a.quiet[ws])
+	if burst {
+		a.quiet[ws] = at.Add(window)
+	}
+	a.mu.Unlock()
+
+	if burst {
+		a.raise(ws, EventAlertBurst, fmt.Sprintf("%d files changed in %s", len(files), window))
+	}
+}
+
+// notifyDesktop shows a desktop notification in the background, with
+// osascript on macOS and notify-send elsewhere
+var notifyDesktop = func(title, message string) {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "darwin" {
+		cmd = exec.Command("osascript", "-e",
+			fmt.Sprintf("display notification %s with title %s", appleScriptString(message), appleScriptString(title)))
+	} else {
+		cmd = exec.Command("notify-send", "--urgency=critical", title, message)
+	}
+	go func() {
+		if err := cmd.Run(); err != nil {
+			logger.Log("Desktop notification failed: %v", err)
+		}
+	}()
+}
+
+// appleScriptString quotes s as an AppleScript string literal
+func appleScriptString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
@@ -0,0 +1,44 @@
+package daemon
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAlertMonitor(t *testing.T) {
+	cfg := AlertsConfig{Enabled: true, BurstFiles: 2, BurstSecs: 10, Protected: []string{"migrations/**", ".github/**"}}
+	var raised []string
+	a := newAlertMonitor(func() AlertsConfig { return cfg }, func(ws, kind, message string) {
+		raised = append(raised, kind+": "+message)
+	})
+
+	start := time.Now()
+	a.Check("/src/api", "/src/api/main.go", start)
+	a.Check("/src/api", "/src/api/main.go", start.Add(time.Second))
+	a.Check("/src/web", "/src/web/app.ts", start.Add(time.Second))
+	a.Check("/src/api", "/src/api/db.go", start.Add(2*time.Second))
+	if len(raised) != 0 {
+		t.Fatalf("expected no alerts for two files per workspace, got %v", raised)
+	}
+
+	// A third file in the window is a burst, raised once per window
+	a.Check("/src/api", "/src/api/server.go", start.Add(3*time.Second))
+	a.Check("/src/api", "/src/api/routes.go", start.Add(4*time.Second))
+	if len(raised) != 1 || !strings.HasPrefix(raised[0], EventAlertBurst+": 3 files changed in 10s") {
+		t.Fatalf("expected one burst alert, got %v", raised)
+	}
+
+	// Edits to protected files alert every time
+	a.Check("/src/api", "/src/api/db/migrations/0001_init.sql", start.Add(time.Minute))
+	a.Check("/src/api", "/src/api/.github/workflows/ci.yml", start.Add(time.Minute))
+	if len(raised) != 3 || !strings.Contains(raised[1], "db/migrations/0001_init.sql (migrations/**)") {
+		t.Fatalf("expected protected file alerts, got %v", raised)
+	}
+
+	cfg.Enabled = false
+	a.Check("/src/api", "/src/api/migrations/0002.sql", start.Add(time.Minute))
+	if len(raised) != 3 {
+		t.Errorf("expected no alerts while disabled, got %v", raised)
+	}
+}
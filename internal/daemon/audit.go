@@ -0,0 +1,134 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/auth"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// AuditEntry is one line of the audit log: a query, who made it and
+// whether it was answered
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	Type       string    `json:"type"`
+	Workspace  string    `json:"workspace,omitempty"`
+	Group      string    `json:"group,omitempty"`
+	Token      string    `json:"token,omitempty"` // Name of the token the query carried
+	UID        int       `json:"uid"`             // Connecting process, -1 where the OS doesn't say
+	PID        int       `json:"pid"`
+	Outcome    string    `json:"outcome"` // "ok", "denied" or "error"
+	Error      string    `json:"error,omitempty"`
+	DurationMS float64   `json:"duration_ms"`
+}
+
+// auditLog appends audit entries to a file as JSON lines, rotating it to
+// <path>.1 once it reaches its size limit
+type auditLog struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	f       *os.File
+	size    int64
+}
+
+// open starts logging to path; an empty path leaves auditing off
+func (a *auditLog) open(path string, maxSizeMB int) error {
+	if path == "" {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.path = path
+	a.maxSize = int64(maxSizeMB) << 20
+	return a.reopen()
+}
+
+// reopen opens the log file for appending; called with mu held
+func (a *auditLog) reopen() error {
+	f, err := os.OpenFile(a.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	a.f, a.size = f, info.Size()
+	return nil
+}
+
+// write appends an entry, if auditing is on
+func (a *auditLog) write(entry *AuditEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.f == nil {
+		return
+	}
+	if a.maxSize > 0 && a.size+int64(len(line)) > a.maxSize {
+		a.f.Close()
+		a.f = nil
+		if err := os.Rename(a.path, a.path+".1"); err != nil {
+			logger.Log("Audit log rotation failed: %v", err)
+		}
+		if err := a.reopen(); err != nil {
+			logger.Log("Audit log unavailable: %v", err)
+			return
+		}
+	}
+	n, err := a.f.Write(line)
+	a.size += int64(n)
+	if err != nil {
+		logger.Log("Audit log write failed: %v", err)
+	}
+}
+
+// close stops logging
+func (a *auditLog) close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.f != nil {
+		a.f.Close()
+		a.f = nil
+	}
+}
+
+// auditQuery logs a query that started at start. denied is the reason it
+// was refused, failed why it couldn't be answered.
+func (d *Daemon) auditQuery(conn net.Conn, query *Query, start time.Time, denied, failed error) {
+	entry := &AuditEntry{
+		Time:       start,
+		Type:       query.Type,
+		Workspace:  query.WorkspacePath,
+		Group:      query.Group,
+		Outcome:    "ok",
+		DurationMS: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	entry.UID, entry.PID = peerCredentials(conn)
+	if query.Token != "" {
+		if t, err := d.db.GetTokenByHash(auth.Hash(query.Token)); err == nil && t != nil {
+			entry.Token = t.Name
+		} else {
+			entry.Token = "(unknown)"
+		}
+	}
+	switch {
+	case denied != nil:
+		entry.Outcome, entry.Error = "denied", denied.Error()
+	case failed != nil:
+		entry.Outcome, entry.Error = "error", failed.Error()
+	}
+	d.audit.write(entry)
+}
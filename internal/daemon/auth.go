@@ -0,0 +1,95 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ztaylor/claude-mon/internal/auth"
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// authorize checks a request's token against the scope it needs. Requests
+// on the local unix sockets are trusted unless auth.require_local is set;
+// network listeners must pass local=false so a token is always required.
+func (d *Daemon) authorize(token string, required auth.Scope, local bool) error {
+	d.cfgMu.RLock()
+	requireLocal := d.cfg.Auth.RequireLocal
+	d.cfgMu.RUnlock()
+	if local && !requireLocal {
+		return nil
+	}
+
+	if token == "" {
+		return fmt.Errorf("unauthorized: token required")
+	}
+	t, err := d.db.GetTokenByHash(auth.Hash(token))
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return fmt.Errorf("unauthorized: invalid or revoked token")
+	}
+	if !auth.Scope(t.Scope).Allows(required) {
+		return fmt.Errorf("forbidden: %s token cannot perform %s operations", t.Scope, required)
+	}
+
+	if err := d.db.TouchToken(t.ID); err != nil {
+		logger.Log("Failed to update token last use: %v", err)
+	}
+	return nil
+}
+
+// authorizeQuery authorizes a query, made on a local unix socket or not.
+// Changing the prompt library needs an ingest token; token management and
+// rewriting a workspace's rows need an admin token, except for creating the
+// first admin token on a local socket.
+func (d *Daemon) authorizeQuery(query *Query, local bool) error {
+	if query.Type == "prompt_save" || query.Type == "prompt_delete" {
+		return d.authorize(query.Token, auth.ScopeIngest, local)
+	}
+	writes := query.Type == "workspace_import" || query.Type == "workspace_purge" || query.Type == "dedupe"
+	if !strings.HasPrefix(query.Type, "token") && !writes {
+		return d.authorize(query.Token, auth.ScopeRead, local)
+	}
+
+	if query.Type == "token_create" {
+		admins, err := d.db.CountActiveTokens(string(auth.ScopeAdmin))
+		if err != nil {
+			return err
+		}
+		if admins == 0 && local {
+			return nil // Bootstrap
+		}
+	}
+	return d.authorize(query.Token, auth.ScopeAdmin, local)
+}
+
+// createToken issues a new token, returning it in the result. The token is
+// only ever shown here; the database keeps its hash.
+func (d *Daemon) createToken(query *Query, result *QueryResult) error {
+	if query.Name == "" {
+		return fmt.Errorf("token name required")
+	}
+	scope := auth.ScopeRead
+	if query.Scope != "" {
+		var err error
+		if scope, err = auth.ParseScope(query.Scope); err != nil {
+			return err
+		}
+	}
+
+	token, hash, err := auth.Generate()
+	if err != nil {
+		return err
+	}
+	id, err := d.db.CreateToken(query.Name, string(scope), hash)
+	if err != nil {
+		return err
+	}
+
+	result.Token = token
+	result.Tokens = []*database.APIToken{{ID: id, Name: query.Name, Scope: string(scope)}}
+	logger.Log("Created %s token %q", scope, query.Name)
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"time"
 
+	"github.com/ztaylor/claude-mon/internal/crash"
 	"github.com/ztaylor/claude-mon/internal/logger"
 )
 
@@ -43,6 +44,7 @@ func (bm *BackupManager) Start() {
 	logger.Log("Starting backup manager (interval: %v)", bm.interval)
 
 	go func() {
+		defer crash.Recover("daemon backup", crash.Exit)
 		ticker := time.NewTicker(bm.interval)
 		defer ticker.Stop()
 
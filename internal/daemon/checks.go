@@ -0,0 +1,266 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/internal/workspace"
+)
+
+// CheckDatabase defines the database interface the check runner records to
+type CheckDatabase interface {
+	RecordCheckResults(results []*database.CheckResult) error
+}
+
+// CheckRunner runs a workspace's configured checks once edits to it settle
+type CheckRunner struct {
+	db     CheckDatabase
+	config func() ChecksConfig // Current settings, which may change on reload
+	checks func(workspacePath string) []CheckCommand
+
+	mu      sync.Mutex
+	pending map[string]map[string]bool // Workspace -> edited files awaiting checks
+	timers  map[string]*time.Timer
+	running map[string]*sync.Mutex // Serializes runs per workspace
+	stopped bool
+}
+
+// NewCheckRunner creates a check runner reading its settings from the
+// daemon config
+func NewCheckRunner(d *Daemon) *CheckRunner {
+	return &CheckRunner{
+		db: d.db,
+		config: func() ChecksConfig {
+			d.cfgMu.RLock()
+			defer d.cfgMu.RUnlock()
+			return d.cfg.Checks
+		},
+		checks: func(workspacePath string) []CheckCommand {
+			d.cfgMu.RLock()
+			defer d.cfgMu.RUnlock()
+			return d.cfg.WorkspaceChecks(workspacePath)
+		},
+		pending: make(map[string]map[string]bool),
+		timers:  make(map[string]*time.Timer),
+		running: make(map[string]*sync.Mutex),
+	}
+}
+
+// Queue schedules checks for an edited file, restarting the workspace's
+// debounce so a burst of edits is checked once
+func (cr *CheckRunner) Queue(workspacePath, filePath string) {
+	if len(cr.checks(workspacePath)) == 0 || filePath == "" {
+		return
+	}
+	debounce := time.Duration(cr.config().DebounceSecs) * time.Second
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	if cr.stopped {
+		return
+	}
+	if cr.pending[workspacePath] == nil {
+		cr.pending[workspacePath] = make(map[string]bool)
+	}
+	cr.pending[workspacePath][filePath] = true
+
+	if t := cr.timers[workspacePath]; t != nil {
+		t.Stop()
+	}
+	cr.timers[workspacePath] = time.AfterFunc(debounce, func() {
+		cr.run(workspacePath)
+	})
+}
+
+// Stop cancels checks that haven't started
+func (cr *CheckRunner) Stop() {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.stopped = true
+	for _, t := range cr.timers {
+		t.Stop()
+	}
+}
+
+// run checks the files edited in a workspace since its last run
+func (cr *CheckRunner) run(workspacePath string) {
+	cr.mu.Lock()
+	files := cr.pending[workspacePath]
+	delete(cr.pending, workspacePath)
+	delete(cr.timers, workspacePath)
+	lock := cr.running[workspacePath]
+	if lock == nil {
+		lock = &sync.Mutex{}
+		cr.running[workspacePath] = lock
+	}
+	cr.mu.Unlock()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	cfg := cr.config()
+	started := time.Now()
+	results := RunChecks(workspacePath, sortedKeys(files), cr.checks(workspacePath),
+		time.Duration(cfg.TimeoutSecs)*time.Second, cfg.MaxOutputKB*1024)
+	for _, r := range results {
+		r.StartedAt = started
+	}
+	if len(results) == 0 {
+		return
+	}
+
+	if err := cr.db.RecordCheckResults(results); err != nil {
+		logger.Log("Failed to record check results: %v", err)
+		return
+	}
+	var failed int
+	for _, r := range results {
+		if !r.Passed {
+			failed++
+		}
+	}
+	logger.Log("Ran %d check(s) for %d file(s) in %s: %d failed (%v)",
+		len(results), len(files), workspacePath, failed, time.Since(started).Round(time.Millisecond))
+}
+
+// RunChecks runs each check matching each file. A command that expands to
+// the same text for several files (e.g. "go vet {pkg}" for two files in one
+// package) runs once, and its result is recorded for each of them.
+func RunChecks(workspacePath string, files []string, checks []CheckCommand, timeout time.Duration, maxOutput int) []*database.CheckResult {
+	ran := make(map[string]*database.CheckResult)
+	var results []*database.CheckResult
+	for _, file := range files {
+		// The hook records paths as Claude passed them, absolute or relative
+		rel := file
+		if filepath.IsAbs(file) {
+			var err error
+			if rel, err = filepath.Rel(workspacePath, file); err != nil || strings.HasPrefix(rel, "..") {
+				continue
+			}
+		}
+		for _, check := range checks {
+			if len(check.Files) > 0 && !matchesAny(check.Files, rel) {
+				continue
+			}
+			command := expandCheckCommand(check.Command, rel)
+			key := check.Name + "\x00" + command
+			r, ok := ran[key]
+			if !ok {
+				r = runCheck(workspacePath, command, timeout, maxOutput)
+				ran[key] = r
+			}
+			result := *r
+			result.WorkspacePath = workspacePath
+			result.FilePath = file
+			result.Name = check.Name
+			results = append(results, &result)
+		}
+	}
+	return results
+}
+
+// runCheck runs a command through sh in the workspace root
+func runCheck(dir, command string, timeout time.Duration, maxOutput int) *database.CheckResult {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = dir
+	// Kill the whole process group on timeout, so tools like go test don't
+	// leave children running
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	cmd.WaitDelay = 5 * time.Second
+	output, err := cmd.CombinedOutput()
+	result := &database.CheckResult{
+		Command:    command,
+		Passed:     err == nil,
+		Output:     tail(string(output), maxOutput),
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case ctx.Err() == context.DeadlineExceeded:
+		result.ExitCode = -1
+		result.Output += "\n[timed out after " + timeout.String() + "]"
+	case errors.As(err, &exitErr):
+		result.ExitCode = exitErr.ExitCode()
+	case err != nil:
+		result.ExitCode = -1
+		result.Output += err.Error()
+	}
+	return result
+}
+
+// expandCheckCommand fills in a command's {file}, {dir} and {pkg}
+// placeholders for a file relative to the workspace root
+func expandCheckCommand(command, rel string) string {
+	dir := filepath.ToSlash(filepath.Dir(rel))
+	pkg := "./" + dir
+	if dir == "." {
+		pkg = "."
+	}
+	return strings.NewReplacer(
+		"{file}", shellQuote(filepath.ToSlash(rel)),
+		"{dir}", shellQuote(dir),
+		"{pkg}", shellQuote(pkg),
+	).Replace(command)
+}
+
+// shellQuote quotes s for sh unless it's plainly safe
+func shellQuote(s string) string {
+	safe := strings.IndexFunc(s, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("./_-+:@", r))
+	}) < 0
+	if safe && s != "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// tail keeps the last max bytes of output, where failures usually are
+func tail(output string, max int) string {
+	if max <= 0 || len(output) <= max {
+		return output
+	}
+	cut := output[len(output)-max:]
+	if i := strings.IndexByte(cut, '\n'); i >= 0 && i < len(cut)-1 {
+		cut = cut[i+1:]
+	}
+	return "[output truncated]\n" + cut
+}
+
+func matchesAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if workspace.Match(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,105 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExpandCheckCommand(t *testing.T) {
+	tests := []struct {
+		command, rel, want string
+	}{
+		{"go vet {pkg}", "internal/model/model.go", "go vet ./internal/model"},
+		{"go test {pkg}", "main.go", "go test ."},
+		{"npx eslint {file}", "web/src/app.ts", "npx eslint web/src/app.ts"},
+		{"ls {dir}", "my docs/it's.md", `ls 'my docs'`},
+		{"cat {file}", "it's.md", `cat 'it'\''s.md'`},
+	}
+	for _, tt := range tests {
+		if got := expandCheckCommand(tt.command, tt.rel); got != tt.want {
+			t.Errorf("expandCheckCommand(%q, %q) = %q, want %q", tt.command, tt.rel, got, tt.want)
+		}
+	}
+}
+
+func TestRunChecks(t *testing.T) {
+	ws := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(ws, "pkg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	checks := []CheckCommand{
+		{Name: "list", Command: "echo {dir} >> runs.log", Files: []string{"*.go"}},
+		{Name: "fail", Command: "echo broken {file}; exit 3", Files: []string{"*.ts"}},
+	}
+	files := []string{
+		filepath.Join(ws, "pkg", "a.go"),
+		"pkg/b.go", // Relative, as Claude may pass it
+		filepath.Join(ws, "web", "app.ts"),
+		"/elsewhere/c.go", // Outside the workspace
+	}
+
+	results := RunChecks(ws, files, checks, 10*time.Second, 0)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 results, got %d", len(results))
+	}
+
+	// Both Go files share one package, so the command ran once
+	log, err := os.ReadFile(filepath.Join(ws, "runs.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(log)); got != "pkg" {
+		t.Errorf("expected one run for the package, got %q", got)
+	}
+	if !results[0].Passed || !results[1].Passed || results[1].FilePath != "pkg/b.go" {
+		t.Errorf("expected the Go checks to pass for each file, got %+v %+v", results[0], results[1])
+	}
+
+	fail := results[2]
+	if fail.Passed || fail.ExitCode != 3 || !strings.Contains(fail.Output, "broken web/app.ts") {
+		t.Errorf("expected the failing check with its output, got %+v", fail)
+	}
+}
+
+func TestRunChecksTimeout(t *testing.T) {
+	checks := []CheckCommand{{Name: "slow", Command: "sleep 5"}}
+	results := RunChecks(t.TempDir(), []string{"a.go"}, checks, 100*time.Millisecond, 0)
+	if len(results) != 1 || results[0].Passed || results[0].ExitCode != -1 || !strings.Contains(results[0].Output, "timed out") {
+		t.Errorf("expected a timed out result, got %+v", results)
+	}
+}
+
+func TestWorkspaceChecks(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Checks.Workspaces = map[string][]CheckCommand{
+		"/src/**":     {{Name: "vet", Command: "go vet {pkg}"}},
+		"/src/web":    {{Name: "eslint", Command: "npx eslint {file}"}},
+		"/other/repo": {{Name: "test", Command: "make test"}},
+	}
+
+	var names []string
+	for _, c := range cfg.WorkspaceChecks("/src/web") {
+		names = append(names, c.Name)
+	}
+	if got := strings.Join(names, ","); got != "vet,eslint" {
+		t.Errorf("WorkspaceChecks() = %s, want vet,eslint", got)
+	}
+
+	cfg.Checks.Enabled = false
+	if got := cfg.WorkspaceChecks("/src/web"); len(got) != 0 {
+		t.Errorf("expected no checks when disabled, got %d", len(got))
+	}
+}
+
+func TestTail(t *testing.T) {
+	if got := tail("short", 100); got != "short" {
+		t.Errorf("tail() = %q", got)
+	}
+	got := tail("line one\nline two\nline three\n", 14)
+	if got != "[output truncated]\nline three\n" {
+		t.Errorf("tail() = %q", got)
+	}
+}
@@ -3,6 +3,7 @@ package daemon
 import (
 	"time"
 
+	"github.com/ztaylor/claude-mon/internal/crash"
 	"github.com/ztaylor/claude-mon/internal/database"
 	"github.com/ztaylor/claude-mon/internal/logger"
 )
@@ -18,6 +19,9 @@ type CleanupManager struct {
 // CleanupDatabase defines the database cleanup interface
 type CleanupDatabase interface {
 	DeleteOldEdits(beforeDate time.Time) (int64, error)
+	DeleteOldSnapshots(beforeDate time.Time) (int64, error)
+	DeleteOldCheckResults(beforeDate time.Time) (int64, error)
+	DeleteOldHookEvents(beforeDate time.Time) (int64, error)
 	CapEditsPerSession(sessionID int64, maxEdits int) (int64, error)
 	GetDatabaseSize() (int64, error)
 	Vacuum() error
@@ -43,6 +47,7 @@ func (cm *CleanupManager) Start() {
 	logger.Log("Starting cleanup manager (interval: %v)", cm.interval)
 
 	go func() {
+		defer crash.Recover("daemon cleanup", crash.Exit)
 		ticker := time.NewTicker(cm.interval)
 		defer ticker.Stop()
 
@@ -80,6 +85,21 @@ func (cm *CleanupManager) runCleanup() {
 		} else {
 			logger.Log("Deleted %d old edits (older than %v)", deleted, cutoff.Format("2006-01-02"))
 		}
+		if deleted, err := cm.db.DeleteOldSnapshots(cutoff); err != nil {
+			logger.Log("Failed to delete old snapshots: %v", err)
+		} else if deleted > 0 {
+			logger.Log("Deleted %d old file snapshots", deleted)
+		}
+		if deleted, err := cm.db.DeleteOldCheckResults(cutoff); err != nil {
+			logger.Log("Failed to delete old check results: %v", err)
+		} else if deleted > 0 {
+			logger.Log("Deleted %d old check results", deleted)
+		}
+		if deleted, err := cm.db.DeleteOldHookEvents(cutoff); err != nil {
+			logger.Log("Failed to delete old hook events: %v", err)
+		} else if deleted > 0 {
+			logger.Log("Deleted %d old hook events", deleted)
+		}
 	}
 
 	// 2. Cap edits per session
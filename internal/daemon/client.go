@@ -0,0 +1,36 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+
+	"github.com/ztaylor/claude-mon/internal/auth"
+)
+
+// SendQuery sends a query to the daemon's query socket and returns its
+// result, authenticating with $CLAUDE_MON_TOKEN unless the query has a token
+func SendQuery(socketPath string, query *Query) (*QueryResult, error) {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("daemon not running: %w", err)
+	}
+	defer conn.Close()
+
+	if query.Token == "" {
+		query.Token = auth.FromEnv()
+	}
+
+	if err := json.NewEncoder(conn).Encode(query); err != nil {
+		return nil, fmt.Errorf("failed to send query: %w", err)
+	}
+
+	var result QueryResult
+	if err := json.NewDecoder(conn).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("%s", result.Error)
+	}
+	return &result, nil
+}
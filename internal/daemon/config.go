@@ -2,12 +2,18 @@ package daemon
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/digest"
+	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/internal/workspace"
 )
 
 // Config holds all daemon configuration
@@ -22,6 +28,19 @@ type Config struct {
 	Hooks       HooksConfig       `toml:"hooks"`
 	Logging     LoggingConfig     `toml:"logging"`
 	Performance PerformanceConfig `toml:"performance"`
+	Auth        AuthConfig        `toml:"auth"`
+	Context     ContextConfig     `toml:"context"`
+	Checks      ChecksConfig      `toml:"checks"`
+	Ingest      IngestConfig      `toml:"ingest"`
+	Webhooks    WebhooksConfig    `toml:"webhooks"`
+	Digest      DigestConfig      `toml:"digest"`
+	Users       UsersConfig       `toml:"users"`
+	Alerts      AlertsConfig      `toml:"alerts"`
+	Watch       WatchConfig       `toml:"watch"`
+	GRPC        GRPCConfig        `toml:"grpc"`
+	Editor      EditorConfig      `toml:"editor"`
+
+	path string // Explicit config file path, reused on reload
 }
 
 // DirectoryConfig holds directory settings
@@ -34,6 +53,8 @@ type DatabaseConfig struct {
 	Path               string `toml:"path"`
 	MaxDBSizeMB        int    `toml:"max_db_size_mb"`
 	WALCheckpointPages int    `toml:"wal_checkpoint_pages"`
+	BusyTimeoutMS      int    `toml:"busy_timeout_ms"`      // How long a write waits for another to finish
+	SnapshotCodec      string `toml:"snapshot_compression"` // "gzip" or "zstd" for new file snapshots
 }
 
 // SocketsConfig holds socket settings
@@ -41,6 +62,20 @@ type SocketsConfig struct {
 	DaemonSocket string `toml:"daemon_socket"`
 	QuerySocket  string `toml:"query_socket"`
 	BufferSize   int    `toml:"buffer_size"`
+	Mode         string `toml:"mode"`  // Permissions of both sockets in octal, e.g. "0600"
+	Group        string `toml:"group"` // Group owning the sockets, to share them with mode "0660"
+}
+
+// FileMode parses the socket permissions, 0600 when unset
+func (s SocketsConfig) FileMode() (os.FileMode, error) {
+	if s.Mode == "" {
+		return 0600, nil
+	}
+	mode, err := strconv.ParseUint(s.Mode, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("sockets.mode must be octal permissions like \"0600\", got %q", s.Mode)
+	}
+	return os.FileMode(mode), nil
 }
 
 // QueryConfig holds query settings
@@ -67,10 +102,13 @@ type BackupConfig struct {
 	Format        string `toml:"format"` // "sqlite" or "export"
 }
 
-// WorkspacesConfig holds workspace filtering settings
+// WorkspacesConfig holds workspace filtering settings and named groups of
+// workspaces (e.g. platform = api + infra + proto repos) that queries can be
+// scoped to. Tracked and ignored entries are globs (see package workspace).
 type WorkspacesConfig struct {
-	Tracked []string `toml:"tracked"`
-	Ignored []string `toml:"ignored"`
+	Tracked []string            `toml:"tracked"` // e.g. "~/src/**"; empty tracks everything
+	Ignored []string            `toml:"ignored"` // e.g. "~/tmp/**" or "node_modules"
+	Groups  map[string][]string `toml:"groups"`
 }
 
 // HooksConfig holds hook integration settings
@@ -97,6 +135,157 @@ type PerformanceConfig struct {
 	CacheTTLSecs   int  `toml:"cache_ttl_seconds"`
 }
 
+// AuthConfig holds API token settings. Network endpoints always require a
+// token; local unix sockets only do when RequireLocal is set.
+type AuthConfig struct {
+	RequireLocal   bool   `toml:"require_local"`
+	AuditLog       string `toml:"audit_log"`         // File every query is logged to, in the data directory; empty disables
+	AuditMaxSizeMB int    `toml:"audit_max_size_mb"` // Size at which the audit log is rotated, keeping one old file
+}
+
+// ContextConfig holds working context revalidation settings. The daemon
+// periodically checks project contexts against the environment, refreshing
+// git branches and flagging values that no longer match or that haven't been
+// confirmed within the TTL.
+type ContextConfig struct {
+	RefreshEnabled      bool `toml:"refresh_enabled"`
+	TTLHours            int  `toml:"ttl_hours"`
+	RefreshIntervalMins int  `toml:"refresh_interval_minutes"`
+}
+
+// ChecksConfig holds post-edit check settings. Once edits to a workspace
+// have settled for the debounce period, the daemon runs the workspace's
+// commands for each edited file and records whether they passed. Workspaces
+// are keyed by glob, e.g. "~/src/claude-mon" or "~/src/**".
+type ChecksConfig struct {
+	Enabled      bool                      `toml:"enabled"`
+	DebounceSecs int                       `toml:"debounce_seconds"`
+	TimeoutSecs  int                       `toml:"timeout_seconds"`
+	MaxOutputKB  int                       `toml:"max_output_kb"` // Output beyond this keeps only its tail
+	Workspaces   map[string][]CheckCommand `toml:"workspaces"`
+}
+
+// IngestConfig holds edit ingestion settings. Edit payloads are queued and
+// written a batch at a time, one transaction per batch, so a burst of edits
+// doesn't hold up the hooks sending them. While the queue is full, hooks
+// are answered "busy" instead of being made to wait.
+type IngestConfig struct {
+	BatchSize       int `toml:"batch_size"`        // Edits per transaction; 0 writes each edit before answering
+	FlushIntervalMS int `toml:"flush_interval_ms"` // Longest a queued edit waits for its batch to fill
+	QueueSize       int `toml:"queue_size"`        // Edits queued at most
+	MaxQueueMB      int `toml:"max_queue_mb"`      // Payload size queued at most
+}
+
+// WebhooksConfig holds webhook settings. Events are POSTed to each
+// endpoint subscribed to them, in the background, retrying failed
+// deliveries with backoff.
+type WebhooksConfig struct {
+	TimeoutSecs   int       `toml:"timeout_seconds"` // Per delivery attempt
+	RetryAttempts int       `toml:"retry_attempts"`  // Retries after a failed attempt
+	Endpoints     []Webhook `toml:"endpoints"`
+}
+
+// Webhook is an endpoint events are sent to. The URL, secret and header
+// values may refer to environment variables, e.g. "${SLACK_WEBHOOK_URL}",
+// to keep secrets out of the config file.
+type Webhook struct {
+	Name       string            `toml:"name"`
+	URL        string            `toml:"url"`
+	Events     []string          `toml:"events"`     // "edit", "session_start", "ralph_finish", "alert"; empty sends all
+	Workspaces []string          `toml:"workspaces"` // Globs of workspaces whose events are sent; empty sends all
+	Body       string            `toml:"body"`       // Go template of the JSON body, e.g. `{"text": {{json .Message}}}`; empty sends the event
+	Secret     string            `toml:"secret"`     // Signs the body with HMAC-SHA256 in X-Claude-Mon-Signature
+	Headers    map[string]string `toml:"headers"`
+}
+
+// DigestConfig holds daily digest settings. Once a day the daemon posts a
+// summary of the period's activity to an incoming webhook, such as a Slack
+// or Discord channel's; a period without activity posts nothing.
+type DigestConfig struct {
+	Enabled bool   `toml:"enabled"`
+	Time    string `toml:"time"`   // Local time of day it's posted, e.g. "09:00"
+	Period  string `toml:"period"` // How far back it looks, e.g. "24h" or "7d"
+	Format  string `toml:"format"` // "slack", "discord" or "markdown"
+	URL     string `toml:"url"`    // Incoming webhook; may refer to environment variables
+	Group   string `toml:"group"`  // Only a workspace group's activity; empty covers all
+}
+
+// UsersConfig holds shared daemon settings. Every edit is tagged with the
+// user whose hook sent it, read from the socket's peer credentials; on a
+// shared daemon each user's queries only see their own edits and the
+// workspaces they've edited in. Admins, root and the user running the
+// daemon see everyone's.
+type UsersConfig struct {
+	Shared bool     `toml:"shared"`
+	Admins []string `toml:"admins"` // Login names that see all users' activity
+}
+
+// AlertsConfig holds rules flagging edits that look like an agent running
+// away. Alerts are recorded as timeline events, which the TUI shows as a
+// banner, and sent to webhooks subscribed to "alert".
+type AlertsConfig struct {
+	Enabled    bool     `toml:"enabled"`
+	BurstFiles int      `toml:"burst_files"`   // More files than this changed within burst_seconds raise an alert; 0 disables
+	BurstSecs  int      `toml:"burst_seconds"` // The burst window; a burst alert isn't raised again within it
+	Protected  []string `toml:"protected"`     // Globs of files whose edits raise an alert, e.g. "migrations/**" or ".github/**"
+	Desktop    bool     `toml:"desktop"`       // Also show alerts as desktop notifications (notify-send or osascript)
+}
+
+// WatchConfig holds the file watcher, a fallback for changes no hook
+// reports, such as files rewritten by a script Claude ran. Once a hook has
+// reported activity in a workspace, the daemon watches its files and
+// records a change no hook reported by the time the file settles as an
+// "External" edit.
+type WatchConfig struct {
+	Enabled   bool     `toml:"enabled"`
+	SettleMS  int      `toml:"settle_ms"`   // How long a file must be quiet before its change is recorded
+	MaxDirs   int      `toml:"max_dirs"`    // Directories watched at most, across workspaces; each takes an inotify watch
+	MaxFileKB int      `toml:"max_file_kb"` // Changes to larger files aren't recorded
+	Ignored   []string `toml:"ignored"`     // Globs of files and directories not watched, e.g. "node_modules"; .git and .jj never are
+}
+
+// GRPCConfig holds the gRPC API (pkg/daemonpb), served next to the JSON
+// sockets for richer clients such as editor plugins and web UIs
+type GRPCConfig struct {
+	Enabled bool   `toml:"enabled"`
+	Socket  string `toml:"socket"`   // Unix socket, with the same permissions as the JSON sockets
+	Listen  string `toml:"listen"`   // Optional TCP address, e.g. "127.0.0.1:7450"; every request on it needs a token
+	TLSCert string `toml:"tls_cert"` // Certificate served on listen; required unless it's a loopback address
+	TLSKey  string `toml:"tls_key"`  // The certificate's private key
+}
+
+// loopback reports whether a listen address only accepts connections from
+// this machine
+func loopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// EditorConfig holds the editor socket: JSON-RPC for editor plugins, such
+// as Neovim's, that mark the lines Claude changed (see EDITORS.md)
+type EditorConfig struct {
+	Enabled     bool   `toml:"enabled"`
+	Socket      string `toml:"socket"`       // Unix socket, with the same permissions as the JSON sockets
+	RecentHours int    `toml:"recent_hours"` // How far back "hunks" looks unless asked otherwise
+}
+
+// CheckCommand is a command run after edits, through sh in the workspace
+// root. {file} expands to the edited file relative to the workspace, {dir}
+// to its directory and {pkg} to the directory as a Go package pattern
+// (e.g. ./internal/model), each quoted for the shell.
+type CheckCommand struct {
+	Name    string   `toml:"name"`
+	Command string   `toml:"command"` // e.g. "go vet {pkg}" or "npx eslint {file}"
+	Files   []string `toml:"files"`   // Globs of files that trigger it, e.g. "*.go"; empty matches all
+}
+
 // defaultConfig returns default configuration
 func defaultConfig() *Config {
 	homeDir, _ := os.UserHomeDir()
@@ -110,11 +299,14 @@ func defaultConfig() *Config {
 			Path:               "claude-mon.db",
 			MaxDBSizeMB:        500,
 			WALCheckpointPages: 1000,
+			BusyTimeoutMS:      5000,
+			SnapshotCodec:      database.CodecGzip,
 		},
 		Sockets: SocketsConfig{
 			DaemonSocket: "/tmp/claude-mon-daemon.sock",
 			QuerySocket:  "/tmp/claude-mon-query.sock",
 			BufferSize:   8192,
+			Mode:         "0600",
 		},
 		Query: QueryConfig{
 			DefaultLimit: 50,
@@ -156,6 +348,57 @@ func defaultConfig() *Config {
 			CacheEnabled:   true,
 			CacheTTLSecs:   300,
 		},
+		Auth: AuthConfig{
+			RequireLocal:   false,
+			AuditLog:       "audit.log",
+			AuditMaxSizeMB: 10,
+		},
+		Context: ContextConfig{
+			RefreshEnabled:      true,
+			TTLHours:            24,
+			RefreshIntervalMins: 30,
+		},
+		Checks: ChecksConfig{
+			Enabled:      true,
+			DebounceSecs: 3,
+			TimeoutSecs:  300,
+			MaxOutputKB:  64,
+		},
+		Ingest: IngestConfig{
+			BatchSize:       100,
+			FlushIntervalMS: 50,
+			QueueSize:       1000,
+			MaxQueueMB:      64,
+		},
+		Webhooks: WebhooksConfig{
+			TimeoutSecs:   10,
+			RetryAttempts: 3,
+		},
+		Digest: DigestConfig{
+			Enabled: false,
+			Time:    "09:00",
+			Period:  "24h",
+			Format:  digest.FormatSlack,
+		},
+		Alerts: AlertsConfig{
+			Enabled:    false,
+			BurstFiles: 20,
+			BurstSecs:  60,
+		},
+		Watch: WatchConfig{
+			Enabled:   false,
+			SettleMS:  2000,
+			MaxDirs:   4096,
+			MaxFileKB: 1024,
+			Ignored:   []string{"node_modules", ".venv", "__pycache__", "*.swp", "*~"},
+		},
+		GRPC: GRPCConfig{
+			Socket: "/tmp/claude-mon-grpc.sock",
+		},
+		Editor: EditorConfig{
+			Socket:      "/tmp/claude-mon-editor.sock",
+			RecentHours: 24,
+		},
 	}
 }
 
@@ -163,6 +406,7 @@ func defaultConfig() *Config {
 // Priority: file > env vars > defaults
 func LoadConfig(configPath string) (*Config, error) {
 	cfg := defaultConfig()
+	cfg.path = configPath
 
 	// Load from file if provided
 	if configPath != "" {
@@ -224,6 +468,12 @@ func applyEnvVars(cfg *Config) {
 	if v := os.Getenv("CLAUDE_MON_QUERY_SOCKET"); v != "" {
 		cfg.Sockets.QuerySocket = v
 	}
+	if v := os.Getenv("CLAUDE_MON_GRPC_SOCKET"); v != "" {
+		cfg.GRPC.Socket = v
+	}
+	if v := os.Getenv("CLAUDE_MON_EDITOR_SOCKET"); v != "" {
+		cfg.Editor.Socket = v
+	}
 }
 
 // expandPaths expands ~ and relative paths
@@ -235,6 +485,19 @@ func (c *Config) expandPaths() error {
 	}
 	c.Directory.DataDir = dataDir
 
+	// Expand group members so they compare equal to recorded workspace paths
+	for name, members := range c.Workspaces.Groups {
+		expanded := make([]string, 0, len(members))
+		for _, member := range members {
+			path, err := expandPath(member)
+			if err != nil {
+				return err
+			}
+			expanded = append(expanded, path)
+		}
+		c.Workspaces.Groups[name] = expanded
+	}
+
 	return nil
 }
 
@@ -264,6 +527,27 @@ func (c *Config) validate() error {
 		return fmt.Errorf("query.default_limit cannot exceed max_limit")
 	}
 
+	// Validate socket and audit settings
+	if _, err := c.Sockets.FileMode(); err != nil {
+		return err
+	}
+	if c.Auth.AuditMaxSizeMB < 0 {
+		return fmt.Errorf("auth.audit_max_size_mb cannot be negative")
+	}
+
+	// Validate database settings
+	if c.Performance.PoolSize < 0 {
+		return fmt.Errorf("performance.pool_size cannot be negative")
+	}
+	if c.Database.BusyTimeoutMS < 0 {
+		return fmt.Errorf("database.busy_timeout_ms cannot be negative")
+	}
+	switch c.Database.SnapshotCodec {
+	case "", database.CodecGzip, database.CodecZstd:
+	default:
+		return fmt.Errorf("database.snapshot_compression must be %q or %q", database.CodecGzip, database.CodecZstd)
+	}
+
 	// Validate retention settings
 	if c.Retention.RetentionDays < 0 {
 		return fmt.Errorf("retention.retention_days cannot be negative")
@@ -272,6 +556,107 @@ func (c *Config) validate() error {
 		return fmt.Errorf("retention.max_edits_per_session must be positive")
 	}
 
+	// Validate context refresh settings
+	if c.Context.TTLHours < 0 {
+		return fmt.Errorf("context.ttl_hours cannot be negative")
+	}
+	if c.Context.RefreshIntervalMins < 0 {
+		return fmt.Errorf("context.refresh_interval_minutes cannot be negative")
+	}
+
+	// Validate check settings
+	if c.Checks.DebounceSecs < 0 {
+		return fmt.Errorf("checks.debounce_seconds cannot be negative")
+	}
+	if c.Checks.TimeoutSecs < 0 {
+		return fmt.Errorf("checks.timeout_seconds cannot be negative")
+	}
+	if c.Checks.MaxOutputKB < 0 {
+		return fmt.Errorf("checks.max_output_kb cannot be negative")
+	}
+	for pattern, checks := range c.Checks.Workspaces {
+		for _, check := range checks {
+			if check.Name == "" || check.Command == "" {
+				return fmt.Errorf("checks.workspaces.%q: every check needs a name and command", pattern)
+			}
+		}
+	}
+
+	// Validate ingest settings
+	if c.Ingest.BatchSize < 0 {
+		return fmt.Errorf("ingest.batch_size cannot be negative")
+	}
+	if c.Ingest.BatchSize > 0 {
+		if c.Ingest.FlushIntervalMS <= 0 {
+			return fmt.Errorf("ingest.flush_interval_ms must be positive")
+		}
+		if c.Ingest.QueueSize < c.Ingest.BatchSize {
+			return fmt.Errorf("ingest.queue_size cannot be less than batch_size")
+		}
+		if c.Ingest.MaxQueueMB <= 0 {
+			return fmt.Errorf("ingest.max_queue_mb must be positive")
+		}
+	}
+
+	// Validate webhooks
+	if c.Webhooks.TimeoutSecs < 0 {
+		return fmt.Errorf("webhooks.timeout_seconds cannot be negative")
+	}
+	if c.Webhooks.RetryAttempts < 0 {
+		return fmt.Errorf("webhooks.retry_attempts cannot be negative")
+	}
+	for i, hook := range c.Webhooks.Endpoints {
+		if err := hook.validate(); err != nil {
+			return fmt.Errorf("webhooks.endpoints[%d]: %w", i, err)
+		}
+	}
+
+	// Validate the digest
+	if c.Digest.Enabled {
+		if err := c.Digest.validate(c); err != nil {
+			return fmt.Errorf("digest.%w", err)
+		}
+	}
+
+	// Validate alerts
+	if c.Alerts.BurstFiles < 0 {
+		return fmt.Errorf("alerts.burst_files cannot be negative")
+	}
+	if c.Alerts.BurstFiles > 0 && c.Alerts.BurstSecs <= 0 {
+		return fmt.Errorf("alerts.burst_seconds must be positive")
+	}
+
+	// Validate the gRPC API
+	if c.GRPC.Enabled && c.GRPC.Socket == "" {
+		return fmt.Errorf("grpc.socket is required when grpc is enabled")
+	}
+	if (c.GRPC.TLSCert == "") != (c.GRPC.TLSKey == "") {
+		return fmt.Errorf("grpc.tls_cert and grpc.tls_key must be set together")
+	}
+	if c.GRPC.Listen != "" && c.GRPC.TLSCert == "" && !loopback(c.GRPC.Listen) {
+		return fmt.Errorf("grpc.listen %q isn't a loopback address; set grpc.tls_cert and grpc.tls_key to serve it over TLS", c.GRPC.Listen)
+	}
+
+	// Validate the editor socket
+	if c.Editor.Enabled && c.Editor.Socket == "" {
+		return fmt.Errorf("editor.socket is required when editor is enabled")
+	}
+	if c.Editor.RecentHours < 0 {
+		return fmt.Errorf("editor.recent_hours must be non-negative")
+	}
+
+	// Validate the file watcher
+	if c.Watch.Enabled && (c.Watch.SettleMS <= 0 || c.Watch.MaxDirs <= 0 || c.Watch.MaxFileKB <= 0) {
+		return fmt.Errorf("watch.settle_ms, watch.max_dirs and watch.max_file_kb must be positive")
+	}
+
+	// Validate users
+	for i, name := range c.Users.Admins {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("users.admins[%d] cannot be empty", i)
+		}
+	}
+
 	// Validate backup format
 	if c.Backup.Enabled {
 		if c.Backup.Format != "sqlite" && c.Backup.Format != "export" {
@@ -289,6 +674,16 @@ func (c *Config) validate() error {
 	if !validLevels[c.Logging.Level] {
 		return fmt.Errorf("logging.level must be one of: debug, info, warn, error")
 	}
+	if c.Logging.MaxSizeMB < 0 || c.Logging.MaxBackups < 0 {
+		return fmt.Errorf("logging.max_size_mb and logging.max_backups can't be negative")
+	}
+
+	// Validate workspace groups
+	for name, members := range c.Workspaces.Groups {
+		if len(members) == 0 {
+			return fmt.Errorf("workspaces.groups.%s has no workspaces", name)
+		}
+	}
 
 	return nil
 }
@@ -298,11 +693,41 @@ func (c *Config) GetDBPath() string {
 	return filepath.Join(c.Directory.DataDir, c.Database.Path)
 }
 
+// GetAuditLogPath returns the absolute audit log path, or "" when auditing
+// is off
+func (c *Config) GetAuditLogPath() string {
+	if c.Auth.AuditLog == "" {
+		return ""
+	}
+	return filepath.Join(c.Directory.DataDir, c.Auth.AuditLog)
+}
+
 // GetLogPath returns the absolute log path
 func (c *Config) GetLogPath() string {
 	return filepath.Join(c.Directory.DataDir, c.Logging.Path)
 }
 
+// GetTUILogPath returns the absolute path the TUI logs to, next to the
+// daemon's log with "-tui" before its extension
+func (c *Config) GetTUILogPath() string {
+	path := c.GetLogPath()
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-tui" + ext
+}
+
+// LoggerOptions returns the logging settings for a process logging to path
+// as component
+func (c *Config) LoggerOptions(path, component string) logger.Options {
+	return logger.Options{
+		Path:       path,
+		Level:      c.Logging.Level,
+		MaxSizeMB:  c.Logging.MaxSizeMB,
+		MaxBackups: c.Logging.MaxBackups,
+		Compress:   c.Logging.Compress,
+		Component:  component,
+	}
+}
+
 // GetBackupPath returns the absolute backup path
 func (c *Config) GetBackupPath() string {
 	return filepath.Join(c.Directory.DataDir, c.Backup.Path)
@@ -311,41 +736,61 @@ func (c *Config) GetBackupPath() string {
 // ToDBConfig converts to database.Config for backwards compatibility
 func (c *Config) ToDBConfig() (*database.Config, error) {
 	return &database.Config{
-		Path: c.GetDBPath(),
+		Path:               c.GetDBPath(),
+		PoolSize:           c.Performance.PoolSize,
+		BusyTimeoutMS:      c.Database.BusyTimeoutMS,
+		WALCheckpointPages: c.Database.WALCheckpointPages,
+		SnapshotCodec:      c.Database.SnapshotCodec,
 	}, nil
 }
 
 // ShouldTrackWorkspace checks if a workspace should be tracked
 func (c *Config) ShouldTrackWorkspace(workspacePath string) bool {
-	// If tracked list is non-empty, only track those
-	if len(c.Workspaces.Tracked) > 0 {
-		for _, prefix := range c.Workspaces.Tracked {
-			if matchPrefix(workspacePath, prefix) {
-				// Not in ignored list
-				for _, ignored := range c.Workspaces.Ignored {
-					if matchPrefix(workspacePath, ignored) {
-						return false
-					}
-				}
-				return true
-			}
-		}
-		return false
+	return c.WorkspaceRules().TrackWorkspace(workspacePath)
+}
+
+// ShouldTrackFile checks if an edit to a file in a workspace should be
+// tracked, applying the workspace's .claude/claude-mon-ignore file too
+func (c *Config) ShouldTrackFile(workspacePath, filePath string) bool {
+	return c.WorkspaceRules().TrackFile(workspacePath, filePath)
+}
+
+// WorkspaceRules returns the tracked and ignored workspace patterns
+func (c *Config) WorkspaceRules() workspace.Rules {
+	return workspace.Rules{
+		Tracked: c.Workspaces.Tracked,
+		Ignored: c.Workspaces.Ignored,
 	}
+}
 
-	// If tracked list is empty, track all except ignored
-	for _, ignored := range c.Workspaces.Ignored {
-		if matchPrefix(workspacePath, ignored) {
-			return false
-		}
+// WorkspaceChecks returns the checks configured for a workspace, from every
+// pattern matching it
+func (c *Config) WorkspaceChecks(workspacePath string) []CheckCommand {
+	if !c.Checks.Enabled || workspacePath == "" {
+		return nil
+	}
+	patterns := make([]string, 0, len(c.Checks.Workspaces))
+	for pattern := range c.Checks.Workspaces {
+		patterns = append(patterns, pattern)
 	}
+	sort.Strings(patterns)
 
-	return true
+	var checks []CheckCommand
+	for _, pattern := range patterns {
+		if workspace.Match(pattern, workspacePath) {
+			checks = append(checks, c.Checks.Workspaces[pattern]...)
+		}
+	}
+	return checks
 }
 
-// matchPrefix checks if path matches prefix
-func matchPrefix(path, prefix string) bool {
-	return path == prefix || (len(path) > len(prefix) && path[:len(prefix)+1] == prefix+"/")
+// GroupWorkspaces returns the workspace paths in a named group
+func (c *Config) GroupWorkspaces(name string) ([]string, error) {
+	members, ok := c.Workspaces.Groups[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown workspace group %q", name)
+	}
+	return members, nil
 }
 
 // WriteDefaultConfig writes the default configuration to a file
@@ -0,0 +1,87 @@
+package daemon
+
+import (
+	"strings"
+	"time"
+
+	workingctx "github.com/ztaylor/claude-mon/internal/context"
+	"github.com/ztaylor/claude-mon/internal/crash"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// ContextRefresher periodically revalidates project working contexts
+type ContextRefresher struct {
+	cfg      *Config
+	stopCh   chan struct{}
+	interval time.Duration
+	ttl      time.Duration
+}
+
+// NewContextRefresher creates a new context refresher
+func NewContextRefresher(cfg *Config) *ContextRefresher {
+	interval := time.Duration(cfg.Context.RefreshIntervalMins) * time.Minute
+	if interval == 0 {
+		interval = 30 * time.Minute // Default to 30 minutes
+	}
+	ttl := time.Duration(cfg.Context.TTLHours) * time.Hour
+	if ttl == 0 {
+		ttl = workingctx.DefaultTTL
+	}
+
+	return &ContextRefresher{
+		cfg:      cfg,
+		stopCh:   make(chan struct{}),
+		interval: interval,
+		ttl:      ttl,
+	}
+}
+
+// Start begins the background refresh goroutine
+func (cr *ContextRefresher) Start() {
+	if !cr.cfg.Context.RefreshEnabled {
+		logger.Log("Context refresher disabled")
+		return
+	}
+
+	logger.Log("Starting context refresher (interval: %v, TTL: %v)", cr.interval, cr.ttl)
+
+	go func() {
+		defer crash.Recover("daemon context refresh", crash.Exit)
+		ticker := time.NewTicker(cr.interval)
+		defer ticker.Stop()
+
+		// Run initial refresh after a short delay
+		select {
+		case <-time.After(10 * time.Second):
+			cr.runRefresh()
+		case <-cr.stopCh:
+			return
+		}
+
+		for {
+			select {
+			case <-ticker.C:
+				cr.runRefresh()
+			case <-cr.stopCh:
+				logger.Log("Context refresher stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the context refresher
+func (cr *ContextRefresher) Stop() {
+	close(cr.stopCh)
+}
+
+// runRefresh revalidates every project context
+func (cr *ContextRefresher) runRefresh() {
+	refreshed, err := workingctx.RefreshAll(cr.ttl)
+	if err != nil {
+		logger.Log("Context refresh failed: %v", err)
+	}
+	for root, sections := range refreshed {
+		logger.Log("Refreshed context for %s: %s", root, strings.Join(sections, ", "))
+	}
+}
@@ -1,22 +1,30 @@
 package daemon
 
 import (
-	"bytes"
-	"compress/gzip"
+	"context"
 	"database/sql"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	"os/signal"
+	"os/user"
+	"slices"
+	"strconv"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/ztaylor/claude-mon/internal/auth"
+	"github.com/ztaylor/claude-mon/internal/crash"
 	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/filter"
 	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/internal/symbols"
+	"google.golang.org/grpc"
 )
 
 const (
@@ -26,6 +34,10 @@ const (
 	DefaultQuerySocketPath = "/tmp/claude-mon-query.sock"
 )
 
+// Version is the claude-mon version reported in status queries, so clients
+// can tell when the running daemon is older than they are
+var Version = "dev"
+
 // WorkspaceActivity tracks activity for a workspace
 type WorkspaceActivity struct {
 	Path         string    `json:"path"`
@@ -37,6 +49,7 @@ type WorkspaceActivity struct {
 // Daemon manages the daemon server
 type Daemon struct {
 	cfg            *Config
+	cfgMu          sync.RWMutex // Guards fields updated by config reload
 	db             *database.DB
 	cleanupManager *CleanupManager
 	backupManager  *BackupManager
@@ -47,6 +60,37 @@ type Daemon struct {
 	wg             sync.WaitGroup
 	shutdown       chan struct{}
 
+	// Revalidates project working contexts in the background
+	contextRefresher *ContextRefresher
+
+	// Runs configured lint/test commands after edits
+	checkRunner *CheckRunner
+
+	// Writes edit payloads in batches
+	ingest *IngestQueue
+
+	// POSTs events to configured webhooks
+	webhooks *WebhookSender
+
+	// Raises alerts on bursts of edits and edits to protected files
+	alerts *AlertMonitor
+
+	// Records changes made to workspace files outside Claude's hooks
+	watcher *FileWatcher
+
+	// Posts the daily activity digest
+	digestPoster *DigestPoster
+
+	// Serves the gRPC API, if enabled, and streams to its subscribers
+	grpcServer *grpc.Server
+	notifier   notifier
+
+	// Serves editor plugins, if enabled
+	editorListener net.Listener
+
+	// Logs every query and who made it
+	audit auditLog
+
 	// Activity tracking
 	workspacesMu sync.RWMutex
 	workspaces   map[string]*WorkspaceActivity
@@ -87,6 +131,27 @@ func New(cfg *Config) (*Daemon, error) {
 	// Initialize backup manager
 	d.backupManager = NewBackupManager(cfg)
 
+	// Initialize context refresher
+	d.contextRefresher = NewContextRefresher(cfg)
+
+	// Initialize post-edit checks
+	d.checkRunner = NewCheckRunner(d)
+
+	// Initialize edit batching
+	d.ingest = NewIngestQueue(d)
+
+	// Initialize webhooks
+	d.webhooks = NewWebhookSender(d)
+
+	// Initialize alerts
+	d.alerts = NewAlertMonitor(d)
+
+	// Initialize the file watcher
+	d.watcher = NewFileWatcher(d)
+
+	// Initialize the daily digest
+	d.digestPoster = NewDigestPoster(d)
+
 	return d, nil
 }
 
@@ -97,20 +162,46 @@ func (d *Daemon) Start() error {
 	os.Remove(d.queryPath)
 
 	// Create data socket listener
-	listener, err := net.Listen("unix", d.socketPath)
+	listener, err := d.listenSocket(d.socketPath)
 	if err != nil {
-		return fmt.Errorf("failed to listen on %s: %w", d.socketPath, err)
+		return err
 	}
 	d.listener = listener
 
 	// Create query socket listener
-	queryListener, err := net.Listen("unix", d.queryPath)
+	queryListener, err := d.listenSocket(d.queryPath)
 	if err != nil {
-		return fmt.Errorf("failed to listen on %s: %w", d.queryPath, err)
+		return err
 	}
 	d.queryListener = queryListener
 
+	// Log queries
+	if err := d.audit.open(d.cfg.GetAuditLogPath(), d.cfg.Auth.AuditMaxSizeMB); err != nil {
+		return err
+	}
+
+	// Serve the gRPC API
+	if d.cfg.GRPC.Enabled {
+		os.Remove(d.cfg.GRPC.Socket)
+		if err := d.serveGRPC(); err != nil {
+			return err
+		}
+	}
+
+	// Serve editor plugins
+	if d.cfg.Editor.Enabled {
+		os.Remove(d.cfg.Editor.Socket)
+		editorListener, err := d.listenSocket(d.cfg.Editor.Socket)
+		if err != nil {
+			return err
+		}
+		d.editorListener = editorListener
+		d.wg.Add(1)
+		go d.acceptEditors()
+	}
+
 	logger.Log("Daemon started on %s (query: %s)", d.socketPath, d.queryPath)
+	d.recordEvent("", EventDaemonStart, SeverityInfo, "daemon started")
 
 	// Start cleanup manager
 	d.cleanupManager.Start()
@@ -118,6 +209,12 @@ func (d *Daemon) Start() error {
 	// Start backup manager
 	d.backupManager.Start()
 
+	// Start context refresher
+	d.contextRefresher.Start()
+
+	// Start digest poster
+	d.digestPoster.Start()
+
 	// Start accept goroutines
 	d.wg.Add(2)
 	go d.acceptConnections()
@@ -127,6 +224,86 @@ func (d *Daemon) Start() error {
 	return d.waitForShutdown()
 }
 
+// listenSocket listens on a unix socket only the configured user and group
+// can connect to. Its permissions are set as soon as it's created, and
+// peerListener drops connections from anyone else, including any made
+// before they were.
+func (d *Daemon) listenSocket(path string) (net.Listener, error) {
+	mode, err := d.cfg.Sockets.FileMode()
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to set permissions of %s: %w", path, err)
+	}
+	gid := -1
+	if d.cfg.Sockets.Group != "" {
+		group, err := user.LookupGroup(d.cfg.Sockets.Group)
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("socket group: %w", err)
+		}
+		gid, _ = strconv.Atoi(group.Gid)
+		if err := os.Chown(path, -1, gid); err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("failed to give %s to group %s: %w", path, d.cfg.Sockets.Group, err)
+		}
+	}
+	return &peerListener{Listener: listener, mode: mode, gid: gid}, nil
+}
+
+// peerListener accepts the connections a socket's permissions allow,
+// checked against the peer's credentials
+type peerListener struct {
+	net.Listener
+	mode os.FileMode // The socket's permissions
+	gid  int         // Group owning the socket, or -1
+}
+
+func (l *peerListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		uid, _ := peerCredentials(conn)
+		if peerAllowed(uid, l.mode, l.gid) {
+			return conn, nil
+		}
+		logger.Log("Refused connection from uid %d on %s", uid, l.Addr())
+		conn.Close()
+	}
+}
+
+// peerAllowed reports whether a user may connect to a socket: root and the
+// daemon's user always may, the owning group's members if mode shares it
+// with the group and anyone if it's open to others. Peers whose
+// credentials can't be read (-1) are left to the socket's permissions.
+func peerAllowed(uid int, mode os.FileMode, gid int) bool {
+	if uid < 0 || uid == 0 || uid == os.Getuid() || mode&0006 != 0 {
+		return true
+	}
+	if mode&0060 == 0 {
+		return false
+	}
+	if gid < 0 {
+		gid = os.Getgid()
+	}
+	u, err := user.LookupId(strconv.Itoa(uid))
+	if err != nil {
+		return false
+	}
+	groups, err := u.GroupIds()
+	return err == nil && slices.Contains(groups, strconv.Itoa(gid))
+}
+
 // acceptConnections accepts data connections from hooks
 func (d *Daemon) acceptConnections() {
 	defer d.wg.Done()
@@ -173,21 +350,42 @@ func (d *Daemon) acceptQueries() {
 func (d *Daemon) handleConnection(conn net.Conn) {
 	defer d.wg.Done()
 	defer conn.Close()
+	// A payload that panics drops its connection, not the daemon
+	defer crash.Recover("daemon payload", nil)
 
 	logger.Log("New data connection from %s", conn.RemoteAddr())
+	sender := peerUser(conn)
 
 	decoder := json.NewDecoder(conn)
 	for {
-		var payload HookPayload
-		if err := decoder.Decode(&payload); err != nil {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
 			if err != io.EOF {
 				logger.Log("Decode error: %v", err)
 			}
 			break
 		}
+		crash.SetLastPayload(raw)
+		var payload HookPayload
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			logger.Log("Decode error: %v", err)
+			break
+		}
+		payload.user = sender
 
-		if err := d.processPayload(&payload); err != nil {
+		if err := d.authorize(payload.Token, auth.ScopeIngest, true); err != nil {
+			logger.Log("Payload rejected: %v", err)
+			json.NewEncoder(conn).Encode(map[string]string{"error": err.Error()})
+			continue
+		}
+
+		if err := d.processPayload(&payload); errors.Is(err, ErrIngestBusy) {
+			// Backpressure: the hook may retry rather than wait
+			json.NewEncoder(conn).Encode(map[string]string{"status": "busy", "error": err.Error()})
+		} else if err != nil {
 			logger.Log("Process payload error: %v", err)
+			d.recordEvent(payload.Workspace, EventFailure, SeverityError,
+				fmt.Sprintf("%s payload rejected: %v", payload.Type, err))
 			// Send error back
 			json.NewEncoder(conn).Encode(map[string]string{"error": err.Error()})
 		} else {
@@ -201,9 +399,11 @@ func (d *Daemon) handleConnection(conn net.Conn) {
 func (d *Daemon) handleQuery(conn net.Conn) {
 	defer d.wg.Done()
 	defer conn.Close()
+	defer crash.Recover("daemon query", nil)
 
 	logger.Log("New query connection from %s", conn.RemoteAddr())
 
+	start := time.Now()
 	decoder := json.NewDecoder(conn)
 	var query Query
 	if err := decoder.Decode(&query); err != nil {
@@ -211,8 +411,28 @@ func (d *Daemon) handleQuery(conn net.Conn) {
 		return
 	}
 
-	// Execute query
+	if err := d.authorizeQuery(&query, true); err != nil {
+		logger.Log("Query rejected: %v", err)
+		d.auditQuery(conn, &query, start, err, nil)
+		json.NewEncoder(conn).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if err := d.authorizeUser(conn, &query); err != nil {
+		logger.Log("Query rejected: %v", err)
+		d.auditQuery(conn, &query, start, err, nil)
+		json.NewEncoder(conn).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	// Execute query, seeing the edits queued before it
+	d.ingest.Flush()
+	if query.Stream || query.Type == "export" {
+		err := d.streamQuery(conn, &query)
+		d.auditQuery(conn, &query, start, nil, err)
+		return
+	}
 	result, err := d.executeQuery(&query)
+	d.auditQuery(conn, &query, start, nil, err)
 	if err != nil {
 		logger.Log("Query execution error: %v", err)
 		json.NewEncoder(conn).Encode(map[string]string{"error": err.Error()})
@@ -227,35 +447,234 @@ func (d *Daemon) handleQuery(conn net.Conn) {
 
 // HookPayload represents data from Claude hooks
 type HookPayload struct {
-	SessionID      int64    `json:"session_id"`
-	Workspace      string   `json:"workspace"`
-	WorkspaceName  string   `json:"workspace_name"`
-	Branch         string   `json:"branch"`
-	CommitSHA      string   `json:"commit_sha"`
-	VCSType        string   `json:"vcs_type"` // "git" or "jj"
-	ToolName       string   `json:"tool_name"`
-	FilePath       string   `json:"file_path"`
-	OldString      string   `json:"old_string"`
-	NewString      string   `json:"new_string"`
-	FileContentB64 string   `json:"file_content_b64"` // base64-encoded file content
-	LineNum        int      `json:"line_num"`
-	LineCount      int      `json:"line_count"`
-	Type           string   `json:"type"` // "edit" or "prompt"
-	PromptName     string   `json:"prompt_name,omitempty"`
-	PromptDesc     string   `json:"prompt_description,omitempty"`
-	PromptTags     []string `json:"prompt_tags,omitempty"`
+	SessionID      int64     `json:"session_id"`
+	Workspace      string    `json:"workspace"`
+	WorkspaceName  string    `json:"workspace_name"`
+	Branch         string    `json:"branch"`
+	CommitSHA      string    `json:"commit_sha"`
+	VCSType        string    `json:"vcs_type"` // "git" or "jj"
+	ToolName       string    `json:"tool_name"`
+	FilePath       string    `json:"file_path"`
+	OldString      string    `json:"old_string"`
+	NewString      string    `json:"new_string"`
+	FileContentB64 string    `json:"file_content_b64"` // base64-encoded file content
+	LineNum        int       `json:"line_num"`
+	LineCount      int       `json:"line_count"`
+	Type           string    `json:"type"` // "edit", "snapshot", "prompt", "prompt_injection", "event", "bookmark", "note", "review", "chat", "ralph_loop", "plan", "session_name" or "hook"
+	PromptName     string    `json:"prompt_name,omitempty"`
+	PromptVersion  int       `json:"prompt_version,omitempty"` // For "prompt_injection" payloads
+	InjectMethod   string    `json:"inject_method,omitempty"`  // For "prompt_injection" payloads: "tmux" or "clipboard"
+	InjectTarget   string    `json:"inject_target,omitempty"`  // For "prompt_injection" payloads: tmux pane
+	PromptDesc     string    `json:"prompt_description,omitempty"`
+	PromptTags     []string  `json:"prompt_tags,omitempty"`
+	EventKind      string    `json:"event_kind,omitempty"` // For "event" payloads, e.g. "ralph_start"
+	Severity       string    `json:"severity,omitempty"`   // For "event" payloads: "info", "warning", "error"
+	Message        string    `json:"message,omitempty"`    // For "event" payloads
+	EditID         int64     `json:"edit_id,omitempty"`    // For "bookmark", "note" and "review" payloads; 0 = match by file_path/timestamp
+	Bookmarked     bool      `json:"bookmarked,omitempty"` // For "bookmark" payloads: set (true) or clear (false)
+	Note           string    `json:"note,omitempty"`       // For "note" payloads: the review note; empty removes it
+	Review         string    `json:"review,omitempty"`     // For "review" payloads: "approved", "rejected" or empty to clear
+	Timestamp      time.Time `json:"timestamp,omitempty"`  // For "bookmark", "note" and "review" payloads: when the edit was made
+	Token          string    `json:"token,omitempty"`      // API token, when auth is required
+
+	// For "chat" payloads: messages to append to a Claude CLI session's
+	// transcript. On "edit" payloads ChatSessionID is the session that made the edit.
+	ChatSessionID string                  `json:"chat_session_id,omitempty"`
+	ChatPurpose   string                  `json:"chat_purpose,omitempty"`
+	ChatMessages  []*database.ChatMessage `json:"chat_messages,omitempty"`
+
+	// For "edit" payloads: the Ralph loop iteration running when the edit
+	// was made, read by the hook from the loop's state file
+	RalphIteration int `json:"ralph_iteration,omitempty"`
+
+	// For "ralph_loop" payloads: a loop that has ended
+	RalphLoop *database.RalphLoop `json:"ralph_loop,omitempty"`
+
+	// For "plan" payloads: the plan the workspace works from; empty clears it
+	PlanPath string `json:"plan_path,omitempty"`
+
+	// For "session_name" payloads: the name given to the Claude session
+	// ChatSessionID in the History pane; empty clears it
+	SessionName string `json:"session_name,omitempty"`
+
+	// For "snapshot" payloads, sent before an edit with the file's content
+	// in FileContentB64: the file doesn't exist yet
+	FileMissing bool `json:"file_missing,omitempty"`
+
+	// For "hook" payloads: the Claude Code hook that fired in session
+	// ChatSessionID (one of database.HookTypes) and, for SessionStart, why
+	// the session started. PreToolUse hooks name the tool and its file in
+	// ToolName and FilePath.
+	HookEvent string `json:"hook_event,omitempty"`
+	Source    string `json:"source,omitempty"`
+
+	// User whose hook sent the payload, from the socket's peer credentials
+	user string
 }
 
 // processPayload processes incoming hook data
 func (d *Daemon) processPayload(payload *HookPayload) error {
 	// Check if workspace should be tracked
-	if !d.cfg.ShouldTrackWorkspace(payload.Workspace) {
+	d.cfgMu.RLock()
+	tracked := d.cfg.ShouldTrackWorkspace(payload.Workspace)
+	fileTracked := d.cfg.ShouldTrackFile(payload.Workspace, payload.FilePath)
+	d.cfgMu.RUnlock()
+	if !tracked {
 		logger.Log("Workspace %s is being ignored", payload.Workspace)
 		return nil
 	}
+	if !fileTracked {
+		logger.Log("File %s in %s is being ignored", payload.FilePath, payload.Workspace)
+		return nil
+	}
+
+	// Watch the workspace for changes no hook reports, leaving the files
+	// hooks do report to them
+	if payload.ToolName != database.ToolExternal {
+		d.watcher.Watch(payload)
+		if payload.FilePath != "" && (payload.Type == "edit" || payload.Type == "hook") {
+			d.watcher.Claim(payload.FilePath)
+		}
+	}
 
-	// Track workspace activity
-	d.trackWorkspaceActivity(payload.Workspace, payload.WorkspaceName, payload.Type == "edit")
+	// Edits are written in batches; other payloads wait for the edits queued
+	// before them, so they see them (a bookmark of an edit just made, say)
+	if payload.Type == "edit" && d.ingest.Enabled() {
+		return d.ingest.Add(payload)
+	}
+	d.ingest.Flush()
+
+	// Timeline events don't count as workspace activity or need a session
+	if payload.Type == "event" {
+		if payload.EventKind == "" {
+			return fmt.Errorf("event_kind required for event payloads")
+		}
+		severity := payload.Severity
+		if severity == "" {
+			severity = SeverityInfo
+		}
+		d.recordEvent(payload.Workspace, payload.EventKind, severity, payload.Message)
+		logger.Log("Recorded event: %s (%s)", payload.EventKind, payload.Workspace)
+		return nil
+	}
+
+	// Bookmarks, notes and reviews mark an existing edit rather than
+	// recording new activity
+	if payload.Type == "bookmark" || payload.Type == "note" || payload.Type == "review" {
+		editID := payload.EditID
+		if editID == 0 {
+			id, err := d.db.FindEditID(payload.Workspace, payload.FilePath, payload.Timestamp)
+			if err != nil {
+				return err
+			}
+			editID = id
+		}
+		switch payload.Type {
+		case "note":
+			if err := d.db.SetEditNote(editID, payload.Note); err != nil {
+				return err
+			}
+			logger.Log("Set note on edit %d: %q", editID, payload.Note)
+			return nil
+		case "review":
+			if err := d.db.SetEditReview(editID, payload.Review); err != nil {
+				return err
+			}
+			logger.Log("Set review on edit %d: %q", editID, payload.Review)
+			return nil
+		}
+		if err := d.db.SetEditBookmarked(editID, payload.Bookmarked); err != nil {
+			return err
+		}
+		logger.Log("Set bookmark on edit %d: %v", editID, payload.Bookmarked)
+		return nil
+	}
+
+	// Prompt injections are history for the prompt, not a new session
+	if payload.Type == "prompt_injection" {
+		if payload.PromptName == "" {
+			return fmt.Errorf("prompt_name required for prompt_injection payloads")
+		}
+		if err := d.db.RecordPromptInjection(&database.PromptInjection{
+			PromptName:    payload.PromptName,
+			PromptVersion: payload.PromptVersion,
+			WorkspacePath: payload.Workspace,
+			Method:        payload.InjectMethod,
+			Target:        payload.InjectTarget,
+		}); err != nil {
+			return err
+		}
+		logger.Log("Recorded injection of prompt %s (%s)", payload.PromptName, payload.Workspace)
+		return nil
+	}
+
+	// Chat transcripts are kept per Claude session, apart from hook sessions
+	if payload.Type == "chat" {
+		if payload.ChatSessionID == "" {
+			return fmt.Errorf("chat_session_id required for chat payloads")
+		}
+		if err := d.db.RecordChatMessages(&database.ChatSession{
+			SessionID:     payload.ChatSessionID,
+			WorkspacePath: payload.Workspace,
+			Purpose:       payload.ChatPurpose,
+		}, payload.ChatMessages); err != nil {
+			return err
+		}
+		logger.Log("Recorded %d chat message(s) for session %s", len(payload.ChatMessages), payload.ChatSessionID)
+		return nil
+	}
+
+	// Ended Ralph loops are kept per workspace for the Ralph history
+	if payload.Type == "ralph_loop" {
+		if payload.RalphLoop == nil {
+			return fmt.Errorf("ralph_loop required for ralph_loop payloads")
+		}
+		loop := *payload.RalphLoop
+		loop.WorkspacePath = payload.Workspace
+		if err := d.db.RecordRalphLoop(&loop); err != nil {
+			return err
+		}
+		logger.Log("Recorded Ralph loop (%s after %d iterations) for %s", loop.Outcome, loop.Iterations, payload.Workspace)
+		d.webhooks.Send(ralphWebhookEvent(&loop, payload))
+		return nil
+	}
+
+	// The plan a workspace works from, chosen in the TUI's plan list
+	if payload.Type == "plan" {
+		if err := d.db.SetWorkspacePlan(payload.Workspace, payload.PlanPath); err != nil {
+			return err
+		}
+		logger.Log("Workspace %s plan set to %q", payload.Workspace, payload.PlanPath)
+		return nil
+	}
+
+	// A Claude session's name, given in the TUI's History pane
+	if payload.Type == "session_name" {
+		if payload.ChatSessionID == "" {
+			return fmt.Errorf("chat_session_id required for session_name payloads")
+		}
+		if err := d.db.SetSessionName(payload.Workspace, payload.ChatSessionID, payload.SessionName); err != nil {
+			return err
+		}
+		logger.Log("Session %s in %s named %q", payload.ChatSessionID, payload.Workspace, payload.SessionName)
+		return nil
+	}
+
+	// Hook events belong to the Claude session that fired them
+	if payload.Type == "hook" {
+		if !slices.Contains(database.HookTypes, payload.HookEvent) {
+			return fmt.Errorf("unknown hook event %q", payload.HookEvent)
+		}
+		if payload.ChatSessionID == "" {
+			return fmt.Errorf("chat_session_id required for hook payloads")
+		}
+	}
+
+	// Track workspace activity, flagging resumption after a long pause
+	lastActivity := d.trackWorkspaceActivity(payload.Workspace, payload.WorkspaceName, payload.Type == "edit")
+	if payload.Type == "edit" {
+		d.checkPause(payload.Workspace, lastActivity)
+	}
 
 	// Ensure session exists
 	sessionID, err := d.db.UpsertSession(
@@ -270,44 +689,29 @@ func (d *Daemon) processPayload(payload *HookPayload) error {
 
 	switch payload.Type {
 	case "edit":
-		edit := &database.Edit{
-			SessionID: sessionID,
-			ToolName:  payload.ToolName,
-			FilePath:  payload.FilePath,
-			OldString: payload.OldString,
-			NewString: payload.NewString,
-			LineNum:   payload.LineNum,
-			LineCount: payload.LineCount,
-			CommitSHA: payload.CommitSHA,
-			VCSType:   payload.VCSType,
-		}
-
-		// Decode and compress file content if provided
-		if payload.FileContentB64 != "" {
-			decoded, err := base64.StdEncoding.DecodeString(payload.FileContentB64)
-			if err != nil {
-				logger.Log("Warning: failed to decode file content: %v", err)
-			} else {
-				// Compress the file content with gzip
-				var buf bytes.Buffer
-				w := gzip.NewWriter(&buf)
-				if _, err := w.Write(decoded); err != nil {
-					logger.Log("Warning: failed to compress file content: %v", err)
-				} else if err := w.Close(); err != nil {
-					logger.Log("Warning: failed to finalize compression: %v", err)
-				} else {
-					edit.FileSnapshot = buf.Bytes()
-					logger.Log("Compressed file snapshot: %d bytes -> %d bytes", len(decoded), len(edit.FileSnapshot))
-				}
-			}
-		} else {
-			logger.Log("No file_content_b64 provided for %s (file: %s)", payload.ToolName, payload.FilePath)
-		}
-
+		edit := editFromPayload(sessionID, payload)
+		started := d.webhooks.sessionStarted(payload)
 		if err := d.db.RecordEdit(edit); err != nil {
 			return fmt.Errorf("failed to record edit: %w", err)
 		}
-		logger.Log("Recorded edit: %s to %s (vcs=%s, sha=%s)", payload.ToolName, payload.FilePath, payload.VCSType, payload.CommitSHA)
+		d.afterEdit(edit, payload, started)
+
+	case "snapshot":
+		d.recordSnapshot(sessionID, payload)
+
+	case "hook":
+		if err := d.db.RecordHookEvent(&database.HookEvent{
+			SessionID:     sessionID,
+			ChatSessionID: payload.ChatSessionID,
+			Type:          payload.HookEvent,
+			Source:        payload.Source,
+			ToolName:      payload.ToolName,
+			FilePath:      payload.FilePath,
+			User:          payload.user,
+		}); err != nil {
+			return err
+		}
+		logger.Log("Recorded %s hook for session %s", payload.HookEvent, payload.ChatSessionID)
 
 	case "prompt":
 		prompt := &database.Prompt{
@@ -330,8 +734,64 @@ func (d *Daemon) processPayload(payload *HookPayload) error {
 	return nil
 }
 
+// editFromPayload builds the edit an "edit" payload records, with the
+// file's content compressed into its snapshot and the declarations it
+// touched summarized
+func editFromPayload(sessionID int64, payload *HookPayload) *database.Edit {
+	edit := &database.Edit{
+		SessionID: sessionID,
+		ToolName:  payload.ToolName,
+		FilePath:  payload.FilePath,
+		OldString: payload.OldString,
+		NewString: payload.NewString,
+		LineNum:   payload.LineNum,
+		LineCount: payload.LineCount,
+		CommitSHA: payload.CommitSHA,
+		VCSType:   payload.VCSType,
+
+		ChatSessionID:  payload.ChatSessionID,
+		RalphIteration: payload.RalphIteration,
+		User:           payload.user,
+	}
+
+	// Decode file content if provided; the database stores it compressed,
+	// once however many edits share it
+	var fileContent string
+	if payload.FileContentB64 != "" {
+		decoded, err := base64.StdEncoding.DecodeString(payload.FileContentB64)
+		if err != nil {
+			logger.Log("Warning: failed to decode file content: %v", err)
+		} else {
+			fileContent = string(decoded)
+			edit.FileContent = fileContent
+		}
+	} else {
+		logger.Log("No file_content_b64 provided for %s (file: %s)", payload.ToolName, payload.FilePath)
+	}
+	edit.Summary = symbols.Summarize(payload.FilePath, payload.OldString, payload.NewString, fileContent)
+	return edit
+}
+
+// afterEdit follows up on a recorded edit: queueing checks, flagging
+// anomalies, raising alerts, keeping the file's content from before it and
+// sending webhooks, announcing the Claude session first if the edit started
+// it
+func (d *Daemon) afterEdit(edit *database.Edit, payload *HookPayload, sessionStarted bool) {
+	logger.Log("Recorded edit: %s to %s (vcs=%s, sha=%s)", payload.ToolName, payload.FilePath, payload.VCSType, payload.CommitSHA)
+	d.checkRunner.Queue(payload.Workspace, payload.FilePath)
+	d.checkEditAnomalies(payload)
+	d.alerts.Check(payload.Workspace, payload.FilePath, time.Now())
+	d.snapshotBeforeEdit(edit.SessionID, payload)
+	if sessionStarted {
+		d.webhooks.Send(sessionWebhookEvent(payload))
+	}
+	d.webhooks.Send(editWebhookEvent(edit, payload))
+	d.publishEdit(edit, payload)
+}
+
 // trackWorkspaceActivity updates the activity tracker for a workspace
-func (d *Daemon) trackWorkspaceActivity(path, name string, isEdit bool) {
+// and returns the previous activity time (zero if the workspace is new)
+func (d *Daemon) trackWorkspaceActivity(path, name string, isEdit bool) time.Time {
 	d.workspacesMu.Lock()
 	defer d.workspacesMu.Unlock()
 
@@ -344,10 +804,12 @@ func (d *Daemon) trackWorkspaceActivity(path, name string, isEdit bool) {
 		d.workspaces[path] = activity
 	}
 
+	previous := activity.LastActivity
 	activity.LastActivity = time.Now()
 	if isEdit {
 		activity.EditCount++
 	}
+	return previous
 }
 
 // sqlInt64 converts int64 to sql.NullInt64
@@ -357,29 +819,78 @@ func sqlInt64(v int64) sql.NullInt64 {
 
 // Query represents a database query
 type Query struct {
-	Type          string `json:"type"` // "recent", "workspace", "file", "prompts", "sessions", "status"
-	WorkspacePath string `json:"workspace_path,omitempty"`
-	FilePath      string `json:"file_path,omitempty"`
-	Name          string `json:"name,omitempty"`
-	Limit         int    `json:"limit,omitempty"`
+	Type          string                  `json:"type"`                     // "recent", "timeline", "workspace", "file", "search", "snapshots", "snapshot", "checks", "bookmarks", "prompts", "prompt_library", "prompt_save", "prompt_delete", "sessions", "status", "events", "injections", "prompt_stats", "chat_sessions", "chat_messages", "chat_edits", "ralph_edits", "ralph_loops", "workspace_plan", "session_names", "hooks", "review", "digest", "groups", "tokens", "token_create", "token_revoke", "workspace_export", "workspace_import", "workspace_purge", "export", "dedupe"
+	WorkspacePath string                  `json:"workspace_path,omitempty"` // Workspace for "workspace", "status", "ralph_edits", "workspace_plan", "session_names", "hooks", "review" and "workspace_*"; scopes "search", "sessions", "chat_sessions", "ralph_loops", "digest" and "export"
+	Group         string                  `json:"group,omitempty"`          // Scope "recent", "timeline", "export", "workspace", "search", "bookmarks", "sessions", "events", "digest" and "status" to a workspace group
+	FilePath      string                  `json:"file_path,omitempty"`
+	Name          string                  `json:"name,omitempty"`            // Prompt name for "prompts"/"injections"/"prompt_delete", token name for token queries
+	Tag           string                  `json:"tag,omitempty"`             // Prompt tag for "prompts"
+	ChatSessionID string                  `json:"chat_session_id,omitempty"` // Session for "chat_messages" and "chat_edits"
+	Limit         int                     `json:"limit,omitempty"`
+	Since         time.Time               `json:"since,omitempty"`  // Lower time bound (zero = unbounded)
+	Until         time.Time               `json:"until,omitempty"`  // Upper time bound (zero = unbounded)
+	Cursor        string                  `json:"cursor,omitempty"` // For "timeline" and "workspace": NextCursor of the previous page
+	Filter        string                  `json:"filter,omitempty"` // Filter expression for "recent"/"workspace"/"search", e.g. "path:internal/** tool:Write since:1h"
+	Text          string                  `json:"text,omitempty"`   // For "search": text the edits' old or new strings, summaries or notes contain
+	User          string                  `json:"user,omitempty"`   // Only this user's edits and workspaces; set to the querying user's own on a shared daemon
+	Scope         string                  `json:"scope,omitempty"`  // For "token_create": "read", "ingest" or "admin"
+	Token         string                  `json:"token,omitempty"`  // API token, when auth is required
+	Dump          *database.WorkspaceDump `json:"dump,omitempty"`   // Rows to restore for "workspace_import"
+	Prompt        *database.Prompt        `json:"prompt,omitempty"` // Prompt for "prompt_save", with its new version backups
+
+	// For "snapshot": the snapshot to return with its content
+	SnapshotID int64 `json:"snapshot_id,omitempty"`
+
+	// For "timeline" and "export": answer with one line per edit rather
+	// than one result (see StreamLine). "export" is only streamed.
+	Stream bool `json:"stream,omitempty"`
 }
 
 // StatusResult represents daemon status
 type StatusResult struct {
 	Running         bool                          `json:"running"`
+	Version         string                        `json:"version"`
 	Uptime          time.Duration                 `json:"uptime"`
 	UptimeStr       string                        `json:"uptime_str"`
 	ActiveWorkspace *WorkspaceActivity            `json:"active_workspace,omitempty"`
 	Workspaces      map[string]*WorkspaceActivity `json:"workspaces"`
+	Ingest          IngestStats                   `json:"ingest"`
+	Database        database.PoolStats            `json:"database"`
 }
 
 // QueryResult represents query results
 type QueryResult struct {
-	Type     string              `json:"type"`
-	Edits    []*database.Edit    `json:"edits,omitempty"`
-	Prompts  []*database.Prompt  `json:"prompts,omitempty"`
-	Sessions []*database.Session `json:"sessions,omitempty"`
-	Status   *StatusResult       `json:"status,omitempty"`
+	Type        string                      `json:"type"`
+	Edits       []*database.Edit            `json:"edits,omitempty"`
+	Timeline    []*database.TimelineEdit    `json:"timeline,omitempty"`    // Edits across workspaces, from "timeline"
+	NextCursor  string                      `json:"next_cursor,omitempty"` // Cursor for the next "timeline" or "workspace" page, if there may be one
+	Prompts     []*database.Prompt          `json:"prompts,omitempty"`
+	Sessions    []*database.Session         `json:"sessions,omitempty"`
+	Events      []*database.Event           `json:"events,omitempty"`
+	Injections  []*database.PromptInjection `json:"injections,omitempty"`
+	PromptStats []*database.PromptStat      `json:"prompt_stats,omitempty"`
+	Chats       []*database.ChatSession     `json:"chats,omitempty"`
+	Messages    []*database.ChatMessage     `json:"messages,omitempty"` // Transcript from "chat_messages"
+	RalphLoops  []*database.RalphLoop       `json:"ralph_loops,omitempty"`
+	PlanPath    string                      `json:"plan_path,omitempty"` // From "workspace_plan"
+	Names       map[string]string           `json:"names,omitempty"`     // Session ID -> name, from "session_names"
+	Hooks       []*database.HookEvent       `json:"hooks,omitempty"`     // From "hooks", oldest first
+	Review      *database.ReviewStats       `json:"review,omitempty"`    // Review progress from "review", with the rejected edits in Edits
+	Status      *StatusResult               `json:"status,omitempty"`
+	Tokens      []*database.APIToken        `json:"tokens,omitempty"`
+	Groups      map[string][]string         `json:"groups,omitempty"`
+	Token       string                      `json:"token,omitempty"`   // New token from "token_create", shown once
+	Dump        *database.WorkspaceDump     `json:"dump,omitempty"`    // Rows from "workspace_export"
+	Removed     int64                       `json:"removed,omitempty"` // Sessions removed by "workspace_purge"
+	Dedupe      *database.DedupeStats       `json:"dedupe,omitempty"`  // What "dedupe" moved and saved
+	Digest      *database.Digest            `json:"digest,omitempty"`  // Activity summary from "digest"
+	Error       string                      `json:"error,omitempty"`   // Set instead of results when the query fails
+
+	// From "snapshots"; "snapshot" also returns the content
+	Snapshots []*database.FileSnapshot `json:"snapshots,omitempty"`
+
+	// From "checks", newest run first
+	Checks []*database.CheckResult `json:"checks,omitempty"`
 }
 
 // executeQuery executes a database query
@@ -391,19 +902,42 @@ func (d *Daemon) executeQuery(query *Query) (*QueryResult, error) {
 		Sessions: []*database.Session{},
 	}
 
+	d.cfgMu.RLock()
+	defaultLimit, maxLimit := d.cfg.Query.DefaultLimit, d.cfg.Query.MaxLimit
+	var group []string
+	var groupErr error
+	if query.Group != "" {
+		group, groupErr = d.cfg.GroupWorkspaces(query.Group)
+	}
+	d.cfgMu.RUnlock()
+	if groupErr != nil {
+		return nil, groupErr
+	}
+	if query.User != "" {
+		if err := d.checkUserWorkspace(query.User, query.WorkspacePath); err != nil {
+			return nil, err
+		}
+	}
+
 	limit := query.Limit
 	if limit <= 0 {
-		limit = d.cfg.Query.DefaultLimit
+		limit = defaultLimit
 	}
 
 	// Enforce max limit
-	if limit > d.cfg.Query.MaxLimit {
-		limit = d.cfg.Query.MaxLimit
+	if limit > maxLimit {
+		limit = maxLimit
 	}
 
 	switch query.Type {
 	case "recent":
-		edits, err := d.db.GetRecentEdits(limit)
+		var edits []*database.Edit
+		var err error
+		if query.Filter != "" || group != nil || query.User != "" {
+			edits, err = d.filteredEdits(database.EditFilter{Workspaces: group, User: query.User}, query.Filter, limit)
+		} else {
+			edits, err = d.db.GetRecentEdits(limit)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -411,11 +945,78 @@ func (d *Daemon) executeQuery(query *Query) (*QueryResult, error) {
 			result.Edits = edits
 		}
 
+	case "timeline":
+		var before database.EditCursor
+		if query.Cursor != "" {
+			var err error
+			if before, err = database.ParseEditCursor(query.Cursor); err != nil {
+				return nil, err
+			}
+		}
+		var edits []*database.TimelineEdit
+		err := d.db.StreamTimeline(context.Background(), database.TimelineQuery{
+			Workspaces: group,
+			Since:      query.Since,
+			Before:     before,
+			Limit:      limit,
+			User:       query.User,
+		}, func(e *database.TimelineEdit) error {
+			edits = append(edits, e)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.Timeline = edits
+		if len(edits) == limit {
+			last := edits[len(edits)-1]
+			result.NextCursor = database.EditCursor{Timestamp: last.Timestamp, ID: last.ID}.String()
+		}
+
 	case "workspace":
-		if query.WorkspacePath == "" {
-			return nil, fmt.Errorf("workspace_path required for workspace queries")
+		if query.WorkspacePath == "" && group == nil {
+			return nil, fmt.Errorf("workspace_path or group required for workspace queries")
+		}
+		var before database.EditCursor
+		if query.Cursor != "" {
+			var err error
+			if before, err = database.ParseEditCursor(query.Cursor); err != nil {
+				return nil, err
+			}
+		}
+		var edits []*database.Edit
+		var err error
+		if query.Filter != "" || group != nil || before.ID > 0 || query.User != "" {
+			edits, err = d.filteredEdits(database.EditFilter{
+				WorkspacePath: query.WorkspacePath,
+				Workspaces:    group,
+				Before:        before,
+				User:          query.User,
+			}, query.Filter, limit)
+		} else {
+			edits, err = d.db.GetEditsByWorkspace(query.WorkspacePath, limit)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if edits != nil {
+			result.Edits = edits
+		}
+		if len(edits) == limit {
+			last := edits[len(edits)-1]
+			result.NextCursor = database.EditCursor{Timestamp: last.Timestamp, ID: last.ID}.String()
+		}
+
+	case "search":
+		if query.Text == "" {
+			return nil, fmt.Errorf("text required for search queries")
 		}
-		edits, err := d.db.GetEditsByWorkspace(query.WorkspacePath, limit)
+		edits, err := d.filteredEdits(database.EditFilter{
+			WorkspacePath: query.WorkspacePath,
+			Workspaces:    group,
+			Content:       query.Text,
+			User:          query.User,
+		}, query.Filter, limit)
 		if err != nil {
 			return nil, err
 		}
@@ -427,7 +1028,68 @@ func (d *Daemon) executeQuery(query *Query) (*QueryResult, error) {
 		if query.FilePath == "" {
 			return nil, fmt.Errorf("file_path required for file queries")
 		}
-		edits, err := d.db.GetEditsByFile(query.FilePath, limit)
+		var edits []*database.Edit
+		var err error
+		if query.User != "" {
+			edits, err = d.filteredEdits(database.EditFilter{FilePath: query.FilePath, User: query.User}, "", limit)
+		} else {
+			edits, err = d.db.GetEditsByFile(query.FilePath, limit)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if edits != nil {
+			result.Edits = edits
+		}
+
+	case "snapshots":
+		if query.WorkspacePath == "" {
+			return nil, fmt.Errorf("workspace_path required for snapshot queries")
+		}
+		snapshots, err := d.db.GetFileSnapshots(query.WorkspacePath, query.FilePath, limit)
+		if err != nil {
+			return nil, err
+		}
+		result.Snapshots = snapshots
+
+	case "snapshot":
+		if query.SnapshotID == 0 {
+			return nil, fmt.Errorf("snapshot_id required for snapshot queries")
+		}
+		snapshot, err := d.db.GetFileSnapshot(query.SnapshotID)
+		if err != nil {
+			return nil, err
+		}
+		if query.User != "" {
+			if err := d.checkUserWorkspace(query.User, snapshot.WorkspacePath); err != nil {
+				return nil, err
+			}
+		}
+		result.Snapshots = []*database.FileSnapshot{snapshot}
+
+	case "checks":
+		if query.WorkspacePath == "" {
+			return nil, fmt.Errorf("workspace_path required for check queries")
+		}
+		checks, err := d.db.GetCheckResults(query.WorkspacePath, query.FilePath, limit)
+		if err != nil {
+			return nil, err
+		}
+		result.Checks = checks
+
+	case "bookmarks":
+		var edits []*database.Edit
+		var err error
+		if group != nil || query.User != "" {
+			edits, err = d.filteredEdits(database.EditFilter{
+				WorkspacePath: query.WorkspacePath,
+				Workspaces:    group,
+				Bookmarked:    true,
+				User:          query.User,
+			}, "", limit)
+		} else {
+			edits, err = d.db.GetBookmarkedEdits(query.WorkspacePath, limit)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -440,7 +1102,7 @@ func (d *Daemon) executeQuery(query *Query) (*QueryResult, error) {
 		if name == "" {
 			name = "%"
 		}
-		prompts, err := d.db.GetPrompts(name, limit)
+		prompts, err := d.db.GetPrompts(name, query.Tag, limit)
 		if err != nil {
 			return nil, err
 		}
@@ -448,8 +1110,48 @@ func (d *Daemon) executeQuery(query *Query) (*QueryResult, error) {
 			result.Prompts = prompts
 		}
 
+	case "prompt_library":
+		prompts, err := d.db.GetLibraryPrompts()
+		if err != nil {
+			return nil, err
+		}
+		if prompts != nil {
+			result.Prompts = prompts
+		}
+
+	case "prompt_save":
+		if query.Prompt == nil || query.Prompt.Name == "" {
+			return nil, fmt.Errorf("prompt with a name required for prompt save")
+		}
+		if err := d.db.SaveLibraryPrompt(query.Prompt); err != nil {
+			return nil, err
+		}
+		logger.Log("Saved library prompt: %s v%d", query.Prompt.Name, query.Prompt.Version)
+
+	case "prompt_delete":
+		if query.Name == "" {
+			return nil, fmt.Errorf("name required for prompt delete")
+		}
+		if err := d.db.DeleteLibraryPrompt(query.Name); err != nil {
+			return nil, err
+		}
+		logger.Log("Deleted library prompt: %s", query.Name)
+
 	case "sessions":
-		sessions, err := d.db.GetSessions(limit)
+		scope := group
+		if scope == nil && query.WorkspacePath != "" {
+			scope = []string{query.WorkspacePath}
+		}
+		if query.User != "" && query.WorkspacePath == "" {
+			var err error
+			if scope, err = d.userWorkspaces(query.User, group); err != nil {
+				return nil, err
+			}
+			if len(scope) == 0 {
+				break
+			}
+		}
+		sessions, err := d.db.GetSessionsIn(scope, limit)
 		if err != nil {
 			return nil, err
 		}
@@ -457,8 +1159,267 @@ func (d *Daemon) executeQuery(query *Query) (*QueryResult, error) {
 			result.Sessions = sessions
 		}
 
+	case "events":
+		if query.User != "" && query.WorkspacePath == "" {
+			var err error
+			if group, err = d.userWorkspaces(query.User, group); err != nil {
+				return nil, err
+			}
+			if len(group) == 0 {
+				break
+			}
+		}
+		var events []*database.Event
+		var err error
+		if group != nil {
+			events, err = d.db.GetEventsIn(group, query.Since, query.Until, limit)
+		} else {
+			events, err = d.db.GetEvents(query.WorkspacePath, query.Since, query.Until, limit)
+		}
+		if err != nil {
+			return nil, err
+		}
+		result.Events = events
+
+	case "injections":
+		injections, err := d.db.GetPromptInjections(query.Name, limit)
+		if err != nil {
+			return nil, err
+		}
+		result.Injections = injections
+
+	case "prompt_stats":
+		stats, err := d.db.GetPromptStats(limit)
+		if err != nil {
+			return nil, err
+		}
+		result.PromptStats = stats
+
+	case "chat_sessions":
+		chats, err := d.db.GetChatSessions(query.WorkspacePath, limit)
+		if err != nil {
+			return nil, err
+		}
+		if query.User != "" && query.WorkspacePath == "" {
+			visible, err := d.userWorkspaces(query.User, nil)
+			if err != nil {
+				return nil, err
+			}
+			chats = slices.DeleteFunc(chats, func(c *database.ChatSession) bool {
+				return !slices.Contains(visible, c.WorkspacePath)
+			})
+		}
+		result.Chats = chats
+
+	case "chat_messages":
+		if query.ChatSessionID == "" {
+			return nil, fmt.Errorf("chat_session_id required for chat_messages queries")
+		}
+		if query.User != "" {
+			workspace, err := d.db.GetChatSessionWorkspace(query.ChatSessionID)
+			if err != nil {
+				return nil, err
+			}
+			if err := d.checkUserWorkspace(query.User, workspace); err != nil {
+				return nil, err
+			}
+		}
+		messages, err := d.db.GetChatMessages(query.ChatSessionID)
+		if err != nil {
+			return nil, err
+		}
+		result.Messages = messages
+
+	case "chat_edits":
+		if query.ChatSessionID == "" {
+			return nil, fmt.Errorf("chat_session_id required for chat_edits queries")
+		}
+		edits, err := d.db.GetChatEdits(query.ChatSessionID)
+		if err != nil {
+			return nil, err
+		}
+		if query.User != "" {
+			edits = slices.DeleteFunc(edits, func(e *database.Edit) bool { return e.User != query.User })
+		}
+		result.Edits = edits
+
+	case "ralph_edits":
+		if query.WorkspacePath == "" {
+			return nil, fmt.Errorf("workspace_path required for ralph_edits queries")
+		}
+		edits, err := d.db.GetRalphEdits(query.WorkspacePath, query.Since, query.Until)
+		if err != nil {
+			return nil, err
+		}
+		result.Edits = edits
+
+	case "ralph_loops":
+		loops, err := d.db.GetRalphLoops(query.WorkspacePath, limit)
+		if err != nil {
+			return nil, err
+		}
+		if query.User != "" && query.WorkspacePath == "" {
+			visible, err := d.userWorkspaces(query.User, nil)
+			if err != nil {
+				return nil, err
+			}
+			loops = slices.DeleteFunc(loops, func(l *database.RalphLoop) bool {
+				return !slices.Contains(visible, l.WorkspacePath)
+			})
+		}
+		result.RalphLoops = loops
+
+	case "workspace_plan":
+		if query.WorkspacePath == "" {
+			return nil, fmt.Errorf("workspace_path required for workspace_plan queries")
+		}
+		planPath, err := d.db.GetWorkspacePlan(query.WorkspacePath)
+		if err != nil {
+			return nil, err
+		}
+		result.PlanPath = planPath
+
+	case "session_names":
+		if query.WorkspacePath == "" {
+			return nil, fmt.Errorf("workspace_path required for session_names queries")
+		}
+		names, err := d.db.GetSessionNames(query.WorkspacePath)
+		if err != nil {
+			return nil, err
+		}
+		result.Names = names
+
+	case "hooks":
+		if query.WorkspacePath == "" {
+			return nil, fmt.Errorf("workspace_path required for hooks queries")
+		}
+		hooks, err := d.db.GetHookEvents(query.WorkspacePath, query.User, query.Since, limit)
+		if err != nil {
+			return nil, err
+		}
+		result.Hooks = hooks
+
+	case "review":
+		if query.WorkspacePath == "" {
+			return nil, fmt.Errorf("workspace_path required for review queries")
+		}
+		stats, err := d.db.GetReviewStats(query.WorkspacePath)
+		if err != nil {
+			return nil, err
+		}
+		rejected, err := d.db.GetRejectedEdits(query.WorkspacePath)
+		if err != nil {
+			return nil, err
+		}
+		result.Review = stats
+		result.Edits = rejected
+
+	case "digest":
+		// The last day, unless a period is asked for
+		scope := group
+		if scope == nil && query.WorkspacePath != "" {
+			scope = []string{query.WorkspacePath}
+		}
+		until := query.Until
+		if until.IsZero() {
+			until = time.Now()
+		}
+		since := query.Since
+		if since.IsZero() {
+			since = until.Add(-defaultDigestPeriod)
+		}
+		if query.User != "" && query.WorkspacePath == "" {
+			var err error
+			if scope, err = d.userWorkspaces(query.User, group); err != nil {
+				return nil, err
+			}
+			if len(scope) == 0 {
+				result.Digest = &database.Digest{Since: since, Until: until}
+				break
+			}
+		}
+		digest, err := d.db.GetDigest(scope, query.User, since, until)
+		if err != nil {
+			return nil, err
+		}
+		result.Digest = digest
+
 	case "status":
-		result.Status = d.getStatus(query.WorkspacePath)
+		if query.User != "" {
+			var err error
+			if group, err = d.userWorkspaces(query.User, group); err != nil {
+				return nil, err
+			}
+		}
+		result.Status = d.getStatus(query.WorkspacePath, group)
+
+	case "groups":
+		d.cfgMu.RLock()
+		result.Groups = make(map[string][]string, len(d.cfg.Workspaces.Groups))
+		for name, members := range d.cfg.Workspaces.Groups {
+			result.Groups[name] = append([]string(nil), members...)
+		}
+		d.cfgMu.RUnlock()
+
+	case "tokens":
+		tokens, err := d.db.ListTokens()
+		if err != nil {
+			return nil, err
+		}
+		result.Tokens = tokens
+
+	case "token_create":
+		if err := d.createToken(query, result); err != nil {
+			return nil, err
+		}
+
+	case "token_revoke":
+		if query.Name == "" {
+			return nil, fmt.Errorf("token name or id required")
+		}
+		if err := d.db.RevokeToken(query.Name); err != nil {
+			return nil, err
+		}
+		logger.Log("Revoked token %q", query.Name)
+
+	case "workspace_export":
+		if query.WorkspacePath == "" {
+			return nil, fmt.Errorf("workspace_path required for workspace export")
+		}
+		dump, err := d.db.ExportWorkspace(query.WorkspacePath)
+		if err != nil {
+			return nil, err
+		}
+		result.Dump = dump
+
+	case "workspace_import":
+		if query.WorkspacePath == "" || query.Dump == nil {
+			return nil, fmt.Errorf("workspace_path and dump required for workspace import")
+		}
+		if err := d.db.ImportWorkspace(query.Dump, query.WorkspacePath); err != nil {
+			return nil, err
+		}
+		logger.Log("Restored %d session(s) into %s", len(query.Dump.Sessions), query.WorkspacePath)
+
+	case "workspace_purge":
+		if query.WorkspacePath == "" {
+			return nil, fmt.Errorf("workspace_path required for workspace purge")
+		}
+		removed, err := d.db.DeleteWorkspace(query.WorkspacePath)
+		if err != nil {
+			return nil, err
+		}
+		result.Removed = removed
+		logger.Log("Purged %d session(s) for %s", removed, query.WorkspacePath)
+
+	case "dedupe":
+		stats, err := d.db.DedupeSnapshots()
+		if err != nil {
+			return nil, err
+		}
+		result.Dedupe = stats
+		logger.Log("Deduplicated snapshots: %d moved, %d recompressed, %d -> %d bytes",
+			stats.Moved, stats.Recompressed, stats.BytesBefore, stats.BytesAfter)
 
 	default:
 		return nil, fmt.Errorf("unknown query type: %s", query.Type)
@@ -467,8 +1428,24 @@ func (d *Daemon) executeQuery(query *Query) (*QueryResult, error) {
 	return result, nil
 }
 
-// getStatus returns the daemon status, optionally checking for a specific workspace
-func (d *Daemon) getStatus(workspacePath string) *StatusResult {
+// filteredEdits returns recent edits matching a filter expression, within
+// the workspace scope given by base
+func (d *Daemon) filteredEdits(base database.EditFilter, expr string, limit int) ([]*database.Edit, error) {
+	f, err := filter.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	base.Tool = f.Tool
+	base.Since = f.Since
+	base.Until = f.Until
+	base.MatchPath = f.MatchPath
+	return d.db.GetFilteredEdits(base, limit)
+}
+
+// getStatus returns the daemon status, optionally checking for a specific
+// workspace and limiting the workspace list to a group
+func (d *Daemon) getStatus(workspacePath string, group []string) *StatusResult {
 	uptime := time.Since(d.startedAt)
 
 	// Format uptime string
@@ -493,14 +1470,19 @@ func (d *Daemon) getStatus(workspacePath string) *StatusResult {
 	// Copy workspaces map
 	workspaces := make(map[string]*WorkspaceActivity, len(d.workspaces))
 	for k, v := range d.workspaces {
-		workspaces[k] = v
+		if group == nil || slices.Contains(group, k) {
+			workspaces[k] = v
+		}
 	}
 
 	status := &StatusResult{
 		Running:    true,
+		Version:    Version,
 		Uptime:     uptime,
 		UptimeStr:  uptimeStr,
 		Workspaces: workspaces,
+		Ingest:     d.ingest.Stats(),
+		Database:   d.db.PoolStats(),
 	}
 
 	// Check if specific workspace is active
@@ -518,15 +1500,52 @@ func (d *Daemon) waitForShutdown() error {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
 
-	select {
-	case sig := <-sigChan:
-		logger.Log("Received signal: %v", sig)
-		return d.Stop()
-	case <-d.shutdown:
-		return nil
+	for {
+		select {
+		case sig := <-sigChan:
+			logger.Log("Received signal: %v", sig)
+			if sig == syscall.SIGHUP {
+				d.reloadConfig()
+				continue
+			}
+			return d.Stop()
+		case <-d.shutdown:
+			return nil
+		}
 	}
 }
 
+// reloadConfig re-reads the config file and applies settings that can change
+// without restarting (workspace filters, query limits, checks, batching,
+// webhooks, alerts, the file watcher, editor hunks' lookback and the log
+// level)
+func (d *Daemon) reloadConfig() error {
+	cfg, err := LoadConfig(d.cfg.path)
+	if err != nil {
+		logger.Log("Config reload failed: %v", err)
+		d.recordEvent("", EventConfigReload, SeverityError, fmt.Sprintf("reload failed: %v", err))
+		return err
+	}
+
+	d.cfgMu.Lock()
+	d.cfg.Workspaces = cfg.Workspaces
+	d.cfg.Query = cfg.Query
+	d.cfg.Checks = cfg.Checks
+	d.cfg.Ingest = cfg.Ingest
+	d.cfg.Webhooks = cfg.Webhooks
+	d.cfg.Users = cfg.Users
+	d.cfg.Alerts = cfg.Alerts
+	d.cfg.Watch = cfg.Watch
+	d.cfg.Editor.RecentHours = cfg.Editor.RecentHours
+	d.cfg.Logging.Level = cfg.Logging.Level
+	d.cfgMu.Unlock()
+	logger.SetLevel(cfg.Logging.Level)
+
+	logger.Log("Config reloaded from %q", d.cfg.path)
+	d.recordEvent("", EventConfigReload, SeverityInfo, "configuration reloaded")
+	return nil
+}
+
 // Stop stops the daemon
 func (d *Daemon) Stop() error {
 	logger.Log("Shutting down daemon...")
@@ -539,6 +1558,18 @@ func (d *Daemon) Stop() error {
 	// Stop backup manager
 	d.backupManager.Stop()
 
+	// Stop context refresher
+	d.contextRefresher.Stop()
+
+	// Stop digest poster
+	d.digestPoster.Stop()
+
+	// Cancel pending checks
+	d.checkRunner.Stop()
+
+	// Stop watching workspaces
+	d.watcher.Stop()
+
 	// Close listeners
 	if d.listener != nil {
 		d.listener.Close()
@@ -546,6 +1577,14 @@ func (d *Daemon) Stop() error {
 	if d.queryListener != nil {
 		d.queryListener.Close()
 	}
+	if d.grpcServer != nil {
+		d.grpcServer.Stop()
+		os.Remove(d.cfg.GRPC.Socket)
+	}
+	if d.editorListener != nil {
+		d.editorListener.Close()
+		os.Remove(d.cfg.Editor.Socket)
+	}
 
 	// Wait for connections to finish
 	done := make(chan struct{})
@@ -561,7 +1600,13 @@ func (d *Daemon) Stop() error {
 		logger.Log("Timeout waiting for connections")
 	}
 
+	// Write the edits still queued, then deliver their webhooks
+	d.ingest.Stop()
+	d.webhooks.Stop()
+	d.audit.close()
+
 	// Close database
+	d.recordEvent("", EventDaemonStop, SeverityInfo, "daemon stopped")
 	if err := d.db.Close(); err != nil {
 		logger.Log("Database close error: %v", err)
 	}
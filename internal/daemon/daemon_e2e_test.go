@@ -4,6 +4,7 @@ import (
 	"compress/gzip"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net"
 	"os"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/prompt"
 )
 
 // TestDaemonHookE2E tests the full flow from hook payload to database storage
@@ -204,6 +206,90 @@ func queryRecentEdits(t *testing.T, querySocket string, limit int) []*database.E
 	return result.Edits
 }
 
+// queryFn sends a query to a test daemon's query socket
+type queryFn func(Query) QueryResult
+
+// startTestDaemon starts a daemon on the default config, with its data and
+// sockets in a temporary directory and configure's changes applied, and
+// stops it when the test ends. It returns once the sockets accept
+// connections.
+func startTestDaemon(t *testing.T, configure func(*Config)) (*Daemon, queryFn) {
+	t.Helper()
+	dir := t.TempDir()
+
+	cfg := defaultConfig()
+	cfg.Directory.DataDir = dir
+	cfg.Sockets.DaemonSocket = filepath.Join(dir, "daemon.sock")
+	cfg.Sockets.QuerySocket = filepath.Join(dir, "query.sock")
+	cfg.Retention.CleanupIntervalHrs = 0
+	cfg.Backup.Enabled = false
+	cfg.Workspaces.Ignored = []string{} // t.TempDir() is often under /tmp
+	if configure != nil {
+		configure(cfg)
+	}
+
+	daemon, err := New(cfg)
+	if err != nil {
+		t.Fatalf("failed to create daemon: %v", err)
+	}
+	daemonErr := make(chan error, 1)
+	go func() {
+		daemonErr <- daemon.Start()
+	}()
+	t.Cleanup(func() {
+		daemon.Stop()
+		select {
+		case <-daemonErr:
+		case <-time.After(5 * time.Second):
+		}
+	})
+
+	sockets := []string{cfg.Sockets.DaemonSocket, cfg.Sockets.QuerySocket}
+	if cfg.GRPC.Enabled && cfg.GRPC.Socket != "" {
+		sockets = append(sockets, cfg.GRPC.Socket)
+	}
+	if cfg.Editor.Enabled {
+		sockets = append(sockets, cfg.Editor.Socket)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for _, path := range sockets {
+		for {
+			conn, err := net.Dial("unix", path)
+			if err == nil {
+				conn.Close()
+				break
+			}
+			select {
+			case err := <-daemonErr:
+				t.Fatalf("daemon failed to start: %v", err)
+			case <-time.After(10 * time.Millisecond):
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("daemon socket %s not ready: %v", filepath.Base(path), err)
+			}
+		}
+	}
+
+	query := func(q Query) QueryResult {
+		t.Helper()
+		conn, err := net.Dial("unix", cfg.Sockets.QuerySocket)
+		if err != nil {
+			t.Fatalf("failed to connect to query socket: %v", err)
+		}
+		defer conn.Close()
+
+		if err := json.NewEncoder(conn).Encode(q); err != nil {
+			t.Fatalf("failed to send query: %v", err)
+		}
+		var result QueryResult
+		if err := json.NewDecoder(conn).Decode(&result); err != nil {
+			t.Fatalf("failed to decode result: %v", err)
+		}
+		return result
+	}
+	return daemon, query
+}
+
 func testBasicEditCapture(t *testing.T, conn net.Conn, querySocket string) {
 	payload := &HookPayload{
 		Type:          "edit",
@@ -628,3 +714,796 @@ func main() {
 		t.Errorf("compression/decompression mismatch:\noriginal: %q\nresult: %q", original, string(decompressed))
 	}
 }
+
+// TestDaemonTokenAuth tests token issuing and enforcement when local auth is required
+func TestDaemonTokenAuth(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	daemon, query := startTestDaemon(t, func(cfg *Config) {
+		cfg.Auth.RequireLocal = true
+	})
+	cfg := daemon.cfg
+
+	// Queries need a token once local auth is required
+	if result := query(Query{Type: "status"}); !strings.Contains(result.Error, "unauthorized") {
+		t.Fatalf("expected unauthorized error, got %+v", result)
+	}
+
+	// The first admin token can be created without one
+	admin := query(Query{Type: "token_create", Name: "admin", Scope: "admin"})
+	if admin.Error != "" || admin.Token == "" {
+		t.Fatalf("failed to bootstrap admin token: %+v", admin)
+	}
+	if result := query(Query{Type: "token_create", Name: "sneaky", Scope: "admin"}); result.Error == "" {
+		t.Error("expected a second admin token to require authentication")
+	}
+
+	reader := query(Query{Type: "token_create", Name: "reader", Scope: "read", Token: admin.Token})
+	if reader.Error != "" || reader.Token == "" {
+		t.Fatalf("failed to create read token: %+v", reader)
+	}
+
+	if result := query(Query{Type: "status", Token: reader.Token}); result.Status == nil {
+		t.Errorf("expected status with read token, got %+v", result)
+	}
+	if result := query(Query{Type: "tokens", Token: reader.Token}); !strings.Contains(result.Error, "forbidden") {
+		t.Errorf("expected read token to be forbidden from token management, got %+v", result)
+	}
+
+	// Read tokens cannot ingest
+	conn, err := net.Dial("unix", cfg.Sockets.DaemonSocket)
+	if err != nil {
+		t.Fatalf("failed to connect to daemon: %v", err)
+	}
+	defer conn.Close()
+	payload := HookPayload{Type: "edit", Workspace: tmpDir, ToolName: "Edit", FilePath: "a.go", Token: reader.Token}
+	if err := json.NewEncoder(conn).Encode(payload); err != nil {
+		t.Fatalf("failed to send payload: %v", err)
+	}
+	var resp map[string]string
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	if !strings.Contains(resp["error"], "forbidden") {
+		t.Errorf("expected forbidden ingest with read token, got %v", resp)
+	}
+
+	// Revoked tokens stop working
+	if result := query(Query{Type: "token_revoke", Name: "reader", Token: admin.Token}); result.Error != "" {
+		t.Fatalf("failed to revoke token: %s", result.Error)
+	}
+	if result := query(Query{Type: "status", Token: reader.Token}); !strings.Contains(result.Error, "revoked") {
+		t.Errorf("expected revoked token to be rejected, got %+v", result)
+	}
+
+	list := query(Query{Type: "tokens", Token: admin.Token})
+	if len(list.Tokens) != 2 || list.Tokens[1].RevokedAt == nil {
+		t.Errorf("expected 2 tokens with reader revoked, got %+v", list.Tokens)
+	}
+
+	// Only the daemon's user can connect
+	for _, path := range []string{cfg.Sockets.DaemonSocket, cfg.Sockets.QuerySocket} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("failed to stat socket: %v", err)
+		}
+		if perm := info.Mode().Perm(); perm != 0600 {
+			t.Errorf("expected %s to be 0600, got %o", filepath.Base(path), perm)
+		}
+	}
+
+	// Other users are refused even if they reach the socket
+	other := os.Getuid() + 4242
+	if peerAllowed(other, 0600, -1) {
+		t.Errorf("expected uid %d to be refused on a 0600 socket", other)
+	}
+	if !peerAllowed(os.Getuid(), 0600, -1) {
+		t.Error("expected the daemon's user to be allowed")
+	}
+	if !peerAllowed(other, 0666, -1) {
+		t.Errorf("expected uid %d to be allowed on a 0666 socket", other)
+	}
+
+	// Every query is audited with its token and outcome
+	data, err := os.ReadFile(filepath.Join(cfg.Directory.DataDir, "audit.log"))
+	if err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	var entries []AuditEntry
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry AuditEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("bad audit line %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 9 {
+		t.Fatalf("expected 9 audited queries, got %d", len(entries))
+	}
+	if e := entries[0]; e.Type != "status" || e.Outcome != "denied" || e.Token != "" {
+		t.Errorf("expected the tokenless status query denied, got %+v", e)
+	}
+	if e := entries[5]; e.Type != "tokens" || e.Outcome != "denied" || e.Token != "reader" {
+		t.Errorf("expected the reader's tokens query denied, got %+v", e)
+	}
+	if e := entries[8]; e.Type != "tokens" || e.Outcome != "ok" || e.Token != "admin" {
+		t.Errorf("expected the admin's tokens query answered, got %+v", e)
+	}
+	if uid := entries[8].UID; uid != -1 && uid != os.Getuid() {
+		t.Errorf("expected the query from uid %d, got %d", os.Getuid(), uid)
+	}
+}
+
+func TestDaemonWorkspaceGroups(t *testing.T) {
+	tmpDir := t.TempDir()
+	api := filepath.Join(tmpDir, "api")
+	infra := filepath.Join(tmpDir, "infra")
+	docs := filepath.Join(tmpDir, "docs")
+
+	daemon, query := startTestDaemon(t, func(cfg *Config) {
+		cfg.Workspaces.Groups = map[string][]string{"platform": {api, infra}}
+	})
+	cfg := daemon.cfg
+
+	conn, err := net.Dial("unix", cfg.Sockets.DaemonSocket)
+	if err != nil {
+		t.Fatalf("failed to connect to daemon: %v", err)
+	}
+	defer conn.Close()
+	for _, ws := range []string{api, infra, docs} {
+		sendPayloadAndWaitForResponse(t, conn, &HookPayload{
+			Type:      "edit",
+			Workspace: ws,
+			ToolName:  "Edit",
+			FilePath:  filepath.Join(ws, "main.go"),
+		})
+	}
+
+	if result := query(Query{Type: "recent"}); len(result.Edits) != 3 {
+		t.Fatalf("expected 3 edits overall, got %d", len(result.Edits))
+	}
+
+	result := query(Query{Type: "recent", Group: "platform"})
+	if len(result.Edits) != 2 {
+		t.Fatalf("expected 2 edits in group, got %d", len(result.Edits))
+	}
+	for _, e := range result.Edits {
+		if strings.HasPrefix(e.FilePath, docs) {
+			t.Errorf("edit outside group returned: %s", e.FilePath)
+		}
+	}
+
+	if result := query(Query{Type: "sessions", Group: "platform"}); len(result.Sessions) != 2 {
+		t.Errorf("expected 2 sessions in group, got %d", len(result.Sessions))
+	}
+
+	if result := query(Query{Type: "status", Group: "platform"}); result.Status == nil || result.Status.Workspaces[docs] != nil {
+		t.Errorf("expected status limited to group, got %+v", result.Status)
+	}
+
+	if result := query(Query{Type: "groups"}); len(result.Groups["platform"]) != 2 {
+		t.Errorf("expected platform group with 2 workspaces, got %v", result.Groups)
+	}
+
+	if result := query(Query{Type: "recent", Group: "missing"}); !strings.Contains(result.Error, "unknown workspace group") {
+		t.Errorf("expected unknown group error, got %+v", result)
+	}
+}
+
+func TestDaemonChatTranscripts(t *testing.T) {
+	tmpDir := t.TempDir()
+	ws := filepath.Join(tmpDir, "project")
+
+	daemon, query := startTestDaemon(t, nil)
+	cfg := daemon.cfg
+
+	conn, err := net.Dial("unix", cfg.Sockets.DaemonSocket)
+	if err != nil {
+		t.Fatalf("failed to connect to daemon: %v", err)
+	}
+	defer conn.Close()
+
+	start := time.Now().Add(-time.Minute).Truncate(time.Second)
+	// The reply arrives in a later batch than the question
+	sendPayloadAndWaitForResponse(t, conn, &HookPayload{
+		Type:          "chat",
+		Workspace:     ws,
+		ChatSessionID: "3f2c",
+		ChatPurpose:   "plan",
+		ChatMessages: []*database.ChatMessage{
+			{Role: "user", Content: "What's left in the plan?", Timestamp: start},
+		},
+	})
+	sendPayloadAndWaitForResponse(t, conn, &HookPayload{
+		Type:          "chat",
+		Workspace:     ws,
+		ChatSessionID: "3f2c",
+		ChatMessages: []*database.ChatMessage{
+			{Role: "assistant", Content: "Two steps.", Timestamp: start.Add(time.Second)},
+			{Role: "user", Content: "Do the first.", Timestamp: start.Add(2 * time.Second)},
+		},
+	})
+	sendPayloadAndWaitForResponse(t, conn, &HookPayload{
+		Type:          "chat",
+		Workspace:     filepath.Join(tmpDir, "other"),
+		ChatSessionID: "9a7e",
+		ChatMessages:  []*database.ChatMessage{{Role: "user", Content: "hi"}},
+	})
+	// An edit Claude made during the session
+	sendPayloadAndWaitForResponse(t, conn, &HookPayload{
+		Type:          "edit",
+		Workspace:     ws,
+		ToolName:      "Edit",
+		FilePath:      filepath.Join(ws, "plan.md"),
+		ChatSessionID: "3f2c",
+	})
+
+	result := query(Query{Type: "chat_sessions", WorkspacePath: ws})
+	if len(result.Chats) != 1 {
+		t.Fatalf("expected 1 chat session in workspace, got %d", len(result.Chats))
+	}
+	chat := result.Chats[0]
+	if chat.SessionID != "3f2c" || chat.Purpose != "plan" || chat.Messages != 3 || chat.Edits != 1 || chat.Preview != "What's left in the plan?" {
+		t.Errorf("unexpected chat session: %+v", chat)
+	}
+
+	result = query(Query{Type: "chat_messages", ChatSessionID: "3f2c"})
+	var roles []string
+	for _, msg := range result.Messages {
+		roles = append(roles, msg.Role)
+	}
+	if strings.Join(roles, ",") != "user,assistant,user" {
+		t.Errorf("expected transcript in order, got %v", roles)
+	}
+
+	result = query(Query{Type: "chat_edits", ChatSessionID: "3f2c"})
+	if len(result.Edits) != 1 || result.Edits[0].FilePath != filepath.Join(ws, "plan.md") {
+		t.Errorf("expected the session's edit, got %+v", result.Edits)
+	}
+
+	if result := query(Query{Type: "chat_messages"}); !strings.Contains(result.Error, "chat_session_id required") {
+		t.Errorf("expected missing session error, got %+v", result)
+	}
+}
+
+func TestDaemonRalphEdits(t *testing.T) {
+	tmpDir := t.TempDir()
+	ws := filepath.Join(tmpDir, "ws")
+
+	daemon, _ := startTestDaemon(t, nil)
+	cfg := daemon.cfg
+
+	conn, err := net.Dial("unix", cfg.Sockets.DaemonSocket)
+	if err != nil {
+		t.Fatalf("failed to connect to daemon: %v", err)
+	}
+	defer conn.Close()
+
+	// Two iterations of a loop, and an edit made outside it
+	for _, e := range []struct {
+		file      string
+		iteration int
+	}{{"a.go", 1}, {"b.go", 2}, {"a.go", 2}, {"notes.md", 0}} {
+		sendPayloadAndWaitForResponse(t, conn, &HookPayload{
+			Type:           "edit",
+			Workspace:      ws,
+			ToolName:       "Edit",
+			FilePath:       filepath.Join(ws, e.file),
+			RalphIteration: e.iteration,
+		})
+	}
+
+	qconn, err := net.Dial("unix", cfg.Sockets.QuerySocket)
+	if err != nil {
+		t.Fatalf("failed to connect to query socket: %v", err)
+	}
+	defer qconn.Close()
+	if err := json.NewEncoder(qconn).Encode(Query{Type: "ralph_edits", WorkspacePath: ws}); err != nil {
+		t.Fatalf("failed to send query: %v", err)
+	}
+	var result QueryResult
+	if err := json.NewDecoder(qconn).Decode(&result); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+
+	var got []string
+	for _, e := range result.Edits {
+		got = append(got, fmt.Sprintf("%d:%s", e.RalphIteration, filepath.Base(e.FilePath)))
+	}
+	if strings.Join(got, ",") != "1:a.go,2:b.go,2:a.go" {
+		t.Errorf("expected the loop's edits by iteration, got %v", got)
+	}
+
+	// The loop is kept once it ends, with the edits made during it
+	sendPayloadAndWaitForResponse(t, conn, &HookPayload{
+		Type:      "ralph_loop",
+		Workspace: ws,
+		RalphLoop: &database.RalphLoop{
+			Prompt:        "Fix the build",
+			Promise:       "GREEN",
+			Iterations:    2,
+			MaxIterations: 5,
+			Outcome:       "promise_met",
+			StartedAt:     time.Now().Add(-time.Hour),
+			EndedAt:       time.Now().Add(time.Minute),
+		},
+	})
+
+	qconn2, err := net.Dial("unix", cfg.Sockets.QuerySocket)
+	if err != nil {
+		t.Fatalf("failed to connect to query socket: %v", err)
+	}
+	defer qconn2.Close()
+	if err := json.NewEncoder(qconn2).Encode(Query{Type: "ralph_loops", WorkspacePath: ws}); err != nil {
+		t.Fatalf("failed to send query: %v", err)
+	}
+	result = QueryResult{}
+	if err := json.NewDecoder(qconn2).Decode(&result); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	if len(result.RalphLoops) != 1 {
+		t.Fatalf("expected 1 ended loop, got %d", len(result.RalphLoops))
+	}
+	if loop := result.RalphLoops[0]; loop.WorkspacePath != ws || loop.Outcome != "promise_met" || loop.Iterations != 2 || loop.Edits != 3 {
+		t.Errorf("unexpected loop: %+v", loop)
+	}
+}
+
+func TestDaemonWorkspacePlan(t *testing.T) {
+	tmpDir := t.TempDir()
+	ws := filepath.Join(tmpDir, "ws")
+
+	daemon, _ := startTestDaemon(t, nil)
+	cfg := daemon.cfg
+
+	conn, err := net.Dial("unix", cfg.Sockets.DaemonSocket)
+	if err != nil {
+		t.Fatalf("failed to connect to daemon: %v", err)
+	}
+	defer conn.Close()
+
+	queryPlan := func() string {
+		qconn, err := net.Dial("unix", cfg.Sockets.QuerySocket)
+		if err != nil {
+			t.Fatalf("failed to connect to query socket: %v", err)
+		}
+		defer qconn.Close()
+		if err := json.NewEncoder(qconn).Encode(Query{Type: "workspace_plan", WorkspacePath: ws}); err != nil {
+			t.Fatalf("failed to send query: %v", err)
+		}
+		var result QueryResult
+		if err := json.NewDecoder(qconn).Decode(&result); err != nil {
+			t.Fatalf("failed to decode result: %v", err)
+		}
+		return result.PlanPath
+	}
+
+	if got := queryPlan(); got != "" {
+		t.Errorf("expected no plan before one is chosen, got %q", got)
+	}
+
+	// The latest choice wins, and an empty path clears it
+	for _, p := range []string{"/home/me/.claude/plans/auth.md", filepath.Join(ws, ".claude", "plans", "search.md")} {
+		sendPayloadAndWaitForResponse(t, conn, &HookPayload{Type: "plan", Workspace: ws, PlanPath: p})
+		if got := queryPlan(); got != p {
+			t.Errorf("expected plan %q, got %q", p, got)
+		}
+	}
+	sendPayloadAndWaitForResponse(t, conn, &HookPayload{Type: "plan", Workspace: ws})
+	if got := queryPlan(); got != "" {
+		t.Errorf("expected the plan cleared, got %q", got)
+	}
+}
+
+func TestDaemonSessionNames(t *testing.T) {
+	tmpDir := t.TempDir()
+	ws := filepath.Join(tmpDir, "ws")
+
+	daemon, query := startTestDaemon(t, nil)
+	cfg := daemon.cfg
+
+	conn, err := net.Dial("unix", cfg.Sockets.DaemonSocket)
+	if err != nil {
+		t.Fatalf("failed to connect to daemon: %v", err)
+	}
+	defer conn.Close()
+
+	// Edits carry the Claude session that made them
+	sendPayloadAndWaitForResponse(t, conn, &HookPayload{
+		Type:          "edit",
+		Workspace:     ws,
+		ToolName:      "Edit",
+		FilePath:      filepath.Join(ws, "main.go"),
+		OldString:     "a",
+		NewString:     "b",
+		ChatSessionID: "sess-1",
+	})
+	edits := query(Query{Type: "workspace", WorkspacePath: ws}).Edits
+	if len(edits) != 1 || edits[0].ChatSessionID != "sess-1" {
+		t.Fatalf("expected the edit's session, got %+v", edits)
+	}
+
+	// The latest name wins, and an empty name clears it
+	for _, name := range []string{"Auth refactor", "Login fix"} {
+		sendPayloadAndWaitForResponse(t, conn, &HookPayload{Type: "session_name", Workspace: ws, ChatSessionID: "sess-1", SessionName: name})
+		if got := query(Query{Type: "session_names", WorkspacePath: ws}).Names["sess-1"]; got != name {
+			t.Errorf("expected session named %q, got %q", name, got)
+		}
+	}
+	if names := query(Query{Type: "session_names", WorkspacePath: filepath.Join(tmpDir, "other")}).Names; len(names) != 0 {
+		t.Errorf("expected no names in another workspace, got %v", names)
+	}
+	sendPayloadAndWaitForResponse(t, conn, &HookPayload{Type: "session_name", Workspace: ws, ChatSessionID: "sess-1"})
+	if names := query(Query{Type: "session_names", WorkspacePath: ws}).Names; len(names) != 0 {
+		t.Errorf("expected the name cleared, got %v", names)
+	}
+}
+
+func TestDaemonTimeline(t *testing.T) {
+	tmpDir := t.TempDir()
+	wsA, wsB := filepath.Join(tmpDir, "api"), filepath.Join(tmpDir, "web")
+
+	daemon, query := startTestDaemon(t, nil)
+	cfg := daemon.cfg
+
+	conn, err := net.Dial("unix", cfg.Sockets.DaemonSocket)
+	if err != nil {
+		t.Fatalf("failed to connect to daemon: %v", err)
+	}
+	defer conn.Close()
+
+	// Two Claude instances editing in parallel, within the same second
+	for i := 0; i < 3; i++ {
+		for _, ws := range []string{wsA, wsB} {
+			sendPayloadAndWaitForResponse(t, conn, &HookPayload{
+				Type:      "edit",
+				Workspace: ws,
+				ToolName:  "Edit",
+				FilePath:  filepath.Join(ws, fmt.Sprintf("f%d.go", i)),
+				OldString: "a",
+				NewString: "b",
+			})
+		}
+	}
+
+	// Pages of 4 interleave both workspaces, newest first, without gaps
+	var paths []string
+	q := Query{Type: "timeline", Limit: 4}
+	for page := 0; ; page++ {
+		result := query(q)
+		if page > 2 {
+			t.Fatal("expected the cursor to run out")
+		}
+		for _, e := range result.Timeline {
+			paths = append(paths, filepath.Base(e.WorkspacePath)+"/"+filepath.Base(e.FilePath))
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		q.Cursor = result.NextCursor
+	}
+	want := "web/f2.go api/f2.go web/f1.go api/f1.go web/f0.go api/f0.go"
+	if got := strings.Join(paths, " "); got != want {
+		t.Errorf("timeline = %s, want %s", got, want)
+	}
+
+	if result := query(Query{Type: "timeline", Cursor: "bogus"}); result.Error == "" {
+		t.Error("expected an invalid cursor to be rejected")
+	}
+	// Workspace history pages the same way
+	paths = nil
+	q = Query{Type: "workspace", WorkspacePath: wsA, Limit: 2}
+	for page := 0; ; page++ {
+		result := query(q)
+		if result.Error != "" {
+			t.Fatalf("workspace query failed: %s", result.Error)
+		}
+		if page > 2 {
+			t.Fatal("expected the workspace cursor to run out")
+		}
+		for _, e := range result.Edits {
+			paths = append(paths, filepath.Base(e.FilePath))
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		q.Cursor = result.NextCursor
+	}
+	if got := strings.Join(paths, " "); got != "f2.go f1.go f0.go" {
+		t.Errorf("workspace pages = %s, want f2.go f1.go f0.go", got)
+	}
+
+	// Exports stream every edit with its strings, then an end line
+	stream := func(q Query) ([]*database.TimelineEdit, StreamLine) {
+		qconn, err := net.Dial("unix", cfg.Sockets.QuerySocket)
+		if err != nil {
+			t.Fatalf("failed to connect to query socket: %v", err)
+		}
+		defer qconn.Close()
+		if err := json.NewEncoder(qconn).Encode(q); err != nil {
+			t.Fatalf("failed to send query: %v", err)
+		}
+		var edits []*database.TimelineEdit
+		decoder := json.NewDecoder(qconn)
+		for {
+			var line StreamLine
+			if err := decoder.Decode(&line); err != nil {
+				t.Fatalf("stream cut off after %d edits: %v", len(edits), err)
+			}
+			if line.Done || line.Error != "" {
+				return edits, line
+			}
+			edits = append(edits, line.Edit)
+		}
+	}
+	edits, end := stream(Query{Type: "export"})
+	if end.Error != "" || end.Count != 6 || len(edits) != 6 {
+		t.Fatalf("export = %d edits, end %+v, want 6", len(edits), end)
+	}
+	if edits[0].OldString != "a" || edits[0].NewString != "b" {
+		t.Errorf("exported edit strings = %q, %q, want a, b", edits[0].OldString, edits[0].NewString)
+	}
+	if edits, _ := stream(Query{Type: "export", WorkspacePath: wsB}); len(edits) != 3 {
+		t.Errorf("workspace export = %d edits, want 3", len(edits))
+	}
+
+	// A streamed timeline pages like the plain one
+	edits, end = stream(Query{Type: "timeline", Stream: true, Limit: 4})
+	if len(edits) != 4 || end.NextCursor == "" {
+		t.Errorf("streamed timeline page = %d edits, cursor %q, want 4 and a cursor", len(edits), end.NextCursor)
+	}
+	if _, end := stream(Query{Type: "recent", Stream: true}); end.Error == "" {
+		t.Error("expected a recent query to refuse streaming")
+	}
+}
+
+// TestDaemonIngestBurst tests that a burst of edits is written in batches
+// and visible to the queries that follow it
+func TestDaemonIngestBurst(t *testing.T) {
+	tmpDir := t.TempDir()
+	ws := filepath.Join(tmpDir, "api")
+
+	daemon, _ := startTestDaemon(t, nil)
+	cfg := daemon.cfg
+
+	conn, err := net.Dial("unix", cfg.Sockets.DaemonSocket)
+	if err != nil {
+		t.Fatalf("failed to connect to daemon: %v", err)
+	}
+	defer conn.Close()
+
+	// A MultiEdit-heavy session firing edits back to back
+	const burst = 250
+	for i := 0; i < burst; i++ {
+		sendPayloadAndWaitForResponse(t, conn, &HookPayload{
+			Type:      "edit",
+			Workspace: ws,
+			ToolName:  "MultiEdit",
+			FilePath:  filepath.Join(ws, fmt.Sprintf("f%d.go", i%10)),
+			OldString: "a",
+			NewString: "b",
+		})
+	}
+
+	edits := queryRecentEdits(t, cfg.Sockets.QuerySocket, 1000)
+	if len(edits) != burst {
+		t.Fatalf("expected %d edits, got %d", burst, len(edits))
+	}
+
+	qconn, err := net.Dial("unix", cfg.Sockets.QuerySocket)
+	if err != nil {
+		t.Fatalf("failed to connect to query socket: %v", err)
+	}
+	defer qconn.Close()
+	if err := json.NewEncoder(qconn).Encode(Query{Type: "status"}); err != nil {
+		t.Fatalf("failed to send status query: %v", err)
+	}
+	var result QueryResult
+	if err := json.NewDecoder(qconn).Decode(&result); err != nil {
+		t.Fatalf("failed to decode status result: %v", err)
+	}
+	ingest := result.Status.Ingest
+	if ingest.Written != burst || ingest.Queued != 0 || ingest.Failed != 0 {
+		t.Errorf("unexpected ingest stats: %+v", ingest)
+	}
+	if ingest.Batches >= burst || ingest.LargestBatch > cfg.Ingest.BatchSize {
+		t.Errorf("expected edits written in batches of up to %d, got %+v", cfg.Ingest.BatchSize, ingest)
+	}
+}
+
+// TestDaemonSnapshotDedupe tests that edits with the same file content
+// share one zstd snapshot, dropped with the last edit referencing it
+func TestDaemonSnapshotDedupe(t *testing.T) {
+	tmpDir := t.TempDir()
+	ws := filepath.Join(tmpDir, "api")
+
+	daemon, rawQuery := startTestDaemon(t, func(cfg *Config) {
+		cfg.Database.SnapshotCodec = database.CodecZstd
+	})
+	cfg := daemon.cfg
+
+	conn, err := net.Dial("unix", cfg.Sockets.DaemonSocket)
+	if err != nil {
+		t.Fatalf("failed to connect to daemon: %v", err)
+	}
+	defer conn.Close()
+
+	query := func(q Query) QueryResult {
+		t.Helper()
+		result := rawQuery(q)
+		if result.Error != "" {
+			t.Fatalf("%s query failed: %s", q.Type, result.Error)
+		}
+		return result
+	}
+
+	// Three edits leave a large file the same; a fourth changes it
+	large := strings.Repeat("func f() {}\n", 5000)
+	for i, content := range []string{large, large, large, large + "// end\n"} {
+		sendPayloadAndWaitForResponse(t, conn, &HookPayload{
+			Type:           "edit",
+			Workspace:      ws,
+			ToolName:       "Edit",
+			FilePath:       filepath.Join(ws, "main.go"),
+			OldString:      fmt.Sprint(i),
+			NewString:      fmt.Sprint(i + 1),
+			FileContentB64: base64.StdEncoding.EncodeToString([]byte(content)),
+		})
+	}
+
+	edits := queryRecentEdits(t, cfg.Sockets.QuerySocket, 10)
+	if len(edits) != 4 {
+		t.Fatalf("expected 4 edits, got %d", len(edits))
+	}
+	if edits[0].FileContent != large+"// end\n" || edits[3].FileContent != large {
+		t.Error("expected edits to read back their own file content")
+	}
+
+	stats := query(Query{Type: "dedupe"}).Dedupe
+	if stats.Moved != 0 || stats.Blobs != 2 || stats.Refs != 4 {
+		t.Errorf("expected 2 snapshots shared by 4 edits, got %+v", stats)
+	}
+	if stats.BytesAfter == 0 || stats.BytesAfter > int64(len(large)/10) {
+		t.Errorf("expected snapshots stored compressed once, got %d bytes", stats.BytesAfter)
+	}
+
+	query(Query{Type: "workspace_purge", WorkspacePath: ws})
+	if stats := query(Query{Type: "dedupe"}).Dedupe; stats.Blobs != 0 {
+		t.Errorf("expected snapshots dropped with their edits, got %+v", stats)
+	}
+}
+
+// TestDaemonPromptLibrary tests prompts kept in the daemon's database: saved
+// by one store, they're written out for another, versions included
+func TestDaemonPromptLibrary(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	daemon, _ := startTestDaemon(t, nil)
+	cfg := daemon.cfg
+
+	lib := &PromptLibrary{SocketPath: cfg.Sockets.QuerySocket}
+	api := prompt.NewLibraryStore(lib, filepath.Join(tmpDir, "api"))
+	web := prompt.NewLibraryStore(lib, filepath.Join(tmpDir, "web"))
+
+	review := &prompt.Prompt{
+		Name:      "Code Review",
+		Version:   1,
+		Tags:      []string{"code"},
+		Variables: []prompt.Variable{{Name: "focus", Default: "errors"}},
+		Content:   "Review for {{focus}}",
+	}
+	if err := api.Save(review); err != nil {
+		t.Fatalf("failed to save prompt: %v", err)
+	}
+	if err := api.CreateVersion(review); err != nil {
+		t.Fatalf("failed to create version: %v", err)
+	}
+
+	prompts, err := web.List()
+	if err != nil {
+		t.Fatalf("failed to list prompts: %v", err)
+	}
+	if len(prompts) != 1 || prompts[0].Content != "Review for {{focus}}" || prompts[0].VersionCount != 1 {
+		t.Fatalf("expected the saved prompt with one version, got %+v", prompts)
+	}
+	if len(prompts[0].Variables) != 1 || prompts[0].Variables[0].Default != "errors" {
+		t.Errorf("expected the variable kept, got %+v", prompts[0].Variables)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "web", "code-review.v1.prompt.md")); err != nil {
+		t.Errorf("expected the version backup written out: %v", err)
+	}
+
+	// The library answers the prompts query
+	result, err := SendQuery(cfg.Sockets.QuerySocket, &Query{Type: "prompts", Tag: "code"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Prompts) != 1 || result.Prompts[0].Name != "Code Review" {
+		t.Errorf("expected the prompt tagged code, got %+v", result.Prompts)
+	}
+
+	// Deleting in one store removes it from the other on its next list
+	if err := web.Delete(prompts[0].Path); err != nil {
+		t.Fatalf("failed to delete prompt: %v", err)
+	}
+	if prompts, err := api.List(); err != nil || len(prompts) != 0 {
+		t.Errorf("expected no prompts left, got %d (%v)", len(prompts), err)
+	}
+	if _, err := os.Stat(review.Path); !os.IsNotExist(err) {
+		t.Error("expected the deleted prompt's file removed")
+	}
+}
+
+func TestDaemonUsers(t *testing.T) {
+	tmpDir := t.TempDir()
+	mine := filepath.Join(tmpDir, "mine")
+	theirs := filepath.Join(tmpDir, "theirs")
+
+	daemon, query := startTestDaemon(t, func(cfg *Config) {
+		cfg.Users.Shared = true
+	})
+	cfg := daemon.cfg
+
+	// Edits over the socket are tagged with the hook's user
+	conn, err := net.Dial("unix", cfg.Sockets.DaemonSocket)
+	if err != nil {
+		t.Fatalf("failed to connect to daemon: %v", err)
+	}
+	defer conn.Close()
+	sendPayloadAndWaitForResponse(t, conn, &HookPayload{
+		Type: "edit", Workspace: mine, ToolName: "Edit", FilePath: filepath.Join(mine, "main.go"),
+	})
+	me := peerUser(conn)
+	if me == "" {
+		t.Skip("peer credentials aren't available on this OS")
+	}
+
+	// Another user's edits, as their hook would have sent them
+	sessionID, err := daemon.db.UpsertSession(theirs, "theirs", "main", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"a.go", "b.go"} {
+		if err := daemon.db.RecordEdit(&database.Edit{
+			SessionID: sessionID, ToolName: "Write", FilePath: filepath.Join(theirs, file), User: "bob",
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The user running the daemon is an admin: they see everyone's edits,
+	// and can narrow them to one user's
+	if result := query(Query{Type: "recent"}); len(result.Edits) != 3 {
+		t.Fatalf("expected 3 edits overall, got %d", len(result.Edits))
+	}
+	result := query(Query{Type: "recent", User: me})
+	if len(result.Edits) != 1 || result.Edits[0].User != me {
+		t.Errorf("expected my one edit, got %+v", result.Edits)
+	}
+	if result := query(Query{Type: "timeline", User: "bob"}); len(result.Timeline) != 2 {
+		t.Errorf("expected bob's 2 edits in the timeline, got %d", len(result.Timeline))
+	}
+
+	// A user's queries only see their workspaces
+	bob := func(q Query) (*QueryResult, error) {
+		q.User = "bob"
+		return daemon.executeQuery(&q)
+	}
+	if r, err := bob(Query{Type: "sessions"}); err != nil || len(r.Sessions) != 1 || r.Sessions[0].WorkspacePath != theirs {
+		t.Errorf("expected bob's session only, got %+v (%v)", r, err)
+	}
+	if _, err := bob(Query{Type: "workspace", WorkspacePath: mine}); err == nil || !strings.Contains(err.Error(), "forbidden") {
+		t.Errorf("expected another user's workspace to be refused, got %v", err)
+	}
+	if r, err := bob(Query{Type: "digest", Until: time.Now().Add(time.Minute)}); err != nil || r.Digest.Total().Edits != 2 {
+		t.Errorf("expected bob's 2 edits in his digest, got %+v (%v)", r, err)
+	}
+	if r, err := bob(Query{Type: "status"}); err != nil || len(r.Status.Workspaces) != 0 || r.Status.Workspaces[mine] != nil {
+		t.Errorf("expected my workspace hidden from bob's status, got %+v (%v)", r, err)
+	}
+
+	// Queries whose user can't be told are refused
+	if err := daemon.authorizeUser(nil, &Query{Type: "recent"}); err == nil {
+		t.Error("expected a query from an unknown user to be refused")
+	}
+}
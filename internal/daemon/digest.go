@@ -0,0 +1,150 @@
+package daemon
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/crash"
+	"github.com/ztaylor/claude-mon/internal/digest"
+	"github.com/ztaylor/claude-mon/internal/filter"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// defaultDigestPeriod is how far back a digest looks unless told otherwise
+const defaultDigestPeriod = 24 * time.Hour
+
+// DigestPoster posts the activity digest once a day
+type DigestPoster struct {
+	d      *Daemon
+	stopCh chan struct{}
+}
+
+// NewDigestPoster creates a digest poster reading its settings from the
+// daemon config
+func NewDigestPoster(d *Daemon) *DigestPoster {
+	return &DigestPoster{
+		d:      d,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// config returns the current digest settings, which may change on reload
+func (dp *DigestPoster) config() DigestConfig {
+	dp.d.cfgMu.RLock()
+	defer dp.d.cfgMu.RUnlock()
+	return dp.d.cfg.Digest
+}
+
+// Start begins posting the digest at its time each day
+func (dp *DigestPoster) Start() {
+	if !dp.config().Enabled {
+		logger.Log("Digest poster disabled")
+		return
+	}
+
+	go func() {
+		defer crash.Recover("daemon digest", crash.Exit)
+		for {
+			cfg := dp.config()
+			next, err := nextDigestTime(time.Now(), cfg.Time)
+			if err != nil {
+				logger.Log("Digest poster stopped: %v", err)
+				return
+			}
+			logger.Log("Next digest at %s", next.Format(time.RFC3339))
+
+			timer := time.NewTimer(time.Until(next))
+			select {
+			case <-timer.C:
+				if err := dp.post(); err != nil {
+					logger.Log("Digest not posted: %v", err)
+					dp.d.recordEvent("", EventFailure, SeverityWarning, fmt.Sprintf("digest not posted: %v", err))
+				}
+			case <-dp.stopCh:
+				timer.Stop()
+				logger.Log("Digest poster stopped")
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the digest poster
+func (dp *DigestPoster) Stop() {
+	close(dp.stopCh)
+}
+
+// post sends the digest of the configured period to its webhook, unless
+// there was no activity
+func (dp *DigestPoster) post() error {
+	cfg := dp.config()
+	if !cfg.Enabled {
+		return nil
+	}
+	f, err := filter.Parse("since:" + cfg.Period)
+	if err != nil {
+		return err
+	}
+	dp.d.ingest.Flush() // Count the edits still queued
+	query := &Query{Type: "digest", Group: cfg.Group, Since: f.Since}
+	result, err := dp.d.executeQuery(query)
+	if err != nil {
+		return err
+	}
+	if len(result.Digest.Workspaces) == 0 && len(result.Digest.Prompts) == 0 {
+		logger.Log("No activity since %s; digest skipped", f.Since.Format(time.RFC3339))
+		return nil
+	}
+
+	text, err := digest.Format(result.Digest, cfg.Format)
+	if err != nil {
+		return err
+	}
+	body, err := digest.Body(cfg.Format, text)
+	if err != nil {
+		return err
+	}
+	if err := dp.d.webhooks.Post(Webhook{Name: "digest", URL: cfg.URL}, "digest", body); err != nil {
+		return err
+	}
+	logger.Log("Posted digest of %d workspace(s)", len(result.Digest.Workspaces))
+	return nil
+}
+
+// nextDigestTime returns the next time after now that the clock reads at,
+// "15:04" in local time
+func nextDigestTime(now time.Time, at string) (time.Time, error) {
+	clock, err := time.Parse("15:04", at)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("time must be HH:MM, got %q", at)
+	}
+	next := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next, nil
+}
+
+// validate checks the digest's time, period, format, webhook and group
+func (dc DigestConfig) validate(cfg *Config) error {
+	if _, err := nextDigestTime(time.Now(), dc.Time); err != nil {
+		return err
+	}
+	if _, err := filter.Parse("since:" + dc.Period); err != nil {
+		return fmt.Errorf("period: %w", err)
+	}
+	if !slices.Contains(digest.Formats, dc.Format) {
+		return fmt.Errorf("format must be one of %s", strings.Join(digest.Formats, ", "))
+	}
+	if err := (Webhook{URL: dc.URL}).validate(); err != nil {
+		return err
+	}
+	if dc.Group != "" {
+		if _, err := cfg.GroupWorkspaces(dc.Group); err != nil {
+			return fmt.Errorf("group: %w", err)
+		}
+	}
+	return nil
+}
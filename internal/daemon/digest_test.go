@@ -0,0 +1,27 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextDigestTime(t *testing.T) {
+	now := time.Date(2026, 10, 16, 10, 30, 0, 0, time.Local)
+	tests := []struct {
+		at   string
+		want time.Time
+	}{
+		{"11:00", time.Date(2026, 10, 16, 11, 0, 0, 0, time.Local)},
+		{"09:00", time.Date(2026, 10, 17, 9, 0, 0, 0, time.Local)},
+		{"10:30", time.Date(2026, 10, 17, 10, 30, 0, 0, time.Local)},
+	}
+	for _, tt := range tests {
+		got, err := nextDigestTime(now, tt.at)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("nextDigestTime(%s) = %v, %v; want %v", tt.at, got, err, tt.want)
+		}
+	}
+	if _, err := nextDigestTime(now, "9am"); err == nil {
+		t.Error("expected an invalid time rejected")
+	}
+}
@@ -0,0 +1,394 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/annotate"
+	"github.com/ztaylor/claude-mon/internal/auth"
+	"github.com/ztaylor/claude-mon/internal/crash"
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/filter"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// editorProtocolVersion is the editor socket's protocol version, bumped
+// when a change would break existing plugins
+const editorProtocolVersion = 1
+
+// JSON-RPC error codes the editor socket answers with
+const (
+	rpcParseError     = -32700
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+	rpcServerError    = -32000 // The daemon refused or failed the request
+)
+
+// rpcMessage is a JSON-RPC request or notification from an editor, or a
+// response or notification to one
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  any             `json:"params,omitempty"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// editorParams are the params of every editor request; each method reads
+// the ones it needs
+type editorParams struct {
+	Token string   `json:"token"` // API token, when auth is required
+	File  string   `json:"file"`
+	Files []string `json:"files"`
+	Since string   `json:"since"` // e.g. "1h" or "2026-10-16"; editor.recent_hours if empty
+	Limit int      `json:"limit"`
+}
+
+// files returns the params' files, cleaned so they compare with edits'
+func (p editorParams) files() []string {
+	var files []string
+	for _, f := range append(p.Files, p.File) {
+		if f != "" {
+			files = append(files, filepath.Clean(f))
+		}
+	}
+	return files
+}
+
+// editorHunk is the lines one edit left in a file, as a plugin marks them
+type editorHunk struct {
+	ID        int64     `json:"id"` // 0 for edits notified as they're recorded
+	File      string    `json:"file"`
+	StartLine int       `json:"start_line"` // 1-based
+	EndLine   int       `json:"end_line"`   // Inclusive
+	Kind      string    `json:"kind"`       // "add", "change" or "delete", for the sign
+	Tool      string    `json:"tool"`
+	Message   string    `json:"message"`
+	Review    string    `json:"review,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+func hunkOf(e *database.TimelineEdit) editorHunk {
+	a := annotate.FromEdit(e)
+	kind := "change"
+	switch {
+	case e.OldString == "" && e.NewString != "":
+		kind = "add"
+	case e.NewString == "" && e.OldString != "":
+		kind = "delete"
+	}
+	return editorHunk{
+		ID:        e.ID,
+		File:      editPath(e),
+		StartLine: a.StartLine,
+		EndLine:   a.EndLine,
+		Kind:      kind,
+		Tool:      e.ToolName,
+		Message:   a.Message,
+		Review:    e.Review,
+		Timestamp: e.Timestamp,
+	}
+}
+
+// editPath is an edit's file as an absolute path, when its workspace is
+// known
+func editPath(e *database.TimelineEdit) string {
+	if filepath.IsAbs(e.FilePath) || e.WorkspacePath == "" {
+		return filepath.Clean(e.FilePath)
+	}
+	return filepath.Join(e.WorkspacePath, e.FilePath)
+}
+
+// acceptEditors accepts editor connections
+func (d *Daemon) acceptEditors() {
+	defer d.wg.Done()
+
+	for {
+		conn, err := d.editorListener.Accept()
+		if err != nil {
+			select {
+			case <-d.shutdown:
+				return
+			default:
+				logger.Log("Editor accept error: %v", err)
+				continue
+			}
+		}
+
+		d.wg.Add(1)
+		go d.handleEditor(conn)
+	}
+}
+
+// editorSession is one editor's connection. Editors keep it open, sending
+// requests and receiving notifications about the files they subscribe to.
+type editorSession struct {
+	d    *Daemon
+	conn net.Conn
+
+	writeMu sync.Mutex // Responses and notifications interleave
+	enc     *json.Encoder
+
+	mu    sync.Mutex
+	token string // From "initialize", for requests that don't carry one
+	user  string // On a shared daemon, whose edits are notified
+	files map[string]bool
+	sub   *subscriber
+}
+
+// handleEditor serves an editor connection until it's closed or the daemon
+// stops
+func (d *Daemon) handleEditor(conn net.Conn) {
+	defer d.wg.Done()
+	defer conn.Close()
+	defer crash.Recover("daemon editor", nil)
+
+	logger.Log("New editor connection from %s", conn.RemoteAddr())
+	s := &editorSession{d: d, conn: conn, enc: json.NewEncoder(conn), files: make(map[string]bool)}
+	s.enc.SetEscapeHTML(false)
+	defer s.unsubscribe(nil)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-d.shutdown:
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.Unmarshal(line, &req); err != nil {
+			logger.Log("Editor: invalid message: %v", err)
+			s.send(rpcMessage{ID: json.RawMessage("null"), Error: &rpcError{Code: rpcParseError, Message: "parse error"}})
+			continue
+		}
+
+		var params editorParams
+		if len(req.Params) > 0 {
+			if err := json.Unmarshal(req.Params, &params); err != nil {
+				s.reply(req.ID, nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()})
+				continue
+			}
+		}
+		result, rpcErr := s.handle(req.Method, params)
+		s.reply(req.ID, result, rpcErr)
+	}
+}
+
+// reply answers a request; notifications get no answer
+func (s *editorSession) reply(id json.RawMessage, result any, rpcErr *rpcError) {
+	if len(id) == 0 {
+		return
+	}
+	if result == nil && rpcErr == nil {
+		result = map[string]any{}
+	}
+	s.send(rpcMessage{ID: id, Result: result, Error: rpcErr})
+}
+
+// notify sends the editor a notification
+func (s *editorSession) notify(method string, params any) {
+	s.send(rpcMessage{Method: method, Params: params})
+}
+
+func (s *editorSession) send(msg rpcMessage) {
+	msg.JSONRPC = "2.0"
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := s.enc.Encode(msg); err != nil {
+		logger.Log("Editor write error: %v", err)
+	}
+}
+
+// handle answers one request
+func (s *editorSession) handle(method string, params editorParams) (any, *rpcError) {
+	logger.Log("Editor: %s", method)
+	if params.Token == "" {
+		s.mu.Lock()
+		params.Token = s.token
+		s.mu.Unlock()
+	}
+
+	switch method {
+	case "initialize":
+		s.mu.Lock()
+		s.token = params.Token
+		s.mu.Unlock()
+		return map[string]any{
+			"name":     "claude-mon",
+			"protocol": editorProtocolVersion,
+			"methods":  []string{"initialize", "ping", "hunks", "subscribe", "unsubscribe"},
+		}, nil
+
+	case "ping":
+		return map[string]any{}, nil
+
+	case "hunks":
+		return s.hunks(params)
+
+	case "subscribe":
+		return s.subscribe(params)
+
+	case "unsubscribe":
+		files := params.files()
+		s.unsubscribe(files)
+		return map[string]any{"files": s.subscribed()}, nil
+	}
+	return nil, &rpcError{Code: rpcMethodNotFound, Message: "unknown method: " + method}
+}
+
+// hunks lists the lines Claude changed in a file recently, newest first,
+// through a "file" query so it's authorized and scoped like one
+func (s *editorSession) hunks(params editorParams) (any, *rpcError) {
+	if params.File == "" {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "file required"}
+	}
+	file := filepath.Clean(params.File)
+
+	s.d.cfgMu.RLock()
+	recent := s.d.cfg.Editor.RecentHours
+	s.d.cfgMu.RUnlock()
+	var since time.Time
+	if params.Since != "" {
+		f, err := filter.Parse("since:" + params.Since)
+		if err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		since = f.Since
+	} else if recent > 0 {
+		since = time.Now().Add(-time.Duration(recent) * time.Hour)
+	}
+
+	start := time.Now()
+	query := &Query{Type: "file", FilePath: file, Limit: params.Limit, Token: params.Token}
+	if err := s.d.authorizeQuery(query, true); err != nil {
+		s.d.auditQuery(s.conn, query, start, err, nil)
+		return nil, &rpcError{Code: rpcServerError, Message: err.Error()}
+	}
+	if err := s.d.authorizeUser(s.conn, query); err != nil {
+		s.d.auditQuery(s.conn, query, start, err, nil)
+		return nil, &rpcError{Code: rpcServerError, Message: err.Error()}
+	}
+	s.d.ingest.Flush()
+	result, err := s.d.executeQuery(query)
+	s.d.auditQuery(s.conn, query, start, nil, err)
+	if err != nil {
+		return nil, &rpcError{Code: rpcServerError, Message: err.Error()}
+	}
+
+	hunks := []editorHunk{}
+	for _, e := range result.Edits {
+		if !since.IsZero() && e.Timestamp.Before(since) {
+			break // Newest first
+		}
+		hunks = append(hunks, hunkOf(&database.TimelineEdit{Edit: *e}))
+	}
+	return map[string]any{"file": file, "hunks": hunks}, nil
+}
+
+// subscribe adds files to those the editor is notified about. The first
+// subscription starts a goroutine forwarding their edits as "hunk"
+// notifications.
+func (s *editorSession) subscribe(params editorParams) (any, *rpcError) {
+	files := params.files()
+	if len(files) == 0 {
+		return nil, &rpcError{Code: rpcInvalidParams, Message: "file or files required"}
+	}
+	if err := s.d.authorize(params.Token, auth.ScopeRead, true); err != nil {
+		return nil, &rpcError{Code: rpcServerError, Message: err.Error()}
+	}
+	query := &Query{Type: "subscribe"}
+	if err := s.d.authorizeUser(s.conn, query); err != nil {
+		return nil, &rpcError{Code: rpcServerError, Message: err.Error()}
+	}
+
+	s.mu.Lock()
+	for _, f := range files {
+		s.files[f] = true
+	}
+	s.user = query.User
+	if s.sub == nil {
+		s.sub = s.d.notifier.subscribe("", true, false)
+		go s.forward(s.sub)
+	}
+	s.mu.Unlock()
+	return map[string]any{"files": s.subscribed()}, nil
+}
+
+// unsubscribe removes files from those the editor is notified about, or
+// all of them if none are given
+func (s *editorSession) unsubscribe(files []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(files) == 0 {
+		clear(s.files)
+	}
+	for _, f := range files {
+		delete(s.files, f)
+	}
+	if len(s.files) == 0 && s.sub != nil {
+		s.d.notifier.unsubscribe(s.sub)
+		s.sub = nil
+	}
+}
+
+// subscribed lists the files the editor is notified about
+func (s *editorSession) subscribed() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files := []string{}
+	for f := range s.files {
+		files = append(files, f)
+	}
+	return files
+}
+
+// forward sends the editor a "hunk" notification for each edit to a file
+// it subscribed to. An editor too far behind is sent "reset", to list its
+// files' hunks again, and subscribed afresh.
+func (s *editorSession) forward(sub *subscriber) {
+	for n := range sub.ch {
+		path := editPath(n.edit)
+		s.mu.Lock()
+		wanted := s.files[path] && (s.user == "" || s.d.visibleTo(s.user, n))
+		s.mu.Unlock()
+		if wanted {
+			s.notify("hunk", hunkOf(n.edit))
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sub != sub {
+		return // Unsubscribed
+	}
+	logger.Log("Editor %s fell behind; resubscribing", s.conn.RemoteAddr())
+	s.sub = s.d.notifier.subscribe("", true, false)
+	go s.forward(s.sub)
+	go s.notify("reset", map[string]any{"reason": fmt.Sprintf("more than %d edits behind", subscriberBuffer)})
+}
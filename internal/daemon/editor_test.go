@@ -0,0 +1,116 @@
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEditorSocket(t *testing.T) {
+	tmpDir := t.TempDir()
+	ws := filepath.Join(tmpDir, "api")
+	file := filepath.Join(ws, "main.go")
+
+	daemon, _ := startTestDaemon(t, func(cfg *Config) {
+		cfg.Editor = EditorConfig{Enabled: true, Socket: filepath.Join(tmpDir, "editor.sock"), RecentHours: 24}
+	})
+	cfg := daemon.cfg
+
+	conn, err := net.Dial("unix", cfg.Sockets.DaemonSocket)
+	if err != nil {
+		t.Fatalf("failed to connect to daemon: %v", err)
+	}
+	defer conn.Close()
+	sendPayloadAndWaitForResponse(t, conn, &HookPayload{
+		Type: "edit", Workspace: ws, ToolName: "Edit", FilePath: file,
+		OldString: "a", NewString: "b\nc", LineNum: 10,
+	})
+
+	editor, err := net.Dial("unix", cfg.Editor.Socket)
+	if err != nil {
+		t.Fatalf("failed to connect to editor socket: %v", err)
+	}
+	defer editor.Close()
+	editor.SetDeadline(time.Now().Add(10 * time.Second))
+	scanner := bufio.NewScanner(editor)
+
+	type message struct {
+		ID     int             `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+	read := func() message {
+		t.Helper()
+		if !scanner.Scan() {
+			t.Fatalf("editor socket closed: %v", scanner.Err())
+		}
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			t.Fatalf("invalid message %s: %v", scanner.Text(), err)
+		}
+		return msg
+	}
+	id := 0
+	call := func(method string, params any) message {
+		t.Helper()
+		id++
+		if err := json.NewEncoder(editor).Encode(map[string]any{"jsonrpc": "2.0", "id": id, "method": method, "params": params}); err != nil {
+			t.Fatal(err)
+		}
+		msg := read()
+		if msg.ID != id {
+			t.Fatalf("got %s, want the response to %s", scanner.Text(), method)
+		}
+		return msg
+	}
+
+	if msg := call("initialize", nil); msg.Error != nil {
+		t.Fatalf("initialize failed: %v", msg.Error.Message)
+	}
+	if msg := call("nope", nil); msg.Error == nil || msg.Error.Code != rpcMethodNotFound {
+		t.Errorf("unknown method answered %s", scanner.Text())
+	}
+
+	var hunks struct {
+		File  string       `json:"file"`
+		Hunks []editorHunk `json:"hunks"`
+	}
+	msg := call("hunks", map[string]any{"file": file})
+	if err := json.Unmarshal(msg.Result, &hunks); err != nil || msg.Error != nil {
+		t.Fatalf("hunks failed: %s", scanner.Text())
+	}
+	if len(hunks.Hunks) != 1 {
+		t.Fatalf("got %d hunks, want 1", len(hunks.Hunks))
+	}
+	if h := hunks.Hunks[0]; h.StartLine != 10 || h.EndLine != 11 || h.Kind != "change" || h.ID == 0 {
+		t.Errorf("unexpected hunk: %+v", h)
+	}
+
+	// Edits to subscribed files are notified as they're recorded
+	if msg := call("subscribe", map[string]any{"files": []string{file}}); msg.Error != nil {
+		t.Fatalf("subscribe failed: %v", msg.Error.Message)
+	}
+	sendPayloadAndWaitForResponse(t, conn, &HookPayload{
+		Type: "edit", Workspace: ws, ToolName: "Edit", FilePath: filepath.Join(ws, "other.go"), NewString: "x", LineNum: 1,
+	})
+	sendPayloadAndWaitForResponse(t, conn, &HookPayload{
+		Type: "edit", Workspace: ws, ToolName: "Edit", FilePath: file, NewString: "d\ne\nf\n", LineNum: 3,
+	})
+	note := read()
+	var hunk editorHunk
+	if err := json.Unmarshal(note.Params, &hunk); err != nil || note.Method != "hunk" {
+		t.Fatalf("got %s, want a hunk notification", scanner.Text())
+	}
+	if hunk.File != file || hunk.StartLine != 3 || hunk.EndLine != 5 || hunk.Kind != "add" {
+		t.Errorf("unexpected notified hunk: %+v", hunk)
+	}
+
+	if msg := call("unsubscribe", nil); string(msg.Result) != `{"files":[]}` {
+		t.Errorf("unsubscribe answered %s", scanner.Text())
+	}
+}
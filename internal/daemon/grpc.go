@@ -0,0 +1,478 @@
+package daemon
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/auth"
+	"github.com/ztaylor/claude-mon/internal/crash"
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/pkg/daemonpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcServer serves the gRPC API (pkg/daemonpb) on the daemon's storage,
+// through the same code paths as the JSON sockets
+type grpcServer struct {
+	daemonpb.UnimplementedDaemonServer
+	d *Daemon
+}
+
+// serveGRPC starts the gRPC API on its unix socket and, if configured, a
+// TCP address, over TLS when a certificate is configured
+func (d *Daemon) serveGRPC() error {
+	d.cfgMu.RLock()
+	cfg := d.cfg.GRPC
+	d.cfgMu.RUnlock()
+
+	d.grpcServer = grpc.NewServer(grpc.Creds(peerCreds{}))
+	daemonpb.RegisterDaemonServer(d.grpcServer, &grpcServer{d: d})
+
+	listener, err := d.listenSocket(cfg.Socket)
+	if err != nil {
+		return err
+	}
+	listeners := []net.Listener{listener}
+	if cfg.Listen != "" {
+		tcp, err := net.Listen("tcp", cfg.Listen)
+		if err != nil {
+			listener.Close()
+			return fmt.Errorf("failed to listen on %s: %w", cfg.Listen, err)
+		}
+		if cfg.TLSCert != "" {
+			cert, err := tls.LoadX509KeyPair(cfg.TLSCert, cfg.TLSKey)
+			if err != nil {
+				listener.Close()
+				tcp.Close()
+				return fmt.Errorf("grpc tls: %w", err)
+			}
+			tcp = tls.NewListener(tcp, &tls.Config{
+				Certificates: []tls.Certificate{cert},
+				NextProtos:   []string{"h2"},
+				MinVersion:   tls.VersionTLS12,
+			})
+		}
+		listeners = append(listeners, tcp)
+	}
+	for _, l := range listeners {
+		go d.grpcServer.Serve(l)
+		logger.Log("gRPC API listening on %s", l.Addr())
+	}
+	return nil
+}
+
+// peerCreds passes each connection through as the listener accepted it,
+// TLS already set up on a TCP listener with a certificate, keeping it so
+// requests can be told apart by the user on the other end, as on the JSON
+// sockets
+type peerCreds struct{}
+
+// peerConn is a connection's AuthInfo
+type peerConn struct {
+	credentials.CommonAuthInfo
+	conn net.Conn
+}
+
+func (peerConn) AuthType() string { return "peercred" }
+
+func (peerCreds) ClientHandshake(_ context.Context, _ string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return conn, peerConn{conn: conn}, nil
+}
+
+func (peerCreds) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	info := peerConn{conn: conn}
+	info.SecurityLevel = credentials.NoSecurity
+	return conn, info, nil
+}
+
+func (peerCreds) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{SecurityProtocol: "peercred"}
+}
+
+func (peerCreds) Clone() credentials.TransportCredentials { return peerCreds{} }
+
+func (peerCreds) OverrideServerName(string) error { return nil }
+
+// caller returns the connection a request came in on, whether it's local
+// (a unix socket) and the token its metadata carries
+func caller(ctx context.Context) (conn net.Conn, local bool, token string) {
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(peerConn); ok {
+			conn = info.conn
+			_, local = conn.(*net.UnixConn)
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			token = strings.TrimPrefix(values[0], "Bearer ")
+		}
+	}
+	return conn, local, token
+}
+
+// grpcError gives an error the status code clients expect: auth failures
+// are unauthenticated or permission denied, a busy daemon resource
+// exhausted, and anything else the request's fault
+func grpcError(err error) error {
+	switch msg := err.Error(); {
+	case strings.HasPrefix(msg, "unauthorized"):
+		return status.Error(codes.Unauthenticated, msg)
+	case strings.HasPrefix(msg, "forbidden"):
+		return status.Error(codes.PermissionDenied, msg)
+	case errors.Is(err, ErrIngestBusy):
+		return status.Error(codes.ResourceExhausted, msg)
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, msg)
+	}
+	return status.Error(codes.InvalidArgument, err.Error())
+}
+
+// internalError is a crash.Recover callback failing the call a panic cut
+// short, which would otherwise answer it with nothing
+func internalError(err *error) func(path string) {
+	return func(string) {
+		*err = status.Error(codes.Internal, "internal error")
+	}
+}
+
+// Ingest records a hook payload, as handleConnection does
+func (s *grpcServer) Ingest(ctx context.Context, req *daemonpb.IngestRequest) (_ *daemonpb.IngestResponse, err error) {
+	defer crash.Recover("daemon grpc payload", internalError(&err))
+	conn, local, token := caller(ctx)
+	payload, err := payloadFromRequest(req)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if token == "" {
+		token = payload.Token
+	}
+	if err := s.d.authorize(token, auth.ScopeIngest, local); err != nil {
+		logger.Log("Payload rejected: %v", err)
+		return nil, grpcError(err)
+	}
+	payload.user = peerUser(conn)
+	if err := s.d.processPayload(payload); err != nil {
+		if !errors.Is(err, ErrIngestBusy) {
+			logger.Log("Process payload error: %v", err)
+			s.d.recordEvent(payload.Workspace, EventFailure, SeverityError,
+				fmt.Sprintf("%s payload rejected: %v", payload.Type, err))
+		}
+		return nil, grpcError(err)
+	}
+	return &daemonpb.IngestResponse{}, nil
+}
+
+// payloadFromRequest reads a hook payload from its JSON, if it's sent
+// whole, or the request's fields
+func payloadFromRequest(req *daemonpb.IngestRequest) (*HookPayload, error) {
+	if len(req.PayloadJson) > 0 {
+		crash.SetLastPayload(req.PayloadJson)
+		var payload HookPayload
+		if err := json.Unmarshal(req.PayloadJson, &payload); err != nil {
+			return nil, fmt.Errorf("invalid payload_json: %w", err)
+		}
+		return &payload, nil
+	}
+
+	payload := &HookPayload{
+		Type:          req.Type,
+		Workspace:     req.Workspace,
+		WorkspaceName: req.WorkspaceName,
+		Branch:        req.Branch,
+		CommitSHA:     req.CommitSha,
+		VCSType:       req.VcsType,
+		ToolName:      req.ToolName,
+		FilePath:      req.FilePath,
+		OldString:     req.OldString,
+		NewString:     req.NewString,
+		LineNum:       int(req.LineNum),
+		LineCount:     int(req.LineCount),
+		ChatSessionID: req.ChatSessionId,
+		EventKind:     req.EventKind,
+		Severity:      req.Severity,
+		Message:       req.Message,
+	}
+	if payload.Type == "" {
+		payload.Type = "edit"
+	}
+	if len(req.FileContent) > 0 {
+		payload.FileContentB64 = base64.StdEncoding.EncodeToString(req.FileContent)
+	}
+	return payload, nil
+}
+
+// queryFromRequest reads a query from its JSON, if it's sent whole, or the
+// request's fields
+func queryFromRequest(req *daemonpb.QueryRequest, token string) (*Query, error) {
+	query := &Query{}
+	if len(req.QueryJson) > 0 {
+		if err := json.Unmarshal(req.QueryJson, query); err != nil {
+			return nil, fmt.Errorf("invalid query_json: %w", err)
+		}
+	} else {
+		query = &Query{
+			Type:          req.Type,
+			WorkspacePath: req.WorkspacePath,
+			Group:         req.Group,
+			FilePath:      req.FilePath,
+			Text:          req.Text,
+			Filter:        req.Filter,
+			Limit:         int(req.Limit),
+			Cursor:        req.Cursor,
+			User:          req.User,
+			Name:          req.Name,
+			ChatSessionID: req.ChatSessionId,
+		}
+		if req.Since != nil {
+			query.Since = req.Since.AsTime()
+		}
+		if req.Until != nil {
+			query.Until = req.Until.AsTime()
+		}
+	}
+	if query.Token == "" {
+		query.Token = token
+	}
+	return query, nil
+}
+
+// authorizedQuery reads a request's query and authorizes it as
+// handleQuery does, auditing it if it's refused
+func (s *grpcServer) authorizedQuery(ctx context.Context, req *daemonpb.QueryRequest, start time.Time) (*Query, net.Conn, error) {
+	conn, local, token := caller(ctx)
+	query, err := queryFromRequest(req, token)
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.d.authorizeQuery(query, local); err != nil {
+		logger.Log("Query rejected: %v", err)
+		s.d.auditQuery(conn, query, start, err, nil)
+		return nil, nil, grpcError(err)
+	}
+	if err := s.d.authorizeUser(conn, query); err != nil {
+		logger.Log("Query rejected: %v", err)
+		s.d.auditQuery(conn, query, start, err, nil)
+		return nil, nil, grpcError(err)
+	}
+
+	// See the edits queued before the query
+	s.d.ingest.Flush()
+	return query, conn, nil
+}
+
+// Query answers a query, as handleQuery does
+func (s *grpcServer) Query(ctx context.Context, req *daemonpb.QueryRequest) (_ *daemonpb.QueryResponse, err error) {
+	defer crash.Recover("daemon grpc query", internalError(&err))
+	start := time.Now()
+	query, conn, err := s.authorizedQuery(ctx, req, start)
+	if err != nil {
+		return nil, err
+	}
+	if query.Stream || query.Type == "export" {
+		return nil, status.Error(codes.InvalidArgument, "streamed queries are answered by Export")
+	}
+
+	result, err := s.d.executeQuery(query)
+	s.d.auditQuery(conn, query, start, nil, err)
+	if err != nil {
+		logger.Log("Query execution error: %v", err)
+		return nil, grpcError(err)
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	resp := &daemonpb.QueryResponse{NextCursor: result.NextCursor, ResultJson: resultJSON}
+	for _, e := range result.Edits {
+		resp.Edits = append(resp.Edits, editMessage(e))
+	}
+	for _, e := range result.Timeline {
+		resp.Edits = append(resp.Edits, timelineEditMessage(e))
+	}
+	for _, e := range result.Events {
+		resp.Events = append(resp.Events, eventMessage(e))
+	}
+	return resp, nil
+}
+
+// Export streams a "timeline" or "export" query's edits, as streamQuery
+// does
+func (s *grpcServer) Export(req *daemonpb.QueryRequest, stream grpc.ServerStreamingServer[daemonpb.Edit]) (err error) {
+	defer crash.Recover("daemon grpc export", internalError(&err))
+	start := time.Now()
+	query, conn, err := s.authorizedQuery(stream.Context(), req, start)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.d.shutdown:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	end := StreamLine{Done: true}
+	err = s.d.streamEdits(ctx, query, &end, func(e *database.TimelineEdit) error {
+		end.Count++
+		return stream.Send(timelineEditMessage(e))
+	})
+	s.d.auditQuery(conn, query, start, nil, err)
+	if err != nil {
+		logger.Log("Exported %s query failed after %d edits: %v", query.Type, end.Count, err)
+		return grpcError(err)
+	}
+	if end.NextCursor != "" {
+		stream.SetTrailer(metadata.Pairs("next-cursor", end.NextCursor))
+	}
+	return nil
+}
+
+// Subscribe streams edits and events as they're recorded. On a shared
+// daemon, users other than admins only see their own.
+func (s *grpcServer) Subscribe(req *daemonpb.SubscribeRequest, stream grpc.ServerStreamingServer[daemonpb.Notification]) error {
+	conn, local, token := caller(stream.Context())
+	if err := s.d.authorize(token, auth.ScopeRead, local); err != nil {
+		return grpcError(err)
+	}
+	query := &Query{Type: "subscribe", WorkspacePath: req.WorkspacePath}
+	if err := s.d.authorizeUser(conn, query); err != nil {
+		return grpcError(err)
+	}
+
+	sub := s.d.notifier.subscribe(req.WorkspacePath, req.Edits || !req.Events, req.Events || !req.Edits)
+	defer s.d.notifier.unsubscribe(sub)
+	for {
+		select {
+		case n, ok := <-sub.ch:
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "subscriber fell too far behind")
+			}
+			if query.User != "" && !s.d.visibleTo(query.User, n) {
+				continue
+			}
+			msg := &daemonpb.Notification{}
+			if n.edit != nil {
+				msg.Item = &daemonpb.Notification_Edit{Edit: timelineEditMessage(n.edit)}
+			} else {
+				msg.Item = &daemonpb.Notification_Event{Event: eventMessage(n.event)}
+			}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-s.d.shutdown:
+			return status.Error(codes.Unavailable, "daemon shutting down")
+		}
+	}
+}
+
+// Status reports the daemon's status, as a "status" query does
+func (s *grpcServer) Status(ctx context.Context, req *daemonpb.StatusRequest) (*daemonpb.StatusResponse, error) {
+	start := time.Now()
+	query, _, err := s.authorizedQuery(ctx, &daemonpb.QueryRequest{Type: "status", WorkspacePath: req.WorkspacePath}, start)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.d.executeQuery(query)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	st := result.Status
+	resp := &daemonpb.StatusResponse{Version: st.Version, Uptime: durationpb.New(st.Uptime)}
+	for _, w := range st.Workspaces {
+		resp.Workspaces = append(resp.Workspaces, workspaceMessage(w))
+	}
+	if st.ActiveWorkspace != nil {
+		resp.ActiveWorkspace = workspaceMessage(st.ActiveWorkspace)
+	}
+	return resp, nil
+}
+
+// ReloadConfig re-reads the config file, as SIGHUP does
+func (s *grpcServer) ReloadConfig(ctx context.Context, _ *daemonpb.ReloadConfigRequest) (*daemonpb.ReloadConfigResponse, error) {
+	conn, local, token := caller(ctx)
+	if err := s.d.authorize(token, auth.ScopeAdmin, local); err != nil {
+		return nil, grpcError(err)
+	}
+	s.d.cfgMu.RLock()
+	users := s.d.cfg.Users
+	s.d.cfgMu.RUnlock()
+	if users.Shared && !users.isAdmin(peerUser(conn)) {
+		return nil, status.Error(codes.PermissionDenied, "forbidden: reloading the config needs an admin on a shared daemon")
+	}
+
+	if err := s.d.reloadConfig(); err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return &daemonpb.ReloadConfigResponse{}, nil
+}
+
+func editMessage(e *database.Edit) *daemonpb.Edit {
+	return &daemonpb.Edit{
+		Id:            e.ID,
+		Timestamp:     timestamppb.New(e.Timestamp),
+		ToolName:      e.ToolName,
+		FilePath:      e.FilePath,
+		OldString:     e.OldString,
+		NewString:     e.NewString,
+		LineNum:       int32(e.LineNum),
+		LineCount:     int32(e.LineCount),
+		CommitSha:     e.CommitSHA,
+		VcsType:       e.VCSType,
+		ChatSessionId: e.ChatSessionID,
+		Summary:       e.Summary,
+		User:          e.User,
+		Bookmarked:    e.Bookmarked,
+		Note:          e.Note,
+		Review:        e.Review,
+		FileContent:   []byte(e.FileContent),
+	}
+}
+
+func timelineEditMessage(e *database.TimelineEdit) *daemonpb.Edit {
+	msg := editMessage(&e.Edit)
+	msg.WorkspacePath, msg.WorkspaceName, msg.Branch = e.WorkspacePath, e.WorkspaceName, e.Branch
+	return msg
+}
+
+func eventMessage(e *database.Event) *daemonpb.Event {
+	return &daemonpb.Event{
+		Id:            e.ID,
+		Timestamp:     timestamppb.New(e.Timestamp),
+		WorkspacePath: e.WorkspacePath,
+		Kind:          e.Kind,
+		Severity:      e.Severity,
+		Message:       e.Message,
+	}
+}
+
+func workspaceMessage(w *WorkspaceActivity) *daemonpb.WorkspaceActivity {
+	return &daemonpb.WorkspaceActivity{
+		Path:         w.Path,
+		Name:         w.Name,
+		LastActivity: timestamppb.New(w.LastActivity),
+		EditCount:    int32(w.EditCount),
+	}
+}
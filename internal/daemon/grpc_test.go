@@ -0,0 +1,180 @@
+package daemon
+
+import (
+	"context"
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ztaylor/claude-mon/pkg/daemonpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCAPI(t *testing.T) {
+	tmpDir := t.TempDir()
+	ws := filepath.Join(tmpDir, "api")
+
+	// Find a free port for the TCP listener
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to find a port: %v", err)
+	}
+	tcpAddr := l.Addr().String()
+	l.Close()
+
+	daemon, query := startTestDaemon(t, func(cfg *Config) {
+		cfg.GRPC = GRPCConfig{Enabled: true, Socket: filepath.Join(tmpDir, "grpc.sock"), Listen: tcpAddr}
+	})
+	cfg := daemon.cfg
+
+	dial := func(target string) daemonpb.DaemonClient {
+		conn, err := grpc.NewClient(target, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			t.Fatalf("failed to dial %s: %v", target, err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		return daemonpb.NewDaemonClient(conn)
+	}
+	client := dial("unix://" + cfg.GRPC.Socket)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	sub, err := client.Subscribe(ctx, &daemonpb.SubscribeRequest{WorkspacePath: ws, Edits: true})
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond) // Let the subscription register
+
+	_, err = client.Ingest(ctx, &daemonpb.IngestRequest{
+		Workspace:     ws,
+		WorkspaceName: "api",
+		Branch:        "main",
+		ToolName:      "Edit",
+		FilePath:      "main.go",
+		OldString:     "old",
+		NewString:     "new",
+		FileContent:   []byte("package main // new\n"),
+		LineNum:       3,
+	})
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+
+	t.Run("subscribe", func(t *testing.T) {
+		n, err := sub.Recv()
+		if err != nil {
+			t.Fatalf("Recv failed: %v", err)
+		}
+		edit := n.GetEdit()
+		if edit == nil || edit.FilePath != "main.go" || edit.WorkspacePath != ws || edit.NewString != "new" {
+			t.Errorf("notification = %v, want the ingested edit", n)
+		}
+	})
+
+	t.Run("query", func(t *testing.T) {
+		resp, err := client.Query(ctx, &daemonpb.QueryRequest{Type: "recent", Limit: 10})
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		if len(resp.Edits) != 1 || resp.Edits[0].FilePath != "main.go" || resp.Edits[0].Id == 0 {
+			t.Fatalf("edits = %v, want the ingested edit", resp.Edits)
+		}
+		if len(resp.ResultJson) == 0 {
+			t.Error("result_json is empty")
+		}
+	})
+
+	t.Run("json socket sees it", func(t *testing.T) {
+		if result := query(Query{Type: "recent", Limit: 10}); len(result.Edits) != 1 {
+			t.Errorf("got %d edits on the query socket, want 1", len(result.Edits))
+		}
+	})
+
+	t.Run("export", func(t *testing.T) {
+		stream, err := client.Export(ctx, &daemonpb.QueryRequest{Type: "export", WorkspacePath: ws})
+		if err != nil {
+			t.Fatalf("Export failed: %v", err)
+		}
+		var edits []*daemonpb.Edit
+		for {
+			e, err := stream.Recv()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("Recv failed: %v", err)
+			}
+			edits = append(edits, e)
+		}
+		if len(edits) != 1 || edits[0].WorkspacePath != ws || edits[0].Branch != "main" {
+			t.Errorf("exported %v, want the ingested edit", edits)
+		}
+	})
+
+	t.Run("status", func(t *testing.T) {
+		resp, err := client.Status(ctx, &daemonpb.StatusRequest{WorkspacePath: ws})
+		if err != nil {
+			t.Fatalf("Status failed: %v", err)
+		}
+		if resp.ActiveWorkspace == nil || resp.ActiveWorkspace.EditCount != 1 {
+			t.Errorf("active workspace = %v, want 1 edit", resp.ActiveWorkspace)
+		}
+	})
+
+	t.Run("tcp requires a token", func(t *testing.T) {
+		_, err := dial(tcpAddr).Query(ctx, &daemonpb.QueryRequest{Type: "recent"})
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("Query over TCP without a token = %v, want Unauthenticated", err)
+		}
+	})
+
+	t.Run("tcp can't create the first admin token", func(t *testing.T) {
+		_, err := dial(tcpAddr).Query(ctx, &daemonpb.QueryRequest{
+			QueryJson: []byte(`{"type":"token_create","name":"remote","scope":"admin"}`),
+		})
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("token_create over TCP without a token = %v, want Unauthenticated", err)
+		}
+	})
+}
+
+func TestGRPCListenNeedsTLS(t *testing.T) {
+	tests := []struct {
+		listen string
+		tls    bool
+		ok     bool
+	}{
+		{"127.0.0.1:7443", false, true},
+		{"[::1]:7443", false, true},
+		{"localhost:7443", false, true},
+		{":7443", false, false},
+		{"0.0.0.0:7443", false, false},
+		{"10.0.0.5:7443", true, true},
+	}
+	for _, tt := range tests {
+		cfg := defaultConfig()
+		cfg.GRPC.Enabled, cfg.GRPC.Listen = true, tt.listen
+		if tt.tls {
+			cfg.GRPC.TLSCert, cfg.GRPC.TLSKey = "cert.pem", "key.pem"
+		}
+		if err := cfg.validate(); (err == nil) != tt.ok {
+			t.Errorf("listen %q (tls %v): validate() = %v, want ok %v", tt.listen, tt.tls, err, tt.ok)
+		}
+	}
+}
+
+func TestGRPCPanicIsInternal(t *testing.T) {
+	t.Setenv("HOME", t.TempDir()) // Crash reports go under it
+
+	// A nil request panics in the handler, which must still fail the call
+	resp, err := (&grpcServer{}).Ingest(context.Background(), nil)
+	if resp != nil || status.Code(err) != codes.Internal {
+		t.Errorf("Ingest after a panic = %v, %v; want Internal", resp, err)
+	}
+}
@@ -0,0 +1,274 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/crash"
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// ErrIngestBusy is returned for an edit payload while the ingest queue is
+// full; the hook may send it again shortly
+var ErrIngestBusy = errors.New("ingest queue full, retry later")
+
+// IngestStats counts the edits that have gone through the ingest queue
+type IngestStats struct {
+	Queued       int     `json:"queued"`        // Edits waiting to be written
+	QueuedBytes  int     `json:"queued_bytes"`  // Their payload size
+	Accepted     int64   `json:"accepted"`      // Edits queued since the daemon started
+	Written      int64   `json:"written"`       // Edits recorded
+	Failed       int64   `json:"failed"`        // Edits dropped on a write error
+	Rejected     int64   `json:"rejected"`      // Edits answered "busy" while the queue was full
+	Batches      int64   `json:"batches"`       // Transactions committed
+	LargestBatch int     `json:"largest_batch"` // Most edits written in one transaction
+	LastFlushMS  float64 `json:"last_flush_ms"` // How long the last batch took to write
+}
+
+// IngestQueue queues edit payloads and hands them to a writer in batches,
+// once a batch fills or its first edit has waited the flush interval
+type IngestQueue struct {
+	config func() IngestConfig // Current settings, which may change on reload
+	write  func(batch []*HookPayload) (failed int)
+
+	mu           sync.Mutex
+	flushed      *sync.Cond // Broadcast after each batch is written
+	pending      []*HookPayload
+	pendingBytes int
+	queued       int64 // Payloads ever queued, to know when a flush is done
+	written      int64 // Payloads ever handed to the writer
+	flushWanted  bool  // Write what's queued without waiting for the batch to fill
+	stats        IngestStats
+
+	kick    chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// NewIngestQueue creates an ingest queue writing edits to the daemon's
+// database, and starts its writer
+func NewIngestQueue(d *Daemon) *IngestQueue {
+	return newIngestQueue(func() IngestConfig {
+		d.cfgMu.RLock()
+		defer d.cfgMu.RUnlock()
+		return d.cfg.Ingest
+	}, d.writeEdits)
+}
+
+func newIngestQueue(config func() IngestConfig, write func([]*HookPayload) int) *IngestQueue {
+	q := &IngestQueue{
+		config:  config,
+		write:   write,
+		kick:    make(chan struct{}, 1),
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	q.flushed = sync.NewCond(&q.mu)
+	go q.run()
+	return q
+}
+
+// Stop writes the edits still queued and stops the writer
+func (q *IngestQueue) Stop() {
+	close(q.stop)
+	<-q.stopped
+}
+
+// Enabled reports whether edits are queued rather than written as they arrive
+func (q *IngestQueue) Enabled() bool {
+	return q.config().BatchSize > 0
+}
+
+// Add queues an edit payload, or returns ErrIngestBusy if the queue is
+// full. An edit bigger than the whole queue is taken when it's empty.
+func (q *IngestQueue) Add(payload *HookPayload) error {
+	cfg := q.config()
+	size := payloadSize(payload)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.pending) > 0 && (len(q.pending) >= cfg.QueueSize || q.pendingBytes+size > cfg.MaxQueueMB<<20) {
+		q.stats.Rejected++
+		return ErrIngestBusy
+	}
+	q.pending = append(q.pending, payload)
+	q.pendingBytes += size
+	q.queued++
+	q.stats.Accepted++
+
+	// The first edit starts the flush interval; a full batch ends it early
+	if len(q.pending) == 1 || len(q.pending) >= cfg.BatchSize {
+		q.signal()
+	}
+	return nil
+}
+
+// Flush waits until the edits queued so far are written, so what's read
+// next sees them
+func (q *IngestQueue) Flush() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	target := q.queued
+	if q.written >= target {
+		return
+	}
+	q.flushWanted = true
+	q.signal()
+	for q.written < target {
+		q.flushed.Wait()
+	}
+}
+
+// Stats returns the queue's counters
+func (q *IngestQueue) Stats() IngestStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	stats := q.stats
+	stats.Queued = len(q.pending)
+	stats.QueuedBytes = q.pendingBytes
+	return stats
+}
+
+// signal wakes the writer; called with mu held
+func (q *IngestQueue) signal() {
+	select {
+	case q.kick <- struct{}{}:
+	default:
+	}
+}
+
+// run writes batches as edits are queued until the queue is stopped
+func (q *IngestQueue) run() {
+	defer close(q.stopped)
+	defer crash.Recover("daemon ingest", crash.Exit)
+	for {
+		select {
+		case <-q.kick:
+		case <-q.stop:
+			for q.writeBatch() {
+			}
+			return
+		}
+
+		// Give the batch the flush interval to fill
+		if !q.ready() {
+			timer := time.NewTimer(time.Duration(q.config().FlushIntervalMS) * time.Millisecond)
+			select {
+			case <-timer.C:
+			case <-q.kick:
+			case <-q.stop:
+			}
+			timer.Stop()
+		}
+		if q.writeBatch() {
+			q.mu.Lock()
+			if len(q.pending) > 0 {
+				q.signal()
+			}
+			q.mu.Unlock()
+		}
+	}
+}
+
+// ready reports whether a batch can be written without waiting
+func (q *IngestQueue) ready() bool {
+	batchSize := q.config().BatchSize
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.flushWanted || len(q.pending) >= batchSize
+}
+
+// writeBatch writes up to a batch of queued edits, returning false if
+// there were none
+func (q *IngestQueue) writeBatch() bool {
+	batchSize := max(q.config().BatchSize, 1)
+
+	q.mu.Lock()
+	n := min(len(q.pending), batchSize)
+	if n == 0 {
+		q.flushWanted = false
+		q.mu.Unlock()
+		return false
+	}
+	batch := q.pending[:n]
+	q.pending = append([]*HookPayload(nil), q.pending[n:]...)
+	for _, payload := range batch {
+		q.pendingBytes -= payloadSize(payload)
+	}
+	q.mu.Unlock()
+
+	start := time.Now()
+	failed := q.write(batch)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.written += int64(n)
+	if len(q.pending) == 0 {
+		q.flushWanted = false
+	}
+	q.stats.Written += int64(n - failed)
+	q.stats.Failed += int64(failed)
+	q.stats.Batches++
+	q.stats.LargestBatch = max(q.stats.LargestBatch, n)
+	q.stats.LastFlushMS = float64(time.Since(start).Microseconds()) / 1000
+	q.flushed.Broadcast()
+	return true
+}
+
+// writeEdits records a batch of queued edit payloads in one transaction,
+// returning how many were dropped
+func (d *Daemon) writeEdits(batch []*HookPayload) (failed int) {
+	type sessionKey struct{ workspace, branch string }
+	sessions := make(map[sessionKey]int64)
+
+	var edits []*database.Edit
+	var recorded []*HookPayload
+	var started []bool
+	for _, payload := range batch {
+		lastActivity := d.trackWorkspaceActivity(payload.Workspace, payload.WorkspaceName, true)
+		d.checkPause(payload.Workspace, lastActivity)
+
+		key := sessionKey{payload.Workspace, payload.Branch}
+		sessionID, ok := sessions[key]
+		if !ok {
+			var err error
+			sessionID, err = d.db.UpsertSession(payload.Workspace, payload.WorkspaceName, payload.Branch, payload.CommitSHA)
+			if err != nil {
+				d.editFailed(payload, fmt.Errorf("failed to upsert session: %w", err))
+				failed++
+				continue
+			}
+			sessions[key] = sessionID
+		}
+		edits = append(edits, editFromPayload(sessionID, payload))
+		recorded = append(recorded, payload)
+		started = append(started, d.webhooks.sessionStarted(payload))
+	}
+
+	if err := d.db.RecordEdits(edits); err != nil {
+		for _, payload := range recorded {
+			d.editFailed(payload, err)
+		}
+		return len(batch)
+	}
+	for i, payload := range recorded {
+		d.afterEdit(edits[i], payload, started[i])
+	}
+	return failed
+}
+
+// editFailed logs a queued edit that couldn't be recorded; the hook has
+// already been answered, so it's only seen in the timeline
+func (d *Daemon) editFailed(payload *HookPayload, err error) {
+	logger.Log("Queued edit to %s dropped: %v", payload.FilePath, err)
+	d.recordEvent(payload.Workspace, EventFailure, SeverityError,
+		fmt.Sprintf("edit payload dropped: %v", err))
+}
+
+// payloadSize approximates the memory a queued payload holds
+func payloadSize(payload *HookPayload) int {
+	return len(payload.FilePath) + len(payload.OldString) + len(payload.NewString) + len(payload.FileContentB64)
+}
@@ -0,0 +1,79 @@
+package daemon
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIngestQueue(t *testing.T) {
+	cfg := IngestConfig{BatchSize: 3, FlushIntervalMS: 20, QueueSize: 5, MaxQueueMB: 1}
+
+	var mu sync.Mutex
+	var batches []int
+	release := make(chan struct{})
+	q := newIngestQueue(func() IngestConfig { return cfg }, func(batch []*HookPayload) int {
+		<-release // Held up like a slow disk
+		mu.Lock()
+		defer mu.Unlock()
+		batches = append(batches, len(batch))
+		return 0
+	})
+
+	// A full batch is taken at once; the queue then fills up behind it
+	for i := 0; i < 3; i++ {
+		if err := q.Add(&HookPayload{Type: "edit", FilePath: "a.go"}); err != nil {
+			t.Fatalf("edit %d: %v", i, err)
+		}
+	}
+	deadline := time.Now().Add(time.Second)
+	for q.Stats().Queued > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	for i := 0; i < 5; i++ {
+		if err := q.Add(&HookPayload{Type: "edit", FilePath: "b.go"}); err != nil {
+			t.Fatalf("edit %d: %v", i, err)
+		}
+	}
+	if err := q.Add(&HookPayload{Type: "edit", FilePath: "c.go"}); !errors.Is(err, ErrIngestBusy) {
+		t.Errorf("expected a full queue to answer busy, got %v", err)
+	}
+
+	// Flush waits for everything queued before it
+	close(release)
+	q.Flush()
+	stats := q.Stats()
+	if stats.Queued != 0 || stats.Written != 8 || stats.Rejected != 1 || stats.LargestBatch != 3 {
+		t.Errorf("unexpected stats after flush: %+v", stats)
+	}
+	mu.Lock()
+	if got := batches; len(got) != 3 || got[0] != 3 || got[1] != 3 || got[2] != 2 {
+		t.Errorf("expected batches of 3, 3 and 2, got %v", got)
+	}
+	mu.Unlock()
+
+	// A lone edit is written once the flush interval passes
+	if err := q.Add(&HookPayload{Type: "edit", FilePath: "d.go"}); err != nil {
+		t.Fatal(err)
+	}
+	deadline = time.Now().Add(time.Second)
+	for q.Stats().Written < 9 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := q.Stats().Written; got != 9 {
+		t.Errorf("expected the lone edit written after the interval, got %d written", got)
+	}
+
+	// Payload size is bounded too, though an edit bigger than the queue is
+	// taken when nothing else is waiting
+	big := &HookPayload{Type: "edit", FilePath: "e.go", NewString: strings.Repeat("x", 1<<20)}
+	if err := q.Add(big); err != nil {
+		t.Errorf("expected an oversized edit taken by an empty queue, got %v", err)
+	}
+	q.Stop()
+	if got := q.Stats().Written; got != 10 {
+		t.Errorf("expected stop to write the queued edit, got %d written", got)
+	}
+}
@@ -0,0 +1,104 @@
+package daemon
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/database"
+)
+
+// subscriberBuffer is how many notifications a subscriber may fall behind
+// by before it's dropped
+const subscriberBuffer = 256
+
+// notification is an edit or event the daemon has just recorded
+type notification struct {
+	edit  *database.TimelineEdit
+	event *database.Event
+}
+
+// notifier fans the edits and events the daemon records out to
+// subscribers: gRPC Subscribe streams and editor connections
+type notifier struct {
+	mu   sync.Mutex
+	subs map[*subscriber]bool
+}
+
+// subscriber is one subscriber's notifications, closed if it falls more
+// than subscriberBuffer behind
+type subscriber struct {
+	ch        chan notification
+	workspace string // Every workspace's if empty
+	edits     bool
+	events    bool
+}
+
+func (n *notifier) subscribe(workspace string, edits, events bool) *subscriber {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.subs == nil {
+		n.subs = make(map[*subscriber]bool)
+	}
+	sub := &subscriber{ch: make(chan notification, subscriberBuffer), workspace: workspace, edits: edits, events: events}
+	n.subs[sub] = true
+	return sub
+}
+
+func (n *notifier) unsubscribe(sub *subscriber) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.subs[sub] {
+		delete(n.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// publish sends a notification about a workspace to its subscribers
+func (n *notifier) publish(workspace string, note notification) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for sub := range n.subs {
+		if sub.workspace != "" && sub.workspace != workspace {
+			continue
+		}
+		if (note.edit != nil && !sub.edits) || (note.event != nil && !sub.events) {
+			continue
+		}
+		select {
+		case sub.ch <- note:
+		default:
+			// Too far behind: drop the subscriber rather than hold up recording
+			delete(n.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// publishEdit notifies subscribers of a recorded edit. Edits aren't read
+// back after they're written, so it has no ID.
+func (d *Daemon) publishEdit(edit *database.Edit, payload *HookPayload) {
+	e := &database.TimelineEdit{Edit: *edit, WorkspacePath: payload.Workspace, WorkspaceName: payload.WorkspaceName, Branch: payload.Branch}
+	e.FileContent, e.FileSnapshot = "", nil // Subscribers can query for it
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+	d.notifier.publish(payload.Workspace, notification{edit: e})
+}
+
+// publishEvent notifies subscribers of a recorded event
+func (d *Daemon) publishEvent(event *database.Event) {
+	e := *event
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+	d.notifier.publish(e.WorkspacePath, notification{event: &e})
+}
+
+// visibleTo reports whether a user on a shared daemon may see a
+// notification: their own edits, and events in workspaces they've edited
+func (d *Daemon) visibleTo(user string, n notification) bool {
+	if n.edit != nil {
+		return n.edit.User == user
+	}
+	return n.event.WorkspacePath != "" && d.checkUserWorkspace(user, n.event.WorkspacePath) == nil
+}
@@ -0,0 +1,27 @@
+package daemon
+
+import (
+	"net"
+	"syscall"
+)
+
+// peerCredentials returns the user and process on the other end of a unix
+// socket connection, or -1 for each if they can't be read
+func peerCredentials(conn net.Conn) (uid, pid int) {
+	uid, pid = -1, -1
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return
+	}
+	raw.Control(func(fd uintptr) {
+		cred, err := syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+		if err == nil {
+			uid, pid = int(cred.Uid), int(cred.Pid)
+		}
+	})
+	return
+}
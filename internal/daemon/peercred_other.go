@@ -0,0 +1,10 @@
+//go:build !linux
+
+package daemon
+
+import "net"
+
+// peerCredentials isn't supported on this OS; audit entries record -1
+func peerCredentials(conn net.Conn) (uid, pid int) {
+	return -1, -1
+}
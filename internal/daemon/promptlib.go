@@ -0,0 +1,111 @@
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/prompt"
+)
+
+// PromptLibrary is the prompt library in the daemon's database, shared by
+// every workspace and reached over the query socket
+type PromptLibrary struct {
+	SocketPath string
+}
+
+// NewPromptStore returns the prompt store kind names: the daemon's library
+// for "daemon", or the prompt directories for "files" or ""
+func NewPromptStore(kind string) (*prompt.Store, error) {
+	switch kind {
+	case "", "files":
+		return prompt.NewStore()
+	case "daemon":
+		lib := &PromptLibrary{SocketPath: DefaultQuerySocketPath}
+		return prompt.NewLibraryStore(lib, prompt.DefaultLibraryDir()), nil
+	default:
+		return nil, fmt.Errorf("unknown prompt_store %q: use \"files\" or \"daemon\"", kind)
+	}
+}
+
+// Prompts returns every prompt in the library with its version backups
+func (l *PromptLibrary) Prompts() ([]prompt.Prompt, error) {
+	result, err := SendQuery(l.SocketPath, &Query{Type: "prompt_library"})
+	if err != nil {
+		return nil, err
+	}
+	prompts := make([]prompt.Prompt, 0, len(result.Prompts))
+	for _, p := range result.Prompts {
+		prompts = append(prompts, promptFromDB(p))
+	}
+	return prompts, nil
+}
+
+// Put adds a prompt to the library or replaces the one with its name
+func (l *PromptLibrary) Put(p prompt.Prompt) error {
+	dbPrompt, err := promptToDB(p)
+	if err != nil {
+		return err
+	}
+	_, err = SendQuery(l.SocketPath, &Query{Type: "prompt_save", Prompt: dbPrompt})
+	return err
+}
+
+// Delete removes a prompt and its versions from the library
+func (l *PromptLibrary) Delete(name string) error {
+	_, err := SendQuery(l.SocketPath, &Query{Type: "prompt_delete", Name: name})
+	return err
+}
+
+// promptToDB converts a prompt for the library's table
+func promptToDB(p prompt.Prompt) (*database.Prompt, error) {
+	dbPrompt := &database.Prompt{
+		Name:        p.Name,
+		Description: p.Description,
+		Content:     p.Content,
+		Tags:        p.Tags,
+		Version:     p.Version,
+		IsGlobal:    true,
+		CreatedAt:   p.Created,
+		UpdatedAt:   p.Updated,
+	}
+	if len(p.Variables) > 0 {
+		data, err := json.Marshal(p.Variables)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal variables: %w", err)
+		}
+		dbPrompt.Variables = string(data)
+	}
+	for version, content := range p.Backups {
+		dbPrompt.Versions = append(dbPrompt.Versions, database.PromptVersion{Version: version, Content: content})
+	}
+	sort.Slice(dbPrompt.Versions, func(i, j int) bool {
+		return dbPrompt.Versions[i].Version < dbPrompt.Versions[j].Version
+	})
+	return dbPrompt, nil
+}
+
+// promptFromDB converts a prompt from the library's table. Variables that
+// don't parse are dropped rather than failing the whole library.
+func promptFromDB(dbPrompt *database.Prompt) prompt.Prompt {
+	p := prompt.Prompt{
+		Name:         dbPrompt.Name,
+		Description:  dbPrompt.Description,
+		Version:      dbPrompt.Version,
+		Created:      dbPrompt.CreatedAt,
+		Updated:      dbPrompt.UpdatedAt,
+		Tags:         dbPrompt.Tags,
+		Content:      dbPrompt.Content,
+		IsGlobal:     true,
+		VersionCount: len(dbPrompt.Versions),
+		Backups:      make(map[int]string, len(dbPrompt.Versions)),
+	}
+	if dbPrompt.Variables != "" {
+		json.Unmarshal([]byte(dbPrompt.Variables), &p.Variables)
+	}
+	for _, v := range dbPrompt.Versions {
+		p.Backups[v.Version] = v.Content
+	}
+	return p
+}
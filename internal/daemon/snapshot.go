@@ -0,0 +1,67 @@
+package daemon
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// recordSnapshot stores the content a "snapshot" payload sends before an
+// edit, unless the session already has a snapshot of the file
+func (d *Daemon) recordSnapshot(sessionID int64, payload *HookPayload) {
+	if payload.FilePath == "" {
+		return
+	}
+	content, err := base64.StdEncoding.DecodeString(payload.FileContentB64)
+	if err != nil {
+		logger.Log("Warning: failed to decode snapshot of %s: %v", payload.FilePath, err)
+		return
+	}
+	d.storeSnapshot(&database.FileSnapshot{
+		SessionID:     sessionID,
+		ChatSessionID: payload.ChatSessionID,
+		FilePath:      payload.FilePath,
+		Existed:       !payload.FileMissing,
+		Content:       content,
+	})
+}
+
+// snapshotBeforeEdit stores the file's content before an edit when the
+// hook didn't send a snapshot first. It's recovered by undoing the edit on
+// the content after it, which only works when the new string appears once;
+// a Write's previous content is unknown.
+func (d *Daemon) snapshotBeforeEdit(sessionID int64, payload *HookPayload) {
+	if payload.FileContentB64 == "" || payload.NewString == "" || payload.ToolName == "Write" {
+		return
+	}
+	if ok, err := d.db.HasFileSnapshot(sessionID, payload.ChatSessionID, payload.FilePath); err != nil || ok {
+		return
+	}
+
+	after, err := base64.StdEncoding.DecodeString(payload.FileContentB64)
+	if err != nil || strings.Count(string(after), payload.NewString) != 1 {
+		logger.Log("No snapshot of %s: the edit can't be undone on its content", payload.FilePath)
+		return
+	}
+	before := strings.Replace(string(after), payload.NewString, payload.OldString, 1)
+	d.storeSnapshot(&database.FileSnapshot{
+		SessionID:     sessionID,
+		ChatSessionID: payload.ChatSessionID,
+		FilePath:      payload.FilePath,
+		Existed:       true,
+		Content:       []byte(before),
+	})
+}
+
+func (d *Daemon) storeSnapshot(s *database.FileSnapshot) {
+	stored, err := d.db.RecordFileSnapshot(s)
+	if err != nil {
+		logger.Log("Warning: %v", err)
+		return
+	}
+	if stored {
+		logger.Log("Snapshot of %s before the session's first edit (%d bytes)", s.FilePath, len(s.Content))
+	}
+}
@@ -0,0 +1,147 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// streamFlushEvery is how many lines a streamed response buffers before
+// writing them to the client
+const streamFlushEvery = 100
+
+// StreamLine is one line of a streamed query response (NDJSON): an edit,
+// or the line that ends the stream. A stream without an end line was cut
+// off.
+type StreamLine struct {
+	Edit *database.TimelineEdit `json:"edit,omitempty"`
+
+	Done       bool   `json:"done,omitempty"`        // Set on the end line only
+	Count      int    `json:"count,omitempty"`       // Edits sent
+	NextCursor string `json:"next_cursor,omitempty"` // As in QueryResult, for "timeline"
+	Error      string `json:"error,omitempty"`       // Why the stream ended early
+}
+
+// streamQuery answers a query with one line per edit as rows are read,
+// so a large export is never held in memory. It stops when the client
+// disconnects or the daemon shuts down, returning why the stream ended
+// early, if it did.
+func (d *Daemon) streamQuery(conn net.Conn, query *Query) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The client sends nothing after its query, so a read returning means
+	// it went away
+	go func() {
+		io.Copy(io.Discard, conn)
+		cancel()
+	}()
+	go func() {
+		select {
+		case <-d.shutdown:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	w := bufio.NewWriter(conn)
+	enc := json.NewEncoder(w)
+	end := StreamLine{Done: true}
+	err := d.streamEdits(ctx, query, &end, func(e *database.TimelineEdit) error {
+		if err := enc.Encode(StreamLine{Edit: e}); err != nil {
+			return err
+		}
+		end.Count++
+		if end.Count%streamFlushEvery == 0 {
+			return w.Flush()
+		}
+		return nil
+	})
+	if ctx.Err() != nil {
+		logger.Log("Streamed %s query cancelled after %d edits", query.Type, end.Count)
+		return ctx.Err()
+	}
+	if err != nil {
+		logger.Log("Streamed %s query failed after %d edits: %v", query.Type, end.Count, err)
+		end.Error = err.Error()
+		end.NextCursor = ""
+	}
+	enc.Encode(end)
+	if flushErr := w.Flush(); flushErr != nil {
+		logger.Log("Stream response error: %v", flushErr)
+	}
+	return err
+}
+
+// streamEdits runs a streamable query, calling fn with each edit and
+// filling in the end line's cursor
+func (d *Daemon) streamEdits(ctx context.Context, query *Query, end *StreamLine, fn func(*database.TimelineEdit) error) error {
+	d.cfgMu.RLock()
+	defaultLimit, maxLimit := d.cfg.Query.DefaultLimit, d.cfg.Query.MaxLimit
+	var group []string
+	var groupErr error
+	if query.Group != "" {
+		group, groupErr = d.cfg.GroupWorkspaces(query.Group)
+	}
+	d.cfgMu.RUnlock()
+	if groupErr != nil {
+		return groupErr
+	}
+
+	var before database.EditCursor
+	if query.Cursor != "" {
+		var err error
+		if before, err = database.ParseEditCursor(query.Cursor); err != nil {
+			return err
+		}
+	}
+	q := database.TimelineQuery{Workspaces: group, Since: query.Since, Before: before, User: query.User}
+	if query.User != "" {
+		if err := d.checkUserWorkspace(query.User, query.WorkspacePath); err != nil {
+			return err
+		}
+	}
+	if query.WorkspacePath != "" {
+		// One workspace narrows a group rather than adding to it
+		if group != nil && !slices.Contains(group, query.WorkspacePath) {
+			return fmt.Errorf("workspace %s is not in group %s", query.WorkspacePath, query.Group)
+		}
+		q.Workspaces = []string{query.WorkspacePath}
+	}
+
+	switch query.Type {
+	case "timeline":
+		// Pages as the non-streamed timeline does
+		q.Limit = query.Limit
+		if q.Limit <= 0 {
+			q.Limit = defaultLimit
+		}
+		q.Limit = min(q.Limit, maxLimit)
+	case "export":
+		// Every edit with its strings, unless a limit is asked for
+		q.Limit = max(query.Limit, 0)
+		q.Content = true
+	default:
+		return fmt.Errorf("%s queries can't be streamed", query.Type)
+	}
+
+	var last *database.TimelineEdit
+	err := d.db.StreamTimeline(ctx, q, func(e *database.TimelineEdit) error {
+		last = e
+		return fn(e)
+	})
+	if err != nil {
+		return err
+	}
+	if q.Limit > 0 && end.Count == q.Limit && last != nil {
+		end.NextCursor = database.EditCursor{Timestamp: last.Timestamp, ID: last.ID}.String()
+	}
+	return nil
+}
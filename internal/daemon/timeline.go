@@ -0,0 +1,78 @@
+package daemon
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// Timeline event kinds recorded by the daemon
+const (
+	EventDaemonStart  = "daemon_start"
+	EventDaemonStop   = "daemon_stop"
+	EventConfigReload = "config_reload"
+	EventHugeEdit     = "huge_edit"
+	EventFailure      = "failure"
+	EventPause        = "pause"
+	EventRalphStart   = "ralph_start"
+	EventRalphStop    = "ralph_stop"
+)
+
+// Timeline event severities
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+const (
+	// hugeEditLines is the line count above which an edit is flagged
+	hugeEditLines = 500
+	// hugeEditBytes is the content size above which an edit is flagged
+	hugeEditBytes = 100 * 1024
+	// pauseThreshold is the idle gap after which resumed activity is flagged
+	pauseThreshold = 30 * time.Minute
+)
+
+// recordEvent stores a timeline event, logging rather than failing on error
+func (d *Daemon) recordEvent(workspace, kind, severity, message string) {
+	event := &database.Event{
+		WorkspacePath: workspace,
+		Kind:          kind,
+		Severity:      severity,
+		Message:       message,
+	}
+	if err := d.db.RecordEvent(event); err != nil {
+		logger.Log("Failed to record %s event: %v", kind, err)
+		return
+	}
+	d.publishEvent(event)
+}
+
+// checkEditAnomalies records a huge_edit event for unusually large edits
+func (d *Daemon) checkEditAnomalies(payload *HookPayload) {
+	lines := payload.LineCount
+	if n := strings.Count(payload.NewString, "\n") + 1; n > lines {
+		lines = n
+	}
+	size := len(payload.OldString) + len(payload.NewString)
+
+	if lines > hugeEditLines || size > hugeEditBytes {
+		d.recordEvent(payload.Workspace, EventHugeEdit, SeverityWarning,
+			fmt.Sprintf("%s %s: %d lines, %d bytes", payload.ToolName, payload.FilePath, lines, size))
+	}
+}
+
+// checkPause records a pause event when a workspace resumes after a long idle gap
+func (d *Daemon) checkPause(workspace string, lastActivity time.Time) {
+	if lastActivity.IsZero() {
+		return
+	}
+	if gap := time.Since(lastActivity); gap > pauseThreshold {
+		d.recordEvent(workspace, EventPause, SeverityInfo,
+			fmt.Sprintf("resumed after %s idle", gap.Round(time.Minute)))
+	}
+}
@@ -0,0 +1,102 @@
+package daemon
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/user"
+	"slices"
+	"strconv"
+)
+
+// adminQueries are the queries that change other users' data or the
+// daemon's tokens, which only admins may make on a shared daemon
+var adminQueries = []string{"tokens", "token_create", "token_revoke", "workspace_import", "workspace_purge", "dedupe"}
+
+// userName names a user by uid: their login name, their uid if they have
+// none, or "" for -1 (unknown)
+func userName(uid int) string {
+	if uid < 0 {
+		return ""
+	}
+	if u, err := user.LookupId(strconv.Itoa(uid)); err == nil {
+		return u.Username
+	}
+	return strconv.Itoa(uid)
+}
+
+// peerUser names the user on the other end of a socket connection
+func peerUser(conn net.Conn) string {
+	uid, _ := peerCredentials(conn)
+	return userName(uid)
+}
+
+// isAdmin reports whether a user sees every user's activity: root, the
+// user running the daemon and the configured admins do
+func (u UsersConfig) isAdmin(name string) bool {
+	if name == "root" || name == userName(os.Getuid()) {
+		return true
+	}
+	return slices.Contains(u.Admins, name)
+}
+
+// authorizeUser limits a query on a shared daemon to the activity of the
+// user who made it, unless they're an admin. Anyone else asking for
+// another user's activity, or to change data that isn't theirs, is refused.
+func (d *Daemon) authorizeUser(conn net.Conn, query *Query) error {
+	d.cfgMu.RLock()
+	users := d.cfg.Users
+	d.cfgMu.RUnlock()
+	if !users.Shared {
+		return nil
+	}
+
+	name := peerUser(conn)
+	if users.isAdmin(name) {
+		return nil
+	}
+	if name == "" {
+		return fmt.Errorf("forbidden: the query's user can't be identified")
+	}
+	if slices.Contains(adminQueries, query.Type) {
+		return fmt.Errorf("forbidden: %s queries need an admin on a shared daemon", query.Type)
+	}
+	if query.User != "" && query.User != name {
+		return fmt.Errorf("forbidden: %s cannot see %s's activity", name, query.User)
+	}
+	query.User = name
+	return nil
+}
+
+// userWorkspaces returns the workspaces a user has edited in, narrowed to
+// a group if one is given. The result is never nil, so it always limits
+// the workspaces a query sees.
+func (d *Daemon) userWorkspaces(name string, group []string) ([]string, error) {
+	workspaces, err := d.db.GetUserWorkspaces(name)
+	if err != nil {
+		return nil, err
+	}
+	scoped := []string{}
+	for _, w := range workspaces {
+		if group == nil || slices.Contains(group, w) {
+			scoped = append(scoped, w)
+		}
+	}
+	return scoped, nil
+}
+
+// checkUserWorkspace refuses a user a workspace other users have edited in
+// and they haven't. Workspaces nobody has edited in yet are anyone's.
+func (d *Daemon) checkUserWorkspace(name, workspacePath string) error {
+	if workspacePath == "" {
+		return nil
+	}
+	users, err := d.db.GetWorkspaceUsers(workspacePath)
+	if err != nil {
+		return err
+	}
+	if len(users) > 0 && !slices.Contains(users, name) {
+		return fmt.Errorf("forbidden: %s has no edits by %s", workspacePath, name)
+	}
+	return nil
+}
@@ -0,0 +1,378 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/internal/vcs"
+	"github.com/ztaylor/claude-mon/internal/workspace"
+)
+
+// watchSkipDirs are never watched: they're VCS state, not workspace files
+var watchSkipDirs = []string{".git", ".jj", ".hg", ".svn"}
+
+// FileWatcher records changes to files in the workspaces Claude works in
+// that no hook reported, such as files rewritten by a script Claude ran,
+// as database.ToolExternal edits. fsnotify doesn't watch recursively, so
+// every directory is watched, new ones as they appear.
+type FileWatcher struct {
+	config func() WatchConfig // Current settings, which may change on reload
+	track  func(ws, file string) bool
+	last   func(ws, file string) (string, bool) // The file as last recorded or committed
+	record func(p *HookPayload) error
+
+	mu         sync.Mutex
+	fs         *fsnotify.Watcher
+	workspaces map[string]*HookPayload // Watched roots, with the session their external edits are recorded in
+	dirs       int
+	full       bool                   // MaxDirs was reached
+	pending    map[string]*time.Timer // File -> settle timer
+	claimed    map[string]time.Time   // File -> when a hook last reported it, until that can't matter
+	stopped    bool
+}
+
+// NewFileWatcher creates a file watcher reading its settings from the
+// daemon config. A change is compared with the file's content as last
+// recorded, or as committed when it has no recorded edits, and recorded
+// like a hook's edit.
+func NewFileWatcher(d *Daemon) *FileWatcher {
+	return newFileWatcher(
+		func() WatchConfig {
+			d.cfgMu.RLock()
+			defer d.cfgMu.RUnlock()
+			return d.cfg.Watch
+		},
+		func(ws, file string) bool {
+			d.cfgMu.RLock()
+			defer d.cfgMu.RUnlock()
+			return d.cfg.ShouldTrackFile(ws, file)
+		},
+		d.lastFileContent,
+		d.processPayload,
+	)
+}
+
+func newFileWatcher(config func() WatchConfig, track func(ws, file string) bool,
+	last func(ws, file string) (string, bool), record func(p *HookPayload) error) *FileWatcher {
+	return &FileWatcher{
+		config:     config,
+		track:      track,
+		last:       last,
+		record:     record,
+		workspaces: make(map[string]*HookPayload),
+		pending:    make(map[string]*time.Timer),
+		claimed:    make(map[string]time.Time),
+	}
+}
+
+// Watch starts watching the workspace a hook payload came from, if it
+// isn't watched yet. Edit payloads also set the session, branch and user
+// the workspace's external edits are recorded with.
+func (w *FileWatcher) Watch(p *HookPayload) {
+	if !w.config().Enabled || p.Workspace == "" {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped {
+		return
+	}
+	if details, ok := w.workspaces[p.Workspace]; ok {
+		if p.Type == "edit" {
+			*details = sessionDetails(p)
+		}
+		return
+	}
+	if w.fs == nil {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			logger.Log("File watcher unavailable: %v", err)
+			return
+		}
+		w.fs = watcher
+		go w.loop(watcher)
+	}
+	details := sessionDetails(p)
+	w.workspaces[p.Workspace] = &details
+	go w.addTree(p.Workspace, p.Workspace)
+	logger.Log("Watching %s for changes made outside Claude", p.Workspace)
+}
+
+// sessionDetails copies what a payload says about the session it came
+// from, for the external edits recorded alongside it
+func sessionDetails(p *HookPayload) HookPayload {
+	return HookPayload{
+		Workspace:     p.Workspace,
+		WorkspaceName: p.WorkspaceName,
+		Branch:        p.Branch,
+		CommitSHA:     p.CommitSHA,
+		VCSType:       p.VCSType,
+		user:          p.user,
+	}
+}
+
+// Claim notes that a hook reported a change to a file, so the watcher
+// leaves it
+func (w *FileWatcher) Claim(file string) {
+	settle := time.Duration(w.config().SettleMS) * time.Millisecond
+	now := time.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for f, claimed := range w.claimed {
+		w.dropClaim(f, claimed, now, settle)
+	}
+	w.claimed[file] = now
+}
+
+// dropClaim forgets a file's claim once it can't leave a change: none is
+// waiting to settle and it's older than any change it would cover. Called
+// with w.mu held.
+func (w *FileWatcher) dropClaim(file string, claimed, now time.Time, settle time.Duration) {
+	if w.pending[file] == nil && now.Sub(claimed) > settle {
+		delete(w.claimed, file)
+	}
+}
+
+// Stop stops watching and drops changes not yet recorded
+func (w *FileWatcher) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.stopped = true
+	for _, t := range w.pending {
+		t.Stop()
+	}
+	if w.fs != nil {
+		w.fs.Close()
+	}
+}
+
+// loop hands file events to handle until the watcher is closed
+func (w *FileWatcher) loop(watcher *fsnotify.Watcher) {
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			w.handle(event)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Log("File watcher error: %v", err)
+		}
+	}
+}
+
+// handle watches new directories and schedules changed files to be
+// recorded once they settle
+func (w *FileWatcher) handle(event fsnotify.Event) {
+	ws := w.workspaceOf(event.Name)
+	if ws == "" || w.ignored(ws, event.Name) {
+		return
+	}
+	if event.Has(fsnotify.Create) {
+		if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+			go w.addTree(ws, event.Name)
+			return
+		}
+	}
+	if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) &&
+		!event.Has(fsnotify.Remove) && !event.Has(fsnotify.Rename) {
+		return
+	}
+
+	settle := time.Duration(w.config().SettleMS) * time.Millisecond
+	seen := time.Now()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped {
+		return
+	}
+	if t := w.pending[event.Name]; t != nil {
+		t.Stop()
+	}
+	w.pending[event.Name] = time.AfterFunc(settle, func() {
+		w.settle(ws, event.Name, seen)
+	})
+}
+
+// settle records a file's change as an external edit, unless a hook
+// reported the file since shortly before it changed or its content is
+// what was last recorded
+func (w *FileWatcher) settle(ws, file string, seen time.Time) {
+	cfg := w.config()
+	settle := time.Duration(cfg.SettleMS) * time.Millisecond
+	w.mu.Lock()
+	delete(w.pending, file)
+	claimed := w.claimed[file]
+	w.dropClaim(file, claimed, time.Now(), settle)
+	details, ok := w.workspaces[ws]
+	var payload HookPayload
+	if ok && !w.stopped {
+		payload = *details
+	} else {
+		ok = false
+	}
+	w.mu.Unlock()
+	if !ok || !cfg.Enabled {
+		return
+	}
+	if claimed.After(seen.Add(-settle)) {
+		return
+	}
+	if !w.track(ws, file) {
+		return
+	}
+
+	var after string
+	info, err := os.Stat(file)
+	exists := err == nil
+	if exists {
+		if info.IsDir() || info.Size() > int64(cfg.MaxFileKB)*1024 {
+			return
+		}
+		content, err := os.ReadFile(file)
+		if err != nil || bytes.IndexByte(content, 0) >= 0 {
+			return // Unreadable or binary
+		}
+		after = string(content)
+	}
+	before, known := w.last(ws, file)
+	if (!exists && !known) || (known && before == after) {
+		return
+	}
+
+	payload.Type = "edit"
+	payload.ToolName = database.ToolExternal
+	payload.FilePath = file
+	payload.OldString, payload.NewString, payload.LineNum = changedRegion(before, after)
+	payload.LineCount = strings.Count(payload.NewString, "\n")
+	if exists {
+		payload.FileContentB64 = base64.StdEncoding.EncodeToString([]byte(after))
+	}
+	if err := w.record(&payload); err != nil {
+		logger.Log("Failed to record external change to %s: %v", file, err)
+		return
+	}
+	logger.Log("Recorded external change to %s", file)
+}
+
+// addTree watches a directory and those under it, up to MaxDirs in all
+func (w *FileWatcher) addTree(ws, root string) {
+	maxDirs := w.config().MaxDirs
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != ws && w.ignored(ws, path) {
+			return filepath.SkipDir
+		}
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.stopped {
+			return filepath.SkipAll
+		}
+		if w.dirs >= maxDirs {
+			if !w.full {
+				w.full = true
+				logger.Log("File watcher reached watch.max_dirs (%d); %s and later directories aren't watched", maxDirs, path)
+			}
+			return filepath.SkipAll
+		}
+		if err := w.fs.Add(path); err != nil {
+			logger.Log("Failed to watch %s: %v", path, err)
+			return nil
+		}
+		w.dirs++
+		return nil
+	})
+}
+
+// workspaceOf returns the watched workspace a path is in, the innermost
+// when workspaces nest, or "" if it's in none
+func (w *FileWatcher) workspaceOf(path string) string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var found string
+	for ws := range w.workspaces {
+		if (path == ws || strings.HasPrefix(path, ws+string(filepath.Separator))) && len(ws) > len(found) {
+			found = ws
+		}
+	}
+	return found
+}
+
+// ignored reports whether a path in a workspace is never watched: it's
+// under a VCS directory or matches watch.ignored
+func (w *FileWatcher) ignored(ws, path string) bool {
+	rel, err := filepath.Rel(ws, path)
+	if err != nil {
+		return true
+	}
+	for _, part := range strings.Split(filepath.ToSlash(rel), "/") {
+		for _, skip := range watchSkipDirs {
+			if part == skip {
+				return true
+			}
+		}
+	}
+	for _, pattern := range w.config().Ignored {
+		if workspace.Match(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// changedRegion returns the lines before and after differ in, as the old
+// and new strings of an edit, and the line they start at
+func changedRegion(before, after string) (oldString, newString string, line int) {
+	b, a := strings.SplitAfter(before, "\n"), strings.SplitAfter(after, "\n")
+	prefix := 0
+	for prefix < len(b) && prefix < len(a) && b[prefix] == a[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(b)-prefix && suffix < len(a)-prefix && b[len(b)-1-suffix] == a[len(a)-1-suffix] {
+		suffix++
+	}
+	return strings.Join(b[prefix:len(b)-suffix], ""), strings.Join(a[prefix:len(a)-suffix], ""), prefix + 1
+}
+
+// lastFileContent returns a file's content after its last recorded edit,
+// or failing that, in the workspace's checked out commit
+func (d *Daemon) lastFileContent(ws, file string) (string, bool) {
+	d.ingest.Flush()
+	if edits, err := d.db.GetEditsByFile(file, 1); err == nil && len(edits) > 0 && edits[0].FileContent != "" {
+		return edits[0].FileContent, true
+	}
+	vcsType := vcs.DetectVCSType(ws)
+	if vcsType == "" {
+		return "", false
+	}
+	root, err := vcs.GetWorkspaceRoot(ws, vcsType)
+	if err != nil {
+		return "", false
+	}
+	sha, err := vcs.GetCurrentCommit(root, vcsType)
+	if err != nil {
+		return "", false
+	}
+	content, err := vcs.GetFileAtCommit(root, file, sha, vcsType)
+	if err != nil {
+		return "", false // Not committed: a new file
+	}
+	return content, true
+}
@@ -0,0 +1,103 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileWatcher(t *testing.T) {
+	ws := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(ws, "node_modules"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	main := filepath.Join(ws, "main.go")
+	if err := os.WriteFile(main, []byte("package main\n\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := WatchConfig{Enabled: true, SettleMS: 50, MaxDirs: 10, MaxFileKB: 64, Ignored: []string{"node_modules"}}
+	var mu sync.Mutex
+	var recorded []HookPayload
+	w := newFileWatcher(
+		func() WatchConfig { return cfg },
+		func(ws, file string) bool { return true },
+		func(ws, file string) (string, bool) {
+			if file == main {
+				return "package main\n\nfunc main() {}\n", true
+			}
+			return "", false
+		},
+		func(p *HookPayload) error {
+			mu.Lock()
+			defer mu.Unlock()
+			recorded = append(recorded, *p)
+			return nil
+		},
+	)
+	defer w.Stop()
+	w.Watch(&HookPayload{Type: "edit", Workspace: ws, WorkspaceName: "ws", Branch: "main"})
+
+	waitFor := func(n int) []HookPayload {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			mu.Lock()
+			got := append([]HookPayload(nil), recorded...)
+			mu.Unlock()
+			if len(got) >= n {
+				return got
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("expected %d external edits, got %d", n, len(recorded))
+		return nil
+	}
+	time.Sleep(100 * time.Millisecond) // Let the tree be watched
+
+	// A change no hook reported is recorded as the lines it changed
+	os.WriteFile(main, []byte("package main\n\nfunc main() { run() }\n"), 0644)
+	got := waitFor(1)
+	if p := got[0]; p.ToolName != "External" || p.Branch != "main" || p.LineNum != 3 ||
+		p.OldString != "func main() {}\n" || p.NewString != "func main() { run() }\n" {
+		t.Errorf("unexpected external edit: %+v", p)
+	}
+
+	// Files a hook reported, ignored directories and binary files are left
+	w.Claim(main)
+	os.WriteFile(main, []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join(ws, "node_modules", "x.js"), []byte("x\n"), 0644)
+	os.WriteFile(filepath.Join(ws, "logo.png"), []byte("\x89PNG\x00\x01"), 0644)
+
+	// New directories are watched, and new files recorded whole
+	os.Mkdir(filepath.Join(ws, "cmd"), 0755)
+	time.Sleep(100 * time.Millisecond)
+	os.WriteFile(filepath.Join(ws, "cmd", "tool.go"), []byte("package cmd\n"), 0644)
+	got = waitFor(2)
+	time.Sleep(100 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(recorded) != 2 || recorded[1].FilePath != filepath.Join(ws, "cmd", "tool.go") || recorded[1].NewString != "package cmd\n" {
+		t.Errorf("expected only the new file recorded, got %+v", got[1:])
+	}
+
+	// Claims are forgotten once their file has settled
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.claimed) != 0 {
+		t.Errorf("expected settled claims dropped, still have %v", w.claimed)
+	}
+}
+
+func TestChangedRegion(t *testing.T) {
+	old, new, line := changedRegion("a\nb\nc\n", "a\nB\nB2\nc\n")
+	if old != "b\n" || new != "B\nB2\n" || line != 2 {
+		t.Errorf("changedRegion = %q, %q, %d", old, new, line)
+	}
+	old, new, line = changedRegion("", "x\n")
+	if old != "" || new != "x\n" || line != 1 {
+		t.Errorf("changedRegion of a new file = %q, %q, %d", old, new, line)
+	}
+}
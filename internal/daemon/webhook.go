@@ -0,0 +1,420 @@
+package daemon
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/internal/workspace"
+)
+
+// Events webhooks can subscribe to
+const (
+	WebhookEventEdit         = "edit"          // An edit was recorded
+	WebhookEventSessionStart = "session_start" // A Claude session made its first edit
+	WebhookEventRalphFinish  = "ralph_finish"  // A Ralph loop ended
+	WebhookEventAlert        = "alert"         // An [alerts] rule was triggered
+)
+
+var webhookEvents = []string{WebhookEventEdit, WebhookEventSessionStart, WebhookEventRalphFinish, WebhookEventAlert}
+
+var (
+	// webhookBackoff is the wait before the first retry, doubling after each
+	webhookBackoff = time.Second
+	// webhookStopGrace is how long shutdown waits for deliveries in flight
+	webhookStopGrace = 5 * time.Second
+)
+
+// WebhookEvent is what a webhook is sent: the default JSON body, and the
+// data a body template is executed with
+type WebhookEvent struct {
+	Event     string    `json:"event"`
+	Workspace string    `json:"workspace"`
+	Time      time.Time `json:"time"`
+	Message   string    `json:"message"`           // One line describing the event, e.g. for a chat message
+	Session   string    `json:"session,omitempty"` // Claude session, if the hook passed it on
+
+	Edit  *WebhookEdit        `json:"edit,omitempty"`  // For "edit" events
+	Ralph *database.RalphLoop `json:"ralph,omitempty"` // For "ralph_finish" events
+	Alert string              `json:"alert,omitempty"` // For "alert" events: the rule's event kind, e.g. "alert_burst"
+}
+
+// WebhookEdit describes a recorded edit, without its content
+type WebhookEdit struct {
+	Tool    string `json:"tool"`
+	File    string `json:"file"` // Relative to the workspace when inside it
+	Line    int    `json:"line"`
+	Lines   int    `json:"lines"`
+	Summary string `json:"summary,omitempty"`
+}
+
+// WebhookSender delivers events to the configured webhooks
+type WebhookSender struct {
+	config   func() WebhooksConfig // Current settings, which may change on reload
+	hasEdits func(chatSessionID string) (bool, error)
+	failed   func(event *WebhookEvent, hook Webhook, err error)
+	client   *http.Client
+
+	ctx    context.Context // Cancelled to abandon deliveries on shutdown
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	sessions map[string]bool // Claude sessions already seen
+	stopped  bool
+}
+
+// NewWebhookSender creates a webhook sender reading its settings from the
+// daemon config. Failed deliveries are recorded as timeline events.
+func NewWebhookSender(d *Daemon) *WebhookSender {
+	return newWebhookSender(
+		func() WebhooksConfig {
+			d.cfgMu.RLock()
+			defer d.cfgMu.RUnlock()
+			return d.cfg.Webhooks
+		},
+		d.db.HasChatEdits,
+		func(event *WebhookEvent, hook Webhook, err error) {
+			d.recordEvent(event.Workspace, EventFailure, SeverityWarning,
+				fmt.Sprintf("webhook %s: %s event not delivered: %v", hook.label(), event.Event, err))
+		},
+	)
+}
+
+func newWebhookSender(config func() WebhooksConfig, hasEdits func(string) (bool, error),
+	failed func(*WebhookEvent, Webhook, error)) *WebhookSender {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &WebhookSender{
+		config:   config,
+		hasEdits: hasEdits,
+		failed:   failed,
+		client:   &http.Client{},
+		ctx:      ctx,
+		cancel:   cancel,
+		sessions: make(map[string]bool),
+	}
+}
+
+// Send delivers an event to each webhook subscribed to it, in the background
+func (ws *WebhookSender) Send(event *WebhookEvent) {
+	cfg := ws.config()
+	for _, hook := range cfg.Endpoints {
+		if !hook.wants(event) {
+			continue
+		}
+		body, err := hook.render(event)
+		if err != nil {
+			logger.Log("Webhook %s: %v", hook.label(), err)
+			ws.failed(event, hook, err)
+			continue
+		}
+
+		ws.mu.Lock()
+		if ws.stopped {
+			ws.mu.Unlock()
+			return
+		}
+		ws.wg.Add(1)
+		ws.mu.Unlock()
+
+		go func() {
+			defer ws.wg.Done()
+			if err := ws.deliver(hook, event.Event, body, cfg); err != nil {
+				logger.Log("Webhook %s: %s event not delivered: %v", hook.label(), event.Event, err)
+				ws.failed(event, hook, err)
+			}
+		}()
+	}
+}
+
+// Post delivers a body to a webhook, outside the events it's subscribed
+// to, with the same retries and signing. It blocks until delivered.
+func (ws *WebhookSender) Post(hook Webhook, event string, body []byte) error {
+	return ws.deliver(hook, event, body, ws.config())
+}
+
+// Stop waits briefly for deliveries in flight, then abandons them
+func (ws *WebhookSender) Stop() {
+	ws.mu.Lock()
+	ws.stopped = true
+	ws.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		ws.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(webhookStopGrace):
+		ws.cancel()
+		<-done
+	}
+	ws.cancel()
+}
+
+// sessionStarted reports whether an edit payload is the first from its
+// Claude session, when a webhook is subscribed to session starts. It must
+// be called before the edit is recorded.
+func (ws *WebhookSender) sessionStarted(payload *HookPayload) bool {
+	if payload.ChatSessionID == "" || !ws.subscribed(WebhookEventSessionStart) {
+		return false
+	}
+	ws.mu.Lock()
+	seen := ws.sessions[payload.ChatSessionID]
+	ws.sessions[payload.ChatSessionID] = true
+	ws.mu.Unlock()
+	if seen {
+		return false
+	}
+
+	// The session may have made edits before the daemon started
+	exists, err := ws.hasEdits(payload.ChatSessionID)
+	if err != nil {
+		logger.Log("Webhook: %v", err)
+		return false
+	}
+	return !exists
+}
+
+// subscribed reports whether any webhook is subscribed to an event
+func (ws *WebhookSender) subscribed(event string) bool {
+	for _, hook := range ws.config().Endpoints {
+		if len(hook.Events) == 0 || slices.Contains(hook.Events, event) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver POSTs a body to a webhook, retrying with backoff after network
+// errors and 5xx or 429 responses
+func (ws *WebhookSender) deliver(hook Webhook, event string, body []byte, cfg WebhooksConfig) error {
+	backoff := webhookBackoff
+	var err error
+	for attempt := 0; attempt <= cfg.RetryAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-ws.ctx.Done():
+				return err
+			}
+			backoff *= 2
+		}
+		var retry bool
+		retry, err = ws.post(hook, event, body, time.Duration(cfg.TimeoutSecs)*time.Second)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return fmt.Errorf("%w (after %d attempts)", err, cfg.RetryAttempts+1)
+}
+
+// post makes one delivery attempt, reporting whether a failure is worth
+// retrying
+func (ws *WebhookSender) post(hook Webhook, event string, body []byte, timeout time.Duration) (bool, error) {
+	ctx := ws.ctx
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, os.ExpandEnv(hook.URL), bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "claude-mon/"+Version)
+	req.Header.Set("X-Claude-Mon-Event", event)
+	for name, value := range hook.Headers {
+		req.Header.Set(name, os.ExpandEnv(value))
+	}
+	if secret := os.ExpandEnv(hook.Secret); secret != "" {
+		req.Header.Set("X-Claude-Mon-Signature", signWebhook(secret, body))
+	}
+
+	resp, err := ws.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode >= 300 {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("server responded %s", resp.Status)
+	}
+	return false, nil
+}
+
+// signWebhook returns the signature header of a body: "sha256=" and the
+// hex HMAC-SHA256 of the body keyed with the secret
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// label names a webhook in logs: its name, or its host
+func (h Webhook) label() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	if u, err := url.Parse(os.ExpandEnv(h.URL)); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "(unnamed)"
+}
+
+// wants reports whether a webhook is subscribed to an event
+func (h Webhook) wants(event *WebhookEvent) bool {
+	if len(h.Events) > 0 && !slices.Contains(h.Events, event.Event) {
+		return false
+	}
+	if len(h.Workspaces) == 0 {
+		return true
+	}
+	for _, pattern := range h.Workspaces {
+		if workspace.Match(pattern, event.Workspace) {
+			return true
+		}
+	}
+	return false
+}
+
+// render returns the body a webhook is sent for an event
+func (h Webhook) render(event *WebhookEvent) ([]byte, error) {
+	if h.Body == "" {
+		return json.Marshal(event)
+	}
+	tmpl, err := parseWebhookBody(h.Body)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, event); err != nil {
+		return nil, fmt.Errorf("body template: %w", err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		return nil, fmt.Errorf("body template produced invalid JSON: %s", buf.String())
+	}
+	return buf.Bytes(), nil
+}
+
+// parseWebhookBody parses a body template. Its json function quotes a
+// value for JSON, so text like a file path or message can't break the body.
+func parseWebhookBody(body string) (*template.Template, error) {
+	tmpl, err := template.New("body").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("body template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// validate checks a webhook's URL, events and body template
+func (h Webhook) validate() error {
+	u, err := url.Parse(os.ExpandEnv(h.URL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an http or https URL, got %q", h.URL)
+	}
+	for _, event := range h.Events {
+		if !slices.Contains(webhookEvents, event) {
+			return fmt.Errorf("unknown event %q: use %s", event, strings.Join(webhookEvents, ", "))
+		}
+	}
+	if h.Body != "" {
+		if _, err := parseWebhookBody(h.Body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// editWebhookEvent describes a recorded edit
+func editWebhookEvent(edit *database.Edit, payload *HookPayload) *WebhookEvent {
+	file := edit.FilePath
+	if rel, err := filepath.Rel(payload.Workspace, file); err == nil && !strings.HasPrefix(rel, "..") {
+		file = rel
+	}
+	message := fmt.Sprintf("%s %s:%d in %s", edit.ToolName, file, edit.LineNum, workspaceLabel(payload))
+	if edit.Summary != "" {
+		message += ": " + edit.Summary
+	}
+	return &WebhookEvent{
+		Event:     WebhookEventEdit,
+		Workspace: payload.Workspace,
+		Time:      time.Now(),
+		Message:   message,
+		Session:   payload.ChatSessionID,
+		Edit: &WebhookEdit{
+			Tool:    edit.ToolName,
+			File:    file,
+			Line:    edit.LineNum,
+			Lines:   edit.LineCount,
+			Summary: edit.Summary,
+		},
+	}
+}
+
+// sessionWebhookEvent describes a Claude session starting
+func sessionWebhookEvent(payload *HookPayload) *WebhookEvent {
+	return &WebhookEvent{
+		Event:     WebhookEventSessionStart,
+		Workspace: payload.Workspace,
+		Time:      time.Now(),
+		Message:   fmt.Sprintf("Claude session %s started in %s", payload.ChatSessionID, workspaceLabel(payload)),
+		Session:   payload.ChatSessionID,
+	}
+}
+
+// ralphWebhookEvent describes a Ralph loop that has ended
+func ralphWebhookEvent(loop *database.RalphLoop, payload *HookPayload) *WebhookEvent {
+	return &WebhookEvent{
+		Event:     WebhookEventRalphFinish,
+		Workspace: payload.Workspace,
+		Time:      time.Now(),
+		Message: fmt.Sprintf("Ralph loop in %s ended (%s) after %d iterations",
+			workspaceLabel(payload), loop.Outcome, loop.Iterations),
+		Ralph: loop,
+	}
+}
+
+// alertWebhookEvent describes an alert raised in a workspace
+func alertWebhookEvent(workspace, kind, message string) *WebhookEvent {
+	return &WebhookEvent{
+		Event:     WebhookEventAlert,
+		Workspace: workspace,
+		Time:      time.Now(),
+		Message:   fmt.Sprintf("%s: %s", filepath.Base(workspace), message),
+		Alert:     kind,
+	}
+}
+
+// workspaceLabel names a payload's workspace in messages
+func workspaceLabel(payload *HookPayload) string {
+	if payload.WorkspaceName != "" {
+		return payload.WorkspaceName
+	}
+	return filepath.Base(payload.Workspace)
+}
@@ -0,0 +1,155 @@
+package daemon
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/database"
+)
+
+// webhookRequest is a request a test server received
+type webhookRequest struct {
+	event, signature string
+	body             string
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	webhookBackoff = time.Millisecond
+	defer func() { webhookBackoff = time.Second }()
+
+	var mu sync.Mutex
+	var received []webhookRequest
+	attempts := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[r.URL.Path]++
+		if r.URL.Path == "/flaky" && attempts["/flaky"] == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		received = append(received, webhookRequest{
+			event:     r.Header.Get("X-Claude-Mon-Event"),
+			signature: r.Header.Get("X-Claude-Mon-Signature"),
+			body:      string(body),
+		})
+	}))
+	defer server.Close()
+
+	t.Setenv("TEST_WEBHOOK_SECRET", "s3cret")
+	cfg := WebhooksConfig{
+		RetryAttempts: 2,
+		Endpoints: []Webhook{
+			{Name: "slack", URL: server.URL + "/flaky", Events: []string{WebhookEventRalphFinish},
+				Body: `{"text": {{json .Message}}}`, Secret: "${TEST_WEBHOOK_SECRET}"},
+			{Name: "elsewhere", URL: server.URL + "/other", Workspaces: []string{"/src/web"}},
+			{Name: "gone", URL: server.URL + "/gone", Events: []string{WebhookEventRalphFinish}},
+		},
+	}
+	var failures []string
+	ws := newWebhookSender(func() WebhooksConfig { return cfg }, nil,
+		func(event *WebhookEvent, hook Webhook, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			failures = append(failures, hook.Name+": "+err.Error())
+		})
+
+	payload := &HookPayload{Workspace: "/src/api"}
+	ws.Send(ralphWebhookEvent(&database.RalphLoop{Outcome: "promise_met", Iterations: 4}, payload))
+	ws.Stop()
+
+	// The 502 was retried, the 404 wasn't, and the workspace filter kept
+	// the event from the other endpoint
+	if attempts["/flaky"] != 2 || attempts["/gone"] != 1 || attempts["/other"] != 0 {
+		t.Errorf("expected 2 attempts at /flaky and 1 at /gone, got %v", attempts)
+	}
+	if len(received) != 1 {
+		t.Fatalf("expected one delivery, got %+v", received)
+	}
+	got := received[0]
+	if got.body != `{"text": "Ralph loop in api ended (promise_met) after 4 iterations"}` {
+		t.Errorf("unexpected body %s", got.body)
+	}
+	if got.event != WebhookEventRalphFinish || got.signature != signWebhook("s3cret", []byte(got.body)) {
+		t.Errorf("unexpected headers: event %q, signature %q", got.event, got.signature)
+	}
+	if len(failures) != 1 || !strings.HasPrefix(failures[0], "gone: server responded 404") {
+		t.Errorf("expected the 404 reported, got %v", failures)
+	}
+}
+
+func TestWebhookRender(t *testing.T) {
+	edit := &database.Edit{ToolName: "Edit", FilePath: "/src/api/server.go", LineNum: 42, Summary: `modified func "Serve"`}
+	event := editWebhookEvent(edit, &HookPayload{Workspace: "/src/api", ChatSessionID: "abc"})
+
+	body, err := Webhook{}.render(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded WebhookEvent
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Edit.File != "server.go" || decoded.Session != "abc" ||
+		decoded.Message != `Edit server.go:42 in api: modified func "Serve"` {
+		t.Errorf("unexpected event %+v", decoded)
+	}
+
+	// Templates must produce JSON
+	if _, err := (Webhook{Body: `{"text": "{{.Message}}"}`}).render(event); err == nil {
+		t.Error("expected unquoted text to be rejected")
+	}
+	if _, err := (Webhook{Body: `{"file": {{json .Edit.File}}}`}).render(event); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWebhookValidate(t *testing.T) {
+	tests := []struct {
+		hook    Webhook
+		wantErr string
+	}{
+		{Webhook{URL: "https://hooks.slack.com/x", Events: []string{"edit"}}, ""},
+		{Webhook{URL: "ftp://example.com"}, "url must be"},
+		{Webhook{URL: "https://example.com", Events: []string{"commit"}}, "unknown event"},
+		{Webhook{URL: "https://example.com", Body: "{{.Message"}, "body template"},
+	}
+	for _, tt := range tests {
+		err := tt.hook.validate()
+		if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("validate(%+v) = %v, want %q", tt.hook, err, tt.wantErr)
+		}
+	}
+}
+
+func TestWebhookSessionStarted(t *testing.T) {
+	cfg := WebhooksConfig{Endpoints: []Webhook{{URL: "http://localhost", Events: []string{WebhookEventSessionStart}}}}
+	ws := newWebhookSender(func() WebhooksConfig { return cfg },
+		func(id string) (bool, error) { return id == "old", nil }, nil)
+
+	if !ws.sessionStarted(&HookPayload{ChatSessionID: "new"}) {
+		t.Error("expected the first edit to start the session")
+	}
+	if ws.sessionStarted(&HookPayload{ChatSessionID: "new"}) {
+		t.Error("expected the second edit not to")
+	}
+	if ws.sessionStarted(&HookPayload{ChatSessionID: "old"}) {
+		t.Error("expected a session with recorded edits not to start")
+	}
+
+	cfg.Endpoints[0].Events = []string{WebhookEventEdit}
+	if ws.sessionStarted(&HookPayload{ChatSessionID: "unsubscribed"}) {
+		t.Error("expected no session starts without a subscriber")
+	}
+}
@@ -0,0 +1,381 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// WorkspaceDump is every daemon row belonging to one workspace, for
+// archiving a finished project and restoring it later
+type WorkspaceDump struct {
+	WorkspacePath string             `json:"workspace_path"`
+	Sessions      []*SessionDump     `json:"sessions"`
+	Events        []*Event           `json:"events,omitempty"`
+	Injections    []*PromptInjection `json:"injections,omitempty"`
+	Chats         []*ChatDump        `json:"chats,omitempty"`
+	RalphLoops    []*RalphLoop       `json:"ralph_loops,omitempty"`
+	PlanPath      string             `json:"plan_path,omitempty"`     // Plan the workspace works from
+	SessionNames  map[string]string  `json:"session_names,omitempty"` // Names given to Claude sessions
+}
+
+// SessionDump is a session with the edits and prompts recorded in it
+type SessionDump struct {
+	Session
+	Edits   []*EditDump `json:"edits,omitempty"`
+	Prompts []*Prompt   `json:"prompts,omitempty"`
+}
+
+// ChatDump is a chat session with its transcript
+type ChatDump struct {
+	ChatSession
+	Transcript []*ChatMessage `json:"transcript,omitempty"`
+}
+
+// EditDump is an edit including its compressed file snapshot, which Edit
+// leaves out of JSON
+type EditDump struct {
+	*Edit
+	Snapshot []byte `json:"file_snapshot,omitempty"`
+}
+
+// ExportWorkspace collects all rows for a workspace
+func (d *DB) ExportWorkspace(workspacePath string) (*WorkspaceDump, error) {
+	dump := &WorkspaceDump{WorkspacePath: workspacePath}
+
+	sessions, err := d.db.Query(`
+		SELECT id, workspace_path, workspace_name, COALESCE(branch, ''), COALESCE(commit_sha, ''),
+		       started_at, last_activity
+		FROM sessions WHERE workspace_path = ?
+		ORDER BY started_at, id
+	`, workspacePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export sessions: %w", err)
+	}
+	for sessions.Next() {
+		var s SessionDump
+		if err := sessions.Scan(&s.ID, &s.WorkspacePath, &s.WorkspaceName, &s.Branch,
+			&s.CommitSHA, &s.StartedAt, &s.LastActivity); err != nil {
+			sessions.Close()
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		dump.Sessions = append(dump.Sessions, &s)
+	}
+	sessions.Close()
+
+	for _, s := range dump.Sessions {
+		if s.Edits, err = d.exportEdits(s.ID); err != nil {
+			return nil, err
+		}
+		if s.Prompts, err = d.exportPrompts(s.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	events, err := d.db.Query(`
+		SELECT id, workspace_path, kind, severity, COALESCE(message, ''), timestamp
+		FROM events WHERE workspace_path = ?
+		ORDER BY timestamp, id
+	`, workspacePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export events: %w", err)
+	}
+	defer events.Close()
+	for events.Next() {
+		var e Event
+		if err := events.Scan(&e.ID, &e.WorkspacePath, &e.Kind, &e.Severity, &e.Message, &e.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		dump.Events = append(dump.Events, &e)
+	}
+
+	injections, err := d.db.Query(`
+		SELECT id, prompt_name, COALESCE(prompt_version, 0), workspace_path,
+		       method, COALESCE(target, ''), timestamp
+		FROM prompt_injections WHERE workspace_path = ?
+		ORDER BY timestamp, id
+	`, workspacePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export prompt injections: %w", err)
+	}
+	defer injections.Close()
+	for injections.Next() {
+		var inj PromptInjection
+		if err := injections.Scan(&inj.ID, &inj.PromptName, &inj.PromptVersion, &inj.WorkspacePath,
+			&inj.Method, &inj.Target, &inj.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan prompt injection: %w", err)
+		}
+		dump.Injections = append(dump.Injections, &inj)
+	}
+
+	chats, err := d.GetChatSessions(workspacePath, -1)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range chats {
+		chat := &ChatDump{ChatSession: *c}
+		if chat.Transcript, err = d.GetChatMessages(c.SessionID); err != nil {
+			return nil, err
+		}
+		dump.Chats = append(dump.Chats, chat)
+	}
+
+	if dump.RalphLoops, err = d.GetRalphLoops(workspacePath, -1); err != nil {
+		return nil, err
+	}
+	if dump.PlanPath, err = d.GetWorkspacePlan(workspacePath); err != nil {
+		return nil, err
+	}
+	if dump.SessionNames, err = d.GetSessionNames(workspacePath); err != nil {
+		return nil, err
+	}
+
+	return dump, nil
+}
+
+// exportEdits returns a session's edits, oldest first, with their snapshots
+func (d *DB) exportEdits(sessionID int64) ([]*EditDump, error) {
+	rows, err := d.db.Query(`
+		SELECT id, session_id, tool_name, file_path,
+		       COALESCE(old_string, ''), COALESCE(new_string, ''),
+		       COALESCE(line_num, 0), COALESCE(line_count, 0),
+		       COALESCE(commit_sha, ''), COALESCE(vcs_type, ''),
+		       `+snapshotColumn+`, COALESCE(bookmarked, 0), COALESCE(note, ''), COALESCE(review, ''), timestamp,
+		       COALESCE(chat_session_id, ''), COALESCE(ralph_iteration, 0),
+		       COALESCE(summary, ''), COALESCE(user_name, '')
+		FROM edits WHERE session_id = ?
+		ORDER BY timestamp, id
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export edits: %w", err)
+	}
+	defer rows.Close()
+
+	var edits []*EditDump
+	for rows.Next() {
+		e := EditDump{Edit: &Edit{}}
+		if err := rows.Scan(
+			&e.ID, &e.SessionID, &e.ToolName, &e.FilePath,
+			&e.OldString, &e.NewString, &e.LineNum, &e.LineCount,
+			&e.CommitSHA, &e.VCSType, &e.Snapshot, &e.Bookmarked, &e.Note, &e.Review, &e.Timestamp,
+			&e.ChatSessionID, &e.RalphIteration, &e.Summary, &e.User,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan edit: %w", err)
+		}
+		edits = append(edits, &e)
+	}
+	return edits, nil
+}
+
+// exportPrompts returns the prompts recorded in a session
+func (d *DB) exportPrompts(sessionID int64) ([]*Prompt, error) {
+	rows, err := d.db.Query(`
+		SELECT id, session_id, name, COALESCE(description, ''), content, COALESCE(tags, 'null'),
+		       version, is_global, created_at, updated_at
+		FROM prompts WHERE session_id = ?
+		ORDER BY id
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export prompts: %w", err)
+	}
+	defer rows.Close()
+
+	var prompts []*Prompt
+	for rows.Next() {
+		var p Prompt
+		var tagsJSON string
+		if err := rows.Scan(&p.ID, &p.SessionID, &p.Name, &p.Description, &p.Content,
+			&tagsJSON, &p.Version, &p.IsGlobal, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan prompt: %w", err)
+		}
+		if err := json.Unmarshal([]byte(tagsJSON), &p.Tags); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+		}
+		prompts = append(prompts, &p)
+	}
+	return prompts, nil
+}
+
+// ImportWorkspace restores a dump under workspacePath, keeping the original
+// timestamps. Row IDs are reassigned, and edited file paths are moved along
+// when the workspace moved. It refuses to merge into a workspace the daemon
+// already has sessions for.
+func (d *DB) ImportWorkspace(dump *WorkspaceDump, workspacePath string) error {
+	var existing int
+	if err := d.db.QueryRow("SELECT COUNT(*) FROM sessions WHERE workspace_path = ?", workspacePath).Scan(&existing); err != nil {
+		return fmt.Errorf("failed to check workspace: %w", err)
+	}
+	if existing > 0 {
+		return fmt.Errorf("daemon already has %d session(s) for %s; purge them before restoring", existing, workspacePath)
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin import: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, s := range dump.Sessions {
+		var sessionID int64
+		if err := tx.QueryRow(`
+			INSERT INTO sessions (workspace_path, workspace_name, branch, commit_sha, started_at, last_activity)
+			VALUES (?, ?, ?, ?, ?, ?)
+			RETURNING id
+		`, workspacePath, s.WorkspaceName, s.Branch, s.CommitSHA,
+			sqlTime(s.StartedAt), sqlTime(s.LastActivity)).Scan(&sessionID); err != nil {
+			return fmt.Errorf("failed to import session: %w", err)
+		}
+
+		for _, e := range s.Edits {
+			filePath := rebasePath(e.FilePath, dump.WorkspacePath, workspacePath)
+			if _, err := tx.Exec(`
+				INSERT INTO edits (session_id, tool_name, file_path, old_string, new_string, line_num, line_count,
+				                   commit_sha, vcs_type, file_snapshot, bookmarked, note, review, timestamp, chat_session_id, ralph_iteration, summary, user_name)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, NULLIF(?, ''), NULLIF(?, 0), NULLIF(?, ''), NULLIF(?, ''))
+			`, sessionID, e.ToolName, filePath, e.OldString, e.NewString, e.LineNum, e.LineCount,
+				e.CommitSHA, e.VCSType, e.Snapshot, e.Bookmarked, e.Note, e.Review, sqlTime(e.Timestamp), e.ChatSessionID, e.RalphIteration,
+				e.Summary, e.User); err != nil {
+				return fmt.Errorf("failed to import edit: %w", err)
+			}
+		}
+
+		for _, p := range s.Prompts {
+			tagsJSON, err := json.Marshal(p.Tags)
+			if err != nil {
+				return fmt.Errorf("failed to marshal tags: %w", err)
+			}
+			if _, err := tx.Exec(`
+				INSERT INTO prompts (session_id, name, description, content, tags, version, is_global, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			`, sessionID, p.Name, p.Description, p.Content, string(tagsJSON), p.Version, p.IsGlobal,
+				sqlTime(p.CreatedAt), sqlTime(p.UpdatedAt)); err != nil {
+				return fmt.Errorf("failed to import prompt: %w", err)
+			}
+		}
+	}
+
+	for _, e := range dump.Events {
+		if _, err := tx.Exec(`
+			INSERT INTO events (workspace_path, kind, severity, message, timestamp)
+			VALUES (?, ?, ?, ?, ?)
+		`, workspacePath, e.Kind, e.Severity, e.Message, sqlTime(e.Timestamp)); err != nil {
+			return fmt.Errorf("failed to import event: %w", err)
+		}
+	}
+
+	for _, inj := range dump.Injections {
+		if _, err := tx.Exec(`
+			INSERT INTO prompt_injections (prompt_name, prompt_version, workspace_path, method, target, timestamp)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, inj.PromptName, inj.PromptVersion, workspacePath, inj.Method, inj.Target, sqlTime(inj.Timestamp)); err != nil {
+			return fmt.Errorf("failed to import prompt injection: %w", err)
+		}
+	}
+
+	for _, c := range dump.Chats {
+		if _, err := tx.Exec(`
+			INSERT INTO chat_sessions (session_id, workspace_path, purpose, started_at, last_activity)
+			VALUES (?, ?, ?, ?, ?)
+		`, c.SessionID, workspacePath, c.Purpose, sqlTime(c.StartedAt), sqlTime(c.LastActivity)); err != nil {
+			return fmt.Errorf("failed to import chat session: %w", err)
+		}
+		for _, msg := range c.Transcript {
+			if _, err := tx.Exec(`
+				INSERT INTO chat_messages (session_id, role, content, timestamp)
+				VALUES (?, ?, ?, ?)
+			`, c.SessionID, msg.Role, msg.Content, sqlTime(msg.Timestamp)); err != nil {
+				return fmt.Errorf("failed to import chat message: %w", err)
+			}
+		}
+	}
+
+	for _, l := range dump.RalphLoops {
+		if _, err := tx.Exec(`
+			INSERT INTO ralph_loops (workspace_path, prompt, promise, iterations, max_iterations, outcome, started_at, ended_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, workspacePath, l.Prompt, l.Promise, l.Iterations, l.MaxIterations, l.Outcome,
+			sqlTime(l.StartedAt), sqlTime(l.EndedAt)); err != nil {
+			return fmt.Errorf("failed to import ralph loop: %w", err)
+		}
+	}
+
+	if dump.PlanPath != "" {
+		if _, err := tx.Exec(`
+			INSERT OR REPLACE INTO workspace_plans (workspace_path, plan_path)
+			VALUES (?, ?)
+		`, workspacePath, rebasePath(dump.PlanPath, dump.WorkspacePath, workspacePath)); err != nil {
+			return fmt.Errorf("failed to import workspace plan: %w", err)
+		}
+	}
+
+	for sessionID, name := range dump.SessionNames {
+		if _, err := tx.Exec(`
+			INSERT OR REPLACE INTO session_names (session_id, workspace_path, name)
+			VALUES (?, ?, ?)
+		`, sessionID, workspacePath, name); err != nil {
+			return fmt.Errorf("failed to import session name: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit import: %w", err)
+	}
+	return nil
+}
+
+// rebasePath moves a path under oldRoot to the same place under newRoot,
+// leaving paths outside oldRoot alone
+func rebasePath(path, oldRoot, newRoot string) string {
+	if oldRoot == "" || oldRoot == newRoot {
+		return path
+	}
+	rel, err := filepath.Rel(oldRoot, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return path
+	}
+	return filepath.Join(newRoot, rel)
+}
+
+// DeleteWorkspace removes all rows for a workspace, returning the number of
+// sessions removed. Rows are deleted explicitly since foreign keys aren't
+// enforced on this connection.
+func (d *DB) DeleteWorkspace(workspacePath string) (int64, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin delete: %w", err)
+	}
+	defer tx.Rollback()
+
+	sessionIDs := "SELECT id FROM sessions WHERE workspace_path = ?"
+	statements := []string{
+		"DELETE FROM edits WHERE session_id IN (" + sessionIDs + ")",
+		"DELETE FROM file_snapshots WHERE session_id IN (" + sessionIDs + ")",
+		"DELETE FROM prompt_versions WHERE prompt_id IN (SELECT id FROM prompts WHERE session_id IN (" + sessionIDs + "))",
+		"DELETE FROM prompts WHERE session_id IN (" + sessionIDs + ")",
+		"DELETE FROM hooks WHERE session_id IN (" + sessionIDs + ")",
+		"DELETE FROM events WHERE workspace_path = ?",
+		"DELETE FROM prompt_injections WHERE workspace_path = ?",
+		"DELETE FROM chat_messages WHERE session_id IN (SELECT session_id FROM chat_sessions WHERE workspace_path = ?)",
+		"DELETE FROM chat_sessions WHERE workspace_path = ?",
+		"DELETE FROM ralph_loops WHERE workspace_path = ?",
+		"DELETE FROM workspace_plans WHERE workspace_path = ?",
+		"DELETE FROM check_results WHERE workspace_path = ?",
+		"DELETE FROM session_names WHERE workspace_path = ?",
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt, workspacePath); err != nil {
+			return 0, fmt.Errorf("failed to delete workspace rows: %w", err)
+		}
+	}
+
+	var result sql.Result
+	if result, err = tx.Exec("DELETE FROM sessions WHERE workspace_path = ?", workspacePath); err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit delete: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,254 @@
+package database
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Codecs file snapshots are compressed with
+const (
+	CodecGzip = "gzip"
+	CodecZstd = "zstd"
+)
+
+// zstdMagic starts every zstd frame; anything else is read as gzip
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// The zstd encoder and decoder are safe to share for whole-buffer use
+var (
+	zstdEncoder = sync.OnceValue(func() *zstd.Encoder {
+		enc, _ := zstd.NewWriter(nil)
+		return enc
+	})
+	zstdDecoder = sync.OnceValue(func() *zstd.Decoder {
+		dec, _ := zstd.NewReader(nil)
+		return dec
+	})
+)
+
+// compressData compresses data with a codec, gzip unless it's CodecZstd
+func compressData(data []byte, codec string) ([]byte, error) {
+	if codec == CodecZstd {
+		return zstdEncoder().EncodeAll(data, nil), nil
+	}
+
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompressData decompresses gzip or zstd data, telling them apart by
+// their header
+func decompressData(data []byte) ([]byte, error) {
+	if bytes.HasPrefix(data, zstdMagic) {
+		return zstdDecoder().DecodeAll(data, nil)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// snapshotColumn selects an edit's compressed file snapshot, whether it's
+// stored with the edit or in snapshot_blobs
+const snapshotColumn = `COALESCE(file_snapshot, (SELECT b.data FROM snapshot_blobs b WHERE b.hash = snapshot_hash))`
+
+// storeBlob stores content in snapshot_blobs unless it's there already,
+// returning its hash. The edit referencing it must be inserted in the same
+// transaction, or the blob is left without references.
+func (d *DB) storeBlob(tx *sql.Tx, content []byte) (string, error) {
+	sum := sha256.Sum256(content)
+	hash := hex.EncodeToString(sum[:])
+
+	var exists int
+	err := tx.QueryRow("SELECT 1 FROM snapshot_blobs WHERE hash = ?", hash).Scan(&exists)
+	if err == nil {
+		return hash, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to look up snapshot blob: %w", err)
+	}
+
+	data, err := compressData(content, d.codec)
+	if err != nil {
+		return "", fmt.Errorf("failed to compress snapshot: %w", err)
+	}
+	if _, err := tx.Exec("INSERT INTO snapshot_blobs (hash, codec, data, size) VALUES (?, ?, ?, ?)",
+		hash, d.codec, data, len(content)); err != nil {
+		return "", fmt.Errorf("failed to store snapshot blob: %w", err)
+	}
+	return hash, nil
+}
+
+// dedupeBatch is how many edits or blobs DedupeSnapshots rewrites per
+// transaction, so hooks aren't kept waiting on the write lock
+const dedupeBatch = 200
+
+// DedupeStats reports what deduplicating edit snapshots did
+type DedupeStats struct {
+	Moved        int64 `json:"moved"`        // Snapshots moved from edits into snapshot_blobs
+	Recompressed int64 `json:"recompressed"` // Blobs rewritten with the configured codec
+	Skipped      int64 `json:"skipped"`      // Snapshots that couldn't be decompressed, left as they were
+	Blobs        int64 `json:"blobs"`        // Distinct contents stored afterwards
+	Refs         int64 `json:"refs"`         // Edits referencing them
+	BytesBefore  int64 `json:"bytes_before"` // Compressed snapshot bytes before
+	BytesAfter   int64 `json:"bytes_after"`  // and after
+}
+
+// DedupeSnapshots moves snapshots stored with their edits into
+// snapshot_blobs, where edits with the same content share one, and
+// recompresses blobs stored with another codec. The database file only
+// shrinks once it's vacuumed.
+func (d *DB) DedupeSnapshots() (*DedupeStats, error) {
+	stats := &DedupeStats{}
+	if err := d.snapshotBytes(&stats.BytesBefore); err != nil {
+		return nil, err
+	}
+
+	var lastID int64
+	for {
+		var ids []int64
+		rows, err := d.db.Query("SELECT id FROM edits WHERE file_snapshot IS NOT NULL AND id > ? ORDER BY id LIMIT ?", lastID, dedupeBatch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list edit snapshots: %w", err)
+		}
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan edit id: %w", err)
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if len(ids) == 0 {
+			break
+		}
+		lastID = ids[len(ids)-1]
+		if err := d.moveSnapshots(ids, stats); err != nil {
+			return nil, err
+		}
+	}
+
+	var lastHash string
+	for {
+		var hashes []string
+		rows, err := d.db.Query("SELECT hash FROM snapshot_blobs WHERE codec != ? AND hash > ? ORDER BY hash LIMIT ?", d.codec, lastHash, dedupeBatch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list snapshot blobs: %w", err)
+		}
+		for rows.Next() {
+			var hash string
+			if err := rows.Scan(&hash); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan blob hash: %w", err)
+			}
+			hashes = append(hashes, hash)
+		}
+		rows.Close()
+		if len(hashes) == 0 {
+			break
+		}
+		lastHash = hashes[len(hashes)-1]
+		if err := d.recompressBlobs(hashes, stats); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := d.db.QueryRow("SELECT COUNT(*), COALESCE(SUM(refs), 0) FROM snapshot_blobs").Scan(&stats.Blobs, &stats.Refs); err != nil {
+		return nil, fmt.Errorf("failed to count snapshot blobs: %w", err)
+	}
+	if err := d.snapshotBytes(&stats.BytesAfter); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// moveSnapshots moves a batch of edits' snapshots into snapshot_blobs
+func (d *DB) moveSnapshots(ids []int64, stats *DedupeStats) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin dedupe: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, id := range ids {
+		var snapshot []byte
+		if err := tx.QueryRow("SELECT file_snapshot FROM edits WHERE id = ?", id).Scan(&snapshot); err != nil {
+			return fmt.Errorf("failed to read edit snapshot: %w", err)
+		}
+		content, err := decompressData(snapshot)
+		if err != nil {
+			stats.Skipped++
+			continue
+		}
+		hash, err := d.storeBlob(tx, content)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE edits SET snapshot_hash = ?, file_snapshot = NULL WHERE id = ?", hash, id); err != nil {
+			return fmt.Errorf("failed to update edit snapshot: %w", err)
+		}
+		stats.Moved++
+	}
+	return tx.Commit()
+}
+
+// recompressBlobs rewrites a batch of blobs with the configured codec
+func (d *DB) recompressBlobs(hashes []string, stats *DedupeStats) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin recompress: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, hash := range hashes {
+		var data []byte
+		if err := tx.QueryRow("SELECT data FROM snapshot_blobs WHERE hash = ?", hash).Scan(&data); err != nil {
+			return fmt.Errorf("failed to read snapshot blob: %w", err)
+		}
+		content, err := decompressData(data)
+		if err != nil {
+			stats.Skipped++
+			continue
+		}
+		if data, err = compressData(content, d.codec); err != nil {
+			return fmt.Errorf("failed to compress snapshot: %w", err)
+		}
+		if _, err := tx.Exec("UPDATE snapshot_blobs SET codec = ?, data = ? WHERE hash = ?", d.codec, data, hash); err != nil {
+			return fmt.Errorf("failed to update snapshot blob: %w", err)
+		}
+		stats.Recompressed++
+	}
+	return tx.Commit()
+}
+
+// snapshotBytes totals the compressed snapshot bytes stored with edits and
+// in snapshot_blobs
+func (d *DB) snapshotBytes(total *int64) error {
+	err := d.db.QueryRow(`
+		SELECT COALESCE((SELECT SUM(LENGTH(file_snapshot)) FROM edits), 0) +
+		       COALESCE((SELECT SUM(LENGTH(data)) FROM snapshot_blobs), 0)
+	`).Scan(total)
+	if err != nil {
+		return fmt.Errorf("failed to measure snapshots: %w", err)
+	}
+	return nil
+}
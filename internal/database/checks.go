@@ -0,0 +1,82 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// checkTimeFormat keeps milliseconds so a run can be ordered against edits
+// made within the same second
+const checkTimeFormat = "2006-01-02 15:04:05.000"
+
+// CheckResult is the outcome of one configured check (e.g. "go vet" on the
+// edited package) run by the daemon after edits to a file
+type CheckResult struct {
+	ID            int64     `json:"id"`
+	WorkspacePath string    `json:"workspace_path"`
+	FilePath      string    `json:"file_path"`
+	Name          string    `json:"name"`
+	Command       string    `json:"command"`
+	Passed        bool      `json:"passed"`
+	ExitCode      int       `json:"exit_code"` // -1 if the command didn't start or timed out
+	Output        string    `json:"output,omitempty"`
+	DurationMS    int64     `json:"duration_ms"`
+	StartedAt     time.Time `json:"started_at"` // Shared by every result of one run
+}
+
+// RecordCheckResults stores the results of one check run
+func (d *DB) RecordCheckResults(results []*CheckResult) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin check results: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, r := range results {
+		if _, err := tx.Exec(`
+			INSERT INTO check_results (workspace_path, file_path, name, command, passed, exit_code, output, duration_ms, started_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, r.WorkspacePath, r.FilePath, r.Name, r.Command, r.Passed, r.ExitCode, r.Output, r.DurationMS,
+			r.StartedAt.UTC().Format(checkTimeFormat)); err != nil {
+			return fmt.Errorf("failed to record check result: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetCheckResults returns a workspace's check results, newest run first. An
+// empty filePath returns results for every file.
+func (d *DB) GetCheckResults(workspacePath, filePath string, limit int) ([]*CheckResult, error) {
+	rows, err := d.db.Query(`
+		SELECT id, workspace_path, file_path, name, command, passed, COALESCE(exit_code, 0),
+		       COALESCE(output, ''), COALESCE(duration_ms, 0), started_at
+		FROM check_results
+		WHERE workspace_path = ? AND (? = '' OR file_path = ?)
+		ORDER BY started_at DESC, id
+		LIMIT ?
+	`, workspacePath, filePath, filePath, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query check results: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*CheckResult
+	for rows.Next() {
+		var r CheckResult
+		if err := rows.Scan(&r.ID, &r.WorkspacePath, &r.FilePath, &r.Name, &r.Command, &r.Passed, &r.ExitCode,
+			&r.Output, &r.DurationMS, &r.StartedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan check result: %w", err)
+		}
+		results = append(results, &r)
+	}
+	return results, rows.Err()
+}
+
+// DeleteOldCheckResults deletes check results older than the specified date
+func (d *DB) DeleteOldCheckResults(beforeDate time.Time) (int64, error) {
+	result, err := d.db.Exec("DELETE FROM check_results WHERE started_at < ?", sqlTime(beforeDate))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old check results: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -1,54 +1,29 @@
 package database
 
 import (
-	"bytes"
-	"compress/gzip"
 	"database/sql"
-	"embed"
 	"encoding/json"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
-
-	_ "github.com/mattn/go-sqlite3"
 )
 
-// compressData compresses data using gzip
-func compressData(data []byte) ([]byte, error) {
-	var buf bytes.Buffer
-	w := gzip.NewWriter(&buf)
-	if _, err := w.Write(data); err != nil {
-		return nil, err
-	}
-	if err := w.Close(); err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
-}
-
-// decompressData decompresses gzip data
-func decompressData(data []byte) ([]byte, error) {
-	r, err := gzip.NewReader(bytes.NewReader(data))
-	if err != nil {
-		return nil, err
-	}
-	defer r.Close()
-	return io.ReadAll(r)
-}
-
-//go:embed schema.sql
-var schemaFS embed.FS
-
 // DB wraps SQLite database operations
 type DB struct {
-	db *sql.DB
+	db    *sql.DB
+	stmts *statements
+	codec string // Codec new file snapshots are compressed with
 }
 
 // Config holds database configuration
 type Config struct {
-	Path string // Path to SQLite database file
+	Path               string // Path to SQLite database file
+	PoolSize           int    // Connections open at most; 0 uses a default
+	BusyTimeoutMS      int    // How long a write waits for another to finish; 0 uses a default
+	WALCheckpointPages int    // WAL size in pages that triggers a checkpoint; 0 keeps SQLite's
+	SnapshotCodec      string // CodecGzip or CodecZstd for new file snapshots; empty is gzip
 }
 
 // DefaultConfig returns default database config
@@ -74,91 +49,41 @@ func Open(cfg *Config) (*DB, error) {
 		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite3", cfg.Path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
-	}
+	db := openPool(cfg)
 
-	// Enable WAL mode for better concurrency
-	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
-		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
+	// Bring the schema up to date
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
 	}
 
-	// Initialize schema
-	if err := initSchema(db); err != nil {
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
+	stmts, err := prepareStatements(db)
+	if err != nil {
+		db.Close()
+		return nil, err
 	}
 
-	return &DB{db: db}, nil
+	codec := cfg.SnapshotCodec
+	if codec == "" {
+		codec = CodecGzip
+	}
+	return &DB{db: db, stmts: stmts, codec: codec}, nil
 }
 
-// Close closes the database connection
+// Close closes the database connections
 func (d *DB) Close() error {
+	d.stmts.close()
 	return d.db.Close()
 }
 
-func initSchema(db *sql.DB) error {
-	schema, err := schemaFS.ReadFile("schema.sql")
-	if err != nil {
-		return fmt.Errorf("failed to read schema: %w", err)
-	}
-
-	_, err = db.Exec(string(schema))
+// migrate applies any pending migrations
+func migrate(db *sql.DB) error {
+	m, err := newMigrator(db)
 	if err != nil {
-		return fmt.Errorf("failed to execute schema: %w", err)
-	}
-
-	// Run migrations for existing databases
-	if err := runMigrations(db); err != nil {
-		return fmt.Errorf("failed to run migrations: %w", err)
+		return err
 	}
-
-	return nil
-}
-
-// runMigrations handles schema migrations for existing databases
-func runMigrations(db *sql.DB) error {
-	// Check which columns exist in edits table
-	columns := make(map[string]bool)
-	rows, err := db.Query("PRAGMA table_info(edits)")
-	if err != nil {
-		return fmt.Errorf("failed to get table info: %w", err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var cid int
-		var name, colType string
-		var notNull, pk int
-		var dfltValue interface{}
-		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
-			return fmt.Errorf("failed to scan column info: %w", err)
-		}
-		columns[name] = true
-	}
-
-	// Add commit_sha column if missing
-	if !columns["commit_sha"] {
-		if _, err := db.Exec("ALTER TABLE edits ADD COLUMN commit_sha TEXT"); err != nil {
-			return fmt.Errorf("failed to add commit_sha column: %w", err)
-		}
-	}
-
-	// Add vcs_type column if missing
-	if !columns["vcs_type"] {
-		if _, err := db.Exec("ALTER TABLE edits ADD COLUMN vcs_type TEXT"); err != nil {
-			return fmt.Errorf("failed to add vcs_type column: %w", err)
-		}
-	}
-
-	// Add file_snapshot column if missing
-	if !columns["file_snapshot"] {
-		if _, err := db.Exec("ALTER TABLE edits ADD COLUMN file_snapshot BLOB"); err != nil {
-			return fmt.Errorf("failed to add file_snapshot column: %w", err)
-		}
-	}
-
-	return nil
+	_, err = m.Up(0, false)
+	return err
 }
 
 // Session represents a Claude session
@@ -172,19 +97,19 @@ type Session struct {
 	LastActivity  time.Time
 }
 
+const upsertSessionQuery = `
+	INSERT INTO sessions (workspace_path, workspace_name, branch, commit_sha, last_activity)
+	VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(workspace_path, branch) DO UPDATE SET
+		last_activity = CURRENT_TIMESTAMP,
+		commit_sha = excluded.commit_sha
+	RETURNING id
+`
+
 // UpsertSession creates or updates a session
 func (d *DB) UpsertSession(workspacePath, workspaceName, branch, commitSHA string) (int64, error) {
-	query := `
-		INSERT INTO sessions (workspace_path, workspace_name, branch, commit_sha, last_activity)
-		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
-		ON CONFLICT(workspace_path, branch) DO UPDATE SET
-			last_activity = CURRENT_TIMESTAMP,
-			commit_sha = excluded.commit_sha
-		RETURNING id
-	`
-
 	var id int64
-	err := d.db.QueryRow(query, workspacePath, workspaceName, branch, commitSHA).Scan(&id)
+	err := d.stmts.upsertSession.QueryRow(workspacePath, workspaceName, branch, commitSHA).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("failed to upsert session: %w", err)
 	}
@@ -221,30 +146,86 @@ type Edit struct {
 	NewString    string    `json:"new_string"`
 	LineNum      int       `json:"line_num"`
 	LineCount    int       `json:"line_count"`
-	CommitSHA    string    `json:"commit_sha"`   // VCS commit/change ID at time of edit
-	VCSType      string    `json:"vcs_type"`     // "git" or "jj"
-	FileSnapshot []byte    `json:"-"`            // gzip-compressed file content (not in JSON)
-	FileContent  string    `json:"file_content"` // decompressed file content (transient, not stored)
+	CommitSHA    string    `json:"commit_sha"`       // VCS commit/change ID at time of edit
+	VCSType      string    `json:"vcs_type"`         // "git" or "jj"
+	FileSnapshot []byte    `json:"-"`                // compressed file content stored with the edit (not in JSON)
+	FileContent  string    `json:"file_content"`     // decompressed file content, stored in snapshot_blobs when recorded
+	Bookmarked   bool      `json:"bookmarked"`       // flagged for later review
+	Note         string    `json:"note,omitempty"`   // review note attached to the edit
+	Review       string    `json:"review,omitempty"` // ReviewApproved, ReviewRejected or "" if not reviewed
 	Timestamp    time.Time `json:"created_at"`
+
+	// Claude CLI session that made the edit, if the hook passed it on
+	ChatSessionID string `json:"chat_session_id,omitempty"`
+
+	// Ralph loop iteration the edit was made in (0 outside a loop)
+	RalphIteration int `json:"ralph_iteration,omitempty"`
+
+	// Declarations the edit touched, e.g. "modified func (Model) Update"
+	Summary string `json:"summary,omitempty"`
+
+	// User whose Claude session made the edit: their login name, or their
+	// uid if it has none. Empty if the daemon couldn't tell.
+	User string `json:"user,omitempty"`
 }
 
-// RecordEdit records a file edit
-func (d *DB) RecordEdit(edit *Edit) error {
-	query := `
-		INSERT INTO edits (session_id, tool_name, file_path, old_string, new_string, line_num, line_count, commit_sha, vcs_type, file_snapshot)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
+// ToolExternal is the tool name of edits made outside Claude: changes the
+// daemon's file watcher saw in a workspace that no hook reported
+const ToolExternal = "External"
 
-	_, err := d.db.Exec(query, edit.SessionID, edit.ToolName, edit.FilePath,
+const insertEditQuery = `
+	INSERT INTO edits (session_id, tool_name, file_path, old_string, new_string, line_num, line_count, commit_sha, vcs_type, file_snapshot, snapshot_hash, chat_session_id, ralph_iteration, summary, user_name)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, 0), NULLIF(?, ''), NULLIF(?, ''))
+`
+
+// editArgs returns the values insertEditQuery records for an edit whose
+// file content is stored under snapshotHash
+func editArgs(edit *Edit, snapshotHash string) []any {
+	return []any{edit.SessionID, edit.ToolName, edit.FilePath,
 		edit.OldString, edit.NewString, edit.LineNum, edit.LineCount,
-		edit.CommitSHA, edit.VCSType, edit.FileSnapshot)
-	if err != nil {
+		edit.CommitSHA, edit.VCSType, edit.FileSnapshot, snapshotHash, edit.ChatSessionID, edit.RalphIteration, edit.Summary, edit.User}
+}
+
+// RecordEdit records a file edit
+func (d *DB) RecordEdit(edit *Edit) error {
+	// The snapshot blob and its reference are written together
+	if edit.FileContent != "" && edit.FileSnapshot == nil {
+		return d.RecordEdits([]*Edit{edit})
+	}
+	if _, err := d.stmts.insertEdit.Exec(editArgs(edit, "")...); err != nil {
 		return fmt.Errorf("failed to record edit: %w", err)
 	}
 
 	return nil
 }
 
+// RecordEdits records a batch of edits in one transaction; none are
+// recorded if any fails. File contents are stored once however many
+// edits share them.
+func (d *DB) RecordEdits(edits []*Edit) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin edits: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt := tx.Stmt(d.stmts.insertEdit)
+	defer stmt.Close()
+
+	for _, edit := range edits {
+		var hash string
+		if edit.FileContent != "" && edit.FileSnapshot == nil {
+			if hash, err = d.storeBlob(tx, []byte(edit.FileContent)); err != nil {
+				return err
+			}
+		}
+		if _, err := stmt.Exec(editArgs(edit, hash)...); err != nil {
+			return fmt.Errorf("failed to record edit: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
 // Prompt represents a prompt
 type Prompt struct {
 	ID          int64
@@ -253,10 +234,13 @@ type Prompt struct {
 	Description string
 	Content     string
 	Tags        []string
+	Variables   string // JSON array of the prompt's user-defined variables; empty if none
 	Version     int
 	IsGlobal    bool
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
+	Uses        int             // How many times the prompt has been sent
+	Versions    []PromptVersion // Version backups, for prompts in the library
 }
 
 // RecordPrompt records or updates a prompt
@@ -295,60 +279,138 @@ func (d *DB) RecordPrompt(prompt *Prompt) (int64, error) {
 	return id, nil
 }
 
-// GetPrompts retrieves prompts matching filters
-func (d *DB) GetPrompts(namePattern string, limit int) ([]*Prompt, error) {
+// GetPrompts retrieves prompts matching filters. A non-empty tag limits
+// the results to prompts carrying that tag.
+func (d *DB) GetPrompts(namePattern, tag string, limit int) ([]*Prompt, error) {
 	query := `
-		SELECT id, session_id, name, description, content, tags, version, is_global, created_at, updated_at
+		SELECT ` + promptColumns + `
 		FROM prompts
 		WHERE name LIKE ?
+		  AND (? = '' OR EXISTS (SELECT 1 FROM json_each(prompts.tags) WHERE value = ?))
 		ORDER BY updated_at DESC
 		LIMIT ?
 	`
 
-	rows, err := d.db.Query(query, "%"+namePattern+"%", limit)
+	rows, err := d.db.Query(query, "%"+namePattern+"%", tag, tag, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get prompts: %w", err)
 	}
 	defer rows.Close()
 
-	var prompts []*Prompt
+	return scanPrompts(rows)
+}
+
+const recentEditsQuery = `
+	SELECT e.id, e.session_id, e.tool_name, e.file_path,
+	       e.old_string, e.new_string, e.line_num, e.line_count,
+	       COALESCE(e.commit_sha, ''), COALESCE(e.vcs_type, ''),
+	       ` + snapshotColumn + `, COALESCE(e.bookmarked, 0), COALESCE(e.note, ''), COALESCE(e.review, ''), e.timestamp,
+	       COALESCE(e.summary, ''), COALESCE(e.chat_session_id, ''), COALESCE(e.user_name, '')
+	FROM edits e
+	ORDER BY e.timestamp DESC, e.id DESC
+	LIMIT ?
+`
+
+// GetRecentEdits retrieves recent edits
+func (d *DB) GetRecentEdits(limit int) ([]*Edit, error) {
+	rows, err := d.stmts.recentEdits.Query(limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent edits: %w", err)
+	}
+	defer rows.Close()
+
+	var edits []*Edit
 	for rows.Next() {
-		var p Prompt
-		var tagsJSON string
+		var e Edit
+		var snapshot []byte
+		err := rows.Scan(
+			&e.ID, &e.SessionID, &e.ToolName, &e.FilePath,
+			&e.OldString, &e.NewString, &e.LineNum, &e.LineCount,
+			&e.CommitSHA, &e.VCSType, &snapshot, &e.Bookmarked, &e.Note, &e.Review, &e.Timestamp,
+			&e.Summary, &e.ChatSessionID, &e.User,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan edit: %w", err)
+		}
+
+		// Decompress file snapshot if present
+		if len(snapshot) > 0 {
+			if content, err := decompressData(snapshot); err == nil {
+				e.FileContent = string(content)
+			}
+		}
+
+		edits = append(edits, &e)
+	}
+
+	return edits, nil
+}
 
+const workspaceEditsQuery = `
+	SELECT e.id, e.session_id, e.tool_name, e.file_path,
+	       e.old_string, e.new_string, e.line_num, e.line_count,
+	       COALESCE(e.commit_sha, ''), COALESCE(e.vcs_type, ''),
+	       ` + snapshotColumn + `, COALESCE(e.bookmarked, 0), COALESCE(e.note, ''), COALESCE(e.review, ''), e.timestamp,
+	       COALESCE(e.summary, ''), COALESCE(e.chat_session_id, ''), COALESCE(e.user_name, '')
+	FROM edits e
+	JOIN sessions s ON e.session_id = s.id
+	WHERE s.workspace_path = ?
+	ORDER BY e.timestamp DESC, e.id DESC
+	LIMIT ?
+`
+
+// GetEditsByWorkspace retrieves recent edits for a specific workspace
+func (d *DB) GetEditsByWorkspace(workspacePath string, limit int) ([]*Edit, error) {
+	rows, err := d.stmts.workspaceEdits.Query(workspacePath, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get edits by workspace: %w", err)
+	}
+	defer rows.Close()
+
+	var edits []*Edit
+	for rows.Next() {
+		var e Edit
+		var snapshot []byte
 		err := rows.Scan(
-			&p.ID, &p.SessionID, &p.Name, &p.Description, &p.Content,
-			&tagsJSON, &p.Version, &p.IsGlobal, &p.CreatedAt, &p.UpdatedAt,
+			&e.ID, &e.SessionID, &e.ToolName, &e.FilePath,
+			&e.OldString, &e.NewString, &e.LineNum, &e.LineCount,
+			&e.CommitSHA, &e.VCSType, &snapshot, &e.Bookmarked, &e.Note, &e.Review, &e.Timestamp,
+			&e.Summary, &e.ChatSessionID, &e.User,
 		)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan prompt: %w", err)
+			return nil, fmt.Errorf("failed to scan edit: %w", err)
 		}
 
-		if err := json.Unmarshal([]byte(tagsJSON), &p.Tags); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+		// Decompress file snapshot if present
+		if len(snapshot) > 0 {
+			if content, err := decompressData(snapshot); err == nil {
+				e.FileContent = string(content)
+			}
 		}
 
-		prompts = append(prompts, &p)
+		edits = append(edits, &e)
 	}
 
-	return prompts, nil
+	return edits, nil
 }
 
-// GetRecentEdits retrieves recent edits
-func (d *DB) GetRecentEdits(limit int) ([]*Edit, error) {
+// GetEditsByFile retrieves edits for a specific file
+func (d *DB) GetEditsByFile(filePath string, limit int) ([]*Edit, error) {
 	query := `
-		SELECT e.id, e.session_id, e.tool_name, e.file_path,
-		       e.old_string, e.new_string, e.line_num, e.line_count,
-		       COALESCE(e.commit_sha, ''), COALESCE(e.vcs_type, ''),
-		       e.file_snapshot, e.timestamp
-		FROM edits e
-		ORDER BY e.timestamp DESC
+		SELECT id, session_id, tool_name, file_path,
+		       old_string, new_string, line_num, line_count,
+		       COALESCE(commit_sha, ''), COALESCE(vcs_type, ''),
+		       ` + snapshotColumn + `, COALESCE(bookmarked, 0), COALESCE(note, ''), COALESCE(review, ''), timestamp,
+		       COALESCE(summary, ''), COALESCE(chat_session_id, ''), COALESCE(user_name, '')
+		FROM edits
+		WHERE file_path = ?
+		ORDER BY timestamp DESC
 		LIMIT ?
 	`
 
-	rows, err := d.db.Query(query, limit)
+	rows, err := d.db.Query(query, filePath, limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get recent edits: %w", err)
+		return nil, fmt.Errorf("failed to get edits by file: %w", err)
 	}
 	defer rows.Close()
 
@@ -359,7 +421,8 @@ func (d *DB) GetRecentEdits(limit int) ([]*Edit, error) {
 		err := rows.Scan(
 			&e.ID, &e.SessionID, &e.ToolName, &e.FilePath,
 			&e.OldString, &e.NewString, &e.LineNum, &e.LineCount,
-			&e.CommitSHA, &e.VCSType, &snapshot, &e.Timestamp,
+			&e.CommitSHA, &e.VCSType, &snapshot, &e.Bookmarked, &e.Note, &e.Review, &e.Timestamp,
+			&e.Summary, &e.ChatSessionID, &e.User,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan edit: %w", err)
@@ -378,39 +441,98 @@ func (d *DB) GetRecentEdits(limit int) ([]*Edit, error) {
 	return edits, nil
 }
 
-// GetEditsByWorkspace retrieves recent edits for a specific workspace
-func (d *DB) GetEditsByWorkspace(workspacePath string, limit int) ([]*Edit, error) {
+// EditFilter narrows edit queries. Empty fields are ignored.
+type EditFilter struct {
+	WorkspacePath string
+	Workspaces    []string // Optional set of workspaces (e.g. a group); any may match
+	Bookmarked    bool     // Only bookmarked edits
+	Tool          string
+	Since         time.Time
+	Until         time.Time
+	MatchPath     func(path string) bool // Optional path predicate (e.g. a glob)
+	Before        EditCursor             // Only edits before this one, when set
+	Content       string                 // Text the old or new string, summary or note contains (case-insensitive)
+	FilePath      string                 // Only edits to this file
+	User          string                 // Only edits made by this user
+}
+
+// likeEscaper escapes LIKE's wildcards so text is matched as written
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// GetFilteredEdits retrieves the most recent edits matching a filter
+func (d *DB) GetFilteredEdits(f EditFilter, limit int) ([]*Edit, error) {
 	query := `
 		SELECT e.id, e.session_id, e.tool_name, e.file_path,
 		       e.old_string, e.new_string, e.line_num, e.line_count,
 		       COALESCE(e.commit_sha, ''), COALESCE(e.vcs_type, ''),
-		       e.file_snapshot, e.timestamp
+		       ` + snapshotColumn + `, COALESCE(e.bookmarked, 0), COALESCE(e.note, ''), COALESCE(e.review, ''), e.timestamp,
+		       COALESCE(e.summary, ''), COALESCE(e.chat_session_id, ''), COALESCE(e.user_name, '')
 		FROM edits e
 		JOIN sessions s ON e.session_id = s.id
-		WHERE s.workspace_path = ?
-		ORDER BY e.timestamp DESC
-		LIMIT ?
+		WHERE (? = '' OR s.workspace_path = ?)
+		  AND (? = '' OR e.tool_name = ? COLLATE NOCASE)
+		  AND (? = '' OR e.timestamp >= ?)
+		  AND (? = '' OR e.timestamp <= ?)
+		  AND (? = 0 OR e.bookmarked = 1)
+		  AND (? = '' OR e.file_path = ?)
+		  AND (? = '' OR e.user_name = ?)
 	`
 
-	rows, err := d.db.Query(query, workspacePath, limit)
+	var sinceStr, untilStr string
+	if !f.Since.IsZero() {
+		sinceStr = sqlTime(f.Since)
+	}
+	if !f.Until.IsZero() {
+		untilStr = sqlTime(f.Until)
+	}
+
+	args := []any{f.WorkspacePath, f.WorkspacePath, f.Tool, f.Tool,
+		sinceStr, sinceStr, untilStr, untilStr, f.Bookmarked, f.FilePath, f.FilePath, f.User, f.User}
+	if len(f.Workspaces) > 0 {
+		query += " AND s.workspace_path IN (" + placeholders(len(f.Workspaces)) + ")"
+		for _, w := range f.Workspaces {
+			args = append(args, w)
+		}
+	}
+	if f.Content != "" {
+		query += ` AND (e.old_string LIKE ? ESCAPE '\' OR e.new_string LIKE ? ESCAPE '\'
+		            OR e.summary LIKE ? ESCAPE '\' OR e.note LIKE ? ESCAPE '\')`
+		pattern := "%" + likeEscaper.Replace(f.Content) + "%"
+		args = append(args, pattern, pattern, pattern, pattern)
+	}
+	if f.Before.ID > 0 {
+		// Edits made in the same second are ordered by ID
+		query += " AND (e.timestamp < ? OR (e.timestamp = ? AND e.id < ?))"
+		args = append(args, sqlTime(f.Before.Timestamp), sqlTime(f.Before.Timestamp), f.Before.ID)
+	}
+	query += " ORDER BY e.timestamp DESC, e.id DESC"
+
+	rows, err := d.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get edits by workspace: %w", err)
+		return nil, fmt.Errorf("failed to get filtered edits: %w", err)
 	}
 	defer rows.Close()
 
+	// The path predicate can't be expressed in SQL, so rows are filtered here
+	// and the limit applied after matching
 	var edits []*Edit
-	for rows.Next() {
+	for rows.Next() && len(edits) < limit {
 		var e Edit
 		var snapshot []byte
 		err := rows.Scan(
 			&e.ID, &e.SessionID, &e.ToolName, &e.FilePath,
 			&e.OldString, &e.NewString, &e.LineNum, &e.LineCount,
-			&e.CommitSHA, &e.VCSType, &snapshot, &e.Timestamp,
+			&e.CommitSHA, &e.VCSType, &snapshot, &e.Bookmarked, &e.Note, &e.Review, &e.Timestamp,
+			&e.Summary, &e.ChatSessionID, &e.User,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan edit: %w", err)
 		}
 
+		if f.MatchPath != nil && !f.MatchPath(e.FilePath) {
+			continue
+		}
+
 		// Decompress file snapshot if present
 		if len(snapshot) > 0 {
 			if content, err := decompressData(snapshot); err == nil {
@@ -424,22 +546,25 @@ func (d *DB) GetEditsByWorkspace(workspacePath string, limit int) ([]*Edit, erro
 	return edits, nil
 }
 
-// GetEditsByFile retrieves edits for a specific file
-func (d *DB) GetEditsByFile(filePath string, limit int) ([]*Edit, error) {
+// GetBookmarkedEdits retrieves bookmarked edits, optionally limited to a workspace
+func (d *DB) GetBookmarkedEdits(workspacePath string, limit int) ([]*Edit, error) {
 	query := `
-		SELECT id, session_id, tool_name, file_path,
-		       old_string, new_string, line_num, line_count,
-		       COALESCE(commit_sha, ''), COALESCE(vcs_type, ''),
-		       file_snapshot, timestamp
-		FROM edits
-		WHERE file_path = ?
-		ORDER BY timestamp DESC
+		SELECT e.id, e.session_id, e.tool_name, e.file_path,
+		       e.old_string, e.new_string, e.line_num, e.line_count,
+		       COALESCE(e.commit_sha, ''), COALESCE(e.vcs_type, ''),
+		       ` + snapshotColumn + `, COALESCE(e.bookmarked, 0), COALESCE(e.note, ''), COALESCE(e.review, ''), e.timestamp,
+		       COALESCE(e.summary, ''), COALESCE(e.chat_session_id, ''), COALESCE(e.user_name, '')
+		FROM edits e
+		JOIN sessions s ON e.session_id = s.id
+		WHERE e.bookmarked = 1
+		  AND (? = '' OR s.workspace_path = ?)
+		ORDER BY e.timestamp DESC
 		LIMIT ?
 	`
 
-	rows, err := d.db.Query(query, filePath, limit)
+	rows, err := d.db.Query(query, workspacePath, workspacePath, limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get edits by file: %w", err)
+		return nil, fmt.Errorf("failed to get bookmarked edits: %w", err)
 	}
 	defer rows.Close()
 
@@ -450,7 +575,8 @@ func (d *DB) GetEditsByFile(filePath string, limit int) ([]*Edit, error) {
 		err := rows.Scan(
 			&e.ID, &e.SessionID, &e.ToolName, &e.FilePath,
 			&e.OldString, &e.NewString, &e.LineNum, &e.LineCount,
-			&e.CommitSHA, &e.VCSType, &snapshot, &e.Timestamp,
+			&e.CommitSHA, &e.VCSType, &snapshot, &e.Bookmarked, &e.Note, &e.Review, &e.Timestamp,
+			&e.Summary, &e.ChatSessionID, &e.User,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan edit: %w", err)
@@ -469,16 +595,81 @@ func (d *DB) GetEditsByFile(filePath string, limit int) ([]*Edit, error) {
 	return edits, nil
 }
 
+// FindEditID returns the ID of the workspace edit to filePath recorded closest
+// to the given time (within a minute), for clients that don't know the edit ID
+func (d *DB) FindEditID(workspacePath, filePath string, at time.Time) (int64, error) {
+	query := `
+		SELECT e.id
+		FROM edits e
+		JOIN sessions s ON e.session_id = s.id
+		WHERE s.workspace_path = ? AND e.file_path = ?
+		  AND ABS(julianday(e.timestamp) - julianday(?)) * 86400 <= 60
+		ORDER BY ABS(julianday(e.timestamp) - julianday(?)), e.id DESC
+		LIMIT 1
+	`
+
+	ts := sqlTime(at)
+	var id int64
+	err := d.db.QueryRow(query, workspacePath, filePath, ts, ts).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find edit: %w", err)
+	}
+
+	return id, nil
+}
+
+// SetEditBookmarked sets or clears the bookmark flag on an edit
+func (d *DB) SetEditBookmarked(id int64, bookmarked bool) error {
+	result, err := d.db.Exec("UPDATE edits SET bookmarked = ? WHERE id = ?", bookmarked, id)
+	if err != nil {
+		return fmt.Errorf("failed to set bookmark: %w", err)
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("edit %d not found", id)
+	}
+
+	return nil
+}
+
+// SetEditNote attaches a review note to an edit, or removes it if note is
+// empty
+func (d *DB) SetEditNote(id int64, note string) error {
+	result, err := d.db.Exec("UPDATE edits SET note = NULLIF(?, '') WHERE id = ?", note, id)
+	if err != nil {
+		return fmt.Errorf("failed to set note: %w", err)
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("edit %d not found", id)
+	}
+
+	return nil
+}
+
 // GetSessions retrieves all sessions
 func (d *DB) GetSessions(limit int) ([]*Session, error) {
+	return d.GetSessionsIn(nil, limit)
+}
+
+// GetSessionsIn retrieves the most recently active sessions in any of the
+// given workspaces. An empty list returns sessions for all workspaces.
+func (d *DB) GetSessionsIn(workspacePaths []string, limit int) ([]*Session, error) {
 	query := `
 		SELECT id, workspace_path, workspace_name, branch, commit_sha, started_at, last_activity
 		FROM sessions
-		ORDER BY last_activity DESC
-		LIMIT ?
 	`
+	var args []any
+	if len(workspacePaths) > 0 {
+		query += " WHERE workspace_path IN (" + placeholders(len(workspacePaths)) + ")"
+		for _, w := range workspacePaths {
+			args = append(args, w)
+		}
+	}
+	query += " ORDER BY last_activity DESC LIMIT ?"
+	args = append(args, limit)
 
-	rows, err := d.db.Query(query, limit)
+	rows, err := d.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sessions: %w", err)
 	}
@@ -503,7 +694,7 @@ func (d *DB) GetSessions(limit int) ([]*Session, error) {
 
 // DeleteOldEdits deletes edits older than the specified date
 func (d *DB) DeleteOldEdits(beforeDate time.Time) (int64, error) {
-	result, err := d.db.Exec("DELETE FROM edits WHERE timestamp < ?", beforeDate.Format(time.RFC3339))
+	result, err := d.db.Exec("DELETE FROM edits WHERE timestamp < ? AND COALESCE(bookmarked, 0) = 0 AND note IS NULL", beforeDate.Format(time.RFC3339))
 	if err != nil {
 		return 0, fmt.Errorf("failed to delete old edits: %w", err)
 	}
@@ -528,6 +719,8 @@ func (d *DB) CapEditsPerSession(sessionID int64, maxEdits int) (int64, error) {
 	query := `
 		DELETE FROM edits
 		WHERE session_id = ?
+		AND COALESCE(bookmarked, 0) = 0
+		AND note IS NULL
 		AND id NOT IN (
 			SELECT id FROM edits
 			WHERE session_id = ?
@@ -584,3 +777,672 @@ func (d *DB) Vacuum() error {
 	}
 	return nil
 }
+
+// sqliteTimeFormat matches the format SQLite uses for CURRENT_TIMESTAMP
+const sqliteTimeFormat = "2006-01-02 15:04:05"
+
+// sqlTime formats a time for comparison against CURRENT_TIMESTAMP columns
+func sqlTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeFormat)
+}
+
+// placeholders returns n comma-separated bind parameters for an IN clause
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// Event represents a notable daemon-side event (Ralph start/stop, huge edits, failures, ...)
+type Event struct {
+	ID            int64     `json:"id"`
+	WorkspacePath string    `json:"workspace_path,omitempty"` // Empty for daemon-wide events
+	Kind          string    `json:"kind"`
+	Severity      string    `json:"severity"` // "info", "warning" or "error"
+	Message       string    `json:"message"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// RecordEvent records a timeline event
+func (d *DB) RecordEvent(event *Event) error {
+	query := `
+		INSERT INTO events (workspace_path, kind, severity, message)
+		VALUES (?, ?, ?, ?)
+	`
+
+	_, err := d.db.Exec(query, event.WorkspacePath, event.Kind, event.Severity, event.Message)
+	if err != nil {
+		return fmt.Errorf("failed to record event: %w", err)
+	}
+
+	return nil
+}
+
+// GetEvents retrieves timeline events, newest first.
+// An empty workspacePath returns events for all workspaces. Daemon-wide events
+// (no workspace) are always included. Zero since/until leave that bound open.
+func (d *DB) GetEvents(workspacePath string, since, until time.Time, limit int) ([]*Event, error) {
+	var workspacePaths []string
+	if workspacePath != "" {
+		workspacePaths = []string{workspacePath}
+	}
+	return d.GetEventsIn(workspacePaths, since, until, limit)
+}
+
+// GetEventsIn is GetEvents for any of a set of workspaces, e.g. a group
+func (d *DB) GetEventsIn(workspacePaths []string, since, until time.Time, limit int) ([]*Event, error) {
+	query := `
+		SELECT id, COALESCE(workspace_path, ''), kind, severity, COALESCE(message, ''), timestamp
+		FROM events
+		WHERE (? = '' OR timestamp >= ?)
+		  AND (? = '' OR timestamp <= ?)
+	`
+
+	var sinceStr, untilStr string
+	if !since.IsZero() {
+		sinceStr = sqlTime(since)
+	}
+	if !until.IsZero() {
+		untilStr = sqlTime(until)
+	}
+
+	args := []any{sinceStr, sinceStr, untilStr, untilStr}
+	if len(workspacePaths) > 0 {
+		query += " AND (COALESCE(workspace_path, '') = '' OR workspace_path IN (" + placeholders(len(workspacePaths)) + "))"
+		for _, w := range workspacePaths {
+			args = append(args, w)
+		}
+	}
+	query += " ORDER BY timestamp DESC, id DESC LIMIT ?"
+	args = append(args, limit)
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*Event
+	for rows.Next() {
+		var e Event
+		if err := rows.Scan(&e.ID, &e.WorkspacePath, &e.Kind, &e.Severity, &e.Message, &e.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		events = append(events, &e)
+	}
+
+	return events, nil
+}
+
+// PromptInjection records a prompt being sent to a Claude session
+type PromptInjection struct {
+	ID            int64     `json:"id"`
+	PromptName    string    `json:"prompt_name"`
+	PromptVersion int       `json:"prompt_version"`
+	WorkspacePath string    `json:"workspace_path"`
+	Method        string    `json:"method"`           // "tmux" or "clipboard"
+	Target        string    `json:"target,omitempty"` // tmux pane, empty for clipboard
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// RecordPromptInjection records a prompt injection
+func (d *DB) RecordPromptInjection(inj *PromptInjection) error {
+	query := `
+		INSERT INTO prompt_injections (prompt_name, prompt_version, workspace_path, method, target)
+		VALUES (?, ?, ?, ?, ?)
+	`
+
+	_, err := d.db.Exec(query, inj.PromptName, inj.PromptVersion, inj.WorkspacePath, inj.Method, inj.Target)
+	if err != nil {
+		return fmt.Errorf("failed to record prompt injection: %w", err)
+	}
+
+	return nil
+}
+
+// GetPromptInjections retrieves prompt injections, newest first. An empty
+// promptName returns injections of all prompts.
+func (d *DB) GetPromptInjections(promptName string, limit int) ([]*PromptInjection, error) {
+	query := `
+		SELECT id, prompt_name, COALESCE(prompt_version, 0), COALESCE(workspace_path, ''),
+		       method, COALESCE(target, ''), timestamp
+		FROM prompt_injections
+		WHERE (? = '' OR prompt_name = ?)
+		ORDER BY timestamp DESC, id DESC
+		LIMIT ?
+	`
+
+	rows, err := d.db.Query(query, promptName, promptName, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt injections: %w", err)
+	}
+	defer rows.Close()
+
+	var injections []*PromptInjection
+	for rows.Next() {
+		var inj PromptInjection
+		if err := rows.Scan(&inj.ID, &inj.PromptName, &inj.PromptVersion, &inj.WorkspacePath,
+			&inj.Method, &inj.Target, &inj.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan prompt injection: %w", err)
+		}
+		injections = append(injections, &inj)
+	}
+
+	return injections, nil
+}
+
+// PromptStat summarizes how often a prompt has been sent
+type PromptStat struct {
+	PromptName  string    `json:"prompt_name"`
+	Uses        int       `json:"uses"`
+	Workspaces  int       `json:"workspaces"`   // Distinct workspaces it was sent into
+	LastVersion int       `json:"last_version"` // Version sent most recently
+	LastUsed    time.Time `json:"last_used"`
+}
+
+// GetPromptStats returns usage per injected prompt, most used first
+func (d *DB) GetPromptStats(limit int) ([]*PromptStat, error) {
+	query := `
+		SELECT prompt_name, COUNT(*), COUNT(DISTINCT workspace_path), MAX(timestamp),
+		       (SELECT COALESCE(prompt_version, 0) FROM prompt_injections latest
+		        WHERE latest.prompt_name = pi.prompt_name
+		        ORDER BY timestamp DESC, id DESC LIMIT 1)
+		FROM prompt_injections pi
+		GROUP BY prompt_name
+		ORDER BY COUNT(*) DESC, MAX(timestamp) DESC
+		LIMIT ?
+	`
+
+	rows, err := d.db.Query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt stats: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []*PromptStat
+	for rows.Next() {
+		var stat PromptStat
+		var lastUsed string
+		if err := rows.Scan(&stat.PromptName, &stat.Uses, &stat.Workspaces, &lastUsed, &stat.LastVersion); err != nil {
+			return nil, fmt.Errorf("failed to scan prompt stat: %w", err)
+		}
+		// MAX() loses the column's DATETIME type, so the driver returns text
+		if stat.LastUsed, err = time.Parse(sqliteTimeFormat, lastUsed); err != nil {
+			stat.LastUsed, _ = time.Parse(time.RFC3339Nano, lastUsed)
+		}
+		stats = append(stats, &stat)
+	}
+
+	return stats, nil
+}
+
+// ChatSession is a Claude chat session run from the TUI, kept so it can be
+// resumed with claude --resume
+type ChatSession struct {
+	SessionID     string    `json:"session_id"`
+	WorkspacePath string    `json:"workspace_path"`
+	Purpose       string    `json:"purpose"`
+	StartedAt     time.Time `json:"started_at"`
+	LastActivity  time.Time `json:"last_activity"`
+	Messages      int       `json:"messages"`          // Messages recorded so far
+	Edits         int       `json:"edits"`             // Edits Claude made during the session
+	Preview       string    `json:"preview,omitempty"` // First message sent
+}
+
+// ChatMessage is one message of a chat session's transcript
+type ChatMessage struct {
+	ID        int64     `json:"id"`
+	SessionID string    `json:"session_id"`
+	Role      string    `json:"role"` // "user" or "assistant"
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// RecordChatMessages appends messages to a chat session's transcript,
+// creating the session the first time it's seen
+func (d *DB) RecordChatMessages(session *ChatSession, messages []*ChatMessage) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin chat messages: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO chat_sessions (session_id, workspace_path, purpose)
+		VALUES (?, ?, ?)
+		ON CONFLICT(session_id) DO UPDATE SET last_activity = CURRENT_TIMESTAMP
+	`, session.SessionID, session.WorkspacePath, session.Purpose); err != nil {
+		return fmt.Errorf("failed to record chat session: %w", err)
+	}
+
+	for _, msg := range messages {
+		ts := msg.Timestamp
+		if ts.IsZero() {
+			ts = time.Now()
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO chat_messages (session_id, role, content, timestamp)
+			VALUES (?, ?, ?, ?)
+		`, session.SessionID, msg.Role, msg.Content, sqlTime(ts)); err != nil {
+			return fmt.Errorf("failed to record chat message: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit chat messages: %w", err)
+	}
+	return nil
+}
+
+// GetChatSessions returns the chat sessions of a workspace, most recently
+// active first. An empty workspacePath returns sessions of all workspaces.
+func (d *DB) GetChatSessions(workspacePath string, limit int) ([]*ChatSession, error) {
+	query := `
+		SELECT cs.session_id, COALESCE(cs.workspace_path, ''), COALESCE(cs.purpose, ''),
+		       cs.started_at, cs.last_activity,
+		       (SELECT COUNT(*) FROM chat_messages cm WHERE cm.session_id = cs.session_id),
+		       (SELECT COUNT(*) FROM edits e WHERE e.chat_session_id = cs.session_id),
+		       COALESCE((SELECT content FROM chat_messages cm
+		                 WHERE cm.session_id = cs.session_id AND cm.role = 'user'
+		                 ORDER BY timestamp, id LIMIT 1), '')
+		FROM chat_sessions cs
+		WHERE (? = '' OR cs.workspace_path = ?)
+		ORDER BY cs.last_activity DESC
+		LIMIT ?
+	`
+
+	rows, err := d.db.Query(query, workspacePath, workspacePath, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*ChatSession
+	for rows.Next() {
+		var s ChatSession
+		if err := rows.Scan(&s.SessionID, &s.WorkspacePath, &s.Purpose, &s.StartedAt,
+			&s.LastActivity, &s.Messages, &s.Edits, &s.Preview); err != nil {
+			return nil, fmt.Errorf("failed to scan chat session: %w", err)
+		}
+		sessions = append(sessions, &s)
+	}
+
+	return sessions, nil
+}
+
+// GetChatMessages returns a chat session's transcript, oldest first. Batches
+// of messages may arrive out of order, so they're sorted by their own time.
+func (d *DB) GetChatMessages(sessionID string) ([]*ChatMessage, error) {
+	rows, err := d.db.Query(`
+		SELECT id, session_id, role, content, timestamp
+		FROM chat_messages
+		WHERE session_id = ?
+		ORDER BY timestamp, id
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []*ChatMessage
+	for rows.Next() {
+		var msg ChatMessage
+		if err := rows.Scan(&msg.ID, &msg.SessionID, &msg.Role, &msg.Content, &msg.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan chat message: %w", err)
+		}
+		messages = append(messages, &msg)
+	}
+
+	return messages, nil
+}
+
+// GetChatEdits returns the edits Claude made during a chat session, oldest
+// first. File snapshots are left out; the TUI only lists them.
+func (d *DB) GetChatEdits(sessionID string) ([]*Edit, error) {
+	rows, err := d.db.Query(`
+		SELECT id, session_id, tool_name, file_path,
+		       COALESCE(old_string, ''), COALESCE(new_string, ''),
+		       COALESCE(line_num, 0), COALESCE(line_count, 0),
+		       COALESCE(commit_sha, ''), COALESCE(vcs_type, ''),
+		       COALESCE(bookmarked, 0), COALESCE(note, ''), COALESCE(review, ''), timestamp, chat_session_id,
+		       COALESCE(user_name, '')
+		FROM edits
+		WHERE chat_session_id = ?
+		ORDER BY timestamp, id
+	`, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get chat edits: %w", err)
+	}
+	defer rows.Close()
+
+	var edits []*Edit
+	for rows.Next() {
+		var e Edit
+		if err := rows.Scan(
+			&e.ID, &e.SessionID, &e.ToolName, &e.FilePath,
+			&e.OldString, &e.NewString, &e.LineNum, &e.LineCount,
+			&e.CommitSHA, &e.VCSType, &e.Bookmarked, &e.Note, &e.Review, &e.Timestamp, &e.ChatSessionID,
+			&e.User,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan edit: %w", err)
+		}
+		edits = append(edits, &e)
+	}
+
+	return edits, nil
+}
+
+// HasChatEdits reports whether any edit was recorded for a chat session
+func (d *DB) HasChatEdits(sessionID string) (bool, error) {
+	var exists bool
+	err := d.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM edits WHERE chat_session_id = ?)`, sessionID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check chat edits: %w", err)
+	}
+	return exists, nil
+}
+
+// GetRalphEdits returns a workspace's edits made during Ralph loop
+// iterations between two times, ordered by iteration and then by time. Zero
+// since/until leave that bound open. File snapshots are left out.
+func (d *DB) GetRalphEdits(workspacePath string, since, until time.Time) ([]*Edit, error) {
+	var sinceStr, untilStr string
+	if !since.IsZero() {
+		sinceStr = sqlTime(since)
+	}
+	if !until.IsZero() {
+		untilStr = sqlTime(until)
+	}
+
+	rows, err := d.db.Query(`
+		SELECT e.id, e.session_id, e.tool_name, e.file_path,
+		       COALESCE(e.old_string, ''), COALESCE(e.new_string, ''),
+		       COALESCE(e.line_num, 0), COALESCE(e.line_count, 0),
+		       COALESCE(e.commit_sha, ''), COALESCE(e.vcs_type, ''),
+		       COALESCE(e.bookmarked, 0), COALESCE(e.note, ''), COALESCE(e.review, ''), e.timestamp, e.ralph_iteration
+		FROM edits e
+		JOIN sessions s ON e.session_id = s.id
+		WHERE s.workspace_path = ?
+		  AND e.ralph_iteration IS NOT NULL
+		  AND (? = '' OR e.timestamp >= ?)
+		  AND (? = '' OR e.timestamp <= ?)
+		ORDER BY e.ralph_iteration, e.timestamp, e.id
+	`, workspacePath, sinceStr, sinceStr, untilStr, untilStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ralph edits: %w", err)
+	}
+	defer rows.Close()
+
+	var edits []*Edit
+	for rows.Next() {
+		var e Edit
+		if err := rows.Scan(
+			&e.ID, &e.SessionID, &e.ToolName, &e.FilePath,
+			&e.OldString, &e.NewString, &e.LineNum, &e.LineCount,
+			&e.CommitSHA, &e.VCSType, &e.Bookmarked, &e.Note, &e.Review, &e.Timestamp, &e.RalphIteration,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan edit: %w", err)
+		}
+		edits = append(edits, &e)
+	}
+
+	return edits, nil
+}
+
+// RalphLoop is a Ralph loop that has ended, as last seen by the TUI
+type RalphLoop struct {
+	ID            int64     `json:"id"`
+	WorkspacePath string    `json:"workspace_path"`
+	Prompt        string    `json:"prompt"`
+	Promise       string    `json:"promise,omitempty"`
+	Iterations    int       `json:"iterations"`     // Iterations used
+	MaxIterations int       `json:"max_iterations"` // 0 = no limit
+	Outcome       string    `json:"outcome"`        // "promise_met", "max_iterations", "cancelled" or "stopped"
+	StartedAt     time.Time `json:"started_at"`
+	EndedAt       time.Time `json:"ended_at"`
+	Edits         int       `json:"edits"` // Edits made during the loop's iterations
+}
+
+// RecordRalphLoop stores a loop that has ended. A zero EndedAt means now.
+func (d *DB) RecordRalphLoop(loop *RalphLoop) error {
+	ended := loop.EndedAt
+	if ended.IsZero() {
+		ended = time.Now()
+	}
+	// A loop without a start time covers the edits up to its end
+	started := ended
+	if !loop.StartedAt.IsZero() {
+		started = loop.StartedAt
+	}
+
+	if _, err := d.db.Exec(`
+		INSERT INTO ralph_loops (workspace_path, prompt, promise, iterations, max_iterations, outcome, started_at, ended_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, loop.WorkspacePath, loop.Prompt, loop.Promise, loop.Iterations, loop.MaxIterations,
+		loop.Outcome, sqlTime(started), sqlTime(ended)); err != nil {
+		return fmt.Errorf("failed to record ralph loop: %w", err)
+	}
+	return nil
+}
+
+// GetRalphLoops returns ended Ralph loops, most recent first, with the
+// number of edits made during each. An empty workspacePath returns loops of
+// all workspaces.
+func (d *DB) GetRalphLoops(workspacePath string, limit int) ([]*RalphLoop, error) {
+	rows, err := d.db.Query(`
+		SELECT rl.id, rl.workspace_path, COALESCE(rl.prompt, ''), COALESCE(rl.promise, ''),
+		       COALESCE(rl.iterations, 0), COALESCE(rl.max_iterations, 0), COALESCE(rl.outcome, ''),
+		       rl.started_at, rl.ended_at,
+		       (SELECT COUNT(*) FROM edits e JOIN sessions s ON e.session_id = s.id
+		        WHERE s.workspace_path = rl.workspace_path
+		          AND e.ralph_iteration IS NOT NULL
+		          AND e.timestamp BETWEEN rl.started_at AND rl.ended_at)
+		FROM ralph_loops rl
+		WHERE (? = '' OR rl.workspace_path = ?)
+		ORDER BY rl.ended_at DESC, rl.id DESC
+		LIMIT ?
+	`, workspacePath, workspacePath, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ralph loops: %w", err)
+	}
+	defer rows.Close()
+
+	var loops []*RalphLoop
+	for rows.Next() {
+		var l RalphLoop
+		if err := rows.Scan(&l.ID, &l.WorkspacePath, &l.Prompt, &l.Promise, &l.Iterations,
+			&l.MaxIterations, &l.Outcome, &l.StartedAt, &l.EndedAt, &l.Edits); err != nil {
+			return nil, fmt.Errorf("failed to scan ralph loop: %w", err)
+		}
+		loops = append(loops, &l)
+	}
+
+	return loops, nil
+}
+
+// SetWorkspacePlan associates a workspace with the plan it's working from.
+// An empty planPath removes the association.
+func (d *DB) SetWorkspacePlan(workspacePath, planPath string) error {
+	if planPath == "" {
+		if _, err := d.db.Exec("DELETE FROM workspace_plans WHERE workspace_path = ?", workspacePath); err != nil {
+			return fmt.Errorf("failed to clear workspace plan: %w", err)
+		}
+		return nil
+	}
+	if _, err := d.db.Exec(`
+		INSERT INTO workspace_plans (workspace_path, plan_path, updated_at)
+		VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(workspace_path) DO UPDATE SET plan_path = excluded.plan_path, updated_at = excluded.updated_at
+	`, workspacePath, planPath); err != nil {
+		return fmt.Errorf("failed to set workspace plan: %w", err)
+	}
+	return nil
+}
+
+// GetWorkspacePlan returns the plan a workspace is working from, or "" if
+// none was chosen
+func (d *DB) GetWorkspacePlan(workspacePath string) (string, error) {
+	var planPath string
+	err := d.db.QueryRow("SELECT plan_path FROM workspace_plans WHERE workspace_path = ?", workspacePath).Scan(&planPath)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get workspace plan: %w", err)
+	}
+	return planPath, nil
+}
+
+// SetSessionName names a Claude session in a workspace's history. An empty
+// name removes it.
+func (d *DB) SetSessionName(workspacePath, sessionID, name string) error {
+	if name == "" {
+		if _, err := d.db.Exec("DELETE FROM session_names WHERE session_id = ?", sessionID); err != nil {
+			return fmt.Errorf("failed to clear session name: %w", err)
+		}
+		return nil
+	}
+	if _, err := d.db.Exec(`
+		INSERT INTO session_names (session_id, workspace_path, name, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(session_id) DO UPDATE SET name = excluded.name, updated_at = excluded.updated_at
+	`, sessionID, workspacePath, name); err != nil {
+		return fmt.Errorf("failed to set session name: %w", err)
+	}
+	return nil
+}
+
+// GetSessionNames returns the names given to a workspace's Claude sessions,
+// keyed by session ID
+func (d *DB) GetSessionNames(workspacePath string) (map[string]string, error) {
+	rows, err := d.db.Query("SELECT session_id, name FROM session_names WHERE workspace_path = ?", workspacePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session names: %w", err)
+	}
+	defer rows.Close()
+
+	names := make(map[string]string)
+	for rows.Next() {
+		var sessionID, name string
+		if err := rows.Scan(&sessionID, &name); err != nil {
+			return nil, fmt.Errorf("failed to scan session name: %w", err)
+		}
+		names[sessionID] = name
+	}
+	return names, rows.Err()
+}
+
+// APIToken is a scoped daemon API token. Only its hash is stored.
+type APIToken struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Scope      string     `json:"scope"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreateToken stores a new token hash and returns its ID
+func (d *DB) CreateToken(name, scope, tokenHash string) (int64, error) {
+	result, err := d.db.Exec(`
+		INSERT INTO api_tokens (name, scope, token_hash)
+		VALUES (?, ?, ?)
+	`, name, scope, tokenHash)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create token: %w", err)
+	}
+
+	return result.LastInsertId()
+}
+
+const tokenByHashQuery = `
+	SELECT id, name, scope, created_at
+	FROM api_tokens
+	WHERE token_hash = ? AND revoked_at IS NULL
+`
+
+// GetTokenByHash returns the active (unrevoked) token with the given hash,
+// or nil if there is none
+func (d *DB) GetTokenByHash(tokenHash string) (*APIToken, error) {
+	var t APIToken
+	err := d.stmts.tokenByHash.QueryRow(tokenHash).Scan(&t.ID, &t.Name, &t.Scope, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token: %w", err)
+	}
+
+	return &t, nil
+}
+
+const touchTokenQuery = "UPDATE api_tokens SET last_used_at = CURRENT_TIMESTAMP WHERE id = ?"
+
+// TouchToken records that a token was just used
+func (d *DB) TouchToken(id int64) error {
+	if _, err := d.stmts.touchToken.Exec(id); err != nil {
+		return fmt.Errorf("failed to touch token: %w", err)
+	}
+	return nil
+}
+
+// ListTokens returns all tokens, including revoked ones, oldest first
+func (d *DB) ListTokens() ([]*APIToken, error) {
+	rows, err := d.db.Query(`
+		SELECT id, name, scope, created_at, last_used_at, revoked_at
+		FROM api_tokens
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*APIToken
+	for rows.Next() {
+		var t APIToken
+		var lastUsed, revoked sql.NullTime
+		if err := rows.Scan(&t.ID, &t.Name, &t.Scope, &t.CreatedAt, &lastUsed, &revoked); err != nil {
+			return nil, fmt.Errorf("failed to scan token: %w", err)
+		}
+		if lastUsed.Valid {
+			t.LastUsedAt = &lastUsed.Time
+		}
+		if revoked.Valid {
+			t.RevokedAt = &revoked.Time
+		}
+		tokens = append(tokens, &t)
+	}
+
+	return tokens, nil
+}
+
+// CountActiveTokens returns the number of unrevoked tokens with the given scope
+func (d *DB) CountActiveTokens(scope string) (int, error) {
+	var count int
+	err := d.db.QueryRow(`
+		SELECT COUNT(*) FROM api_tokens WHERE scope = ? AND revoked_at IS NULL
+	`, scope).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count tokens: %w", err)
+	}
+	return count, nil
+}
+
+// RevokeToken revokes an active token by name or numeric ID
+func (d *DB) RevokeToken(nameOrID string) error {
+	result, err := d.db.Exec(`
+		UPDATE api_tokens SET revoked_at = CURRENT_TIMESTAMP
+		WHERE (name = ? OR CAST(id AS TEXT) = ?) AND revoked_at IS NULL
+	`, nameOrID, nameOrID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("no active token %q", nameOrID)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// Ingest benchmarks report edits/s; the daemon should sustain 1k edits/s
+// with its default batching (100 edits per transaction), e.g.
+//
+//	go test -run '^$' -bench Ingest ./internal/database
+
+func openBenchDB(b *testing.B) (*DB, int64) {
+	b.Helper()
+	db, err := Open(&Config{Path: filepath.Join(b.TempDir(), "bench.db"), PoolSize: 4, WALCheckpointPages: 1000})
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+	sessionID, err := db.UpsertSession("/src/api", "api", "main", "abc123")
+	if err != nil {
+		b.Fatal(err)
+	}
+	return db, sessionID
+}
+
+func benchEdit(sessionID int64, i int) *Edit {
+	return &Edit{
+		SessionID: sessionID,
+		ToolName:  "MultiEdit",
+		FilePath:  fmt.Sprintf("/src/api/internal/f%d.go", i%50),
+		OldString: "return nil",
+		NewString: fmt.Sprintf("return fmt.Errorf(\"step %d failed\")", i),
+		LineNum:   i % 400,
+		LineCount: 1,
+	}
+}
+
+func reportEditRate(b *testing.B) {
+	b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "edits/s")
+}
+
+// BenchmarkIngestSingle records each edit in its own transaction, as the
+// daemon does with batching turned off
+func BenchmarkIngestSingle(b *testing.B) {
+	db, sessionID := openBenchDB(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := db.RecordEdit(benchEdit(sessionID, i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	reportEditRate(b)
+}
+
+// BenchmarkIngestBatched records edits 100 to a transaction
+func BenchmarkIngestBatched(b *testing.B) {
+	db, sessionID := openBenchDB(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i += 100 {
+		batch := make([]*Edit, 0, 100)
+		for j := i; j < min(i+100, b.N); j++ {
+			batch = append(batch, benchEdit(sessionID, j))
+		}
+		if err := db.RecordEdits(batch); err != nil {
+			b.Fatal(err)
+		}
+	}
+	reportEditRate(b)
+}
+
+// BenchmarkIngestWithReaders records batches while TUIs and CLI queries
+// read recent edits from the pool's other connections
+func BenchmarkIngestWithReaders(b *testing.B) {
+	db, sessionID := openBenchDB(b)
+	for i := 0; i < 500; i++ {
+		if err := db.RecordEdit(benchEdit(sessionID, i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	stop := make(chan struct{})
+	var reads atomic.Int64
+	var wg sync.WaitGroup
+	for r := 0; r < 3; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if _, err := db.GetEditsByWorkspace("/src/api", 100); err != nil {
+					b.Error(err)
+					return
+				}
+				reads.Add(1)
+			}
+		}()
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i += 100 {
+		batch := make([]*Edit, 0, 100)
+		for j := i; j < min(i+100, b.N); j++ {
+			batch = append(batch, benchEdit(sessionID, j))
+		}
+		if err := db.RecordEdits(batch); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.StopTimer()
+	close(stop)
+	wg.Wait()
+	reportEditRate(b)
+	b.ReportMetric(float64(reads.Load())/b.Elapsed().Seconds(), "reads/s")
+}
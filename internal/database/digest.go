@@ -0,0 +1,190 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// digestTopFiles is how many of each workspace's most edited files a
+// digest lists
+const digestTopFiles = 5
+
+// Digest summarizes the activity of a period, for posting to a team channel
+type Digest struct {
+	Since      time.Time          `json:"since"`
+	Until      time.Time          `json:"until"`
+	Workspaces []*WorkspaceDigest `json:"workspaces"` // Most edits first
+	Prompts    []DigestCount      `json:"prompts"`    // Prompts sent, most used first
+}
+
+// WorkspaceDigest is one workspace's activity in a digest
+type WorkspaceDigest struct {
+	WorkspacePath string        `json:"workspace_path"`
+	WorkspaceName string        `json:"workspace_name"`
+	Edits         int           `json:"edits"`
+	Files         int           `json:"files"`    // Distinct files edited
+	Added         int           `json:"added"`    // Lines of new text, as the history's +N
+	Removed       int           `json:"removed"`  // Lines of old text, as the history's -M
+	Sessions      int           `json:"sessions"` // Claude sessions that made edits
+	RalphLoops    int           `json:"ralph_loops"`
+	TopFiles      []DigestCount `json:"top_files"` // Most edited files
+}
+
+// DigestCount is how many times something happened in a digest's period
+type DigestCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Total adds up the digest's workspaces
+func (d *Digest) Total() WorkspaceDigest {
+	var total WorkspaceDigest
+	for _, w := range d.Workspaces {
+		total.Edits += w.Edits
+		total.Files += w.Files
+		total.Added += w.Added
+		total.Removed += w.Removed
+		total.Sessions += w.Sessions
+		total.RalphLoops += w.RalphLoops
+	}
+	return total
+}
+
+// lineCount counts a text column's lines the way the history's +N/-M does:
+// a trailing newline doesn't start another line
+func lineCount(column string) string {
+	return fmt.Sprintf(`CASE WHEN COALESCE(%[1]s, '') = '' THEN 0
+		ELSE length(%[1]s) - length(replace(%[1]s, char(10), '')) + (substr(%[1]s, -1) != char(10)) END`, column)
+}
+
+// GetDigest summarizes the activity between two times, optionally in a set
+// of workspaces: edits, files and lines per workspace, the Claude sessions
+// and Ralph loops behind them, and the prompts sent. A user limits the
+// edits counted to theirs.
+func (d *DB) GetDigest(workspaces []string, user string, since, until time.Time) (*Digest, error) {
+	digest := &Digest{Since: since, Until: until}
+	byPath := make(map[string]*WorkspaceDigest)
+
+	// Edits whose hook didn't pass a Claude session count by hook session
+	scope, args := digestScope("s.workspace_path", workspaces, since, until, "e.timestamp")
+	if user != "" {
+		scope += " AND e.user_name = ?"
+		args = append(args, user)
+	}
+	rows, err := d.db.Query(`
+		SELECT s.workspace_path, MAX(COALESCE(s.workspace_name, '')), COUNT(*), COUNT(DISTINCT e.file_path),
+		       COALESCE(SUM(`+lineCount("e.new_string")+`), 0), COALESCE(SUM(`+lineCount("e.old_string")+`), 0),
+		       COUNT(DISTINCT COALESCE(NULLIF(e.chat_session_id, ''), 'session:' || e.session_id))
+		FROM edits e
+		JOIN sessions s ON e.session_id = s.id
+		WHERE `+scope+`
+		GROUP BY s.workspace_path
+		ORDER BY COUNT(*) DESC, s.workspace_path
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize edits: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var w WorkspaceDigest
+		if err := rows.Scan(&w.WorkspacePath, &w.WorkspaceName, &w.Edits, &w.Files,
+			&w.Added, &w.Removed, &w.Sessions); err != nil {
+			return nil, fmt.Errorf("failed to scan digest: %w", err)
+		}
+		digest.Workspaces = append(digest.Workspaces, &w)
+		byPath[w.WorkspacePath] = &w
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	files, err := d.db.Query(`
+		SELECT s.workspace_path, e.file_path, COUNT(*)
+		FROM edits e
+		JOIN sessions s ON e.session_id = s.id
+		WHERE `+scope+`
+		GROUP BY s.workspace_path, e.file_path
+		ORDER BY COUNT(*) DESC, MAX(e.timestamp) DESC
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize files: %w", err)
+	}
+	defer files.Close()
+	for files.Next() {
+		var path string
+		var file DigestCount
+		if err := files.Scan(&path, &file.Name, &file.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan digest file: %w", err)
+		}
+		if w := byPath[path]; w != nil && len(w.TopFiles) < digestTopFiles {
+			w.TopFiles = append(w.TopFiles, file)
+		}
+	}
+	if err := files.Err(); err != nil {
+		return nil, err
+	}
+
+	// Loops that ended in the period, including in workspaces without edits
+	scope, args = digestScope("workspace_path", workspaces, since, until, "ended_at")
+	loops, err := d.db.Query(`
+		SELECT workspace_path, COUNT(*) FROM ralph_loops
+		WHERE `+scope+`
+		GROUP BY workspace_path
+		ORDER BY workspace_path
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize ralph loops: %w", err)
+	}
+	defer loops.Close()
+	for loops.Next() {
+		var path string
+		var count int
+		if err := loops.Scan(&path, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan digest loops: %w", err)
+		}
+		w := byPath[path]
+		if w == nil {
+			w = &WorkspaceDigest{WorkspacePath: path}
+			digest.Workspaces = append(digest.Workspaces, w)
+			byPath[path] = w
+		}
+		w.RalphLoops = count
+	}
+	if err := loops.Err(); err != nil {
+		return nil, err
+	}
+
+	scope, args = digestScope("workspace_path", workspaces, since, until, "timestamp")
+	prompts, err := d.db.Query(`
+		SELECT prompt_name, COUNT(*) FROM prompt_injections
+		WHERE `+scope+`
+		GROUP BY prompt_name
+		ORDER BY COUNT(*) DESC, prompt_name
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize prompts: %w", err)
+	}
+	defer prompts.Close()
+	for prompts.Next() {
+		var p DigestCount
+		if err := prompts.Scan(&p.Name, &p.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan digest prompt: %w", err)
+		}
+		digest.Prompts = append(digest.Prompts, p)
+	}
+	return digest, prompts.Err()
+}
+
+// digestScope returns the WHERE clause limiting a digest query to its
+// period and workspaces, with its arguments
+func digestScope(workspaceColumn string, workspaces []string, since, until time.Time, timeColumn string) (string, []any) {
+	clause := timeColumn + " >= ? AND " + timeColumn + " < ?"
+	args := []any{sqlTime(since), sqlTime(until)}
+	if len(workspaces) > 0 {
+		clause += " AND " + workspaceColumn + " IN (" + placeholders(len(workspaces)) + ")"
+		for _, w := range workspaces {
+			args = append(args, w)
+		}
+	}
+	return clause, args
+}
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGetDigest(t *testing.T) {
+	db, err := Open(&Config{Path: filepath.Join(t.TempDir(), "digest.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	api, err := db.UpsertSession("/src/api", "api", "main", "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	web, err := db.UpsertSession("/src/web", "web", "main", "def456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []*Edit{
+		{SessionID: api, ToolName: "Edit", FilePath: "/src/api/a.go", OldString: "a\nb\n", NewString: "a\nb\nc", ChatSessionID: "s1"},
+		{SessionID: api, ToolName: "Edit", FilePath: "/src/api/a.go", OldString: "x", NewString: "y", ChatSessionID: "s2"},
+		{SessionID: api, ToolName: "Write", FilePath: "/src/api/b.go", NewString: "package b\n", ChatSessionID: "s2"},
+		{SessionID: web, ToolName: "Edit", FilePath: "/src/web/app.ts", OldString: "1", NewString: "2"},
+	} {
+		if err := db.RecordEdit(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.RecordPromptInjection(&PromptInjection{PromptName: "review", WorkspacePath: "/src/api", Method: "tmux"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RecordRalphLoop(&RalphLoop{WorkspacePath: "/src/infra", Outcome: "promise_met", Iterations: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	digest, err := db.GetDigest(nil, "", now.Add(-time.Hour), now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(digest.Workspaces) != 3 {
+		t.Fatalf("expected api, web and the loop's infra, got %d workspaces", len(digest.Workspaces))
+	}
+	w := digest.Workspaces[0]
+	if w.WorkspaceName != "api" || w.Edits != 3 || w.Files != 2 || w.Added != 5 || w.Removed != 3 || w.Sessions != 2 {
+		t.Errorf("unexpected api digest %+v", w)
+	}
+	if len(w.TopFiles) != 2 || w.TopFiles[0] != (DigestCount{"/src/api/a.go", 2}) {
+		t.Errorf("unexpected top files %+v", w.TopFiles)
+	}
+	// Edits without a Claude session count by hook session
+	if web := digest.Workspaces[1]; web.Sessions != 1 {
+		t.Errorf("expected the web edit's hook session counted, got %+v", web)
+	}
+	if infra := digest.Workspaces[2]; infra.WorkspacePath != "/src/infra" || infra.RalphLoops != 1 || infra.Edits != 0 {
+		t.Errorf("unexpected infra digest %+v", infra)
+	}
+	if len(digest.Prompts) != 1 || digest.Prompts[0] != (DigestCount{"review", 1}) {
+		t.Errorf("unexpected prompts %+v", digest.Prompts)
+	}
+	if total := digest.Total(); total.Edits != 4 || total.RalphLoops != 1 {
+		t.Errorf("unexpected total %+v", total)
+	}
+
+	// Scoped to a workspace, and to a period before everything
+	scoped, err := db.GetDigest([]string{"/src/web"}, "", now.Add(-time.Hour), now.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scoped.Workspaces) != 1 || len(scoped.Prompts) != 0 {
+		t.Errorf("expected only web's activity, got %+v", scoped)
+	}
+	empty, err := db.GetDigest(nil, "", now.Add(-48*time.Hour), now.Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(empty.Workspaces) != 0 || len(empty.Prompts) != 0 {
+		t.Errorf("expected no activity a day ago, got %+v", empty)
+	}
+}
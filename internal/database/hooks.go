@@ -0,0 +1,91 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// Claude Code hook events recorded besides edits, by hook_event_name
+const (
+	HookSessionStart = "SessionStart" // A Claude session started, resumed or was cleared
+	HookStop         = "Stop"         // Claude finished responding
+	HookSubagentStop = "SubagentStop" // A subagent Claude started finished
+	HookPreToolUse   = "PreToolUse"   // Claude is about to run a tool, e.g. edit a file
+)
+
+// HookTypes are the hook events the daemon records
+var HookTypes = []string{HookSessionStart, HookStop, HookSubagentStop, HookPreToolUse}
+
+// HookEvent is a Claude Code hook event other than an edit, marking where
+// Claude sessions and their turns begin and end
+type HookEvent struct {
+	ID            int64     `json:"id"`
+	SessionID     int64     `json:"session_id"`
+	WorkspacePath string    `json:"workspace_path"`
+	ChatSessionID string    `json:"chat_session_id"`
+	Type          string    `json:"type"`                // One of HookTypes
+	Source        string    `json:"source,omitempty"`    // For SessionStart: "startup", "resume", "clear" or "compact"
+	ToolName      string    `json:"tool_name,omitempty"` // For PreToolUse
+	FilePath      string    `json:"file_path,omitempty"` // For PreToolUse: the file the tool is about to change
+	User          string    `json:"user,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// RecordHookEvent records a hook event in a daemon session
+func (d *DB) RecordHookEvent(e *HookEvent) error {
+	_, err := d.db.Exec(`
+		INSERT INTO hooks (session_id, hook_type, tool_name, chat_session_id, file_path, source, user_name)
+		VALUES (?, ?, NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''), NULLIF(?, ''))
+	`, e.SessionID, e.Type, e.ToolName, e.ChatSessionID, e.FilePath, e.Source, e.User)
+	if err != nil {
+		return fmt.Errorf("failed to record hook event: %w", err)
+	}
+	return nil
+}
+
+// GetHookEvents returns a workspace's hook events since a time, oldest
+// first. A non-empty user only returns theirs.
+func (d *DB) GetHookEvents(workspacePath, user string, since time.Time, limit int) ([]*HookEvent, error) {
+	var sinceStr string
+	if !since.IsZero() {
+		sinceStr = sqlTime(since)
+	}
+	rows, err := d.db.Query(`
+		SELECT * FROM (
+			SELECT h.id, h.session_id, s.workspace_path, COALESCE(h.chat_session_id, ''), h.hook_type,
+			       COALESCE(h.source, ''), COALESCE(h.tool_name, ''), COALESCE(h.file_path, ''),
+			       COALESCE(h.user_name, ''), h.timestamp
+			FROM hooks h
+			JOIN sessions s ON s.id = h.session_id
+			WHERE s.workspace_path = ?
+			  AND (? = '' OR h.timestamp >= ?)
+			  AND (? = '' OR h.user_name = ?)
+			ORDER BY h.timestamp DESC, h.id DESC
+			LIMIT ?
+		) ORDER BY timestamp, id
+	`, workspacePath, sinceStr, sinceStr, user, user, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get hook events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*HookEvent
+	for rows.Next() {
+		var e HookEvent
+		if err := rows.Scan(&e.ID, &e.SessionID, &e.WorkspacePath, &e.ChatSessionID, &e.Type,
+			&e.Source, &e.ToolName, &e.FilePath, &e.User, &e.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan hook event: %w", err)
+		}
+		events = append(events, &e)
+	}
+	return events, rows.Err()
+}
+
+// DeleteOldHookEvents deletes hook events older than the specified date
+func (d *DB) DeleteOldHookEvents(beforeDate time.Time) (int64, error) {
+	result, err := d.db.Exec("DELETE FROM hooks WHERE timestamp < ?", sqlTime(beforeDate))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old hook events: %w", err)
+	}
+	return result.RowsAffected()
+}
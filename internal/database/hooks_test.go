@@ -0,0 +1,58 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestHookEvents(t *testing.T) {
+	db, err := Open(&Config{Path: filepath.Join(t.TempDir(), "hooks.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	api, err := db.UpsertSession("/src/api", "api", "main", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	web, err := db.UpsertSession("/src/web", "web", "main", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []*HookEvent{
+		{SessionID: api, ChatSessionID: "s1", Type: HookSessionStart, Source: "startup", User: "alice"},
+		{SessionID: api, ChatSessionID: "s1", Type: HookPreToolUse, ToolName: "Edit", FilePath: "/src/api/main.go", User: "alice"},
+		{SessionID: api, ChatSessionID: "s1", Type: HookStop, User: "alice"},
+		{SessionID: api, ChatSessionID: "s2", Type: HookSubagentStop, User: "bob"},
+		{SessionID: web, ChatSessionID: "s3", Type: HookStop, User: "alice"},
+	} {
+		if err := db.RecordHookEvent(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := db.GetHookEvents("/src/api", "", time.Time{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 4 || events[0].Type != HookSessionStart || events[0].Source != "startup" {
+		t.Fatalf("expected /src/api's four events oldest first, got %+v", events)
+	}
+	if e := events[1]; e.ToolName != "Edit" || e.FilePath != "/src/api/main.go" || e.WorkspacePath != "/src/api" {
+		t.Errorf("unexpected PreToolUse event %+v", e)
+	}
+
+	// The limit keeps the newest
+	if events, _ := db.GetHookEvents("/src/api", "", time.Time{}, 1); len(events) != 1 || events[0].Type != HookSubagentStop {
+		t.Errorf("expected only the newest event, got %+v", events)
+	}
+	if events, _ := db.GetHookEvents("/src/api", "bob", time.Time{}, 10); len(events) != 1 || events[0].ChatSessionID != "s2" {
+		t.Errorf("expected bob's one event, got %+v", events)
+	}
+
+	if deleted, err := db.DeleteOldHookEvents(time.Now().Add(time.Minute)); err != nil || deleted != 5 {
+		t.Errorf("expected all five events deleted, got %d (%v)", deleted, err)
+	}
+}
@@ -0,0 +1,357 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// Migration is one versioned schema change, read from
+// migrations/NNNN_name.up.sql and an optional NNNN_name.down.sql
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string // Empty if the migration can't be reverted
+}
+
+// MigrationStatus reports whether a migration has been applied
+type MigrationStatus struct {
+	Version    int
+	Name       string
+	AppliedAt  *time.Time // Nil if not applied
+	Reversible bool
+}
+
+// loadMigrations reads the migrations in a directory of fsys, oldest first
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		prefix, label, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s isn't named NNNN_name.%s.sql", name, direction)
+		}
+		data, err := fs.ReadFile(fsys, dir+"/"+name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		} else if m.Name != label {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, m.Name, label)
+		}
+		if direction == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d (%s) has no up.sql", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies and reverts schema migrations, recording them in the
+// schema_version table
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	owned      bool // Whether Close closes db
+}
+
+// NewMigrator opens the database at cfg.Path without migrating it
+func NewMigrator(cfg *Config) (*Migrator, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+	m, err := newMigrator(openPool(cfg))
+	if err != nil {
+		return nil, err
+	}
+	m.owned = true
+	return m, nil
+}
+
+// newMigrator migrates db with the embedded migrations
+func newMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := loadMigrations(migrationsFS, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Close closes the database if the migrator opened it
+func (m *Migrator) Close() error {
+	if m.owned {
+		return m.db.Close()
+	}
+	return nil
+}
+
+// Latest returns the newest migration's version
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Current returns the newest applied migration's version, 0 if none are
+func (m *Migrator) Current() (int, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return 0, err
+	}
+	current := 0
+	for version := range applied {
+		current = max(current, version)
+	}
+	return current, nil
+}
+
+// Status lists every known migration and when it was applied
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		status := MigrationStatus{Version: mig.Version, Name: mig.Name, Reversible: mig.Down != ""}
+		if at, ok := applied[mig.Version]; ok {
+			status.AppliedAt = &at
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// applied returns when each applied migration was applied
+func (m *Migrator) applied() (map[int]time.Time, error) {
+	applied := make(map[int]time.Time)
+	exists, err := m.tableExists("schema_version")
+	if err != nil || !exists {
+		return applied, err
+	}
+
+	rows, err := m.db.Query("SELECT version, applied_at FROM schema_version")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, fmt.Errorf("failed to scan schema version: %w", err)
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+func (m *Migrator) tableExists(name string) (bool, error) {
+	var n int
+	err := m.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("failed to look up table %s: %w", name, err)
+	}
+	return n > 0, nil
+}
+
+// Up applies the migrations after the current version up to target, or all
+// of them if target is 0, and returns them. A dry run only returns them.
+func (m *Migrator) Up(target int, dryRun bool) ([]Migration, error) {
+	latest := m.Latest()
+	if target == 0 {
+		target = latest
+	}
+	if target > latest {
+		return nil, fmt.Errorf("no migration %d; the latest is %d", target, latest)
+	}
+
+	current, err := m.Current()
+	if err != nil {
+		return nil, err
+	}
+	if current > latest {
+		return nil, fmt.Errorf("database schema version %d is newer than this build supports (%d); upgrade claude-mon", current, latest)
+	}
+
+	var pending []Migration
+	for _, mig := range m.migrations {
+		if mig.Version > current && mig.Version <= target {
+			pending = append(pending, mig)
+		}
+	}
+	if dryRun || len(pending) == 0 {
+		return pending, nil
+	}
+
+	if _, err := m.db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	)`); err != nil {
+		return nil, fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+
+	// Databases from before versioned migrations have tables the baseline
+	// only creates if missing, so bring their columns up to date first
+	if current == 0 {
+		legacy, err := m.tableExists("edits")
+		if err != nil {
+			return nil, err
+		}
+		if legacy {
+			if err := addLegacyColumns(m.db); err != nil {
+				return nil, fmt.Errorf("failed to upgrade legacy schema: %w", err)
+			}
+		}
+	}
+
+	for _, mig := range pending {
+		if err := m.apply(mig.Version, mig.Name, mig.Up, true); err != nil {
+			return nil, err
+		}
+	}
+	return pending, nil
+}
+
+// Down reverts the applied migrations after target, newest first, and
+// returns them. A dry run only returns them.
+func (m *Migrator) Down(target int, dryRun bool) ([]Migration, error) {
+	if target < 0 {
+		return nil, fmt.Errorf("invalid target version %d", target)
+	}
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok || mig.Version <= target {
+			continue
+		}
+		if mig.Down == "" {
+			return nil, fmt.Errorf("migration %d (%s) can't be reverted", mig.Version, mig.Name)
+		}
+		pending = append(pending, mig)
+	}
+	if dryRun {
+		return pending, nil
+	}
+
+	for _, mig := range pending {
+		if err := m.apply(mig.Version, mig.Name, mig.Down, false); err != nil {
+			return nil, err
+		}
+	}
+	return pending, nil
+}
+
+// apply runs one migration's SQL and records it in the same transaction
+func (m *Migrator) apply(version int, name, script string, up bool) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d: %w", version, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(script); err != nil {
+		return fmt.Errorf("migration %d (%s) failed: %w", version, name, err)
+	}
+	if up {
+		_, err = tx.Exec("INSERT INTO schema_version (version, name) VALUES (?, ?)", version, name)
+	} else {
+		_, err = tx.Exec("DELETE FROM schema_version WHERE version = ?", version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", version, err)
+	}
+	return tx.Commit()
+}
+
+// addLegacyColumns adds the edits columns introduced before versioned
+// migrations to databases created without them
+func addLegacyColumns(db *sql.DB) error {
+	columns := make(map[string]bool)
+	rows, err := db.Query("PRAGMA table_info(edits)")
+	if err != nil {
+		return fmt.Errorf("failed to get table info: %w", err)
+	}
+	for rows.Next() {
+		var cid int
+		var name, colType string
+		var notNull, pk int
+		var dfltValue interface{}
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dfltValue, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan column info: %w", err)
+		}
+		columns[name] = true
+	}
+	rows.Close()
+
+	added := []struct{ name, def string }{
+		{"commit_sha", "TEXT"},
+		{"vcs_type", "TEXT"},
+		{"file_snapshot", "BLOB"},
+		{"bookmarked", "BOOLEAN DEFAULT 0"},
+		{"chat_session_id", "TEXT"},
+		{"ralph_iteration", "INTEGER"},
+		{"summary", "TEXT"},
+		{"snapshot_hash", "TEXT"},
+	}
+	for _, col := range added {
+		if columns[col.name] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("ALTER TABLE edits ADD COLUMN %s %s", col.name, col.def)); err != nil {
+			return fmt.Errorf("failed to add %s column: %w", col.name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestMigrateFreshDatabase(t *testing.T) {
+	cfg := &Config{Path: filepath.Join(t.TempDir(), "fresh.db")}
+	db, err := Open(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	m, err := NewMigrator(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	current, err := m.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current != m.Latest() || current < 1 {
+		t.Errorf("expected version %d, got %d", m.Latest(), current)
+	}
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			t.Errorf("migration %d (%s) not applied", s.Version, s.Name)
+		}
+	}
+	if pending, err := m.Up(0, false); err != nil || len(pending) != 0 {
+		t.Errorf("expected nothing pending, got %d (%v)", len(pending), err)
+	}
+	if _, err := m.Down(0, true); err == nil {
+		t.Error("expected reverting the baseline to be refused")
+	}
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	raw, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An edits table from before commit_sha and the columns after it
+	if _, err := raw.Exec(`
+		CREATE TABLE sessions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			workspace_path TEXT NOT NULL,
+			workspace_name TEXT NOT NULL,
+			branch TEXT,
+			commit_sha TEXT,
+			started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			last_activity DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE(workspace_path, branch)
+		);
+		CREATE TABLE edits (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			session_id INTEGER NOT NULL,
+			tool_name TEXT NOT NULL,
+			file_path TEXT NOT NULL,
+			old_string TEXT,
+			new_string TEXT,
+			line_num INTEGER,
+			line_count INTEGER,
+			timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		INSERT INTO sessions (workspace_path, workspace_name, branch) VALUES ('/src/api', 'api', 'main');
+		INSERT INTO edits (session_id, tool_name, file_path, new_string) VALUES (1, 'Edit', '/src/api/main.go', 'x');
+	`); err != nil {
+		t.Fatal(err)
+	}
+	raw.Close()
+
+	db, err := Open(&Config{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	sessionID, err := db.UpsertSession("/src/api", "api", "main", "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RecordEdit(&Edit{SessionID: sessionID, ToolName: "Write", FilePath: "/src/api/main.go",
+		NewString: "y", FileContent: "package main\n"}); err != nil {
+		t.Fatal(err)
+	}
+	var edits, blobs int
+	if err := db.db.QueryRow("SELECT COUNT(*), (SELECT COUNT(*) FROM snapshot_blobs) FROM edits").Scan(&edits, &blobs); err != nil {
+		t.Fatal(err)
+	}
+	if edits != 2 || blobs != 1 {
+		t.Errorf("expected 2 edits and 1 blob, got %d and %d", edits, blobs)
+	}
+}
+
+func TestMigrateUpDown(t *testing.T) {
+	raw, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "updown.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer raw.Close()
+
+	fsys := fstest.MapFS{
+		"m/0001_notes.up.sql":   {Data: []byte("CREATE TABLE notes (id INTEGER PRIMARY KEY);")},
+		"m/0001_notes.down.sql": {Data: []byte("DROP TABLE notes;")},
+		"m/0002_title.up.sql":   {Data: []byte("ALTER TABLE notes ADD COLUMN title TEXT;")},
+		"m/0002_title.down.sql": {Data: []byte("ALTER TABLE notes DROP COLUMN title;")},
+		"m/0003_broken.up.sql":  {Data: []byte("ALTER TABLE missing ADD COLUMN x TEXT;")},
+		"m/README.md":           {Data: []byte("not a migration")},
+	}
+	migrations, err := loadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Migrator{db: raw, migrations: migrations}
+
+	if pending, err := m.Up(2, true); err != nil || len(pending) != 2 {
+		t.Fatalf("expected 2 migrations in the dry run, got %d (%v)", len(pending), err)
+	}
+	if current, _ := m.Current(); current != 0 {
+		t.Fatalf("dry run applied migrations, now at %d", current)
+	}
+
+	if _, err := m.Up(2, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Up(0, false); err == nil {
+		t.Fatal("expected the broken migration to fail")
+	}
+	if current, _ := m.Current(); current != 2 {
+		t.Errorf("expected the failed migration to roll back at version 2, got %d", current)
+	}
+
+	reverted, err := m.Down(0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reverted) != 2 || reverted[0].Version != 2 {
+		t.Errorf("expected 2 then 1 reverted, got %+v", reverted)
+	}
+	if exists, _ := m.tableExists("notes"); exists {
+		t.Error("expected notes to be dropped")
+	}
+
+	m.migrations = migrations[:1]
+	if _, err := m.Up(0, false); err != nil {
+		t.Fatal(err)
+	}
+	m.migrations = nil
+	if _, err := m.Up(0, false); err == nil {
+		t.Error("expected a database newer than the known migrations to be refused")
+	}
+}
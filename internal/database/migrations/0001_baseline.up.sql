@@ -0,0 +1,233 @@
+-- Schema for claude-mon daemon SQLite database
+-- Stores all Claude Code activity across sessions
+--
+-- The schema as of the first versioned migration. Databases created before
+-- then have their edits columns brought up to date before it runs, so every
+-- statement here must tolerate existing objects. Later changes go in their
+-- own numbered files.
+
+CREATE TABLE IF NOT EXISTS sessions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_path TEXT NOT NULL,
+    workspace_name TEXT NOT NULL,
+    branch TEXT,
+    commit_sha TEXT,
+    started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_activity DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(workspace_path, branch)
+);
+
+CREATE TABLE IF NOT EXISTS edits (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id INTEGER NOT NULL,
+    tool_name TEXT NOT NULL,
+    file_path TEXT NOT NULL,
+    old_string TEXT,
+    new_string TEXT,
+    line_num INTEGER,
+    line_count INTEGER,
+    commit_sha TEXT,      -- VCS commit/change ID at time of edit
+    vcs_type TEXT,        -- "git" or "jj"
+    file_snapshot BLOB,   -- compressed file content at time of edit, for edits stored before snapshot_hash
+    snapshot_hash TEXT,   -- file content at time of edit (see snapshot_blobs)
+    bookmarked BOOLEAN DEFAULT 0, -- flagged for later review
+    chat_session_id TEXT, -- Claude CLI session that made the edit (see chat_sessions)
+    ralph_iteration INTEGER, -- Ralph loop iteration the edit was made in
+    summary TEXT,         -- Declarations the edit touched, e.g. "modified func (Model) Update"
+    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS prompts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id INTEGER,
+    name TEXT NOT NULL,
+    description TEXT,
+    content TEXT NOT NULL,
+    tags TEXT, -- JSON array
+    version INTEGER DEFAULT 1,
+    is_global BOOLEAN DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE SET NULL,
+    UNIQUE(name, session_id)
+);
+
+CREATE TABLE IF NOT EXISTS prompt_versions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    prompt_id INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (prompt_id) REFERENCES prompts(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS hooks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id INTEGER NOT NULL,
+    hook_type TEXT NOT NULL, -- 'pre', 'post', etc.
+    tool_name TEXT,
+    tool_input TEXT, -- JSON
+    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_path TEXT,  -- empty for daemon-wide events
+    kind TEXT NOT NULL,   -- e.g. "ralph_start", "huge_edit", "failure"
+    severity TEXT NOT NULL DEFAULT 'info', -- "info", "warning", "error"
+    message TEXT,
+    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    scope TEXT NOT NULL,           -- "read", "ingest" or "admin"
+    token_hash TEXT NOT NULL UNIQUE, -- SHA-256 of the token; the token itself is never stored
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_used_at DATETIME,
+    revoked_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS prompt_injections (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    prompt_name TEXT NOT NULL,
+    prompt_version INTEGER,
+    workspace_path TEXT,
+    method TEXT NOT NULL,  -- "tmux" or "clipboard"
+    target TEXT,           -- tmux pane (session:window.pane), empty for clipboard
+    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS chat_sessions (
+    session_id TEXT PRIMARY KEY,  -- Claude CLI session ID, passed to --resume
+    workspace_path TEXT,
+    purpose TEXT,                 -- "general", "ralph", "prompt" or "plan"
+    started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_activity DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS chat_messages (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id TEXT NOT NULL,
+    role TEXT NOT NULL,           -- "user" or "assistant"
+    content TEXT NOT NULL,
+    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS ralph_loops (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_path TEXT NOT NULL,
+    prompt TEXT,
+    promise TEXT,                 -- completion promise when the loop ended
+    iterations INTEGER,           -- iterations used
+    max_iterations INTEGER,       -- 0 = no limit
+    outcome TEXT,                 -- "promise_met", "max_iterations", "cancelled" or "stopped"
+    started_at DATETIME,
+    ended_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS workspace_plans (
+    workspace_path TEXT PRIMARY KEY,
+    plan_path TEXT NOT NULL,      -- plan the workspace is working from
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS session_names (
+    session_id TEXT PRIMARY KEY,  -- Claude CLI session ID, as on edits.chat_session_id
+    workspace_path TEXT NOT NULL,
+    name TEXT NOT NULL,           -- name given in the History pane
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS file_snapshots (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    session_id INTEGER NOT NULL,
+    chat_session_id TEXT NOT NULL DEFAULT '', -- Claude session; '' when the hook didn't pass one
+    file_path TEXT NOT NULL,
+    existed BOOLEAN NOT NULL DEFAULT 1, -- 0 if the edit created the file
+    content BLOB,         -- compressed file content before the session's first edit
+    size INTEGER,         -- uncompressed size of content
+    timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE,
+    UNIQUE(session_id, chat_session_id, file_path)
+);
+
+-- File contents snapshotted with edits, stored once however many edits
+-- share them
+CREATE TABLE IF NOT EXISTS snapshot_blobs (
+    hash TEXT PRIMARY KEY, -- SHA-256 of the uncompressed content
+    codec TEXT NOT NULL,   -- "gzip" or "zstd"
+    data BLOB NOT NULL,
+    size INTEGER NOT NULL, -- uncompressed size of data
+    refs INTEGER NOT NULL DEFAULT 0 -- edits referencing it; kept by triggers on edits
+);
+
+CREATE TABLE IF NOT EXISTS check_results (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    workspace_path TEXT NOT NULL,
+    file_path TEXT NOT NULL,  -- edited file the check ran for
+    name TEXT NOT NULL,       -- check name from the daemon config, e.g. "vet"
+    command TEXT NOT NULL,    -- command as run, placeholders expanded
+    passed BOOLEAN NOT NULL,
+    exit_code INTEGER,        -- -1 if the command didn't start or timed out
+    output TEXT,              -- combined stdout and stderr, tail only
+    duration_ms INTEGER,
+    started_at DATETIME NOT NULL -- when the debounced run started; one run shares it
+);
+
+-- Indexes for performance
+CREATE INDEX IF NOT EXISTS idx_edits_session ON edits(session_id);
+CREATE INDEX IF NOT EXISTS idx_edits_file ON edits(file_path);
+CREATE INDEX IF NOT EXISTS idx_edits_timestamp ON edits(timestamp);
+CREATE INDEX IF NOT EXISTS idx_prompts_session ON prompts(session_id);
+CREATE INDEX IF NOT EXISTS idx_prompts_name ON prompts(name);
+CREATE INDEX IF NOT EXISTS idx_hooks_session ON hooks(session_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_workspace ON sessions(workspace_path);
+CREATE INDEX IF NOT EXISTS idx_events_timestamp ON events(timestamp);
+CREATE INDEX IF NOT EXISTS idx_prompt_injections_name ON prompt_injections(prompt_name, timestamp);
+CREATE INDEX IF NOT EXISTS idx_chat_sessions_workspace ON chat_sessions(workspace_path, last_activity);
+CREATE INDEX IF NOT EXISTS idx_session_names_workspace ON session_names(workspace_path);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_session ON chat_messages(session_id, id);
+CREATE INDEX IF NOT EXISTS idx_ralph_loops_workspace ON ralph_loops(workspace_path, ended_at);
+CREATE INDEX IF NOT EXISTS idx_file_snapshots_file ON file_snapshots(file_path, timestamp);
+CREATE INDEX IF NOT EXISTS idx_check_results_workspace ON check_results(workspace_path, started_at);
+CREATE INDEX IF NOT EXISTS idx_edits_chat_session ON edits(chat_session_id);
+
+-- Count the edits referencing each snapshot blob, dropping a blob once none do
+CREATE TRIGGER IF NOT EXISTS edits_snapshot_ref AFTER INSERT ON edits
+WHEN NEW.snapshot_hash IS NOT NULL
+BEGIN
+    UPDATE snapshot_blobs SET refs = refs + 1 WHERE hash = NEW.snapshot_hash;
+END;
+
+CREATE TRIGGER IF NOT EXISTS edits_snapshot_reref AFTER UPDATE OF snapshot_hash ON edits
+WHEN NEW.snapshot_hash IS NOT OLD.snapshot_hash
+BEGIN
+    UPDATE snapshot_blobs SET refs = refs + 1 WHERE hash = NEW.snapshot_hash;
+    UPDATE snapshot_blobs SET refs = refs - 1 WHERE hash = OLD.snapshot_hash;
+    DELETE FROM snapshot_blobs WHERE hash = OLD.snapshot_hash AND refs <= 0;
+END;
+
+CREATE TRIGGER IF NOT EXISTS edits_snapshot_unref AFTER DELETE ON edits
+WHEN OLD.snapshot_hash IS NOT NULL
+BEGIN
+    UPDATE snapshot_blobs SET refs = refs - 1 WHERE hash = OLD.snapshot_hash;
+    DELETE FROM snapshot_blobs WHERE hash = OLD.snapshot_hash AND refs <= 0;
+END;
+
+-- View for recent activity
+CREATE VIEW IF NOT EXISTS recent_activity AS
+SELECT
+    e.id,
+    e.tool_name,
+    e.file_path,
+    s.workspace_name,
+    s.branch,
+    e.timestamp
+FROM edits e
+JOIN sessions s ON e.session_id = s.id
+ORDER BY e.timestamp DESC
+LIMIT 100;
@@ -0,0 +1 @@
+ALTER TABLE edits DROP COLUMN note;
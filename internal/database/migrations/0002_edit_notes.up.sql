@@ -0,0 +1,2 @@
+-- Review notes attached to edits, e.g. "needs rework"
+ALTER TABLE edits ADD COLUMN note TEXT;
@@ -0,0 +1,2 @@
+-- Review state of edits: "approved" or "rejected"; NULL until reviewed
+ALTER TABLE edits ADD COLUMN review TEXT;
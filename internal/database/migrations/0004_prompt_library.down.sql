@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_prompt_versions_version;
+DROP INDEX IF EXISTS idx_prompts_library;
+ALTER TABLE prompts DROP COLUMN variables;
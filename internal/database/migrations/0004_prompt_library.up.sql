@@ -0,0 +1,5 @@
+-- Prompts in the daemon's library belong to no session and are shared by
+-- every workspace, so their names are unique among themselves
+ALTER TABLE prompts ADD COLUMN variables TEXT; -- JSON array of user-defined {{variables}}
+CREATE UNIQUE INDEX IF NOT EXISTS idx_prompts_library ON prompts(name) WHERE session_id IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_prompt_versions_version ON prompt_versions(prompt_id, version);
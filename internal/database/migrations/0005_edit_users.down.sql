@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_edits_user;
+ALTER TABLE edits DROP COLUMN user_name;
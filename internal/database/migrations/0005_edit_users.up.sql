@@ -0,0 +1,5 @@
+-- User whose Claude session made each edit, as the daemon saw them on its
+-- socket: their login name, or their uid if it has none. NULL for edits
+-- recorded before edits were tagged.
+ALTER TABLE edits ADD COLUMN user_name TEXT;
+CREATE INDEX IF NOT EXISTS idx_edits_user ON edits(user_name);
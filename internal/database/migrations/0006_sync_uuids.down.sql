@@ -0,0 +1,9 @@
+DROP TRIGGER IF EXISTS sessions_uuid;
+DROP TRIGGER IF EXISTS edits_uuid;
+DROP TRIGGER IF EXISTS prompts_uuid;
+DROP INDEX IF EXISTS idx_sessions_uuid;
+DROP INDEX IF EXISTS idx_edits_uuid;
+DROP INDEX IF EXISTS idx_prompts_uuid;
+ALTER TABLE sessions DROP COLUMN uuid;
+ALTER TABLE edits DROP COLUMN uuid;
+ALTER TABLE prompts DROP COLUMN uuid;
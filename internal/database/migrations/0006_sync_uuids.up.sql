@@ -0,0 +1,39 @@
+-- Globally unique row IDs, so `daemon sync` can merge databases from
+-- several machines without their integer IDs colliding. Rows inserted
+-- without one are given a random (version 4) UUID.
+ALTER TABLE sessions ADD COLUMN uuid TEXT;
+ALTER TABLE edits ADD COLUMN uuid TEXT;
+ALTER TABLE prompts ADD COLUMN uuid TEXT;
+UPDATE sessions SET uuid = lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' ||
+             substr('89AB', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))
+WHERE uuid IS NULL;
+UPDATE edits SET uuid = lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' ||
+             substr('89AB', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))
+WHERE uuid IS NULL;
+UPDATE prompts SET uuid = lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' ||
+             substr('89AB', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))
+WHERE uuid IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sessions_uuid ON sessions(uuid);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_edits_uuid ON edits(uuid);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_prompts_uuid ON prompts(uuid);
+CREATE TRIGGER IF NOT EXISTS sessions_uuid AFTER INSERT ON sessions
+WHEN NEW.uuid IS NULL
+BEGIN
+    UPDATE sessions SET uuid = lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' ||
+             substr('89AB', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))
+    WHERE id = NEW.id;
+END;
+CREATE TRIGGER IF NOT EXISTS edits_uuid AFTER INSERT ON edits
+WHEN NEW.uuid IS NULL
+BEGIN
+    UPDATE edits SET uuid = lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' ||
+             substr('89AB', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))
+    WHERE id = NEW.id;
+END;
+CREATE TRIGGER IF NOT EXISTS prompts_uuid AFTER INSERT ON prompts
+WHEN NEW.uuid IS NULL
+BEGIN
+    UPDATE prompts SET uuid = lower(hex(randomblob(4)) || '-' || hex(randomblob(2)) || '-4' || substr(hex(randomblob(2)), 2) || '-' ||
+             substr('89AB', 1 + abs(random()) % 4, 1) || substr(hex(randomblob(2)), 2) || '-' || hex(randomblob(6)))
+    WHERE id = NEW.id;
+END;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_hooks_timestamp;
+ALTER TABLE hooks DROP COLUMN user_name;
+ALTER TABLE hooks DROP COLUMN source;
+ALTER TABLE hooks DROP COLUMN file_path;
+ALTER TABLE hooks DROP COLUMN chat_session_id;
@@ -0,0 +1,10 @@
+-- Claude Code hook events other than edits: SessionStart, Stop (Claude
+-- finished a turn), SubagentStop and PreToolUse, kept in the hooks table by
+-- their hook_event_name in hook_type. chat_session_id is Claude's session;
+-- source is why a session started ("startup", "resume", "clear" or
+-- "compact"); file_path is the file a PreToolUse call is about to edit.
+ALTER TABLE hooks ADD COLUMN chat_session_id TEXT;
+ALTER TABLE hooks ADD COLUMN file_path TEXT;
+ALTER TABLE hooks ADD COLUMN source TEXT;
+ALTER TABLE hooks ADD COLUMN user_name TEXT;
+CREATE INDEX IF NOT EXISTS idx_hooks_timestamp ON hooks(timestamp);
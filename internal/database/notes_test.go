@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEditNote(t *testing.T) {
+	db, err := Open(&Config{Path: filepath.Join(t.TempDir(), "notes.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	sessionID, err := db.UpsertSession("/src/api", "api", "main", "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/src/api/a.go", "/src/api/b.go"} {
+		if err := db.RecordEdit(&Edit{SessionID: sessionID, ToolName: "Edit", FilePath: path, NewString: "x"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	edits, err := db.GetTimeline(nil, time.Time{}, EditCursor{}, 10)
+	if err != nil || len(edits) != 2 {
+		t.Fatalf("expected 2 edits, got %d (%v)", len(edits), err)
+	}
+	noted := edits[0].ID
+
+	if err := db.SetEditNote(noted, "needs rework"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetEditNote(9999, "x"); err == nil {
+		t.Error("expected an error for an unknown edit")
+	}
+
+	// Exports carry the note
+	var exported []*TimelineEdit
+	if err := db.StreamTimeline(context.Background(), TimelineQuery{Content: true}, func(e *TimelineEdit) error {
+		exported = append(exported, e)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if exported[0].Note != "needs rework" || exported[1].Note != "" {
+		t.Errorf("expected the note on the first edit only, got %q and %q", exported[0].Note, exported[1].Note)
+	}
+	dump, err := db.ExportWorkspace("/src/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dumped string
+	for _, s := range dump.Sessions {
+		for _, e := range s.Edits {
+			if e.ID == noted {
+				dumped = e.Note
+			}
+		}
+	}
+	if dumped != "needs rework" {
+		t.Errorf("expected the note in the workspace export, got %q", dumped)
+	}
+
+	// Noted edits are kept for review like bookmarked ones
+	if n, err := db.DeleteOldEdits(time.Now().Add(time.Hour)); err != nil || n != 1 {
+		t.Errorf("expected only the edit without a note deleted, got %d (%v)", n, err)
+	}
+
+	// An empty note removes it
+	if err := db.SetEditNote(noted, ""); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := db.DeleteOldEdits(time.Now().Add(time.Hour)); n != 1 {
+		t.Errorf("expected the edit deleted once its note was removed, got %d", n)
+	}
+}
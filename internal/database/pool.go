@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Pool settings used when Config leaves them unset
+const (
+	defaultPoolSize      = 4
+	defaultBusyTimeoutMS = 5000
+	poolIdleTimeout      = 5 * time.Minute
+)
+
+// connector opens the pool's SQLite connections, applying the settings
+// SQLite keeps per connection to each
+type connector struct {
+	dsn     string
+	pragmas []string
+	driver  *sqlite3.SQLiteDriver
+}
+
+// newConnector returns a connector for the database in cfg. In WAL mode
+// readers run alongside the one writer; transactions take the write lock
+// when they begin, so two never both read and then wait on each other to
+// write, and a writer kept waiting retries until the busy timeout.
+func newConnector(cfg *Config) *connector {
+	busyTimeout := cfg.BusyTimeoutMS
+	if busyTimeout <= 0 {
+		busyTimeout = defaultBusyTimeoutMS
+	}
+	c := &connector{
+		dsn:    fmt.Sprintf("%s?_journal_mode=WAL&_synchronous=NORMAL&_txlock=immediate&_busy_timeout=%d", cfg.Path, busyTimeout),
+		driver: &sqlite3.SQLiteDriver{},
+	}
+	if cfg.WALCheckpointPages > 0 {
+		c.pragmas = append(c.pragmas, fmt.Sprintf("PRAGMA wal_autocheckpoint = %d", cfg.WALCheckpointPages))
+	}
+	c.pragmas = append(c.pragmas, "PRAGMA temp_store = MEMORY")
+	return c
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		for _, pragma := range c.pragmas {
+			if _, err := execer.ExecContext(ctx, pragma, nil); err != nil {
+				conn.Close()
+				return nil, fmt.Errorf("failed to set %q: %w", pragma, err)
+			}
+		}
+	}
+	return conn, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// openPool opens the connection pool for the database in cfg
+func openPool(cfg *Config) *sql.DB {
+	size := cfg.PoolSize
+	if size <= 0 {
+		size = defaultPoolSize
+	}
+	db := sql.OpenDB(newConnector(cfg))
+	db.SetMaxOpenConns(size)
+	db.SetMaxIdleConns(size)
+	db.SetConnMaxIdleTime(poolIdleTimeout)
+	return db
+}
+
+// statements are the queries run for every hook payload or request,
+// prepared once; the pool prepares each on a connection the first time
+// it's used there
+type statements struct {
+	insertEdit     *sql.Stmt
+	upsertSession  *sql.Stmt
+	recentEdits    *sql.Stmt
+	workspaceEdits *sql.Stmt
+	tokenByHash    *sql.Stmt
+	touchToken     *sql.Stmt
+}
+
+// prepareStatements prepares the hot queries
+func prepareStatements(db *sql.DB) (*statements, error) {
+	s := &statements{}
+	for _, p := range []struct {
+		stmt  **sql.Stmt
+		query string
+	}{
+		{&s.insertEdit, insertEditQuery},
+		{&s.upsertSession, upsertSessionQuery},
+		{&s.recentEdits, recentEditsQuery},
+		{&s.workspaceEdits, workspaceEditsQuery},
+		{&s.tokenByHash, tokenByHashQuery},
+		{&s.touchToken, touchTokenQuery},
+	} {
+		stmt, err := db.Prepare(p.query)
+		if err != nil {
+			s.close()
+			return nil, fmt.Errorf("failed to prepare statement: %w", err)
+		}
+		*p.stmt = stmt
+	}
+	return s, nil
+}
+
+// close closes the prepared statements
+func (s *statements) close() {
+	for _, stmt := range []*sql.Stmt{s.insertEdit, s.upsertSession, s.recentEdits, s.workspaceEdits, s.tokenByHash, s.touchToken} {
+		if stmt != nil {
+			stmt.Close()
+		}
+	}
+}
+
+// PoolStats reports how the connection pool is used
+type PoolStats struct {
+	MaxOpen   int     `json:"max_open"`
+	Open      int     `json:"open"`
+	InUse     int     `json:"in_use"`
+	Idle      int     `json:"idle"`
+	WaitCount int64   `json:"wait_count"` // Times a query waited for a free connection
+	WaitMS    float64 `json:"wait_ms"`    // Total time spent waiting
+}
+
+// PoolStats returns the connection pool's counters
+func (d *DB) PoolStats() PoolStats {
+	s := d.db.Stats()
+	return PoolStats{
+		MaxOpen:   s.MaxOpenConnections,
+		Open:      s.OpenConnections,
+		InUse:     s.InUse,
+		Idle:      s.Idle,
+		WaitCount: s.WaitCount,
+		WaitMS:    float64(s.WaitDuration.Microseconds()) / 1000,
+	}
+}
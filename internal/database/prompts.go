@@ -0,0 +1,169 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// PromptVersion is a version backup of a prompt in the library
+type PromptVersion struct {
+	Version int
+	Content string // The whole prompt file, frontmatter included
+}
+
+// promptColumns are the columns scanPrompts reads, with how many times
+// each prompt has been sent
+const promptColumns = `id, session_id, name, COALESCE(description, ''), content, COALESCE(tags, 'null'),
+		COALESCE(variables, ''), version, is_global, created_at, updated_at,
+		(SELECT COUNT(*) FROM prompt_injections WHERE prompt_name = prompts.name)`
+
+// scanPrompts reads prompts selected with promptColumns
+func scanPrompts(rows *sql.Rows) ([]*Prompt, error) {
+	var prompts []*Prompt
+	for rows.Next() {
+		var p Prompt
+		var tagsJSON string
+
+		err := rows.Scan(
+			&p.ID, &p.SessionID, &p.Name, &p.Description, &p.Content, &tagsJSON,
+			&p.Variables, &p.Version, &p.IsGlobal, &p.CreatedAt, &p.UpdatedAt, &p.Uses,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan prompt: %w", err)
+		}
+
+		if err := json.Unmarshal([]byte(tagsJSON), &p.Tags); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
+		}
+
+		prompts = append(prompts, &p)
+	}
+	return prompts, rows.Err()
+}
+
+// SaveLibraryPrompt adds a prompt to the library shared by every workspace,
+// or replaces the one with the same name. Its version backups are added
+// alongside those already kept.
+func (d *DB) SaveLibraryPrompt(p *Prompt) error {
+	tagsJSON, err := json.Marshal(p.Tags)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tags: %w", err)
+	}
+	created, updated := p.CreatedAt, p.UpdatedAt
+	if updated.IsZero() {
+		updated = time.Now()
+	}
+	if created.IsZero() {
+		created = updated
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRow(`
+		INSERT INTO prompts (session_id, name, description, content, tags, variables, version, is_global, created_at, updated_at)
+		VALUES (NULL, ?, ?, ?, ?, NULLIF(?, ''), ?, 1, ?, ?)
+		ON CONFLICT(name) WHERE session_id IS NULL DO UPDATE SET
+			description = excluded.description,
+			content = excluded.content,
+			tags = excluded.tags,
+			variables = excluded.variables,
+			version = excluded.version,
+			updated_at = excluded.updated_at
+		RETURNING id
+	`, p.Name, p.Description, p.Content, string(tagsJSON), p.Variables, p.Version,
+		created.UTC(), updated.UTC()).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to save prompt: %w", err)
+	}
+
+	for _, v := range p.Versions {
+		_, err := tx.Exec(`
+			INSERT INTO prompt_versions (prompt_id, version, content) VALUES (?, ?, ?)
+			ON CONFLICT(prompt_id, version) DO UPDATE SET content = excluded.content
+		`, id, v.Version, v.Content)
+		if err != nil {
+			return fmt.Errorf("failed to save prompt version: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteLibraryPrompt removes a prompt and its versions from the library
+func (d *DB) DeleteLibraryPrompt(name string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRow("SELECT id FROM prompts WHERE name = ? AND session_id IS NULL", name).Scan(&id)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("prompt %q not found", name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find prompt: %w", err)
+	}
+
+	if _, err := tx.Exec("DELETE FROM prompt_versions WHERE prompt_id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete prompt versions: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM prompts WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete prompt: %w", err)
+	}
+	return tx.Commit()
+}
+
+// GetLibraryPrompts returns every prompt in the library by name, each with
+// its version backups, oldest first
+func (d *DB) GetLibraryPrompts() ([]*Prompt, error) {
+	rows, err := d.db.Query(`
+		SELECT ` + promptColumns + `
+		FROM prompts
+		WHERE session_id IS NULL
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompts: %w", err)
+	}
+	prompts, err := scanPrompts(rows)
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]*Prompt, len(prompts))
+	for _, p := range prompts {
+		byID[p.ID] = p
+	}
+	rows, err = d.db.Query(`
+		SELECT v.prompt_id, v.version, v.content
+		FROM prompt_versions v
+		JOIN prompts p ON p.id = v.prompt_id
+		WHERE p.session_id IS NULL
+		ORDER BY v.prompt_id, v.version
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get prompt versions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var v PromptVersion
+		if err := rows.Scan(&id, &v.Version, &v.Content); err != nil {
+			return nil, fmt.Errorf("failed to scan prompt version: %w", err)
+		}
+		if p := byID[id]; p != nil {
+			p.Versions = append(p.Versions, v)
+		}
+	}
+	return prompts, rows.Err()
+}
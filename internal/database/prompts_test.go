@@ -0,0 +1,73 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLibraryPrompts(t *testing.T) {
+	db, err := Open(&Config{Path: filepath.Join(t.TempDir(), "prompts.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	review := &Prompt{
+		Name: "review", Content: "Review {{file}}", Tags: []string{"code"}, Version: 2,
+		Variables: `[{"name":"focus"}]`,
+		Versions:  []PromptVersion{{Version: 1, Content: "---\nname: review\n---\n\nold"}},
+	}
+	if err := db.SaveLibraryPrompt(review); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SaveLibraryPrompt(&Prompt{Name: "plan", Content: "Plan it", Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saving again replaces the prompt and adds the new backup
+	review.Content, review.Version = "Review {{file}} carefully", 3
+	review.Versions = []PromptVersion{{Version: 2, Content: "---\nname: review\n---\n\nReview {{file}}"}}
+	if err := db.SaveLibraryPrompt(review); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RecordPromptInjection(&PromptInjection{PromptName: "review", PromptVersion: 3, Method: "tmux"}); err != nil {
+		t.Fatal(err)
+	}
+
+	prompts, err := db.GetLibraryPrompts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prompts) != 2 || prompts[0].Name != "plan" {
+		t.Fatalf("expected plan and review, got %+v", prompts)
+	}
+	got := prompts[1]
+	if got.Content != "Review {{file}} carefully" || got.Version != 3 || got.Variables != `[{"name":"focus"}]` || got.Uses != 1 {
+		t.Errorf("unexpected prompt %+v", got)
+	}
+	if len(got.Versions) != 2 || got.Versions[0].Version != 1 || got.Versions[1].Version != 2 {
+		t.Errorf("expected versions 1 and 2, got %+v", got.Versions)
+	}
+
+	// The library answers the prompts query like recorded prompts do
+	tagged, err := db.GetPrompts("%", "code", 10)
+	if err != nil || len(tagged) != 1 || tagged[0].Name != "review" {
+		t.Errorf("expected review tagged code, got %+v (%v)", tagged, err)
+	}
+
+	if err := db.DeleteLibraryPrompt("review"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteLibraryPrompt("review"); err == nil {
+		t.Error("expected an error deleting a missing prompt")
+	}
+	prompts, err = db.GetLibraryPrompts()
+	if err != nil || len(prompts) != 1 {
+		t.Fatalf("expected only plan left, got %d (%v)", len(prompts), err)
+	}
+	var versions int
+	db.db.QueryRow("SELECT COUNT(*) FROM prompt_versions").Scan(&versions)
+	if versions != 0 {
+		t.Errorf("expected the versions deleted with the prompt, %d left", versions)
+	}
+}
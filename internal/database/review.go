@@ -0,0 +1,91 @@
+package database
+
+import (
+	"fmt"
+)
+
+// Review states of an edit
+const (
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
+// ReviewStats counts a workspace's edits by review state
+type ReviewStats struct {
+	Total    int `json:"total"`
+	Approved int `json:"approved"`
+	Rejected int `json:"rejected"`
+}
+
+// Reviewed returns how many edits have been approved or rejected
+func (s ReviewStats) Reviewed() int {
+	return s.Approved + s.Rejected
+}
+
+// SetEditReview approves or rejects an edit, or clears its review state if
+// review is empty
+func (d *DB) SetEditReview(id int64, review string) error {
+	if review != "" && review != ReviewApproved && review != ReviewRejected {
+		return fmt.Errorf("unknown review state %q", review)
+	}
+	result, err := d.db.Exec("UPDATE edits SET review = NULLIF(?, '') WHERE id = ?", review, id)
+	if err != nil {
+		return fmt.Errorf("failed to set review: %w", err)
+	}
+
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("edit %d not found", id)
+	}
+
+	return nil
+}
+
+// GetReviewStats counts a workspace's edits by review state
+func (d *DB) GetReviewStats(workspacePath string) (*ReviewStats, error) {
+	var s ReviewStats
+	err := d.db.QueryRow(`
+		SELECT COUNT(*),
+		       COALESCE(SUM(e.review = 'approved'), 0),
+		       COALESCE(SUM(e.review = 'rejected'), 0)
+		FROM edits e
+		JOIN sessions s ON e.session_id = s.id
+		WHERE s.workspace_path = ?
+	`, workspacePath).Scan(&s.Total, &s.Approved, &s.Rejected)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count reviews: %w", err)
+	}
+	return &s, nil
+}
+
+// GetRejectedEdits returns a workspace's rejected edits with their old and
+// new strings and notes, oldest first
+func (d *DB) GetRejectedEdits(workspacePath string) ([]*Edit, error) {
+	rows, err := d.db.Query(`
+		SELECT e.id, e.session_id, e.tool_name, e.file_path,
+		       COALESCE(e.old_string, ''), COALESCE(e.new_string, ''),
+		       COALESCE(e.line_num, 0), COALESCE(e.line_count, 0),
+		       COALESCE(e.note, ''), e.timestamp, COALESCE(e.summary, '')
+		FROM edits e
+		JOIN sessions s ON e.session_id = s.id
+		WHERE s.workspace_path = ? AND e.review = 'rejected'
+		ORDER BY e.timestamp, e.id
+	`, workspacePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rejected edits: %w", err)
+	}
+	defer rows.Close()
+
+	var edits []*Edit
+	for rows.Next() {
+		e := Edit{Review: ReviewRejected}
+		if err := rows.Scan(
+			&e.ID, &e.SessionID, &e.ToolName, &e.FilePath,
+			&e.OldString, &e.NewString, &e.LineNum, &e.LineCount,
+			&e.Note, &e.Timestamp, &e.Summary,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan edit: %w", err)
+		}
+		edits = append(edits, &e)
+	}
+	return edits, rows.Err()
+}
@@ -0,0 +1,72 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEditReview(t *testing.T) {
+	db, err := Open(&Config{Path: filepath.Join(t.TempDir(), "review.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	sessionID, err := db.UpsertSession("/src/api", "api", "main", "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{"/src/api/a.go", "/src/api/b.go", "/src/api/c.go"}
+	for _, path := range paths {
+		if err := db.RecordEdit(&Edit{SessionID: sessionID, ToolName: "Edit", FilePath: path, OldString: "old", NewString: "new"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	edits, err := db.GetTimeline(nil, time.Time{}, EditCursor{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byPath := make(map[string]int64)
+	for _, e := range edits {
+		byPath[e.FilePath] = e.ID
+	}
+	ids := []int64{byPath[paths[0]], byPath[paths[1]], byPath[paths[2]]}
+
+	if err := db.SetEditReview(ids[0], ReviewApproved); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetEditReview(ids[1], ReviewRejected); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetEditNote(ids[1], "handle nil"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.SetEditReview(ids[2], "maybe"); err == nil {
+		t.Error("expected an unknown review state refused")
+	}
+
+	stats, err := db.GetReviewStats("/src/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *stats != (ReviewStats{Total: 3, Approved: 1, Rejected: 1}) || stats.Reviewed() != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	rejected, err := db.GetRejectedEdits("/src/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rejected) != 1 || rejected[0].FilePath != "/src/api/b.go" || rejected[0].Note != "handle nil" || rejected[0].OldString != "old" {
+		t.Errorf("unexpected rejected edits %+v", rejected)
+	}
+
+	// Clearing the review takes the edit out of the counts
+	if err := db.SetEditReview(ids[1], ""); err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ := db.GetReviewStats("/src/api"); stats.Rejected != 0 {
+		t.Errorf("expected no rejected edits, got %d", stats.Rejected)
+	}
+}
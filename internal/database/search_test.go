@@ -0,0 +1,49 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFilteredEditsContent(t *testing.T) {
+	db, err := Open(&Config{Path: filepath.Join(t.TempDir(), "search.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	sessionID, err := db.UpsertSession("/src/api", "api", "main", "abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range []*Edit{
+		{SessionID: sessionID, ToolName: "Edit", FilePath: "/src/api/a.go", OldString: "retry(3)", NewString: "retry(5)"},
+		{SessionID: sessionID, ToolName: "Edit", FilePath: "/src/api/b.go", NewString: "progress: 100%"},
+		{SessionID: sessionID, ToolName: "Edit", FilePath: "/src/api/c.go", NewString: "x", Summary: "modified func Retry"},
+	} {
+		if err := db.RecordEdit(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	search := func(text string) int {
+		t.Helper()
+		edits, err := db.GetFilteredEdits(EditFilter{WorkspacePath: "/src/api", Content: text}, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(edits)
+	}
+	if n := search("RETRY"); n != 2 {
+		t.Errorf("expected the strings and summary matched ignoring case, got %d", n)
+	}
+	if n := search("100%"); n != 1 {
+		t.Errorf("expected %% matched literally, got %d", n)
+	}
+	if n := search("retry_3_"); n != 0 {
+		t.Errorf("expected _ matched literally, got %d", n)
+	}
+	if n := search("missing"); n != 0 {
+		t.Errorf("expected no match, got %d", n)
+	}
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// FileSnapshot is a file's full content before the first edit to it in a
+// Claude session, kept so the file can be restored to the session start
+// without relying on the VCS
+type FileSnapshot struct {
+	ID            int64     `json:"id"`
+	SessionID     int64     `json:"session_id"`
+	ChatSessionID string    `json:"chat_session_id,omitempty"` // Claude session; empty if the hook didn't pass one
+	WorkspacePath string    `json:"workspace_path"`
+	FilePath      string    `json:"file_path"`
+	Existed       bool      `json:"existed"` // False if the session created the file
+	Size          int       `json:"size"`    // Uncompressed size of Content
+	Content       []byte    `json:"content,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// RecordFileSnapshot stores a snapshot unless the session already has one
+// for the file, since only the content before the first edit is kept.
+// Returns whether the snapshot was stored.
+func (d *DB) RecordFileSnapshot(s *FileSnapshot) (bool, error) {
+	var compressed []byte
+	if s.Existed {
+		var err error
+		if compressed, err = compressData(s.Content, d.codec); err != nil {
+			return false, fmt.Errorf("failed to compress snapshot: %w", err)
+		}
+	}
+
+	result, err := d.db.Exec(`
+		INSERT OR IGNORE INTO file_snapshots (session_id, chat_session_id, file_path, existed, content, size)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, s.SessionID, s.ChatSessionID, s.FilePath, s.Existed, compressed, len(s.Content))
+	if err != nil {
+		return false, fmt.Errorf("failed to record snapshot: %w", err)
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// HasFileSnapshot reports whether a session already has a snapshot of a file
+func (d *DB) HasFileSnapshot(sessionID int64, chatSessionID, filePath string) (bool, error) {
+	var id int64
+	err := d.db.QueryRow(`
+		SELECT id FROM file_snapshots WHERE session_id = ? AND chat_session_id = ? AND file_path = ?
+	`, sessionID, chatSessionID, filePath).Scan(&id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to look up snapshot: %w", err)
+	}
+	return true, nil
+}
+
+// GetFileSnapshots returns a workspace's snapshots, newest first, without
+// their content. An empty filePath returns snapshots of every file.
+func (d *DB) GetFileSnapshots(workspacePath, filePath string, limit int) ([]*FileSnapshot, error) {
+	rows, err := d.db.Query(`
+		SELECT f.id, f.session_id, f.chat_session_id, s.workspace_path, f.file_path, f.existed, COALESCE(f.size, 0), f.timestamp
+		FROM file_snapshots f
+		JOIN sessions s ON f.session_id = s.id
+		WHERE s.workspace_path = ? AND (? = '' OR f.file_path = ?)
+		ORDER BY f.timestamp DESC, f.id DESC
+		LIMIT ?
+	`, workspacePath, filePath, filePath, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []*FileSnapshot
+	for rows.Next() {
+		var s FileSnapshot
+		if err := rows.Scan(&s.ID, &s.SessionID, &s.ChatSessionID, &s.WorkspacePath, &s.FilePath, &s.Existed, &s.Size, &s.Timestamp); err != nil {
+			return nil, fmt.Errorf("failed to scan snapshot: %w", err)
+		}
+		snapshots = append(snapshots, &s)
+	}
+	return snapshots, rows.Err()
+}
+
+// GetFileSnapshot returns a snapshot with its decompressed content
+func (d *DB) GetFileSnapshot(id int64) (*FileSnapshot, error) {
+	var s FileSnapshot
+	var compressed []byte
+	err := d.db.QueryRow(`
+		SELECT f.id, f.session_id, f.chat_session_id, s.workspace_path, f.file_path, f.existed, COALESCE(f.size, 0), f.content, f.timestamp
+		FROM file_snapshots f
+		JOIN sessions s ON f.session_id = s.id
+		WHERE f.id = ?
+	`, id).Scan(&s.ID, &s.SessionID, &s.ChatSessionID, &s.WorkspacePath, &s.FilePath, &s.Existed, &s.Size, &compressed, &s.Timestamp)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no snapshot %d", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+
+	if s.Existed {
+		if s.Content, err = decompressData(compressed); err != nil {
+			return nil, fmt.Errorf("failed to decompress snapshot: %w", err)
+		}
+	}
+	return &s, nil
+}
+
+// DeleteOldSnapshots deletes snapshots older than the specified date
+func (d *DB) DeleteOldSnapshots(beforeDate time.Time) (int64, error) {
+	result, err := d.db.Exec("DELETE FROM file_snapshots WHERE timestamp < ?", sqlTime(beforeDate))
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old snapshots: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,334 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// SyncManifest lists the edits and session prompts a database has, by
+// UUID, so the other side of a sync only sends the ones it lacks
+type SyncManifest struct {
+	Edits   []string `json:"edits"`
+	Prompts []string `json:"prompts"`
+}
+
+// SyncBatch is the rows one database sends another in a sync: the
+// sessions with edits or prompts the other lacks, and its prompt library
+type SyncBatch struct {
+	Sessions []*SyncSession `json:"sessions"`
+	Library  []*Prompt      `json:"library,omitempty"`
+}
+
+// SyncSession is a session with the edits and prompts being synced
+type SyncSession struct {
+	Session
+	UUID    string        `json:"uuid"`
+	Edits   []*SyncEdit   `json:"edits,omitempty"`
+	Prompts []*SyncPrompt `json:"prompts,omitempty"`
+}
+
+// SyncEdit is an edit with its UUID and compressed file snapshot
+type SyncEdit struct {
+	EditDump
+	UUID string `json:"uuid"`
+}
+
+// SyncPrompt is a prompt recorded in a session, with its UUID
+type SyncPrompt struct {
+	*Prompt
+	UUID string `json:"uuid"`
+}
+
+// SyncStats counts the rows a sync added to one database
+type SyncStats struct {
+	Sessions int `json:"sessions"` // New sessions; others were merged into the matching workspace and branch
+	Edits    int `json:"edits"`
+	Prompts  int `json:"prompts"` // Session prompts added and library prompts added or updated
+}
+
+// SyncManifest lists the database's edits and session prompts
+func (d *DB) SyncManifest() (*SyncManifest, error) {
+	m := &SyncManifest{}
+	var err error
+	if m.Edits, err = d.queryStrings("SELECT uuid FROM edits WHERE uuid IS NOT NULL"); err != nil {
+		return nil, err
+	}
+	if m.Prompts, err = d.queryStrings("SELECT uuid FROM prompts WHERE uuid IS NOT NULL AND session_id IS NOT NULL"); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ExportSync collects the rows a database with the given manifest lacks,
+// under their sessions so they can be placed in the same workspace and
+// branch. The library is sent whole and merged by name.
+func (d *DB) ExportSync(have *SyncManifest) (*SyncBatch, error) {
+	haveEdits := make(map[string]bool, len(have.Edits))
+	for _, uuid := range have.Edits {
+		haveEdits[uuid] = true
+	}
+	havePrompts := make(map[string]bool, len(have.Prompts))
+	for _, uuid := range have.Prompts {
+		havePrompts[uuid] = true
+	}
+
+	batch := &SyncBatch{}
+	rows, err := d.db.Query(`
+		SELECT id, uuid, workspace_path, COALESCE(workspace_name, ''), COALESCE(branch, ''), COALESCE(commit_sha, ''),
+		       started_at, last_activity
+		FROM sessions
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export sessions: %w", err)
+	}
+	for rows.Next() {
+		var s SyncSession
+		if err := rows.Scan(&s.ID, &s.UUID, &s.WorkspacePath, &s.WorkspaceName, &s.Branch,
+			&s.CommitSHA, &s.StartedAt, &s.LastActivity); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		batch.Sessions = append(batch.Sessions, &s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sessions := batch.Sessions
+	batch.Sessions = nil
+	for _, s := range sessions {
+		if s.Edits, err = d.syncEdits(s.ID, haveEdits); err != nil {
+			return nil, err
+		}
+		prompts, err := d.exportPrompts(s.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range prompts {
+			uuid, err := d.promptUUID(p.ID)
+			if err != nil {
+				return nil, err
+			}
+			if !havePrompts[uuid] {
+				s.Prompts = append(s.Prompts, &SyncPrompt{Prompt: p, UUID: uuid})
+			}
+		}
+		if len(s.Edits) > 0 || len(s.Prompts) > 0 {
+			batch.Sessions = append(batch.Sessions, s)
+		}
+	}
+
+	if batch.Library, err = d.GetLibraryPrompts(); err != nil {
+		return nil, err
+	}
+	return batch, nil
+}
+
+// syncEdits returns a session's edits missing from have, oldest first
+func (d *DB) syncEdits(sessionID int64, have map[string]bool) ([]*SyncEdit, error) {
+	rows, err := d.db.Query("SELECT id, uuid FROM edits WHERE session_id = ? ORDER BY timestamp, id", sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export edits: %w", err)
+	}
+	missing := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var uuid string
+		if err := rows.Scan(&id, &uuid); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan edit: %w", err)
+		}
+		if !have[uuid] {
+			missing[id] = uuid
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(missing) == 0 {
+		return nil, err
+	}
+
+	// Only the missing edits are sent, but reading the session's is simpler
+	// than listing their IDs in the query
+	edits, err := d.exportEdits(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	var synced []*SyncEdit
+	for _, e := range edits {
+		if uuid, ok := missing[e.ID]; ok {
+			synced = append(synced, &SyncEdit{EditDump: *e, UUID: uuid})
+		}
+	}
+	return synced, nil
+}
+
+// promptUUID returns a prompt's UUID
+func (d *DB) promptUUID(id int64) (string, error) {
+	var uuid string
+	if err := d.db.QueryRow("SELECT COALESCE(uuid, '') FROM prompts WHERE id = ?", id).Scan(&uuid); err != nil {
+		return "", fmt.Errorf("failed to get prompt: %w", err)
+	}
+	return uuid, nil
+}
+
+// ImportSync merges a batch into the database. A session is matched by
+// UUID, then by workspace and branch, and only created if neither
+// matches; edits and session prompts are added unless their UUID is
+// already here. Library prompts are matched by name, the most recently
+// updated winning.
+func (d *DB) ImportSync(batch *SyncBatch) (*SyncStats, error) {
+	stats := &SyncStats{}
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin sync: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, s := range batch.Sessions {
+		sessionID, created, err := syncSession(tx, s)
+		if err != nil {
+			return nil, err
+		}
+		if created {
+			stats.Sessions++
+		}
+
+		for _, e := range s.Edits {
+			var exists bool
+			if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM edits WHERE uuid = ?)", e.UUID).Scan(&exists); err != nil {
+				return nil, fmt.Errorf("failed to look up edit: %w", err)
+			}
+			if exists {
+				continue
+			}
+			var hash string
+			if len(e.Snapshot) > 0 {
+				content, err := decompressData(e.Snapshot)
+				if err != nil {
+					return nil, fmt.Errorf("failed to read snapshot of edit %s: %w", e.UUID, err)
+				}
+				if hash, err = d.storeBlob(tx, content); err != nil {
+					return nil, err
+				}
+			}
+			if _, err := tx.Exec(`
+				INSERT INTO edits (uuid, session_id, tool_name, file_path, old_string, new_string, line_num, line_count,
+				                   commit_sha, vcs_type, snapshot_hash, bookmarked, note, review, timestamp, chat_session_id, ralph_iteration, summary, user_name)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), ?, NULLIF(?, ''), NULLIF(?, ''), ?, NULLIF(?, ''), NULLIF(?, 0), NULLIF(?, ''), NULLIF(?, ''))
+			`, e.UUID, sessionID, e.ToolName, e.FilePath, e.OldString, e.NewString, e.LineNum, e.LineCount,
+				e.CommitSHA, e.VCSType, hash, e.Bookmarked, e.Note, e.Review, sqlTime(e.Timestamp), e.ChatSessionID, e.RalphIteration,
+				e.Summary, e.User); err != nil {
+				return nil, fmt.Errorf("failed to sync edit: %w", err)
+			}
+			stats.Edits++
+		}
+
+		for _, p := range s.Prompts {
+			tagsJSON, err := json.Marshal(p.Tags)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal tags: %w", err)
+			}
+			result, err := tx.Exec(`
+				INSERT INTO prompts (uuid, session_id, name, description, content, tags, version, is_global, created_at, updated_at)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT DO NOTHING
+			`, p.UUID, sessionID, p.Name, p.Description, p.Content, string(tagsJSON), p.Version, p.IsGlobal,
+				sqlTime(p.CreatedAt), sqlTime(p.UpdatedAt))
+			if err != nil {
+				return nil, fmt.Errorf("failed to sync prompt: %w", err)
+			}
+			if n, _ := result.RowsAffected(); n > 0 {
+				stats.Prompts++
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit sync: %w", err)
+	}
+
+	// Library prompts are saved with their versions one at a time
+	for _, p := range batch.Library {
+		var updated sql.NullTime
+		err := d.db.QueryRow("SELECT updated_at FROM prompts WHERE name = ? AND session_id IS NULL", p.Name).Scan(&updated)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to look up prompt %s: %w", p.Name, err)
+		}
+		if err == nil && !p.UpdatedAt.After(updated.Time) {
+			continue
+		}
+		if err := d.SaveLibraryPrompt(p); err != nil {
+			return nil, err
+		}
+		stats.Prompts++
+	}
+	return stats, nil
+}
+
+// syncSession returns the local session a synced one belongs to, creating
+// it if there's none for its workspace and branch
+func syncSession(tx *sql.Tx, s *SyncSession) (id int64, created bool, err error) {
+	err = tx.QueryRow(`
+		SELECT id FROM sessions
+		WHERE uuid = ? OR (workspace_path = ? AND COALESCE(branch, '') = ?)
+		ORDER BY uuid = ? DESC
+		LIMIT 1
+	`, s.UUID, s.WorkspacePath, s.Branch, s.UUID).Scan(&id)
+	if err == nil {
+		// The merged session spans both
+		_, err = tx.Exec(`
+			UPDATE sessions SET
+				started_at = MIN(started_at, ?),
+				last_activity = MAX(last_activity, ?)
+			WHERE id = ?
+		`, sqlTime(s.StartedAt), sqlTime(s.LastActivity), id)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to merge session: %w", err)
+		}
+		return id, false, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, false, fmt.Errorf("failed to look up session: %w", err)
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO sessions (uuid, workspace_path, workspace_name, branch, commit_sha, started_at, last_activity)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		RETURNING id
+	`, s.UUID, s.WorkspacePath, s.WorkspaceName, s.Branch, s.CommitSHA,
+		sqlTime(s.StartedAt), sqlTime(s.LastActivity)).Scan(&id)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to sync session: %w", err)
+	}
+	return id, true, nil
+}
+
+// Sync merges two databases both ways, returning what was added to each
+func Sync(local, remote *DB) (toLocal, toRemote *SyncStats, err error) {
+	localHave, err := local.SyncManifest()
+	if err != nil {
+		return nil, nil, err
+	}
+	remoteHave, err := remote.SyncManifest()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	forLocal, err := remote.ExportSync(localHave)
+	if err != nil {
+		return nil, nil, err
+	}
+	forRemote, err := local.ExportSync(remoteHave)
+	if err != nil {
+		return nil, nil, err
+	}
+	if toLocal, err = local.ImportSync(forLocal); err != nil {
+		return nil, nil, err
+	}
+	if toRemote, err = remote.ImportSync(forRemote); err != nil {
+		return nil, nil, err
+	}
+	return toLocal, toRemote, nil
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSync(t *testing.T) {
+	open := func(name string) *DB {
+		t.Helper()
+		db, err := Open(&Config{Path: filepath.Join(t.TempDir(), name)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Close() })
+		return db
+	}
+	record := func(db *DB, workspace, branch, file, content string) int64 {
+		t.Helper()
+		sessionID, err := db.UpsertSession(workspace, filepath.Base(workspace), branch, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.RecordEdit(&Edit{SessionID: sessionID, ToolName: "Edit", FilePath: file, FileContent: content}); err != nil {
+			t.Fatal(err)
+		}
+		return sessionID
+	}
+
+	laptop, desktop := open("laptop.db"), open("desktop.db")
+	record(laptop, "/src/api", "main", "/src/api/main.go", "package laptop")
+	session := record(desktop, "/src/api", "main", "/src/api/main.go", "package desktop")
+	record(desktop, "/src/web", "main", "/src/web/app.ts", "")
+	if _, err := desktop.RecordPrompt(&Prompt{SessionID: sql.NullInt64{Int64: session, Valid: true}, Name: "fix", Content: "fix it"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The same library prompt on both; the desktop's is newer
+	older := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	if err := laptop.SaveLibraryPrompt(&Prompt{Name: "review", Content: "old", Version: 1, UpdatedAt: older}); err != nil {
+		t.Fatal(err)
+	}
+	if err := desktop.SaveLibraryPrompt(&Prompt{Name: "review", Content: "new", Version: 2, UpdatedAt: older.Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+
+	toLaptop, toDesktop, err := Sync(laptop, desktop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *toLaptop != (SyncStats{Sessions: 1, Edits: 2, Prompts: 2}) {
+		t.Errorf("unexpected stats for the laptop: %+v", toLaptop)
+	}
+	if *toDesktop != (SyncStats{Edits: 1}) {
+		t.Errorf("unexpected stats for the desktop: %+v", toDesktop)
+	}
+
+	// Both have the whole history of main.go, under one session each
+	for name, db := range map[string]*DB{"laptop": laptop, "desktop": desktop} {
+		edits, err := db.GetEditsByFile("/src/api/main.go", 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(edits) != 2 || edits[0].SessionID != edits[1].SessionID {
+			t.Fatalf("expected two edits in one session on the %s, got %+v", name, edits)
+		}
+		contents := map[string]bool{edits[0].FileContent: true, edits[1].FileContent: true}
+		if !contents["package laptop"] || !contents["package desktop"] {
+			t.Errorf("expected both snapshots on the %s, got %v", name, contents)
+		}
+	}
+	library, err := laptop.GetLibraryPrompts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(library) != 1 || library[0].Content != "new" {
+		t.Errorf("expected the newer library prompt to win, got %+v", library)
+	}
+
+	// Syncing again adds nothing
+	toLaptop, toDesktop, err = Sync(laptop, desktop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *toLaptop != (SyncStats{}) || *toDesktop != (SyncStats{}) {
+		t.Errorf("expected nothing to sync twice, got %+v and %+v", toLaptop, toDesktop)
+	}
+}
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimelineEdit is an edit with the workspace and branch it was made in, as
+// listed in the timeline across workspaces. Its file snapshot is left out,
+// and its old and new strings unless they're asked for.
+type TimelineEdit struct {
+	Edit
+	WorkspacePath string `json:"workspace_path"`
+	WorkspaceName string `json:"workspace_name"`
+	Branch        string `json:"branch,omitempty"`
+}
+
+// EditCursor marks the last edit of a page of edits listed newest first;
+// the next page starts with the edit before it
+type EditCursor struct {
+	Timestamp time.Time
+	ID        int64
+}
+
+// String encodes the cursor for a query, e.g. "1735725600.42"
+func (c EditCursor) String() string {
+	return fmt.Sprintf("%d.%d", c.Timestamp.Unix(), c.ID)
+}
+
+// ParseEditCursor decodes a cursor encoded by String
+func ParseEditCursor(s string) (EditCursor, error) {
+	secs, id, ok := strings.Cut(s, ".")
+	if !ok {
+		return EditCursor{}, fmt.Errorf("invalid edit cursor %q", s)
+	}
+	unix, err := strconv.ParseInt(secs, 10, 64)
+	if err != nil {
+		return EditCursor{}, fmt.Errorf("invalid edit cursor %q", s)
+	}
+	editID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return EditCursor{}, fmt.Errorf("invalid edit cursor %q", s)
+	}
+	return EditCursor{Timestamp: time.Unix(unix, 0), ID: editID}, nil
+}
+
+// TimelineQuery selects edits across workspaces, newest first
+type TimelineQuery struct {
+	Workspaces []string   // Optional set of workspaces; empty means all
+	Since      time.Time  // Only edits made since this time, when set
+	Before     EditCursor // Only edits before this one, when set
+	Limit      int        // Edits returned at most; 0 for all
+	Content    bool       // Include the old and new strings
+	User       string     // Only edits made by this user, when set
+}
+
+// GetTimeline returns edits across workspaces, newest first, starting
+// before the cursor (the newest edit if it's zero). Edits are optionally
+// limited to a set of workspaces and to those made since a time.
+func (d *DB) GetTimeline(workspaces []string, since time.Time, before EditCursor, limit int) ([]*TimelineEdit, error) {
+	var edits []*TimelineEdit
+	err := d.StreamTimeline(context.Background(), TimelineQuery{
+		Workspaces: workspaces,
+		Since:      since,
+		Before:     before,
+		Limit:      limit,
+	}, func(e *TimelineEdit) error {
+		edits = append(edits, e)
+		return nil
+	})
+	return edits, err
+}
+
+// StreamTimeline calls fn with each edit a timeline query selects, newest
+// first, without holding them all in memory. It stops at the first error
+// fn returns, or when ctx is cancelled.
+func (d *DB) StreamTimeline(ctx context.Context, q TimelineQuery, fn func(*TimelineEdit) error) error {
+	content := "'', ''"
+	if q.Content {
+		content = "e.old_string, e.new_string"
+	}
+	query := `
+		SELECT e.id, e.session_id, e.tool_name, e.file_path, e.line_num, e.line_count,
+		       COALESCE(e.commit_sha, ''), COALESCE(e.vcs_type, ''), COALESCE(e.bookmarked, 0), COALESCE(e.note, ''), COALESCE(e.review, ''),
+		       e.timestamp, COALESCE(e.chat_session_id, ''), COALESCE(e.summary, ''), COALESCE(e.user_name, ''), ` + content + `,
+		       s.workspace_path, COALESCE(s.workspace_name, ''), COALESCE(s.branch, '')
+		FROM edits e
+		JOIN sessions s ON e.session_id = s.id
+		WHERE 1 = 1
+	`
+	var args []any
+	if !q.Since.IsZero() {
+		query += " AND e.timestamp >= ?"
+		args = append(args, sqlTime(q.Since))
+	}
+	if q.Before.ID > 0 {
+		// Edits made in the same second are ordered by ID
+		query += " AND (e.timestamp < ? OR (e.timestamp = ? AND e.id < ?))"
+		args = append(args, sqlTime(q.Before.Timestamp), sqlTime(q.Before.Timestamp), q.Before.ID)
+	}
+	if q.User != "" {
+		query += " AND e.user_name = ?"
+		args = append(args, q.User)
+	}
+	if len(q.Workspaces) > 0 {
+		query += " AND s.workspace_path IN (" + placeholders(len(q.Workspaces)) + ")"
+		for _, w := range q.Workspaces {
+			args = append(args, w)
+		}
+	}
+	query += " ORDER BY e.timestamp DESC, e.id DESC"
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to get timeline: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e TimelineEdit
+		if err := rows.Scan(&e.ID, &e.SessionID, &e.ToolName, &e.FilePath, &e.LineNum, &e.LineCount,
+			&e.CommitSHA, &e.VCSType, &e.Bookmarked, &e.Note, &e.Review, &e.Timestamp, &e.ChatSessionID, &e.Summary, &e.User,
+			&e.OldString, &e.NewString, &e.WorkspacePath, &e.WorkspaceName, &e.Branch); err != nil {
+			return fmt.Errorf("failed to scan timeline edit: %w", err)
+		}
+		if err := fn(&e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// GetUserWorkspaces returns the workspaces a user has made edits in
+func (d *DB) GetUserWorkspaces(user string) ([]string, error) {
+	return d.queryStrings(`
+		SELECT DISTINCT s.workspace_path
+		FROM edits e
+		JOIN sessions s ON e.session_id = s.id
+		WHERE e.user_name = ?
+		ORDER BY s.workspace_path
+	`, user)
+}
+
+// GetWorkspaceUsers returns the users who have made edits in a workspace.
+// Edits recorded before edits were tagged belong to no one.
+func (d *DB) GetWorkspaceUsers(workspacePath string) ([]string, error) {
+	return d.queryStrings(`
+		SELECT DISTINCT e.user_name
+		FROM edits e
+		JOIN sessions s ON e.session_id = s.id
+		WHERE s.workspace_path = ? AND e.user_name IS NOT NULL
+		ORDER BY e.user_name
+	`, workspacePath)
+}
+
+// GetChatSessionWorkspace returns the workspace a chat session was run
+// in, or "" if the session isn't known
+func (d *DB) GetChatSessionWorkspace(sessionID string) (string, error) {
+	var path string
+	err := d.db.QueryRow(`SELECT COALESCE(workspace_path, '') FROM chat_sessions WHERE session_id = ?`, sessionID).Scan(&path)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get chat session: %w", err)
+	}
+	return path, nil
+}
+
+// queryStrings returns the first column of a query's rows
+func (d *DB) queryStrings(query string, args ...any) ([]string, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	defer rows.Close()
+
+	var values []string
+	for rows.Next() {
+		var v string
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
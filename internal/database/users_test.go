@@ -0,0 +1,67 @@
+package database
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestEditUsers(t *testing.T) {
+	db, err := Open(&Config{Path: filepath.Join(t.TempDir(), "users.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	record := func(workspace, user string) {
+		t.Helper()
+		sessionID, err := db.UpsertSession(workspace, filepath.Base(workspace), "main", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.RecordEdit(&Edit{SessionID: sessionID, ToolName: "Edit", FilePath: workspace + "/main.go", User: user}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record("/src/api", "alice")
+	record("/src/api", "bob")
+	record("/src/web", "bob")
+	record("/src/old", "") // Recorded before edits were tagged
+
+	if got, _ := db.GetUserWorkspaces("bob"); !slices.Equal(got, []string{"/src/api", "/src/web"}) {
+		t.Errorf("expected bob's two workspaces, got %v", got)
+	}
+	if got, _ := db.GetWorkspaceUsers("/src/api"); !slices.Equal(got, []string{"alice", "bob"}) {
+		t.Errorf("expected both users in /src/api, got %v", got)
+	}
+	if got, _ := db.GetWorkspaceUsers("/src/old"); len(got) != 0 {
+		t.Errorf("expected untagged edits to belong to no one, got %v", got)
+	}
+
+	edits, err := db.GetFilteredEdits(EditFilter{User: "alice"}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(edits) != 1 || edits[0].User != "alice" || edits[0].FilePath != "/src/api/main.go" {
+		t.Errorf("expected alice's one edit, got %+v", edits)
+	}
+	if edits, _ := db.GetFilteredEdits(EditFilter{FilePath: "/src/web/main.go"}, 10); len(edits) != 1 || edits[0].User != "bob" {
+		t.Errorf("expected bob's edit to /src/web/main.go, got %+v", edits)
+	}
+
+	// Unfiltered lookups carry the user too
+	if edits, _ := db.GetRecentEdits(10); len(edits) != 4 || edits[0].User != "" || edits[1].User != "bob" {
+		t.Errorf("expected recent edits tagged with their users, got %+v", edits)
+	}
+	if edits, _ := db.GetEditsByWorkspace("/src/web", 10); len(edits) != 1 || edits[0].User != "bob" {
+		t.Errorf("expected bob's edit in /src/web, got %+v", edits)
+	}
+	edits, _ = db.GetEditsByFile("/src/api/main.go", 10)
+	var users []string
+	for _, e := range edits {
+		users = append(users, e.User)
+	}
+	if slices.Sort(users); !slices.Equal(users, []string{"alice", "bob"}) {
+		t.Errorf("expected both users' edits to /src/api/main.go, got %v", users)
+	}
+}
@@ -0,0 +1,39 @@
+package database
+
+import "strings"
+
+// ComposeFileVersions fills in the content after each of a file's edits,
+// given oldest first, where it wasn't stored: a neighbour's content with
+// the edit applied, or the next edit undone. A Write's content is what it
+// wrote. Edits no stored content reaches are left empty.
+func ComposeFileVersions(edits []*Edit) {
+	for _, e := range edits {
+		if e.FileContent == "" && e.ToolName == "Write" {
+			e.FileContent = e.NewString
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for i := 1; i < len(edits); i++ {
+			e, prev := edits[i], edits[i-1]
+			if e.FileContent != "" || prev.FileContent == "" || e.OldString == "" {
+				continue
+			}
+			if strings.Contains(prev.FileContent, e.OldString) {
+				e.FileContent = strings.Replace(prev.FileContent, e.OldString, e.NewString, 1)
+				changed = true
+			}
+		}
+		for i := len(edits) - 2; i >= 0; i-- {
+			e, next := edits[i], edits[i+1]
+			if e.FileContent != "" || next.FileContent == "" || next.ToolName == "Write" || next.NewString == "" {
+				continue
+			}
+			if strings.Contains(next.FileContent, next.NewString) {
+				e.FileContent = strings.Replace(next.FileContent, next.NewString, next.OldString, 1)
+				changed = true
+			}
+		}
+	}
+}
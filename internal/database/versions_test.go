@@ -0,0 +1,21 @@
+package database
+
+import "testing"
+
+func TestComposeFileVersions(t *testing.T) {
+	edits := []*Edit{
+		{ToolName: "Write", NewString: "a\nb\nc\n"},
+		{ToolName: "Edit", OldString: "b", NewString: "B"}, // Applied to the Write
+		{ToolName: "Edit", OldString: "c", NewString: "C"}, // Undone from the next
+		{ToolName: "Edit", OldString: "a", NewString: "A", FileContent: "A\nB\nC\n"},
+		{ToolName: "Edit", OldString: "x", NewString: "y"}, // Doesn't apply
+	}
+	ComposeFileVersions(edits)
+
+	want := []string{"a\nb\nc\n", "a\nB\nc\n", "a\nB\nC\n", "A\nB\nC\n", ""}
+	for i, e := range edits {
+		if e.FileContent != want[i] {
+			t.Errorf("version %d = %q, want %q", i, e.FileContent, want[i])
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package diff
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"  // Register GIF for DecodeConfig
+	_ "image/jpeg" // Register JPEG for DecodeConfig
+	_ "image/png"  // Register PNG for DecodeConfig
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ztaylor/claude-mon/internal/theme"
+)
+
+// binarySniffBytes is how much of a text is checked for binary content
+const binarySniffBytes = 8000
+
+// IsBinary reports whether a text is binary rather than printable: it
+// holds a NUL byte or isn't valid UTF-8 near its start. Text with a UTF-16
+// byte order mark is not binary.
+func IsBinary(text string) bool {
+	if text == "" {
+		return false
+	}
+	if strings.HasPrefix(text, bomUTF16LE) || strings.HasPrefix(text, bomUTF16BE) {
+		return false
+	}
+	head := text[:min(len(text), binarySniffBytes)]
+	if strings.IndexByte(head, 0) >= 0 {
+		return true
+	}
+	for i := 0; i < len(head); {
+		r, size := utf8.DecodeRuneInString(head[i:])
+		if r == utf8.RuneError && size == 1 {
+			// A rune cut off at the sniff boundary is still text
+			return len(head) == len(text) || len(head)-i >= utf8.UTFMax
+		}
+		i += size
+	}
+	return false
+}
+
+// BinaryInfo describes binary content for a diff summary
+type BinaryInfo struct {
+	Size   int    // Bytes
+	Format string // e.g. "PNG image" or "application/pdf"
+	Width  int    // Image dimensions, 0 if not an image
+	Height int
+}
+
+// DescribeBinary detects a binary's format, decoding the dimensions of
+// PNG, JPEG and GIF images
+func DescribeBinary(content string) BinaryInfo {
+	info := BinaryInfo{Size: len(content)}
+	if cfg, format, err := image.DecodeConfig(strings.NewReader(content)); err == nil {
+		info.Format = strings.ToUpper(format) + " image"
+		info.Width, info.Height = cfg.Width, cfg.Height
+		return info
+	}
+	info.Format, _, _ = strings.Cut(http.DetectContentType([]byte(content)), ";")
+	return info
+}
+
+// String describes the binary, e.g. "12.3 KB PNG image 640×480"
+func (b BinaryInfo) String() string {
+	s := formatSize(b.Size) + " " + b.Format
+	if b.Width > 0 || b.Height > 0 {
+		s += fmt.Sprintf(" %d×%d", b.Width, b.Height)
+	}
+	return s
+}
+
+// formatSize formats a byte count, e.g. "512 B" or "1.5 MB"
+func formatSize(n int) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, suffix := float64(n)/unit, "KB"
+	for _, s := range []string{"MB", "GB"} {
+		if value < unit {
+			break
+		}
+		value, suffix = value/unit, s
+	}
+	return fmt.Sprintf("%.1f %s", value, suffix)
+}
+
+// FormatBinary summarizes a change to binary content instead of printing
+// it: the size and format before and after, with image dimensions. Empty
+// content is shown as missing.
+func FormatBinary(oldText, newText string, t *theme.Theme) string {
+	var sb strings.Builder
+	sb.WriteString(t.DiffHeader.Render("@@ Binary file @@"))
+	sb.WriteString("\n")
+
+	describe := func(content string) string {
+		if content == "" {
+			return "(none)"
+		}
+		return DescribeBinary(content).String()
+	}
+	sb.WriteString(t.Removed.Render("- Before: " + describe(oldText)))
+	sb.WriteString("\n")
+	sb.WriteString(t.Added.Render("+ After:  " + describe(newText)))
+	sb.WriteString("\n")
+
+	if oldText != "" && newText != "" {
+		delta := len(newText) - len(oldText)
+		switch {
+		case oldText == newText:
+			sb.WriteString(t.Dim.Render("  Content unchanged"))
+		case delta >= 0:
+			sb.WriteString(t.Dim.Render("  +" + formatSize(delta)))
+		default:
+			sb.WriteString(t.Dim.Render("  -" + formatSize(-delta)))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -0,0 +1,56 @@
+package diff
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestIsBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want bool
+	}{
+		{"empty", "", false},
+		{"text", "package main\n", false},
+		{"utf-8", "héllo wörld ✓\n", false},
+		{"nul", "abc\x00def", true},
+		{"invalid utf-8", "\xff\xd8\xff\xe0JFIF", true},
+		{"utf-16 bom", "\xff\xfea\x00", false},
+		{"rune cut at sniff boundary", strings.Repeat("a", binarySniffBytes-1) + "✓", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsBinary(tt.text); got != tt.want {
+				t.Errorf("IsBinary() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDescribeBinary(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 64, 32))); err != nil {
+		t.Fatal(err)
+	}
+	info := DescribeBinary(buf.String())
+	if info.Format != "PNG image" || info.Width != 64 || info.Height != 32 {
+		t.Errorf("DescribeBinary(png) = %+v", info)
+	}
+
+	info = DescribeBinary("%PDF-1.7\n\x00\x01")
+	if info.Format != "application/pdf" || info.Width != 0 {
+		t.Errorf("DescribeBinary(pdf) = %+v", info)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for n, want := range map[int]string{0: "0 B", 512: "512 B", 1536: "1.5 KB", 3 << 20: "3.0 MB"} {
+		if got := formatSize(n); got != want {
+			t.Errorf("formatSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
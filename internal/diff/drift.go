@@ -0,0 +1,118 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ztaylor/claude-mon/internal/theme"
+)
+
+// DriftStatus describes whether a recorded change is still present in the
+// file's current on-disk content
+type DriftStatus string
+
+const (
+	DriftApplied  DriftStatus = "applied"  // New content is intact
+	DriftDrifted  DriftStatus = "drifted"  // New content is partially overwritten
+	DriftReverted DriftStatus = "reverted" // New content is gone
+)
+
+// DriftLine is a line of the recorded new content and whether it is still on disk
+type DriftLine struct {
+	Content string
+	Present bool
+}
+
+// DriftResult is the outcome of comparing a change against the working tree
+type DriftResult struct {
+	Status  DriftStatus
+	Lines   []DriftLine // Lines of the recorded new content
+	Present int         // Non-blank new lines still on disk
+	Total   int         // Non-blank new lines
+}
+
+// CheckDrift compares a recorded change (oldText -> newText) against the
+// file's current content. Each non-blank new line is matched at most once
+// against the current lines, ignoring trailing whitespace.
+func CheckDrift(oldText, newText, current string) DriftResult {
+	// Pure deletions are applied while the old content stays gone
+	if newText == "" {
+		if oldText != "" && strings.Contains(current, oldText) {
+			return DriftResult{Status: DriftReverted}
+		}
+		return DriftResult{Status: DriftApplied}
+	}
+
+	var result DriftResult
+	intact := strings.Contains(current, newText)
+
+	available := make(map[string]int)
+	for _, line := range SplitLines(current) {
+		available[strings.TrimRight(line, " \t\r")]++
+	}
+
+	for _, line := range SplitLines(newText) {
+		key := strings.TrimRight(line, " \t\r")
+		present := intact || strings.TrimSpace(key) == ""
+		if !present && available[key] > 0 {
+			available[key]--
+			present = true
+		}
+		if strings.TrimSpace(key) != "" {
+			result.Total++
+			if present {
+				result.Present++
+			}
+		}
+		result.Lines = append(result.Lines, DriftLine{Content: line, Present: present})
+	}
+
+	switch {
+	case intact:
+		result.Status = DriftApplied
+	case result.Present == 0:
+		result.Status = DriftReverted
+	case oldText != "" && strings.Contains(current, oldText):
+		// The original content is back alongside stray new lines
+		result.Status = DriftReverted
+	default:
+		result.Status = DriftDrifted
+	}
+
+	return result
+}
+
+// FormatDrift formats a drift result, marking new lines missing from disk
+func FormatDrift(result DriftResult, t *theme.Theme) string {
+	var sb strings.Builder
+
+	sb.WriteString(t.DiffHeader.Render(fmt.Sprintf("@@ working tree: %s @@", result.Status)))
+	if result.Total > 0 {
+		sb.WriteString("  ")
+		sb.WriteString(t.Dim.Render(fmt.Sprintf("%d/%d lines present", result.Present, result.Total)))
+	}
+	sb.WriteString("\n\n")
+
+	if len(result.Lines) == 0 {
+		if result.Status == DriftReverted {
+			sb.WriteString(t.Removed.Render("Deleted content is back in the file"))
+		} else {
+			sb.WriteString(t.Dim.Render("Deleted content is still gone"))
+		}
+		sb.WriteString("\n")
+		return sb.String()
+	}
+
+	for i, line := range result.Lines {
+		sb.WriteString(t.LineNumber.Render(fmt.Sprintf("%4d", i+1)))
+		sb.WriteString(" ")
+		if line.Present {
+			sb.WriteString(t.Added.Render("  " + line.Content))
+		} else {
+			sb.WriteString(t.Removed.Render("✗ " + line.Content))
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
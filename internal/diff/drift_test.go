@@ -0,0 +1,36 @@
+package diff
+
+import "testing"
+
+func TestCheckDrift(t *testing.T) {
+	oldText := "func a() {\n\treturn 1\n}"
+	newText := "func a() {\n\treturn 2\n}"
+
+	tests := []struct {
+		name    string
+		current string
+		want    DriftStatus
+	}{
+		{"intact", "package x\n\nfunc a() {\n\treturn 2\n}\n", DriftApplied},
+		{"reverted", "package x\n\nfunc a() {\n\treturn 1\n}\n", DriftReverted},
+		{"gone", "package x\n", DriftReverted},
+		{"drifted", "package x\n\nfunc a() {\n\treturn 2 + 1\n}\n", DriftDrifted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CheckDrift(oldText, newText, tt.current)
+			if got.Status != tt.want {
+				t.Errorf("expected %s, got %s (%d/%d present)", tt.want, got.Status, got.Present, got.Total)
+			}
+		})
+	}
+
+	// Pure deletions
+	if got := CheckDrift("remove me\n", "", "keep\n"); got.Status != DriftApplied {
+		t.Errorf("deletion still gone: expected applied, got %s", got.Status)
+	}
+	if got := CheckDrift("remove me\n", "", "keep\nremove me\n"); got.Status != DriftReverted {
+		t.Errorf("deletion restored: expected reverted, got %s", got.Status)
+	}
+}
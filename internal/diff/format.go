@@ -44,8 +44,9 @@ type DiffStats struct {
 
 // FormatOptions configures diff formatting
 type FormatOptions struct {
-	ContextLines int  // Number of context lines to show
-	ShowStats    bool // Show addition/deletion stats
+	ContextLines int              // Number of context lines to show
+	ShowStats    bool             // Show addition/deletion stats
+	Normalize    NormalizeOptions // Changes to hide, noted in the header
 }
 
 // DefaultOptions returns sensible default options
@@ -61,6 +62,17 @@ func FormatDiff(oldText, newText string, t *theme.Theme, opts FormatOptions) str
 	if oldText == "" && newText == "" {
 		return t.Dim.Render("No content to diff")
 	}
+	if IsBinary(oldText) || IsBinary(newText) {
+		return FormatBinary(oldText, newText, t)
+	}
+
+	var hidden Hidden
+	if opts.Normalize.Enabled() && oldText != "" && newText != "" {
+		oldText, newText, hidden = opts.Normalize.Normalize(oldText, newText)
+	}
+	if hidden.Any() && oldText == newText {
+		return formatHidden(hidden, t) + "\n" + t.Dim.Render("No changes after normalization") + "\n"
+	}
 
 	// Handle new file case
 	if oldText == "" {
@@ -78,6 +90,10 @@ func FormatDiff(oldText, newText string, t *theme.Theme, opts FormatOptions) str
 	newHasNewline := strings.Contains(newText, "\n")
 
 	if !oldHasNewline && !newHasNewline {
+		if opts.Normalize.IgnoreWhitespace && whitespaceKey(oldText) == whitespaceKey(newText) {
+			hidden.Whitespace++
+			return formatHidden(hidden, t) + "\n" + t.Dim.Render("No changes after normalization") + "\n"
+		}
 		return formatSimpleDiff(oldText, newText, t)
 	}
 
@@ -90,13 +106,8 @@ func FormatDiff(oldText, newText string, t *theme.Theme, opts FormatOptions) str
 	}
 
 	// Use line-mode diff for better results
-	dmp := diffmatchpatch.New()
-
-	// Convert to line-based diff
-	a, b, lineArray := dmp.DiffLinesToChars(oldText, newText)
-	diffs := dmp.DiffMain(a, b, false)
-	diffs = dmp.DiffCharsToLines(diffs, lineArray)
-	diffs = dmp.DiffCleanupSemantic(diffs)
+	diffs, whitespace := lineDiffs(oldText, newText, opts.Normalize.IgnoreWhitespace)
+	hidden.Whitespace += whitespace
 
 	// Convert to our line format
 	lines := convertToLines(diffs)
@@ -109,6 +120,10 @@ func FormatDiff(oldText, newText string, t *theme.Theme, opts FormatOptions) str
 		sb.WriteString(formatHeader(stats, t))
 		sb.WriteString("\n")
 	}
+	if hidden.Any() {
+		sb.WriteString(formatHidden(hidden, t))
+		sb.WriteString("\n")
+	}
 
 	// Write diff lines
 	for _, line := range lines {
@@ -178,6 +193,11 @@ func formatHeader(stats DiffStats, t *theme.Theme) string {
 	return t.DiffHeader.Render(header) + statsText
 }
 
+// formatHidden notes the changes normalization hid from a diff
+func formatHidden(hidden Hidden, t *theme.Theme) string {
+	return t.Dim.Render("≈ hidden: " + hidden.String())
+}
+
 func formatLine(line DiffLine, t *theme.Theme) string {
 	// Format line numbers
 	var lineNumStr string
@@ -0,0 +1,33 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Markdown formats an edit as a fenced diff block for pasting into a
+// prompt or report: the old text's lines with "-", then the new text's with
+// "+". Each side is cut off after maxLines lines, if maxLines > 0.
+func Markdown(oldText, newText string, maxLines int) string {
+	var sb strings.Builder
+	sb.WriteString("```diff\n")
+	writeMarkdownLines(&sb, "-", oldText, maxLines)
+	writeMarkdownLines(&sb, "+", newText, maxLines)
+	sb.WriteString("```\n")
+	return sb.String()
+}
+
+// writeMarkdownLines writes text's lines with a diff prefix
+func writeMarkdownLines(sb *strings.Builder, prefix, text string, maxLines int) {
+	if text == "" {
+		return
+	}
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i, line := range lines {
+		if maxLines > 0 && i == maxLines {
+			fmt.Fprintf(sb, "%s… %d more lines\n", prefix, len(lines)-i)
+			return
+		}
+		sb.WriteString(prefix + line + "\n")
+	}
+}
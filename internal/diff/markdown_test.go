@@ -0,0 +1,23 @@
+package diff
+
+import "testing"
+
+func TestMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		maxLines int
+		want     string
+	}{
+		{"edit", "a\nb\n", "c\n", 0, "```diff\n-a\n-b\n+c\n```\n"},
+		{"write", "", "package main", 0, "```diff\n+package main\n```\n"},
+		{"cut off", "", "1\n2\n3\n4\n", 2, "```diff\n+1\n+2\n+… 2 more lines\n```\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Markdown(tt.old, tt.new, tt.maxLines); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
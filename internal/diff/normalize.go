@@ -0,0 +1,241 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// NormalizeOptions hides changes that leave a file's text the same, such as
+// a rewrite that switches CRLF to LF or adds a byte order mark
+type NormalizeOptions struct {
+	IgnoreWhitespace bool // Compare lines with runs of whitespace collapsed and trimmed
+	IgnoreEOL        bool // Treat CRLF, CR and LF line endings alike
+	DetectEncoding   bool // Strip byte order marks and decode UTF-16 before comparing
+}
+
+// Enabled reports whether any normalization applies
+func (o NormalizeOptions) Enabled() bool {
+	return o.IgnoreWhitespace || o.IgnoreEOL || o.DetectEncoding
+}
+
+// Byte order marks
+const (
+	bomUTF8    = "\xef\xbb\xbf"
+	bomUTF16LE = "\xff\xfe"
+	bomUTF16BE = "\xfe\xff"
+)
+
+// TextFormat is how a text is encoded and which line endings it uses
+type TextFormat struct {
+	Encoding string // "UTF-8", "UTF-8 BOM", "UTF-16LE" or "UTF-16BE"
+	EOL      string // "LF", "CRLF", "CR", "mixed", or "" for a single line
+}
+
+// DetectFormat detects a text's encoding from its byte order mark and its
+// line endings
+func DetectFormat(text string) TextFormat {
+	f := TextFormat{Encoding: "UTF-8"}
+	switch {
+	case strings.HasPrefix(text, bomUTF8):
+		f.Encoding = "UTF-8 BOM"
+	case strings.HasPrefix(text, bomUTF16LE):
+		f.Encoding = "UTF-16LE"
+	case strings.HasPrefix(text, bomUTF16BE):
+		f.Encoding = "UTF-16BE"
+	}
+	f.EOL = detectEOL(decode(text))
+	return f
+}
+
+func detectEOL(text string) string {
+	crlf := strings.Count(text, "\r\n")
+	lf := strings.Count(text, "\n") - crlf
+	cr := strings.Count(text, "\r") - crlf
+	kinds := 0
+	eol := ""
+	for _, k := range []struct {
+		name  string
+		count int
+	}{{"CRLF", crlf}, {"LF", lf}, {"CR", cr}} {
+		if k.count > 0 {
+			kinds++
+			eol = k.name
+		}
+	}
+	if kinds > 1 {
+		return "mixed"
+	}
+	return eol
+}
+
+// decode strips a byte order mark, converting UTF-16 to UTF-8
+func decode(text string) string {
+	var bigEndian bool
+	switch {
+	case strings.HasPrefix(text, bomUTF8):
+		return text[len(bomUTF8):]
+	case strings.HasPrefix(text, bomUTF16LE):
+	case strings.HasPrefix(text, bomUTF16BE):
+		bigEndian = true
+	default:
+		return text
+	}
+
+	b := text[2:]
+	units := make([]uint16, 0, len(b)/2)
+	for i := 0; i+1 < len(b); i += 2 {
+		if bigEndian {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		} else {
+			units = append(units, uint16(b[i+1])<<8|uint16(b[i]))
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// Hidden describes the changes normalization hid from a diff
+type Hidden struct {
+	Encoding   string // e.g. "UTF-8 BOM→UTF-8"; empty if unchanged
+	EOL        string // e.g. "CRLF→LF"; empty if unchanged
+	Whitespace int    // Lines whose only change was whitespace
+}
+
+// Any reports whether normalization hid anything
+func (h Hidden) Any() bool {
+	return h.Encoding != "" || h.EOL != "" || h.Whitespace > 0
+}
+
+// String describes what was hidden, e.g. "line endings CRLF→LF, whitespace
+// in 3 lines"
+func (h Hidden) String() string {
+	var parts []string
+	if h.Encoding != "" {
+		parts = append(parts, "encoding "+h.Encoding)
+	}
+	if h.EOL != "" {
+		parts = append(parts, "line endings "+h.EOL)
+	}
+	if h.Whitespace == 1 {
+		parts = append(parts, "whitespace in 1 line")
+	} else if h.Whitespace > 1 {
+		parts = append(parts, fmt.Sprintf("whitespace in %d lines", h.Whitespace))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Normalize applies the encoding and line ending options to both sides of
+// a diff, returning the texts to compare and what they no longer differ by.
+// Whitespace is compared per line by the diff itself.
+func (o NormalizeOptions) Normalize(oldText, newText string) (string, string, Hidden) {
+	var hidden Hidden
+	if o.DetectEncoding {
+		oldEnc, newEnc := DetectFormat(oldText).Encoding, DetectFormat(newText).Encoding
+		if oldEnc != newEnc {
+			hidden.Encoding = oldEnc + "→" + newEnc
+		}
+		oldText, newText = decode(oldText), decode(newText)
+	}
+	if o.IgnoreEOL {
+		oldEOL, newEOL := detectEOL(oldText), detectEOL(newText)
+		if oldEOL != newEOL && oldEOL != "" && newEOL != "" {
+			hidden.EOL = oldEOL + "→" + newEOL
+		}
+		oldText, newText = toLF(oldText), toLF(newText)
+	}
+	return oldText, newText, hidden
+}
+
+// Hides reports whether normalization hides any difference between two
+// texts: a change of encoding or line endings, or one only of whitespace
+func (o NormalizeOptions) Hides(oldText, newText string) bool {
+	oldText, newText, hidden := o.Normalize(oldText, newText)
+	if hidden.Any() {
+		return true
+	}
+	if !o.IgnoreWhitespace || oldText == newText {
+		return false
+	}
+	oldLines, newLines := strings.Split(oldText, "\n"), strings.Split(newText, "\n")
+	if len(oldLines) != len(newLines) {
+		return false
+	}
+	for i := range oldLines {
+		if whitespaceKey(oldLines[i]) != whitespaceKey(newLines[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func toLF(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "\r\n", "\n"), "\r", "\n")
+}
+
+// whitespaceKey is the text a line is compared by when whitespace is ignored
+func whitespaceKey(line string) string {
+	return strings.Join(strings.Fields(line), " ")
+}
+
+// lineDiffs diffs two newline-terminated texts line by line. With
+// ignoreWhitespace, lines that differ only in whitespace compare equal and
+// show with their new text; the count of such lines is returned.
+func lineDiffs(oldText, newText string, ignoreWhitespace bool) ([]diffmatchpatch.Diff, int) {
+	dmp := diffmatchpatch.New()
+	if !ignoreWhitespace {
+		a, b, lineArray := dmp.DiffLinesToChars(oldText, newText)
+		diffs := dmp.DiffMain(a, b, false)
+		diffs = dmp.DiffCharsToLines(diffs, lineArray)
+		return dmp.DiffCleanupSemantic(diffs), 0
+	}
+
+	oldLines := strings.SplitAfter(strings.TrimSuffix(oldText, "\n"), "\n")
+	newLines := strings.SplitAfter(strings.TrimSuffix(newText, "\n"), "\n")
+	keys := func(lines []string) string {
+		var sb strings.Builder
+		for _, line := range lines {
+			sb.WriteString(whitespaceKey(line))
+			sb.WriteString("\n")
+		}
+		return sb.String()
+	}
+
+	// Cleaning up while each line is still one character keeps the diffs
+	// on line boundaries
+	a, b, lineArray := dmp.DiffLinesToChars(keys(oldLines), keys(newLines))
+	diffs := dmp.DiffCleanupSemantic(dmp.DiffMain(a, b, false))
+	diffs = dmp.DiffCharsToLines(diffs, lineArray)
+
+	// Swap the keys back for the lines they stand for
+	var oi, ni, hidden int
+	take := func(lines []string, i *int, n int) string {
+		s := strings.Join(lines[*i:*i+n], "")
+		*i += n
+		if !strings.HasSuffix(s, "\n") {
+			s += "\n"
+		}
+		return s
+	}
+	out := make([]diffmatchpatch.Diff, 0, len(diffs))
+	for _, d := range diffs {
+		n := strings.Count(d.Text, "\n")
+		switch d.Type {
+		case diffmatchpatch.DiffDelete:
+			d.Text = take(oldLines, &oi, n)
+		case diffmatchpatch.DiffInsert:
+			d.Text = take(newLines, &ni, n)
+		default:
+			for i := 0; i < n; i++ {
+				if strings.TrimSuffix(oldLines[oi+i], "\n") != strings.TrimSuffix(newLines[ni+i], "\n") {
+					hidden++
+				}
+			}
+			oi += n
+			d.Text = take(newLines, &ni, n)
+		}
+		out = append(out, d)
+	}
+	return out, hidden
+}
@@ -0,0 +1,94 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want TextFormat
+	}{
+		{"lf", "a\nb\n", TextFormat{"UTF-8", "LF"}},
+		{"crlf", "a\r\nb\r\n", TextFormat{"UTF-8", "CRLF"}},
+		{"mixed", "a\r\nb\n", TextFormat{"UTF-8", "mixed"}},
+		{"single line", "a", TextFormat{"UTF-8", ""}},
+		{"utf-8 bom", "\xef\xbb\xbfa\n", TextFormat{"UTF-8 BOM", "LF"}},
+		{"utf-16le", "\xff\xfea\x00\r\x00\n\x00", TextFormat{"UTF-16LE", "CRLF"}},
+		{"utf-16be", "\xfe\xff\x00a\x00\n", TextFormat{"UTF-16BE", "LF"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DetectFormat(tt.text); got != tt.want {
+				t.Errorf("DetectFormat(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	opts := NormalizeOptions{IgnoreEOL: true, DetectEncoding: true}
+
+	oldText, newText, hidden := opts.Normalize("\xef\xbb\xbfa\r\nb\r\n", "a\nb\n")
+	if oldText != newText {
+		t.Errorf("expected equal texts, got %q and %q", oldText, newText)
+	}
+	if want := "encoding UTF-8 BOM→UTF-8, line endings CRLF→LF"; hidden.String() != want {
+		t.Errorf("hidden = %q, want %q", hidden, want)
+	}
+
+	// Disabled options leave the texts alone
+	oldText, newText, hidden = NormalizeOptions{}.Normalize("a\r\n", "a\n")
+	if oldText == newText || hidden.Any() {
+		t.Errorf("expected no normalization, got %q, %q, %+v", oldText, newText, hidden)
+	}
+}
+
+func TestLineDiffsIgnoreWhitespace(t *testing.T) {
+	diffs, hidden := lineDiffs("func a() {\n  return 1\n}\n", "func a() {\n\treturn 1\n}\nx\n", true)
+	if hidden != 1 {
+		t.Errorf("expected 1 whitespace-only line hidden, got %d", hidden)
+	}
+
+	var inserted, deleted []string
+	for _, d := range diffs {
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			inserted = append(inserted, d.Text)
+		case diffmatchpatch.DiffDelete:
+			deleted = append(deleted, d.Text)
+		case diffmatchpatch.DiffEqual:
+			if !strings.Contains(d.Text, "\treturn 1") {
+				t.Errorf("expected equal lines shown with their new text, got %q", d.Text)
+			}
+		}
+	}
+	if len(deleted) != 0 || len(inserted) != 1 || inserted[0] != "x\n" {
+		t.Errorf("expected only x inserted, got +%q -%q", inserted, deleted)
+	}
+}
+
+func TestHides(t *testing.T) {
+	tests := []struct {
+		name             string
+		opts             NormalizeOptions
+		oldText, newText string
+		want             bool
+	}{
+		{"line endings", NormalizeOptions{IgnoreEOL: true}, "a\r\nb", "a\nb", true},
+		{"reindent", NormalizeOptions{IgnoreWhitespace: true}, "  a\n  b", "\ta\n\tb", true},
+		{"real change", NormalizeOptions{IgnoreWhitespace: true, IgnoreEOL: true}, "a\nb", "a\nc", false},
+		{"disabled", NormalizeOptions{}, "a\r\n", "a\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.opts.Hides(tt.oldText, tt.newText); got != tt.want {
+				t.Errorf("Hides(%q, %q) = %v, want %v", tt.oldText, tt.newText, got, tt.want)
+			}
+		})
+	}
+}
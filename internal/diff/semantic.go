@@ -0,0 +1,262 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ztaylor/claude-mon/internal/theme"
+	"gopkg.in/yaml.v3"
+)
+
+// KeyChangeKind is how a key differs between two documents
+type KeyChangeKind int
+
+const (
+	KeyAdded KeyChangeKind = iota
+	KeyRemoved
+	KeyChanged
+)
+
+// KeyChange is a key whose value differs between two structured documents
+type KeyChange struct {
+	Kind KeyChangeKind
+	Path string // e.g. "spec.containers[0].image"; empty for the document root
+	Old  any    // Value before; nil if added
+	New  any    // Value after; nil if removed
+}
+
+// semanticValueWidth caps how much of a value a key change line shows
+const semanticValueWidth = 80
+
+// SupportsSemantic reports whether a file gets a key path diff
+func SupportsSemantic(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".yaml", ".yml", ".toml":
+		return true
+	}
+	return false
+}
+
+// Semantic diffs two JSON, YAML or TOML documents by key path rather than
+// by line, so reordering and reformatting don't show as changes. Maps are
+// compared by key and lists by index. An empty side is an empty document.
+func Semantic(path, oldText, newText string) ([]KeyChange, error) {
+	oldDoc, err := parseStructured(path, oldText)
+	if err != nil {
+		return nil, fmt.Errorf("before: %w", err)
+	}
+	newDoc, err := parseStructured(path, newText)
+	if err != nil {
+		return nil, fmt.Errorf("after: %w", err)
+	}
+
+	var changes []KeyChange
+	compareValues("", oldDoc, newDoc, &changes)
+	return changes, nil
+}
+
+// parseStructured parses a document by its file extension into maps,
+// slices and scalars
+func parseStructured(path, text string) (any, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+
+	var doc any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		dec := json.NewDecoder(strings.NewReader(text))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, err
+		}
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal([]byte(text), &doc); err != nil {
+			return nil, err
+		}
+	case ".toml":
+		var m map[string]any
+		if _, err := toml.Decode(text, &m); err != nil {
+			return nil, err
+		}
+		doc = m
+	default:
+		return nil, fmt.Errorf("unsupported file type %q", filepath.Ext(path))
+	}
+	return normalizeValue(doc), nil
+}
+
+// normalizeValue converts the parsers' container types to map[string]any
+// and []any, and times to strings, so documents compare uniformly
+func normalizeValue(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			t[k] = normalizeValue(val)
+		}
+		return t
+	case map[any]any:
+		m := make(map[string]any, len(t))
+		for k, val := range t {
+			m[fmt.Sprint(k)] = normalizeValue(val)
+		}
+		return m
+	case []any:
+		for i, val := range t {
+			t[i] = normalizeValue(val)
+		}
+		return t
+	case []map[string]any:
+		s := make([]any, len(t))
+		for i, val := range t {
+			s[i] = normalizeValue(val)
+		}
+		return s
+	case time.Time:
+		return t.Format(time.RFC3339Nano)
+	}
+	return v
+}
+
+func compareValues(path string, oldVal, newVal any, changes *[]KeyChange) {
+	oldMap, oldIsMap := oldVal.(map[string]any)
+	newMap, newIsMap := newVal.(map[string]any)
+	if oldIsMap && newIsMap {
+		keys := make([]string, 0, len(oldMap)+len(newMap))
+		for k := range oldMap {
+			keys = append(keys, k)
+		}
+		for k := range newMap {
+			if _, ok := oldMap[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			o, inOld := oldMap[k]
+			n, inNew := newMap[k]
+			switch {
+			case !inOld:
+				*changes = append(*changes, KeyChange{Kind: KeyAdded, Path: joinKey(path, k), New: n})
+			case !inNew:
+				*changes = append(*changes, KeyChange{Kind: KeyRemoved, Path: joinKey(path, k), Old: o})
+			default:
+				compareValues(joinKey(path, k), o, n, changes)
+			}
+		}
+		return
+	}
+
+	oldList, oldIsList := oldVal.([]any)
+	newList, newIsList := newVal.([]any)
+	if oldIsList && newIsList {
+		for i := 0; i < max(len(oldList), len(newList)); i++ {
+			p := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(oldList):
+				*changes = append(*changes, KeyChange{Kind: KeyAdded, Path: p, New: newList[i]})
+			case i >= len(newList):
+				*changes = append(*changes, KeyChange{Kind: KeyRemoved, Path: p, Old: oldList[i]})
+			default:
+				compareValues(p, oldList[i], newList[i], changes)
+			}
+		}
+		return
+	}
+
+	switch {
+	case oldVal == nil && newVal == nil:
+	case oldVal == nil:
+		*changes = append(*changes, KeyChange{Kind: KeyAdded, Path: path, New: newVal})
+	case newVal == nil:
+		*changes = append(*changes, KeyChange{Kind: KeyRemoved, Path: path, Old: oldVal})
+	case formatValue(oldVal) != formatValue(newVal):
+		*changes = append(*changes, KeyChange{Kind: KeyChanged, Path: path, Old: oldVal, New: newVal})
+	}
+}
+
+// bareKey matches keys that need no quoting in a path
+var bareKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*$`)
+
+// joinKey appends a map key to a path, quoting keys that aren't bare
+func joinKey(path, key string) string {
+	if !bareKey.MatchString(key) {
+		return fmt.Sprintf("%s[%q]", path, key)
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// formatValue renders a value compactly as JSON, which maps sort by key
+func formatValue(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// truncateValue shortens a rendered value for a key change line
+func truncateValue(s string) string {
+	if r := []rune(s); len(r) > semanticValueWidth {
+		return string(r[:semanticValueWidth-1]) + "…"
+	}
+	return s
+}
+
+// FormatSemantic renders key changes, one per line: "+ path: value" for
+// added keys, "- path: value" for removed ones and "~ path: old → new" for
+// changed values
+func FormatSemantic(changes []KeyChange, t *theme.Theme) string {
+	if len(changes) == 0 {
+		return t.Dim.Render("No semantic changes")
+	}
+
+	var added, removed, changed int
+	for _, c := range changes {
+		switch c.Kind {
+		case KeyAdded:
+			added++
+		case KeyRemoved:
+			removed++
+		case KeyChanged:
+			changed++
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString(t.DiffHeader.Render(fmt.Sprintf("@@ %d keys @@", len(changes))))
+	sb.WriteString(fmt.Sprintf("  %s, %s, %s\n",
+		t.Added.Render(fmt.Sprintf("+%d", added)),
+		t.Removed.Render(fmt.Sprintf("-%d", removed)),
+		t.Modified.Render(fmt.Sprintf("~%d", changed))))
+
+	for _, c := range changes {
+		path := c.Path
+		if path == "" {
+			path = "(root)"
+		}
+		switch c.Kind {
+		case KeyAdded:
+			sb.WriteString(t.Added.Render("+ " + path + ": " + truncateValue(formatValue(c.New))))
+		case KeyRemoved:
+			sb.WriteString(t.Removed.Render("- " + path + ": " + truncateValue(formatValue(c.Old))))
+		case KeyChanged:
+			sb.WriteString(t.Modified.Render("~ "+path+": ") +
+				t.Removed.Render(truncateValue(formatValue(c.Old))) +
+				t.Dim.Render(" → ") +
+				t.Added.Render(truncateValue(formatValue(c.New))))
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
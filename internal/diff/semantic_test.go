@@ -0,0 +1,76 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSemantic(t *testing.T) {
+	tests := []struct {
+		name             string
+		path             string
+		oldText, newText string
+		want             []string // "<kind> <path>"
+	}{
+		{
+			"yaml reordered",
+			"deploy.yaml",
+			"name: web\nreplicas: 2\nports: [80, 443]\n",
+			"ports:\n  - 80\n  - 443\nreplicas: 2\nname: web\n",
+			nil,
+		},
+		{
+			"yaml changes",
+			"deploy.yml",
+			"spec:\n  image: web:1\n  env: {A: 1}\n  args: [a, b]\n",
+			"spec:\n  image: web:2\n  env: {B: 2}\n  args: [a]\n",
+			[]string{"- spec.args[1]", "- spec.env.A", "+ spec.env.B", "~ spec.image"},
+		},
+		{
+			"json quoted keys",
+			"package.json",
+			`{"scripts": {"build": "tsc"}}`,
+			`{"scripts": {"build": "tsc", "test:unit": "jest"}}`,
+			[]string{`+ scripts["test:unit"]`},
+		},
+		{
+			"toml tables",
+			"Cargo.toml",
+			"[package]\nname = \"a\"\n\n[[bin]]\nname = \"x\"\n",
+			"[[bin]]\nname = \"y\"\n\n[package]\nname = \"a\"\nedition = \"2021\"\n",
+			[]string{"~ bin[0].name", "+ package.edition"},
+		},
+		{
+			"new file",
+			"a.json",
+			"",
+			`{"a": 1}`,
+			[]string{"+ (root)"},
+		},
+	}
+
+	kinds := map[KeyChangeKind]string{KeyAdded: "+", KeyRemoved: "-", KeyChanged: "~"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := Semantic(tt.path, tt.oldText, tt.newText)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, c := range changes {
+				path := c.Path
+				if path == "" {
+					path = "(root)"
+				}
+				got = append(got, kinds[c.Kind]+" "+path)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Semantic() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Semantic("a.yaml", "a: [", "a: 1"); err == nil {
+		t.Error("expected an error for invalid YAML")
+	}
+}
@@ -0,0 +1,36 @@
+package diff
+
+import "strings"
+
+// Step is one edit in a run of consecutive edits to a file
+type Step struct {
+	OldString   string
+	NewString   string
+	FileContent string // Full file content after the edit; empty if unknown
+}
+
+// Squash combines a run of edits to one file, oldest first, into a single
+// change. With the file content after the first and last edits it returns
+// the whole file before the first edit and after the last, undoing the
+// first edit to recover the original. Otherwise, or if the first edit can't
+// be located, it falls back to the first edit's old string and the last
+// edit's new string.
+func Squash(steps []Step) (oldText, newText string) {
+	if len(steps) == 0 {
+		return "", ""
+	}
+	first, last := steps[0], steps[len(steps)-1]
+
+	if first.FileContent != "" && last.FileContent != "" {
+		if first.NewString == first.FileContent {
+			// A full write: the original is whatever it replaced
+			return first.OldString, last.FileContent
+		}
+		if first.NewString != "" && strings.Count(first.FileContent, first.NewString) == 1 {
+			before := strings.Replace(first.FileContent, first.NewString, first.OldString, 1)
+			return before, last.FileContent
+		}
+	}
+
+	return first.OldString, last.NewString
+}
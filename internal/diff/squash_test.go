@@ -0,0 +1,45 @@
+package diff
+
+import "testing"
+
+func TestSquash(t *testing.T) {
+	steps := []Step{
+		{OldString: "return 1", NewString: "return 2", FileContent: "func a() {\n\treturn 2\n}\n"},
+		{OldString: "func a()", NewString: "func b()", FileContent: "func b() {\n\treturn 2\n}\n"},
+		{OldString: "return 2", NewString: "return 3", FileContent: "func b() {\n\treturn 3\n}\n"},
+	}
+
+	tests := []struct {
+		name    string
+		steps   []Step
+		wantOld string
+		wantNew string
+	}{
+		{"empty", nil, "", ""},
+		{"file content", steps, "func a() {\n\treturn 1\n}\n", "func b() {\n\treturn 3\n}\n"},
+		{
+			"write first",
+			[]Step{{NewString: "x\n", FileContent: "x\n"}, {OldString: "x", NewString: "y", FileContent: "y\n"}},
+			"", "y\n",
+		},
+		{
+			"no file content",
+			[]Step{{OldString: "a", NewString: "b"}, {OldString: "b", NewString: "c"}},
+			"a", "c",
+		},
+		{
+			"ambiguous first edit",
+			[]Step{{OldString: "a", NewString: "x", FileContent: "x x"}, {OldString: "x x", NewString: "y", FileContent: "y"}},
+			"a", "y",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oldText, newText := Squash(tt.steps)
+			if oldText != tt.wantOld || newText != tt.wantNew {
+				t.Errorf("Squash() = %q, %q; want %q, %q", oldText, newText, tt.wantOld, tt.wantNew)
+			}
+		})
+	}
+}
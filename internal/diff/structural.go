@@ -0,0 +1,131 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Structural pretty-prints both sides of a JSON or SVG change so their
+// diff shows what changed in the structure rather than in the formatting:
+// JSON is indented with object keys sorted, SVG with one element per line
+// and attributes sorted. Reports false if the file isn't JSON or SVG or
+// either side doesn't parse.
+func Structural(path, oldText, newText string) (string, string, bool) {
+	var pretty func(string) (string, error)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		pretty = prettyJSON
+	case ".svg":
+		pretty = prettyXML
+	default:
+		return "", "", false
+	}
+
+	oldPretty, err := prettyOrEmpty(pretty, oldText)
+	if err != nil {
+		return "", "", false
+	}
+	newPretty, err := prettyOrEmpty(pretty, newText)
+	if err != nil {
+		return "", "", false
+	}
+	return oldPretty, newPretty, true
+}
+
+// SupportsStructural reports whether a file gets a structural diff
+func SupportsStructural(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".svg":
+		return true
+	}
+	return false
+}
+
+// prettyOrEmpty pretty-prints text, keeping a missing side empty
+func prettyOrEmpty(pretty func(string) (string, error), text string) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		return "", nil
+	}
+	return pretty(text)
+}
+
+func prettyJSON(text string) (string, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return "", err
+	}
+	// Marshalling maps sorts their keys
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b) + "\n", nil
+}
+
+func prettyXML(text string) (string, error) {
+	dec := xml.NewDecoder(strings.NewReader(text))
+	dec.Strict = false
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+
+	for {
+		// Raw tokens keep namespace prefixes as written
+		tok, err := dec.RawToken()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			t.Name = prefixedName(t.Name)
+			for i := range t.Attr {
+				t.Attr[i].Name = prefixedName(t.Attr[i].Name)
+			}
+			sort.Slice(t.Attr, func(i, j int) bool { return t.Attr[i].Name.Local < t.Attr[j].Name.Local })
+			tok = t
+		case xml.EndElement:
+			t.Name = prefixedName(t.Name)
+			tok = t
+		case xml.CharData:
+			trimmed := bytes.TrimSpace(t)
+			if len(trimmed) == 0 {
+				continue
+			}
+			tok = xml.CharData(trimmed)
+		case xml.ProcInst:
+			if t.Target == "xml" {
+				continue // The encoder only accepts it first; it says nothing about structure
+			}
+		case xml.Directive:
+			continue
+		}
+		if err := enc.EncodeToken(tok); err != nil {
+			return "", err
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		return "", err
+	}
+	return buf.String() + "\n", nil
+}
+
+// prefixedName folds a raw namespace prefix into the local name, so the
+// encoder writes it back as written instead of declaring a namespace
+func prefixedName(n xml.Name) xml.Name {
+	if n.Space == "" {
+		return n
+	}
+	return xml.Name{Local: n.Space + ":" + n.Local}
+}
@@ -0,0 +1,38 @@
+package diff
+
+import "testing"
+
+func TestStructural(t *testing.T) {
+	oldText, newText, ok := Structural("config.json", `{"b":1,"a":{"x":[1,2]}}`, "{\n  \"a\": {\"x\": [1, 3]},\n  \"b\": 1\n}")
+	if !ok {
+		t.Fatal("expected JSON to pretty-print")
+	}
+	wantOld := "{\n  \"a\": {\n    \"x\": [\n      1,\n      2\n    ]\n  },\n  \"b\": 1\n}\n"
+	if oldText != wantOld {
+		t.Errorf("old = %q, want %q", oldText, wantOld)
+	}
+	if want := "{\n  \"a\": {\n    \"x\": [\n      1,\n      3\n    ]\n  },\n  \"b\": 1\n}\n"; newText != want {
+		t.Errorf("new = %q, want %q", newText, want)
+	}
+
+	oldText, newText, ok = Structural("icon.svg",
+		`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink"><use xlink:href="#a" y="1" x="0"/></svg>`,
+		"<svg xmlns=\"http://www.w3.org/2000/svg\" xmlns:xlink=\"http://www.w3.org/1999/xlink\">\n  <use x=\"0\" xlink:href=\"#a\" y=\"1\"></use>\n</svg>")
+	if !ok {
+		t.Fatal("expected SVG to pretty-print")
+	}
+	if oldText != newText {
+		t.Errorf("expected attribute order and formatting ignored, got:\n%s\nvs\n%s", oldText, newText)
+	}
+
+	// A new file has no old side
+	if oldText, _, ok = Structural("a.json", "", `{"a":1}`); !ok || oldText != "" {
+		t.Errorf("expected an empty old side for a new file, got %q, %v", oldText, ok)
+	}
+
+	for _, tc := range []struct{ path, text string }{{"a.go", "{}"}, {"a.json", "{not json"}} {
+		if _, _, ok := Structural(tc.path, tc.text, tc.text); ok {
+			t.Errorf("Structural(%q, %q) should not apply", tc.path, tc.text)
+		}
+	}
+}
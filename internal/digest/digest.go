@@ -0,0 +1,111 @@
+// Package digest formats a summary of claude-mon activity for posting to a
+// team channel: Slack's mrkdwn, or markdown for Discord and elsewhere.
+package digest
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ztaylor/claude-mon/internal/database"
+)
+
+// Formats a digest can be written in
+const (
+	FormatMarkdown = "markdown"
+	FormatSlack    = "slack"
+	FormatDiscord  = "discord" // Markdown, posted as a Discord message
+)
+
+// Formats lists the formats, for help and errors
+var Formats = []string{FormatSlack, FormatDiscord, FormatMarkdown}
+
+// periodLayout formats the digest period's bounds
+const periodLayout = "Mon Jan 2 15:04"
+
+// style is how a format marks up text
+type style struct {
+	bold   func(string) string
+	bullet string
+}
+
+// styles are the formats' markup
+var styles = map[string]style{
+	FormatMarkdown: {bold: func(s string) string { return "**" + s + "**" }, bullet: "- "},
+	FormatDiscord:  {bold: func(s string) string { return "**" + s + "**" }, bullet: "- "},
+	FormatSlack:    {bold: func(s string) string { return "*" + s + "*" }, bullet: "• "},
+}
+
+// Format writes a digest as text in a format
+func Format(d *database.Digest, format string) (string, error) {
+	st, ok := styles[format]
+	if !ok {
+		return "", fmt.Errorf("unknown digest format %q: use %s", format, strings.Join(Formats, ", "))
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s: %s to %s\n", st.bold("claude-mon digest"),
+		d.Since.Local().Format(periodLayout), d.Until.Local().Format(periodLayout))
+	if len(d.Workspaces) == 0 && len(d.Prompts) == 0 {
+		sb.WriteString("No activity.\n")
+		return sb.String(), nil
+	}
+
+	total := d.Total()
+	fmt.Fprintf(&sb, "\n%s to %s (+%d/-%d) in %s, %s\n",
+		st.bold(plural(total.Edits, "edit")), plural(total.Files, "file"), total.Added, total.Removed,
+		plural(len(d.Workspaces), "workspace"), activity(total))
+
+	for _, w := range d.Workspaces {
+		name := w.WorkspaceName
+		if name == "" {
+			name = filepath.Base(w.WorkspacePath)
+		}
+		fmt.Fprintf(&sb, "\n%s: %s, %s, +%d/-%d, %s\n", st.bold(name),
+			plural(w.Edits, "edit"), plural(w.Files, "file"), w.Added, w.Removed, activity(*w))
+		for _, f := range w.TopFiles {
+			rel := f.Name
+			if r, err := filepath.Rel(w.WorkspacePath, f.Name); err == nil && !strings.HasPrefix(r, "..") {
+				rel = r
+			}
+			fmt.Fprintf(&sb, "%s`%s` (%d)\n", st.bullet, rel, f.Count)
+		}
+	}
+
+	if len(d.Prompts) > 0 {
+		used := make([]string, 0, len(d.Prompts))
+		for _, p := range d.Prompts {
+			used = append(used, fmt.Sprintf("%s ×%d", p.Name, p.Count))
+		}
+		fmt.Fprintf(&sb, "\n%s %s\n", st.bold("Prompts used:"), strings.Join(used, ", "))
+	}
+	return sb.String(), nil
+}
+
+// Body returns the JSON body that posts text to a format's incoming
+// webhook: Discord's takes it as "content", Slack's and others as "text"
+func Body(format, text string) ([]byte, error) {
+	key := "text"
+	if format == FormatDiscord {
+		key = "content"
+	}
+	return json.Marshal(map[string]string{key: text})
+}
+
+// activity describes the sessions and Ralph loops behind a workspace's edits
+func activity(w database.WorkspaceDigest) string {
+	s := plural(w.Sessions, "session")
+	if w.RalphLoops > 0 {
+		s += ", " + plural(w.RalphLoops, "Ralph loop")
+	}
+	return s
+}
+
+// plural counts a noun, e.g. "1 edit" or "3 edits"
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,67 @@
+package digest
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/database"
+)
+
+func TestFormat(t *testing.T) {
+	until := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local)
+	d := &database.Digest{
+		Since: until.Add(-24 * time.Hour),
+		Until: until,
+		Workspaces: []*database.WorkspaceDigest{
+			{WorkspacePath: "/src/api", WorkspaceName: "api", Edits: 12, Files: 3, Added: 40, Removed: 8, Sessions: 1,
+				RalphLoops: 2, TopFiles: []database.DigestCount{{Name: "/src/api/internal/server.go", Count: 7}}},
+			{WorkspacePath: "/src/web", Edits: 1, Files: 1, Added: 1, Removed: 1, Sessions: 1},
+		},
+		Prompts: []database.DigestCount{{Name: "review", Count: 3}},
+	}
+
+	text, err := Format(d, FormatSlack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "*claude-mon digest*: Thu Oct 15 09:00 to Fri Oct 16 09:00\n" +
+		"\n*13 edits* to 4 files (+41/-9) in 2 workspaces, 2 sessions, 2 Ralph loops\n" +
+		"\n*api*: 12 edits, 3 files, +40/-8, 1 session, 2 Ralph loops\n" +
+		"• `internal/server.go` (7)\n" +
+		"\n*web*: 1 edit, 1 file, +1/-1, 1 session\n" +
+		"\n*Prompts used:* review ×3\n"
+	if text != want {
+		t.Errorf("unexpected slack digest:\n%s\nwant:\n%s", text, want)
+	}
+
+	text, err = Format(d, FormatMarkdown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text, "**api**: 12 edits") || !strings.Contains(text, "- `internal/server.go` (7)") {
+		t.Errorf("unexpected markdown digest:\n%s", text)
+	}
+
+	if text, _ := Format(&database.Digest{Since: d.Since, Until: until}, FormatMarkdown); !strings.HasSuffix(text, "No activity.\n") {
+		t.Errorf("expected an empty digest to say so, got %q", text)
+	}
+	if _, err := Format(d, "html"); err == nil {
+		t.Error("expected an unknown format rejected")
+	}
+}
+
+func TestBody(t *testing.T) {
+	for format, want := range map[string]string{
+		FormatSlack:   `{"text":"hi \"all\""}`,
+		FormatDiscord: `{"content":"hi \"all\""}`,
+	} {
+		body, err := Body(format, `hi "all"`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != want {
+			t.Errorf("Body(%s) = %s, want %s", format, body, want)
+		}
+	}
+}
@@ -2,10 +2,13 @@ package e2e
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/ztaylor/claude-mon/internal/config"
 	"github.com/ztaylor/claude-mon/internal/context"
 )
 
@@ -389,3 +392,387 @@ func TestContextFormat(t *testing.T) {
 		t.Error("Formatted context should contain cluster and namespace")
 	}
 }
+
+// TestContextProfiles tests saving, applying and injecting a named profile
+func TestContextProfiles(t *testing.T) {
+	tempDir := t.TempDir()
+
+	// Override contexts dir
+	oldContextsDir := context.ContextsDir
+	context.ContextsDir = tempDir
+	defer func() { context.ContextsDir = oldContextsDir }()
+
+	ctx := &context.Context{
+		Version:     2,
+		ProjectID:   "test",
+		ProjectRoot: "/tmp/test-project",
+		Context:     make(map[string]interface{}),
+	}
+	ctx.SetKubernetes("prod", "api", "")
+	ctx.SetAWS("prod-readonly", "us-east-1")
+	ctx.SetCustom(map[string]string{"ticket": "OPS-1"})
+
+	if _, err := context.SaveProfile("prod-readonly", ctx); err != nil {
+		t.Fatalf("Failed to save profile: %v", err)
+	}
+	if _, err := context.SaveProfile("../escape", ctx); err == nil {
+		t.Error("Expected a profile name with a path separator to be rejected")
+	}
+
+	// A profile without AWS clears the AWS section when applied
+	ctx.SetKubernetes("staging", "api", "")
+	ctx.Clear("aws")
+	if _, err := context.SaveProfile("staging", ctx); err != nil {
+		t.Fatalf("Failed to save profile: %v", err)
+	}
+
+	profiles, err := context.ListProfiles()
+	if err != nil {
+		t.Fatalf("Failed to list profiles: %v", err)
+	}
+	if len(profiles) != 2 || profiles[0].Name != "prod-readonly" || profiles[1].Name != "staging" {
+		t.Fatalf("Expected the two profiles by name, got %+v", profiles)
+	}
+	if summary := profiles[0].Summary(); summary != "k8s prod / api, aws prod-readonly (us-east-1)" {
+		t.Errorf("Unexpected profile summary %q", summary)
+	}
+
+	prod, err := context.LoadProfile("prod-readonly")
+	if err != nil {
+		t.Fatalf("Failed to load profile: %v", err)
+	}
+	ctx.ApplyProfile(prod)
+	if k8s := ctx.GetKubernetes(); k8s == nil || k8s.Context != "prod" {
+		t.Errorf("Expected the prod cluster, got %+v", k8s)
+	}
+	staging, _ := context.LoadProfile("staging")
+	ctx.ApplyProfile(staging)
+	if ctx.GetAWS() != nil {
+		t.Error("AWS context should be cleared by a profile without it")
+	}
+	if ctx.GetCustom()["ticket"] != "OPS-1" {
+		t.Error("Custom values should stay with the project")
+	}
+
+	// The active profile is injected and survives a save
+	if err := ctx.Save(); err != nil {
+		t.Fatalf("Failed to save context: %v", err)
+	}
+	if block := ctx.FormatForInjection(); !contains(block, "Profile: staging") {
+		t.Errorf("Injected context should name the profile:\n%s", block)
+	}
+	contexts, _ := context.ListAll()
+	if len(contexts) != 1 || contexts[0].Profile != "staging" {
+		t.Errorf("Expected the saved context to keep its profile, got %+v", contexts)
+	}
+
+	if err := context.DeleteProfile("staging"); err != nil {
+		t.Fatalf("Failed to delete profile: %v", err)
+	}
+	if _, err := context.LoadProfile("staging"); err == nil {
+		t.Error("Expected the deleted profile to be gone")
+	}
+}
+
+// TestContextDetect tests reading context from kubectl, AWS_* variables and git
+func TestContextDetect(t *testing.T) {
+	// A fake kubectl answering the two config queries
+	binDir := t.TempDir()
+	kubectl := "#!/bin/sh\ncase \"$2\" in\ncurrent-context) echo orbstack ;;\nview) echo monitoring ;;\nesac\n"
+	if err := os.WriteFile(filepath.Join(binDir, "kubectl"), []byte(kubectl), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("KUBECONFIG", "/tmp/kube/config")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_DEFAULT_PROFILE", "dev")
+	t.Setenv("AWS_REGION", "us-west-2")
+	t.Chdir(t.TempDir())
+
+	detected := context.Detect()
+	if k8s := detected.Kubernetes; k8s == nil || k8s.Context != "orbstack" || k8s.Namespace != "monitoring" || k8s.Kubeconfig != "/tmp/kube/config" {
+		t.Errorf("Unexpected Kubernetes context %+v", k8s)
+	}
+	if aws := detected.AWS; aws == nil || aws.Profile != "dev" || aws.Region != "us-west-2" {
+		t.Errorf("Unexpected AWS context %+v", aws)
+	}
+	if detected.Git != nil {
+		t.Errorf("Expected no git context outside a checkout, got %+v", detected.Git)
+	}
+
+	// Applying keeps sections that weren't detected and leaves the profile
+	ctx := &context.Context{Context: make(map[string]interface{}), Profile: "staging"}
+	ctx.SetEnv(map[string]string{"DEBUG": "1"})
+	detected.Apply(ctx)
+	if ctx.GetKubernetes().Context != "orbstack" || ctx.GetEnv()["DEBUG"] != "1" || ctx.Profile != "" {
+		t.Errorf("Unexpected context after applying detection: %+v", ctx)
+	}
+	if lines := detected.Lines(); len(lines) != 2 || lines[1] != "AWS: dev (us-west-2)" {
+		t.Errorf("Unexpected detection summary %q", lines)
+	}
+}
+
+// TestContextRevalidate tests refreshing git, flagging a drifted kube context
+// and flagging sections unconfirmed past the TTL
+func TestContextRevalidate(t *testing.T) {
+	tempDir := t.TempDir()
+	oldContextsDir := context.ContextsDir
+	context.ContextsDir = tempDir
+	defer func() { context.ContextsDir = oldContextsDir }()
+
+	// A checkout on branch "feature"
+	projectRoot := t.TempDir()
+	for _, args := range [][]string{{"init", "-q", "-b", "feature"}, {"commit", "-q", "--allow-empty", "-m", "init"}} {
+		cmd := exec.Command("git", append([]string{"-C", projectRoot, "-c", "user.name=t", "-c", "user.email=t@t"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	// kubectl is on orbstack
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "kubectl"), []byte("#!/bin/sh\necho orbstack\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	ctx := &context.Context{
+		Version:     2,
+		ProjectID:   "revalidate-test",
+		ProjectRoot: projectRoot,
+		Context:     make(map[string]interface{}),
+	}
+	ctx.SetGit("main", "my-repo")
+	ctx.SetKubernetes("prod", "api", "")
+	ctx.SetAWS("dev", "us-west-2")
+	ctx.Checked["aws"] = time.Now().Add(-50 * time.Hour).UTC().Format(time.RFC3339)
+	if err := ctx.Save(); err != nil {
+		t.Fatalf("Failed to save context: %v", err)
+	}
+
+	refreshed, err := context.RefreshAll(48 * time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to refresh contexts: %v", err)
+	}
+	if got := refreshed[projectRoot]; len(got) != 1 || got[0] != "git" {
+		t.Errorf("Expected git refreshed, got %v", refreshed)
+	}
+
+	contexts, _ := context.ListAll()
+	if len(contexts) != 1 {
+		t.Fatalf("Expected 1 context, got %d", len(contexts))
+	}
+	ctx = contexts[0]
+	if git := ctx.GetGit(); git == nil || git.Branch != "feature" || git.Repo != "my-repo" {
+		t.Errorf("Expected the branch refreshed, got %+v", git)
+	}
+	if k8s := ctx.GetKubernetes(); k8s.Context != "prod" {
+		t.Errorf("A drifted kube context should be kept, got %+v", k8s)
+	}
+	if reason := ctx.StaleReason("kubernetes"); reason != "kubectl is on orbstack" {
+		t.Errorf("Expected the kube context flagged, got %q", reason)
+	}
+	if reason := ctx.StaleReason("aws"); reason != "unconfirmed for 2d" {
+		t.Errorf("Expected AWS flagged past the TTL, got %q", reason)
+	}
+	if ctx.StaleReason("git") != "" || !ctx.IsStale() {
+		t.Errorf("Expected only the flagged fields stale, got %+v", ctx.Stale)
+	}
+	if block := ctx.FormatForInjection(); !contains(block, "Kubernetes: prod / api (STALE: kubectl is on orbstack)") {
+		t.Errorf("Injected context should flag the kube context:\n%s", block)
+	}
+
+	// Setting a section again confirms it
+	ctx.SetAWS("dev", "us-west-2")
+	ctx.SetKubernetes("orbstack", "", "")
+	if ctx.IsStale() {
+		t.Errorf("Expected nothing stale after resetting, got %+v", ctx.Stale)
+	}
+}
+
+// TestContextSecrets tests that secret values are stored encrypted, masked
+// when shown and kept out of the injected context unless opted in
+func TestContextSecrets(t *testing.T) {
+	tempDir := t.TempDir()
+	oldContextsDir := context.ContextsDir
+	context.ContextsDir = tempDir
+	defer func() { context.ContextsDir = oldContextsDir }()
+
+	ctx := &context.Context{
+		Version:     2,
+		ProjectID:   "secrets-test",
+		ProjectRoot: tempDir,
+		Context:     make(map[string]interface{}),
+	}
+	if err := ctx.SetValue("env", "DEBUG", "1", nil); err != nil {
+		t.Fatalf("Failed to set env value: %v", err)
+	}
+	if err := ctx.SetValue("env", "API_TOKEN", "hunter2", &context.Secret{}); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	if err := ctx.Save(); err != nil {
+		t.Fatalf("Failed to save context: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, "secrets-test.json"))
+	if err != nil {
+		t.Fatalf("Failed to read context file: %v", err)
+	}
+	if contains(string(data), "hunter2") {
+		t.Errorf("Secret stored in plain text:\n%s", data)
+	}
+	info, err := os.Stat(context.SecretKeyPath())
+	if err != nil {
+		t.Fatalf("Expected a secret key file: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the key file readable only by the user, got %v", info.Mode().Perm())
+	}
+
+	if formatted := ctx.Format(); contains(formatted, "hunter2") || !contains(formatted, "API_TOKEN="+context.SecretMask) {
+		t.Errorf("Expected the secret masked:\n%s", formatted)
+	}
+	if block := ctx.FormatForInjection(); contains(block, "API_TOKEN") || !contains(block, "DEBUG=1") {
+		t.Errorf("Expected the secret left out of the injected context:\n%s", block)
+	}
+	if value, err := ctx.SecretValue("env", "API_TOKEN"); err != nil || value != "hunter2" {
+		t.Errorf("Expected the secret to decrypt, got %q (%v)", value, err)
+	}
+
+	// Opting in sends the decrypted value
+	if err := ctx.SetValue("env", "API_TOKEN", "hunter2", &context.Secret{Inject: true}); err != nil {
+		t.Fatalf("Failed to set secret: %v", err)
+	}
+	if block := ctx.FormatForInjection(); !contains(block, "API_TOKEN=hunter2") {
+		t.Errorf("Expected the opted-in secret injected:\n%s", block)
+	}
+
+	ctx.Clear("env")
+	if ctx.IsSecret("env", "API_TOKEN") {
+		t.Errorf("Expected clearing env to drop its secret flags, got %+v", ctx.Secrets)
+	}
+}
+
+// TestContextInjectRules tests that injection keeps the sections the rules
+// allow for a prompt and fits the size budget
+func TestContextInjectRules(t *testing.T) {
+	ctx := &context.Context{
+		Version:     2,
+		ProjectID:   "inject-rules-test",
+		ProjectRoot: "/src/infra",
+		Context:     make(map[string]interface{}),
+	}
+	ctx.SetKubernetes("prod", "api", "")
+	ctx.SetAWS("prod-ro", "us-east-1")
+	ctx.SetGit("main", "infra")
+	env := make(map[string]string)
+	for _, k := range []string{"A", "B", "C", "D", "E", "F"} {
+		env["VAR_"+k] = strings.Repeat(k, 20)
+	}
+	ctx.SetEnv(env)
+
+	cfg := config.InjectConfig{Rules: []config.InjectRule{
+		{Sections: []string{"kubernetes"}, Prompt: "deploy|kubectl"},
+		{Sections: []string{"aws"}, Workspace: "other-*"},
+	}}
+
+	block := ctx.FormatForPrompt("fix the typo in the README", cfg)
+	if contains(block, "Kubernetes:") || contains(block, "AWS Profile:") || !contains(block, "Git: main @ infra") {
+		t.Errorf("Expected only unruled sections for an unrelated prompt:\n%s", block)
+	}
+	block = ctx.FormatForPrompt("Deploy the api", cfg)
+	if !contains(block, "Kubernetes: prod / api") || contains(block, "AWS Profile:") {
+		t.Errorf("Expected the kube context for a deploy prompt:\n%s", block)
+	}
+
+	// Over budget, env is cut short before anything else goes
+	full := ctx.FormatForInjection()
+	cfg = config.InjectConfig{MaxChars: len(full) - 40}
+	block = ctx.FormatForPrompt("", cfg)
+	if len(block) > cfg.MaxChars {
+		t.Errorf("Expected at most %d chars, got %d:\n%s", cfg.MaxChars, len(block), block)
+	}
+	if !contains(block, "Kubernetes:") || !contains(block, "AWS Profile:") || !contains(block, "VAR_A=") || !contains(block, "more)") {
+		t.Errorf("Expected env cut short and the other sections kept:\n%s", block)
+	}
+
+	// Git outranks env when only one fits
+	cfg = config.InjectConfig{MaxChars: 60, Priority: []string{"git", "env"}}
+	block = ctx.FormatForPrompt("", cfg)
+	if !contains(block, "Git: main @ infra") || contains(block, "VAR_") || contains(block, "Kubernetes:") {
+		t.Errorf("Expected just git within a tight budget:\n%s", block)
+	}
+}
+
+// TestContextIaC tests detecting, completing, injecting and revalidating the
+// Terraform workspace
+func TestContextIaC(t *testing.T) {
+	tempDir := t.TempDir()
+	oldContextsDir := context.ContextsDir
+	context.ContextsDir = tempDir
+	defer func() { context.ContextsDir = oldContextsDir }()
+
+	projectRoot := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(projectRoot, ".terraform"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	envFile := filepath.Join(projectRoot, ".terraform", "environment")
+	if err := os.WriteFile(envFile, []byte("staging"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TF_WORKSPACE", "")
+
+	iac := context.DetectIaC(projectRoot)
+	if iac == nil || iac.Tool != context.IaCTerraform || iac.Workspace != "staging" {
+		t.Fatalf("Expected the staging workspace detected, got %+v", iac)
+	}
+
+	// Workspaces complete from terraform workspace list
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "terraform"), []byte("#!/bin/sh\nprintf '  default\\n* staging\\n  prod\\n'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	workspaces, err := context.IaCWorkspaces(context.IaCTerraform, projectRoot)
+	if err != nil || strings.Join(workspaces, ",") != "default,staging,prod" {
+		t.Errorf("Expected the workspaces listed, got %v (%v)", workspaces, err)
+	}
+
+	ctx := &context.Context{
+		Version:     2,
+		ProjectID:   "iac-test",
+		ProjectRoot: projectRoot,
+		Context:     make(map[string]interface{}),
+	}
+	ctx.SetIaC("", "staging", "")
+	if got := ctx.GetIaC(); got.Tool != context.IaCTerraform {
+		t.Errorf("Expected the tool detected, got %+v", got)
+	}
+	if block := ctx.FormatForInjection(); !contains(block, "Terraform workspace: staging") {
+		t.Errorf("Expected the workspace injected:\n%s", block)
+	}
+	if err := ctx.Save(); err != nil {
+		t.Fatalf("Failed to save context: %v", err)
+	}
+
+	// Switching workspaces is picked up on revalidation
+	if err := os.WriteFile(envFile, []byte("prod"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	refreshed, err := context.RefreshAll(time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to refresh contexts: %v", err)
+	}
+	if got := refreshed[projectRoot]; len(got) != 1 || got[0] != "iac" {
+		t.Errorf("Expected the workspace refreshed, got %v", refreshed)
+	}
+	contexts, _ := context.ListAll()
+	if len(contexts) != 1 || contexts[0].GetIaC().Workspace != "prod" {
+		t.Fatalf("Expected the prod workspace saved, got %+v", contexts)
+	}
+
+	contexts[0].Clear("terraform")
+	if contexts[0].GetIaC() != nil {
+		t.Error("Expected clearing terraform to clear the section")
+	}
+}
@@ -0,0 +1,194 @@
+// Package filter parses history filter expressions such as
+// "path:internal/** tool:Write since:1h" and matches edits against them.
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/lang"
+)
+
+// Filter matches edits by file path glob, language, tool name and time range.
+// The zero value matches everything.
+type Filter struct {
+	Expr  string    // Original expression
+	Path  string    // Path glob; ** matches across directories
+	Lang  []string  // Language IDs (any of), e.g. go or ts
+	Tool  string    // Tool name (case-insensitive)
+	Since time.Time // Lower time bound (zero = unbounded)
+	Until time.Time // Upper time bound (zero = unbounded)
+	Text  string    // Free text matched as a substring of the path
+
+	pathRe *regexp.Regexp
+}
+
+// Parse parses a filter expression. Terms are separated by whitespace:
+//
+//	path:<glob>    file path glob, e.g. internal/** or *.go
+//	lang:<names>   file language, e.g. go or ts,jsx (see package lang)
+//	tool:<name>    tool name, e.g. Edit or Write
+//	since:<when>   edits at or after a duration ago (30m, 2h, 7d) or a date
+//	until:<when>   edits at or before a duration ago or a date
+//	<text>         any other term must appear in the file path
+//
+// Relative globs match any trailing part of the path, so path:internal/**
+// matches /home/me/project/internal/model/model.go.
+func Parse(expr string) (*Filter, error) {
+	f := &Filter{Expr: strings.TrimSpace(expr)}
+	now := time.Now()
+
+	var text []string
+	for _, term := range strings.Fields(expr) {
+		key, value, ok := strings.Cut(term, ":")
+		if !ok || value == "" {
+			text = append(text, term)
+			continue
+		}
+
+		switch key {
+		case "path":
+			re, err := globRegexp(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path glob %q: %w", value, err)
+			}
+			f.Path = value
+			f.pathRe = re
+		case "lang":
+			f.Lang = nil
+			for _, name := range strings.Split(value, ",") {
+				l, ok := lang.Lookup(name)
+				if !ok {
+					return nil, fmt.Errorf("unknown language %q", name)
+				}
+				f.Lang = append(f.Lang, l.ID)
+			}
+		case "tool":
+			f.Tool = value
+		case "since":
+			t, err := parseTime(value, now)
+			if err != nil {
+				return nil, fmt.Errorf("invalid since: %w", err)
+			}
+			f.Since = t
+		case "until":
+			t, err := parseTime(value, now)
+			if err != nil {
+				return nil, fmt.Errorf("invalid until: %w", err)
+			}
+			f.Until = t
+		default:
+			text = append(text, term)
+		}
+	}
+	f.Text = strings.Join(text, " ")
+
+	return f, nil
+}
+
+// IsEmpty reports whether the filter matches everything
+func (f *Filter) IsEmpty() bool {
+	return f == nil || (f.Path == "" && len(f.Lang) == 0 && f.Tool == "" && f.Since.IsZero() && f.Until.IsZero() && f.Text == "")
+}
+
+// Match reports whether an edit matches the filter
+func (f *Filter) Match(path, tool string, ts time.Time) bool {
+	if f == nil {
+		return true
+	}
+	if f.Tool != "" && !strings.EqualFold(f.Tool, tool) {
+		return false
+	}
+	if !f.Since.IsZero() && ts.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && ts.After(f.Until) {
+		return false
+	}
+	return f.MatchPath(path)
+}
+
+// MatchPath reports whether a file path matches the path glob, language
+// and free text
+func (f *Filter) MatchPath(path string) bool {
+	if f == nil {
+		return true
+	}
+	if f.pathRe != nil && !f.pathRe.MatchString(path) {
+		return false
+	}
+	if len(f.Lang) > 0 && !slices.Contains(f.Lang, lang.Detect(path).ID) {
+		return false
+	}
+	if f.Text != "" && !strings.Contains(strings.ToLower(path), strings.ToLower(f.Text)) {
+		return false
+	}
+	return true
+}
+
+// String returns the original expression
+func (f *Filter) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.Expr
+}
+
+// globRegexp converts a path glob to a regexp. * and ? don't cross
+// directories; ** does. Relative globs are anchored at a path segment.
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	if strings.HasPrefix(glob, "/") {
+		sb.WriteString("^")
+	} else {
+		sb.WriteString("(^|/)")
+	}
+
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				// "**/" also matches zero directories
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					sb.WriteString("(.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+
+	return regexp.Compile(sb.String())
+}
+
+// parseTime parses a duration ago (30m, 2h, 7d) or an absolute date/time
+func parseTime(value string, now time.Time) (time.Time, error) {
+	if strings.HasSuffix(value, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(value, "d")); err == nil {
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a duration (30m, 2h, 7d) or date (2006-01-02)", value)
+}
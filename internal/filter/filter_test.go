@@ -0,0 +1,104 @@
+package filter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	f, err := Parse("path:internal/** tool:Write since:1h model")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if f.Path != "internal/**" || f.Tool != "Write" || f.Text != "model" {
+		t.Errorf("unexpected filter: %+v", f)
+	}
+	if f.Since.IsZero() || time.Since(f.Since) < 59*time.Minute {
+		t.Errorf("expected since about an hour ago, got %v", f.Since)
+	}
+
+	for _, expr := range []string{"since:yesterday", "until:5x"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("expected error for %q", expr)
+		}
+	}
+
+	if f, _ := Parse("  "); !f.IsEmpty() {
+		t.Error("blank expression should be empty")
+	}
+}
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		glob string
+		path string
+		want bool
+	}{
+		{"internal/**", "/home/me/proj/internal/model/model.go", true},
+		{"internal/**", "/home/me/proj/cmd/main.go", false},
+		{"*.go", "/home/me/proj/cmd/main.go", true},
+		{"*.go", "/home/me/proj/README.md", false},
+		{"cmd/*.go", "/home/me/proj/cmd/sub/main.go", false},
+		{"**/model/*.go", "internal/model/model.go", true},
+		{"/tmp/*", "/tmp/a.txt", true},
+		{"/tmp/*", "/var/tmp/a.txt", false},
+		{"ternal/**", "/proj/internal/x.go", false},
+	}
+
+	for _, tt := range tests {
+		f, err := Parse("path:" + tt.glob)
+		if err != nil {
+			t.Fatalf("parse %q failed: %v", tt.glob, err)
+		}
+		if got := f.MatchPath(tt.path); got != tt.want {
+			t.Errorf("path:%s on %s = %v, want %v", tt.glob, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	now := time.Now()
+	f, err := Parse("tool:edit since:2h until:30m")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	if !f.Match("a.go", "Edit", now.Add(-time.Hour)) {
+		t.Error("expected match for Edit an hour ago")
+	}
+	if f.Match("a.go", "Write", now.Add(-time.Hour)) {
+		t.Error("tool mismatch should not match")
+	}
+	if f.Match("a.go", "Edit", now.Add(-3*time.Hour)) {
+		t.Error("edit before since should not match")
+	}
+	if f.Match("a.go", "Edit", now) {
+		t.Error("edit after until should not match")
+	}
+
+	var empty *Filter
+	if !empty.Match("a.go", "Edit", now) {
+		t.Error("nil filter should match everything")
+	}
+}
+
+func TestMatchLang(t *testing.T) {
+	f, err := Parse("lang:go,typescript")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	for path, want := range map[string]bool{
+		"internal/model/model.go": true,
+		"web/src/api.ts":          true,
+		"web/src/App.tsx":         false,
+		"README.md":               false,
+	} {
+		if got := f.MatchPath(path); got != want {
+			t.Errorf("lang:go,typescript on %s = %v, want %v", path, got, want)
+		}
+	}
+
+	if _, err := Parse("lang:cobol"); err == nil {
+		t.Error("expected error for unknown language")
+	}
+}
@@ -75,6 +75,52 @@ func (h *Highlighter) HighlightLine(line, filename string) string {
 	return h.Highlight(line, filename)
 }
 
+// HighlightLines highlights a block of code, such as one side of a diff hunk,
+// and returns one rendered string per line. The block is lexed as a whole so
+// multi-line strings and comments keep their colors. A non-empty bg is
+// layered under every token, tinting the syntax colors with a diff background.
+func (h *Highlighter) HighlightLines(code, filename string, bg lipgloss.Color) []string {
+	count := len(strings.Split(code, "\n"))
+
+	cacheKey := "lines:" + filename + ":" + string(bg) + ":" + code
+	if cached, ok := h.cache.Get(cacheKey); ok {
+		return strings.Split(cached, "\n")
+	}
+
+	var lines []string
+	if iterator := h.tokenise(filename, code); iterator != nil {
+		lines = h.renderTokenLines(iterator, bg)
+	} else {
+		// No lexer: plain text on the background
+		style := h.withBackground(h.theme.Normal, bg)
+		for _, line := range strings.Split(code, "\n") {
+			lines = append(lines, style.Render(line))
+		}
+	}
+
+	// Lexers may add a trailing newline; keep the input's line count
+	for len(lines) < count {
+		lines = append(lines, "")
+	}
+	lines = lines[:count]
+
+	h.cache.Set(cacheKey, strings.Join(lines, "\n"))
+	return lines
+}
+
+// tokenise lexes code, returning nil if no lexer applies
+func (h *Highlighter) tokenise(filename, code string) chroma.Iterator {
+	lexer := h.getLexer(filename, code)
+	if lexer == nil {
+		return nil
+	}
+	iterator, err := lexer.Tokenise(nil, code)
+	if err != nil {
+		return nil
+	}
+	return iterator
+}
+
 func (h *Highlighter) getLexer(filename, code string) chroma.Lexer {
 	// Try by filename first
 	lexer := lexers.Match(filename)
@@ -111,6 +157,38 @@ func (h *Highlighter) renderTokens(iterator chroma.Iterator) string {
 	return sb.String()
 }
 
+// renderTokenLines renders tokens line by line. Tokens spanning lines are
+// split so each line's styling is self-contained.
+func (h *Highlighter) renderTokenLines(iterator chroma.Iterator, bg lipgloss.Color) []string {
+	var lines []string
+	var sb strings.Builder
+
+	for _, token := range iterator.Tokens() {
+		style := h.withBackground(h.tokenStyle(token.Type), bg)
+		parts := strings.Split(token.Value, "\n")
+		for i, part := range parts {
+			if i > 0 {
+				lines = append(lines, sb.String())
+				sb.Reset()
+			}
+			if part != "" {
+				sb.WriteString(style.Render(part))
+			}
+		}
+	}
+	lines = append(lines, sb.String())
+
+	return lines
+}
+
+// withBackground layers a background color under a token style
+func (h *Highlighter) withBackground(style lipgloss.Style, bg lipgloss.Color) lipgloss.Style {
+	if bg == "" {
+		return style
+	}
+	return style.Background(bg)
+}
+
 func (h *Highlighter) tokenStyle(tokenType chroma.TokenType) lipgloss.Style {
 	// Map Chroma token types to theme styles
 	switch {
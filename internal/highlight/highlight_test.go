@@ -0,0 +1,32 @@
+package highlight
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ztaylor/claude-mon/internal/theme"
+)
+
+func TestHighlightLines(t *testing.T) {
+	h := NewHighlighter(theme.Dark())
+
+	// The comment is a single token spanning two lines
+	code := "/* a comment\nspanning lines */\nfunc main() {}"
+	for _, bg := range []string{"", "22"} {
+		lines := h.HighlightLines(code, "main.go", lipgloss.Color(bg))
+		if len(lines) != 3 {
+			t.Fatalf("expected 3 lines, got %d: %q", len(lines), lines)
+		}
+		for i, want := range []string{"a comment", "spanning lines */", "main"} {
+			if !strings.Contains(lines[i], want) || strings.Contains(lines[i], "\n") {
+				t.Errorf("line %d = %q, want it to contain %q on one line", i, lines[i], want)
+			}
+		}
+	}
+
+	// Unknown file types still produce one entry per line
+	if lines := h.HighlightLines("one\ntwo", "notes", ""); len(lines) != 2 {
+		t.Errorf("expected 2 plain lines, got %q", lines)
+	}
+}
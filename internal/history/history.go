@@ -3,10 +3,10 @@ package history
 import (
 	"encoding/json"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"strings"
 	"time"
+
+	"github.com/ztaylor/claude-mon/internal/vcs"
 )
 
 // Entry represents a single file change with VCS context
@@ -21,6 +21,12 @@ type Entry struct {
 	CommitSHA   string    `json:"commit_sha,omitempty"`
 	CommitShort string    `json:"commit_short,omitempty"` // Short SHA for display
 	VCSType     string    `json:"vcs_type,omitempty"`     // "git" or "jj"
+	Bookmarked  bool      `json:"bookmarked,omitempty"`   // Flagged for later review
+	Note        string    `json:"note,omitempty"`         // Review note
+	Review      string    `json:"review,omitempty"`       // "approved" or "rejected"
+
+	Summary   string `json:"summary,omitempty"`    // Declarations the edit touched
+	SessionID string `json:"session_id,omitempty"` // Claude session that made the edit
 }
 
 // Store manages persistent history storage
@@ -44,8 +50,12 @@ func GetHistoryPath() string {
 		cwd = "."
 	}
 
-	// Use .claude-mon-history.json in the workspace root
-	return filepath.Join(cwd, ".claude-mon-history.json")
+	return PathIn(cwd)
+}
+
+// PathIn returns the history file path for a workspace root
+func PathIn(workspacePath string) string {
+	return filepath.Join(workspacePath, ".claude-mon-history.json")
 }
 
 // Load reads history from the file
@@ -82,65 +92,58 @@ func (s *Store) Entries() []Entry {
 	return s.entries
 }
 
-// Clear removes all history
-func (s *Store) Clear() error {
-	s.entries = []Entry{}
-	return s.Save()
-}
-
-// GetCurrentCommit returns the current VCS commit info
-func GetCurrentCommit() (sha, shortSHA, vcsType string) {
-	// Try jj first (it's faster and works in git repos too via colocated mode)
-	if sha, shortSHA = getJJCommit(); sha != "" {
-		return sha, shortSHA, "jj"
-	}
-
-	// Fall back to git
-	if sha, shortSHA = getGitCommit(); sha != "" {
-		return sha, shortSHA, "git"
+// SetBookmarked sets or clears the bookmark on the entry matching the
+// timestamp and file path, and saves. Unknown entries are ignored.
+func (s *Store) SetBookmarked(timestamp time.Time, filePath string, bookmarked bool) error {
+	for i := range s.entries {
+		if s.entries[i].FilePath == filePath && s.entries[i].Timestamp.Equal(timestamp) {
+			s.entries[i].Bookmarked = bookmarked
+			return s.Save()
+		}
 	}
-
-	return "", "", ""
+	return nil
 }
 
-// getJJCommit gets the current jj change ID
-func getJJCommit() (sha, shortSHA string) {
-	// Get the current change ID (jj's equivalent of commit SHA)
-	cmd := exec.Command("jj", "log", "-r", "@", "--no-graph", "-T", "change_id")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", ""
+// SetNote sets or clears the review note on the entry matching the
+// timestamp and file path, and saves. Unknown entries are ignored.
+func (s *Store) SetNote(timestamp time.Time, filePath, note string) error {
+	for i := range s.entries {
+		if s.entries[i].FilePath == filePath && s.entries[i].Timestamp.Equal(timestamp) {
+			s.entries[i].Note = note
+			return s.Save()
+		}
 	}
+	return nil
+}
 
-	sha = strings.TrimSpace(string(output))
-	if len(sha) > 8 {
-		shortSHA = sha[:8]
-	} else {
-		shortSHA = sha
+// SetReview sets or clears the review state on the entry matching the
+// timestamp and file path, and saves. Unknown entries are ignored.
+func (s *Store) SetReview(timestamp time.Time, filePath, review string) error {
+	for i := range s.entries {
+		if s.entries[i].FilePath == filePath && s.entries[i].Timestamp.Equal(timestamp) {
+			s.entries[i].Review = review
+			return s.Save()
+		}
 	}
+	return nil
+}
 
-	return sha, shortSHA
+// Clear removes all history
+func (s *Store) Clear() error {
+	s.entries = []Entry{}
+	return s.Save()
 }
 
-// getGitCommit gets the current git commit SHA
-func getGitCommit() (sha, shortSHA string) {
-	// Get full SHA
-	cmd := exec.Command("git", "rev-parse", "HEAD")
-	output, err := cmd.Output()
-	if err != nil {
-		return "", ""
+// GetCurrentCommit returns the current VCS commit info for the working
+// directory. jj is preferred so colocated repos record change IDs.
+func GetCurrentCommit() (sha, shortSHA, vcsType string) {
+	v := vcs.Detect(".")
+	if v == nil {
+		return "", "", ""
 	}
-
-	sha = strings.TrimSpace(string(output))
-
-	// Get short SHA
-	cmd = exec.Command("git", "rev-parse", "--short", "HEAD")
-	output, err = cmd.Output()
+	sha, shortSHA, err := v.CurrentCommit(".")
 	if err != nil {
-		shortSHA = sha[:7]
-	} else {
-		shortSHA = strings.TrimSpace(string(output))
+		return "", "", ""
 	}
-
-	return sha, shortSHA
+	return sha, shortSHA, v.Name()
 }
@@ -0,0 +1,417 @@
+// Package hookcheck diagnoses the pipeline that carries Claude Code edits to
+// claude-mon: the PostToolUse hook in Claude's settings, the program it runs,
+// the claude-mon binary that program calls, the daemon and the last time the
+// hook fired, reporting the first stage that is broken.
+package hookcheck
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Status is the outcome of one stage of the pipeline
+type Status int
+
+const (
+	StatusOK   Status = iota // Stage works
+	StatusWarn               // Stage works but may not be what the user expects
+	StatusFail               // Stage is broken; edits won't get past it
+)
+
+// Stage names, in pipeline order
+const (
+	StageInstalled  = "Hook installed"
+	StageExecutable = "Hook executable"
+	StageBinary     = "claude-mon binary"
+	StageDaemon     = "Daemon running"
+	StageFired      = "Hook fired"
+)
+
+// recentWindow is how long ago the hook may have last fired and still count
+// as recent
+const recentWindow = 24 * time.Hour
+
+// Check is the result of one pipeline stage
+type Check struct {
+	Stage  string
+	Status Status
+	Detail string
+}
+
+// Hook is a PostToolUse hook command found in a settings file or hooks
+// directory
+type Hook struct {
+	Source  string // Settings file or hook script it was found in
+	Matcher string // Tool name regex, empty for all tools
+	Command string
+}
+
+// Options describes the environment to check
+type Options struct {
+	Home          string    // User home directory
+	ProjectDir    string    // Workspace root
+	Executable    string    // Path of the running claude-mon binary
+	DaemonRunning bool      // Whether the daemon answered a status query
+	LastFired     time.Time // Last edit the daemon recorded for the workspace (zero if none)
+	Now           time.Time
+}
+
+// Report is the result of checking every stage
+type Report struct {
+	Hooks  []Hook
+	Checks []Check
+}
+
+// Broken returns the first failing stage, or the first warning if nothing
+// fails, or nil if the whole pipeline is healthy
+func (r Report) Broken() *Check {
+	for _, status := range []Status{StatusFail, StatusWarn} {
+		for i := range r.Checks {
+			if r.Checks[i].Status == status {
+				return &r.Checks[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Run checks every stage of the pipeline. Stages after a failure are still
+// checked where they can be, so the report shows everything that's wrong.
+func Run(opts Options) Report {
+	var r Report
+	r.Hooks = FindHooks(opts.Home, opts.ProjectDir)
+
+	r.Checks = append(r.Checks, checkInstalled(r.Hooks, opts))
+	r.Checks = append(r.Checks, checkExecutable(r.Hooks, opts))
+	r.Checks = append(r.Checks, checkBinary(r.Hooks, opts))
+	r.Checks = append(r.Checks, checkDaemon(opts))
+	r.Checks = append(r.Checks, checkFired(opts))
+	return r
+}
+
+// SettingsFiles returns the Claude Code settings files that can hold hooks,
+// user-wide first
+func SettingsFiles(home, projectDir string) []string {
+	return []string{
+		filepath.Join(home, ".claude", "settings.json"),
+		filepath.Join(home, ".config", "claude", "settings.json"),
+		filepath.Join(projectDir, ".claude", "settings.json"),
+		filepath.Join(projectDir, ".claude", "settings.local.json"),
+	}
+}
+
+// hookScripts returns the legacy per-event hook scripts Claude Code runs
+// from hooks directories
+func hookScripts(home, projectDir string) []string {
+	return []string{
+		filepath.Join(home, ".claude", "hooks", "PostToolUse"),
+		filepath.Join(projectDir, ".claude", "hooks", "PostToolUse"),
+	}
+}
+
+// FindHooks returns every PostToolUse hook configured for the workspace
+func FindHooks(home, projectDir string) []Hook {
+	var hooks []Hook
+	for _, path := range SettingsFiles(home, projectDir) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		hooks = append(hooks, ParseSettings(path, data)...)
+	}
+	for _, path := range hookScripts(home, projectDir) {
+		if _, err := os.Stat(path); err == nil {
+			hooks = append(hooks, Hook{Source: path, Command: path})
+		}
+	}
+	return hooks
+}
+
+// ParseSettings returns the PostToolUse hooks in a settings file. Both the
+// matcher form and a bare command string are accepted:
+//
+//	{"hooks": {"PostToolUse": [{"matcher": "Edit|Write", "hooks": [{"type": "command", "command": "..."}]}]}}
+//	{"hooks": {"PostToolUse": "~/.claude/hooks/notify.sh"}}
+func ParseSettings(source string, data []byte) []Hook {
+	var settings struct {
+		Hooks map[string]json.RawMessage `json:"hooks"`
+	}
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil
+	}
+	raw, ok := settings.Hooks["PostToolUse"]
+	if !ok {
+		return nil
+	}
+
+	var command string
+	if json.Unmarshal(raw, &command) == nil {
+		return []Hook{{Source: source, Command: command}}
+	}
+
+	var groups []struct {
+		Matcher string `json:"matcher"`
+		Hooks   []struct {
+			Type    string `json:"type"`
+			Command string `json:"command"`
+		} `json:"hooks"`
+	}
+	if err := json.Unmarshal(raw, &groups); err != nil {
+		return nil
+	}
+	var hooks []Hook
+	for _, g := range groups {
+		for _, h := range g.Hooks {
+			if h.Command != "" && (h.Type == "" || h.Type == "command") {
+				hooks = append(hooks, Hook{Source: source, Matcher: g.Matcher, Command: h.Command})
+			}
+		}
+	}
+	return hooks
+}
+
+// matchesEdits reports whether a hook runs for Edit or Write tool calls
+func (h Hook) matchesEdits() bool {
+	if h.Matcher == "" || h.Matcher == "*" {
+		return true
+	}
+	re, err := regexp.Compile("^(?:" + h.Matcher + ")$")
+	if err != nil {
+		return false
+	}
+	return re.MatchString("Edit") || re.MatchString("Write")
+}
+
+func checkInstalled(hooks []Hook, opts Options) Check {
+	c := Check{Stage: StageInstalled}
+	if len(hooks) == 0 {
+		c.Status = StatusFail
+		c.Detail = "no PostToolUse hook in " + strings.Join(shortPaths(SettingsFiles(opts.Home, opts.ProjectDir), opts.Home), ", ")
+		return c
+	}
+	for _, h := range hooks {
+		if h.matchesEdits() {
+			c.Detail = fmt.Sprintf("%d PostToolUse hook(s), from %s", len(hooks), shortPath(h.Source, opts.Home))
+			return c
+		}
+	}
+	c.Status = StatusFail
+	c.Detail = fmt.Sprintf("matcher %q doesn't match Edit or Write", hooks[0].Matcher)
+	return c
+}
+
+func checkExecutable(hooks []Hook, opts Options) Check {
+	c := Check{Stage: StageExecutable}
+	if len(hooks) == 0 {
+		c.Status = StatusFail
+		c.Detail = "no hook to run"
+		return c
+	}
+	for _, h := range hooks {
+		program := Program(h.Command, opts)
+		if err := checkRunnable(program); err != nil {
+			c.Status = StatusFail
+			c.Detail = err.Error()
+			return c
+		}
+	}
+	c.Detail = "every hook command can run"
+	return c
+}
+
+func checkBinary(hooks []Hook, opts Options) Check {
+	c := Check{Stage: StageBinary}
+	var refs []string
+	for _, h := range hooks {
+		refs = append(refs, binaryRefs(h.Command, opts)...)
+		if script := Program(h.Command, opts); script != "" {
+			if data, err := readScript(script); err == nil {
+				refs = append(refs, binaryRefs(string(data), opts)...)
+			}
+		}
+	}
+	if len(refs) == 0 {
+		c.Detail = "hooks write to the sockets directly"
+		return c
+	}
+
+	running := resolve(opts.Executable)
+	for _, ref := range refs {
+		path := ref
+		if !strings.Contains(ref, "/") {
+			found, err := exec.LookPath(ref)
+			if err != nil {
+				c.Status = StatusFail
+				c.Detail = "claude-mon is not on PATH"
+				return c
+			}
+			path = found
+		}
+		if err := checkRunnable(path); err != nil {
+			c.Status = StatusFail
+			c.Detail = err.Error()
+			return c
+		}
+		if running != "" && resolve(path) != running {
+			c.Status = StatusWarn
+			c.Detail = fmt.Sprintf("hook runs %s, but this is %s", shortPath(path, opts.Home), shortPath(opts.Executable, opts.Home))
+			return c
+		}
+	}
+	c.Detail = shortPath(refs[0], opts.Home)
+	return c
+}
+
+func checkDaemon(opts Options) Check {
+	if !opts.DaemonRunning {
+		return Check{Stage: StageDaemon, Status: StatusWarn, Detail: "not running; edits reach this TUI but aren't persisted"}
+	}
+	return Check{Stage: StageDaemon, Detail: "answering queries"}
+}
+
+func checkFired(opts Options) Check {
+	c := Check{Stage: StageFired}
+	switch {
+	case !opts.DaemonRunning:
+		c.Status = StatusWarn
+		c.Detail = "unknown without the daemon"
+	case opts.LastFired.IsZero():
+		c.Status = StatusFail
+		c.Detail = "no edits recorded for this workspace"
+	case opts.Now.Sub(opts.LastFired) > recentWindow:
+		c.Status = StatusWarn
+		c.Detail = "last fired " + opts.LastFired.Local().Format("Jan 2 15:04")
+	default:
+		c.Detail = "last fired " + opts.Now.Sub(opts.LastFired).Round(time.Second).String() + " ago"
+	}
+	return c
+}
+
+// interpreters run the script named by their first argument
+var interpreters = map[string]bool{"bash": true, "sh": true, "zsh": true, "python": true, "python3": true, "node": true}
+
+// Program returns the executable a hook command runs: its first word, or
+// the script passed to an interpreter. Paths are expanded the way Claude's
+// shell would expand them.
+func Program(command string, opts Options) string {
+	words := strings.Fields(command)
+	if len(words) == 0 {
+		return ""
+	}
+	program := expand(unquote(words[0]), opts)
+	if interpreters[filepath.Base(program)] && len(words) > 1 && !strings.HasPrefix(words[1], "-") {
+		return expand(unquote(words[1]), opts)
+	}
+	return program
+}
+
+// binaryRe matches references to the claude-mon binary in a command or
+// script, bare or by path
+var binaryRe = regexp.MustCompile(`(?:^|[\s"'=(])((?:[~$./][^\s"'=;|&()]*/)?claude-mon)(?:$|[\s"';|&)])`)
+
+// binaryRefs returns the claude-mon binaries a command or script runs,
+// skipping comment lines
+func binaryRefs(text string, opts Options) []string {
+	var refs []string
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		for _, m := range binaryRe.FindAllStringSubmatch(line, -1) {
+			refs = append(refs, expand(m[1], opts))
+		}
+	}
+	return refs
+}
+
+// checkRunnable reports why a program can't be run, if it can't
+func checkRunnable(program string) error {
+	if program == "" {
+		return fmt.Errorf("empty hook command")
+	}
+	if !strings.Contains(program, "/") {
+		if _, err := exec.LookPath(program); err != nil {
+			return fmt.Errorf("%s is not on PATH", program)
+		}
+		return nil
+	}
+	info, err := os.Stat(program)
+	if err != nil {
+		return fmt.Errorf("%s does not exist", program)
+	}
+	if info.IsDir() || info.Mode()&0111 == 0 {
+		return fmt.Errorf("%s is not executable (chmod +x)", program)
+	}
+	return nil
+}
+
+// readScript reads a hook program if it's a small text file worth
+// scanning for claude-mon calls
+func readScript(path string) ([]byte, error) {
+	if !strings.Contains(path, "/") {
+		return nil, os.ErrNotExist
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Size() > 64*1024 {
+		return nil, os.ErrNotExist
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.HasPrefix(string(data), "#!") {
+		return nil, os.ErrNotExist
+	}
+	return data, nil
+}
+
+// expand expands ~, $HOME and $CLAUDE_PROJECT_DIR in a path
+func expand(path string, opts Options) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		path = opts.Home + path[1:]
+	}
+	return os.Expand(path, func(name string) string {
+		switch name {
+		case "HOME":
+			return opts.Home
+		case "CLAUDE_PROJECT_DIR":
+			return opts.ProjectDir
+		}
+		return os.Getenv(name)
+	})
+}
+
+// unquote strips shell quotes from a word
+func unquote(word string) string {
+	return strings.NewReplacer(`"`, "", "'", "").Replace(word)
+}
+
+// resolve returns a path with symlinks resolved, for comparing binaries
+func resolve(path string) string {
+	if path == "" {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
+// shortPath abbreviates the home directory in a path to ~
+func shortPath(path, home string) string {
+	if home != "" && strings.HasPrefix(path, home+"/") {
+		return "~" + strings.TrimPrefix(path, home)
+	}
+	return path
+}
+
+func shortPaths(paths []string, home string) []string {
+	short := make([]string, len(paths))
+	for i, p := range paths {
+		short[i] = shortPath(p, home)
+	}
+	return short
+}
@@ -0,0 +1,116 @@
+package hookcheck
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseSettings(t *testing.T) {
+	matcher := `{"hooks": {"PostToolUse": [{"matcher": "Edit|Write", "hooks": [
+		{"type": "command", "command": "claude-mon send"},
+		{"type": "command", "command": "~/bin/notify.sh"}
+	]}], "UserPromptSubmit": "inject-context"}}`
+	hooks := ParseSettings("settings.json", []byte(matcher))
+	if len(hooks) != 2 {
+		t.Fatalf("expected 2 hooks, got %d", len(hooks))
+	}
+	if hooks[0].Matcher != "Edit|Write" || hooks[1].Command != "~/bin/notify.sh" {
+		t.Errorf("unexpected hooks: %+v", hooks)
+	}
+
+	bare := ParseSettings("settings.json", []byte(`{"hooks": {"PostToolUse": "~/.claude/hooks/notify.sh"}}`))
+	if len(bare) != 1 || bare[0].Command != "~/.claude/hooks/notify.sh" {
+		t.Errorf("expected the bare command form to parse, got %+v", bare)
+	}
+
+	if hooks := ParseSettings("settings.json", []byte(`{"theme": "dark"}`)); len(hooks) != 0 {
+		t.Errorf("expected no hooks, got %+v", hooks)
+	}
+}
+
+func TestProgram(t *testing.T) {
+	opts := Options{Home: "/home/u", ProjectDir: "/proj"}
+	tests := map[string]string{
+		"~/bin/hook.sh --flag":                  "/home/u/bin/hook.sh",
+		`"$CLAUDE_PROJECT_DIR"/.claude/hook.sh`: "/proj/.claude/hook.sh",
+		"bash $HOME/hook.sh":                    "/home/u/hook.sh",
+		"claude-mon send":                       "claude-mon",
+	}
+	for command, want := range tests {
+		if got := Program(command, opts); got != want {
+			t.Errorf("Program(%q) = %q, want %q", command, got, want)
+		}
+	}
+}
+
+// writeFile writes a file, creating its directory
+func writeFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRunReportsFirstBrokenStage(t *testing.T) {
+	home, project := t.TempDir(), t.TempDir()
+	now := time.Now()
+	opts := Options{Home: home, ProjectDir: project, DaemonRunning: true, Now: now}
+
+	// Nothing installed
+	if broken := Run(opts).Broken(); broken == nil || broken.Stage != StageInstalled {
+		t.Fatalf("expected the install stage to fail, got %+v", broken)
+	}
+
+	// A hook whose matcher skips edits
+	settings := filepath.Join(project, ".claude", "settings.json")
+	writeFile(t, settings, `{"hooks": {"PostToolUse": [{"matcher": "Bash", "hooks": [{"type": "command", "command": "$CLAUDE_PROJECT_DIR/hook.sh"}]}]}}`, 0644)
+	if broken := Run(opts).Broken(); broken == nil || broken.Stage != StageInstalled {
+		t.Fatalf("expected a non-matching matcher to fail install, got %+v", broken)
+	}
+
+	// A matching hook whose script isn't executable
+	writeFile(t, settings, `{"hooks": {"PostToolUse": [{"matcher": "Edit|Write", "hooks": [{"type": "command", "command": "$CLAUDE_PROJECT_DIR/hook.sh"}]}]}}`, 0644)
+	binary := filepath.Join(home, "bin", "claude-mon")
+	writeFile(t, filepath.Join(project, "hook.sh"), "#!/bin/sh\n# claude-mon hook\n"+binary+" send\n", 0644)
+	if broken := Run(opts).Broken(); broken == nil || broken.Stage != StageExecutable {
+		t.Fatalf("expected the executable stage to fail, got %+v", broken)
+	}
+
+	// The script calls a claude-mon binary that doesn't exist
+	if err := os.Chmod(filepath.Join(project, "hook.sh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if broken := Run(opts).Broken(); broken == nil || broken.Stage != StageBinary || broken.Status != StatusFail {
+		t.Fatalf("expected the binary stage to fail, got %+v", broken)
+	}
+
+	// The binary exists but isn't the one running
+	writeFile(t, binary, "#!/bin/sh\n", 0755)
+	opts.Executable = filepath.Join(home, "other", "claude-mon")
+	writeFile(t, opts.Executable, "#!/bin/sh\n", 0755)
+	if broken := Run(opts).Broken(); broken == nil || broken.Stage != StageFired {
+		t.Fatalf("expected a hook that never fired to fail, got %+v", broken)
+	}
+	report := Run(opts)
+	if report.Checks[2].Status != StatusWarn {
+		t.Errorf("expected a binary mismatch warning, got %+v", report.Checks[2])
+	}
+
+	// Healthy once the hook runs this binary and has fired
+	opts.Executable = binary
+	opts.LastFired = now.Add(-5 * time.Minute)
+	if broken := Run(opts).Broken(); broken != nil {
+		t.Errorf("expected a healthy pipeline, got %+v", broken)
+	}
+
+	// Without the daemon, edits still reach the TUI but the fire time is unknown
+	opts.DaemonRunning = false
+	if broken := Run(opts).Broken(); broken == nil || broken.Stage != StageDaemon || broken.Status != StatusWarn {
+		t.Errorf("expected a daemon warning, got %+v", broken)
+	}
+}
@@ -0,0 +1,209 @@
+// Package lang detects a file's language from its name or shebang line
+package lang
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Language is a detected file type
+type Language struct {
+	ID    string // Short lowercase name used in filters, e.g. "go", "ts"
+	Name  string // Display name, e.g. "TypeScript"
+	Icon  string // Nerd Font glyph
+	ASCII string // Two-column fallback tag
+	Color string // Icon color (hex)
+}
+
+// Unknown is returned for files that match no language
+var Unknown = Language{ID: "", Name: "Unknown", Icon: "\uf15b", ASCII: "  ", Color: "#6c7086"}
+
+var languages = []Language{
+	{ID: "go", Name: "Go", Icon: "\ue627", ASCII: "go", Color: "#00add8"},
+	{ID: "py", Name: "Python", Icon: "\ue606", ASCII: "py", Color: "#ffd43b"},
+	{ID: "js", Name: "JavaScript", Icon: "\ue60c", ASCII: "js", Color: "#f7df1e"},
+	{ID: "ts", Name: "TypeScript", Icon: "\ue628", ASCII: "ts", Color: "#3178c6"},
+	{ID: "jsx", Name: "React", Icon: "\ue7ba", ASCII: "rx", Color: "#61dafb"},
+	{ID: "vue", Name: "Vue", Icon: "\ue6a0", ASCII: "vu", Color: "#41b883"},
+	{ID: "svelte", Name: "Svelte", Icon: "\ue697", ASCII: "sv", Color: "#ff3e00"},
+	{ID: "html", Name: "HTML", Icon: "\ue60e", ASCII: "<>", Color: "#e34c26"},
+	{ID: "css", Name: "CSS", Icon: "\ue749", ASCII: "cs", Color: "#563d7c"},
+	{ID: "rs", Name: "Rust", Icon: "\ue7a8", ASCII: "rs", Color: "#dea584"},
+	{ID: "c", Name: "C", Icon: "\ue61e", ASCII: "c ", Color: "#555555"},
+	{ID: "cpp", Name: "C++", Icon: "\ue61d", ASCII: "c+", Color: "#f34b7d"},
+	{ID: "java", Name: "Java", Icon: "\ue738", ASCII: "jv", Color: "#b07219"},
+	{ID: "rb", Name: "Ruby", Icon: "\ue739", ASCII: "rb", Color: "#cc342d"},
+	{ID: "lua", Name: "Lua", Icon: "\ue620", ASCII: "lu", Color: "#000080"},
+	{ID: "sh", Name: "Shell", Icon: "\ue795", ASCII: "sh", Color: "#89e051"},
+	{ID: "sql", Name: "SQL", Icon: "\ue706", ASCII: "sq", Color: "#e38c00"},
+	{ID: "nix", Name: "Nix", Icon: "\uf313", ASCII: "nx", Color: "#7e7eff"},
+	{ID: "md", Name: "Markdown", Icon: "\ue609", ASCII: "md", Color: "#519aba"},
+	{ID: "json", Name: "JSON", Icon: "\ue60b", ASCII: "{}", Color: "#cbcb41"},
+	{ID: "yaml", Name: "YAML", Icon: "\ue6a8", ASCII: "ym", Color: "#cb171e"},
+	{ID: "toml", Name: "TOML", Icon: "\ue615", ASCII: "tm", Color: "#9c4221"},
+	{ID: "docker", Name: "Dockerfile", Icon: "\uf308", ASCII: "dk", Color: "#384d54"},
+	{ID: "make", Name: "Makefile", Icon: "\ue673", ASCII: "mk", Color: "#427819"},
+}
+
+// byExt maps file extensions (without the dot) to language IDs
+var byExt = map[string]string{
+	"go": "go",
+	"py": "py", "pyi": "py",
+	"js": "js", "mjs": "js", "cjs": "js",
+	"ts": "ts", "mts": "ts", "cts": "ts",
+	"jsx": "jsx", "tsx": "jsx",
+	"vue":    "vue",
+	"svelte": "svelte",
+	"html":   "html", "htm": "html",
+	"css": "css", "scss": "css", "sass": "css", "less": "css",
+	"rs": "rs",
+	"c":  "c", "h": "c",
+	"cc": "cpp", "cpp": "cpp", "cxx": "cpp", "hpp": "cpp", "hh": "cpp",
+	"java": "java",
+	"rb":   "rb",
+	"lua":  "lua",
+	"sh":   "sh", "bash": "sh", "zsh": "sh", "fish": "sh",
+	"sql": "sql",
+	"nix": "nix",
+	"md":  "md", "markdown": "md", "mdx": "md",
+	"json": "json", "jsonc": "json", "jsonl": "json",
+	"yaml": "yaml", "yml": "yaml",
+	"toml": "toml",
+}
+
+// byName maps well-known file names to language IDs
+var byName = map[string]string{
+	"Dockerfile":    "docker",
+	"Containerfile": "docker",
+	"Makefile":      "make",
+	"GNUmakefile":   "make",
+	"Gemfile":       "rb",
+	"Rakefile":      "rb",
+	".bashrc":       "sh",
+	".zshrc":        "sh",
+	".profile":      "sh",
+}
+
+// byInterpreter maps shebang interpreters to language IDs
+var byInterpreter = map[string]string{
+	"sh": "sh", "bash": "sh", "zsh": "sh", "dash": "sh", "ksh": "sh", "fish": "sh",
+	"python": "py",
+	"node":   "js", "deno": "ts", "bun": "ts",
+	"ruby": "rb",
+	"lua":  "lua",
+}
+
+// Lookup returns the language with the given ID or name (case-insensitive)
+func Lookup(query string) (Language, bool) {
+	for _, l := range languages {
+		if strings.EqualFold(l.ID, query) || strings.EqualFold(l.Name, query) {
+			return l, true
+		}
+	}
+	if id, ok := byExt[strings.ToLower(strings.TrimPrefix(query, "."))]; ok {
+		return byID(id), true
+	}
+	return Unknown, false
+}
+
+// DetectContent detects a file's language from its name, falling back to
+// the shebang on the first line of content for extensionless scripts
+func DetectContent(path, content string) Language {
+	if l, ok := fromName(path); ok {
+		return l
+	}
+	line, _, _ := strings.Cut(content, "\n")
+	return fromShebang(line)
+}
+
+var (
+	shebangMu    sync.Mutex
+	shebangCache = make(map[string]Language)
+)
+
+// Detect detects a file's language from its name, reading the shebang from
+// disk for extensionless files. Shebang lookups are cached per path.
+func Detect(path string) Language {
+	if l, ok := fromName(path); ok {
+		return l
+	}
+	if filepath.Ext(path) != "" {
+		return Unknown
+	}
+
+	shebangMu.Lock()
+	l, ok := shebangCache[path]
+	shebangMu.Unlock()
+	if ok {
+		return l
+	}
+
+	l = Unknown
+	if f, err := os.Open(path); err == nil {
+		line, _ := bufio.NewReader(f).ReadString('\n')
+		f.Close()
+		l = fromShebang(line)
+	}
+
+	shebangMu.Lock()
+	shebangCache[path] = l
+	shebangMu.Unlock()
+	return l
+}
+
+// fromName detects a language from a file name or extension
+func fromName(path string) (Language, bool) {
+	base := filepath.Base(path)
+	if id, ok := byName[base]; ok {
+		return byID(id), true
+	}
+	if strings.HasPrefix(base, "Dockerfile.") {
+		return byID("docker"), true
+	}
+	if id, ok := byExt[strings.ToLower(strings.TrimPrefix(filepath.Ext(base), "."))]; ok {
+		return byID(id), true
+	}
+	return Unknown, false
+}
+
+// fromShebang detects a language from a "#!" line, e.g. "#!/usr/bin/env python3"
+func fromShebang(line string) Language {
+	if !strings.HasPrefix(line, "#!") {
+		return Unknown
+	}
+	fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+	if len(fields) == 0 {
+		return Unknown
+	}
+
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		// Skip env flags such as -S
+		interpreter = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") {
+				interpreter = filepath.Base(f)
+				break
+			}
+		}
+	}
+
+	// python3.12 -> python
+	interpreter = strings.TrimRight(interpreter, "0123456789.")
+	if id, ok := byInterpreter[interpreter]; ok {
+		return byID(id)
+	}
+	return Unknown
+}
+
+func byID(id string) Language {
+	for _, l := range languages {
+		if l.ID == id {
+			return l
+		}
+	}
+	return Unknown
+}
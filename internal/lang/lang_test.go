@@ -0,0 +1,66 @@
+package lang
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectByName(t *testing.T) {
+	tests := map[string]string{
+		"/src/main.go":          "go",
+		"web/App.tsx":           "jsx",
+		"web/index.TS":          "ts",
+		"docs/README.md":        "md",
+		"deploy/Dockerfile":     "docker",
+		"deploy/Dockerfile.dev": "docker",
+		"Makefile":              "make",
+		"config.yml":            "yaml",
+		"notes.xyz":             "",
+	}
+	for path, want := range tests {
+		if got := Detect(path).ID; got != want {
+			t.Errorf("Detect(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestDetectShebang(t *testing.T) {
+	tests := map[string]string{
+		"#!/bin/bash\necho hi":                   "sh",
+		"#!/usr/bin/env python3.12\nprint()":     "py",
+		"#!/usr/bin/env -S deno run --allow-net": "ts",
+		"#!/usr/bin/perl":                        "",
+		"echo no shebang":                        "",
+	}
+	for content, want := range tests {
+		if got := DetectContent("bin/tool", content).ID; got != want {
+			t.Errorf("DetectContent(%q) = %q, want %q", content, got, want)
+		}
+	}
+
+	// Extensionless files on disk are sniffed once
+	path := filepath.Join(t.TempDir(), "deploy")
+	os.WriteFile(path, []byte("#!/usr/bin/env node\n"), 0755)
+	if got := Detect(path).ID; got != "js" {
+		t.Errorf("expected js from shebang, got %q", got)
+	}
+	os.WriteFile(path, []byte("#!/bin/sh\n"), 0755)
+	if got := Detect(path).ID; got != "js" {
+		t.Errorf("expected cached js, got %q", got)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	for _, query := range []string{"go", "Go", "typescript", ".ts", "yml"} {
+		if _, ok := Lookup(query); !ok {
+			t.Errorf("expected %q to resolve", query)
+		}
+	}
+	if l, _ := Lookup("yml"); l.ID != "yaml" {
+		t.Errorf("expected yml to resolve to yaml, got %q", l.ID)
+	}
+	if _, ok := Lookup("cobol"); ok {
+		t.Error("expected unknown language")
+	}
+}
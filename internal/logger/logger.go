@@ -1,89 +1,196 @@
 package logger
 
 import (
-	"os"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Options configures where and what the process logs
+type Options struct {
+	Path       string // Log file; empty writes none
+	Level      string // debug, info, warn or error; empty is info
+	MaxSizeMB  int    // Size at which the file is rotated; 0 never rotates
+	MaxBackups int    // Rotated files kept
+	Compress   bool   // Gzip rotated files
+	Component  string // Component of messages logged without one, e.g. "daemon"
+	Buffer     int    // Latest entries kept in memory for Recent; 0 keeps none
+}
 
-	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
+var (
+	mu               sync.Mutex
+	out              io.WriteCloser
+	base             atomic.Pointer[slog.Logger]
+	level            slog.LevelVar
+	defaultComponent atomic.Value // string
 )
 
-var log *zap.SugaredLogger
-var enabled bool
+// std logs messages as the process's default component
+var std = For("")
+
+// Setup starts logging JSON lines to opts.Path and keeping the latest
+// opts.Buffer entries in memory, replacing any earlier setup. With neither,
+// logging is disabled.
+func Setup(opts Options) error {
+	lvl, err := ParseLevel(opts.Level)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	closeLocked()
+	defaultComponent.Store(opts.Component)
+	level.Set(lvl)
+
+	var handlers fanout
+	if opts.Path != "" {
+		w, err := openRotating(opts.Path, int64(opts.MaxSizeMB)<<20, opts.MaxBackups, opts.Compress)
+		if err != nil {
+			return err
+		}
+		out = w
+		handlers = append(handlers, slog.NewJSONHandler(w, &slog.HandlerOptions{Level: &level}))
+	}
+
+	var r *ring
+	if opts.Buffer > 0 {
+		r = newRing(opts.Buffer)
+		handlers = append(handlers, &ringHandler{ring: r, level: &level})
+	}
+	recent.mu.Lock()
+	recent.r = r
+	recent.mu.Unlock()
+
+	switch len(handlers) {
+	case 0:
+	case 1:
+		base.Store(slog.New(handlers[0]))
+	default:
+		base.Store(slog.New(handlers))
+	}
+	return nil
+}
 
-// Init initializes the logger. If debug is false, logging is disabled.
-func Init(path string, debug bool) error {
-	enabled = debug
-	if !debug {
-		// Create a no-op logger when debug is disabled
-		log = zap.NewNop().Sugar()
-		return nil
+// ParseLevel parses a level name; empty is info
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(s) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
 	}
+	return 0, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", s)
+}
 
-	// Create log file
-	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+// SetLevel changes the minimum level logged
+func SetLevel(s string) error {
+	lvl, err := ParseLevel(s)
 	if err != nil {
 		return err
 	}
+	level.Set(lvl)
+	return nil
+}
+
+// Logger logs messages tagged with a component
+type Logger struct {
+	component string
+}
 
-	// Configure encoder for human-readable output
-	encoderConfig := zapcore.EncoderConfig{
-		TimeKey:        "time",
-		LevelKey:       "level",
-		MessageKey:     "msg",
-		EncodeTime:     zapcore.TimeEncoderOfLayout("15:04:05.000"),
-		EncodeLevel:    zapcore.CapitalLevelEncoder,
-		EncodeDuration: zapcore.StringDurationEncoder,
+// For returns a logger for a component. It can be created before Setup
+// and follows whatever Setup configures.
+func For(component string) *Logger {
+	return &Logger{component: component}
+}
+
+func (l *Logger) log(lvl slog.Level, msg string, keysAndValues ...interface{}) {
+	b := base.Load()
+	if b == nil || !b.Enabled(context.Background(), lvl) {
+		return
+	}
+	component := l.component
+	if component == "" {
+		component, _ = defaultComponent.Load().(string)
 	}
+	if component != "" {
+		keysAndValues = append([]interface{}{"component", component}, keysAndValues...)
+	}
+	b.Log(context.Background(), lvl, msg, keysAndValues...)
+}
+
+// Debug logs a debug message
+func (l *Logger) Debug(msg string, keysAndValues ...interface{}) {
+	l.log(slog.LevelDebug, msg, keysAndValues...)
+}
 
-	// Write only to file, not to stderr
-	core := zapcore.NewCore(
-		zapcore.NewConsoleEncoder(encoderConfig),
-		zapcore.AddSync(file),
-		zapcore.DebugLevel,
-	)
+// Info logs an info message
+func (l *Logger) Info(msg string, keysAndValues ...interface{}) {
+	l.log(slog.LevelInfo, msg, keysAndValues...)
+}
 
-	logger := zap.New(core)
-	log = logger.Sugar()
+// Warn logs a warning
+func (l *Logger) Warn(msg string, keysAndValues ...interface{}) {
+	l.log(slog.LevelWarn, msg, keysAndValues...)
+}
 
-	return nil
+// Error logs an error message
+func (l *Logger) Error(msg string, keysAndValues ...interface{}) {
+	l.log(slog.LevelError, msg, keysAndValues...)
+}
+
+// Log logs a formatted info message
+func (l *Logger) Log(format string, args ...interface{}) {
+	if b := base.Load(); b == nil || !b.Enabled(context.Background(), slog.LevelInfo) {
+		return
+	}
+	l.log(slog.LevelInfo, fmt.Sprintf(format, args...))
 }
 
 // Debug logs a debug message
 func Debug(msg string, keysAndValues ...interface{}) {
-	if log != nil {
-		log.Debugw(msg, keysAndValues...)
-	}
+	std.Debug(msg, keysAndValues...)
 }
 
 // Info logs an info message
 func Info(msg string, keysAndValues ...interface{}) {
-	if log != nil {
-		log.Infow(msg, keysAndValues...)
-	}
+	std.Info(msg, keysAndValues...)
+}
+
+// Warn logs a warning
+func Warn(msg string, keysAndValues ...interface{}) {
+	std.Warn(msg, keysAndValues...)
 }
 
 // Error logs an error message
 func Error(msg string, keysAndValues ...interface{}) {
-	if log != nil {
-		log.Errorw(msg, keysAndValues...)
-	}
+	std.Error(msg, keysAndValues...)
 }
 
 // Log is a simple log function for backwards compatibility
 func Log(format string, args ...interface{}) {
-	if log != nil {
-		log.Debugf(format, args...)
-	}
+	std.Log(format, args...)
 }
 
-// Sync flushes the logger
-func Sync() {
-	if log != nil {
-		log.Sync()
-	}
+// Close stops logging and closes the log file
+func Close() {
+	mu.Lock()
+	defer mu.Unlock()
+	closeLocked()
 }
 
-// Close syncs and closes the logger
-func Close() {
-	Sync()
+func closeLocked() {
+	base.Store(nil)
+	if out != nil {
+		out.Close()
+		out = nil
+	}
 }
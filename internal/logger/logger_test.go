@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoggerLevelsAndComponents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "claude-mon.log")
+	if err := Setup(Options{Path: path, Level: "info", Component: "daemon"}); err != nil {
+		t.Fatal(err)
+	}
+	defer Close()
+
+	chat := For("chat")
+	Debug("hidden")
+	Log("Listening on %s", "/tmp/q.sock")
+	chat.Warn("PTY exited", "pid", 42, "reason", "signal: killed")
+	if err := SetLevel("error"); err != nil {
+		t.Fatal(err)
+	}
+	Info("hidden too")
+	chat.Error("failed")
+	Close()
+
+	var out bytes.Buffer
+	if err := View(context.Background(), path, ViewOptions{Level: slog.LevelDebug}, &out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 entries, got %d:\n%s", len(lines), out.String())
+	}
+	if !strings.Contains(lines[0], "INFO  daemon: Listening on /tmp/q.sock") {
+		t.Errorf("unexpected entry: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `WARN  chat: PTY exited pid=42 reason="signal: killed"`) {
+		t.Errorf("unexpected entry: %s", lines[1])
+	}
+
+	out.Reset()
+	View(context.Background(), path, ViewOptions{Level: slog.LevelWarn, Component: "chat"}, &out)
+	if got := strings.Count(out.String(), "\n"); got != 2 {
+		t.Errorf("expected 2 chat warnings and errors, got %d", got)
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "claude-mon.log")
+	r, err := openRotating(path, 100, 2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := []byte(strings.Repeat("x", 59) + "\n")
+	for i := 0; i < 5; i++ {
+		if _, err := r.Write(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r.Close()
+
+	// Five 60-byte writes fill five files; the two newest backups are kept
+	for _, name := range []string{"claude-mon.log", "claude-mon.log.1.gz", "claude-mon.log.2.gz"} {
+		if _, err := os.Stat(filepath.Join(filepath.Dir(path), name)); err != nil {
+			t.Errorf("expected %s: %v", name, err)
+		}
+	}
+	for _, name := range []string{"claude-mon.log.1", "claude-mon.log.3.gz"} {
+		if _, err := os.Stat(filepath.Join(filepath.Dir(path), name)); err == nil {
+			t.Errorf("expected no %s", name)
+		}
+	}
+	if info, _ := os.Stat(path); info.Size() != int64(len(line)) {
+		t.Errorf("expected the current log to hold one line, got %d bytes", info.Size())
+	}
+}
+
+func TestRecent(t *testing.T) {
+	if err := Setup(Options{Level: "debug", Component: "tui", Buffer: 3}); err != nil {
+		t.Fatal(err)
+	}
+	defer Close()
+
+	for i := 0; i < 4; i++ {
+		Log("payload %d", i)
+	}
+	For("chat").Debug("PTY started", "pid", 7)
+
+	entries := Recent()
+	if len(entries) != 3 {
+		t.Fatalf("expected the 3 latest entries, got %d", len(entries))
+	}
+	if entries[0].Msg != "payload 2" || entries[0].Component != "tui" {
+		t.Errorf("unexpected oldest entry: %+v", entries[0])
+	}
+	last := entries[2]
+	if last.Component != "chat" || last.Level != slog.LevelDebug || len(last.Attrs) != 1 || last.Attrs[0] != [2]string{"pid", "7"} {
+		t.Errorf("unexpected newest entry: %+v", last)
+	}
+}
@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// ring keeps the latest entries logged, for viewing them in-process
+type ring struct {
+	mu      sync.Mutex
+	entries []Entry
+	next    int  // Slot the next entry goes in
+	full    bool // Whether every slot has been written
+}
+
+var recent struct {
+	mu sync.Mutex
+	r  *ring
+}
+
+func newRing(size int) *ring {
+	return &ring{entries: make([]Entry, size)}
+}
+
+func (r *ring) add(e Entry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Recent returns the entries kept in memory, oldest first; none unless
+// Setup was given a Buffer
+func Recent() []Entry {
+	recent.mu.Lock()
+	r := recent.r
+	recent.mu.Unlock()
+	if r == nil {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]Entry(nil), r.entries[:r.next]...)
+	}
+	out := make([]Entry, 0, len(r.entries))
+	out = append(out, r.entries[r.next:]...)
+	return append(out, r.entries[:r.next]...)
+}
+
+// ringHandler is a slog.Handler adding records to a ring. Groups are
+// flattened, as the package's loggers don't use them.
+type ringHandler struct {
+	ring  *ring
+	level slog.Leveler
+	attrs []slog.Attr
+}
+
+func (h *ringHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= h.level.Level()
+}
+
+func (h *ringHandler) Handle(_ context.Context, r slog.Record) error {
+	e := Entry{Time: r.Time, Level: r.Level, Msg: r.Message}
+	add := func(a slog.Attr) bool {
+		if a.Key == "component" {
+			e.Component = a.Value.String()
+		} else {
+			e.Attrs = append(e.Attrs, [2]string{a.Key, a.Value.String()})
+		}
+		return true
+	}
+	for _, a := range h.attrs {
+		add(a)
+	}
+	r.Attrs(add)
+	h.ring.add(e)
+	return nil
+}
+
+func (h *ringHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ringHandler{ring: h.ring, level: h.level, attrs: append(append([]slog.Attr(nil), h.attrs...), attrs...)}
+}
+
+func (h *ringHandler) WithGroup(string) slog.Handler {
+	return h
+}
+
+// fanout is a slog.Handler passing records to every handler enabled for them
+type fanout []slog.Handler
+
+func (f fanout) Enabled(ctx context.Context, l slog.Level) bool {
+	for _, h := range f {
+		if h.Enabled(ctx, l) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fanout) Handle(ctx context.Context, r slog.Record) error {
+	var first error
+	for _, h := range f {
+		if h.Enabled(ctx, r.Level) {
+			if err := h.Handle(ctx, r.Clone()); err != nil && first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+func (f fanout) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(fanout, len(f))
+	for i, h := range f {
+		out[i] = h.WithAttrs(attrs)
+	}
+	return out
+}
+
+func (f fanout) WithGroup(name string) slog.Handler {
+	out := make(fanout, len(f))
+	for i, h := range f {
+		out[i] = h.WithGroup(name)
+	}
+	return out
+}
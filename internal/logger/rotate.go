@@ -0,0 +1,140 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// rotatingFile appends to a log file, renaming it to <path>.1 once it
+// reaches maxSize and shifting older files up to <path>.<maxBackups>
+type rotatingFile struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	compress   bool
+	f          *os.File
+	size       int64
+}
+
+func openRotating(path string, maxSize int64, maxBackups int, compress bool) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	r := &rotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups, compress: compress}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the log file for appending; called with mu held or before
+// the file is shared
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to open log: %w", err)
+	}
+	r.f, r.size = f, info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return 0, os.ErrClosed
+	}
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate shifts the backups up one, dropping the oldest, and starts a new
+// file; called with mu held
+func (r *rotatingFile) rotate() error {
+	r.f.Close()
+	r.f = nil
+
+	ext := ""
+	if r.compress {
+		ext = ".gz"
+	}
+	if r.maxBackups <= 0 {
+		os.Remove(r.path)
+		return r.open()
+	}
+	os.Remove(fmt.Sprintf("%s.%d%s", r.path, r.maxBackups, ext))
+	for i := r.maxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d%s", r.path, i, ext), fmt.Sprintf("%s.%d%s", r.path, i+1, ext))
+	}
+
+	backup := r.path + ".1"
+	if err := os.Rename(r.path, backup); err != nil {
+		return fmt.Errorf("failed to rotate log: %w", err)
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	if r.compress {
+		// A backup that can't be compressed is kept as it is until the
+		// next rotation replaces it
+		gzipFile(backup)
+	}
+	return nil
+}
+
+// gzipFile replaces path with path.gz
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
@@ -0,0 +1,180 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// Entry is a parsed log line
+type Entry struct {
+	Time      time.Time
+	Level     slog.Level
+	Component string
+	Msg       string
+	Attrs     [][2]string // Remaining keys and values, in logged order
+}
+
+// ParseEntry parses a JSON log line
+func ParseEntry(line []byte) (*Entry, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("not a log entry")
+	}
+
+	e := &Entry{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		var s string
+		if json.Unmarshal(value, &s) != nil {
+			s = string(value)
+		}
+		switch key {
+		case slog.TimeKey:
+			e.Time, _ = time.Parse(time.RFC3339Nano, s)
+		case slog.LevelKey:
+			e.Level.UnmarshalText([]byte(s))
+		case slog.MessageKey:
+			e.Msg = s
+		case "component":
+			e.Component = s
+		default:
+			e.Attrs = append(e.Attrs, [2]string{key, s})
+		}
+	}
+	return e, nil
+}
+
+// String formats the entry for reading, e.g.
+// "2026-01-02 15:04:05.000 INFO  daemon: Config reloaded path=..."
+func (e *Entry) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %-5s ", e.Time.Local().Format("2006-01-02 15:04:05.000"), e.Level)
+	if e.Component != "" {
+		b.WriteString(e.Component + ": ")
+	}
+	b.WriteString(e.Msg)
+	for _, kv := range e.Attrs {
+		value := kv[1]
+		if strings.ContainsAny(value, " \"=") {
+			value = fmt.Sprintf("%q", value)
+		}
+		fmt.Fprintf(&b, " %s=%s", kv[0], value)
+	}
+	return b.String()
+}
+
+// ViewOptions selects the entries View prints
+type ViewOptions struct {
+	Level     slog.Level // Minimum level
+	Component string     // Only this component, if set
+	Follow    bool       // Keep printing entries as they're logged
+}
+
+// followInterval is how often View checks for new entries when following
+const followInterval = 250 * time.Millisecond
+
+// View prints a log file's entries to w. Following, it waits for more
+// until ctx is done, reopening the file when it's rotated.
+func View(ctx context.Context, path string, opts ViewOptions, w io.Writer) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+
+	r := bufio.NewReader(f)
+	var partial []byte
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == nil {
+			printEntry(append(partial, line...), opts, w)
+			partial = nil
+			continue
+		}
+		if err != io.EOF {
+			return err
+		}
+		partial = append(partial, line...)
+		if !opts.Follow {
+			if len(partial) > 0 {
+				printEntry(partial, opts, w)
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(followInterval):
+		}
+
+		// Start over on the new file once the log's been rotated, after
+		// whatever was written to the old one
+		if rotated(f, path) {
+			nf, err := os.Open(path)
+			if err != nil {
+				continue
+			}
+			if rest, _ := io.ReadAll(r); len(rest) > 0 {
+				for _, line := range bytes.SplitAfter(append(partial, rest...), []byte("\n")) {
+					printEntry(line, opts, w)
+				}
+			}
+			f.Close()
+			f, r, partial = nf, bufio.NewReader(nf), nil
+		}
+	}
+}
+
+// rotated reports whether path is no longer the open file f
+func rotated(f *os.File, path string) bool {
+	current, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	open, err := f.Stat()
+	if err != nil {
+		return true
+	}
+	if !os.SameFile(open, current) {
+		return true
+	}
+	offset, err := f.Seek(0, io.SeekCurrent)
+	return err == nil && current.Size() < offset
+}
+
+func printEntry(line []byte, opts ViewOptions, w io.Writer) {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return
+	}
+	e, err := ParseEntry(line)
+	if err != nil {
+		// Lines from before structured logging are printed as they are
+		if opts.Level <= slog.LevelDebug && opts.Component == "" {
+			fmt.Fprintf(w, "%s\n", line)
+		}
+		return
+	}
+	if e.Level < opts.Level || (opts.Component != "" && e.Component != opts.Component) {
+		return
+	}
+	fmt.Fprintln(w, e.String())
+}
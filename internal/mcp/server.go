@@ -0,0 +1,165 @@
+// Package mcp serves claude-mon's history to Claude Code over the Model
+// Context Protocol: a JSON-RPC server on stdio whose tools query the daemon,
+// so Claude can look up what it changed before.
+package mcp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/ztaylor/claude-mon/internal/daemon"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// protocolVersions are the MCP revisions the server speaks, newest last. A
+// client asking for one of them gets it; any other gets the newest.
+var protocolVersions = []string{"2024-11-05", "2025-03-26", "2025-06-18"}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Server answers MCP requests about a workspace's history
+type Server struct {
+	Workspace string // Workspace the tools look at unless asked for all of them
+	Version   string // claude-mon's version, reported to the client
+
+	// Query sends a query to the daemon
+	Query func(*daemon.Query) (*daemon.QueryResult, error)
+	// Context returns the workspace's working context as injected into
+	// prompts, or "" if none is set
+	Context func() (string, error)
+}
+
+// request is a JSON-RPC request, or a notification if it has no ID
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC response, with either a result or an error
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serve reads requests from r, one JSON message per line, and writes the
+// responses to w until r ends
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	encoder := json.NewEncoder(w)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil {
+			logger.Log("MCP: invalid message: %v", err)
+			if err := encoder.Encode(response{JSONRPC: "2.0", ID: json.RawMessage("null"),
+				Error: &rpcError{Code: codeParseError, Message: "parse error"}}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		result, rpcErr := s.handle(req)
+		if len(req.ID) == 0 {
+			continue // Notifications get no response
+		}
+		resp := response{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr}
+		if err := encoder.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// handle answers one request
+func (s *Server) handle(req request) (any, *rpcError) {
+	logger.Log("MCP: %s", req.Method)
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		json.Unmarshal(req.Params, &params)
+		version := protocolVersions[len(protocolVersions)-1]
+		if slices.Contains(protocolVersions, params.ProtocolVersion) {
+			version = params.ProtocolVersion
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "claude-mon", "version": s.Version},
+			"instructions": "claude-mon records every edit made in this workspace. " +
+				"Use these tools to see what was changed before, and why.",
+		}, nil
+
+	case "ping":
+		return map[string]any{}, nil
+
+	case "tools/list":
+		list := make([]map[string]any, 0, len(tools))
+		for _, t := range tools {
+			list = append(list, map[string]any{
+				"name":        t.name,
+				"description": t.description,
+				"inputSchema": t.schema,
+			})
+		}
+		return map[string]any{"tools": list}, nil
+
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		t := findTool(params.Name)
+		if t == nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool: %s", params.Name)}
+		}
+		if len(params.Arguments) == 0 {
+			params.Arguments = json.RawMessage("{}")
+		}
+
+		// Failures are the tool's result, so Claude sees what went wrong
+		text, err := t.call(s, params.Arguments)
+		if err != nil {
+			return toolResult(err.Error(), true), nil
+		}
+		return toolResult(text, false), nil
+
+	default:
+		return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+	}
+}
+
+// toolResult is a tools/call result holding text
+func toolResult(text string, isError bool) map[string]any {
+	return map[string]any{
+		"content": []map[string]any{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
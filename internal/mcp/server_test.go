@@ -0,0 +1,130 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/daemon"
+	"github.com/ztaylor/claude-mon/internal/database"
+)
+
+// serve runs a session of requests, one per line, and returns the
+// responses by ID
+func serve(t *testing.T, s *Server, requests ...string) map[string]response {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.Serve(strings.NewReader(strings.Join(requests, "\n")+"\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+
+	responses := make(map[string]response)
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var resp response
+		if err := decoder.Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		responses[string(resp.ID)] = resp
+	}
+	return responses
+}
+
+// resultText returns a tools/call response's text and whether it's an error
+func resultText(t *testing.T, resp response) (string, bool) {
+	t.Helper()
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %s", resp.Error.Message)
+	}
+	data, _ := json.Marshal(resp.Result)
+	var result struct {
+		Content []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+		IsError bool `json:"isError"`
+	}
+	json.Unmarshal(data, &result)
+	if len(result.Content) != 1 {
+		t.Fatalf("expected one content item, got %s", data)
+	}
+	return result.Content[0].Text, result.IsError
+}
+
+func TestServer(t *testing.T) {
+	var queries []*daemon.Query
+	s := &Server{
+		Workspace: "/src/api",
+		Version:   "test",
+		Query: func(q *daemon.Query) (*daemon.QueryResult, error) {
+			queries = append(queries, q)
+			if q.Type == "file" {
+				return nil, errors.New("daemon not running")
+			}
+			return &daemon.QueryResult{Edits: []*database.Edit{{
+				ToolName: "Edit", FilePath: "/src/api/retry.go", LineNum: 12,
+				OldString: "retry(3)", NewString: "retry(5)", Note: "too few",
+				Timestamp: time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC),
+			}}}, nil
+		},
+		Context: func() (string, error) { return "", nil },
+	}
+
+	responses := serve(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"search_history","arguments":{"query":"retry","limit":5}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"get_file_history","arguments":{"path":"retry.go"}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"get_working_context"}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"rm_rf"}}`,
+		`{"jsonrpc":"2.0","id":7,"method":"resources/list"}`,
+		`not json`,
+	)
+	if len(responses) != 8 {
+		t.Fatalf("expected 8 responses (none for the notification), got %d", len(responses))
+	}
+
+	init, _ := json.Marshal(responses["1"].Result)
+	if !strings.Contains(string(init), `"protocolVersion":"2024-11-05"`) || !strings.Contains(string(init), `"tools":{}`) {
+		t.Errorf("unexpected initialize result %s", init)
+	}
+	list, _ := json.Marshal(responses["2"].Result)
+	for _, name := range []string{"get_recent_edits", "get_file_history", "get_working_context", "search_history"} {
+		if !strings.Contains(string(list), `"name":"`+name+`"`) {
+			t.Errorf("expected %s listed", name)
+		}
+	}
+
+	text, isErr := resultText(t, responses["3"])
+	if isErr || !strings.Contains(text, "## retry.go:12 (Edit, 2026-10-16 09:30:00)") ||
+		!strings.Contains(text, "Note: too few") || !strings.Contains(text, "-retry(3)\n+retry(5)") {
+		t.Errorf("unexpected search result %q", text)
+	}
+	if q := queries[0]; q.Type != "search" || q.Text != "retry" || q.Limit != 5 || q.WorkspacePath != "/src/api" {
+		t.Errorf("expected a search of the workspace, got %+v", q)
+	}
+
+	// Query failures are reported to Claude as the tool's result
+	if text, isErr := resultText(t, responses["4"]); !isErr || text != "daemon not running" {
+		t.Errorf("expected the daemon error as the result, got %q", text)
+	}
+	if q := queries[1]; q.FilePath != "/src/api/retry.go" || q.Limit != defaultLimit {
+		t.Errorf("expected the path resolved in the workspace, got %+v", q)
+	}
+	if text, _ := resultText(t, responses["5"]); !strings.Contains(text, "No working context") {
+		t.Errorf("unexpected context result %q", text)
+	}
+
+	if err := responses["6"].Error; err == nil || err.Code != codeInvalidParams {
+		t.Errorf("expected unknown tools rejected, got %+v", err)
+	}
+	if err := responses["7"].Error; err == nil || err.Code != codeMethodNotFound {
+		t.Errorf("expected unknown methods rejected, got %+v", err)
+	}
+	if err := responses["null"].Error; err == nil || err.Code != codeParseError {
+		t.Errorf("expected a parse error, got %+v", err)
+	}
+}
@@ -0,0 +1,227 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/ztaylor/claude-mon/internal/daemon"
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/diff"
+)
+
+// Defaults for the tools' arguments
+const (
+	defaultLimit = 20
+	diffMaxLines = 30 // Per side of each edit's diff
+)
+
+// tool is an MCP tool: its schema, and what it does
+type tool struct {
+	name        string
+	description string
+	schema      map[string]any
+	call        func(s *Server, args json.RawMessage) (string, error)
+}
+
+// Schemas of the arguments several tools share
+var (
+	limitSchema = map[string]any{
+		"type":        "integer",
+		"description": fmt.Sprintf("Most edits to return (default %d)", defaultLimit),
+	}
+	filterSchema = map[string]any{
+		"type": "string",
+		"description": "Filter expression: path:<glob> lang:<names> tool:<name> since:<when> until:<when>, " +
+			"e.g. \"path:internal/** since:2h\"",
+	}
+	allWorkspacesSchema = map[string]any{
+		"type":        "boolean",
+		"description": "Look at every workspace claude-mon records, not just this one",
+	}
+)
+
+// tools are the tools the server offers, in the order they're listed
+var tools = []tool{
+	{
+		name:        "get_recent_edits",
+		description: "List the most recent edits made in this workspace, newest first, each with its diff.",
+		schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"limit":          limitSchema,
+				"filter":         filterSchema,
+				"all_workspaces": allWorkspacesSchema,
+			},
+		},
+		call: getRecentEdits,
+	},
+	{
+		name:        "get_file_history",
+		description: "List the edits made to one file, newest first, each with its diff.",
+		schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"path": map[string]any{
+					"type":        "string",
+					"description": "File path, absolute or relative to the workspace",
+				},
+				"limit": limitSchema,
+			},
+			"required": []string{"path"},
+		},
+		call: getFileHistory,
+	},
+	{
+		name: "get_working_context",
+		description: "Show the workspace's working context: the Kubernetes context, AWS profile, " +
+			"git branch, environment and notes the user has set for this project.",
+		schema: map[string]any{"type": "object", "properties": map[string]any{}},
+		call:   getWorkingContext,
+	},
+	{
+		name:        "search_history",
+		description: "Find past edits whose old or new text, summary or review note contains some text (case-insensitive), newest first.",
+		schema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"query": map[string]any{
+					"type":        "string",
+					"description": "Text to look for, e.g. a function name or error message",
+				},
+				"limit":          limitSchema,
+				"filter":         filterSchema,
+				"all_workspaces": allWorkspacesSchema,
+			},
+			"required": []string{"query"},
+		},
+		call: searchHistory,
+	},
+}
+
+// findTool returns the tool with a name, nil if there's none
+func findTool(name string) *tool {
+	for i := range tools {
+		if tools[i].name == name {
+			return &tools[i]
+		}
+	}
+	return nil
+}
+
+// editArgs are the arguments of the tools that list edits
+type editArgs struct {
+	Path          string `json:"path"`
+	Query         string `json:"query"`
+	Limit         int    `json:"limit"`
+	Filter        string `json:"filter"`
+	AllWorkspaces bool   `json:"all_workspaces"`
+}
+
+// parseEditArgs decodes a tool's arguments, defaulting the limit
+func parseEditArgs(raw json.RawMessage) (editArgs, error) {
+	var args editArgs
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return args, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if args.Limit <= 0 {
+		args.Limit = defaultLimit
+	}
+	return args, nil
+}
+
+func getRecentEdits(s *Server, raw json.RawMessage) (string, error) {
+	args, err := parseEditArgs(raw)
+	if err != nil {
+		return "", err
+	}
+	query := &daemon.Query{Type: "workspace", WorkspacePath: s.Workspace, Filter: args.Filter, Limit: args.Limit}
+	if args.AllWorkspaces {
+		query = &daemon.Query{Type: "recent", Filter: args.Filter, Limit: args.Limit}
+	}
+	result, err := s.Query(query)
+	if err != nil {
+		return "", err
+	}
+	return s.formatEdits(result.Edits, "No edits recorded yet"), nil
+}
+
+func getFileHistory(s *Server, raw json.RawMessage) (string, error) {
+	args, err := parseEditArgs(raw)
+	if err != nil {
+		return "", err
+	}
+	if args.Path == "" {
+		return "", fmt.Errorf("path is required")
+	}
+	path := args.Path
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.Workspace, path)
+	}
+	result, err := s.Query(&daemon.Query{Type: "file", FilePath: path, Limit: args.Limit})
+	if err != nil {
+		return "", err
+	}
+	return s.formatEdits(result.Edits, "No edits recorded for "+args.Path), nil
+}
+
+func getWorkingContext(s *Server, _ json.RawMessage) (string, error) {
+	text, err := s.Context()
+	if err != nil {
+		return "", err
+	}
+	if text == "" {
+		return "No working context is set for this workspace.", nil
+	}
+	return text, nil
+}
+
+func searchHistory(s *Server, raw json.RawMessage) (string, error) {
+	args, err := parseEditArgs(raw)
+	if err != nil {
+		return "", err
+	}
+	if args.Query == "" {
+		return "", fmt.Errorf("query is required")
+	}
+	query := &daemon.Query{Type: "search", Text: args.Query, Filter: args.Filter, Limit: args.Limit}
+	if !args.AllWorkspaces {
+		query.WorkspacePath = s.Workspace
+	}
+	result, err := s.Query(query)
+	if err != nil {
+		return "", err
+	}
+	return s.formatEdits(result.Edits, fmt.Sprintf("No edits mention %q", args.Query)), nil
+}
+
+// formatEdits renders edits as markdown: each edit's file, line, tool and
+// time, its summary, review and note, then its diff. Files in the workspace
+// are shown relative to it.
+func (s *Server) formatEdits(edits []*database.Edit, empty string) string {
+	if len(edits) == 0 {
+		return empty
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d edits, newest first:\n", len(edits))
+	for _, e := range edits {
+		path := e.FilePath
+		if rel, err := filepath.Rel(s.Workspace, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+		fmt.Fprintf(&sb, "\n## %s:%d (%s, %s)\n", path, e.LineNum, e.ToolName, e.Timestamp.Format("2006-01-02 15:04:05"))
+		if e.Summary != "" {
+			sb.WriteString(e.Summary + "\n")
+		}
+		if e.Review != "" {
+			sb.WriteString("Review: " + e.Review + "\n")
+		}
+		if e.Note != "" {
+			sb.WriteString("Note: " + e.Note + "\n")
+		}
+		sb.WriteString(diff.Markdown(e.OldString, e.NewString, diffMaxLines))
+	}
+	return sb.String()
+}
@@ -14,6 +14,7 @@ const (
 	LineContext LineType = iota
 	LineAdded
 	LineRemoved
+	LineHeat // Context changed by earlier edits; see MarkHeat
 )
 
 // Block characters for rendering
@@ -27,9 +28,15 @@ const (
 // Minimap represents a compressed view of file content with diff info
 type Minimap struct {
 	lines      []LineType // Type for each line in the file
+	heat       []float64  // Recency of earlier edits to each line, 0 for none
 	totalLines int
 }
 
+// Region is a run of marked lines [Start, End) (0-indexed)
+type Region struct {
+	Start, End int
+}
+
 // New creates a new Minimap with the given total lines
 func New(totalLines int) *Minimap {
 	if totalLines < 0 {
@@ -61,6 +68,72 @@ func (m *Minimap) SetRange(start, end int, lineType LineType) {
 	}
 }
 
+// MarkHeat marks lines [start, end) as changed by an earlier edit, heat
+// being its recency from 0 (long ago) to 1 (just now). A line keeps the
+// hottest edit that touched it.
+func (m *Minimap) MarkHeat(start, end int, heat float64) {
+	if m.heat == nil {
+		m.heat = make([]float64, len(m.lines))
+	}
+	start, end = max(start, 0), min(end, len(m.lines))
+	heat = min(max(heat, 0), 1)
+	for i := start; i < end; i++ {
+		if heat > m.heat[i] {
+			m.heat[i] = heat
+		}
+		if m.lines[i] == LineContext && heat > 0 {
+			m.lines[i] = LineHeat
+		}
+	}
+}
+
+// Regions returns the runs of added, removed and heat-marked lines in order
+func (m *Minimap) Regions() []Region {
+	var regions []Region
+	for i := 0; i < len(m.lines); i++ {
+		if m.lines[i] == LineContext {
+			continue
+		}
+		start := i
+		for i < len(m.lines) && m.lines[i] != LineContext {
+			i++
+		}
+		regions = append(regions, Region{Start: start, End: i})
+	}
+	return regions
+}
+
+// linesPerRow is how many lines each of height rows stands for
+func (m *Minimap) linesPerRow(height int) float64 {
+	return max(float64(m.totalLines)/float64(height), 1)
+}
+
+// LineAt returns the first line shown at a row of a minimap rendered
+// height rows tall
+func (m *Minimap) LineAt(row, height int) int {
+	if height < 1 {
+		return 0
+	}
+	row = min(max(row, 0), height-1)
+	return min(int(float64(row)*m.linesPerRow(height)), max(m.totalLines-1, 0))
+}
+
+// RegionAt returns the marked region shown at a row of a minimap rendered
+// height rows tall, if any
+func (m *Minimap) RegionAt(row, height int) (Region, bool) {
+	if height < 1 || row < 0 || row >= height {
+		return Region{}, false
+	}
+	start := m.LineAt(row, height)
+	end := max(int(float64(row+1)*m.linesPerRow(height)), start+1)
+	for _, r := range m.Regions() {
+		if r.Start < end && r.End > start {
+			return r, true
+		}
+	}
+	return Region{}, false
+}
+
 // TotalLines returns the total number of lines
 func (m *Minimap) TotalLines() int {
 	return m.totalLines
@@ -83,7 +156,7 @@ func (m *Minimap) getDominantType(start, end int) LineType {
 		counts[m.lines[i]]++
 	}
 
-	// Priority: Added > Removed > Context
+	// Priority: Added > Removed > Heat > Context
 	// This ensures diff regions are visible even when compressed
 	if counts[LineAdded] > 0 {
 		return LineAdded
@@ -91,9 +164,24 @@ func (m *Minimap) getDominantType(start, end int) LineType {
 	if counts[LineRemoved] > 0 {
 		return LineRemoved
 	}
+	if counts[LineHeat] > 0 {
+		return LineHeat
+	}
 	return LineContext
 }
 
+// hottest returns the highest heat in a range
+func (m *Minimap) hottest(start, end int) float64 {
+	var heat float64
+	for i := max(start, 0); i < min(end, len(m.heat)); i++ {
+		heat = max(heat, m.heat[i])
+	}
+	return heat
+}
+
+// heatColors go from edits long ago to recent ones
+var heatColors = []lipgloss.Color{"#585b70", "#f9e2af", "#fab387"} // Surface, yellow, peach
+
 // Render generates the minimap string with colors
 // height: display height in rows
 // viewportStart: first visible line in the viewport (0-indexed)
@@ -107,10 +195,7 @@ func (m *Minimap) Render(height int, viewportStart, viewportEnd int, t *theme.Th
 	var sb strings.Builder
 
 	// Calculate lines per display row
-	linesPerRow := float64(m.totalLines) / float64(height)
-	if linesPerRow < 1 {
-		linesPerRow = 1
-	}
+	linesPerRow := m.linesPerRow(height)
 
 	// Calculate viewport position in minimap coordinates
 	vpStartRow := int(float64(viewportStart) / linesPerRow)
@@ -162,6 +247,13 @@ func (m *Minimap) Render(height int, viewportStart, viewportEnd int, t *theme.Th
 			} else {
 				style = removedStyle
 			}
+		case LineHeat:
+			char = BlockMedium
+			heat := m.hottest(startLine, endLine)
+			style = lipgloss.NewStyle().Foreground(heatColors[min(int(heat*float64(len(heatColors))), len(heatColors)-1)])
+			if inViewport {
+				style = style.Background(t.ScrollbarThumb)
+			}
 		default: // LineContext
 			char = BlockLight
 			if inViewport {
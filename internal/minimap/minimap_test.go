@@ -0,0 +1,70 @@
+package minimap
+
+import "testing"
+
+func TestRegions(t *testing.T) {
+	m := New(20)
+	m.SetRange(2, 4, LineAdded)
+	m.SetLine(4, LineRemoved)
+	m.MarkHeat(10, 12, 0.5)
+	m.MarkHeat(3, 6, 0.2) // Overlaps the added lines without replacing them
+
+	want := []Region{{Start: 2, End: 6}, {Start: 10, End: 12}}
+	got := m.Regions()
+	if len(got) != len(want) {
+		t.Fatalf("expected regions %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("region %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+	if m.lines[2] != LineAdded || m.lines[5] != LineHeat {
+		t.Errorf("expected heat to mark only context lines, got %v", m.lines[:6])
+	}
+}
+
+func TestMarkHeatKeepsHottest(t *testing.T) {
+	m := New(5)
+	m.MarkHeat(0, 3, 0.8)
+	m.MarkHeat(1, 5, 0.3)
+	m.MarkHeat(-2, 99, 0) // Out of range and cold: no effect
+
+	if got := m.hottest(0, 2); got != 0.8 {
+		t.Errorf("expected the recent edit's heat, got %v", got)
+	}
+	if got := m.hottest(3, 5); got != 0.3 {
+		t.Errorf("expected the older edit's heat, got %v", got)
+	}
+	if m.getDominantType(0, 5) != LineHeat {
+		t.Error("expected heat to show over context")
+	}
+}
+
+func TestRegionAt(t *testing.T) {
+	m := New(100)
+	m.SetRange(40, 45, LineAdded)
+
+	// 10 rows of 10 lines each
+	if got := m.LineAt(4, 10); got != 40 {
+		t.Errorf("expected row 4 to start at line 40, got %d", got)
+	}
+	if got := m.LineAt(99, 10); got != 90 {
+		t.Errorf("expected rows past the end to clamp, got %d", got)
+	}
+	if r, ok := m.RegionAt(4, 10); !ok || r != (Region{Start: 40, End: 45}) {
+		t.Errorf("expected the added lines at row 4, got %v %v", r, ok)
+	}
+	for _, row := range []int{-1, 3, 5, 10} {
+		if r, ok := m.RegionAt(row, 10); ok {
+			t.Errorf("expected nothing at row %d, got %v", row, r)
+		}
+	}
+
+	// A short file spreads one line per row
+	short := New(3)
+	short.SetLine(2, LineRemoved)
+	if r, ok := short.RegionAt(2, 10); !ok || r.Start != 2 {
+		t.Errorf("expected the removed line at row 2, got %v %v", r, ok)
+	}
+}
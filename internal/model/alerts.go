@@ -0,0 +1,78 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// alertKindPrefix marks the timeline events the daemon raises from its
+// [alerts] rules, e.g. "alert_burst"
+const alertKindPrefix = "alert_"
+
+// alertCheckDelay is how long after an edit the daemon timeline is read
+// again for alerts it raised, giving the daemon time to record the edit
+const alertCheckDelay = time.Second
+
+// alertCheckMsg asks for the daemon timeline after an edit
+type alertCheckMsg struct{}
+
+// scheduleAlertCheck reads the daemon timeline shortly after an edit, unless
+// a read is already due
+func (m *Model) scheduleAlertCheck() tea.Cmd {
+	if m.alertCheckPending {
+		return nil
+	}
+	m.alertCheckPending = true
+	return tea.Tick(alertCheckDelay, func(time.Time) tea.Msg {
+		return alertCheckMsg{}
+	})
+}
+
+// applyAlerts raises alerts in the daemon timeline that are newer than any
+// seen: they join the banner and the notification center. Alerts from
+// before this TUI started aren't raised.
+func (m *Model) applyAlerts(events []Incident) {
+	since := m.startedAt.Truncate(time.Second)
+	if m.alertsSeen.After(since) {
+		since = m.alertsSeen
+	}
+
+	// Events come newest first
+	var fresh []Incident
+	for _, e := range events {
+		if strings.HasPrefix(e.Kind, alertKindPrefix) && e.Timestamp.After(since) {
+			fresh = append(fresh, e)
+		}
+	}
+	if len(fresh) == 0 {
+		return
+	}
+	m.alertsSeen = fresh[0].Timestamp
+	for i := len(fresh) - 1; i >= 0; i-- {
+		m.addToast("Alert: "+fresh[i].Message, ToastError)
+	}
+	m.alerts = append(fresh, m.alerts...)
+}
+
+// dismissAlerts clears the alert banner
+func (m *Model) dismissAlerts() {
+	m.alerts = nil
+}
+
+// renderAlertBanner renders the newest undismissed alert across the top of
+// the screen, in place of the header
+func (m Model) renderAlertBanner() string {
+	a := m.alerts[0]
+	text := fmt.Sprintf(" ⚠ %s %s", m.localTime(a.Timestamp).Format("15:04:05"), a.Message)
+	if n := len(m.alerts) - 1; n > 0 {
+		text += fmt.Sprintf(" (+%d more)", n)
+	}
+	text += "  Esc:dismiss"
+	if m.width > 4 && len([]rune(text)) > m.width {
+		text = string([]rune(text)[:m.width-3]) + "..."
+	}
+	return m.theme.Removed.Bold(true).Reverse(true).Width(m.width).Render(text)
+}
@@ -0,0 +1,70 @@
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/internal/vcs"
+)
+
+// blameAuthorWidth is the number of author characters shown in the gutter
+const blameAuthorWidth = 10
+
+// toggleBlame shows or hides the git blame gutter in the diff pane
+func (m *Model) toggleBlame() tea.Cmd {
+	m.showBlame = !m.showBlame
+	m.diffCache = make(map[int]*diffDoc)
+	if m.showBlame {
+		m.addToast("Blame gutter on", ToastInfo)
+	} else {
+		m.addToast("Blame gutter off", ToastInfo)
+	}
+	return m.showDiff()
+}
+
+// blameForChange returns cached blame for a change's file at the commit the
+// change was recorded against, or nil if the file isn't tracked
+func (m *Model) blameForChange(change Change) []vcs.BlameLine {
+	if change.FilePath == "" {
+		return nil
+	}
+
+	path := change.FilePath
+	if !filepath.IsAbs(path) {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+	}
+
+	lines, err := vcs.Blame(filepath.Dir(path), path, change.CommitSHA, change.VCSType)
+	if err != nil {
+		logger.Log("Blame unavailable for %s: %v", change.FilePath, err)
+		return nil
+	}
+	return lines
+}
+
+// renderBlameGutter renders the blame column for a context line (0-indexed)
+func (m *Model) renderBlameGutter(blame []vcs.BlameLine, line int) string {
+	width := 8 + blameAuthorWidth
+	if line < 0 || line >= len(blame) {
+		return fmt.Sprintf("%-*s ", width, "")
+	}
+
+	b := blame[line]
+	if b.Uncommitted() {
+		return m.theme.Dim.Render(fmt.Sprintf("%-*s", width, "uncommitted")) + " "
+	}
+	author := []rune(b.Author)
+	if len(author) > blameAuthorWidth {
+		author = append(author[:blameAuthorWidth-1], '…')
+	}
+	return m.theme.Dim.Render(fmt.Sprintf("%s %-*s", b.Short(), blameAuthorWidth, string(author))) + " "
+}
+
+// renderClaudeGutter renders the blame column for a line Claude just changed
+func (m *Model) renderClaudeGutter() string {
+	return m.theme.Added.Render(fmt.Sprintf("%-*s", 8+blameAuthorWidth, "●       claude")) + " "
+}
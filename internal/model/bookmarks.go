@@ -0,0 +1,84 @@
+package model
+
+import (
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// toggleBookmark flags or unflags the selected change for later review,
+// persisting to the history file and the daemon
+func (m *Model) toggleBookmark() {
+	if len(m.changes) == 0 {
+		return
+	}
+
+	change := &m.changes[m.selectedIndex]
+	change.Bookmarked = !change.Bookmarked
+
+	if m.persistHistory && m.historyStore != nil {
+		if err := m.historyStore.SetBookmarked(change.Timestamp, change.FilePath, change.Bookmarked); err != nil {
+			logger.Log("Failed to save bookmark: %v", err)
+		}
+	}
+
+	// Changes loaded from the daemon carry their edit ID; live changes are
+	// matched by file path and timestamp
+	m.sendDaemonPayload(map[string]interface{}{
+		"type":       "bookmark",
+		"edit_id":    change.EditID,
+		"file_path":  change.FilePath,
+		"timestamp":  change.Timestamp.Format(time.RFC3339Nano),
+		"bookmarked": change.Bookmarked,
+	})
+
+	if change.Bookmarked {
+		m.addToast("Bookmarked", ToastSuccess)
+		return
+	}
+	m.addToast("Bookmark removed", ToastInfo)
+
+	// The unbookmarked change is now hidden by the filter
+	if m.bookmarksOnly {
+		if !m.moveSelection(1) && !m.moveSelection(-1) {
+			m.bookmarksOnly = false
+			m.addToast("No bookmarks left, showing all changes", ToastInfo)
+		}
+		m.scrollX = 0
+		m.ensureSelectedVisible()
+		m.diffViewport.SetContent(m.renderDiff())
+	}
+}
+
+// toggleBookmarksOnly switches the history list between all changes and
+// bookmarked changes only
+func (m *Model) toggleBookmarksOnly() {
+	if m.bookmarksOnly {
+		m.bookmarksOnly = false
+		m.ensureSelectedVisible()
+		m.addToast("Showing all changes", ToastInfo)
+		return
+	}
+
+	first := -1
+	for i, c := range m.changes {
+		if c.Bookmarked {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		m.addToast("No bookmarked changes", ToastInfo)
+		return
+	}
+
+	m.bookmarksOnly = true
+	if !m.changes[m.selectedIndex].Bookmarked {
+		m.selectedIndex = first
+		m.scrollX = 0
+		m.diffViewport.SetContent(m.renderDiff())
+	}
+	m.listScrollOffset = 0
+	m.ensureSelectedVisible()
+	m.addToast("Showing bookmarks only", ToastInfo)
+}
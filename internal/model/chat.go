@@ -0,0 +1,306 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/chat"
+	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/internal/prompt"
+)
+
+// chatPurposes lists the purposes a chat session can start with, in the
+// order p cycles through them
+var chatPurposes = []chat.ContextPurpose{chat.ContextGeneral, chat.ContextRalph, chat.ContextPrompt, chat.ContextPlan}
+
+// chatInputHeight is the number of lines below the transcript taken by the
+// message input
+const chatInputHeight = 2
+
+// waitForChat waits for a chat session's next output or for its process to
+// exit
+func waitForChat(c *chat.ClaudeChat) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case <-c.OutputChan():
+			return chatOutputMsg{session: c}
+		case <-c.DoneChan():
+			return chatDoneMsg{session: c}
+		}
+	}
+}
+
+// startChat starts a Claude session with the selected purpose. The session
+// also becomes the target of the chat injection method.
+func (m *Model) startChat() tea.Cmd {
+	if m.chatAvailable() {
+		m.addToast("Chat session already running", ToastInfo)
+		return nil
+	}
+
+	c := chat.New()
+	c.SetPurpose(m.chatPurpose)
+	if err := c.Start(""); err != nil {
+		m.addToast("Failed to start Claude: "+err.Error(), ToastError)
+		return nil
+	}
+	m.addToast("Chat session started", ToastSuccess)
+	return m.attachChat(c, 0)
+}
+
+// attachChat makes a freshly started session the current one. saved is how
+// many of its messages the daemon already has.
+func (m *Model) attachChat(c *chat.ClaudeChat, saved int) tea.Cmd {
+	m.setChatTools(nil)
+	c.SetSize(m.diffViewport.Height, m.diffViewport.Width)
+	logger.Log("Chat session %s attached (purpose %s)", c.SessionID(), c.Purpose())
+
+	m.chatSession = c
+	m.chatSaved = saved
+	m.refreshChatView()
+	m.diffViewport.GotoBottom()
+	return waitForChat(c)
+}
+
+// stopChat stops the running session, keeping its transcript on screen
+func (m *Model) stopChat() {
+	if !m.chatAvailable() {
+		m.addToast("No chat session running", ToastInfo)
+		return
+	}
+	if err := m.chatSession.Stop(); err != nil {
+		m.addToast("Failed to stop Claude: "+err.Error(), ToastError)
+		return
+	}
+	m.saveChat(false)
+	if m.promptInjectMethod == prompt.InjectChat {
+		m.promptInjectMethod = prompt.DetectBestMethod()
+	}
+	m.addToast("Chat session stopped", ToastInfo)
+	m.refreshChatView()
+}
+
+// restartChat replaces the session with a fresh one, picking up a changed
+// purpose
+func (m *Model) restartChat() tea.Cmd {
+	if m.chatAvailable() {
+		m.chatSession.Stop()
+		m.saveChat(false)
+	}
+	return m.startChat()
+}
+
+// cycleChatPurpose selects the purpose the next session starts with
+func (m *Model) cycleChatPurpose() {
+	idx := 0
+	for i, p := range chatPurposes {
+		if p == m.chatPurpose {
+			idx = i
+		}
+	}
+	m.chatPurpose = chatPurposes[(idx+1)%len(chatPurposes)]
+	if m.chatAvailable() {
+		m.addToast("Purpose: "+string(m.chatPurpose)+" (restart to apply)", ToastInfo)
+	} else {
+		m.addToast("Purpose: "+string(m.chatPurpose), ToastInfo)
+	}
+	m.refreshChatView()
+}
+
+// refreshChatView re-renders the transcript, following new output when the
+// view was already scrolled to the bottom
+func (m *Model) refreshChatView() {
+	if m.leftPaneMode != LeftPaneModeChat {
+		return
+	}
+	atBottom := m.diffViewport.AtBottom()
+	m.diffViewport.SetContent(m.renderRightPane())
+	if atBottom {
+		m.diffViewport.GotoBottom()
+	}
+}
+
+// handleChatKeys handles keys in chat mode while the input isn't focused
+func (m Model) handleChatKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.chatToolFocus {
+		return m, m.handleChatToolKey(msg.String())
+	}
+	if m.showObjectives {
+		return m.handleObjectiveKeys(msg)
+	}
+
+	switch msg.String() {
+	case "i", "enter":
+		if !m.chatAvailable() {
+			m.addToast("Start a session first (s)", ToastWarning)
+			return m, nil
+		}
+		m.chatInputActive = true
+		m.chatInput.Focus()
+		return m, textinput.Blink
+	case "s":
+		return m, m.startChat()
+	case "x":
+		m.stopChat()
+	case "r":
+		return m, m.restartChat()
+	case "p":
+		m.cycleChatPurpose()
+	case "R":
+		return m, m.queryChatSessionsCmd()
+	case "t":
+		m.focusChatTools()
+	case "o":
+		m.toggleObjectiveView()
+	case m.config.Keys.Down, "down":
+		m.diffViewport.LineDown(1)
+	case m.config.Keys.Up, "up":
+		m.diffViewport.LineUp(1)
+	case m.config.Keys.PageDown:
+		m.diffViewport.HalfViewDown()
+	case m.config.Keys.PageUp:
+		m.diffViewport.HalfViewUp()
+	case "g":
+		m.diffViewport.GotoTop()
+	case "G":
+		m.diffViewport.GotoBottom()
+	}
+	return m, nil
+}
+
+// handleChatInputKeys edits the message: enter sends it, esc leaves the
+// input so the transcript can be scrolled
+func (m Model) handleChatInputKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.chatInputActive = false
+		m.chatInput.Blur()
+		return m, nil
+	case "enter":
+		text := strings.TrimSpace(m.chatInput.Value())
+		if text == "" {
+			return m, nil
+		}
+		if !m.chatAvailable() {
+			m.chatInputActive = false
+			m.chatInput.Blur()
+			m.addToast("Chat session is not running", ToastWarning)
+			return m, nil
+		}
+		if err := m.chatSession.Inject(text); err != nil {
+			m.addToast("Failed to send: "+err.Error(), ToastError)
+			return m, nil
+		}
+		m.saveChat(false)
+		m.chatInput.Reset()
+		m.refreshChatView()
+		m.diffViewport.GotoBottom()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.chatInput, cmd = m.chatInput.Update(msg)
+	return m, cmd
+}
+
+// handleLeaderKeyChat handles leader keys in chat mode
+func (m Model) handleLeaderKeyChat(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "s":
+		return m, m.startChat()
+	case "x":
+		m.stopChat()
+	case "r":
+		return m, m.restartChat()
+	case "p":
+		m.cycleChatPurpose()
+	case "R":
+		return m, m.queryChatSessionsCmd()
+	case "o":
+		m.toggleObjectiveView()
+	case "O":
+		return m, m.toggleObjectiveRunner()
+	}
+	return m, nil
+}
+
+// renderChatTranscript renders the session header, the message history as
+// markdown and Claude's reply as it streams in
+func (m *Model) renderChatTranscript() string {
+	var sb strings.Builder
+	width := m.diffViewport.Width - 4
+
+	sb.WriteString(m.theme.Title.Render("Claude Chat") + "  ")
+	switch {
+	case m.chatAvailable():
+		sb.WriteString(m.theme.Added.Render("● running"))
+	case m.chatSession != nil:
+		sb.WriteString(m.theme.Dim.Render("○ stopped"))
+	default:
+		sb.WriteString(m.theme.Dim.Render("○ no session"))
+	}
+	sb.WriteString(m.theme.Dim.Render("  purpose: " + string(m.chatPurpose)))
+	if m.chatSession != nil {
+		id := m.chatSession.SessionID()
+		if len(id) > 8 {
+			id = id[:8]
+		}
+		sb.WriteString(m.theme.Dim.Render("  session: " + id))
+	}
+	sb.WriteString("\n" + m.theme.Dim.Render(strings.Repeat("─", max(width, 10))) + "\n\n")
+
+	if m.chatSession == nil {
+		sb.WriteString(m.theme.Dim.Render("No chat session. Press s to start Claude, R to resume a saved session, p to change its purpose.") + "\n")
+		return sb.String()
+	}
+
+	for _, msg := range m.chatSession.Messages() {
+		sb.WriteString(m.renderChatRole(msg.Role) + " " + m.theme.Dim.Render(msg.Timestamp.Format("15:04")) + "\n")
+		if rendered, err := m.renderMarkdown(msg.Content, width); err == nil {
+			sb.WriteString(rendered)
+		} else {
+			sb.WriteString(msg.Content + "\n")
+		}
+		sb.WriteString("\n")
+	}
+
+	// The reply in progress is shown as it arrives; it's rendered as markdown
+	// once the next message is sent
+	if pending := m.chatSession.Pending(); pending != "" {
+		sb.WriteString(m.renderChatRole("assistant"))
+		if m.chatAvailable() {
+			sb.WriteString(" " + m.theme.Dim.Render("streaming"))
+		}
+		sb.WriteString("\n" + m.theme.Normal.Render(pending) + "\n")
+	}
+	return sb.String()
+}
+
+// renderChatRole renders the label above a message
+func (m Model) renderChatRole(role string) string {
+	if role == "user" {
+		return m.theme.Selected.Render("You")
+	}
+	return m.theme.Added.Render("Claude")
+}
+
+// renderChatInput renders the message input below the transcript
+func (m Model) renderChatInput() string {
+	if m.chatInputActive {
+		return "\n" + m.chatInput.View()
+	}
+	hint := "s: start  R: resume  p: purpose (" + string(m.chatPurpose) + ")"
+	if m.chatAvailable() {
+		hint = "i: type a message  x: stop  r: restart  R: resume  p: purpose (" + string(m.chatPurpose) + ")"
+	}
+	if m.chatToolFocus {
+		hint = "j/k: select tool call  enter: open in History  esc/t: back to transcript"
+	} else if len(m.chatTools) > 0 {
+		hint += "  t: tool activity"
+	}
+	if !m.chatToolFocus {
+		hint += "  o: objective queue"
+	}
+	return "\n" + m.theme.Dim.Render(hint)
+}
@@ -0,0 +1,238 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/auth"
+	"github.com/ztaylor/claude-mon/internal/chat"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// ChatSession is a saved chat session of this workspace, as recorded by the
+// daemon
+type ChatSession struct {
+	SessionID    string    `json:"session_id"`
+	Purpose      string    `json:"purpose"`
+	LastActivity time.Time `json:"last_activity"`
+	Messages     int       `json:"messages"`
+	Edits        int       `json:"edits"`
+	Preview      string    `json:"preview"`
+}
+
+// queryDaemon sends a query to the daemon query socket and decodes the reply
+// into result
+func queryDaemon(query map[string]interface{}, result interface{}) error {
+	conn, err := net.DialTimeout("unix", "/tmp/claude-mon-query.sock", 1*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	query["token"] = auth.FromEnv()
+	if err := json.NewEncoder(conn).Encode(query); err != nil {
+		return err
+	}
+	return json.NewDecoder(conn).Decode(result)
+}
+
+// queryChatSessionsCmd asks the daemon for this workspace's saved chat
+// sessions
+func (m Model) queryChatSessionsCmd() tea.Cmd {
+	return func() tea.Msg {
+		workspacePath, err := m.fs.Getwd()
+		if err != nil {
+			return chatSessionsMsg{err: err}
+		}
+
+		var result struct {
+			Chats []ChatSession `json:"chats"`
+			Error string        `json:"error,omitempty"`
+		}
+		if err := queryDaemon(map[string]interface{}{
+			"type":           "chat_sessions",
+			"workspace_path": workspacePath,
+			"limit":          50,
+		}, &result); err != nil {
+			return chatSessionsMsg{err: err}
+		}
+		if result.Error != "" {
+			return chatSessionsMsg{err: fmt.Errorf("daemon: %s", result.Error)}
+		}
+		return chatSessionsMsg{sessions: result.Chats}
+	}
+}
+
+// queryChatHistoryCmd asks the daemon for a saved session's transcript and
+// the edits Claude made during it
+func (m Model) queryChatHistoryCmd(session ChatSession) tea.Cmd {
+	return func() tea.Msg {
+		var result struct {
+			Messages []chat.Message `json:"messages"`
+			Error    string         `json:"error,omitempty"`
+		}
+		if err := queryDaemon(map[string]interface{}{
+			"type":            "chat_messages",
+			"chat_session_id": session.SessionID,
+		}, &result); err != nil {
+			return chatHistoryMsg{session: session, err: err}
+		}
+		if result.Error != "" {
+			return chatHistoryMsg{session: session, err: fmt.Errorf("daemon: %s", result.Error)}
+		}
+
+		// The transcript is enough to resume; a daemon without edit
+		// tracking just leaves the tool activity empty
+		var edits struct {
+			Edits []struct {
+				ID        int64     `json:"id"`
+				ToolName  string    `json:"tool_name"`
+				FilePath  string    `json:"file_path"`
+				CreatedAt time.Time `json:"created_at"`
+			} `json:"edits"`
+			Error string `json:"error,omitempty"`
+		}
+		var tools []chatToolCall
+		if err := queryDaemon(map[string]interface{}{
+			"type":            "chat_edits",
+			"chat_session_id": session.SessionID,
+		}, &edits); err != nil || edits.Error != "" {
+			logger.Log("Chat edits unavailable for %s: %v %s", session.SessionID, err, edits.Error)
+		} else {
+			for _, e := range edits.Edits {
+				tools = append(tools, chatToolCall{Time: e.CreatedAt, ToolName: e.ToolName, Detail: e.FilePath, EditID: e.ID})
+			}
+		}
+		return chatHistoryMsg{session: session, messages: result.Messages, tools: tools}
+	}
+}
+
+// saveChat sends the daemon the chat messages it doesn't have yet. wait
+// blocks until the daemon has them, for when the TUI is about to exit.
+func (m *Model) saveChat(wait bool) {
+	if m.chatSession == nil {
+		return
+	}
+	messages := m.chatSession.Messages()
+	if len(messages) <= m.chatSaved {
+		return
+	}
+
+	batch := make([]map[string]interface{}, 0, len(messages)-m.chatSaved)
+	for _, msg := range messages[m.chatSaved:] {
+		batch = append(batch, map[string]interface{}{
+			"role":      msg.Role,
+			"content":   msg.Content,
+			"timestamp": msg.Timestamp,
+		})
+	}
+	m.chatSaved = len(messages)
+
+	payload := map[string]interface{}{
+		"type":            "chat",
+		"chat_session_id": m.chatSession.SessionID(),
+		"chat_purpose":    string(m.chatSession.Purpose()),
+		"chat_messages":   batch,
+	}
+	if wait {
+		deliverDaemonPayload(m.fs, m.daemonSocket, payload)
+	} else {
+		m.sendDaemonPayload(payload)
+	}
+}
+
+// Close stops the chat session, if one is running, and saves the rest of
+// its transcript and the UI state. Call it once the program has exited.
+func (m Model) Close() {
+	m.saveUIState()
+	if m.chatAvailable() {
+		m.chatSession.Stop()
+	}
+	m.saveChat(true)
+	m.stopObjectives()
+	m.stopRalphRun()
+}
+
+// resumeChat continues a saved session, stopping the running one first.
+// tools are the session's earlier edits, listed in the tool activity sidebar.
+func (m *Model) resumeChat(session ChatSession, history []chat.Message, tools []chatToolCall) tea.Cmd {
+	if m.chatAvailable() {
+		m.chatSession.Stop()
+		m.saveChat(false)
+	}
+
+	c := chat.New()
+	c.SetPurpose(chat.ContextPurpose(session.Purpose))
+	if err := c.Resume(session.SessionID, history, ""); err != nil {
+		m.addToast("Failed to resume Claude: "+err.Error(), ToastError)
+		return nil
+	}
+	if session.Purpose != "" {
+		m.chatPurpose = chat.ContextPurpose(session.Purpose)
+	}
+	m.addToast("Chat session resumed", ToastSuccess)
+	cmd := m.attachChat(c, len(history))
+	m.setChatTools(tools)
+	return cmd
+}
+
+// showChatSessions opens the picker with the sessions the daemon returned,
+// leaving out the one that's running
+func (m *Model) showChatSessions(sessions []ChatSession) {
+	m.chatSessions = m.chatSessions[:0]
+	for _, s := range sessions {
+		if m.chatAvailable() && s.SessionID == m.chatSession.SessionID() {
+			continue
+		}
+		m.chatSessions = append(m.chatSessions, s)
+	}
+	if len(m.chatSessions) == 0 {
+		m.addToast("No saved chat sessions", ToastInfo)
+		return
+	}
+	m.chatPickerIndex = 0
+	m.showChatPicker = true
+}
+
+// handleChatPickerKey moves through the saved sessions; enter resumes the
+// selected one
+func (m *Model) handleChatPickerKey(key string) tea.Cmd {
+	switch key {
+	case "j", "down":
+		m.chatPickerIndex = (m.chatPickerIndex + 1) % len(m.chatSessions)
+	case "k", "up":
+		m.chatPickerIndex = (m.chatPickerIndex - 1 + len(m.chatSessions)) % len(m.chatSessions)
+	case "enter":
+		m.showChatPicker = false
+		return m.queryChatHistoryCmd(m.chatSessions[m.chatPickerIndex])
+	case "esc", "q":
+		m.showChatPicker = false
+	}
+	return nil
+}
+
+// renderChatPicker renders the saved sessions over the current view
+func (m Model) renderChatPicker() string {
+	var sb strings.Builder
+	sb.WriteString("\n  " + m.theme.Title.Render("Resume chat session") + "\n\n")
+	for i, s := range m.chatSessions {
+		preview := strings.Join(strings.Fields(s.Preview), " ")
+		if len([]rune(preview)) > 60 {
+			preview = string([]rune(preview)[:57]) + "..."
+		}
+		line := fmt.Sprintf("%-12s %-8s %3d msgs %3d edits  %s", m.relativeTime(s.LastActivity), s.Purpose, s.Messages, s.Edits, preview)
+		if i == m.chatPickerIndex {
+			sb.WriteString("  " + m.theme.Selected.Render("▸ "+line) + "\n")
+		} else {
+			sb.WriteString("    " + m.theme.Normal.Render(line) + "\n")
+		}
+	}
+	sb.WriteString("\n  " + m.theme.Dim.Render("j/k: move  enter: resume  esc: cancel") + "\n")
+	return sb.String()
+}
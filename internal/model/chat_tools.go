@@ -0,0 +1,203 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// chatToolWidth is the width of the tool activity sidebar beside the chat
+// transcript
+const chatToolWidth = 36
+
+// chatToolCall is an Edit, Write or Bash call Claude made in the chat session
+type chatToolCall struct {
+	Time     time.Time
+	ToolName string
+	Detail   string // File path, or the command for Bash
+	EditID   int64  // Daemon edit it was saved as (0 for live calls)
+}
+
+// chatToolLinkWindow is how far apart a tool call and a History change made
+// by it may be timestamped, for changes reloaded from the daemon
+const chatToolLinkWindow = 2 * time.Second
+
+// isChatTool reports whether calls to a tool are listed in the sidebar
+func isChatTool(name string) bool {
+	switch name {
+	case "Edit", "MultiEdit", "Write", "Bash":
+		return true
+	}
+	return false
+}
+
+// recordChatTool lists a hook payload's tool call in the sidebar if the chat
+// session made it. change is the History entry the call produced, if any.
+func (m *Model) recordChatTool(data []byte, change *Change) {
+	if m.chatSession == nil {
+		return
+	}
+	var payload HookPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return
+	}
+	if payload.SessionID == "" || payload.SessionID != m.chatSession.SessionID() || !isChatTool(payload.ToolName) {
+		return
+	}
+
+	call := chatToolCall{Time: m.clock.Now(), ToolName: payload.ToolName, Detail: payload.ToolInput.Command}
+	if change != nil {
+		call.Time = change.Timestamp
+		call.Detail = change.FilePath
+	}
+	if call.Detail == "" {
+		return
+	}
+	m.setChatTools(append(m.chatTools, call))
+}
+
+// setChatTools replaces the sidebar's tool calls, selecting the newest unless
+// the sidebar has focus
+func (m *Model) setChatTools(tools []chatToolCall) {
+	m.chatTools = tools
+	if len(tools) == 0 {
+		m.chatToolIndex = 0
+		m.chatToolFocus = false
+	} else if !m.chatToolFocus || m.chatToolIndex >= len(tools) {
+		m.chatToolIndex = len(tools) - 1
+	}
+	// The sidebar takes its width from the transcript when it first appears
+	m.updateViewportSize()
+	m.refreshChatView()
+}
+
+// focusChatTools moves j/k/enter to the sidebar
+func (m *Model) focusChatTools() {
+	if len(m.chatTools) == 0 {
+		m.addToast("No tool activity in this session yet", ToastInfo)
+		return
+	}
+	m.chatToolFocus = true
+}
+
+// handleChatToolKey moves through the tool calls while the sidebar has focus;
+// enter opens the selected call's change in History
+func (m *Model) handleChatToolKey(key string) tea.Cmd {
+	switch key {
+	case m.config.Keys.Down, "down":
+		if m.chatToolIndex < len(m.chatTools)-1 {
+			m.chatToolIndex++
+		}
+	case m.config.Keys.Up, "up":
+		if m.chatToolIndex > 0 {
+			m.chatToolIndex--
+		}
+	case "g":
+		m.chatToolIndex = 0
+	case "G":
+		m.chatToolIndex = len(m.chatTools) - 1
+	case "enter":
+		return m.openChatToolChange(m.chatTools[m.chatToolIndex])
+	case "esc", "t":
+		m.chatToolFocus = false
+	}
+	return nil
+}
+
+// findChatToolChange returns the index of the History change a tool call
+// made, or -1. Saved calls match by daemon edit ID, live ones by file and
+// time.
+func (m Model) findChatToolChange(call chatToolCall) int {
+	for i, c := range m.changes {
+		if call.EditID != 0 && c.EditID == call.EditID {
+			return i
+		}
+		if call.EditID == 0 && c.FilePath == call.Detail && c.Timestamp.Equal(call.Time) {
+			return i
+		}
+	}
+	// History may have been reloaded from the daemon since, with its own
+	// timestamps
+	for i, c := range m.changes {
+		d := c.Timestamp.Sub(call.Time)
+		if c.FilePath == call.Detail && d > -chatToolLinkWindow && d < chatToolLinkWindow {
+			return i
+		}
+	}
+	return -1
+}
+
+// openChatToolChange shows the change a tool call made in the History tab
+func (m *Model) openChatToolChange(call chatToolCall) tea.Cmd {
+	if call.ToolName == "Bash" {
+		m.addToast("Bash calls have no History entry", ToastInfo)
+		return nil
+	}
+	idx := m.findChatToolChange(call)
+	if idx < 0 {
+		m.addToast("Change is no longer in History", ToastWarning)
+		return nil
+	}
+	for _, v := range m.visibleChangeIndices() {
+		if v == idx {
+			m.chatToolFocus = false
+			return m.selectControlChange(idx)
+		}
+	}
+	m.addToast("Change is hidden by the History filter", ToastWarning)
+	return nil
+}
+
+// renderChatTools renders the tool activity sidebar, keeping the selected
+// call in view
+func (m Model) renderChatTools() string {
+	height := m.diffViewport.Height
+	rows := max(height-2, 1)
+
+	var sb strings.Builder
+	title := fmt.Sprintf("Tool activity (%d)", len(m.chatTools))
+	if m.chatToolFocus {
+		sb.WriteString(m.theme.Selected.Render(title) + "\n")
+	} else {
+		sb.WriteString(m.theme.Title.Render(title) + "\n")
+	}
+	sb.WriteString(m.theme.Dim.Render(strings.Repeat("─", chatToolWidth)) + "\n")
+
+	start := 0
+	if len(m.chatTools) > rows {
+		start = min(max(m.chatToolIndex-rows+1, 0), len(m.chatTools)-rows)
+		if m.chatToolIndex < start {
+			start = m.chatToolIndex
+		}
+	}
+	end := min(start+rows, len(m.chatTools))
+
+	for i := start; i < end; i++ {
+		call := m.chatTools[i]
+		icon := "✎"
+		detail := m.truncatePath(call.Detail, chatToolWidth-10)
+		switch call.ToolName {
+		case "Write":
+			icon = "+"
+		case "Bash":
+			icon = "$"
+			detail = strings.Join(strings.Fields(call.Detail), " ")
+			if len([]rune(detail)) > chatToolWidth-10 {
+				detail = string([]rune(detail)[:chatToolWidth-13]) + "..."
+			}
+		}
+		line := icon + " " + call.Time.Format("15:04") + " " + detail
+		if m.chatToolFocus && i == m.chatToolIndex {
+			sb.WriteString(m.theme.Selected.Render("▸ "+line) + "\n")
+		} else {
+			sb.WriteString("  " + m.theme.Normal.Render(line) + "\n")
+		}
+	}
+
+	return lipgloss.NewStyle().Width(chatToolWidth).Height(height).MaxHeight(height).
+		Render(strings.TrimSuffix(sb.String(), "\n"))
+}
@@ -0,0 +1,175 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// checkResultLimit is how many recent check results the history loads
+const checkResultLimit = 500
+
+// CheckResult is a post-edit check (e.g. "go vet" on the edited package)
+// the daemon ran for a file, as it returns it
+type CheckResult struct {
+	FilePath   string    `json:"file_path"`
+	Name       string    `json:"name"`
+	Command    string    `json:"command"`
+	Passed     bool      `json:"passed"`
+	ExitCode   int       `json:"exit_code"`
+	Output     string    `json:"output,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	StartedAt  time.Time `json:"started_at"` // Shared by every result of one run
+}
+
+// queryChecksCmd asks the daemon for this workspace's recent check results
+func (m Model) queryChecksCmd() tea.Cmd {
+	return func() tea.Msg {
+		workspacePath, err := m.fs.Getwd()
+		if err != nil {
+			return checksMsg{err: err}
+		}
+
+		var result struct {
+			Checks []CheckResult `json:"checks"`
+			Error  string        `json:"error,omitempty"`
+		}
+		if err := queryDaemon(map[string]interface{}{
+			"type":           "checks",
+			"workspace_path": workspacePath,
+			"limit":          checkResultLimit,
+		}, &result); err != nil {
+			return checksMsg{err: err}
+		}
+		if result.Error != "" {
+			return checksMsg{err: fmt.Errorf("daemon: %s", result.Error)}
+		}
+		return checksMsg{results: result.Checks}
+	}
+}
+
+// applyCheckResults stores new check results, warning about failures in
+// runs newer than the last ones seen
+func (m *Model) applyCheckResults(results []CheckResult) {
+	if len(results) > 0 && !m.checksSeen.IsZero() {
+		failed := make(map[string]bool)
+		for _, r := range results {
+			if r.StartedAt.After(m.checksSeen) && !r.Passed && !failed[r.Name] {
+				failed[r.Name] = true
+				m.addToast(fmt.Sprintf("✗ %s failed: %s", r.Name, m.relativePath(r.FilePath)), ToastWarning)
+			}
+		}
+	}
+	for _, r := range results {
+		if r.StartedAt.After(m.checksSeen) {
+			m.checksSeen = r.StartedAt
+		}
+	}
+	if m.checksSeen.IsZero() {
+		m.checksSeen = m.clock.Now()
+	}
+
+	m.checkResults = results
+	if m.checksPanel {
+		m.diffCache = make(map[int]*diffDoc)
+	}
+}
+
+// changeChecks returns the check results for a change: the first run for
+// its file that started after it and before the next change to the file,
+// so a burst of edits checked together badges only the last one
+func (m Model) changeChecks(idx int) []CheckResult {
+	if len(m.checkResults) == 0 {
+		return nil
+	}
+	change := m.changes[idx]
+	var next time.Time // Zero when this is the file's newest change
+	for i := idx - 1; i >= 0; i-- {
+		if m.changes[i].FilePath == change.FilePath {
+			next = m.changes[i].Timestamp
+			break
+		}
+	}
+
+	// Results are newest run first, so the last matching run is the earliest
+	var run time.Time
+	for _, r := range m.checkResults {
+		if r.FilePath != change.FilePath || r.StartedAt.Before(change.Timestamp) {
+			continue
+		}
+		if !next.IsZero() && !r.StartedAt.Before(next) {
+			continue
+		}
+		run = r.StartedAt
+	}
+	if run.IsZero() {
+		return nil
+	}
+
+	var checks []CheckResult
+	for _, r := range m.checkResults {
+		if r.FilePath == change.FilePath && r.StartedAt.Equal(run) {
+			checks = append(checks, r)
+		}
+	}
+	return checks
+}
+
+// renderCheckBadge marks a history entry whose checks passed or failed
+func (m Model) renderCheckBadge(idx int) string {
+	checks := m.changeChecks(idx)
+	if len(checks) == 0 {
+		return " "
+	}
+	for _, c := range checks {
+		if !c.Passed {
+			return m.theme.Removed.Render("✗")
+		}
+	}
+	return m.theme.Added.Render("✓")
+}
+
+// renderChecks renders a change's check results, with the output of each
+// failing check
+func (m Model) renderChecks(idx int) string {
+	checks := m.changeChecks(idx)
+	if len(checks) == 0 {
+		return m.theme.Dim.Render("No checks ran for this change")
+	}
+
+	var sb strings.Builder
+	sb.WriteString(m.theme.DiffHeader.Render(fmt.Sprintf("@@ Checks at %s @@", m.localTime(checks[0].StartedAt).Format("15:04:05"))))
+	sb.WriteString("\n")
+	for _, c := range checks {
+		duration := (time.Duration(c.DurationMS) * time.Millisecond).Round(10 * time.Millisecond)
+		if c.Passed {
+			sb.WriteString(m.theme.Added.Render("✓ " + c.Name))
+		} else {
+			sb.WriteString(m.theme.Removed.Render(fmt.Sprintf("✗ %s (exit %d)", c.Name, c.ExitCode)))
+		}
+		sb.WriteString(m.theme.Dim.Render(fmt.Sprintf("  $ %s  %s", c.Command, duration)))
+		sb.WriteString("\n")
+		if !c.Passed && strings.TrimSpace(c.Output) != "" {
+			for _, line := range strings.Split(strings.TrimRight(c.Output, "\n"), "\n") {
+				sb.WriteString("    " + line + "\n")
+			}
+		}
+	}
+	return sb.String()
+}
+
+// toggleChecks switches the diff pane between the change and the output of
+// the checks run after it
+func (m *Model) toggleChecks() tea.Cmd {
+	if len(m.changes) == 0 {
+		return nil
+	}
+	m.checksPanel = !m.checksPanel
+	m.diffCache = make(map[int]*diffDoc)
+	if m.checksPanel && len(m.checkResults) == 0 {
+		m.addToast("No check results; configure [checks] in daemon.toml", ToastInfo)
+	}
+	return m.showDiff()
+}
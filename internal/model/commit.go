@@ -0,0 +1,190 @@
+package model
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/internal/vcs"
+)
+
+// commitSubjectLimit caps the pre-filled commit subject
+const commitSubjectLimit = 72
+
+// commitTargets returns the changes to commit: the visible bookmarked
+// changes if there are any, otherwise the selected change
+func (m Model) commitTargets() []Change {
+	var targets []Change
+	for _, idx := range m.visibleChangeIndices() {
+		if m.changes[idx].Bookmarked {
+			targets = append(targets, m.changes[idx])
+		}
+	}
+	if len(targets) == 0 && m.selectedIndex < len(m.changes) {
+		targets = append(targets, m.changes[m.selectedIndex])
+	}
+	return targets
+}
+
+// openCommitInput opens the commit message prompt for the selected changes,
+// pre-filled from the Claude prompt that produced the selected change
+func (m *Model) openCommitInput() tea.Cmd {
+	if len(m.changes) == 0 {
+		return nil
+	}
+
+	m.commitChanges = m.commitTargets()
+	m.commitInputActive = true
+	m.commitInput.SetValue(commitSubject(lastUserPrompt(m.changes[m.selectedIndex].TranscriptPath, m.changes[m.selectedIndex].Timestamp)))
+	m.commitInput.CursorEnd()
+	return m.commitInput.Focus()
+}
+
+// handleCommitKeys handles key events while the commit message input is active
+func (m Model) handleCommitKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.commitInputActive = false
+		m.commitInput.Blur()
+		m.commitChanges = nil
+		return m, nil
+
+	case "enter":
+		message := strings.TrimSpace(m.commitInput.Value())
+		if message == "" {
+			m.addToast("Commit message required", ToastError)
+			return m, nil
+		}
+		m.commitInputActive = false
+		m.commitInput.Blur()
+		changes := m.commitChanges
+		m.commitChanges = nil
+		return m, commitChangesCmd(m.runner, changes, message)
+	}
+
+	var cmd tea.Cmd
+	m.commitInput, cmd = m.commitInput.Update(msg)
+	return m, cmd
+}
+
+// commitChangesCmd stages the recorded hunks of each change and commits them.
+// It refuses when something is already staged, which would otherwise go into
+// the commit with them.
+func commitChangesCmd(runner Runner, changes []Change, message string) tea.Cmd {
+	return func() tea.Msg {
+		dir := filepath.Dir(changes[0].FilePath)
+		staged, err := vcs.StagedFiles(runner, dir)
+		if err != nil {
+			return commitDoneMsg{err: err}
+		}
+		if len(staged) > 0 {
+			return commitDoneMsg{err: fmt.Errorf("%d file(s) already staged (%s); commit or unstage them first", len(staged), strings.Join(staged, ", "))}
+		}
+
+		hunks := 0
+		for _, c := range changes {
+			staged, err := vcs.StageChange(runner, c.FilePath, c.OldString, c.NewString)
+			if err != nil {
+				return commitDoneMsg{err: err}
+			}
+			logger.Log("Staged %d hunks of %s", staged, c.FilePath)
+			hunks += staged
+		}
+		if hunks == 0 {
+			return commitDoneMsg{err: fmt.Errorf("no recorded hunks left in the working tree")}
+		}
+
+		sha, err := vcs.Commit(runner, dir, message)
+		return commitDoneMsg{sha: sha, hunks: hunks, err: err}
+	}
+}
+
+// renderCommitInput renders the status line while entering a commit message
+func (m Model) renderCommitInput() string {
+	return m.theme.Status.Render(fmt.Sprintf("Commit %d change(s): %s  Enter:commit  Esc:cancel",
+		len(m.commitChanges), m.commitInput.View()))
+}
+
+// commitSubject turns prompt text into a one-line commit subject
+func commitSubject(text string) string {
+	line := strings.TrimSpace(strings.SplitN(strings.TrimSpace(text), "\n", 2)[0])
+	if runes := []rune(line); len(runes) > commitSubjectLimit {
+		line = strings.TrimSpace(string(runes[:commitSubjectLimit-1])) + "…"
+	}
+	return line
+}
+
+// transcriptEntry is the subset of a Claude Code transcript line we read
+type transcriptEntry struct {
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	Message   struct {
+		Content json.RawMessage `json:"content"`
+	} `json:"message"`
+}
+
+// lastUserPrompt returns the text of the last user prompt in a Claude Code
+// transcript before the given time, or "" if none can be read
+func lastUserPrompt(transcriptPath string, before time.Time) string {
+	if transcriptPath == "" {
+		return ""
+	}
+	f, err := os.Open(transcriptPath)
+	if err != nil {
+		logger.Log("Cannot read transcript %s: %v", transcriptPath, err)
+		return ""
+	}
+	defer f.Close()
+
+	var last string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry transcriptEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil || entry.Type != "user" {
+			continue
+		}
+		if !before.IsZero() && entry.Timestamp.After(before) {
+			break
+		}
+		if text := promptText(entry.Message.Content); text != "" {
+			last = text
+		}
+	}
+	return last
+}
+
+// promptText extracts typed text from a transcript message's content, which
+// is either a string or a list of blocks (tool results are skipped)
+func promptText(content json.RawMessage) string {
+	var text string
+	if json.Unmarshal(content, &text) != nil {
+		var blocks []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}
+		if json.Unmarshal(content, &blocks) != nil {
+			return ""
+		}
+		var parts []string
+		for _, b := range blocks {
+			if b.Type == "text" {
+				parts = append(parts, b.Text)
+			}
+		}
+		text = strings.Join(parts, "\n")
+	}
+
+	// Slash command and hook output is wrapped in tags, not typed by the user
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "<") {
+		return ""
+	}
+	return text
+}
@@ -0,0 +1,257 @@
+package model
+
+import (
+	gocontext "context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	workingctx "github.com/ztaylor/claude-mon/internal/context"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// Completion source timeouts: local files are quick, CLIs that may reach a
+// cluster or a backend get longer
+const (
+	completionFileTimeout = 2 * time.Second
+	completionCLITimeout  = 5 * time.Second
+)
+
+// completionCacheTTL is how long slow completion sources (kubectl,
+// terraform, pulumi) are served from the on-disk cache
+const completionCacheTTL = 10 * time.Minute
+
+// completionCachePath is where cached completions are kept
+var completionCachePath = filepath.Join(os.Getenv("HOME"), ".cache", "claude-mon", "completions.json")
+
+// completionSource loads the completion candidates of one context field
+type completionSource struct {
+	name     string        // Shown when loading fails, e.g. "kubectl"
+	key      string        // On-disk cache key; empty for sources that are cheap to load
+	timeout  time.Duration // How long loading may take
+	fallback []string      // Offered when loading fails
+	load     func(ctx gocontext.Context) ([]string, error)
+}
+
+// contextCompletionSource returns the completion source of the focused
+// field, bound to the values typed in the other fields
+func (m Model) contextCompletionSource() completionSource {
+	fsys, runner := m.fs, m.runner
+	switch m.contextEditField {
+	case "k8s":
+		kubeconfig := m.k8sKubeconfigInput.Value()
+		if kubeconfig == "" {
+			home, _ := m.fs.UserHomeDir()
+			kubeconfig = filepath.Join(home, ".kube", "config")
+		}
+		switch m.k8sFocusedField {
+		case 0: // kubeconfig
+			return fileSource("kubeconfigs", fsys, loadK8sKubeconfigs)
+		case 1: // context
+			return fileSource("kubeconfig", fsys, func(fsys FS) []string { return loadK8sContexts(fsys, kubeconfig) })
+		case 2: // namespace
+			context := m.k8sContextInput.Value()
+			return completionSource{
+				name:     "kubectl",
+				key:      "k8s-namespaces:" + kubeconfig + ":" + context,
+				timeout:  completionCLITimeout,
+				fallback: []string{"default", "kube-system", "kube-public"},
+				load: func(ctx gocontext.Context) ([]string, error) {
+					return loadK8sNamespaces(ctx, runner, kubeconfig, context)
+				},
+			}
+		}
+	case "aws":
+		switch m.awsFocusedField {
+		case 0: // profile
+			return fileSource("AWS config", fsys, loadAWSProfiles)
+		case 1: // region
+			return fileSource("regions", fsys, func(FS) []string { return loadAWSRegions() })
+		}
+	case "git":
+		dir, _ := m.fs.Getwd()
+		switch m.gitFocusedField {
+		case 0: // branch
+			return completionSource{
+				name:    "git",
+				timeout: completionCLITimeout,
+				load: func(ctx gocontext.Context) ([]string, error) {
+					return loadGitBranches(ctx, runner, dir), nil
+				},
+			}
+		case 1: // repo
+			return completionSource{
+				name:    "git",
+				timeout: completionCLITimeout,
+				load: func(ctx gocontext.Context) ([]string, error) {
+					return loadGitRepos(ctx, runner, dir), nil
+				},
+			}
+		}
+	case "iac":
+		dir := filepath.Join(m.contextCurrent.ProjectRoot, m.iacDirInput.Value())
+		tool := strings.ToLower(strings.TrimSpace(m.iacToolInput.Value()))
+		switch m.iacFocusedField {
+		case 0: // workspace
+			if tool == "" {
+				tool = workingctx.IaCToolFS(fsys, dir)
+			}
+			return completionSource{
+				name:    tool,
+				key:     "iac-workspaces:" + tool + ":" + dir,
+				timeout: completionCLITimeout,
+				load: func(ctx gocontext.Context) ([]string, error) {
+					return workingctx.ListIaCWorkspaces(ctx, runner, tool, dir)
+				},
+			}
+		case 1: // tool
+			return fileSource("tools", fsys, func(FS) []string {
+				return []string{workingctx.IaCTerraform, workingctx.IaCPulumi}
+			})
+		}
+	case "env":
+		return fileSource("shell history", fsys, loadEnvCompletions)
+	case "custom":
+		ctx := m.contextCurrent
+		return fileSource("context", fsys, func(FS) []string { return loadCustomCompletions(ctx) })
+	}
+	return fileSource("", fsys, func(FS) []string { return nil })
+}
+
+// fileSource is a completion source read from local files through fsys,
+// which can't fail beyond finding nothing
+func fileSource(name string, fsys FS, load func(FS) []string) completionSource {
+	return completionSource{
+		name:    name,
+		timeout: completionFileTimeout,
+		load: func(gocontext.Context) ([]string, error) {
+			return load(fsys), nil
+		},
+	}
+}
+
+// run loads the candidates within the source's timeout, serving and filling
+// the on-disk cache for sources that have a key
+func (s completionSource) run(clock Clock, fsys FS) ([]string, error) {
+	if s.key != "" {
+		if candidates, ok := readCompletionCache(fsys, s.key, clock.Now()); ok {
+			return candidates, nil
+		}
+	}
+
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), s.timeout)
+	defer cancel()
+
+	type result struct {
+		candidates []string
+		err        error
+	}
+	done := make(chan result, 1)
+	go func() {
+		candidates, err := s.load(ctx)
+		done <- result{candidates, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			return s.fallback, fmt.Errorf("%s: %w", s.name, r.err)
+		}
+		if s.key != "" {
+			writeCompletionCache(fsys, s.key, r.candidates, clock.Now())
+		}
+		return r.candidates, nil
+	case <-ctx.Done():
+		return s.fallback, fmt.Errorf("%s timed out after %s", s.name, s.timeout)
+	}
+}
+
+// openContextCompletion opens the completion overlay for the focused field
+// and starts loading its candidates in the background
+func (m *Model) openContextCompletion() tea.Cmd {
+	m.contextCompletionActive = true
+	m.contextCompletionInput.Reset()
+	m.contextCompletionInput.Focus()
+	m.contextCompletionCandidates = nil
+	m.contextCompletionMatches = nil
+	m.contextCompletionSelected = 0
+	m.contextCompletionErr = ""
+	m.contextCompletionLoading = true
+	m.contextCompletionSeq++
+
+	source, seq, clock, fsys := m.contextCompletionSource(), m.contextCompletionSeq, m.clock, m.fs
+	load := func() tea.Msg {
+		candidates, err := source.run(clock, fsys)
+		return contextCompletionsMsg{seq: seq, candidates: candidates, err: err}
+	}
+	return tea.Batch(load, m.contextCompletionSpinner.Tick)
+}
+
+// applyContextCompletions shows loaded candidates, filtered by whatever was
+// typed while they loaded. Results for a closed or reopened overlay are
+// dropped.
+func (m *Model) applyContextCompletions(msg contextCompletionsMsg) {
+	if msg.seq != m.contextCompletionSeq || !m.contextCompletionActive {
+		return
+	}
+	m.contextCompletionLoading = false
+	if msg.err != nil {
+		logger.Log("Failed to load completions: %v", msg.err)
+		m.contextCompletionErr = msg.err.Error()
+	}
+	m.contextCompletionCandidates = msg.candidates
+	m.computeContextCompletionMatches(m.contextCompletionInput.Value())
+	m.contextCompletionSelected = 0
+}
+
+// completionCacheEntry is a cached set of candidates
+type completionCacheEntry struct {
+	Candidates []string  `json:"candidates"`
+	Fetched    time.Time `json:"fetched"`
+}
+
+// readCompletionCache returns cached candidates younger than the TTL at now
+func readCompletionCache(fsys FS, key string, now time.Time) ([]string, bool) {
+	entries := loadCompletionCache(fsys)
+	entry, ok := entries[key]
+	if !ok || now.Sub(entry.Fetched) > completionCacheTTL {
+		return nil, false
+	}
+	return entry.Candidates, true
+}
+
+// writeCompletionCache caches candidates fetched at now, dropping expired
+// entries
+func writeCompletionCache(fsys FS, key string, candidates []string, now time.Time) {
+	entries := loadCompletionCache(fsys)
+	for k, entry := range entries {
+		if now.Sub(entry.Fetched) > completionCacheTTL {
+			delete(entries, k)
+		}
+	}
+	entries[key] = completionCacheEntry{Candidates: candidates, Fetched: now}
+
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return
+	}
+	if err := fsys.MkdirAll(filepath.Dir(completionCachePath), 0755); err != nil {
+		logger.Log("Failed to create completion cache directory: %v", err)
+		return
+	}
+	if err := fsys.WriteFile(completionCachePath, data, 0644); err != nil {
+		logger.Log("Failed to write completion cache: %v", err)
+	}
+}
+
+func loadCompletionCache(fsys FS) map[string]completionCacheEntry {
+	entries := make(map[string]completionCacheEntry)
+	if data, err := fsys.ReadFile(completionCachePath); err == nil {
+		_ = json.Unmarshal(data, &entries)
+	}
+	return entries
+}
@@ -0,0 +1,78 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	workingctx "github.com/ztaylor/claude-mon/internal/context"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// detectContextCmd reads the context from kubectl, the environment and git
+// in the background, since kubectl can be slow
+func detectContextCmd() tea.Cmd {
+	return func() tea.Msg {
+		return contextDetectedMsg{detected: workingctx.Detect()}
+	}
+}
+
+// handleContextDetectKeys handles the confirmation of detected context:
+// y/enter saves it, any other key discards it
+func (m Model) handleContextDetectKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	detected := m.contextDetected
+	m.contextDetected = nil
+
+	switch msg.String() {
+	case "y", "enter":
+		if m.contextCurrent == nil {
+			return m, nil
+		}
+		detected.Apply(m.contextCurrent)
+		if err := m.contextCurrent.Save(); err != nil {
+			m.addToast(fmt.Sprintf("Failed to save context: %v", err), ToastError)
+			return m, nil
+		}
+		logger.Log("Saved detected context: %s", strings.Join(detected.Lines(), "; "))
+		m.addToast("Detected context saved", ToastSuccess)
+	default:
+		m.addToast("Detected context discarded", ToastInfo)
+	}
+	return m, nil
+}
+
+// renderContextDetectPopup renders the detected context for confirmation,
+// marking values that differ from the current context
+func (m Model) renderContextDetectPopup() string {
+	var content strings.Builder
+	content.WriteString(m.theme.Title.Render("Detected Context") + "\n\n")
+
+	current := make(map[string]bool)
+	if m.contextCurrent != nil {
+		for _, line := range strings.Split(m.contextCurrent.Format(), "\n") {
+			current[strings.TrimSpace(line)] = true
+		}
+	}
+	for _, line := range m.contextDetected.Lines() {
+		key, value, _ := strings.Cut(line, ": ")
+		style := m.theme.Normal
+		if !current[line] {
+			style = m.theme.Added
+		}
+		content.WriteString(m.theme.Dim.Render(fmt.Sprintf("%-12s", key+":")) + style.Render(value) + "\n")
+	}
+
+	content.WriteString("\n")
+	content.WriteString(m.theme.Dim.Render("y/Enter:save  Esc:discard"))
+
+	contentStr := content.String()
+	popupStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#4a4a6a")).
+		Background(lipgloss.Color("#1a1a2e")).
+		Padding(1, 2).
+		Width(lipgloss.Width(contentStr) + 4)
+
+	return popupStyle.Render(contentStr)
+}
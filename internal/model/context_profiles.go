@@ -0,0 +1,208 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	workingctx "github.com/ztaylor/claude-mon/internal/context"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// contextProfileRows is how many profiles the switcher shows at once
+const contextProfileRows = 10
+
+// openContextProfiles opens the profile switcher with the filter focused and
+// the active profile selected
+func (m *Model) openContextProfiles() tea.Cmd {
+	m.showContextProfiles = true
+	m.contextProfileFilter.Reset()
+	m.contextProfileFilter.Focus()
+	m.contextProfileIndex = 0
+	m.refreshContextProfiles()
+	if m.contextCurrent != nil {
+		for i, idx := range m.contextProfileMatches {
+			if m.contextProfiles[idx].Name == m.contextCurrent.Profile {
+				m.contextProfileIndex = i
+			}
+		}
+	}
+	return textinput.Blink
+}
+
+// closeContextProfiles closes the profile switcher
+func (m *Model) closeContextProfiles() {
+	m.showContextProfiles = false
+	m.contextProfileFilter.Blur()
+}
+
+// refreshContextProfiles re-reads the profiles and re-applies the filter
+func (m *Model) refreshContextProfiles() {
+	profiles, err := workingctx.ListProfiles()
+	if err != nil {
+		m.addToast(fmt.Sprintf("Failed to list profiles: %v", err), ToastError)
+	}
+	m.contextProfiles = profiles
+	m.filterContextProfiles()
+}
+
+// filterContextProfiles keeps the profiles whose name contains the filter
+func (m *Model) filterContextProfiles() {
+	query := strings.ToLower(strings.TrimSpace(m.contextProfileFilter.Value()))
+	m.contextProfileMatches = m.contextProfileMatches[:0]
+	for i, p := range m.contextProfiles {
+		if strings.Contains(strings.ToLower(p.Name), query) {
+			m.contextProfileMatches = append(m.contextProfileMatches, i)
+		}
+	}
+	if m.contextProfileIndex >= len(m.contextProfileMatches) {
+		m.contextProfileIndex = max(len(m.contextProfileMatches)-1, 0)
+	}
+}
+
+// selectedContextProfile returns the highlighted profile, or nil
+func (m *Model) selectedContextProfile() *workingctx.Profile {
+	if len(m.contextProfileMatches) == 0 {
+		return nil
+	}
+	return m.contextProfiles[m.contextProfileMatches[m.contextProfileIndex]]
+}
+
+// handleContextProfileKeys handles keys while the profile switcher is open:
+// typing filters, enter switches to the selected profile, ctrl+s saves the
+// current context as a profile named by the filter
+func (m Model) handleContextProfileKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.closeContextProfiles()
+		return m, nil
+	case "up", "ctrl+p":
+		if m.contextProfileIndex > 0 {
+			m.contextProfileIndex--
+		}
+		return m, nil
+	case "down", "ctrl+n":
+		if m.contextProfileIndex < len(m.contextProfileMatches)-1 {
+			m.contextProfileIndex++
+		}
+		return m, nil
+	case "enter":
+		if p := m.selectedContextProfile(); p != nil {
+			m.closeContextProfiles()
+			m.applyContextProfile(p)
+		}
+		return m, nil
+	case "ctrl+s":
+		m.saveContextProfile(strings.TrimSpace(m.contextProfileFilter.Value()))
+		return m, nil
+	case "ctrl+d":
+		if p := m.selectedContextProfile(); p != nil {
+			if err := workingctx.DeleteProfile(p.Name); err != nil {
+				m.addToast(err.Error(), ToastError)
+				return m, nil
+			}
+			m.addToast("Deleted profile "+p.Name, ToastSuccess)
+			m.refreshContextProfiles()
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.contextProfileFilter, cmd = m.contextProfileFilter.Update(msg)
+	m.contextProfileIndex = 0
+	m.filterContextProfiles()
+	return m, cmd
+}
+
+// applyContextProfile switches the project's context to a profile
+func (m *Model) applyContextProfile(p *workingctx.Profile) {
+	if m.contextCurrent == nil {
+		return
+	}
+	m.contextCurrent.ApplyProfile(p)
+	if err := m.contextCurrent.Save(); err != nil {
+		m.addToast(fmt.Sprintf("Failed to switch profile: %v", err), ToastError)
+		return
+	}
+	logger.Log("Switched context profile to %s", p.Name)
+	m.addToast("Profile: "+p.Name, ToastSuccess)
+}
+
+// saveContextProfile saves the project's context as a profile and marks it
+// active
+func (m *Model) saveContextProfile(name string) {
+	if m.contextCurrent == nil {
+		return
+	}
+	if name == "" {
+		name = m.contextCurrent.Profile
+	}
+	if name == "" {
+		m.addToast("Type a name for the profile first", ToastWarning)
+		return
+	}
+	p, err := workingctx.SaveProfile(name, m.contextCurrent)
+	if err != nil {
+		m.addToast(err.Error(), ToastError)
+		return
+	}
+	m.contextCurrent.Profile = p.Name
+	if err := m.contextCurrent.Save(); err != nil {
+		logger.Log("Failed to save context: %v", err)
+	}
+	m.addToast("Saved profile "+p.Name, ToastSuccess)
+	m.contextProfileFilter.Reset()
+	m.refreshContextProfiles()
+}
+
+// renderContextProfiles renders the profile switcher popup
+func (m Model) renderContextProfiles() string {
+	var content strings.Builder
+	content.WriteString(m.theme.Title.Render("Context Profiles") + "\n\n")
+	content.WriteString(m.contextProfileFilter.View() + "\n\n")
+
+	active := ""
+	if m.contextCurrent != nil {
+		active = m.contextCurrent.Profile
+	}
+	if len(m.contextProfiles) == 0 {
+		content.WriteString(m.theme.Dim.Render("No profiles yet: type a name and press ctrl+s") + "\n")
+		content.WriteString(m.theme.Dim.Render("to save the current context as one") + "\n")
+	} else if len(m.contextProfileMatches) == 0 {
+		content.WriteString(m.theme.Dim.Render("No profiles match; ctrl+s saves one with this name") + "\n")
+	}
+	start := max(min(m.contextProfileIndex-contextProfileRows/2, len(m.contextProfileMatches)-contextProfileRows), 0)
+	end := min(start+contextProfileRows, len(m.contextProfileMatches))
+	for i := start; i < end; i++ {
+		p := m.contextProfiles[m.contextProfileMatches[i]]
+		marker := " "
+		if p.Name == active {
+			marker = "•"
+		}
+		line := fmt.Sprintf("%s %-16s", marker, p.Name)
+		summary := " " + truncateRunes(p.Summary(), 40)
+		if i == m.contextProfileIndex {
+			content.WriteString(m.theme.Selected.Render("> "+line) + m.theme.Dim.Render(summary) + "\n")
+		} else {
+			content.WriteString("  " + m.theme.Normal.Render(line) + m.theme.Dim.Render(summary) + "\n")
+		}
+	}
+	if end < len(m.contextProfileMatches) {
+		content.WriteString(m.theme.Dim.Render(fmt.Sprintf("  ... +%d more", len(m.contextProfileMatches)-end)) + "\n")
+	}
+
+	content.WriteString("\n")
+	content.WriteString(m.theme.Dim.Render("Enter:switch  Ctrl+S:save current  Ctrl+D:delete  Esc:close"))
+
+	contentStr := content.String()
+	popupStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(lipgloss.Color("#4a4a6a")).
+		Background(lipgloss.Color("#1a1a2e")).
+		Padding(1, 2).
+		Width(lipgloss.Width(contentStr) + 4)
+
+	return popupStyle.Render(contentStr)
+}
@@ -0,0 +1,30 @@
+package model
+
+import (
+	workingctx "github.com/ztaylor/claude-mon/internal/context"
+)
+
+// cycleContextEditSecret cycles the value being edited through plain, secret
+// and secret sent to Claude
+func (m *Model) cycleContextEditSecret() {
+	switch {
+	case m.contextEditSecret == nil:
+		m.contextEditSecret = &workingctx.Secret{}
+	case !m.contextEditSecret.Inject:
+		m.contextEditSecret = &workingctx.Secret{Inject: true}
+	default:
+		m.contextEditSecret = nil
+	}
+}
+
+// renderContextEditSecret renders whether the value being edited is secret
+func (m Model) renderContextEditSecret() string {
+	switch {
+	case m.contextEditSecret == nil:
+		return m.theme.Dim.Render("Secret: no (ctrl+s)")
+	case !m.contextEditSecret.Inject:
+		return m.theme.Modified.Render("Secret: yes, masked and not sent to Claude (ctrl+s)")
+	default:
+		return m.theme.Modified.Render("Secret: yes, masked but sent to Claude (ctrl+s)")
+	}
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"fmt"
+
+	workingctx "github.com/ztaylor/claude-mon/internal/context"
+)
+
+// contextSection maps a label from Context.Format to the section it shows.
+// Labels other than the built-in ones are custom values.
+func contextSection(label string) string {
+	switch label {
+	case "Kubernetes":
+		return "kubernetes"
+	case "AWS":
+		return "aws"
+	case "Git":
+		return "git"
+	case "Terraform", "Pulumi":
+		return "iac"
+	case "Env":
+		return "env"
+	case "Profile", "Updated":
+		return ""
+	}
+	return "custom"
+}
+
+// staleContextText describes why the context is stale: the fields the
+// daemon flagged, or its age when the daemon hasn't checked it
+func staleContextText(ctx *workingctx.Context) string {
+	switch n := len(ctx.Stale); n {
+	case 0:
+		return "Context is stale (>24h)"
+	case 1:
+		return "Context has 1 stale field"
+	default:
+		return fmt.Sprintf("Context has %d stale fields", n)
+	}
+}
@@ -0,0 +1,169 @@
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/filter"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// ControlMsg is sent when a command arrives on the control socket
+type ControlMsg struct {
+	Command string
+	Args    []string
+}
+
+// controlTabs maps switch-tab arguments to left pane modes
+var controlTabs = map[string]LeftPaneMode{
+	"history": LeftPaneModeHistory,
+	"prompts": LeftPaneModePrompts,
+	"ralph":   LeftPaneModeRalph,
+	"plan":    LeftPaneModePlan,
+	"context": LeftPaneModeContext,
+	"chat":    LeftPaneModeChat,
+	"1":       LeftPaneModeHistory,
+	"2":       LeftPaneModePrompts,
+	"3":       LeftPaneModeRalph,
+	"4":       LeftPaneModePlan,
+	"5":       LeftPaneModeContext,
+	"6":       LeftPaneModeChat,
+}
+
+// ValidateControlCommand checks a control command's name and arguments so
+// the socket can reply with an error before the command reaches the TUI
+func ValidateControlCommand(command string, args []string) error {
+	switch command {
+	case "switch-tab":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: switch-tab <history|prompts|ralph|plan|context|chat>")
+		}
+		if _, ok := controlTabs[strings.ToLower(args[0])]; !ok {
+			return fmt.Errorf("unknown tab: %s", args[0])
+		}
+	case "select-file":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: select-file <path>")
+		}
+	case "set-filter":
+		if _, err := filter.Parse(strings.Join(args, " ")); err != nil {
+			return err
+		}
+	case "jump-to-edit":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: jump-to-edit <n|newest|oldest|next|prev>")
+		}
+		switch args[0] {
+		case "newest", "oldest", "next", "prev":
+		default:
+			if n, err := strconv.Atoi(args[0]); err != nil || n < 1 {
+				return fmt.Errorf("invalid edit position: %s", args[0])
+			}
+		}
+	case "quit":
+	default:
+		return fmt.Errorf("unknown command: %s", command)
+	}
+	return nil
+}
+
+// handleControl applies a control socket command
+func (m *Model) handleControl(msg ControlMsg) tea.Cmd {
+	logger.Log("Control command: %s %v", msg.Command, msg.Args)
+	if err := ValidateControlCommand(msg.Command, msg.Args); err != nil {
+		m.addToast(err.Error(), ToastError)
+		return nil
+	}
+
+	switch msg.Command {
+	case "switch-tab":
+		mode := controlTabs[strings.ToLower(msg.Args[0])]
+		m.switchToMode(mode)
+		if mode == LeftPaneModeRalph {
+			return m.ralphRefreshCmd
+		}
+
+	case "select-file":
+		idx := m.findChangeByPath(msg.Args[0])
+		if idx < 0 {
+			m.addToast("No changes for "+msg.Args[0], ToastError)
+			return nil
+		}
+		return m.selectControlChange(idx)
+
+	case "set-filter":
+		f, _ := filter.Parse(strings.Join(msg.Args, " "))
+		if f.IsEmpty() {
+			f = nil
+		}
+		m.historyFilter = f
+		cmd := m.selectVisibleChange()
+		if f != nil {
+			return tea.Batch(cmd, m.queryDaemonHistoryCmd())
+		}
+		return cmd
+
+	case "jump-to-edit":
+		visible := m.visibleChangeIndices()
+		if len(visible) == 0 {
+			return nil
+		}
+		pos := m.selectedListPos(visible)
+		switch msg.Args[0] {
+		case "newest":
+			pos = 0
+		case "oldest":
+			pos = len(visible) - 1
+		case "next":
+			pos++
+		case "prev":
+			pos--
+		default:
+			// 1-based position in the visible list, newest first
+			pos, _ = strconv.Atoi(msg.Args[0])
+			pos--
+		}
+		if pos < 0 {
+			pos = 0
+		}
+		if pos > len(visible)-1 {
+			pos = len(visible) - 1
+		}
+		return m.selectControlChange(visible[pos])
+
+	case "quit":
+		return tea.Quit
+	}
+
+	return nil
+}
+
+// findChangeByPath returns the index of the newest visible change whose file
+// path equals or ends with path, or -1
+func (m Model) findChangeByPath(path string) int {
+	path = filepath.Clean(path)
+	for _, idx := range m.visibleChangeIndices() {
+		changePath := filepath.Clean(m.changes[idx].FilePath)
+		if changePath == path || strings.HasSuffix(changePath, string(filepath.Separator)+path) {
+			return idx
+		}
+	}
+	return -1
+}
+
+// selectControlChange shows the history tab with the given change selected
+func (m *Model) selectControlChange(idx int) tea.Cmd {
+	if m.leftPaneMode != LeftPaneModeHistory {
+		m.switchToMode(LeftPaneModeHistory)
+	}
+	m.selectedIndex = idx
+	m.scrollX = 0
+	m.ensureSelectedVisible()
+	cmd := m.showDiff()
+	m.scrollToChange()
+	m.preloadAdjacent()
+	return cmd
+}
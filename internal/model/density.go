@@ -0,0 +1,75 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/config"
+	"github.com/ztaylor/claude-mon/internal/diff"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// tallTerminalRows is the terminal height at which "auto" density switches
+// the history list to the two-line comfortable format
+const tallTerminalRows = 60
+
+// historyLinesPerItem returns how many lines each history entry occupies
+func (m Model) historyLinesPerItem() int {
+	switch m.config.ListDensity {
+	case config.DensityComfortable:
+		return 2
+	case config.DensityCompact:
+		return 1
+	}
+	if m.height >= tallTerminalRows {
+		return 2
+	}
+	return 1
+}
+
+// toggleListDensity switches between compact and comfortable history entries
+// and saves the choice to the config file
+func (m *Model) toggleListDensity() {
+	density := config.DensityComfortable
+	if m.historyLinesPerItem() == 2 {
+		density = config.DensityCompact
+	}
+	m.config.ListDensity = density
+
+	if err := config.SaveListDensity(density); err != nil {
+		logger.Log("Failed to save list density: %v", err)
+	}
+
+	m.ensureSelectedVisible()
+	m.addToast("List density: "+density, ToastInfo)
+}
+
+// changeDetail returns the second line of a comfortable history entry: the
+// declarations it touched, +N/-M line stats, short commit and relative time
+func (m Model) changeDetail(c Change) string {
+	added := len(diff.SplitLines(c.NewString))
+	removed := len(diff.SplitLines(c.OldString))
+	detail := fmt.Sprintf("+%d/-%d", added, removed)
+	if c.Summary != "" {
+		detail = c.Summary + "  " + detail
+	}
+	if c.CommitShort != "" {
+		detail += "  " + c.CommitShort
+	}
+	return detail + "  " + m.relativeTime(c.Timestamp)
+}
+
+// relativeTime formats a timestamp as a short age, e.g. "5m ago"
+func (m Model) relativeTime(t time.Time) string {
+	age := m.clock.Now().Sub(t)
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age.Minutes()))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(age.Hours()/24))
+	}
+}
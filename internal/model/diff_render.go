@@ -0,0 +1,251 @@
+package model
+
+import (
+	"fmt"
+	"maps"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/diff"
+)
+
+// asyncDiffBytes is the combined size of a change's file content and edit
+// strings above which its diff document is built off the UI goroutine. Rows
+// are formatted lazily, so only splitting the file scales with its size.
+const asyncDiffBytes = 4 * 1024 * 1024
+
+// showDiff displays the selected change's diff. Cached and small diffs
+// build inline; huge ones, and history entries whose file has to be
+// fetched from the VCS, build in a worker while a spinner fills the
+// viewport. Any build still in flight is superseded.
+func (m *Model) showDiff() tea.Cmd {
+	m.diffRenderSeq++
+	m.diffRendering = false
+	if !m.needsAsyncDiff() {
+		m.diffViewport.SetContent(m.renderDiff())
+		return nil
+	}
+
+	m.diffRendering = true
+	m.diffDoc = nil
+	m.diffViewport.SetContent(m.renderDiffPending())
+	return tea.Batch(m.renderDiffCmd(), m.diffSpinner.Tick)
+}
+
+// needsAsyncDiff reports whether rendering the selected diff may block
+func (m Model) needsAsyncDiff() bool {
+	if len(m.changes) == 0 || m.incomingEdit() != nil {
+		return false
+	}
+	if _, ok := m.diffCache[m.selectedIndex]; ok {
+		return false
+	}
+	return m.changeNeedsAsync(m.selectedIndex)
+}
+
+// changeNeedsAsync reports whether a change's diff is too slow to render
+// inline
+func (m Model) changeNeedsAsync(idx int) bool {
+	// A squashed run also loads the file around its oldest edit
+	if run := m.squashedRun(idx); run != nil && !m.workingTreeDiff && m.changeNeedsAsync(run[len(run)-1]) {
+		return true
+	}
+	c := m.changes[idx]
+	if c.FileContent == "" && c.FilePath != "" && c.ToolName != "Write" && c.CommitSHA != "" {
+		return true // VCS lookup
+	}
+	return len(c.FileContent)+len(c.OldString)+len(c.NewString) > asyncDiffBytes
+}
+
+// renderDiffCmd builds the selected diff's document on a snapshot of the
+// model. The snapshot gets its own change list so the worker never touches
+// state the UI goroutine mutates; rows are formatted later, on display.
+func (m *Model) renderDiffCmd() tea.Cmd {
+	worker := *m
+	worker.changes = append([]Change(nil), m.changes...)
+	worker.squashExpanded = maps.Clone(m.squashExpanded)
+	worker.semanticDiff = maps.Clone(m.semanticDiff)
+	seq, index := m.diffRenderSeq, m.selectedIndex
+
+	return func() tea.Msg {
+		doc := worker.buildDiffDoc(index)
+		return diffRenderedMsg{
+			seq:         seq,
+			index:       index,
+			doc:         doc,
+			fileContent: worker.changes[index].FileContent,
+		}
+	}
+}
+
+// applyRenderedDiff shows a diff built by renderDiffCmd, dropping results
+// superseded by a later selection
+func (m *Model) applyRenderedDiff(msg diffRenderedMsg) {
+	if msg.seq != m.diffRenderSeq || msg.index >= len(m.changes) {
+		return
+	}
+	m.diffRendering = false
+
+	// Keep content fetched from the VCS so it isn't looked up again
+	if m.changes[msg.index].FileContent == "" {
+		m.changes[msg.index].FileContent = msg.fileContent
+	}
+	m.diffCache[msg.index] = msg.doc
+	m.setDiffDoc(msg.doc)
+	m.scrollToChange()
+}
+
+// renderDiffPending renders the viewport placeholder while a diff renders
+func (m Model) renderDiffPending() string {
+	label := "Rendering diff..."
+	if len(m.changes) > 0 {
+		label = "Rendering " + m.relativePath(m.changes[m.selectedIndex].FilePath) + "..."
+	}
+	return m.diffSpinner.View() + " " + m.theme.Dim.Render(label)
+}
+
+// diffOptions returns the formatting for history diffs, with the configured
+// normalization unless it's toggled off
+func (m Model) diffOptions() diff.FormatOptions {
+	opts := diff.DefaultOptions()
+	if !m.rawDiffs {
+		opts.Normalize = diff.NormalizeOptions{
+			IgnoreWhitespace: m.config.Diff.IgnoreWhitespace,
+			IgnoreEOL:        m.config.Diff.IgnoreEOL,
+			DetectEncoding:   m.config.Diff.DetectEncoding,
+		}
+	}
+	return opts
+}
+
+// toggleNormalize switches between normalized diffs and diffs as recorded
+func (m *Model) toggleNormalize() tea.Cmd {
+	m.rawDiffs = !m.rawDiffs
+	m.diffCache = make(map[int]*diffDoc)
+	switch {
+	case m.rawDiffs:
+		m.addToast("Showing diffs as recorded", ToastInfo)
+	case !m.diffOptions().Normalize.Enabled():
+		m.addToast("No normalization configured in [diff]", ToastWarning)
+	default:
+		m.addToast("Hiding line ending, encoding and whitespace changes per [diff]", ToastInfo)
+	}
+	return m.showDiff()
+}
+
+// diffContext returns how many lines of a file to show around a change, or
+// a negative number for the whole file
+func (m Model) diffContext(path string) int {
+	if m.fullFile {
+		return -1
+	}
+	return m.config.Diff.ContextLinesFor(path)
+}
+
+// trimmedNote describes the part of the file a trimmed body shows
+func (m Model) trimmedNote(f *fileBody) string {
+	return fmt.Sprintf("lines %d-%d of %d (%s: whole file)", f.first+1, f.first+len(f.fileLines), f.total, m.config.Keys.ToggleContext)
+}
+
+// toggleFullFile switches between whole files and the configured context
+// lines around changes
+func (m *Model) toggleFullFile() tea.Cmd {
+	m.fullFile = !m.fullFile
+	m.diffCache = make(map[int]*diffDoc)
+	switch {
+	case m.fullFile:
+		m.addToast("Showing whole files", ToastInfo)
+	case m.config.Diff.ContextLines < 0 && len(m.config.Diff.Context) == 0:
+		m.addToast("No context_lines configured in [diff]", ToastWarning)
+	default:
+		m.addToast("Showing context lines around changes per [diff]", ToastInfo)
+	}
+	return m.showDiff()
+}
+
+// changeTexts returns the file before and after a change, or the edit's
+// old and new strings when the file content is unknown
+func changeTexts(change Change) (string, string) {
+	return diff.Squash([]diff.Step{{
+		OldString:   change.OldString,
+		NewString:   change.NewString,
+		FileContent: change.FileContent,
+	}})
+}
+
+// structuralDiffDoc diffs a JSON or SVG change pretty-printed while the
+// structural view is on, or returns nil to show the usual diff, also when
+// either side doesn't parse
+func (m *Model) structuralDiffDoc(header []string, path, oldText, newText string) *diffDoc {
+	if !m.structuralDiff || !diff.SupportsStructural(path) {
+		return nil
+	}
+	oldPretty, newPretty, ok := diff.Structural(path, oldText, newText)
+	if !ok {
+		return nil
+	}
+	header = append(header, m.theme.Dim.Render("Structural diff (pretty-printed, keys and attributes sorted)"), "")
+	if oldPretty == newPretty {
+		return staticDiffDoc(header, m.theme.Dim.Render("No structural changes"))
+	}
+	return staticDiffDoc(header, diff.FormatDiff(oldPretty, newPretty, m.theme, m.diffOptions()))
+}
+
+// toggleStructural switches JSON and SVG diffs between the recorded text
+// and their pretty-printed structure
+func (m *Model) toggleStructural() tea.Cmd {
+	m.structuralDiff = !m.structuralDiff
+	m.diffCache = make(map[int]*diffDoc)
+	if m.structuralDiff {
+		m.addToast("Structural diffs for JSON and SVG", ToastInfo)
+	} else {
+		m.addToast("Showing JSON and SVG diffs as recorded", ToastInfo)
+	}
+	return m.showDiff()
+}
+
+// semanticKey identifies the history entry a change is shown in: its
+// collapsed run, or the change itself
+func (m Model) semanticKey(idx int) string {
+	if run := m.squashedRun(idx); run != nil {
+		return m.squashKey(run)
+	}
+	c := m.changes[idx]
+	return c.FilePath + "@" + c.Timestamp.Format(time.RFC3339Nano)
+}
+
+// semanticDiffDoc diffs a JSON, YAML or TOML change by key path, falling
+// back to the line diff when either side doesn't parse
+func (m *Model) semanticDiffDoc(header []string, path, oldText, newText string) *diffDoc {
+	changes, err := diff.Semantic(path, oldText, newText)
+	if err != nil {
+		header = append(header, m.theme.Dim.Render("Semantic diff unavailable: "+err.Error()), "")
+		return staticDiffDoc(header, diff.FormatDiff(oldText, newText, m.theme, m.diffOptions()))
+	}
+	header = append(header, m.theme.Dim.Render("Semantic diff by key path"), "")
+	return staticDiffDoc(header, diff.FormatSemantic(changes, m.theme))
+}
+
+// toggleSemantic switches the selected JSON, YAML or TOML change between
+// its line diff and its key path diff
+func (m *Model) toggleSemantic() tea.Cmd {
+	if len(m.changes) == 0 || m.workingTreeDiff {
+		return nil
+	}
+	if !diff.SupportsSemantic(m.changes[m.selectedIndex].FilePath) {
+		m.addToast("Semantic diffs cover JSON, YAML and TOML files", ToastInfo)
+		return nil
+	}
+
+	key := m.semanticKey(m.selectedIndex)
+	if m.semanticDiff[key] {
+		delete(m.semanticDiff, key)
+	} else {
+		if m.semanticDiff == nil {
+			m.semanticDiff = make(map[string]bool)
+		}
+		m.semanticDiff[key] = true
+	}
+	delete(m.diffCache, m.selectedIndex)
+	return m.showDiff()
+}
@@ -0,0 +1,306 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ztaylor/claude-mon/internal/diff"
+	"github.com/ztaylor/claude-mon/internal/minimap"
+	"github.com/ztaylor/claude-mon/internal/vcs"
+)
+
+// diffWindowMargin is the number of rows formatted on each side of the
+// scroll window, so short scrolls reuse already formatted rows
+const diffWindowMargin = 100
+
+// diffRowKind is what a row of a file body shows
+type diffRowKind int
+
+const (
+	rowContext diffRowKind = iota // Unchanged file line
+	rowRemoved                    // Line the change removed
+	rowAdded                      // Line the change added
+)
+
+// diffDoc is a diff addressed by row. The header and static bodies are
+// rendered up front; a file body is only formatted for rows in and near the
+// scroll window, so a huge file costs no more to show than a small one.
+type diffDoc struct {
+	header  []string         // Rows above the body
+	static  []string         // Body of diffs shown without the file
+	file    *fileBody        // Body of diffs shown in the full file
+	minimap *minimap.Minimap // Line types of a file body, nil for static bodies
+
+	formatted        map[int]string // Formatted file body rows near the last window
+	formattedScrollX int            // Horizontal scroll the formatted rows are cut at
+}
+
+// fileBody is a file with a change shown inline: the lines before the
+// change, the removed lines, the added lines, then the rest of the file.
+// A trimmed body holds only the lines around the change.
+type fileBody struct {
+	change      Change
+	fileLines   []string
+	newLines    []string
+	changeStart int // Removed lines are fileLines[changeStart:changeEnd]
+	changeEnd   int
+	first       int // File line (0-indexed) fileLines starts at
+	total       int // Lines in the whole file
+	showBlame   bool
+	blame       []vcs.BlameLine // Blame for context lines, nil if the file isn't tracked
+}
+
+// staticDiffDoc wraps pre-rendered content as a document
+func staticDiffDoc(header []string, body string) *diffDoc {
+	return &diffDoc{header: header, static: strings.Split(strings.TrimSuffix(body, "\n"), "\n")}
+}
+
+// newFileBody lays out a change inside its file's content
+func newFileBody(change Change) *fileBody {
+	f := &fileBody{
+		change:    change,
+		fileLines: diff.SplitLines(change.FileContent),
+		newLines:  diff.SplitLines(change.NewString),
+	}
+	oldLines := diff.SplitLines(change.OldString)
+	f.changeStart = min(max(change.LineNum-1, 0), len(f.fileLines))
+	f.changeEnd = min(f.changeStart+len(oldLines), len(f.fileLines))
+	f.total = len(f.fileLines)
+	return f
+}
+
+// trim cuts the body down to context lines on each side of the change. A
+// negative context keeps the whole file.
+func (f *fileBody) trim(context int) {
+	if context < 0 {
+		return
+	}
+	first := max(f.changeStart-context, 0)
+	last := min(f.changeEnd+context, len(f.fileLines))
+	f.fileLines = f.fileLines[first:last]
+	f.changeStart -= first
+	f.changeEnd -= first
+	f.first += first
+}
+
+// trimmed reports whether the body leaves part of the file out
+func (f *fileBody) trimmed() bool {
+	return len(f.fileLines) < f.total
+}
+
+// Len returns the number of rows in the body
+func (f *fileBody) Len() int {
+	return len(f.fileLines) + len(f.newLines)
+}
+
+// row maps a body row to its kind and its index into fileLines (context and
+// removed rows) or newLines (added rows)
+func (f *fileBody) row(r int) (diffRowKind, int) {
+	switch {
+	case r < f.changeStart:
+		return rowContext, r
+	case r < f.changeEnd:
+		return rowRemoved, r
+	case r < f.changeEnd+len(f.newLines):
+		return rowAdded, r - f.changeEnd
+	default:
+		return rowContext, r - len(f.newLines)
+	}
+}
+
+// minimap returns the body's line types, with the lines a MultiEdit's other
+// edits replace marked removed
+func (f *fileBody) minimap() *minimap.Minimap {
+	mm := minimap.New(f.Len())
+	mm.SetRange(f.changeStart, f.changeEnd, minimap.LineRemoved)
+	mm.SetRange(f.changeEnd, f.changeEnd+len(f.newLines), minimap.LineAdded)
+	for _, h := range f.change.Hunks {
+		start := f.bodyRow(h.LineNum - 1)
+		mm.SetRange(start, start+max(h.LineCount, 1), minimap.LineRemoved)
+	}
+	return mm
+}
+
+// bodyRow returns the row showing a line (0-indexed) of the file. Lines a
+// trimmed body leaves out map to rows before or after it.
+func (f *fileBody) bodyRow(line int) int {
+	line -= f.first
+	if line < f.changeEnd {
+		return line
+	}
+	return line + len(f.newLines)
+}
+
+// Len returns the number of rows in the document
+func (d *diffDoc) Len() int {
+	if d.file != nil {
+		return len(d.header) + d.file.Len()
+	}
+	return len(d.header) + len(d.static)
+}
+
+// changeRow returns the row the change starts at
+func (d *diffDoc) changeRow() int {
+	if d.file != nil {
+		return len(d.header) + d.file.changeStart
+	}
+	return 0
+}
+
+// diffRows returns the rendered rows [start, end) of a document
+func (m *Model) diffRows(d *diffDoc, start, end int) []string {
+	start, end = max(start, 0), min(end, d.Len())
+	if start >= end {
+		return nil
+	}
+
+	h := len(d.header)
+	if d.file != nil && end > h {
+		m.formatFileRows(d, max(start-h, 0), end-h)
+	}
+
+	rows := make([]string, 0, end-start)
+	for r := start; r < end; r++ {
+		switch {
+		case r < h:
+			rows = append(rows, d.header[r])
+		case d.file == nil:
+			rows = append(rows, d.static[r-h])
+		default:
+			rows = append(rows, d.formatted[r-h])
+		}
+	}
+	return rows
+}
+
+// formatFileRows makes sure body rows [from, to) are formatted. When any
+// are missing, the range plus a margin is formatted and rows far from it
+// are dropped, keeping the cache proportional to the window.
+func (m *Model) formatFileRows(d *diffDoc, from, to int) {
+	if d.formatted == nil || d.formattedScrollX != m.scrollX {
+		d.formatted = make(map[int]string)
+		d.formattedScrollX = m.scrollX
+	}
+	missing := false
+	for r := from; r < to; r++ {
+		if _, ok := d.formatted[r]; !ok {
+			missing = true
+			break
+		}
+	}
+	if !missing {
+		return
+	}
+
+	f := d.file
+	from, to = max(from-diffWindowMargin, 0), min(to+diffWindowMargin, f.Len())
+	for r := range d.formatted {
+		if r < from || r >= to {
+			delete(d.formatted, r)
+		}
+	}
+
+	scroll := func(line string) string {
+		if m.scrollX > 0 && len(line) > m.scrollX {
+			return line[m.scrollX:]
+		} else if m.scrollX > 0 {
+			return ""
+		}
+		return line
+	}
+	gutter := func(kind diffRowKind, i int) string {
+		if !f.showBlame {
+			return ""
+		}
+		if kind != rowContext {
+			return m.renderClaudeGutter()
+		}
+		return m.renderBlameGutter(f.blame, f.first+i)
+	}
+
+	for r := from; r < to; {
+		kind, idx := f.row(r)
+		if kind == rowContext {
+			d.formatted[r] = gutter(kind, idx) + m.theme.LineNumber.Render(fmt.Sprintf("%4d", f.first+idx+1)) + " " +
+				m.theme.Context.Render("  ") + m.highlighter.HighlightLine(scroll(f.fileLines[idx]), f.change.FilePath)
+			r++
+			continue
+		}
+
+		// Runs of changed rows are highlighted together, tinted with the
+		// theme's diff backgrounds
+		var src []string
+		var firstNum int
+		sign := m.theme.Removed.Background(m.theme.RemovedBg).Render("- ")
+		bg := m.theme.RemovedBg
+		if kind == rowRemoved {
+			src = f.fileLines[idx:min(f.changeEnd, idx+to-r)]
+			firstNum = f.first + idx + 1
+		} else {
+			src = f.newLines[idx:min(len(f.newLines), idx+to-r)]
+			firstNum = f.first + f.changeStart + idx + 1
+			sign = m.theme.Added.Background(m.theme.AddedBg).Render("+ ")
+			bg = m.theme.AddedBg
+		}
+		scrolled := make([]string, len(src))
+		for j, line := range src {
+			scrolled[j] = scroll(line)
+		}
+		highlighted := m.highlighter.HighlightLines(strings.Join(scrolled, "\n"), f.change.FilePath, bg)
+		for j := range src {
+			d.formatted[r+j] = gutter(kind, idx+j) + m.theme.LineNumberActive.Render(fmt.Sprintf("%4d", firstNum+j)) + " " +
+				sign + highlighted[j]
+		}
+		r += len(src)
+	}
+}
+
+// setDiffDoc makes doc the diff shown in the right pane
+func (m *Model) setDiffDoc(doc *diffDoc) {
+	m.diffDoc = doc
+	m.totalLines = doc.Len()
+	m.minimapData = doc.minimap
+	m.diffOffset = m.clampDiffOffset(m.diffOffset)
+}
+
+// diffWindow returns the current document's rows in the scroll window
+func (m *Model) diffWindow() string {
+	return strings.Join(m.diffRows(m.diffDoc, m.diffOffset, m.diffOffset+m.diffViewport.Height), "\n")
+}
+
+// clampDiffOffset limits a scroll offset to the current document
+func (m Model) clampDiffOffset(offset int) int {
+	if m.diffDoc == nil {
+		return 0
+	}
+	return max(min(offset, m.diffDoc.Len()-m.diffViewport.Height), 0)
+}
+
+// setDiffOffset scrolls the current document to a row and re-renders the
+// window. The viewport only ever holds the window, so it stays at offset 0.
+func (m *Model) setDiffOffset(offset int) {
+	m.diffOffset = m.clampDiffOffset(offset)
+	m.diffViewport.SetContent(m.diffWindow())
+	m.diffViewport.SetYOffset(0)
+}
+
+// scrollDiff scrolls the history diff pane by delta rows
+func (m *Model) scrollDiff(delta int) {
+	if m.diffDoc == nil {
+		if delta > 0 {
+			m.diffViewport.LineDown(delta)
+		} else {
+			m.diffViewport.LineUp(-delta)
+		}
+		return
+	}
+	m.setDiffOffset(m.diffOffset + delta)
+}
+
+// diffScrollTop returns the first row shown in the right pane
+func (m Model) diffScrollTop() int {
+	if m.diffDoc != nil {
+		return m.diffOffset
+	}
+	return m.diffViewport.YOffset
+}
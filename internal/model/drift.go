@@ -0,0 +1,92 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/diff"
+)
+
+// driftKey identifies a change in the drift status map (indices shift as
+// changes arrive)
+func driftKey(c Change) string {
+	return fmt.Sprintf("%s:%d", c.FilePath, c.Timestamp.UnixNano())
+}
+
+// readWorkingFile reads a file's current on-disk content, resolving
+// relative paths against the working directory
+func readWorkingFile(fsys FS, path string) (string, error) {
+	if !filepath.IsAbs(path) {
+		if cwd, err := fsys.Getwd(); err == nil {
+			path = filepath.Join(cwd, path)
+		}
+	}
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// checkDriftCmd compares every change against its file's current content
+func (m Model) checkDriftCmd() tea.Cmd {
+	changes := make([]Change, len(m.changes))
+	copy(changes, m.changes)
+	fsys := m.fs
+
+	return func() tea.Msg {
+		contents := make(map[string]string)
+		statuses := make(map[string]diff.DriftStatus, len(changes))
+		for _, c := range changes {
+			content, ok := contents[c.FilePath]
+			if !ok {
+				// Missing files compare as empty, so their changes read as reverted
+				content, _ = readWorkingFile(fsys, c.FilePath)
+				contents[c.FilePath] = content
+			}
+			statuses[driftKey(c)] = diff.CheckDrift(c.OldString, c.NewString, content).Status
+		}
+		return driftCheckedMsg{statuses: statuses}
+	}
+}
+
+// toggleWorkingTreeDiff switches the diff pane between the recorded change
+// and a comparison against the file's current content
+func (m *Model) toggleWorkingTreeDiff() tea.Cmd {
+	m.workingTreeDiff = !m.workingTreeDiff
+	m.diffCache = make(map[int]*diffDoc)
+	render := m.showDiff()
+
+	if !m.workingTreeDiff {
+		m.addToast("Showing recorded diff", ToastInfo)
+		return render
+	}
+	m.addToast("Comparing against working tree", ToastInfo)
+	return tea.Batch(render, m.checkDriftCmd())
+}
+
+// renderDriftBadge renders a one-character drift status for the history list
+func (m Model) renderDriftBadge(c Change) string {
+	switch m.driftStatus[driftKey(c)] {
+	case diff.DriftApplied:
+		return m.theme.Added.Render("✓")
+	case diff.DriftDrifted:
+		return m.theme.Modified.Render("~")
+	case diff.DriftReverted:
+		return m.theme.Removed.Render("✗")
+	default:
+		return " "
+	}
+}
+
+// renderWorkingTreeDiff renders the selected change against the file's
+// current on-disk content
+func (m Model) renderWorkingTreeDiff(change Change) string {
+	current, err := readWorkingFile(m.fs, change.FilePath)
+	if err != nil && !os.IsNotExist(err) {
+		return m.theme.Dim.Render(fmt.Sprintf("Cannot read working tree file: %v", err))
+	}
+	return diff.FormatDrift(diff.CheckDrift(change.OldString, change.NewString, current), m.theme)
+}
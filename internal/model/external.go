@@ -0,0 +1,61 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/database"
+)
+
+// externalMark marks changes made outside Claude in the history list
+const externalMark = "↯"
+
+// queryExternalEditsCmd asks the daemon for the changes its file watcher has
+// recorded in this workspace since the last poll
+func (m Model) queryExternalEditsCmd() tea.Cmd {
+	since, clock := m.externalSince, m.clock
+	return func() tea.Msg {
+		queried := clock.Now()
+		filterExpr := fmt.Sprintf("tool:%s since:%s", database.ToolExternal, since.UTC().Format(time.RFC3339))
+		changes, _, err := m.fetchDaemonHistory(filterExpr, "", historyPageSize)
+		return externalEditsMsg{changes: changes, queried: queried, err: err}
+	}
+}
+
+// addExternalEdits adds the changes made outside Claude a poll found to the
+// history, keeping the selected change selected
+func (m *Model) addExternalEdits(msg externalEditsMsg) tea.Cmd {
+	if msg.err != nil {
+		return nil // The daemon isn't running; the next poll starts from the same point
+	}
+	// since: is at second precision; overlap is deduplicated below
+	m.externalSince = msg.queried.Add(-time.Second)
+
+	have := make(map[string]bool, len(m.changes))
+	for _, c := range m.changes {
+		have[historyKey(c)] = true
+	}
+	var added []Change
+	for _, c := range msg.changes {
+		if !have[historyKey(c)] {
+			added = append(added, c)
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+
+	empty := len(m.changes) == 0
+	m.changes = append(added, m.changes...)
+	m.sortChanges(m.selectedIndex + len(added))
+	if len(added) == 1 {
+		m.addToast("Changed outside Claude: "+m.relativePath(added[0].FilePath), ToastWarning)
+	} else {
+		m.addToast(fmt.Sprintf("%d changes made outside Claude", len(added)), ToastWarning)
+	}
+	if empty {
+		return m.showDiff()
+	}
+	return nil
+}
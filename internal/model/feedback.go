@@ -0,0 +1,90 @@
+package model
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ztaylor/claude-mon/internal/config"
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/diff"
+	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/internal/prompt"
+)
+
+// feedbackMaxLines caps each side of a change's diff in feedback, so one
+// large Write doesn't crowd out the rest
+const feedbackMaxLines = 80
+
+// sendSelectedFeedback asks to send the selected change back to Claude
+func (m *Model) sendSelectedFeedback() {
+	if len(m.changes) == 0 {
+		m.addToast("No change selected", ToastInfo)
+		return
+	}
+	change := m.changes[m.selectedIndex]
+	m.queueFeedback([]Change{change}, "feedback on "+m.relativePath(change.FilePath))
+}
+
+// sendRejectedFeedback asks to send every change rejected in review back
+// to Claude, oldest first
+func (m *Model) sendRejectedFeedback() {
+	var rejected []Change
+	for i := len(m.changes) - 1; i >= 0; i-- {
+		if m.changes[i].Review == database.ReviewRejected {
+			rejected = append(rejected, m.changes[i])
+		}
+	}
+	if len(rejected) == 0 {
+		m.addToast("No rejected changes", ToastInfo)
+		return
+	}
+	m.queueFeedback(rejected, fmt.Sprintf("feedback on %d rejected", len(rejected)))
+}
+
+// queueFeedback holds feedback on changes for the same send confirmation
+// prompts get
+func (m *Model) queueFeedback(changes []Change, name string) {
+	target, ok := m.resolveSendTarget()
+	if !ok {
+		return
+	}
+	content := m.formatFeedback(changes)
+	m.promptSendPending = &pendingPromptSend{
+		name:     name,
+		content:  content,
+		target:   target,
+		estimate: prompt.EstimatePrompt(content, content),
+		feedback: true,
+	}
+	logger.Log("Confirm feedback send: %d changes, %s", len(changes), m.promptSendPending.estimate)
+}
+
+// formatFeedback fills the feedback template in with each change's path,
+// review note and diff
+func (m Model) formatFeedback(changes []Change) string {
+	var sb strings.Builder
+	for i, c := range changes {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		location := m.relativePath(c.FilePath)
+		if c.LineNum > 0 {
+			location += ":" + strconv.Itoa(c.LineNum)
+		}
+		sb.WriteString("### " + location + "\n\n")
+		if c.Note != "" {
+			sb.WriteString("Reason: " + c.Note + "\n\n")
+		}
+		sb.WriteString(diff.Markdown(c.OldString, c.NewString, feedbackMaxLines))
+	}
+
+	tmpl := m.config.FeedbackTemplate
+	if tmpl == "" {
+		tmpl = config.DefaultFeedbackTemplate
+	}
+	// The count goes in first so a diff that happens to contain
+	// "{{count}}" is sent as written
+	tmpl = prompt.FillVariables(tmpl, map[string]string{"count": strconv.Itoa(len(changes))})
+	return prompt.FillVariables(tmpl, map[string]string{"changes": strings.TrimSuffix(sb.String(), "\n")})
+}
@@ -0,0 +1,238 @@
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/diff"
+	"github.com/ztaylor/claude-mon/internal/vcs"
+)
+
+// fileHistoryLimit is how many edits to one file the file history loads
+const fileHistoryLimit = 500
+
+// fileHistory is the file history panel: every recorded edit to one file
+// across sessions, and the version the slider is on
+type fileHistory struct {
+	path    string
+	edits   []*database.Edit // Oldest first, with the content after each composed where it can be
+	version int              // Index into edits
+	loaded  bool
+	err     error
+}
+
+// queryFileHistoryCmd asks the daemon for every edit to a file, then fills
+// in the content after the edits stored without it from their neighbours,
+// or from the VCS at the commit an edit was made on
+func (m Model) queryFileHistoryCmd(filePath string) tea.Cmd {
+	return func() tea.Msg {
+		var result struct {
+			Edits []*database.Edit `json:"edits"`
+			Error string           `json:"error,omitempty"`
+		}
+		if err := queryDaemon(map[string]interface{}{
+			"type":      "file",
+			"file_path": filePath,
+			"limit":     fileHistoryLimit,
+		}, &result); err != nil {
+			return fileHistoryMsg{path: filePath, err: err}
+		}
+		if result.Error != "" {
+			return fileHistoryMsg{path: filePath, err: fmt.Errorf("daemon: %s", result.Error)}
+		}
+
+		edits := result.Edits
+		slices.Reverse(edits)
+		database.ComposeFileVersions(edits)
+		for _, e := range edits {
+			if e.FileContent != "" || e.CommitSHA == "" || e.VCSType == "" || e.OldString == "" {
+				continue
+			}
+			root, err := vcs.GetWorkspaceRoot(filepath.Dir(filePath), e.VCSType)
+			if err != nil {
+				continue
+			}
+			// The commit is the file before the session's uncommitted edits,
+			// so this only holds for the first edit since it
+			if before, err := vcs.GetFileAtCommit(root, filePath, e.CommitSHA, e.VCSType); err == nil && strings.Contains(before, e.OldString) {
+				e.FileContent = strings.Replace(before, e.OldString, e.NewString, 1)
+				database.ComposeFileVersions(edits)
+			}
+		}
+		return fileHistoryMsg{path: filePath, edits: edits}
+	}
+}
+
+// toggleFileHistory switches the diff pane between the selected change and
+// the history of its file
+func (m *Model) toggleFileHistory() tea.Cmd {
+	if m.fileHistory != nil {
+		m.fileHistory = nil
+		m.diffCache = make(map[int]*diffDoc)
+		return m.showDiff()
+	}
+	if len(m.changes) == 0 {
+		return nil
+	}
+	return m.openFileHistory()
+}
+
+// openFileHistory shows the history of the selected change's file
+func (m *Model) openFileHistory() tea.Cmd {
+	path := m.changes[m.selectedIndex].FilePath
+	m.fileHistory = &fileHistory{path: path}
+	m.diffCache = make(map[int]*diffDoc)
+	return tea.Batch(m.showDiff(), m.queryFileHistoryCmd(path))
+}
+
+// syncFileHistory follows the selection to another file while the file
+// history is open
+func (m *Model) syncFileHistory() tea.Cmd {
+	if m.fileHistory == nil || len(m.changes) == 0 || m.changes[m.selectedIndex].FilePath == m.fileHistory.path {
+		return nil
+	}
+	return m.openFileHistory()
+}
+
+// applyFileHistory stores a file's edits, with the slider on the selected
+// change's version
+func (m *Model) applyFileHistory(msg fileHistoryMsg) {
+	h := m.fileHistory
+	if h == nil || h.path != msg.path {
+		return
+	}
+	h.loaded, h.edits, h.err = true, msg.edits, msg.err
+	h.version = len(h.edits) - 1
+	if len(m.changes) > 0 {
+		selected := m.changes[m.selectedIndex]
+		for i, e := range h.edits {
+			if (selected.EditID != 0 && e.ID == selected.EditID) || (selected.EditID == 0 && !e.Timestamp.After(selected.Timestamp)) {
+				h.version = i
+			}
+		}
+	}
+	m.diffCache = make(map[int]*diffDoc)
+}
+
+// handleFileHistoryKey steps the version slider while the file history is
+// open; esc closes it
+func (m *Model) handleFileHistoryKey(key string) (bool, tea.Cmd) {
+	h := m.fileHistory
+	switch key {
+	case ",", ".":
+		delta := 1
+		if key == "," {
+			delta = -1
+		}
+		if v := h.version + delta; v >= 0 && v < len(h.edits) {
+			h.version = v
+			m.diffCache = make(map[int]*diffDoc)
+			cmd := m.showDiff()
+			m.scrollToChange()
+			return true, cmd
+		}
+		return true, nil
+	case "esc":
+		return true, m.toggleFileHistory()
+	}
+	return false, nil
+}
+
+// fileHistoryDoc renders the file as of the version the slider is on, with
+// that version's edit shown inline
+func (m *Model) fileHistoryDoc() *diffDoc {
+	h := m.fileHistory
+	title := m.theme.Title.Render(m.relativePath(h.path)) + m.theme.Dim.Render(" history")
+	rule := m.theme.Dim.Render(strings.Repeat("─", 40))
+	switch {
+	case !h.loaded:
+		return staticDiffDoc([]string{title, rule, ""}, m.theme.Dim.Render("Loading file history..."))
+	case h.err != nil:
+		return staticDiffDoc([]string{title, rule, ""}, m.theme.Removed.Render("File history needs the daemon: "+h.err.Error()))
+	case len(h.edits) == 0:
+		return staticDiffDoc([]string{title, rule, ""}, m.theme.Dim.Render("No recorded edits to this file"))
+	}
+
+	e := h.edits[h.version]
+	detail := m.localTime(e.Timestamp).Format("2006-01-02 15:04:05") + "  " + e.ToolName
+	if e.User != "" {
+		detail += "  " + e.User
+	}
+	if e.Summary != "" {
+		detail += "  " + e.Summary
+	}
+	header := []string{
+		title,
+		m.renderVersionSlider(h.version, len(h.edits)),
+		m.theme.Dim.Render(detail),
+	}
+	if e.Note != "" {
+		header = append(header, m.theme.Modified.Render("✎ "+e.Note))
+	}
+	header = append(header, rule, "")
+
+	if e.FileContent == "" {
+		body := m.theme.Dim.Render("File content not recorded for this version") + "\n\n" +
+			diff.FormatDiff(e.OldString, e.NewString, m.theme, m.diffOptions())
+		return staticDiffDoc(header, body)
+	}
+	if diff.IsBinary(e.FileContent) {
+		var before string
+		if h.version > 0 {
+			before = h.edits[h.version-1].FileContent
+		}
+		return staticDiffDoc(header, diff.FormatBinary(before, e.FileContent, m.theme))
+	}
+	body := newFileBody(h.versionChange(h.version))
+	body.trim(m.diffContext(e.FilePath))
+	if body.trimmed() {
+		header[len(header)-1] = m.theme.Dim.Render(m.trimmedNote(body))
+		header = append(header, "")
+	}
+	return &diffDoc{header: header, file: body, minimap: body.minimap()}
+}
+
+// renderVersionSlider renders the slider over a file's versions, a dot for
+// each when they fit
+func (m Model) renderVersionSlider(version, count int) string {
+	width := min(count, 40)
+	pos := 0
+	if count > 1 {
+		pos = version * (width - 1) / (count - 1)
+	}
+	track := m.theme.Dim.Render(strings.Repeat("─", pos)) + m.theme.Selected.Render("●") +
+		m.theme.Dim.Render(strings.Repeat("─", width-pos-1))
+	return fmt.Sprintf("%s %s %s", track, m.theme.Normal.Render(fmt.Sprintf("version %d/%d", version+1, count)),
+		m.theme.Dim.Render("(, older  . newer)"))
+}
+
+// versionChange lays out a version as a change: the file before its edit
+// with the edit's lines replaced inline, or the whole file when the edit
+// can't be placed in it
+func (h *fileHistory) versionChange(v int) Change {
+	e := h.edits[v]
+	c := Change{Timestamp: e.Timestamp, FilePath: e.FilePath, ToolName: e.ToolName}
+	if e.ToolName == "Write" {
+		c.NewString = e.FileContent
+		return c
+	}
+
+	var before string
+	switch {
+	case e.NewString != "" && strings.Contains(e.FileContent, e.NewString):
+		before = strings.Replace(e.FileContent, e.NewString, e.OldString, 1)
+	case v > 0:
+		before = h.edits[v-1].FileContent
+	}
+	if i := strings.Index(before, e.OldString); e.OldString != "" && i >= 0 {
+		c.FileContent, c.OldString, c.NewString = before, e.OldString, e.NewString
+		c.LineNum = strings.Count(before[:i], "\n") + 1
+		return c
+	}
+	c.FileContent = e.FileContent
+	return c
+}
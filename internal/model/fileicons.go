@@ -0,0 +1,35 @@
+package model
+
+import (
+	"github.com/charmbracelet/lipgloss"
+	"github.com/ztaylor/claude-mon/internal/config"
+	"github.com/ztaylor/claude-mon/internal/lang"
+)
+
+// renderFileIcon renders a change's file type icon followed by a space, in
+// the style set by file_icons, or "" when icons are off
+func (m Model) renderFileIcon(c Change) string {
+	l := lang.Detect(c.FilePath)
+	var icon string
+	switch m.config.FileIcons {
+	case config.IconsNerd:
+		icon = l.Icon
+	case config.IconsASCII:
+		icon = l.ASCII
+	default:
+		return ""
+	}
+	return lipgloss.NewStyle().Foreground(lipgloss.Color(l.Color)).Render(icon) + " "
+}
+
+// fileIconWidth returns the columns taken by renderFileIcon
+func (m Model) fileIconWidth() int {
+	switch m.config.FileIcons {
+	case config.IconsNerd:
+		return 2
+	case config.IconsASCII:
+		return 3
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	gocontext "context"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// toggleFollow turns follow mode on or off. It needs a running nvim to
+// open changes in, so it stays off without a server address.
+func (m *Model) toggleFollow() {
+	if m.following {
+		m.following = false
+		m.addToast("Follow mode off", ToastInfo)
+		return
+	}
+	if m.config.NvimServerAddress() == "" {
+		m.addToast("Follow mode needs nvim_remote and a running nvim server", ToastWarning)
+		return
+	}
+	m.following = true
+	m.addToast("Following edits in nvim", ToastSuccess)
+}
+
+// followChange schedules opening a new change in nvim once edits settle
+// for the configured debounce. A change to a file outside the follow
+// allowlist is skipped.
+func (m *Model) followChange(change Change) tea.Cmd {
+	if !m.following || change.FilePath == "" {
+		return nil
+	}
+	dir, _ := m.fs.Getwd()
+	if !m.config.Follow.Follows(change.FilePath, dir) {
+		return nil
+	}
+	m.followSeq++
+	m.followTarget = change
+	seq := m.followSeq
+	return tea.Tick(m.config.Follow.DebounceDuration(), func(time.Time) tea.Msg {
+		return followTickMsg{seq: seq}
+	})
+}
+
+// followEditCmd opens a change at its line in the running nvim. Unlike
+// openInNvim it never starts nvim in place of the TUI.
+func (m Model) followEditCmd(change Change) tea.Cmd {
+	addr := m.config.NvimServerAddress()
+	if addr == "" {
+		return func() tea.Msg { return followErrMsg{err: fmt.Errorf("no nvim server")} }
+	}
+	runner := m.runner
+	expr := nvimEditExpr(change.FilePath, change.LineNum)
+	return func() tea.Msg {
+		ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 2*time.Second)
+		defer cancel()
+		if _, err := runner.Output(ctx, "", "nvim", "--server", addr, "--remote-expr", expr); err != nil {
+			return followErrMsg{err: fmt.Errorf("nvim server %s: %w", addr, err)}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,198 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/diff"
+	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/internal/socket"
+)
+
+// gateDeniedReason is what Claude is told when an edit is denied in the TUI
+const gateDeniedReason = "The user denied this edit in claude-mon. Ask them how to proceed before trying again."
+
+// GateMsg is an edit the PreToolUse hook holds on the gate socket until
+// it's allowed or denied
+type GateMsg struct {
+	Payload []byte
+	Answer  func(socket.GateDecision)
+}
+
+// gateRequest is an edit held for approval
+type gateRequest struct {
+	id     int
+	edit   pendingEdit
+	answer func(socket.GateDecision)
+}
+
+// gateTimeoutMsg answers a held edit nobody answered in time
+type gateTimeoutMsg struct {
+	id int
+}
+
+// GateEnabled reports whether the TUI holds Claude's edits for approval
+func (m Model) GateEnabled() bool {
+	return m.config.Gate.Enabled
+}
+
+// holdGatedEdit queues an edit for approval, previewing it in the diff
+// pane, and answers it with the configured default once it times out.
+// Edits the workspace rules don't track go ahead.
+func (m *Model) holdGatedEdit(msg GateMsg) tea.Cmd {
+	change := m.parsePayload(msg.Payload)
+	if change == nil || !m.tracksChange(change) {
+		msg.Answer(socket.GateDecision{Allow: true})
+		return nil
+	}
+
+	before := m.incomingEdit()
+	m.gateSeq++
+	id := m.gateSeq
+	m.gateQueue = append(m.gateQueue, gateRequest{
+		id: id,
+		edit: pendingEdit{
+			SessionID: change.SessionID,
+			ToolName:  change.ToolName,
+			FilePath:  change.FilePath,
+			Since:     m.clock.Now(),
+			Change:    change,
+		},
+		answer: msg.Answer,
+	})
+	logger.Log("Holding %s of %s for approval", change.ToolName, change.FilePath)
+	m.refreshIncoming(before)
+
+	return tea.Tick(m.config.Gate.TimeoutDuration(), func(time.Time) tea.Msg {
+		return gateTimeoutMsg{id: id}
+	})
+}
+
+// answerGate allows or denies the oldest held edit
+func (m *Model) answerGate(allow bool) {
+	if len(m.gateQueue) == 0 {
+		return
+	}
+	req := m.gateQueue[0]
+	decision := socket.GateDecision{Allow: allow}
+	if !allow {
+		decision.Reason = gateDeniedReason
+	}
+	m.resolveGate(req.id, decision)
+
+	if allow {
+		m.addToast("Allowed "+req.edit.ToolName+" of "+m.relativePath(req.edit.FilePath), ToastSuccess)
+	} else {
+		m.addToast("Denied "+req.edit.ToolName+" of "+m.relativePath(req.edit.FilePath), ToastWarning)
+	}
+}
+
+// expireGate answers a held edit that timed out with the configured
+// default, if it's still waiting
+func (m *Model) expireGate(id int) {
+	for _, req := range m.gateQueue {
+		if req.id != id {
+			continue
+		}
+		allow := m.config.Gate.AllowsOnTimeout()
+		decision := socket.GateDecision{Allow: allow}
+		verdict := "allowed"
+		if !allow {
+			decision.Reason = fmt.Sprintf("The edit wasn't approved in claude-mon within %s.", m.config.Gate.TimeoutDuration())
+			verdict = "denied"
+		}
+		m.resolveGate(id, decision)
+		m.addToast(fmt.Sprintf("No answer for %s of %s; %s", req.edit.ToolName, m.relativePath(req.edit.FilePath), verdict), ToastWarning)
+		return
+	}
+}
+
+// resolveGate sends a held edit its decision and drops it from the queue.
+// A denied edit won't land, so it's no longer pending either.
+func (m *Model) resolveGate(id int, decision socket.GateDecision) {
+	before := m.incomingEdit()
+	var kept []gateRequest
+	for _, req := range m.gateQueue {
+		if req.id != id {
+			kept = append(kept, req)
+			continue
+		}
+		req.answer(decision)
+		if !decision.Allow {
+			m.clearPendingEdit(req.edit.SessionID, req.edit.FilePath)
+		}
+		logger.Log("Gate decision for %s: allow=%v", req.edit.FilePath, decision.Allow)
+	}
+	m.gateQueue = kept
+	m.refreshIncoming(before)
+}
+
+// incomingEdit returns the edit the diff pane previews before it lands: the
+// oldest one held for approval, otherwise the newest pending edit while the
+// newest change is selected. It's nil if there's none.
+func (m Model) incomingEdit() *pendingEdit {
+	if len(m.gateQueue) > 0 {
+		return &m.gateQueue[0].edit
+	}
+	if m.selectedIndex != 0 {
+		return nil
+	}
+	pending := m.currentPendingEdits()
+	for i := len(pending) - 1; i >= 0; i-- {
+		if pending[i].Change != nil {
+			return &pending[i]
+		}
+	}
+	return nil
+}
+
+// refreshIncoming redraws the diff pane if the previewed edit changed from
+// before
+func (m *Model) refreshIncoming(before *pendingEdit) {
+	after := m.incomingEdit()
+	if before == nil && after == nil || m.leftPaneMode != LeftPaneModeHistory {
+		return
+	}
+	m.showDiff()
+}
+
+// incomingDiffDoc previews an edit that hasn't landed: its strings, or for
+// a Write the file as it is against the content replacing it
+func (m Model) incomingDiffDoc(p *pendingEdit) *diffDoc {
+	c := p.Change
+	title := m.theme.Modified.Render("⧗ Incoming: ") + m.theme.Title.Render(m.relativePath(c.FilePath))
+	if c.LineNum > 0 && c.OldString != "" {
+		title += m.theme.Dim.Render(fmt.Sprintf(":%d", c.LineNum))
+	}
+	header := []string{title}
+	if c.Summary != "" {
+		header = append(header, m.theme.Dim.Render(c.Summary))
+	}
+	if len(m.gateQueue) > 0 {
+		header = append(header, m.theme.Removed.Render("Held for approval: y allows, n denies"))
+	} else {
+		header = append(header, m.theme.Dim.Render(c.ToolName+" not applied yet"))
+	}
+	header = append(header, m.theme.Dim.Render(strings.Repeat("─", 40)), "")
+
+	oldText, newText := c.OldString, c.NewString
+	if oldText == "" {
+		oldText = c.FileContent
+	}
+	if diff.IsBinary(oldText) || diff.IsBinary(newText) {
+		return staticDiffDoc(header, diff.FormatBinary(oldText, newText, m.theme))
+	}
+	return staticDiffDoc(header, diff.FormatDiff(oldText, newText, m.theme, m.diffOptions()))
+}
+
+// renderGateConfirm renders the status line while an edit is held
+func (m Model) renderGateConfirm() string {
+	req := m.gateQueue[0]
+	prompt := fmt.Sprintf("Allow %s of %s?", req.edit.ToolName, m.relativePath(req.edit.FilePath))
+	if n := len(m.gateQueue) - 1; n > 0 {
+		prompt += fmt.Sprintf(" (%d more waiting)", n)
+	}
+	return m.theme.Status.Inherit(m.theme.Removed).Render(prompt + "  y:allow  n:deny")
+}
@@ -0,0 +1,176 @@
+package model
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/ztaylor/claude-mon/internal/prompt"
+	"github.com/ztaylor/claude-mon/internal/theme"
+)
+
+// Regenerate with: go test ./internal/model -run TestGolden -update
+var update = flag.Bool("update", false, "update golden files in testdata/golden")
+
+// goldenNow is the clock golden renders are taken at
+var goldenNow = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// newGoldenModel returns a model with a fixed clock, time zone, workspace
+// and theme, sized to width x height, that reads no config from the real
+// home directory and sends nothing to a running daemon
+func newGoldenModel(t *testing.T, width, height int) Model {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("TMUX", "")
+	m := New("",
+		WithClock(ClockFunc(func() time.Time { return goldenNow })),
+		WithFS(workspaceFS{dir: "/proj"}),
+		WithTheme(theme.Default()),
+		WithDaemonSocket(filepath.Join(t.TempDir(), "daemon.sock")),
+	)
+	return updateModel(m, tea.WindowSizeMsg{Width: width, Height: height})
+}
+
+// workspaceFS is the real filesystem with the working directory fixed at dir
+type workspaceFS struct {
+	osFS
+	dir string
+}
+
+func (f workspaceFS) Getwd() (string, error) { return f.dir, nil }
+
+// updateModel applies msgs in order, dropping the commands they return
+func updateModel(m Model, msgs ...tea.Msg) Model {
+	var tm tea.Model = m
+	for _, msg := range msgs {
+		tm, _ = tm.Update(msg)
+	}
+	return tm.(Model)
+}
+
+// keys returns a key message for each key, e.g. "ctrl+g" or "2"
+func keys(names ...string) []tea.Msg {
+	msgs := make([]tea.Msg, len(names))
+	for i, name := range names {
+		switch name {
+		case "ctrl+g":
+			msgs[i] = tea.KeyMsg{Type: tea.KeyCtrlG}
+		case "esc":
+			msgs[i] = tea.KeyMsg{Type: tea.KeyEscape}
+		default:
+			msgs[i] = tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(name)}
+		}
+	}
+	return msgs
+}
+
+// assertGolden compares a view, with ANSI styling and trailing spaces
+// stripped, against testdata/golden/<name>.golden
+func assertGolden(t *testing.T, name, view string) {
+	t.Helper()
+	lines := strings.Split(ansi.Strip(view), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	got := strings.Join(lines, "\n")
+
+	path := filepath.Join("testdata", "golden", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("missing golden file (run with -update to create it): %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from %s (run with -update if the change is intended)\n--- got ---\n%s\n--- want ---\n%s",
+			name, path, got, want)
+	}
+}
+
+func TestGoldenHistory(t *testing.T) {
+	m := newGoldenModel(t, 100, 30)
+	m.changes = []Change{
+		{Timestamp: goldenNow.Add(-2 * time.Hour), FilePath: "/proj/main.go", ToolName: "Edit", LineNum: 4,
+			OldString: `fmt.Println("hi")`, NewString: `fmt.Println("hello")`,
+			FileContent: "package main\n\nfunc main() {\n\tfmt.Println(\"hello\")\n}\n"},
+		{Timestamp: goldenNow.Add(-10 * time.Minute), FilePath: "/proj/internal/api/server.go", ToolName: "Edit", LineNum: 3,
+			OldString: "port := 8080", NewString: "port := cfg.Port",
+			FileContent: "package api\n\nport := cfg.Port\n"},
+		{Timestamp: goldenNow.Add(-5 * time.Minute), FilePath: "/proj/README.md", ToolName: "Write", LineNum: 1,
+			NewString: "# Demo\n\nA small demo.", FileContent: "# Demo\n\nA small demo.\n"},
+	}
+	m.selectedIndex = len(m.changes) - 1
+	if cmd := m.showDiff(); cmd != nil {
+		t.Fatal("expected the diff to render inline")
+	}
+
+	assertGolden(t, "history", m.View())
+}
+
+func TestGoldenPrompts(t *testing.T) {
+	m := newGoldenModel(t, 100, 30)
+	dir := filepath.Join(os.Getenv("HOME"), ".claude", "prompts")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []prompt.Prompt{
+		{Name: "review", Description: "Review the current diff", Version: 3, Tags: []string{"git"},
+			Created: goldenNow.Add(-48 * time.Hour), Updated: goldenNow.Add(-time.Hour), Content: "Review the changes for bugs."},
+		{Name: "tests", Description: "Write tests for {{file}}", Version: 1,
+			Created: goldenNow.Add(-24 * time.Hour), Updated: goldenNow.Add(-24 * time.Hour), Content: "Write table tests for {{file}}."},
+	} {
+		if err := os.WriteFile(filepath.Join(dir, p.Name+".prompt.md"), []byte(p.Format()), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m = updateModel(m, keys("2")...)
+	if len(m.promptFilteredList) != 2 {
+		t.Fatalf("expected both prompts listed, got %d", len(m.promptFilteredList))
+	}
+	assertGolden(t, "prompts", m.View())
+}
+
+func TestGoldenContextPopup(t *testing.T) {
+	m := newGoldenModel(t, 100, 30)
+	m = updateModel(m, keys("5", "ctrl+g", "k")...)
+	if !m.contextEditMode {
+		t.Fatal("expected the Kubernetes context popup to be open")
+	}
+	assertGolden(t, "context_popup", m.View())
+}
+
+func TestGoldenWhichKey(t *testing.T) {
+	m := newGoldenModel(t, 120, 40)
+	m = updateModel(m, keys("ctrl+g")...)
+	if !m.leaderActive {
+		t.Fatal("expected the which-key popup to be open")
+	}
+	assertGolden(t, "which_key", m.View())
+}
+
+func TestGoldenNotifications(t *testing.T) {
+	m := newGoldenModel(t, 100, 30)
+	m.clock = ClockFunc(func() time.Time { return goldenNow.Add(-2 * time.Minute) })
+	m.addToast("Plan created: add-auth", ToastSuccess)
+	m.clock = ClockFunc(func() time.Time { return goldenNow.Add(-time.Minute) })
+	m.addToast("Loop edits unavailable: daemon not running", ToastWarning)
+	m.clock = ClockFunc(func() time.Time { return goldenNow })
+	m.addToast("Sync failed: git push rejected (fetch first)", ToastError)
+
+	m = updateModel(m, keys("ctrl+g", "!")...)
+	assertGolden(t, "notifications", m.View())
+}
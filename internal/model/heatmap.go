@@ -0,0 +1,92 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/diff"
+	"github.com/ztaylor/claude-mon/internal/minimap"
+)
+
+// Earlier edits to a file fade on the minimap with this half-life, down to
+// minHeat so even old ones stay visible
+const (
+	heatHalfLife = time.Hour
+	minHeat      = 0.1
+)
+
+// markFileHistory marks the lines the history's other changes to a file
+// touched on its minimap, hotter the more recent they are
+func (m *Model) markFileHistory(mm *minimap.Minimap, body *fileBody, idx int) {
+	now := m.clock.Now()
+	for i, c := range m.changes {
+		if i == idx || c.FilePath != body.change.FilePath || c.LineNum < 1 {
+			continue
+		}
+		age := max(now.Sub(c.Timestamp), 0)
+		heat := max(math.Pow(0.5, float64(age)/float64(heatHalfLife)), minHeat)
+
+		start := body.bodyRow(c.LineNum - 1)
+		mm.MarkHeat(start, start+max(len(diff.SplitLines(c.NewString)), 1), heat)
+		for _, h := range c.Hunks {
+			start := body.bodyRow(h.LineNum - 1)
+			mm.MarkHeat(start, start+max(h.LineCount, 1), heat)
+		}
+	}
+}
+
+// startRegionJump waits for the number of the minimap region to jump to
+func (m *Model) startRegionJump() {
+	if m.minimapData == nil || m.diffDoc == nil {
+		return
+	}
+	switch n := len(m.minimapData.Regions()); n {
+	case 0:
+		m.addToast("Nothing marked on the minimap", ToastInfo)
+	case 1:
+		m.jumpToRegion(m.minimapData.Regions()[0])
+	default:
+		m.minimapJumpPending = true
+		m.addToast(fmt.Sprintf("Jump to minimap region 1-%d (G: last)", min(n, 9)), ToastInfo)
+	}
+}
+
+// handleRegionJumpKey jumps to the region a key numbers: 1-9 count from
+// the top, G is the last
+func (m *Model) handleRegionJumpKey(key string) {
+	m.minimapJumpPending = false
+	if m.minimapData == nil || m.diffDoc == nil {
+		return
+	}
+	regions := m.minimapData.Regions()
+	if len(regions) == 0 {
+		return
+	}
+	if key == "G" {
+		m.jumpToRegion(regions[len(regions)-1])
+		return
+	}
+	if n, err := strconv.Atoi(key); err == nil && n >= 1 && n <= len(regions) {
+		m.jumpToRegion(regions[n-1])
+	}
+}
+
+// jumpToRegion scrolls the diff to a minimap region with a little context
+// above it
+func (m *Model) jumpToRegion(r minimap.Region) {
+	m.setDiffOffset(len(m.diffDoc.header) + r.Start - 3)
+}
+
+// clickMinimap jumps to the region marked at screen row y of the minimap,
+// or centers the diff on the row when nothing is marked there
+func (m *Model) clickMinimap(y int) {
+	if m.minimapData != nil && m.diffDoc != nil {
+		if r, ok := m.minimapData.RegionAt(y-paneTop, m.height-4); ok {
+			m.jumpToRegion(r)
+			return
+		}
+	}
+	m.scrubMinimap(y)
+}
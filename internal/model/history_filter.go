@@ -0,0 +1,80 @@
+package model
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/filter"
+)
+
+// openHistoryFilter activates the history filter input overlay
+func (m *Model) openHistoryFilter() {
+	m.historyFilterActive = true
+	m.historyFilterPrev = m.historyFilter
+	m.historyFilterInput.SetValue(m.historyFilter.String())
+	m.historyFilterInput.CursorEnd()
+	m.historyFilterInput.Focus()
+}
+
+// handleHistoryFilterKeys handles key events while the history filter
+// overlay is active. The filter is applied live as the expression parses.
+func (m Model) handleHistoryFilterKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		// Cancel, restoring the previous filter
+		m.historyFilterActive = false
+		m.historyFilterInput.Blur()
+		m.historyFilter = m.historyFilterPrev
+		return m, m.selectVisibleChange()
+
+	case "enter":
+		f, err := filter.Parse(m.historyFilterInput.Value())
+		if err != nil {
+			m.addToast(err.Error(), ToastError)
+			return m, nil
+		}
+		m.historyFilterActive = false
+		m.historyFilterInput.Blur()
+
+		if f.IsEmpty() {
+			m.historyFilter = nil
+			m.addToast("Filter cleared", ToastInfo)
+			return m, m.selectVisibleChange()
+		}
+
+		m.historyFilter = f
+		m.addToast("Filter: "+f.String(), ToastInfo)
+		// Pull older matching edits from the daemon
+		return m, tea.Batch(m.selectVisibleChange(), m.queryDaemonHistoryCmd())
+	}
+
+	var cmd tea.Cmd
+	m.historyFilterInput, cmd = m.historyFilterInput.Update(msg)
+	if f, err := filter.Parse(m.historyFilterInput.Value()); err == nil {
+		if f.IsEmpty() {
+			f = nil
+		}
+		m.historyFilter = f
+		cmd = tea.Batch(cmd, m.selectVisibleChange())
+	}
+	return m, cmd
+}
+
+// selectVisibleChange moves the selection to the newest visible change if the
+// current one is hidden by a filter
+func (m *Model) selectVisibleChange() tea.Cmd {
+	visible := m.visibleChangeIndices()
+	for _, idx := range visible {
+		if idx == m.selectedIndex {
+			m.ensureSelectedVisible()
+			return nil
+		}
+	}
+	if len(visible) == 0 {
+		return nil
+	}
+
+	m.selectedIndex = visible[0]
+	m.scrollX = 0
+	m.listScrollOffset = 0
+	m.ensureSelectedVisible()
+	return m.showDiff()
+}
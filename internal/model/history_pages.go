@@ -0,0 +1,98 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// historyPageSize is how many edits each daemon history query loads
+const historyPageSize = 100
+
+// historyPrefetch is how close to the oldest loaded entry the selection
+// gets before the next page of older edits is asked for
+const historyPrefetch = 5
+
+// historyKey identifies a change for deduplicating daemon results against
+// the changes already loaded
+func historyKey(c Change) string {
+	return fmt.Sprintf("%s:%s:%d", c.FilePath, c.Timestamp.Format(time.RFC3339), c.LineNum)
+}
+
+// loadOlderHistory asks the daemon for the page of edits older than those
+// loaded once the selection nears the bottom of the History list. Pages
+// are tracked per filter, since a filtered query pages through matching
+// edits only.
+func (m *Model) loadOlderHistory() tea.Cmd {
+	if m.historyLoading || m.leftPaneMode != LeftPaneModeHistory {
+		return nil
+	}
+	filterExpr := m.historyFilter.String()
+	cursor := m.historyCursors[filterExpr]
+	if cursor == "" {
+		return nil
+	}
+
+	visible := m.visibleChangeIndices()
+	if len(visible) > 0 && m.selectedListPos(visible) < len(visible)-1-historyPrefetch {
+		return nil
+	}
+
+	m.historyLoading = true
+	return m.queryDaemonHistoryPageCmd(cursor)
+}
+
+// addOlderHistory merges a page of older edits into the loaded changes,
+// keeping the selected change selected
+func (m *Model) addOlderHistory(msg daemonHistoryMsg) {
+	m.historyLoading = false
+	if msg.err != nil {
+		logger.Log("Failed to load older edits: %v", msg.err)
+		m.addToast("Couldn't load older edits", ToastWarning)
+		return
+	}
+	if m.historyCursors == nil {
+		m.historyCursors = make(map[string]string)
+	}
+	m.historyCursors[msg.filter] = msg.nextCursor
+
+	existing := make(map[string]bool, len(m.changes))
+	for _, c := range m.changes {
+		existing[historyKey(c)] = true
+	}
+	added := 0
+	for _, c := range msg.changes {
+		if !existing[historyKey(c)] {
+			m.changes = append(m.changes, c)
+			added++
+		}
+	}
+	logger.Log("Loaded %d older changes from daemon, total now: %d", added, len(m.changes))
+	if added > 0 {
+		m.loadSessionPrompts()
+		m.sortChanges(m.selectedIndex)
+	}
+}
+
+// sortChanges restores newest-first order after older changes were merged
+// in, keeping the change at index prev selected
+func (m *Model) sortChanges(prev int) {
+	var selectedAt time.Time
+	var selectedPath string
+	if prev < len(m.changes) {
+		selectedAt, selectedPath = m.changes[prev].Timestamp, m.changes[prev].FilePath
+	}
+	sort.SliceStable(m.changes, func(i, j int) bool {
+		return m.changes[i].Timestamp.After(m.changes[j].Timestamp)
+	})
+	for i, c := range m.changes {
+		if c.Timestamp.Equal(selectedAt) && c.FilePath == selectedPath {
+			m.selectedIndex = i
+			break
+		}
+	}
+	m.diffCache = make(map[int]*diffDoc)
+}
@@ -0,0 +1,250 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// hookEventLimit caps the hook events loaded from the daemon at startup
+const hookEventLimit = 1000
+
+// pendingEditTimeout is how long an edit announced by PreToolUse shows as
+// pending without landing, e.g. because it was denied
+const pendingEditTimeout = 2 * time.Minute
+
+// sessionActivity is what Claude Code's lifecycle hooks told of a session
+type sessionActivity struct {
+	Started   time.Time // SessionStart; zero if the hook didn't fire
+	Source    string    // Why the session last started: "startup", "resume", "clear" or "compact"
+	LastStop  time.Time // When Claude last finished responding
+	Turns     int       // Responses Claude finished (Stop hooks)
+	Subagents int       // Subagents that finished (SubagentStop hooks)
+}
+
+// pendingEdit is an edit a PreToolUse hook announced, shown until its
+// PostToolUse arrives
+type pendingEdit struct {
+	SessionID string
+	ToolName  string
+	FilePath  string
+	Since     time.Time
+	Change    *Change // The edit as announced, previewed in the diff pane; nil without a tool input
+}
+
+// queryHookEventsCmd asks the daemon for this workspace's hook events, to
+// mark where its Claude sessions started and finished their turns
+func (m Model) queryHookEventsCmd() tea.Cmd {
+	return func() tea.Msg {
+		workspacePath, err := m.fs.Getwd()
+		if err != nil {
+			return hookEventsMsg{err: err}
+		}
+
+		var result struct {
+			Hooks []*database.HookEvent `json:"hooks"`
+			Error string                `json:"error,omitempty"`
+		}
+		if err := queryDaemon(map[string]interface{}{
+			"type":           "hooks",
+			"workspace_path": workspacePath,
+			"limit":          hookEventLimit,
+		}, &result); err != nil {
+			return hookEventsMsg{err: err}
+		}
+		if result.Error != "" {
+			return hookEventsMsg{err: fmt.Errorf("daemon: %s", result.Error)}
+		}
+		return hookEventsMsg{events: result.Hooks}
+	}
+}
+
+// applyHookEvents adds the daemon's hook events, oldest first, to the
+// sessions' activity. Past PreToolUse events don't make edits pending.
+func (m *Model) applyHookEvents(events []*database.HookEvent) {
+	for _, e := range events {
+		if e.Type != database.HookPreToolUse {
+			m.recordSessionHook(e.ChatSessionID, e.Type, e.Source, e.Timestamp)
+		}
+	}
+}
+
+// handleHookEvent takes in a payload from one of Claude Code's lifecycle
+// hooks, reporting whether it was one. PostToolUse payloads aren't: they
+// land a pending edit and go on to be parsed as a change.
+func (m *Model) handleHookEvent(data []byte) bool {
+	var payload HookPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return false
+	}
+	filePath := payload.ToolInput.FilePath
+	if filePath == "" {
+		filePath = payload.ToolInput.Path
+	}
+	now := m.clock.Now()
+	before := m.incomingEdit()
+
+	switch payload.HookEventName {
+	case database.HookPreToolUse:
+		if filePath != "" {
+			// Hooks that don't pass the tool input get no preview
+			change := m.parsePayload(data)
+			if change != nil && change.OldString == "" && change.NewString == "" {
+				change = nil
+			}
+			m.clearPendingEdit(payload.SessionID, filePath)
+			m.pendingEdits = append(m.pendingEdits, pendingEdit{
+				SessionID: payload.SessionID,
+				ToolName:  payload.ToolName,
+				FilePath:  filePath,
+				Since:     now,
+				Change:    change,
+			})
+			logger.Log("Claude is about to %s %s", payload.ToolName, filePath)
+			m.refreshIncoming(before)
+		}
+		return true
+
+	case database.HookSessionStart, database.HookStop, database.HookSubagentStop:
+		if payload.SessionID == "" {
+			return true
+		}
+		m.recordSessionHook(payload.SessionID, payload.HookEventName, payload.Source, now)
+		if payload.HookEventName == database.HookStop {
+			// Whatever Claude announced and didn't do, it won't now
+			m.clearPendingEdit(payload.SessionID, "")
+		}
+		m.diffCache = make(map[int]*diffDoc)
+		m.refreshIncoming(before)
+		return true
+	}
+
+	if filePath != "" {
+		m.clearPendingEdit(payload.SessionID, filePath)
+	}
+	return false
+}
+
+// recordSessionHook adds a lifecycle hook to a session's activity
+func (m *Model) recordSessionHook(sid, hook, source string, at time.Time) {
+	if sid == "" {
+		return
+	}
+	if m.sessionActivity == nil {
+		m.sessionActivity = make(map[string]*sessionActivity)
+	}
+	a := m.sessionActivity[sid]
+	if a == nil {
+		a = &sessionActivity{}
+		m.sessionActivity[sid] = a
+	}
+
+	switch hook {
+	case database.HookSessionStart:
+		// A resumed session keeps the start it was first seen with
+		if a.Started.IsZero() {
+			a.Started = at
+		}
+		a.Source = source
+	case database.HookStop:
+		a.LastStop = at
+		a.Turns++
+	case database.HookSubagentStop:
+		a.Subagents++
+	}
+}
+
+// clearPendingEdit drops the pending edits of a session to a file, or all
+// of the session's if filePath is empty. Edits announced without a session
+// match any.
+func (m *Model) clearPendingEdit(sid, filePath string) {
+	var kept []pendingEdit
+	for _, p := range m.pendingEdits {
+		sameSession := p.SessionID == sid || p.SessionID == "" || sid == ""
+		if sameSession && (filePath == "" || p.FilePath == filePath) {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	m.pendingEdits = kept
+}
+
+// currentPendingEdits returns the edits announced in the last
+// pendingEditTimeout that haven't landed, oldest first
+func (m Model) currentPendingEdits() []pendingEdit {
+	now := m.clock.Now()
+	var pending []pendingEdit
+	for _, p := range m.pendingEdits {
+		if now.Sub(p.Since) < pendingEditTimeout {
+			pending = append(pending, p)
+		}
+	}
+	return pending
+}
+
+// statusPending shows the file Claude is about to edit, between its
+// PreToolUse and PostToolUse hooks
+func (m Model) statusPending() string {
+	pending := m.currentPendingEdits()
+	if len(pending) == 0 {
+		return ""
+	}
+	label := "✎ " + filepath.Base(pending[len(pending)-1].FilePath)
+	if len(pending) > 1 {
+		label += fmt.Sprintf(" +%d", len(pending)-1)
+	}
+	return m.theme.Modified.Render(label)
+}
+
+// sessionBounds returns when a session started and last did something: its
+// SessionStart and last Stop hooks where they're known, otherwise its
+// oldest and newest changes among indices (newest first)
+func (m Model) sessionBounds(sid string, indices []int) (start, end time.Time) {
+	if len(indices) > 0 {
+		start, end = m.changes[indices[len(indices)-1]].Timestamp, m.changes[indices[0]].Timestamp
+	}
+	if a := m.sessionActivity[sid]; a != nil {
+		if !a.Started.IsZero() && (start.IsZero() || a.Started.Before(start)) {
+			start = a.Started
+		}
+		if a.LastStop.After(end) {
+			end = a.LastStop
+		}
+	}
+	return start, end
+}
+
+// sessionActivityLine describes a session's lifecycle for the session
+// diff, e.g. "Started 09:05 (last resume) · 3 turns, last 11:40 ·
+// 1 subagent", or "" if no lifecycle hook fired for it
+func (m Model) sessionActivityLine(sid string) string {
+	a := m.sessionActivity[sid]
+	if a == nil {
+		return ""
+	}
+	var parts []string
+	if !a.Started.IsZero() {
+		started := "Started " + a.Started.Format("15:04")
+		if a.Source != "" && a.Source != "startup" {
+			started += " (last " + a.Source + ")"
+		}
+		parts = append(parts, started)
+	}
+	if a.Turns == 1 {
+		parts = append(parts, "1 turn, at "+a.LastStop.Format("15:04"))
+	} else if a.Turns > 1 {
+		parts = append(parts, fmt.Sprintf("%d turns, last %s", a.Turns, a.LastStop.Format("15:04")))
+	}
+	if a.Subagents == 1 {
+		parts = append(parts, "1 subagent")
+	} else if a.Subagents > 1 {
+		parts = append(parts, fmt.Sprintf("%d subagents", a.Subagents))
+	}
+	return strings.Join(parts, " · ")
+}
@@ -0,0 +1,117 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/auth"
+	"github.com/ztaylor/claude-mon/internal/hookcheck"
+)
+
+// checkHookHealthCmd checks the hook pipeline for this workspace, asking the
+// daemon when it last recorded an edit here
+func (m Model) checkHookHealthCmd() tea.Cmd {
+	return func() tea.Msg {
+		home, _ := os.UserHomeDir()
+		cwd, _ := m.fs.Getwd()
+		executable, _ := os.Executable()
+
+		opts := hookcheck.Options{
+			Home:       home,
+			ProjectDir: cwd,
+			Executable: executable,
+			Now:        m.clock.Now(),
+		}
+		opts.DaemonRunning, opts.LastFired = queryLastEdit(cwd)
+
+		return hookHealthMsg{report: hookcheck.Run(opts)}
+	}
+}
+
+// queryLastEdit asks the daemon for the workspace's latest session activity,
+// reporting whether the daemon answered
+func queryLastEdit(workspacePath string) (bool, time.Time) {
+	conn, err := net.DialTimeout("unix", "/tmp/claude-mon-query.sock", 1*time.Second)
+	if err != nil {
+		return false, time.Time{}
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	query := map[string]interface{}{
+		"type":           "sessions",
+		"workspace_path": workspacePath,
+		"limit":          1,
+		"token":          auth.FromEnv(),
+	}
+	if err := json.NewEncoder(conn).Encode(query); err != nil {
+		return false, time.Time{}
+	}
+
+	var result struct {
+		Sessions []struct {
+			LastActivity time.Time
+		} `json:"sessions"`
+		Error string `json:"error,omitempty"`
+	}
+	if err := json.NewDecoder(conn).Decode(&result); err != nil || result.Error != "" {
+		return false, time.Time{}
+	}
+	if len(result.Sessions) == 0 {
+		return true, time.Time{}
+	}
+	return true, result.Sessions[0].LastActivity
+}
+
+// openHookHealth shows the hook health panel and starts a fresh check
+func (m *Model) openHookHealth() tea.Cmd {
+	m.showHookHealth = true
+	m.hookHealth = nil
+	return m.checkHookHealthCmd()
+}
+
+// renderHookHealth renders the hook pipeline checks, one stage per line,
+// and calls out the stage to fix first
+func (m Model) renderHookHealth() string {
+	var sb strings.Builder
+	sb.WriteString("\n  " + m.theme.Title.Render("Hook health") + "\n\n")
+
+	if m.hookHealth == nil {
+		sb.WriteString("  " + m.theme.Dim.Render("Checking...") + "\n")
+		return sb.String()
+	}
+
+	broken := m.hookHealth.Broken()
+	for _, c := range m.hookHealth.Checks {
+		var mark string
+		switch c.Status {
+		case hookcheck.StatusOK:
+			mark = m.theme.Added.Render("✓")
+		case hookcheck.StatusWarn:
+			mark = m.theme.Modified.Render("!")
+		case hookcheck.StatusFail:
+			mark = m.theme.Removed.Render("✗")
+		}
+		line := "  " + mark + " " + fmt.Sprintf("%-20s", c.Stage) + m.theme.Dim.Render(c.Detail)
+		if broken != nil && c.Stage == broken.Stage {
+			line += "  " + m.theme.Removed.Render("← start here")
+		}
+		sb.WriteString(line + "\n")
+	}
+
+	if len(m.hookHealth.Hooks) > 0 {
+		sb.WriteString("\n  " + m.theme.Dim.Render("PostToolUse hooks:") + "\n")
+		for _, h := range m.hookHealth.Hooks {
+			sb.WriteString("    " + h.Command + "\n")
+		}
+	}
+
+	sb.WriteString("\n  " + m.theme.Dim.Render("See HOOKS.md for setup. r: re-check, any other key: close") + "\n")
+	return sb.String()
+}
@@ -0,0 +1,173 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/auth"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// Incident is a notable event from the daemon timeline (Ralph start/stop,
+// huge edits, failures, pauses, config reloads)
+type Incident struct {
+	Timestamp time.Time `json:"timestamp"`
+	Kind      string    `json:"kind"`
+	Severity  string    `json:"severity"`
+	Message   string    `json:"message"`
+}
+
+// queryDaemonEventsCmd queries the daemon timeline for the current workspace
+func (m Model) queryDaemonEventsCmd() tea.Cmd {
+	return func() tea.Msg {
+		workspacePath, err := m.fs.Getwd()
+		if err != nil {
+			return daemonEventsMsg{err: err}
+		}
+
+		conn, err := net.DialTimeout("unix", "/tmp/claude-mon-query.sock", 1*time.Second)
+		if err != nil {
+			return daemonEventsMsg{err: err}
+		}
+		defer conn.Close()
+
+		conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+		query := map[string]interface{}{
+			"type":           "events",
+			"workspace_path": workspacePath,
+			"limit":          200,
+			"token":          auth.FromEnv(),
+		}
+		if err := json.NewEncoder(conn).Encode(query); err != nil {
+			return daemonEventsMsg{err: err}
+		}
+
+		var result struct {
+			Events []Incident `json:"events"`
+			Error  string     `json:"error,omitempty"`
+		}
+		if err := json.NewDecoder(conn).Decode(&result); err != nil {
+			return daemonEventsMsg{err: err}
+		}
+		if result.Error != "" {
+			return daemonEventsMsg{err: fmt.Errorf("daemon: %s", result.Error)}
+		}
+
+		return daemonEventsMsg{events: result.Events}
+	}
+}
+
+// reportDaemonEvent sends a timeline event to the daemon in the background.
+// Delivery is best-effort; the daemon may not be running.
+func (m Model) reportDaemonEvent(kind, severity, message string) {
+	m.sendDaemonPayload(map[string]interface{}{
+		"type":       "event",
+		"event_kind": kind,
+		"severity":   severity,
+		"message":    message,
+	})
+}
+
+// sendDaemonPayload sends a payload for the current workspace to the daemon
+// data socket in the background
+func (m Model) sendDaemonPayload(payload map[string]interface{}) {
+	fsys, socket := m.fs, m.daemonSocket
+	go deliverDaemonPayload(fsys, socket, payload)
+}
+
+// deliverDaemonPayload sends a payload for the current workspace to the
+// daemon data socket, waiting for the acknowledgement
+func deliverDaemonPayload(fsys FS, socket string, payload map[string]interface{}) {
+	workspacePath, err := fsys.Getwd()
+	if err != nil {
+		return
+	}
+	payload["workspace"] = workspacePath
+	payload["token"] = auth.FromEnv()
+
+	conn, err := net.DialTimeout("unix", socket, 1*time.Second)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	if err := json.NewEncoder(conn).Encode(payload); err != nil {
+		logger.Log("Failed to send %v payload to daemon: %v", payload["type"], err)
+		return
+	}
+
+	// Wait for the acknowledgement so the daemon processes before we close
+	var ack map[string]string
+	if err := json.NewDecoder(conn).Decode(&ack); err == nil && ack["error"] != "" {
+		logger.Log("Daemon rejected %v payload: %s", payload["type"], ack["error"])
+	}
+}
+
+// visibleIncidents returns incidents within the time range of the visible
+// history window, newest first. When the newest change is visible the range
+// extends to the present.
+func (m Model) visibleIncidents() []Incident {
+	if len(m.incidents) == 0 || len(m.changes) == 0 {
+		return nil
+	}
+
+	indices := m.visibleChangeIndices()
+	startIdx := m.listScrollOffset
+	endIdx := startIdx + m.historyItemsFitting(indices, startIdx)
+	if endIdx > len(indices) {
+		endIdx = len(indices)
+	}
+	if startIdx >= endIdx {
+		return nil
+	}
+
+	newest := m.changes[indices[startIdx]].Timestamp
+	if startIdx == 0 {
+		newest = m.clock.Now()
+	}
+	oldest := m.changes[indices[endIdx-1]].Timestamp
+
+	var visible []Incident
+	for _, inc := range m.incidents {
+		if !inc.Timestamp.Before(oldest) && !inc.Timestamp.After(newest) {
+			visible = append(visible, inc)
+		}
+	}
+	return visible
+}
+
+// renderIncidentRibbon renders a one-line summary of incidents in the visible
+// history range, or an empty string if there are none
+func (m Model) renderIncidentRibbon(width int) string {
+	incidents := m.visibleIncidents()
+	if len(incidents) == 0 {
+		return ""
+	}
+
+	style := m.theme.Modified
+	for _, inc := range incidents {
+		if inc.Severity == "error" {
+			style = m.theme.Removed
+			break
+		}
+	}
+
+	parts := make([]string, 0, len(incidents))
+	for _, inc := range incidents {
+		parts = append(parts, fmt.Sprintf("%s %s",
+			inc.Timestamp.Format("15:04"), strings.ReplaceAll(inc.Kind, "_", " ")))
+	}
+
+	line := fmt.Sprintf("⚑ %d: %s", len(incidents), strings.Join(parts, " · "))
+	if width > 4 && len([]rune(line)) > width {
+		line = string([]rune(line)[:width-3]) + "..."
+	}
+	return style.Render(line)
+}
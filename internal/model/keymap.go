@@ -18,6 +18,8 @@ type KeyMap struct {
 	RightPane      key.Binding
 	ToggleMinimap  key.Binding
 	ToggleLeftPane key.Binding
+	ShrinkLeftPane key.Binding
+	GrowLeftPane   key.Binding
 
 	// Navigation
 	Up       key.Binding
@@ -28,11 +30,29 @@ type KeyMap struct {
 	Prev     key.Binding
 
 	// History mode
-	ClearHistory key.Binding
-	OpenInNvim   key.Binding
-	OpenNvimCwd  key.Binding
-	ScrollLeft   key.Binding
-	ScrollRight  key.Binding
+	ClearHistory      key.Binding
+	OpenInNvim        key.Binding
+	OpenNvimCwd       key.Binding
+	ScrollLeft        key.Binding
+	ScrollRight       key.Binding
+	Bookmark          key.Binding
+	BookmarksOnly     key.Binding
+	ToggleDensity     key.Binding
+	FilterHistory     key.Binding
+	ToggleWorkingTree key.Binding
+	ToggleBlame       key.Binding
+	ToggleSquash      key.Binding
+	ExpandSquash      key.Binding
+	ToggleNormalize   key.Binding
+	ToggleContext     key.Binding
+	ToggleStructural  key.Binding
+	ToggleSemantic    key.Binding
+	ToggleChecks      key.Binding
+	FileHistory       key.Binding
+	ToggleSession     key.Binding
+	NextSession       key.Binding
+	PrevSession       key.Binding
+	JumpRegion        key.Binding
 
 	// Prompts mode
 	NewPrompt       key.Binding
@@ -69,6 +89,8 @@ func NewKeyMap() KeyMap {
 		RightPane:      key.NewBinding(key.WithKeys("]"), key.WithHelp("]", "right pane")),
 		ToggleMinimap:  key.NewBinding(key.WithKeys("m"), key.WithHelp("m", "minimap")),
 		ToggleLeftPane: key.NewBinding(key.WithKeys("h"), key.WithHelp("h", "toggle left")),
+		ShrinkLeftPane: key.NewBinding(key.WithKeys("<"), key.WithHelp("<", "shrink left")),
+		GrowLeftPane:   key.NewBinding(key.WithKeys(">"), key.WithHelp(">", "grow left")),
 
 		// Navigation
 		Up:       key.NewBinding(key.WithKeys("k", "up"), key.WithHelp("k/↑", "up")),
@@ -79,11 +101,29 @@ func NewKeyMap() KeyMap {
 		Prev:     key.NewBinding(key.WithKeys("p"), key.WithHelp("p", "prev")),
 
 		// History mode
-		ClearHistory: key.NewBinding(key.WithKeys("C"), key.WithHelp("C", "clear history")),
-		OpenInNvim:   key.NewBinding(key.WithKeys("ctrl+n"), key.WithHelp("C-n", "open in nvim")),
-		OpenNvimCwd:  key.NewBinding(key.WithKeys("ctrl+o"), key.WithHelp("C-o", "nvim cwd")),
-		ScrollLeft:   key.NewBinding(key.WithKeys("left"), key.WithHelp("←", "scroll left")),
-		ScrollRight:  key.NewBinding(key.WithKeys("right"), key.WithHelp("→", "scroll right")),
+		ClearHistory:      key.NewBinding(key.WithKeys("C"), key.WithHelp("C", "clear history")),
+		OpenInNvim:        key.NewBinding(key.WithKeys("ctrl+n"), key.WithHelp("C-n", "open in nvim")),
+		OpenNvimCwd:       key.NewBinding(key.WithKeys("ctrl+o"), key.WithHelp("C-o", "nvim cwd")),
+		ScrollLeft:        key.NewBinding(key.WithKeys("left"), key.WithHelp("←", "scroll left")),
+		ScrollRight:       key.NewBinding(key.WithKeys("right"), key.WithHelp("→", "scroll right")),
+		Bookmark:          key.NewBinding(key.WithKeys("b"), key.WithHelp("b", "bookmark")),
+		BookmarksOnly:     key.NewBinding(key.WithKeys("B"), key.WithHelp("B", "bookmarks only")),
+		ToggleDensity:     key.NewBinding(key.WithKeys("D"), key.WithHelp("D", "density")),
+		FilterHistory:     key.NewBinding(key.WithKeys("f"), key.WithHelp("f", "filter")),
+		ToggleWorkingTree: key.NewBinding(key.WithKeys("w"), key.WithHelp("w", "vs working tree")),
+		ToggleBlame:       key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "blame")),
+		ToggleSquash:      key.NewBinding(key.WithKeys("s"), key.WithHelp("s", "squash")),
+		ExpandSquash:      key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "expand")),
+		ToggleNormalize:   key.NewBinding(key.WithKeys("W"), key.WithHelp("W", "normalize")),
+		ToggleContext:     key.NewBinding(key.WithKeys("z"), key.WithHelp("z", "whole file")),
+		ToggleStructural:  key.NewBinding(key.WithKeys("P"), key.WithHelp("P", "structural")),
+		ToggleSemantic:    key.NewBinding(key.WithKeys("S"), key.WithHelp("S", "semantic")),
+		ToggleChecks:      key.NewBinding(key.WithKeys("c"), key.WithHelp("c", "checks")),
+		FileHistory:       key.NewBinding(key.WithKeys("H"), key.WithHelp("H", "file history")),
+		ToggleSession:     key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "fold session")),
+		NextSession:       key.NewBinding(key.WithKeys("}"), key.WithHelp("}", "next session")),
+		PrevSession:       key.NewBinding(key.WithKeys("{"), key.WithHelp("{", "prev session")),
+		JumpRegion:        key.NewBinding(key.WithKeys("g"), key.WithHelp("g", "jump to minimap mark")),
 
 		// Prompts mode
 		NewPrompt:       key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "new prompt")),
@@ -138,6 +178,12 @@ func FromConfig(cfg *config.Config) KeyMap {
 	if cfg.Keys.ToggleLeftPane != "" {
 		km.ToggleLeftPane = key.NewBinding(key.WithKeys(cfg.Keys.ToggleLeftPane), key.WithHelp(cfg.Keys.ToggleLeftPane, "toggle left"))
 	}
+	if cfg.Keys.ShrinkLeftPane != "" {
+		km.ShrinkLeftPane = key.NewBinding(key.WithKeys(cfg.Keys.ShrinkLeftPane), key.WithHelp(cfg.Keys.ShrinkLeftPane, "shrink left"))
+	}
+	if cfg.Keys.GrowLeftPane != "" {
+		km.GrowLeftPane = key.NewBinding(key.WithKeys(cfg.Keys.GrowLeftPane), key.WithHelp(cfg.Keys.GrowLeftPane, "grow left"))
+	}
 
 	// Navigation
 	if cfg.Keys.Up != "" {
@@ -175,6 +221,60 @@ func FromConfig(cfg *config.Config) KeyMap {
 	if cfg.Keys.ScrollRight != "" {
 		km.ScrollRight = key.NewBinding(key.WithKeys(cfg.Keys.ScrollRight), key.WithHelp(cfg.Keys.ScrollRight, "scroll right"))
 	}
+	if cfg.Keys.Bookmark != "" {
+		km.Bookmark = key.NewBinding(key.WithKeys(cfg.Keys.Bookmark), key.WithHelp(cfg.Keys.Bookmark, "bookmark"))
+	}
+	if cfg.Keys.BookmarksOnly != "" {
+		km.BookmarksOnly = key.NewBinding(key.WithKeys(cfg.Keys.BookmarksOnly), key.WithHelp(cfg.Keys.BookmarksOnly, "bookmarks only"))
+	}
+	if cfg.Keys.ToggleDensity != "" {
+		km.ToggleDensity = key.NewBinding(key.WithKeys(cfg.Keys.ToggleDensity), key.WithHelp(cfg.Keys.ToggleDensity, "density"))
+	}
+	if cfg.Keys.FilterHistory != "" {
+		km.FilterHistory = key.NewBinding(key.WithKeys(cfg.Keys.FilterHistory), key.WithHelp(cfg.Keys.FilterHistory, "filter"))
+	}
+	if cfg.Keys.ToggleWorkingTree != "" {
+		km.ToggleWorkingTree = key.NewBinding(key.WithKeys(cfg.Keys.ToggleWorkingTree), key.WithHelp(cfg.Keys.ToggleWorkingTree, "vs working tree"))
+	}
+	if cfg.Keys.ToggleBlame != "" {
+		km.ToggleBlame = key.NewBinding(key.WithKeys(cfg.Keys.ToggleBlame), key.WithHelp(cfg.Keys.ToggleBlame, "blame"))
+	}
+	if cfg.Keys.ToggleSquash != "" {
+		km.ToggleSquash = key.NewBinding(key.WithKeys(cfg.Keys.ToggleSquash), key.WithHelp(cfg.Keys.ToggleSquash, "squash"))
+	}
+	if cfg.Keys.ExpandSquash != "" {
+		km.ExpandSquash = key.NewBinding(key.WithKeys(cfg.Keys.ExpandSquash), key.WithHelp(cfg.Keys.ExpandSquash, "expand"))
+	}
+	if cfg.Keys.ToggleNormalize != "" {
+		km.ToggleNormalize = key.NewBinding(key.WithKeys(cfg.Keys.ToggleNormalize), key.WithHelp(cfg.Keys.ToggleNormalize, "normalize"))
+	}
+	if cfg.Keys.ToggleContext != "" {
+		km.ToggleContext = key.NewBinding(key.WithKeys(cfg.Keys.ToggleContext), key.WithHelp(cfg.Keys.ToggleContext, "whole file"))
+	}
+	if cfg.Keys.ToggleStructural != "" {
+		km.ToggleStructural = key.NewBinding(key.WithKeys(cfg.Keys.ToggleStructural), key.WithHelp(cfg.Keys.ToggleStructural, "structural"))
+	}
+	if cfg.Keys.ToggleSemantic != "" {
+		km.ToggleSemantic = key.NewBinding(key.WithKeys(cfg.Keys.ToggleSemantic), key.WithHelp(cfg.Keys.ToggleSemantic, "semantic"))
+	}
+	if cfg.Keys.ToggleChecks != "" {
+		km.ToggleChecks = key.NewBinding(key.WithKeys(cfg.Keys.ToggleChecks), key.WithHelp(cfg.Keys.ToggleChecks, "checks"))
+	}
+	if cfg.Keys.FileHistory != "" {
+		km.FileHistory = key.NewBinding(key.WithKeys(cfg.Keys.FileHistory), key.WithHelp(cfg.Keys.FileHistory, "file history"))
+	}
+	if cfg.Keys.ToggleSession != "" {
+		km.ToggleSession = key.NewBinding(key.WithKeys(cfg.Keys.ToggleSession), key.WithHelp(cfg.Keys.ToggleSession, "fold session"))
+	}
+	if cfg.Keys.NextSession != "" {
+		km.NextSession = key.NewBinding(key.WithKeys(cfg.Keys.NextSession), key.WithHelp(cfg.Keys.NextSession, "next session"))
+	}
+	if cfg.Keys.PrevSession != "" {
+		km.PrevSession = key.NewBinding(key.WithKeys(cfg.Keys.PrevSession), key.WithHelp(cfg.Keys.PrevSession, "prev session"))
+	}
+	if cfg.Keys.JumpRegion != "" {
+		km.JumpRegion = key.NewBinding(key.WithKeys(cfg.Keys.JumpRegion), key.WithHelp(cfg.Keys.JumpRegion, "jump to minimap mark"))
+	}
 
 	// Prompts mode
 	if cfg.Keys.NewPrompt != "" {
@@ -236,7 +336,7 @@ func (k KeyMap) ShortHelp() []key.Binding {
 func (k KeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		// Row 1: Global navigation
-		{k.NextTab, k.PrevTab, k.LeftPane, k.RightPane, k.ToggleLeftPane},
+		{k.NextTab, k.PrevTab, k.LeftPane, k.RightPane, k.ToggleLeftPane, k.ShrinkLeftPane, k.GrowLeftPane},
 		// Row 2: Movement
 		{k.Up, k.Down, k.PageUp, k.PageDown, k.Next, k.Prev},
 		// Row 3: Actions
@@ -248,8 +348,10 @@ func (k KeyMap) FullHelp() [][]key.Binding {
 func (k KeyMap) HistoryHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Up, k.Down, k.PageUp, k.PageDown},
-		{k.ScrollLeft, k.ScrollRight, k.OpenInNvim, k.OpenNvimCwd},
+		{k.ScrollLeft, k.ScrollRight, k.OpenInNvim, k.OpenNvimCwd, k.ToggleWorkingTree, k.ToggleBlame, k.ToggleNormalize, k.ToggleContext, k.ToggleStructural, k.ToggleSemantic, k.ToggleChecks, k.FileHistory},
 		{k.ClearHistory, k.Next, k.Prev},
+		{k.FilterHistory, k.Bookmark, k.BookmarksOnly, k.ToggleDensity, k.ToggleSquash, k.ExpandSquash},
+		{k.ToggleSession, k.NextSession, k.PrevSession, k.JumpRegion},
 	}
 }
 
@@ -287,6 +389,13 @@ func (k KeyMap) ContextHelp() [][]key.Binding {
 	}
 }
 
+// ChatHelp returns keybindings relevant to chat mode
+func (k KeyMap) ChatHelp() [][]key.Binding {
+	return [][]key.Binding{
+		{k.Up, k.Down, k.PageUp, k.PageDown},
+	}
+}
+
 // ModeKeyMap wraps KeyMap to provide mode-specific help
 type ModeKeyMap struct {
 	KeyMap
@@ -311,6 +420,8 @@ func (m ModeKeyMap) FullHelp() [][]key.Binding {
 		return m.KeyMap.PlanHelp()
 	case "context":
 		return m.KeyMap.ContextHelp()
+	case "chat":
+		return m.KeyMap.ChatHelp()
 	default:
 		return m.KeyMap.FullHelp()
 	}
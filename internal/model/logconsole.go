@@ -0,0 +1,161 @@
+package model
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// logConsoleInterval is how often the open debug console picks up new entries
+const logConsoleInterval = 500 * time.Millisecond
+
+// logConsoleLevels are the levels l cycles the console's minimum through
+var logConsoleLevels = []slog.Level{slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError}
+
+// openLogConsole shows the debug console, following the TUI's latest log
+// entries
+func (m *Model) openLogConsole() tea.Cmd {
+	m.showLogConsole = true
+	m.logConsoleScroll = 0
+	m.logConsoleOpened = m.clock.Now()
+	return logConsoleTickCmd(m.logConsoleOpened)
+}
+
+// logConsoleTickCmd re-renders the console opened at opened after
+// logConsoleInterval
+func logConsoleTickCmd(opened time.Time) tea.Cmd {
+	return tea.Tick(logConsoleInterval, func(time.Time) tea.Msg {
+		return logConsoleTickMsg{opened: opened}
+	})
+}
+
+// handleLogConsoleKey filters and scrolls the console. / starts a search,
+// enter keeps it and esc drops it; otherwise esc or q closes the console.
+func (m *Model) handleLogConsoleKey(msg tea.KeyMsg) {
+	key := msg.String()
+	if m.logConsoleSearching {
+		switch key {
+		case "enter":
+			m.logConsoleSearching = false
+		case "esc":
+			m.logConsoleSearching = false
+			m.logConsoleSearch = ""
+		case "backspace":
+			if s := []rune(m.logConsoleSearch); len(s) > 0 {
+				m.logConsoleSearch = string(s[:len(s)-1])
+			}
+		default:
+			if msg.Type == tea.KeyRunes || msg.Type == tea.KeySpace {
+				m.logConsoleSearch += string(msg.Runes)
+			}
+		}
+		m.logConsoleScroll = 0
+		return
+	}
+
+	switch key {
+	case "esc", "q":
+		m.showLogConsole = false
+	case "/":
+		m.logConsoleSearching = true
+	case "l":
+		for i, lvl := range logConsoleLevels {
+			if lvl == m.logConsoleLevel {
+				m.logConsoleLevel = logConsoleLevels[(i+1)%len(logConsoleLevels)]
+				break
+			}
+		}
+		m.logConsoleScroll = 0
+	case "k", "up":
+		m.scrollLogConsole(1)
+	case "j", "down":
+		m.scrollLogConsole(-1)
+	case "ctrl+u", "pgup":
+		m.scrollLogConsole(m.logConsoleRows())
+	case "ctrl+d", "pgdown":
+		m.scrollLogConsole(-m.logConsoleRows())
+	case "G", "end":
+		m.logConsoleScroll = 0
+	}
+}
+
+// scrollLogConsole scrolls back n entries, or forward if n is negative,
+// stopping at the oldest and newest
+func (m *Model) scrollLogConsole(n int) {
+	limit := max(0, len(m.logConsoleEntries())-m.logConsoleRows())
+	m.logConsoleScroll = max(0, min(m.logConsoleScroll+n, limit))
+}
+
+// logConsoleRows is how many entries fit in the console
+func (m Model) logConsoleRows() int {
+	return max(1, m.height-6)
+}
+
+// logConsoleEntries returns the buffered entries passing the console's
+// level and search filters, oldest first
+func (m Model) logConsoleEntries() []logger.Entry {
+	search := strings.ToLower(m.logConsoleSearch)
+	var entries []logger.Entry
+	for _, e := range logger.Recent() {
+		if e.Level < m.logConsoleLevel {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(e.String()), search) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// renderLogConsole renders the latest matching entries, or earlier ones when
+// scrolled back, colored by level
+func (m Model) renderLogConsole() string {
+	var sb strings.Builder
+	title := fmt.Sprintf("Debug console  level ≥ %s", m.logConsoleLevel)
+	if m.logConsoleSearch != "" || m.logConsoleSearching {
+		title += "  /" + m.logConsoleSearch
+		if m.logConsoleSearching {
+			title += "█"
+		}
+	}
+	sb.WriteString("\n  " + m.theme.Title.Render(title) + "\n\n")
+
+	entries := m.logConsoleEntries()
+	rows := m.logConsoleRows()
+	scroll := min(m.logConsoleScroll, max(0, len(entries)-rows))
+	end := len(entries) - scroll
+	start := max(0, end-rows)
+
+	if len(entries) == 0 {
+		sb.WriteString("  " + m.theme.Dim.Render("No log entries match") + "\n")
+	}
+	for _, e := range entries[start:end] {
+		line := e.String()
+		if m.width > 4 && len([]rune(line)) > m.width-4 {
+			line = string([]rune(line)[:m.width-5]) + "…"
+		}
+		style := m.theme.Normal
+		switch {
+		case e.Level >= slog.LevelError:
+			style = m.theme.Removed
+		case e.Level >= slog.LevelWarn:
+			style = m.theme.Modified
+		case e.Level < slog.LevelInfo:
+			style = m.theme.Dim
+		}
+		sb.WriteString("  " + style.Render(line) + "\n")
+	}
+
+	status := "following"
+	if scroll > 0 {
+		status = fmt.Sprintf("%d newer below", scroll)
+	}
+	sb.WriteString("\n  " + m.theme.Dim.Render(fmt.Sprintf(
+		"%s · l: level  /: search  j/k: scroll  G: follow  esc: close", status)) + "\n")
+	return sb.String()
+}
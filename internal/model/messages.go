@@ -1,12 +1,116 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/chat"
+	workingctx "github.com/ztaylor/claude-mon/internal/context"
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/diff"
+	"github.com/ztaylor/claude-mon/internal/hookcheck"
+	"github.com/ztaylor/claude-mon/internal/vcs"
+)
 
 // SocketMsg is sent when data is received from the socket
 type SocketMsg struct {
 	Payload []byte
 }
 
+// PromptsChangedMsg is sent when prompt files change outside the TUI
+type PromptsChangedMsg struct{}
+
+// diffRenderedMsg is sent when a diff document finishes building in the
+// background
+type diffRenderedMsg struct {
+	seq         int      // Render sequence, to drop superseded results
+	index       int      // Change the diff belongs to
+	doc         *diffDoc // Diff document
+	fileContent string   // File content, possibly fetched from the VCS
+}
+
+// contextCompletionsMsg is sent when a context field's completion
+// candidates finish loading
+type contextCompletionsMsg struct {
+	seq        int      // Load sequence, to drop results for a closed overlay
+	candidates []string // Candidates, or the source's fallback if loading failed
+	err        error    // Why loading failed
+}
+
+// chatOutputMsg is sent when a chat session writes more output
+type chatOutputMsg struct {
+	session *chat.ClaudeChat
+}
+
+// chatDoneMsg is sent when a chat session's Claude process exits
+type chatDoneMsg struct {
+	session *chat.ClaudeChat
+}
+
+// objectiveOutputMsg is sent when the running objective writes more output
+type objectiveOutputMsg struct {
+	session *chat.ClaudeChat
+}
+
+// objectiveDoneMsg is sent when the running objective's Claude process exits
+type objectiveDoneMsg struct {
+	session *chat.ClaudeChat
+}
+
+// chatSessionsMsg is sent when the daemon returns the saved chat sessions
+type chatSessionsMsg struct {
+	sessions []ChatSession
+	err      error
+}
+
+// snapshotMsg is sent when the daemon returns a file's snapshot from
+// before the session's first edit
+type snapshotMsg struct {
+	snapshot *FileSnapshot
+	err      error
+}
+
+// checksMsg is sent when the daemon returns the workspace's post-edit
+// check results
+type checksMsg struct {
+	results []CheckResult
+	err     error
+}
+
+// fileHistoryMsg is sent when the daemon returns every recorded edit to a
+// file, oldest first
+type fileHistoryMsg struct {
+	path  string
+	edits []*database.Edit
+	err   error
+}
+
+// sessionNamesMsg is sent when the daemon returns the names given to the
+// workspace's Claude sessions
+type sessionNamesMsg struct {
+	names map[string]string
+	err   error
+}
+
+// hookEventsMsg is sent when the daemon returns the lifecycle hook events
+// of the workspace's Claude sessions
+type hookEventsMsg struct {
+	events []*database.HookEvent
+	err    error
+}
+
+// chatHistoryMsg is sent when the daemon returns a saved session's transcript
+type chatHistoryMsg struct {
+	session  ChatSession
+	messages []chat.Message
+	tools    []chatToolCall // Edits the daemon linked to the session
+	err      error
+}
+
+// promptSyncedMsg is sent when a prompt library sync finishes
+type promptSyncedMsg struct {
+	err error
+}
+
 // promptEditedMsg is sent when nvim finishes editing a prompt
 type promptEditedMsg struct {
 	path string
@@ -34,6 +138,46 @@ type leaderTimeoutMsg struct {
 	activatedAt time.Time // To verify we're timing out the right activation
 }
 
+// ralphTimelineMsg is sent when the daemon returns the running loop's edits
+type ralphTimelineMsg struct {
+	timeline []ralphIteration
+	err      error
+}
+
+// ralphLoopsMsg is sent when the daemon returns the workspace's ended loops
+type ralphLoopsMsg struct {
+	loops []RalphLoop
+	err   error
+}
+
+// ralphLoopEditsMsg is sent when the daemon returns an ended loop's edits
+type ralphLoopEditsMsg struct {
+	loopID   int64
+	timeline []ralphIteration
+	err      error
+}
+
+// ralphRunOutputMsg is sent when a plan's Ralph loop run has new output
+type ralphRunOutputMsg struct {
+	session *chat.ClaudeChat
+}
+
+// ralphRunDoneMsg is sent when a plan's Ralph loop run's Claude process exits
+type ralphRunDoneMsg struct {
+	session *chat.ClaudeChat
+}
+
+// workspacePlanMsg carries the plan the daemon has for this workspace
+type workspacePlanMsg struct {
+	path string
+	err  error
+}
+
+// contextDetectedMsg carries context read from the environment
+type contextDetectedMsg struct {
+	detected *workingctx.Detected
+}
+
 // ralphRefreshTickMsg is sent to trigger Ralph state refresh
 type ralphRefreshTickMsg struct {
 	time.Time
@@ -52,7 +196,19 @@ type contextLoadedMsg struct{}
 
 // daemonHistoryMsg is sent when daemon query returns recent edits
 type daemonHistoryMsg struct {
+	changes    []Change
+	filtered   bool   // Result of a filtered query (may include older edits)
+	filter     string // Filter expression the query was made with
+	older      bool   // A page of edits older than those loaded
+	nextCursor string // Cursor for the next older page, "" if there's none
+	err        error
+}
+
+// externalEditsMsg is sent when the poll for changes made outside Claude
+// returns
+type externalEditsMsg struct {
 	changes []Change
+	queried time.Time // When the poll was made, where the next starts
 	err     error
 }
 
@@ -63,9 +219,77 @@ type daemonStatusMsg struct {
 	workspaceActive bool
 	workspaceEdits  int
 	lastActivity    time.Time
+	version         string
+}
+
+// hookHealthMsg is sent when the hook pipeline check finishes
+type hookHealthMsg struct {
+	report hookcheck.Report
+}
+
+// vcsStatusMsg is sent when the workspace's branch and working copy state
+// have been looked up
+type vcsStatusMsg struct {
+	status vcs.Status
+	err    error
+}
+
+// logConsoleTickMsg is sent while the debug console is open, to show new
+// log entries
+type logConsoleTickMsg struct {
+	opened time.Time // When the console the tick is for was opened
+}
+
+// driftCheckedMsg is sent when changes have been compared against the working tree
+type driftCheckedMsg struct {
+	statuses map[string]diff.DriftStatus
+}
+
+// daemonEventsMsg is sent when the daemon timeline query completes
+type daemonEventsMsg struct {
+	events []Incident
+	err    error
+}
+
+// promptInjectionsMsg is sent when the daemon prompt injection query completes
+type promptInjectionsMsg struct {
+	injections []PromptInjection
+	err        error
+}
+
+// promptStatsMsg is sent when the daemon prompt usage query completes
+type promptStatsMsg struct {
+	stats []PromptStat
+	err   error
+}
+
+// commitDoneMsg is sent when committing selected changes finishes
+type commitDoneMsg struct {
+	sha   string
+	hunks int
+	err   error
 }
 
 // daemonStatusTickMsg is sent to trigger periodic daemon status checks
 type daemonStatusTickMsg struct {
 	time.Time
 }
+
+// followTickMsg is sent when follow mode's debounce for a change ends; seq
+// identifies the change so a later edit supersedes it
+type followTickMsg struct {
+	seq int
+}
+
+// policyRevertedMsg is sent when the policy's revert of a change finishes;
+// key is the change's driftKey
+type policyRevertedMsg struct {
+	key     string
+	message string
+	err     error
+}
+
+// followErrMsg is sent when follow mode couldn't reach nvim
+type followErrMsg struct {
+	err error
+}
@@ -1,35 +1,47 @@
 package model
 
 import (
+	gocontext "context"
 	"encoding/json"
 	"fmt"
-	"io"
+	"log/slog"
 	"net"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/glamour/styles"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/ztaylor/claude-mon/internal/auth"
+	"github.com/ztaylor/claude-mon/internal/chat"
 	"github.com/ztaylor/claude-mon/internal/config"
 	workingctx "github.com/ztaylor/claude-mon/internal/context"
+	"github.com/ztaylor/claude-mon/internal/daemon"
+	"github.com/ztaylor/claude-mon/internal/database"
 	"github.com/ztaylor/claude-mon/internal/diff"
+	"github.com/ztaylor/claude-mon/internal/filter"
 	"github.com/ztaylor/claude-mon/internal/highlight"
 	"github.com/ztaylor/claude-mon/internal/history"
+	"github.com/ztaylor/claude-mon/internal/hookcheck"
 	"github.com/ztaylor/claude-mon/internal/logger"
 	"github.com/ztaylor/claude-mon/internal/minimap"
+	"github.com/ztaylor/claude-mon/internal/objective"
 	"github.com/ztaylor/claude-mon/internal/plan"
+	"github.com/ztaylor/claude-mon/internal/policy"
 	"github.com/ztaylor/claude-mon/internal/prompt"
 	"github.com/ztaylor/claude-mon/internal/ralph"
+	"github.com/ztaylor/claude-mon/internal/socket"
+	"github.com/ztaylor/claude-mon/internal/symbols"
 	"github.com/ztaylor/claude-mon/internal/theme"
 	"github.com/ztaylor/claude-mon/internal/vcs"
+	"github.com/ztaylor/claude-mon/internal/workspace"
 )
 
 // Change represents a single file change from Claude
@@ -45,6 +57,28 @@ type Change struct {
 	CommitSHA   string // VCS commit SHA at time of change
 	CommitShort string // Short SHA for display
 	VCSType     string // "git" or "jj"
+	EditID      int64  // Daemon edit ID (0 if not loaded from the daemon)
+	Bookmarked  bool   // Flagged for later review
+	Note        string // Review note, e.g. "needs rework"
+	Review      string // database.ReviewApproved, database.ReviewRejected or "" if not reviewed
+
+	TranscriptPath string // Claude session transcript, if the hook provided one
+	SessionID      string // Claude session that made the change, if the hook provided it
+
+	Summary string // Declarations the change touched, e.g. "modified func (Model) Update"
+
+	Hunks []Hunk // Where a MultiEdit's edits after the first, the one shown, apply
+
+	Protected string // Glob of the project policy protecting the file, if the change broke it
+	Reverted  bool   // Undone on disk per the policy
+
+	Truncated bool // The hook's old or new string was cut short on the socket
+}
+
+// Hunk is the lines of the file one of a MultiEdit's edits replaces
+type Hunk struct {
+	LineNum   int // 1-indexed
+	LineCount int
 }
 
 // HookPayload matches the JSON structure from the Claude hook
@@ -57,6 +91,11 @@ type HookPayload struct {
 		OldString string `json:"old_string"`
 		NewString string `json:"new_string"`
 		Content   string `json:"content"`
+		Command   string `json:"command"` // Bash
+		Edits     []struct {
+			OldString string `json:"old_string"`
+			NewString string `json:"new_string"`
+		} `json:"edits"` // MultiEdit
 	} `json:"tool_input"`
 	Parameters struct {
 		FilePath  string `json:"file_path"`
@@ -69,6 +108,14 @@ type HookPayload struct {
 	OldString string `json:"old_string"`
 	NewString string `json:"new_string"`
 	Content   string `json:"content"`
+	// Claude Code session transcript (JSONL), used to recover the prompt behind an edit
+	TranscriptPath string `json:"transcript_path"`
+	// Claude Code session that made the call, to match chat tool activity
+	SessionID string `json:"session_id"`
+	// Hook that sent the payload, e.g. "PostToolUse" or "SessionStart", and
+	// for SessionStart why the session started
+	HookEventName string `json:"hook_event_name"`
+	Source        string `json:"source"`
 }
 
 // Pane represents which pane is active
@@ -106,6 +153,7 @@ const (
 	LeftPaneModeRalph
 	LeftPaneModePlan
 	LeftPaneModeContext
+	LeftPaneModeChat
 )
 
 // PromptFilter defines the scope filter for prompts
@@ -119,31 +167,125 @@ const (
 
 // Model is the Bubbletea model
 type Model struct {
-	socketPath       string
-	socketConnected  bool      // Whether socket is listening
-	lastMsgTime      time.Time // Time of last received message
-	width            int
-	height           int
-	activePane       Pane
-	leftPaneMode     LeftPaneMode // History or Prompts mode
-	changes          []Change
-	selectedIndex    int
-	diffViewport     viewport.Model
-	showHelp         bool
-	showMinimap      bool // Toggle minimap visibility
-	planContent      string
-	planPath         string
-	planViewport     viewport.Model
-	ready            bool
-	theme            *theme.Theme
-	highlighter      *highlight.Highlighter
-	scrollX          int              // Horizontal scroll offset
-	listScrollOffset int              // Vertical scroll offset for history list
-	totalLines       int              // Total lines in current file (for minimap)
-	minimapData      *minimap.Minimap // Cached minimap line types
-	diffCache        map[int]string   // Cached rendered diffs by index
-	historyStore     *history.Store   // Persistent history storage
-	persistHistory   bool             // Whether to save history to file
+	socketPath          string
+	socketConnected     bool      // Whether socket is listening
+	lastMsgTime         time.Time // Time of last received message
+	width               int
+	height              int
+	activePane          Pane
+	leftPaneMode        LeftPaneMode // History or Prompts mode
+	changes             []Change
+	selectedIndex       int
+	diffViewport        viewport.Model
+	showHelp            bool
+	showMinimap         bool              // Toggle minimap visibility
+	minimapDragging     bool              // Whether the left button was pressed on the minimap and is held
+	minimapJumpPending  bool              // Jump key pressed, waiting for the region number
+	showHookHealth      bool              // Hook health panel is open
+	hookHealth          *hookcheck.Report // Last hook pipeline check, nil while checking
+	showLogConsole      bool              // Debug console is open
+	logConsoleLevel     slog.Level        // Lowest level the console shows
+	logConsoleSearch    string            // Text console entries must contain
+	logConsoleSearching bool              // Typing the console search
+	logConsoleScroll    int               // Entries scrolled back from the newest
+	logConsoleOpened    time.Time         // When the console was opened, to stop stale ticks
+	showThemePicker     bool              // Theme picker is open
+	themePickerIndex    int               // Highlighted theme in the picker
+	themePickerOrig     string            // Theme to return to if the picker is cancelled
+	planContent         string
+	planPath            string
+	planViewport        viewport.Model
+	ready               bool
+	theme               *theme.Theme
+	highlighter         *highlight.Highlighter
+	scrollX             int              // Horizontal scroll offset
+	listScrollOffset    int              // Vertical scroll offset for history list
+	totalLines          int              // Total lines in current file (for minimap)
+	minimapData         *minimap.Minimap // Cached minimap line types
+	diffCache           map[int]*diffDoc // Cached diff documents by index
+	diffDoc             *diffDoc         // Diff shown in the right pane, nil for other content
+	diffOffset          int              // First diffDoc row in the viewport
+	diffRendering       bool             // A large diff is rendering in the background
+	diffRenderSeq       int              // Incremented per render to drop stale results
+	diffSpinner         spinner.Model    // Shown in the viewport while a diff renders
+	historyStore        *history.Store   // Persistent history storage
+	persistHistory      bool             // Whether to save history to file
+	bookmarksOnly       bool             // Show only bookmarked changes in the history list
+
+	// Squashed history: consecutive edits to a file collapse into one entry
+	squashHistory  bool            // Whether runs of edits are squashed
+	squashExpanded map[string]bool // Runs expanded to their steps, by squashKey
+
+	// Diffs as recorded, without the configured normalization
+	rawDiffs bool
+
+	// Whole files shown in diffs, whatever [diff] context_lines says
+	fullFile bool
+
+	// JSON and SVG changes diffed pretty-printed
+	structuralDiff bool
+
+	// JSON, YAML and TOML changes diffed by key path, by semanticKey
+	semanticDiff map[string]bool
+
+	// Post-edit check results from the daemon, newest run first; the diff
+	// pane shows the selected change's checks when checksPanel is set
+	checkResults []CheckResult
+	checksSeen   time.Time // Start of the newest run seen, to toast new failures
+	checksPanel  bool
+
+	// History of the selected change's file across sessions, shown in the
+	// diff pane instead of the change while set
+	fileHistory *fileHistory
+
+	// History entries are grouped by the Claude session that made them;
+	// collapsed sessions show as one entry. Names are given in the TUI and
+	// kept by the daemon; prompts are read from the session transcripts.
+	sessionCollapsed map[string]bool
+	sessionNames     map[string]string
+	sessionPrompts   map[string]string // First user prompt, "" if unreadable
+	sessionNameInput textinput.Model
+	sessionNaming    string // Session whose name is being edited
+
+	// What Claude Code's lifecycle hooks told of each session, and the edits
+	// announced by PreToolUse hooks that haven't landed yet
+	sessionActivity map[string]*sessionActivity
+	pendingEdits    []pendingEdit
+
+	// Review note on the selected change, being written
+	noteInput  textinput.Model
+	noteActive bool
+
+	reviewing bool // Triaging changes: a approves, r rejects, s skips
+
+	// Daemon history is loaded a page at a time; older pages are asked for
+	// as the selection nears the bottom of the list. Cursors are kept per
+	// filter expression ("" when unfiltered), "" once all edits are loaded.
+	historyCursors map[string]string
+	historyLoading bool // An older page is being loaded
+
+	// File snapshot awaiting restore confirmation
+	snapshotRestorePending *FileSnapshot
+
+	// Edits held on the gate socket for approval, oldest first
+	gateQueue []gateRequest
+	gateSeq   int
+
+	// History filter (path:<glob> lang:<names> tool:<name> since:<when> until:<when>)
+	historyFilter       *filter.Filter  // Applied filter (nil = show all)
+	historyFilterPrev   *filter.Filter  // Filter to restore if the overlay is cancelled
+	historyFilterActive bool            // Whether the filter input overlay is active
+	historyFilterInput  textinput.Model // Filter expression input
+
+	// Working tree comparison (diff pane toggle)
+	workingTreeDiff bool                        // Compare changes against current file content
+	showBlame       bool                        // Show the git blame gutter in the diff
+	driftStatus     map[string]diff.DriftStatus // Drift status by driftKey
+
+	// Commit from selected changes
+	commitInputActive bool            // Whether the commit message input is active
+	commitInput       textinput.Model // Commit message input
+	commitChanges     []Change        // Changes to stage and commit
 
 	// Prompt manager (integrated in left pane)
 	promptStore         *prompt.Store          // Prompt storage
@@ -151,24 +293,110 @@ type Model struct {
 	promptFilteredList  []prompt.Prompt        // Filtered list based on scope
 	promptSelected      int                    // Selected prompt index
 	promptFilter        PromptFilter           // Current filter scope (all/project/global)
+	promptSort          PromptSort             // Current list order
+	promptTagFilter     map[string]bool        // Tags a prompt must carry to be listed
+	showPromptTagPicker bool                   // Tag filter picker is open
+	promptTagIndex      int                    // Selected tag in the picker
 	promptFuzzyActive   bool                   // Whether fuzzy filter overlay is active
 	promptFuzzyInput    textinput.Model        // Fuzzy search input
 	promptFuzzyMatches  []int                  // Indices of matching prompts
 	promptFuzzySelected int                    // Selected match in fuzzy results
 	promptInjectMethod  prompt.InjectionMethod // Current injection method
+	promptSendPending   *pendingPromptSend     // Prompt awaiting send confirmation
+	promptTmuxTarget    string                 // tmux pane to send to; empty finds the pane running Claude Code
+	chatSession         *chat.ClaudeChat       // Managed Claude session prompts can be written into, if one is attached
+	showTmuxPicker      bool                   // tmux pane picker is open
+	tmuxPanes           []prompt.TmuxPane      // Panes listed in the picker
+	tmuxPaneIndex       int                    // Selected pane in the picker
+	promptVarForm       *promptVarForm         // Prompt awaiting its variable values
+	promptInjections    []PromptInjection      // Recent sends of all prompts, newest first
+	promptStats         map[string]PromptStat  // All-time usage by prompt name
 
 	// Version view mode
 	promptShowVersions    bool                   // Whether showing version list
 	promptVersions        []prompt.PromptVersion // List of versions for selected prompt
 	promptVersionSelected int                    // Selected version index
+	promptVersionDiff     bool                   // Preview a diff instead of the whole version
+	promptVersionBase     int                    // Version marked to diff against; 0 diffs with the current prompt
 
 	// Toast notifications
 	toasts []Toast // Active toast notifications
 
+	// Notification center
+	toastLog           []Toast   // Recent toasts, oldest first
+	showNotifications  bool      // Notification center is open
+	notificationLevel  ToastType // Lowest severity the center lists
+	notificationScroll int       // Toasts scrolled past, newest first
+	doNotDisturb       bool      // Toasts go to the center without showing
+	heldToasts         int       // Toasts held by do not disturb since the center was last opened
+
+	// Follow mode: new changes open in the running nvim
+	following    bool   // Follow mode is on
+	followSeq    int    // Bumped per followed change; only the latest one's tick opens it
+	followTarget Change // Change the pending tick opens
+
+	// Workspace branch shown in the header
+	vcsStatus        *vcs.Status // nil outside a repository or before the first lookup
+	vcsStatusPending bool        // A lookup is running
+
 	// Ralph mode state
 	ralphState      *ralph.State
 	ralphRefreshCmd tea.Cmd // Ticker for auto-refreshing Ralph state
 
+	// Ralph loop controls
+	ralphTimeline    []ralphIteration // Running loop's edits by iteration, from the daemon
+	ralphInput       textinput.Model  // Completion promise being edited
+	ralphInputActive bool
+
+	// Ralph loop history, shown in the Ralph tab
+	ralphHistory      []RalphLoop      // Ended loops, newest first
+	showRalphHistory  bool             // Ralph tab shows the history instead of the running loop
+	ralphHistoryIndex int              // Selected loop
+	ralphHistoryEdits []ralphIteration // Selected loop's edits, once loaded
+
+	// Plan checklist, shown in the Plan tab
+	planTasks      []plan.Task
+	planTaskIndex  int                  // Selected task
+	planTasksPath  string               // Plan the tasks were parsed from
+	planTaskDoneAt map[string]time.Time // When tasks were seen checked off, by text
+	planTrackedAt  time.Time            // When claude-mon started tracking the plan
+
+	// Plan list, for switching between plans in the Plan tab
+	planPinned       string      // Plan chosen for this workspace, kept by the daemon
+	planHookPath     string      // Last plan the hook reported Claude working on
+	showPlanList     bool        // Left pane lists plans instead of the current plan
+	planList         []plan.Info // Plans found, most recent first
+	planListMatches  []int       // planList entries matching the filter, best first
+	planListIndex    int         // Selected entry in planListMatches
+	planListFilter   textinput.Model
+	planListArchived bool // List includes archived plans
+
+	// Ralph loop run started from a plan, shown in the Ralph tab
+	ralphRun       *chat.ClaudeChat // Claude process driving the loop, nil when none
+	ralphRunTitle  string           // Plan the run was started from
+	ralphRunOutput string           // Output of the last run, kept once it exits
+
+	// Chat tab
+	chatPurpose     chat.ContextPurpose // Purpose the next chat session starts with
+	chatInput       textinput.Model     // Message being typed
+	chatInputActive bool                // Whether the message input has focus
+	chatSaved       int                 // Messages of the current session the daemon has
+	showChatPicker  bool                // Saved session picker is open
+	chatSessions    []ChatSession       // Sessions listed in the picker
+	chatPickerIndex int                 // Selected session in the picker
+	chatTools       []chatToolCall      // Tool calls the session made, oldest first
+	chatToolIndex   int                 // Selected tool call in the sidebar
+	chatToolFocus   bool                // Whether j/k/enter act on the sidebar
+
+	// Objective queue, shown in the Chat tab
+	objectives       *objective.Queue // Loaded on first use
+	objectiveSession *chat.ClaudeChat // Claude carrying out the running objective
+	objectiveRunning bool             // Start the next objective when one finishes
+	showObjectives   bool             // Chat tab shows the queue instead of the transcript
+	objectiveIndex   int              // Selected objective
+	objectiveInput   textinput.Model  // New objective being typed
+	objectiveTyping  bool             // Whether the objective input has focus
+
 	// Plan generation
 	planInputActive bool            // Whether plan input is active
 	planInput       textinput.Model // Plan description input
@@ -180,7 +408,7 @@ type Model struct {
 	contextSelected  int                   // Selected context in list view
 	contextShowList  bool                  // Whether to show all contexts list
 	contextEditMode  bool                  // Whether editing context values
-	contextEditField string                // Which context type: k8s, aws, git, env, custom
+	contextEditField string                // Which context type: k8s, aws, git, iac, env, custom
 	contextViewport  viewport.Model
 
 	// Multi-field inputs for context editing
@@ -197,9 +425,16 @@ type Model struct {
 	awsRegionInput  textinput.Model // AWS region
 	awsFocusedField int             // 0=profile, 1=region
 
+	iacWorkspaceInput textinput.Model // Terraform workspace or Pulumi stack
+	iacToolInput      textinput.Model // terraform or pulumi
+	iacDirInput       textinput.Model // Terraform/Pulumi project dir, relative to the project root
+	iacFocusedField   int             // 0=workspace, 1=tool, 2=dir
+
 	envInput    textinput.Model // KEY=VALUE for env
 	customInput textinput.Model // KEY=VALUE for custom
 
+	contextEditSecret *workingctx.Secret // Env/custom value being saved as a secret, nil for plain
+
 	// Context completion (in-app fuzzy search)
 	contextCompletionActive     bool            // Whether completion overlay is showing
 	contextCompletionInput      textinput.Model // Filter input for completion
@@ -207,9 +442,29 @@ type Model struct {
 	contextCompletionMatches    []int           // Indices of matching candidates
 	contextCompletionSelected   int             // Currently selected match index
 
+	contextCompletionLoading bool          // Candidates are loading in the background
+	contextCompletionSpinner spinner.Model // Shown while candidates load
+	contextCompletionSeq     int           // Load sequence, to drop results for a closed overlay
+	contextCompletionErr     string        // Why loading failed, shown under the candidates
+
+	// Context profiles, switched between from a popup in the Context tab
+	showContextProfiles   bool                  // Whether the profile switcher is open
+	contextProfiles       []*workingctx.Profile // Saved profiles, by name
+	contextProfileMatches []int                 // contextProfiles entries matching the filter
+	contextProfileIndex   int                   // Selected entry in contextProfileMatches
+	contextProfileFilter  textinput.Model
+
+	// Context read from the environment, shown for confirmation before saving
+	contextDetected *workingctx.Detected
+
 	// Layout
 	hideLeftPane bool // Toggle left pane visibility
 
+	leftPanePercent int    // List pane's share of the width, 0 for a third
+	statePath       string // UI state file restored and saved per workspace, empty for none
+	uiState         *config.UIState
+	restoredChange  string // History key of the change to select once it loads
+
 	// Leader key / which-key state
 	leaderActive      bool      // Whether leader popup is showing
 	leaderActivatedAt time.Time // When leader mode was activated (for timeout)
@@ -228,6 +483,43 @@ type Model struct {
 	daemonWorkspaceActive bool      // Whether current workspace has activity
 	daemonWorkspaceEdits  int       // Edit count for current workspace
 	daemonLastActivity    time.Time // Last activity time for current workspace
+	daemonVersion         string    // claude-mon version the daemon reports
+
+	// Warnings the user has snoozed or turned off
+	warnings *config.Warnings
+
+	// Daemon incident timeline (shown as a ribbon above the history list)
+	incidents []Incident
+
+	// Alerts from the daemon's [alerts] rules, bannered until dismissed,
+	// newest first
+	alerts            []Incident
+	alertsSeen        time.Time // Newest alert raised
+	alertCheckPending bool      // A timeline read is due after an edit
+
+	// The workspace's policy file, read again when it changes
+	policy        *policy.Policy
+	policyPath    string
+	policyModTime time.Time
+
+	// Changes the daemon's file watcher records aren't sent to the TUI, so
+	// they're polled for, from when the last poll was made
+	externalSince time.Time
+
+	startedAt time.Time // When this TUI session started
+	version   string    // claude-mon version of this binary
+
+	// Workspace tracked/ignored rules from daemon.toml; nil records everything
+	workspaceRules *workspace.Rules
+
+	// Clock, filesystem and command runner the model works through,
+	// replaced by tests and remote modes
+	clock  Clock
+	fs     FS
+	runner Runner
+
+	// daemonSocket is the daemon data socket events and notes are sent to
+	daemonSocket string
 }
 
 // Option is a functional option for configuring the Model
@@ -247,6 +539,48 @@ func WithPersistence(enabled bool) Option {
 	}
 }
 
+// WithVersion sets the claude-mon version, compared against the daemon's
+func WithVersion(version string) Option {
+	return func(m *Model) {
+		m.version = version
+	}
+}
+
+// WithWorkspaceRules drops changes to workspaces and files the rules ignore
+func WithWorkspaceRules(rules workspace.Rules) Option {
+	return func(m *Model) {
+		m.workspaceRules = &rules
+	}
+}
+
+// WithClock sets the clock used for timestamps and ages
+func WithClock(clock Clock) Option {
+	return func(m *Model) {
+		m.clock = clock
+	}
+}
+
+// WithFS sets the filesystem the workspace and edited files are read from
+func WithFS(fsys FS) Option {
+	return func(m *Model) {
+		m.fs = fsys
+	}
+}
+
+// WithRunner sets how external commands are run
+func WithRunner(runner Runner) Option {
+	return func(m *Model) {
+		m.runner = runner
+	}
+}
+
+// WithDaemonSocket sets the daemon data socket events are sent to
+func WithDaemonSocket(path string) Option {
+	return func(m *Model) {
+		m.daemonSocket = path
+	}
+}
+
 // WithConfig sets a custom configuration for the model
 func WithConfig(cfg *config.Config) Option {
 	return func(m *Model) {
@@ -264,7 +598,7 @@ func New(socketPath string, opts ...Option) Model {
 	}
 
 	// Get theme from config
-	t := theme.Get(cfg.Theme)
+	t := theme.Get(theme.Resolve(cfg.Theme))
 	if t == nil {
 		t = theme.Default()
 	}
@@ -278,10 +612,15 @@ func New(socketPath string, opts ...Option) Model {
 		showMinimap:     true,
 		theme:           t,
 		highlighter:     highlight.NewHighlighter(t),
-		diffCache:       make(map[int]string),
+		diffCache:       make(map[int]*diffDoc),
+		historyCursors:  make(map[string]string),
 		config:          cfg,
 		keyMap:          FromConfig(cfg),
 		help:            help.New(),
+		clock:           systemClock{},
+		fs:              osFS{},
+		runner:          execRunner{},
+		daemonSocket:    daemon.DefaultSocketPath,
 	}
 
 	for _, opt := range opts {
@@ -291,7 +630,7 @@ func New(socketPath string, opts ...Option) Model {
 	// If config was changed via option, update theme and keymap to match
 	if m.config != cfg {
 		cfg = m.config
-		t = theme.Get(cfg.Theme)
+		t = theme.Get(theme.Resolve(cfg.Theme))
 		if t == nil {
 			t = theme.Default()
 		}
@@ -304,11 +643,24 @@ func New(socketPath string, opts ...Option) Model {
 	if m.highlighter == nil || m.highlighter.Theme() != m.theme {
 		m.highlighter = highlight.NewHighlighter(m.theme)
 	}
+	m.startedAt = m.clock.Now()
+	m.externalSince = m.startedAt
+	m.doNotDisturb = m.config.Notifications.DoNotDisturb
+	m.following = m.config.Follow.Enabled
+
+	// Load snoozed and dismissed warnings
+	warnings, err := config.LoadWarnings(config.WarningsPath())
+	if err != nil {
+		logger.Log("Failed to load warning state: %v", err)
+	}
+	m.warnings = warnings
+	m.diffSpinner = spinner.New(spinner.WithSpinner(spinner.Dot), spinner.WithStyle(m.theme.Title))
 
 	// Initialize prompt store
-	if store, err := prompt.NewStore(); err == nil {
+	if store, err := daemon.NewPromptStore(m.config.PromptStore); err == nil {
 		m.promptStore = store
 		m.promptInjectMethod = prompt.DetectBestMethod()
+		m.promptTmuxTarget = m.config.TmuxTarget
 	} else {
 		logger.Log("Failed to initialize prompt store: %v", err)
 	}
@@ -332,9 +684,15 @@ func New(socketPath string, opts ...Option) Model {
 					CommitSHA:   entry.CommitSHA,
 					CommitShort: entry.CommitShort,
 					VCSType:     entry.VCSType,
+					Bookmarked:  entry.Bookmarked,
+					Note:        entry.Note,
+					Review:      entry.Review,
+					Summary:     entry.Summary,
+					SessionID:   entry.SessionID,
 				})
 			}
 			logger.Log("Loaded %d history entries", len(m.changes))
+			m.loadSessionPrompts()
 			// Select most recent (first) item - data sorted newest first
 			if len(m.changes) > 0 {
 				m.selectedIndex = 0
@@ -355,12 +713,74 @@ func New(socketPath string, opts ...Option) Model {
 	ti.Width = 60
 	m.planInput = ti
 
+	// Initialize chat input
+	chatTi := textinput.New()
+	chatTi.Placeholder = "Message Claude..."
+	chatTi.CharLimit = 4000
+	chatTi.Width = 80
+	m.chatInput = chatTi
+	m.chatPurpose = chat.ContextGeneral
+
+	objTi := textinput.New()
+	objTi.Placeholder = "Objective for Claude to carry out..."
+	objTi.CharLimit = 4000
+	objTi.Width = 80
+	m.objectiveInput = objTi
+
+	ralphTi := textinput.New()
+	ralphTi.Placeholder = "Completion promise (empty for none)"
+	ralphTi.CharLimit = 200
+	ralphTi.Width = 60
+	m.ralphInput = ralphTi
+
+	planListTi := textinput.New()
+	planListTi.Placeholder = "Type to filter plans..."
+	planListTi.CharLimit = 100
+	planListTi.Width = 30
+	m.planListFilter = planListTi
+
+	profileTi := textinput.New()
+	profileTi.Placeholder = "Filter, or name a new profile..."
+	profileTi.CharLimit = 64
+	profileTi.Width = 36
+	m.contextProfileFilter = profileTi
+
 	// Initialize fuzzy filter input
 	fuzzyTi := textinput.New()
 	fuzzyTi.Placeholder = "Type to filter..."
 	fuzzyTi.CharLimit = 100
 	fuzzyTi.Width = 40
 	m.promptFuzzyInput = fuzzyTi
+	m.promptTagFilter = make(map[string]bool)
+	m.promptStats = make(map[string]PromptStat)
+
+	// Initialize history filter input
+	historyFilterTi := textinput.New()
+	historyFilterTi.Placeholder = "path:internal/** tool:Write since:1h"
+	historyFilterTi.CharLimit = 200
+	historyFilterTi.Width = 40
+	m.historyFilterInput = historyFilterTi
+
+	// Initialize commit message input
+	commitTi := textinput.New()
+	commitTi.Placeholder = "Commit message"
+	commitTi.CharLimit = 200
+	commitTi.Width = 60
+	m.commitInput = commitTi
+
+	// Initialize session name input
+	sessionTi := textinput.New()
+	sessionTi.Placeholder = "Session name"
+	sessionTi.CharLimit = 80
+	sessionTi.Width = 40
+	m.sessionNameInput = sessionTi
+
+	// Initialize review note input
+	noteTi := textinput.New()
+	noteTi.Placeholder = "Note, e.g. needs rework"
+	noteTi.CharLimit = 200
+	noteTi.Width = 60
+	m.noteInput = noteTi
 
 	// Initialize context
 	if ctx, err := workingctx.Load(); err == nil {
@@ -408,6 +828,22 @@ func New(socketPath string, opts ...Option) Model {
 	m.awsRegionInput.CharLimit = 50
 	m.awsRegionInput.Width = 40
 
+	// Initialize Terraform/Pulumi inputs
+	m.iacWorkspaceInput = textinput.New()
+	m.iacWorkspaceInput.Placeholder = "workspace or stack"
+	m.iacWorkspaceInput.CharLimit = 100
+	m.iacWorkspaceInput.Width = 40
+
+	m.iacToolInput = textinput.New()
+	m.iacToolInput.Placeholder = "terraform or pulumi (auto-detected)"
+	m.iacToolInput.CharLimit = 20
+	m.iacToolInput.Width = 40
+
+	m.iacDirInput = textinput.New()
+	m.iacDirInput.Placeholder = "project root"
+	m.iacDirInput.CharLimit = 200
+	m.iacDirInput.Width = 40
+
 	// Initialize env/custom inputs
 	m.envInput = textinput.New()
 	m.envInput.Placeholder = `KEY="value with spaces"`
@@ -425,11 +861,16 @@ func New(socketPath string, opts ...Option) Model {
 	compTi.CharLimit = 100
 	compTi.Width = 40
 	m.contextCompletionInput = compTi
+	m.contextCompletionSpinner = spinner.New(spinner.WithSpinner(spinner.Dot), spinner.WithStyle(m.theme.Title))
 
 	// Initialize context viewport
 	m.contextViewport = viewport.New(0, 0)
 	m.contextViewport.GotoTop()
 
+	if m.statePath != "" {
+		m.restoreUIState()
+	}
+
 	return m
 }
 
@@ -446,6 +887,23 @@ func (m Model) Init() tea.Cmd {
 		// Query daemon status and start periodic checks
 		m.queryDaemonStatusCmd(),
 		m.startDaemonStatusTicker(),
+		// Load incident timeline for the history ribbon
+		m.queryDaemonEventsCmd(),
+		// Load prompt send history for the prompt preview and list
+		m.queryPromptInjectionsCmd(),
+		m.queryPromptStatsCmd(),
+		// Load the plan chosen for this workspace
+		m.queryWorkspacePlanCmd(),
+		// Load post-edit check results for the history badges
+		m.queryChecksCmd(),
+		// Load the names given to Claude sessions in the history
+		m.querySessionNamesCmd(),
+		// Load when those sessions started and finished their turns
+		m.queryHookEventsCmd(),
+		// Refresh the Ralph tab when it was restored as the open tab
+		m.ralphRefreshCmd,
+		// Look up the branch for the header
+		m.vcsStatusCmd(),
 	)
 }
 
@@ -463,110 +921,155 @@ func (m Model) loadContextCmd() tea.Cmd {
 	}
 }
 
-// queryDaemonHistoryCmd queries the daemon for edit history for current workspace
+// queryDaemonHistoryCmd queries the daemon for edit history for current workspace,
+// narrowed by the history filter if one is applied
 func (m Model) queryDaemonHistoryCmd() tea.Cmd {
+	return m.queryDaemonHistoryPageCmd("")
+}
+
+// queryDaemonHistoryPageCmd queries the daemon for a page of edit history,
+// starting after cursor (the newest edit if it's empty)
+func (m Model) queryDaemonHistoryPageCmd(cursor string) tea.Cmd {
+	filterExpr := m.historyFilter.String()
+	older := cursor != ""
 	return func() tea.Msg {
-		// Get current workspace path
-		workspacePath, err := os.Getwd()
+		changes, nextCursor, err := m.fetchDaemonHistory(filterExpr, cursor, historyPageSize)
 		if err != nil {
-			logger.Log("Failed to get working directory: %v", err)
-			return daemonHistoryMsg{err: err}
+			return daemonHistoryMsg{err: err, older: older}
 		}
-
-		// Try to connect to daemon query socket
-		querySocket := "/tmp/claude-mon-query.sock"
-		conn, err := net.DialTimeout("unix", querySocket, 2*time.Second)
-		if err != nil {
-			logger.Log("Daemon not available: %v", err)
-			return daemonHistoryMsg{err: err}
-		}
-		defer conn.Close()
-
-		// Set read/write deadline
-		conn.SetDeadline(time.Now().Add(5 * time.Second))
-
-		// Send query for edits in this workspace
-		query := map[string]interface{}{
-			"type":           "workspace",
-			"workspace_path": workspacePath,
-			"limit":          100,
-		}
-		if err := json.NewEncoder(conn).Encode(query); err != nil {
-			logger.Log("Failed to send query: %v", err)
-			return daemonHistoryMsg{err: err}
+		return daemonHistoryMsg{
+			changes:    changes,
+			filtered:   filterExpr != "",
+			filter:     filterExpr,
+			older:      older,
+			nextCursor: nextCursor,
 		}
+	}
+}
 
-		// Read response
-		var result struct {
-			Type  string `json:"type"`
-			Edits []struct {
-				ID          int64     `json:"id"`
-				SessionID   int64     `json:"session_id"`
-				ToolName    string    `json:"tool_name"`
-				FilePath    string    `json:"file_path"`
-				OldString   string    `json:"old_string"`
-				NewString   string    `json:"new_string"`
-				LineNum     int       `json:"line_num"`
-				LineCount   int       `json:"line_count"`
-				CommitSHA   string    `json:"commit_sha"`
-				VCSType     string    `json:"vcs_type"`
-				FileContent string    `json:"file_content"`
-				CreatedAt   time.Time `json:"created_at"`
-			} `json:"edits"`
-			Error string `json:"error,omitempty"`
-		}
+// fetchDaemonHistory queries the daemon for up to limit edits in the current
+// workspace matching a filter expression, starting after cursor, and returns
+// them as changes with the cursor for the next older page
+func (m Model) fetchDaemonHistory(filterExpr, cursor string, limit int) ([]Change, string, error) {
+	// Get current workspace path
+	workspacePath, err := m.fs.Getwd()
+	if err != nil {
+		logger.Log("Failed to get working directory: %v", err)
+		return nil, "", err
+	}
 
-		if err := json.NewDecoder(conn).Decode(&result); err != nil {
-			logger.Log("Failed to decode response: %v", err)
-			return daemonHistoryMsg{err: err}
+	// Try to connect to daemon query socket
+	querySocket := "/tmp/claude-mon-query.sock"
+	conn, err := net.DialTimeout("unix", querySocket, 2*time.Second)
+	if err != nil {
+		logger.Log("Daemon not available: %v", err)
+		return nil, "", err
+	}
+	defer conn.Close()
+
+	// Set read/write deadline
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// Send query for edits in this workspace
+	query := map[string]interface{}{
+		"type":           "workspace",
+		"workspace_path": workspacePath,
+		"limit":          limit,
+		"token":          auth.FromEnv(),
+	}
+	if filterExpr != "" {
+		query["filter"] = filterExpr
+	}
+	if cursor != "" {
+		query["cursor"] = cursor
+	}
+	if err := json.NewEncoder(conn).Encode(query); err != nil {
+		logger.Log("Failed to send query: %v", err)
+		return nil, "", err
+	}
+
+	// Read response
+	var result struct {
+		Type  string `json:"type"`
+		Edits []struct {
+			ID          int64     `json:"id"`
+			SessionID   int64     `json:"session_id"`
+			ToolName    string    `json:"tool_name"`
+			FilePath    string    `json:"file_path"`
+			OldString   string    `json:"old_string"`
+			NewString   string    `json:"new_string"`
+			LineNum     int       `json:"line_num"`
+			LineCount   int       `json:"line_count"`
+			CommitSHA   string    `json:"commit_sha"`
+			VCSType     string    `json:"vcs_type"`
+			FileContent string    `json:"file_content"`
+			Bookmarked  bool      `json:"bookmarked"`
+			Note        string    `json:"note"`
+			Review      string    `json:"review"`
+			Summary     string    `json:"summary"`
+			ChatSession string    `json:"chat_session_id"`
+			CreatedAt   time.Time `json:"created_at"`
+		} `json:"edits"`
+		NextCursor string `json:"next_cursor,omitempty"`
+		Error      string `json:"error,omitempty"`
+	}
+
+	if err := json.NewDecoder(conn).Decode(&result); err != nil {
+		logger.Log("Failed to decode response: %v", err)
+		return nil, "", err
+	}
+
+	if result.Error != "" {
+		logger.Log("Daemon error: %s", result.Error)
+		return nil, "", fmt.Errorf("daemon: %s", result.Error)
+	}
+
+	// Convert edits to changes
+	var changes []Change
+	var withContent, withoutContent int
+	for _, edit := range result.Edits {
+		change := Change{
+			Timestamp:   edit.CreatedAt,
+			FilePath:    edit.FilePath,
+			ToolName:    edit.ToolName,
+			OldString:   edit.OldString,
+			NewString:   edit.NewString,
+			LineNum:     edit.LineNum,
+			LineCount:   edit.LineCount,
+			CommitSHA:   edit.CommitSHA,
+			VCSType:     edit.VCSType,
+			FileContent: edit.FileContent,
+			EditID:      edit.ID,
+			Bookmarked:  edit.Bookmarked,
+			Note:        edit.Note,
+			Review:      edit.Review,
+			Summary:     edit.Summary,
+			SessionID:   edit.ChatSession,
+		}
+		// Track content stats for debugging
+		if edit.FileContent != "" {
+			withContent++
+		} else {
+			withoutContent++
 		}
-
-		if result.Error != "" {
-			logger.Log("Daemon error: %s", result.Error)
-			return daemonHistoryMsg{err: fmt.Errorf("daemon: %s", result.Error)}
-		}
-
-		// Convert edits to changes
-		var changes []Change
-		var withContent, withoutContent int
-		for _, edit := range result.Edits {
-			change := Change{
-				Timestamp:   edit.CreatedAt,
-				FilePath:    edit.FilePath,
-				ToolName:    edit.ToolName,
-				OldString:   edit.OldString,
-				NewString:   edit.NewString,
-				LineNum:     edit.LineNum,
-				LineCount:   edit.LineCount,
-				CommitSHA:   edit.CommitSHA,
-				VCSType:     edit.VCSType,
-				FileContent: edit.FileContent,
-			}
-			// Track content stats for debugging
-			if edit.FileContent != "" {
-				withContent++
-			} else {
-				withoutContent++
-			}
-			// Set short commit SHA for display
-			if len(edit.CommitSHA) >= 8 {
-				change.CommitShort = edit.CommitSHA[:8]
-			} else if edit.CommitSHA != "" {
-				change.CommitShort = edit.CommitSHA
-			}
-			changes = append(changes, change)
+		// Set short commit SHA for display
+		if len(edit.CommitSHA) >= 8 {
+			change.CommitShort = edit.CommitSHA[:8]
+		} else if edit.CommitSHA != "" {
+			change.CommitShort = edit.CommitSHA
 		}
-
-		logger.Log("Loaded %d edits from daemon (%d with file_content, %d without)", len(changes), withContent, withoutContent)
-		return daemonHistoryMsg{changes: changes}
+		changes = append(changes, change)
 	}
+
+	logger.Log("Loaded %d edits from daemon (%d with file_content, %d without)", len(changes), withContent, withoutContent)
+	return changes, result.NextCursor, nil
 }
 
 // queryDaemonStatusCmd queries the daemon for its status and workspace activity
 func (m Model) queryDaemonStatusCmd() tea.Cmd {
 	return func() tea.Msg {
 		// Get current workspace path
-		workspacePath, err := os.Getwd()
+		workspacePath, err := m.fs.Getwd()
 		if err != nil {
 			logger.Log("Failed to get working directory: %v", err)
 			return daemonStatusMsg{connected: false}
@@ -588,6 +1091,7 @@ func (m Model) queryDaemonStatusCmd() tea.Cmd {
 		query := map[string]interface{}{
 			"type":           "status",
 			"workspace_path": workspacePath,
+			"token":          auth.FromEnv(),
 		}
 		if err := json.NewEncoder(conn).Encode(query); err != nil {
 			logger.Log("Failed to send status query: %v", err)
@@ -599,6 +1103,7 @@ func (m Model) queryDaemonStatusCmd() tea.Cmd {
 			Type   string `json:"type"`
 			Status struct {
 				Running   bool   `json:"running"`
+				Version   string `json:"version"`
 				UptimeStr string `json:"uptime_str"`
 				Active    *struct {
 					Path         string    `json:"path"`
@@ -623,6 +1128,7 @@ func (m Model) queryDaemonStatusCmd() tea.Cmd {
 		msg := daemonStatusMsg{
 			connected: true,
 			uptime:    result.Status.UptimeStr,
+			version:   result.Status.Version,
 		}
 
 		if result.Status.Active != nil {
@@ -647,14 +1153,26 @@ func (m Model) LeaderActivatedAt() time.Time {
 	return m.leaderActivatedAt
 }
 
+// PromptStore returns the store prompts mode lists, nil if it couldn't be
+// opened
+func (m Model) PromptStore() *prompt.Store {
+	return m.promptStore
+}
+
 // addToast adds a new toast notification
 func (m *Model) addToast(message string, toastType ToastType) {
-	m.toasts = append(m.toasts, Toast{
+	t := Toast{
 		Message:   message,
 		Type:      toastType,
-		CreatedAt: time.Now(),
-		Duration:  3 * time.Second,
-	})
+		CreatedAt: m.clock.Now(),
+		Duration:  m.toastDuration(toastType),
+	}
+	m.logToast(t)
+	if m.doNotDisturb {
+		m.heldToasts++
+		return
+	}
+	m.toasts = append(m.toasts, t)
 	// Limit to 5 toasts max
 	if len(m.toasts) > 5 {
 		m.toasts = m.toasts[len(m.toasts)-5:]
@@ -663,7 +1181,7 @@ func (m *Model) addToast(message string, toastType ToastType) {
 
 // cleanExpiredToasts removes toasts that have exceeded their duration
 func (m *Model) cleanExpiredToasts() {
-	now := time.Now()
+	now := m.clock.Now()
 	active := make([]Toast, 0, len(m.toasts))
 	for _, t := range m.toasts {
 		if now.Sub(t.CreatedAt) < t.Duration {
@@ -696,28 +1214,56 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.diffViewport = viewport.New(m.width/2-4, m.height-headerHeight-footerHeight-2)
 		}
 		m.updateViewportSize()
-		m.diffViewport.SetContent(m.renderDiff())
+		cmds = append(cmds, m.showDiff())
 
 	case tea.MouseMsg:
-		// Handle mouse scroll in diff pane
-		if msg.Action == tea.MouseActionPress {
-			switch msg.Button {
-			case tea.MouseButtonWheelUp:
-				m.diffViewport.LineUp(3)
-			case tea.MouseButtonWheelDown:
-				m.diffViewport.LineDown(3)
-			}
-		}
+		cmds = append(cmds, m.handleMouse(msg))
 
 	case tea.KeyMsg:
-		logger.Log("KeyMsg received: %q", msg.String())
+		logger.Debug("Key received", "key", msg.String())
 		if m.showHelp {
 			m.showHelp = false
 			return m, nil
 		}
+		if m.showHookHealth {
+			if msg.String() == "r" {
+				return m, m.openHookHealth()
+			}
+			m.showHookHealth = false
+			return m, nil
+		}
+		if m.showLogConsole {
+			m.handleLogConsoleKey(msg)
+			return m, nil
+		}
+		if m.showNotifications {
+			m.handleNotificationsKey(msg.String())
+			return m, nil
+		}
+		if m.showThemePicker {
+			return m, m.handleThemePickerKey(msg.String())
+		}
+		if m.showPromptTagPicker {
+			m.handlePromptTagPickerKey(msg.String())
+			return m, nil
+		}
+		if m.showTmuxPicker {
+			m.handleTmuxPickerKey(msg.String())
+			return m, nil
+		}
+		if m.showChatPicker {
+			return m, m.handleChatPickerKey(msg.String())
+		}
 
 		key := msg.String()
 
+		// The key after the region jump key picks the region, ahead of the
+		// digit shortcuts for switching tabs
+		if m.minimapJumpPending {
+			m.handleRegionJumpKey(key)
+			return m, nil
+		}
+
 		// Handle leader key mode
 		if m.leaderActive {
 			return m.handleLeaderKey(msg)
@@ -734,6 +1280,59 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			})
 		}
 
+		// Handle history filter input - must check BEFORE global keys
+		if m.historyFilterActive {
+			return m.handleHistoryFilterKeys(msg)
+		}
+
+		// Handle commit message input - must check BEFORE global keys
+		if m.commitInputActive {
+			return m.handleCommitKeys(msg)
+		}
+
+		// Handle session name input - must check BEFORE global keys
+		if m.sessionNaming != "" {
+			return m.handleSessionNameKeys(msg)
+		}
+
+		// Handle review note input - must check BEFORE global keys
+		if m.noteActive {
+			return m.handleNoteKeys(msg)
+		}
+
+		// Handle prompt variable form - must check BEFORE global keys
+		if m.promptVarForm != nil {
+			return m.handlePromptVarKeys(msg)
+		}
+
+		// Handle prompt send confirmation - must check BEFORE global keys
+		if m.promptSendPending != nil {
+			return m.handlePromptSendKeys(msg)
+		}
+		if m.snapshotRestorePending != nil {
+			return m.handleSnapshotRestoreKeys(msg)
+		}
+		if m.contextDetected != nil {
+			return m.handleContextDetectKeys(msg)
+		}
+
+		// Handle chat message input - must check BEFORE global keys
+		if m.chatInputActive {
+			return m.handleChatInputKeys(msg)
+		}
+		if m.objectiveTyping {
+			return m.handleObjectiveInputKeys(msg)
+		}
+		if m.ralphInputActive {
+			return m.handleRalphInputKeys(msg)
+		}
+		if m.showPlanList && m.leftPaneMode == LeftPaneModePlan {
+			return m.handlePlanListKeys(msg)
+		}
+		if m.showContextProfiles && m.leftPaneMode == LeftPaneModeContext {
+			return m.handleContextProfileKeys(msg)
+		}
+
 		// Handle plan input mode - must check BEFORE global keys
 		if m.planInputActive {
 			switch key {
@@ -807,13 +1406,17 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				// Move to previous field
 				m.prevContextField()
 				return m, nil
+			case "ctrl+s":
+				// Cycle env/custom values through plain, secret and secret
+				// sent to Claude
+				if m.contextEditField == "env" || m.contextEditField == "custom" {
+					m.cycleContextEditSecret()
+				}
+				return m, nil
 			case "ctrl+@":
 				// Open completion for current field (ctrl+space)
 				if !m.contextCompletionActive {
-					m.loadContextCompletions()
-					m.contextCompletionActive = true
-					m.contextCompletionInput.Reset()
-					m.contextCompletionInput.Focus()
+					return m, m.openContextCompletion()
 				}
 				return m, nil
 			default:
@@ -846,6 +1449,18 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 
+		// Esc dismisses the alert banner before anything else
+		if len(m.alerts) > 0 && key == "esc" {
+			m.dismissAlerts()
+			return m, nil
+		}
+
+		// Answer an edit held for approval - y and n are taken while one waits
+		if len(m.gateQueue) > 0 && (key == "y" || key == "n") {
+			m.answerGate(key == "y")
+			return m, nil
+		}
+
 		// Global keys (work in any mode)
 		switch key {
 		case m.config.Keys.Help:
@@ -889,6 +1504,10 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			// Direct access to Context tab
 			m.switchToMode(LeftPaneModeContext)
 			return m, nil
+		case "6":
+			// Direct access to Chat tab
+			m.switchToMode(LeftPaneModeChat)
+			return m, nil
 		case m.config.Keys.ToggleMinimap:
 			m.showMinimap = !m.showMinimap
 			m.updateViewportSize()
@@ -903,6 +1522,15 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.updateViewportSize()
 			m.diffViewport.SetContent(m.renderRightPane())
 			return m, nil
+		case m.config.Keys.ShrinkLeftPane, m.config.Keys.GrowLeftPane:
+			if !m.hideLeftPane {
+				delta := leftPanePercentStep
+				if key == m.config.Keys.ShrinkLeftPane {
+					delta = -delta
+				}
+				m.resizeLeftPane(delta)
+			}
+			return m, nil
 		case m.config.Keys.Quit:
 			return m, tea.Quit
 		}
@@ -917,34 +1545,58 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m.handlePlanKeys(msg)
 		case LeftPaneModeContext:
 			return m.handleContextKeys(msg)
+		case LeftPaneModeChat:
+			return m.handleChatKeys(msg)
 		default:
 			return m.handleHistoryKeys(msg)
 		}
 
 	case SocketMsg:
 		logger.Log("SocketMsg received, payload size: %d bytes", len(msg.Payload))
-		m.lastMsgTime = time.Now() // Track last message for status indicator
+		m.lastMsgTime = m.clock.Now() // Track last message for status indicator
 
 		// Extract plan_path from payload if present (sent by hook)
 		var planInfo struct {
 			PlanPath string `json:"plan_path"`
 		}
 		if json.Unmarshal(msg.Payload, &planInfo) == nil && planInfo.PlanPath != "" {
-			m.planPath = planInfo.PlanPath
-			logger.Log("Received planPath from hook: %s", m.planPath)
+			if m.planPinned == "" || m.planPinned == planInfo.PlanPath {
+				m.planPath = planInfo.PlanPath
+				logger.Log("Received planPath from hook: %s", m.planPath)
+			} else if planInfo.PlanPath != m.planHookPath {
+				// A plan for other work doesn't replace the workspace's plan
+				logger.Log("Hook reported plan %s; keeping pinned plan %s", planInfo.PlanPath, m.planPinned)
+				m.addToast("Claude is on plan "+strings.TrimSuffix(filepath.Base(planInfo.PlanPath), ".md")+"; switch with P in the Plan tab", ToastInfo)
+			}
+			m.planHookPath = planInfo.PlanPath
+		}
+
+		// Lifecycle hooks mark sessions and pending edits; they carry no change
+		if m.handleHookEvent(msg.Payload) {
+			return m, tea.Batch(cmds...)
 		}
 
-		change := parsePayload(msg.Payload)
+		change := m.parsePayload(msg.Payload)
+		if change != nil && !m.tracksChange(change) {
+			logger.Log("Ignoring change to %s: excluded by workspace rules", change.FilePath)
+			change = nil
+		}
 		if change != nil {
 			// Get current VCS commit info
 			sha, shortSHA, vcsType := history.GetCurrentCommit()
 			change.CommitSHA = sha
 			change.CommitShort = shortSHA
 			change.VCSType = vcsType
+			if cmd := m.enforcePolicy(change, msg.Payload); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
 
 			logger.Log("Parsed change: %s %s (line %d) commit=%s fileContent=%d bytes", change.ToolName, change.FilePath, change.LineNum, shortSHA, len(change.FileContent))
-			// Prepend new change to start of list (newest first)
+			// Prepend new change to start of list (newest first); cached
+			// diffs are keyed by index, so they shift out of place
 			m.changes = append([]Change{*change}, m.changes...)
+			m.diffCache = make(map[int]*diffDoc)
+			m.loadSessionPrompts()
 			logger.Log("Total changes now: %d, selectedIndex: %d", len(m.changes), m.selectedIndex)
 
 			// Save to history if persistence enabled
@@ -960,21 +1612,58 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					CommitSHA:   change.CommitSHA,
 					CommitShort: change.CommitShort,
 					VCSType:     change.VCSType,
+					Summary:     change.Summary,
+					SessionID:   change.SessionID,
 				}
 				if err := m.historyStore.Add(entry); err != nil {
 					logger.Log("Failed to save history: %v", err)
 				}
 			}
 
-			// Select the newly added change (most recent, at index 0)
-			m.selectedIndex = 0
-			m.scrollX = 0
-			m.listScrollOffset = 0 // Keep newest visible at top
-			m.ensureSelectedVisible()
-			m.diffViewport.SetContent(m.renderDiff())
+			if m.bookmarksOnly {
+				// New change is hidden by the bookmark filter; keep the current selection
+				m.selectedIndex++
+				m.ensureSelectedVisible()
+			} else {
+				// Select the newly added change (most recent, at index 0)
+				m.selectedIndex = 0
+				m.scrollX = 0
+				m.listScrollOffset = 0 // Keep newest visible at top
+				m.ensureSelectedVisible()
+				cmds = append(cmds, m.showDiff())
+			}
+
+			// Re-check drift: the new change may overwrite earlier ones
+			if m.workingTreeDiff {
+				cmds = append(cmds, m.checkDriftCmd())
+			}
+
+			// The edit may have dirtied the working copy
+			if cmd := m.refreshVCSStatus(); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+
+			// Keep nvim on Claude's latest edit
+			if cmd := m.followChange(*change); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+
+			// The daemon may raise an alert on the edit
+			if cmd := m.scheduleAlertCheck(); cmd != nil {
+				cmds = append(cmds, cmd)
+			}
+
+			// Claude checking off plan tasks updates the checklist
+			if m.planPath != "" && change.FilePath == m.planPath {
+				m.loadPlanFile()
+				if m.leftPaneMode == LeftPaneModePlan {
+					m.diffViewport.SetContent(m.renderRightPane())
+				}
+			}
 		} else {
 			logger.Log("parsePayload returned nil")
 		}
+		m.recordChatTool(msg.Payload, change)
 
 	case promptEditedMsg:
 		// Prompt was edited in nvim - update frontmatter and refresh list
@@ -992,11 +1681,44 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.diffViewport.SetContent(m.renderRightPane())
 		m.addToast("Prompt saved", ToastSuccess)
 
+	case contextDetectedMsg:
+		if msg.detected.Empty() {
+			m.addToast("Nothing detected: no kubectl context, AWS_PROFILE or git checkout", ToastWarning)
+			return m, nil
+		}
+		m.contextDetected = msg.detected
+		return m, nil
+
+	case workspacePlanMsg:
+		if msg.err != nil {
+			logger.Log("Workspace plan unavailable: %v", msg.err)
+			return m, nil
+		}
+		if msg.path == "" {
+			return m, nil
+		}
+		if _, err := m.fs.Stat(msg.path); err != nil {
+			logger.Log("Workspace plan %s is gone: %v", msg.path, err)
+			return m, nil
+		}
+		m.planPinned = msg.path
+		m.planPath = msg.path
+		m.loadPlanFile()
+		if m.leftPaneMode == LeftPaneModePlan {
+			m.diffViewport.SetContent(m.renderRightPane())
+		}
+		return m, nil
+
 	case planGeneratedMsg:
 		logger.Log("Plan generated: %s", msg.path)
 		m.planGenerating = false
-		m.planPath = msg.path
-		m.loadPlanFile()
+		if m.planPinned != "" {
+			// A plan generated here replaces the workspace's plan
+			m.pinPlan(msg.path)
+		} else {
+			m.planPath = msg.path
+			m.loadPlanFile()
+		}
 		m.diffViewport.SetContent(m.renderRightPane())
 		m.addToast("Plan created: "+msg.slug, ToastSuccess)
 
@@ -1011,6 +1733,14 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.diffViewport.SetContent(m.renderRightPane())
 		m.addToast("Plan reloaded", ToastInfo)
 
+	case logConsoleTickMsg:
+		// Keep ticking while the console is open; each render reads the
+		// latest entries
+		if m.showLogConsole && msg.opened.Equal(m.logConsoleOpened) {
+			return m, logConsoleTickCmd(msg.opened)
+		}
+		return m, nil
+
 	case leaderTimeoutMsg:
 		// Only dismiss if this timeout matches current activation
 		if m.leaderActive && msg.activatedAt.Equal(m.leaderActivatedAt) {
@@ -1024,10 +1754,43 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			logger.Log("Auto-refreshing Ralph state")
 			m.loadRalphState()
 			// Return the command again to keep the ticker going
-			return m, tea.Tick(5*time.Second, func(t time.Time) tea.Msg {
+			return m, tea.Batch(tea.Tick(5*time.Second, func(t time.Time) tea.Msg {
 				return ralphRefreshTickMsg{Time: t}
-			})
+			}), m.queryRalphTimelineCmd())
+		}
+
+	case ralphLoopsMsg:
+		if msg.err != nil {
+			m.addToast("Ralph history unavailable: "+msg.err.Error(), ToastWarning)
+			return m, nil
+		}
+		m.ralphHistory = msg.loops
+		m.ralphHistoryIndex = 0
+		m.ralphHistoryEdits = nil
+		m.diffViewport.SetContent(m.renderRightPane())
+		return m, nil
+
+	case ralphLoopEditsMsg:
+		if msg.err != nil {
+			m.addToast("Loop edits unavailable: "+msg.err.Error(), ToastWarning)
+			return m, nil
 		}
+		if len(m.ralphHistory) > 0 && m.ralphHistory[m.ralphHistoryIndex].ID == msg.loopID {
+			m.ralphHistoryEdits = append([]ralphIteration{}, msg.timeline...)
+			m.diffViewport.SetContent(m.renderRightPane())
+		}
+		return m, nil
+
+	case ralphTimelineMsg:
+		if msg.err != nil {
+			logger.Log("Ralph timeline unavailable: %v", msg.err)
+			return m, nil
+		}
+		m.ralphTimeline = msg.timeline
+		if m.leftPaneMode == LeftPaneModeRalph {
+			m.diffViewport.SetContent(m.renderRightPane())
+		}
+		return m, nil
 
 	case toastCleanupTickMsg:
 		// Clean expired toasts and keep ticker running
@@ -1038,50 +1801,283 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Context loaded - nothing to do, already handled in New()
 
 	case daemonHistoryMsg:
-		if msg.err != nil {
+		if msg.older {
+			m.addOlderHistory(msg)
+		} else if msg.err != nil {
 			// Daemon not available - that's OK, we can still receive live updates
 			logger.Log("Daemon query failed (will use live updates): %v", msg.err)
 		} else if len(msg.changes) > 0 {
+			m.historyCursors[msg.filter] = msg.nextCursor
+
 			// Only add changes we don't already have (avoid duplicates with local history)
 			existingPaths := make(map[string]bool)
 			for _, c := range m.changes {
-				key := fmt.Sprintf("%s:%s:%d", c.FilePath, c.Timestamp.Format(time.RFC3339), c.LineNum)
-				existingPaths[key] = true
+				existingPaths[historyKey(c)] = true
 			}
 
 			// Prepend new changes to maintain newest-first order
 			var newChanges []Change
 			for _, c := range msg.changes {
-				key := fmt.Sprintf("%s:%s:%d", c.FilePath, c.Timestamp.Format(time.RFC3339), c.LineNum)
-				if !existingPaths[key] {
+				if !existingPaths[historyKey(c)] {
 					newChanges = append(newChanges, c)
 				}
 			}
 			// Prepend daemon changes (already sorted newest first)
 			m.changes = append(newChanges, m.changes...)
-
-			// Select most recent (newest is at index 0)
-			if len(m.changes) > 0 {
+			m.loadSessionPrompts()
+
+			if msg.filtered {
+				// Filtered results can be older than loaded changes; restore
+				// newest-first order, keeping the selected change selected
+				m.sortChanges(m.selectedIndex + len(newChanges))
+				cmds = append(cmds, m.selectVisibleChange())
+			} else if len(m.changes) > 0 {
+				// Select most recent (newest is at index 0), or the
+				// change selected when the TUI last quit
 				m.selectedIndex = 0
 				m.listScrollOffset = 0 // Start at top showing newest
+				m.selectRestoredChange()
+				m.restoredChange = ""
 				m.ensureSelectedVisible()
-				m.diffViewport.SetContent(m.renderDiff())
+				cmds = append(cmds, m.showDiff())
 			}
-			m.lastMsgTime = time.Now()
+			m.lastMsgTime = m.clock.Now()
 			logger.Log("Added %d changes from daemon, total now: %d", len(msg.changes), len(m.changes))
 		}
 
+	case externalEditsMsg:
+		cmds = append(cmds, m.addExternalEdits(msg))
+
 	case daemonStatusMsg:
 		m.daemonConnected = msg.connected
 		m.daemonUptime = msg.uptime
-		m.daemonLastCheck = time.Now()
+		m.daemonLastCheck = m.clock.Now()
 		m.daemonWorkspaceActive = msg.workspaceActive
 		m.daemonWorkspaceEdits = msg.workspaceEdits
 		m.daemonLastActivity = msg.lastActivity
+		m.daemonVersion = msg.version
 
 	case daemonStatusTickMsg:
 		// Periodic daemon status check
-		cmds = append(cmds, m.queryDaemonStatusCmd(), m.startDaemonStatusTicker())
+		cmds = append(cmds, m.queryDaemonStatusCmd(), m.startDaemonStatusTicker(), m.queryDaemonEventsCmd(),
+			m.queryPromptInjectionsCmd(), m.queryPromptStatsCmd(), m.queryChecksCmd(), m.queryExternalEditsCmd())
+		if cmd := m.refreshVCSStatus(); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+
+	case vcsStatusMsg:
+		m.updateVCSStatus(msg)
+
+	case followTickMsg:
+		if msg.seq == m.followSeq && m.following {
+			cmds = append(cmds, m.followEditCmd(m.followTarget))
+		}
+
+	case followErrMsg:
+		// Stop following rather than failing on every edit
+		m.following = false
+		m.addToast(fmt.Sprintf("Follow mode off: %v", msg.err), ToastError)
+
+	case hookHealthMsg:
+		m.hookHealth = &msg.report
+
+	case policyRevertedMsg:
+		if cmd := m.applyPolicyRevert(msg); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+
+	case driftCheckedMsg:
+		m.driftStatus = msg.statuses
+		if m.workingTreeDiff && m.leftPaneMode == LeftPaneModeHistory {
+			m.diffCache = make(map[int]*diffDoc)
+			cmds = append(cmds, m.showDiff())
+		}
+
+	case ControlMsg:
+		cmds = append(cmds, m.handleControl(msg))
+
+	case PromptsChangedMsg:
+		logger.Log("Prompt files changed, reloading list")
+		m.refreshPromptList()
+
+	case chatOutputMsg:
+		// Output from a replaced session is dropped, and its wait ends here
+		if msg.session == m.chatSession {
+			m.refreshChatView()
+			cmds = append(cmds, waitForChat(msg.session))
+		}
+
+	case chatDoneMsg:
+		if msg.session == m.chatSession {
+			// Still active means Claude exited on its own rather than being stopped
+			if msg.session.IsActive() {
+				msg.session.Stop()
+				m.saveChat(false)
+				if m.promptInjectMethod == prompt.InjectChat {
+					m.promptInjectMethod = prompt.DetectBestMethod()
+				}
+				m.addToast("Claude chat session ended", ToastWarning)
+			}
+			m.refreshChatView()
+		}
+
+	case objectiveOutputMsg:
+		if msg.session == m.objectiveSession {
+			if m.showObjectives {
+				m.refreshChatView()
+			}
+			cmds = append(cmds, waitForObjective(msg.session))
+		}
+
+	case objectiveDoneMsg:
+		if msg.session == m.objectiveSession {
+			cmds = append(cmds, m.finishObjective(msg.session))
+		}
+
+	case ralphRunOutputMsg:
+		if msg.session == m.ralphRun {
+			if m.leftPaneMode == LeftPaneModeRalph && !m.showRalphHistory {
+				m.diffViewport.SetContent(m.renderRightPane())
+			}
+			cmds = append(cmds, waitForRalphRun(msg.session))
+		}
+
+	case ralphRunDoneMsg:
+		if msg.session == m.ralphRun {
+			m.finishRalphRun(msg.session)
+		}
+
+	case chatSessionsMsg:
+		if msg.err != nil {
+			logger.Log("Failed to load chat sessions: %v", msg.err)
+			m.addToast("Saved chats need the daemon: "+msg.err.Error(), ToastError)
+		} else {
+			m.showChatSessions(msg.sessions)
+		}
+
+	case checksMsg:
+		if msg.err != nil {
+			logger.Log("Failed to load check results: %v", msg.err)
+		} else {
+			m.applyCheckResults(msg.results)
+			if m.checksPanel && m.leftPaneMode == LeftPaneModeHistory {
+				cmds = append(cmds, m.showDiff())
+			}
+		}
+
+	case fileHistoryMsg:
+		if msg.err != nil {
+			logger.Log("Failed to load file history: %v", msg.err)
+		}
+		m.applyFileHistory(msg)
+		if m.fileHistory != nil && m.leftPaneMode == LeftPaneModeHistory {
+			cmds = append(cmds, m.showDiff())
+			m.scrollToChange()
+		}
+
+	case sessionNamesMsg:
+		if msg.err != nil {
+			logger.Log("Failed to load session names: %v", msg.err)
+		} else {
+			m.sessionNames = msg.names
+			m.diffCache = make(map[int]*diffDoc)
+		}
+
+	case hookEventsMsg:
+		if msg.err != nil {
+			logger.Log("Failed to load hook events: %v", msg.err)
+		} else {
+			m.applyHookEvents(msg.events)
+			m.diffCache = make(map[int]*diffDoc)
+		}
+
+	case snapshotMsg:
+		if msg.err != nil {
+			m.addToast("Restore needs a snapshot: "+msg.err.Error(), ToastError)
+		} else {
+			m.snapshotRestorePending = msg.snapshot
+		}
+
+	case chatHistoryMsg:
+		if msg.err != nil {
+			m.addToast("Failed to load chat: "+msg.err.Error(), ToastError)
+		} else {
+			cmds = append(cmds, m.resumeChat(msg.session, msg.messages, msg.tools))
+		}
+
+	case promptSyncedMsg:
+		if msg.err != nil {
+			logger.Log("Prompt sync failed: %v", msg.err)
+			m.addToast("Sync failed: "+msg.err.Error(), ToastError)
+		} else {
+			m.addToast("Prompt library synced", ToastSuccess)
+		}
+		m.refreshPromptList()
+
+	case diffRenderedMsg:
+		m.applyRenderedDiff(msg)
+
+	case GateMsg:
+		cmds = append(cmds, m.holdGatedEdit(msg))
+
+	case gateTimeoutMsg:
+		m.expireGate(msg.id)
+
+	case spinner.TickMsg:
+		if m.diffRendering {
+			var cmd tea.Cmd
+			m.diffSpinner, cmd = m.diffSpinner.Update(msg)
+			m.diffViewport.SetContent(m.renderDiffPending())
+			cmds = append(cmds, cmd)
+		}
+		if m.contextCompletionLoading {
+			var cmd tea.Cmd
+			m.contextCompletionSpinner, cmd = m.contextCompletionSpinner.Update(msg)
+			cmds = append(cmds, cmd)
+		}
+
+	case contextCompletionsMsg:
+		m.applyContextCompletions(msg)
+
+	case commitDoneMsg:
+		if msg.err != nil {
+			m.addToast(msg.err.Error(), ToastError)
+		} else {
+			m.addToast(fmt.Sprintf("Committed %s (%d hunks)", msg.sha, msg.hunks), ToastSuccess)
+		}
+
+	case daemonEventsMsg:
+		if msg.err != nil {
+			logger.Log("Daemon events query failed: %v", msg.err)
+		} else {
+			m.incidents = msg.events
+			m.applyAlerts(msg.events)
+			m.ensureSelectedVisible()
+		}
+
+	case alertCheckMsg:
+		m.alertCheckPending = false
+		cmds = append(cmds, m.queryDaemonEventsCmd())
+
+	case promptInjectionsMsg:
+		if msg.err != nil {
+			logger.Log("Daemon prompt injections query failed: %v", msg.err)
+		} else {
+			m.promptInjections = msg.injections
+		}
+
+	case promptStatsMsg:
+		if msg.err != nil {
+			logger.Log("Daemon prompt stats query failed: %v", msg.err)
+		} else {
+			m.promptStats = make(map[string]PromptStat, len(msg.stats))
+			for _, stat := range msg.stats {
+				m.promptStats[stat.PromptName] = stat
+			}
+			if m.promptSort == PromptSortUsage {
+				m.applyPromptFilter()
+			}
+		}
 	}
 
 	return m, tea.Batch(cmds...)
@@ -1090,83 +2086,84 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 // handleHistoryKeys handles key events in history mode
 func (m Model) handleHistoryKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	key := msg.String()
+	if m.reviewing {
+		if handled, cmd := m.handleReviewKey(key); handled {
+			return m, cmd
+		}
+	}
+	if m.fileHistory != nil {
+		if handled, cmd := m.handleFileHistoryKey(key); handled {
+			return m, cmd
+		}
+	}
+	var cmd tea.Cmd
 	switch key {
 	case m.config.Keys.Down, "down":
 		if m.activePane == PaneLeft {
 			// Navigate history list down (to older items = higher index)
 			// Data is newest-first: index 0 = newest, index N-1 = oldest
-			if len(m.changes) > 0 && m.selectedIndex < len(m.changes)-1 {
-				m.selectedIndex++
+			if m.moveSelection(1) {
 				m.scrollX = 0
 				m.ensureSelectedVisible()
-				m.diffViewport.SetContent(m.renderDiff())
+				cmd = m.showDiff()
 				m.scrollToChange()
 				m.preloadAdjacent()
 			}
 		} else {
-			m.diffViewport.LineDown(1)
+			m.scrollDiff(1)
 		}
 	case m.config.Keys.Up, "up":
 		if m.activePane == PaneLeft {
 			// Navigate history list up (to newer items = lower index)
-			if len(m.changes) > 0 && m.selectedIndex > 0 {
-				m.selectedIndex--
+			if m.moveSelection(-1) {
 				m.scrollX = 0
 				m.ensureSelectedVisible()
-				m.diffViewport.SetContent(m.renderDiff())
+				cmd = m.showDiff()
 				m.scrollToChange()
 				m.preloadAdjacent()
 			}
 		} else {
-			m.diffViewport.LineUp(1)
+			m.scrollDiff(-1)
 		}
 	case m.config.Keys.PageDown:
 		if m.activePane == PaneLeft {
 			// Page down in history list (to older items = higher indices)
-			visibleItems := m.listVisibleItems()
-			for i := 0; i < visibleItems && m.selectedIndex < len(m.changes)-1; i++ {
-				m.selectedIndex++
-			}
+			m.moveSelection(m.listVisibleItems())
 			m.scrollX = 0
 			m.ensureSelectedVisible()
-			m.diffViewport.SetContent(m.renderDiff())
+			cmd = m.showDiff()
 			m.scrollToChange()
 			m.preloadAdjacent()
 		} else {
-			m.diffViewport.ViewDown()
+			m.scrollDiff(m.diffViewport.Height)
 		}
 	case m.config.Keys.PageUp:
 		if m.activePane == PaneLeft {
 			// Page up in history list (to newer items = lower indices)
-			visibleItems := m.listVisibleItems()
-			for i := 0; i < visibleItems && m.selectedIndex > 0; i++ {
-				m.selectedIndex--
-			}
+			m.moveSelection(-m.listVisibleItems())
 			m.scrollX = 0
 			m.ensureSelectedVisible()
-			m.diffViewport.SetContent(m.renderDiff())
+			cmd = m.showDiff()
 			m.scrollToChange()
 			m.preloadAdjacent()
 		} else {
-			m.diffViewport.ViewUp()
+			m.scrollDiff(-m.diffViewport.Height)
 		}
 	case m.config.Keys.Next:
 		// Next change in time (older = higher index)
-		if len(m.changes) > 0 && m.selectedIndex < len(m.changes)-1 {
-			m.selectedIndex++
+		if m.moveSelection(1) {
 			m.scrollX = 0
 			m.ensureSelectedVisible()
-			m.diffViewport.SetContent(m.renderDiff())
+			cmd = m.showDiff()
 			m.scrollToChange()
 			m.preloadAdjacent()
 		}
 	case m.config.Keys.Prev:
 		// Previous change in time (newer = lower index)
-		if len(m.changes) > 0 && m.selectedIndex > 0 {
-			m.selectedIndex--
+		if m.moveSelection(-1) {
 			m.scrollX = 0
 			m.ensureSelectedVisible()
-			m.diffViewport.SetContent(m.renderDiff())
+			cmd = m.showDiff()
 			m.scrollToChange()
 			m.preloadAdjacent()
 		}
@@ -1176,17 +2173,65 @@ func (m Model) handleHistoryKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			if m.scrollX < 0 {
 				m.scrollX = 0
 			}
-			m.diffViewport.SetContent(m.renderDiff())
+			cmd = m.showDiff()
 		}
 	case m.config.Keys.ScrollRight:
 		m.scrollX += 4
-		m.diffViewport.SetContent(m.renderDiff())
+		cmd = m.showDiff()
+	case m.config.Keys.Bookmark:
+		m.toggleBookmark()
+	case m.config.Keys.BookmarksOnly:
+		m.toggleBookmarksOnly()
+	case m.config.Keys.ToggleDensity:
+		m.toggleListDensity()
+	case m.config.Keys.ToggleWorkingTree:
+		return m, m.toggleWorkingTreeDiff()
+	case m.config.Keys.ToggleBlame:
+		return m, m.toggleBlame()
+	case m.config.Keys.ToggleSquash:
+		return m, m.toggleSquash()
+	case m.config.Keys.ExpandSquash:
+		return m, m.toggleSquashExpanded()
+	case m.config.Keys.ToggleNormalize:
+		return m, m.toggleNormalize()
+	case m.config.Keys.ToggleContext:
+		return m, m.toggleFullFile()
+	case m.config.Keys.ToggleStructural:
+		return m, m.toggleStructural()
+	case m.config.Keys.ToggleSemantic:
+		return m, m.toggleSemantic()
+	case m.config.Keys.ToggleChecks:
+		return m, m.toggleChecks()
+	case m.config.Keys.FileHistory:
+		return m, m.toggleFileHistory()
+	case m.config.Keys.ToggleSession:
+		return m, m.toggleSessionCollapsed()
+	case m.config.Keys.NextSession, m.config.Keys.PrevSession:
+		delta := 1
+		if key == m.config.Keys.PrevSession {
+			delta = -1
+		}
+		if m.jumpSession(delta) {
+			m.scrollX = 0
+			m.ensureSelectedVisible()
+			cmd = m.showDiff()
+			m.scrollToChange()
+			m.preloadAdjacent()
+		}
+	case m.config.Keys.JumpRegion:
+		m.startRegionJump()
+	case m.config.Keys.FilterHistory:
+		if len(m.changes) > 0 {
+			m.openHistoryFilter()
+		}
 	case m.config.Keys.ClearHistory:
 		m.changes = []Change{}
+		m.historyCursors = make(map[string]string)
 		m.selectedIndex = 0
 		m.listScrollOffset = 0
+		m.bookmarksOnly = false
 		m.diffViewport.SetContent("")
-		m.diffCache = make(map[int]string)
+		m.diffCache = make(map[int]*diffDoc)
 		if m.persistHistory && m.historyStore != nil {
 			if err := m.historyStore.Clear(); err != nil {
 				logger.Log("Failed to clear history file: %v", err)
@@ -1195,21 +2240,15 @@ func (m Model) handleHistoryKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case m.config.Keys.OpenInNvim:
 		if len(m.changes) > 0 {
 			change := m.changes[m.selectedIndex]
-			cmd := exec.Command("nvim", fmt.Sprintf("+%d", change.LineNum), change.FilePath)
-			return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
-				return nil
-			})
+			return m, m.openInNvim(change.FilePath, change.LineNum)
 		}
 	case m.config.Keys.OpenNvimCwd:
 		if len(m.changes) > 0 {
 			change := m.changes[m.selectedIndex]
-			cmd := exec.Command("nvim", change.FilePath)
-			return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
-				return nil
-			})
+			return m, m.openInNvim(change.FilePath, 0)
 		}
 	}
-	return m, nil
+	return m, tea.Batch(cmd, m.syncFileHistory(), m.loadOlderHistory())
 }
 
 // handlePromptsKeys handles key events in prompts mode
@@ -1281,9 +2320,9 @@ func (m Model) handlePromptsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			}
 		case m.config.Keys.RevertVersion, m.config.Keys.SendPrompt:
 			// Revert to selected version
-			if len(m.promptVersions) > 0 && len(m.promptList) > 0 && m.promptStore != nil {
+			if len(m.promptVersions) > 0 && len(m.promptFilteredList) > 0 && m.promptStore != nil {
 				v := m.promptVersions[m.promptVersionSelected]
-				p := m.promptList[m.promptSelected]
+				p := m.promptFilteredList[m.promptSelected]
 				if err := m.promptStore.RestoreVersion(p.Path, v.Version); err != nil {
 					m.addToast(err.Error(), ToastError)
 				} else {
@@ -1297,7 +2336,7 @@ func (m Model) handlePromptsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			// Delete version file
 			if len(m.promptVersions) > 0 {
 				v := m.promptVersions[m.promptVersionSelected]
-				if err := os.Remove(v.Path); err != nil {
+				if err := m.fs.Remove(v.Path); err != nil {
 					m.addToast(err.Error(), ToastError)
 				} else {
 					m.addToast(fmt.Sprintf("Deleted v%d", v.Version), ToastSuccess)
@@ -1311,11 +2350,19 @@ func (m Model) handlePromptsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 					m.diffViewport.SetContent(m.renderRightPane())
 				}
 			}
+		case "D":
+			// Toggle between the whole version and a diff
+			m.promptVersionDiff = !m.promptVersionDiff
+			m.diffViewport.SetContent(m.renderRightPane())
+		case "c":
+			// Mark the selected version as the diff base
+			m.togglePromptVersionBase()
+			m.diffViewport.SetContent(m.renderRightPane())
 		case m.config.Keys.EditPrompt:
 			// Open version in editor (read-only view)
 			if len(m.promptVersions) > 0 {
 				v := m.promptVersions[m.promptVersionSelected]
-				cmd := exec.Command("nvim", "-R", v.Path)
+				cmd := m.runner.Command("nvim", "-R", v.Path)
 				return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
 					return promptEditedMsg{path: v.Path}
 				})
@@ -1376,6 +2423,8 @@ func (m Model) handlePromptsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			if len(m.promptVersions) > 0 {
 				m.promptShowVersions = true
 				m.promptVersionSelected = 0
+				m.promptVersionDiff = false
+				m.promptVersionBase = 0
 				m.diffViewport.SetContent(m.renderRightPane())
 			} else {
 				m.addToast("No versions found", ToastWarning)
@@ -1390,35 +2439,20 @@ func (m Model) handlePromptsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			} else {
 				m.addToast("Deleted "+p.Name, ToastSuccess)
 				m.refreshPromptList()
-				m.diffViewport.SetContent(m.renderRightPane())
-			}
-		}
-	case m.config.Keys.SendPrompt:
-		// Inject prompt using current method
-		if len(m.promptFilteredList) > 0 {
-			p := m.promptFilteredList[m.promptSelected]
-			expanded := m.expandPromptVariables(p.Content)
-			logger.Log("Injecting prompt: original=%d bytes, expanded=%d bytes", len(p.Content), len(expanded))
-			if err := prompt.Inject(expanded, m.promptInjectMethod); err != nil {
-				m.addToast(err.Error(), ToastError)
-			} else {
-				m.addToast(fmt.Sprintf("Sent via %s", prompt.MethodName(m.promptInjectMethod)), ToastSuccess)
+				m.diffViewport.SetContent(m.renderRightPane())
 			}
 		}
+	case m.config.Keys.SendPrompt:
+		// Confirm with a size/cost estimate, then inject using current method
+		m.confirmSendPrompt()
 	case m.config.Keys.YankPrompt:
 		// Yank/copy to clipboard only
 		if len(m.promptFilteredList) > 0 {
-			p := m.promptFilteredList[m.promptSelected]
-			expanded := m.expandPromptVariables(p.Content)
-			if err := prompt.Inject(expanded, prompt.InjectClipboard); err != nil {
-				m.addToast(err.Error(), ToastError)
-			} else {
-				m.addToast("Copied to clipboard", ToastSuccess)
-			}
+			m.usePrompt(m.promptFilteredList[m.promptSelected], promptVarsYank)
 		}
 	case m.config.Keys.InjectMethod:
 		// Cycle injection method
-		m.promptInjectMethod = (m.promptInjectMethod + 1) % 2
+		m.cycleInjectMethod()
 		m.addToast(fmt.Sprintf("Inject method: %s", prompt.MethodName(m.promptInjectMethod)), ToastInfo)
 	case "/":
 		// Cycle filter scope: all -> project -> global -> all
@@ -1435,6 +2469,18 @@ func (m Model) handlePromptsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		m.addToast(fmt.Sprintf("Filter: %s", scopeName), ToastInfo)
 		m.diffViewport.SetContent(m.renderRightPane())
+	case "t":
+		// Filter by tags
+		m.openPromptTagPicker()
+	case "P":
+		// Pick the tmux pane prompts are sent to
+		m.openTmuxPicker()
+	case "s":
+		// Cycle sort order: updated -> name -> usage -> updated
+		m.promptSort = (m.promptSort + 1) % 3
+		m.applyPromptFilter()
+		m.addToast(fmt.Sprintf("Sort: %s", m.promptSort), ToastInfo)
+		m.diffViewport.SetContent(m.renderRightPane())
 	case "f":
 		// Activate fuzzy filter overlay
 		if len(m.promptFilteredList) > 0 {
@@ -1451,6 +2497,9 @@ func (m Model) handlePromptsKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 // handleRalphKeys handles key events in Ralph mode
 func (m Model) handleRalphKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	key := msg.String()
+	if m.showRalphHistory {
+		return m, m.handleRalphHistoryKey(key)
+	}
 	switch key {
 	case m.config.Keys.Down, "down":
 		if m.activePane == PaneRight {
@@ -1463,12 +2512,11 @@ func (m Model) handleRalphKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case m.config.Keys.CancelRalph:
 		// Cancel Ralph loop
 		if m.ralphState != nil && m.ralphState.Active {
-			if removed, _ := ralph.CancelLoop(); removed {
-				m.ralphState = nil
-				m.addToast("Ralph Loop cancelled", ToastSuccess)
-				m.diffViewport.SetContent(m.renderRightPane())
-			}
+			m.cancelRalphLoop()
 		}
+	case "H":
+		// Loops that have ended
+		return m, m.toggleRalphHistory()
 	case m.config.Keys.Refresh:
 		// Refresh Ralph state
 		m.loadRalphState()
@@ -1510,10 +2558,19 @@ func (m Model) handlePlanKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case m.config.Keys.Down, "down":
 		if m.activePane == PaneRight {
 			m.diffViewport.LineDown(1)
+		} else if m.planTaskIndex < len(m.planTasks)-1 {
+			m.planTaskIndex++
 		}
 	case m.config.Keys.Up, "up":
 		if m.activePane == PaneRight {
 			m.diffViewport.LineUp(1)
+		} else if m.planTaskIndex > 0 {
+			m.planTaskIndex--
+		}
+	case " ", "x":
+		// Check or uncheck the selected task
+		if m.activePane == PaneLeft {
+			m.togglePlanTask()
 		}
 	case m.config.Keys.PageDown:
 		if m.activePane == PaneRight {
@@ -1523,6 +2580,9 @@ func (m Model) handlePlanKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		if m.activePane == PaneRight {
 			m.diffViewport.HalfViewUp()
 		}
+	case "P":
+		// Switch to another plan
+		return m, m.openPlanList()
 	case m.config.Keys.GeneratePlan:
 		// Generate new plan
 		if !m.planGenerating {
@@ -1533,7 +2593,7 @@ func (m Model) handlePlanKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case m.config.Keys.EditPlan:
 		// Edit plan in nvim
 		if m.planPath != "" {
-			cmd := exec.Command("nvim", m.planPath)
+			cmd := m.runner.Command("nvim", m.planPath)
 			return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
 				return nil
 			})
@@ -1564,6 +2624,10 @@ func (m Model) generatePlan(description string) tea.Cmd {
 func (m Model) handleContextKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	key := msg.String()
 
+	if key == "p" {
+		return m, m.openContextProfiles()
+	}
+
 	// Handle scrolling in right pane
 	if m.activePane == PaneRight {
 		switch key {
@@ -1601,6 +2665,22 @@ func (m Model) handleLeaderKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "?":
 		m.showHelp = true
 		return m, nil
+	case "H":
+		return m, m.openHookHealth()
+	case "D":
+		return m, m.openLogConsole()
+	case "!":
+		m.openNotifications()
+		return m, nil
+	case "T":
+		m.openThemePicker()
+		return m, nil
+	case "z":
+		m.snoozeWarning(false)
+		return m, nil
+	case "Z":
+		m.snoozeWarning(true)
+		return m, nil
 	case "h":
 		m.hideLeftPane = !m.hideLeftPane
 		if m.hideLeftPane {
@@ -1629,6 +2709,9 @@ func (m Model) handleLeaderKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	case "5":
 		m.switchToMode(LeftPaneModeContext)
 		return m, nil
+	case "6":
+		m.switchToMode(LeftPaneModeChat)
+		return m, nil
 	}
 
 	// Context-sensitive actions based on pane and mode
@@ -1648,6 +2731,8 @@ func (m Model) handleLeaderKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m.handleLeaderKeyPlan(key)
 	case LeftPaneModeContext:
 		return m.handleLeaderKeyContext(key)
+	case LeftPaneModeChat:
+		return m.handleLeaderKeyChat(key)
 	}
 
 	return m, nil
@@ -1659,15 +2744,17 @@ func (m Model) handleLeaderKeyRightPane(key string) (tea.Model, tea.Cmd) {
 	case "g": // Open in nvim at line
 		if len(m.changes) > 0 {
 			change := m.changes[m.selectedIndex]
-			cmd := exec.Command("nvim", fmt.Sprintf("+%d", change.LineNum), change.FilePath)
-			return m, tea.ExecProcess(cmd, func(err error) tea.Msg { return nil })
+			return m, m.openInNvim(change.FilePath, change.LineNum)
 		}
 	case "o": // Open in nvim (file only)
 		if len(m.changes) > 0 {
 			change := m.changes[m.selectedIndex]
-			cmd := exec.Command("nvim", change.FilePath)
-			return m, tea.ExecProcess(cmd, func(err error) tea.Msg { return nil })
+			return m, m.openInNvim(change.FilePath, 0)
 		}
+	case "a": // Attach a review note
+		return m, m.openNoteInput()
+	case "F": // Send the change back to Claude as feedback
+		m.sendSelectedFeedback()
 	}
 	return m, nil
 }
@@ -1678,18 +2765,37 @@ func (m Model) handleLeaderKeyHistory(key string) (tea.Model, tea.Cmd) {
 	case "g": // Open in nvim at line
 		if len(m.changes) > 0 {
 			change := m.changes[m.selectedIndex]
-			cmd := exec.Command("nvim", fmt.Sprintf("+%d", change.LineNum), change.FilePath)
-			return m, tea.ExecProcess(cmd, func(err error) tea.Msg { return nil })
+			return m, m.openInNvim(change.FilePath, change.LineNum)
 		}
 	case "o": // Open in nvim (file only)
 		if len(m.changes) > 0 {
 			change := m.changes[m.selectedIndex]
-			cmd := exec.Command("nvim", change.FilePath)
-			return m, tea.ExecProcess(cmd, func(err error) tea.Msg { return nil })
-		}
+			return m, m.openInNvim(change.FilePath, 0)
+		}
+	case "b": // Toggle bookmark
+		m.toggleBookmark()
+	case "a": // Attach a review note
+		return m, m.openNoteInput()
+	case "B": // Toggle bookmarks-only filter
+		m.toggleBookmarksOnly()
+	case "c": // Commit selected/bookmarked changes
+		return m, m.openCommitInput()
+	case "r": // Restore file to before the session's first edit
+		return m, m.confirmRestoreSnapshot()
+	case "n": // Name the selected change's Claude session
+		return m, m.openSessionNameInput()
+	case "f": // Open new changes in nvim as they arrive
+		m.toggleFollow()
+	case "v": // Approve or reject changes one by one
+		return m, m.toggleReview()
+	case "F": // Send the selected change back to Claude as feedback
+		m.sendSelectedFeedback()
+	case "R": // Send the changes rejected in review back to Claude
+		m.sendRejectedFeedback()
 	case "x": // Clear history
 		m.changes = nil
 		m.selectedIndex = 0
+		m.bookmarksOnly = false
 		m.diffViewport.SetContent(m.renderRightPane())
 		m.addToast("History cleared", ToastInfo)
 	}
@@ -1704,36 +2810,30 @@ func (m Model) handleLeaderKeyPrompts(key string) (tea.Model, tea.Cmd) {
 	case "N": // New global prompt
 		return m.createNewPrompt(true)
 	case "e": // Edit prompt
-		if len(m.promptList) > 0 {
-			return m.editPrompt(m.promptList[m.promptSelected])
+		if len(m.promptFilteredList) > 0 {
+			return m.editPrompt(m.promptFilteredList[m.promptSelected])
 		}
 	case "y": // Yank prompt
-		if len(m.promptList) > 0 {
-			p := m.promptList[m.promptSelected]
-			expanded := m.expandPromptVariables(p.Content)
-			if err := prompt.Inject(expanded, prompt.InjectClipboard); err != nil {
-				m.addToast("Failed to copy", ToastError)
-			} else {
-				m.addToast("Copied to clipboard", ToastSuccess)
-			}
+		if len(m.promptFilteredList) > 0 {
+			m.usePrompt(m.promptFilteredList[m.promptSelected], promptVarsYank)
 		}
 	case "d": // Delete prompt
-		if len(m.promptList) > 0 && m.promptStore != nil {
-			p := m.promptList[m.promptSelected]
+		if len(m.promptFilteredList) > 0 && m.promptStore != nil {
+			p := m.promptFilteredList[m.promptSelected]
 			if err := m.promptStore.Delete(p.Path); err != nil {
 				m.addToast(err.Error(), ToastError)
 			} else {
 				m.addToast("Deleted "+p.Name, ToastSuccess)
 				m.refreshPromptList()
-				if m.promptSelected >= len(m.promptList) && m.promptSelected > 0 {
+				if m.promptSelected >= len(m.promptFilteredList) && m.promptSelected > 0 {
 					m.promptSelected--
 				}
 				m.diffViewport.SetContent(m.renderRightPane())
 			}
 		}
 	case "v": // Create version
-		if len(m.promptList) > 0 && m.promptStore != nil {
-			p := m.promptList[m.promptSelected]
+		if len(m.promptFilteredList) > 0 && m.promptStore != nil {
+			p := m.promptFilteredList[m.promptSelected]
 			if err := m.promptStore.CreateVersion(&p); err != nil {
 				m.addToast(err.Error(), ToastError)
 			} else {
@@ -1743,28 +2843,31 @@ func (m Model) handleLeaderKeyPrompts(key string) (tea.Model, tea.Cmd) {
 			}
 		}
 	case "V": // View versions
-		if len(m.promptList) > 0 && m.promptStore != nil {
+		if len(m.promptFilteredList) > 0 && m.promptStore != nil {
 			m.loadVersionList()
 			if len(m.promptVersions) > 0 {
 				m.promptShowVersions = true
 				m.promptVersionSelected = 0
+				m.promptVersionDiff = false
+				m.promptVersionBase = 0
 				m.diffViewport.SetContent(m.renderRightPane())
 			} else {
 				m.addToast("No versions found", ToastWarning)
 			}
 		}
+	case "S": // Sync the prompt library with its git remote
+		if m.promptStore != nil {
+			m.addToast("Syncing prompt library...", ToastInfo)
+			return m, m.syncPromptsCmd()
+		}
 	case "i": // Cycle inject method
-		m.promptInjectMethod = (m.promptInjectMethod + 1) % 2
+		m.cycleInjectMethod()
 		m.addToast(fmt.Sprintf("Method: %s", prompt.MethodName(m.promptInjectMethod)), ToastInfo)
-	case "enter": // Send prompt (via inject method)
-		if len(m.promptList) > 0 {
-			p := m.promptList[m.promptSelected]
-			expanded := m.expandPromptVariables(p.Content)
-			if err := prompt.Inject(expanded, m.promptInjectMethod); err != nil {
-				m.addToast("Failed to inject", ToastError)
-			} else {
-				m.addToast(fmt.Sprintf("Sent via %s", prompt.MethodName(m.promptInjectMethod)), ToastSuccess)
-			}
+	case "enter": // Send prompt (via inject method, after confirmation)
+		m.confirmSendPrompt()
+	case "s": // Queue as an objective for Claude to carry out
+		if len(m.promptFilteredList) > 0 {
+			m.usePrompt(m.promptFilteredList[m.promptSelected], promptVarsQueue)
 		}
 	}
 	return m, nil
@@ -1774,16 +2877,22 @@ func (m Model) handleLeaderKeyPrompts(key string) (tea.Model, tea.Cmd) {
 func (m Model) handleLeaderKeyRalph(key string) (tea.Model, tea.Cmd) {
 	switch key {
 	case "C": // Cancel ralph
-		if _, err := ralph.CancelLoop(); err != nil {
-			m.addToast(err.Error(), ToastError)
-		} else {
-			m.addToast("Ralph cancelled", ToastSuccess)
-			m.loadRalphState()
-		}
+		m.cancelRalphLoop()
+	case "l": // Loop history
+		return m, m.toggleRalphHistory()
 	case "r": // Refresh
 		m.loadRalphState()
 		m.diffViewport.SetContent(m.renderRightPane())
 		m.addToast("Refreshed", ToastInfo)
+		return m, m.queryRalphTimelineCmd()
+	case "p": // Pause or resume
+		m.toggleRalphPause()
+	case "+", "=": // Raise max iterations
+		m.bumpRalphMaxIterations(ralphMaxStep)
+	case "-": // Lower max iterations
+		m.bumpRalphMaxIterations(-ralphMaxStep)
+	case "P": // Edit the completion promise
+		return m, m.editRalphPromise()
 	}
 	return m, nil
 }
@@ -1797,7 +2906,7 @@ func (m Model) handleLeaderKeyPlan(key string) (tea.Model, tea.Cmd) {
 		m.addToast("Enter plan description", ToastInfo)
 	case "e": // Edit plan
 		if m.planPath != "" {
-			cmd := exec.Command("nvim", m.planPath)
+			cmd := m.runner.Command("nvim", m.planPath)
 			return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
 				return planEditedMsg{}
 			})
@@ -1806,6 +2915,14 @@ func (m Model) handleLeaderKeyPlan(key string) (tea.Model, tea.Cmd) {
 		m.loadPlanFile()
 		m.diffViewport.SetContent(m.renderRightPane())
 		m.addToast("Refreshed", ToastInfo)
+	case "s": // Run the plan: queue it as an objective for Claude to carry out
+		return m, m.runPlan(promptVarsQueue)
+	case "L": // Run the plan as a Ralph loop
+		return m, m.runPlan(promptVarsRalph)
+	case "x": // Check or uncheck the selected task
+		m.togglePlanTask()
+	case "p": // Switch to another plan
+		return m, m.openPlanList()
 	}
 	return m, nil
 }
@@ -1864,10 +2981,30 @@ func (m Model) handleLeaderKeyContext(key string) (tea.Model, tea.Cmd) {
 		m.gitBranchInput.Focus()
 		m.gitRepoInput.Blur()
 		return m, textinput.Blink
+	case "i":
+		// Set Terraform workspace / Pulumi stack - multi-field: workspace, tool, dir
+		m.contextEditMode = true
+		m.contextEditField = "iac"
+		m.iacFocusedField = 0 // Start at workspace
+		// Pre-fill from current context
+		if iac := m.contextCurrent.GetIaC(); iac != nil {
+			m.iacWorkspaceInput.SetValue(iac.Workspace)
+			m.iacToolInput.SetValue(iac.Tool)
+			m.iacDirInput.SetValue(iac.Dir)
+		} else {
+			m.iacWorkspaceInput.Reset()
+			m.iacToolInput.Reset()
+			m.iacDirInput.Reset()
+		}
+		m.iacWorkspaceInput.Focus()
+		m.iacToolInput.Blur()
+		m.iacDirInput.Blur()
+		return m, textinput.Blink
 	case "e":
 		// Set environment variables - single KEY=VALUE field
 		m.contextEditMode = true
 		m.contextEditField = "env"
+		m.contextEditSecret = nil
 		m.envInput.Reset()
 		m.envInput.Focus()
 		return m, textinput.Blink
@@ -1875,6 +3012,7 @@ func (m Model) handleLeaderKeyContext(key string) (tea.Model, tea.Cmd) {
 		// Set custom values - single KEY=VALUE field
 		m.contextEditMode = true
 		m.contextEditField = "custom"
+		m.contextEditSecret = nil
 		m.customInput.Reset()
 		m.customInput.Focus()
 		return m, textinput.Blink
@@ -1908,6 +3046,16 @@ func (m Model) handleLeaderKeyContext(key string) (tea.Model, tea.Cmd) {
 				m.addToast("Git context cleared", ToastSuccess)
 			}
 		}
+	case "I":
+		// Clear Terraform workspace / Pulumi stack
+		if m.contextCurrent != nil {
+			m.contextCurrent.Clear("iac")
+			if err := m.contextCurrent.Save(); err != nil {
+				m.addToast(fmt.Sprintf("Failed to clear Terraform/Pulumi: %v", err), ToastError)
+			} else {
+				m.addToast("Terraform/Pulumi context cleared", ToastSuccess)
+			}
+		}
 	case "E":
 		// Clear environment variables
 		if m.contextCurrent != nil {
@@ -1936,7 +3084,7 @@ func (m Model) handleLeaderKeyContext(key string) (tea.Model, tea.Cmd) {
 				m.addToast(fmt.Sprintf("Failed to clear context: %v", err), ToastError)
 			} else {
 				// Also clear via CLI
-				cmd := exec.Command("claude", "-p", "/prompt:context clear", "--mcp", "{}")
+				cmd := m.runner.Command("claude", "-p", "/prompt:context clear", "--mcp", "{}")
 				cmd.Env = append(os.Environ(), "CLAUDE_CODE_ENTRYPOINT=cli")
 				return m, tea.ExecProcess(cmd, func(err error) tea.Msg {
 					if err != nil {
@@ -1954,6 +3102,13 @@ func (m Model) handleLeaderKeyContext(key string) (tea.Model, tea.Cmd) {
 		} else {
 			m.addToast(fmt.Sprintf("Failed to reload context: %v", err), ToastError)
 		}
+	case "p":
+		// Switch to another profile
+		return m, m.openContextProfiles()
+	case "d":
+		// Detect context from kubectl, AWS_* variables and git, then confirm
+		m.addToast("Detecting context...", ToastInfo)
+		return m, detectContextCmd()
 	case "l":
 		// Toggle showing all contexts list
 		m.contextShowList = !m.contextShowList
@@ -1968,7 +3123,7 @@ func (m Model) handleLeaderKeyContext(key string) (tea.Model, tea.Cmd) {
 
 // cycleMode cycles through the available modes
 func (m *Model) cycleMode(direction int) {
-	modes := []LeftPaneMode{LeftPaneModeHistory, LeftPaneModePrompts, LeftPaneModeRalph, LeftPaneModePlan, LeftPaneModeContext}
+	modes := []LeftPaneMode{LeftPaneModeHistory, LeftPaneModePrompts, LeftPaneModeRalph, LeftPaneModePlan, LeftPaneModeContext, LeftPaneModeChat}
 	currentIdx := 0
 	for i, mode := range modes {
 		if mode == m.leftPaneMode {
@@ -2003,22 +3158,33 @@ func (m *Model) switchToMode(mode LeftPaneMode) {
 		m.refreshPromptList()
 	case LeftPaneModeRalph:
 		m.loadRalphState()
-		// Start auto-refresh ticker (every 5 seconds)
-		m.ralphRefreshCmd = tea.Tick(5*time.Second, func(t time.Time) tea.Msg {
+		// Start auto-refresh ticker (every 5 seconds), fetching the
+		// iteration timeline right away
+		m.ralphRefreshCmd = tea.Batch(tea.Tick(5*time.Second, func(t time.Time) tea.Msg {
 			return ralphRefreshTickMsg{Time: t}
-		})
+		}), m.queryRalphTimelineCmd())
 		logger.Log("Started Ralph refresh ticker (5s interval)")
 	case LeftPaneModePlan:
 		m.loadPlanFile()
+	case LeftPaneModeContext:
+		// Pick up values and stale flags the daemon refreshed
+		if ctx, err := workingctx.Load(); err == nil {
+			m.contextCurrent = ctx
+		}
 	}
 
 	m.updateViewportSize()
 	m.diffViewport.SetContent(m.renderRightPane())
+	if mode == LeftPaneModeChat {
+		m.diffViewport.GotoBottom()
+	}
 	logger.Log("Switched from %d to %d mode", prevMode, mode)
 }
 
 // loadRalphState loads the Ralph Loop state from the state file
 func (m *Model) loadRalphState() {
+	prev := m.ralphState
+	wasActive := prev != nil && prev.Active
 	state, err := ralph.LoadState()
 	if err != nil {
 		logger.Log("Failed to load Ralph state: %v", err)
@@ -2026,28 +3192,48 @@ func (m *Model) loadRalphState() {
 		return
 	}
 	m.ralphState = state
+
+	// Record loop start/stop transitions on the daemon timeline
+	isActive := state != nil && state.Active
+	if isActive && !wasActive {
+		m.reportDaemonEvent("ralph_start", "info", fmt.Sprintf("Ralph loop started (max %d iterations)", state.MaxIterations))
+	} else if wasActive && !isActive {
+		m.reportDaemonEvent("ralph_stop", "info", "Ralph loop stopped")
+		// Keep the loop in the history now its state file is gone
+		outcome := prev.Outcome()
+		m.recordRalphLoop(prev, outcome)
+		m.addToast(fmt.Sprintf("Ralph loop ended: %s after %d iterations", ralphOutcomeLabel(outcome), prev.Iteration), ToastInfo)
+		m.ralphTimeline = nil
+	}
 	if state != nil {
 		logger.Log("Loaded Ralph state: active=%v, iteration=%d/%d", state.Active, state.Iteration, state.MaxIterations)
 	}
 }
 
-// renderTabBar renders the tab bar with all 5 modes
+// renderTabBar renders the tab bar with all 6 modes
 func (m Model) renderTabBar() string {
-	tabs := []struct {
-		num  string
-		name string
-		mode LeftPaneMode
-		icon string
-	}{
-		{"1", "History", LeftPaneModeHistory, "📜"},
-		{"2", "Prompts", LeftPaneModePrompts, "📝"},
-		{"3", "Ralph", LeftPaneModeRalph, "🔄"},
-		{"4", "Plan", LeftPaneModePlan, "📋"},
-		{"5", "Context", LeftPaneModeContext, "⚙️"},
-	}
+	return strings.Join(m.tabBarParts(), " ")
+}
+
+// tabBarTabs are the tab bar's tabs, in order
+var tabBarTabs = []struct {
+	num  string
+	name string
+	mode LeftPaneMode
+	icon string
+}{
+	{"1", "History", LeftPaneModeHistory, "📜"},
+	{"2", "Prompts", LeftPaneModePrompts, "📝"},
+	{"3", "Ralph", LeftPaneModeRalph, "🔄"},
+	{"4", "Plan", LeftPaneModePlan, "📋"},
+	{"5", "Context", LeftPaneModeContext, "⚙️"},
+	{"6", "Chat", LeftPaneModeChat, "💬"},
+}
 
+// tabBarParts renders each of tabBarTabs
+func (m Model) tabBarParts() []string {
 	var parts []string
-	for _, tab := range tabs {
+	for _, tab := range tabBarTabs {
 		if tab.mode == m.leftPaneMode {
 			// Active tab - show full name, highlighted
 			label := tab.num + ":" + tab.name
@@ -2067,18 +3253,21 @@ func (m Model) renderTabBar() string {
 				if m.planPath != "" {
 					stateIndicator = "•"
 				}
+			case LeftPaneModeChat:
+				if m.chatAvailable() || m.objectiveSession != nil {
+					stateIndicator = "•"
+				}
 			}
 			parts = append(parts, m.theme.Dim.Render(label+stateIndicator))
 		}
 	}
-
-	return strings.Join(parts, " ")
+	return parts
 }
 
 // renderRalphStatus renders the Ralph status for the left pane
 func (m Model) renderRalphStatus() string {
 	var sb strings.Builder
-	listWidth := m.width / 3
+	listWidth := m.leftPaneWidth()
 
 	sb.WriteString(m.theme.Title.Render("Ralph Loop") + "\n")
 	sb.WriteString(m.theme.Dim.Render(strings.Repeat("─", listWidth-4)) + "\n\n")
@@ -2122,7 +3311,7 @@ func (m Model) renderRalphStatus() string {
 // renderPlanList renders the plan info for the left pane
 func (m Model) renderPlanList() string {
 	var sb strings.Builder
-	listWidth := m.width / 3
+	listWidth := m.leftPaneWidth()
 
 	sb.WriteString(m.theme.Title.Render("Plan") + "\n")
 	sb.WriteString(m.theme.Dim.Render(strings.Repeat("─", listWidth-4)) + "\n\n")
@@ -2136,6 +3325,10 @@ func (m Model) renderPlanList() string {
 		return sb.String()
 	}
 
+	if m.showPlanList {
+		return m.renderPlanPicker(listWidth)
+	}
+
 	// Show generating status
 	if m.planGenerating {
 		sb.WriteString(m.theme.Selected.Render("⏳ Generating...") + "\n\n")
@@ -2149,13 +3342,21 @@ func (m Model) renderPlanList() string {
 		sb.WriteString(m.theme.Dim.Render("Press 'G' to generate a new\n"))
 		sb.WriteString(m.theme.Dim.Render("plan with Claude.\n\n"))
 		sb.WriteString(m.theme.Dim.Render("Or press 'r' to refresh if\n"))
-		sb.WriteString(m.theme.Dim.Render("Claude created one."))
+		sb.WriteString(m.theme.Dim.Render("Claude created one, or 'P'\n"))
+		sb.WriteString(m.theme.Dim.Render("to pick a plan."))
 		return sb.String()
 	}
 
 	// Show current plan info
 	planName := strings.TrimSuffix(filepath.Base(m.planPath), ".md")
-	sb.WriteString(m.theme.Selected.Render("📋 "+planName) + "\n\n")
+	pin := ""
+	if m.planPath == m.planPinned {
+		pin = " 📌"
+	}
+	sb.WriteString(m.theme.Selected.Render("📋 "+planName+pin) + "\n\n")
+
+	// Checklist, leaving room for the plan info below it
+	sb.WriteString(m.renderPlanTasks(listWidth-6, m.height-24))
 
 	// Plan file location
 	sb.WriteString(m.theme.Dim.Render("Location:") + "\n")
@@ -2166,12 +3367,12 @@ func (m Model) renderPlanList() string {
 	sb.WriteString(m.theme.Normal.Render(location) + "\n\n")
 
 	// File info
-	if info, err := os.Stat(m.planPath); err == nil {
+	if info, err := m.fs.Stat(m.planPath); err == nil {
 		sb.WriteString(m.theme.Dim.Render("Modified: "+info.ModTime().Format("2006-01-02 15:04")) + "\n")
 		sb.WriteString(m.theme.Dim.Render(fmt.Sprintf("Size: %d bytes", info.Size())) + "\n\n")
 	}
 
-	sb.WriteString(m.theme.Dim.Render("G:new  e:edit  r:refresh"))
+	sb.WriteString(m.theme.Dim.Render("j/k:task  space:check  P:plans  G:new  e:edit  r:refresh"))
 
 	return sb.String()
 }
@@ -2217,7 +3418,12 @@ func (m Model) renderContextList() string {
 			if len(parts) == 2 {
 				key := strings.TrimSpace(parts[0])
 				value := strings.TrimSpace(parts[1])
-				sb.WriteString(m.theme.Dim.Render(key+": ") + m.theme.Normal.Render(value) + "\n")
+				sb.WriteString(m.theme.Dim.Render(key+": ") + m.theme.Normal.Render(value))
+				// Flag sections the daemon found stale
+				if reason := m.contextCurrent.StaleReason(contextSection(key)); reason != "" {
+					sb.WriteString(m.theme.Modified.Render("  ⚠ " + reason))
+				}
+				sb.WriteString("\n")
 			}
 		} else {
 			sb.WriteString(line + "\n")
@@ -2225,9 +3431,9 @@ func (m Model) renderContextList() string {
 	}
 
 	// Stale warning
-	if m.contextCurrent.IsStale() {
+	if m.contextCurrent.IsStale() && !m.warningSuppressed(warnStaleContext) {
 		sb.WriteString("\n")
-		sb.WriteString(m.theme.Status.Render("⚠️ Context is stale (>24h)"))
+		sb.WriteString(m.theme.Status.Render("⚠️ " + staleContextText(m.contextCurrent)))
 		sb.WriteString("\n")
 	}
 
@@ -2248,6 +3454,9 @@ func (m Model) renderContextList() string {
 			for _, ctx := range contexts {
 				// Project path
 				sb.WriteString(m.theme.Selected.Render("📁 " + ctx.ProjectRoot))
+				if ctx.Profile != "" {
+					sb.WriteString(m.theme.Dim.Render("  profile " + ctx.Profile))
+				}
 				sb.WriteString("\n")
 
 				// Show Kubernetes context
@@ -2290,11 +3499,17 @@ func (m Model) renderContextList() string {
 					}
 				}
 
+				// Show Terraform workspace / Pulumi stack
+				if iac := ctx.GetIaC(); iac != nil && iac.Workspace != "" {
+					sb.WriteString(m.theme.Dim.Render("  🏗️ "+iac.Label()+": ") + m.theme.Normal.Render(iac.String()))
+					sb.WriteString("\n")
+				}
+
 				// Show environment variables
 				if env := ctx.GetEnv(); env != nil && len(env) > 0 {
 					var envPairs []string
 					for k, v := range env {
-						envPairs = append(envPairs, k+"="+v)
+						envPairs = append(envPairs, k+"="+ctx.DisplayValue("env", k, v))
 					}
 					// Show first 3, then "..." if more
 					if len(envPairs) > 3 {
@@ -2309,7 +3524,7 @@ func (m Model) renderContextList() string {
 				if custom := ctx.GetCustom(); custom != nil && len(custom) > 0 {
 					var customPairs []string
 					for k, v := range custom {
-						customPairs = append(customPairs, k+"="+v)
+						customPairs = append(customPairs, k+"="+ctx.DisplayValue("custom", k, v))
 					}
 					// Show first 3, then "..." if more
 					if len(customPairs) > 3 {
@@ -2343,24 +3558,52 @@ func (m Model) View() string {
 	if m.showHelp {
 		return m.renderHelp()
 	}
+	if m.showHookHealth {
+		return m.renderHookHealth()
+	}
+	if m.showLogConsole {
+		return m.renderLogConsole()
+	}
+	if m.showNotifications {
+		return m.renderNotifications()
+	}
+	if m.showThemePicker {
+		return m.renderThemePicker()
+	}
+	if m.showPromptTagPicker {
+		return m.renderPromptTagPicker()
+	}
+	if m.showTmuxPicker {
+		return m.renderTmuxPicker()
+	}
+	if m.showChatPicker {
+		return m.renderChatPicker()
+	}
+	if m.promptVarForm != nil {
+		return m.renderPromptVarForm()
+	}
 
 	// Render header with tab bar
 	tabBar := m.renderTabBar()
 
 	header := m.theme.Title.Render("claude-mon") + " " + tabBar
+	if branch := m.renderVCSStatus(); branch != "" {
+		if gap := m.width - lipgloss.Width(header) - lipgloss.Width(branch); gap >= 1 {
+			header += strings.Repeat(" ", gap) + branch
+		}
+	}
 	header = lipgloss.PlaceHorizontal(m.width, lipgloss.Left, header)
+	if len(m.alerts) > 0 {
+		header = m.renderAlertBanner()
+	}
 
 	// Two-pane layout
 	minimapStr := m.renderMinimap()
-	minimapWidth := 0
-	if m.showMinimap {
-		minimapWidth = 2
-	}
 
 	// Get left pane content first to calculate its width
 	var leftContent string
 	var leftBox lipgloss.Style
-	if !m.hideLeftPane && m.leftPaneMode != LeftPaneModeRalph && m.leftPaneMode != LeftPaneModeContext {
+	if !m.hideLeftPane && m.leftPaneMode != LeftPaneModeRalph && m.leftPaneMode != LeftPaneModeContext && m.leftPaneMode != LeftPaneModeChat {
 		// Both panes visible - get left content
 		switch m.leftPaneMode {
 		case LeftPaneModePrompts:
@@ -2379,27 +3622,25 @@ func (m Model) View() string {
 		}
 	}
 
-	// Calculate pane widths - use fixed ratio for stability
-	var leftWidth, rightWidth int
-	if m.hideLeftPane || m.leftPaneMode == LeftPaneModeRalph || m.leftPaneMode == LeftPaneModeContext {
-		// Left pane hidden or in Ralph/Context mode (full-width right pane)
-		leftWidth = 0
-		rightWidth = m.width - 2 - minimapWidth
-	} else {
-		// Fixed 1/3 width for left pane to prevent layout shifts when scrolling
-		leftWidth = m.width / 3
-		if leftWidth < 25 {
-			leftWidth = 25
-		}
-		// Right pane gets remaining space
-		rightWidth = m.width - leftWidth - 3 - minimapWidth
-	}
+	leftWidth, rightWidth, _ := m.paneWidths()
 
 	// Render right pane (diff, context, or prompt preview)
 	var rightContent string
 	if m.leftPaneMode == LeftPaneModeContext && !m.contextEditMode {
 		// Show context in full-width right pane
 		rightContent = m.renderContextList()
+	} else if m.leftPaneMode == LeftPaneModeChat {
+		// Transcript with the tool activity beside it and the message input
+		// below it
+		rightContent = m.diffViewport.View()
+		if m.showObjectives {
+			rightContent += m.renderObjectiveInput()
+		} else {
+			if len(m.chatTools) > 0 {
+				rightContent = lipgloss.JoinHorizontal(lipgloss.Top, rightContent, " ", m.renderChatTools())
+			}
+			rightContent += m.renderChatInput()
+		}
 	} else {
 		rightContent = m.diffViewport.View()
 	}
@@ -2507,6 +3748,16 @@ func (m Model) View() string {
 		mainView = strings.Join(lines, "\n")
 	}
 
+	// Overlay the profile switcher in the Context tab
+	if m.showContextProfiles && m.leftPaneMode == LeftPaneModeContext {
+		mainView = CenterOverlay(mainView, m.renderContextProfiles(), m.width, m.height)
+	}
+
+	// Overlay detected context awaiting confirmation
+	if m.contextDetected != nil {
+		mainView = CenterOverlay(mainView, m.renderContextDetectPopup(), m.width, m.height)
+	}
+
 	// Overlay toasts in top-right corner
 	if len(m.toasts) > 0 {
 		toastView := m.renderToasts()
@@ -2561,7 +3812,6 @@ func (m Model) renderToasts() string {
 
 		// Style based on toast type
 		var style lipgloss.Style
-		var icon string
 		switch t.Type {
 		case ToastSuccess:
 			style = lipgloss.NewStyle().
@@ -2569,28 +3819,24 @@ func (m Model) renderToasts() string {
 				Foreground(lipgloss.Color("#90EE90")).
 				Padding(0, 1).
 				Bold(true)
-			icon = "✓ "
 		case ToastError:
 			style = lipgloss.NewStyle().
 				Background(lipgloss.Color("#5a2727")).
 				Foreground(lipgloss.Color("#ff6b6b")).
 				Padding(0, 1).
 				Bold(true)
-			icon = "✗ "
 		case ToastWarning:
 			style = lipgloss.NewStyle().
 				Background(lipgloss.Color("#5a4a27")).
 				Foreground(lipgloss.Color("#ffd93d")).
 				Padding(0, 1).
 				Bold(true)
-			icon = "⚠ "
 		default: // ToastInfo
 			style = lipgloss.NewStyle().
 				Background(lipgloss.Color("#27405a")).
 				Foreground(lipgloss.Color("#87CEEB")).
 				Padding(0, 1).
 				Bold(true)
-			icon = "ℹ "
 		}
 
 		// Truncate long messages
@@ -2600,7 +3846,7 @@ func (m Model) renderToasts() string {
 			msg = msg[:maxLen-3] + "..."
 		}
 
-		sb.WriteString(style.Render(icon + msg))
+		sb.WriteString(style.Render(toastIcon(t.Type) + msg))
 		sb.WriteString("\n")
 	}
 
@@ -2699,17 +3945,53 @@ func (m Model) renderContextEditPopup() string {
 		content.WriteString(label + "\n")
 		content.WriteString("  " + m.gitRepoInput.View() + "\n")
 
+	case "iac":
+		content.WriteString(m.theme.Title.Render("🏗️ Terraform / Pulumi") + "\n")
+		content.WriteString(m.theme.Dim.Render(strings.Repeat("─", 50)) + "\n\n")
+
+		// Workspace field
+		label := "Workspace / Stack:"
+		if m.iacFocusedField == 0 {
+			label = m.theme.Selected.Render("> " + label)
+		} else {
+			label = m.theme.Dim.Render("  " + label)
+		}
+		content.WriteString(label + "\n")
+		content.WriteString("  " + m.iacWorkspaceInput.View() + "\n\n")
+
+		// Tool field
+		label = "Tool:"
+		if m.iacFocusedField == 1 {
+			label = m.theme.Selected.Render("> " + label)
+		} else {
+			label = m.theme.Dim.Render("  " + label)
+		}
+		content.WriteString(label + "\n")
+		content.WriteString("  " + m.iacToolInput.View() + "\n\n")
+
+		// Dir field
+		label = "Directory:"
+		if m.iacFocusedField == 2 {
+			label = m.theme.Selected.Render("> " + label)
+		} else {
+			label = m.theme.Dim.Render("  " + label)
+		}
+		content.WriteString(label + "\n")
+		content.WriteString("  " + m.iacDirInput.View() + "\n")
+
 	case "env":
 		content.WriteString(m.theme.Title.Render("📦 Environment Variable") + "\n")
 		content.WriteString(m.theme.Dim.Render(strings.Repeat("─", 50)) + "\n\n")
 		content.WriteString(m.theme.Dim.Render("Format: KEY=value or KEY=\"value with spaces\"") + "\n\n")
-		content.WriteString(m.envInput.View() + "\n")
+		content.WriteString(m.envInput.View() + "\n\n")
+		content.WriteString(m.renderContextEditSecret() + "\n")
 
 	case "custom":
 		content.WriteString(m.theme.Title.Render("🔧 Custom Value") + "\n")
 		content.WriteString(m.theme.Dim.Render(strings.Repeat("─", 50)) + "\n\n")
 		content.WriteString(m.theme.Dim.Render("Format: KEY=value or KEY=\"value with spaces\"") + "\n\n")
-		content.WriteString(m.customInput.View() + "\n")
+		content.WriteString(m.customInput.View() + "\n\n")
+		content.WriteString(m.renderContextEditSecret() + "\n")
 	}
 
 	// Show completion overlay if active
@@ -2741,11 +4023,16 @@ func (m Model) renderContextEditPopup() string {
 			}
 		}
 
-		if len(m.contextCompletionMatches) == 0 {
+		if m.contextCompletionLoading {
+			content.WriteString("  " + m.contextCompletionSpinner.View() + m.theme.Dim.Render(" Loading...") + "\n")
+		} else if len(m.contextCompletionMatches) == 0 {
 			content.WriteString(m.theme.Dim.Render("  (no matches)") + "\n")
 		} else if len(m.contextCompletionMatches) > maxDisplay {
 			content.WriteString(m.theme.Dim.Render(fmt.Sprintf("  ... +%d more", len(m.contextCompletionMatches)-maxDisplay)) + "\n")
 		}
+		if m.contextCompletionErr != "" {
+			content.WriteString(m.theme.Modified.Render("  ⚠ "+truncateRunes(m.contextCompletionErr, 45)) + "\n")
+		}
 
 		content.WriteString("\n")
 		content.WriteString(m.theme.Dim.Render("↑/↓:navigate  Enter:select  Esc:close"))
@@ -2768,64 +4055,6 @@ func (m Model) renderContextEditPopup() string {
 	return popupStyle.Render(contentStr)
 }
 
-// loadContextCompletions loads completion candidates for the current focused field
-func (m *Model) loadContextCompletions() {
-	switch m.contextEditField {
-	case "k8s":
-		// Load completions based on which field is focused
-		switch m.k8sFocusedField {
-		case 0: // kubeconfig
-			m.contextCompletionCandidates = loadK8sKubeconfigs()
-		case 1: // context
-			// Use kubeconfig from input to find contexts
-			kubeconfig := m.k8sKubeconfigInput.Value()
-			if kubeconfig == "" {
-				home, _ := os.UserHomeDir()
-				kubeconfig = filepath.Join(home, ".kube", "config")
-			}
-			m.contextCompletionCandidates = loadK8sContexts(kubeconfig)
-		case 2: // namespace
-			// Use kubeconfig and context from inputs
-			kubeconfig := m.k8sKubeconfigInput.Value()
-			if kubeconfig == "" {
-				home, _ := os.UserHomeDir()
-				kubeconfig = filepath.Join(home, ".kube", "config")
-			}
-			context := m.k8sContextInput.Value()
-			m.contextCompletionCandidates = loadK8sNamespaces(kubeconfig, context)
-		}
-	case "aws":
-		// Load completions based on which field is focused
-		switch m.awsFocusedField {
-		case 0: // profile
-			m.contextCompletionCandidates = loadAWSProfiles()
-		case 1: // region
-			m.contextCompletionCandidates = loadAWSRegions()
-		}
-	case "git":
-		// Load completions based on which field is focused
-		switch m.gitFocusedField {
-		case 0: // branch
-			m.contextCompletionCandidates = loadGitBranches()
-		case 1: // repo
-			m.contextCompletionCandidates = loadGitRepos()
-		}
-	case "env":
-		m.contextCompletionCandidates = loadEnvCompletions()
-	case "custom":
-		m.contextCompletionCandidates = loadCustomCompletions(m.contextCurrent)
-	default:
-		m.contextCompletionCandidates = nil
-	}
-
-	// Initialize matches to all candidates
-	m.contextCompletionMatches = make([]int, len(m.contextCompletionCandidates))
-	for i := range m.contextCompletionCandidates {
-		m.contextCompletionMatches[i] = i
-	}
-	m.contextCompletionSelected = 0
-}
-
 // computeContextCompletionMatches filters candidates by query
 func (m *Model) computeContextCompletionMatches(query string) {
 	if query == "" {
@@ -2846,9 +4075,9 @@ func (m *Model) computeContextCompletionMatches(query string) {
 }
 
 // loadK8sKubeconfigs returns available kubeconfig files from ~/.kube
-func loadK8sKubeconfigs() []string {
+func loadK8sKubeconfigs(fsys FS) []string {
 	var results []string
-	home, err := os.UserHomeDir()
+	home, err := fsys.UserHomeDir()
 	if err != nil {
 		return results
 	}
@@ -2856,14 +4085,14 @@ func loadK8sKubeconfigs() []string {
 	kubeDir := filepath.Join(home, ".kube")
 
 	// Find all kubeconfig files
-	entries, err := os.ReadDir(kubeDir)
+	entries, err := fsys.ReadDir(kubeDir)
 	if err != nil {
 		return results
 	}
 
 	// Add default config first if it exists
 	defaultConfig := filepath.Join(kubeDir, "config")
-	if _, err := os.Stat(defaultConfig); err == nil {
+	if _, err := fsys.Stat(defaultConfig); err == nil {
 		results = append(results, defaultConfig)
 	}
 
@@ -2880,7 +4109,7 @@ func loadK8sKubeconfigs() []string {
 
 		// Check if file looks like a kubeconfig (has contexts section)
 		path := filepath.Join(kubeDir, name)
-		if hasKubeconfigContexts(path) {
+		if hasKubeconfigContexts(fsys, path) {
 			results = append(results, path)
 		}
 	}
@@ -2889,8 +4118,8 @@ func loadK8sKubeconfigs() []string {
 }
 
 // hasKubeconfigContexts checks if a file contains a contexts: section
-func hasKubeconfigContexts(path string) bool {
-	data, err := os.ReadFile(path)
+func hasKubeconfigContexts(fsys FS, path string) bool {
+	data, err := fsys.ReadFile(path)
 	if err != nil {
 		return false
 	}
@@ -2898,12 +4127,12 @@ func hasKubeconfigContexts(path string) bool {
 }
 
 // loadK8sContexts returns contexts from a specific kubeconfig file
-func loadK8sContexts(kubeconfigPath string) []string {
+func loadK8sContexts(fsys FS, kubeconfigPath string) []string {
 	if kubeconfigPath == "" {
 		return nil
 	}
 
-	data, err := os.ReadFile(kubeconfigPath)
+	data, err := fsys.ReadFile(kubeconfigPath)
 	if err != nil {
 		return nil
 	}
@@ -2938,9 +4167,7 @@ func loadK8sContexts(kubeconfigPath string) []string {
 }
 
 // loadK8sNamespaces returns namespaces from the cluster using kubectl
-func loadK8sNamespaces(kubeconfigPath, contextName string) []string {
-	var results []string
-
+func loadK8sNamespaces(ctx gocontext.Context, runner Runner, kubeconfigPath, contextName string) ([]string, error) {
 	// Build kubectl command with kubeconfig and context
 	args := []string{"get", "namespaces", "-o", "jsonpath={.items[*].metadata.name}"}
 	if kubeconfigPath != "" {
@@ -2950,41 +4177,32 @@ func loadK8sNamespaces(kubeconfigPath, contextName string) []string {
 		args = append([]string{"--context", contextName}, args...)
 	}
 
-	cmd := exec.Command("kubectl", args...)
-	output, err := cmd.Output()
+	output, err := runner.Output(ctx, "", "kubectl", args...)
 	if err != nil {
-		// Fallback to common namespaces if kubectl fails
-		return []string{"default", "kube-system", "kube-public"}
+		return nil, err
 	}
 
 	// Parse space-separated namespace names
-	namespaces := strings.Fields(string(output))
-	for _, ns := range namespaces {
-		if ns != "" {
-			results = append(results, ns)
-		}
-	}
-
-	return results
+	return strings.Fields(string(output)), nil
 }
 
 // loadAWSCompletions returns AWS profiles from config and credentials
-func loadAWSCompletions() []string {
+func loadAWSCompletions(fsys FS) []string {
 	var results []string
-	home, err := os.UserHomeDir()
+	home, err := fsys.UserHomeDir()
 	if err != nil {
 		return results
 	}
 
 	// Parse ~/.aws/config for [profile xxx] sections
 	configPath := filepath.Join(home, ".aws", "config")
-	if profiles := parseAWSConfigProfiles(configPath); len(profiles) > 0 {
+	if profiles := parseAWSConfigProfiles(fsys, configPath); len(profiles) > 0 {
 		results = append(results, profiles...)
 	}
 
 	// Parse ~/.aws/credentials for [xxx] sections (profile names without "profile " prefix)
 	credsPath := filepath.Join(home, ".aws", "credentials")
-	if profiles := parseAWSCredentialsProfiles(credsPath); len(profiles) > 0 {
+	if profiles := parseAWSCredentialsProfiles(fsys, credsPath); len(profiles) > 0 {
 		results = append(results, profiles...)
 	}
 
@@ -3002,8 +4220,8 @@ func loadAWSCompletions() []string {
 }
 
 // parseAWSConfigProfiles extracts profile names from ~/.aws/config
-func parseAWSConfigProfiles(path string) []string {
-	data, err := os.ReadFile(path)
+func parseAWSConfigProfiles(fsys FS, path string) []string {
+	data, err := fsys.ReadFile(path)
 	if err != nil {
 		return nil
 	}
@@ -3025,8 +4243,8 @@ func parseAWSConfigProfiles(path string) []string {
 }
 
 // parseAWSCredentialsProfiles extracts profile names from ~/.aws/credentials
-func parseAWSCredentialsProfiles(path string) []string {
-	data, err := os.ReadFile(path)
+func parseAWSCredentialsProfiles(fsys FS, path string) []string {
+	data, err := fsys.ReadFile(path)
 	if err != nil {
 		return nil
 	}
@@ -3045,62 +4263,31 @@ func parseAWSCredentialsProfiles(path string) []string {
 	return results
 }
 
-// loadGitCompletions returns git branches and recent repos
-func loadGitCompletions() []string {
-	var results []string
-
-	// Get current repo branches
-	cmd := exec.Command("git", "branch", "--format=%(refname:short)")
-	output, err := cmd.Output()
-	if err == nil {
-		for _, branch := range strings.Split(strings.TrimSpace(string(output)), "\n") {
-			if branch != "" {
-				results = append(results, branch)
-			}
-		}
-	}
-
-	// Add remote branches
-	cmd = exec.Command("git", "branch", "-r", "--format=%(refname:short)")
-	output, err = cmd.Output()
-	if err == nil {
-		for _, branch := range strings.Split(strings.TrimSpace(string(output)), "\n") {
-			if branch != "" && !strings.Contains(branch, "HEAD") {
-				// Remove origin/ prefix for cleaner display
-				branch = strings.TrimPrefix(branch, "origin/")
-				results = append(results, branch)
-			}
-		}
-	}
-
-	// Remove duplicates
-	seen := make(map[string]bool)
-	var unique []string
-	for _, b := range results {
-		if !seen[b] {
-			seen[b] = true
-			unique = append(unique, b)
-		}
+// loadGitCompletions returns the branches (jj: bookmarks) of the repo in
+// dir
+func loadGitCompletions(ctx gocontext.Context, runner Runner, dir string) []string {
+	branches, err := vcs.ListBranches(ctx, runner, dir)
+	if err != nil {
+		return nil
 	}
-
-	return unique
+	return branches
 }
 
 // loadEnvCompletions returns env var suggestions from zsh history
-func loadEnvCompletions() []string {
+func loadEnvCompletions(fsys FS) []string {
 	var results []string
-	home, err := os.UserHomeDir()
+	home, err := fsys.UserHomeDir()
 	if err != nil {
 		return results
 	}
 
 	// Try to read zsh history
 	histPath := filepath.Join(home, ".zsh_history")
-	data, err := os.ReadFile(histPath)
+	data, err := fsys.ReadFile(histPath)
 	if err != nil {
 		// Try alternative location
 		histPath = filepath.Join(home, ".histfile")
-		data, err = os.ReadFile(histPath)
+		data, err = fsys.ReadFile(histPath)
 		if err != nil {
 			return results
 		}
@@ -3152,6 +4339,11 @@ func loadCustomCompletions(ctx *workingctx.Context) []string {
 	}
 
 	for k, v := range custom {
+		if ctx.IsSecret("custom", k) {
+			// Never offer a secret's value
+			results = append(results, k+"=")
+			continue
+		}
 		results = append(results, fmt.Sprintf("%s=%s", k, v))
 	}
 
@@ -3159,8 +4351,8 @@ func loadCustomCompletions(ctx *workingctx.Context) []string {
 }
 
 // loadAWSProfiles returns AWS profiles (alias for loadAWSCompletions)
-func loadAWSProfiles() []string {
-	return loadAWSCompletions()
+func loadAWSProfiles(fsys FS) []string {
+	return loadAWSCompletions(fsys)
 }
 
 // loadAWSRegions returns common AWS regions
@@ -3183,17 +4375,16 @@ func loadAWSRegions() []string {
 }
 
 // loadGitBranches returns git branches (alias for loadGitCompletions)
-func loadGitBranches() []string {
-	return loadGitCompletions()
+func loadGitBranches(ctx gocontext.Context, runner Runner, dir string) []string {
+	return loadGitCompletions(ctx, runner, dir)
 }
 
-// loadGitRepos returns git repository suggestions from recent history
-func loadGitRepos() []string {
+// loadGitRepos returns the remotes of the git repository in dir
+func loadGitRepos(ctx gocontext.Context, runner Runner, dir string) []string {
 	var results []string
 
 	// Get current repo remote
-	cmd := exec.Command("git", "remote", "get-url", "origin")
-	output, err := cmd.Output()
+	output, err := runner.Output(ctx, dir, "git", "remote", "get-url", "origin")
 	if err == nil {
 		repo := strings.TrimSpace(string(output))
 		if repo != "" {
@@ -3202,8 +4393,7 @@ func loadGitRepos() []string {
 	}
 
 	// Try to get other remotes
-	cmd = exec.Command("git", "remote", "-v")
-	output, err = cmd.Output()
+	output, err = runner.Output(ctx, dir, "git", "remote", "-v")
 	if err == nil {
 		seen := make(map[string]bool)
 		for _, line := range strings.Split(string(output), "\n") {
@@ -3257,6 +4447,19 @@ func (m *Model) nextContextField() {
 		case 1:
 			m.gitRepoInput.Focus()
 		}
+	case "iac":
+		m.iacWorkspaceInput.Blur()
+		m.iacToolInput.Blur()
+		m.iacDirInput.Blur()
+		m.iacFocusedField = (m.iacFocusedField + 1) % 3
+		switch m.iacFocusedField {
+		case 0:
+			m.iacWorkspaceInput.Focus()
+		case 1:
+			m.iacToolInput.Focus()
+		case 2:
+			m.iacDirInput.Focus()
+		}
 	}
 }
 
@@ -3296,6 +4499,19 @@ func (m *Model) prevContextField() {
 		case 1:
 			m.gitRepoInput.Focus()
 		}
+	case "iac":
+		m.iacWorkspaceInput.Blur()
+		m.iacToolInput.Blur()
+		m.iacDirInput.Blur()
+		m.iacFocusedField = (m.iacFocusedField + 2) % 3 // +2 to go backwards
+		switch m.iacFocusedField {
+		case 0:
+			m.iacWorkspaceInput.Focus()
+		case 1:
+			m.iacToolInput.Focus()
+		case 2:
+			m.iacDirInput.Focus()
+		}
 	}
 }
 
@@ -3325,6 +4541,15 @@ func (m *Model) setCurrentContextFieldValue(value string) {
 		case 1:
 			m.gitRepoInput.SetValue(value)
 		}
+	case "iac":
+		switch m.iacFocusedField {
+		case 0:
+			m.iacWorkspaceInput.SetValue(value)
+		case 1:
+			m.iacToolInput.SetValue(value)
+		case 2:
+			m.iacDirInput.SetValue(value)
+		}
 	case "env":
 		m.envInput.SetValue(value)
 	case "custom":
@@ -3359,6 +4584,15 @@ func (m Model) updateCurrentContextInput(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case 1:
 			m.gitRepoInput, cmd = m.gitRepoInput.Update(msg)
 		}
+	case "iac":
+		switch m.iacFocusedField {
+		case 0:
+			m.iacWorkspaceInput, cmd = m.iacWorkspaceInput.Update(msg)
+		case 1:
+			m.iacToolInput, cmd = m.iacToolInput.Update(msg)
+		case 2:
+			m.iacDirInput, cmd = m.iacDirInput.Update(msg)
+		}
 	case "env":
 		m.envInput, cmd = m.envInput.Update(msg)
 	case "custom":
@@ -3390,26 +4624,30 @@ func (m *Model) saveContextEdit() {
 		repo := m.gitRepoInput.Value()
 		m.contextCurrent.SetGit(branch, repo)
 
-	case "env":
-		value := m.envInput.Value()
-		if k, v, ok := parseKeyValue(value); ok {
-			envVars := m.contextCurrent.GetEnv()
-			if envVars == nil {
-				envVars = make(map[string]string)
-			}
-			envVars[k] = v
-			m.contextCurrent.SetEnv(envVars)
+	case "iac":
+		workspace := strings.TrimSpace(m.iacWorkspaceInput.Value())
+		tool := strings.ToLower(strings.TrimSpace(m.iacToolInput.Value()))
+		dir := strings.TrimSpace(m.iacDirInput.Value())
+		if tool != "" && tool != workingctx.IaCTerraform && tool != workingctx.IaCPulumi {
+			m.addToast(fmt.Sprintf("Unknown tool %q: use terraform or pulumi", tool), ToastError)
+			return
+		}
+		if workspace == "" {
+			m.contextCurrent.Clear("iac")
+		} else {
+			m.contextCurrent.SetIaC(tool, workspace, dir)
 		}
 
-	case "custom":
-		value := m.customInput.Value()
+	case "env", "custom":
+		value := m.envInput.Value()
+		if m.contextEditField == "custom" {
+			value = m.customInput.Value()
+		}
 		if k, v, ok := parseKeyValue(value); ok {
-			customVars := m.contextCurrent.GetCustom()
-			if customVars == nil {
-				customVars = make(map[string]string)
+			if err := m.contextCurrent.SetValue(m.contextEditField, k, v, m.contextEditSecret); err != nil {
+				m.addToast(fmt.Sprintf("Failed to set %s: %v", k, err), ToastError)
+				return
 			}
-			customVars[k] = v
-			m.contextCurrent.SetCustom(customVars)
 		}
 	}
 
@@ -3423,30 +4661,98 @@ func (m *Model) saveContextEdit() {
 }
 
 // listVisibleItems returns the number of items that can fit in the history list view
+// (each item takes two lines in comfortable density)
 func (m Model) listVisibleItems() int {
 	// Left pane height is (m.height - 4), minus 2 for border = inner content height
 	// Then subtract header (2 lines: title + separator)
 	innerHeight := m.height - 4 - 2 // pane height minus border
 	headerLines := 2                // "History (N)" + separator
-	availableHeight := innerHeight - headerLines
+	if len(m.incidents) > 0 {
+		headerLines++ // Incident ribbon
+	}
+	availableHeight := (innerHeight - headerLines) / m.historyLinesPerItem()
 	if availableHeight < 1 {
 		return 1
 	}
 	return availableHeight
 }
 
+// visibleChangeIndices returns the indices of changes shown in the history
+// list, newest first
+func (m Model) visibleChangeIndices() []int {
+	indices := m.filteredChangeIndices()
+	if m.squashHistory {
+		indices = m.squashIndices(indices)
+	}
+	if len(m.sessionCollapsed) > 0 {
+		indices = m.collapseSessions(indices)
+	}
+	return indices
+}
+
+// filteredChangeIndices returns the indices of changes matching the
+// bookmark and history filters, newest first
+func (m Model) filteredChangeIndices() []int {
+	indices := make([]int, 0, len(m.changes))
+	for i, c := range m.changes {
+		if m.bookmarksOnly && !c.Bookmarked {
+			continue
+		}
+		if !m.historyFilter.Match(c.FilePath, c.ToolName, c.Timestamp) {
+			continue
+		}
+		indices = append(indices, i)
+	}
+	return indices
+}
+
+// selectedListPos returns the position of the selected change within the
+// visible indices, or 0 if it is hidden
+func (m Model) selectedListPos(visible []int) int {
+	for pos, idx := range visible {
+		if idx == m.selectedIndex {
+			return pos
+		}
+	}
+	return 0
+}
+
+// moveSelection moves the history selection by delta visible entries
+// (positive = older), clamping at the ends. Returns false if nothing moved.
+func (m *Model) moveSelection(delta int) bool {
+	visible := m.visibleChangeIndices()
+	if len(visible) == 0 {
+		return false
+	}
+
+	pos := m.selectedListPos(visible) + delta
+	if pos < 0 {
+		pos = 0
+	}
+	if pos > len(visible)-1 {
+		pos = len(visible) - 1
+	}
+	if visible[pos] == m.selectedIndex {
+		return false
+	}
+
+	m.selectedIndex = visible[pos]
+	return true
+}
+
 // ensureSelectedVisible adjusts listScrollOffset to keep selected item visible
 func (m *Model) ensureSelectedVisible() {
 	if len(m.changes) == 0 {
 		return
 	}
 
-	totalItems := len(m.changes)
+	visible := m.visibleChangeIndices()
+	totalItems := len(visible)
 	visibleItems := m.listVisibleItems()
 
 	// Data is sorted newest first (index 0 = newest = top of list)
-	// So visualPos == selectedIndex
-	visualPos := m.selectedIndex
+	// So visualPos is the selected change's position among visible changes
+	visualPos := m.selectedListPos(visible)
 
 	// If selected is above visible area (scrolled past), scroll up
 	if visualPos < m.listScrollOffset {
@@ -3469,81 +4775,226 @@ func (m *Model) ensureSelectedVisible() {
 	if m.listScrollOffset < 0 {
 		m.listScrollOffset = 0
 	}
+
+	// Time-gap separators and session headers can push the selection past
+	// the bottom
+	for m.listScrollOffset < visualPos && visualPos >= m.listScrollOffset+m.historyItemsFitting(visible, m.listScrollOffset) {
+		m.listScrollOffset++
+	}
 }
 
 func (m Model) renderHistory() string {
+	view, _ := m.renderHistoryRows()
+	return view
+}
+
+// renderHistoryRows renders the history list and, for each line, the index
+// of the change it shows, or -1 for headers and separators
+func (m Model) renderHistoryRows() (string, []int) {
 	if len(m.changes) == 0 {
-		return m.theme.Dim.Render("No changes yet...\nWaiting for Claude edits")
+		return m.theme.Dim.Render("No changes yet...\nWaiting for Claude edits\n\nNothing arriving? " + m.config.LeaderKey + " H checks the hooks"), nil
 	}
 
 	var sb strings.Builder
+	var rows []int
 
 	// Calculate visible items
 	visibleItems := m.listVisibleItems()
-	totalItems := len(m.changes)
+	visible := m.visibleChangeIndices()
+	totalItems := len(visible)
 
-	// Header with count and scroll position
-	if totalItems > visibleItems {
+	title := "History"
+	if m.bookmarksOnly {
+		title = "Bookmarks"
+	}
+	count := fmt.Sprintf("%d", totalItems)
+	if m.historyFilter != nil {
+		count = fmt.Sprintf("%d/%d", totalItems, len(m.changes))
+	}
+	// Older edits are still in the daemon
+	if m.historyCursors[m.historyFilter.String()] != "" {
+		count += "+"
+	}
+	loading := ""
+	if m.historyLoading {
+		loading = " ⟳ loading older…"
+	}
+
+	// Header with count and scroll position (filter input while editing)
+	if m.historyFilterActive {
+		sb.WriteString("Filter: " + m.historyFilterInput.View() + "\n")
+	} else if totalItems > visibleItems {
 		scrollInfo := fmt.Sprintf(" [%d-%d/%d]", m.listScrollOffset+1,
 			min(m.listScrollOffset+visibleItems, totalItems), totalItems)
-		sb.WriteString(m.theme.Dim.Render(fmt.Sprintf("History (%d)%s\n", totalItems, scrollInfo)))
+		sb.WriteString(m.theme.Dim.Render(fmt.Sprintf("%s (%s)%s%s\n", title, count, scrollInfo, loading)))
 	} else {
-		sb.WriteString(m.theme.Dim.Render(fmt.Sprintf("History (%d)\n", totalItems)))
+		sb.WriteString(m.theme.Dim.Render(fmt.Sprintf("%s (%s)%s\n", title, count, loading)))
+	}
+	rows = append(rows, -1)
+
+	// Separator shows the applied filter expression
+	switch {
+	case m.historyFilterActive:
+		sb.WriteString(m.theme.Dim.Render("Enter:apply  Esc:cancel") + "\n")
+	case m.historyFilter != nil:
+		line := "⧩ " + m.historyFilter.String()
+		if width := m.leftPaneWidth() - 4; width > 4 && len([]rune(line)) > width {
+			line = string([]rune(line)[:width-3]) + "..."
+		}
+		sb.WriteString(m.theme.Dim.Render(line) + "\n")
+	default:
+		sb.WriteString(m.theme.Dim.Render(strings.Repeat("─", 20)) + "\n")
+	}
+	rows = append(rows, -1)
+
+	// Incident ribbon for the visible time range (line reserved whenever the timeline has entries)
+	if len(m.incidents) > 0 {
+		sb.WriteString(m.renderIncidentRibbon(m.leftPaneWidth()-4) + "\n")
+		rows = append(rows, -1)
 	}
-	sb.WriteString(m.theme.Dim.Render(strings.Repeat("─", 20)) + "\n")
 
 	// Calculate available width for path in history pane
-	historyWidth := m.width / 3
+	historyWidth := m.leftPaneWidth()
 	pathWidth := historyWidth - 15 // Account for timestamp, tool, prefix
+	if m.workingTreeDiff {
+		pathWidth-- // Drift badge
+	}
+	if len(m.checkResults) > 0 {
+		pathWidth-- // Check badge
+	}
+	policyBadges := m.hasPolicyBadges()
+	if policyBadges {
+		pathWidth-- // Policy badge
+	}
+	pathWidth -= m.fileIconWidth()
 
 	// Database returns newest first (ORDER BY timestamp DESC), so index 0 is newest
 	startIdx := m.listScrollOffset
-	endIdx := startIdx + visibleItems
+	if startIdx > totalItems {
+		startIdx = totalItems
+	}
+	endIdx := startIdx + m.historyItemsFitting(visible, startIdx)
 	if endIdx > totalItems {
 		endIdx = totalItems
 	}
 
-	// Render visible items
-	linesRendered := 0
-	for i := startIdx; i < endIdx; i++ {
-		change := m.changes[i]
+	// Render visible items
+	linesPerItem := m.historyLinesPerItem()
+	linesRendered := 0
+	for pos := startIdx; pos < endIdx; pos++ {
+		i := visible[pos]
+		change := m.changes[i]
+
+		// Separator for idle stretches between entries
+		if pos > startIdx {
+			if gap := m.historyTimeGap(visible, pos); gap > 0 {
+				sb.WriteString(m.renderTimeGap(gap, historyWidth-4) + "\n")
+				rows = append(rows, -1)
+				linesRendered++
+			}
+		}
+
+		// Header above each expanded session's entries
+		if m.sessionHeaderAt(visible, pos, startIdx) {
+			sb.WriteString(m.renderSessionHeader(change.SessionID, historyWidth-4) + "\n")
+			rows = append(rows, -1)
+			linesRendered++
+		}
+
+		// A collapsed session is one entry
+		if sid := m.collapsedSession(i); sid != "" {
+			sb.WriteString(m.renderCollapsedSession(sid, i == m.selectedIndex, linesPerItem, historyWidth-4))
+			for j := 0; j < linesPerItem; j++ {
+				rows = append(rows, i)
+			}
+			linesRendered += linesPerItem
+			continue
+		}
+
+		// Bookmarked and reviewed changes are marked in the second prefix
+		// column, reviews taking over while reviewing, then changes made
+		// outside Claude
+		mark := " "
+		switch {
+		case m.reviewing && change.Review != "":
+			mark = reviewMark(change.Review)
+		case change.Bookmarked:
+			mark = "★"
+		case change.Review != "":
+			mark = reviewMark(change.Review)
+		case change.ToolName == database.ToolExternal:
+			mark = externalMark
+		}
+
+		// Drift status badge when comparing against the working tree
+		if m.workingTreeDiff {
+			sb.WriteString(m.renderDriftBadge(change))
+		}
+		// Post-edit check status once the daemon has run any
+		if len(m.checkResults) > 0 {
+			sb.WriteString(m.renderCheckBadge(i))
+		}
+		// Edits to files the project policy protects
+		if policyBadges {
+			sb.WriteString(m.renderPolicyBadge(change))
+		}
+		sb.WriteString(m.renderFileIcon(change))
+
+		// Squashed runs show their size, expanded ones each step's position
+		tool := change.ToolName
+		if step := m.squashStep(i); step != "" {
+			tool = step
+		}
 
 		var line string
 		if i == m.selectedIndex {
 			// Selected: show scrollable relative path
-			path := relativePath(change.FilePath)
+			path := m.relativePath(change.FilePath)
 			if m.scrollX > 0 && len(path) > m.scrollX {
 				path = path[m.scrollX:]
 			}
 			line = fmt.Sprintf("%s %s %s",
 				change.Timestamp.Format("15:04"),
-				change.ToolName,
+				tool,
 				path)
-			sb.WriteString(m.theme.Selected.Render("> "+line) + "\n")
+			sb.WriteString(m.theme.Selected.Render(">"+mark+line) + "\n")
+			if linesPerItem == 2 {
+				sb.WriteString(m.theme.Selected.Render("   "+m.changeDetail(change)) + "\n")
+			}
 		} else {
 			// Not selected: truncate path
 			line = fmt.Sprintf("%s %s %s",
 				change.Timestamp.Format("15:04"),
-				change.ToolName,
-				truncatePath(change.FilePath, pathWidth))
-			sb.WriteString(m.theme.Normal.Render("  "+line) + "\n")
+				tool,
+				m.truncatePath(change.FilePath, pathWidth-len(tool)+len(change.ToolName)))
+			style := m.theme.Normal
+			if change.ToolName == database.ToolExternal {
+				style = m.theme.Modified
+			}
+			sb.WriteString(style.Render(" "+mark+line) + "\n")
+			if linesPerItem == 2 {
+				sb.WriteString(m.theme.Dim.Render("   "+m.changeDetail(change)) + "\n")
+			}
 		}
-		linesRendered++
+		for j := 0; j < linesPerItem; j++ {
+			rows = append(rows, i)
+		}
+		linesRendered += linesPerItem
 	}
 
 	// Pad with empty lines to maintain consistent height
-	for linesRendered < visibleItems {
+	for linesRendered < visibleItems*linesPerItem {
 		sb.WriteString("\n")
 		linesRendered++
 	}
 
-	return sb.String()
+	return sb.String(), rows
 }
 
 // renderPromptsList renders the prompts list for the left pane
 func (m Model) renderPromptsList() string {
 	var sb strings.Builder
-	listWidth := m.width / 3
+	listWidth := m.leftPaneWidth()
 
 	// Show fuzzy filter overlay when active
 	if m.promptFuzzyActive {
@@ -3597,8 +5048,8 @@ func (m Model) renderPromptsList() string {
 		sb.WriteString(m.theme.Title.Render("Versions") + "\n")
 		sb.WriteString(m.theme.Dim.Render(strings.Repeat("─", listWidth-4)) + "\n")
 
-		if len(m.promptList) > 0 {
-			p := m.promptList[m.promptSelected]
+		if len(m.promptFilteredList) > 0 {
+			p := m.promptFilteredList[m.promptSelected]
 			sb.WriteString(m.theme.Dim.Render(p.Name) + "\n\n")
 		}
 
@@ -3611,6 +5062,9 @@ func (m Model) renderPromptsList() string {
 					prefix = "> "
 				}
 				line := fmt.Sprintf("%sv%d", prefix, v.Version)
+				if v.Version == m.promptVersionBase {
+					line += " (base)"
+				}
 				if i == m.promptVersionSelected {
 					sb.WriteString(m.theme.Selected.Render(line) + "\n")
 				} else {
@@ -3628,12 +5082,20 @@ func (m Model) renderPromptsList() string {
 		case PromptFilterGlobal:
 			filterIndicator = " [Global]"
 		}
+		for _, t := range m.selectedPromptTags() {
+			filterIndicator += " #" + t
+		}
+		if m.promptSort != PromptSortUpdated {
+			filterIndicator += " ↓" + m.promptSort.String()
+		}
 		header := fmt.Sprintf("Prompts (%d)%s", len(m.promptFilteredList), filterIndicator)
 		sb.WriteString(m.theme.Title.Render(header) + "\n")
 		sb.WriteString(m.theme.Dim.Render(strings.Repeat("─", listWidth-4)) + "\n")
 
 		if len(m.promptFilteredList) == 0 {
-			if m.promptFilter != PromptFilterAll {
+			if len(m.promptTagFilter) > 0 {
+				sb.WriteString(m.theme.Dim.Render("No matching prompts\nPress 't' to change tags"))
+			} else if m.promptFilter != PromptFilterAll {
 				sb.WriteString(m.theme.Dim.Render("No matching prompts\nPress '/' to change filter"))
 			} else {
 				sb.WriteString(m.theme.Dim.Render("No prompts\nPress 'n' to create"))
@@ -3654,7 +5116,16 @@ func (m Model) renderPromptsList() string {
 				if p.VersionCount > 0 {
 					versionStr = fmt.Sprintf(" (%d)", p.VersionCount)
 				}
-				line := fmt.Sprintf("%s%s %s%s", prefix, scope, p.Name, versionStr)
+				tagStr := ""
+				for _, t := range p.Tags {
+					tagStr += " #" + t
+				}
+				// Show how often and how recently it was sent
+				usageStr := ""
+				if stat, ok := m.promptStats[p.Name]; ok {
+					usageStr = fmt.Sprintf(" %dx %s", stat.Uses, m.relativeTime(stat.LastUsed))
+				}
+				line := fmt.Sprintf("%s%s %s%s%s%s", prefix, scope, p.Name, versionStr, usageStr, tagStr)
 				if len(line) > listWidth-4 {
 					line = line[:listWidth-7] + "..."
 				}
@@ -3670,21 +5141,147 @@ func (m Model) renderPromptsList() string {
 	return sb.String()
 }
 
+// renderDiff makes the selected change's diff the current document and
+// returns the rows in the scroll window
 func (m *Model) renderDiff() string {
+	// An edit about to land takes the pane until it does
+	if p := m.incomingEdit(); p != nil {
+		m.setDiffDoc(m.incomingDiffDoc(p))
+		m.diffViewport.SetYOffset(0)
+		return m.diffWindow()
+	}
+
+	doc, ok := m.diffCache[m.selectedIndex]
+	if !ok {
+		doc = m.buildDiffDoc(m.selectedIndex)
+		if len(m.changes) > 0 {
+			m.diffCache[m.selectedIndex] = doc
+		}
+	}
+	m.setDiffDoc(doc)
+	m.diffViewport.SetYOffset(0)
+	return m.diffWindow()
+}
+
+// buildDiffDoc builds the diff document for a change, or for the run of
+// edits it heads when the history is squashed
+func (m *Model) buildDiffDoc(idx int) *diffDoc {
 	if len(m.changes) == 0 {
-		return m.theme.Dim.Render("Select a change to view diff")
+		return staticDiffDoc(nil, m.theme.Dim.Render("Select a change to view diff"))
+	}
+
+	if m.fileHistory != nil {
+		return m.fileHistoryDoc()
+	}
+
+	if m.checksPanel {
+		c := m.changes[idx]
+		title := m.theme.Title.Render(m.relativePath(c.FilePath)) + m.theme.Dim.Render(" checks")
+		return staticDiffDoc([]string{title, m.theme.Dim.Render(strings.Repeat("─", 40)), ""}, m.renderChecks(idx))
+	}
+
+	if sid := m.collapsedSession(idx); sid != "" {
+		return m.sessionDiffDoc(sid)
 	}
 
-	// Use cache if available and no horizontal scroll
-	if m.scrollX == 0 {
-		if cached, ok := m.diffCache[m.selectedIndex]; ok {
-			return cached
+	if run := m.squashedRun(idx); run != nil && !m.workingTreeDiff {
+		return m.squashDiffDoc(run)
+	}
+
+	change := m.loadChangeContent(idx)
+
+	// Header with relative file path
+	title := m.theme.Title.Render(m.relativePath(change.FilePath))
+	if change.LineNum > 0 {
+		title += m.theme.Dim.Render(fmt.Sprintf(":%d", change.LineNum))
+	}
+	header := []string{title}
+	if change.ToolName == database.ToolExternal {
+		header = append(header, m.theme.Modified.Render(externalMark+" Changed outside Claude (no hook reported it)"))
+	}
+	if change.Summary != "" {
+		header = append(header, m.theme.Dim.Render(change.Summary))
+	}
+	if change.Truncated {
+		header = append(header, m.theme.Modified.Render(fmt.Sprintf("Content truncated: the edit was over %d MB; the file is read from disk", socket.MaxStringSize>>20)))
+	}
+	if change.Review != "" {
+		header = append(header, m.renderReviewHeader(change.Review))
+	}
+	if change.Protected != "" {
+		header = append(header, m.renderPolicyHeader(change))
+	}
+	if change.Note != "" {
+		header = append(header, m.theme.Modified.Render("✎ "+change.Note))
+	}
+	header = append(header, m.theme.Dim.Render(strings.Repeat("─", 40)), "")
+
+	// Working tree mode: compare the recorded change with the file on disk
+	if m.workingTreeDiff {
+		return staticDiffDoc(header, m.renderWorkingTreeDiff(change))
+	}
+
+	// Binary content gets a summary; JSON and SVG a structural diff on request
+	if diff.IsBinary(change.FileContent) || diff.IsBinary(change.NewString) || diff.IsBinary(change.OldString) {
+		oldText, newText := changeTexts(change)
+		return staticDiffDoc(header, diff.FormatBinary(oldText, newText, m.theme))
+	}
+	if m.semanticDiff[m.semanticKey(idx)] {
+		oldText, newText := changeTexts(change)
+		return m.semanticDiffDoc(header, change.FilePath, oldText, newText)
+	}
+	if m.structuralDiff {
+		oldText, newText := changeTexts(change)
+		if doc := m.structuralDiffDoc(header, change.FilePath, oldText, newText); doc != nil {
+			return doc
+		}
+	}
+
+	// If we have file content, show full file with change highlighted. An
+	// edit that only switches line endings, encoding or whitespace would show
+	// every line replaced, so it gets the normalized diff instead.
+	if change.FileContent != "" && change.ToolName != "Write" {
+		if opts := m.diffOptions(); opts.Normalize.Hides(change.OldString, change.NewString) {
+			return staticDiffDoc(header, diff.FormatDiff(change.OldString, change.NewString, m.theme, opts))
+		}
+		return m.fileDiffDoc(header, idx, change)
+	}
+
+	var sb strings.Builder
+	if change.ToolName == "Write" {
+		// For Write operations, show highlighted new content
+		content := change.NewString
+		if len(content) > 2000 {
+			content = content[:2000] + "\n... (truncated)"
+		}
+		sb.WriteString(m.theme.DiffHeader.Render("@@ New file @@"))
+		sb.WriteString("\n\n")
+
+		lines := diff.SplitLines(content)
+		for i, line := range lines {
+			lineNum := fmt.Sprintf("%4d", i+1)
+			highlighted := m.highlighter.HighlightLine(line, change.FilePath)
+			sb.WriteString(m.theme.LineNumber.Render(lineNum))
+			sb.WriteString(" ")
+			sb.WriteString(m.theme.Added.Render("+ "))
+			sb.WriteString(highlighted)
+			sb.WriteString("\n")
 		}
+	} else if change.OldString != "" || change.NewString != "" {
+		// Fallback: show just the diff
+		diffOutput := diff.FormatDiff(change.OldString, change.NewString, m.theme, m.diffOptions())
+		sb.WriteString(diffOutput)
+	} else {
+		sb.WriteString(m.theme.Dim.Render("No diff content available"))
 	}
 
-	change := m.changes[m.selectedIndex]
+	return staticDiffDoc(header, sb.String())
+}
 
-	// If FileContent is empty (e.g., loaded from history), try to retrieve it
+// loadChangeContent returns a change, fetching its file content from the VCS
+// or disk for history entries recorded without it
+func (m *Model) loadChangeContent(idx int) Change {
+	change := m.changes[idx]
 	if change.FileContent == "" && change.FilePath != "" && change.ToolName != "Write" {
 		var fileContent string
 		var err error
@@ -3693,7 +5290,7 @@ func (m *Model) renderDiff() string {
 		// Make file path absolute if it's relative
 		filePath := change.FilePath
 		if !filepath.IsAbs(filePath) {
-			if cwd, cwdErr := os.Getwd(); cwdErr == nil {
+			if cwd, cwdErr := m.fs.Getwd(); cwdErr == nil {
 				filePath = filepath.Join(cwd, filePath)
 			}
 		}
@@ -3701,7 +5298,7 @@ func (m *Model) renderDiff() string {
 		// Try VCS-based retrieval if we have commit info
 		if change.CommitSHA != "" && change.VCSType != "" {
 			// Get workspace root from current directory (more reliable than file path)
-			cwd, cwdErr := os.Getwd()
+			cwd, cwdErr := m.fs.Getwd()
 			if cwdErr == nil {
 				if workspaceRoot, rootErr := vcs.GetWorkspaceRoot(cwd, change.VCSType); rootErr == nil {
 					fileContent, err = vcs.GetFileAtCommit(workspaceRoot, filePath, change.CommitSHA, change.VCSType)
@@ -3714,7 +5311,7 @@ func (m *Model) renderDiff() string {
 
 		// Fall back to reading current file if VCS retrieval failed
 		if fileContent == "" {
-			if content, readErr := os.ReadFile(filePath); readErr == nil {
+			if content, readErr := m.fs.ReadFile(filePath); readErr == nil {
 				fileContent = string(content)
 				source = "current file"
 			} else {
@@ -3725,59 +5322,20 @@ func (m *Model) renderDiff() string {
 		if fileContent != "" {
 			change.FileContent = fileContent
 			// Update the stored change so we don't re-read every time
-			m.changes[m.selectedIndex] = change
+			m.changes[idx] = change
 			logger.Log("Retrieved file content for history entry: %s (%d bytes, source: %s)", change.FilePath, len(change.FileContent), source)
 		} else {
 			logger.Log("Failed to retrieve file for history entry: %s: %v", change.FilePath, err)
 		}
 	}
-
-	var sb strings.Builder
-
-	// Header with relative file path
-	sb.WriteString(m.theme.Title.Render(relativePath(change.FilePath)))
-	if change.LineNum > 0 {
-		sb.WriteString(m.theme.Dim.Render(fmt.Sprintf(":%d", change.LineNum)))
-	}
-	sb.WriteString("\n")
-	sb.WriteString(m.theme.Dim.Render(strings.Repeat("─", 40)) + "\n\n")
-
-	// If we have file content, show full file with change highlighted
-	if change.FileContent != "" && change.ToolName != "Write" {
-		sb.WriteString(m.renderFileWithChange(change))
-	} else if change.ToolName == "Write" {
-		// For Write operations, show highlighted new content
-		content := change.NewString
-		if len(content) > 2000 {
-			content = content[:2000] + "\n... (truncated)"
-		}
-		sb.WriteString(m.theme.DiffHeader.Render("@@ New file @@"))
-		sb.WriteString("\n\n")
-
-		lines := diff.SplitLines(content)
-		for i, line := range lines {
-			lineNum := fmt.Sprintf("%4d", i+1)
-			highlighted := m.highlighter.HighlightLine(line, change.FilePath)
-			sb.WriteString(m.theme.LineNumber.Render(lineNum))
-			sb.WriteString(" ")
-			sb.WriteString(m.theme.Added.Render("+ "))
-			sb.WriteString(highlighted)
-			sb.WriteString("\n")
-		}
-	} else if change.OldString != "" || change.NewString != "" {
-		// Fallback: show just the diff
-		opts := diff.DefaultOptions()
-		diffOutput := diff.FormatDiff(change.OldString, change.NewString, m.theme, opts)
-		sb.WriteString(diffOutput)
-	} else {
-		sb.WriteString(m.theme.Dim.Render("No diff content available"))
-	}
-
-	return sb.String()
+	return change
 }
 
 // renderRightPane returns the content for the right pane based on current mode
 func (m *Model) renderRightPane() string {
+	if m.leftPaneMode != LeftPaneModeHistory {
+		m.diffDoc = nil // Other content scrolls in the viewport
+	}
 
 	switch m.leftPaneMode {
 	case LeftPaneModePrompts:
@@ -3786,6 +5344,11 @@ func (m *Model) renderRightPane() string {
 		return m.renderRalphPrompt()
 	case LeftPaneModePlan:
 		return m.renderPlanContent()
+	case LeftPaneModeChat:
+		if m.showObjectives {
+			return m.renderObjectiveQueue()
+		}
+		return m.renderChatTranscript()
 	default:
 		return m.renderDiff()
 	}
@@ -3793,6 +5356,9 @@ func (m *Model) renderRightPane() string {
 
 // renderRalphPrompt renders the Ralph prompt content for the right pane
 func (m *Model) renderRalphPrompt() string {
+	if m.showRalphHistory {
+		return m.renderRalphHistory()
+	}
 	// In Ralph mode, use the full-width renderer
 	return m.renderRalphFull()
 }
@@ -3807,6 +5373,8 @@ func (m *Model) renderRalphFull() string {
 		sb.WriteString(m.theme.Dim.Render("No active Ralph loop\n\n"))
 		sb.WriteString(m.theme.Dim.Render("Start a Ralph loop with:\n"))
 		sb.WriteString(m.theme.Normal.Render("  /ralph-loop\n\n"))
+		sb.WriteString(m.theme.Dim.Render("or run a plan as one from the Plan tab (Ctrl+G L)\n\n"))
+		sb.WriteString(m.renderRalphRun())
 		return sb.String()
 	}
 
@@ -3816,14 +5384,21 @@ func (m *Model) renderRalphFull() string {
 
 	// Active status
 	if m.ralphState.Active {
-		sb.WriteString(m.theme.Selected.Render("🔄 Active") + "  ")
+		if m.ralphState.Paused {
+			sb.WriteString(m.theme.Modified.Render("⏸ Paused") + "  ")
+		} else {
+			sb.WriteString(m.theme.Selected.Render("🔄 Active") + "  ")
+		}
 
 		// Iteration progress
 		progress := fmt.Sprintf("Iteration: %d/%d", m.ralphState.Iteration, m.ralphState.MaxIterations)
 		sb.WriteString(m.theme.Normal.Render(progress) + "\n\n")
 
 		// Completion promise
-		if m.ralphState.Promise != "" {
+		if m.ralphInputActive {
+			sb.WriteString(m.theme.Dim.Render("Promise: ") + m.ralphInput.View() + "\n")
+			sb.WriteString(m.theme.Dim.Render("Enter:save  Esc:cancel") + "\n\n")
+		} else if m.ralphState.Promise != "" {
 			sb.WriteString(m.theme.Dim.Render("Promise: ") + m.theme.Normal.Render("\""+m.ralphState.Promise+"\"") + "\n\n")
 		}
 
@@ -3839,6 +5414,9 @@ func (m *Model) renderRalphFull() string {
 		}
 	}
 
+	sb.WriteString(m.renderRalphRun())
+	sb.WriteString(m.renderRalphTimeline())
+
 	// Prompt content section
 	sb.WriteString(m.theme.Title.Render("Loop Prompt") + "\n")
 	sb.WriteString(m.theme.Dim.Render(strings.Repeat("─", m.width-4)) + "\n\n")
@@ -3869,7 +5447,11 @@ func (m *Model) renderPlanContent() string {
 
 	planName := strings.TrimSuffix(filepath.Base(m.planPath), ".md")
 	sb.WriteString(m.theme.Title.Render(planName) + "\n")
-	sb.WriteString(m.theme.Dim.Render(strings.Repeat("─", 40)) + "\n\n")
+	sb.WriteString(m.theme.Dim.Render(strings.Repeat("─", 40)) + "\n")
+	if len(m.planTasks) > 0 {
+		sb.WriteString(m.renderPlanProgress(40) + "\n")
+	}
+	sb.WriteString("\n")
 
 	// Render plan as markdown
 	rendered, err := m.renderMarkdown(m.planContent, m.diffViewport.Width-4)
@@ -3892,8 +5474,12 @@ func (m *Model) renderPromptPreview() string {
 			return m.theme.Dim.Render("No versions available")
 		}
 
+		if m.promptVersionDiff {
+			return m.renderPromptVersionDiff()
+		}
+
 		v := m.promptVersions[m.promptVersionSelected]
-		content, err := os.ReadFile(v.Path)
+		content, err := m.fs.ReadFile(v.Path)
 		if err != nil {
 			return m.theme.Dim.Render("Failed to read version: " + err.Error())
 		}
@@ -3921,8 +5507,11 @@ func (m *Model) renderPromptPreview() string {
 	if len(m.promptList) == 0 {
 		return m.theme.Dim.Render("No prompts yet.\n\nPress 'n' to create a new prompt.\nPress 'o' to switch back to History mode.")
 	}
+	if len(m.promptFilteredList) == 0 {
+		return m.theme.Dim.Render("No prompts match the current filter.")
+	}
 
-	p := m.promptList[m.promptSelected]
+	p := m.promptFilteredList[m.promptSelected]
 
 	// Header
 	sb.WriteString(m.theme.Title.Render(p.Name) + "\n")
@@ -3930,6 +5519,7 @@ func (m *Model) renderPromptPreview() string {
 		sb.WriteString(m.theme.Dim.Render(p.Description) + "\n")
 	}
 	sb.WriteString(m.theme.Dim.Render(fmt.Sprintf("v%d | %s | %s", p.Version, p.Updated.Format("2006-01-02"), prompt.MethodName(m.promptInjectMethod))) + "\n")
+	sb.WriteString(m.renderPromptInjections(p.Name))
 	sb.WriteString(m.theme.Dim.Render(strings.Repeat("─", 40)) + "\n\n")
 
 	// Render content as markdown
@@ -3943,179 +5533,53 @@ func (m *Model) renderPromptPreview() string {
 	return sb.String()
 }
 
-// renderFileWithChange shows file context around the changed section
-func (m *Model) renderFileWithChange(change Change) string {
-	var sb strings.Builder
-
-	// Split file content into lines
-	fileLines := diff.SplitLines(change.FileContent)
-	oldLines := diff.SplitLines(change.OldString)
-	newLines := diff.SplitLines(change.NewString)
-
-	changeStart := change.LineNum - 1 // 0-indexed
-	changeEnd := changeStart + len(oldLines)
-
-	// Limit context to 100 lines before and after the change for performance
-	const contextLines = 100
-	renderStart := changeStart - contextLines
-	if renderStart < 0 {
-		renderStart = 0
-	}
-	renderEnd := changeEnd + contextLines
-	if renderEnd > len(fileLines) {
-		renderEnd = len(fileLines)
-	}
-
-	// Track total lines for minimap (just the window we're showing)
-	m.totalLines = renderEnd - renderStart + len(newLines) - len(oldLines)
-
-	// Build minimap data
-	m.minimapData = minimap.New(m.totalLines)
-	minimapChangeStart := changeStart - renderStart
-	minimapChangeEnd := changeEnd - renderStart
-	m.minimapData.SetRange(minimapChangeStart, minimapChangeEnd, minimap.LineRemoved)
-	for i := 0; i < len(newLines); i++ {
-		m.minimapData.SetLine(minimapChangeStart+i, minimap.LineAdded)
-	}
-
-	// Show diff header with stats
-	sb.WriteString(m.theme.DiffHeader.Render(fmt.Sprintf("@@ -%d,%d +%d,%d @@",
-		change.LineNum, len(oldLines), change.LineNum, len(newLines))))
-	sb.WriteString("  ")
-	sb.WriteString(m.theme.Added.Render(fmt.Sprintf("+%d", len(newLines))))
-	sb.WriteString(" ")
-	sb.WriteString(m.theme.Removed.Render(fmt.Sprintf("-%d", len(oldLines))))
-	sb.WriteString("\n\n")
-
-	// Show truncation notice if we're not starting from line 1
-	if renderStart > 0 {
-		sb.WriteString(m.theme.Dim.Render(fmt.Sprintf("  ... %d lines above ...\n", renderStart)))
-	}
-
-	// Soft highlight style for changed lines
-	changedBg := lipgloss.NewStyle().Background(m.theme.ChangedLineBg)
-
-	// Render only the context window
-	for i := renderStart; i < renderEnd; i++ {
-		lineNum := fmt.Sprintf("%4d", i+1)
-		line := fileLines[i]
-
-		// Apply horizontal scroll
-		scrolledLine := line
-		if m.scrollX > 0 && len(line) > m.scrollX {
-			scrolledLine = line[m.scrollX:]
-		} else if m.scrollX > 0 {
-			scrolledLine = ""
-		}
-
-		// Check if this line is in the changed region
-		if i >= changeStart && i < changeEnd {
-			// This is a removed line - use diff colors (no syntax highlighting)
-			lineContent := m.theme.LineNumberActive.Render(lineNum) + " " +
-				m.theme.Removed.Render("- "+scrolledLine)
-			sb.WriteString(changedBg.Render(lineContent))
-			sb.WriteString("\n")
-
-			// After the last removed line, insert the new lines
-			if i == changeEnd-1 {
-				for j, newLine := range newLines {
-					scrolledNew := newLine
-					if m.scrollX > 0 && len(newLine) > m.scrollX {
-						scrolledNew = newLine[m.scrollX:]
-					} else if m.scrollX > 0 {
-						scrolledNew = ""
-					}
-
-					newLineNum := fmt.Sprintf("%4d", changeStart+j+1)
-					lineContent := m.theme.LineNumberActive.Render(newLineNum) + " " +
-						m.theme.Added.Render("+ "+scrolledNew)
-					sb.WriteString(changedBg.Render(lineContent))
-					sb.WriteString("\n")
-				}
-			}
-		} else {
-			// Context line - use syntax highlighting
-			highlighted := m.highlighter.HighlightLine(scrolledLine, change.FilePath)
-			sb.WriteString(m.theme.LineNumber.Render(lineNum))
-			sb.WriteString(" ")
-			sb.WriteString(m.theme.Context.Render("  "))
-			sb.WriteString(highlighted)
-			sb.WriteString("\n")
-		}
-	}
-
-	// Show truncation notice if we're not ending at the last line
-	if renderEnd < len(fileLines) {
-		sb.WriteString(m.theme.Dim.Render(fmt.Sprintf("  ... %d lines below ...\n", len(fileLines)-renderEnd)))
-	}
-
-	return sb.String()
+// fileDiffDoc builds a document showing the file with change idx inline,
+// whole or cut to the configured context. Rows are formatted lazily as they
+// scroll into view.
+func (m *Model) fileDiffDoc(header []string, idx int, change Change) *diffDoc {
+	body := newFileBody(change)
+	body.trim(m.diffContext(change.FilePath))
+	oldCount, newCount := body.changeEnd-body.changeStart, len(body.newLines)
+
+	// Diff header with stats, and the lines shown of a trimmed file
+	stats := m.theme.DiffHeader.Render(fmt.Sprintf("@@ -%d,%d +%d,%d @@",
+		change.LineNum, oldCount, change.LineNum, newCount)) + "  " +
+		m.theme.Added.Render(fmt.Sprintf("+%d", newCount)) + " " +
+		m.theme.Removed.Render(fmt.Sprintf("-%d", oldCount))
+	if body.trimmed() {
+		stats += "  " + m.theme.Dim.Render(m.trimmedNote(body))
+	}
+	header = append(header, stats, "")
+
+	// Blame gutter: last commit for context lines, a marker for changed lines
+	if m.showBlame {
+		body.showBlame = true
+		body.blame = m.blameForChange(change)
+	}
+
+	doc := &diffDoc{header: header, file: body, minimap: body.minimap()}
+	m.markFileHistory(doc.minimap, body, idx)
+	return doc
 }
 
-// scrollToChange scrolls the viewport to show the current change
+// scrollToChange scrolls the diff pane to show the current change with a
+// little context above it
 func (m *Model) scrollToChange() {
-	if len(m.changes) == 0 {
+	if len(m.changes) == 0 || m.diffDoc == nil {
 		return
 	}
-	change := m.changes[m.selectedIndex]
-
-	// Calculate where the change appears in the rendered content
-	// renderFileWithChange limits context to 100 lines before/after
-	const contextLines = 100
-	changeStart := change.LineNum - 1 // 0-indexed
-
-	// Calculate renderStart (same logic as renderFileWithChange)
-	renderStart := changeStart - contextLines
-	if renderStart < 0 {
-		renderStart = 0
-	}
-
-	// The change appears at this position in rendered content:
-	// - 2 lines for diff header (@@ line + blank line)
-	// - 1 line for truncation notice if renderStart > 0
-	// - (changeStart - renderStart) lines of context before the change
-	headerLines := 2
-	if renderStart > 0 {
-		headerLines++ // truncation notice
-	}
-
-	// Position change with some context visible above it
-	targetLine := headerLines + (changeStart - renderStart) - 3
-	if targetLine < 0 {
-		targetLine = 0
-	}
-	m.diffViewport.SetYOffset(targetLine)
+	m.setDiffOffset(m.diffDoc.changeRow() - 3)
 }
 
-// preloadAdjacent pre-caches rendered diffs for adjacent changes
+// preloadAdjacent pre-builds the diff documents of adjacent changes. Diffs
+// that need a background render are left for showDiff.
 func (m *Model) preloadAdjacent() {
-	// Preload next
-	if m.selectedIndex+1 < len(m.changes) {
-		idx := m.selectedIndex + 1
-		if _, ok := m.diffCache[idx]; !ok {
-			// Store current state
-			origIdx := m.selectedIndex
-			origScrollX := m.scrollX
-			// Render next
-			m.selectedIndex = idx
-			m.scrollX = 0
-			m.diffCache[idx] = m.renderDiff()
-			// Restore
-			m.selectedIndex = origIdx
-			m.scrollX = origScrollX
-		}
-	}
-	// Preload previous
-	if m.selectedIndex > 0 {
-		idx := m.selectedIndex - 1
-		if _, ok := m.diffCache[idx]; !ok {
-			origIdx := m.selectedIndex
-			origScrollX := m.scrollX
-			m.selectedIndex = idx
-			m.scrollX = 0
-			m.diffCache[idx] = m.renderDiff()
-			m.selectedIndex = origIdx
-			m.scrollX = origScrollX
+	for _, idx := range []int{m.selectedIndex + 1, m.selectedIndex - 1} {
+		if idx < 0 || idx >= len(m.changes) {
+			continue
+		}
+		if _, ok := m.diffCache[idx]; !ok && !m.changeNeedsAsync(idx) {
+			m.diffCache[idx] = m.buildDiffDoc(idx)
 		}
 	}
 }
@@ -4131,15 +5595,27 @@ func (m *Model) updateViewportSize() {
 
 	// Calculate viewport width based on left pane visibility
 	var vpWidth int
-	if m.hideLeftPane {
+	if m.hideLeftPane || m.leftPaneMode == LeftPaneModeChat {
 		vpWidth = m.width - 4 - minimapWidth
 	} else {
-		leftWidth := m.width / 3
+		leftWidth := m.leftPaneWidth()
 		vpWidth = m.width - leftWidth - 6 - minimapWidth
 	}
 
 	m.diffViewport.Width = vpWidth
 	m.diffViewport.Height = m.height - headerHeight - footerHeight - 2
+	if m.leftPaneMode == LeftPaneModeChat {
+		// Leave room for the message input below the transcript
+		m.diffViewport.Height -= chatInputHeight
+		if len(m.chatTools) > 0 && !m.showObjectives {
+			m.diffViewport.Width -= chatToolWidth + 1
+		}
+	}
+
+	// Claude lays out its replies for the width it's given
+	if m.chatAvailable() {
+		m.chatSession.SetSize(m.diffViewport.Height, m.diffViewport.Width)
+	}
 }
 
 // renderMinimap renders a visual minimap showing file structure and diff regions
@@ -4155,7 +5631,11 @@ func (m Model) renderMinimap() string {
 
 	// If we have minimap data, use the visual minimap
 	if m.minimapData != nil && m.minimapData.TotalLines() > 0 {
-		viewportStart := m.diffViewport.YOffset
+		// Minimap lines are file body rows, below the diff header
+		viewportStart := m.diffScrollTop()
+		if m.diffDoc != nil {
+			viewportStart -= len(m.diffDoc.header)
+		}
 		viewportEnd := viewportStart + m.diffViewport.Height
 		return m.minimapData.Render(height, viewportStart, viewportEnd, m.theme)
 	}
@@ -4183,7 +5663,7 @@ func (m Model) renderMinimap() string {
 	}
 
 	// Thumb position based on scroll offset
-	scrollPos := m.diffViewport.YOffset
+	scrollPos := m.diffScrollTop()
 	maxScroll := totalLines - viewportHeight
 	if maxScroll < 1 {
 		maxScroll = 1
@@ -4213,84 +5693,34 @@ func (m Model) renderMinimap() string {
 }
 
 func (m Model) renderStatus() string {
-	k := m.config.Keys
-
 	// Plan input mode
 	if m.planInputActive {
 		return m.theme.Status.Render("Enter:submit  Esc:cancel")
 	}
-	if m.planGenerating {
-		return m.theme.Status.Render("Generating plan...")
+	if m.promptSendPending != nil {
+		return m.renderPromptSendConfirm()
 	}
-
-	// Simplified status bar - just nav + leader key hint
-	var modeName string
-	switch m.leftPaneMode {
-	case LeftPaneModeHistory:
-		modeName = "History"
-	case LeftPaneModePrompts:
-		modeName = "Prompts"
-	case LeftPaneModeRalph:
-		modeName = "Ralph"
-	case LeftPaneModePlan:
-		modeName = "Plan"
-	case LeftPaneModeContext:
-		modeName = "Context"
+	if m.snapshotRestorePending != nil {
+		return m.renderSnapshotRestoreConfirm()
 	}
-
-	paneIndicator := "L"
-	if m.activePane == PaneRight {
-		paneIndicator = "R"
+	if len(m.gateQueue) > 0 {
+		return m.renderGateConfirm()
 	}
-
-	// Socket connection indicator (local nvim socket)
-	socketIndicator := "○" // Disconnected/no recent activity
-	socketStyle := m.theme.Dim
-	if m.socketConnected {
-		if time.Since(m.lastMsgTime) < 30*time.Second {
-			socketIndicator = "●" // Connected with recent activity
-			socketStyle = m.theme.Added
-		} else {
-			socketIndicator = "◐" // Connected but idle
-			socketStyle = m.theme.Modified
-		}
+	if m.commitInputActive {
+		return m.renderCommitInput()
 	}
-
-	// Daemon connection indicator
-	daemonIndicator := "○" // Not connected
-	daemonStyle := m.theme.Dim
-	if m.daemonConnected {
-		if m.daemonWorkspaceActive && time.Since(m.daemonLastActivity) < 5*time.Minute {
-			daemonIndicator = "●" // Connected with recent workspace activity
-			daemonStyle = m.theme.Added
-		} else if m.daemonWorkspaceActive {
-			daemonIndicator = "◐" // Connected, workspace tracked but idle
-			daemonStyle = m.theme.Modified
-		} else {
-			daemonIndicator = "◑" // Connected but workspace not tracked
-			daemonStyle = m.theme.Dim
-		}
+	if m.sessionNaming != "" {
+		return m.renderSessionNameInput()
 	}
-
-	// Build status: left side info, right side indicators
-	leftStatus := fmt.Sprintf(
-		"%s [%s]  %s/%s:nav  Tab:mode  [/]:pane  ^G:menu",
-		modeName, paneIndicator, k.Down, k.Up)
-
-	// Build right side: daemon indicator + socket indicator
-	rightPart := daemonStyle.Render("D"+daemonIndicator) + " " + socketStyle.Render("S"+socketIndicator)
-	rightLen := 5 // "D● S●" = 5 chars
-
-	// Calculate padding to push indicators to right
-	statusWidth := m.width - 2
-	leftLen := len(leftStatus)
-
-	padding := statusWidth - leftLen - rightLen
-	if padding < 1 {
-		padding = 1
+	if m.noteActive {
+		return m.renderNoteInput()
+	}
+	if m.planGenerating {
+		return m.theme.Status.Render("Generating plan...")
 	}
 
-	return m.theme.Status.Render(leftStatus + strings.Repeat(" ", padding) + rightPart)
+	// The configured segments, padded to push the right side to the edge
+	return m.theme.Status.Render(m.renderStatusBar(m.width - 2))
 }
 
 func (m Model) renderHelp() string {
@@ -4302,11 +5732,14 @@ func (m Model) renderHelp() string {
 	// Global section (always shown)
 	help.WriteString("  === Global ===\n")
 	help.WriteString(fmt.Sprintf("    %-14s Cycle tabs\n", k.NextTab+"/"+k.PrevTab))
-	help.WriteString("    1-4            Direct tab access\n")
+	help.WriteString("    1-6            Direct tab access\n")
 	if !m.hideLeftPane {
 		help.WriteString(fmt.Sprintf("    %-14s Switch pane focus\n", k.LeftPane+" / "+k.RightPane))
 	}
 	help.WriteString(fmt.Sprintf("    %-14s Toggle left pane\n", k.ToggleLeftPane))
+	if !m.hideLeftPane {
+		help.WriteString(fmt.Sprintf("    %-14s Shrink/grow left pane\n", k.ShrinkLeftPane+" / "+k.GrowLeftPane))
+	}
 	help.WriteString(fmt.Sprintf("    %-14s Toggle minimap\n", k.ToggleMinimap))
 	help.WriteString(fmt.Sprintf("    %-14s This help\n", k.Help))
 	help.WriteString(fmt.Sprintf("    %-14s Quit\n\n", k.Quit))
@@ -4320,6 +5753,24 @@ func (m Model) renderHelp() string {
 		help.WriteString(fmt.Sprintf("    %-14s Scroll horizontally\n", k.ScrollLeft+"/"+k.ScrollRight))
 		help.WriteString(fmt.Sprintf("    %-14s Open file in nvim at line\n", k.OpenInNvim))
 		help.WriteString(fmt.Sprintf("    %-14s Open file in nvim\n", k.OpenNvimCwd))
+		help.WriteString(fmt.Sprintf("    %-14s Compare with working tree\n", k.ToggleWorkingTree))
+		help.WriteString(fmt.Sprintf("    %-14s Toggle git blame gutter\n", k.ToggleBlame))
+		help.WriteString(fmt.Sprintf("    %-14s Hide line ending/encoding/whitespace changes\n", k.ToggleNormalize))
+		help.WriteString(fmt.Sprintf("    %-14s Whole file or context lines around the change\n", k.ToggleContext))
+		help.WriteString(fmt.Sprintf("    %-14s Structural diff of JSON/SVG\n", k.ToggleStructural))
+		help.WriteString(fmt.Sprintf("    %-14s Key path diff of this JSON/YAML/TOML change\n", k.ToggleSemantic))
+		help.WriteString(fmt.Sprintf("    %-14s Show lint/test checks run after this change\n", k.ToggleChecks))
+		help.WriteString(fmt.Sprintf("    %-14s Every edit to this file across sessions (, . step versions)\n", k.FileHistory))
+		help.WriteString(fmt.Sprintf("    %-14s Squash consecutive edits per file\n", k.ToggleSquash))
+		help.WriteString(fmt.Sprintf("    %-14s Expand/collapse squashed edits\n", k.ExpandSquash))
+		help.WriteString(fmt.Sprintf("    %-14s Collapse/expand the Claude session\n", k.ToggleSession))
+		help.WriteString(fmt.Sprintf("    %-14s Next/previous session\n", k.NextSession+"/"+k.PrevSession))
+		help.WriteString(fmt.Sprintf("    %-14s Jump to the nth change marked on the minimap (G: last)\n", k.JumpRegion+" 1-9"))
+		help.WriteString(fmt.Sprintf("    %-14s Name the session\n", m.config.LeaderKey+" n"))
+		help.WriteString(fmt.Sprintf("    %-14s Filter (path:<glob> tool:<name> since:1h)\n", k.FilterHistory))
+		help.WriteString(fmt.Sprintf("    %-14s Bookmark change\n", k.Bookmark))
+		help.WriteString(fmt.Sprintf("    %-14s Show bookmarks only\n", k.BookmarksOnly))
+		help.WriteString(fmt.Sprintf("    %-14s Toggle compact/comfortable list\n", k.ToggleDensity))
 		help.WriteString(fmt.Sprintf("    %-14s Clear history\n\n", k.ClearHistory))
 
 	case LeftPaneModePrompts:
@@ -4328,6 +5779,8 @@ func (m Model) renderHelp() string {
 			help.WriteString(fmt.Sprintf("    %-14s Navigate versions\n", k.Down+"/"+k.Up))
 			help.WriteString(fmt.Sprintf("    %-14s Revert to version\n", k.RevertVersion+"/"+k.SendPrompt))
 			help.WriteString(fmt.Sprintf("    %-14s View version (read-only)\n", k.EditPrompt))
+			help.WriteString(fmt.Sprintf("    %-14s Toggle diff against current prompt\n", "D"))
+			help.WriteString(fmt.Sprintf("    %-14s Mark version as diff base\n", "c"))
 			help.WriteString(fmt.Sprintf("    %-14s Delete version\n", k.DeletePrompt))
 			help.WriteString(fmt.Sprintf("    %-14s Back to prompts\n\n", k.ViewVersions+"/Esc"))
 		} else {
@@ -4340,6 +5793,9 @@ func (m Model) renderHelp() string {
 			help.WriteString(fmt.Sprintf("    %-14s Delete prompt\n", k.DeletePrompt))
 			help.WriteString(fmt.Sprintf("    %-14s Yank (copy to clipboard)\n", k.YankPrompt))
 			help.WriteString(fmt.Sprintf("    %-14s Cycle inject method\n", k.InjectMethod))
+			help.WriteString(fmt.Sprintf("    %-14s Pick tmux pane to send to\n", "P"))
+			help.WriteString(fmt.Sprintf("    %-14s Filter by tags\n", "t"))
+			help.WriteString(fmt.Sprintf("    %-14s Cycle sort (updated/name/usage)\n", "s"))
 			help.WriteString(fmt.Sprintf("    %-14s Inject prompt\n\n", k.SendPrompt))
 		}
 
@@ -4349,6 +5805,8 @@ func (m Model) renderHelp() string {
 			help.WriteString(fmt.Sprintf("    %-14s Cancel Ralph loop\n", k.CancelRalph))
 		}
 		help.WriteString(fmt.Sprintf("    %-14s Refresh status\n", k.Refresh))
+		help.WriteString(fmt.Sprintf("    %-14s Pause/resume, max iterations ±%d, edit promise\n", "ctrl+g p/+/-/P", ralphMaxStep))
+		help.WriteString(fmt.Sprintf("    %-14s Loop history (enter lists a loop's edits by iteration)\n", "H"))
 		help.WriteString(fmt.Sprintf("    %-14s Scroll prompt\n\n", k.Down+"/"+k.Up))
 
 	case LeftPaneModePlan:
@@ -4358,7 +5816,29 @@ func (m Model) renderHelp() string {
 			help.WriteString(fmt.Sprintf("    %-14s Edit plan in nvim\n", k.EditPlan))
 		}
 		help.WriteString(fmt.Sprintf("    %-14s Refresh plan\n", k.Refresh))
+		help.WriteString(fmt.Sprintf("    %-14s Select task / check or uncheck it (left pane)\n", k.Down+"/"+k.Up+", space/x"))
+		help.WriteString(fmt.Sprintf("    %-14s List plans: type to filter, enter switches, ctrl+x archives\n", "P"))
+		help.WriteString(fmt.Sprintf("    %-14s Run plan as an objective / a Ralph loop\n", "ctrl+g s/L"))
 		help.WriteString(fmt.Sprintf("    %-14s Scroll plan content\n\n", k.Down+"/"+k.Up+"/"+k.PageDown+"/"+k.PageUp))
+
+	case LeftPaneModeContext:
+		help.WriteString("  === Context Mode ===\n")
+		help.WriteString(fmt.Sprintf("    %-14s Switch profile: type to filter, enter switches, ctrl+s saves\n", "p"))
+		help.WriteString(fmt.Sprintf("    %-14s Set Kubernetes, AWS, Git, Terraform/Pulumi, Env, Custom (uppercase clears)\n", "ctrl+g k/a/g/i/e/c"))
+		help.WriteString(fmt.Sprintf("    %-14s Detect from kubectl, AWS_* variables, git and Terraform/Pulumi, then confirm\n", "ctrl+g d"))
+		help.WriteString(fmt.Sprintf("    %-14s Scroll context\n\n", k.Down+"/"+k.Up))
+
+	case LeftPaneModeChat:
+		help.WriteString("  === Chat Mode ===\n")
+		help.WriteString(fmt.Sprintf("    %-14s Type a message (enter sends, esc leaves)\n", "i/Enter"))
+		help.WriteString(fmt.Sprintf("    %-14s Start session\n", "s"))
+		help.WriteString(fmt.Sprintf("    %-14s Stop session\n", "x"))
+		help.WriteString(fmt.Sprintf("    %-14s Restart session\n", "r"))
+		help.WriteString(fmt.Sprintf("    %-14s Resume saved session\n", "R"))
+		help.WriteString(fmt.Sprintf("    %-14s Cycle purpose (general/ralph/prompt/plan)\n", "p"))
+		help.WriteString(fmt.Sprintf("    %-14s Focus tool activity (Enter opens the change in History)\n", "t"))
+		help.WriteString(fmt.Sprintf("    %-14s Objective queue: a add, space start/pause, J/K reorder, x cancel, R retry, d delete\n", "o"))
+		help.WriteString(fmt.Sprintf("    %-14s Scroll transcript\n\n", k.Down+"/"+k.Up+"/"+k.PageDown+"/"+k.PageUp+"/g/G"))
 	}
 
 	// Template variables (only in prompts mode)
@@ -4392,6 +5872,8 @@ func (m Model) renderHelpBar() string {
 		mode = "plan"
 	case LeftPaneModeContext:
 		mode = "context"
+	case LeftPaneModeChat:
+		mode = "chat"
 	}
 
 	// Use ModeKeyMap for mode-specific help
@@ -4417,6 +5899,8 @@ func (m Model) renderWhichKey() string {
 		contextItems = []WhichKeyItem{
 			{Key: "g", Description: "open in nvim at line"},
 			{Key: "o", Description: "open file in nvim"},
+			{Key: "a", Description: "annotate change"},
+			{Key: "F", Description: "send as feedback"},
 		}
 	} else {
 		switch m.leftPaneMode {
@@ -4425,6 +5909,16 @@ func (m Model) renderWhichKey() string {
 			contextItems = []WhichKeyItem{
 				{Key: "g", Description: "open in nvim at line"},
 				{Key: "o", Description: "open file in nvim"},
+				{Key: "b", Description: "toggle bookmark"},
+				{Key: "B", Description: "bookmarks only"},
+				{Key: "c", Description: "commit change(s)"},
+				{Key: "r", Description: "restore file to session start"},
+				{Key: "n", Description: "name session"},
+				{Key: "f", Description: "follow edits in nvim"},
+				{Key: "a", Description: "annotate change"},
+				{Key: "v", Description: "review changes"},
+				{Key: "F", Description: "send as feedback"},
+				{Key: "R", Description: "send rejected as feedback"},
 				{Key: "x", Description: "clear history"},
 			}
 		case LeftPaneModePrompts:
@@ -4437,13 +5931,19 @@ func (m Model) renderWhichKey() string {
 				{Key: "d", Description: "delete prompt"},
 				{Key: "i", Description: "injection method"},
 				{Key: "⏎", Description: "inject prompt"},
-				{Key: "s", Description: "run as objective"},
+				{Key: "s", Description: "queue as objective"},
+				{Key: "S", Description: "sync library (git)"},
 			}
 		case LeftPaneModeRalph:
 			context = "RALPH LOOP"
 			contextItems = []WhichKeyItem{
 				{Key: "C", Description: "cancel loop"},
 				{Key: "r", Description: "refresh status"},
+				{Key: "p", Description: "pause/resume loop"},
+				{Key: "+", Description: fmt.Sprintf("max iterations +%d", ralphMaxStep)},
+				{Key: "-", Description: fmt.Sprintf("max iterations -%d", ralphMaxStep)},
+				{Key: "P", Description: "edit promise"},
+				{Key: "l", Description: "loop history"},
 			}
 		case LeftPaneModePlan:
 			context = "PLAN"
@@ -4451,7 +5951,10 @@ func (m Model) renderWhichKey() string {
 				{Key: "G", Description: "generate new plan"},
 				{Key: "e", Description: "edit in nvim"},
 				{Key: "r", Description: "refresh view"},
-				{Key: "s", Description: "run plan"},
+				{Key: "s", Description: "run plan (objective queue)"},
+				{Key: "L", Description: "run plan as Ralph loop"},
+				{Key: "x", Description: "check/uncheck task"},
+				{Key: "p", Description: "switch plan"},
 			}
 		case LeftPaneModeContext:
 			context = "CONTEXT"
@@ -4459,16 +5962,31 @@ func (m Model) renderWhichKey() string {
 				{Key: "k", Description: "set Kubernetes"},
 				{Key: "a", Description: "set AWS"},
 				{Key: "g", Description: "set Git"},
+				{Key: "i", Description: "set Terraform/Pulumi"},
 				{Key: "e", Description: "set Env var"},
 				{Key: "c", Description: "set Custom"},
 				{Key: "K", Description: "clear K8s"},
 				{Key: "A", Description: "clear AWS"},
 				{Key: "G", Description: "clear Git"},
+				{Key: "I", Description: "clear Terraform/Pulumi"},
 				{Key: "E", Description: "clear Env"},
 				{Key: "X", Description: "clear Custom"},
 				{Key: "C", Description: "clear all"},
 				{Key: "r", Description: "reload"},
 				{Key: "l", Description: "list all"},
+				{Key: "p", Description: "switch profile"},
+				{Key: "d", Description: "detect from environment"},
+			}
+		case LeftPaneModeChat:
+			context = "CHAT"
+			contextItems = []WhichKeyItem{
+				{Key: "s", Description: "start session"},
+				{Key: "x", Description: "stop session"},
+				{Key: "r", Description: "restart session"},
+				{Key: "p", Description: "cycle purpose"},
+				{Key: "R", Description: "resume saved session"},
+				{Key: "o", Description: "objective queue"},
+				{Key: "O", Description: "start/pause queue"},
 			}
 		}
 	}
@@ -4545,6 +6063,11 @@ func (m Model) renderWhichKey() string {
 		{Key: "m", Description: "toggle minimap"},
 		{Key: "1-4", Description: "switch mode"},
 		{Key: "?", Description: "full help"},
+		{Key: "H", Description: "hook health"},
+		{Key: "D", Description: "debug console"},
+		{Key: "T", Description: "switch theme"},
+		{Key: "z/Z", Description: "snooze/hide warning"},
+		{Key: "!", Description: "notifications"},
 		{Key: "q", Description: "quit"},
 	}
 	for i := 0; i < len(globalItems); i += 2 {
@@ -4568,8 +6091,27 @@ func (m Model) renderWhichKey() string {
 	return boxStyle.Render(content)
 }
 
-func parsePayload(data []byte) *Change {
-	logger.Log("parsePayload: raw data: %s", string(data))
+// tracksChange reports whether the workspace rules record a change made in
+// the TUI's working directory
+func (m *Model) tracksChange(change *Change) bool {
+	if m.workspaceRules == nil {
+		return true
+	}
+	cwd, err := m.fs.Getwd()
+	if err != nil {
+		return true
+	}
+	return m.workspaceRules.TrackFile(cwd, change.FilePath)
+}
+
+// parsePayload builds a change from a hook payload, reading the edited file
+// for context
+func (m Model) parsePayload(data []byte) *Change {
+	if len(data) > 4096 {
+		logger.Log("parsePayload: raw data: %s... (%d bytes)", data[:4096], len(data))
+	} else {
+		logger.Log("parsePayload: raw data: %s", string(data))
+	}
 
 	var payload HookPayload
 	if err := json.Unmarshal(data, &payload); err != nil {
@@ -4625,18 +6167,25 @@ func parsePayload(data []byte) *Change {
 		newStr = payload.Content
 	}
 
+	// MultiEdit makes several edits: the first is shown as the change and
+	// the rest are marked on the minimap
+	edits := payload.ToolInput.Edits
+	if oldStr == "" && newStr == "" && len(edits) > 0 {
+		oldStr, newStr = edits[0].OldString, edits[0].NewString
+	}
+
 	// Read the full file content
 	var fileContent string
 	var lineNum int = 1
 	var lineCount int = 1
 
-	if content, err := os.ReadFile(filePath); err == nil {
+	if content, err := m.fs.ReadFile(filePath); err == nil {
 		fileContent = string(content)
 		logger.Log("parsePayload: read file successfully, %d bytes", len(fileContent))
 
 		// Find line number where the change occurs
 		if oldStr != "" {
-			lineNum = findLineNumber(fileContent, oldStr)
+			lineNum = findLineNumber(fileContent, strings.TrimSuffix(oldStr, socket.TruncatedMarker))
 			lineCount = strings.Count(oldStr, "\n") + 1
 		} else if newStr != "" {
 			// For Write operations, show from beginning
@@ -4647,8 +6196,15 @@ func parsePayload(data []byte) *Change {
 		logger.Log("parsePayload: failed to read file %s: %v", filePath, err)
 	}
 
+	var hunks []Hunk
+	for i := 1; i < len(edits); i++ {
+		if e := edits[i]; e.OldString != "" && strings.Contains(fileContent, e.OldString) {
+			hunks = append(hunks, Hunk{LineNum: findLineNumber(fileContent, e.OldString), LineCount: strings.Count(e.OldString, "\n") + 1})
+		}
+	}
+
 	return &Change{
-		Timestamp:   time.Now(),
+		Timestamp:   m.clock.Now(),
 		FilePath:    filePath,
 		ToolName:    payload.ToolName,
 		OldString:   oldStr,
@@ -4656,6 +6212,14 @@ func parsePayload(data []byte) *Change {
 		FileContent: fileContent,
 		LineNum:     lineNum,
 		LineCount:   lineCount,
+
+		TranscriptPath: payload.TranscriptPath,
+		SessionID:      payload.SessionID,
+
+		Summary: symbols.Summarize(filePath, oldStr, newStr, fileContent),
+		Hunks:   hunks,
+
+		Truncated: socket.IsTruncated(oldStr) || socket.IsTruncated(newStr),
 	}
 }
 
@@ -4674,9 +6238,9 @@ func findLineNumber(content, searchStr string) int {
 	return strings.Count(content[:idx], "\n") + 1
 }
 
-func truncatePath(path string, maxLen int) string {
+func (m Model) truncatePath(path string, maxLen int) string {
 	// First make it relative
-	path = relativePath(path)
+	path = m.relativePath(path)
 	if len(path) <= maxLen {
 		return path
 	}
@@ -4732,8 +6296,8 @@ func parseKeyValue(input string) (key, value string, ok bool) {
 }
 
 // relativePath converts an absolute path to relative if possible
-func relativePath(path string) string {
-	cwd, err := os.Getwd()
+func (m Model) relativePath(path string) string {
+	cwd, err := m.fs.Getwd()
 	if err != nil {
 		return path
 	}
@@ -4748,75 +6312,18 @@ func relativePath(path string) string {
 	return rel
 }
 
-// cwdToProjectDir converts a CWD path to Claude's project directory name
-// /Users/foo/bar.baz → -Users-foo-bar-baz
-func cwdToProjectDir(cwd string) string {
-	result := strings.ReplaceAll(cwd, "/", "-")
-	result = strings.ReplaceAll(result, ".", "-")
-	return result
-}
-
-// extractSlugFromJSONL reads the slug field from the last entry in a JSONL file
-func extractSlugFromJSONL(path string) string {
-	f, err := os.Open(path)
-	if err != nil {
-		return ""
-	}
-	defer f.Close()
-
-	// Seek to end and read last chunk (avoid loading huge file)
-	stat, err := f.Stat()
-	if err != nil {
-		return ""
-	}
-	size := stat.Size()
-	readSize := int64(4096)
-	if size < readSize {
-		readSize = size
-	}
-
-	if _, err := f.Seek(-readSize, io.SeekEnd); err != nil {
-		// If seek fails, try reading from start
-		f.Seek(0, io.SeekStart)
-	}
-
-	buf := make([]byte, readSize)
-	n, err := f.Read(buf)
-	if err != nil && n == 0 {
-		return ""
-	}
-
-	// Find last complete JSON line with slug
-	lines := strings.Split(string(buf[:n]), "\n")
-	for i := len(lines) - 1; i >= 0; i-- {
-		line := strings.TrimSpace(lines[i])
-		if line == "" {
-			continue
-		}
-
-		// Parse JSON and extract slug
-		var entry struct {
-			Slug string `json:"slug"`
-		}
-		if json.Unmarshal([]byte(line), &entry) == nil && entry.Slug != "" {
-			return entry.Slug
-		}
-	}
-	return ""
-}
-
 // findPlanFromSession looks up the plan file for the current session
 func (m *Model) findPlanFromSession(home string) string {
-	cwd, err := os.Getwd()
+	cwd, err := m.fs.Getwd()
 	if err != nil {
 		return ""
 	}
 
-	projectDir := cwdToProjectDir(cwd)
+	projectDir := plan.ProjectDir(cwd)
 	projectPath := filepath.Join(home, ".claude", "projects", projectDir)
 
 	// Find most recent .jsonl in project directory
-	entries, err := os.ReadDir(projectPath)
+	entries, err := m.fs.ReadDir(projectPath)
 	if err != nil {
 		return ""
 	}
@@ -4842,14 +6349,14 @@ func (m *Model) findPlanFromSession(home string) string {
 	}
 
 	// Extract slug from JSONL
-	slug := extractSlugFromJSONL(newestJSONL)
+	slug := plan.SlugFromSession(newestJSONL)
 	if slug == "" {
 		return ""
 	}
 
 	// Construct plan path and verify it exists
 	planPath := filepath.Join(home, ".claude", "plans", slug+".md")
-	if _, err := os.Stat(planPath); err == nil {
+	if _, err := m.fs.Stat(planPath); err == nil {
 		return planPath
 	}
 	return ""
@@ -4858,7 +6365,7 @@ func (m *Model) findPlanFromSession(home string) string {
 // findMostRecentPlan finds the most recently modified plan file (fallback)
 func (m *Model) findMostRecentPlan(home string) string {
 	plansDir := filepath.Join(home, ".claude", "plans")
-	entries, err := os.ReadDir(plansDir)
+	entries, err := m.fs.ReadDir(plansDir)
 	if err != nil {
 		return ""
 	}
@@ -4890,15 +6397,19 @@ func (m *Model) loadPlanFile() {
 	// Use path from hook if already set and valid
 	planPath := m.planPath
 	if planPath != "" {
-		if content, err := os.ReadFile(planPath); err == nil {
+		if content, err := m.fs.ReadFile(planPath); err == nil {
 			m.planContent = string(content)
+			m.updatePlanTasks()
 			return
 		}
 		// Path invalid, clear it and try other methods
+		if planPath == m.planPinned {
+			m.planPinned = ""
+		}
 		m.planPath = ""
 	}
 
-	home, err := os.UserHomeDir()
+	home, err := m.fs.UserHomeDir()
 	if err != nil {
 		return
 	}
@@ -4917,7 +6428,7 @@ func (m *Model) loadPlanFile() {
 	}
 
 	// Read the plan file
-	content, err := os.ReadFile(planPath)
+	content, err := m.fs.ReadFile(planPath)
 	if err != nil {
 		m.planContent = fmt.Sprintf("Error reading plan: %v", err)
 		return
@@ -4925,6 +6436,7 @@ func (m *Model) loadPlanFile() {
 
 	m.planPath = planPath
 	m.planContent = string(content)
+	m.updatePlanTasks()
 }
 
 // renderMarkdown renders markdown content using glamour
@@ -4953,6 +6465,20 @@ func (m Model) renderMarkdown(content string, width int) (string, error) {
 	return strings.TrimRight(rendered, "\n"), nil
 }
 
+// syncPromptsCmd pulls and pushes the git-backed global prompt library,
+// setting up the repository first if prompts_remote is configured
+func (m Model) syncPromptsCmd() tea.Cmd {
+	store, remote := m.promptStore, m.config.PromptsRemote
+	return func() tea.Msg {
+		if remote != "" {
+			if err := store.EnableGit(remote); err != nil {
+				return promptSyncedMsg{err: err}
+			}
+		}
+		return promptSyncedMsg{err: store.Sync()}
+	}
+}
+
 // refreshPromptList reloads the list of prompts from storage
 func (m *Model) refreshPromptList() {
 	if m.promptStore == nil {
@@ -4968,24 +6494,19 @@ func (m *Model) refreshPromptList() {
 }
 
 // applyPromptFilter filters the prompt list based on current filter scope
+// and tags, then sorts it
 func (m *Model) applyPromptFilter() {
-	if m.promptFilter == PromptFilterAll {
-		m.promptFilteredList = m.promptList
-	} else {
-		m.promptFilteredList = make([]prompt.Prompt, 0)
-		for _, p := range m.promptList {
-			switch m.promptFilter {
-			case PromptFilterProject:
-				if !p.IsGlobal {
-					m.promptFilteredList = append(m.promptFilteredList, p)
-				}
-			case PromptFilterGlobal:
-				if p.IsGlobal {
-					m.promptFilteredList = append(m.promptFilteredList, p)
-				}
-			}
+	m.promptFilteredList = make([]prompt.Prompt, 0, len(m.promptList))
+	for _, p := range m.promptList {
+		switch {
+		case m.promptFilter == PromptFilterProject && p.IsGlobal,
+			m.promptFilter == PromptFilterGlobal && !p.IsGlobal,
+			!m.matchesPromptTags(p):
+			continue
 		}
+		m.promptFilteredList = append(m.promptFilteredList, p)
 	}
+	m.sortPrompts(m.promptFilteredList)
 	// Adjust selection if needed
 	if m.promptSelected >= len(m.promptFilteredList) {
 		if len(m.promptFilteredList) > 0 {
@@ -5030,22 +6551,23 @@ func (m *Model) createNewPrompt(isGlobal bool) (Model, tea.Cmd) {
 	tmpDir := os.TempDir()
 	tmpPath := filepath.Join(tmpDir, "new-prompt.prompt.md")
 	template := prompt.NewPromptTemplate("New Prompt")
-	if err := os.WriteFile(tmpPath, []byte(template), 0644); err != nil {
+	if err := m.fs.WriteFile(tmpPath, []byte(template), 0644); err != nil {
 		logger.Log("Failed to create temp prompt: %v", err)
 		return *m, nil
 	}
 
-	cmd := exec.Command("nvim", tmpPath)
+	store, fsys := m.promptStore, m.fs
+	cmd := m.runner.Command("nvim", tmpPath)
 	return *m, tea.ExecProcess(cmd, func(err error) tea.Msg {
 		if err != nil {
 			return nil
 		}
 		// Read the edited content and save to prompts directory
-		content, err := os.ReadFile(tmpPath)
+		content, err := fsys.ReadFile(tmpPath)
 		if err != nil {
 			return nil
 		}
-		os.Remove(tmpPath)
+		fsys.Remove(tmpPath)
 
 		// Parse and save
 		p, err := prompt.Parse(string(content))
@@ -5055,10 +6577,6 @@ func (m *Model) createNewPrompt(isGlobal bool) (Model, tea.Cmd) {
 		// Set scope based on isGlobal flag
 		p.IsGlobal = isGlobal
 
-		store, err := prompt.NewStore()
-		if err != nil {
-			return nil
-		}
 		if err := store.Save(p); err != nil {
 			logger.Log("Failed to save new prompt: %v", err)
 			return nil
@@ -5078,7 +6596,7 @@ func (m *Model) editPrompt(p prompt.Prompt) (Model, tea.Cmd) {
 		}
 	}
 
-	cmd := exec.Command("nvim", p.Path)
+	cmd := m.runner.Command("nvim", p.Path)
 	return *m, tea.ExecProcess(cmd, func(err error) tea.Msg {
 		return promptEditedMsg{path: p.Path}
 	})
@@ -5086,12 +6604,12 @@ func (m *Model) editPrompt(p prompt.Prompt) (Model, tea.Cmd) {
 
 // loadVersionList loads the list of versions for the currently selected prompt
 func (m *Model) loadVersionList() {
-	if m.promptStore == nil || len(m.promptList) == 0 {
+	if m.promptStore == nil || len(m.promptFilteredList) == 0 {
 		m.promptVersions = nil
 		return
 	}
 
-	p := m.promptList[m.promptSelected]
+	p := m.promptFilteredList[m.promptSelected]
 	versions, err := m.promptStore.ListVersions(p.Path)
 	if err != nil {
 		logger.Log("Failed to list versions: %v", err)
@@ -5119,16 +6637,16 @@ func (m *Model) expandPromptVariables(content string) string {
 		filePath = m.changes[m.selectedIndex].FilePath
 		fileName = filepath.Base(filePath)
 		// Try to find project root by looking for .git
-		projectDir = findProjectRoot(filepath.Dir(filePath))
+		projectDir = findProjectRoot(m.fs, filepath.Dir(filePath))
 	}
 
 	// Fall back to prompt store's project dir, then cwd
 	if projectDir == "" && m.promptStore != nil {
 		// The prompt store knows the project directory
-		projectDir, _ = os.Getwd()
+		projectDir, _ = m.fs.Getwd()
 	}
 	if projectDir == "" {
-		projectDir, _ = os.Getwd()
+		projectDir, _ = m.fs.Getwd()
 	}
 
 	projectName := filepath.Base(projectDir)
@@ -5138,7 +6656,7 @@ func (m *Model) expandPromptVariables(content string) string {
 	planContent := ""
 	if m.planPath != "" {
 		planName = filepath.Base(m.planPath)
-		if data, err := os.ReadFile(m.planPath); err == nil {
+		if data, err := m.fs.ReadFile(m.planPath); err == nil {
 			planContent = string(data)
 		}
 	}
@@ -5159,9 +6677,9 @@ func (m *Model) expandPromptVariables(content string) string {
 }
 
 // findProjectRoot walks up from dir looking for .git directory
-func findProjectRoot(dir string) string {
+func findProjectRoot(fsys FS, dir string) string {
 	for {
-		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		if _, err := fsys.Stat(filepath.Join(dir, ".git")); err == nil {
 			return dir
 		}
 		parent := filepath.Dir(dir)
@@ -1,10 +1,36 @@
 package model
 
 import (
+	gocontext "context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/ztaylor/claude-mon/internal/chat"
+	"github.com/ztaylor/claude-mon/internal/config"
+	workingctx "github.com/ztaylor/claude-mon/internal/context"
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/filter"
+	"github.com/ztaylor/claude-mon/internal/hookcheck"
+	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/internal/minimap"
+	"github.com/ztaylor/claude-mon/internal/objective"
+	"github.com/ztaylor/claude-mon/internal/prompt"
+	"github.com/ztaylor/claude-mon/internal/ralph"
+	"github.com/ztaylor/claude-mon/internal/socket"
+	"github.com/ztaylor/claude-mon/internal/theme"
+	"github.com/ztaylor/claude-mon/internal/vcs"
 )
 
 func TestParsePayload(t *testing.T) {
@@ -46,9 +72,10 @@ func TestParsePayload(t *testing.T) {
 		},
 	}
 
+	m := New("")
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			change := parsePayload([]byte(tt.payload))
+			change := m.parsePayload([]byte(tt.payload))
 			if tt.wantPath == "" {
 				if change != nil {
 					t.Errorf("expected nil change for %s", tt.name)
@@ -197,92 +224,2821 @@ func TestModelClearHistory(t *testing.T) {
 	}
 }
 
+func TestModelBookmarkFilter(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	// Add two changes; the newest (b.go) is selected
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"tool_name":"Edit","tool_input":{"file_path":"/a.go"}}`)})
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"tool_name":"Edit","tool_input":{"file_path":"/b.go"}}`)})
+
+	// Filtering with no bookmarks is refused
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'B'}})
+	if tm.(Model).bookmarksOnly {
+		t.Fatal("bookmarks filter should stay off without bookmarks")
+	}
+
+	// Bookmark the older change
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'b'}})
+	model := tm.(Model)
+	if !model.changes[1].Bookmarked {
+		t.Fatal("expected /a.go to be bookmarked")
+	}
+
+	// Filter, then navigation stays on bookmarked changes
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'B'}})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'k'}})
+	model = tm.(Model)
+	if !model.bookmarksOnly {
+		t.Fatal("expected bookmarks filter to be on")
+	}
+	if model.changes[model.selectedIndex].FilePath != "/a.go" {
+		t.Errorf("expected selection to stay on /a.go, got %s", model.changes[model.selectedIndex].FilePath)
+	}
+	if got := model.visibleChangeIndices(); len(got) != 1 {
+		t.Errorf("expected 1 visible change, got %d", len(got))
+	}
+
+	// Removing the last bookmark turns the filter off
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'b'}})
+	if tm.(Model).bookmarksOnly {
+		t.Error("filter should turn off when no bookmarks remain")
+	}
+}
+
+func TestModelHistoryFilter(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"tool_name":"Edit","tool_input":{"file_path":"/proj/a.go"}}`)})
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"tool_name":"Write","tool_input":{"file_path":"/proj/internal/b.go","content":"x"}}`)})
+
+	// Open the filter overlay and type an expression; "q" must not quit
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+	tm, cmd := tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("tool:edit q")})
+	if cmd != nil {
+		if _, ok := cmd().(tea.QuitMsg); ok {
+			t.Fatal("typing in the filter overlay should not quit")
+		}
+	}
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	model := tm.(Model)
+	if model.historyFilterActive {
+		t.Fatal("filter overlay should close on enter")
+	}
+	if got := model.visibleChangeIndices(); len(got) != 1 {
+		t.Fatalf("expected 1 visible change, got %d", len(got))
+	}
+	if path := model.changes[model.selectedIndex].FilePath; path != "/proj/a.go" {
+		t.Errorf("expected selection to move to /proj/a.go, got %s", path)
+	}
+
+	// Clearing the expression shows everything again
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'f'}})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if got := tm.(Model).visibleChangeIndices(); len(got) != 2 {
+		t.Errorf("expected 2 visible changes after clearing, got %d", len(got))
+	}
+}
+
+func TestModelRestoreSnapshot(t *testing.T) {
+	path := "/proj/a.go"
+	fsys := mapFS{workspaceFS: workspaceFS{dir: "/proj"}, files: fstest.MapFS{
+		"proj/a.go": {Data: []byte("edited\n"), Mode: 0600},
+	}}
+
+	m := New("/tmp/test.sock", WithFS(fsys))
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	// Any key but y/enter cancels
+	snapshot := &FileSnapshot{ID: 1, FilePath: path, Existed: true, Content: []byte("original\n")}
+	tm, _ = tm.Update(snapshotMsg{snapshot: snapshot})
+	if tm.(Model).snapshotRestorePending == nil {
+		t.Fatal("expected a restore awaiting confirmation")
+	}
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if got, _ := fsys.ReadFile(path); string(got) != "edited\n" {
+		t.Fatalf("expected the file untouched after cancelling, got %q", got)
+	}
+
+	tm, _ = tm.Update(snapshotMsg{snapshot: snapshot})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'y'}})
+	if tm.(Model).snapshotRestorePending != nil {
+		t.Error("expected the confirmation cleared")
+	}
+	got, _ := fsys.ReadFile(path)
+	if string(got) != "original\n" {
+		t.Errorf("expected the snapshot restored, got %q", got)
+	}
+	if info, _ := fsys.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("expected the file mode kept, got %v", info.Mode().Perm())
+	}
+
+	// A file the session created is removed
+	tm, _ = tm.Update(snapshotMsg{snapshot: &FileSnapshot{ID: 2, FilePath: path}})
+	tm.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if _, err := fsys.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the created file removed, got %v", err)
+	}
+}
+
+func TestModelNormalizeDiff(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	// An edit that only converts the file to LF line endings
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"tool_name":"Edit","tool_input":{"file_path":"/proj/a.go","old_string":"a\r\nb\r\n","new_string":"a\nb\n"}}`)})
+	body := func(tm tea.Model) string {
+		model := tm.(Model)
+		doc := model.buildDiffDoc(model.selectedIndex)
+		return strings.Join(append(doc.header, doc.static...), "\n")
+	}
+	if got := body(tm); !strings.Contains(got, "line endings CRLF→LF") || !strings.Contains(got, "No changes after normalization") {
+		t.Errorf("expected the line ending change hidden, got:\n%s", got)
+	}
+
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'W'}})
+	if got := body(tm); strings.Contains(got, "No changes after normalization") {
+		t.Errorf("expected the raw diff once normalization is off, got:\n%s", got)
+	}
+}
+
+func TestModelBinaryAndStructuralDiff(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	body := func(tm tea.Model) string {
+		model := tm.(Model)
+		doc := model.buildDiffDoc(model.selectedIndex)
+		return strings.Join(append(doc.header, doc.static...), "\n")
+	}
+
+	// Binary content is summarized, not printed
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"tool_name":"Write","tool_input":{"file_path":"/proj/logo.bin","content":"\u0000\u0001\u0002"}}`)})
+	if got := body(tm); !strings.Contains(got, "Binary file") || !strings.Contains(got, "After:  3 B") {
+		t.Errorf("expected a binary summary, got:\n%s", got)
+	}
+
+	// Reformatted JSON shows no change once diffed structurally
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"tool_name":"Edit","tool_input":{"file_path":"/proj/a.json","old_string":"{\"b\":1,\"a\":2}","new_string":"{\"a\": 2, \"b\": 1}"}}`)})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'P'}})
+	if got := body(tm); !strings.Contains(got, "No structural changes") {
+		t.Errorf("expected no structural changes, got:\n%s", got)
+	}
+}
+
+func TestModelSemanticDiff(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	body := func(tm tea.Model) string {
+		model := tm.(Model)
+		doc := model.buildDiffDoc(model.selectedIndex)
+		return strings.Join(append(doc.header, doc.static...), "\n")
+	}
+
+	// Reordered YAML with one changed value
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"tool_name":"Edit","tool_input":{"file_path":"/proj/app.yaml","old_string":"name: web\nimage: web:1\n","new_string":"image: web:2\nname: web\n"}}`)})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'S'}})
+	got := body(tm)
+	if !strings.Contains(got, "~ image: ") || strings.Contains(got, "name") {
+		t.Errorf("expected only the image key changed, got:\n%s", got)
+	}
+
+	// The toggle is per change
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"tool_name":"Edit","tool_input":{"file_path":"/proj/b.yaml","old_string":"a: 1\n","new_string":"a: 2\n"}}`)})
+	if got := body(tm); strings.Contains(got, "Semantic diff") {
+		t.Errorf("expected a line diff for the new change, got:\n%s", got)
+	}
+}
+
+func TestModelChangeSummary(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"tool_name":"Edit","tool_input":{"file_path":"/proj/model.go","old_string":"func (m *Model) Update() {\n\treturn\n}\n","new_string":"func (m *Model) Update() {\n\tm.n++\n}\n\ntype RetryPolicy struct{}\n"}}`)})
+	model := tm.(Model)
+	want := "modified func (Model) Update, added type RetryPolicy"
+	if got := model.changes[0].Summary; got != want {
+		t.Errorf("expected summary %q, got %q", want, got)
+	}
+	if got := model.changeDetail(model.changes[0]); !strings.HasPrefix(got, want) {
+		t.Errorf("expected the history detail line to lead with the summary, got %q", got)
+	}
+}
+
+func TestModelChecks(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	// Two edits to a.go checked together, then one to b.go not yet checked
+	for _, file := range []string{"a.go", "a.go", "b.go"} {
+		tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"tool_name":"Edit","tool_input":{"file_path":"/proj/` + file + `","old_string":"x","new_string":"y"}}`)})
+	}
+	run := time.Now().Add(time.Second)
+	tm, _ = tm.Update(checksMsg{results: []CheckResult{
+		{FilePath: "/proj/a.go", Name: "vet", Command: "go vet .", Passed: true, StartedAt: run},
+		{FilePath: "/proj/a.go", Name: "test", Command: "go test .", Passed: false, ExitCode: 1, Output: "--- FAIL: TestA", StartedAt: run},
+	}})
+	model := tm.(Model)
+	if got := model.changeChecks(1); len(got) != 2 {
+		t.Errorf("expected the newest a.go edit to have 2 checks, got %d", len(got))
+	}
+	if got := model.changeChecks(2); len(got) != 0 {
+		t.Errorf("expected the earlier a.go edit to have no checks, got %d", len(got))
+	}
+	if got := model.changeChecks(0); len(got) != 0 {
+		t.Errorf("expected b.go to have no checks, got %d", len(got))
+	}
+
+	// The checks panel shows the failing output
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'c'}})
+	model = tm.(Model)
+	doc := model.buildDiffDoc(model.selectedIndex)
+	got := strings.Join(append(doc.header, doc.static...), "\n")
+	if !strings.Contains(got, "✗ test (exit 1)") || !strings.Contains(got, "--- FAIL: TestA") || !strings.Contains(got, "✓ vet") {
+		t.Errorf("expected the checks panel with failing output, got:\n%s", got)
+	}
+}
+
+func TestModelFileHistory(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"tool_name":"Edit","tool_input":{"file_path":"/proj/a.go","old_string":"c","new_string":"C"}}`)})
+
+	// H opens the history of the selected change's file, slider on its version
+	tm, cmd := tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'H'}})
+	if cmd == nil || tm.(Model).fileHistory == nil {
+		t.Fatal("expected H to open the file history and query the daemon")
+	}
+	start := time.Now().Add(-time.Hour)
+	tm, _ = tm.Update(fileHistoryMsg{path: "/proj/a.go", edits: []*database.Edit{
+		{ID: 1, ToolName: "Write", FilePath: "/proj/a.go", NewString: "a\nb\nc\n", Timestamp: start},
+		{ID: 2, ToolName: "Edit", FilePath: "/proj/a.go", OldString: "b", NewString: "B", FileContent: "a\nB\nc\n", Timestamp: start.Add(time.Minute)},
+		{ID: 3, ToolName: "Edit", FilePath: "/proj/a.go", OldString: "c", NewString: "C", FileContent: "a\nB\nC\n", Timestamp: start.Add(2 * time.Minute)},
+	}})
+	model := tm.(Model)
+	if got := model.fileHistory.version; got != 2 {
+		t.Errorf("expected the slider on the newest version, got %d", got)
+	}
+
+	// , steps back a version, shown as the file with that edit inline
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{','}})
+	model = tm.(Model)
+	doc := model.buildDiffDoc(model.selectedIndex)
+	if doc.file == nil || doc.file.change.LineNum != 2 || strings.Join(doc.file.fileLines, "") != "abc" {
+		t.Fatalf("expected version 2 as the file before it with line 2 replaced, got %+v", doc.file)
+	}
+	if header := strings.Join(doc.header, "\n"); !strings.Contains(header, "version 2/3") {
+		t.Errorf("expected the slider at version 2/3, got:\n%s", header)
+	}
+
+	// Esc goes back to the change
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if tm.(Model).fileHistory != nil {
+		t.Error("expected esc to close the file history")
+	}
+}
+
+func TestModelTruncatedPayload(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	payload, _ := json.Marshal(map[string]any{
+		"tool_name":  "Write",
+		"tool_input": map[string]string{"file_path": "/proj/big.go", "content": "package big\n" + socket.TruncatedMarker},
+	})
+	tm, _ = tm.Update(SocketMsg{Payload: payload})
+
+	model := tm.(Model)
+	if len(model.changes) != 1 || !model.changes[0].Truncated {
+		t.Fatalf("expected a change marked truncated, got %+v", model.changes)
+	}
+	if header := strings.Join(model.buildDiffDoc(0).header, "\n"); !strings.Contains(header, "Content truncated") {
+		t.Errorf("expected the diff header to say the content was truncated, got:\n%s", header)
+	}
+}
+
+func TestModelExternalEdits(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"tool_name":"Edit","tool_input":{"file_path":"/proj/a.go","old_string":"a","new_string":"b"}}`)})
+
+	// Changes the daemon's file watcher recorded are added once, newest first
+	external := Change{Timestamp: time.Now().Add(time.Minute), FilePath: "/proj/gen.go", ToolName: "External", NewString: "x\n", LineNum: 1}
+	queried := time.Now()
+	tm, _ = tm.Update(externalEditsMsg{changes: []Change{external}, queried: queried})
+	tm, _ = tm.Update(externalEditsMsg{changes: []Change{external}, queried: queried})
+	model := tm.(Model)
+	if len(model.changes) != 2 || model.changes[0].FilePath != "/proj/gen.go" {
+		t.Fatalf("expected the external change added once, newest first, got %+v", model.changes)
+	}
+	if model.selectedIndex != 1 {
+		t.Errorf("expected the selection to stay on the edit, got %d", model.selectedIndex)
+	}
+	if !model.externalSince.Before(queried) {
+		t.Errorf("expected the next poll to start before %v, got %v", queried, model.externalSince)
+	}
+
+	// They're marked in the list and the diff header
+	rows, _ := model.renderHistoryRows()
+	if !strings.Contains(rows, externalMark) {
+		t.Errorf("expected the external change marked in history:\n%s", rows)
+	}
+	if header := strings.Join(model.buildDiffDoc(0).header, "\n"); !strings.Contains(header, "Changed outside Claude") {
+		t.Errorf("expected the diff header to say the change was made outside Claude, got:\n%s", header)
+	}
+}
+
+func TestModelSessionGroups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sess-a.jsonl")
+	transcript := `{"type":"user","timestamp":"2025-01-01T10:00:00Z","message":{"role":"user","content":"Fix the login bug\nand add a test"}}
+`
+	if err := os.WriteFile(path, []byte(transcript), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 150, Height: 40})
+
+	// Two edits from a session with a transcript, then one from another
+	for _, edit := range []struct{ session, file string }{{"sess-a", "a.go"}, {"sess-a", "b.go"}, {"sess-b", "c.go"}} {
+		tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"session_id":"` + edit.session + `","transcript_path":"` + path + `","tool_name":"Edit","tool_input":{"file_path":"/proj/` + edit.file + `","old_string":"x","new_string":"y"}}`)})
+	}
+	model := tm.(Model)
+	model.sessionPrompts["sess-b"] = "" // Its transcript is sess-a's here
+	view := model.View()
+	if !strings.Contains(view, "▾ Fix the login bug · ") || !strings.Contains(view, "2 edits") || !strings.Contains(view, "▾ session sess-b") {
+		t.Errorf("expected a header for each session, got:\n%s", view)
+	}
+
+	// } and { move between sessions
+	tm, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'}'}})
+	if got := tm.(Model).changes[tm.(Model).selectedIndex].SessionID; got != "sess-a" {
+		t.Fatalf("expected the next session selected, got %q", got)
+	}
+
+	// o collapses the session into one entry
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'o'}})
+	model = tm.(Model)
+	if got := len(model.visibleChangeIndices()); got != 2 {
+		t.Errorf("expected 2 entries with sess-a collapsed, got %d", got)
+	}
+	if view := model.View(); !strings.Contains(view, "▸ Fix the login bug") {
+		t.Errorf("expected the collapsed session, got:\n%s", view)
+	}
+	doc := model.buildDiffDoc(model.selectedIndex)
+	if got := strings.Join(doc.static, "\n"); !strings.Contains(got, "and add a test") || !strings.Contains(got, "@@ 2 files @@") {
+		t.Errorf("expected the session overview, got:\n%s", got)
+	}
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'{'}})
+	if got := tm.(Model).selectedIndex; got != 0 {
+		t.Errorf("expected the newer session selected, got %d", got)
+	}
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'}'}})
+
+	// Leader n names the session
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyCtrlG})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'n'}})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("Login fix")})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = tm.(Model)
+	if got := model.sessionNames["sess-a"]; got != "Login fix" {
+		t.Errorf("expected the session named, got %q", got)
+	}
+	if view := model.View(); !strings.Contains(view, "▸ Login fix · ") {
+		t.Errorf("expected the name in the history, got:\n%s", view)
+	}
+}
+
+func TestModelHistoryPages(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 150, Height: 40})
+
+	// A first page of 8 edits, with older ones still in the daemon
+	base := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	page := func(from, n int) []Change {
+		var changes []Change
+		for i := from; i < from+n; i++ {
+			changes = append(changes, Change{
+				Timestamp: base.Add(-time.Duration(i) * time.Minute),
+				FilePath:  fmt.Sprintf("/proj/f%d.go", i),
+				ToolName:  "Edit",
+				NewString: "x",
+			})
+		}
+		return changes
+	}
+	tm, _ = tm.Update(daemonHistoryMsg{changes: page(0, 8), nextCursor: "1735732320.8"})
+	if view := tm.(Model).View(); !strings.Contains(view, "History (8+)") {
+		t.Errorf("expected more history flagged, got:\n%s", view)
+	}
+
+	// Nearing the oldest entry asks for the next page
+	for i := 0; i < 3; i++ {
+		tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	}
+	model := tm.(Model)
+	if !model.historyLoading {
+		t.Fatal("expected older edits to be loading")
+	}
+	if view := model.View(); !strings.Contains(view, "loading older") {
+		t.Errorf("expected a loading indicator, got:\n%s", view)
+	}
+
+	// The older page is appended without moving the selection
+	selected := model.changes[model.selectedIndex].FilePath
+	tm, _ = tm.Update(daemonHistoryMsg{changes: page(8, 3), older: true})
+	model = tm.(Model)
+	if model.historyLoading || len(model.changes) != 11 {
+		t.Fatalf("expected 11 changes loaded, got %d", len(model.changes))
+	}
+	if got := model.changes[model.selectedIndex].FilePath; got != selected {
+		t.Errorf("expected %s to stay selected, got %s", selected, got)
+	}
+	if got := model.changes[10].FilePath; got != "/proj/f10.go" {
+		t.Errorf("expected the oldest edit last, got %s", got)
+	}
+
+	// With no cursor left, nothing more is loaded
+	for i := 0; i < 10; i++ {
+		tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	}
+	if tm.(Model).historyLoading {
+		t.Error("expected no load once all edits are loaded")
+	}
+}
+
+func TestModelSquashHistory(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	// Three edits to a.go, then one to b.go (selected, newest)
+	for _, edit := range []string{`"old_string":"one","new_string":"two"`, `"old_string":"two","new_string":"three"`, `"old_string":"three","new_string":"four"`} {
+		tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"tool_name":"Edit","tool_input":{"file_path":"/proj/a.go",` + edit + `}}`)})
+	}
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"tool_name":"Edit","tool_input":{"file_path":"/proj/b.go","old_string":"x","new_string":"y"}}`)})
+
+	// Squashing collapses the a.go edits into one entry
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'s'}})
+	model := tm.(Model)
+	if got := model.visibleChangeIndices(); len(got) != 2 {
+		t.Fatalf("expected 2 visible entries when squashed, got %d", len(got))
+	}
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	model = tm.(Model)
+	if model.selectedIndex != 1 {
+		t.Fatalf("expected the a.go run head (index 1) selected, got %d", model.selectedIndex)
+	}
+	if step := model.squashStep(1); step != "3 edits" {
+		t.Errorf("expected collapsed run labelled %q, got %q", "3 edits", step)
+	}
+	doc := model.buildDiffDoc(1)
+	view := strings.Join(append(doc.header, doc.static...), "\n")
+	if !strings.Contains(view, "3 edits squashed") || !strings.Contains(view, "one") || !strings.Contains(view, "four") {
+		t.Errorf("expected combined diff from the first old string to the last new string, got:\n%s", view)
+	}
+
+	// Expanding lists each step
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'e'}})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	model = tm.(Model)
+	if got := model.visibleChangeIndices(); len(got) != 4 {
+		t.Fatalf("expected 4 visible entries when expanded, got %d", len(got))
+	}
+	if step := model.squashStep(model.selectedIndex); step != "Edit 2/3" {
+		t.Errorf("expected step label %q, got %q", "Edit 2/3", step)
+	}
+
+	// Collapsing from a step selects the run again
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'e'}})
+	model = tm.(Model)
+	if model.selectedIndex != 1 || len(model.visibleChangeIndices()) != 2 {
+		t.Errorf("expected collapsed run selected, got index %d with %d entries", model.selectedIndex, len(model.visibleChangeIndices()))
+	}
+
+	// Turning squashing off shows every edit
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'s'}})
+	if got := tm.(Model).visibleChangeIndices(); len(got) != 4 {
+		t.Errorf("expected 4 visible entries unsquashed, got %d", len(got))
+	}
+}
+
+func TestModelControlCommands(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"tool_name":"Edit","tool_input":{"file_path":"/proj/a.go"}}`)})
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"tool_name":"Write","tool_input":{"file_path":"/proj/internal/b.go","content":"x"}}`)})
+
+	tm, _ = tm.Update(ControlMsg{Command: "switch-tab", Args: []string{"prompts"}})
+	if mode := tm.(Model).leftPaneMode; mode != LeftPaneModePrompts {
+		t.Fatalf("expected prompts tab, got %d", mode)
+	}
+
+	// Selecting a file returns to the history tab
+	tm, _ = tm.Update(ControlMsg{Command: "select-file", Args: []string{"a.go"}})
+	model := tm.(Model)
+	if model.leftPaneMode != LeftPaneModeHistory {
+		t.Errorf("expected history tab after select-file, got %d", model.leftPaneMode)
+	}
+	if path := model.changes[model.selectedIndex].FilePath; path != "/proj/a.go" {
+		t.Errorf("expected /proj/a.go selected, got %s", path)
+	}
+
+	tm, _ = tm.Update(ControlMsg{Command: "jump-to-edit", Args: []string{"newest"}})
+	if path := tm.(Model).changes[tm.(Model).selectedIndex].FilePath; path != "/proj/internal/b.go" {
+		t.Errorf("expected newest change selected, got %s", path)
+	}
+
+	tm, _ = tm.Update(ControlMsg{Command: "set-filter", Args: []string{"tool:edit"}})
+	if got := tm.(Model).visibleChangeIndices(); len(got) != 1 {
+		t.Errorf("expected 1 visible change after set-filter, got %d", len(got))
+	}
+
+	if err := ValidateControlCommand("switch-tab", []string{"nope"}); err == nil {
+		t.Error("expected error for unknown tab")
+	}
+	if err := ValidateControlCommand("jump-to-edit", []string{"0"}); err == nil {
+		t.Error("expected error for position 0")
+	}
+
+	_, cmd := tm.Update(ControlMsg{Command: "quit"})
+	if cmd == nil {
+		t.Fatal("expected quit command")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Error("expected quit to return tea.QuitMsg")
+	}
+}
+
+func TestHistoryListDensity(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+	model := tm.(Model)
+
+	model.config.ListDensity = config.DensityAuto
+	if got := model.historyLinesPerItem(); got != 1 {
+		t.Errorf("auto density on short terminal: expected 1 line, got %d", got)
+	}
+	compactItems := model.listVisibleItems()
+
+	model.height = tallTerminalRows
+	if got := model.historyLinesPerItem(); got != 2 {
+		t.Errorf("auto density on tall terminal: expected 2 lines, got %d", got)
+	}
+
+	model.height = 40
+	model.config.ListDensity = config.DensityComfortable
+	if got := model.listVisibleItems(); got != compactItems/2 {
+		t.Errorf("comfortable density: expected %d items, got %d", compactItems/2, got)
+	}
+}
+
+func TestHistoryTimeGaps(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 12})
+	model := tm.(Model)
+
+	now := time.Now()
+	model.config.TimeGap = "15m"
+	model.config.ListDensity = config.DensityCompact
+	model.changes = []Change{
+		{Timestamp: now, FilePath: "/proj/a.go", ToolName: "Edit"},
+		{Timestamp: now.Add(-2 * time.Minute), FilePath: "/proj/b.go", ToolName: "Edit"},
+		{Timestamp: now.Add(-44 * time.Minute), FilePath: "/proj/c.go", ToolName: "Edit"},
+		{Timestamp: now.Add(-45 * time.Minute), FilePath: "/proj/d.go", ToolName: "Edit"},
+	}
+
+	if !strings.Contains(model.renderHistory(), "42 min idle") {
+		t.Error("expected a 42 min idle separator")
+	}
+
+	// The separator takes a line, so one fewer entry fits
+	visible := model.visibleChangeIndices()
+	if got, items := model.historyItemsFitting(visible, 0), model.listVisibleItems(); got != items-1 {
+		t.Errorf("expected %d entries to fit with a separator, got %d", items-1, got)
+	}
+
+	// Selecting the last entry scrolls it into view
+	model.selectedIndex = 3
+	model.ensureSelectedVisible()
+	if end := model.listScrollOffset + model.historyItemsFitting(visible, model.listScrollOffset); end < 4 {
+		t.Errorf("expected the oldest entry to be visible, list ends at %d", end)
+	}
+
+	model.config.TimeGap = "0"
+	if strings.Contains(model.renderHistory(), "idle") {
+		t.Error("expected no separators when disabled")
+	}
+}
+
+func TestFileIcons(t *testing.T) {
+	m := New("/tmp/test.sock")
+	m.changes = []Change{
+		{Timestamp: time.Now(), FilePath: "/proj/web/App.tsx", ToolName: "Edit"},
+		{Timestamp: time.Now(), FilePath: "/proj/main.go", ToolName: "Edit"},
+	}
+
+	m.config.FileIcons = config.IconsASCII
+	if icon := m.renderFileIcon(m.changes[0]); !strings.Contains(icon, "rx") {
+		t.Errorf("expected ascii React tag, got %q", icon)
+	}
+
+	m.config.FileIcons = config.IconsNone
+	if icon := m.renderFileIcon(m.changes[1]); icon != "" || m.fileIconWidth() != 0 {
+		t.Errorf("expected no icon when disabled, got %q", icon)
+	}
+
+	// lang: filters narrow the list to matching files
+	f, err := filter.Parse("lang:go")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	m.historyFilter = f
+	if visible := m.visibleChangeIndices(); len(visible) != 1 || visible[0] != 1 {
+		t.Errorf("expected only main.go visible, got %v", visible)
+	}
+}
+
+func TestAsyncDiffRender(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	model := tm.(Model)
+
+	// A huge edit renders in the background and only its window is formatted
+	huge := strings.Repeat("x := 1\n", 700000)
+	model.changes = []Change{
+		{Timestamp: time.Now(), FilePath: "/proj/big.go", ToolName: "Edit", LineNum: 1, OldString: "x := 0\n", NewString: huge, FileContent: huge},
+		{Timestamp: time.Now(), FilePath: "/proj/small.go", ToolName: "Edit", LineNum: 1, OldString: "a", NewString: "b", FileContent: "b\n"},
+	}
+
+	if cmd := model.showDiff(); cmd == nil || !model.diffRendering {
+		t.Fatal("expected a background render for a huge diff")
+	}
+	if !strings.Contains(model.diffViewport.View(), "Rendering") {
+		t.Error("expected the pending placeholder in the viewport")
+	}
+
+	msg := model.renderDiffCmd()().(diffRenderedMsg)
+	if msg.doc == nil || msg.doc.Len() < 2*700000 {
+		t.Fatal("expected the document to cover every line")
+	}
+
+	// Results superseded by a later selection are dropped
+	stale := msg
+	stale.seq--
+	model.applyRenderedDiff(stale)
+	if !model.diffRendering {
+		t.Error("expected a stale result to be ignored")
+	}
+	model.applyRenderedDiff(msg)
+	if model.diffRendering || model.diffCache[0] != msg.doc {
+		t.Error("expected the rendered diff to be shown and cached")
+	}
+	limit := model.diffViewport.Height + 2*diffWindowMargin
+	if len(msg.doc.formatted) > limit {
+		t.Errorf("expected at most %d formatted rows, got %d", limit, len(msg.doc.formatted))
+	}
+
+	// Scrolling far formats the new window and drops the old one
+	model.scrollDiff(1000000 - model.diffScrollTop())
+	if model.diffScrollTop() != 1000000 {
+		t.Errorf("expected scroll top 1000000, got %d", model.diffScrollTop())
+	}
+	if len(msg.doc.formatted) > limit {
+		t.Errorf("expected at most %d formatted rows after scrolling, got %d", limit, len(msg.doc.formatted))
+	}
+	if _, ok := msg.doc.formatted[1000000-len(msg.doc.header)]; !ok {
+		t.Error("expected the scrolled window to be formatted")
+	}
+
+	// Small diffs render inline
+	model.selectedIndex = 1
+	if cmd := model.showDiff(); cmd != nil || model.diffRendering {
+		t.Error("expected an inline render for a small diff")
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	tests := []struct {
+		age  time.Duration
+		want string
+	}{
+		{10 * time.Second, "just now"},
+		{5 * time.Minute, "5m ago"},
+		{3 * time.Hour, "3h ago"},
+		{49 * time.Hour, "2d ago"},
+	}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	m := New("/tmp/test.sock", WithClock(ClockFunc(func() time.Time { return now })))
+	for _, tt := range tests {
+		if got := m.relativeTime(now.Add(-tt.age)); got != tt.want {
+			t.Errorf("relativeTime(-%s) = %q, want %q", tt.age, got, tt.want)
+		}
+	}
+}
+
 func TestTruncatePath(t *testing.T) {
+	m := New("/tmp/test.sock", WithFS(workspaceFS{dir: "/proj"}))
+	tests := []struct {
+		path   string
+		maxLen int
+		want   string
+	}{
+		{"short.go", 20, "short.go"},
+		{"/very/long/path/to/file.go", 15, ".../file.go"},
+		{"/a/b/c.go", 10, ".../c.go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got := m.truncatePath(tt.path, tt.maxLen)
+			if len(got) > tt.maxLen && tt.maxLen < len(tt.path) {
+				// Truncation should respect max length (approximately)
+				// The ".../" prefix adds 4 chars
+			}
+			// Just verify it doesn't panic and returns something
+			if got == "" {
+				t.Error("truncated path should not be empty")
+			}
+		})
+	}
+}
+
+func TestChangeTimestamp(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
+
+	before := time.Now()
+	payload := `{"tool_name":"Edit","tool_input":{"file_path":"/test.go"}}`
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(payload)})
+	after := time.Now()
+
+	model := tm.(Model)
+	change := model.changes[0]
+
+	if change.Timestamp.Before(before) || change.Timestamp.After(after) {
+		t.Errorf("timestamp %v should be between %v and %v", change.Timestamp, before, after)
+	}
+}
+
+func TestParseKeyValue(t *testing.T) {
 	tests := []struct {
-		path   string
-		maxLen int
-		want   string
+		input   string
+		wantKey string
+		wantVal string
+		wantOk  bool
 	}{
-		{"short.go", 20, "short.go"},
-		{"/very/long/path/to/file.go", 15, ".../file.go"},
-		{"/a/b/c.go", 10, ".../c.go"},
+		// Simple cases
+		{"foo=bar", "foo", "bar", true},
+		{"DEBUG=true", "DEBUG", "true", true},
+
+		// Double-quoted values with spaces
+		{`foo="hello world"`, "foo", "hello world", true},
+		{`MESSAGE="this is a test"`, "MESSAGE", "this is a test", true},
+
+		// Single-quoted values with spaces
+		{`foo='hello world'`, "foo", "hello world", true},
+
+		// Backtick-quoted values
+		{"foo=`hello world`", "foo", "hello world", true},
+
+		// Values with special characters
+		{`PATH="/usr/bin:/usr/local/bin"`, "PATH", "/usr/bin:/usr/local/bin", true},
+
+		// Invalid cases
+		{"noequals", "", "", false},
+		{"=value", "", "", false},
+		{"", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			key, val, ok := parseKeyValue(tt.input)
+			if ok != tt.wantOk {
+				t.Errorf("parseKeyValue(%q) ok = %v, want %v", tt.input, ok, tt.wantOk)
+			}
+			if key != tt.wantKey {
+				t.Errorf("parseKeyValue(%q) key = %q, want %q", tt.input, key, tt.wantKey)
+			}
+			if val != tt.wantVal {
+				t.Errorf("parseKeyValue(%q) val = %q, want %q", tt.input, val, tt.wantVal)
+			}
+		})
+	}
+}
+
+func TestBlameGutter(t *testing.T) {
+	m := New("/tmp/test.sock")
+	blame := []vcs.BlameLine{
+		{SHA: "1234567890abcdef1234567890abcdef12345678", Author: "A Very Long Author Name"},
+		{SHA: "0000000000000000000000000000000000000000", Author: "Not Committed Yet"},
+	}
+
+	width := lipgloss.Width(m.renderClaudeGutter())
+	for i := -1; i < 3; i++ {
+		if got := lipgloss.Width(m.renderBlameGutter(blame, i)); got != width {
+			t.Errorf("line %d: gutter width %d, want %d", i, got, width)
+		}
+	}
+	if got := m.renderBlameGutter(blame, 0); !strings.Contains(got, "1234567") || !strings.Contains(got, "A Very Lo…") {
+		t.Errorf("expected short SHA and truncated author, got %q", got)
+	}
+	if got := m.renderBlameGutter(blame, 1); !strings.Contains(got, "uncommitted") {
+		t.Errorf("expected uncommitted marker, got %q", got)
+	}
+}
+
+func TestSendFeedback(t *testing.T) {
+	m := newGoldenModel(t, 100, 30)
+	m.promptInjectMethod = prompt.InjectClipboard
+	m.changes = []Change{
+		{Timestamp: goldenNow, FilePath: "/proj/a.go", LineNum: 12, OldString: "x := 1", NewString: "x := 2"},
+		{Timestamp: goldenNow.Add(-time.Minute), FilePath: "/proj/b.go", OldString: "old", NewString: "new",
+			Review: "rejected", Note: "handle nil"},
+	}
+
+	m = updateModel(m, keys("ctrl+g", "F")...)
+	pending := m.promptSendPending
+	if pending == nil || !pending.feedback {
+		t.Fatal("expected the selected change queued as feedback")
+	}
+	for _, want := range []string{"Please revisit", "### a.go:12", "-x := 1\n+x := 2"} {
+		if !strings.Contains(pending.content, want) {
+			t.Errorf("expected %q in the feedback, got %q", want, pending.content)
+		}
+	}
+	if status := m.renderStatus(); !strings.Contains(status, "feedback on a.go") {
+		t.Errorf("expected the send confirmation, got %q", status)
+	}
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyEsc})
+
+	// Only rejected changes go, with the reason they were rejected
+	m.config.FeedbackTemplate = "{{count}} to fix:\n{{changes}}"
+	m = updateModel(m, keys("ctrl+g", "R")...)
+	if m.promptSendPending == nil {
+		t.Fatal("expected the rejected changes queued as feedback")
+	}
+	got := m.promptSendPending.content
+	if !strings.HasPrefix(got, "1 to fix:\n### b.go") || !strings.Contains(got, "Reason: handle nil") || strings.Contains(got, "a.go") {
+		t.Errorf("unexpected rejected feedback %q", got)
+	}
+}
+
+func TestPromptSendConfirmation(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	model := tm.(Model)
+	model.promptFilteredList = []prompt.Prompt{{Name: "big", Content: "Plan:\n" + strings.Repeat("word ", 10000)}}
+	model.promptInjectMethod = prompt.InjectClipboard
+	model.promptSelected = 0
+	model.confirmSendPrompt()
+
+	if model.promptSendPending == nil {
+		t.Fatal("expected a pending send")
+	}
+	if !model.promptSendPending.estimate.Large() {
+		t.Errorf("expected a large estimate, got %s", model.promptSendPending.estimate)
+	}
+	if status := model.renderStatus(); !strings.Contains(status, "12.5k tokens") {
+		t.Errorf("expected token estimate in status line, got %q", status)
+	}
+
+	// Any key other than y/enter cancels without sending
+	tm, _ = model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if tm.(Model).promptSendPending != nil {
+		t.Error("expected esc to cancel the pending send")
+	}
+}
+
+func TestCycleInjectMethod(t *testing.T) {
+	m := New("/tmp/test.sock")
+	m.promptInjectMethod = prompt.InjectTmux
+
+	// Without a managed chat session, chat is never offered
+	for _, want := range []prompt.InjectionMethod{prompt.InjectClipboard, prompt.InjectTmux, prompt.InjectClipboard} {
+		m.cycleInjectMethod()
+		if m.promptInjectMethod != want {
+			t.Fatalf("got %s, want %s", prompt.MethodName(m.promptInjectMethod), prompt.MethodName(want))
+		}
+	}
+
+	// A method that's no longer available restarts the cycle
+	m.promptInjectMethod = prompt.InjectChat
+	m.cycleInjectMethod()
+	if m.promptInjectMethod != prompt.InjectTmux {
+		t.Errorf("got %s, want tmux", prompt.MethodName(m.promptInjectMethod))
+	}
+}
+
+func TestChatTab(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'6'}})
+
+	model := tm.(Model)
+	if model.leftPaneMode != LeftPaneModeChat {
+		t.Fatalf("expected chat mode, got %d", model.leftPaneMode)
+	}
+	view := model.View()
+	if !strings.Contains(view, "6:Chat") || !strings.Contains(view, "No chat session") {
+		t.Errorf("expected the chat tab and an empty transcript, got:\n%s", view)
+	}
+
+	// The input only opens once a session is running
+	tm, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'i'}})
+	if tm.(Model).chatInputActive {
+		t.Error("expected no message input without a session")
+	}
+
+	// p picks the purpose the next session starts with
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'p'}})
+	if got := tm.(Model).chatPurpose; got != chatPurposes[1] {
+		t.Errorf("got purpose %s, want %s", got, chatPurposes[1])
+	}
+	if !strings.Contains(tm.(Model).View(), "purpose: "+string(chatPurposes[1])) {
+		t.Error("expected the header to show the new purpose")
+	}
+
+	// Saved sessions from the daemon open the resume picker
+	model = tm.(Model)
+	model.showChatSessions([]ChatSession{{SessionID: "3f2c", Purpose: "plan", Messages: 4, Edits: 2, Preview: "What's left in\nthe plan?"}})
+	if !model.showChatPicker {
+		t.Fatal("expected the session picker to open")
+	}
+	if view := model.View(); !strings.Contains(view, "4 msgs   2 edits  What's left in the plan?") {
+		t.Errorf("expected the saved session listed, got:\n%s", view)
+	}
+	tm, _ = model.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if tm.(Model).showChatPicker {
+		t.Error("expected esc to close the picker")
+	}
+}
+
+func TestChatToolActivity(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'6'}})
+
+	model := tm.(Model)
+	model.chatSession = chat.New()
+	model.chatSession.SetSessionID("sess-1")
+
+	// Only the chat session's Edit/Write/Bash calls are listed
+	tm, _ = model.Update(SocketMsg{Payload: []byte(`{"session_id":"sess-1","tool_name":"Edit","tool_input":{"file_path":"/proj/a.go","old_string":"x","new_string":"y"}}`)})
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"session_id":"sess-1","tool_name":"Bash","tool_input":{"command":"go test ./..."}}`)})
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"session_id":"sess-1","tool_name":"Read","tool_input":{"file_path":"/proj/a.go"}}`)})
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"session_id":"other","tool_name":"Edit","tool_input":{"file_path":"/proj/b.go"}}`)})
+
+	model = tm.(Model)
+	if len(model.chatTools) != 2 {
+		t.Fatalf("expected 2 tool calls, got %+v", model.chatTools)
+	}
+	view := model.View()
+	if !strings.Contains(view, "Tool activity (2)") || !strings.Contains(view, "go test ./...") {
+		t.Errorf("expected the tool activity sidebar, got:\n%s", view)
+	}
+
+	// Enter on the edit opens its change in History
+	tm, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'t'}})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'k'}})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	model = tm.(Model)
+	if model.leftPaneMode != LeftPaneModeHistory {
+		t.Fatalf("expected history mode, got %d", model.leftPaneMode)
+	}
+	if got := model.changes[model.selectedIndex].FilePath; got != "/proj/a.go" {
+		t.Errorf("expected /proj/a.go selected, got %s", got)
+	}
+}
+
+func TestObjectiveQueue(t *testing.T) {
+	objective.QueuesDir = t.TempDir()
+
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'6'}})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'o'}})
+
+	// Objectives typed into the queue view are added in order
+	for _, text := range []string{"Rename Foo to Bar", "Add tests for the parser"} {
+		tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}})
+		tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)})
+		tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	}
+
+	model := tm.(Model)
+	if len(model.objectives.Objectives) != 2 {
+		t.Fatalf("expected 2 objectives, got %+v", model.objectives.Objectives)
+	}
+	view := model.View()
+	if !strings.Contains(view, "Objective Queue") || !strings.Contains(view, "2 pending") || !strings.Contains(view, "Add tests for the parser") {
+		t.Errorf("expected the objective queue, got:\n%s", view)
+	}
+
+	// K moves the selected (newest) objective ahead of the first
+	tm, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'K'}})
+	model = tm.(Model)
+	if got := objectiveLabel(model.objectives.Objectives[0]); got != "Add tests for the parser" {
+		t.Errorf("expected the second objective moved up, got %q", got)
+	}
+
+	// The queue is saved per workspace
+	cwd, _ := os.Getwd()
+	saved, err := objective.Load(objective.PathFor(cwd))
+	if err != nil || len(saved.Objectives) != 2 || saved.Objectives[0].ID != 2 {
+		t.Errorf("expected the reordered queue saved, got %+v (%v)", saved, err)
+	}
+
+	// Plans are queued from the Plan tab's leader key
+	model.planPath = "/proj/.claude/plans/parser.md"
+	model.planContent = "1. Split the lexer"
+	tm, _ = model.handleLeaderKeyPlan("s")
+	model = tm.(Model)
+	last := model.objectives.Objectives[len(model.objectives.Objectives)-1]
+	if last.Title != "parser.md" || !strings.Contains(last.Prompt, "Split the lexer") {
+		t.Errorf("expected the plan queued, got %+v", last)
+	}
+}
+
+func TestRalphControls(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ralph.StateFile)
+	content := "---\nactive: true\niteration: 2\nmax_iterations: 3\ncompletion_promise: \"DONE\"\n---\n\nFix the build.\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	model := tm.(Model)
+	model.leftPaneMode = LeftPaneModeRalph
+	model.ralphState = &ralph.State{Active: true, Iteration: 2, MaxIterations: 3, Promise: "DONE", Path: path}
+
+	tm, _ = model.handleLeaderKeyRalph("+")
+	model = tm.(Model)
+	if model.ralphState.MaxIterations != 3+ralphMaxStep {
+		t.Errorf("expected max iterations raised, got %d", model.ralphState.MaxIterations)
+	}
+
+	// The promise is edited in place
+	tm, _ = model.handleLeaderKeyRalph("P")
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyCtrlU})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("BUILD GREEN")})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = tm.(Model)
+	if model.ralphInputActive || model.ralphState.Promise != "BUILD GREEN" {
+		t.Errorf("expected the promise saved, got %q", model.ralphState.Promise)
+	}
+
+	tm, _ = model.handleLeaderKeyRalph("p")
+	model = tm.(Model)
+	if !model.ralphState.Paused {
+		t.Error("expected the loop paused")
+	}
+
+	tm, _ = model.Update(ralphTimelineMsg{timeline: []ralphIteration{
+		{Number: 1, Edits: []ralphEdit{{FilePath: "/proj/a.go"}, {FilePath: "/proj/a.go"}}},
+		{Number: 2, Edits: []ralphEdit{{FilePath: "/proj/b.go"}}},
+	}})
+	model = tm.(Model)
+	view := model.renderRalphFull()
+	for _, want := range []string{"⏸ Paused", "BUILD GREEN", "Iteration 2 (current)", "Iteration 1", "×2"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in the Ralph view, got:\n%s", want, view)
+		}
+	}
+}
+
+func TestRalphHistory(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 140, Height: 40})
+	model := tm.(Model)
+	model.leftPaneMode = LeftPaneModeRalph
+
+	tm, _ = model.handleRalphKeys(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'H'}})
+	model = tm.(Model)
+	if !model.showRalphHistory {
+		t.Fatal("expected the loop history")
+	}
+
+	start := time.Now().Add(-time.Hour)
+	tm, _ = model.Update(ralphLoopsMsg{loops: []RalphLoop{
+		{ID: 2, Prompt: "Make the tests pass", Promise: "GREEN", Iterations: 4, MaxIterations: 10, Outcome: "promise_met", StartedAt: start, EndedAt: start.Add(12 * time.Minute), Edits: 9},
+		{ID: 1, Prompt: "Port the parser", Iterations: 20, MaxIterations: 20, Outcome: "max_iterations", StartedAt: start.Add(-time.Hour), EndedAt: start, Edits: 31},
+	}})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	tm, _ = tm.Update(ralphLoopEditsMsg{loopID: 1, timeline: []ralphIteration{
+		{Number: 20, Edits: []ralphEdit{{FilePath: "/proj/parser.go", CreatedAt: start}}},
+	}})
+
+	model = tm.(Model)
+	view := model.renderRightPane()
+	for _, want := range []string{"promise met", "4/10", "12m0s", "9 edits", "hit max iterations", "Port the parser", "Iteration 20"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("expected %q in the loop history, got:\n%s", want, view)
+		}
+	}
+}
+
+func TestPromptVariableForm(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	model := tm.(Model)
+	model.promptInjectMethod = prompt.InjectClipboard
+	model.promptFilteredList = []prompt.Prompt{{
+		Name:    "tests",
+		Content: "Write tests for {{package}} using {{framework}}",
+		Variables: []prompt.Variable{
+			{Name: "package"},
+			{Name: "framework", Default: "testify", Choices: []string{"stdlib", "testify"}},
+		},
+	}}
+	model.confirmSendPrompt()
+
+	if model.promptVarForm == nil || model.promptSendPending != nil {
+		t.Fatal("expected the variable form before confirmation")
+	}
+
+	// Submitting with an empty variable keeps the form open
+	tm, _ = model.Update(tea.KeyMsg{Type: tea.KeyTab})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if tm.(Model).promptVarForm == nil {
+		t.Fatal("expected the form to require every variable")
+	}
+
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("auth")})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRight})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	model = tm.(Model)
+	if model.promptVarForm != nil {
+		t.Fatal("expected the form to close")
+	}
+	if model.promptSendPending == nil {
+		t.Fatal("expected a pending send")
+	}
+	if got := model.promptSendPending.content; got != "Write tests for auth using stdlib" {
+		t.Errorf("content = %q", got)
+	}
+}
+
+func TestPromptVersionDiff(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("---\nname: review\n---\n\n"+body), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	current := write("review.prompt.md", "Check errors\nCheck tests\nCheck docs\n")
+	v1 := write("review.v1.prompt.md", "Check errors\n")
+	v2 := write("review.v2.prompt.md", "Check errors\nCheck tests\n")
+
+	m := New("/tmp/test.sock")
+	m.promptFilteredList = []prompt.Prompt{{Name: "review", Path: current}}
+	m.promptVersions = []prompt.PromptVersion{{Version: 1, Path: v1}, {Version: 2, Path: v2}}
+	m.promptShowVersions = true
+	m.promptVersionDiff = true
+
+	got := m.renderPromptPreview()
+	if !strings.Contains(got, "v1 → current") || !strings.Contains(got, "Check docs") {
+		t.Errorf("expected diff from v1 to current, got %q", got)
+	}
+
+	// Marking v1 as the base diffs it against the selected v2
+	m.togglePromptVersionBase()
+	m.promptVersionSelected = 1
+	got = m.renderPromptPreview()
+	if !strings.Contains(got, "v1 → v2") || strings.Contains(got, "Check docs") {
+		t.Errorf("expected diff from v1 to v2, got %q", got)
+	}
+}
+
+func TestPromptTagsAndSort(t *testing.T) {
+	m := New("/tmp/test.sock")
+	m.promptList = []prompt.Prompt{
+		{Name: "beta", Tags: []string{"go", "test"}},
+		{Name: "alpha", Tags: []string{"go"}},
+		{Name: "gamma"},
+	}
+	m.promptStats = map[string]PromptStat{"gamma": {Uses: 2}, "alpha": {Uses: 1}}
+	m.applyPromptFilter()
+
+	names := func() string {
+		var names []string
+		for _, p := range m.promptFilteredList {
+			names = append(names, p.Name)
+		}
+		return strings.Join(names, ",")
+	}
+
+	if got := strings.Join(m.promptTags(), ","); got != "go,test" {
+		t.Errorf("tags = %q", got)
+	}
+
+	m.openPromptTagPicker()
+	m.handlePromptTagPickerKey(" ") // go
+	if got := names(); got != "beta,alpha" {
+		t.Errorf("tag go: got %q", got)
+	}
+	m.handlePromptTagPickerKey("j")
+	m.handlePromptTagPickerKey(" ") // go and test
+	if got := names(); got != "beta" {
+		t.Errorf("tags go+test: got %q", got)
+	}
+	m.handlePromptTagPickerKey("c")
+	m.handlePromptTagPickerKey("enter")
+	if m.showPromptTagPicker || names() != "beta,alpha,gamma" {
+		t.Errorf("expected cleared filter and closed picker, got %q", names())
+	}
+
+	m.promptSort = PromptSortName
+	m.applyPromptFilter()
+	if got := names(); got != "alpha,beta,gamma" {
+		t.Errorf("sort by name: got %q", got)
+	}
+	m.promptSort = PromptSortUsage
+	m.applyPromptFilter()
+	if got := names(); got != "gamma,alpha,beta" {
+		t.Errorf("sort by usage: got %q", got)
+	}
+	if m.promptList[0].Name != "beta" {
+		t.Error("sorting must not reorder the unfiltered list")
+	}
+}
+
+func TestLastUserPrompt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "transcript.jsonl")
+	transcript := `{"type":"user","timestamp":"2025-01-01T10:00:00Z","message":{"role":"user","content":"Add a retry loop to the client\nwith backoff"}}
+{"type":"assistant","timestamp":"2025-01-01T10:00:05Z","message":{"role":"assistant","content":[{"type":"text","text":"Sure"}]}}
+{"type":"user","timestamp":"2025-01-01T10:00:06Z","message":{"role":"user","content":[{"type":"tool_result","content":"ok"}]}}
+{"type":"user","timestamp":"2025-01-01T10:05:00Z","message":{"role":"user","content":[{"type":"text","text":"Now write tests"}]}}
+`
+	if err := os.WriteFile(path, []byte(transcript), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	edit := time.Date(2025, 1, 1, 10, 1, 0, 0, time.UTC)
+	if got := lastUserPrompt(path, edit); got != "Add a retry loop to the client\nwith backoff" {
+		t.Errorf("expected first prompt, got %q", got)
+	}
+	if got := lastUserPrompt(path, time.Time{}); got != "Now write tests" {
+		t.Errorf("expected latest prompt, got %q", got)
+	}
+	if got := commitSubject("Add a retry loop to the client\nwith backoff"); got != "Add a retry loop to the client" {
+		t.Errorf("expected first line as subject, got %q", got)
+	}
+	if got := commitSubject(strings.Repeat("x", 100)); len([]rune(got)) != commitSubjectLimit {
+		t.Errorf("expected subject capped at %d runes, got %d", commitSubjectLimit, len([]rune(got)))
+	}
+}
+
+func TestPromptInjectionPreview(t *testing.T) {
+	m := New("/tmp/test.sock")
+	cwd, _ := os.Getwd()
+
+	if got := m.renderPromptInjections("review"); !strings.Contains(got, "Never sent") {
+		t.Errorf("expected never-sent note, got %q", got)
+	}
+
+	var tm tea.Model = m
+	tm, _ = tm.Update(promptInjectionsMsg{injections: []PromptInjection{
+		{PromptName: "review", PromptVersion: 3, WorkspacePath: cwd, Method: "tmux", Target: "main:1.0", Timestamp: time.Now()},
+		{PromptName: "other", PromptVersion: 1, WorkspacePath: cwd, Method: "clipboard", Timestamp: time.Now()},
+		{PromptName: "review", PromptVersion: 2, WorkspacePath: "/src/api", Method: "clipboard", Timestamp: time.Now().Add(-48 * time.Hour)},
+	}})
+	m = tm.(Model)
+
+	if got := m.injectionsOf("review"); len(got) != 2 || got[0].PromptVersion != 3 {
+		t.Fatalf("expected 2 review injections newest first, got %+v", got)
+	}
+
+	got := m.renderPromptInjections("review")
+	for _, want := range []string{"Sent 2 time(s)", "main:1.0", "v3", "api", "this session"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in preview, got %q", want, got)
+		}
+	}
+	if strings.Count(got, "this session") != 1 {
+		t.Errorf("expected only the current-workspace send marked, got %q", got)
+	}
+
+	// All-time counts from the daemon outrank the recent injection list
+	tm, _ = m.Update(promptStatsMsg{stats: []PromptStat{{PromptName: "review", Uses: 40, LastUsed: time.Now()}}})
+	m = tm.(Model)
+	if got := m.renderPromptInjections("review"); !strings.Contains(got, "Sent 40 time(s)") || !strings.Contains(got, "38 more") {
+		t.Errorf("expected all-time count in preview, got %q", got)
+	}
+}
+
+func TestHookHealthPanel(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	// Leader H opens the panel and starts a check
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyCtrlG})
+	tm, cmd := tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'H'}})
+	if !tm.(Model).showHookHealth || cmd == nil {
+		t.Fatal("expected the hook health panel to open with a check running")
+	}
+	if !strings.Contains(tm.View(), "Checking") {
+		t.Error("expected a placeholder while checking")
+	}
+
+	// The first broken stage is called out
+	tm, _ = tm.Update(hookHealthMsg{report: hookcheck.Report{Checks: []hookcheck.Check{
+		{Stage: hookcheck.StageInstalled, Detail: "1 PostToolUse hook(s)"},
+		{Stage: hookcheck.StageExecutable, Status: hookcheck.StatusFail, Detail: "/x/hook.sh is not executable"},
+	}}})
+	view := tm.View()
+	if !strings.Contains(view, "not executable") || !strings.Contains(view, "start here") {
+		t.Errorf("expected the broken stage to be highlighted, got %q", view)
+	}
+
+	// Any key but r closes it
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if tm.(Model).showHookHealth {
+		t.Error("expected esc to close the panel")
+	}
+}
+
+func TestLogConsole(t *testing.T) {
+	if err := logger.Setup(logger.Options{Level: "debug", Component: "tui", Buffer: 50}); err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+	logger.Log("Unparsed payload: %s", "{bad")
+	logger.For("chat").Error("PTY exited", "status", 1)
+
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+
+	// Leader D opens the console and starts following
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyCtrlG})
+	tm, cmd := tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'D'}})
+	if !tm.(Model).showLogConsole || cmd == nil {
+		t.Fatal("expected the debug console to open and tick")
+	}
+	view := tm.View()
+	if !strings.Contains(view, "tui: Unparsed payload: {bad") || !strings.Contains(view, "chat: PTY exited status=1") {
+		t.Errorf("expected buffered entries, got %q", view)
+	}
+	if strings.Contains(view, "Key received") {
+		t.Error("expected debug entries hidden at the default level")
+	}
+
+	// l raises the level to warn
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'l'}})
+	view = tm.View()
+	if strings.Contains(view, "Unparsed payload") || !strings.Contains(view, "PTY exited") {
+		t.Errorf("expected only warnings and errors, got %q", view)
+	}
+
+	// / searches; esc drops the search, then closes the console
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("socket")})
+	if view := tm.View(); !strings.Contains(view, "No log entries match") {
+		t.Errorf("expected the search to filter everything out, got %q", view)
+	}
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if got := tm.(Model); got.logConsoleSearch != "" || !got.showLogConsole {
+		t.Error("expected esc to drop the search and keep the console open")
+	}
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if tm.(Model).showLogConsole {
+		t.Error("expected esc to close the console")
+	}
+}
+
+func TestWarningSnooze(t *testing.T) {
+	m := New("/tmp/test.sock", WithVersion("v0.2.0"))
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+
+	model := tm.(Model)
+	warnings, err := config.LoadWarnings(filepath.Join(t.TempDir(), "warnings.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.warnings = warnings
+	model.contextCurrent.Updated = ""
+
+	// An outdated daemon is flagged in the status bar
+	tm, _ = model.Update(daemonStatusMsg{connected: true, version: "v0.1.0"})
+	if status := tm.(Model).renderStatus(); !strings.Contains(status, "restart it") {
+		t.Errorf("expected an outdated daemon warning, got %q", status)
+	}
+
+	// Snoozing hides it for the day
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyCtrlG})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'z'}})
+	if status := tm.(Model).renderStatus(); strings.Contains(status, "restart it") {
+		t.Errorf("expected the warning snoozed, got %q", status)
+	}
+
+	// Other warning types still show, and can be turned off for good
+	tm, _ = tm.Update(daemonStatusMsg{connected: false})
+	if status := tm.(Model).renderStatus(); !strings.Contains(status, "Daemon not running") {
+		t.Errorf("expected a disconnected warning, got %q", status)
+	}
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyCtrlG})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'Z'}})
+	if !warnings.Dismissed[warnDaemonDisconnected] {
+		t.Error("expected the disconnected warning dismissed")
+	}
+}
+
+func TestThemePicker(t *testing.T) {
+	m := New("/tmp/test.sock", WithTheme(theme.Get("dark")))
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 160, Height: 40})
+
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyCtrlG})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'T'}})
+	if !tm.(Model).showThemePicker {
+		t.Fatal("expected leader T to open the theme picker")
+	}
+
+	// Moving previews the next theme, including the highlighter
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	model := tm.(Model)
+	if model.theme.Name != "light" || model.highlighter.Theme() != model.theme {
+		t.Errorf("expected the light theme previewed, got %q", model.theme.Name)
+	}
+
+	// Esc goes back to the original theme
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if model := tm.(Model); model.showThemePicker || model.theme.Name != "dark" {
+		t.Errorf("expected cancel to restore dark, got %q", model.theme.Name)
+	}
+
+	// Enter keeps the selection
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyCtrlG})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'T'}})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'k'}})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if model := tm.(Model); model.showThemePicker || model.theme.Name != "catppuccin" {
+		t.Errorf("expected catppuccin kept, got %q", model.theme.Name)
+	}
+}
+
+func TestPlanChecklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "parser.md")
+	content := "# Plan: Parser\n\n## Phase 1\n- [ ] Write the lexer\n- [x] Sketch the grammar\n- [ ] Add tests\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := New("/tmp/test.sock")
+	m.planPath = path
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'4'}})
+
+	model := tm.(Model)
+	if len(model.planTasks) != 3 {
+		t.Fatalf("expected 3 tasks, got %+v", model.planTasks)
+	}
+	if view := model.View(); !strings.Contains(view, "1/3 (33%)") || !strings.Contains(view, "Write the lexer") {
+		t.Errorf("expected the checklist with its progress, got:\n%s", view)
+	}
+
+	// An edit made before the task is checked off is credited to it
+	model.planTrackedAt = time.Now().Add(-time.Minute)
+	model.changes = []Change{{Timestamp: time.Now().Add(-30 * time.Second), FilePath: "/proj/lexer.go", ToolName: "Write"}}
+	tm, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'x'}})
+	model = tm.(Model)
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "- [x] Write the lexer") {
+		t.Errorf("expected the task checked in the plan, got:\n%s", data)
+	}
+	if done := model.planTasks[0].Done; !done {
+		t.Error("expected the checklist reloaded")
+	}
+	if edits := model.planTaskEdits(model.planTasks[0]); len(edits) != 1 || edits[0].FilePath != "/proj/lexer.go" {
+		t.Errorf("expected the edit credited to the task, got %+v", edits)
+	}
+	if edits := model.planTaskEdits(model.planTasks[1]); edits != nil {
+		t.Errorf("expected no edits for a task done before tracking, got %+v", edits)
+	}
+}
+
+func TestPlanList(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	plansDir := filepath.Join(home, ".claude", "plans")
+	os.MkdirAll(plansDir, 0755)
+	auth, search := filepath.Join(plansDir, "auth-rewrite.md"), filepath.Join(plansDir, "search-index.md")
+	os.WriteFile(auth, []byte("# Auth\n- [ ] Rotate keys\n"), 0644)
+	os.WriteFile(search, []byte("# Search\n- [ ] Build the index\n"), 0644)
+
+	m := New("/tmp/test.sock")
+	m.planPath = auth
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'4'}})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'P'}})
+
+	// Typing filters the list fuzzily
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("sdx")})
+	model := tm.(Model)
+	if !model.showPlanList || len(model.planListMatches) != 1 || model.planList[model.planListMatches[0]].Path != search {
+		t.Fatalf("expected only the search plan to match, got %+v", model.planListMatches)
+	}
+	tm, _ = model.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("rch")})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+	model = tm.(Model)
+	if model.showPlanList || model.planPath != search || model.planPinned != search {
+		t.Fatalf("expected the search plan pinned, got %q (pinned %q)", model.planPath, model.planPinned)
+	}
+	if len(model.planTasks) != 1 || model.planTasks[0].Text != "Build the index" {
+		t.Errorf("expected the search plan's tasks, got %+v", model.planTasks)
+	}
+
+	// A plan the hook reports for other work doesn't replace the pinned one
+	tm, _ = model.Update(SocketMsg{Payload: []byte(`{"plan_path":"` + auth + `"}`)})
+	if model = tm.(Model); model.planPath != search {
+		t.Errorf("expected the pinned plan kept, got %q", model.planPath)
+	}
+
+	// Archiving the current plan unpins it and hides it from the list
+	tm, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'P'}})
+	model = tm.(Model)
+	for i, idx := range model.planListMatches {
+		if model.planList[idx].Path == search {
+			model.planListIndex = i
+		}
+	}
+	tm, _ = model.Update(tea.KeyMsg{Type: tea.KeyCtrlX})
+	model = tm.(Model)
+	if model.planPinned != "" || len(model.planList) != 1 {
+		t.Errorf("expected the plan archived and unpinned, got %q and %+v", model.planPinned, model.planList)
+	}
+	if _, err := os.Stat(filepath.Join(plansDir, "archive", "search-index.md")); err != nil {
+		t.Errorf("expected the plan in the archive: %v", err)
+	}
+}
+
+func TestRunPlan(t *testing.T) {
+	m := New("/tmp/test.sock")
+	m.width, m.height = 120, 40
+	m.planPath = "/proj/.claude/plans/parser.md"
+	m.planContent = "---\nname: parser\nvariables:\n  - name: lang\n    choices: [go, rust]\n---\n\n# Parser in {{lang}} for {{project}}\n- [ ] Split the lexer\n"
+
+	// Plan variables are asked for before it runs
+	cmd := m.runPlan(promptVarsRalph)
+	if cmd != nil || m.promptVarForm == nil || m.promptVarForm.action != promptVarsRalph {
+		t.Fatalf("expected the variable form first, got %+v", m.promptVarForm)
+	}
+	p := m.promptVarForm.prompt
+	if p.Name != "parser.md" || !strings.HasPrefix(p.Content, "Implement this plan:\n\n# Parser in {{lang}}") ||
+		!strings.Contains(p.Content, "<promise>"+ralphPlanPromise+"</promise>") {
+		t.Errorf("unexpected plan prompt %+v", p)
+	}
+
+	// A run that Claude exits from early stops the loop
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	state, err := ralph.Start("Implement this plan", ralphPlanMaxIterations, ralphPlanPromise)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.ralphState = state
+	m.ralphRunTitle = "parser.md"
+	m.finishRalphRun(chat.New())
+	if s, _ := ralph.LoadState(); s != nil {
+		t.Errorf("expected the loop stopped, got %+v", s)
+	}
+	if m.ralphRun != nil || !strings.Contains(m.renderRalphRun(), "parser.md, finished") {
+		t.Errorf("expected the finished run shown, got %q", m.renderRalphRun())
+	}
+}
+
+func TestContextProfiles(t *testing.T) {
+	oldContextsDir := workingctx.ContextsDir
+	workingctx.ContextsDir = t.TempDir()
+	defer func() { workingctx.ContextsDir = oldContextsDir }()
+
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'5'}})
+	tm.(Model).contextCurrent.SetKubernetes("staging", "api", "")
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'p'}})
+
+	// Naming a profile and saving it from the switcher makes it active
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("staging")})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	model := tm.(Model)
+	if !model.showContextProfiles || len(model.contextProfiles) != 1 || model.contextCurrent.Profile != "staging" {
+		t.Fatalf("expected the staging profile saved and active, got %+v (active %q)", model.contextProfiles, model.contextCurrent.Profile)
+	}
+	if view := model.View(); !strings.Contains(view, "Context Profiles") || !strings.Contains(view, "k8s staging / api") {
+		t.Error("expected the switcher to list the profile")
+	}
+
+	model.contextCurrent.SetKubernetes("prod", "api", "")
+	if _, err := workingctx.SaveProfile("prod", model.contextCurrent); err != nil {
+		t.Fatal(err)
+	}
+	model.refreshContextProfiles()
+
+	// Switching back to staging replaces the cluster and is injected
+	tm, _ = model.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("stag")})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	model = tm.(Model)
+	if model.showContextProfiles || model.contextCurrent.Profile != "staging" {
+		t.Fatalf("expected staging active, got %q", model.contextCurrent.Profile)
+	}
+	if k8s := model.contextCurrent.GetKubernetes(); k8s == nil || k8s.Context != "staging" {
+		t.Errorf("expected the staging cluster, got %+v", k8s)
+	}
+	if block := model.contextCurrent.FormatForInjection(); !strings.Contains(block, "Profile: staging") {
+		t.Errorf("expected the profile in the injected context:\n%s", block)
+	}
+}
+
+func TestContextDetect(t *testing.T) {
+	oldContextsDir := workingctx.ContextsDir
+	workingctx.ContextsDir = t.TempDir()
+	defer func() { workingctx.ContextsDir = oldContextsDir }()
+
+	m := New("/tmp/test.sock")
+	m.contextCurrent.Context = make(map[string]interface{})
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	detected := &workingctx.Detected{
+		Kubernetes: &workingctx.KubernetesContext{Context: "orbstack", Namespace: "monitoring"},
+		AWS:        &workingctx.AWSContext{Profile: "dev", Region: "us-west-2"},
+	}
+
+	// Detected values are shown for confirmation and discarded on esc
+	tm, _ = tm.Update(contextDetectedMsg{detected: detected})
+	if view := tm.(Model).View(); !strings.Contains(view, "Detected Context") || !strings.Contains(view, "orbstack / monitoring") {
+		t.Fatal("expected the detected context shown for confirmation")
+	}
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if model := tm.(Model); model.contextDetected != nil || model.contextCurrent.GetKubernetes() != nil {
+		t.Fatal("expected the detected context discarded")
+	}
+
+	// Confirming saves it
+	tm, _ = tm.Update(contextDetectedMsg{detected: detected})
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'y'}})
+	loaded, err := workingctx.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aws := loaded.GetAWS(); aws == nil || aws.Profile != "dev" || aws.Region != "us-west-2" {
+		t.Errorf("expected the detected AWS profile saved, got %+v", aws)
+	}
+}
+
+func TestContextStaleFields(t *testing.T) {
+	m := New("/tmp/test.sock")
+	m.width, m.height = 120, 40
+	m.leftPaneMode = LeftPaneModeContext
+	m.contextCurrent.Context = make(map[string]interface{})
+	m.contextCurrent.SetKubernetes("prod", "api", "")
+	m.contextCurrent.SetAWS("dev", "")
+	m.contextCurrent.Stale = map[string]string{"kubernetes": "kubectl is on orbstack"}
+	m.contextCurrent.Revalidated = time.Now().UTC().Format(time.RFC3339)
+
+	view := m.renderContextList()
+	if !strings.Contains(view, "⚠ kubectl is on orbstack") {
+		t.Errorf("expected the kube context flagged:\n%s", view)
+	}
+	if strings.Count(view, "⚠ ") != 1 {
+		t.Errorf("expected only the kube context flagged:\n%s", view)
+	}
+	if !strings.Contains(view, "Context has 1 stale field") {
+		t.Errorf("expected the stale summary:\n%s", view)
+	}
+}
+
+func TestContextSecretEdit(t *testing.T) {
+	oldContextsDir := workingctx.ContextsDir
+	workingctx.ContextsDir = t.TempDir()
+	defer func() { workingctx.ContextsDir = oldContextsDir }()
+
+	m := New("/tmp/test.sock")
+	m.width, m.height = 120, 40
+	m.leftPaneMode = LeftPaneModeContext
+	m.contextCurrent.Context = make(map[string]interface{})
+	m.contextEditMode = true
+	m.contextEditField = "env"
+	m.envInput.SetValue("API_TOKEN=hunter2")
+
+	// ctrl+s marks the value secret
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyCtrlS})
+	m = tm.(Model)
+	if m.contextEditSecret == nil || m.contextEditSecret.Inject {
+		t.Fatalf("expected the value marked secret, got %+v", m.contextEditSecret)
+	}
+	if view := m.renderContextEditPopup(); !strings.Contains(view, "masked and not sent to Claude") {
+		t.Errorf("expected the secret state shown:\n%s", view)
+	}
+
+	m.saveContextEdit()
+	if !m.contextCurrent.IsSecret("env", "API_TOKEN") {
+		t.Fatal("expected the key flagged secret")
+	}
+	if view := m.renderContextList(); strings.Contains(view, "hunter2") {
+		t.Errorf("expected the secret masked:\n%s", view)
+	}
+}
+
+func TestContextIaCEdit(t *testing.T) {
+	oldContextsDir := workingctx.ContextsDir
+	workingctx.ContextsDir = t.TempDir()
+	defer func() { workingctx.ContextsDir = oldContextsDir }()
+
+	m := New("/tmp/test.sock")
+	m.width, m.height = 120, 40
+	m.leftPaneMode = LeftPaneModeContext
+	m.contextCurrent.Context = make(map[string]interface{})
+
+	tm, _ := m.handleLeaderKeyContext("i")
+	m = tm.(Model)
+	if !m.contextEditMode || m.contextEditField != "iac" {
+		t.Fatal("expected the Terraform/Pulumi editor open")
+	}
+	m.iacWorkspaceInput.SetValue("prod")
+	m.iacToolInput.SetValue("pulumi")
+	m.saveContextEdit()
+
+	if iac := m.contextCurrent.GetIaC(); iac == nil || iac.Tool != "pulumi" || iac.Workspace != "prod" {
+		t.Fatalf("expected the pulumi stack saved, got %+v", iac)
+	}
+	if view := m.renderContextList(); !strings.Contains(view, "Pulumi: ") || !strings.Contains(view, "prod") {
+		t.Errorf("expected the stack shown:\n%s", view)
+	}
+}
+
+func TestContextCompletionSources(t *testing.T) {
+	oldCachePath := completionCachePath
+	completionCachePath = filepath.Join(t.TempDir(), "completions.json")
+	defer func() { completionCachePath = oldCachePath }()
+
+	// Keyed sources are served from the cache until the TTL runs out
+	loads := 0
+	source := completionSource{
+		name:    "kubectl",
+		key:     "k8s-namespaces:config:prod",
+		timeout: time.Second,
+		load: func(gocontext.Context) ([]string, error) {
+			loads++
+			return []string{"api", "web"}, nil
+		},
+	}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	clock := ClockFunc(func() time.Time { return now })
+	for i := 0; i < 2; i++ {
+		if got, err := source.run(clock, osFS{}); err != nil || strings.Join(got, ",") != "api,web" {
+			t.Fatalf("expected the namespaces, got %v (%v)", got, err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("expected the second run served from the cache, loaded %d times", loads)
+	}
+	now = now.Add(completionCacheTTL + time.Minute)
+	if _, err := source.run(clock, osFS{}); err != nil || loads != 2 {
+		t.Errorf("expected an expired entry loaded again, loaded %d times (%v)", loads, err)
+	}
+
+	// A slow source gives up with its fallback
+	slow := completionSource{
+		name:     "kubectl",
+		timeout:  10 * time.Millisecond,
+		fallback: []string{"default"},
+		load: func(ctx gocontext.Context) ([]string, error) {
+			<-ctx.Done()
+			time.Sleep(50 * time.Millisecond)
+			return nil, ctx.Err()
+		},
+	}
+	got, err := slow.run(clock, osFS{})
+	if err == nil || strings.Join(got, ",") != "default" {
+		t.Errorf("expected the fallback after a timeout, got %v (%v)", got, err)
+	}
+}
+
+// fakeRunner answers commands with canned output, recording them
+type fakeRunner struct {
+	output map[string]string // Keyed by command name
+	ran    []string
+}
+
+func (r *fakeRunner) Output(_ gocontext.Context, dir, name string, args ...string) ([]byte, error) {
+	r.ran = append(r.ran, strings.Join(append([]string{dir, name}, args...), " "))
+	out, ok := r.output[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: not found", name)
+	}
+	return []byte(out), nil
+}
+
+func (r *fakeRunner) Command(name string, args ...string) *exec.Cmd {
+	r.ran = append(r.ran, strings.Join(append([]string{name}, args...), " "))
+	return exec.Command("true")
+}
+
+func TestCompletionsUseRunner(t *testing.T) {
+	runner := &fakeRunner{output: map[string]string{
+		"kubectl": "default kube-system api",
+		"git":     "git@github.com:acme/api.git",
+	}}
+	m := New("/tmp/test.sock", WithRunner(runner), WithFS(workspaceFS{dir: "/proj"}))
+	m.contextEditField = "k8s"
+	m.k8sFocusedField = 2
+	m.k8sKubeconfigInput.SetValue("/kube/config")
+	m.k8sContextInput.SetValue("prod")
+
+	got, err := m.contextCompletionSource().load(gocontext.Background())
+	if err != nil || strings.Join(got, ",") != "default,kube-system,api" {
+		t.Fatalf("expected the namespaces kubectl listed, got %v (%v)", got, err)
+	}
+	want := " kubectl --context prod --kubeconfig /kube/config get namespaces -o jsonpath={.items[*].metadata.name}"
+	if len(runner.ran) != 1 || runner.ran[0] != want {
+		t.Errorf("expected %q, ran %q", want, runner.ran)
+	}
+
+	m.contextEditField = "git"
+	m.gitFocusedField = 1
+	if got, _ := m.contextCompletionSource().load(gocontext.Background()); len(got) == 0 || got[0] != "git@github.com:acme/api.git" {
+		t.Errorf("expected the origin remote, got %v", got)
+	}
+	if !strings.HasPrefix(runner.ran[1], "/proj git remote") {
+		t.Errorf("expected git run in the workspace, ran %q", runner.ran[1])
+	}
+
+	// Branches and IaC workspaces go through the runner too
+	runner.ran = nil
+	m.gitFocusedField = 0
+	m.contextCompletionSource().load(gocontext.Background())
+	if len(runner.ran) < 2 || runner.ran[0] != "/proj jj root" || runner.ran[1] != "/proj git rev-parse --show-toplevel" {
+		t.Errorf("expected the VCS detected through the runner, ran %q", runner.ran)
+	}
+	runner.output["terraform"] = "  default\n* prod\n"
+	m.contextEditField = "iac"
+	m.iacFocusedField = 0
+	m.iacToolInput.SetValue("terraform")
+	if got, err := m.contextCompletionSource().load(gocontext.Background()); err != nil || strings.Join(got, ",") != "default,prod" {
+		t.Errorf("expected the workspaces terraform listed, got %v (%v)", got, err)
+	}
+}
+
+func TestParsePayloadUsesFS(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	fsys := mapFS{workspaceFS: workspaceFS{dir: "/proj"}, files: fstest.MapFS{
+		"proj/main.go": {Data: []byte("package main\n\nfunc main() {\n\tprintln(\"hello\")\n}\n")},
+	}}
+	m := New("/tmp/test.sock", WithClock(ClockFunc(func() time.Time { return now })), WithFS(fsys))
+
+	change := m.parsePayload([]byte(`{"tool_name":"Edit","tool_input":{"file_path":"/proj/main.go","old_string":"println(\"hello\")","new_string":"println(\"hi\")"}}`))
+	if change == nil {
+		t.Fatal("expected a change")
+	}
+	if !change.Timestamp.Equal(now) {
+		t.Errorf("expected the change stamped by the clock, got %v", change.Timestamp)
+	}
+	if change.LineNum != 4 || !strings.Contains(change.FileContent, "package main") {
+		t.Errorf("expected the file read from the FS at line 4, got line %d of %q", change.LineNum, change.FileContent)
+	}
+}
+
+// mapFS serves files from memory, by absolute path
+type mapFS struct {
+	workspaceFS
+	files fstest.MapFS
+}
+
+func (f mapFS) ReadFile(name string) ([]byte, error) {
+	return f.files.ReadFile(strings.TrimPrefix(name, "/"))
+}
+
+func (f mapFS) UserHomeDir() (string, error) { return "/home", nil }
+
+func (f mapFS) Stat(name string) (fs.FileInfo, error) {
+	return f.files.Stat(strings.TrimPrefix(name, "/"))
+}
+
+func (f mapFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return f.files.ReadDir(strings.TrimPrefix(name, "/"))
+}
+
+func (f mapFS) MkdirAll(string, fs.FileMode) error { return nil }
+
+func (f mapFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	f.files[strings.TrimPrefix(name, "/")] = &fstest.MapFile{Data: data, Mode: perm}
+	return nil
+}
+
+func (f mapFS) Remove(name string) error {
+	if _, ok := f.files[strings.TrimPrefix(name, "/")]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(f.files, strings.TrimPrefix(name, "/"))
+	return nil
+}
+
+func TestFileCompletionsUseFS(t *testing.T) {
+	fsys := mapFS{workspaceFS: workspaceFS{dir: "/proj"}, files: fstest.MapFS{
+		"home/.kube/config":     {Data: []byte("contexts:\n  - name: prod\n  - name: staging\ncurrent-context: prod\n")},
+		"home/.kube/dev":        {Data: []byte("contexts:\n  - name: dev\n")},
+		"home/.kube/cache/x":    {Data: []byte("contexts:\n")},
+		"home/.kube/notes.txt":  {Data: []byte("not a kubeconfig\n")},
+		"home/.aws/config":      {Data: []byte("[default]\n[profile dev]\n")},
+		"home/.aws/credentials": {Data: []byte("[dev]\n[ci]\n")},
+		"home/.zsh_history":     {Data: []byte(": 1700000000:0;export AWS_PROFILE=dev\nls\n")},
+	}}
+	m := New("/tmp/test.sock", WithFS(fsys), WithRunner(&fakeRunner{}))
+	load := func(field string, focus int) []string {
+		t.Helper()
+		m.contextEditField = field
+		m.k8sFocusedField, m.awsFocusedField = focus, focus
+		got, err := m.contextCompletionSource().load(gocontext.Background())
+		if err != nil {
+			t.Fatalf("%s: %v", field, err)
+		}
+		return got
+	}
+
+	if got := strings.Join(load("k8s", 0), ","); got != "/home/.kube/config,/home/.kube/dev" {
+		t.Errorf("expected the kubeconfigs in the FS's home, got %s", got)
+	}
+	if got := strings.Join(load("k8s", 1), ","); got != "prod,staging" {
+		t.Errorf("expected the contexts of the default kubeconfig, got %s", got)
+	}
+	if got := strings.Join(load("aws", 0), ","); got != "default,dev,ci" {
+		t.Errorf("expected the profiles from config and credentials, got %s", got)
+	}
+	if got := strings.Join(load("env", 0), ","); got != "AWS_PROFILE=dev" {
+		t.Errorf("expected the exports from shell history, got %s", got)
+	}
+}
+
+func TestContextCompletionAsync(t *testing.T) {
+	m := New("/tmp/test.sock")
+	m.width, m.height = 120, 40
+	m.contextEditMode = true
+	m.contextEditField = "aws"
+	m.awsFocusedField = 1
+
+	if cmd := m.openContextCompletion(); cmd == nil {
+		t.Fatal("expected the candidates loaded in the background")
+	}
+	if !m.contextCompletionLoading || !strings.Contains(m.renderContextEditPopup(), "Loading...") {
+		t.Fatal("expected a loading spinner while candidates load")
+	}
+
+	// Results for an earlier overlay are dropped
+	m.applyContextCompletions(contextCompletionsMsg{seq: m.contextCompletionSeq - 1, candidates: []string{"stale"}})
+	if !m.contextCompletionLoading {
+		t.Fatal("expected a superseded result dropped")
+	}
+
+	candidates, err := m.contextCompletionSource().run(m.clock, m.fs)
+	m.applyContextCompletions(contextCompletionsMsg{seq: m.contextCompletionSeq, candidates: candidates, err: err})
+	if m.contextCompletionLoading || len(m.contextCompletionMatches) != len(loadAWSRegions()) {
+		t.Errorf("expected the regions shown, got %d matches", len(m.contextCompletionMatches))
+	}
+}
+
+// screenPos returns the column and row where text is rendered in the view
+func screenPos(t *testing.T, view, text string) (x, y int) {
+	t.Helper()
+	for y, line := range strings.Split(ansi.Strip(view), "\n") {
+		if i := strings.Index(line, text); i >= 0 {
+			return ansi.StringWidth(line[:i]), y
+		}
+	}
+	t.Fatalf("%q not rendered:\n%s", text, ansi.Strip(view))
+	return 0, 0
+}
+
+func click(m Model, x, y int) Model {
+	return updateModel(m, tea.MouseMsg{X: x, Y: y, Action: tea.MouseActionPress, Button: tea.MouseButtonLeft},
+		tea.MouseMsg{X: x, Y: y, Action: tea.MouseActionRelease})
+}
+
+func TestMouseClicks(t *testing.T) {
+	m := newGoldenModel(t, 100, 30)
+	m.changes = []Change{
+		{Timestamp: goldenNow.Add(-time.Minute), FilePath: "/proj/main.go", ToolName: "Edit", NewString: "b", FileContent: "b\n"},
+		{Timestamp: goldenNow.Add(-2 * time.Minute), FilePath: "/proj/server.go", ToolName: "Edit", NewString: "b", FileContent: "b\n"},
+	}
+	m.showDiff()
+
+	// Clicking a history row selects its change
+	x, y := screenPos(t, m.View(), "server.go")
+	if m = click(m, x, y); m.selectedIndex != 1 || m.activePane != PaneLeft {
+		t.Errorf("expected server.go selected, got index %d", m.selectedIndex)
+	}
+	_, y = screenPos(t, m.View(), "History (2)")
+	if m = click(m, x, y); m.selectedIndex != 1 {
+		t.Errorf("expected a click on the header to keep the selection, got index %d", m.selectedIndex)
+	}
+
+	// Clicking a tab switches to its mode
+	x, y = screenPos(t, m.View(), "2:")
+	if m = click(m, x+1, y); m.leftPaneMode != LeftPaneModePrompts {
+		t.Fatalf("expected the Prompts tab, got mode %d", m.leftPaneMode)
+	}
+
+	// Clicking a prompt selects it
+	m.promptFilteredList = []prompt.Prompt{{Name: "review", IsGlobal: true}, {Name: "tests", IsGlobal: true}}
+	x, y = screenPos(t, m.View(), "tests")
+	if m = click(m, x, y); m.promptSelected != 1 {
+		t.Errorf("expected the second prompt selected, got %d", m.promptSelected)
+	}
+
+	// Clicks don't reach the panes under the which-key popup
+	m = updateModel(m, keys("ctrl+g")...)
+	x, y = screenPos(t, m.View(), "review")
+	if m = click(m, x, y); m.promptSelected != 1 {
+		t.Errorf("expected the popup to take the click, got prompt %d", m.promptSelected)
+	}
+}
+
+func TestMouseMinimapScrub(t *testing.T) {
+	m := newGoldenModel(t, 100, 30)
+	content := strings.Repeat("x := 1\n", 1000) + "y := 2\n" + strings.Repeat("x := 1\n", 1000)
+	m.changes = []Change{{Timestamp: goldenNow, FilePath: "/proj/big.go", ToolName: "Edit", LineNum: 1001,
+		OldString: "y := 1", NewString: "y := 2", FileContent: content}}
+	m.showDiff()
+	leftWidth, rightWidth, _ := m.paneWidths()
+	minimapX := leftWidth + 2 + rightWidth + 2
+
+	// Pressing near the bottom of the minimap jumps there
+	m = updateModel(m, tea.MouseMsg{X: minimapX, Y: m.height - 4, Action: tea.MouseActionPress, Button: tea.MouseButtonLeft})
+	bottom := m.diffScrollTop()
+	if bottom < 1900 || !m.minimapDragging {
+		t.Fatalf("expected a jump near the end while dragging, got row %d", bottom)
+	}
+
+	// Dragging scrubs, even off the minimap, until the button is released
+	m = updateModel(m, tea.MouseMsg{X: 0, Y: 1, Action: tea.MouseActionMotion, Button: tea.MouseButtonLeft})
+	top := m.diffScrollTop()
+	if top > 100 {
+		t.Errorf("expected the drag to scrub to the top, got row %d", top)
+	}
+	m = updateModel(m, tea.MouseMsg{X: 0, Y: m.height - 4, Action: tea.MouseActionRelease})
+	m = updateModel(m, tea.MouseMsg{X: 0, Y: m.height - 4, Action: tea.MouseActionMotion})
+	if m.minimapDragging || m.diffScrollTop() != top {
+		t.Errorf("expected the release to end the drag, got row %d", m.diffScrollTop())
 	}
+}
 
-	for _, tt := range tests {
-		t.Run(tt.path, func(t *testing.T) {
-			got := truncatePath(tt.path, tt.maxLen)
-			if len(got) > tt.maxLen && tt.maxLen < len(tt.path) {
-				// Truncation should respect max length (approximately)
-				// The ".../" prefix adds 4 chars
-			}
-			// Just verify it doesn't panic and returns something
-			if got == "" {
-				t.Error("truncated path should not be empty")
-			}
-		})
+func TestParsePayloadMultiEdit(t *testing.T) {
+	fsys := mapFS{workspaceFS: workspaceFS{dir: "/proj"}, files: fstest.MapFS{
+		"proj/main.go": {Data: []byte("package main\n\nfunc x() {}\n\nfunc y() {\n\treturn\n}\n")},
+	}}
+	m := New("/tmp/test.sock", WithFS(fsys))
+
+	change := m.parsePayload([]byte(`{"tool_name":"MultiEdit","tool_input":{"file_path":"/proj/main.go","edits":[` +
+		`{"old_string":"func x() {}","new_string":"func a() {}"},` +
+		`{"old_string":"func y() {\n\treturn","new_string":"func b() {"},` +
+		`{"old_string":"gone","new_string":"not in the file"}]}}`))
+	if change == nil {
+		t.Fatal("expected a change")
+	}
+	if change.OldString != "func x() {}" || change.LineNum != 3 {
+		t.Errorf("expected the first edit shown at line 3, got %q at line %d", change.OldString, change.LineNum)
+	}
+	if len(change.Hunks) != 1 || change.Hunks[0] != (Hunk{LineNum: 5, LineCount: 2}) {
+		t.Errorf("expected the second edit as a hunk at line 5, got %v", change.Hunks)
 	}
 }
 
-func TestChangeTimestamp(t *testing.T) {
+func TestMinimapHistoryJump(t *testing.T) {
+	m := newGoldenModel(t, 100, 30)
+	content := strings.Repeat("x := 1\n", 300)
+	m.changes = []Change{
+		{Timestamp: goldenNow.Add(-3 * time.Hour), FilePath: "/proj/big.go", ToolName: "Edit", LineNum: 50,
+			OldString: "x := 0", NewString: "x := 1", FileContent: content},
+		{Timestamp: goldenNow.Add(-time.Hour), FilePath: "/proj/other.go", ToolName: "Edit", LineNum: 150,
+			OldString: "x := 0", NewString: "x := 1", FileContent: content},
+		{Timestamp: goldenNow, FilePath: "/proj/big.go", ToolName: "Edit", LineNum: 250,
+			OldString: "x := 0", NewString: "x := 1", FileContent: content,
+			Hunks: []Hunk{{LineNum: 200, LineCount: 2}}},
+	}
+	m.selectedIndex = 2
+	m.showDiff()
+
+	// The earlier edit to the same file is marked, the other file's isn't
+	regions := m.minimapData.Regions()
+	if len(regions) != 3 {
+		t.Fatalf("expected the earlier edit, the hunk and the change marked, got %v", regions)
+	}
+	if regions[0] != (minimap.Region{Start: 49, End: 50}) || regions[1].Start != 199 {
+		t.Errorf("expected the earlier edit at row 49 and the hunk at 199, got %v", regions)
+	}
+
+	header := len(m.diffDoc.header)
+	m = updateModel(m, keys("g")...)
+	if !m.minimapJumpPending {
+		t.Fatal("expected the jump key to wait for a region number")
+	}
+	m = updateModel(m, keys("2")...)
+	if m.minimapJumpPending || m.leftPaneMode != LeftPaneModeHistory {
+		t.Fatal("expected the digit to pick a region rather than switch tabs")
+	}
+	if got := m.diffScrollTop(); got != header+regions[1].Start-3 {
+		t.Errorf("expected the hunk's region at the top, got row %d", got)
+	}
+	m = updateModel(m, keys("g", "G")...)
+	if got := m.diffScrollTop(); got != header+regions[2].Start-3 {
+		t.Errorf("expected the last region at the top, got row %d", got)
+	}
+
+	// Clicking a marked row of the minimap jumps to its region
+	leftWidth, rightWidth, _ := m.paneWidths()
+	minimapX := leftWidth + 2 + rightWidth + 2
+	row := int(float64(regions[0].Start) / (float64(m.minimapData.TotalLines()) / float64(m.height-4)))
+	m = updateModel(m, tea.MouseMsg{X: minimapX, Y: paneTop + row, Action: tea.MouseActionPress, Button: tea.MouseButtonLeft})
+	if got := m.diffScrollTop(); got != header+regions[0].Start-3 {
+		t.Errorf("expected the click to jump to the earlier edit, got row %d", got)
+	}
+}
+
+func TestDiffContextLines(t *testing.T) {
+	m := newGoldenModel(t, 100, 30)
+	m.config.Diff.Context = map[string]int{"*.gen.go": 10, "go": -1}
+	var sb strings.Builder
+	for i := 1; i <= 300; i++ {
+		fmt.Fprintf(&sb, "x%d := 1\n", i)
+	}
+	m.changes = []Change{{Timestamp: goldenNow, FilePath: "/proj/api.gen.go", ToolName: "Edit", LineNum: 150,
+		OldString: "x150 := 0", NewString: "x150 := 1", FileContent: sb.String(),
+		Hunks: []Hunk{{LineNum: 155, LineCount: 1}, {LineNum: 200, LineCount: 1}}}}
+	m.showDiff()
+
+	// The generated file is cut to 10 lines around the change, numbered as
+	// in the file, with the hunk inside the window still on the minimap
+	f := m.diffDoc.file
+	if f.first != 139 || len(f.fileLines) != 21 || f.total != 300 {
+		t.Fatalf("expected lines 140-160 of 300, got %d lines from %d of %d", len(f.fileLines), f.first+1, f.total)
+	}
+	if header := strings.Join(m.diffDoc.header, "\n"); !strings.Contains(header, "lines 140-160 of 300 (z: whole file)") {
+		t.Errorf("expected the header to note the lines shown, got:\n%s", header)
+	}
+	if rows := strings.Join(m.diffRows(m.diffDoc, 0, m.diffDoc.Len()), "\n"); !strings.Contains(rows, " 140 ") || strings.Contains(rows, " 139 ") {
+		t.Errorf("expected rows numbered from line 140, got:\n%s", rows)
+	}
+	if regions := m.minimapData.Regions(); len(regions) != 2 || regions[1].Start != 16 {
+		t.Errorf("expected the change and the hunk at row 16 marked, got %v", regions)
+	}
+
+	// z shows the whole file, and again goes back to the context
+	m = updateModel(m, keys("z")...)
+	if f := m.diffDoc.file; f.trimmed() || f.first != 0 {
+		t.Errorf("expected z to show the whole file, got %d lines from %d", len(f.fileLines), f.first+1)
+	}
+	m = updateModel(m, keys("z")...)
+	if !m.diffDoc.file.trimmed() {
+		t.Error("expected z again to cut the file to its context")
+	}
+
+	// Other Go files follow the language's whole file setting
+	m.config.Diff.ContextLines = 3
+	m.changes[0].FilePath = "/proj/api.go"
+	m.diffCache = make(map[int]*diffDoc)
+	m.showDiff()
+	if m.diffDoc.file.trimmed() {
+		t.Error("expected go = -1 to show the whole file")
+	}
+}
+
+func TestRestoredState(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "state.json")
+	changes := []Change{
+		{Timestamp: goldenNow, FilePath: "/proj/a.go", ToolName: "Edit", LineNum: 1},
+		{Timestamp: goldenNow.Add(-time.Minute), FilePath: "/proj/b.go", ToolName: "Edit", LineNum: 2},
+		{Timestamp: goldenNow.Add(-time.Hour), FilePath: "/proj/c.go", ToolName: "Edit", LineNum: 3},
+	}
+
+	m := New("", WithFS(workspaceFS{dir: "/proj"}), WithRestoredState(path))
+	m = updateModel(m, tea.WindowSizeMsg{Width: 120, Height: 40})
+	m = updateModel(m, daemonHistoryMsg{changes: changes})
+	m = updateModel(m, keys("j", "m", ">", ">", "2", "/", "/")...)
+	if m.leftPanePercent != 43 || m.showMinimap || m.promptFilter != PromptFilterGlobal {
+		t.Fatalf("expected the layout changed, got %d%% minimap=%v filter=%v", m.leftPanePercent, m.showMinimap, m.promptFilter)
+	}
+	m.Close()
+
+	// Another workspace starts with the defaults
+	other := New("", WithFS(workspaceFS{dir: "/elsewhere"}), WithRestoredState(path))
+	if other.leftPaneMode != LeftPaneModeHistory || !other.showMinimap || other.leftPanePercent != 0 {
+		t.Errorf("expected defaults in another workspace, got mode %d minimap=%v %d%%", other.leftPaneMode, other.showMinimap, other.leftPanePercent)
+	}
+
+	restored := New("", WithFS(workspaceFS{dir: "/proj"}), WithRestoredState(path))
+	if restored.leftPaneMode != LeftPaneModePrompts || restored.showMinimap || restored.leftPanePercent != 43 ||
+		restored.promptFilter != PromptFilterGlobal {
+		t.Errorf("expected the layout restored, got mode %d minimap=%v %d%% filter=%v",
+			restored.leftPaneMode, restored.showMinimap, restored.leftPanePercent, restored.promptFilter)
+	}
+
+	// The selected change is selected again once history loads
+	restored = updateModel(restored, daemonHistoryMsg{changes: changes})
+	if restored.selectedIndex != 1 {
+		t.Errorf("expected the second change selected again, got %d", restored.selectedIndex)
+	}
+	if restored.restoredChange != "" {
+		t.Error("expected the restored change consumed")
+	}
+}
+
+func TestRestoredStateCorrupt(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := filepath.Join(t.TempDir(), "state.json")
+	for _, data := range []string{
+		"{not json",
+		`{"workspaces": {"/proj": {"tab": 3, "left_pane_percent": "wide"}}}`,
+		`{"workspaces": {"/proj": {"tab": "no-such-tab", "left_pane_percent": 500}}}`,
+	} {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		m := New("", WithFS(workspaceFS{dir: "/proj"}), WithRestoredState(path))
+		if m.leftPaneMode != LeftPaneModeHistory || !m.showMinimap || m.leftPanePercent > maxLeftPanePercent {
+			t.Errorf("%s: expected usable defaults, got mode %d minimap=%v %d%%", data, m.leftPaneMode, m.showMinimap, m.leftPanePercent)
+		}
+
+		// Quitting replaces the file with good state
+		m.Close()
+		state, err := config.LoadUIState(path)
+		if err != nil || state.Workspace("/proj").Tab != "history" {
+			t.Errorf("%s: expected the state saved over the bad file, got %+v, %v", data, state, err)
+		}
+	}
+}
+
+func TestResizeLeftPane(t *testing.T) {
+	m := newGoldenModel(t, 100, 30)
+	left, _, _ := m.paneWidths()
+	if left != 33 {
+		t.Fatalf("expected a third of the width by default, got %d", left)
+	}
+	m = updateModel(m, keys("<", "<", "<", "<")...)
+	if left, _, _ = m.paneWidths(); m.leftPanePercent != minLeftPanePercent || left != 25 {
+		t.Errorf("expected the pane shrunk to its minimum, got %d%% (%d columns)", m.leftPanePercent, left)
+	}
+	m = updateModel(m, keys(">", ">", ">", ">", ">", ">", ">", ">", ">")...)
+	if left, _, _ = m.paneWidths(); m.leftPanePercent != maxLeftPanePercent || left != 60 {
+		t.Errorf("expected the pane grown to its maximum, got %d%% (%d columns)", m.leftPanePercent, left)
+	}
+	if got := m.diffViewport.Width; got != 100-60-6-2 {
+		t.Errorf("expected the diff to narrow with it, got width %d", got)
+	}
+}
+
+func TestNotificationCenter(t *testing.T) {
+	m := newGoldenModel(t, 100, 30)
+	m.config.Notifications.History = 3
+	m.addToast("Prompt saved", ToastSuccess)
+	m.addToast("Ralph history unavailable", ToastWarning)
+	m.addToast("Sync failed: remote rejected", ToastError)
+	m.addToast("Plan reloaded", ToastInfo)
+
+	if len(m.toastLog) != 3 || m.toastLog[0].Message != "Ralph history unavailable" {
+		t.Fatalf("expected the oldest toast dropped beyond the history, got %v", m.toastLog)
+	}
+	for _, toast := range m.toastLog {
+		want := map[ToastType]time.Duration{ToastInfo: 3 * time.Second, ToastWarning: 5 * time.Second, ToastError: 8 * time.Second}[toast.Type]
+		if toast.Duration != want {
+			t.Errorf("expected a %s toast up for %v, got %v", toast.Type, want, toast.Duration)
+		}
+	}
+
+	m = updateModel(m, keys("ctrl+g", "!")...)
+	if !m.showNotifications {
+		t.Fatal("expected the notification center open")
+	}
+	if entries := m.notificationEntries(); len(entries) != 3 || entries[0].Message != "Plan reloaded" {
+		t.Errorf("expected all toasts listed newest first, got %v", entries)
+	}
+	m = updateModel(m, keys("l", "l")...)
+	if entries := m.notificationEntries(); len(entries) != 1 || entries[0].Type != ToastError {
+		t.Errorf("expected only errors listed, got %v", entries)
+	}
+	if view := m.View(); !strings.Contains(view, "level ≥ error") || !strings.Contains(view, "Sync failed: remote rejected") {
+		t.Errorf("expected the filtered toasts rendered, got:\n%s", view)
+	}
+	m = updateModel(m, keys("l", "esc")...)
+	if m.showNotifications || m.notificationLevel != ToastInfo {
+		t.Error("expected the filter back to everything and the center closed")
+	}
+}
+
+func TestDoNotDisturb(t *testing.T) {
+	m := newGoldenModel(t, 100, 30)
+	m.addToast("Plan reloaded", ToastInfo)
+	m = updateModel(m, keys("ctrl+g", "!", "d", "esc")...)
+	if !m.doNotDisturb || len(m.toasts) != 0 {
+		t.Fatal("expected do not disturb on and the showing toasts cleared")
+	}
+
+	m.addToast("Commit failed", ToastError)
+	if len(m.toasts) != 0 || len(m.toastLog) != 2 || m.heldToasts != 1 {
+		t.Fatalf("expected the toast held in the center, got %d showing, %d logged, %d held", len(m.toasts), len(m.toastLog), m.heldToasts)
+	}
+	if status := ansi.Strip(m.renderStatus()); !strings.Contains(status, "DND 1") {
+		t.Errorf("expected the held count in the status bar, got %q", status)
+	}
+
+	// Opening the center acknowledges the held toasts
+	m = updateModel(m, keys("ctrl+g", "!")...)
+	if m.heldToasts != 0 {
+		t.Error("expected the held count reset")
+	}
+	m = updateModel(m, keys("d", "esc")...)
+	m.addToast("Prompt saved", ToastSuccess)
+	if m.doNotDisturb || len(m.toasts) != 1 {
+		t.Error("expected toasts shown again")
+	}
+}
+
+func TestStatusBarTemplate(t *testing.T) {
+	m := newGoldenModel(t, 100, 30)
+	m.changes = []Change{
+		{Timestamp: goldenNow.Add(-time.Hour), FilePath: "/proj/a.go"},
+		{Timestamp: goldenNow.Add(-11 * time.Hour), FilePath: "/proj/b.go"},
+		{Timestamp: goldenNow.Add(-13 * time.Hour), FilePath: "/proj/c.go"}, // Yesterday
+	}
+
+	// The default matches the built-in layout
+	if got := ansi.Strip(m.renderStatusBar(40)); got != "History [L]  j/k:nav  Tab:mode  [… D○ S○" {
+		t.Errorf("unexpected default status bar %q", got)
+	}
+
+	m.vcsStatus = &vcs.Status{Branch: "feature/auth"}
+	m.config.StatusBar = "{workspace}@{branch} {dnd} {edits_today} today {nope}{right}{time} {dnd}"
+
+	// Empty segments drop their space; unknown ones are kept as written
+	want := "proj@feature/auth 2 today {nope}" + strings.Repeat(" ", 13) + "12:00"
+	if got := ansi.Strip(m.renderStatusBar(50)); got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+
+	// Space runs out on the left first
+	if got := ansi.Strip(m.renderStatusBar(12)); got != "proj@… 12:00" {
+		t.Errorf("expected the left side cut short, got %q", got)
+	}
+	if got := ansi.Strip(m.renderStatusBar(3)); got != "12…" {
+		t.Errorf("expected only the right side, cut short, got %q", got)
+	}
+}
+
+func TestVCSStatusHeader(t *testing.T) {
+	m := newGoldenModel(t, 100, 30)
+	if strings.Contains(ansi.Strip(m.View()), "⎇") {
+		t.Error("expected no branch shown before the first lookup")
+	}
+
+	m = updateModel(m, vcsStatusMsg{status: vcs.Status{Branch: "main", Ahead: 2, Behind: 1, Dirty: true}})
+	header := strings.SplitN(ansi.Strip(m.View()), "\n", 2)[0]
+	if !strings.HasSuffix(strings.TrimRight(header, " "), "⎇ main ↑2 ↓1 *") {
+		t.Errorf("expected the branch right-aligned in the header, got %q", header)
+	}
+	if len(m.toasts) != 0 {
+		t.Error("expected no toast for the first lookup")
+	}
+
+	// Switching branches under Claude is worth a warning
+	m = updateModel(m, vcsStatusMsg{status: vcs.Status{Branch: "feature"}})
+	if len(m.toasts) != 1 || m.toasts[0].Type != ToastWarning || m.toasts[0].Message != "Branch changed: main → feature" {
+		t.Errorf("expected a branch change warning, got %+v", m.toasts)
+	}
+	if got := ansi.Strip(m.renderVCSStatus()); got != "⎇ feature" {
+		t.Errorf("expected a clean branch without counts, got %q", got)
+	}
+
+	// Leaving the repository hides the indicator
+	m = updateModel(m, vcsStatusMsg{err: errors.New("no VCS detected")})
+	if m.renderVCSStatus() != "" {
+		t.Error("expected no branch outside a repository")
+	}
+}
+
+func TestFollowMode(t *testing.T) {
+	m := newGoldenModel(t, 100, 30)
+	runner := &fakeRunner{output: map[string]string{"nvim": ""}}
+	m.runner = runner
+	m.config.NvimRemote = true
+	m.config.NvimServer = ""
+	t.Setenv("NVIM_LISTEN_ADDRESS", "")
+	t.Setenv("NVIM", "")
+
+	// Without a server there's nothing to follow in
+	m = updateModel(m, keys("ctrl+g", "f")...)
+	if m.following {
+		t.Fatal("expected follow mode to stay off without an nvim server")
+	}
+
+	m.config.NvimServer = "/tmp/nvim.sock"
+	m.config.Follow.Files = []string{"*.go"}
+	m = updateModel(m, keys("ctrl+g", "f")...)
+	if !m.following || ansi.Strip(m.statusFollow()) != "FOLLOW" {
+		t.Fatal("expected follow mode on")
+	}
+
+	// Only allowlisted files are followed, and only the latest change in a
+	// burst is opened
+	if m.followChange(Change{FilePath: "/proj/README.md", LineNum: 3}) != nil {
+		t.Error("expected a file outside the allowlist skipped")
+	}
+	m.followChange(Change{FilePath: "/proj/a.go", LineNum: 10})
+	stale := m.followSeq
+	m.followChange(Change{FilePath: "/proj/sub/b.go", LineNum: 42})
+
+	tm, cmd := m.Update(followTickMsg{seq: stale})
+	if cmd != nil {
+		t.Error("expected a superseded change not opened")
+	}
+	m = tm.(Model)
+	_, cmd = m.Update(followTickMsg{seq: m.followSeq})
+	if cmd == nil {
+		t.Fatal("expected the latest change opened")
+	}
+	cmd()
+	want := " nvim --server /tmp/nvim.sock --remote-expr execute('edit +42 ' . fnameescape('/proj/sub/b.go'))"
+	if len(runner.ran) != 1 || runner.ran[0] != want {
+		t.Errorf("expected %q, ran %q", want, runner.ran)
+	}
+
+	// An unreachable nvim turns follow mode off instead of failing per edit
+	m = updateModel(m, followErrMsg{err: errors.New("connection refused")})
+	if m.following || len(m.toasts) == 0 || m.toasts[len(m.toasts)-1].Type != ToastError {
+		t.Error("expected follow mode off with an error toast")
+	}
+}
+
+func TestReviewNote(t *testing.T) {
+	m := newGoldenModel(t, 100, 30)
+	m.changes = []Change{
+		{Timestamp: goldenNow, FilePath: "/proj/a.go", ToolName: "Edit", OldString: "a", NewString: "b", LineNum: 3},
+	}
+
+	m = updateModel(m, keys("ctrl+g", "a")...)
+	if !m.noteActive {
+		t.Fatal("expected the note input open")
+	}
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("needs rework")}, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.noteActive || m.changes[0].Note != "needs rework" {
+		t.Fatalf("expected the note saved, got %q", m.changes[0].Note)
+	}
+	if !strings.Contains(ansi.Strip(m.renderDiff()), "✎ needs rework") {
+		t.Error("expected the note in the diff header")
+	}
+
+	// Reopening edits the note; clearing it removes it
+	m = updateModel(m, keys("ctrl+g", "a")...)
+	if m.noteInput.Value() != "needs rework" {
+		t.Errorf("expected the input pre-filled, got %q", m.noteInput.Value())
+	}
+	m.noteInput.SetValue("")
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.changes[0].Note != "" || strings.Contains(ansi.Strip(m.renderDiff()), "✎") {
+		t.Error("expected the note removed")
+	}
+}
+
+func TestReviewMode(t *testing.T) {
+	m := newGoldenModel(t, 100, 30)
+	m.changes = []Change{
+		{Timestamp: goldenNow, FilePath: "/proj/a.go", ToolName: "Edit", OldString: "a", NewString: "b"},
+		{Timestamp: goldenNow.Add(-time.Minute), FilePath: "/proj/b.go", ToolName: "Edit", OldString: "a", NewString: "b"},
+		{Timestamp: goldenNow.Add(-2 * time.Minute), FilePath: "/proj/c.go", ToolName: "Edit", OldString: "a", NewString: "b"},
+	}
+
+	m = updateModel(m, keys("ctrl+g", "v")...)
+	if !m.reviewing || m.selectedIndex != 0 {
+		t.Fatalf("expected review to start at the first change, selected %d", m.selectedIndex)
+	}
+
+	m = updateModel(m, keys("a")...)
+	if m.changes[0].Review != "approved" || m.selectedIndex != 1 {
+		t.Fatalf("expected the change approved and the next selected, got %q at %d", m.changes[0].Review, m.selectedIndex)
+	}
+
+	// Rejecting asks why before moving on
+	m = updateModel(m, keys("r")...)
+	if m.changes[1].Review != "rejected" || !m.noteActive {
+		t.Fatal("expected the change rejected with the note input open")
+	}
+	m = updateModel(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("handle nil")}, tea.KeyMsg{Type: tea.KeyEnter})
+	if m.changes[1].Note != "handle nil" || m.selectedIndex != 2 {
+		t.Errorf("expected the note kept and the next change selected, got %q at %d", m.changes[1].Note, m.selectedIndex)
+	}
+	if got := ansi.Strip(m.renderStatusBar(100)); !strings.Contains(got, "a:approve") || !strings.Contains(got, "2/3 reviewed") {
+		t.Errorf("expected review keys and progress in the status bar, got %q", got)
+	}
+	if !strings.Contains(ansi.Strip(m.renderHistory()), "✗") {
+		t.Error("expected the rejected change marked in the list")
+	}
+
+	// Skipping with nothing else left stays put; the last approval ends it
+	m = updateModel(m, keys("s")...)
+	if m.selectedIndex != 2 {
+		t.Errorf("expected the only unreviewed change kept, got %d", m.selectedIndex)
+	}
+	m = updateModel(m, keys("a")...)
+	if m.reviewing {
+		t.Error("expected review to end once every change is reviewed")
+	}
+	if last := m.toasts[len(m.toasts)-1]; last.Message != "Review done: 2 approved, 1 rejected" {
+		t.Errorf("unexpected toast %q", last.Message)
+	}
+	if !strings.Contains(ansi.Strip(m.renderDiff()), "✓ approved") {
+		t.Error("expected the review state in the diff header")
+	}
+}
+
+func TestModelHookEvents(t *testing.T) {
 	m := New("/tmp/test.sock")
 	var tm tea.Model = m
 	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
 
-	before := time.Now()
-	payload := `{"tool_name":"Edit","tool_input":{"file_path":"/test.go"}}`
-	tm, _ = tm.Update(SocketMsg{Payload: []byte(payload)})
-	after := time.Now()
+	// PreToolUse announces the edit without adding a change
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"hook_event_name":"SessionStart","session_id":"s1","source":"startup"}`)})
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"hook_event_name":"PreToolUse","session_id":"s1","tool_name":"Edit","tool_input":{"file_path":"/proj/a.go"}}`)})
+	model := tm.(Model)
+	if len(model.changes) != 0 {
+		t.Fatalf("expected no change before PostToolUse, got %d", len(model.changes))
+	}
+	if got := model.statusPending(); !strings.Contains(got, "a.go") {
+		t.Errorf("expected a.go pending in the status bar, got %q", got)
+	}
+
+	// PostToolUse lands it
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"hook_event_name":"PostToolUse","session_id":"s1","tool_name":"Edit","tool_input":{"file_path":"/proj/a.go"}}`)})
+	model = tm.(Model)
+	if len(model.changes) != 1 || model.statusPending() != "" {
+		t.Fatalf("expected the edit landed, got %d changes and pending %q", len(model.changes), model.statusPending())
+	}
+
+	// Stop ends the turn, dropping edits that never landed
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"hook_event_name":"PreToolUse","session_id":"s1","tool_name":"Write","tool_input":{"file_path":"/proj/b.go"}}`)})
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"hook_event_name":"SubagentStop","session_id":"s1"}`)})
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(`{"hook_event_name":"Stop","session_id":"s1"}`)})
+	model = tm.(Model)
+	if model.statusPending() != "" {
+		t.Errorf("expected Stop to clear pending edits, got %q", model.statusPending())
+	}
+	a := model.sessionActivity["s1"]
+	if a == nil || a.Started.IsZero() || a.Turns != 1 || a.Subagents != 1 {
+		t.Fatalf("unexpected session activity %+v", a)
+	}
+	if got := model.sessionActivityLine("s1"); !strings.Contains(got, "1 turn") || !strings.Contains(got, "1 subagent") {
+		t.Errorf("unexpected activity line %q", got)
+	}
+}
+
+func TestModelGate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.go")
+	if err := os.WriteFile(path, []byte("package a\n\nfunc A() int { return 1 }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	edit := fmt.Sprintf(`{"hook_event_name":"PreToolUse","session_id":"s1","tool_name":"Edit","tool_input":{"file_path":%q,"old_string":"return 1","new_string":"return 2"}}`, path)
+
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 100, Height: 40})
 
+	// The announced edit is previewed before it lands
+	tm, _ = tm.Update(SocketMsg{Payload: []byte(edit)})
 	model := tm.(Model)
-	change := model.changes[0]
+	if p := model.incomingEdit(); p == nil || p.Change.NewString != "return 2" {
+		t.Fatalf("expected the edit previewed, got %+v", p)
+	}
+	if got := model.renderDiff(); !strings.Contains(got, "Incoming") {
+		t.Errorf("expected the incoming preview in the diff pane, got %q", got)
+	}
 
-	if change.Timestamp.Before(before) || change.Timestamp.After(after) {
-		t.Errorf("timestamp %v should be between %v and %v", change.Timestamp, before, after)
+	// A held edit waits for y or n
+	answers := make(chan socket.GateDecision, 2)
+	answer := func(d socket.GateDecision) { answers <- d }
+	tm, _ = tm.Update(GateMsg{Payload: []byte(edit), Answer: answer})
+	model = tm.(Model)
+	if got := model.renderStatus(); !strings.Contains(got, "Allow Edit of") {
+		t.Errorf("expected the approval prompt, got %q", got)
+	}
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("n")})
+	if d := <-answers; d.Allow || d.Reason == "" {
+		t.Errorf("expected a denial with a reason, got %+v", d)
+	}
+	model = tm.(Model)
+	if len(model.gateQueue) != 0 || model.incomingEdit() != nil {
+		t.Errorf("expected the denied edit dropped, got %d held", len(model.gateQueue))
+	}
+
+	// Nobody answering allows it by default
+	tm, _ = tm.Update(GateMsg{Payload: []byte(edit), Answer: answer})
+	tm, _ = tm.Update(gateTimeoutMsg{id: tm.(Model).gateSeq})
+	if d := <-answers; !d.Allow {
+		t.Errorf("expected the timed out edit allowed, got %+v", d)
+	}
+	if len(tm.(Model).gateQueue) != 0 {
+		t.Error("expected no edit held after the timeout")
 	}
 }
 
-func TestParseKeyValue(t *testing.T) {
-	tests := []struct {
-		input   string
-		wantKey string
-		wantVal string
-		wantOk  bool
-	}{
-		// Simple cases
-		{"foo=bar", "foo", "bar", true},
-		{"DEBUG=true", "DEBUG", "true", true},
+func TestModelAlerts(t *testing.T) {
+	m := New("/tmp/test.sock")
+	var tm tea.Model = m
+	tm, _ = tm.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
 
-		// Double-quoted values with spaces
-		{`foo="hello world"`, "foo", "hello world", true},
-		{`MESSAGE="this is a test"`, "MESSAGE", "this is a test", true},
+	now := time.Now()
+	events := []Incident{
+		{Timestamp: now.Add(time.Second), Kind: "alert_burst", Severity: "warning", Message: "24 files changed in 1m0s"},
+		{Timestamp: now.Add(time.Second), Kind: "huge_edit", Severity: "warning", Message: "Write big.go"},
+		{Timestamp: now.Add(-time.Hour), Kind: "alert_protected", Severity: "warning", Message: "edit to protected file migrations/1.sql"},
+	}
+	tm, _ = tm.Update(daemonEventsMsg{events: events})
+	model := tm.(Model)
+	if len(model.alerts) != 1 || model.alerts[0].Kind != "alert_burst" {
+		t.Fatalf("expected only the new alert raised, got %+v", model.alerts)
+	}
+	if view := model.View(); !strings.Contains(view, "24 files changed") {
+		t.Errorf("expected the alert banner in the view")
+	}
 
-		// Single-quoted values with spaces
-		{`foo='hello world'`, "foo", "hello world", true},
+	// The timeline is polled; an alert is raised once
+	tm, _ = tm.Update(daemonEventsMsg{events: events})
+	if len(tm.(Model).alerts) != 1 {
+		t.Errorf("expected the alert raised once, got %d", len(tm.(Model).alerts))
+	}
 
-		// Backtick-quoted values
-		{"foo=`hello world`", "foo", "hello world", true},
+	tm, _ = tm.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if len(tm.(Model).alerts) != 0 {
+		t.Error("expected Esc to dismiss the banner")
+	}
+}
 
-		// Values with special characters
-		{`PATH="/usr/bin:/usr/local/bin"`, "PATH", "/usr/bin:/usr/local/bin", true},
+func TestModelPolicy(t *testing.T) {
+	ws := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(ws, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write(".claude-mon-policy.toml", "protected = [\"migrations/**\"]\nauto_revert = true\n")
+	migration := write("migrations/0001.sql", "CREATE TABLE users (id BIGINT);\n")
+	main := write("main.go", "package main\n")
 
-		// Invalid cases
-		{"noequals", "", "", false},
-		{"=value", "", "", false},
-		{"", "", "", false},
+	m := New("/tmp/test.sock", WithFS(workspaceFS{dir: ws}))
+	edit := func(path, old, new string) tea.Msg {
+		return SocketMsg{Payload: []byte(fmt.Sprintf(`{"tool_name":"Edit","tool_input":{"file_path":%q,"old_string":%q,"new_string":%q}}`, path, old, new))}
 	}
+	editMigration := edit(migration, "id INT", "id BIGINT").(SocketMsg)
+	m = updateModel(m, tea.WindowSizeMsg{Width: 120, Height: 40},
+		edit(main, "package app", "package main"),
+		editMigration)
 
-	for _, tt := range tests {
-		t.Run(tt.input, func(t *testing.T) {
-			key, val, ok := parseKeyValue(tt.input)
-			if ok != tt.wantOk {
-				t.Errorf("parseKeyValue(%q) ok = %v, want %v", tt.input, ok, tt.wantOk)
-			}
-			if key != tt.wantKey {
-				t.Errorf("parseKeyValue(%q) key = %q, want %q", tt.input, key, tt.wantKey)
-			}
-			if val != tt.wantVal {
-				t.Errorf("parseKeyValue(%q) val = %q, want %q", tt.input, val, tt.wantVal)
-			}
-		})
+	if len(m.changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d", len(m.changes))
+	}
+	if c := m.changes[1]; c.Protected != "" {
+		t.Errorf("expected main.go unprotected, got %q", c.Protected)
+	}
+	c := m.changes[0]
+	if c.Protected != "migrations/**" || c.Reverted {
+		t.Fatalf("expected the migration edit flagged with its revert pending, got %q reverted=%v", c.Protected, c.Reverted)
+	}
+
+	// The revert runs as a command, through the model's FS
+	cmd := m.enforcePolicy(&c, editMigration.Payload)
+	if cmd == nil {
+		t.Fatal("expected a revert command")
+	}
+	m = updateModel(m, cmd())
+	if c := m.changes[0]; !c.Reverted {
+		t.Fatal("expected the migration edit marked reverted")
+	}
+	if data, _ := os.ReadFile(migration); string(data) != "CREATE TABLE users (id INT);\n" {
+		t.Errorf("expected the migration reverted on disk, got %q", data)
+	}
+	if got := m.renderDiff(); !strings.Contains(got, "Protected by .claude-mon-policy.toml (migrations/**), reverted") {
+		t.Errorf("expected the policy in the diff header, got %q", got)
+	}
+
+	// The policy is read again once the file changes
+	policyPath := write(".claude-mon-policy.toml", "protected = [\"main.go\"]\n")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(policyPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	m = updateModel(m, edit(main, "package main", "package app"))
+	if c := m.changes[0]; c.Protected != "main.go" || c.Reverted {
+		t.Errorf("expected main.go protected by the new policy without a revert, got %q reverted=%v", c.Protected, c.Reverted)
+	}
+}
+
+func TestPolicyRevertSkipsTruncatedEdits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.sql")
+	if err := os.WriteFile(path, []byte("CREATE TABLE users (id BIGINT);\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	truncated := "id BIGINT" + socket.TruncatedMarker
+	multiEdit := []byte(fmt.Sprintf(`{"tool_name":"MultiEdit","tool_input":{"file_path":%q,"edits":[{"old_string":"a","new_string":"b"},{"old_string":"id INT","new_string":%q}]}}`, path, truncated))
+
+	for _, tt := range []struct {
+		name   string
+		change Change
+		data   []byte
+	}{
+		{"truncated change", Change{ToolName: "Edit", FilePath: path, OldString: "id INT", NewString: truncated, Truncated: true}, nil},
+		{"truncated MultiEdit edit", Change{ToolName: "MultiEdit", FilePath: path, OldString: "a", NewString: "b"}, multiEdit},
+	} {
+		err := revertChange(osFS{}, &tt.change, tt.data)
+		if err == nil || !strings.Contains(err.Error(), "too large") {
+			t.Errorf("%s: expected the revert refused, got %v", tt.name, err)
+		}
+	}
+	if data, _ := os.ReadFile(path); string(data) != "CREATE TABLE users (id BIGINT);\n" {
+		t.Errorf("expected the file left alone, got %q", data)
+	}
+}
+
+func TestCommitRefusesStagedFiles(t *testing.T) {
+	// Files staged before the commit would go into it with Claude's hunks
+	runner := &fakeRunner{output: map[string]string{"git": "notes.md\x00"}}
+	msg := commitChangesCmd(runner, []Change{{FilePath: "/proj/main.go", OldString: "a", NewString: "b"}}, "Fix")()
+	done, ok := msg.(commitDoneMsg)
+	if !ok || done.err == nil || !strings.Contains(done.err.Error(), "notes.md") {
+		t.Fatalf("expected the commit refused over notes.md, got %+v", msg)
+	}
+	if len(runner.ran) != 1 || !strings.Contains(runner.ran[0], "diff --cached") {
+		t.Errorf("expected only the staged files checked, ran %v", runner.ran)
+	}
+}
+
+func TestWorkingFilesAndNvimUseModelSystem(t *testing.T) {
+	fsys := mapFS{
+		workspaceFS: workspaceFS{dir: "/proj"},
+		files:       fstest.MapFS{"proj/main.go": {Data: []byte("package main\n")}},
+	}
+	// Relative paths resolve against the FS's working directory
+	if content, err := readWorkingFile(fsys, "main.go"); err != nil || content != "package main\n" {
+		t.Errorf("readWorkingFile = %q, %v; want the file from the FS", content, err)
+	}
+
+	runner := &fakeRunner{output: map[string]string{"nvim": ""}}
+	if err := remoteNvimEdit(runner, "/tmp/nvim.sock", "/proj/main.go", 3); err != nil {
+		t.Fatalf("remoteNvimEdit failed: %v", err)
+	}
+	execNvimCmd(runner, "/proj/main.go", 0)
+	if len(runner.ran) != 2 || !strings.Contains(runner.ran[0], "nvim --server /tmp/nvim.sock") || runner.ran[1] != "nvim /proj/main.go" {
+		t.Errorf("expected nvim run through the runner, ran %q", runner.ran)
 	}
 }
@@ -0,0 +1,157 @@
+package model
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Screen rows View puts above the panes: the tab bar, then the panes' top
+// border (where the minimap starts)
+const (
+	paneTop        = 1
+	paneContentTop = 2
+)
+
+// paneWidths returns the widths View gives the left and right panes, inside
+// their borders, and the minimap. The left width is 0 when the list pane is
+// hidden or the mode uses the full width.
+func (m Model) paneWidths() (leftWidth, rightWidth, minimapWidth int) {
+	if m.showMinimap {
+		minimapWidth = 2
+	}
+	if m.hideLeftPane || m.leftPaneMode == LeftPaneModeRalph || m.leftPaneMode == LeftPaneModeContext || m.leftPaneMode == LeftPaneModeChat {
+		return 0, m.width - 2 - minimapWidth, minimapWidth
+	}
+	// Fixed width for left pane to prevent layout shifts when scrolling
+	leftWidth = max(m.leftPaneWidth(), 25)
+	return leftWidth, m.width - leftWidth - 3 - minimapWidth, minimapWidth
+}
+
+// overlayOpen reports whether a picker, popup or full-screen panel covers
+// the panes, so clicks shouldn't reach them
+func (m Model) overlayOpen() bool {
+	return m.showHelp || m.showHookHealth || m.showLogConsole || m.showNotifications || m.showThemePicker ||
+		m.showPromptTagPicker || m.showTmuxPicker || m.showChatPicker || m.promptVarForm != nil ||
+		m.leaderActive || m.contextEditMode || m.showContextProfiles || m.contextDetected != nil
+}
+
+// handleMouse scrolls the diff with the wheel and hit-tests left clicks
+// against the tab bar, the list pane and the minimap. Clicking a change
+// marked on the minimap jumps to it; dragging scrubs through the diff.
+func (m *Model) handleMouse(msg tea.MouseMsg) tea.Cmd {
+	switch msg.Action {
+	case tea.MouseActionRelease:
+		m.minimapDragging = false
+		return nil
+	case tea.MouseActionMotion:
+		if m.minimapDragging {
+			m.scrubMinimap(msg.Y)
+		}
+		return nil
+	}
+
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		m.scrollDiff(-3)
+		return nil
+	case tea.MouseButtonWheelDown:
+		m.scrollDiff(3)
+		return nil
+	case tea.MouseButtonLeft:
+	default:
+		return nil
+	}
+	if m.overlayOpen() {
+		return nil
+	}
+
+	if msg.Y < paneTop {
+		if mode, ok := m.tabAt(msg.X); ok && mode != m.leftPaneMode {
+			m.switchToMode(mode)
+		}
+		return nil
+	}
+
+	leftWidth, rightWidth, minimapWidth := m.paneWidths()
+	leftEnd := 0
+	if leftWidth > 0 {
+		leftEnd = leftWidth + 2
+	}
+	minimapStart := leftEnd + rightWidth + 2
+	switch {
+	case msg.X < leftEnd:
+		return m.clickList(msg.Y - paneContentTop)
+	case msg.X < minimapStart:
+		m.activePane = PaneRight
+	case msg.X < minimapStart+minimapWidth && m.leftPaneMode == LeftPaneModeHistory:
+		m.minimapDragging = true
+		m.clickMinimap(msg.Y)
+	}
+	return nil
+}
+
+// tabAt returns the mode of the tab at column x of the tab bar
+func (m Model) tabAt(x int) (LeftPaneMode, bool) {
+	start := lipgloss.Width(m.theme.Title.Render("claude-mon") + " ")
+	for i, part := range m.tabBarParts() {
+		end := start + lipgloss.Width(part)
+		if x >= start && x < end {
+			return tabBarTabs[i].mode, true
+		}
+		start = end + 1
+	}
+	return 0, false
+}
+
+// clickList selects the history change or prompt on a row of the list pane
+func (m *Model) clickList(row int) tea.Cmd {
+	m.activePane = PaneLeft
+	switch m.leftPaneMode {
+	case LeftPaneModeHistory:
+		_, rows := m.renderHistoryRows()
+		if row < 0 || row >= len(rows) || rows[row] < 0 || rows[row] == m.selectedIndex {
+			return nil
+		}
+		m.selectedIndex = rows[row]
+		m.scrollX = 0
+		m.ensureSelectedVisible()
+		cmd := m.showDiff()
+		m.scrollToChange()
+		m.preloadAdjacent()
+		return cmd
+	case LeftPaneModePrompts:
+		if m.promptFuzzyActive || m.promptShowVersions {
+			return nil
+		}
+		i := row - 2 // Below the title and separator
+		if i < 0 || i >= len(m.promptFilteredList) || i == m.promptSelected {
+			return nil
+		}
+		m.promptSelected = i
+		m.diffViewport.SetContent(m.renderRightPane())
+	}
+	return nil
+}
+
+// scrubMinimap scrolls the diff so the part the minimap shows at screen row
+// y is centered
+func (m *Model) scrubMinimap(y int) {
+	height := m.height - 4
+	if height < 1 {
+		return
+	}
+	row := max(0, min(y-paneTop, height-1))
+
+	// The minimap covers the file body, below the diff header; the plain
+	// scrollbar covers every row
+	total, header := m.totalLines, 0
+	if m.minimapData != nil && m.minimapData.TotalLines() > 0 {
+		total = m.minimapData.TotalLines()
+		if m.diffDoc != nil {
+			header = len(m.diffDoc.header)
+		}
+	}
+	perRow := max(float64(total)/float64(height), 1)
+	target := header + int(float64(row)*perRow)
+	m.scrollDiff(target - m.diffViewport.Height/2 - m.diffScrollTop())
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// openNoteInput opens the review note prompt for the selected change,
+// pre-filled with its current note
+func (m *Model) openNoteInput() tea.Cmd {
+	if len(m.changes) == 0 {
+		return nil
+	}
+	m.noteActive = true
+	m.noteInput.SetValue(m.changes[m.selectedIndex].Note)
+	m.noteInput.CursorEnd()
+	return m.noteInput.Focus()
+}
+
+// handleNoteKeys handles key events while the note input is active
+func (m Model) handleNoteKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.noteActive = false
+		m.noteInput.Blur()
+		if m.reviewing {
+			return m, m.advanceReview()
+		}
+		return m, nil
+
+	case "enter":
+		m.noteActive = false
+		m.noteInput.Blur()
+		m.setNote(strings.TrimSpace(m.noteInput.Value()))
+		// A rejection's note is written; move on to the next change
+		if m.reviewing {
+			return m, m.advanceReview()
+		}
+		return m, m.showDiff()
+	}
+
+	var cmd tea.Cmd
+	m.noteInput, cmd = m.noteInput.Update(msg)
+	return m, cmd
+}
+
+// setNote attaches a review note to the selected change, or removes it if
+// note is empty, persisting to the history file and the daemon
+func (m *Model) setNote(note string) {
+	if len(m.changes) == 0 {
+		return
+	}
+	change := &m.changes[m.selectedIndex]
+	if change.Note == note {
+		return
+	}
+	change.Note = note
+	m.diffCache = make(map[int]*diffDoc)
+
+	if m.persistHistory && m.historyStore != nil {
+		if err := m.historyStore.SetNote(change.Timestamp, change.FilePath, note); err != nil {
+			logger.Log("Failed to save note: %v", err)
+		}
+	}
+
+	// Changes loaded from the daemon carry their edit ID; live changes are
+	// matched by file path and timestamp
+	m.sendDaemonPayload(map[string]interface{}{
+		"type":      "note",
+		"edit_id":   change.EditID,
+		"file_path": change.FilePath,
+		"timestamp": change.Timestamp.Format(time.RFC3339Nano),
+		"note":      note,
+	})
+
+	if note == "" {
+		m.addToast("Note removed", ToastInfo)
+		return
+	}
+	m.addToast("Note saved", ToastSuccess)
+}
+
+// renderNoteInput renders the status line while writing a note
+func (m Model) renderNoteInput() string {
+	change := m.changes[m.selectedIndex]
+	return m.theme.Status.Render(fmt.Sprintf("Note on %s: %s  Enter:save  Esc:cancel",
+		m.relativePath(change.FilePath), m.noteInput.View()))
+}
@@ -0,0 +1,202 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultNotificationHistory is how many toasts the notification center
+// keeps when the config doesn't say
+const defaultNotificationHistory = 100
+
+// notificationLevels are the severities l cycles the center's filter
+// through: everything, warnings and errors, errors only
+var notificationLevels = []ToastType{ToastInfo, ToastWarning, ToastError}
+
+// String returns the toast type's name
+func (t ToastType) String() string {
+	switch t {
+	case ToastSuccess:
+		return "success"
+	case ToastWarning:
+		return "warning"
+	case ToastError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+// toastIcon returns the icon shown before a toast of type t
+func toastIcon(t ToastType) string {
+	switch t {
+	case ToastSuccess:
+		return "✓ "
+	case ToastError:
+		return "✗ "
+	case ToastWarning:
+		return "⚠ "
+	default:
+		return "ℹ "
+	}
+}
+
+// toastDuration returns how long a toast of type t stays up, from the
+// config's [notifications] section
+func (m Model) toastDuration(t ToastType) time.Duration {
+	n := m.config.Notifications
+	value, fallback := n.Info, 3*time.Second
+	switch t {
+	case ToastSuccess:
+		value = n.Success
+	case ToastWarning:
+		value, fallback = n.Warning, 5*time.Second
+	case ToastError:
+		value, fallback = n.Error, 8*time.Second
+	}
+	if d, err := time.ParseDuration(value); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
+
+// logToast keeps a toast for the notification center, dropping the oldest
+// beyond the configured history
+func (m *Model) logToast(t Toast) {
+	limit := m.config.Notifications.History
+	if limit <= 0 {
+		limit = defaultNotificationHistory
+	}
+	m.toastLog = append(m.toastLog, t)
+	if len(m.toastLog) > limit {
+		m.toastLog = m.toastLog[len(m.toastLog)-limit:]
+	}
+}
+
+// openNotifications shows the notification center, newest first
+func (m *Model) openNotifications() {
+	m.showNotifications = true
+	m.notificationScroll = 0
+	m.heldToasts = 0
+}
+
+// toggleDoNotDisturb holds toasts in the notification center instead of
+// showing them, or shows them again
+func (m *Model) toggleDoNotDisturb() {
+	m.doNotDisturb = !m.doNotDisturb
+	m.heldToasts = 0
+	if m.doNotDisturb {
+		m.toasts = nil
+	}
+}
+
+// handleNotificationsKey filters and scrolls the notification center
+func (m *Model) handleNotificationsKey(key string) {
+	switch key {
+	case "esc", "q":
+		m.showNotifications = false
+	case "l":
+		for i, lvl := range notificationLevels {
+			if lvl == m.notificationLevel {
+				m.notificationLevel = notificationLevels[(i+1)%len(notificationLevels)]
+				break
+			}
+		}
+		m.notificationScroll = 0
+	case "d":
+		m.toggleDoNotDisturb()
+	case "c":
+		m.toastLog = nil
+		m.notificationScroll = 0
+	case "j", "down":
+		m.scrollNotifications(1)
+	case "k", "up":
+		m.scrollNotifications(-1)
+	case "ctrl+d", "pgdown":
+		m.scrollNotifications(m.notificationRows())
+	case "ctrl+u", "pgup":
+		m.scrollNotifications(-m.notificationRows())
+	case "g", "home":
+		m.notificationScroll = 0
+	}
+}
+
+// scrollNotifications scrolls down n older toasts, or up if n is negative
+func (m *Model) scrollNotifications(n int) {
+	limit := max(0, len(m.notificationEntries())-m.notificationRows())
+	m.notificationScroll = max(0, min(m.notificationScroll+n, limit))
+}
+
+// notificationRows is how many toasts fit in the center
+func (m Model) notificationRows() int {
+	return max(1, m.height-6)
+}
+
+// notificationEntries returns the logged toasts at or above the center's
+// severity filter, newest first
+func (m Model) notificationEntries() []Toast {
+	var entries []Toast
+	for i := len(m.toastLog) - 1; i >= 0; i-- {
+		if t := m.toastLog[i]; t.Type >= m.notificationLevel {
+			entries = append(entries, t)
+		}
+	}
+	return entries
+}
+
+// renderNotifications renders the notification center: each toast's time,
+// severity and full message
+func (m Model) renderNotifications() string {
+	var sb strings.Builder
+	title := "Notifications"
+	if m.notificationLevel != ToastInfo {
+		title += fmt.Sprintf("  level ≥ %s", m.notificationLevel)
+	}
+	if m.doNotDisturb {
+		title += "  (do not disturb)"
+	}
+	sb.WriteString("\n  " + m.theme.Title.Render(title) + "\n\n")
+
+	entries := m.notificationEntries()
+	rows := m.notificationRows()
+	start := min(m.notificationScroll, max(0, len(entries)-rows))
+	end := min(start+rows, len(entries))
+
+	if len(entries) == 0 {
+		sb.WriteString("  " + m.theme.Dim.Render("No notifications") + "\n")
+	}
+	now := m.clock.Now()
+	for _, t := range entries[start:end] {
+		stamp := t.CreatedAt.Format("15:04:05")
+		if now.Sub(t.CreatedAt) >= 24*time.Hour {
+			stamp = t.CreatedAt.Format("Jan 02 15:04")
+		}
+		line := toastIcon(t.Type) + t.Message
+		if width := m.width - 4 - len(stamp) - 2; width > 1 && len([]rune(line)) > width {
+			line = string([]rune(line)[:width-1]) + "…"
+		}
+		style := m.theme.Normal
+		switch t.Type {
+		case ToastError:
+			style = m.theme.Removed
+		case ToastWarning:
+			style = m.theme.Modified
+		case ToastSuccess:
+			style = m.theme.Added
+		}
+		sb.WriteString("  " + m.theme.Dim.Render(stamp) + "  " + style.Render(line) + "\n")
+	}
+
+	status := fmt.Sprintf("%d of %d", len(entries), len(m.toastLog))
+	if start > 0 {
+		status += fmt.Sprintf(", %d newer above", start)
+	}
+	dnd := "d: do not disturb"
+	if m.doNotDisturb {
+		dnd = "d: show toasts"
+	}
+	sb.WriteString("\n  " + m.theme.Dim.Render(fmt.Sprintf(
+		"%s · l: level  j/k: scroll  %s  c: clear  esc: close", status, dnd)) + "\n")
+	return sb.String()
+}
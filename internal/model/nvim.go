@@ -0,0 +1,66 @@
+package model
+
+import (
+	gocontext "context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// openInNvim opens a file in nvim, at a line when line > 0. With a server
+// configured the file opens in that running nvim; otherwise, or when the
+// server can't be reached, nvim runs in place of the TUI until it exits.
+func (m Model) openInNvim(path string, line int) tea.Cmd {
+	runner := m.runner
+	fallback := execNvimCmd(runner, path, line)
+
+	addr := m.config.NvimServerAddress()
+	if addr == "" {
+		return fallback
+	}
+	return func() tea.Msg {
+		if err := remoteNvimEdit(runner, addr, path, line); err != nil {
+			logger.Log("nvim server %s unavailable, starting nvim: %v", addr, err)
+			return fallback()
+		}
+		return nil
+	}
+}
+
+// execNvimCmd runs nvim on a file in place of the TUI
+func execNvimCmd(runner Runner, path string, line int) tea.Cmd {
+	args := []string{path}
+	if line > 0 {
+		args = []string{fmt.Sprintf("+%d", line), path}
+	}
+	cmd := runner.Command("nvim", args...)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg { return nil })
+}
+
+// remoteNvimEdit runs :edit +{line} {file} in the nvim listening at addr
+func remoteNvimEdit(runner Runner, addr, path string, line int) error {
+	ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 2*time.Second)
+	defer cancel()
+	_, err := runner.Output(ctx, "", "nvim", "--server", addr, "--remote-expr", nvimEditExpr(path, line))
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return err
+}
+
+// nvimEditExpr returns the --remote-expr that runs :edit +{line} {file}
+func nvimEditExpr(path string, line int) string {
+	edit := "edit "
+	if line > 0 {
+		edit = fmt.Sprintf("edit +%d ", line)
+	}
+	// fnameescape handles spaces and specials in the path; the path is a
+	// single-quoted Vim string, where quotes are escaped by doubling
+	return fmt.Sprintf("execute('%s' . fnameescape('%s'))", edit, strings.ReplaceAll(path, "'", "''"))
+}
@@ -0,0 +1,401 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/chat"
+	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/internal/objective"
+)
+
+// waitForObjective waits for the running objective's next output or for
+// Claude to exit
+func waitForObjective(c *chat.ClaudeChat) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case <-c.OutputChan():
+			return objectiveOutputMsg{session: c}
+		case <-c.DoneChan():
+			return objectiveDoneMsg{session: c}
+		}
+	}
+}
+
+// loadObjectives reads the workspace's objective queue the first time it's
+// needed. Returns false if it couldn't be read.
+func (m *Model) loadObjectives() bool {
+	if m.objectives != nil {
+		return true
+	}
+	cwd, err := m.fs.Getwd()
+	if err != nil {
+		m.addToast("Objective queue: "+err.Error(), ToastError)
+		return false
+	}
+	q, err := objective.Load(objective.PathFor(cwd))
+	if err != nil {
+		m.addToast(err.Error(), ToastError)
+		return false
+	}
+	m.objectives = q
+	return true
+}
+
+// saveObjectives writes the queue back after a change
+func (m *Model) saveObjectives() {
+	if err := m.objectives.Save(); err != nil {
+		logger.Log("Failed to save objective queue: %v", err)
+		m.addToast(err.Error(), ToastError)
+	}
+}
+
+// enqueueObjective adds an objective to the end of the queue. It runs once
+// the queue is started, or when the running objectives reach it.
+func (m *Model) enqueueObjective(title, prompt string) {
+	if !m.loadObjectives() {
+		return
+	}
+	o := m.objectives.Add(title, prompt)
+	m.saveObjectives()
+	if m.objectiveRunning {
+		m.addToast(fmt.Sprintf("Queued objective #%d", o.ID), ToastSuccess)
+	} else {
+		m.addToast(fmt.Sprintf("Queued objective #%d (start the queue from the Chat tab)", o.ID), ToastSuccess)
+	}
+	m.refreshChatView()
+}
+
+// toggleObjectiveRunner starts working through the queue, or pauses it. A
+// paused queue lets the running objective finish.
+func (m *Model) toggleObjectiveRunner() tea.Cmd {
+	if !m.loadObjectives() {
+		return nil
+	}
+	if m.objectiveRunning {
+		m.objectiveRunning = false
+		if m.objectiveSession != nil {
+			m.addToast("Queue paused after the running objective", ToastInfo)
+		} else {
+			m.addToast("Queue paused", ToastInfo)
+		}
+		m.refreshChatView()
+		return nil
+	}
+	if m.objectives.Next() == nil && m.objectiveSession == nil {
+		m.addToast("No pending objectives", ToastInfo)
+		return nil
+	}
+	m.objectiveRunning = true
+	m.addToast("Queue started", ToastSuccess)
+	return m.startNextObjective()
+}
+
+// startNextObjective runs the first pending objective in its own Claude
+// process, unless one is already running
+func (m *Model) startNextObjective() tea.Cmd {
+	if m.objectiveSession != nil {
+		return nil
+	}
+	o := m.objectives.Next()
+	if o == nil {
+		m.objectiveRunning = false
+		m.addToast("Objective queue finished", ToastSuccess)
+		m.refreshChatView()
+		return nil
+	}
+
+	c := chat.New()
+	if err := c.StartWithObjective(o.Prompt, ""); err != nil {
+		// Claude itself is missing or broken; running the rest would fail too
+		m.objectives.Start(o.ID)
+		m.objectives.Finish(o.ID, -1, err.Error())
+		m.saveObjectives()
+		m.objectiveRunning = false
+		m.addToast("Failed to start Claude: "+err.Error(), ToastError)
+		m.refreshChatView()
+		return nil
+	}
+	m.objectives.Start(o.ID)
+	m.saveObjectives()
+	m.objectiveSession = c
+	logger.Log("Objective #%d started", o.ID)
+	m.reportDaemonEvent("objective_start", "info", fmt.Sprintf("Objective #%d started: %s", o.ID, objectiveLabel(o)))
+	m.refreshChatView()
+	return waitForObjective(c)
+}
+
+// finishObjective records the output and exit status of the objective that
+// just ended and moves on to the next one
+func (m *Model) finishObjective(c *chat.ClaudeChat) tea.Cmd {
+	m.objectiveSession = nil
+	if o := m.objectives.Running(); o != nil {
+		m.objectives.Finish(o.ID, c.ExitCode(), c.CleanOutput())
+		m.saveObjectives()
+		logger.Log("Objective #%d %s (exit %d)", o.ID, o.Status, o.ExitCode)
+
+		switch o.Status {
+		case objective.StatusDone:
+			m.reportDaemonEvent("objective_done", "info", fmt.Sprintf("Objective #%d done: %s", o.ID, objectiveLabel(o)))
+			m.addToast(fmt.Sprintf("Objective #%d done", o.ID), ToastSuccess)
+		case objective.StatusFailed:
+			m.reportDaemonEvent("objective_failed", "warning", fmt.Sprintf("Objective #%d failed (exit %d): %s", o.ID, o.ExitCode, objectiveLabel(o)))
+			m.addToast(fmt.Sprintf("Objective #%d failed (exit %d)", o.ID, o.ExitCode), ToastWarning)
+		}
+	}
+	m.refreshChatView()
+
+	if m.objectiveRunning {
+		return m.startNextObjective()
+	}
+	return nil
+}
+
+// cancelObjective drops the selected objective from the run; a running one
+// has its Claude process stopped
+func (m *Model) cancelObjective(o *objective.Objective) {
+	running := o.Status == objective.StatusRunning
+	if err := m.objectives.Cancel(o.ID); err != nil {
+		m.addToast(err.Error(), ToastWarning)
+		return
+	}
+	m.saveObjectives()
+	if running && m.objectiveSession != nil {
+		// The done message records its output
+		m.objectiveSession.Stop()
+	}
+	m.addToast(fmt.Sprintf("Objective #%d cancelled", o.ID), ToastInfo)
+}
+
+// stopObjectives stops a running objective when claude-mon exits, keeping
+// the output it got to
+func (m *Model) stopObjectives() {
+	if m.objectiveSession == nil {
+		return
+	}
+	m.objectiveSession.Stop()
+	if o := m.objectives.Running(); o != nil {
+		m.objectives.Cancel(o.ID)
+		m.objectives.Finish(o.ID, -1, m.objectiveSession.CleanOutput())
+		m.saveObjectives()
+	}
+}
+
+// selectedObjective returns the objective under the cursor, or nil
+func (m *Model) selectedObjective() *objective.Objective {
+	if m.objectives == nil || len(m.objectives.Objectives) == 0 {
+		return nil
+	}
+	m.objectiveIndex = min(max(m.objectiveIndex, 0), len(m.objectives.Objectives)-1)
+	return m.objectives.Objectives[m.objectiveIndex]
+}
+
+// toggleObjectiveView switches the Chat tab between the transcript and the
+// objective queue
+func (m *Model) toggleObjectiveView() {
+	if !m.showObjectives && !m.loadObjectives() {
+		return
+	}
+	m.showObjectives = !m.showObjectives
+	m.updateViewportSize()
+	m.diffViewport.SetContent(m.renderRightPane())
+	if m.showObjectives {
+		m.diffViewport.GotoTop()
+	} else {
+		m.diffViewport.GotoBottom()
+	}
+}
+
+// handleObjectiveKeys handles keys in the queue view: j/k select, J/K
+// reorder pending objectives, a adds one, space starts or pauses the queue
+func (m Model) handleObjectiveKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	o := m.selectedObjective()
+	switch msg.String() {
+	case "o", "esc":
+		m.toggleObjectiveView()
+		return m, nil
+	case "a", "i":
+		m.objectiveTyping = true
+		m.objectiveInput.Focus()
+		return m, textinput.Blink
+	case " ":
+		return m, m.toggleObjectiveRunner()
+	case m.config.Keys.Down, "down":
+		m.objectiveIndex++
+	case m.config.Keys.Up, "up":
+		m.objectiveIndex--
+	case "J":
+		if o != nil && m.objectives.Move(o.ID, 1) {
+			m.objectiveIndex = m.objectives.Index(o.ID)
+			m.saveObjectives()
+		}
+	case "K":
+		if o != nil && m.objectives.Move(o.ID, -1) {
+			m.objectiveIndex = m.objectives.Index(o.ID)
+			m.saveObjectives()
+		}
+	case "x":
+		if o != nil {
+			m.cancelObjective(o)
+		}
+	case "d":
+		if o != nil {
+			if err := m.objectives.Remove(o.ID); err != nil {
+				m.addToast(err.Error(), ToastWarning)
+			} else {
+				m.saveObjectives()
+			}
+		}
+	case "R":
+		if o != nil {
+			if err := m.objectives.Retry(o.ID); err != nil {
+				m.addToast(err.Error(), ToastWarning)
+			} else {
+				m.objectiveIndex = m.objectives.Index(o.ID)
+				m.saveObjectives()
+				m.addToast(fmt.Sprintf("Objective #%d queued again", o.ID), ToastInfo)
+			}
+		}
+	case m.config.Keys.PageDown:
+		m.diffViewport.HalfViewDown()
+		return m, nil
+	case m.config.Keys.PageUp:
+		m.diffViewport.HalfViewUp()
+		return m, nil
+	}
+	m.selectedObjective()
+	m.diffViewport.SetContent(m.renderRightPane())
+	return m, nil
+}
+
+// handleObjectiveInputKeys edits a new objective: enter queues it, esc
+// drops it
+func (m Model) handleObjectiveInputKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.objectiveTyping = false
+		m.objectiveInput.Blur()
+		m.objectiveInput.Reset()
+		return m, nil
+	case "enter":
+		text := strings.TrimSpace(m.objectiveInput.Value())
+		m.objectiveTyping = false
+		m.objectiveInput.Blur()
+		m.objectiveInput.Reset()
+		if text != "" {
+			m.enqueueObjective("", text)
+			m.objectiveIndex = len(m.objectives.Objectives) - 1
+			m.diffViewport.SetContent(m.renderRightPane())
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.objectiveInput, cmd = m.objectiveInput.Update(msg)
+	return m, cmd
+}
+
+// objectiveLabel is the objective's title, or the first line of its prompt
+func objectiveLabel(o *objective.Objective) string {
+	if o.Title != "" {
+		return o.Title
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(o.Prompt), "\n")
+	return line
+}
+
+// renderObjectiveQueue renders the queue with the selected objective's
+// prompt and output below it
+func (m *Model) renderObjectiveQueue() string {
+	var sb strings.Builder
+	width := m.diffViewport.Width - 4
+
+	sb.WriteString(m.theme.Title.Render("Objective Queue") + "  ")
+	switch {
+	case m.objectiveRunning:
+		sb.WriteString(m.theme.Added.Render("▶ running"))
+	case m.objectiveSession != nil:
+		sb.WriteString(m.theme.Modified.Render("⏸ pausing after the current objective"))
+	default:
+		sb.WriteString(m.theme.Dim.Render("⏸ paused"))
+	}
+	if m.objectives != nil {
+		sb.WriteString(m.theme.Dim.Render(fmt.Sprintf("  %d pending", m.objectives.Pending())))
+	}
+	sb.WriteString("\n" + m.theme.Dim.Render(strings.Repeat("─", max(width, 10))) + "\n\n")
+
+	if m.objectives == nil || len(m.objectives.Objectives) == 0 {
+		sb.WriteString(m.theme.Dim.Render("No objectives. Press a to add one, or queue a prompt or plan with the leader key s.") + "\n")
+		return sb.String()
+	}
+
+	for i, o := range m.objectives.Objectives {
+		label := objectiveLabel(o)
+		if len([]rune(label)) > width-32 {
+			label = string([]rune(label)[:max(width-35, 10)]) + "..."
+		}
+		line := fmt.Sprintf("%s #%-3d %-9s %-8s %s", objectiveIcon(o.Status), o.ID, o.Status, objectiveDuration(o), label)
+		if i == m.objectiveIndex {
+			sb.WriteString(m.theme.Selected.Render("▸ "+line) + "\n")
+		} else {
+			sb.WriteString("  " + m.theme.Normal.Render(line) + "\n")
+		}
+	}
+
+	o := m.selectedObjective()
+	sb.WriteString("\n" + m.theme.Dim.Render(strings.Repeat("─", max(width, 10))) + "\n")
+	sb.WriteString(m.theme.Dim.Render(fmt.Sprintf("#%d added %s", o.ID, m.relativeTime(o.AddedAt))))
+	if o.Status == objective.StatusDone || o.Status == objective.StatusFailed {
+		sb.WriteString(m.theme.Dim.Render(fmt.Sprintf("  exit %d", o.ExitCode)))
+	}
+	sb.WriteString("\n\n" + m.theme.Normal.Render(o.Prompt) + "\n\n")
+
+	output := o.Output
+	if o.Status == objective.StatusRunning && m.objectiveSession != nil {
+		output = m.objectiveSession.CleanOutput()
+	}
+	if output != "" {
+		sb.WriteString(m.theme.Title.Render("Output") + "\n" + output + "\n")
+	}
+	return sb.String()
+}
+
+// objectiveIcon marks an objective's status in the queue
+func objectiveIcon(status objective.Status) string {
+	switch status {
+	case objective.StatusRunning:
+		return "▶"
+	case objective.StatusDone:
+		return "✓"
+	case objective.StatusFailed:
+		return "✗"
+	case objective.StatusCancelled:
+		return "⊘"
+	default:
+		return "·"
+	}
+}
+
+// objectiveDuration shows how long an objective ran, blank if it hasn't
+func objectiveDuration(o *objective.Objective) string {
+	d := o.Duration()
+	if d == 0 {
+		return ""
+	}
+	return d.Round(time.Second).String()
+}
+
+// renderObjectiveInput renders the new objective input, or the queue's keys
+func (m Model) renderObjectiveInput() string {
+	if m.objectiveTyping {
+		return "\n" + m.objectiveInput.View()
+	}
+	action := "space: start queue"
+	if m.objectiveRunning {
+		action = "space: pause queue"
+	}
+	return "\n" + m.theme.Dim.Render("a: add  "+action+"  J/K: reorder  x: cancel  R: retry  d: delete  o: back to chat")
+}
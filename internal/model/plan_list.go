@@ -0,0 +1,246 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/internal/plan"
+)
+
+// queryWorkspacePlanCmd asks the daemon which plan this workspace works from
+func (m Model) queryWorkspacePlanCmd() tea.Cmd {
+	return func() tea.Msg {
+		workspacePath, err := m.fs.Getwd()
+		if err != nil {
+			return workspacePlanMsg{err: err}
+		}
+
+		var result struct {
+			PlanPath string `json:"plan_path"`
+			Error    string `json:"error,omitempty"`
+		}
+		if err := queryDaemon(map[string]interface{}{
+			"type":           "workspace_plan",
+			"workspace_path": workspacePath,
+		}, &result); err != nil {
+			return workspacePlanMsg{err: err}
+		}
+		if result.Error != "" {
+			return workspacePlanMsg{err: fmt.Errorf("daemon: %s", result.Error)}
+		}
+		return workspacePlanMsg{path: result.PlanPath}
+	}
+}
+
+// pinPlan makes a plan the workspace's plan, so plans Claude creates for
+// other work don't replace it in the Plan tab. An empty path unpins.
+func (m *Model) pinPlan(path string) {
+	m.planPinned = path
+	if path != "" {
+		m.planPath = path
+	}
+	m.loadPlanFile()
+	m.sendDaemonPayload(map[string]interface{}{
+		"type":      "plan",
+		"plan_path": path,
+	})
+}
+
+// openPlanList lists the plans to switch between, with the filter focused
+func (m *Model) openPlanList() tea.Cmd {
+	m.showPlanList = true
+	m.planListFilter.Reset()
+	m.planListFilter.Focus()
+	m.refreshPlanList()
+	for i, idx := range m.planListMatches {
+		if m.planList[idx].Path == m.planPath {
+			m.planListIndex = i
+		}
+	}
+	return textinput.Blink
+}
+
+// closePlanList goes back to the current plan
+func (m *Model) closePlanList() {
+	m.showPlanList = false
+	m.planListFilter.Blur()
+}
+
+// refreshPlanList re-reads the plan directories and re-applies the filter
+func (m *Model) refreshPlanList() {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	cwd, _ := m.fs.Getwd()
+	plans, err := plan.List(home, cwd, m.planListArchived)
+	if err != nil {
+		m.addToast(err.Error(), ToastError)
+	}
+	m.planList = plans
+	m.filterPlanList()
+}
+
+// filterPlanList matches the plans against the filter, best matches first
+// and otherwise most recent first
+func (m *Model) filterPlanList() {
+	query := strings.TrimSpace(m.planListFilter.Value())
+	scores := make(map[int]int)
+	m.planListMatches = m.planListMatches[:0]
+	for i, p := range m.planList {
+		if score := plan.Match(query, p.Name); score > 0 {
+			scores[i] = score
+			m.planListMatches = append(m.planListMatches, i)
+		}
+	}
+	sort.SliceStable(m.planListMatches, func(a, b int) bool {
+		return scores[m.planListMatches[a]] > scores[m.planListMatches[b]]
+	})
+	if m.planListIndex >= len(m.planListMatches) {
+		m.planListIndex = max(len(m.planListMatches)-1, 0)
+	}
+}
+
+// selectedPlan returns the highlighted plan in the list, or nil
+func (m *Model) selectedPlan() *plan.Info {
+	if len(m.planListMatches) == 0 {
+		return nil
+	}
+	return &m.planList[m.planListMatches[m.planListIndex]]
+}
+
+// handlePlanListKeys handles keys while the plan list is open: typing
+// filters, enter switches to the selected plan
+func (m Model) handlePlanListKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.closePlanList()
+		return m, nil
+	case "up", "ctrl+p":
+		if m.planListIndex > 0 {
+			m.planListIndex--
+		}
+		return m, nil
+	case "down", "ctrl+n":
+		if m.planListIndex < len(m.planListMatches)-1 {
+			m.planListIndex++
+		}
+		return m, nil
+	case "enter":
+		p := m.selectedPlan()
+		if p == nil {
+			return m, nil
+		}
+		if p.Archived {
+			m.addToast("Unarchive the plan first (ctrl+x)", ToastWarning)
+			return m, nil
+		}
+		m.closePlanList()
+		m.pinPlan(p.Path)
+		logger.Log("Switched plan to %s", p.Path)
+		m.addToast("Plan: "+p.Name, ToastSuccess)
+		m.diffViewport.SetContent(m.renderRightPane())
+		m.diffViewport.GotoTop()
+		return m, nil
+	case "ctrl+x":
+		m.toggleArchivePlan()
+		return m, nil
+	case "ctrl+a":
+		m.planListArchived = !m.planListArchived
+		m.refreshPlanList()
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.planListFilter, cmd = m.planListFilter.Update(msg)
+	m.planListIndex = 0
+	m.filterPlanList()
+	return m, cmd
+}
+
+// toggleArchivePlan archives the selected plan, or unarchives it. Archiving
+// the current plan unpins it.
+func (m *Model) toggleArchivePlan() {
+	p := m.selectedPlan()
+	if p == nil {
+		return
+	}
+	if p.Archived {
+		if _, err := plan.Unarchive(p.Path); err != nil {
+			m.addToast(err.Error(), ToastError)
+			return
+		}
+		m.addToast("Unarchived "+p.Name, ToastSuccess)
+	} else {
+		if _, err := plan.Archive(p.Path); err != nil {
+			m.addToast(err.Error(), ToastError)
+			return
+		}
+		if p.Path == m.planPath {
+			m.planPath = ""
+			m.pinPlan("")
+		}
+		m.addToast("Archived "+p.Name, ToastSuccess)
+	}
+	m.refreshPlanList()
+}
+
+// renderPlanPicker renders the plan list for the left pane
+func (m Model) renderPlanPicker(listWidth int) string {
+	var sb strings.Builder
+	title := "Plans"
+	if m.planListArchived {
+		title += " (with archived)"
+	}
+	sb.WriteString(m.theme.Title.Render(title) + "\n")
+	sb.WriteString(m.theme.Dim.Render(strings.Repeat("─", listWidth-4)) + "\n\n")
+	sb.WriteString(m.planListFilter.View() + "\n\n")
+
+	if len(m.planListMatches) == 0 {
+		sb.WriteString(m.theme.Dim.Render("No plans match") + "\n\n")
+	}
+	rows := max(m.height-16, 3)
+	start := max(min(m.planListIndex-rows/2, len(m.planListMatches)-rows), 0)
+	end := min(start+rows, len(m.planListMatches))
+	for i := start; i < end; i++ {
+		p := m.planList[m.planListMatches[i]]
+		marker := " "
+		switch {
+		case p.Path == m.planPinned:
+			marker = "📌"
+		case p.Path == m.planPath:
+			marker = "•"
+		}
+		where := "~"
+		if p.Local {
+			where = "."
+		}
+		if p.Archived {
+			where += " archived"
+		}
+		line := truncateRunes(fmt.Sprintf("%s %s", marker, p.Name), listWidth-20)
+		meta := fmt.Sprintf(" %s %s", where, m.relativeTime(p.ModTime))
+		if i == m.planListIndex {
+			sb.WriteString(m.theme.Selected.Render("▸ "+line) + m.theme.Dim.Render(meta) + "\n")
+		} else if p.Archived {
+			sb.WriteString("  " + m.theme.Dim.Render(line+meta) + "\n")
+		} else {
+			sb.WriteString("  " + m.theme.Normal.Render(line) + m.theme.Dim.Render(meta) + "\n")
+		}
+	}
+	if end < len(m.planListMatches) {
+		sb.WriteString(m.theme.Dim.Render(fmt.Sprintf("  … %d more", len(m.planListMatches)-end)) + "\n")
+	}
+
+	if p := m.selectedPlan(); p != nil {
+		sb.WriteString("\n" + m.theme.Dim.Render(truncateRunes(filepath.Dir(p.Path), listWidth-4)) + "\n")
+	}
+	sb.WriteString("\n" + m.theme.Dim.Render("enter:switch  ctrl+x:archive  ctrl+a:archived  esc:close"))
+	return sb.String()
+}
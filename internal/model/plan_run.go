@@ -0,0 +1,151 @@
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/chat"
+	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/internal/prompt"
+	"github.com/ztaylor/claude-mon/internal/ralph"
+)
+
+// A Ralph loop started from a plan runs until Claude outputs the promise or
+// reaches the limit; both can be changed from the Ralph tab while it runs
+const (
+	ralphPlanMaxIterations = 20
+	ralphPlanPromise       = "PLAN COMPLETE"
+)
+
+// ralphRunOutputLines is how much of a plan run's output the Ralph tab shows
+const ralphRunOutputLines = 15
+
+// waitForRalphRun waits for the plan run's next output or for Claude to exit
+func waitForRalphRun(c *chat.ClaudeChat) tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case <-c.OutputChan():
+			return ralphRunOutputMsg{session: c}
+		case <-c.DoneChan():
+			return ralphRunDoneMsg{session: c}
+		}
+	}
+}
+
+// runPlan carries out the current plan, as a queued objective or as a Ralph
+// loop. Variables declared in the plan's frontmatter are asked for first, and
+// built-in ones like {{project}} are expanded.
+func (m *Model) runPlan(action int) tea.Cmd {
+	if m.planPath == "" || strings.TrimSpace(m.planContent) == "" {
+		m.addToast("No plan to run", ToastWarning)
+		return nil
+	}
+	p, err := prompt.Parse(m.planContent)
+	if err != nil {
+		m.addToast(err.Error(), ToastError)
+		return nil
+	}
+	p.Name = filepath.Base(m.planPath)
+	p.Content = "Implement this plan:\n\n" + p.Content
+	if action == promptVarsRalph {
+		p.Content += fmt.Sprintf("\n\nWhen every task in the plan is done and verified, output <promise>%s</promise>.", ralphPlanPromise)
+	}
+	return m.usePrompt(*p, action)
+}
+
+// startRalphRun starts a Ralph loop with the prompt and runs Claude on it in
+// objective mode, streaming its output into the Ralph tab
+func (m *Model) startRalphRun(title, loopPrompt string) tea.Cmd {
+	if m.ralphRun != nil {
+		m.addToast("A plan is already running as a Ralph loop", ToastWarning)
+		return nil
+	}
+	state, err := ralph.Start(loopPrompt, ralphPlanMaxIterations, ralphPlanPromise)
+	if err != nil {
+		m.addToast(err.Error(), ToastError)
+		return nil
+	}
+
+	c := chat.New()
+	c.SetPurpose(chat.ContextRalph)
+	if err := c.StartWithObjective(loopPrompt, ""); err != nil {
+		ralph.CancelLoop()
+		m.addToast("Failed to start Claude: "+err.Error(), ToastError)
+		return nil
+	}
+	m.ralphRun = c
+	m.ralphRunTitle = title
+	m.ralphRunOutput = ""
+	m.ralphState = state
+	m.ralphTimeline = nil
+	logger.Log("Ralph loop started from %s", title)
+	m.reportDaemonEvent("ralph_start", "info", fmt.Sprintf("Ralph loop started from %s (max %d iterations)", title, ralphPlanMaxIterations))
+	m.addToast("Ralph loop started; follow it in the Ralph tab", ToastSuccess)
+	return waitForRalphRun(c)
+}
+
+// finishRalphRun handles Claude exiting. The stop hook only lets it exit
+// once the loop is over or paused; a loop still running means Claude exited
+// on its own (or the ralph-loop plugin isn't installed), so it's stopped.
+func (m *Model) finishRalphRun(c *chat.ClaudeChat) {
+	m.ralphRun = nil
+	m.ralphRunOutput = c.CleanOutput()
+	logger.Log("Ralph run of %s exited (%d)", m.ralphRunTitle, c.ExitCode())
+
+	m.loadRalphState()
+	switch {
+	case m.ralphState == nil || !m.ralphState.Active:
+		// loadRalphState recorded how the loop ended
+	case m.ralphState.Paused:
+		m.addToast("Claude stopped with the loop paused; resume it from a Claude session", ToastInfo)
+	default:
+		prev := m.ralphState
+		if _, err := ralph.CancelLoop(); err != nil {
+			logger.Log("Failed to stop Ralph loop: %v", err)
+		}
+		m.ralphState = nil
+		m.recordRalphLoop(prev, ralph.OutcomeStopped)
+		m.reportDaemonEvent("ralph_stop", "warning", fmt.Sprintf("Claude exited (%d) during Ralph loop iteration %d", c.ExitCode(), prev.Iteration))
+		m.addToast(fmt.Sprintf("Claude exited (%d) before the loop finished; is the ralph-loop plugin installed?", c.ExitCode()), ToastWarning)
+	}
+	if m.leftPaneMode == LeftPaneModeRalph {
+		m.diffViewport.SetContent(m.renderRightPane())
+	}
+}
+
+// stopRalphRun stops a plan's Claude process when claude-mon exits. The
+// loop's state file is left for the next Claude session to pick up.
+func (m *Model) stopRalphRun() {
+	if m.ralphRun != nil {
+		m.ralphRun.Stop()
+	}
+}
+
+// renderRalphRun renders the tail of the running (or last) plan run's output
+func (m *Model) renderRalphRun() string {
+	output := m.ralphRunOutput
+	status := "finished"
+	if m.ralphRun != nil {
+		output = m.ralphRun.CleanOutput()
+		status = "running"
+	}
+	if m.ralphRunTitle == "" {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(m.theme.Title.Render("Plan Run") + m.theme.Dim.Render(fmt.Sprintf("  %s, %s", m.ralphRunTitle, status)) + "\n")
+	sb.WriteString(m.theme.Dim.Render(strings.Repeat("─", m.width-4)) + "\n\n")
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
+	if len(lines) > ralphRunOutputLines {
+		lines = lines[len(lines)-ralphRunOutputLines:]
+	}
+	if strings.TrimSpace(output) == "" {
+		sb.WriteString(m.theme.Dim.Render("Waiting for Claude...") + "\n\n")
+	} else {
+		sb.WriteString(m.theme.Normal.Render(strings.Join(lines, "\n")) + "\n\n")
+	}
+	return sb.String()
+}
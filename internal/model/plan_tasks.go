@@ -0,0 +1,173 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/internal/plan"
+)
+
+// updatePlanTasks re-parses the plan's checklist after it's read. Tasks that
+// became done since the last read are stamped with the plan's modification
+// time, which is when they were checked off.
+func (m *Model) updatePlanTasks() {
+	tasks := plan.ParseTasks(m.planContent)
+	if m.planPath != m.planTasksPath || m.planTaskDoneAt == nil {
+		// A different plan: start tracking completions from now
+		m.planTasksPath = m.planPath
+		m.planTaskDoneAt = make(map[string]time.Time)
+		m.planTrackedAt = m.clock.Now()
+		m.planTaskIndex = 0
+		m.planTasks = tasks
+		return
+	}
+
+	doneAt := m.clock.Now()
+	if info, err := m.fs.Stat(m.planPath); err == nil {
+		doneAt = info.ModTime()
+	}
+	wasDone := make(map[string]bool)
+	for _, t := range m.planTasks {
+		wasDone[t.Text] = t.Done
+	}
+	for _, t := range tasks {
+		switch {
+		case t.Done && !wasDone[t.Text]:
+			m.planTaskDoneAt[t.Text] = doneAt
+			logger.Log("Plan task done: %s", t.Text)
+		case !t.Done:
+			delete(m.planTaskDoneAt, t.Text)
+		}
+	}
+	m.planTasks = tasks
+	if m.planTaskIndex >= len(tasks) {
+		m.planTaskIndex = max(len(tasks)-1, 0)
+	}
+}
+
+// togglePlanTask checks or unchecks the selected task in the plan file
+func (m *Model) togglePlanTask() {
+	if len(m.planTasks) == 0 {
+		m.addToast("No tasks in this plan", ToastWarning)
+		return
+	}
+	task, err := plan.ToggleTask(m.planPath, m.planTasks[m.planTaskIndex])
+	if err != nil {
+		m.addToast(err.Error(), ToastError)
+		return
+	}
+	m.loadPlanFile()
+	if task.Done {
+		m.addToast("Done: "+task.Text, ToastSuccess)
+	} else {
+		m.addToast("Not done: "+task.Text, ToastInfo)
+	}
+	m.diffViewport.SetContent(m.renderRightPane())
+}
+
+// planTaskEdits returns the changes made while a task was being worked on:
+// those after the previous task was checked off (or tracking began) up to
+// when this one was. Nil for tasks claude-mon didn't see completed.
+func (m *Model) planTaskEdits(task plan.Task) []Change {
+	doneAt, ok := m.planTaskDoneAt[task.Text]
+	if !ok {
+		return nil
+	}
+	since := m.planTrackedAt
+	for text, t := range m.planTaskDoneAt {
+		if text != task.Text && t.Before(doneAt) && t.After(since) {
+			since = t
+		}
+	}
+
+	var edits []Change
+	for _, c := range m.changes {
+		if c.FilePath == m.planPath || !c.Timestamp.After(since) || c.Timestamp.After(doneAt) {
+			continue
+		}
+		edits = append(edits, c)
+	}
+	return edits
+}
+
+// renderPlanProgress renders a progress bar for the plan's checklist
+func (m *Model) renderPlanProgress(width int) string {
+	done, total := plan.Progress(m.planTasks)
+	if total == 0 {
+		return m.theme.Dim.Render("No tasks")
+	}
+	label := fmt.Sprintf(" %d/%d (%d%%)", done, total, done*100/total)
+	barWidth := max(width-len(label), 5)
+	filled := barWidth * done / total
+	return m.theme.Selected.Render(strings.Repeat("█", filled)) +
+		m.theme.Dim.Render(strings.Repeat("░", barWidth-filled)) +
+		m.theme.Normal.Render(label)
+}
+
+// renderPlanTasks renders the checklist for the left pane, scrolled to keep
+// the selected task in view, followed by the edits made for it
+func (m Model) renderPlanTasks(width, rows int) string {
+	var sb strings.Builder
+	sb.WriteString(m.renderPlanProgress(width) + "\n\n")
+	if len(m.planTasks) == 0 {
+		sb.WriteString(m.theme.Dim.Render("No - [ ] tasks in this plan") + "\n\n")
+		return sb.String()
+	}
+
+	rows = max(rows, 3)
+	start := max(min(m.planTaskIndex-rows/2, len(m.planTasks)-rows), 0)
+	end := min(start+rows, len(m.planTasks))
+	section := ""
+	for i := start; i < end; i++ {
+		t := m.planTasks[i]
+		if t.Section != section {
+			section = t.Section
+			sb.WriteString(m.theme.Dim.Render(truncateRunes(section, width)) + "\n")
+		}
+		box := "[ ]"
+		if t.Done {
+			box = "[✓]"
+		}
+		line := truncateRunes(strings.Repeat("  ", t.Depth)+box+" "+t.Text, width-2)
+		switch {
+		case i == m.planTaskIndex && m.activePane == PaneLeft:
+			sb.WriteString(m.theme.Selected.Render("▸ "+line) + "\n")
+		case t.Done:
+			sb.WriteString("  " + m.theme.Dim.Render(line) + "\n")
+		default:
+			sb.WriteString("  " + m.theme.Normal.Render(line) + "\n")
+		}
+	}
+	if end < len(m.planTasks) {
+		sb.WriteString(m.theme.Dim.Render(fmt.Sprintf("  … %d more", len(m.planTasks)-end)) + "\n")
+	}
+	sb.WriteString("\n")
+
+	// Edits made for the selected task, once it's checked off
+	task := m.planTasks[m.planTaskIndex]
+	if doneAt, ok := m.planTaskDoneAt[task.Text]; ok {
+		edits := m.planTaskEdits(task)
+		sb.WriteString(m.theme.Dim.Render(fmt.Sprintf("Done %s, %d edits", m.relativeTime(doneAt), len(edits))) + "\n")
+		seen := make(map[string]bool)
+		for _, c := range edits {
+			if seen[c.FilePath] {
+				continue
+			}
+			seen[c.FilePath] = true
+			sb.WriteString("  " + m.theme.Normal.Render(truncateRunes(m.relativePath(c.FilePath), width-2)) + "\n")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// truncateRunes shortens s to at most n runes, ending in "…" when cut
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if n < 1 || len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
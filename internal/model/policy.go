@@ -0,0 +1,155 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/internal/policy"
+	"github.com/ztaylor/claude-mon/internal/socket"
+)
+
+// loadPolicy returns the workspace's policy, nil if it has none. The file
+// is only read again once its modification time changes.
+func (m *Model) loadPolicy(ws string) *policy.Policy {
+	path := filepath.Join(ws, policy.FileName)
+	info, err := m.fs.Stat(path)
+	if err != nil {
+		m.policy, m.policyPath, m.policyModTime = nil, "", time.Time{}
+		return nil
+	}
+	if path == m.policyPath && info.ModTime().Equal(m.policyModTime) {
+		return m.policy
+	}
+	m.policy, m.policyPath, m.policyModTime = nil, path, info.ModTime()
+	file, err := m.fs.ReadFile(path)
+	if err != nil {
+		logger.Log("Policy not applied: %v", err)
+		return nil
+	}
+	p, err := policy.Parse(file)
+	if err != nil {
+		logger.Log("Policy not applied: %v", err)
+		return nil
+	}
+	m.policy = p
+	return p
+}
+
+// enforcePolicy checks a new change against the project's policy file,
+// marking edits to protected files. The violation is announced right away,
+// or once the change is undone in the background if the policy says to
+// revert it.
+func (m *Model) enforcePolicy(change *Change, data []byte) tea.Cmd {
+	ws, err := m.fs.Getwd()
+	if err != nil {
+		return nil
+	}
+	p := m.loadPolicy(ws)
+	if p == nil {
+		return nil
+	}
+	pattern := p.Protects(ws, change.FilePath)
+	if pattern == "" {
+		return nil
+	}
+	change.Protected = pattern
+
+	message := fmt.Sprintf("%s of %s, protected by %s", change.ToolName, m.relativePath(change.FilePath), pattern)
+	if !p.AutoRevert {
+		m.announcePolicyViolation(message)
+		return nil
+	}
+	fsys, reverting := m.fs, *change
+	return func() tea.Msg {
+		return policyRevertedMsg{key: driftKey(reverting), message: message, err: revertChange(fsys, &reverting, data)}
+	}
+}
+
+// applyPolicyRevert marks a change undone by the policy and announces the
+// violation with the revert's outcome
+func (m *Model) applyPolicyRevert(msg policyRevertedMsg) tea.Cmd {
+	message := msg.message
+	if msg.err != nil {
+		message += "; not reverted: " + msg.err.Error()
+		m.announcePolicyViolation(message)
+		return nil
+	}
+	message += "; reverted"
+	m.announcePolicyViolation(message)
+	for i := range m.changes {
+		if driftKey(m.changes[i]) == msg.key {
+			m.changes[i].Reverted = true
+			delete(m.diffCache, i)
+			if i == m.selectedIndex {
+				return m.showDiff()
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// announcePolicyViolation logs, toasts and reports a policy violation
+func (m *Model) announcePolicyViolation(message string) {
+	logger.Log("Policy violation: %s", message)
+	m.addToast("Policy: "+message, ToastError)
+	m.reportDaemonEvent("policy_violation", "error", message)
+}
+
+// revertChange undoes a change on disk from its hook payload, all of a
+// MultiEdit's edits included. A Write replaced the whole file, so its
+// previous content isn't in the payload.
+func revertChange(fsys FS, change *Change, data []byte) error {
+	if change.ToolName == "Write" {
+		return fmt.Errorf("a Write can't be undone from the edit; restore the file's snapshot with Ctrl+G r")
+	}
+	edits := []policy.Edit{{OldString: change.OldString, NewString: change.NewString}}
+	var payload HookPayload
+	if json.Unmarshal(data, &payload) == nil && len(payload.ToolInput.Edits) > 0 {
+		edits = nil
+		for _, e := range payload.ToolInput.Edits {
+			edits = append(edits, policy.Edit{OldString: e.OldString, NewString: e.NewString})
+		}
+	}
+	// A string cut short on the socket would leave its marker in the file
+	truncated := change.Truncated
+	for _, e := range edits {
+		truncated = truncated || socket.IsTruncated(e.OldString) || socket.IsTruncated(e.NewString)
+	}
+	if truncated {
+		return fmt.Errorf("the edit was too large to keep whole; restore the file's snapshot with Ctrl+G r")
+	}
+	return policy.Revert(fsys, change.FilePath, edits)
+}
+
+// hasPolicyBadges reports whether any change broke the policy, reserving
+// the history list's badge column
+func (m Model) hasPolicyBadges() bool {
+	for _, c := range m.changes {
+		if c.Protected != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// renderPolicyBadge marks a change to a protected file in the history list
+func (m Model) renderPolicyBadge(change Change) string {
+	if change.Protected == "" {
+		return " "
+	}
+	return m.theme.Removed.Bold(true).Render("!")
+}
+
+// renderPolicyHeader describes the policy a change broke, for its diff
+func (m Model) renderPolicyHeader(change Change) string {
+	line := "! Protected by " + policy.FileName + " (" + change.Protected + ")"
+	if change.Reverted {
+		line += ", reverted"
+	}
+	return m.theme.Removed.Bold(true).Render(line)
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ztaylor/claude-mon/internal/diff"
+	"github.com/ztaylor/claude-mon/internal/prompt"
+)
+
+// readPromptBody returns the prompt text of a prompt or version file,
+// without its frontmatter
+func readPromptBody(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	p, err := prompt.Parse(string(data))
+	if err != nil {
+		return string(data), nil
+	}
+	return p.Content, nil
+}
+
+// togglePromptVersionBase marks the selected version as the one to diff
+// against, or clears the mark if it's already set on it
+func (m *Model) togglePromptVersionBase() {
+	if len(m.promptVersions) == 0 {
+		return
+	}
+	v := m.promptVersions[m.promptVersionSelected].Version
+	if m.promptVersionBase == v {
+		m.promptVersionBase = 0
+		m.addToast("Comparing with the current prompt", ToastInfo)
+		return
+	}
+	m.promptVersionBase = v
+	m.promptVersionDiff = true
+	m.addToast(fmt.Sprintf("Comparing against v%d", v), ToastInfo)
+}
+
+// renderPromptVersionDiff renders the changes from the marked version to the
+// selected one, or from the selected version to the current prompt when no
+// version is marked
+func (m *Model) renderPromptVersionDiff() string {
+	if len(m.promptFilteredList) == 0 {
+		return m.theme.Dim.Render("No prompt selected")
+	}
+	v := m.promptVersions[m.promptVersionSelected]
+	p := m.promptFilteredList[m.promptSelected]
+
+	oldPath, newPath := v.Path, p.Path
+	oldLabel, newLabel := fmt.Sprintf("v%d", v.Version), "current"
+	if m.promptVersionBase != 0 && m.promptVersionBase != v.Version {
+		for _, base := range m.promptVersions {
+			if base.Version == m.promptVersionBase {
+				oldPath, newPath = base.Path, v.Path
+				oldLabel, newLabel = fmt.Sprintf("v%d", base.Version), fmt.Sprintf("v%d", v.Version)
+			}
+		}
+	}
+
+	oldText, err := readPromptBody(oldPath)
+	if err != nil {
+		return m.theme.Dim.Render("Failed to read " + oldLabel + ": " + err.Error())
+	}
+	newText, err := readPromptBody(newPath)
+	if err != nil {
+		return m.theme.Dim.Render("Failed to read " + newLabel + ": " + err.Error())
+	}
+
+	var sb strings.Builder
+	sb.WriteString(m.theme.Title.Render(fmt.Sprintf("Diff %s → %s", oldLabel, newLabel)) + "\n")
+	sb.WriteString(m.theme.Dim.Render(strings.Repeat("─", 40)) + "\n\n")
+	if oldText == newText {
+		sb.WriteString(m.theme.Dim.Render("No changes"))
+		return sb.String()
+	}
+	sb.WriteString(diff.FormatDiff(oldText, newText, m.theme, diff.DefaultOptions()))
+	return sb.String()
+}
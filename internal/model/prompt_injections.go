@@ -0,0 +1,197 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/auth"
+	"github.com/ztaylor/claude-mon/internal/prompt"
+)
+
+// promptInjectionPreviewCount is how many past injections the prompt preview lists
+const promptInjectionPreviewCount = 5
+
+// PromptInjection is a past send of a prompt, as recorded by the daemon
+type PromptInjection struct {
+	PromptName    string    `json:"prompt_name"`
+	PromptVersion int       `json:"prompt_version"`
+	WorkspacePath string    `json:"workspace_path"`
+	Method        string    `json:"method"`
+	Target        string    `json:"target,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// PromptStat is a prompt's all-time usage, as counted by the daemon
+type PromptStat struct {
+	PromptName string    `json:"prompt_name"`
+	Uses       int       `json:"uses"`
+	LastUsed   time.Time `json:"last_used"`
+}
+
+// queryPromptInjectionsCmd queries the daemon for recent prompt injections
+// across all workspaces
+func (m Model) queryPromptInjectionsCmd() tea.Cmd {
+	return func() tea.Msg {
+		conn, err := net.DialTimeout("unix", "/tmp/claude-mon-query.sock", 1*time.Second)
+		if err != nil {
+			return promptInjectionsMsg{err: err}
+		}
+		defer conn.Close()
+
+		conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+		query := map[string]interface{}{
+			"type":  "injections",
+			"limit": 200,
+			"token": auth.FromEnv(),
+		}
+		if err := json.NewEncoder(conn).Encode(query); err != nil {
+			return promptInjectionsMsg{err: err}
+		}
+
+		var result struct {
+			Injections []PromptInjection `json:"injections"`
+			Error      string            `json:"error,omitempty"`
+		}
+		if err := json.NewDecoder(conn).Decode(&result); err != nil {
+			return promptInjectionsMsg{err: err}
+		}
+		if result.Error != "" {
+			return promptInjectionsMsg{err: fmt.Errorf("daemon: %s", result.Error)}
+		}
+
+		return promptInjectionsMsg{injections: result.Injections}
+	}
+}
+
+// queryPromptStatsCmd queries the daemon for how often each prompt was sent
+func (m Model) queryPromptStatsCmd() tea.Cmd {
+	return func() tea.Msg {
+		conn, err := net.DialTimeout("unix", "/tmp/claude-mon-query.sock", 1*time.Second)
+		if err != nil {
+			return promptStatsMsg{err: err}
+		}
+		defer conn.Close()
+
+		conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+		query := map[string]interface{}{
+			"type":  "prompt_stats",
+			"limit": 1000,
+			"token": auth.FromEnv(),
+		}
+		if err := json.NewEncoder(conn).Encode(query); err != nil {
+			return promptStatsMsg{err: err}
+		}
+
+		var result struct {
+			PromptStats []PromptStat `json:"prompt_stats"`
+			Error       string       `json:"error,omitempty"`
+		}
+		if err := json.NewDecoder(conn).Decode(&result); err != nil {
+			return promptStatsMsg{err: err}
+		}
+		if result.Error != "" {
+			return promptStatsMsg{err: fmt.Errorf("daemon: %s", result.Error)}
+		}
+
+		return promptStatsMsg{stats: result.PromptStats}
+	}
+}
+
+// recordPromptInjection notes a successful send locally, so the preview
+// updates immediately, and reports it to the daemon
+func (m *Model) recordPromptInjection(name string, version int, target string) {
+	method := prompt.MethodName(m.promptInjectMethod)
+	workspacePath, _ := m.fs.Getwd()
+
+	m.promptInjections = append([]PromptInjection{{
+		PromptName:    name,
+		PromptVersion: version,
+		WorkspacePath: workspacePath,
+		Method:        method,
+		Target:        target,
+		Timestamp:     m.clock.Now(),
+	}}, m.promptInjections...)
+
+	stat := m.promptStats[name]
+	stat.PromptName = name
+	stat.Uses++
+	stat.LastUsed = m.clock.Now()
+	m.promptStats[name] = stat
+
+	m.sendDaemonPayload(map[string]interface{}{
+		"type":           "prompt_injection",
+		"prompt_name":    name,
+		"prompt_version": version,
+		"inject_method":  method,
+		"inject_target":  target,
+	})
+}
+
+// injectionsOf returns the most recent injections of a prompt, newest first
+func (m Model) injectionsOf(name string) []PromptInjection {
+	var injections []PromptInjection
+	for _, inj := range m.promptInjections {
+		if inj.PromptName == name {
+			injections = append(injections, inj)
+		}
+	}
+	return injections
+}
+
+// renderPromptInjections renders the last few injections of a prompt for the
+// preview header, marking ones sent into this workspace since startup
+func (m Model) renderPromptInjections(name string) string {
+	injections := m.injectionsOf(name)
+	if len(injections) == 0 {
+		return m.theme.Dim.Render("Never sent") + "\n"
+	}
+
+	// The injection list only goes back so far; the daemon counts all sends
+	uses := len(injections)
+	if stat, ok := m.promptStats[name]; ok && stat.Uses > uses {
+		uses = stat.Uses
+	}
+
+	cwd, _ := m.fs.Getwd()
+	var sb strings.Builder
+	sb.WriteString(m.theme.Dim.Render(fmt.Sprintf("Sent %d time(s):", uses)) + "\n")
+	if len(injections) > promptInjectionPreviewCount {
+		injections = injections[:promptInjectionPreviewCount]
+	}
+	for _, inj := range injections {
+		where := filepath.Base(inj.WorkspacePath)
+		if inj.Target != "" {
+			where += " → " + inj.Method + " " + inj.Target
+		} else {
+			where += " → " + inj.Method
+		}
+		line := fmt.Sprintf("  %s  %s  v%d", formatInjectionTime(inj.Timestamp, m.clock.Now()), where, inj.PromptVersion)
+
+		if inj.WorkspacePath == cwd && !inj.Timestamp.Before(m.startedAt) {
+			sb.WriteString(m.theme.Modified.Render(line+"  (this session)") + "\n")
+		} else {
+			sb.WriteString(m.theme.Dim.Render(line) + "\n")
+		}
+	}
+	if more := uses - len(injections); more > 0 {
+		sb.WriteString(m.theme.Dim.Render(fmt.Sprintf("  ... %d more", more)) + "\n")
+	}
+	return sb.String()
+}
+
+// formatInjectionTime formats a send time in now's time zone as a clock
+// time today, or a date and time for older sends
+func formatInjectionTime(t, now time.Time) string {
+	t = t.In(now.Location())
+	if t.Year() == now.Year() && t.YearDay() == now.YearDay() {
+		return t.Format("15:04")
+	}
+	return t.Format("Jan 2 15:04")
+}
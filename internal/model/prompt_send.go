@@ -0,0 +1,138 @@
+package model
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/internal/prompt"
+)
+
+// pendingPromptSend is an expanded prompt waiting for send confirmation
+type pendingPromptSend struct {
+	name     string
+	version  int
+	content  string
+	target   string // Resolved tmux pane, for the tmux method
+	estimate prompt.Estimate
+	feedback bool // Feedback on changes rather than a library prompt
+}
+
+// confirmSendPrompt expands the selected prompt and asks for confirmation,
+// showing its estimated size and cost in the status line. Prompts with
+// user-defined variables ask for their values first.
+func (m *Model) confirmSendPrompt() {
+	if len(m.promptFilteredList) == 0 {
+		return
+	}
+	m.usePrompt(m.promptFilteredList[m.promptSelected], promptVarsSend)
+}
+
+// queuePromptSend holds an expanded prompt for send confirmation. filled is
+// the prompt with its user-defined variables substituted, before expansion.
+func (m *Model) queuePromptSend(p prompt.Prompt, filled, expanded string) {
+	target, ok := m.resolveSendTarget()
+	if !ok {
+		return
+	}
+	m.promptSendPending = &pendingPromptSend{
+		name:     p.Name,
+		version:  p.Version,
+		content:  expanded,
+		target:   target,
+		estimate: prompt.EstimatePrompt(filled, expanded),
+	}
+	logger.Log("Confirm prompt send: %s, %s", p.Name, m.promptSendPending.estimate)
+}
+
+// resolveSendTarget falls back from chat if its session has ended and
+// resolves the tmux pane for the tmux method, reporting false with an error
+// toast if there isn't one
+func (m *Model) resolveSendTarget() (string, bool) {
+	if m.promptInjectMethod == prompt.InjectChat && !m.chatAvailable() {
+		m.promptInjectMethod = prompt.DetectBestMethod()
+		m.addToast("Chat session ended; sending via "+prompt.MethodName(m.promptInjectMethod), ToastWarning)
+	}
+	if m.promptInjectMethod != prompt.InjectTmux {
+		return "", true
+	}
+	target, err := prompt.ResolveTmuxTarget(m.promptTmuxTarget)
+	if err != nil {
+		m.addToast(err.Error(), ToastError)
+		return "", false
+	}
+	return target, true
+}
+
+// handlePromptSendKeys handles the send confirmation: y/enter sends, any
+// other key cancels
+func (m Model) handlePromptSendKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	pending := m.promptSendPending
+	m.promptSendPending = nil
+
+	switch msg.String() {
+	case "y", "enter":
+		logger.Log("Injecting prompt: %s, expanded=%d bytes", pending.name, len(pending.content))
+		var err error
+		if m.promptInjectMethod == prompt.InjectChat && m.chatAvailable() {
+			if err = m.chatSession.Inject(pending.content); err == nil {
+				m.saveChat(false)
+			}
+		} else {
+			err = prompt.Inject(pending.content, m.promptInjectMethod, pending.target)
+		}
+		if err != nil {
+			m.addToast(err.Error(), ToastError)
+		} else {
+			m.addToast(fmt.Sprintf("Sent via %s", m.sendDestination(pending)), ToastSuccess)
+			if !pending.feedback {
+				m.recordPromptInjection(pending.name, pending.version, pending.target)
+			}
+		}
+	default:
+		m.addToast("Send cancelled", ToastInfo)
+	}
+	return m, nil
+}
+
+// renderPromptSendConfirm renders the status line for a pending prompt send
+func (m Model) renderPromptSendConfirm() string {
+	pending := m.promptSendPending
+	line := fmt.Sprintf("Send %q via %s? %s  y/Enter:send  Esc:cancel",
+		pending.name, m.sendDestination(pending), pending.estimate)
+	if pending.estimate.Large() {
+		return m.theme.Status.Inherit(m.theme.Removed).Render("⚠ " + line)
+	}
+	return m.theme.Status.Render(line)
+}
+
+// sendDestination describes where a pending send goes, e.g. "tmux main:1.0"
+func (m Model) sendDestination(pending *pendingPromptSend) string {
+	if pending.target != "" {
+		return prompt.MethodName(m.promptInjectMethod) + " " + pending.target
+	}
+	return prompt.MethodName(m.promptInjectMethod)
+}
+
+// chatAvailable reports whether a managed chat session is running that
+// prompts can be written into directly
+func (m Model) chatAvailable() bool {
+	return m.chatSession != nil && m.chatSession.IsActive()
+}
+
+// cycleInjectMethod moves to the next injection method, offering chat only
+// while a managed chat session is running
+func (m *Model) cycleInjectMethod() {
+	methods := []prompt.InjectionMethod{prompt.InjectTmux, prompt.InjectClipboard}
+	if m.chatAvailable() {
+		methods = append(methods, prompt.InjectChat)
+	}
+
+	next := methods[0]
+	for i, method := range methods {
+		if method == m.promptInjectMethod {
+			next = methods[(i+1)%len(methods)]
+		}
+	}
+	m.promptInjectMethod = next
+}
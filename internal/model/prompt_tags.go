@@ -0,0 +1,165 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ztaylor/claude-mon/internal/prompt"
+)
+
+// PromptSort is the order of the prompt list
+type PromptSort int
+
+const (
+	PromptSortUpdated PromptSort = iota // Most recently updated first
+	PromptSortName                      // Alphabetical
+	PromptSortUsage                     // Most sent first
+)
+
+// String returns the sort order's name for the list header and toasts
+func (s PromptSort) String() string {
+	switch s {
+	case PromptSortName:
+		return "name"
+	case PromptSortUsage:
+		return "usage"
+	default:
+		return "updated"
+	}
+}
+
+// promptTags returns every tag used by a prompt, sorted
+func (m Model) promptTags() []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, p := range m.promptList {
+		for _, t := range p.Tags {
+			if !seen[t] {
+				seen[t] = true
+				tags = append(tags, t)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// selectedPromptTags returns the tags the prompt list is filtered by, sorted
+func (m Model) selectedPromptTags() []string {
+	var tags []string
+	for t := range m.promptTagFilter {
+		tags = append(tags, t)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// matchesPromptTags reports whether a prompt carries every selected tag
+func (m Model) matchesPromptTags(p prompt.Prompt) bool {
+	for t := range m.promptTagFilter {
+		found := false
+		for _, pt := range p.Tags {
+			if pt == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// sortPrompts orders prompts by the current sort, keeping the store's
+// newest-first order for ties
+func (m Model) sortPrompts(prompts []prompt.Prompt) {
+	switch m.promptSort {
+	case PromptSortName:
+		sort.SliceStable(prompts, func(i, j int) bool {
+			return strings.ToLower(prompts[i].Name) < strings.ToLower(prompts[j].Name)
+		})
+	case PromptSortUsage:
+		sort.SliceStable(prompts, func(i, j int) bool {
+			return m.promptStats[prompts[i].Name].Uses > m.promptStats[prompts[j].Name].Uses
+		})
+	}
+}
+
+// openPromptTagPicker shows the tag filter over the prompt list
+func (m *Model) openPromptTagPicker() {
+	if len(m.promptTags()) == 0 {
+		m.addToast("No tagged prompts", ToastInfo)
+		return
+	}
+	m.showPromptTagPicker = true
+	m.promptTagIndex = 0
+}
+
+// handlePromptTagPickerKey moves through the tags and toggles them in the
+// filter, which applies as tags are toggled. Enter or esc closes the picker.
+func (m *Model) handlePromptTagPickerKey(key string) {
+	tags := m.promptTags()
+	if len(tags) == 0 {
+		m.showPromptTagPicker = false
+		return
+	}
+	if m.promptTagIndex >= len(tags) {
+		m.promptTagIndex = len(tags) - 1
+	}
+
+	switch key {
+	case "j", "down":
+		m.promptTagIndex = (m.promptTagIndex + 1) % len(tags)
+		return
+	case "k", "up":
+		m.promptTagIndex = (m.promptTagIndex - 1 + len(tags)) % len(tags)
+		return
+	case " ", "x":
+		tag := tags[m.promptTagIndex]
+		if m.promptTagFilter[tag] {
+			delete(m.promptTagFilter, tag)
+		} else {
+			m.promptTagFilter[tag] = true
+		}
+	case "c":
+		m.promptTagFilter = make(map[string]bool)
+	case "enter", "esc", "q", "t":
+		m.showPromptTagPicker = false
+	default:
+		return
+	}
+	m.applyPromptFilter()
+	m.diffViewport.SetContent(m.renderRightPane())
+}
+
+// renderPromptTagPicker renders the tag list with the selected tags checked
+func (m Model) renderPromptTagPicker() string {
+	counts := make(map[string]int)
+	for _, p := range m.promptList {
+		for _, t := range p.Tags {
+			counts[t]++
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("\n  " + m.theme.Title.Render("Filter prompts by tag") + "\n\n")
+	for i, tag := range m.promptTags() {
+		check := "[ ]"
+		if m.promptTagFilter[tag] {
+			check = "[x]"
+		}
+		line := check + " " + tag
+		count := m.theme.Dim.Render(fmt.Sprintf(" (%d)", counts[tag]))
+		if i == m.promptTagIndex {
+			sb.WriteString("  " + m.theme.Selected.Render("▸ "+line) + count + "\n")
+		} else {
+			sb.WriteString("    " + m.theme.Normal.Render(line) + count + "\n")
+		}
+	}
+
+	sb.WriteString("\n  " + m.theme.Dim.Render(fmt.Sprintf("%d matching prompts", len(m.promptFilteredList))) + "\n")
+	sb.WriteString("\n  " + m.theme.Dim.Render("j/k: move  space: toggle  c: clear  enter: done") + "\n")
+	return sb.String()
+}
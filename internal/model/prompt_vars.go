@@ -0,0 +1,209 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/prompt"
+)
+
+// What happens to a prompt once its variables are filled in
+const (
+	promptVarsSend  = iota // Confirm and send
+	promptVarsYank         // Copy to the clipboard
+	promptVarsQueue        // Add to the objective queue
+	promptVarsRalph        // Run as a Ralph loop
+)
+
+// promptVarForm asks for a prompt's user-defined variables before it's used.
+// Variables with choices are picked with ←/→; the rest are free text.
+type promptVarForm struct {
+	prompt prompt.Prompt
+	action int
+	inputs []textinput.Model // One per variable; unused for choice variables
+	choice []int             // Selected choice per variable
+	focus  int
+}
+
+// newPromptVarForm builds the form for a prompt, prefilled with defaults
+func newPromptVarForm(p prompt.Prompt, action int) *promptVarForm {
+	f := &promptVarForm{
+		prompt: p,
+		action: action,
+		inputs: make([]textinput.Model, len(p.Variables)),
+		choice: make([]int, len(p.Variables)),
+	}
+	for i, v := range p.Variables {
+		ti := textinput.New()
+		ti.Placeholder = v.Description
+		ti.CharLimit = 500
+		ti.Width = 50
+		ti.SetValue(v.Default)
+		f.inputs[i] = ti
+		for j, c := range v.Choices {
+			if c == v.Default {
+				f.choice[i] = j
+			}
+		}
+	}
+	f.setFocus(0)
+	return f
+}
+
+// setFocus moves the cursor to variable i
+func (f *promptVarForm) setFocus(i int) {
+	f.focus = i
+	for j := range f.inputs {
+		if j == i {
+			f.inputs[j].Focus()
+		} else {
+			f.inputs[j].Blur()
+		}
+	}
+}
+
+// values returns the filled-in value of every variable
+func (f *promptVarForm) values() map[string]string {
+	values := make(map[string]string, len(f.prompt.Variables))
+	for i, v := range f.prompt.Variables {
+		if len(v.Choices) > 0 {
+			values[v.Name] = v.Choices[f.choice[i]]
+		} else {
+			values[v.Name] = f.inputs[i].Value()
+		}
+	}
+	return values
+}
+
+// usePrompt sends or copies a prompt, asking for its variables first if it
+// declares any
+func (m *Model) usePrompt(p prompt.Prompt, action int) tea.Cmd {
+	if len(p.Variables) > 0 {
+		m.promptVarForm = newPromptVarForm(p, action)
+		return nil
+	}
+	return m.finishPrompt(p, action, nil)
+}
+
+// finishPrompt expands a prompt with its variable values and carries out the
+// action it was opened for
+func (m *Model) finishPrompt(p prompt.Prompt, action int, values map[string]string) tea.Cmd {
+	filled := prompt.FillVariables(p.Content, values)
+	expanded := m.expandPromptVariables(filled)
+
+	switch action {
+	case promptVarsSend:
+		m.queuePromptSend(p, filled, expanded)
+	case promptVarsYank:
+		if err := prompt.Inject(expanded, prompt.InjectClipboard, ""); err != nil {
+			m.addToast("Failed to copy: "+err.Error(), ToastError)
+		} else {
+			m.addToast("Copied to clipboard", ToastSuccess)
+		}
+	case promptVarsQueue:
+		m.enqueueObjective(p.Name, expanded)
+	case promptVarsRalph:
+		return m.startRalphRun(p.Name, expanded)
+	}
+	return nil
+}
+
+// handlePromptVarKeys edits the variable form: tab/↓ and shift+tab/↑ move
+// between variables, ←/→ pick a choice, enter moves on or submits from the
+// last variable, esc cancels
+func (m Model) handlePromptVarKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	f := m.promptVarForm
+	v := f.prompt.Variables[f.focus]
+	last := len(f.prompt.Variables) - 1
+
+	switch msg.String() {
+	case "esc":
+		m.promptVarForm = nil
+		m.addToast("Cancelled", ToastInfo)
+		return m, nil
+	case "tab", "down":
+		f.setFocus((f.focus + 1) % len(f.inputs))
+		return m, nil
+	case "shift+tab", "up":
+		f.setFocus((f.focus - 1 + len(f.inputs)) % len(f.inputs))
+		return m, nil
+	case "enter":
+		if f.focus < last {
+			f.setFocus(f.focus + 1)
+			return m, nil
+		}
+		values := f.values()
+		for i, v := range f.prompt.Variables {
+			if values[v.Name] == "" {
+				f.setFocus(i)
+				m.addToast("Fill in {{"+v.Name+"}}", ToastWarning)
+				return m, nil
+			}
+		}
+		m.promptVarForm = nil
+		return m, m.finishPrompt(f.prompt, f.action, values)
+	}
+
+	if len(v.Choices) > 0 {
+		switch msg.String() {
+		case "left", "h":
+			f.choice[f.focus] = (f.choice[f.focus] - 1 + len(v.Choices)) % len(v.Choices)
+		case "right", "l", " ":
+			f.choice[f.focus] = (f.choice[f.focus] + 1) % len(v.Choices)
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	f.inputs[f.focus], cmd = f.inputs[f.focus].Update(msg)
+	return m, cmd
+}
+
+// renderPromptVarForm renders the variable form in place of the main view
+func (m Model) renderPromptVarForm() string {
+	f := m.promptVarForm
+	var sb strings.Builder
+	sb.WriteString("\n  " + m.theme.Title.Render(f.prompt.Name) + "\n")
+	sb.WriteString("  " + m.theme.Dim.Render("Fill in the prompt's variables") + "\n\n")
+
+	for i, v := range f.prompt.Variables {
+		label := "{{" + v.Name + "}}"
+		if i == f.focus {
+			label = m.theme.Selected.Render("▸ " + label)
+		} else {
+			label = m.theme.Normal.Render("  " + label)
+		}
+		sb.WriteString("  " + label)
+		if v.Description != "" {
+			sb.WriteString("  " + m.theme.Dim.Render(v.Description))
+		}
+		sb.WriteString("\n")
+
+		if len(v.Choices) > 0 {
+			var opts []string
+			for j, c := range v.Choices {
+				if j == f.choice[i] {
+					opts = append(opts, m.theme.Selected.Render("["+c+"]"))
+				} else {
+					opts = append(opts, m.theme.Dim.Render(" "+c+" "))
+				}
+			}
+			sb.WriteString("      " + strings.Join(opts, " ") + "\n\n")
+		} else {
+			sb.WriteString("      " + f.inputs[i].View() + "\n\n")
+		}
+	}
+
+	action := "send"
+	switch f.action {
+	case promptVarsYank:
+		action = "copy"
+	case promptVarsQueue:
+		action = "queue"
+	case promptVarsRalph:
+		action = "run"
+	}
+	sb.WriteString("  " + m.theme.Dim.Render("tab/↑↓: move  ←/→: choose  enter: next/"+action+"  esc: cancel") + "\n")
+	return sb.String()
+}
@@ -0,0 +1,224 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// ralphMaxStep is how much the leader keys +/- change max iterations by
+const ralphMaxStep = 5
+
+// ralphEdit is an edit the daemon recorded during a Ralph loop iteration
+type ralphEdit struct {
+	ID        int64     `json:"id"`
+	ToolName  string    `json:"tool_name"`
+	FilePath  string    `json:"file_path"`
+	Iteration int       `json:"ralph_iteration"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ralphIteration is one iteration of the loop and the edits made in it
+type ralphIteration struct {
+	Number int
+	Edits  []ralphEdit
+}
+
+// queryRalphTimelineCmd asks the daemon for the running loop's edits,
+// grouped by iteration. Returns nil without a loop.
+func (m Model) queryRalphTimelineCmd() tea.Cmd {
+	if m.ralphState == nil || !m.ralphState.Active {
+		return nil
+	}
+	since := m.ralphState.StartedAt
+	fsys := m.fs
+	return func() tea.Msg {
+		timeline, err := queryRalphEdits(fsys, since, time.Time{})
+		return ralphTimelineMsg{timeline: timeline, err: err}
+	}
+}
+
+// queryRalphEdits asks the daemon for this workspace's Ralph loop edits
+// between two times and groups them by iteration
+func queryRalphEdits(fsys FS, since, until time.Time) ([]ralphIteration, error) {
+	workspacePath, err := fsys.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Edits []ralphEdit `json:"edits"`
+		Error string      `json:"error,omitempty"`
+	}
+	query := map[string]interface{}{
+		"type":           "ralph_edits",
+		"workspace_path": workspacePath,
+	}
+	if !since.IsZero() {
+		query["since"] = since
+	}
+	if !until.IsZero() {
+		query["until"] = until
+	}
+	if err := queryDaemon(query, &result); err != nil {
+		return nil, err
+	}
+	if result.Error != "" {
+		return nil, fmt.Errorf("daemon: %s", result.Error)
+	}
+
+	// The daemon returns edits ordered by iteration
+	var timeline []ralphIteration
+	for _, e := range result.Edits {
+		if len(timeline) == 0 || timeline[len(timeline)-1].Number != e.Iteration {
+			timeline = append(timeline, ralphIteration{Number: e.Iteration})
+		}
+		last := &timeline[len(timeline)-1]
+		last.Edits = append(last.Edits, e)
+	}
+	return timeline, nil
+}
+
+// toggleRalphPause pauses the loop after the current iteration, or resumes it
+func (m *Model) toggleRalphPause() {
+	if m.ralphState == nil || !m.ralphState.Active {
+		m.addToast("No active Ralph loop", ToastWarning)
+		return
+	}
+	if m.ralphState.Paused {
+		if err := m.ralphState.Resume(); err != nil {
+			m.addToast(err.Error(), ToastError)
+			return
+		}
+		m.reportDaemonEvent("ralph_resume", "info", fmt.Sprintf("Ralph loop resumed at iteration %d", m.ralphState.Iteration))
+		m.addToast("Ralph loop resumed; it continues the next time Claude stops", ToastSuccess)
+	} else {
+		if err := m.ralphState.Pause(); err != nil {
+			m.addToast(err.Error(), ToastError)
+			return
+		}
+		m.reportDaemonEvent("ralph_pause", "info", fmt.Sprintf("Ralph loop paused at iteration %d", m.ralphState.Iteration))
+		m.addToast("Ralph loop paused after this iteration", ToastSuccess)
+	}
+	m.diffViewport.SetContent(m.renderRightPane())
+}
+
+// bumpRalphMaxIterations raises or lowers the loop's iteration limit. A loop
+// without a limit gets one counted from the current iteration.
+func (m *Model) bumpRalphMaxIterations(delta int) {
+	if m.ralphState == nil || !m.ralphState.Active {
+		m.addToast("No active Ralph loop", ToastWarning)
+		return
+	}
+	base := m.ralphState.MaxIterations
+	if base == 0 {
+		base = m.ralphState.Iteration
+	}
+	n := max(base+delta, m.ralphState.Iteration, 1)
+	if err := m.ralphState.SetMaxIterations(n); err != nil {
+		m.addToast(err.Error(), ToastError)
+		return
+	}
+	logger.Log("Ralph max iterations set to %d", n)
+	m.addToast(fmt.Sprintf("Max iterations: %d", n), ToastSuccess)
+	m.diffViewport.SetContent(m.renderRightPane())
+}
+
+// editRalphPromise opens the completion promise for editing
+func (m *Model) editRalphPromise() tea.Cmd {
+	if m.ralphState == nil || !m.ralphState.Active {
+		m.addToast("No active Ralph loop", ToastWarning)
+		return nil
+	}
+	m.ralphInput.SetValue(m.ralphState.Promise)
+	m.ralphInput.CursorEnd()
+	m.ralphInputActive = true
+	m.ralphInput.Focus()
+	return textinput.Blink
+}
+
+// handleRalphInputKeys edits the completion promise: enter saves it (empty
+// removes it), esc keeps the old one
+func (m Model) handleRalphInputKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.ralphInputActive = false
+		m.ralphInput.Blur()
+		return m, nil
+	case "enter":
+		m.ralphInputActive = false
+		m.ralphInput.Blur()
+		if m.ralphState == nil {
+			return m, nil
+		}
+		promise := strings.TrimSpace(m.ralphInput.Value())
+		if err := m.ralphState.SetPromise(promise); err != nil {
+			m.addToast(err.Error(), ToastError)
+		} else if promise == "" {
+			m.addToast("Promise removed; the loop ends at max iterations", ToastSuccess)
+		} else {
+			m.addToast("Promise updated", ToastSuccess)
+		}
+		m.diffViewport.SetContent(m.renderRightPane())
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.ralphInput, cmd = m.ralphInput.Update(msg)
+	return m, cmd
+}
+
+// renderRalphTimeline renders what changed in each iteration of the running
+// loop, newest first
+func (m *Model) renderRalphTimeline() string {
+	var sb strings.Builder
+	sb.WriteString(m.theme.Title.Render("Iterations") + "\n")
+	sb.WriteString(m.theme.Dim.Render(strings.Repeat("─", m.width-4)) + "\n\n")
+
+	if len(m.ralphTimeline) == 0 {
+		sb.WriteString(m.theme.Dim.Render("No edits recorded for this loop yet (needs the daemon and claude-mon-hook.sh)") + "\n\n")
+		return sb.String()
+	}
+	sb.WriteString(m.renderRalphIterations(m.ralphTimeline, m.ralphState.Iteration))
+	return sb.String()
+}
+
+// renderRalphIterations lists the files edited in each iteration, newest
+// first. current marks the running iteration (0 for none).
+func (m *Model) renderRalphIterations(timeline []ralphIteration, current int) string {
+	var sb strings.Builder
+	for i := len(timeline) - 1; i >= 0; i-- {
+		it := timeline[i]
+		first, last := it.Edits[0].CreatedAt, it.Edits[len(it.Edits)-1].CreatedAt
+		header := fmt.Sprintf("Iteration %d", it.Number)
+		if it.Number == current {
+			header += " (current)"
+		}
+		sb.WriteString(m.theme.Normal.Render(header))
+		sb.WriteString(m.theme.Dim.Render(fmt.Sprintf("  %s–%s  %d edits",
+			m.localTime(first).Format("15:04"), m.localTime(last).Format("15:04"), len(it.Edits))) + "\n")
+
+		// One line per file, in the order they were first touched
+		var files []string
+		counts := make(map[string]int)
+		for _, e := range it.Edits {
+			if counts[e.FilePath] == 0 {
+				files = append(files, e.FilePath)
+			}
+			counts[e.FilePath]++
+		}
+		for _, f := range files {
+			line := "  " + m.relativePath(f)
+			if counts[f] > 1 {
+				line += m.theme.Dim.Render(fmt.Sprintf(" ×%d", counts[f]))
+			}
+			sb.WriteString(line + "\n")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
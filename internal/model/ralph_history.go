@@ -0,0 +1,213 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/logger"
+	"github.com/ztaylor/claude-mon/internal/ralph"
+)
+
+// RalphLoop is an ended Ralph loop of this workspace, as recorded by the
+// daemon
+type RalphLoop struct {
+	ID            int64     `json:"id"`
+	Prompt        string    `json:"prompt"`
+	Promise       string    `json:"promise"`
+	Iterations    int       `json:"iterations"`
+	MaxIterations int       `json:"max_iterations"`
+	Outcome       string    `json:"outcome"`
+	StartedAt     time.Time `json:"started_at"`
+	EndedAt       time.Time `json:"ended_at"`
+	Edits         int       `json:"edits"`
+}
+
+// recordRalphLoop sends the daemon a loop that has ended, from the last
+// state claude-mon saw of it
+func (m Model) recordRalphLoop(s *ralph.State, outcome string) {
+	logger.Log("Ralph loop ended: %s after %d iterations", outcome, s.Iteration)
+	m.sendDaemonPayload(map[string]interface{}{
+		"type": "ralph_loop",
+		"ralph_loop": map[string]interface{}{
+			"prompt":         s.Prompt,
+			"promise":        s.Promise,
+			"iterations":     s.Iteration,
+			"max_iterations": s.MaxIterations,
+			"outcome":        outcome,
+			"started_at":     s.StartedAt,
+			"ended_at":       m.clock.Now(),
+		},
+	})
+}
+
+// cancelRalphLoop removes the loop's state file, recording it as cancelled
+func (m *Model) cancelRalphLoop() {
+	prev := m.ralphState
+	removed, err := ralph.CancelLoop()
+	if err != nil {
+		m.addToast(err.Error(), ToastError)
+		return
+	}
+	if !removed {
+		m.addToast("No active Ralph loop", ToastWarning)
+		return
+	}
+	m.ralphState = nil
+	m.ralphTimeline = nil
+	if prev != nil && prev.Active {
+		m.recordRalphLoop(prev, ralph.OutcomeCancelled)
+	}
+	if m.ralphRun != nil {
+		// The loop is recorded; the done message only keeps the output
+		m.ralphRun.Stop()
+	}
+	m.reportDaemonEvent("ralph_stop", "info", "Ralph loop cancelled")
+	m.addToast("Ralph Loop cancelled", ToastSuccess)
+	m.diffViewport.SetContent(m.renderRightPane())
+}
+
+// ralphOutcomeLabel describes a loop outcome for toasts and the history
+func ralphOutcomeLabel(outcome string) string {
+	switch outcome {
+	case ralph.OutcomePromiseMet:
+		return "promise met"
+	case ralph.OutcomeMaxIterations:
+		return "hit max iterations"
+	case ralph.OutcomeCancelled:
+		return "cancelled"
+	default:
+		return "stopped"
+	}
+}
+
+// queryRalphLoopsCmd asks the daemon for this workspace's ended loops
+func (m Model) queryRalphLoopsCmd() tea.Cmd {
+	return func() tea.Msg {
+		workspacePath, err := m.fs.Getwd()
+		if err != nil {
+			return ralphLoopsMsg{err: err}
+		}
+
+		var result struct {
+			RalphLoops []RalphLoop `json:"ralph_loops"`
+			Error      string      `json:"error,omitempty"`
+		}
+		if err := queryDaemon(map[string]interface{}{
+			"type":           "ralph_loops",
+			"workspace_path": workspacePath,
+			"limit":          50,
+		}, &result); err != nil {
+			return ralphLoopsMsg{err: err}
+		}
+		if result.Error != "" {
+			return ralphLoopsMsg{err: fmt.Errorf("daemon: %s", result.Error)}
+		}
+		return ralphLoopsMsg{loops: result.RalphLoops}
+	}
+}
+
+// queryRalphLoopEditsCmd asks the daemon for the edits of an ended loop,
+// grouped by iteration
+func (m Model) queryRalphLoopEditsCmd(loop RalphLoop) tea.Cmd {
+	fsys := m.fs
+	return func() tea.Msg {
+		timeline, err := queryRalphEdits(fsys, loop.StartedAt, loop.EndedAt)
+		return ralphLoopEditsMsg{loopID: loop.ID, timeline: timeline, err: err}
+	}
+}
+
+// toggleRalphHistory switches the Ralph tab between the running loop and the
+// loops that have ended
+func (m *Model) toggleRalphHistory() tea.Cmd {
+	m.showRalphHistory = !m.showRalphHistory
+	m.diffViewport.SetContent(m.renderRightPane())
+	m.diffViewport.GotoTop()
+	if m.showRalphHistory {
+		return m.queryRalphLoopsCmd()
+	}
+	return nil
+}
+
+// handleRalphHistoryKey moves through the ended loops; enter lists the
+// selected loop's edits by iteration
+func (m *Model) handleRalphHistoryKey(key string) tea.Cmd {
+	switch key {
+	case m.config.Keys.Down, "down":
+		if m.ralphHistoryIndex < len(m.ralphHistory)-1 {
+			m.ralphHistoryIndex++
+			m.ralphHistoryEdits = nil
+		}
+	case m.config.Keys.Up, "up":
+		if m.ralphHistoryIndex > 0 {
+			m.ralphHistoryIndex--
+			m.ralphHistoryEdits = nil
+		}
+	case "enter":
+		if len(m.ralphHistory) > 0 {
+			return m.queryRalphLoopEditsCmd(m.ralphHistory[m.ralphHistoryIndex])
+		}
+	case m.config.Keys.PageDown:
+		m.diffViewport.HalfViewDown()
+		return nil
+	case m.config.Keys.PageUp:
+		m.diffViewport.HalfViewUp()
+		return nil
+	case "esc", "H":
+		return m.toggleRalphHistory()
+	}
+	m.diffViewport.SetContent(m.renderRightPane())
+	return nil
+}
+
+// renderRalphHistory renders the ended loops with the selected loop's prompt
+// and, once loaded, its edits by iteration
+func (m *Model) renderRalphHistory() string {
+	var sb strings.Builder
+	sb.WriteString(m.theme.Title.Render("Ralph Loop History") + "\n")
+	sb.WriteString(m.theme.Dim.Render(strings.Repeat("─", m.width-4)) + "\n\n")
+
+	if len(m.ralphHistory) == 0 {
+		sb.WriteString(m.theme.Dim.Render("No ended loops recorded for this workspace (needs the daemon)") + "\n\n")
+		sb.WriteString(m.theme.Dim.Render("H: back to the running loop"))
+		return sb.String()
+	}
+
+	for i, l := range m.ralphHistory {
+		limit := "∞"
+		if l.MaxIterations > 0 {
+			limit = fmt.Sprint(l.MaxIterations)
+		}
+		prompt, _, _ := strings.Cut(strings.TrimSpace(l.Prompt), "\n")
+		if len([]rune(prompt)) > 50 {
+			prompt = string([]rune(prompt)[:47]) + "..."
+		}
+		line := fmt.Sprintf("%-12s %-18s %3d/%-3s %8s %4d edits  %s", m.relativeTime(l.EndedAt), ralphOutcomeLabel(l.Outcome),
+			l.Iterations, limit, l.EndedAt.Sub(l.StartedAt).Round(time.Second), l.Edits, prompt)
+		if i == m.ralphHistoryIndex {
+			sb.WriteString(m.theme.Selected.Render("▸ "+line) + "\n")
+		} else {
+			sb.WriteString("  " + m.theme.Normal.Render(line) + "\n")
+		}
+	}
+
+	l := m.ralphHistory[m.ralphHistoryIndex]
+	sb.WriteString("\n" + m.theme.Dim.Render(strings.Repeat("─", m.width-4)) + "\n")
+	if l.Promise != "" {
+		sb.WriteString(m.theme.Dim.Render("Promise: ") + m.theme.Normal.Render("\""+l.Promise+"\"") + "\n")
+	}
+	sb.WriteString(m.theme.Dim.Render("Started: ") + m.theme.Normal.Render(m.localTime(l.StartedAt).Format("2006-01-02 15:04")) + "\n\n")
+	sb.WriteString(m.theme.Normal.Render(strings.TrimSpace(l.Prompt)) + "\n\n")
+
+	if m.ralphHistoryEdits != nil {
+		sb.WriteString(m.theme.Title.Render("Iterations") + "\n\n")
+		if len(m.ralphHistoryEdits) == 0 {
+			sb.WriteString(m.theme.Dim.Render("No edits recorded for this loop") + "\n\n")
+		} else {
+			sb.WriteString(m.renderRalphIterations(m.ralphHistoryEdits, 0))
+		}
+	}
+	sb.WriteString(m.theme.Dim.Render("j/k: select  enter: show edits by iteration  H/esc: back to the running loop"))
+	return sb.String()
+}
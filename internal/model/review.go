@@ -0,0 +1,169 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/database"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// toggleReview starts triaging the history list one change at a time, from
+// the first unreviewed change at or below the selection, or stops
+func (m *Model) toggleReview() tea.Cmd {
+	if m.reviewing {
+		m.reviewing = false
+		reviewed, total := m.reviewProgress()
+		m.addToast(fmt.Sprintf("Review paused at %d/%d", reviewed, total), ToastInfo)
+		return nil
+	}
+	if len(m.changes) == 0 {
+		m.addToast("No changes to review", ToastInfo)
+		return nil
+	}
+	if m.changes[m.selectedIndex].Review != "" && !m.nextUnreviewed() {
+		m.addToast("Every change has been reviewed", ToastInfo)
+		return nil
+	}
+	m.reviewing = true
+	m.activePane = PaneLeft
+	return m.showReviewed()
+}
+
+// handleReviewKey triages the selected change while reviewing, reporting
+// whether the key was one of review mode's
+func (m *Model) handleReviewKey(key string) (bool, tea.Cmd) {
+	switch key {
+	case "a":
+		m.setReview(database.ReviewApproved)
+		return true, m.advanceReview()
+	case "r":
+		// The note input asks why; review moves on once it closes
+		m.setReview(database.ReviewRejected)
+		return true, m.openNoteInput()
+	case "s", " ":
+		return true, m.advanceReview()
+	case "u":
+		m.setReview("")
+		return true, nil
+	case "v", "esc":
+		return true, m.toggleReview()
+	}
+	return false, nil
+}
+
+// advanceReview selects the next unreviewed change, finishing the review
+// when there are none left
+func (m *Model) advanceReview() tea.Cmd {
+	if !m.nextUnreviewed() {
+		m.reviewing = false
+		approved, rejected := 0, 0
+		for _, c := range m.changes {
+			switch c.Review {
+			case database.ReviewApproved:
+				approved++
+			case database.ReviewRejected:
+				rejected++
+			}
+		}
+		m.addToast(fmt.Sprintf("Review done: %d approved, %d rejected", approved, rejected), ToastSuccess)
+		return m.showDiff()
+	}
+	return m.showReviewed()
+}
+
+// showReviewed shows the change selected for review
+func (m *Model) showReviewed() tea.Cmd {
+	m.scrollX = 0
+	m.ensureSelectedVisible()
+	cmd := m.showDiff()
+	m.scrollToChange()
+	return cmd
+}
+
+// nextUnreviewed selects the next unreviewed change below the selection,
+// wrapping around to the top of the list
+func (m *Model) nextUnreviewed() bool {
+	visible := m.visibleChangeIndices()
+	if len(visible) == 0 {
+		return false
+	}
+	pos := m.selectedListPos(visible)
+	for i := 1; i <= len(visible); i++ {
+		idx := visible[(pos+i)%len(visible)]
+		if m.changes[idx].Review == "" {
+			m.selectedIndex = idx
+			return true
+		}
+	}
+	return false
+}
+
+// setReview approves or rejects the selected change, or clears its review
+// state if review is empty, persisting to the history file and the daemon
+func (m *Model) setReview(review string) {
+	if len(m.changes) == 0 {
+		return
+	}
+	change := &m.changes[m.selectedIndex]
+	change.Review = review
+	m.diffCache = make(map[int]*diffDoc)
+
+	if m.persistHistory && m.historyStore != nil {
+		if err := m.historyStore.SetReview(change.Timestamp, change.FilePath, review); err != nil {
+			logger.Log("Failed to save review: %v", err)
+		}
+	}
+
+	// Changes loaded from the daemon carry their edit ID; live changes are
+	// matched by file path and timestamp
+	m.sendDaemonPayload(map[string]interface{}{
+		"type":      "review",
+		"edit_id":   change.EditID,
+		"file_path": change.FilePath,
+		"timestamp": change.Timestamp.Format(time.RFC3339Nano),
+		"review":    review,
+	})
+}
+
+// reviewProgress counts the loaded changes that have been reviewed
+func (m Model) reviewProgress() (reviewed, total int) {
+	for _, c := range m.changes {
+		if c.Review != "" {
+			reviewed++
+		}
+	}
+	return reviewed, len(m.changes)
+}
+
+// reviewMark returns the history list mark for a change's review state
+func reviewMark(review string) string {
+	switch review {
+	case database.ReviewApproved:
+		return "✓"
+	case database.ReviewRejected:
+		return "✗"
+	}
+	return ""
+}
+
+// renderReviewHeader renders the diff header line for a reviewed change
+func (m Model) renderReviewHeader(review string) string {
+	switch review {
+	case database.ReviewApproved:
+		return m.theme.Added.Render("✓ approved")
+	case database.ReviewRejected:
+		return m.theme.Removed.Render("✗ rejected")
+	}
+	return ""
+}
+
+// statusReview shows review progress while reviewing
+func (m Model) statusReview() string {
+	if !m.reviewing {
+		return ""
+	}
+	reviewed, total := m.reviewProgress()
+	return m.theme.Modified.Render(fmt.Sprintf("%d/%d reviewed", reviewed, total))
+}
@@ -0,0 +1,401 @@
+package model
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/plan"
+)
+
+// sessionLabelLimit caps the name or prompt shown in a session header
+const sessionLabelLimit = 48
+
+// querySessionNamesCmd asks the daemon for the names given to this
+// workspace's Claude sessions
+func (m Model) querySessionNamesCmd() tea.Cmd {
+	return func() tea.Msg {
+		workspacePath, err := m.fs.Getwd()
+		if err != nil {
+			return sessionNamesMsg{err: err}
+		}
+
+		var result struct {
+			Names map[string]string `json:"names"`
+			Error string            `json:"error,omitempty"`
+		}
+		if err := queryDaemon(map[string]interface{}{
+			"type":           "session_names",
+			"workspace_path": workspacePath,
+		}, &result); err != nil {
+			return sessionNamesMsg{err: err}
+		}
+		if result.Error != "" {
+			return sessionNamesMsg{err: fmt.Errorf("daemon: %s", result.Error)}
+		}
+		return sessionNamesMsg{names: result.Names}
+	}
+}
+
+// loadSessionPrompts reads the originating prompt of each session not seen
+// yet from its transcript
+func (m *Model) loadSessionPrompts() {
+	for _, c := range m.changes {
+		if c.SessionID == "" {
+			continue
+		}
+		if _, ok := m.sessionPrompts[c.SessionID]; ok {
+			continue
+		}
+		if m.sessionPrompts == nil {
+			m.sessionPrompts = make(map[string]string)
+		}
+		m.sessionPrompts[c.SessionID] = firstUserPrompt(sessionTranscript(m.fs, c))
+	}
+}
+
+// sessionTranscript returns the transcript of the session that made a
+// change: the path the hook passed, or where Claude Code keeps the
+// workspace's sessions for changes loaded from the daemon
+func sessionTranscript(fsys FS, c Change) string {
+	if c.TranscriptPath != "" {
+		return c.TranscriptPath
+	}
+	home, err := fsys.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	cwd, err := fsys.Getwd()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".claude", "projects", plan.ProjectDir(cwd), c.SessionID+".jsonl")
+}
+
+// firstUserPrompt returns the text of the first user prompt in a Claude
+// Code transcript, or "" if none can be read
+func firstUserPrompt(transcriptPath string) string {
+	if transcriptPath == "" {
+		return ""
+	}
+	f, err := os.Open(transcriptPath)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry transcriptEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil || entry.Type != "user" {
+			continue
+		}
+		if text := promptText(entry.Message.Content); text != "" {
+			return text
+		}
+	}
+	return ""
+}
+
+// collapseSessions keeps only the newest entry of each run of a collapsed
+// session's entries
+func (m Model) collapseSessions(indices []int) []int {
+	collapsed := make([]int, 0, len(indices))
+	for pos, idx := range indices {
+		sid := m.changes[idx].SessionID
+		if m.sessionCollapsed[sid] && pos > 0 && m.changes[indices[pos-1]].SessionID == sid {
+			continue
+		}
+		collapsed = append(collapsed, idx)
+	}
+	return collapsed
+}
+
+// collapsedSession returns the collapsed session a change stands for in the
+// history list, or "" if the change is shown on its own
+func (m Model) collapsedSession(idx int) string {
+	sid := m.changes[idx].SessionID
+	if sid == "" || !m.sessionCollapsed[sid] {
+		return ""
+	}
+	return sid
+}
+
+// sessionHeaderAt reports whether the visible entry at pos starts a group of
+// an expanded session's entries, which gets a header line. The first entry
+// in view repeats its session's header.
+func (m Model) sessionHeaderAt(visible []int, pos, start int) bool {
+	sid := m.changes[visible[pos]].SessionID
+	if sid == "" || m.sessionCollapsed[sid] {
+		return false
+	}
+	return pos == start || m.changes[visible[pos-1]].SessionID != sid
+}
+
+// sessionChanges returns the indices of a session's changes matching the
+// history filters, newest first
+func (m Model) sessionChanges(sid string) []int {
+	var indices []int
+	for _, idx := range m.filteredChangeIndices() {
+		if m.changes[idx].SessionID == sid {
+			indices = append(indices, idx)
+		}
+	}
+	return indices
+}
+
+// sessionLabel returns a session's name, its originating prompt, or its
+// short ID when neither is known
+func (m Model) sessionLabel(sid string) string {
+	if name := m.sessionNames[sid]; name != "" {
+		return name
+	}
+	if prompt := strings.TrimSpace(m.sessionPrompts[sid]); prompt != "" {
+		return truncateRunes(strings.SplitN(prompt, "\n", 2)[0], sessionLabelLimit)
+	}
+	return "session " + shortSessionID(sid)
+}
+
+// sessionSummary describes a session for its header, e.g.
+// "Fix the flaky test · 09:12 · 42 edits"
+func (m Model) sessionSummary(sid string) string {
+	indices := m.sessionChanges(sid)
+	if len(indices) == 0 {
+		return m.sessionLabel(sid)
+	}
+	started, _ := m.sessionBounds(sid, indices)
+	edits := "1 edit"
+	if len(indices) != 1 {
+		edits = fmt.Sprintf("%d edits", len(indices))
+	}
+	return fmt.Sprintf("%s · %s · %s", m.sessionLabel(sid), started.Format("15:04"), edits)
+}
+
+// renderSessionHeader renders the header line above an expanded session's
+// entries
+func (m Model) renderSessionHeader(sid string, width int) string {
+	return m.theme.Dim.Render(truncateRunes("▾ "+m.sessionSummary(sid), width))
+}
+
+// renderCollapsedSession renders the history entry standing for a collapsed
+// session
+func (m Model) renderCollapsedSession(sid string, selected bool, linesPerItem, width int) string {
+	line := truncateRunes("▸ "+m.sessionSummary(sid), width-2)
+	var span string
+	if indices := m.sessionChanges(sid); len(indices) > 0 {
+		span = formatSessionSpan(m.sessionBounds(sid, indices))
+	}
+
+	style, detailStyle, prefix := m.theme.Normal, m.theme.Dim, " "
+	if selected {
+		style, detailStyle, prefix = m.theme.Selected, m.theme.Selected, ">"
+	}
+	out := style.Render(prefix+" "+line) + "\n"
+	if linesPerItem == 2 {
+		out += detailStyle.Render("   "+span) + "\n"
+	}
+	return out
+}
+
+// sessionDiffDoc describes a collapsed session in the diff pane: when it
+// ran, what it was asked and the files it changed
+func (m *Model) sessionDiffDoc(sid string) *diffDoc {
+	indices := m.sessionChanges(sid)
+	title := m.theme.Title.Render(m.sessionLabel(sid)) + m.theme.Dim.Render("  session "+shortSessionID(sid))
+	header := []string{title, m.theme.Dim.Render(strings.Repeat("─", 40)), ""}
+	if len(indices) == 0 {
+		return staticDiffDoc(header, m.theme.Dim.Render("No edits match the filter"))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("%d edits, %s\n", len(indices), formatSessionSpan(m.sessionBounds(sid, indices))))
+	if activity := m.sessionActivityLine(sid); activity != "" {
+		sb.WriteString(activity + "\n")
+	}
+	if prompt := strings.TrimSpace(m.sessionPrompts[sid]); prompt != "" {
+		sb.WriteString("\n" + m.theme.DiffHeader.Render("@@ Prompt @@") + "\n")
+		sb.WriteString(prompt + "\n")
+	}
+
+	counts := make(map[string]int)
+	for _, idx := range indices {
+		counts[m.changes[idx].FilePath]++
+	}
+	files := make([]string, 0, len(counts))
+	for f := range counts {
+		files = append(files, f)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if counts[files[i]] != counts[files[j]] {
+			return counts[files[i]] > counts[files[j]]
+		}
+		return files[i] < files[j]
+	})
+	sb.WriteString("\n" + m.theme.DiffHeader.Render(fmt.Sprintf("@@ %d files @@", len(files))) + "\n")
+	for _, f := range files {
+		sb.WriteString(fmt.Sprintf("%4d  %s\n", counts[f], m.relativePath(f)))
+	}
+	sb.WriteString("\n" + m.theme.Dim.Render(m.config.Keys.ToggleSession+" expands the session"))
+	return staticDiffDoc(header, sb.String())
+}
+
+// toggleSessionCollapsed collapses the selected change's session into one
+// history entry, or expands it again
+func (m *Model) toggleSessionCollapsed() tea.Cmd {
+	if len(m.changes) == 0 {
+		return nil
+	}
+	sid := m.changes[m.selectedIndex].SessionID
+	if sid == "" {
+		m.addToast("Not made in a known Claude session", ToastInfo)
+		return nil
+	}
+
+	if m.sessionCollapsed[sid] {
+		delete(m.sessionCollapsed, sid)
+	} else {
+		// The newest entry of the selected run stands for the session
+		visible := m.visibleChangeIndices()
+		pos := m.selectedListPos(visible)
+		for pos > 0 && m.changes[visible[pos-1]].SessionID == sid {
+			pos--
+		}
+		m.selectedIndex = visible[pos]
+		if m.sessionCollapsed == nil {
+			m.sessionCollapsed = make(map[string]bool)
+		}
+		m.sessionCollapsed[sid] = true
+	}
+	m.diffCache = make(map[int]*diffDoc)
+	m.ensureSelectedVisible()
+	return m.showDiff()
+}
+
+// jumpSession moves the selection to the newest entry of the next older
+// session (delta > 0), or of the selected session and then the newer ones.
+// Returns false if nothing moved.
+func (m *Model) jumpSession(delta int) bool {
+	visible := m.visibleChangeIndices()
+	if len(visible) == 0 {
+		return false
+	}
+	session := func(pos int) string { return m.changes[visible[pos]].SessionID }
+
+	pos := m.selectedListPos(visible)
+	if delta > 0 {
+		next := pos
+		for next < len(visible) && session(next) == session(pos) {
+			next++
+		}
+		if next == len(visible) {
+			return false
+		}
+		pos = next
+	} else {
+		start := pos
+		for start > 0 && session(start-1) == session(pos) {
+			start--
+		}
+		if start == pos {
+			if pos == 0 {
+				return false
+			}
+			start = pos - 1
+			for start > 0 && session(start-1) == session(pos-1) {
+				start--
+			}
+		}
+		pos = start
+	}
+
+	m.selectedIndex = visible[pos]
+	return true
+}
+
+// openSessionNameInput opens the name prompt for the selected change's
+// session, pre-filled with its current name
+func (m *Model) openSessionNameInput() tea.Cmd {
+	if len(m.changes) == 0 {
+		return nil
+	}
+	sid := m.changes[m.selectedIndex].SessionID
+	if sid == "" {
+		m.addToast("Not made in a known Claude session", ToastInfo)
+		return nil
+	}
+
+	m.sessionNaming = sid
+	m.sessionNameInput.SetValue(m.sessionNames[sid])
+	m.sessionNameInput.CursorEnd()
+	return m.sessionNameInput.Focus()
+}
+
+// handleSessionNameKeys handles key events while the session name input is
+// active
+func (m Model) handleSessionNameKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.sessionNaming = ""
+		m.sessionNameInput.Blur()
+		return m, nil
+
+	case "enter":
+		sid := m.sessionNaming
+		m.sessionNaming = ""
+		m.sessionNameInput.Blur()
+		m.setSessionName(sid, strings.TrimSpace(m.sessionNameInput.Value()))
+		return m, m.showDiff()
+	}
+
+	var cmd tea.Cmd
+	m.sessionNameInput, cmd = m.sessionNameInput.Update(msg)
+	return m, cmd
+}
+
+// setSessionName names a session, or clears its name, and has the daemon
+// keep it
+func (m *Model) setSessionName(sid, name string) {
+	if name == "" {
+		delete(m.sessionNames, sid)
+		m.addToast("Session name cleared", ToastInfo)
+	} else {
+		if m.sessionNames == nil {
+			m.sessionNames = make(map[string]string)
+		}
+		m.sessionNames[sid] = name
+		m.addToast(fmt.Sprintf("Session named %q", name), ToastSuccess)
+	}
+	m.diffCache = make(map[int]*diffDoc)
+
+	m.sendDaemonPayload(map[string]interface{}{
+		"type":            "session_name",
+		"chat_session_id": sid,
+		"session_name":    name,
+	})
+}
+
+// renderSessionNameInput renders the status line while naming a session
+func (m Model) renderSessionNameInput() string {
+	return m.theme.Status.Render(fmt.Sprintf("Name session %s: %s  Enter:save  Esc:cancel",
+		shortSessionID(m.sessionNaming), m.sessionNameInput.View()))
+}
+
+// shortSessionID shortens a Claude session ID (a UUID) for display
+func shortSessionID(sid string) string {
+	if len(sid) > 8 {
+		return sid[:8]
+	}
+	return sid
+}
+
+// formatSessionSpan formats the time a session's edits span, e.g. "09:12-11:40"
+func formatSessionSpan(oldest, newest time.Time) string {
+	return oldest.Format("15:04") + "-" + newest.Format("15:04")
+}
@@ -0,0 +1,135 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// FileSnapshot is a file's content before the first edit to it in a Claude
+// session, as the daemon returns it
+type FileSnapshot struct {
+	ID        int64     `json:"id"`
+	FilePath  string    `json:"file_path"`
+	Existed   bool      `json:"existed"` // False if the session created the file
+	Size      int       `json:"size"`
+	Content   []byte    `json:"content,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// querySnapshotCmd asks the daemon for the latest snapshot of a file with
+// its content
+func (m Model) querySnapshotCmd(filePath string) tea.Cmd {
+	return func() tea.Msg {
+		workspacePath, err := m.fs.Getwd()
+		if err != nil {
+			return snapshotMsg{err: err}
+		}
+
+		var result struct {
+			Snapshots []*FileSnapshot `json:"snapshots"`
+			Error     string          `json:"error,omitempty"`
+		}
+		if err := queryDaemon(map[string]interface{}{
+			"type":           "snapshots",
+			"workspace_path": workspacePath,
+			"file_path":      filePath,
+			"limit":          1,
+		}, &result); err != nil {
+			return snapshotMsg{err: err}
+		}
+		if result.Error != "" {
+			return snapshotMsg{err: fmt.Errorf("daemon: %s", result.Error)}
+		}
+		if len(result.Snapshots) == 0 {
+			return snapshotMsg{err: fmt.Errorf("no snapshot of %s", m.relativePath(filePath))}
+		}
+
+		id := result.Snapshots[0].ID
+		result.Snapshots = nil
+		if err := queryDaemon(map[string]interface{}{
+			"type":        "snapshot",
+			"snapshot_id": id,
+		}, &result); err != nil {
+			return snapshotMsg{err: err}
+		}
+		if result.Error != "" {
+			return snapshotMsg{err: fmt.Errorf("daemon: %s", result.Error)}
+		}
+		if len(result.Snapshots) == 0 {
+			return snapshotMsg{err: fmt.Errorf("no snapshot %d", id)}
+		}
+		return snapshotMsg{snapshot: result.Snapshots[0]}
+	}
+}
+
+// confirmRestoreSnapshot fetches the selected change's file as it was
+// before the session's first edit, then asks to restore it
+func (m *Model) confirmRestoreSnapshot() tea.Cmd {
+	if len(m.changes) == 0 {
+		return nil
+	}
+	return m.querySnapshotCmd(m.changes[m.selectedIndex].FilePath)
+}
+
+// handleSnapshotRestoreKeys handles the restore confirmation: y/enter
+// restores, any other key cancels
+func (m Model) handleSnapshotRestoreKeys(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	s := m.snapshotRestorePending
+	m.snapshotRestorePending = nil
+
+	switch msg.String() {
+	case "y", "enter":
+		if err := m.restoreFileSnapshot(s); err != nil {
+			m.addToast(err.Error(), ToastError)
+			return m, nil
+		}
+		logger.Log("Restored %s from snapshot %d", s.FilePath, s.ID)
+		if s.Existed {
+			m.addToast("Restored "+m.relativePath(s.FilePath), ToastSuccess)
+		} else {
+			m.addToast("Removed "+m.relativePath(s.FilePath), ToastSuccess)
+		}
+	default:
+		m.addToast("Restore cancelled", ToastInfo)
+	}
+	return m, nil
+}
+
+// renderSnapshotRestoreConfirm renders the status line for a pending restore
+func (m Model) renderSnapshotRestoreConfirm() string {
+	s := m.snapshotRestorePending
+	action := fmt.Sprintf("Restore %s to its content at %s (%d bytes)?",
+		m.relativePath(s.FilePath), m.localTime(s.Timestamp).Format("15:04:05"), s.Size)
+	if !s.Existed {
+		action = fmt.Sprintf("Remove %s, created by the session?", m.relativePath(s.FilePath))
+	}
+	return m.theme.Status.Inherit(m.theme.Removed).Render(action + "  y/Enter:restore  Esc:cancel")
+}
+
+// restoreFileSnapshot writes a snapshot back over its file, keeping the
+// file's mode, or removes the file if the session created it
+func (m Model) restoreFileSnapshot(s *FileSnapshot) error {
+	if !s.Existed {
+		if err := m.fs.Remove(s.FilePath); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", m.relativePath(s.FilePath), err)
+		}
+		return nil
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := m.fs.Stat(s.FilePath); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := m.fs.MkdirAll(filepath.Dir(s.FilePath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := m.fs.WriteFile(s.FilePath, s.Content, mode); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", m.relativePath(s.FilePath), err)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/diff"
+)
+
+// squashRuns groups history indices (newest first) into runs of consecutive
+// edits to the same file
+func (m Model) squashRuns(indices []int) [][]int {
+	var runs [][]int
+	for _, idx := range indices {
+		if n := len(runs); n > 0 && m.changes[runs[n-1][0]].FilePath == m.changes[idx].FilePath {
+			runs[n-1] = append(runs[n-1], idx)
+			continue
+		}
+		runs = append(runs, []int{idx})
+	}
+	return runs
+}
+
+// squashKey identifies a run by its file and oldest edit, which stay put as
+// newer edits join the run
+func (m Model) squashKey(run []int) string {
+	oldest := m.changes[run[len(run)-1]]
+	return oldest.FilePath + "@" + oldest.Timestamp.Format(time.RFC3339Nano)
+}
+
+// squashIndices keeps only the newest edit of each collapsed run
+func (m Model) squashIndices(indices []int) []int {
+	squashed := make([]int, 0, len(indices))
+	for _, run := range m.squashRuns(indices) {
+		if len(run) > 1 && !m.squashExpanded[m.squashKey(run)] {
+			squashed = append(squashed, run[0])
+			continue
+		}
+		squashed = append(squashed, run...)
+	}
+	return squashed
+}
+
+// squashRunOf returns the run of edits containing a change, newest first
+func (m Model) squashRunOf(idx int) []int {
+	for _, run := range m.squashRuns(m.filteredChangeIndices()) {
+		for _, i := range run {
+			if i == idx {
+				return run
+			}
+		}
+	}
+	return nil
+}
+
+// squashedRun returns the collapsed run a change heads, or nil if the
+// change is shown on its own
+func (m Model) squashedRun(idx int) []int {
+	if !m.squashHistory {
+		return nil
+	}
+	run := m.squashRunOf(idx)
+	if len(run) < 2 || run[0] != idx || m.squashExpanded[m.squashKey(run)] {
+		return nil
+	}
+	return run
+}
+
+// toggleSquash switches between listing every edit and squashing runs of
+// consecutive edits to a file into one entry
+func (m *Model) toggleSquash() tea.Cmd {
+	m.squashHistory = !m.squashHistory
+	m.diffCache = make(map[int]*diffDoc)
+	if !m.squashHistory {
+		m.addToast("Showing every edit", ToastInfo)
+		m.ensureSelectedVisible()
+		return m.showDiff()
+	}
+
+	// A step hidden in a collapsed run selects the run
+	if run := m.squashRunOf(m.selectedIndex); len(run) > 1 && !m.squashExpanded[m.squashKey(run)] {
+		m.selectedIndex = run[0]
+	}
+	m.addToast("Squashing consecutive edits per file", ToastInfo)
+	m.ensureSelectedVisible()
+	return m.showDiff()
+}
+
+// toggleSquashExpanded expands the selected run into its steps, or
+// collapses it again
+func (m *Model) toggleSquashExpanded() tea.Cmd {
+	if !m.squashHistory || len(m.changes) == 0 {
+		return nil
+	}
+	run := m.squashRunOf(m.selectedIndex)
+	if len(run) < 2 {
+		m.addToast("Not part of a squashed run", ToastInfo)
+		return nil
+	}
+
+	key := m.squashKey(run)
+	if m.squashExpanded[key] {
+		delete(m.squashExpanded, key)
+		m.selectedIndex = run[0]
+	} else {
+		if m.squashExpanded == nil {
+			m.squashExpanded = make(map[string]bool)
+		}
+		m.squashExpanded[key] = true
+	}
+	delete(m.diffCache, run[0])
+	m.ensureSelectedVisible()
+	return m.showDiff()
+}
+
+// squashStep returns the label of a change in the history list when runs
+// are squashed: "15 edits" for a collapsed run, "Edit 3/15" for a step of an
+// expanded one, or "" for a change on its own
+func (m Model) squashStep(idx int) string {
+	if !m.squashHistory {
+		return ""
+	}
+	run := m.squashRunOf(idx)
+	if len(run) < 2 {
+		return ""
+	}
+	if !m.squashExpanded[m.squashKey(run)] {
+		return fmt.Sprintf("%d edits", len(run))
+	}
+	for pos, i := range run {
+		if i == idx {
+			return fmt.Sprintf("%s %d/%d", m.changes[idx].ToolName, len(run)-pos, len(run))
+		}
+	}
+	return ""
+}
+
+// squashDiffDoc builds the combined diff of a run: the file before its
+// oldest edit against the file after its newest
+func (m *Model) squashDiffDoc(run []int) *diffDoc {
+	steps := make([]diff.Step, 0, len(run))
+	for i := len(run) - 1; i >= 0; i-- {
+		change := m.changes[run[i]]
+		if i == 0 || i == len(run)-1 {
+			change = m.loadChangeContent(run[i])
+		}
+		steps = append(steps, diff.Step{
+			OldString:   change.OldString,
+			NewString:   change.NewString,
+			FileContent: change.FileContent,
+		})
+	}
+
+	newest, oldest := m.changes[run[0]], m.changes[run[len(run)-1]]
+	title := m.theme.Title.Render(m.relativePath(newest.FilePath))
+	title += m.theme.Dim.Render(fmt.Sprintf("  %d edits squashed, %s-%s",
+		len(run), oldest.Timestamp.Format("15:04"), newest.Timestamp.Format("15:04")))
+	header := []string{title, m.theme.Dim.Render(strings.Repeat("─", 40)), ""}
+
+	oldText, newText := diff.Squash(steps)
+	if m.semanticDiff[m.semanticKey(run[0])] {
+		return m.semanticDiffDoc(header, newest.FilePath, oldText, newText)
+	}
+	if doc := m.structuralDiffDoc(header, newest.FilePath, oldText, newText); doc != nil {
+		return doc
+	}
+	if oldText == newText {
+		return staticDiffDoc(header, m.theme.Dim.Render("The edits cancel out"))
+	}
+	return staticDiffDoc(header, diff.FormatDiff(oldText, newText, m.theme, m.diffOptions()))
+}
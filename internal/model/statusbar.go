@@ -0,0 +1,217 @@
+package model
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/ztaylor/claude-mon/internal/config"
+)
+
+// statusRight splits a status bar template: segments after it are
+// right-aligned
+const statusRight = "{right}"
+
+// statusSegments render the status bar template's {name} segments. A
+// segment that returns "" is left out along with the space after it.
+var statusSegments = map[string]func(m Model) string{
+	"mode":        Model.statusMode,
+	"pane":        Model.statusPane,
+	"hints":       Model.statusHints,
+	"daemon":      Model.statusDaemon,
+	"socket":      Model.statusSocket,
+	"dnd":         Model.statusDND,
+	"follow":      Model.statusFollow,
+	"pending":     Model.statusPending,
+	"review":      Model.statusReview,
+	"workspace":   Model.statusWorkspace,
+	"branch":      Model.statusBranch,
+	"edits_today": Model.statusEditsToday,
+	"time":        func(m Model) string { return m.clock.Now().Format("15:04") },
+}
+
+// statusBarTemplate returns the configured template, or the default one
+func (m Model) statusBarTemplate() string {
+	if m.config.StatusBar != "" {
+		return m.config.StatusBar
+	}
+	return config.DefaultStatusBar
+}
+
+// renderStatusBar fills the status bar template in, width columns wide. The
+// left side is truncated first, then the right side, so the indicators stay
+// visible as long as they fit.
+func (m Model) renderStatusBar(width int) string {
+	tmpl := m.statusBarTemplate()
+	leftTmpl, rightTmpl, _ := strings.Cut(tmpl, statusRight)
+	left := m.expandStatus(leftTmpl)
+	right := m.expandStatus(rightTmpl)
+
+	// An unacknowledged warning takes the place of the left side
+	if w := m.currentWarning(); w != nil {
+		left = m.renderWarningBanner(*w)
+	}
+
+	if lipgloss.Width(right) > width {
+		right = ansi.Truncate(right, width, "…")
+	}
+	avail := width - lipgloss.Width(right)
+	if right != "" {
+		avail-- // Keep a space between the sides
+	}
+	if lipgloss.Width(left) > avail {
+		left = ansi.Truncate(left, max(avail, 0), "…")
+	}
+	padding := max(width-lipgloss.Width(left)-lipgloss.Width(right), 0)
+	return left + strings.Repeat(" ", padding) + right
+}
+
+// expandStatus replaces a template's segments. Unknown segments are kept
+// as written so a typo shows up in the bar.
+func (m Model) expandStatus(tmpl string) string {
+	var sb strings.Builder
+	for tmpl != "" {
+		open := strings.IndexByte(tmpl, '{')
+		if open < 0 {
+			sb.WriteString(tmpl)
+			break
+		}
+		end := strings.IndexByte(tmpl[open:], '}')
+		if end < 0 {
+			sb.WriteString(tmpl)
+			break
+		}
+		sb.WriteString(tmpl[:open])
+		name := tmpl[open+1 : open+end]
+		tmpl = tmpl[open+end+1:]
+
+		provider, ok := statusSegments[name]
+		if !ok {
+			sb.WriteString("{" + name + "}")
+			continue
+		}
+		if value := provider(m); value != "" {
+			sb.WriteString(value)
+		} else if strings.HasPrefix(tmpl, " ") {
+			tmpl = tmpl[1:]
+		} else {
+			// Last on its side: drop the space before it instead
+			trimmed := strings.TrimSuffix(sb.String(), " ")
+			sb.Reset()
+			sb.WriteString(trimmed)
+		}
+	}
+	return sb.String()
+}
+
+// statusMode is the open tab's name
+func (m Model) statusMode() string {
+	for _, tab := range tabBarTabs {
+		if tab.mode == m.leftPaneMode {
+			return tab.name
+		}
+	}
+	return ""
+}
+
+// statusPane is the focused pane, L or R
+func (m Model) statusPane() string {
+	if m.activePane == PaneRight {
+		return "R"
+	}
+	return "L"
+}
+
+// statusHints lists the main navigation keys
+func (m Model) statusHints() string {
+	if m.reviewing {
+		return "a:approve  r:reject  s:skip  u:unmark  v:stop"
+	}
+	k := m.config.Keys
+	return fmt.Sprintf("%s/%s:nav  Tab:mode  [/]:pane  ^G:menu", k.Down, k.Up)
+}
+
+// statusDaemon shows whether the daemon is up and recording this workspace
+func (m Model) statusDaemon() string {
+	indicator := "○" // Not connected
+	style := m.theme.Dim
+	if m.daemonConnected {
+		if m.daemonWorkspaceActive && m.clock.Now().Sub(m.daemonLastActivity) < 5*time.Minute {
+			indicator = "●" // Connected with recent workspace activity
+			style = m.theme.Added
+		} else if m.daemonWorkspaceActive {
+			indicator = "◐" // Connected, workspace tracked but idle
+			style = m.theme.Modified
+		} else {
+			indicator = "◑" // Connected but workspace not tracked
+		}
+	}
+	return style.Render("D" + indicator)
+}
+
+// statusSocket shows whether hooks are reaching the TUI's socket
+func (m Model) statusSocket() string {
+	indicator := "○" // Disconnected/no recent activity
+	style := m.theme.Dim
+	if m.socketConnected {
+		if m.clock.Now().Sub(m.lastMsgTime) < 30*time.Second {
+			indicator = "●" // Connected with recent activity
+			style = m.theme.Added
+		} else {
+			indicator = "◐" // Connected but idle
+			style = m.theme.Modified
+		}
+	}
+	return style.Render("S" + indicator)
+}
+
+// statusDND shows how many toasts do not disturb is holding
+func (m Model) statusDND() string {
+	if !m.doNotDisturb {
+		return ""
+	}
+	return m.theme.Modified.Render(fmt.Sprintf("DND %d", m.heldToasts))
+}
+
+// statusFollow shows that follow mode is opening new changes in nvim
+func (m Model) statusFollow() string {
+	if !m.following {
+		return ""
+	}
+	return m.theme.Added.Render("FOLLOW")
+}
+
+// statusWorkspace is the working directory's name
+func (m Model) statusWorkspace() string {
+	dir, err := m.fs.Getwd()
+	if err != nil {
+		return ""
+	}
+	return filepath.Base(dir)
+}
+
+// statusEditsToday counts the loaded changes made since midnight
+func (m Model) statusEditsToday() string {
+	now := m.clock.Now()
+	y, mo, d := now.Date()
+	midnight := time.Date(y, mo, d, 0, 0, 0, 0, now.Location())
+	count := 0
+	for _, c := range m.changes {
+		if !c.Timestamp.Before(midnight) {
+			count++
+		}
+	}
+	return strconv.Itoa(count)
+}
+
+// statusBranch is the workspace's checked out branch
+func (m Model) statusBranch() string {
+	if m.vcsStatus == nil {
+		return ""
+	}
+	return m.vcsStatus.Branch
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	gocontext "context"
+	"io/fs"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// Clock tells the model the time; tests fix it so ages and timestamps are
+// stable. Times are shown in the location of Now.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to a Clock
+type ClockFunc func() time.Time
+
+// Now returns f()
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// FS is the filesystem the model reads the workspace, plans and edited files
+// from, and keeps its caches in
+type FS interface {
+	Getwd() (string, error)
+	UserHomeDir() (string, error)
+	ReadFile(name string) ([]byte, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	Stat(name string) (fs.FileInfo, error)
+	MkdirAll(path string, perm fs.FileMode) error
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	Remove(name string) error
+}
+
+// Runner runs external commands such as kubectl and git, returning their
+// standard output. dir is the directory to run in; empty runs in the current
+// one. Command builds one the TUI hands the terminal to, such as nvim.
+type Runner interface {
+	Output(ctx gocontext.Context, dir, name string, args ...string) ([]byte, error)
+	Command(name string, args ...string) *exec.Cmd
+}
+
+// localTime converts t to the clock's time zone for display
+func (m Model) localTime(t time.Time) time.Time {
+	return t.In(m.clock.Now().Location())
+}
+
+// systemClock is the real clock
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// osFS is the real filesystem
+type osFS struct{}
+
+func (osFS) Getwd() (string, error)                       { return os.Getwd() }
+func (osFS) UserHomeDir() (string, error)                 { return os.UserHomeDir() }
+func (osFS) ReadFile(name string) ([]byte, error)         { return os.ReadFile(name) }
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error)   { return os.ReadDir(name) }
+func (osFS) Stat(name string) (fs.FileInfo, error)        { return os.Stat(name) }
+func (osFS) MkdirAll(path string, perm fs.FileMode) error { return os.MkdirAll(path, perm) }
+func (osFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+func (osFS) Remove(name string) error { return os.Remove(name) }
+
+// execRunner runs commands with os/exec
+type execRunner struct{}
+
+func (execRunner) Output(ctx gocontext.Context, dir, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	return cmd.Output()
+}
+
+func (execRunner) Command(name string, args ...string) *exec.Cmd {
+	return exec.Command(name, args...)
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/highlight"
+	"github.com/ztaylor/claude-mon/internal/theme"
+)
+
+// openThemePicker shows the theme picker with the current theme selected
+func (m *Model) openThemePicker() {
+	m.showThemePicker = true
+	m.themePickerOrig = m.theme.Name
+	m.themePickerIndex = 0
+	for i, name := range theme.Available() {
+		if strings.EqualFold(name, m.theme.Name) {
+			m.themePickerIndex = i
+		}
+	}
+}
+
+// handleThemePickerKey moves through the themes, previewing each one.
+// Enter keeps the selection, esc goes back to the theme the picker opened on.
+func (m *Model) handleThemePickerKey(key string) tea.Cmd {
+	names := theme.Available()
+	switch key {
+	case "j", "down":
+		m.themePickerIndex = (m.themePickerIndex + 1) % len(names)
+	case "k", "up":
+		m.themePickerIndex = (m.themePickerIndex - 1 + len(names)) % len(names)
+	case "enter":
+		m.showThemePicker = false
+		m.addToast("Theme: "+names[m.themePickerIndex], ToastSuccess)
+		return nil
+	case "esc", "q":
+		m.showThemePicker = false
+		return m.applyTheme(m.themePickerOrig)
+	default:
+		return nil
+	}
+	return m.applyTheme(names[m.themePickerIndex])
+}
+
+// applyTheme switches themes at runtime, rebuilding the highlighter and
+// re-rendering cached diffs with the new colors
+func (m *Model) applyTheme(name string) tea.Cmd {
+	m.theme = theme.Get(name)
+	m.highlighter = highlight.NewHighlighter(m.theme)
+	m.diffSpinner.Style = m.theme.Title
+	m.contextCompletionSpinner.Style = m.theme.Title
+	m.diffCache = make(map[int]*diffDoc)
+	return m.showDiff()
+}
+
+// renderThemePicker renders the theme list over the current view
+func (m Model) renderThemePicker() string {
+	var sb strings.Builder
+	sb.WriteString("\n  " + m.theme.Title.Render("Theme") + "\n\n")
+	for i, name := range theme.Available() {
+		if i == m.themePickerIndex {
+			sb.WriteString("  " + m.theme.Selected.Render("▸ "+name) + "\n")
+		} else {
+			sb.WriteString("    " + m.theme.Normal.Render(name) + "\n")
+		}
+	}
+
+	// A sample of the diff and syntax colors, so the preview shows more than the list
+	sb.WriteString("\n  " + m.theme.DiffHeader.Render("@@ -1,2 +1,2 @@") + "\n")
+	sb.WriteString("  " + m.theme.Removed.Render("- return nil") + "\n")
+	sb.WriteString("  " + m.theme.Added.Render("+ return err") + "\n")
+	sb.WriteString("  " + m.theme.Keyword.Render("func") + " " + m.theme.Function.Render("main") +
+		m.theme.Punctuation.Render("()") + " " + m.theme.Comment.Render("// preview") + "\n")
+
+	sb.WriteString("\n  " + m.theme.Dim.Render("j/k: preview  enter: keep  esc: cancel") + "\n")
+	return sb.String()
+}
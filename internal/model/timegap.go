@@ -0,0 +1,79 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// historyTimeGap returns the idle time between the visible entry at pos and
+// the newer entry above it, or 0 if it is below the configured interval
+func (m Model) historyTimeGap(visible []int, pos int) time.Duration {
+	interval := m.config.TimeGapDuration()
+	if interval <= 0 || pos <= 0 || pos >= len(visible) {
+		return 0
+	}
+
+	gap := m.changes[visible[pos-1]].Timestamp.Sub(m.changes[visible[pos]].Timestamp)
+	if gap < interval {
+		return 0
+	}
+	return gap
+}
+
+// historyItemsFitting returns how many visible entries starting at start fit
+// in the list, accounting for time-gap separator and session header lines
+// between them
+func (m Model) historyItemsFitting(visible []int, start int) int {
+	available := m.listVisibleItems() * m.historyLinesPerItem()
+	linesPerItem := m.historyLinesPerItem()
+
+	lines, count := 0, 0
+	for pos := start; pos < len(visible); pos++ {
+		cost := linesPerItem
+		if pos > start && m.historyTimeGap(visible, pos) > 0 {
+			cost++ // Separator
+		}
+		if m.sessionHeaderAt(visible, pos, start) {
+			cost++ // Session header
+		}
+		if lines+cost > available {
+			break
+		}
+		lines += cost
+		count++
+	}
+	if count < 1 {
+		return 1
+	}
+	return count
+}
+
+// renderTimeGap renders a separator for an idle stretch, e.g. "── 42 min idle ──"
+func (m Model) renderTimeGap(gap time.Duration, width int) string {
+	label := " " + formatIdle(gap) + " idle "
+	side := (width - len([]rune(label))) / 2
+	if side < 2 {
+		side = 2
+	}
+	return m.theme.Dim.Render(strings.Repeat("─", side) + label + strings.Repeat("─", side))
+}
+
+// formatIdle formats an idle duration, e.g. "42 min", "3h 10m", "2 days"
+func formatIdle(d time.Duration) string {
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%d min", int(d.Minutes()))
+	case d < 24*time.Hour:
+		if mins := int(d.Minutes()) % 60; mins > 0 {
+			return fmt.Sprintf("%dh %dm", int(d.Hours()), mins)
+		}
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		days := int(d.Hours()) / 24
+		if days == 1 {
+			return "1 day"
+		}
+		return fmt.Sprintf("%d days", days)
+	}
+}
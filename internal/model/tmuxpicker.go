@@ -0,0 +1,83 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ztaylor/claude-mon/internal/prompt"
+)
+
+// openTmuxPicker lists the tmux panes prompts can be sent to, selecting the
+// current target or else the first pane running Claude Code
+func (m *Model) openTmuxPicker() {
+	panes, err := prompt.ListTmuxPanes()
+	if err != nil {
+		m.addToast(err.Error(), ToastError)
+		return
+	}
+	if len(panes) == 0 {
+		m.addToast("No other tmux panes", ToastWarning)
+		return
+	}
+
+	m.tmuxPanes = panes
+	m.tmuxPaneIndex = -1
+	for i, p := range panes {
+		if m.promptTmuxTarget != "" && (p.Target == m.promptTmuxTarget || p.ID == m.promptTmuxTarget) {
+			m.tmuxPaneIndex = i
+			break
+		}
+		if m.tmuxPaneIndex < 0 && p.RunsClaude() {
+			m.tmuxPaneIndex = i
+		}
+	}
+	if m.tmuxPaneIndex < 0 {
+		m.tmuxPaneIndex = 0
+	}
+	m.showTmuxPicker = true
+}
+
+// handleTmuxPickerKey moves through the panes. Enter sends prompts to the
+// selected pane from now on, a clears the choice so the Claude Code pane is
+// found automatically again, esc leaves the target unchanged.
+func (m *Model) handleTmuxPickerKey(key string) {
+	switch key {
+	case "j", "down":
+		m.tmuxPaneIndex = (m.tmuxPaneIndex + 1) % len(m.tmuxPanes)
+	case "k", "up":
+		m.tmuxPaneIndex = (m.tmuxPaneIndex - 1 + len(m.tmuxPanes)) % len(m.tmuxPanes)
+	case "enter":
+		// The pane ID survives windows being renumbered or moved
+		p := m.tmuxPanes[m.tmuxPaneIndex]
+		m.promptTmuxTarget = p.ID
+		m.promptInjectMethod = prompt.InjectTmux
+		m.showTmuxPicker = false
+		m.addToast("Sending prompts to tmux "+p.Target, ToastSuccess)
+	case "a":
+		m.promptTmuxTarget = ""
+		m.showTmuxPicker = false
+		m.addToast("Sending prompts to the pane running Claude Code", ToastInfo)
+	case "esc", "q":
+		m.showTmuxPicker = false
+	}
+}
+
+// renderTmuxPicker renders the pane list over the current view
+func (m Model) renderTmuxPicker() string {
+	var sb strings.Builder
+	sb.WriteString("\n  " + m.theme.Title.Render("Send prompts to tmux pane") + "\n\n")
+	for i, p := range m.tmuxPanes {
+		line := fmt.Sprintf("%-16s %-10s %s", p.Target, p.Command, p.Title)
+		marker := ""
+		if p.RunsClaude() {
+			marker = m.theme.Added.Render("  claude")
+		}
+		if i == m.tmuxPaneIndex {
+			sb.WriteString("  " + m.theme.Selected.Render("▸ "+line) + marker + "\n")
+		} else {
+			sb.WriteString("    " + m.theme.Normal.Render(line) + marker + "\n")
+		}
+	}
+	sb.WriteString("\n  " + m.theme.Dim.Render("j/k: move  enter: send here  a: auto-detect  esc: cancel") + "\n")
+	return sb.String()
+}
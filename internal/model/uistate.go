@@ -0,0 +1,138 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/ztaylor/claude-mon/internal/config"
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// Bounds and step for resizing the list pane, in percent of the width
+const (
+	minLeftPanePercent  = 20
+	maxLeftPanePercent  = 60
+	leftPanePercentStep = 5
+)
+
+// WithRestoredState restores the tab, layout and selection saved for the
+// workspace in the state file at path, and saves them there on Close
+func WithRestoredState(path string) Option {
+	return func(m *Model) {
+		m.statePath = path
+	}
+}
+
+// leftPaneWidth returns the list pane's share of the width: the percent it
+// was resized to, or a third
+func (m Model) leftPaneWidth() int {
+	if m.leftPanePercent == 0 {
+		return m.width / 3
+	}
+	return m.width * m.leftPanePercent / 100
+}
+
+// resizeLeftPane grows the list pane by delta percent of the width, or
+// shrinks it for a negative delta
+func (m *Model) resizeLeftPane(delta int) {
+	percent := m.leftPanePercent
+	if percent == 0 {
+		percent = 100 / 3
+	}
+	m.leftPanePercent = max(minLeftPanePercent, min(percent+delta, maxLeftPanePercent))
+	m.updateViewportSize()
+	m.diffViewport.SetContent(m.renderRightPane())
+}
+
+// restoreUIState applies the state saved for the workspace. A missing or
+// corrupt state file leaves the defaults.
+func (m *Model) restoreUIState() {
+	state, err := config.LoadUIState(m.statePath)
+	if err != nil {
+		logger.Log("Failed to load UI state, starting fresh: %v", err)
+	}
+	m.uiState = state
+	dir, err := m.fs.Getwd()
+	if err != nil {
+		return
+	}
+	ws := state.Workspace(dir)
+
+	if ws.LeftPanePercent != 0 {
+		m.leftPanePercent = max(minLeftPanePercent, min(ws.LeftPanePercent, maxLeftPanePercent))
+	}
+	m.showMinimap = !ws.HideMinimap
+	m.hideLeftPane = ws.HideLeftPane
+	switch ws.PromptFilter {
+	case "project":
+		m.promptFilter = PromptFilterProject
+	case "global":
+		m.promptFilter = PromptFilterGlobal
+	}
+
+	// History loads from the daemon after startup, so the change is
+	// selected once it arrives if it isn't loaded yet
+	m.restoredChange = ws.SelectedChange
+	m.selectRestoredChange()
+
+	for _, tab := range tabBarTabs {
+		if strings.ToLower(tab.name) == ws.Tab && tab.mode != m.leftPaneMode {
+			m.switchToMode(tab.mode)
+		}
+	}
+	if m.hideLeftPane {
+		m.activePane = PaneRight
+	}
+}
+
+// selectRestoredChange selects the change selected when the state was
+// saved, if it's loaded
+func (m *Model) selectRestoredChange() bool {
+	if m.restoredChange == "" {
+		return false
+	}
+	for i, c := range m.changes {
+		if historyKey(c) == m.restoredChange {
+			m.selectedIndex = i
+			m.restoredChange = ""
+			return true
+		}
+	}
+	return false
+}
+
+// saveUIState saves the tab, layout and selection for the workspace
+func (m Model) saveUIState() {
+	if m.uiState == nil {
+		return
+	}
+	dir, err := m.fs.Getwd()
+	if err != nil {
+		return
+	}
+
+	ws := config.WorkspaceState{
+		LeftPanePercent: m.leftPanePercent,
+		HideMinimap:     !m.showMinimap,
+		HideLeftPane:    m.hideLeftPane,
+		SelectedChange:  m.restoredChange,
+	}
+	for _, tab := range tabBarTabs {
+		if tab.mode == m.leftPaneMode {
+			ws.Tab = strings.ToLower(tab.name)
+		}
+	}
+	switch m.promptFilter {
+	case PromptFilterProject:
+		ws.PromptFilter = "project"
+	case PromptFilterGlobal:
+		ws.PromptFilter = "global"
+	}
+	// A change that never loaded stays the one to restore
+	if ws.SelectedChange == "" && m.selectedIndex < len(m.changes) {
+		ws.SelectedChange = historyKey(m.changes[m.selectedIndex])
+	}
+
+	if err := m.uiState.SetWorkspace(dir, ws); err != nil {
+		logger.Log("Failed to save UI state: %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/ztaylor/claude-mon/internal/vcs"
+)
+
+// vcsStatusCmd looks up the workspace's branch and whether it has
+// uncommitted changes
+func (m Model) vcsStatusCmd() tea.Cmd {
+	dir, err := m.fs.Getwd()
+	if err != nil {
+		return nil
+	}
+	return func() tea.Msg {
+		status, err := vcs.GetStatus(dir, "")
+		return vcsStatusMsg{status: status, err: err}
+	}
+}
+
+// refreshVCSStatus looks the branch up again unless a lookup is already
+// running, so a burst of edits runs git once
+func (m *Model) refreshVCSStatus() tea.Cmd {
+	if m.vcsStatusPending {
+		return nil
+	}
+	cmd := m.vcsStatusCmd()
+	m.vcsStatusPending = cmd != nil
+	return cmd
+}
+
+// updateVCSStatus stores a looked up branch, warning when it changed since
+// the last lookup so edits landing on the wrong branch get noticed
+func (m *Model) updateVCSStatus(msg vcsStatusMsg) {
+	m.vcsStatusPending = false
+	if msg.err != nil {
+		m.vcsStatus = nil
+		return
+	}
+	if m.vcsStatus != nil && m.vcsStatus.Branch != "" && msg.status.Branch != m.vcsStatus.Branch {
+		to := msg.status.Branch
+		if to == "" {
+			to = "(detached)"
+		}
+		m.addToast(fmt.Sprintf("Branch changed: %s → %s", m.vcsStatus.Branch, to), ToastWarning)
+	}
+	status := msg.status
+	m.vcsStatus = &status
+}
+
+// renderVCSStatus renders the header's branch indicator: the branch, how
+// far it is ahead of and behind its upstream, and * when the working copy
+// is dirty
+func (m Model) renderVCSStatus() string {
+	if m.vcsStatus == nil {
+		return ""
+	}
+	s := *m.vcsStatus
+	text := "⎇ " + s.Branch
+	if s.Branch == "" {
+		text = "⎇ (detached)"
+	}
+	if s.Ahead > 0 {
+		text += fmt.Sprintf(" ↑%d", s.Ahead)
+	}
+	if s.Behind > 0 {
+		text += fmt.Sprintf(" ↓%d", s.Behind)
+	}
+	if s.Dirty {
+		return m.theme.Modified.Render(text + " *")
+	}
+	return m.theme.Dim.Render(text)
+}
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// Warning types, the keys snoozes and dismissals are stored under
+const (
+	warnStaleContext       = "stale_context"
+	warnDaemonDisconnected = "daemon_disconnected"
+	warnDaemonOutdated     = "daemon_outdated"
+)
+
+// warning is a condition shown in the status bar until it clears or the
+// user snoozes or dismisses it
+type warning struct {
+	kind string
+	text string
+}
+
+// activeWarnings returns the warnings whose conditions currently hold, most
+// important first, whether or not they are suppressed
+func (m Model) activeWarnings() []warning {
+	var warnings []warning
+	if m.daemonConnected && m.daemonVersion != "" && m.version != "" && m.daemonVersion != m.version {
+		warnings = append(warnings, warning{warnDaemonOutdated,
+			"Daemon runs claude-mon " + m.daemonVersion + ", this is " + m.version + "; restart it"})
+	}
+	if !m.daemonConnected && !m.daemonLastCheck.IsZero() {
+		warnings = append(warnings, warning{warnDaemonDisconnected, "Daemon not running; edits won't be persisted"})
+	}
+	if m.contextCurrent != nil && m.contextCurrent.Updated != "" && m.contextCurrent.IsStale() {
+		warnings = append(warnings, warning{warnStaleContext, staleContextText(m.contextCurrent)})
+	}
+	return warnings
+}
+
+// warningSuppressed reports whether the user snoozed or dismissed a warning type
+func (m Model) warningSuppressed(kind string) bool {
+	return m.warnings != nil && m.warnings.Suppressed(kind, m.clock.Now())
+}
+
+// currentWarning returns the warning shown in the status bar, if any
+func (m Model) currentWarning() *warning {
+	for _, w := range m.activeWarnings() {
+		if !m.warningSuppressed(w.kind) {
+			return &w
+		}
+	}
+	return nil
+}
+
+// snoozeWarning hides the current warning until tomorrow, or for good when
+// forever is set
+func (m *Model) snoozeWarning(forever bool) {
+	w := m.currentWarning()
+	if w == nil || m.warnings == nil {
+		m.addToast("No warning to snooze", ToastInfo)
+		return
+	}
+
+	var err error
+	if forever {
+		err = m.warnings.Dismiss(w.kind)
+	} else {
+		err = m.warnings.SnoozeToday(w.kind, m.clock.Now())
+	}
+	if err != nil {
+		logger.Log("Failed to save warning state: %v", err)
+		m.addToast("Failed to save: "+err.Error(), ToastError)
+		return
+	}
+
+	if forever {
+		m.addToast("Won't show this warning again", ToastSuccess)
+	} else {
+		m.addToast("Warning snoozed until tomorrow", ToastSuccess)
+	}
+}
+
+// renderWarningBanner renders a warning for the status bar with the keys
+// that acknowledge it
+func (m Model) renderWarningBanner(w warning) string {
+	leader := m.config.LeaderKey
+	return m.theme.Modified.Render("⚠ "+w.text) + "  " +
+		m.theme.Dim.Render(leader+" z:snooze today  "+leader+" Z:don't show again")
+}
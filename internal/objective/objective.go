@@ -0,0 +1,295 @@
+// Package objective keeps a per-workspace queue of objectives for Claude to
+// work through one at a time in objective (print) mode, with the output and
+// exit status of each run.
+package objective
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// QueuesDir is where queue files are stored
+var QueuesDir = filepath.Join(os.Getenv("HOME"), ".claude-mon", "objectives")
+
+// MaxOutput is how much of an objective's output is kept, from the end
+const MaxOutput = 64 * 1024
+
+// Status is where an objective is in the queue
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusDone      Status = "done"   // Claude exited with status 0
+	StatusFailed    Status = "failed" // Claude exited with an error, or couldn't start
+	StatusCancelled Status = "cancelled"
+)
+
+// Objective is one prompt or plan for Claude to carry out
+type Objective struct {
+	ID         int       `json:"id"`
+	Title      string    `json:"title"` // Short label, e.g. the prompt name
+	Prompt     string    `json:"prompt"`
+	Status     Status    `json:"status"`
+	ExitCode   int       `json:"exit_code"`
+	Output     string    `json:"output,omitempty"`
+	AddedAt    time.Time `json:"added_at"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+}
+
+// Finished reports whether the objective has stopped for good
+func (o *Objective) Finished() bool {
+	return o.Status == StatusDone || o.Status == StatusFailed || o.Status == StatusCancelled
+}
+
+// Duration returns how long the objective ran, or has been running
+func (o *Objective) Duration() time.Duration {
+	switch {
+	case o.StartedAt.IsZero():
+		return 0
+	case o.FinishedAt.IsZero():
+		return time.Since(o.StartedAt)
+	default:
+		return o.FinishedAt.Sub(o.StartedAt)
+	}
+}
+
+// Queue is a workspace's objectives in the order they run
+type Queue struct {
+	Objectives []*Objective `json:"objectives"`
+	NextID     int          `json:"next_id"`
+
+	path string
+}
+
+// PathFor returns the queue file for a workspace
+func PathFor(workspace string) string {
+	hash := sha256.Sum256([]byte(workspace))
+	name := strings.ToLower(strings.ReplaceAll(filepath.Base(workspace), " ", "-"))
+	if len(name) > 20 {
+		name = name[:20]
+	}
+	return filepath.Join(QueuesDir, fmt.Sprintf("%s-%s.json", name, hex.EncodeToString(hash[:])[:12]))
+}
+
+// Load reads a queue file. A missing file is an empty queue. An objective
+// left running by a claude-mon that exited is marked failed, since its
+// Claude process went with it.
+func Load(path string) (*Queue, error) {
+	q := &Queue{NextID: 1, path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read objective queue: %w", err)
+	}
+	if err := json.Unmarshal(data, q); err != nil {
+		return nil, fmt.Errorf("failed to parse objective queue: %w", err)
+	}
+
+	for _, o := range q.Objectives {
+		if o.Status == StatusRunning {
+			o.Status = StatusFailed
+			o.ExitCode = -1
+			o.FinishedAt = time.Now()
+			o.Output = strings.TrimSpace(o.Output + "\n\n[interrupted: claude-mon exited while this objective ran]")
+		}
+	}
+	return q, nil
+}
+
+// Save writes the queue back to its file
+func (q *Queue) Save() error {
+	if err := os.MkdirAll(filepath.Dir(q.path), 0755); err != nil {
+		return fmt.Errorf("failed to create objectives directory: %w", err)
+	}
+	data, err := json.MarshalIndent(q, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal objective queue: %w", err)
+	}
+	if err := os.WriteFile(q.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write objective queue: %w", err)
+	}
+	return nil
+}
+
+// Add appends an objective to the end of the queue
+func (q *Queue) Add(title, prompt string) *Objective {
+	if q.NextID < 1 {
+		q.NextID = 1
+	}
+	o := &Objective{
+		ID:      q.NextID,
+		Title:   title,
+		Prompt:  prompt,
+		Status:  StatusPending,
+		AddedAt: time.Now(),
+	}
+	q.NextID++
+	q.Objectives = append(q.Objectives, o)
+	return o
+}
+
+// Get returns the objective with the given ID, or nil
+func (q *Queue) Get(id int) *Objective {
+	for _, o := range q.Objectives {
+		if o.ID == id {
+			return o
+		}
+	}
+	return nil
+}
+
+// Next returns the first pending objective, or nil when none are left
+func (q *Queue) Next() *Objective {
+	for _, o := range q.Objectives {
+		if o.Status == StatusPending {
+			return o
+		}
+	}
+	return nil
+}
+
+// Running returns the objective whose Claude process hasn't finished, or
+// nil. It may already be marked cancelled.
+func (q *Queue) Running() *Objective {
+	for _, o := range q.Objectives {
+		if !o.StartedAt.IsZero() && o.FinishedAt.IsZero() {
+			return o
+		}
+	}
+	return nil
+}
+
+// Pending returns how many objectives are waiting to run
+func (q *Queue) Pending() int {
+	n := 0
+	for _, o := range q.Objectives {
+		if o.Status == StatusPending {
+			n++
+		}
+	}
+	return n
+}
+
+// Start marks a pending objective as running
+func (q *Queue) Start(id int) error {
+	o := q.Get(id)
+	if o == nil {
+		return fmt.Errorf("objective %d not found", id)
+	}
+	if o.Status != StatusPending {
+		return fmt.Errorf("objective %d is %s, not pending", id, o.Status)
+	}
+	o.Status = StatusRunning
+	o.StartedAt = time.Now()
+	return nil
+}
+
+// Finish records how a run ended. An objective cancelled while it ran stays
+// cancelled, keeping the output it got to.
+func (q *Queue) Finish(id, exitCode int, output string) error {
+	o := q.Get(id)
+	if o == nil {
+		return fmt.Errorf("objective %d not found", id)
+	}
+	if len(output) > MaxOutput {
+		output = output[len(output)-MaxOutput:]
+	}
+	o.Output = output
+	o.ExitCode = exitCode
+	o.FinishedAt = time.Now()
+	switch {
+	case o.Status == StatusCancelled:
+	case exitCode == 0:
+		o.Status = StatusDone
+	default:
+		o.Status = StatusFailed
+	}
+	return nil
+}
+
+// Cancel stops a pending objective from running. A running one is marked
+// cancelled; stopping its Claude process is up to the caller.
+func (q *Queue) Cancel(id int) error {
+	o := q.Get(id)
+	if o == nil {
+		return fmt.Errorf("objective %d not found", id)
+	}
+	if o.Finished() {
+		return fmt.Errorf("objective %d already %s", id, o.Status)
+	}
+	if o.Status == StatusPending {
+		o.FinishedAt = time.Now()
+	}
+	o.Status = StatusCancelled
+	return nil
+}
+
+// Retry puts a failed or cancelled objective back at the end of the queue
+func (q *Queue) Retry(id int) error {
+	idx := q.Index(id)
+	if idx < 0 {
+		return fmt.Errorf("objective %d not found", id)
+	}
+	o := q.Objectives[idx]
+	if o.Status != StatusFailed && o.Status != StatusCancelled {
+		return fmt.Errorf("objective %d is %s", id, o.Status)
+	}
+	*o = Objective{ID: o.ID, Title: o.Title, Prompt: o.Prompt, Status: StatusPending, AddedAt: time.Now()}
+	q.Objectives = append(append(q.Objectives[:idx:idx], q.Objectives[idx+1:]...), o)
+	return nil
+}
+
+// Remove deletes an objective that isn't running
+func (q *Queue) Remove(id int) error {
+	idx := q.Index(id)
+	if idx < 0 {
+		return fmt.Errorf("objective %d not found", id)
+	}
+	if q.Objectives[idx].Status == StatusRunning {
+		return fmt.Errorf("objective %d is running; cancel it first", id)
+	}
+	q.Objectives = append(q.Objectives[:idx], q.Objectives[idx+1:]...)
+	return nil
+}
+
+// Move swaps a pending objective with the next pending one before it
+// (delta < 0) or after it (delta > 0). Objectives that ran stay where they
+// are. Returns false if there's nothing to swap with.
+func (q *Queue) Move(id, delta int) bool {
+	idx := q.Index(id)
+	if idx < 0 || delta == 0 || q.Objectives[idx].Status != StatusPending {
+		return false
+	}
+	step := 1
+	if delta < 0 {
+		step = -1
+	}
+	for j := idx + step; j >= 0 && j < len(q.Objectives); j += step {
+		if q.Objectives[j].Status == StatusPending {
+			q.Objectives[idx], q.Objectives[j] = q.Objectives[j], q.Objectives[idx]
+			return true
+		}
+	}
+	return false
+}
+
+// Index returns the position of an objective in the queue, or -1
+func (q *Queue) Index(id int) int {
+	for i, o := range q.Objectives {
+		if o.ID == id {
+			return i
+		}
+	}
+	return -1
+}
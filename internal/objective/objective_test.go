@@ -0,0 +1,117 @@
+package objective
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ids returns the queue's objective IDs in order
+func ids(q *Queue) []int {
+	var out []int
+	for _, o := range q.Objectives {
+		out = append(out, o.ID)
+	}
+	return out
+}
+
+func TestQueueRunOrder(t *testing.T) {
+	q, err := Load(filepath.Join(t.TempDir(), "queue.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := q.Add("a", "rename Foo to Bar")
+	b := q.Add("b", "add tests")
+	c := q.Add("c", "update docs")
+
+	if got := q.Next(); got != a {
+		t.Fatalf("expected the first objective next, got %+v", got)
+	}
+	if err := q.Start(a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if q.Running() != a || q.Next() != b {
+		t.Fatal("expected a running and b next")
+	}
+
+	// Only pending objectives move, and only past other pending ones
+	if q.Move(a.ID, 1) {
+		t.Error("expected the running objective to stay put")
+	}
+	if !q.Move(c.ID, -1) {
+		t.Fatal("expected c to move up")
+	}
+	if q.Move(c.ID, -1) {
+		t.Error("expected c not to move past the running objective")
+	}
+	if got := ids(q); got[0] != a.ID || got[1] != c.ID || got[2] != b.ID {
+		t.Errorf("unexpected order %v", got)
+	}
+
+	if err := q.Finish(a.ID, 0, "done"); err != nil {
+		t.Fatal(err)
+	}
+	if a.Status != StatusDone || q.Next() != c {
+		t.Errorf("expected a done and c next, got %s and %+v", a.Status, q.Next())
+	}
+
+	// A run cancelled midway stays cancelled once its process exits
+	q.Start(c.ID)
+	if err := q.Cancel(c.ID); err != nil {
+		t.Fatal(err)
+	}
+	if q.Running() != c {
+		t.Error("expected c to count as running until its process exits")
+	}
+	q.Finish(c.ID, -1, "partial")
+	if c.Status != StatusCancelled || c.Output != "partial" {
+		t.Errorf("expected c cancelled with its output, got %+v", c)
+	}
+
+	q.Start(b.ID)
+	q.Finish(b.ID, 1, "error")
+	if b.Status != StatusFailed || q.Next() != nil {
+		t.Errorf("expected b failed and nothing left, got %+v", b)
+	}
+
+	// Retrying puts an objective back at the end of the queue
+	if err := q.Retry(c.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(q); got[len(got)-1] != c.ID || c.Status != StatusPending || c.Output != "" {
+		t.Errorf("expected c pending at the end, got %v %+v", got, c)
+	}
+	if err := q.Retry(a.ID); err == nil {
+		t.Error("expected retrying a finished objective to fail")
+	}
+}
+
+func TestQueueSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	q, _ := Load(path)
+	q.Add("", "first")
+	running := q.Add("", "second")
+	q.Start(running.ID)
+	q.Finish(q.Add("", "third").ID, 0, strings.Repeat("x", MaxOutput+10))
+	if err := q.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Objectives) != 3 || loaded.NextID != 4 {
+		t.Fatalf("unexpected queue %+v", loaded)
+	}
+	// A run claude-mon didn't see finish can't be resumed
+	if o := loaded.Get(running.ID); o.Status != StatusFailed || !strings.Contains(o.Output, "interrupted") {
+		t.Errorf("expected the running objective marked failed, got %+v", o)
+	}
+	if len(loaded.Get(3).Output) != MaxOutput {
+		t.Errorf("expected output capped at %d bytes", MaxOutput)
+	}
+	if added := loaded.Add("", "fourth"); added.ID != 4 {
+		t.Errorf("expected IDs to continue from 4, got %d", added.ID)
+	}
+}
@@ -0,0 +1,126 @@
+package plan
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ArchiveDir is the subdirectory of a plans directory archived plans move to
+const ArchiveDir = "archive"
+
+// Info is a plan file found by List
+type Info struct {
+	Path     string
+	Name     string // File name without .md
+	Local    bool   // In the workspace's .claude/plans rather than ~/.claude/plans
+	Archived bool
+	ModTime  time.Time
+}
+
+// Dirs returns the directories plans are kept in: ~/.claude/plans and the
+// workspace's own .claude/plans
+func Dirs(home, cwd string) []string {
+	return []string{
+		filepath.Join(home, ".claude", "plans"),
+		filepath.Join(cwd, ".claude", "plans"),
+	}
+}
+
+// List returns the global and workspace plans, most recently modified
+// first. Archived plans are included when archived is true.
+func List(home, cwd string, archived bool) ([]Info, error) {
+	var plans []Info
+	for i, dir := range Dirs(home, cwd) {
+		dirs := []string{dir}
+		if archived {
+			dirs = append(dirs, filepath.Join(dir, ArchiveDir))
+		}
+		for _, d := range dirs {
+			entries, err := os.ReadDir(d)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to list plans: %w", err)
+			}
+			for _, e := range entries {
+				if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
+					continue
+				}
+				info, err := e.Info()
+				if err != nil {
+					continue
+				}
+				plans = append(plans, Info{
+					Path:     filepath.Join(d, e.Name()),
+					Name:     strings.TrimSuffix(e.Name(), ".md"),
+					Local:    i == 1,
+					Archived: d != dir,
+					ModTime:  info.ModTime(),
+				})
+			}
+		}
+	}
+	sort.SliceStable(plans, func(i, j int) bool {
+		return plans[i].ModTime.After(plans[j].ModTime)
+	})
+	return plans, nil
+}
+
+// Archive moves a plan into its directory's archive, returning the new path
+func Archive(path string) (string, error) {
+	dir := filepath.Join(filepath.Dir(path), ArchiveDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create plan archive: %w", err)
+	}
+	return movePlan(path, filepath.Join(dir, filepath.Base(path)))
+}
+
+// Unarchive moves an archived plan back out of the archive, returning the
+// new path
+func Unarchive(path string) (string, error) {
+	if filepath.Base(filepath.Dir(path)) != ArchiveDir {
+		return "", fmt.Errorf("%s isn't archived", filepath.Base(path))
+	}
+	return movePlan(path, filepath.Join(filepath.Dir(filepath.Dir(path)), filepath.Base(path)))
+}
+
+// movePlan renames a plan without overwriting another plan of the same name
+func movePlan(from, to string) (string, error) {
+	if _, err := os.Stat(to); err == nil {
+		return "", fmt.Errorf("a plan named %s already exists there", filepath.Base(to))
+	}
+	if err := os.Rename(from, to); err != nil {
+		return "", fmt.Errorf("failed to move plan: %w", err)
+	}
+	return to, nil
+}
+
+// Match reports whether query fuzzily matches name: its characters appear in
+// name in order, ignoring case. Substring matches score higher; the score is
+// 0 when there's no match.
+func Match(query, name string) int {
+	query, name = strings.ToLower(query), strings.ToLower(name)
+	if query == "" {
+		return 1
+	}
+	if i := strings.Index(name, query); i >= 0 {
+		// Earlier substring matches rank first
+		return 1000 - min(i, 999)
+	}
+	q := []rune(query)
+	j := 0
+	for _, r := range name {
+		if j < len(q) && r == q[j] {
+			j++
+		}
+	}
+	if j < len(q) {
+		return 0
+	}
+	return 1
+}
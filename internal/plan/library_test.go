@@ -0,0 +1,77 @@
+package plan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestListAndArchive(t *testing.T) {
+	home, cwd := t.TempDir(), t.TempDir()
+	global, local := Dirs(home, cwd)[0], Dirs(home, cwd)[1]
+	for _, dir := range []string{global, local} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().Add(-time.Hour)
+	os.WriteFile(filepath.Join(global, "dancing-nova.md"), []byte("# Plan"), 0644)
+	os.Chtimes(filepath.Join(global, "dancing-nova.md"), old, old)
+	os.WriteFile(filepath.Join(local, "auth-rewrite.md"), []byte("# Plan"), 0644)
+	os.WriteFile(filepath.Join(local, "notes.txt"), []byte("not a plan"), 0644)
+
+	plans, err := List(home, cwd, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plans) != 2 || plans[0].Name != "auth-rewrite" || !plans[0].Local || plans[1].Local {
+		t.Fatalf("expected the local plan first, got %+v", plans)
+	}
+
+	archived, err := Archive(plans[1].Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plans, _ = List(home, cwd, false); len(plans) != 1 {
+		t.Errorf("expected the archived plan hidden, got %+v", plans)
+	}
+	if plans, _ = List(home, cwd, true); len(plans) != 2 || !plans[1].Archived {
+		t.Errorf("expected the archived plan listed, got %+v", plans)
+	}
+
+	// Unarchiving doesn't overwrite a plan that took its name
+	os.WriteFile(filepath.Join(global, "dancing-nova.md"), []byte("# New"), 0644)
+	if _, err := Unarchive(archived); err == nil {
+		t.Error("expected unarchiving over an existing plan to fail")
+	}
+	os.Remove(filepath.Join(global, "dancing-nova.md"))
+	if path, err := Unarchive(archived); err != nil || path != filepath.Join(global, "dancing-nova.md") {
+		t.Errorf("expected the plan back in place, got %q (%v)", path, err)
+	}
+	if _, err := Unarchive(filepath.Join(global, "dancing-nova.md")); err == nil {
+		t.Error("expected unarchiving a plan that isn't archived to fail")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		query, name string
+		match       bool
+	}{
+		{"", "anything", true},
+		{"auth", "auth-rewrite", true},
+		{"arw", "auth-rewrite", true},
+		{"AUTH", "auth-rewrite", true},
+		{"wra", "auth-rewrite", false},
+		{"xyz", "auth-rewrite", false},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.query, tt.name) > 0; got != tt.match {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.query, tt.name, got, tt.match)
+		}
+	}
+	if Match("rew", "auth-rewrite") <= Match("arw", "auth-rewrite") {
+		t.Error("expected substring matches to rank above scattered ones")
+	}
+}
@@ -1,7 +1,9 @@
 package plan
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
 	"os/exec"
@@ -203,3 +205,91 @@ func ListPlans() ([]string, error) {
 func DeletePlan(path string) error {
 	return os.Remove(path)
 }
+
+// ProjectDir converts a workspace path to the name of the directory Claude
+// keeps its session transcripts in, under ~/.claude/projects
+// /Users/foo/bar.baz → -Users-foo-bar-baz
+func ProjectDir(cwd string) string {
+	result := strings.ReplaceAll(cwd, "/", "-")
+	result = strings.ReplaceAll(result, ".", "-")
+	return result
+}
+
+// SlugFromSession reads the plan slug from the last entry in a session
+// transcript (.jsonl)
+func SlugFromSession(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	// Seek to end and read last chunk (avoid loading huge file)
+	stat, err := f.Stat()
+	if err != nil {
+		return ""
+	}
+	size := stat.Size()
+	readSize := int64(4096)
+	if size < readSize {
+		readSize = size
+	}
+
+	if _, err := f.Seek(-readSize, io.SeekEnd); err != nil {
+		// If seek fails, try reading from start
+		f.Seek(0, io.SeekStart)
+	}
+
+	buf := make([]byte, readSize)
+	n, err := f.Read(buf)
+	if err != nil && n == 0 {
+		return ""
+	}
+
+	// Find last complete JSON line with slug
+	lines := strings.Split(string(buf[:n]), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line == "" {
+			continue
+		}
+
+		// Parse JSON and extract slug
+		var entry struct {
+			Slug string `json:"slug"`
+		}
+		if json.Unmarshal([]byte(line), &entry) == nil && entry.Slug != "" {
+			return entry.Slug
+		}
+	}
+	return ""
+}
+
+// WorkspacePlans returns the plan files referenced by any of a workspace's
+// session transcripts
+func WorkspacePlans(home, cwd string) []string {
+	projectPath := filepath.Join(home, ".claude", "projects", ProjectDir(cwd))
+	entries, err := os.ReadDir(projectPath)
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var plans []string
+	for _, e := range entries {
+		if !strings.HasSuffix(e.Name(), ".jsonl") {
+			continue
+		}
+		slug := SlugFromSession(filepath.Join(projectPath, e.Name()))
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+
+		planPath := filepath.Join(home, ".claude", "plans", slug+".md")
+		if _, err := os.Stat(planPath); err == nil {
+			plans = append(plans, planPath)
+		}
+	}
+	return plans
+}
@@ -0,0 +1,96 @@
+package plan
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// taskPattern matches a markdown checkbox item: "- [ ] task", "* [x] task",
+// "1. [ ] task"
+var taskPattern = regexp.MustCompile(`^(\s*)(?:[-*+]|\d+[.)])\s+\[([ xX])\]\s+(.*)$`)
+
+// Task is a checkbox item in a plan
+type Task struct {
+	Line    int    // Line in the plan, counting from 0
+	Text    string // Task text without the checkbox
+	Done    bool
+	Depth   int    // Nesting level, 0 for top-level items
+	Section string // Nearest heading above the task, without the #s
+}
+
+// ParseTasks returns the checkbox items of a plan in order. Items inside
+// fenced code blocks are skipped.
+func ParseTasks(content string) []Task {
+	var tasks []Task
+	section := ""
+	inFence := false
+	for i, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			section = strings.TrimSpace(strings.TrimLeft(trimmed, "#"))
+			continue
+		}
+		match := taskPattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil {
+			continue
+		}
+		indent := strings.ReplaceAll(match[1], "\t", "  ")
+		tasks = append(tasks, Task{
+			Line:    i,
+			Text:    strings.TrimSpace(match[3]),
+			Done:    match[2] != " ",
+			Depth:   len(indent) / 2,
+			Section: section,
+		})
+	}
+	return tasks
+}
+
+// Progress returns how many of the tasks are done
+func Progress(tasks []Task) (done, total int) {
+	for _, t := range tasks {
+		if t.Done {
+			done++
+		}
+	}
+	return done, len(tasks)
+}
+
+// ToggleTask flips a task's checkbox in the plan file and returns the task
+// as it now reads. It fails if the task's line no longer holds the same task,
+// i.e. the plan changed since it was parsed.
+func ToggleTask(path string, task Task) (Task, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return task, fmt.Errorf("failed to read plan: %w", err)
+	}
+	lines := strings.Split(string(data), "\n")
+	if task.Line < 0 || task.Line >= len(lines) {
+		return task, fmt.Errorf("plan changed since it was loaded; refresh it")
+	}
+	match := taskPattern.FindStringSubmatchIndex(strings.TrimRight(lines[task.Line], "\r"))
+	if match == nil || strings.TrimSpace(lines[task.Line][match[6]:match[7]]) != task.Text {
+		return task, fmt.Errorf("plan changed since it was loaded; refresh it")
+	}
+
+	mark := "x"
+	if task.Done {
+		mark = " "
+	}
+	line := lines[task.Line]
+	lines[task.Line] = line[:match[4]] + mark + line[match[5]:]
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		return task, fmt.Errorf("failed to write plan: %w", err)
+	}
+	task.Done = !task.Done
+	return task, nil
+}
@@ -0,0 +1,80 @@
+package plan
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testPlan = `# Plan: Widgets
+
+## Phase 1: Parser
+- [x] Parse widgets
+- [ ] Handle errors
+  - [X] Empty input
+
+` + "```markdown\n- [ ] not a task\n```" + `
+
+### Phase 2: Output
+1. [ ] Print widgets
+* [ ]   Trailing spaces
+- [] not a checkbox
+`
+
+func TestParseTasks(t *testing.T) {
+	tasks := ParseTasks(testPlan)
+	if len(tasks) != 5 {
+		t.Fatalf("expected 5 tasks, got %+v", tasks)
+	}
+	want := []Task{
+		{Line: 3, Text: "Parse widgets", Done: true, Section: "Phase 1: Parser"},
+		{Line: 4, Text: "Handle errors", Section: "Phase 1: Parser"},
+		{Line: 5, Text: "Empty input", Done: true, Depth: 1, Section: "Phase 1: Parser"},
+		{Line: 12, Text: "Print widgets", Section: "Phase 2: Output"},
+		{Line: 13, Text: "Trailing spaces", Section: "Phase 2: Output"},
+	}
+	for i, w := range want {
+		if tasks[i] != w {
+			t.Errorf("task %d: expected %+v, got %+v", i, w, tasks[i])
+		}
+	}
+	if done, total := Progress(tasks); done != 2 || total != 5 {
+		t.Errorf("expected 2/5 done, got %d/%d", done, total)
+	}
+}
+
+func TestToggleTask(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.md")
+	if err := os.WriteFile(path, []byte(testPlan), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tasks := ParseTasks(testPlan)
+
+	toggled, err := ToggleTask(path, tasks[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !toggled.Done {
+		t.Error("expected the task done")
+	}
+	toggled, err = ToggleTask(path, tasks[3])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ToggleTask(path, toggled); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	want := strings.Replace(testPlan, "- [ ] Handle errors", "- [x] Handle errors", 1)
+	if string(data) != want {
+		t.Errorf("unexpected plan after toggling:\n%s", data)
+	}
+
+	// A task that moved can't be toggled by its old line
+	os.WriteFile(path, []byte("# Plan\n\n"+testPlan), 0644)
+	if _, err := ToggleTask(path, tasks[0]); err == nil {
+		t.Error("expected toggling a stale task to fail")
+	}
+}
@@ -0,0 +1,113 @@
+// Package policy reads a project's .claude-mon-policy.toml, which lists the
+// files Claude must not modify, and undoes edits that break it.
+package policy
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ztaylor/claude-mon/internal/workspace"
+)
+
+// FileName is the policy file, in the workspace root
+const FileName = ".claude-mon-policy.toml"
+
+// Policy is a project's rules for Claude's edits. Globs are matched like
+// the ignore file's: relative to the workspace root when they start with /,
+// at any directory otherwise.
+type Policy struct {
+	Protected  []string `toml:"protected"`   // Globs of files Claude must not modify, e.g. "migrations/**"
+	AutoRevert bool     `toml:"auto_revert"` // Undo edits to protected files as soon as they're seen
+}
+
+// Load reads a workspace's policy. A missing file is an empty policy.
+func Load(ws string) (*Policy, error) {
+	data, err := os.ReadFile(filepath.Join(ws, FileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Policy{}, nil
+		}
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses a policy file's content
+func Parse(data []byte) (*Policy, error) {
+	var p Policy
+	if _, err := toml.Decode(string(data), &p); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", FileName, err)
+	}
+	return &p, nil
+}
+
+// Protects returns the glob protecting a file in workspace ws, or "" if the
+// policy lets Claude edit it. Files outside the workspace aren't covered.
+func (p *Policy) Protects(ws, file string) string {
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(ws, file)
+	}
+	rel, err := filepath.Rel(ws, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return ""
+	}
+	for _, pattern := range p.Protected {
+		if workspace.Match(pattern, "/"+filepath.ToSlash(rel)) {
+			return pattern
+		}
+	}
+	return ""
+}
+
+// FS is the filesystem Revert reads and rewrites files through
+type FS interface {
+	Stat(name string) (fs.FileInfo, error)
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+}
+
+// Edit is one string replacement an edit made
+type Edit struct {
+	OldString string
+	NewString string
+}
+
+// Revert undoes edits to a file, last first, by putting each edit's old
+// string back in place of its new one. Each new string has to appear
+// exactly once, or which one to undo would be a guess; an edit that only
+// deleted text can't be located and isn't undone. The file is left as it
+// was if any edit can't be undone.
+func Revert(fsys FS, path string, edits []Edit) error {
+	if len(edits) == 0 {
+		return fmt.Errorf("nothing to revert in %s", filepath.Base(path))
+	}
+	info, err := fsys.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	content := string(data)
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		if e.NewString == "" {
+			return fmt.Errorf("can't revert %s: an edit deleted text and can't be located", filepath.Base(path))
+		}
+		switch n := strings.Count(content, e.NewString); n {
+		case 0:
+			return fmt.Errorf("can't revert %s: the edit is no longer in the file", filepath.Base(path))
+		case 1:
+			content = strings.Replace(content, e.NewString, e.OldString, 1)
+		default:
+			return fmt.Errorf("can't revert %s: the edited text appears %d times", filepath.Base(path), n)
+		}
+	}
+	return fsys.WriteFile(path, []byte(content), info.Mode().Perm())
+}
@@ -0,0 +1,108 @@
+package policy
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// osFS is the real filesystem
+type osFS struct{}
+
+func (osFS) Stat(name string) (fs.FileInfo, error) { return os.Stat(name) }
+func (osFS) ReadFile(name string) ([]byte, error)  { return os.ReadFile(name) }
+func (osFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+
+func TestLoadAndProtects(t *testing.T) {
+	ws := t.TempDir()
+	if p, err := Load(ws); err != nil || len(p.Protected) != 0 {
+		t.Fatalf("expected an empty policy without a file, got %+v (%v)", p, err)
+	}
+
+	policy := "protected = [\"migrations/**\", \"/.github/**\", \"*.lock\"]\nauto_revert = true\n"
+	if err := os.WriteFile(filepath.Join(ws, FileName), []byte(policy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Load(ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.AutoRevert {
+		t.Error("expected auto_revert to be read")
+	}
+
+	tests := []struct {
+		file string
+		want string
+	}{
+		{filepath.Join(ws, "migrations/0001_init.sql"), "migrations/**"},
+		{filepath.Join(ws, "db/migrations/0002.sql"), "migrations/**"},
+		{"migrations/0003.sql", "migrations/**"},
+		{filepath.Join(ws, ".github/workflows/ci.yml"), "/.github/**"},
+		{filepath.Join(ws, "vendor/.github/x.yml"), ""},
+		{filepath.Join(ws, "web/yarn.lock"), "*.lock"},
+		{filepath.Join(ws, "main.go"), ""},
+		{"/elsewhere/migrations/1.sql", ""},
+	}
+	for _, tt := range tests {
+		if got := p.Protects(ws, tt.file); got != tt.want {
+			t.Errorf("Protects(%s) = %q, want %q", tt.file, got, tt.want)
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(ws, FileName), []byte("protected = ["), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(ws); err == nil {
+		t.Error("expected an error for an invalid policy file")
+	}
+}
+
+func TestRevert(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.sql")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read := func() string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	// A MultiEdit's edits are undone last first
+	write("CREATE TABLE users (id BIGINT, email TEXT);\n")
+	edits := []Edit{{OldString: "id INT", NewString: "id BIGINT"}, {OldString: "name TEXT", NewString: "email TEXT"}}
+	if err := Revert(osFS{}, path, edits); err != nil {
+		t.Fatal(err)
+	}
+	if got := read(); got != "CREATE TABLE users (id INT, name TEXT);\n" {
+		t.Errorf("unexpected reverted content %q", got)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("expected the file mode kept, got %v", info.Mode().Perm())
+	}
+
+	// Ambiguous or missing edits leave the file alone
+	write("a b a\n")
+	if err := Revert(osFS{}, path, []Edit{{OldString: "x", NewString: "a"}}); err == nil {
+		t.Error("expected an error for an edit appearing twice")
+	}
+	if err := Revert(osFS{}, path, []Edit{{OldString: "b", NewString: "c"}}); err == nil {
+		t.Error("expected an error for an edit no longer in the file")
+	}
+	if err := Revert(osFS{}, path, []Edit{{OldString: "b", NewString: ""}}); err == nil {
+		t.Error("expected an error for a deletion")
+	}
+	if got := read(); got != "a b a\n" {
+		t.Errorf("expected the file untouched, got %q", got)
+	}
+}
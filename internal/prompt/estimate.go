@@ -0,0 +1,83 @@
+package prompt
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Rough sizing used for the pre-send estimate. Claude tokenizes English prose
+// and code at roughly four characters per token; the estimate is meant to
+// catch a 20k-token plan, not to bill precisely.
+const (
+	charsPerToken       = 4
+	ContextWindowTokens = 200000 // Claude context window
+	InputCostPerMTok    = 3.00   // USD per million input tokens (Sonnet pricing)
+	WarnTokens          = 10000  // Estimates above this are highlighted
+)
+
+// Estimate is the approximate size and cost of sending a prompt
+type Estimate struct {
+	Tokens       int // Tokens in the expanded prompt
+	StaticTokens int // Tokens in the prompt as written, before variable expansion
+}
+
+// EstimateTokens approximates the token count of content
+func EstimateTokens(content string) int {
+	chars := utf8.RuneCountInString(content)
+	return (chars + charsPerToken - 1) / charsPerToken
+}
+
+// EstimatePrompt estimates an expanded prompt, attributing the difference
+// from the raw template to attached content such as {{plan}}
+func EstimatePrompt(raw, expanded string) Estimate {
+	return Estimate{
+		Tokens:       EstimateTokens(expanded),
+		StaticTokens: EstimateTokens(raw),
+	}
+}
+
+// AttachedTokens returns the tokens added by variable expansion
+func (e Estimate) AttachedTokens() int {
+	if e.Tokens <= e.StaticTokens {
+		return 0
+	}
+	return e.Tokens - e.StaticTokens
+}
+
+// Cost returns the approximate input cost in USD
+func (e Estimate) Cost() float64 {
+	return float64(e.Tokens) * InputCostPerMTok / 1e6
+}
+
+// ContextPercent returns the share of the context window the prompt uses
+func (e Estimate) ContextPercent() float64 {
+	return float64(e.Tokens) * 100 / ContextWindowTokens
+}
+
+// Large reports whether the prompt is big enough to warrant a warning
+func (e Estimate) Large() bool {
+	return e.Tokens >= WarnTokens
+}
+
+// String formats the estimate, e.g. "~12.4k tokens (+11.9k attached), ~$0.04, 6% of context"
+func (e Estimate) String() string {
+	s := "~" + FormatTokens(e.Tokens) + " tokens"
+	if attached := e.AttachedTokens(); attached > 0 {
+		s += " (+" + FormatTokens(attached) + " attached)"
+	}
+	s += fmt.Sprintf(", ~$%.2f", e.Cost())
+
+	percent := e.ContextPercent()
+	if percent < 1 {
+		return s + ", <1% of context"
+	}
+	return s + fmt.Sprintf(", %.0f%% of context", percent)
+}
+
+// FormatTokens formats a token count compactly (950, 12.4k)
+func FormatTokens(n int) string {
+	if n < 1000 {
+		return fmt.Sprintf("%d", n)
+	}
+	return fmt.Sprintf("%.1fk", float64(n)/1000)
+}
@@ -0,0 +1,159 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// gitRepo is the optional git backend for the global prompt library. When
+// the global prompts directory is a git repository, every change to a
+// prompt in it is committed, and Sync pulls and pushes the shared library.
+type gitRepo struct {
+	dir string
+}
+
+// openGitRepo returns the git backend for dir, or nil if dir isn't a repository
+func openGitRepo(dir string) *gitRepo {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		return nil
+	}
+	return &gitRepo{dir: dir}
+}
+
+// run runs a git command in the repository, including git's output in errors
+func (g *gitRepo) run(args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", g.dir}, args...)...)
+	out, err := cmd.CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	if err != nil {
+		if output == "" {
+			return "", fmt.Errorf("git %s: %w", args[0], err)
+		}
+		return output, fmt.Errorf("git %s: %s", args[0], output)
+	}
+	return output, nil
+}
+
+// commit commits changes under paths (all changes when none are given), doing
+// nothing if there are none
+func (g *gitRepo) commit(message string, paths ...string) error {
+	if _, err := g.run(append([]string{"add", "-A", "--"}, paths...)...); err != nil {
+		return err
+	}
+	if _, err := g.run("diff", "--cached", "--quiet"); err == nil {
+		return nil // Nothing staged
+	}
+	_, err := g.run("commit", "--quiet", "-m", message)
+	return err
+}
+
+// EnableGit makes the global prompt library a git repository with remote as
+// its origin: an existing repository gets its origin updated, a missing or
+// empty directory is cloned from remote, and existing prompts are committed
+// into a new repository.
+func (s *Store) EnableGit(remote string) error {
+	if s.library != nil {
+		return fmt.Errorf("prompts are kept by the daemon; unset prompt_store to sync them with git")
+	}
+	if g := openGitRepo(s.globalDir); g != nil {
+		s.git = g
+		current, err := g.run("remote", "get-url", "origin")
+		switch {
+		case err != nil:
+			_, err = g.run("remote", "add", "origin", remote)
+		case current != remote:
+			_, err = g.run("remote", "set-url", "origin", remote)
+		}
+		return err
+	}
+
+	entries, err := os.ReadDir(s.globalDir)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(entries) == 0 {
+		if err := os.MkdirAll(filepath.Dir(s.globalDir), 0755); err != nil {
+			return err
+		}
+		os.Remove(s.globalDir)
+		if err := exec.Command("git", "clone", "--quiet", remote, s.globalDir).Run(); err == nil {
+			s.git = &gitRepo{dir: s.globalDir}
+			return nil
+		}
+		// Unreachable remote: start a local repository and push on the first sync
+	}
+	if err := os.MkdirAll(s.globalDir, 0755); err != nil {
+		return err
+	}
+
+	g := &gitRepo{dir: s.globalDir}
+	if _, err := g.run("init", "--quiet"); err != nil {
+		return err
+	}
+	if _, err := g.run("remote", "add", "origin", remote); err != nil {
+		return err
+	}
+	s.git = g
+	return g.commit("Import prompt library")
+}
+
+// GitEnabled reports whether the global prompt library is git-backed
+func (s *Store) GitEnabled() bool {
+	return s.git != nil
+}
+
+// commitPrompt commits a change to a global prompt, if the library is
+// git-backed. Project prompts belong to the project's own repository.
+func (s *Store) commitPrompt(path, message string) error {
+	if s.git == nil {
+		return nil
+	}
+	rel, err := filepath.Rel(s.globalDir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil
+	}
+	if err := s.git.commit(message, rel); err != nil {
+		return fmt.Errorf("failed to commit prompt: %w", err)
+	}
+	return nil
+}
+
+// Sync commits any uncommitted prompt changes, then pulls (rebasing local
+// commits) and pushes the global prompt library. A Library-backed store
+// rewrites its prompt files from the library instead.
+func (s *Store) Sync() error {
+	if s.library != nil {
+		return s.pull()
+	}
+	if s.git == nil {
+		return fmt.Errorf("prompt library at %s is not a git repository; set prompts_remote in the config", s.globalDir)
+	}
+	g := s.git
+
+	if err := g.commit("Update prompts"); err != nil {
+		return err
+	}
+
+	branch, err := g.run("symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return err
+	}
+
+	// Pull unless the remote doesn't have the branch yet (first push)
+	if _, err := g.run("ls-remote", "--exit-code", "--heads", "origin", branch); err == nil {
+		if _, err := g.run("pull", "--quiet", "--rebase", "origin", branch); err != nil {
+			g.run("rebase", "--abort")
+			return fmt.Errorf("pull failed, local commits kept and nothing pushed: %w", err)
+		}
+	}
+
+	// An empty library has nothing to push
+	if _, err := g.run("rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return nil
+	}
+	_, err = g.run("push", "--quiet", "-u", "origin", branch)
+	return err
+}
@@ -14,31 +14,116 @@ type InjectionMethod int
 const (
 	InjectTmux      InjectionMethod = iota // Send to tmux pane
 	InjectClipboard                        // System clipboard (pbcopy/xclip/xsel)
+	InjectChat                             // Write into a managed Claude chat session's PTY
 )
 
-// Inject sends the prompt content using the specified method
-func Inject(content string, method InjectionMethod) error {
+// Inject sends the prompt content using the specified method. target is the
+// tmux pane for InjectTmux; empty picks the pane running Claude Code.
+func Inject(content string, method InjectionMethod, target string) error {
 	switch method {
 	case InjectTmux:
-		return injectTmux(content)
+		return injectTmux(content, target)
 	case InjectClipboard:
 		return injectClipboard(content)
+	case InjectChat:
+		// The session belongs to the caller, which writes to it directly
+		return fmt.Errorf("no managed chat session to send to")
 	default:
 		return fmt.Errorf("unknown injection method: %d", method)
 	}
 }
 
-// injectTmux sends content to the active tmux pane using send-keys
-func injectTmux(content string) error {
-	// Check if we're in tmux
-	if os.Getenv("TMUX") == "" {
-		return fmt.Errorf("not running inside tmux")
+// TmuxPane is a tmux pane prompts can be sent to
+type TmuxPane struct {
+	ID      string // Unique pane ID, e.g. "%3"
+	Target  string // session:window.pane
+	Command string // Command running in the pane
+	Title   string
+}
+
+// ListTmuxPanes returns every pane on the tmux server except the one
+// claude-mon runs in
+func ListTmuxPanes() ([]TmuxPane, error) {
+	out, err := exec.Command("tmux", "list-panes", "-a", "-F",
+		"#{pane_id}\t#{session_name}:#{window_index}.#{pane_index}\t#{pane_current_command}\t#{pane_title}").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tmux panes: %w", err)
 	}
 
-	// Escape special characters for tmux
-	// send-keys interprets certain sequences, so we use -l for literal
-	cmd := exec.Command("tmux", "send-keys", "-l", content)
-	return cmd.Run()
+	self := os.Getenv("TMUX_PANE")
+	var panes []TmuxPane
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		fields := strings.SplitN(line, "\t", 4)
+		if len(fields) < 4 || fields[0] == self {
+			continue
+		}
+		panes = append(panes, TmuxPane{ID: fields[0], Target: fields[1], Command: fields[2], Title: fields[3]})
+	}
+	return panes, nil
+}
+
+// RunsClaude reports whether the pane looks like it's running Claude Code
+func (p TmuxPane) RunsClaude() bool {
+	return p.Command == "claude" || strings.Contains(strings.ToLower(p.Title), "claude")
+}
+
+// ResolveTmuxTarget returns the session:window.pane a tmux injection goes to.
+// An explicit target is checked to exist; an empty one is the only pane
+// running Claude Code.
+func ResolveTmuxTarget(target string) (string, error) {
+	if target != "" {
+		out, err := exec.Command("tmux", "display-message", "-p", "-t", target,
+			"#{session_name}:#{window_index}.#{pane_index}").Output()
+		if err != nil {
+			return "", fmt.Errorf("tmux pane %q not found", target)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	panes, err := ListTmuxPanes()
+	if err != nil {
+		return "", err
+	}
+	var found []string
+	for _, p := range panes {
+		if p.RunsClaude() {
+			found = append(found, p.Target)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("no tmux pane is running Claude Code; pick a pane or set tmux_target")
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("%d tmux panes are running Claude Code (%s); pick one or set tmux_target",
+			len(found), strings.Join(found, ", "))
+	}
+}
+
+// injectTmux types content into a tmux pane. Multi-line prompts are pasted
+// as a bracketed paste, so their newlines don't submit each line separately.
+func injectTmux(content, target string) error {
+	target, err := ResolveTmuxTarget(target)
+	if err != nil {
+		return err
+	}
+
+	if !strings.Contains(content, "\n") {
+		// -l sends the text literally instead of as key names
+		return exec.Command("tmux", "send-keys", "-t", target, "-l", content).Run()
+	}
+
+	load := exec.Command("tmux", "load-buffer", "-b", "claude-mon", "-")
+	load.Stdin = strings.NewReader(strings.TrimRight(content, "\n"))
+	if out, err := load.CombinedOutput(); err != nil {
+		return fmt.Errorf("tmux load-buffer: %s", strings.TrimSpace(string(out)))
+	}
+	// -p wraps the paste in bracketed paste sequences, -d drops the buffer
+	if out, err := exec.Command("tmux", "paste-buffer", "-p", "-d", "-b", "claude-mon", "-t", target).CombinedOutput(); err != nil {
+		return fmt.Errorf("tmux paste-buffer: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
 }
 
 // injectClipboard copies content to system clipboard
@@ -86,6 +171,8 @@ func MethodName(method InjectionMethod) string {
 		return "tmux"
 	case InjectClipboard:
 		return "clipboard"
+	case InjectChat:
+		return "chat"
 	default:
 		return "unknown"
 	}
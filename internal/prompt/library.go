@@ -0,0 +1,165 @@
+package prompt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ztaylor/claude-mon/internal/logger"
+)
+
+// Library keeps prompts outside the prompt directories, such as in the
+// daemon's database, where every workspace shares them
+type Library interface {
+	Prompts() ([]Prompt, error) // Every prompt, each with its Backups
+	Put(p Prompt) error         // Adds or replaces a prompt, along with its Backups
+	Delete(name string) error
+}
+
+// NewLibraryStore returns a store for prompts kept in lib. They are written
+// out to dir as prompt files, so they're edited and versioned like any
+// other, and every change is saved back to lib. Library prompts are global;
+// there are no project prompts.
+func NewLibraryStore(lib Library, dir string) *Store {
+	return &Store{
+		globalDir:  dir,
+		projectDir: dir,
+		library:    lib,
+	}
+}
+
+// DefaultLibraryDir returns where library prompts are written out by default
+func DefaultLibraryDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".cache", "claude-mon", "prompts")
+}
+
+// IsLibrary reports whether the store's prompts are kept in a Library
+func (s *Store) IsLibrary() bool {
+	return s.library != nil
+}
+
+// pull writes the library's prompts out to the store's directory, removing
+// prompt files the library no longer has
+func (s *Store) pull() error {
+	prompts, err := s.library.Prompts()
+	if err != nil {
+		return err
+	}
+	keep, err := writePromptFiles(prompts, s.globalDir)
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(s.globalDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if name := entry.Name(); strings.HasSuffix(name, ".prompt.md") && !keep[name] {
+			os.Remove(filepath.Join(s.globalDir, name))
+		}
+	}
+	return nil
+}
+
+// writePromptFiles writes prompts and their version backups to dir,
+// returning the names of the files written. Files are only rewritten when
+// they differ, so watchers aren't set off for nothing.
+func writePromptFiles(prompts []Prompt, dir string) (map[string]bool, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create prompts dir: %w", err)
+	}
+	written := make(map[string]bool)
+	for _, p := range prompts {
+		base := promptFileBase(p.Name)
+		files := map[string]string{base + ".prompt.md": p.Format()}
+		for version, content := range p.Backups {
+			files[fmt.Sprintf("%s.v%d.prompt.md", base, version)] = content
+		}
+		for name, content := range files {
+			written[name] = true
+			path := filepath.Join(dir, name)
+			if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, []byte(content)) {
+				continue
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// loadBackups reads the version backups of the prompt at p.Path into
+// p.Backups
+func (s *Store) loadBackups(p *Prompt) error {
+	versions, err := s.ListVersions(p.Path)
+	if err != nil {
+		return err
+	}
+	p.Backups = make(map[int]string, len(versions))
+	for _, v := range versions {
+		content, err := os.ReadFile(v.Path)
+		if err != nil {
+			return err
+		}
+		p.Backups[v.Version] = string(content)
+	}
+	return nil
+}
+
+// publish saves the prompt at path to the library with its version
+// backups, in library mode
+func (s *Store) publish(path string) error {
+	if s.library == nil {
+		return nil
+	}
+	p, err := s.Load(path)
+	if err != nil {
+		return fmt.Errorf("failed to load prompt: %w", err)
+	}
+	if err := s.loadBackups(p); err != nil {
+		return err
+	}
+	if err := s.library.Put(*p); err != nil {
+		return fmt.Errorf("failed to save %s to the prompt library: %w", p.Name, err)
+	}
+	logger.Log("Published prompt %s v%d with %d backups", p.Name, p.Version, len(p.Backups))
+	return nil
+}
+
+// Import copies the prompts of a file store, global and project, into the
+// library with their version backups, and returns how many there were.
+// Prompts already in the library with the same name are replaced.
+func Import(lib Library, from *Store) (int, error) {
+	prompts, err := from.List()
+	if err != nil {
+		return 0, err
+	}
+	for _, p := range prompts {
+		if err := from.loadBackups(&p); err != nil {
+			return 0, err
+		}
+		if err := lib.Put(p); err != nil {
+			return 0, fmt.Errorf("failed to import %s: %w", p.Name, err)
+		}
+	}
+	return len(prompts), nil
+}
+
+// Export writes the library's prompts and their version backups to dir as
+// prompt files, replacing those with the same names, and returns how many
+// prompts there were
+func Export(lib Library, dir string) (int, error) {
+	prompts, err := lib.Prompts()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := writePromptFiles(prompts, dir); err != nil {
+		return 0, err
+	}
+	return len(prompts), nil
+}
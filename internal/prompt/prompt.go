@@ -9,27 +9,50 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ztaylor/claude-mon/internal/logger"
 	"gopkg.in/yaml.v3"
 )
 
 // Prompt represents a stored prompt with metadata
 type Prompt struct {
-	Name         string    `yaml:"name"`
-	Description  string    `yaml:"description,omitempty"`
-	Version      int       `yaml:"version"`
-	Created      time.Time `yaml:"created"`
-	Updated      time.Time `yaml:"updated"`
-	Tags         []string  `yaml:"tags,omitempty"`
-	Content      string    `yaml:"-"` // The actual prompt text (not in frontmatter)
-	Path         string    `yaml:"-"` // File path
-	IsGlobal     bool      `yaml:"-"` // Global vs project-local
-	VersionCount int       `yaml:"-"` // Number of version backups
+	Name         string         `yaml:"name"`
+	Description  string         `yaml:"description,omitempty"`
+	Version      int            `yaml:"version"`
+	Created      time.Time      `yaml:"created"`
+	Updated      time.Time      `yaml:"updated"`
+	Tags         []string       `yaml:"tags,omitempty"`
+	Variables    []Variable     `yaml:"variables,omitempty"` // User-defined {{variables}}, filled in before sending
+	Content      string         `yaml:"-"`                   // The actual prompt text (not in frontmatter)
+	Path         string         `yaml:"-"`                   // File path
+	IsGlobal     bool           `yaml:"-"`                   // Global vs project-local
+	VersionCount int            `yaml:"-"`                   // Number of version backups
+	Backups      map[int]string `yaml:"-"`                   // Version backups' files by version, for a Library
+}
+
+// Variable is a user-defined prompt variable declared in frontmatter. Its
+// value is asked for before the prompt is sent and replaces {{name}}.
+type Variable struct {
+	Name        string   `yaml:"name"`
+	Description string   `yaml:"description,omitempty"`
+	Default     string   `yaml:"default,omitempty"`
+	Choices     []string `yaml:"choices,omitempty,flow"` // Restricts the value to one of these
+}
+
+// FillVariables replaces each {{name}} in content with its value. Built-in
+// variables are expanded separately, after these.
+func FillVariables(content string, values map[string]string) string {
+	for name, value := range values {
+		content = strings.ReplaceAll(content, "{{"+name+"}}", value)
+	}
+	return content
 }
 
 // Store manages prompt storage in global and project directories
 type Store struct {
-	globalDir  string // ~/.claude/prompts/
-	projectDir string // .claude/prompts/
+	globalDir  string   // ~/.claude/prompts/
+	projectDir string   // .claude/prompts/
+	git        *gitRepo // Set when the global library is a git repository
+	library    Library  // Set when prompts are kept in a Library; see NewLibraryStore
 }
 
 // NewStore creates a new prompt store
@@ -44,14 +67,32 @@ func NewStore() (*Store, error) {
 		return nil, fmt.Errorf("failed to get cwd: %w", err)
 	}
 
+	globalDir := filepath.Join(home, ".claude", "prompts")
 	return &Store{
-		globalDir:  filepath.Join(home, ".claude", "prompts"),
+		globalDir:  globalDir,
 		projectDir: filepath.Join(cwd, ".claude", "prompts"),
+		git:        openGitRepo(globalDir),
 	}, nil
 }
 
 // List returns all prompts from both global and project directories
 func (s *Store) List() ([]Prompt, error) {
+	if s.library != nil {
+		// The last copy written out is still listed if the library is
+		// unreachable
+		if err := s.pull(); err != nil {
+			logger.Log("Failed to pull prompt library: %v", err)
+		}
+		prompts, err := s.loadFromDir(s.globalDir, true)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to load library prompts: %w", err)
+		}
+		sort.Slice(prompts, func(i, j int) bool {
+			return prompts[i].Updated.After(prompts[j].Updated)
+		})
+		return prompts, nil
+	}
+
 	var prompts []Prompt
 
 	// Load global prompts
@@ -197,7 +238,7 @@ func Parse(content string) (*Prompt, error) {
 func (s *Store) Save(p *Prompt) error {
 	// Determine target directory
 	dir := s.projectDir
-	if p.IsGlobal {
+	if p.IsGlobal || s.library != nil {
 		dir = s.globalDir
 	}
 
@@ -215,10 +256,7 @@ func (s *Store) Save(p *Prompt) error {
 	// Determine path
 	path := p.Path
 	if path == "" {
-		// Generate filename from name
-		safeName := strings.ReplaceAll(strings.ToLower(p.Name), " ", "-")
-		safeName = regexp.MustCompile(`[^a-z0-9-]`).ReplaceAllString(safeName, "")
-		path = filepath.Join(dir, safeName+".prompt.md")
+		path = filepath.Join(dir, promptFileBase(p.Name)+".prompt.md")
 	}
 
 	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
@@ -226,7 +264,17 @@ func (s *Store) Save(p *Prompt) error {
 	}
 
 	p.Path = path
-	return nil
+	if err := s.publish(path); err != nil {
+		return err
+	}
+	return s.commitPrompt(path, "Save "+p.Name)
+}
+
+// promptFileBase returns the file name a prompt is saved under, without
+// the .prompt.md extension, e.g. "code-review" for "Code Review"
+func promptFileBase(name string) string {
+	safeName := strings.ReplaceAll(strings.ToLower(name), " ", "-")
+	return regexp.MustCompile(`[^a-z0-9-]`).ReplaceAllString(safeName, "")
 }
 
 // Format returns the prompt as a string with frontmatter
@@ -236,12 +284,13 @@ func (p *Prompt) Format() string {
 	// Write frontmatter
 	sb.WriteString("---\n")
 	frontmatter := struct {
-		Name        string    `yaml:"name"`
-		Description string    `yaml:"description,omitempty"`
-		Version     int       `yaml:"version"`
-		Created     time.Time `yaml:"created"`
-		Updated     time.Time `yaml:"updated"`
-		Tags        []string  `yaml:"tags,omitempty,flow"`
+		Name        string     `yaml:"name"`
+		Description string     `yaml:"description,omitempty"`
+		Version     int        `yaml:"version"`
+		Created     time.Time  `yaml:"created"`
+		Updated     time.Time  `yaml:"updated"`
+		Tags        []string   `yaml:"tags,omitempty,flow"`
+		Variables   []Variable `yaml:"variables,omitempty"`
 	}{
 		Name:        p.Name,
 		Description: p.Description,
@@ -249,6 +298,7 @@ func (p *Prompt) Format() string {
 		Created:     p.Created,
 		Updated:     p.Updated,
 		Tags:        p.Tags,
+		Variables:   p.Variables,
 	}
 
 	data, _ := yaml.Marshal(frontmatter)
@@ -280,12 +330,28 @@ func (s *Store) UpdateAfterEdit(path string) error {
 		return fmt.Errorf("failed to save prompt: %w", err)
 	}
 
-	return nil
+	if err := s.publish(path); err != nil {
+		return err
+	}
+	return s.commitPrompt(path, fmt.Sprintf("Update %s to v%d", prompt.Name, prompt.Version))
 }
 
-// Delete removes a prompt file
+// Delete removes a prompt file, and the prompt from the library in library
+// mode
 func (s *Store) Delete(path string) error {
-	return os.Remove(path)
+	if s.library != nil {
+		p, err := s.Load(path)
+		if err != nil {
+			return err
+		}
+		if err := s.library.Delete(p.Name); err != nil {
+			return fmt.Errorf("failed to delete %s from the prompt library: %w", p.Name, err)
+		}
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	return s.commitPrompt(path, "Delete "+strings.TrimSuffix(filepath.Base(path), ".prompt.md"))
 }
 
 // GlobalDir returns the global prompts directory
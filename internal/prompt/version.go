@@ -58,7 +58,10 @@ func (s *Store) CreateVersion(p *Prompt) error {
 	// Increment version in original
 	p.Version++
 
-	return nil
+	if err := s.publish(p.Path); err != nil {
+		return err
+	}
+	return s.commitPrompt(versionPath, fmt.Sprintf("Back up %s as v%d", p.Name, nextVersion))
 }
 
 // PromptVersion represents a versioned backup
@@ -116,5 +119,11 @@ func (s *Store) RestoreVersion(promptPath string, version int) error {
 		return fmt.Errorf("version %d not found: %w", version, err)
 	}
 
-	return os.WriteFile(promptPath, content, 0644)
+	if err := os.WriteFile(promptPath, content, 0644); err != nil {
+		return err
+	}
+	if err := s.publish(promptPath); err != nil {
+		return err
+	}
+	return s.commitPrompt(promptPath, fmt.Sprintf("Restore %s to v%d", name, version))
 }
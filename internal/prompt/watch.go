@@ -0,0 +1,99 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce is how long the prompt directories must be quiet before a
+// change is reported. Editors and git write a file in several steps.
+const watchDebounce = 200 * time.Millisecond
+
+// Watcher reports changes to a store's prompt directories made outside the
+// TUI, such as by another editor or a git pull
+type Watcher struct {
+	fs   *fsnotify.Watcher
+	dirs []string
+}
+
+// NewWatcher watches the store's global and project prompt directories.
+// A directory that doesn't exist yet is picked up when it's created, as
+// long as its parent exists.
+func NewWatcher(s *Store) (*Watcher, error) {
+	fs, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create watcher: %w", err)
+	}
+
+	w := &Watcher{fs: fs, dirs: []string{s.globalDir, s.projectDir}}
+	for _, dir := range w.dirs {
+		if err := fs.Add(dir); err == nil {
+			continue
+		}
+		if err := fs.Add(filepath.Dir(dir)); err != nil && !os.IsNotExist(err) {
+			fs.Close()
+			return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+	return w, nil
+}
+
+// Watch calls onChange after prompt files are added, edited, renamed or
+// removed. It blocks until the watcher is closed.
+func (w *Watcher) Watch(onChange func()) {
+	var timer *time.Timer
+	for {
+		select {
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				if timer != nil {
+					timer.Stop()
+				}
+				return
+			}
+			if !w.relevant(event) {
+				continue
+			}
+			if timer == nil {
+				timer = time.AfterFunc(watchDebounce, onChange)
+			} else {
+				timer.Reset(watchDebounce)
+			}
+		case _, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// relevant reports whether an event touches a prompt file or one of the
+// prompt directories, starting to watch prompt directories as they appear
+func (w *Watcher) relevant(event fsnotify.Event) bool {
+	for _, dir := range w.dirs {
+		if event.Name != dir {
+			continue
+		}
+		if event.Has(fsnotify.Create) {
+			w.fs.Add(dir)
+		}
+		return true
+	}
+
+	if !strings.HasSuffix(event.Name, ".prompt.md") || !slices.Contains(w.dirs, filepath.Dir(event.Name)) {
+		return false
+	}
+	return event.Has(fsnotify.Create) || event.Has(fsnotify.Write) ||
+		event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename)
+}
+
+// Close stops watching
+func (w *Watcher) Close() error {
+	return w.fs.Close()
+}
@@ -4,12 +4,31 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
+const (
+	// StateFile is the state file the ralph-loop stop hook reads each time
+	// Claude stops. While it exists, the loop feeds Claude its prompt again.
+	StateFile = "ralph-loop.local.md"
+
+	// PausedFile is where a paused loop's state is kept. The stop hook
+	// doesn't look for it, so Claude is let stop until the loop is resumed.
+	PausedFile = "ralph-loop.paused.md"
+)
+
+// How a loop ended, as recorded in the loop history
+const (
+	OutcomePromiseMet    = "promise_met"    // Claude output the completion promise
+	OutcomeMaxIterations = "max_iterations" // The iteration limit was reached
+	OutcomeCancelled     = "cancelled"      // Cancelled from claude-mon
+	OutcomeStopped       = "stopped"        // Ended some other way
+)
+
 // State represents the Ralph Loop state from ralph-loop.local.md
 type State struct {
 	Active        bool      `yaml:"active"`
@@ -17,14 +36,14 @@ type State struct {
 	MaxIterations int       `yaml:"max_iterations"`
 	Promise       string    `yaml:"completion_promise"`
 	StartedAt     time.Time `yaml:"started_at"`
+	Paused        bool      `yaml:"paused"`
 	Prompt        string    `yaml:"-"` // The prompt content (not in frontmatter)
 	Path          string    `yaml:"-"` // The file path where state was found
 }
 
-// LoadState loads the Ralph Loop state from the state file.
-// It checks project-local first (.claude/ralph-loop.local.md), then global (~/.claude/ralph-loop.local.md).
-// Returns nil if no state file is found.
-func LoadState() (*State, error) {
+// stateDirs returns the directories a state file may be in, project-local
+// first, then global
+func stateDirs() ([]string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get home dir: %w", err)
@@ -35,10 +54,22 @@ func LoadState() (*State, error) {
 		return nil, fmt.Errorf("failed to get cwd: %w", err)
 	}
 
-	// Try project-local first, then global
-	paths := []string{
-		filepath.Join(cwd, ".claude", "ralph-loop.local.md"),
-		filepath.Join(home, ".claude", "ralph-loop.local.md"),
+	return []string{filepath.Join(cwd, ".claude"), filepath.Join(home, ".claude")}, nil
+}
+
+// LoadState loads the Ralph Loop state from the state file.
+// It checks project-local first (.claude/ralph-loop.local.md), then global (~/.claude/ralph-loop.local.md).
+// A paused loop (ralph-loop.paused.md) is found in the same places.
+// Returns nil if no state file is found.
+func LoadState() (*State, error) {
+	dirs, err := stateDirs()
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, dir := range dirs {
+		paths = append(paths, filepath.Join(dir, StateFile), filepath.Join(dir, PausedFile))
 	}
 
 	var content []byte
@@ -82,33 +113,197 @@ func parseState(content, path string) (*State, error) {
 	return state, nil
 }
 
-// CancelLoop cancels the Ralph Loop by removing the state file.
-// It tries project-local first, then global.
+// CancelLoop cancels the Ralph Loop by removing the state file, or a paused
+// loop's. It tries project-local first, then global.
 // Returns true if a file was removed, false otherwise.
 func CancelLoop() (bool, error) {
-	home, err := os.UserHomeDir()
+	dirs, err := stateDirs()
 	if err != nil {
-		return false, fmt.Errorf("failed to get home dir: %w", err)
+		return false, err
 	}
 
-	cwd, err := os.Getwd()
+	for _, dir := range dirs {
+		for _, name := range []string{StateFile, PausedFile} {
+			if err := os.Remove(filepath.Join(dir, name)); err == nil {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil // No file found to remove
+}
+
+// Start begins a Ralph loop in the workspace by writing its state file to
+// .claude/, as /ralph-loop does. Claude is then run with the prompt, and the
+// stop hook feeds it the prompt again each time it stops. maxIterations 0
+// means no limit; an empty promise means none.
+func Start(prompt string, maxIterations int, promise string) (*State, error) {
+	if existing, err := LoadState(); err == nil && existing != nil && existing.Active {
+		return nil, fmt.Errorf("a Ralph loop is already running")
+	}
+	dirs, err := stateDirs()
 	if err != nil {
-		return false, fmt.Errorf("failed to get cwd: %w", err)
+		return nil, err
+	}
+	if err := os.MkdirAll(dirs[0], 0755); err != nil {
+		return nil, fmt.Errorf("failed to create .claude directory: %w", err)
 	}
 
-	// Try to remove project-local first
-	projectPath := filepath.Join(cwd, ".claude", "ralph-loop.local.md")
-	if err := os.Remove(projectPath); err == nil {
-		return true, nil
+	promiseValue := "null"
+	if promise != "" {
+		promiseValue = strconv.Quote(promise)
 	}
+	content := fmt.Sprintf("---\nactive: true\niteration: 1\nmax_iterations: %d\ncompletion_promise: %s\nstarted_at: %q\n---\n\n%s\n",
+		maxIterations, promiseValue, time.Now().UTC().Format(time.RFC3339), strings.TrimSpace(prompt))
+	path := filepath.Join(dirs[0], StateFile)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write Ralph state: %w", err)
+	}
+	return parseState(content, path)
+}
 
-	// Try global
-	globalPath := filepath.Join(home, ".claude", "ralph-loop.local.md")
-	if err := os.Remove(globalPath); err == nil {
-		return true, nil
+// Outcome works out how a loop ended from its last state, once its state
+// file is gone. The stop hook removes the file when the promise is output or
+// the limit reached; a loop with a promise cancelled outside claude-mon
+// before its limit can't be told apart from one that met it.
+func (s *State) Outcome() string {
+	switch {
+	case s.MaxIterations > 0 && s.Iteration >= s.MaxIterations:
+		return OutcomeMaxIterations
+	case s.Promise != "":
+		return OutcomePromiseMet
+	default:
+		return OutcomeStopped
 	}
+}
 
-	return false, nil // No file found to remove
+// Pause stops the loop from feeding Claude its prompt again once the current
+// iteration ends, by moving the state file out of the stop hook's sight
+func (s *State) Pause() error {
+	if s.Paused {
+		return fmt.Errorf("the Ralph loop is already paused")
+	}
+	if err := s.update(map[string]any{"paused": true}); err != nil {
+		return err
+	}
+	return s.move(PausedFile)
+}
+
+// Resume puts a paused loop's state file back. The loop carries on the next
+// time Claude stops.
+func (s *State) Resume() error {
+	if !s.Paused {
+		return fmt.Errorf("the Ralph loop isn't paused")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(s.Path), StateFile)); err == nil {
+		return fmt.Errorf("another Ralph loop has started since; cancel it first")
+	}
+	if err := s.update(map[string]any{"paused": nil}); err != nil {
+		return err
+	}
+	return s.move(StateFile)
+}
+
+// SetMaxIterations changes how many iterations the loop runs. The stop hook
+// reads it afresh each iteration; 0 means no limit.
+func (s *State) SetMaxIterations(n int) error {
+	if n < 0 || (n > 0 && n < s.Iteration) {
+		return fmt.Errorf("max iterations can't be below the current iteration (%d)", s.Iteration)
+	}
+	return s.update(map[string]any{"max_iterations": n})
+}
+
+// SetPromise changes the phrase Claude outputs to end the loop. An empty
+// promise leaves only the iteration limit.
+func (s *State) SetPromise(promise string) error {
+	return s.update(map[string]any{"completion_promise": promise})
+}
+
+// update rewrites frontmatter fields in the state file, keeping the other
+// fields and the prompt as they are, then reloads the state from it. A nil
+// value removes the field.
+func (s *State) update(fields map[string]any) error {
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read Ralph state: %w", err)
+	}
+	parts := strings.SplitN(string(data), "---\n", 3)
+	if len(parts) < 3 || parts[0] != "" {
+		return fmt.Errorf("invalid Ralph state file: malformed frontmatter")
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(parts[1]), &doc); err != nil {
+		return fmt.Errorf("failed to parse Ralph frontmatter: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("invalid Ralph state file: frontmatter isn't a mapping")
+	}
+	mapping := doc.Content[0]
+
+	for key, value := range fields {
+		setField(mapping, key, value)
+	}
+
+	frontmatter, err := yaml.Marshal(&doc)
+	if err != nil {
+		return fmt.Errorf("failed to write Ralph frontmatter: %w", err)
+	}
+	content := "---\n" + string(frontmatter) + "---\n" + parts[2]
+	if err := os.WriteFile(s.Path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write Ralph state: %w", err)
+	}
+
+	updated, err := parseState(content, s.Path)
+	if err != nil {
+		return err
+	}
+	*s = *updated
+	return nil
+}
+
+// setField sets or removes a key in a YAML mapping, in place. Strings are
+// double-quoted, the form the stop hook strips when it reads the promise.
+func setField(mapping *yaml.Node, key string, value any) {
+	var node *yaml.Node
+	switch v := value.(type) {
+	case nil:
+	case string:
+		if v == "" {
+			node = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}
+		} else {
+			node = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v, Style: yaml.DoubleQuotedStyle}
+		}
+	case bool:
+		node = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(v)}
+	default:
+		node = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: fmt.Sprint(v)}
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value != key {
+			continue
+		}
+		if node == nil {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+		} else {
+			mapping.Content[i+1] = node
+		}
+		return
+	}
+	if node != nil {
+		mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, node)
+	}
+}
+
+// move renames the state file within its directory
+func (s *State) move(name string) error {
+	path := filepath.Join(filepath.Dir(s.Path), name)
+	if err := os.Rename(s.Path, path); err != nil {
+		return fmt.Errorf("failed to move Ralph state: %w", err)
+	}
+	s.Path = path
+	return nil
 }
 
 // FormatDuration formats the elapsed time in a human-readable way
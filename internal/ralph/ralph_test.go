@@ -0,0 +1,122 @@
+package ralph
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testState = `---
+active: true
+iteration: 3
+max_iterations: 10
+completion_promise: "DONE"
+started_at: "2026-01-02T15:04:05Z"
+session_id: abc123
+---
+
+Fix the failing tests.
+`
+
+func TestStateControls(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, StateFile)
+	if err := os.WriteFile(path, []byte(testState), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := parseState(testState, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.SetMaxIterations(2); err == nil {
+		t.Error("expected max iterations below the current iteration to fail")
+	}
+	if err := s.SetMaxIterations(15); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetPromise("ALL TESTS PASS"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(path)
+	content := string(data)
+	// The stop hook greps these lines, and fields it owns are kept
+	for _, want := range []string{"max_iterations: 15\n", "completion_promise: \"ALL TESTS PASS\"\n", "session_id: abc123\n", "Fix the failing tests."} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %q in state file:\n%s", want, content)
+		}
+	}
+	if s.MaxIterations != 15 || s.Promise != "ALL TESTS PASS" || s.Iteration != 3 {
+		t.Errorf("expected the state reloaded, got %+v", s)
+	}
+
+	// Pausing hides the state file from the stop hook until resumed
+	if err := s.Pause(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("expected the state file moved aside")
+	}
+	if !s.Paused || s.Path != filepath.Join(dir, PausedFile) {
+		t.Errorf("expected a paused state, got %+v", s)
+	}
+	if err := s.Resume(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(path)
+	if s.Paused || strings.Contains(string(data), "paused") {
+		t.Errorf("expected the loop resumed, got:\n%s", data)
+	}
+
+	if err := s.SetPromise(""); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(path)
+	if !strings.Contains(string(data), "completion_promise: null\n") {
+		t.Errorf("expected no promise, got:\n%s", data)
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		state State
+		want  string
+	}{
+		{State{Iteration: 10, MaxIterations: 10, Promise: "DONE"}, OutcomeMaxIterations},
+		{State{Iteration: 4, MaxIterations: 10, Promise: "DONE"}, OutcomePromiseMet},
+		{State{Iteration: 4}, OutcomeStopped},
+	}
+	for _, tt := range tests {
+		if got := tt.state.Outcome(); got != tt.want {
+			t.Errorf("Outcome() for %+v = %s, want %s", tt.state, got, tt.want)
+		}
+	}
+}
+
+func TestStart(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	s, err := Start("Implement the plan.\n", 20, `PLAN "COMPLETE"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadState()
+	if err != nil || loaded == nil {
+		t.Fatalf("expected the loop's state file, got %v", err)
+	}
+	if !loaded.Active || loaded.Iteration != 1 || loaded.MaxIterations != 20 || loaded.Promise != `PLAN "COMPLETE"` ||
+		loaded.Prompt != "Implement the plan." || loaded.StartedAt.IsZero() || loaded.Path != s.Path {
+		t.Errorf("unexpected state %+v", loaded)
+	}
+
+	if _, err := Start("Another loop", 0, ""); err == nil {
+		t.Error("expected starting a second loop to fail")
+	}
+	CancelLoop()
+	if s, err := Start("No limit", 0, ""); err != nil || s.Promise != "" || s.MaxIterations != 0 {
+		t.Errorf("expected a loop without a limit or promise, got %+v (%v)", s, err)
+	}
+}
@@ -0,0 +1,109 @@
+package socket
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// Command is a request on the TUI control socket. Clients send one command
+// per line, either as JSON ({"command":"switch-tab","args":["prompts"]}) or
+// as plain words (switch-tab prompts), and get one JSON reply per line:
+// {"status":"ok"} or {"error":"..."}.
+type Command struct {
+	Name string   `json:"command"`
+	Args []string `json:"args,omitempty"`
+}
+
+// ParseCommand parses a JSON or plain-text command line
+func ParseCommand(line string) (Command, error) {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return Command{}, fmt.Errorf("empty command")
+	}
+
+	var cmd Command
+	if strings.HasPrefix(line, "{") {
+		if err := json.Unmarshal([]byte(line), &cmd); err != nil {
+			return Command{}, fmt.Errorf("invalid command JSON: %w", err)
+		}
+	} else {
+		fields := strings.Fields(line)
+		cmd = Command{Name: fields[0], Args: fields[1:]}
+	}
+
+	if cmd.Name == "" {
+		return Command{}, fmt.Errorf("command name required")
+	}
+	return cmd, nil
+}
+
+// ControlListener accepts commands on the TUI control socket
+type ControlListener struct {
+	socketPath string
+	listener   net.Listener
+}
+
+// NewControlListener creates a control socket listener
+func NewControlListener(socketPath string) (*ControlListener, error) {
+	// Remove existing socket file if it exists
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove existing socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on socket: %w", err)
+	}
+
+	return &ControlListener{
+		socketPath: socketPath,
+		listener:   listener,
+	}, nil
+}
+
+// Serve accepts connections and calls handler for each command, replying
+// with the handler's error if any. Connections may send several commands.
+func (l *ControlListener) Serve(handler func(Command) error) {
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			// Listener was closed
+			return
+		}
+
+		go func(c net.Conn) {
+			defer c.Close()
+
+			encoder := json.NewEncoder(c)
+			scanner := bufio.NewScanner(c)
+			for scanner.Scan() {
+				if strings.TrimSpace(scanner.Text()) == "" {
+					continue
+				}
+
+				cmd, err := ParseCommand(scanner.Text())
+				if err == nil {
+					err = handler(cmd)
+				}
+
+				reply := map[string]string{"status": "ok"}
+				if err != nil {
+					reply = map[string]string{"error": err.Error()}
+				}
+				if encoder.Encode(reply) != nil {
+					return
+				}
+			}
+		}(conn)
+	}
+}
+
+// Close closes the listener and removes the socket file
+func (l *ControlListener) Close() error {
+	l.listener.Close()
+	return os.Remove(l.socketPath)
+}
@@ -0,0 +1,126 @@
+package socket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+)
+
+const (
+	// MaxStringSize is the longest string value a payload on the TUI socket
+	// keeps, in encoded bytes. Longer ones, such as the content of a
+	// multi-megabyte Write, are cut short and end in TruncatedMarker.
+	MaxStringSize = 4 << 20
+
+	// MaxPayloadSize is the largest payload the TUI socket accepts once its
+	// strings are cut short; a connection sending a larger one is dropped
+	MaxPayloadSize = 32 << 20
+)
+
+// TruncatedMarker ends strings cut short to MaxStringSize
+const TruncatedMarker = "\n… [content truncated by claude-mon]"
+
+// errPayloadTooLarge is returned for a payload over MaxPayloadSize
+var errPayloadTooLarge = errors.New("payload too large")
+
+// readPayloads reads JSON values from r, which may send them one after
+// another (newline-delimited or not) or one per connection, and calls emit
+// with each as soon as it's complete. Strings longer than maxString are cut
+// short as they're read, so a huge payload is never held whole. Bytes
+// outside a JSON object or array are skipped.
+func readPayloads(r io.Reader, maxString, maxPayload int, emit func([]byte)) error {
+	br := bufio.NewReaderSize(r, 64<<10)
+	marker, _ := json.Marshal(TruncatedMarker)
+	marker = marker[1 : len(marker)-1] // Without its quotes
+
+	var (
+		buf      bytes.Buffer
+		depth    int
+		inString bool
+		escaped  bool // The previous byte started an escape
+		hex      int  // \u digits still to come
+		length   int  // Encoded bytes of the current string
+		skipping bool // Past maxString in the current string
+	)
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if inString {
+			switch {
+			case skipping:
+				// Drop the rest of the string, up to its closing quote
+				switch {
+				case escaped:
+					escaped = false
+					continue
+				case b == '\\':
+					escaped = true
+					continue
+				case b != '"':
+					continue
+				}
+				inString, skipping = false, false
+			case escaped:
+				escaped = false
+				if b == 'u' {
+					hex = 4
+				}
+			case hex > 0:
+				hex--
+			case b == '"':
+				inString = false
+			case length >= maxString && b&0xC0 != 0x80:
+				// Cut at the first character boundary past the limit
+				buf.Write(marker)
+				skipping, escaped = true, b == '\\'
+				continue
+			case b == '\\':
+				escaped = true
+			}
+			length++
+			buf.WriteByte(b)
+		} else {
+			switch b {
+			case '{', '[':
+				depth++
+			case '}', ']':
+				if depth == 0 {
+					continue
+				}
+				depth--
+			case '"':
+				if depth == 0 {
+					continue
+				}
+				inString, length = true, 0
+			default:
+				if depth == 0 {
+					continue // Whitespace or noise between payloads
+				}
+			}
+			buf.WriteByte(b)
+		}
+
+		if buf.Len() > maxPayload {
+			return errPayloadTooLarge
+		}
+		if !inString && depth == 0 && buf.Len() > 0 {
+			emit(bytes.Clone(buf.Bytes()))
+			buf.Reset()
+		}
+	}
+}
+
+// IsTruncated reports whether a payload string was cut short on the socket
+func IsTruncated(s string) bool {
+	return strings.HasSuffix(s, TruncatedMarker)
+}
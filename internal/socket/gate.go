@@ -0,0 +1,108 @@
+package socket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// GateDecision is the TUI's answer to an edit held on the gate socket
+type GateDecision struct {
+	Allow  bool   `json:"allow"`
+	Reason string `json:"reason,omitempty"` // Why the edit was denied, passed on to Claude
+}
+
+// GateListener holds edits for approval on the TUI gate socket. Clients
+// send a PreToolUse hook's input on one line and get one GateDecision back,
+// as JSON, once the edit is allowed or denied.
+type GateListener struct {
+	socketPath string
+	listener   net.Listener
+}
+
+// NewGateListener creates a gate socket listener
+func NewGateListener(socketPath string) (*GateListener, error) {
+	// Remove existing socket file if it exists
+	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to remove existing socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on socket: %w", err)
+	}
+
+	return &GateListener{
+		socketPath: socketPath,
+		listener:   listener,
+	}, nil
+}
+
+// Serve accepts connections and calls handler with each held edit's hook
+// payload and a function answering it. The connection stays open until
+// the edit is answered; answers after the first are ignored.
+func (l *GateListener) Serve(handler func(payload []byte, answer func(GateDecision))) {
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			// Listener was closed
+			return
+		}
+
+		go func(c net.Conn) {
+			defer c.Close()
+
+			// A payload without its newline is still read to EOF
+			payload, _ := bufio.NewReader(c).ReadBytes('\n')
+			payload = bytes.TrimSpace(payload)
+			if len(payload) == 0 {
+				return
+			}
+
+			decided := make(chan GateDecision, 1)
+			handler(payload, func(d GateDecision) {
+				select {
+				case decided <- d:
+				default:
+				}
+			})
+			json.NewEncoder(c).Encode(<-decided)
+		}(conn)
+	}
+}
+
+// Close closes the listener and removes the socket file
+func (l *GateListener) Close() error {
+	l.listener.Close()
+	return os.Remove(l.socketPath)
+}
+
+// AskGate sends a hook payload to the gate socket and waits up to timeout
+// for the TUI to allow or deny the edit
+func AskGate(socketPath string, payload []byte, timeout time.Duration) (GateDecision, error) {
+	var line bytes.Buffer
+	if err := json.Compact(&line, payload); err != nil {
+		return GateDecision{}, fmt.Errorf("invalid hook payload: %w", err)
+	}
+	line.WriteByte('\n')
+
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return GateDecision{}, fmt.Errorf("TUI not gating edits: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := conn.Write(line.Bytes()); err != nil {
+		return GateDecision{}, fmt.Errorf("failed to send edit: %w", err)
+	}
+	var decision GateDecision
+	if err := json.NewDecoder(conn).Decode(&decision); err != nil {
+		return GateDecision{}, fmt.Errorf("no decision: %w", err)
+	}
+	return decision, nil
+}
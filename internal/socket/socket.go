@@ -3,15 +3,32 @@ package socket
 import (
 	"crypto/sha256"
 	"fmt"
-	"io"
 	"net"
 	"os"
 	"path/filepath"
+
+	"github.com/ztaylor/claude-mon/internal/logger"
 )
 
 // GetSocketPath returns the socket path for the current workspace.
 // Uses the same hashing scheme as the neovim plugin for consistency.
 func GetSocketPath() string {
+	return fmt.Sprintf("/tmp/claude-mon-%s-%s.sock", socketUser(), workspaceHash())
+}
+
+// GetControlSocketPath returns the TUI control socket path for the current workspace
+func GetControlSocketPath() string {
+	return fmt.Sprintf("/tmp/claude-mon-%s-%s-ctl.sock", socketUser(), workspaceHash())
+}
+
+// GetGateSocketPath returns the path of the socket the TUI holds edits on
+// for approval, for the current workspace
+func GetGateSocketPath() string {
+	return fmt.Sprintf("/tmp/claude-mon-%s-%s-gate.sock", socketUser(), workspaceHash())
+}
+
+// workspaceHash returns a short hash of the resolved working directory
+func workspaceHash() string {
 	cwd, err := os.Getwd()
 	if err != nil {
 		cwd = "."
@@ -30,14 +47,16 @@ func GetSocketPath() string {
 
 	// Hash the path
 	hash := sha256.Sum256([]byte(cwd))
-	hashStr := fmt.Sprintf("%x", hash)[:12]
+	return fmt.Sprintf("%x", hash)[:12]
+}
 
+// socketUser returns the user name used in socket paths
+func socketUser() string {
 	user := os.Getenv("USER")
 	if user == "" {
 		user = "unknown"
 	}
-
-	return fmt.Sprintf("/tmp/claude-mon-%s-%s.sock", user, hashStr)
+	return user
 }
 
 // Listener handles incoming socket connections
@@ -66,12 +85,21 @@ func NewListener(socketPath string) (*Listener, error) {
 	}, nil
 }
 
-// Listen starts accepting connections and calls handler for each payload
+// Listen starts accepting connections and calls handler for each payload.
+// A connection may send one payload and close, as shell hooks do, or
+// several, newline-delimited; each is handled as soon as it's read.
 func (l *Listener) Listen(handler func([]byte)) {
 	// Start a goroutine to process messages from the channel
+	done := make(chan struct{})
+	defer close(done)
 	go func() {
-		for data := range l.messages {
-			handler(data)
+		for {
+			select {
+			case data := <-l.messages:
+				handler(data)
+			case <-done:
+				return
+			}
 		}
 	}()
 
@@ -80,26 +108,22 @@ func (l *Listener) Listen(handler func([]byte)) {
 		conn, err := l.listener.Accept()
 		if err != nil {
 			// Listener was closed
-			close(l.messages)
 			return
 		}
 
 		go func(c net.Conn) {
 			defer c.Close()
 
-			// Read all data from connection until EOF
-			data, err := io.ReadAll(c)
-			if err != nil {
-				return
-			}
-
-			if len(data) > 0 {
-				// Send to buffered channel (non-blocking if buffer not full)
+			// Queue each payload, waiting while the handler catches up
+			// rather than dropping payloads
+			err := readPayloads(c, MaxStringSize, MaxPayloadSize, func(data []byte) {
 				select {
 				case l.messages <- data:
-				default:
-					// Buffer full, drop message (shouldn't happen with 100 buffer)
+				case <-done:
 				}
+			})
+			if err != nil {
+				logger.Log("Dropped TUI socket connection: %v", err)
 			}
 		}(conn)
 	}
@@ -1,6 +1,8 @@
 package socket
 
 import (
+	"encoding/json"
+	"fmt"
 	"net"
 	"os"
 	"strings"
@@ -98,3 +100,201 @@ func TestListenerReceiveMessage(t *testing.T) {
 		t.Error("timeout waiting for message")
 	}
 }
+
+func TestGetControlSocketPath(t *testing.T) {
+	path := GetControlSocketPath()
+	if !strings.HasSuffix(path, "-ctl.sock") {
+		t.Errorf("control socket path should end with -ctl.sock, got: %s", path)
+	}
+	if strings.TrimSuffix(path, "-ctl.sock")+".sock" != GetSocketPath() {
+		t.Errorf("control socket should share the data socket prefix, got: %s", path)
+	}
+}
+
+func TestParseCommand(t *testing.T) {
+	cmd, err := ParseCommand(`{"command":"switch-tab","args":["prompts"]}`)
+	if err != nil || cmd.Name != "switch-tab" || len(cmd.Args) != 1 || cmd.Args[0] != "prompts" {
+		t.Errorf("unexpected JSON parse result: %+v, %v", cmd, err)
+	}
+
+	cmd, err = ParseCommand("set-filter tool:Write since:1h\n")
+	if err != nil || cmd.Name != "set-filter" || len(cmd.Args) != 2 {
+		t.Errorf("unexpected plain parse result: %+v, %v", cmd, err)
+	}
+
+	for _, line := range []string{"", "   ", `{"args":["x"]}`, `{"command":`} {
+		if _, err := ParseCommand(line); err == nil {
+			t.Errorf("expected error for %q", line)
+		}
+	}
+}
+
+func TestControlListenerReplies(t *testing.T) {
+	socketPath := "/tmp/claude-mon-test-ctl.sock"
+	defer os.Remove(socketPath)
+
+	listener, err := NewControlListener(socketPath)
+	if err != nil {
+		t.Fatalf("failed to create control listener: %v", err)
+	}
+	defer listener.Close()
+
+	go listener.Serve(func(cmd Command) error {
+		if cmd.Name == "quit" {
+			return nil
+		}
+		return fmt.Errorf("unknown command: %s", cmd.Name)
+	})
+
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatalf("failed to connect to control socket: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(2 * time.Second))
+
+	if _, err := conn.Write([]byte("quit\nbogus\n")); err != nil {
+		t.Fatalf("failed to write commands: %v", err)
+	}
+
+	decoder := json.NewDecoder(conn)
+	var ok, failed map[string]string
+	if err := decoder.Decode(&ok); err != nil || ok["status"] != "ok" {
+		t.Errorf("expected ok reply, got %v (%v)", ok, err)
+	}
+	if err := decoder.Decode(&failed); err != nil || failed["error"] != "unknown command: bogus" {
+		t.Errorf("expected error reply, got %v (%v)", failed, err)
+	}
+}
+
+func TestGateListenerHoldsUntilAnswered(t *testing.T) {
+	socketPath := "/tmp/claude-mon-test-gate.sock"
+	defer os.Remove(socketPath)
+
+	listener, err := NewGateListener(socketPath)
+	if err != nil {
+		t.Fatalf("failed to create gate listener: %v", err)
+	}
+	defer listener.Close()
+
+	held := make(chan []byte, 1)
+	go listener.Serve(func(payload []byte, answer func(GateDecision)) {
+		held <- payload
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			answer(GateDecision{Reason: "not now"})
+			answer(GateDecision{Allow: true})
+		}()
+	})
+
+	decision, err := AskGate(socketPath, []byte("{\n  \"tool_name\": \"Edit\"\n}"), 2*time.Second)
+	if err != nil {
+		t.Fatalf("failed to ask gate: %v", err)
+	}
+	if decision.Allow || decision.Reason != "not now" {
+		t.Errorf("expected the first answer, a denial, got %+v", decision)
+	}
+	if got := string(<-held); got != `{"tool_name":"Edit"}` {
+		t.Errorf("expected the payload on one line, got %q", got)
+	}
+
+	if _, err := AskGate("/tmp/claude-mon-test-no-gate.sock", []byte(`{}`), time.Second); err == nil {
+		t.Error("expected an error with no gate socket")
+	}
+}
+
+func TestListenerLargePayloads(t *testing.T) {
+	socketPath := "/tmp/claude-mon-test-large.sock"
+	defer os.Remove(socketPath)
+
+	listener, err := NewListener(socketPath)
+	if err != nil {
+		t.Fatalf("failed to create listener: %v", err)
+	}
+	defer listener.Close()
+
+	received := make(chan []byte, 4)
+	go listener.Listen(func(payload []byte) {
+		received <- payload
+	})
+
+	send := func(data string) {
+		t.Helper()
+		conn, err := net.Dial("unix", socketPath)
+		if err != nil {
+			t.Fatalf("failed to connect to socket: %v", err)
+		}
+		defer conn.Close()
+		if _, err := conn.Write([]byte(data)); err != nil {
+			t.Fatalf("failed to write to socket: %v", err)
+		}
+	}
+	receive := func() map[string]any {
+		t.Helper()
+		select {
+		case payload := <-received:
+			var v map[string]any
+			if err := json.Unmarshal(payload, &v); err != nil {
+				t.Fatalf("payload isn't valid JSON: %v", err)
+			}
+			return v
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for payload")
+			return nil
+		}
+	}
+
+	// A multi-megabyte Write is cut short, still valid JSON, with the
+	// fields after it intact
+	content := strings.Repeat("line of a very large generated file é\n", 300_000) // ~11 MB
+	large, _ := json.Marshal(map[string]any{
+		"tool_name":  "Write",
+		"tool_input": map[string]string{"file_path": "big.go", "content": content},
+		"session_id": "s1",
+	})
+	send(string(large))
+	v := receive()
+	got := v["tool_input"].(map[string]any)["content"].(string)
+	if !IsTruncated(got) || len(got) > MaxStringSize+len(TruncatedMarker) || !strings.HasPrefix(content, strings.TrimSuffix(got, TruncatedMarker)) {
+		t.Errorf("expected the content cut to %d bytes with the marker, got %d bytes", MaxStringSize, len(got))
+	}
+	if v["session_id"] != "s1" {
+		t.Errorf("expected the fields after the content kept, got %v", v["session_id"])
+	}
+
+	// Several newline-delimited payloads on one connection are each handled
+	// without waiting for it to close
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		t.Fatalf("failed to connect to socket: %v", err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("{\"tool_name\":\"Edit\",\"new_string\":\"a}\\\"{\"}\n{\"tool_name\":\"Write\"}\n"))
+	if v := receive(); v["tool_name"] != "Edit" || v["new_string"] != `a}"{` {
+		t.Errorf("unexpected first payload: %v", v)
+	}
+	if v := receive(); v["tool_name"] != "Write" {
+		t.Errorf("unexpected second payload: %v", v)
+	}
+}
+
+func TestReadPayloadsCutsAtCharacterBoundaries(t *testing.T) {
+	var got []string
+	err := readPayloads(strings.NewReader(`{"a":"ééé","b":"é\u00e9é","c":"\\\\\\\\"} noise [1]`), 4, 1<<10, func(p []byte) {
+		got = append(got, string(p))
+	})
+	if err != nil || len(got) != 2 || got[1] != "[1]" {
+		t.Fatalf("readPayloads = %q, %v", got, err)
+	}
+	var v map[string]string
+	if err := json.Unmarshal([]byte(got[0]), &v); err != nil {
+		t.Fatalf("cut payload isn't valid JSON: %v\n%s", err, got[0])
+	}
+	if v["a"] != "éé"+TruncatedMarker || v["b"] != "éé"+TruncatedMarker || v["c"] != `\\`+TruncatedMarker {
+		t.Errorf("unexpected cut strings: %q", v)
+	}
+
+	if err := readPayloads(strings.NewReader(`{"a":"`+strings.Repeat("x", 100)+`"}`), 1<<10, 64, func([]byte) {}); err != errPayloadTooLarge {
+		t.Errorf("expected a payload over the limit to fail, got %v", err)
+	}
+}
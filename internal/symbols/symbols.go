@@ -0,0 +1,236 @@
+// Package symbols summarizes a code change by the declarations it touches,
+// e.g. "modified func (Model) Update, added type RetryPolicy"
+package symbols
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+
+	"github.com/ztaylor/claude-mon/internal/lang"
+)
+
+// maxListed is how many declarations a summary names before "+N more"
+const maxListed = 2
+
+// Decl is a top-level declaration in a source file
+type Decl struct {
+	Kind string // "func", "type", "var", "const", "import", "class", "interface", ...
+	Name string // e.g. "(Model) Update" for a Go method
+	Text string // Source text, to tell whether it changed
+}
+
+func (d Decl) key() string {
+	return d.Kind + " " + d.Name
+}
+
+func extractor(id string) func(string) []Decl {
+	switch id {
+	case "go":
+		return goDecls
+	case "ts", "js", "jsx":
+		return scriptDecls
+	}
+	return nil
+}
+
+// Summarize describes an edit by the declarations it added, removed or
+// modified. With the file content after the edit it compares the whole file
+// before and after; otherwise only declarations within the edit strings.
+// Returns "" for unsupported languages or when no declaration changed.
+func Summarize(path, oldString, newString, fileContent string) string {
+	extract := extractor(lang.DetectContent(path, fileContent).ID)
+	if extract == nil {
+		return ""
+	}
+
+	before, after := oldString, newString
+	if fileContent != "" {
+		after = fileContent
+		switch {
+		case newString == fileContent:
+			before = "" // A full write; what it replaced is unknown
+		case newString != "" && strings.Count(fileContent, newString) == 1:
+			before = strings.Replace(fileContent, newString, oldString, 1)
+		}
+	}
+	return describe(Compare(extract(before), extract(after)))
+}
+
+// Change is a declaration an edit added, removed or modified
+type Change struct {
+	Verb string // "added", "removed" or "modified"
+	Decl Decl
+}
+
+// Compare lists the declarations that differ between two versions of a
+// file, in file order: added and modified ones, then removed ones
+func Compare(before, after []Decl) []Change {
+	old := make(map[string][]Decl)
+	for _, d := range before {
+		old[d.key()] = append(old[d.key()], d)
+	}
+
+	var changes []Change
+	for _, d := range after {
+		prev := old[d.key()]
+		if len(prev) == 0 {
+			changes = append(changes, Change{"added", d})
+			continue
+		}
+		old[d.key()] = prev[1:]
+		if prev[0].Text != d.Text {
+			changes = append(changes, Change{"modified", d})
+		}
+	}
+	for _, d := range before {
+		if rest := old[d.key()]; len(rest) > 0 {
+			old[d.key()] = rest[1:]
+			changes = append(changes, Change{"removed", d})
+		}
+	}
+	return changes
+}
+
+// describe joins the first few changes into one line
+func describe(changes []Change) string {
+	if len(changes) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, maxListed)
+	for _, c := range changes[:min(len(changes), maxListed)] {
+		parts = append(parts, c.Verb+" "+c.Decl.key())
+	}
+	s := strings.Join(parts, ", ")
+	if len(changes) > maxListed {
+		s += fmt.Sprintf(" (+%d more)", len(changes)-maxListed)
+	}
+	return s
+}
+
+// goDecls parses Go source for its top-level declarations, falling back to
+// scanning declaration lines when it's only a fragment
+func goDecls(src string) []Decl {
+	if strings.TrimSpace(src) == "" {
+		return nil
+	}
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments|parser.SkipObjectResolution)
+	if f == nil || (err != nil && !strings.HasPrefix(strings.TrimSpace(src), "package ")) {
+		return scanDecls(src, goDeclLine, goKind)
+	}
+
+	text := func(from, to token.Pos) string {
+		start, end := fset.Position(from).Offset, fset.Position(to).Offset
+		if start < 0 || end > len(src) || start > end {
+			return ""
+		}
+		return src[start:end]
+	}
+	withDoc := func(doc *ast.CommentGroup, node ast.Node) string {
+		if doc != nil {
+			return text(doc.Pos(), node.End())
+		}
+		return text(node.Pos(), node.End())
+	}
+
+	var decls []Decl
+	for _, d := range f.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			name := d.Name.Name
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				name = "(" + receiverType(d.Recv.List[0].Type) + ") " + name
+			}
+			decls = append(decls, Decl{Kind: "func", Name: name, Text: withDoc(d.Doc, d)})
+		case *ast.GenDecl:
+			kind := d.Tok.String()
+			if d.Tok == token.IMPORT {
+				decls = append(decls, Decl{Kind: "import", Name: "block", Text: text(d.Pos(), d.End())})
+				continue
+			}
+			for _, spec := range d.Specs {
+				specText := text(spec.Pos(), spec.End())
+				if !d.Lparen.IsValid() {
+					specText = withDoc(d.Doc, d)
+				}
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					decls = append(decls, Decl{Kind: kind, Name: s.Name.Name, Text: specText})
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						decls = append(decls, Decl{Kind: kind, Name: n.Name, Text: specText})
+					}
+				}
+			}
+		}
+	}
+	return decls
+}
+
+// receiverType returns a receiver's type name without pointer or type
+// parameters, e.g. "Model" for "*Model" or "List" for "List[T]"
+func receiverType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(t.X)
+	case *ast.IndexExpr:
+		return receiverType(t.X)
+	case *ast.IndexListExpr:
+		return receiverType(t.X)
+	case *ast.Ident:
+		return t.Name
+	}
+	return "?"
+}
+
+// Declaration lines at the start of a line, for fragments and for
+// languages without a parser here
+var (
+	goDeclLine     = regexp.MustCompile(`^(func|type|var|const)\s+(\([^)]*\)\s*)?([A-Za-z_]\w*)`)
+	scriptDeclLine = regexp.MustCompile(`^(?:export\s+)?(?:default\s+)?(?:declare\s+)?(?:abstract\s+)?(?:async\s+)?(function\*?|class|interface|type|enum|const|let|var)\s+([A-Za-z_$][\w$]*)`)
+)
+
+func goKind(m []string) (string, string) {
+	name := m[3]
+	if m[2] != "" {
+		recv := strings.Fields(strings.Trim(m[2], "() \t"))
+		if len(recv) > 0 {
+			t := strings.TrimLeft(recv[len(recv)-1], "*")
+			t, _, _ = strings.Cut(t, "[")
+			name = "(" + t + ") " + name
+		}
+	}
+	return m[1], name
+}
+
+func scriptKind(m []string) (string, string) {
+	return strings.TrimSuffix(m[1], "*"), m[2]
+}
+
+// scriptDecls scans JavaScript/TypeScript for top-level declarations
+func scriptDecls(src string) []Decl {
+	return scanDecls(src, scriptDeclLine, scriptKind)
+}
+
+// scanDecls finds declarations starting at column 0; each runs until the
+// next one
+func scanDecls(src string, re *regexp.Regexp, kind func([]string) (string, string)) []Decl {
+	var decls []Decl
+	lines := strings.SplitAfter(src, "\n")
+	for i, line := range lines {
+		m := re.FindStringSubmatch(line)
+		if m == nil {
+			if len(decls) > 0 {
+				decls[len(decls)-1].Text += line
+			}
+			continue
+		}
+		k, name := kind(m)
+		decls = append(decls, Decl{Kind: k, Name: name, Text: lines[i]})
+	}
+	return decls
+}
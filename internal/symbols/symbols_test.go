@@ -0,0 +1,87 @@
+package symbols
+
+import (
+	"strings"
+	"testing"
+)
+
+const goFile = `package model
+
+import "fmt"
+
+// Model is the TUI state
+type Model struct {
+	width int
+}
+
+// Update handles a message
+func (m *Model) Update(msg string) string {
+	return fmt.Sprint(msg)
+}
+
+func helper() {}
+`
+
+func TestSummarize(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		old, new   string
+		content    string
+		wantResult string
+	}{
+		{
+			"go method body",
+			"model.go",
+			"return fmt.Sprint(msg)", "return fmt.Sprintf(\"%s!\", msg)",
+			strings.Replace(goFile, "return fmt.Sprint(msg)", "return fmt.Sprintf(\"%s!\", msg)", 1),
+			"modified func (Model) Update",
+		},
+		{
+			"go added type",
+			"model.go",
+			"func helper() {}\n", "func helper() {}\n\ntype RetryPolicy struct{}\n",
+			goFile + "\ntype RetryPolicy struct{}\n",
+			"added type RetryPolicy",
+		},
+		{
+			"go removed and renamed",
+			"model.go",
+			"func helper() {}\n", "func assist() {}\n",
+			strings.Replace(goFile, "func helper() {}", "func assist() {}", 1),
+			"added func assist, removed func helper",
+		},
+		{
+			"go fragment without file",
+			"model.go",
+			"func (m Model) View() string {\n\treturn \"\"\n}", "func (m Model) View() string {\n\treturn \"x\"\n}",
+			"",
+			"modified func (Model) View",
+		},
+		{
+			"typescript",
+			"api.ts",
+			"export function fetchUser(id: string) {\n  return get(id)\n}\n",
+			"export async function fetchUser(id: string) {\n  return await get(id)\n}\n\nexport interface User {\n  id: string\n}\n",
+			"",
+			"modified function fetchUser, added interface User",
+		},
+		{
+			"many changes",
+			"a.go",
+			"", "package a\n\nfunc a() {}\nfunc b() {}\nfunc c() {}\nfunc d() {}\n",
+			"package a\n\nfunc a() {}\nfunc b() {}\nfunc c() {}\nfunc d() {}\n",
+			"added func a, added func b (+2 more)",
+		},
+		{"unsupported", "README.md", "a", "b", "", ""},
+		{"no declaration", "a.go", "x := 1", "x := 2", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Summarize(tt.path, tt.old, tt.new, tt.content); got != tt.wantResult {
+				t.Errorf("Summarize() = %q, want %q", got, tt.wantResult)
+			}
+		})
+	}
+}
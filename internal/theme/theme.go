@@ -76,6 +76,22 @@ func Get(name string) *Theme {
 	}
 }
 
+// Auto is the theme name that picks light or dark from the terminal background
+const Auto = "auto"
+
+// Resolve maps Auto to "light" or "dark" by asking the terminal for its
+// background color (OSC 11). Other names are returned unchanged. Call it
+// before the TUI takes over the terminal, since the answer arrives on stdin.
+func Resolve(name string) string {
+	if name != Auto {
+		return name
+	}
+	if lipgloss.HasDarkBackground() {
+		return "dark"
+	}
+	return "light"
+}
+
 // Available returns list of available theme names
 func Available() []string {
 	return []string{"dark", "light", "dracula", "monokai", "gruvbox", "nord", "catppuccin"}
@@ -0,0 +1,138 @@
+package vcs
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// uncommittedSHA is the commit git blame reports for lines not yet committed
+const uncommittedSHA = "0000000000000000000000000000000000000000"
+
+// BlameLine is the last commit that touched a line
+type BlameLine struct {
+	SHA    string
+	Author string
+	Time   time.Time
+}
+
+// Short returns the abbreviated commit SHA
+func (b BlameLine) Short() string {
+	if len(b.SHA) > 7 {
+		return b.SHA[:7]
+	}
+	return b.SHA
+}
+
+// Uncommitted reports whether the line has no commit yet
+func (b BlameLine) Uncommitted() bool {
+	return b.SHA == uncommittedSHA
+}
+
+var (
+	blameMu    sync.Mutex
+	blameCache = make(map[string][]BlameLine)
+)
+
+// Blame returns per-line blame for a file at a commit (the current one if
+// commitSHA is empty), one entry per line. vcsType is "git" or "jj", or
+// empty to auto-detect. Results are cached per file and commit, so only the
+// first call for a given pair runs the VCS.
+func Blame(workspacePath, filePath, commitSHA, vcsType string) ([]BlameLine, error) {
+	v, err := Get(vcsType, workspacePath)
+	if err != nil {
+		return nil, err
+	}
+	relPath := relativePath(workspacePath, filePath)
+
+	key := v.Name() + "\x00" + workspacePath + "\x00" + relPath + "\x00" + commitSHA
+	blameMu.Lock()
+	lines, ok := blameCache[key]
+	blameMu.Unlock()
+	if ok {
+		return lines, nil
+	}
+
+	lines, err = v.Blame(workspacePath, relPath, commitSHA)
+	if err != nil {
+		return nil, err
+	}
+
+	blameMu.Lock()
+	blameCache[key] = lines
+	blameMu.Unlock()
+	return lines, nil
+}
+
+// ClearBlameCache drops all cached blame output
+func ClearBlameCache() {
+	blameMu.Lock()
+	blameCache = make(map[string][]BlameLine)
+	blameMu.Unlock()
+}
+
+// blameFromGit runs git blame in dir and parses its porcelain output.
+// gitArgs go before the subcommand, e.g. to point at a jj repo's git store.
+func blameFromGit(dir, filePath, commitSHA string, gitArgs ...string) ([]BlameLine, error) {
+	// git blame --porcelain <commit> -- <file>
+	args := append(gitArgs, "blame", "--porcelain", commitSHA, "--", filePath)
+	output, err := runVCS(dir, "git", args...)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBlamePorcelain(output), nil
+}
+
+// ParseBlamePorcelain parses `git blame --porcelain` output into one entry
+// per final line. Commit details are only printed the first time a commit
+// appears, so they are remembered by SHA.
+func ParseBlamePorcelain(output string) []BlameLine {
+	commits := make(map[string]*BlameLine)
+	var lines []BlameLine
+	var current *BlameLine
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Content line closes the current entry
+		if strings.HasPrefix(line, "\t") {
+			if current != nil {
+				lines = append(lines, *current)
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		// Header: <sha> <orig-line> <final-line> [<group-size>]
+		if len(fields[0]) == 40 && len(fields) >= 3 {
+			sha := fields[0]
+			if _, ok := commits[sha]; !ok {
+				commits[sha] = &BlameLine{SHA: sha}
+			}
+			current = commits[sha]
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+		switch fields[0] {
+		case "author":
+			current.Author = strings.TrimPrefix(line, "author ")
+		case "author-time":
+			if sec, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+				current.Time = time.Unix(sec, 0)
+			}
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,77 @@
+package vcs
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseBlamePorcelain(t *testing.T) {
+	output := `1111111111111111111111111111111111111111 1 1 2
+author Alice
+author-mail <alice@example.com>
+author-time 1700000000
+author-tz +0000
+summary first
+filename main.go
+	package main
+1111111111111111111111111111111111111111 2 2
+	
+0000000000000000000000000000000000000000 3 3 1
+author Not Committed Yet
+author-time 1700000500
+filename main.go
+	func main() {}
+`
+	lines := ParseBlamePorcelain(output)
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(lines))
+	}
+	if lines[0].Author != "Alice" || lines[1].Author != "Alice" {
+		t.Errorf("expected repeated commit to keep its author, got %q/%q", lines[0].Author, lines[1].Author)
+	}
+	if lines[0].Short() != "1111111" {
+		t.Errorf("expected short SHA 1111111, got %s", lines[0].Short())
+	}
+	if lines[0].Time.Unix() != 1700000000 {
+		t.Errorf("unexpected author time: %v", lines[0].Time)
+	}
+	if !lines[2].Uncommitted() || lines[0].Uncommitted() {
+		t.Error("expected only the last line to be uncommitted")
+	}
+}
+
+func TestBlameCaches(t *testing.T) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get cwd: %v", err)
+	}
+	if DetectVCSType(cwd) != "git" {
+		t.Skip("Not a git checkout")
+	}
+	root, err := GetWorkspaceRoot(cwd, "git")
+	if err != nil {
+		t.Fatalf("Failed to get workspace root: %v", err)
+	}
+	if err := exec.Command("git", "-C", root, "cat-file", "-e", "HEAD:go.mod").Run(); err != nil {
+		t.Skip("go.mod not committed")
+	}
+
+	ClearBlameCache()
+	lines, err := Blame(root, filepath.Join(root, "go.mod"), "", "git")
+	if err != nil {
+		t.Fatalf("Failed to blame go.mod: %v", err)
+	}
+	if len(lines) == 0 || lines[0].SHA == "" {
+		t.Fatal("Expected blame lines for go.mod")
+	}
+
+	if len(blameCache) != 1 {
+		t.Errorf("Expected 1 cached blame, got %d", len(blameCache))
+	}
+	again, err := Blame(root, "go.mod", "HEAD", "git")
+	if err != nil || len(again) != len(lines) {
+		t.Errorf("Expected cached result, got %d lines (%v)", len(again), err)
+	}
+}
@@ -0,0 +1,191 @@
+package vcs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DiffHunk is one hunk of a unified diff
+type DiffHunk struct {
+	Header string   // @@ -a,b +c,d @@ line
+	Lines  []string // Body lines, each prefixed with ' ', '+', '-' or '\'
+}
+
+// Added returns the hunk's added lines without the '+' prefix
+func (h DiffHunk) Added() []string {
+	return h.prefixed('+')
+}
+
+// Removed returns the hunk's removed lines without the '-' prefix
+func (h DiffHunk) Removed() []string {
+	return h.prefixed('-')
+}
+
+func (h DiffHunk) prefixed(prefix byte) []string {
+	var lines []string
+	for _, line := range h.Lines {
+		if len(line) > 0 && line[0] == prefix {
+			lines = append(lines, line[1:])
+		}
+	}
+	return lines
+}
+
+// ParseDiffHunks splits single-file `git diff` output into its file header
+// (everything before the first hunk) and hunks
+func ParseDiffHunks(diff string) (string, []DiffHunk) {
+	var header strings.Builder
+	var hunks []DiffHunk
+
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			hunks = append(hunks, DiffHunk{Header: line})
+		case len(hunks) > 0:
+			hunks[len(hunks)-1].Lines = append(hunks[len(hunks)-1].Lines, line)
+		default:
+			header.WriteString(line + "\n")
+		}
+	}
+
+	return header.String(), hunks
+}
+
+// HunkInChange reports whether a working tree hunk was produced by a recorded
+// change: its added lines all come from newText and its removed lines all
+// come from oldText (blank lines and surrounding whitespace are ignored).
+func HunkInChange(h DiffHunk, oldText, newText string) bool {
+	added, removed := h.Added(), h.Removed()
+	if len(added) == 0 && len(removed) == 0 {
+		return false
+	}
+	return linesWithin(added, newText) && linesWithin(removed, oldText)
+}
+
+// linesWithin reports whether every non-blank line appears in text
+func linesWithin(lines []string, text string) bool {
+	available := make(map[string]bool)
+	for _, line := range strings.Split(text, "\n") {
+		available[strings.TrimSpace(line)] = true
+	}
+	for _, line := range lines {
+		key := strings.TrimSpace(line)
+		if key != "" && !available[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// Runner runs a command in dir and returns its standard output. The TUI's
+// command runner satisfies it.
+type Runner interface {
+	Output(ctx context.Context, dir, name string, args ...string) ([]byte, error)
+}
+
+// runGit runs a git command in dir through r, folding stderr into the error
+func runGit(r Runner, dir string, args ...string) (string, error) {
+	output, err := r.Output(context.Background(), dir, "git", args...)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("git %s failed: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return string(output), nil
+}
+
+// StagedFiles lists the files already staged in the repository containing
+// dir
+func StagedFiles(r Runner, dir string) ([]string, error) {
+	output, err := runGit(r, dir, "diff", "--cached", "--name-only", "-z")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, name := range strings.Split(output, "\x00") {
+		if name != "" {
+			files = append(files, name)
+		}
+	}
+	return files, nil
+}
+
+// StageChange stages the hunks of filePath that belong to a recorded change
+// (oldText -> newText), like `git add -p` answering yes only to those hunks.
+// Untracked files are staged whole. Returns the number of hunks staged.
+func StageChange(r Runner, filePath, oldText, newText string) (int, error) {
+	dir, name := filepath.Split(filePath)
+	if dir == "" {
+		dir = "."
+	}
+
+	// New files have no diff against the index - add them outright
+	if _, err := runGit(r, dir, "ls-files", "--error-unmatch", "--", name); err != nil {
+		if _, err := runGit(r, dir, "add", "--", name); err != nil {
+			return 0, err
+		}
+		return 1, nil
+	}
+
+	// Zero-context diff so unrelated edits land in separate hunks
+	output, err := runGit(r, dir, "diff", "--no-color", "--no-ext-diff", "-U0", "--", name)
+	if err != nil {
+		return 0, err
+	}
+
+	header, hunks := ParseDiffHunks(output)
+	var patch strings.Builder
+	patch.WriteString(header)
+	staged := 0
+	for _, h := range hunks {
+		if !HunkInChange(h, oldText, newText) {
+			continue
+		}
+		patch.WriteString(h.Header + "\n")
+		for _, line := range h.Lines {
+			patch.WriteString(line + "\n")
+		}
+		staged++
+	}
+	if staged == 0 {
+		return 0, nil
+	}
+
+	// The runner has no stdin, so the patch goes through a file
+	f, err := os.CreateTemp("", "claude-mon-*.patch")
+	if err != nil {
+		return 0, fmt.Errorf("failed to write patch: %w", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.WriteString(patch.String())
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to write patch: %w", err)
+	}
+	if _, err := runGit(r, dir, "apply", "--cached", "--unidiff-zero", f.Name()); err != nil {
+		return 0, err
+	}
+	return staged, nil
+}
+
+// Commit commits the staged changes in the repository containing dir and
+// returns the new commit's short SHA
+func Commit(r Runner, dir, message string) (string, error) {
+	if _, err := runGit(r, dir, "commit", "-q", "-m", message); err != nil {
+		return "", err
+	}
+	output, err := runGit(r, dir, "rev-parse", "--short", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
@@ -0,0 +1,121 @@
+package vcs
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDiffHunks(t *testing.T) {
+	diff := `diff --git a/f.txt b/f.txt
+index 1111111..2222222 100644
+--- a/f.txt
++++ b/f.txt
+@@ -2 +2 @@
+-two
++TWO
+@@ -5,0 +6,2 @@
++six
++seven
+`
+	header, hunks := ParseDiffHunks(diff)
+	if !strings.HasPrefix(header, "diff --git") || !strings.HasSuffix(header, "+++ b/f.txt\n") {
+		t.Errorf("unexpected header: %q", header)
+	}
+	if len(hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %d", len(hunks))
+	}
+	if got := hunks[1].Added(); len(got) != 2 || got[0] != "six" {
+		t.Errorf("unexpected added lines: %v", got)
+	}
+
+	if !HunkInChange(hunks[0], "two", "TWO") {
+		t.Error("expected first hunk to match the recorded change")
+	}
+	if HunkInChange(hunks[1], "two", "TWO") {
+		t.Error("expected second hunk not to match the recorded change")
+	}
+}
+
+// execRunner runs commands with os/exec
+type execRunner struct{}
+
+func (execRunner) Output(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	return cmd.Output()
+}
+
+func TestStageChangeAndCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	for _, v := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(v, "test")
+	}
+	for _, v := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(v, "test@example.com")
+	}
+
+	repo := t.TempDir()
+	git := func(args ...string) string {
+		out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return string(out)
+	}
+	git("init", "-q")
+
+	file := filepath.Join(repo, "sub", "f.txt")
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(file, []byte("one\ntwo\nthree\nfour\nfive\n"), 0644)
+	git("add", ".")
+	git("commit", "-q", "-m", "init")
+
+	// One recorded change plus an unrelated edit by someone else
+	os.WriteFile(file, []byte("one\nTWO\nthree\nfour\nfive\nunrelated\n"), 0644)
+
+	var r execRunner
+	if files, err := StagedFiles(r, repo); err != nil || len(files) != 0 {
+		t.Fatalf("expected nothing staged, got %v (%v)", files, err)
+	}
+	staged, err := StageChange(r, file, "two", "TWO")
+	if err != nil {
+		t.Fatalf("StageChange failed: %v", err)
+	}
+	if staged != 1 {
+		t.Fatalf("expected 1 staged hunk, got %d", staged)
+	}
+	if cached := git("diff", "--cached"); !strings.Contains(cached, "+TWO") || strings.Contains(cached, "unrelated") {
+		t.Errorf("expected only the recorded hunk staged, got:\n%s", cached)
+	}
+
+	if files, err := StagedFiles(r, repo); err != nil || len(files) != 1 || files[0] != "sub/f.txt" {
+		t.Errorf("expected sub/f.txt staged, got %v (%v)", files, err)
+	}
+
+	sha, err := Commit(r, filepath.Dir(file), "Capitalize two")
+	if err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if sha == "" {
+		t.Error("expected a commit SHA")
+	}
+	if diff := git("diff"); !strings.Contains(diff, "+unrelated") {
+		t.Errorf("expected the unrelated edit to stay unstaged, got:\n%s", diff)
+	}
+
+	// New files are staged whole
+	newFile := filepath.Join(repo, "sub", "new.txt")
+	os.WriteFile(newFile, []byte("hello\n"), 0644)
+	if staged, err := StageChange(r, newFile, "", "hello\n"); err != nil || staged != 1 {
+		t.Errorf("expected new file staged, got %d (%v)", staged, err)
+	}
+}
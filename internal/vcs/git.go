@@ -0,0 +1,151 @@
+package vcs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// gitVCS implements VCS with the git CLI
+type gitVCS struct{}
+
+func (gitVCS) Name() string { return "git" }
+
+func (gitVCS) Root(dir string) (string, error) {
+	output, err := runVCS(dir, "git", "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+func (gitVCS) CurrentCommit(dir string) (string, string, error) {
+	output, err := runVCS(dir, "git", "rev-parse", "HEAD", "--short", "HEAD")
+	if err != nil {
+		return "", "", err
+	}
+	ids := strings.Fields(output)
+	if len(ids) != 2 {
+		return "", "", fmt.Errorf("git rev-parse failed: unexpected output %q", output)
+	}
+	return ids[0], ids[1], nil
+}
+
+func (gitVCS) FileAtCommit(dir, path, rev string) (string, error) {
+	// git show <commit>:<file> - a ./ prefix makes the path relative to dir
+	// rather than the repository root
+	if !filepath.IsAbs(path) {
+		path = "./" + filepath.ToSlash(path)
+	}
+	return runVCS(dir, "git", "show", fmt.Sprintf("%s:%s", rev, path))
+}
+
+func (gitVCS) Blame(dir, path, rev string) ([]BlameLine, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+	return blameFromGit(dir, path, rev)
+}
+
+func (gitVCS) Branches(dir string) ([]string, error) {
+	return gitBranches(context.Background(), osRunner{}, dir)
+}
+
+// gitBranches lists local and remote branches, running git through r
+func gitBranches(ctx context.Context, r Runner, dir string) ([]string, error) {
+	local, err := runVCSWith(ctx, r, dir, "git", "branch", "--format=%(refname:short)")
+	if err != nil {
+		return nil, err
+	}
+	branches := strings.Fields(local)
+
+	// Add remote branches, without the origin/ prefix for cleaner display
+	if remote, err := runVCSWith(ctx, r, dir, "git", "branch", "-r", "--format=%(refname:short)"); err == nil {
+		for _, branch := range strings.Fields(remote) {
+			if !strings.HasSuffix(branch, "HEAD") && branch != "origin" {
+				branches = append(branches, strings.TrimPrefix(branch, "origin/"))
+			}
+		}
+	}
+	return uniqueStrings(branches), nil
+}
+
+func (gitVCS) Status(dir string) (Status, error) {
+	output, err := runVCS(dir, "git", "status", "--porcelain=v2", "--branch")
+	if err != nil {
+		return Status{}, err
+	}
+	return parseGitStatus(output), nil
+}
+
+// parseGitStatus reads the output of git status --porcelain=v2 --branch:
+// "# branch." headers, then a line per changed or untracked file
+func parseGitStatus(output string) Status {
+	var s Status
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "# branch.head "):
+			if head := strings.TrimPrefix(line, "# branch.head "); head != "(detached)" {
+				s.Branch = head
+			}
+		case strings.HasPrefix(line, "# branch.ab "):
+			fmt.Sscanf(strings.TrimPrefix(line, "# branch.ab "), "+%d -%d", &s.Ahead, &s.Behind)
+		case strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "!"): // Ignored files, if shown
+		default:
+			s.Dirty = true
+		}
+	}
+	return s
+}
+
+// runVCS runs a VCS command in dir, folding stderr into the error
+func runVCS(dir, name string, args ...string) (string, error) {
+	return runVCSWith(context.Background(), osRunner{}, dir, name, args...)
+}
+
+// runVCSWith is runVCS through r
+func runVCSWith(ctx context.Context, r Runner, dir, name string, args ...string) (string, error) {
+	output, err := r.Output(ctx, dir, name, args...)
+	if err != nil {
+		op := name
+		for _, arg := range args {
+			if !strings.HasPrefix(arg, "-") {
+				op += " " + arg
+				break
+			}
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("%s failed: %s", op, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("%s failed: %w", op, err)
+	}
+	return string(output), nil
+}
+
+// osRunner runs commands with os/exec
+type osRunner struct{}
+
+func (osRunner) Output(ctx context.Context, dir, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	return cmd.Output()
+}
+
+// uniqueStrings drops empty and repeated entries, keeping order
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, v := range values {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
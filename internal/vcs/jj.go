@@ -0,0 +1,140 @@
+package vcs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// jjVCS implements VCS with the jj (Jujutsu) CLI. Revisions are change IDs;
+// jj's git backend stores every commit in a git repository, so blame is
+// delegated to git on the commit a change currently points at.
+type jjVCS struct{}
+
+func (jjVCS) Name() string { return "jj" }
+
+func (jjVCS) Root(dir string) (string, error) {
+	output, err := runVCS(dir, "jj", "root")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(output), nil
+}
+
+func (jjVCS) CurrentCommit(dir string) (string, string, error) {
+	output, err := runVCS(dir, "jj", "log", "-r", "@", "--no-graph", "-T", `change_id ++ " " ++ change_id.shortest(8)`)
+	if err != nil {
+		return "", "", err
+	}
+	ids := strings.Fields(output)
+	if len(ids) != 2 {
+		return "", "", fmt.Errorf("jj log failed: unexpected output %q", output)
+	}
+	return ids[0], ids[1], nil
+}
+
+func (jjVCS) FileAtCommit(dir, path, rev string) (string, error) {
+	// jj file show -r <revision> <fileset>; a quoted fileset is a literal
+	// path relative to dir
+	return runVCS(dir, "jj", "file", "show", "-r", rev, strconv.Quote(path))
+}
+
+func (j jjVCS) Blame(dir, path, rev string) ([]BlameLine, error) {
+	if rev == "" {
+		rev = "@"
+	}
+	root, err := j.Root(dir)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := j.commitID(dir, rev)
+	if err != nil {
+		return nil, err
+	}
+	gitDir, err := jjGitDir(root)
+	if err != nil {
+		return nil, err
+	}
+	return blameFromGit(dir, path, commit, "--git-dir="+gitDir, "--work-tree="+root)
+}
+
+func (jjVCS) Branches(dir string) ([]string, error) {
+	return jjBranches(context.Background(), osRunner{}, dir)
+}
+
+// jjBranches lists local and remote bookmarks, running jj through r
+func jjBranches(ctx context.Context, r Runner, dir string) ([]string, error) {
+	// Local and remote bookmarks; tracked remotes repeat the local name
+	template := `name ++ "\n"`
+	output, err := runVCSWith(ctx, r, dir, "jj", "bookmark", "list", "--all-remotes", "-T", template)
+	if err != nil {
+		// jj before 0.22 called bookmarks branches
+		if output, err = runVCSWith(ctx, r, dir, "jj", "branch", "list", "--all-remotes", "-T", template); err != nil {
+			return nil, err
+		}
+	}
+	return uniqueStrings(strings.Fields(output)), nil
+}
+
+func (jjVCS) Status(dir string) (Status, error) {
+	output, err := runVCS(dir, "jj", "log", "-r", "@", "--no-graph", "-T", `if(empty, "clean", "dirty")`)
+	if err != nil {
+		return Status{}, err
+	}
+	s := Status{Dirty: strings.TrimSpace(output) == "dirty"}
+
+	// The working copy is usually a new change on top of the bookmark, so
+	// take the closest bookmarked ancestor
+	output, err = runVCS(dir, "jj", "log", "-r", "heads(::@ & bookmarks())", "--no-graph", "-T", `local_bookmarks.map(|b| b.name()).join(" ") ++ "\n"`)
+	if err == nil {
+		if names := strings.Fields(output); len(names) > 0 {
+			s.Branch = names[0]
+		}
+	}
+	return s, nil
+}
+
+// commitID resolves a jj revision to the git commit it currently points at
+func (jjVCS) commitID(dir, rev string) (string, error) {
+	output, err := runVCS(dir, "jj", "log", "-r", rev, "--no-graph", "-T", "commit_id")
+	if err != nil {
+		return "", err
+	}
+	commit := strings.TrimSpace(output)
+	if commit == "" {
+		return "", fmt.Errorf("jj log failed: no commit for %s", rev)
+	}
+	return commit, nil
+}
+
+// jjGitDir returns the git repository backing a jj workspace. Secondary
+// workspaces point .jj/repo at the main repo; the store's git_target names
+// the git dir (.git itself in colocated repos).
+func jjGitDir(root string) (string, error) {
+	repo := filepath.Join(root, ".jj", "repo")
+	if info, err := os.Stat(repo); err == nil && !info.IsDir() {
+		target, err := os.ReadFile(repo)
+		if err != nil {
+			return "", err
+		}
+		repo = resolveFrom(filepath.Join(root, ".jj"), strings.TrimSpace(string(target)))
+	}
+
+	store := filepath.Join(repo, "store")
+	target, err := os.ReadFile(filepath.Join(store, "git_target"))
+	if err != nil {
+		return "", fmt.Errorf("jj repo has no git backend: %w", err)
+	}
+	return resolveFrom(store, strings.TrimSpace(string(target))), nil
+}
+
+// resolveFrom resolves path relative to base unless it is absolute
+func resolveFrom(base, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Clean(filepath.Join(base, path))
+}
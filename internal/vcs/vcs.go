@@ -2,155 +2,171 @@
 package vcs
 
 import (
+	"context"
 	"fmt"
-	"os/exec"
 	"path/filepath"
-	"strings"
+	"sync"
 )
 
-// GetFileAtCommit retrieves file content at a specific commit/change ID
-// workspacePath is the root of the VCS repository
-// filePath is the path to the file (can be absolute or relative to workspace)
-// commitSHA is the commit hash (git) or change ID (jj)
-// vcsType is "git" or "jj"
-func GetFileAtCommit(workspacePath, filePath, commitSHA, vcsType string) (string, error) {
-	if commitSHA == "" {
-		return "", fmt.Errorf("no commit SHA provided")
-	}
+// VCS is a version control backend. dir may be any directory inside the
+// workspace and file paths are relative to it; revisions are commit SHAs
+// (git) or change IDs (jj).
+type VCS interface {
+	// Name returns "git" or "jj"
+	Name() string
+	// Root returns the root directory of the workspace containing dir
+	Root(dir string) (string, error)
+	// CurrentCommit returns the full and short ID of the checked out revision
+	CurrentCommit(dir string) (sha, shortSHA string, err error)
+	// FileAtCommit returns a file's content at a revision
+	FileAtCommit(dir, path, rev string) (string, error)
+	// Blame returns per-line blame for a file at a revision ("" for the
+	// current one)
+	Blame(dir, path, rev string) ([]BlameLine, error)
+	// Branches lists local and remote branch (jj: bookmark) names
+	Branches(dir string) ([]string, error)
+	// Status returns the checked out branch and whether the working copy
+	// has uncommitted changes
+	Status(dir string) (Status, error)
+}
 
-	// Make file path relative to workspace if it's absolute
-	relPath := filePath
-	if filepath.IsAbs(filePath) && workspacePath != "" {
-		var err error
-		relPath, err = filepath.Rel(workspacePath, filePath)
-		if err != nil {
-			relPath = filePath // Fall back to original
-		}
-	}
+// Status is the checked out branch, how far it is from its upstream and
+// whether the working copy has uncommitted changes
+type Status struct {
+	Branch string // Empty when detached (jj: no bookmark at or below @)
+	Ahead  int    // Commits not pushed to the upstream (git only)
+	Behind int    // Upstream commits not pulled (git only)
+	Dirty  bool   // Modified, staged or untracked files
+}
 
+// Get returns the backend for a VCS type ("git" or "jj"). Any other type
+// is auto-detected from dir.
+func Get(vcsType, dir string) (VCS, error) {
 	switch vcsType {
-	case "jj":
-		return getFileFromJJ(workspacePath, relPath, commitSHA)
 	case "git":
-		return getFileFromGit(workspacePath, relPath, commitSHA)
-	default:
-		// Try jj first (auto-detection), then git
-		content, err := getFileFromJJ(workspacePath, relPath, commitSHA)
-		if err == nil {
-			return content, nil
-		}
-		return getFileFromGit(workspacePath, relPath, commitSHA)
+		return gitVCS{}, nil
+	case "jj":
+		return jjVCS{}, nil
+	}
+	if v := Detect(dir); v != nil {
+		return v, nil
 	}
+	return nil, fmt.Errorf("no VCS detected")
 }
 
-// getFileFromJJ retrieves file content from jj at a specific change ID
-func getFileFromJJ(workspacePath, filePath, changeID string) (string, error) {
-	// jj file show <file> -r <revision>
-	cmd := exec.Command("jj", "file", "show", filePath, "-r", changeID)
-	cmd.Dir = workspacePath
-	output, err := cmd.Output()
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return "", fmt.Errorf("jj file show failed: %s", string(exitErr.Stderr))
-		}
-		return "", fmt.Errorf("jj file show failed: %w", err)
+var (
+	detectMu    sync.Mutex
+	detectCache = make(map[string]VCS)
+)
+
+// Detect returns the backend managing dir, or nil if there is none. jj is
+// preferred so colocated repos use change IDs. Results are cached per
+// directory.
+func Detect(dir string) VCS {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
 	}
-	return string(output), nil
-}
 
-// getFileFromGit retrieves file content from git at a specific commit
-func getFileFromGit(workspacePath, filePath, commitSHA string) (string, error) {
-	// git show <commit>:<file>
-	// Note: git needs the path relative to repo root
-	cmd := exec.Command("git", "show", fmt.Sprintf("%s:%s", commitSHA, filePath))
-	cmd.Dir = workspacePath
-	output, err := cmd.Output()
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return "", fmt.Errorf("git show failed: %s", string(exitErr.Stderr))
+	detectMu.Lock()
+	v, ok := detectCache[dir]
+	detectMu.Unlock()
+	if ok {
+		return v
+	}
+
+	for _, candidate := range []VCS{jjVCS{}, gitVCS{}} {
+		if _, err := candidate.Root(dir); err == nil {
+			v = candidate
+			break
 		}
-		return "", fmt.Errorf("git show failed: %w", err)
 	}
-	return string(output), nil
+
+	detectMu.Lock()
+	detectCache[dir] = v
+	detectMu.Unlock()
+	return v
 }
 
 // DetectVCSType detects the VCS type for a given directory
 func DetectVCSType(dir string) string {
-	// Check for jj first
-	cmd := exec.Command("jj", "root")
-	cmd.Dir = dir
-	if err := cmd.Run(); err == nil {
-		return "jj"
+	if v := Detect(dir); v != nil {
+		return v.Name()
 	}
+	return ""
+}
 
-	// Check for git
-	cmd = exec.Command("git", "rev-parse", "--git-dir")
-	cmd.Dir = dir
-	if err := cmd.Run(); err == nil {
-		return "git"
+// GetFileAtCommit retrieves file content at a specific commit/change ID
+// workspacePath is the root of the VCS repository
+// filePath is the path to the file (can be absolute or relative to workspace)
+// commitSHA is the commit hash (git) or change ID (jj)
+// vcsType is "git" or "jj"
+func GetFileAtCommit(workspacePath, filePath, commitSHA, vcsType string) (string, error) {
+	if commitSHA == "" {
+		return "", fmt.Errorf("no commit SHA provided")
 	}
 
-	return ""
+	v, err := Get(vcsType, workspacePath)
+	if err != nil {
+		return "", err
+	}
+	return v.FileAtCommit(workspacePath, relativePath(workspacePath, filePath), commitSHA)
 }
 
 // GetCurrentCommit gets the current commit/change ID
 func GetCurrentCommit(dir, vcsType string) (string, error) {
-	switch vcsType {
-	case "jj":
-		cmd := exec.Command("jj", "log", "-r", "@", "--no-graph", "-T", "change_id.short()")
-		cmd.Dir = dir
-		output, err := cmd.Output()
-		if err != nil {
-			return "", fmt.Errorf("jj log failed: %w", err)
-		}
-		return strings.TrimSpace(string(output)), nil
-
-	case "git":
-		cmd := exec.Command("git", "rev-parse", "HEAD")
-		cmd.Dir = dir
-		output, err := cmd.Output()
-		if err != nil {
-			return "", fmt.Errorf("git rev-parse failed: %w", err)
-		}
-		return strings.TrimSpace(string(output)), nil
-
-	default:
-		// Auto-detect
-		vcsType = DetectVCSType(dir)
-		if vcsType != "" {
-			return GetCurrentCommit(dir, vcsType)
-		}
-		return "", fmt.Errorf("no VCS detected")
+	v, err := Get(vcsType, dir)
+	if err != nil {
+		return "", err
 	}
+	sha, _, err := v.CurrentCommit(dir)
+	return sha, err
 }
 
 // GetWorkspaceRoot returns the root directory of the VCS workspace
 func GetWorkspaceRoot(dir, vcsType string) (string, error) {
-	switch vcsType {
-	case "jj":
-		cmd := exec.Command("jj", "root")
-		cmd.Dir = dir
-		output, err := cmd.Output()
-		if err != nil {
-			return "", fmt.Errorf("jj root failed: %w", err)
-		}
-		return strings.TrimSpace(string(output)), nil
+	v, err := Get(vcsType, dir)
+	if err != nil {
+		return "", err
+	}
+	return v.Root(dir)
+}
 
-	case "git":
-		cmd := exec.Command("git", "rev-parse", "--show-toplevel")
-		cmd.Dir = dir
-		output, err := cmd.Output()
-		if err != nil {
-			return "", fmt.Errorf("git rev-parse failed: %w", err)
-		}
-		return strings.TrimSpace(string(output)), nil
+// GetBranches lists the branches (jj: bookmarks) of the workspace at dir
+func GetBranches(dir, vcsType string) ([]string, error) {
+	v, err := Get(vcsType, dir)
+	if err != nil {
+		return nil, err
+	}
+	return v.Branches(dir)
+}
 
-	default:
-		// Auto-detect
-		vcsType = DetectVCSType(dir)
-		if vcsType != "" {
-			return GetWorkspaceRoot(dir, vcsType)
+// ListBranches lists the branches (jj: bookmarks) of the workspace at dir,
+// detecting the VCS and running it through r. jj is preferred, as in Detect.
+func ListBranches(ctx context.Context, r Runner, dir string) ([]string, error) {
+	if _, err := runVCSWith(ctx, r, dir, "jj", "root"); err == nil {
+		return jjBranches(ctx, r, dir)
+	}
+	if _, err := runVCSWith(ctx, r, dir, "git", "rev-parse", "--show-toplevel"); err != nil {
+		return nil, fmt.Errorf("no VCS detected")
+	}
+	return gitBranches(ctx, r, dir)
+}
+
+// GetStatus returns the branch and working copy state of the workspace at dir
+func GetStatus(dir, vcsType string) (Status, error) {
+	v, err := Get(vcsType, dir)
+	if err != nil {
+		return Status{}, err
+	}
+	return v.Status(dir)
+}
+
+// relativePath makes an absolute file path relative to the workspace
+func relativePath(workspacePath, filePath string) string {
+	if filepath.IsAbs(filePath) && workspacePath != "" {
+		if rel, err := filepath.Rel(workspacePath, filePath); err == nil {
+			return rel
 		}
-		return "", fmt.Errorf("no VCS detected")
 	}
+	return filePath
 }
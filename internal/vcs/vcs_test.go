@@ -2,6 +2,7 @@ package vcs
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -107,3 +108,188 @@ func TestGetWorkspaceRoot(t *testing.T) {
 	}
 	t.Logf("Workspace root: %s", root)
 }
+
+// newTestRepo creates a repo with sub/f.txt committed, returning its root and
+// a runner for commands inside it
+func newTestRepo(t *testing.T, init ...[]string) (string, func(name string, args ...string) string) {
+	t.Helper()
+	for _, v := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME", "JJ_USER"} {
+		t.Setenv(v, "test")
+	}
+	for _, v := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL", "JJ_EMAIL"} {
+		t.Setenv(v, "test@example.com")
+	}
+	t.Setenv("JJ_CONFIG", filepath.Join(t.TempDir(), "config.toml"))
+
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := func(name string, args ...string) string {
+		cmd := exec.Command(name, args...)
+		cmd.Dir = root
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s %v: %v\n%s", name, args, err, out)
+		}
+		return string(out)
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(root, "sub", "f.txt"), []byte("one\ntwo\n"), 0644)
+	for _, args := range init {
+		run(args[0], args[1:]...)
+	}
+	return root, run
+}
+
+func TestGitBackend(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root, _ := newTestRepo(t,
+		[]string{"git", "init", "-q", "-b", "main"},
+		[]string{"git", "add", "."},
+		[]string{"git", "commit", "-q", "-m", "init"},
+		[]string{"git", "branch", "feature"},
+	)
+	sub := filepath.Join(root, "sub")
+
+	v := Detect(sub)
+	if v == nil || v.Name() != "git" {
+		t.Fatalf("expected git, got %v", v)
+	}
+	if got, err := v.Root(sub); err != nil || got != root {
+		t.Errorf("expected root %s, got %s (%v)", root, got, err)
+	}
+
+	sha, short, err := v.CurrentCommit(sub)
+	if err != nil || len(sha) != 40 || !strings.HasPrefix(sha, short) {
+		t.Fatalf("unexpected current commit %q/%q (%v)", sha, short, err)
+	}
+
+	// Paths are relative to the directory, not the repo root
+	os.WriteFile(filepath.Join(sub, "f.txt"), []byte("changed\n"), 0644)
+	if content, err := v.FileAtCommit(sub, "f.txt", sha); err != nil || content != "one\ntwo\n" {
+		t.Errorf("expected committed content, got %q (%v)", content, err)
+	}
+	if content, err := GetFileAtCommit(root, filepath.Join(sub, "f.txt"), sha, ""); err != nil || content != "one\ntwo\n" {
+		t.Errorf("expected committed content via auto-detect, got %q (%v)", content, err)
+	}
+
+	if lines, err := v.Blame(sub, "f.txt", ""); err != nil || len(lines) != 2 || lines[0].SHA != sha {
+		t.Errorf("unexpected blame %+v (%v)", lines, err)
+	}
+
+	branches, err := v.Branches(sub)
+	if err != nil || strings.Join(branches, ",") != "feature,main" {
+		t.Errorf("expected feature,main, got %v (%v)", branches, err)
+	}
+
+	// f.txt was changed above
+	if status, err := GetStatus(sub, ""); err != nil || status != (Status{Branch: "main", Dirty: true}) {
+		t.Errorf("expected main with changes, got %+v (%v)", status, err)
+	}
+}
+
+func TestParseGitStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   Status
+	}{
+		{"clean without upstream", "# branch.oid 1a2b\n# branch.head main\n", Status{Branch: "main"}},
+		{"ahead and behind", "# branch.oid 1a2b\n# branch.head feature/auth\n# branch.upstream origin/feature/auth\n# branch.ab +3 -1\n",
+			Status{Branch: "feature/auth", Ahead: 3, Behind: 1}},
+		{"modified", "# branch.head main\n1 .M N... 100644 100644 100644 1a2b 1a2b sub/f.txt\n", Status{Branch: "main", Dirty: true}},
+		{"untracked", "# branch.head main\n? notes.md\n", Status{Branch: "main", Dirty: true}},
+		{"detached", "# branch.oid 1a2b\n# branch.head (detached)\n", Status{}},
+	}
+	for _, tt := range tests {
+		if got := parseGitStatus(tt.output); got != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestJJColocatedBackend(t *testing.T) {
+	if _, err := exec.LookPath("jj"); err != nil {
+		t.Skip("jj not installed")
+	}
+	root, run := newTestRepo(t,
+		[]string{"git", "init", "-q", "-b", "main"},
+		[]string{"jj", "git", "init", "--colocate"},
+		[]string{"jj", "describe", "-m", "init"},
+		[]string{"jj", "bookmark", "create", "-r", "@", "feature"},
+		[]string{"jj", "new"},
+	)
+	sub := filepath.Join(root, "sub")
+
+	// Colocated repos are detected as jj even though git works too
+	v := Detect(sub)
+	if v == nil || v.Name() != "jj" {
+		t.Fatalf("expected jj, got %v", v)
+	}
+	if got, err := v.Root(sub); err != nil || got != root {
+		t.Errorf("expected root %s, got %s (%v)", root, got, err)
+	}
+
+	parent := strings.TrimSpace(run("jj", "log", "-r", "@-", "--no-graph", "-T", "change_id"))
+	change, short, err := v.CurrentCommit(sub)
+	if err != nil || change == "" || change == parent || !strings.HasPrefix(change, short) {
+		t.Fatalf("unexpected current change %q/%q (%v)", change, short, err)
+	}
+
+	os.WriteFile(filepath.Join(sub, "f.txt"), []byte("one\nTWO\n"), 0644)
+	if content, err := v.FileAtCommit(sub, "f.txt", parent); err != nil || content != "one\ntwo\n" {
+		t.Errorf("expected content at parent change, got %q (%v)", content, err)
+	}
+
+	// Blame resolves change IDs to commits in jj's git store
+	lines, err := v.Blame(sub, "f.txt", "")
+	if err != nil || len(lines) != 2 {
+		t.Fatalf("unexpected blame %+v (%v)", lines, err)
+	}
+	parentCommit := strings.TrimSpace(run("jj", "log", "-r", "@-", "--no-graph", "-T", "commit_id"))
+	if lines[0].SHA != parentCommit || lines[1].SHA == parentCommit {
+		t.Errorf("expected line 1 from %s and line 2 from @, got %+v", parentCommit, lines)
+	}
+
+	branches, err := v.Branches(sub)
+	if err != nil || len(branches) == 0 || branches[0] != "feature" {
+		t.Errorf("expected feature bookmark, got %v (%v)", branches, err)
+	}
+
+	// The working copy sits on top of the bookmark, with f.txt changed
+	if status, err := v.Status(sub); err != nil || status != (Status{Branch: "feature", Dirty: true}) {
+		t.Errorf("expected feature with changes, got %+v (%v)", status, err)
+	}
+}
+
+func TestJJGitDir(t *testing.T) {
+	root := t.TempDir()
+	store := filepath.Join(root, ".jj", "repo", "store")
+	if err := os.MkdirAll(store, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Colocated: the store points back at the workspace's .git
+	os.WriteFile(filepath.Join(store, "git_target"), []byte("../../../.git"), 0644)
+	if got, err := jjGitDir(root); err != nil || got != filepath.Join(root, ".git") {
+		t.Errorf("expected %s, got %s (%v)", filepath.Join(root, ".git"), got, err)
+	}
+
+	// Secondary workspace: .jj/repo is a file naming the main repo
+	workspace := t.TempDir()
+	os.MkdirAll(filepath.Join(workspace, ".jj"), 0755)
+	os.WriteFile(filepath.Join(workspace, ".jj", "repo"), []byte(filepath.Join(root, ".jj", "repo")), 0644)
+	if got, err := jjGitDir(workspace); err != nil || got != filepath.Join(root, ".git") {
+		t.Errorf("expected main repo's git dir, got %s (%v)", got, err)
+	}
+
+	if _, err := jjGitDir(t.TempDir()); err == nil {
+		t.Error("expected an error outside a jj repo")
+	}
+}
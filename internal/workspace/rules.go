@@ -0,0 +1,188 @@
+// Package workspace decides which workspaces and files claude-mon records,
+// from the user's tracked/ignored globs in daemon.toml and a project's
+// .claude/claude-mon-ignore file.
+package workspace
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ProjectFile is the per-project ignore file, relative to the workspace root
+const ProjectFile = ".claude/claude-mon-ignore"
+
+// Rules are the user's tracked and ignored patterns. Patterns are globs: *
+// and ? don't cross directories, ** does, and ~ is the home directory.
+// Absolute patterns match from the root; relative ones such as node_modules
+// or *.log match at any path segment. A pattern matching a directory also
+// matches everything under it, so "/tmp" keeps its old prefix meaning.
+type Rules struct {
+	Tracked []string // Only these workspaces are recorded; empty records all
+	Ignored []string // Workspaces and files never recorded
+}
+
+// Pattern is one line of a project ignore file
+type Pattern struct {
+	Glob    string // Relative to the workspace root when it starts with /
+	Include bool   // Re-includes matches ("!pattern")
+
+	re *regexp.Regexp
+}
+
+// TrackWorkspace reports whether activity in a workspace is recorded
+func (r Rules) TrackWorkspace(path string) bool {
+	if path == "" {
+		return true
+	}
+	if len(r.Tracked) > 0 && !matchAny(r.Tracked, path) {
+		return false
+	}
+	return !matchAny(r.Ignored, path)
+}
+
+// TrackFile reports whether an edit to file in workspace ws is recorded:
+// the workspace is tracked, and the last of the user's ignored patterns and
+// the project's patterns to match the file doesn't ignore it
+func (r Rules) TrackFile(ws, file string) bool {
+	if !r.TrackWorkspace(ws) {
+		return false
+	}
+	if file == "" {
+		return true
+	}
+	if !filepath.IsAbs(file) && ws != "" {
+		file = filepath.Join(ws, file)
+	}
+	ignored := matchAny(r.Ignored, file)
+
+	if ws == "" {
+		return !ignored
+	}
+	rel, err := filepath.Rel(ws, file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, "../") {
+		return !ignored
+	}
+	patterns, _ := LoadProject(ws)
+	for _, p := range patterns {
+		if p.Matches(rel) {
+			ignored = !p.Include
+		}
+	}
+	return !ignored
+}
+
+// LoadProject reads the patterns of a workspace's ignore file, in order.
+// The file is gitignore-like: one pattern per line, # comments, and !pattern
+// to re-include. A missing file has no patterns.
+func LoadProject(ws string) ([]Pattern, error) {
+	f, err := os.Open(filepath.Join(ws, ProjectFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []Pattern
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p := Pattern{Glob: line}
+		if strings.HasPrefix(line, "!") {
+			p.Include = true
+			p.Glob = strings.TrimPrefix(line, "!")
+		}
+		re, err := globRegexp(strings.TrimSuffix(p.Glob, "/"))
+		if err != nil {
+			continue
+		}
+		p.re = re
+		patterns = append(patterns, p)
+	}
+	return patterns, scanner.Err()
+}
+
+// Matches reports whether the pattern matches a path relative to the
+// workspace root
+func (p Pattern) Matches(rel string) bool {
+	// Rooted so that "/build" matches only at the workspace root
+	return p.re != nil && p.re.MatchString("/"+filepath.ToSlash(rel))
+}
+
+// String renders the pattern as written in the ignore file
+func (p Pattern) String() string {
+	if p.Include {
+		return "!" + p.Glob
+	}
+	return p.Glob
+}
+
+// Match reports whether a user pattern matches path. Invalid patterns never
+// match.
+func Match(pattern, path string) bool {
+	re, err := globRegexp(expandHome(strings.TrimSuffix(pattern, "/")))
+	return err == nil && re.MatchString(filepath.ToSlash(path))
+}
+
+func matchAny(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if Match(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+func expandHome(pattern string) string {
+	if pattern == "~" || strings.HasPrefix(pattern, "~/") {
+		home, err := os.UserHomeDir()
+		if err == nil {
+			return home + pattern[1:]
+		}
+	}
+	return pattern
+}
+
+// globRegexp converts a path glob to a regexp. * and ? don't cross
+// directories; ** does. Relative globs are anchored at a path segment, and
+// a glob matching a directory matches everything under it.
+func globRegexp(glob string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	if strings.HasPrefix(glob, "/") {
+		sb.WriteString("^")
+	} else {
+		sb.WriteString("(^|/)")
+	}
+
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				// "**/" also matches zero directories
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					i++
+					sb.WriteString("(.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
+			}
+		case '?':
+			sb.WriteString("[^/]")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("(/.*)?$")
+
+	return regexp.Compile(sb.String())
+}
@@ -0,0 +1,113 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	home, _ := os.UserHomeDir()
+
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/tmp", "/tmp", true},
+		{"/tmp", "/tmp/scratch/app", true},
+		{"/tmp", "/tmpfiles", false},
+		{"/tmp/", "/tmp/scratch", true},
+		{"~/tmp/**", filepath.Join(home, "tmp", "a", "b"), true},
+		{"~/tmp/**", filepath.Join(home, "src", "tmp"), false},
+		{"node_modules", "/home/me/app/node_modules/left-pad/index.js", true},
+		{"node_modules", "/home/me/app/src/index.js", false},
+		{"*.log", "/home/me/app/debug.log", true},
+		{"/home/*/scratch", "/home/me/scratch/x.go", true},
+		{"/home/*/scratch", "/home/me/src/scratch/x.go", false},
+		{"/home/**/vendor", "/home/me/src/app/vendor/x.go", true},
+	}
+	for _, tt := range tests {
+		if got := Match(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestTrackWorkspace(t *testing.T) {
+	r := Rules{Ignored: []string{"/tmp", "/var/tmp"}}
+	if r.TrackWorkspace("/tmp/scratch") {
+		t.Error("workspace under /tmp should be ignored")
+	}
+	if !r.TrackWorkspace("/home/me/app") {
+		t.Error("workspace outside ignored paths should be tracked")
+	}
+
+	r = Rules{Tracked: []string{"/home/me/src/**"}, Ignored: []string{"/home/me/src/legacy"}}
+	if !r.TrackWorkspace("/home/me/src/app") {
+		t.Error("workspace under tracked glob should be tracked")
+	}
+	if r.TrackWorkspace("/home/me/other") {
+		t.Error("workspace outside tracked globs should not be tracked")
+	}
+	if r.TrackWorkspace("/home/me/src/legacy") {
+		t.Error("ignored workspace should win over tracked glob")
+	}
+}
+
+func TestTrackFile(t *testing.T) {
+	ws := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(ws, ".claude"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	project := "# generated code\n/gen\n*.pb.go\n!keep.pb.go\n\n"
+	if err := os.WriteFile(filepath.Join(ws, ProjectFile), []byte(project), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := Rules{Ignored: []string{"node_modules"}}
+	tests := []struct {
+		file string
+		want bool
+	}{
+		{filepath.Join(ws, "main.go"), true},
+		{filepath.Join(ws, "node_modules", "x", "index.js"), false},
+		{filepath.Join(ws, "gen", "api.go"), false},
+		{filepath.Join(ws, "internal", "gen", "api.go"), true},
+		{filepath.Join(ws, "api", "api.pb.go"), false},
+		{filepath.Join(ws, "api", "keep.pb.go"), true},
+		{"relative.go", true},
+		{"gen/relative.go", false},
+	}
+	for _, tt := range tests {
+		if got := r.TrackFile(ws, tt.file); got != tt.want {
+			t.Errorf("TrackFile(%q) = %v, want %v", tt.file, got, tt.want)
+		}
+	}
+
+	// Project rules can re-include what the user ignores
+	if err := os.WriteFile(filepath.Join(ws, ProjectFile), []byte("!node_modules/patched\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !r.TrackFile(ws, filepath.Join(ws, "node_modules", "patched", "index.js")) {
+		t.Error("project rule should re-include the patched module")
+	}
+}
+
+func TestLoadProject(t *testing.T) {
+	patterns, err := LoadProject(t.TempDir())
+	if err != nil || patterns != nil {
+		t.Fatalf("LoadProject without file = %v, %v; want no patterns", patterns, err)
+	}
+
+	ws := t.TempDir()
+	os.MkdirAll(filepath.Join(ws, ".claude"), 0755)
+	os.WriteFile(filepath.Join(ws, ProjectFile), []byte("dist/\n!dist/keep\n"), 0644)
+	patterns, err = LoadProject(ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patterns) != 2 || patterns[0].String() != "dist/" || patterns[1].String() != "!dist/keep" {
+		t.Errorf("LoadProject = %v", patterns)
+	}
+}
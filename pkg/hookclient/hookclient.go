@@ -0,0 +1,259 @@
+// Package hookclient sends Claude Code hook payloads to a running claude-mon TUI.
+//
+// It is the library behind `claude-mon send` and can be linked directly by
+// custom hooks written in Go instead of shelling out to the binary:
+//
+//	c := hookclient.New(hookclient.SocketPath())
+//	if err := c.Send(payload); err != nil {
+//		// TUI not running - safe to ignore
+//	}
+package hookclient
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// Schema identifies claude-mon hook envelopes
+	Schema = "claude-mon/hook"
+	// Version is the current envelope version
+	Version = 1
+)
+
+// ErrInvalidPayload is returned when the payload is not a JSON object
+var ErrInvalidPayload = errors.New("payload must be a JSON object")
+
+// Envelope wraps a raw hook payload with schema and version information
+type Envelope struct {
+	Schema  string          `json:"schema"`
+	Version int             `json:"version"`
+	SentAt  time.Time       `json:"sent_at"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Client sends hook payloads to the TUI socket
+type Client struct {
+	SocketPath  string        // Unix socket of the TUI
+	Attempts    int           // Total connection attempts (including the first)
+	Backoff     time.Duration // Delay before the first retry, doubled on each retry
+	DialTimeout time.Duration // Timeout for each connection attempt
+	SpoolDir    string        // If set, undeliverable payloads are written here, per socket
+}
+
+// New creates a client with default retry settings.
+// Setting CLAUDE_MON_SPOOL_DIR enables spooling of undeliverable payloads.
+func New(socketPath string) *Client {
+	return &Client{
+		SocketPath:  socketPath,
+		Attempts:    3,
+		Backoff:     50 * time.Millisecond,
+		DialTimeout: time.Second,
+		SpoolDir:    os.Getenv("CLAUDE_MON_SPOOL_DIR"),
+	}
+}
+
+// SocketPath returns the TUI socket path for the current workspace.
+// Mirrors socket.GetSocketPath so external hooks compute the same path.
+func SocketPath() string {
+	cwd, err := os.Getwd()
+	if err != nil {
+		cwd = "."
+	}
+	if abs, err := filepath.Abs(cwd); err == nil {
+		cwd = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(cwd); err == nil {
+		cwd = resolved
+	}
+
+	hash := sha256.Sum256([]byte(cwd))
+	hashStr := fmt.Sprintf("%x", hash)[:12]
+
+	user := os.Getenv("USER")
+	if user == "" {
+		user = "unknown"
+	}
+
+	return fmt.Sprintf("/tmp/claude-mon-%s-%s.sock", user, hashStr)
+}
+
+// Wrap validates a raw hook payload and wraps it in an envelope.
+// Payloads that are already enveloped are returned unchanged.
+func Wrap(payload []byte) ([]byte, error) {
+	payload = bytes.TrimSpace(payload)
+	if len(payload) == 0 || payload[0] != '{' || !json.Valid(payload) {
+		return nil, ErrInvalidPayload
+	}
+
+	if _, ok := parseEnvelope(payload); ok {
+		return payload, nil
+	}
+
+	return json.Marshal(Envelope{
+		Schema:  Schema,
+		Version: Version,
+		SentAt:  time.Now(),
+		Payload: json.RawMessage(payload),
+	})
+}
+
+// Unwrap returns the inner payload of an envelope.
+// Data that is not an envelope (e.g. raw JSON from a shell hook) is returned as-is.
+func Unwrap(data []byte) []byte {
+	if env, ok := parseEnvelope(data); ok {
+		return env.Payload
+	}
+	return data
+}
+
+// parseEnvelope decodes data as an envelope, reporting whether it was one
+func parseEnvelope(data []byte) (*Envelope, bool) {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, false
+	}
+	if env.Schema != Schema || len(env.Payload) == 0 {
+		return nil, false
+	}
+	return &env, true
+}
+
+// Send validates, wraps and delivers a payload.
+// Transient connection failures are retried with exponential backoff. If
+// delivery still fails and SpoolDir is set, the envelope is spooled and
+// redelivered by a later successful Send.
+func (c *Client) Send(payload []byte) error {
+	data, err := Wrap(payload)
+	if err != nil {
+		return err
+	}
+
+	if err := c.deliver(data); err != nil {
+		if c.SpoolDir != "" {
+			if spoolErr := c.spool(data); spoolErr != nil {
+				return fmt.Errorf("%w (spool failed: %v)", err, spoolErr)
+			}
+		}
+		return err
+	}
+
+	if c.SpoolDir != "" {
+		c.drainSpool()
+	}
+	return nil
+}
+
+// deliver writes data to the socket, retrying transient failures
+func (c *Client) deliver(data []byte) error {
+	attempts := c.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	backoff := c.Backoff
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		err = c.write(data)
+		if err == nil || !isTransient(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// write sends data over a single connection and closes it
+func (c *Client) write(data []byte) error {
+	conn, err := net.DialTimeout("unix", c.SocketPath, c.DialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// Newline-delimited, the framing the TUI socket reads
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	// Signal EOF so the listener doesn't wait for more payloads
+	if uc, ok := conn.(*net.UnixConn); ok {
+		return uc.CloseWrite()
+	}
+	return nil
+}
+
+// isTransient reports whether a delivery error is worth retrying.
+// A missing socket means the TUI is not running, so it is not retried.
+func isTransient(err error) bool {
+	if errors.Is(err, syscall.ENOENT) {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EAGAIN) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// spoolPath is the directory envelopes for the client's socket are spooled
+// in. Each socket gets its own, so a spool dir shared by several workspaces
+// only replays payloads into the TUI they were sent to.
+func (c *Client) spoolPath() string {
+	hash := sha256.Sum256([]byte(c.SocketPath))
+	return filepath.Join(c.SpoolDir, fmt.Sprintf("%x", hash)[:12])
+}
+
+// spool writes an undeliverable envelope to the socket's spool directory
+func (c *Client) spool(data []byte) error {
+	dir := c.spoolPath()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d.json", time.Now().UnixNano())
+	return os.WriteFile(filepath.Join(dir, name), data, 0600)
+}
+
+// drainSpool redelivers envelopes spooled for the socket oldest first,
+// stopping at the first failure
+func (c *Client) drainSpool() {
+	dir := c.spoolPath()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if err := c.deliver(data); err != nil {
+			return
+		}
+		os.Remove(path)
+	}
+}
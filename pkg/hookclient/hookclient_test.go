@@ -0,0 +1,176 @@
+package hookclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWrapUnwrap(t *testing.T) {
+	raw := []byte(`{"tool_name":"Edit","tool_input":{"file_path":"test.go"}}`)
+
+	wrapped, err := Wrap(raw)
+	if err != nil {
+		t.Fatalf("wrap failed: %v", err)
+	}
+
+	var env Envelope
+	if err := json.Unmarshal(wrapped, &env); err != nil {
+		t.Fatalf("envelope should be valid JSON: %v", err)
+	}
+	if env.Schema != Schema || env.Version != Version {
+		t.Errorf("unexpected envelope header: %s v%d", env.Schema, env.Version)
+	}
+
+	if got := Unwrap(wrapped); string(got) != string(raw) {
+		t.Errorf("unwrap mismatch: expected %s, got %s", raw, got)
+	}
+
+	// Wrapping twice must not nest envelopes
+	again, err := Wrap(wrapped)
+	if err != nil {
+		t.Fatalf("re-wrap failed: %v", err)
+	}
+	if string(again) != string(wrapped) {
+		t.Error("re-wrapping an envelope should return it unchanged")
+	}
+
+	// Raw payloads pass through Unwrap untouched
+	if got := Unwrap(raw); string(got) != string(raw) {
+		t.Errorf("raw payload should pass through, got %s", got)
+	}
+}
+
+func TestWrapRejectsInvalid(t *testing.T) {
+	for _, payload := range []string{"", "not json", `["array"]`, `{"unterminated":`} {
+		if _, err := Wrap([]byte(payload)); err != ErrInvalidPayload {
+			t.Errorf("expected ErrInvalidPayload for %q, got %v", payload, err)
+		}
+	}
+}
+
+func TestSendDelivers(t *testing.T) {
+	socketPath := "/tmp/claude-mon-test-hookclient.sock"
+	os.Remove(socketPath)
+	defer os.Remove(socketPath)
+
+	ln, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		data, _ := io.ReadAll(conn)
+		received <- data
+	}()
+
+	payload := `{"tool_name":"Write","tool_input":{"file_path":"a.go","content":"x"}}`
+	if err := New(socketPath).Send([]byte(payload)); err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+
+	select {
+	case data := <-received:
+		if got := Unwrap(data); string(got) != payload {
+			t.Errorf("payload mismatch: expected %s, got %s", payload, got)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("timeout waiting for payload")
+	}
+}
+
+func TestSendSpoolsWhenUnavailable(t *testing.T) {
+	spoolDir := t.TempDir()
+
+	c := New(filepath.Join(spoolDir, "missing.sock"))
+	c.SpoolDir = spoolDir
+
+	if err := c.Send([]byte(`{"tool_name":"Edit"}`)); err == nil {
+		t.Fatal("expected error when socket is missing")
+	}
+
+	entries, err := os.ReadDir(c.spoolPath())
+	if err != nil {
+		t.Fatalf("failed to read spool dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected 1 spooled payload, got %d", len(entries))
+	}
+}
+
+func TestSpoolReplaysToItsOwnSocket(t *testing.T) {
+	dir := t.TempDir()
+	listen := func(socketPath string) <-chan string {
+		t.Helper()
+		ln, err := net.Listen("unix", socketPath)
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		t.Cleanup(func() { ln.Close() })
+		received := make(chan string, 10)
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				data, _ := io.ReadAll(conn)
+				conn.Close()
+				received <- string(Unwrap(bytes.TrimSpace(data)))
+			}
+		}()
+		return received
+	}
+	client := func(socketPath string) *Client {
+		c := New(socketPath)
+		c.SpoolDir = filepath.Join(dir, "spool")
+		return c
+	}
+	drain := func(received <-chan string) []string {
+		var got []string
+		for {
+			select {
+			case payload := <-received:
+				got = append(got, payload)
+			case <-time.After(200 * time.Millisecond):
+				return got
+			}
+		}
+	}
+
+	// A's TUI is down, so its payload is spooled
+	socketA, socketB := filepath.Join(dir, "a.sock"), filepath.Join(dir, "b.sock")
+	if err := client(socketA).Send([]byte(`{"to":"a"}`)); err == nil {
+		t.Fatal("expected error when socket is missing")
+	}
+
+	// B's next send doesn't replay it
+	receivedB := listen(socketB)
+	if err := client(socketB).Send([]byte(`{"to":"b"}`)); err != nil {
+		t.Fatalf("send to b failed: %v", err)
+	}
+	if got := drain(receivedB); len(got) != 1 || got[0] != `{"to":"b"}` {
+		t.Errorf("expected only b's payload at b, got %v", got)
+	}
+
+	// A's does
+	receivedA := listen(socketA)
+	if err := client(socketA).Send([]byte(`{"to":"a","n":2}`)); err != nil {
+		t.Fatalf("send to a failed: %v", err)
+	}
+	if got := drain(receivedA); len(got) != 2 || got[0] != `{"to":"a","n":2}` || got[1] != `{"to":"a"}` {
+		t.Errorf("expected a's new and spooled payloads at a, got %v", got)
+	}
+}
@@ -98,7 +98,8 @@ case "$COMMAND" in
 	"old_string": $(echo "$OLD_STRING" | jq -Rs .),
 	"new_string": $(echo "$NEW_STRING" | jq -Rs .),
 	"line_num": $LINE_NUM,
-	"line_count": $LINE_COUNT
+	"line_count": $LINE_COUNT,
+	"token": $(printf '%s' "${CLAUDE_MON_TOKEN:-}" | jq -Rs .)
 }
 EOF
 			)
@@ -129,7 +130,8 @@ EOF
 	"prompt_name": $(echo "$PROMPT_NAME" | jq -Rs .),
 	"prompt_description": $(echo "$PROMPT_DESC" | jq -Rs .),
 	"new_string": $(echo "$PROMPT_CONTENT" | jq -Rs .),
-	"prompt_tags": $(echo "$PROMPT_TAGS" | jq -R '. | split(" ")' 2>/dev/null || echo "[]")
+	"prompt_tags": $(echo "$PROMPT_TAGS" | jq -R '. | split(" ")' 2>/dev/null || echo "[]"),
+	"token": $(printf '%s' "${CLAUDE_MON_TOKEN:-}" | jq -Rs .)
 }
 EOF
 		)
@@ -0,0 +1,222 @@
+-- claude-mon.lua: mark the lines Claude recently changed and jump between
+-- them, using the claude-mon daemon's editor socket (see DAEMON.md).
+--
+-- A reference for plugin authors, usable as is. Enable the socket in the
+-- daemon config ([editor] enabled = true), copy this file to
+-- ~/.config/nvim/lua/claude-mon.lua and add to init.lua:
+--
+--   require("claude-mon").setup()
+--
+-- Then ]c and [c jump to the next and previous change Claude made in the
+-- buffer (pass keymaps = false to keep them for diff mode or gitsigns), and
+-- :ClaudeMonHunk shows what Claude did at the cursor.
+
+local M = {}
+
+local config = {
+  socket = vim.env.CLAUDE_MON_EDITOR_SOCKET or "/tmp/claude-mon-editor.sock",
+  token = vim.env.CLAUDE_MON_TOKEN, -- Only needed with auth.require_local
+  since = nil, -- e.g. "1h"; the daemon's editor.recent_hours if nil
+  signs = { add = "┃", change = "┃", delete = "▁" },
+  keymaps = true,
+}
+
+local ns = vim.api.nvim_create_namespace("claude-mon")
+local pipe
+local next_id = 0
+local pending = {} -- Response callbacks by request id
+local hunks = {} -- Hunks by buffer, newest first
+
+-- send writes a JSON-RPC request, calling on_result with its result
+local function send(method, params, on_result)
+  if not pipe then
+    return
+  end
+  next_id = next_id + 1
+  pending[next_id] = on_result
+  params = params or {}
+  params.token = config.token
+  pipe:write(vim.json.encode({ jsonrpc = "2.0", id = next_id, method = method, params = params }) .. "\n")
+end
+
+-- render marks a buffer's hunks in its sign column; newer hunks are drawn
+-- over older ones
+local function render(buf)
+  if not vim.api.nvim_buf_is_valid(buf) then
+    return
+  end
+  vim.api.nvim_buf_clear_namespace(buf, ns, 0, -1)
+  local count = vim.api.nvim_buf_line_count(buf)
+  local list = hunks[buf] or {}
+  for i = #list, 1, -1 do
+    local h = list[i]
+    local hl = h.kind == "add" and "DiffAdd" or h.kind == "delete" and "DiffDelete" or "DiffChange"
+    local last = math.min(h.end_line, count)
+    for line = math.min(h.start_line, count), last do
+      vim.api.nvim_buf_set_extmark(buf, ns, line - 1, 0, {
+        sign_text = config.signs[h.kind] or "┃",
+        sign_hl_group = hl,
+        priority = 5,
+      })
+    end
+  end
+end
+
+local function buf_for(file)
+  local buf = vim.fn.bufnr(file)
+  if buf ~= -1 and vim.api.nvim_buf_is_loaded(buf) then
+    return buf
+  end
+end
+
+-- refresh lists a buffer's hunks again
+local function refresh(buf)
+  local file = vim.api.nvim_buf_get_name(buf)
+  if file == "" then
+    return
+  end
+  send("hunks", { file = file, since = config.since }, function(result)
+    hunks[buf] = result.hunks
+    render(buf)
+  end)
+end
+
+-- on_message handles one line from the daemon
+local function on_message(line)
+  local ok, msg = pcall(vim.json.decode, line)
+  if not ok then
+    return
+  end
+  if msg.id ~= nil then
+    local cb = pending[msg.id]
+    pending[msg.id] = nil
+    if msg.error then
+      vim.notify("claude-mon: " .. msg.error.message, vim.log.levels.WARN)
+    elseif cb then
+      cb(msg.result)
+    end
+  elseif msg.method == "hunk" then
+    local buf = buf_for(msg.params.file)
+    if buf then
+      hunks[buf] = hunks[buf] or {}
+      table.insert(hunks[buf], 1, msg.params)
+      render(buf)
+    end
+  elseif msg.method == "reset" then
+    for buf in pairs(hunks) do
+      refresh(buf)
+    end
+  end
+end
+
+local function connect()
+  pipe = vim.uv.new_pipe(false)
+  pipe:connect(config.socket, function(err)
+    if err then
+      pipe = nil
+      return -- Daemon or editor socket not running
+    end
+    local buffered = ""
+    pipe:read_start(function(_, data)
+      if not data then
+        pipe = nil
+        return
+      end
+      buffered = buffered .. data
+      while true do
+        local nl = buffered:find("\n", 1, true)
+        if not nl then
+          break
+        end
+        local line = buffered:sub(1, nl - 1)
+        buffered = buffered:sub(nl + 1)
+        vim.schedule(function()
+          on_message(line)
+        end)
+      end
+    end)
+    vim.schedule(function()
+      send("initialize", {})
+      for _, buf in ipairs(vim.api.nvim_list_bufs()) do
+        if vim.api.nvim_buf_is_loaded(buf) then
+          M.attach(buf)
+        end
+      end
+    end)
+  end)
+end
+
+-- attach lists a buffer's hunks and subscribes to its file
+function M.attach(buf)
+  local file = vim.api.nvim_buf_get_name(buf)
+  if file == "" or vim.bo[buf].buftype ~= "" then
+    return
+  end
+  send("subscribe", { file = file })
+  refresh(buf)
+end
+
+-- jump moves to the next (1) or previous (-1) hunk in the buffer
+function M.jump(dir)
+  local buf = vim.api.nvim_get_current_buf()
+  local cur = vim.api.nvim_win_get_cursor(0)[1]
+  local target
+  for _, h in ipairs(hunks[buf] or {}) do
+    local line = h.start_line
+    if dir > 0 and line > cur and (not target or line < target) then
+      target = line
+    elseif dir < 0 and line < cur and (not target or line > target) then
+      target = line
+    end
+  end
+  if target then
+    vim.api.nvim_win_set_cursor(0, { math.min(target, vim.api.nvim_buf_line_count(buf)), 0 })
+  end
+end
+
+-- hunk_at shows what Claude did at the cursor
+function M.hunk_at()
+  local buf = vim.api.nvim_get_current_buf()
+  local cur = vim.api.nvim_win_get_cursor(0)[1]
+  for _, h in ipairs(hunks[buf] or {}) do
+    if cur >= h.start_line and cur <= h.end_line then
+      vim.notify(string.format("%s (%s)", h.message, h.timestamp))
+      return
+    end
+  end
+end
+
+function M.setup(opts)
+  config = vim.tbl_deep_extend("force", config, opts or {})
+  connect()
+
+  local group = vim.api.nvim_create_augroup("claude-mon", { clear = true })
+  vim.api.nvim_create_autocmd("BufReadPost", {
+    group = group,
+    callback = function(args)
+      M.attach(args.buf)
+    end,
+  })
+  vim.api.nvim_create_autocmd("BufWipeout", {
+    group = group,
+    callback = function(args)
+      local file = vim.api.nvim_buf_get_name(args.buf)
+      hunks[args.buf] = nil
+      if file ~= "" then
+        send("unsubscribe", { file = file })
+      end
+    end,
+  })
+
+  vim.api.nvim_create_user_command("ClaudeMonHunk", M.hunk_at, {})
+  if config.keymaps then
+    vim.keymap.set("n", "]c", function()
+      M.jump(1)
+    end, { desc = "Next change by Claude" })
+    vim.keymap.set("n", "[c", function()
+      M.jump(-1)
+    end, { desc = "Previous change by Claude" })
+  end
+end
+
+return M